@@ -0,0 +1,405 @@
+// Command choochooctl manages the dynamic configuration of a choochoo
+// instance as a declarative YAML bundle.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/config"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/metrics"
+	"github.com/deedubs/choochoo/internal/settings"
+	"github.com/deedubs/choochoo/internal/tui"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const usage = `Usage: choochooctl <command> [flags]
+
+Commands:
+  export                       Print the stored configuration as a YAML bundle
+  diff -f FILE [-env ENV]      Show the changes applying FILE would make
+  apply -f FILE [-env ENV]     Apply FILE, removing settings it does not list
+  config render -f FILE [-env ENV]
+                               Print FILE with the overlay of ENV applied
+  grafana export [-o FILE]     Print a Grafana dashboard of the pushed metrics
+  token create -name NAME -scopes SCOPES [-org ORG]
+                               Create an API token with comma-separated scopes
+                               (read, stats, replay, admin) and print it once;
+                               with -org the token is limited to that tenant
+  token revoke -name NAME      Revoke the API token called NAME
+  token list                   List API tokens with their scopes and last use
+  tui [-interval DURATION] [-limit N]
+                               Watch events, queue depths and failures live,
+                               replaying events and pausing processing
+
+-env defaults to CHOOCHOO_ENV; without it only the base settings are used.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var code int
+	switch os.Args[1] {
+	case "export":
+		code = export()
+	case "diff":
+		code = apply("diff", os.Args[2:], true)
+	case "apply":
+		code = apply("apply", os.Args[2:], false)
+	case "config":
+		code = configCommand(os.Args[2:])
+	case "grafana":
+		code = grafanaCommand(os.Args[2:])
+	case "token":
+		code = tokenCommand(os.Args[2:])
+	case "tui":
+		code = tuiCommand(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		code = 2
+	}
+	os.Exit(code)
+}
+
+// envVar selects the environment overlay when -env is not given
+const envVar = "CHOOCHOO_ENV"
+
+// readBundle reads the bundle in file and resolves the overlay of env
+func readBundle(file, env string) (*settings.Bundle, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := settings.ParseBundle(data)
+	if err != nil {
+		return nil, err
+	}
+	return bundle.Resolve(env)
+}
+
+// configCommand runs a config subcommand
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "render" {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config render", flag.ExitOnError)
+	file := flags.String("f", "", "config bundle to render")
+	env := flags.String("env", os.Getenv(envVar), "environment overlay to apply")
+	flags.Parse(args[1:])
+
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "config render: -f is required")
+		return 2
+	}
+
+	bundle, err := readBundle(*file, *env)
+	if err == nil {
+		// Rendering validates the result the way apply would
+		_, err = bundle.Settings()
+	}
+	if err == nil {
+		_, err = bundle.OverrideSettings()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config render: %v\n", err)
+		return 1
+	}
+	data, err := bundle.Marshal()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config render: %v\n", err)
+		return 1
+	}
+	os.Stdout.Write(data)
+	return 0
+}
+
+// grafanaCommand runs a grafana subcommand
+func grafanaCommand(args []string) int {
+	if len(args) == 0 || args[0] != "export" {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("grafana export", flag.ExitOnError)
+	output := flags.String("o", "", "file to write the dashboard to instead of stdout")
+	flags.Parse(args[1:])
+
+	data, err := metrics.GrafanaDashboard()
+	if err == nil && *output != "" {
+		err = os.WriteFile(*output, append(data, '\n'), 0o644)
+	} else if err == nil {
+		_, err = os.Stdout.Write(append(data, '\n'))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "grafana export: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// tokenCommand runs a token subcommand
+func tokenCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+	command := "token " + args[0]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	name := flags.String("name", "", "name of the token")
+	scopeList := flags.String("scopes", "", "comma-separated scopes of the token: read, stats, replay or admin")
+	org := flags.String("org", "", "organization to limit the token to, for tenants")
+	flags.Parse(args[1:])
+
+	var scopes []apitoken.Scope
+	switch args[0] {
+	case "create":
+		if *name == "" {
+			fmt.Fprintf(os.Stderr, "%s: -name is required\n", command)
+			return 2
+		}
+		var err error
+		if scopes, err = apitoken.ParseScopes(*scopeList); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+			return 2
+		}
+	case "revoke":
+		if *name == "" {
+			fmt.Fprintf(os.Stderr, "%s: -name is required\n", command)
+			return 2
+		}
+	case "list":
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+
+	ctx := context.Background()
+
+	dbConn, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return 1
+	}
+	defer dbConn.Close(ctx)
+
+	switch args[0] {
+	case "create":
+		var token string
+		if *org != "" {
+			token, err = apitoken.CreateTenant(ctx, dbConn.Queries(), *org, *name, scopes)
+		} else {
+			token, err = apitoken.Create(ctx, dbConn.Queries(), *name, scopes)
+		}
+		if err == nil {
+			fmt.Println(token)
+			fmt.Fprintln(os.Stderr, "Store the token now; it cannot be shown again.")
+		}
+	case "revoke":
+		if err = apitoken.Revoke(ctx, dbConn.Queries(), *name); err == nil {
+			fmt.Printf("Revoked %s\n", *name)
+		}
+	case "list":
+		err = listTokens(ctx, dbConn.Queries())
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return 1
+	}
+	return 0
+}
+
+// listTokens prints the stored tokens as a table
+func listTokens(ctx context.Context, queries *db.Queries) error {
+	tokens, err := queries.ListAPITokens(ctx)
+	if err != nil {
+		return err
+	}
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "NAME\tSCOPES\tORGANIZATION\tCREATED\tLAST USED\tREVOKED")
+	for _, token := range tokens {
+		org := token.Organization.String
+		if org == "" {
+			org = "-"
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%s\n", token.Name, strings.Join(token.Scopes, ","), org,
+			formatTime(token.CreatedAt), formatTime(token.LastUsedAt), formatTime(token.RevokedAt))
+	}
+	return out.Flush()
+}
+
+// formatTime formats a nullable timestamp for a table, with - for NULL
+func formatTime(ts pgtype.Timestamptz) string {
+	if !ts.Valid {
+		return "-"
+	}
+	return ts.Time.Format(time.RFC3339)
+}
+
+// connect opens the database of the instance configured by the config file
+// in CHOOCHOO_CONFIG and the environment
+func connect(ctx context.Context) (*database.Connection, error) {
+	cfg, err := config.Load(os.Getenv(config.PathEnv))
+	if err != nil {
+		return nil, err
+	}
+	if database.IsSQLite(cfg.DatabaseURL) {
+		return nil, fmt.Errorf("choochooctl needs a PostgreSQL DATABASE_URL")
+	}
+	return database.NewConnection(ctx, cfg.DatabaseURL)
+}
+
+// export prints the stored settings as a bundle
+func export() int {
+	ctx := context.Background()
+
+	dbConn, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	defer dbConn.Close(ctx)
+
+	stored, err := settings.Load(ctx, dbConn.Queries())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	overrides, err := settings.LoadOverrides(ctx, dbConn.Queries())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	data, err := settings.FromStored(stored, overrides).Marshal()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	os.Stdout.Write(data)
+	return 0
+}
+
+// apply prints the changes a bundle makes to the stored settings and, unless
+// dryRun is set, applies them in a single transaction
+func apply(command string, args []string, dryRun bool) int {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	file := flags.String("f", "", "config bundle to apply")
+	if !dryRun {
+		flags.BoolVar(&dryRun, "dry-run", false, "only show the changes")
+	}
+	env := flags.String("env", os.Getenv(envVar), "environment overlay to apply")
+	flags.Parse(args)
+
+	if *file == "" {
+		fmt.Fprintf(os.Stderr, "%s: -f is required\n", command)
+		return 2
+	}
+
+	bundle, err := readBundle(*file, *env)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return 1
+	}
+	desired, err := bundle.Settings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return 1
+	}
+	desiredOverrides, err := bundle.OverrideSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return 1
+	}
+
+	ctx := context.Background()
+
+	dbConn, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return 1
+	}
+	defer dbConn.Close(ctx)
+
+	var changes []settings.Change
+	err = dbConn.InTx(ctx, func(queries *db.Queries) error {
+		if err := queries.LockInstanceSettings(ctx); err != nil {
+			return err
+		}
+		current, err := settings.Load(ctx, queries)
+		if err != nil {
+			return err
+		}
+		currentOverrides, err := settings.LoadOverrides(ctx, queries)
+		if err != nil {
+			return err
+		}
+		changes = append(settings.Diff(current, desired), settings.DiffOverrides(currentOverrides, desiredOverrides)...)
+		if dryRun {
+			return nil
+		}
+		return settings.Apply(ctx, queries, changes)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return 1
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+		return 0
+	}
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	if !dryRun {
+		fmt.Printf("Applied %d changes. Restart choochoo to pick them up.\n", len(changes))
+	}
+	return 0
+}
+
+// tuiCommand runs the terminal UI until it is quit
+func tuiCommand(args []string) int {
+	flags := flag.NewFlagSet("tui", flag.ExitOnError)
+	interval := flags.Duration("interval", tui.DefaultInterval, "how often to poll the database")
+	limit := flags.Int("limit", 100, "number of recent events to show")
+	flags.Parse(args)
+
+	if *interval <= 0 || *limit <= 0 {
+		fmt.Fprintln(os.Stderr, "tui: -interval and -limit must be positive")
+		return 2
+	}
+
+	ctx := context.Background()
+	dbConn, err := connect(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tui: %v\n", err)
+		return 1
+	}
+	defer dbConn.Close(ctx)
+
+	// Pauses are recorded as made by the operator's login
+	operator := "choochooctl"
+	if user := os.Getenv("USER"); user != "" {
+		operator += ":" + user
+	}
+	model := tui.New(tui.NewPostgresSource(dbConn.Queries(), *limit), operator, *interval)
+	if _, err := tea.NewProgram(model, tea.WithAltScreen()).Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "tui: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,34 @@
+// Command schemagen writes the JSON Schemas of the configuration formats to
+// a directory, where they are kept for editors and validators that do not
+// fetch them from a running server. It runs with go generate and make build.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/deedubs/choochoo/internal/schema"
+)
+
+func main() {
+	dir := flag.String("dir", "schemas", "directory to write the schemas to")
+	flag.Parse()
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "schemagen: %v\n", err)
+		os.Exit(1)
+	}
+	for _, name := range schema.Names() {
+		data, err := schema.Generate(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "schemagen: %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(filepath.Join(*dir, name), data, 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "schemagen: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Command webhookgen generates Go types of the payloads of the supported
+// webhook events from GitHub's OpenAPI description, read from a file or
+// fetched from a URL. With -ref, the description is fetched from a tag or
+// commit of github/rest-api-description, so the generated code does not
+// change with GitHub's default branch. It runs with go generate and make
+// webhook-types.
+//
+// With -examples, it writes the example payloads GitHub documents for the
+// supported events instead, into a directory for each event type, for the
+// contract tests of the webhook handler. It runs with make webhook-examples.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/webhook"
+	"github.com/deedubs/choochoo/internal/webhookgen"
+)
+
+// specURL is the URL of GitHub's OpenAPI description at a ref of
+// github/rest-api-description
+const specURL = "https://raw.githubusercontent.com/github/rest-api-description/%s/descriptions/api.github.com/api.github.com.json"
+
+func main() {
+	spec := flag.String("spec", "", "path or URL of GitHub's OpenAPI description")
+	ref := flag.String("ref", "", "tag or commit SHA of github/rest-api-description to fetch the description from, instead of -spec")
+	out := flag.String("out", "events_gen.go", "file to write the types to")
+	pkg := flag.String("package", "events", "package of the generated file")
+	examples := flag.String("examples", "", "directory to write the example payloads to, instead of generating types")
+	flag.Parse()
+
+	switch {
+	case *spec != "" && *ref != "":
+		fmt.Fprintln(os.Stderr, "webhookgen: -spec and -ref are mutually exclusive")
+		os.Exit(2)
+	case *ref != "":
+		*spec = fmt.Sprintf(specURL, *ref)
+	case *spec == "":
+		fmt.Fprintln(os.Stderr, "webhookgen: -spec or -ref is required")
+		os.Exit(2)
+	}
+	data, err := read(*spec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "webhookgen: %v\n", err)
+		os.Exit(1)
+	}
+
+	var events []string
+	for event := range webhook.SupportedEventTypes {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	if *examples != "" {
+		if err := writeExamples(data, events, *examples); err != nil {
+			fmt.Fprintf(os.Stderr, "webhookgen: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	src, err := webhookgen.Generate(data, webhookgen.Options{Package: *pkg, Source: *spec, Events: events})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "webhookgen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "webhookgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// writeExamples replaces the examples in the directory of each event type
+// under dir with the examples in the description. Events without examples
+// are reported, and keep the examples they had.
+func writeExamples(spec []byte, events []string, dir string) error {
+	examples, err := webhookgen.Examples(spec, events)
+	if err != nil {
+		return err
+	}
+	byEvent := make(map[string][]webhookgen.Example)
+	for _, example := range examples {
+		byEvent[example.Event] = append(byEvent[example.Event], example)
+	}
+	for _, event := range events {
+		if len(byEvent[event]) == 0 {
+			fmt.Fprintf(os.Stderr, "webhookgen: no examples for %s events in the description\n", event)
+			continue
+		}
+		eventDir := filepath.Join(dir, event)
+		old, err := filepath.Glob(filepath.Join(eventDir, "*.json"))
+		if err != nil {
+			return err
+		}
+		for _, path := range old {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(eventDir, 0o755); err != nil {
+			return err
+		}
+		for _, example := range byEvent[event] {
+			if err := os.WriteFile(filepath.Join(eventDir, example.Name+".json"), example.Payload, 0o644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// read reads the description from a file or an http(s) URL
+func read(spec string) ([]byte, error) {
+	if !strings.HasPrefix(spec, "https://") && !strings.HasPrefix(spec, "http://") {
+		return os.ReadFile(spec)
+	}
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Get(spec)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", spec, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
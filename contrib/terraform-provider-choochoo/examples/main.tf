@@ -0,0 +1,55 @@
+terraform {
+  required_providers {
+    choochoo = {
+      source = "deedubs/choochoo"
+    }
+  }
+}
+
+# endpoint and token default to CHOOCHOO_ENDPOINT and CHOOCHOO_TOKEN
+provider "choochoo" {
+  endpoint = "https://choochoo.example.com"
+}
+
+resource "choochoo_route" "critical_alerts" {
+  kind  = "security_alerts"
+  match = "critical"
+  url   = "https://chat.example.com/hooks/security"
+}
+
+resource "choochoo_route" "octo_org_digest" {
+  kind  = "community_digests"
+  match = "octo-org/*"
+  url   = "https://chat.example.com/hooks/maintainers"
+}
+
+resource "choochoo_setting" "retention" {
+  name  = "RETENTION_POLICY"
+  value = "push=30d,*=90d"
+}
+
+resource "choochoo_rule" "large_prs" {
+  name      = "large-prs"
+  condition = "event == \"pull_request\" && action == \"opened\" && payload.pull_request.additions > 500"
+  actions = jsonencode([
+    { type = "label", labels = ["size/large"] },
+    { type = "notify", url = "https://chat.example.com/hooks/reviews", message = "Large pull request opened" },
+  ])
+}
+
+resource "choochoo_api_token" "octo_org_ci" {
+  organization = "octo-org"
+  name         = "ci"
+  scopes       = ["read", "replay"]
+}
+
+output "octo_org_ci_token" {
+  value     = choochoo_api_token.octo_org_ci.token
+  sensitive = true
+}
+
+# Existing resources are imported by ID:
+#   terraform import choochoo_route.critical_alerts security_alerts/critical
+#   terraform import choochoo_setting.retention RETENTION_POLICY
+#   terraform import choochoo_rule.large_prs large-prs
+#   terraform import choochoo_api_token.octo_org_ci octo-org/ci
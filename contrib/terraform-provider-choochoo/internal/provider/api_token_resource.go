@@ -0,0 +1,173 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ resource.ResourceWithConfigure   = &apiTokenResource{}
+	_ resource.ResourceWithImportState = &apiTokenResource{}
+)
+
+// apiTokenResource manages one API token of an organization, identified by
+// the organization and the token name. Tokens cannot be changed, so every
+// change replaces the token.
+type apiTokenResource struct {
+	client *Client
+}
+
+// apiTokenModel is the state of a choochoo_api_token
+type apiTokenModel struct {
+	ID           types.String   `tfsdk:"id"`
+	Organization types.String   `tfsdk:"organization"`
+	Name         types.String   `tfsdk:"name"`
+	Scopes       []types.String `tfsdk:"scopes"`
+	Token        types.String   `tfsdk:"token"`
+}
+
+// NewAPITokenResource creates the choochoo_api_token resource
+func NewAPITokenResource() resource.Resource {
+	return &apiTokenResource{}
+}
+
+func (r *apiTokenResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_api_token"
+}
+
+func (r *apiTokenResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "An API token limited to an organization. Imported as organization/name, without its token.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"organization": schema.StringAttribute{
+				Description:   "Organization the token is limited to.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"name": schema.StringAttribute{
+				Description:   "Token name.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"scopes": schema.ListAttribute{
+				Description:   "Scopes of the token: read, stats, replay or admin.",
+				ElementType:   types.StringType,
+				Required:      true,
+				PlanModifiers: []planmodifier.List{listplanmodifier.RequiresReplace()},
+			},
+			"token": schema.StringAttribute{
+				Description:   "The token, known only to the resource that created it.",
+				Computed:      true,
+				Sensitive:     true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+		},
+	}
+}
+
+func (r *apiTokenResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*Client)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *Client, got %T", req.ProviderData))
+		return
+	}
+	r.client = client
+}
+
+func (r *apiTokenResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan apiTokenModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	scopes := make([]string, 0, len(plan.Scopes))
+	for _, scope := range plan.Scopes {
+		scopes = append(scopes, scope.ValueString())
+	}
+	token, err := r.client.CreateAPIToken(ctx, plan.Organization.ValueString(), plan.Name.ValueString(), scopes)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create API token", err.Error())
+		return
+	}
+	plan.ID = types.StringValue(plan.Organization.ValueString() + "/" + plan.Name.ValueString())
+	plan.Token = types.StringValue(token.Token)
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *apiTokenResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state apiTokenModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	token, err := r.client.GetAPIToken(ctx, state.Organization.ValueString(), state.Name.ValueString())
+	if errors.Is(err, ErrNotFound) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read API token", err.Error())
+		return
+	}
+	state.Scopes = make([]types.String, 0, len(token.Scopes))
+	for _, scope := range token.Scopes {
+		state.Scopes = append(state.Scopes, types.StringValue(scope))
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+// Update only stores the plan: every attribute that can be configured
+// replaces the token
+func (r *apiTokenResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan apiTokenModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *apiTokenResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state apiTokenModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.client.RevokeAPIToken(ctx, state.Organization.ValueString(), state.Name.ValueString())
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to revoke API token", err.Error())
+	}
+}
+
+// ImportState imports a token by organization/name. The token itself cannot
+// be read back, so it stays empty.
+func (r *apiTokenResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	organization, name, ok := strings.Cut(req.ID, "/")
+	if !ok || organization == "" || name == "" {
+		resp.Diagnostics.AddError("Invalid API token ID", fmt.Sprintf("Expected organization/name, got %q", req.ID))
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("organization"), organization)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), name)...)
+}
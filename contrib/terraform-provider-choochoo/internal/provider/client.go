@@ -0,0 +1,205 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when a route, setting, rule or token does not exist
+var ErrNotFound = errors.New("not found")
+
+// Client calls the choochoo management API
+type Client struct {
+	endpoint   string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the instance at endpoint
+func NewClient(endpoint, token string) *Client {
+	return &Client{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Route is a notification route managed by the API
+type Route struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Match string `json:"match"`
+	URL   string `json:"url"`
+}
+
+// Setting is a policy or flag managed by the API
+type Setting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Rule is an event rule managed by the API. Actions are kept as raw JSON,
+// since the provider does not need to know their fields.
+type Rule struct {
+	Name      string          `json:"name"`
+	Condition string          `json:"condition"`
+	Actions   json.RawMessage `json:"actions"`
+	Source    string          `json:"source,omitempty"`
+}
+
+// APIToken is an API token of an organization. Token is only set when it is
+// created.
+type APIToken struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	Token     string     `json:"token,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// GetRoute reads the route for match in the list of kind
+func (c *Client) GetRoute(ctx context.Context, kind, match string) (*Route, error) {
+	var route Route
+	err := c.do(ctx, http.MethodGet, routePath(kind, match), nil, &route)
+	return &route, err
+}
+
+// PutRoute creates or replaces the route for match in the list of kind
+func (c *Client) PutRoute(ctx context.Context, kind, match, routeURL string) (*Route, error) {
+	var route Route
+	err := c.do(ctx, http.MethodPut, routePath(kind, match), map[string]string{"url": routeURL}, &route)
+	return &route, err
+}
+
+// DeleteRoute removes the route for match from the list of kind
+func (c *Client) DeleteRoute(ctx context.Context, kind, match string) error {
+	return c.do(ctx, http.MethodDelete, routePath(kind, match), nil, nil)
+}
+
+// GetSetting reads a setting
+func (c *Client) GetSetting(ctx context.Context, name string) (*Setting, error) {
+	var setting Setting
+	err := c.do(ctx, http.MethodGet, settingPath(name), nil, &setting)
+	return &setting, err
+}
+
+// PutSetting creates or replaces a setting
+func (c *Client) PutSetting(ctx context.Context, name, value string) (*Setting, error) {
+	var setting Setting
+	err := c.do(ctx, http.MethodPut, settingPath(name), map[string]string{"value": value}, &setting)
+	return &setting, err
+}
+
+// DeleteSetting removes a setting
+func (c *Client) DeleteSetting(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, settingPath(name), nil, nil)
+}
+
+// GetRule reads a rule
+func (c *Client) GetRule(ctx context.Context, name string) (*Rule, error) {
+	var rule Rule
+	err := c.do(ctx, http.MethodGet, rulePath(name), nil, &rule)
+	return &rule, err
+}
+
+// PutRule creates or replaces a stored rule
+func (c *Client) PutRule(ctx context.Context, name, condition string, actions json.RawMessage) (*Rule, error) {
+	var rule Rule
+	err := c.do(ctx, http.MethodPut, rulePath(name), Rule{Name: name, Condition: condition, Actions: actions}, &rule)
+	return &rule, err
+}
+
+// DeleteRule removes a stored rule
+func (c *Client) DeleteRule(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, rulePath(name), nil, nil)
+}
+
+// GetAPIToken reads the active token called name of organization
+func (c *Client) GetAPIToken(ctx context.Context, organization, name string) (*APIToken, error) {
+	var tokens []APIToken
+	if err := c.do(ctx, http.MethodGet, tokensPath(organization), nil, &tokens); err != nil {
+		return nil, err
+	}
+	// The list includes revoked tokens, which may share the name
+	for _, token := range tokens {
+		if token.Name == name && token.RevokedAt == nil {
+			return &token, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// CreateAPIToken creates a token of organization, returning it with the
+// secret token
+func (c *Client) CreateAPIToken(ctx context.Context, organization, name string, scopes []string) (*APIToken, error) {
+	var token APIToken
+	err := c.do(ctx, http.MethodPost, tokensPath(organization), APIToken{Name: name, Scopes: scopes}, &token)
+	return &token, err
+}
+
+// RevokeAPIToken revokes the active token called name of organization
+func (c *Client) RevokeAPIToken(ctx context.Context, organization, name string) error {
+	return c.do(ctx, http.MethodDelete, tokensPath(organization)+"/"+url.PathEscape(name), nil, nil)
+}
+
+func routePath(kind, match string) string {
+	return "/api/v1/routes/" + url.PathEscape(kind) + "/" + url.PathEscape(match)
+}
+
+func settingPath(name string) string {
+	return "/api/v1/settings/" + url.PathEscape(name)
+}
+
+func rulePath(name string) string {
+	return "/api/v1/rules/" + url.PathEscape(name)
+}
+
+func tokensPath(organization string) string {
+	return "/api/v1/tenants/" + url.PathEscape(organization) + "/tokens"
+}
+
+// do sends a request with an optional JSON body and decodes the JSON
+// response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode >= 300:
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(message)))
+	case out == nil:
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
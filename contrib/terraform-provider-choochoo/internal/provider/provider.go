@@ -0,0 +1,92 @@
+// Package provider implements the choochoo Terraform provider on top of the
+// management API served under /api/v1.
+package provider
+
+import (
+	"context"
+	"os"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// choochooProvider configures the API client shared by every resource
+type choochooProvider struct {
+	version string
+}
+
+// providerModel is the provider block of a configuration
+type providerModel struct {
+	Endpoint types.String `tfsdk:"endpoint"`
+	Token    types.String `tfsdk:"token"`
+}
+
+// New returns a constructor for the provider
+func New(version string) func() provider.Provider {
+	return func() provider.Provider {
+		return &choochooProvider{version: version}
+	}
+}
+
+func (p *choochooProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "choochoo"
+	resp.Version = p.version
+}
+
+func (p *choochooProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages the routes, settings, rules and API tokens of a choochoo instance.",
+		Attributes: map[string]schema.Attribute{
+			"endpoint": schema.StringAttribute{
+				Description: "Base URL of the instance. Defaults to CHOOCHOO_ENDPOINT.",
+				Optional:    true,
+			},
+			"token": schema.StringAttribute{
+				Description: "Management API token (MANAGEMENT_API_TOKEN on the server). Defaults to CHOOCHOO_TOKEN.",
+				Optional:    true,
+				Sensitive:   true,
+			},
+		},
+	}
+}
+
+func (p *choochooProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var config providerModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	endpoint := os.Getenv("CHOOCHOO_ENDPOINT")
+	if !config.Endpoint.IsNull() {
+		endpoint = config.Endpoint.ValueString()
+	}
+	token := os.Getenv("CHOOCHOO_TOKEN")
+	if !config.Token.IsNull() {
+		token = config.Token.ValueString()
+	}
+	if endpoint == "" || token == "" {
+		resp.Diagnostics.AddError("Missing choochoo configuration", "Set endpoint and token in the provider block or CHOOCHOO_ENDPOINT and CHOOCHOO_TOKEN.")
+		return
+	}
+
+	client := NewClient(endpoint, token)
+	resp.ResourceData = client
+	resp.DataSourceData = client
+}
+
+func (p *choochooProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		NewRouteResource,
+		NewSettingResource,
+		NewRuleResource,
+		NewAPITokenResource,
+	}
+}
+
+func (p *choochooProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return nil
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ resource.ResourceWithConfigure   = &routeResource{}
+	_ resource.ResourceWithImportState = &routeResource{}
+)
+
+// routeResource manages one notification route, identified by kind/match
+type routeResource struct {
+	client *Client
+}
+
+// routeModel is the state of a choochoo_route
+type routeModel struct {
+	ID    types.String `tfsdk:"id"`
+	Kind  types.String `tfsdk:"kind"`
+	Match types.String `tfsdk:"match"`
+	URL   types.String `tfsdk:"url"`
+}
+
+// NewRouteResource creates the choochoo_route resource
+func NewRouteResource() resource.Resource {
+	return &routeResource{}
+}
+
+func (r *routeResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_route"
+}
+
+func (r *routeResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A notification route. Imported by its ID, kind/match.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"kind": schema.StringAttribute{
+				Description:   "Route list: security_alerts, discussions, project_columns, docs or community_digests.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"match": schema.StringAttribute{
+				Description:   "Severity, category, column, docs kind or repository pattern the route matches.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"url": schema.StringAttribute{
+				Description: "Channel URL that matching notifications are POSTed to.",
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (r *routeResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*Client)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *Client, got %T", req.ProviderData))
+		return
+	}
+	r.client = client
+}
+
+func (r *routeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan routeModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := r.put(ctx, &plan); err != nil {
+		resp.Diagnostics.AddError("Failed to write route", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *routeResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state routeModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	route, err := r.client.GetRoute(ctx, state.Kind.ValueString(), state.Match.ValueString())
+	if errors.Is(err, ErrNotFound) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read route", err.Error())
+		return
+	}
+	state.ID = types.StringValue(route.ID)
+	state.URL = types.StringValue(route.URL)
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *routeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan routeModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := r.put(ctx, &plan); err != nil {
+		resp.Diagnostics.AddError("Failed to write route", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *routeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state routeModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.client.DeleteRoute(ctx, state.Kind.ValueString(), state.Match.ValueString())
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to delete route", err.Error())
+	}
+}
+
+// ImportState imports a route by its ID, kind/match. The match may itself
+// contain slashes, as repository patterns do.
+func (r *routeResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	kind, match, ok := strings.Cut(req.ID, "/")
+	if !ok || kind == "" || match == "" {
+		resp.Diagnostics.AddError("Invalid route ID", fmt.Sprintf("Expected kind/match, got %q", req.ID))
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("kind"), kind)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("match"), match)...)
+}
+
+// put creates or replaces the planned route and records its ID. PUT is
+// idempotent, so Create and Update share it.
+func (r *routeResource) put(ctx context.Context, plan *routeModel) error {
+	route, err := r.client.PutRoute(ctx, plan.Kind.ValueString(), plan.Match.ValueString(), plan.URL.ValueString())
+	if err != nil {
+		return err
+	}
+	plan.ID = types.StringValue(route.ID)
+	return nil
+}
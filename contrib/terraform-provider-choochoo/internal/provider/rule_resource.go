@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ resource.ResourceWithConfigure   = &ruleResource{}
+	_ resource.ResourceWithImportState = &ruleResource{}
+)
+
+// ruleResource manages one stored rule, identified by its name
+type ruleResource struct {
+	client *Client
+}
+
+// ruleModel is the state of a choochoo_rule
+type ruleModel struct {
+	ID        types.String `tfsdk:"id"`
+	Name      types.String `tfsdk:"name"`
+	Condition types.String `tfsdk:"condition"`
+	Actions   types.String `tfsdk:"actions"`
+}
+
+// NewRuleResource creates the choochoo_rule resource
+func NewRuleResource() resource.Resource {
+	return &ruleResource{}
+}
+
+func (r *ruleResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_rule"
+}
+
+func (r *ruleResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A rule running its actions on events matching its condition. Rules of the rules file cannot be managed. Imported by its name.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Computed:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.UseStateForUnknown()},
+			},
+			"name": schema.StringAttribute{
+				Description:   "Rule name.",
+				Required:      true,
+				PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()},
+			},
+			"condition": schema.StringAttribute{
+				Description: "CEL expression the event must match.",
+				Required:    true,
+			},
+			"actions": schema.StringAttribute{
+				Description: "JSON array of the actions, as in the rules file. Use jsonencode.",
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (r *ruleResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+	client, ok := req.ProviderData.(*Client)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", fmt.Sprintf("Expected *Client, got %T", req.ProviderData))
+		return
+	}
+	r.client = client
+}
+
+func (r *ruleResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan ruleModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := r.put(ctx, &plan); err != nil {
+		resp.Diagnostics.AddError("Failed to write rule", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *ruleResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state ruleModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rule, err := r.client.GetRule(ctx, state.Name.ValueString())
+	if errors.Is(err, ErrNotFound) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read rule", err.Error())
+		return
+	}
+	state.ID = types.StringValue(rule.Name)
+	state.Condition = types.StringValue(rule.Condition)
+	// The server encodes the actions with its own key order and spacing, so
+	// keep the configured JSON unless they differ in value
+	if !sameJSON(state.Actions.ValueString(), rule.Actions) {
+		state.Actions = types.StringValue(string(rule.Actions))
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, state)...)
+}
+
+func (r *ruleResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan ruleModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if err := r.put(ctx, &plan); err != nil {
+		resp.Diagnostics.AddError("Failed to write rule", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, plan)...)
+}
+
+func (r *ruleResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state ruleModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := r.client.DeleteRule(ctx, state.Name.ValueString())
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		resp.Diagnostics.AddError("Failed to delete rule", err.Error())
+	}
+}
+
+// ImportState imports a rule by its name
+func (r *ruleResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), req.ID)...)
+}
+
+// put creates or replaces the planned rule. PUT is idempotent, so Create
+// and Update share it.
+func (r *ruleResource) put(ctx context.Context, plan *ruleModel) error {
+	actions := json.RawMessage(plan.Actions.ValueString())
+	if !json.Valid(actions) {
+		return errors.New("actions is not valid JSON")
+	}
+	if _, err := r.client.PutRule(ctx, plan.Name.ValueString(), plan.Condition.ValueString(), actions); err != nil {
+		return err
+	}
+	plan.ID = plan.Name
+	return nil
+}
+
+// sameJSON reports whether configured and stored encode the same value
+func sameJSON(configured string, stored json.RawMessage) bool {
+	var a, b interface{}
+	if json.Unmarshal([]byte(configured), &a) != nil || json.Unmarshal(stored, &b) != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
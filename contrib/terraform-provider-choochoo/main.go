@@ -0,0 +1,28 @@
+// Command terraform-provider-choochoo is a reference Terraform and OpenTofu
+// provider for the choochoo management API.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/deedubs/choochoo/contrib/terraform-provider-choochoo/internal/provider"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+)
+
+// version is set by the release build
+var version = "dev"
+
+func main() {
+	debug := flag.Bool("debug", false, "run the provider with support for debuggers")
+	flag.Parse()
+
+	err := providerserver.Serve(context.Background(), provider.New(version), providerserver.ServeOpts{
+		Address: "registry.terraform.io/deedubs/choochoo",
+		Debug:   *debug,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
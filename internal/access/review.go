@@ -0,0 +1,208 @@
+package access
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// Review is an access review: the access currently held according to the
+// changes choochoo has observed, and the changes made during the window
+type Review struct {
+	GeneratedAt  time.Time `json:"generated_at"`
+	Organization string    `json:"organization,omitempty"`
+	WindowDays   int       `json:"window_days"`
+	Grants       []Grant   `json:"grants"`
+	Changes      []Change  `json:"changes"`
+	AdminGrants  int       `json:"admin_grants"`
+}
+
+// Grant is access currently held by a principal
+type Grant struct {
+	Principal     string    `json:"principal"`
+	PrincipalType string    `json:"principal_type"`
+	Resource      string    `json:"resource"`
+	Permission    string    `json:"permission"`
+	GrantedBy     string    `json:"granted_by,omitempty"`
+	GrantedAt     time.Time `json:"granted_at"`
+}
+
+// Change is a single access change within the review window
+type Change struct {
+	Principal          string    `json:"principal"`
+	PrincipalType      string    `json:"principal_type"`
+	Resource           string    `json:"resource"`
+	Change             string    `json:"change"`
+	Permission         string    `json:"permission,omitempty"`
+	PreviousPermission string    `json:"previous_permission,omitempty"`
+	AdminGrant         bool      `json:"admin_grant"`
+	Sender             string    `json:"sender,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// Resource describes what a stored access change applies to
+func Resource(row db.AccessChange) string {
+	change := webhook.AccessChange{
+		Organization: row.Organization,
+		Repository:   row.RepositoryName.String,
+		Team:         row.TeamSlug.String,
+	}
+	return change.Resource()
+}
+
+// BuildReview computes an access review from stored changes, which must be
+// ordered oldest first
+func BuildReview(rows []db.AccessChange, organization string, windowDays int, now time.Time) Review {
+	review := Review{
+		GeneratedAt:  now,
+		Organization: organization,
+		WindowDays:   windowDays,
+		Grants:       []Grant{},
+		Changes:      []Change{},
+	}
+	since := now.AddDate(0, 0, -windowDays)
+
+	current := make(map[string]Grant)
+	for _, row := range rows {
+		resource := Resource(row)
+		key := row.PrincipalType + "/" + row.Principal + "@" + resource
+
+		switch row.Change {
+		case webhook.AccessRevoked:
+			delete(current, key)
+		case webhook.AccessModified:
+			grant, ok := current[key]
+			if !ok {
+				grant = Grant{GrantedBy: row.SenderLogin.String, GrantedAt: row.CreatedAt.Time}
+			}
+			grant.Principal, grant.PrincipalType, grant.Resource = row.Principal, row.PrincipalType, resource
+			grant.Permission = row.Permission.String
+			current[key] = grant
+		default:
+			current[key] = Grant{
+				Principal:     row.Principal,
+				PrincipalType: row.PrincipalType,
+				Resource:      resource,
+				Permission:    row.Permission.String,
+				GrantedBy:     row.SenderLogin.String,
+				GrantedAt:     row.CreatedAt.Time,
+			}
+		}
+
+		if row.CreatedAt.Time.Before(since) {
+			continue
+		}
+		review.Changes = append(review.Changes, Change{
+			Principal:          row.Principal,
+			PrincipalType:      row.PrincipalType,
+			Resource:           resource,
+			Change:             row.Change,
+			Permission:         row.Permission.String,
+			PreviousPermission: row.PreviousPermission.String,
+			AdminGrant:         row.AdminGrant,
+			Sender:             row.SenderLogin.String,
+			CreatedAt:          row.CreatedAt.Time,
+		})
+		if row.AdminGrant {
+			review.AdminGrants++
+		}
+	}
+
+	for _, grant := range current {
+		review.Grants = append(review.Grants, grant)
+	}
+	sort.Slice(review.Grants, func(i, j int) bool {
+		a, b := review.Grants[i], review.Grants[j]
+		if a.Resource != b.Resource {
+			return a.Resource < b.Resource
+		}
+		return a.Principal < b.Principal
+	})
+
+	return review
+}
+
+// WriteCSV writes the current grants of a review as CSV for sign-off
+func WriteCSV(w io.Writer, review Review) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"resource", "principal", "principal_type", "permission", "granted_by", "granted_at"})
+	for _, grant := range review.Grants {
+		out.Write([]string{
+			grant.Resource,
+			grant.Principal,
+			grant.PrincipalType,
+			grant.Permission,
+			grant.GrantedBy,
+			grant.GrantedAt.Format(time.RFC3339),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// LoadFunc loads stored access changes, oldest first
+type LoadFunc func(ctx context.Context) ([]db.AccessChange, error)
+
+// RunExportLoop writes an access review to dir every interval until ctx is
+// cancelled. Each export produces a JSON review and a CSV of current grants.
+func RunExportLoop(ctx context.Context, interval time.Duration, dir string, windowDays int, load LoadFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			path, err := Export(ctx, dir, windowDays, load)
+			if err != nil {
+				log.Printf("Access review export failed: %v", err)
+				continue
+			}
+			log.Printf("Wrote access review to %s", path)
+		}
+	}
+}
+
+// Export writes an access review to dir and returns the path of the JSON file
+func Export(ctx context.Context, dir string, windowDays int, load LoadFunc) (string, error) {
+	rows, err := load(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to load access changes: %w", err)
+	}
+	now := time.Now().UTC()
+	review := BuildReview(rows, "", windowDays, now)
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create access review directory: %w", err)
+	}
+	base := filepath.Join(dir, "access-review-"+now.Format("20060102T150405Z"))
+
+	data, err := json.MarshalIndent(review, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".json", data, 0o600); err != nil {
+		return "", err
+	}
+
+	file, err := os.OpenFile(base+".csv", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if err := WriteCSV(file, review); err != nil {
+		return "", err
+	}
+	return base + ".json", nil
+}
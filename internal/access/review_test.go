@@ -0,0 +1,51 @@
+package access
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestBuildReview(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	at := func(days int) pgtype.Timestamptz {
+		return pgtype.Timestamptz{Time: now.AddDate(0, 0, -days), Valid: true}
+	}
+	text := func(s string) pgtype.Text {
+		return pgtype.Text{String: s, Valid: s != ""}
+	}
+	repo := text("octo-org/hello-world")
+
+	rows := []db.AccessChange{
+		{Change: "granted", Organization: "octo-org", RepositoryName: repo, Principal: "alice", PrincipalType: "user", Permission: text("write"), SenderLogin: text("owner"), CreatedAt: at(200)},
+		{Change: "granted", Organization: "octo-org", RepositoryName: repo, Principal: "bob", PrincipalType: "user", Permission: text("read"), CreatedAt: at(150)},
+		{Change: "modified", Organization: "octo-org", RepositoryName: repo, Principal: "alice", PrincipalType: "user", Permission: text("admin"), PreviousPermission: text("write"), AdminGrant: true, CreatedAt: at(10)},
+		{Change: "revoked", Organization: "octo-org", RepositoryName: repo, Principal: "bob", PrincipalType: "user", CreatedAt: at(5)},
+	}
+
+	review := BuildReview(rows, "octo-org", 90, now)
+
+	if len(review.Grants) != 1 {
+		t.Fatalf("Expected 1 current grant, got %+v", review.Grants)
+	}
+	grant := review.Grants[0]
+	if grant.Principal != "alice" || grant.Permission != "admin" || grant.GrantedBy != "owner" || !grant.GrantedAt.Equal(at(200).Time) {
+		t.Errorf("Unexpected grant: %+v", grant)
+	}
+	if len(review.Changes) != 2 || review.AdminGrants != 1 {
+		t.Errorf("Expected 2 changes in window with 1 admin grant, got %d and %d", len(review.Changes), review.AdminGrants)
+	}
+
+	var out bytes.Buffer
+	if err := WriteCSV(&out, review); err != nil {
+		t.Fatalf("WriteCSV failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[1], "repository:octo-org/hello-world,alice,user,admin,owner,") {
+		t.Errorf("Unexpected CSV: %q", out.String())
+	}
+}
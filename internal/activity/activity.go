@@ -0,0 +1,211 @@
+// Package activity turns the hourly delivery counts kept by the
+// delivery_activity projection into the hour-of-week heat maps and daily
+// activity calendars rendered by dashboards.
+package activity
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/privacy"
+)
+
+// Default and largest number of days covered
+const (
+	DefaultHeatmapDays  = 28
+	DefaultCalendarDays = 365
+	MaxDays             = 366
+)
+
+// Levels is how many shades of activity a calendar has, besides no activity
+const Levels = 4
+
+// Hour is the number of deliveries in one local hour of a day
+type Hour struct {
+	// Date is the local day, at midnight UTC
+	Date   time.Time
+	Hour   int
+	Events int64
+}
+
+// Filter selects the deliveries of a repository or of every repository of
+// an owner. The zero Filter selects every delivery.
+type Filter struct {
+	Repository string `json:"repository,omitempty"`
+	Owner      string `json:"owner,omitempty"`
+}
+
+// Period is the span of local days covered, from First through Last
+type Period struct {
+	TimeZone string    `json:"time_zone"`
+	First    string    `json:"first"`
+	Last     string    `json:"last"`
+	Since    time.Time `json:"since"`
+}
+
+// NewPeriod returns the days local days up to and including today in loc
+func NewPeriod(now time.Time, loc *time.Location, days int) Period {
+	local := now.In(loc)
+	last := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	first := last.AddDate(0, 0, -(days - 1))
+	return Period{
+		TimeZone: loc.String(),
+		First:    first.Format(time.DateOnly),
+		Last:     last.Format(time.DateOnly),
+		Since:    first.UTC(),
+	}
+}
+
+// ParseDays parses a number of days between 1 and MaxDays, defaulting to def
+func ParseDays(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 1 || days > MaxDays {
+		return 0, fmt.Errorf("expected 1 to %d days, got %q", MaxDays, value)
+	}
+	return days, nil
+}
+
+// Heatmap is the number of deliveries in each hour of the week. Matrix is
+// indexed by weekday, Sunday first, then by hour of the day.
+type Heatmap struct {
+	Filter
+	Period
+	Weekdays []string     `json:"weekdays"`
+	Matrix   [7][24]int64 `json:"matrix"`
+	Total    int64        `json:"total"`
+	// Max is the busiest hour of the week, for scaling colors
+	Max int64 `json:"max"`
+	// Privacy is the policy the counts were protected with, if any
+	Privacy *privacy.Policy `json:"privacy,omitempty"`
+}
+
+// BuildHeatmap sums hours by weekday and hour of the day
+func BuildHeatmap(filter Filter, period Period, hours []Hour) Heatmap {
+	heatmap := Heatmap{Filter: filter, Period: period, Weekdays: make([]string, 7)}
+	for day := range heatmap.Weekdays {
+		heatmap.Weekdays[day] = time.Weekday(day).String()
+	}
+	for _, hour := range hours {
+		if hour.Hour < 0 || hour.Hour > 23 {
+			continue
+		}
+		heatmap.Matrix[hour.Date.Weekday()][hour.Hour] += hour.Events
+		heatmap.Total += hour.Events
+	}
+	for _, day := range heatmap.Matrix {
+		for _, events := range day {
+			heatmap.Max = max(heatmap.Max, events)
+		}
+	}
+	return heatmap
+}
+
+// Protect replaces the counts of the heat map with those protected by
+// policy. Totals are sums of the protected counts.
+func (h Heatmap) Protect(policy *privacy.Policy) Heatmap {
+	h.Total, h.Max, h.Privacy = 0, 0, policy
+	for day, hours := range h.Matrix {
+		for hour, events := range hours {
+			events = policy.Count(fmt.Sprintf("heatmap %s %s %d %d", h.Filter.scope(), h.TimeZone, day, hour), events)
+			h.Matrix[day][hour] = events
+			h.Total += events
+			h.Max = max(h.Max, events)
+		}
+	}
+	return h
+}
+
+// Cell is an hour of a heat map, shaded like the days of a calendar
+type Cell struct {
+	Events int64
+	Level  int
+}
+
+// Cells shades each hour of the week relative to the busiest hour, for
+// rendering the heat map
+func (h Heatmap) Cells() [7][24]Cell {
+	var cells [7][24]Cell
+	for day, hours := range h.Matrix {
+		for hour, events := range hours {
+			cells[day][hour] = Cell{Events: events, Level: level(events, h.Max)}
+		}
+	}
+	return cells
+}
+
+// Day is the number of deliveries on one day of a calendar. Level shades it
+// from 0, no deliveries, to Levels, the busiest days.
+type Day struct {
+	Date   string `json:"date"`
+	Events int64  `json:"events"`
+	Level  int    `json:"level"`
+}
+
+// Calendar is the number of deliveries on each day of a period
+type Calendar struct {
+	Filter
+	Period
+	Days    []Day           `json:"days"`
+	Total   int64           `json:"total"`
+	Max     int64           `json:"max"`
+	Privacy *privacy.Policy `json:"privacy,omitempty"`
+}
+
+// BuildCalendar sums hours by day, with every day of period present
+func BuildCalendar(filter Filter, period Period, hours []Hour) Calendar {
+	events := map[string]int64{}
+	for _, hour := range hours {
+		events[hour.Date.Format(time.DateOnly)] += hour.Events
+	}
+
+	calendar := Calendar{Filter: filter, Period: period, Days: []Day{}}
+	first, err := time.Parse(time.DateOnly, period.First)
+	if err != nil {
+		return calendar
+	}
+	for date := first; date.Format(time.DateOnly) <= period.Last; date = date.AddDate(0, 0, 1) {
+		day := Day{Date: date.Format(time.DateOnly), Events: events[date.Format(time.DateOnly)]}
+		calendar.Days = append(calendar.Days, day)
+		calendar.Total += day.Events
+		calendar.Max = max(calendar.Max, day.Events)
+	}
+	for i := range calendar.Days {
+		calendar.Days[i].Level = level(calendar.Days[i].Events, calendar.Max)
+	}
+	return calendar
+}
+
+// Protect replaces the counts of the calendar with those protected by
+// policy, shading the days again
+func (c Calendar) Protect(policy *privacy.Policy) Calendar {
+	days := make([]Day, len(c.Days))
+	c.Total, c.Max, c.Privacy = 0, 0, policy
+	for i, day := range c.Days {
+		day.Events = policy.Count(fmt.Sprintf("calendar %s %s %s", c.Filter.scope(), c.TimeZone, day.Date), day.Events)
+		days[i] = day
+		c.Total += day.Events
+		c.Max = max(c.Max, day.Events)
+	}
+	for i := range days {
+		days[i].Level = level(days[i].Events, c.Max)
+	}
+	c.Days = days
+	return c
+}
+
+// scope names the deliveries a filter selects, naming the protected counts
+func (f Filter) scope() string {
+	return fmt.Sprintf("repository=%s owner=%s", f.Repository, f.Owner)
+}
+
+// level shades events relative to the busiest day
+func level(events, busiest int64) int {
+	if events <= 0 || busiest <= 0 {
+		return 0
+	}
+	return int((events*Levels + busiest - 1) / busiest)
+}
@@ -0,0 +1,115 @@
+package activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/privacy"
+)
+
+func date(value string) time.Time {
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func TestNewPeriod(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("No time zone database: %v", err)
+	}
+	// Still the 1st in New York
+	period := NewPeriod(time.Date(2024, 5, 2, 2, 0, 0, 0, time.UTC), loc, 7)
+	if period.First != "2024-04-25" || period.Last != "2024-05-01" || period.TimeZone != "America/New_York" {
+		t.Errorf("Unexpected period: %+v", period)
+	}
+	if want := time.Date(2024, 4, 25, 4, 0, 0, 0, time.UTC); !period.Since.Equal(want) {
+		t.Errorf("Expected the period to start at %v, got %v", want, period.Since)
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	if days, err := ParseDays("", 28); err != nil || days != 28 {
+		t.Errorf("Expected the default, got %d, %v", days, err)
+	}
+	if days, err := ParseDays("90", 28); err != nil || days != 90 {
+		t.Errorf("Expected 90 days, got %d, %v", days, err)
+	}
+	for _, value := range []string{"0", "-1", "367", "ten", "7d", "1.5"} {
+		if _, err := ParseDays(value, 28); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
+
+func TestBuildHeatmap(t *testing.T) {
+	period := NewPeriod(time.Date(2024, 5, 7, 12, 0, 0, 0, time.UTC), time.UTC, 14)
+	heatmap := BuildHeatmap(Filter{Owner: "octo-org"}, period, []Hour{
+		{Date: date("2024-05-06"), Hour: 9, Events: 5},  // Monday
+		{Date: date("2024-04-29"), Hour: 9, Events: 3},  // Monday
+		{Date: date("2024-05-05"), Hour: 23, Events: 1}, // Sunday
+	})
+
+	if heatmap.Matrix[time.Monday][9] != 8 || heatmap.Matrix[time.Sunday][23] != 1 {
+		t.Errorf("Unexpected matrix: %v", heatmap.Matrix)
+	}
+	if heatmap.Total != 9 || heatmap.Max != 8 || heatmap.Weekdays[0] != "Sunday" || heatmap.Owner != "octo-org" {
+		t.Errorf("Unexpected heat map: %+v", heatmap)
+	}
+
+	cells := heatmap.Cells()
+	if cells[time.Monday][9].Level != Levels || cells[time.Sunday][23].Level != 1 || cells[time.Monday][10].Level != 0 {
+		t.Errorf("Unexpected shading: %v", cells)
+	}
+}
+
+func TestBuildCalendar(t *testing.T) {
+	period := NewPeriod(time.Date(2024, 5, 7, 12, 0, 0, 0, time.UTC), time.UTC, 7)
+	calendar := BuildCalendar(Filter{}, period, []Hour{
+		{Date: date("2024-05-01"), Hour: 9, Events: 6},
+		{Date: date("2024-05-01"), Hour: 10, Events: 2},
+		{Date: date("2024-05-06"), Hour: 14, Events: 1},
+	})
+
+	if len(calendar.Days) != 7 || calendar.Days[0].Date != "2024-05-01" || calendar.Days[6].Date != "2024-05-07" {
+		t.Fatalf("Expected every day of the period, got %+v", calendar.Days)
+	}
+	if calendar.Days[0].Events != 8 || calendar.Days[0].Level != Levels {
+		t.Errorf("Expected the busiest day at the top level, got %+v", calendar.Days[0])
+	}
+	if calendar.Days[5].Events != 1 || calendar.Days[5].Level != 1 {
+		t.Errorf("Expected a quiet day at the lowest level, got %+v", calendar.Days[5])
+	}
+	if calendar.Days[1].Level != 0 || calendar.Total != 9 || calendar.Max != 8 {
+		t.Errorf("Unexpected calendar: %+v", calendar)
+	}
+}
+
+func TestProtect(t *testing.T) {
+	policy, err := privacy.NewPolicy(0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	period := NewPeriod(time.Date(2024, 5, 7, 12, 0, 0, 0, time.UTC), time.UTC, 7)
+	hours := []Hour{
+		{Date: date("2024-05-01"), Hour: 9, Events: 6},
+		{Date: date("2024-05-01"), Hour: 10, Events: 2},
+		{Date: date("2024-05-06"), Hour: 14, Events: 1},
+	}
+
+	heatmap := BuildHeatmap(Filter{}, period, hours)
+	protected := heatmap.Protect(policy)
+	if protected.Matrix[3][9] != 6 || protected.Matrix[3][10] != 0 || protected.Total != 6 || protected.Privacy != policy {
+		t.Errorf("Expected hours below the minimum to be suppressed, got %+v", protected)
+	}
+	if heatmap.Matrix[3][10] != 2 {
+		t.Error("Expected the original heat map to be unchanged")
+	}
+
+	calendar := BuildCalendar(Filter{}, period, hours).Protect(policy)
+	if calendar.Days[0].Events != 8 || calendar.Days[5].Events != 0 || calendar.Days[5].Level != 0 || calendar.Total != 8 {
+		t.Errorf("Expected days below the minimum to be suppressed, got %+v", calendar)
+	}
+}
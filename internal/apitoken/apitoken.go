@@ -0,0 +1,462 @@
+// Package apitoken authenticates requests to the query, replay and admin APIs
+// with bearer tokens. Tokens are stored as SHA-256 hashes in the api_tokens
+// table, each with the scopes it is allowed to use.
+package apitoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Scope is a set of endpoints a token may call
+type Scope string
+
+// Token scopes. ScopeAdmin allows everything the other scopes do, and
+// ScopeRead everything ScopeStats does.
+const (
+	// ScopeRead allows the query APIs and the live event streams
+	ScopeRead Scope = "read"
+	// ScopeStats allows only the aggregate activity stats, protected by the
+	// STATS_PRIVACY_* settings, for sharing them beyond the owning team
+	ScopeStats Scope = "stats"
+	// ScopeReplay allows replaying stored deliveries and releasing
+	// quarantined ones
+	ScopeReplay Scope = "replay"
+	// ScopeAdmin allows the management API and the admin dashboard
+	ScopeAdmin Scope = "admin"
+)
+
+// Scopes lists every scope
+var Scopes = []Scope{ScopeRead, ScopeStats, ScopeReplay, ScopeAdmin}
+
+// prefix marks choochoo tokens so secret scanners and people can tell them
+// apart
+const prefix = "cct_"
+
+var (
+	// ErrNotConfigured is returned when there is neither a static token nor a
+	// token store to check requests against
+	ErrNotConfigured = errors.New("API authentication not configured")
+	// ErrUnauthorized is returned for a missing, unknown or revoked token
+	ErrUnauthorized = errors.New("invalid token")
+	// ErrForbidden is returned for a token without the required scope
+	ErrForbidden = errors.New("token lacks the required scope")
+	// ErrOtherTenant is returned for a tenant token used outside of its
+	// organization, including on instance endpoints
+	ErrOtherTenant = errors.New("token is limited to another organization")
+	// ErrNotFound is returned when revoking a token that is not active
+	ErrNotFound = errors.New("token not found")
+	// ErrDenied is returned when the authorization policy denies a request,
+	// or cannot be asked
+	ErrDenied = errors.New("denied by policy")
+)
+
+// ParseScopes parses a comma-separated list of scopes
+func ParseScopes(list string) ([]Scope, error) {
+	var scopes []Scope
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		scope := Scope(name)
+		if !scope.valid() {
+			return nil, fmt.Errorf("unknown scope %q, want one of read, stats, replay or admin", name)
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+func (s Scope) valid() bool {
+	for _, scope := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Token is an active API token. Tokens of a tenant have the Organization
+// they are limited to; instance tokens have none and may be used everywhere.
+type Token struct {
+	ID           int32   `json:"-"`
+	Name         string  `json:"name"`
+	Scopes       []Scope `json:"scopes"`
+	Organization string  `json:"organization,omitempty"`
+}
+
+// Allows reports whether the token may use scope
+func (t Token) Allows(scope Scope) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope || granted == ScopeAdmin || (granted == ScopeRead && scope == ScopeStats) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrganization reports whether the token may administer organization
+func (t Token) AllowsOrganization(organization string) bool {
+	return t.Organization == "" || strings.EqualFold(t.Organization, organization)
+}
+
+// Generate creates a new random token
+func Generate() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// Hash returns the hash a token is stored under. Tokens are random, so a
+// plain SHA-256 is enough to keep them from being recovered from the table.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Store looks up stored tokens
+type Store interface {
+	// Lookup returns the active token stored under hash, or ErrUnauthorized
+	Lookup(ctx context.Context, hash string) (Token, error)
+}
+
+type postgresStore struct {
+	queries *db.Queries
+}
+
+// NewPostgresStore creates a store on the api_tokens table
+func NewPostgresStore(queries *db.Queries) Store {
+	return &postgresStore{queries: queries}
+}
+
+// Lookup returns the active token stored under hash and records its use
+func (s *postgresStore) Lookup(ctx context.Context, hash string) (Token, error) {
+	row, err := s.queries.GetActiveAPITokenByHash(ctx, hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Token{}, ErrUnauthorized
+	}
+	if err != nil {
+		return Token{}, err
+	}
+	if err := s.queries.TouchAPIToken(ctx, row.ID); err != nil {
+		log.Printf("Failed to record use of API token %s: %v", row.Name, err)
+	}
+	return newToken(row), nil
+}
+
+func newToken(row db.ApiToken) Token {
+	token := Token{ID: row.ID, Name: row.Name, Organization: row.Organization.String}
+	for _, scope := range row.Scopes {
+		token.Scopes = append(token.Scopes, Scope(scope))
+	}
+	return token
+}
+
+// Create stores a new token with scopes under name, returning the token.
+// Only its hash is stored, so it cannot be shown again.
+func Create(ctx context.Context, queries *db.Queries, name string, scopes []Scope) (string, error) {
+	return create(ctx, queries, name, "", scopes)
+}
+
+// CreateTenant stores a new token limited to organization
+func CreateTenant(ctx context.Context, queries *db.Queries, organization, name string, scopes []Scope) (string, error) {
+	return create(ctx, queries, name, strings.ToLower(organization), scopes)
+}
+
+func create(ctx context.Context, queries *db.Queries, name, organization string, scopes []Scope) (string, error) {
+	token, err := Generate()
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		names = append(names, string(scope))
+	}
+	_, err = queries.CreateAPIToken(ctx, db.CreateAPITokenParams{
+		Name:         name,
+		TokenHash:    Hash(token),
+		Scopes:       names,
+		Organization: pgtype.Text{String: organization, Valid: organization != ""},
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Revoke revokes the active token called name
+func Revoke(ctx context.Context, queries *db.Queries, name string) error {
+	revoked, err := queries.RevokeAPIToken(ctx, name)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RevokeTenant revokes the active token called name if it is limited to
+// organization
+func RevokeTenant(ctx context.Context, queries *db.Queries, organization, name string) error {
+	revoked, err := queries.RevokeTenantAPIToken(ctx, db.RevokeTenantAPITokenParams{
+		Name:         name,
+		Organization: pgtype.Text{String: strings.ToLower(organization), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Request is what an authorization policy decides on: who made a request,
+// the scope it needs and, for tenant endpoints, the organization it is for
+type Request struct {
+	Token        Token  `json:"token"`
+	Scope        Scope  `json:"scope"`
+	Organization string `json:"organization,omitempty"`
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	RemoteAddr   string `json:"remote_addr"`
+}
+
+// Policy decides which requests are allowed in place of the token scopes
+type Policy interface {
+	Allow(ctx context.Context, request Request) (bool, error)
+}
+
+// Users signs in people with a user name and password, such as the users
+// provisioned over SCIM, returning a token with the scopes of their role
+type Users interface {
+	// SignIn returns ErrUnauthorized for unknown, deactivated or wrong
+	// credentials
+	SignIn(ctx context.Context, username, password string) (Token, error)
+}
+
+// Identities verifies the ID tokens machine clients authenticate with instead
+// of API tokens, returning a token with the scopes granted to their identity
+type Identities interface {
+	// Verify returns ErrUnauthorized for tokens that are invalid, expired or
+	// not trusted
+	Verify(ctx context.Context, idToken string) (Token, error)
+}
+
+// Authenticator checks the bearer token of requests. The static token, the
+// existing MANAGEMENT_API_TOKEN, is allowed every scope so deployments that
+// predate stored tokens keep working.
+type Authenticator struct {
+	static     string
+	store      Store
+	policy     Policy
+	users      Users
+	identities Identities
+}
+
+// NewAuthenticator creates an authenticator accepting the static token and
+// the tokens in store. Either may be empty.
+func NewAuthenticator(static string, store Store) *Authenticator {
+	return &Authenticator{static: static, store: store}
+}
+
+// WithPolicy makes the policy decide which scopes authenticated requests may
+// use, instead of the scopes of their tokens. Tenant tokens stay limited to
+// their organization.
+func (a *Authenticator) WithPolicy(policy Policy) *Authenticator {
+	a.policy = policy
+	return a
+}
+
+// WithUsers accepts the basic auth credentials of users, besides tokens
+func (a *Authenticator) WithUsers(users Users) *Authenticator {
+	a.users = users
+	return a
+}
+
+// WithIdentities accepts the ID tokens identities verifies as bearer tokens,
+// besides API tokens
+func (a *Authenticator) WithIdentities(identities Identities) *Authenticator {
+	a.identities = identities
+	return a
+}
+
+// Enabled reports whether any token or user can be accepted
+func (a *Authenticator) Enabled() bool {
+	return a != nil && (a.static != "" || a.store != nil || a.users != nil || a.identities != nil)
+}
+
+// SignsInUsers reports whether users can sign in with basic auth
+func (a *Authenticator) SignsInUsers() bool {
+	return a != nil && a.users != nil
+}
+
+// Authenticate checks that the request has an instance token allowed to use
+// scope
+func (a *Authenticator) Authenticate(r *http.Request, scope Scope) (Token, error) {
+	token, err := a.lookup(r)
+	if err != nil {
+		return token, err
+	}
+	if token.Organization != "" {
+		return token, ErrOtherTenant
+	}
+	return token, a.Allow(r, token, "", scope)
+}
+
+// AuthenticateTenant checks that the request has a token allowed to use
+// scope for organization: an instance token, or a token of the organization
+func (a *Authenticator) AuthenticateTenant(r *http.Request, organization string, scope Scope) (Token, error) {
+	token, err := a.lookup(r)
+	if err != nil {
+		return token, err
+	}
+	if !token.AllowsOrganization(organization) {
+		return token, ErrOtherTenant
+	}
+	return token, a.Allow(r, token, organization, scope)
+}
+
+// Allow checks that an authenticated token may use scope, for organization
+// on tenant endpoints. Without a policy the token must have the scope; with
+// one, the policy decides and requests are denied if it cannot be asked.
+func (a *Authenticator) Allow(r *http.Request, token Token, organization string, scope Scope) error {
+	if a == nil || a.policy == nil {
+		if !token.Allows(scope) {
+			return ErrForbidden
+		}
+		return nil
+	}
+	allowed, err := a.policy.Allow(r.Context(), Request{
+		Token:        token,
+		Scope:        scope,
+		Organization: organization,
+		Method:       r.Method,
+		Path:         r.URL.Path,
+		RemoteAddr:   r.RemoteAddr,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDenied, err)
+	}
+	if !allowed {
+		return ErrDenied
+	}
+	return nil
+}
+
+// lookup returns the token of the request. Besides the bearer token, a
+// stored token is accepted as the basic auth password, so people can sign in
+// to the tenant pages of the dashboard with a browser, and so are the
+// credentials of users. A bearer token shaped like a JWT is verified as an
+// ID token.
+func (a *Authenticator) lookup(r *http.Request) (Token, error) {
+	if !a.Enabled() {
+		return Token{}, ErrNotConfigured
+	}
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		username, password, basic := r.BasicAuth()
+		if basic && strings.HasPrefix(password, prefix) {
+			provided, ok = password, true
+		} else if basic && a.users != nil && username != "" {
+			return a.users.SignIn(r.Context(), username, password)
+		}
+	}
+	if !ok || provided == "" {
+		return Token{}, ErrUnauthorized
+	}
+	if a.static != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(a.static)) == 1 {
+		return Token{Name: "MANAGEMENT_API_TOKEN", Scopes: []Scope{ScopeAdmin}}, nil
+	}
+	if a.identities != nil && strings.Count(provided, ".") == 2 {
+		return a.identities.Verify(r.Context(), provided)
+	}
+	if a.store == nil || !strings.HasPrefix(provided, prefix) {
+		return Token{}, ErrUnauthorized
+	}
+	return a.store.Lookup(r.Context(), Hash(provided))
+}
+
+// Authorize checks that the request has a token allowed to use scope,
+// writing an error response if it does not
+func (a *Authenticator) Authorize(w http.ResponseWriter, r *http.Request, scope Scope) bool {
+	_, ok := a.AuthorizeToken(w, r, scope)
+	return ok
+}
+
+// AuthorizeToken is Authorize for handlers that need to know which token
+// made the request, such as to record who requested a change
+func (a *Authenticator) AuthorizeToken(w http.ResponseWriter, r *http.Request, scope Scope) (Token, bool) {
+	token, err := a.Authenticate(r, scope)
+	if !check(w, r, token, scope, err) {
+		return Token{}, false
+	}
+	return token, true
+}
+
+// AuthorizeTenant checks that the request has a token allowed to use scope
+// for organization, writing an error response if it does not
+func (a *Authenticator) AuthorizeTenant(w http.ResponseWriter, r *http.Request, organization string, scope Scope) (Token, bool) {
+	token, err := a.AuthenticateTenant(r, organization, scope)
+	if !check(w, r, token, scope, err) {
+		return Token{}, false
+	}
+	return token, true
+}
+
+// check reports whether a request was authenticated, writing the error
+// response if it was not
+func check(w http.ResponseWriter, r *http.Request, token Token, scope Scope, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrNotConfigured):
+		http.Error(w, "API authentication not configured", http.StatusServiceUnavailable)
+	case errors.Is(err, ErrUnauthorized):
+		log.Printf("Invalid API token from %s: %v", r.RemoteAddr, err)
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+	case errors.Is(err, ErrForbidden):
+		log.Printf("API token %s used without the %s scope from %s", token.Name, scope, r.RemoteAddr)
+		http.Error(w, fmt.Sprintf("Token lacks the %s scope", scope), http.StatusForbidden)
+	case errors.Is(err, ErrDenied):
+		log.Printf("Policy denied API token %s the %s scope from %s: %v", token.Name, scope, r.RemoteAddr, err)
+		http.Error(w, "Denied by policy", http.StatusForbidden)
+	case errors.Is(err, ErrOtherTenant):
+		log.Printf("API token %s of %s used outside of its organization from %s", token.Name, token.Organization, r.RemoteAddr)
+		http.Error(w, fmt.Sprintf("Token is limited to the %s organization", token.Organization), http.StatusForbidden)
+	default:
+		log.Printf("Failed to look up API token: %v", err)
+		http.Error(w, "Failed to check token", http.StatusInternalServerError)
+	}
+	return false
+}
+
+// Require wraps next so it is only called for requests with a token allowed
+// to use scope
+func (a *Authenticator) Require(scope Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.Authorize(w, r, scope) {
+			next(w, r)
+		}
+	}
+}
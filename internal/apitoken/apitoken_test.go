@@ -0,0 +1,332 @@
+package apitoken
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type fakeStore map[string]Token
+
+func (s fakeStore) Lookup(ctx context.Context, hash string) (Token, error) {
+	token, ok := s[hash]
+	if !ok {
+		return Token{}, ErrUnauthorized
+	}
+	return token, nil
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes("read, replay")
+	if err != nil || len(scopes) != 2 || scopes[0] != ScopeRead || scopes[1] != ScopeReplay {
+		t.Errorf("ParseScopes = %v, %v", scopes, err)
+	}
+	for _, list := range []string{"", " , ", "read,write"} {
+		if _, err := ParseScopes(list); err == nil {
+			t.Errorf("Expected an error for %q", list)
+		}
+	}
+}
+
+func TestToken_Allows(t *testing.T) {
+	reader := Token{Scopes: []Scope{ScopeRead}}
+	if !reader.Allows(ScopeRead) || !reader.Allows(ScopeStats) || reader.Allows(ScopeReplay) || reader.Allows(ScopeAdmin) {
+		t.Errorf("Unexpected scopes allowed for %+v", reader)
+	}
+	stats := Token{Scopes: []Scope{ScopeStats}}
+	if !stats.Allows(ScopeStats) || stats.Allows(ScopeRead) {
+		t.Errorf("Unexpected scopes allowed for %+v", stats)
+	}
+	admin := Token{Scopes: []Scope{ScopeAdmin}}
+	for _, scope := range Scopes {
+		if !admin.Allows(scope) {
+			t.Errorf("Expected admin to allow %s", scope)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	first, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	second, _ := Generate()
+	if !strings.HasPrefix(first, prefix) || first == second {
+		t.Errorf("Unexpected tokens %q and %q", first, second)
+	}
+	if len(Hash(first)) != 64 || Hash(first) == Hash(second) {
+		t.Errorf("Unexpected hash %q", Hash(first))
+	}
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	reader, _ := Generate()
+	revoked, _ := Generate()
+	store := fakeStore{Hash(reader): {ID: 1, Name: "grafana", Scopes: []Scope{ScopeRead}}}
+	auth := NewAuthenticator("static-secret", store)
+
+	tests := []struct {
+		name  string
+		token string
+		scope Scope
+		want  error
+	}{
+		{"stored token", reader, ScopeRead, nil},
+		{"missing scope", reader, ScopeReplay, ErrForbidden},
+		{"static token", "static-secret", ScopeAdmin, nil},
+		{"unknown token", revoked, ScopeRead, ErrUnauthorized},
+		{"not a token", "wrong", ScopeRead, ErrUnauthorized},
+		{"no token", "", ScopeRead, ErrUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/usage", nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			if _, err := auth.Authenticate(req, test.scope); !errors.Is(err, test.want) {
+				t.Errorf("Authenticate = %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	if _, err := NewAuthenticator("", nil).Authenticate(httptest.NewRequest(http.MethodGet, "/", nil), ScopeRead); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("Expected ErrNotConfigured, got %v", err)
+	}
+}
+
+func TestAuthenticator_Require(t *testing.T) {
+	reader, _ := Generate()
+	auth := NewAuthenticator("", fakeStore{Hash(reader): {Name: "grafana", Scopes: []Scope{ScopeRead}}})
+	next := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) }
+
+	tests := []struct {
+		name     string
+		auth     *Authenticator
+		scope    Scope
+		token    string
+		expected int
+	}{
+		{"allowed", auth, ScopeRead, reader, http.StatusTeapot},
+		{"forbidden", auth, ScopeAdmin, reader, http.StatusForbidden},
+		{"unauthorized", auth, ScopeRead, "", http.StatusUnauthorized},
+		{"not configured", NewAuthenticator("", nil), ScopeRead, reader, http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/usage", nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			rr := httptest.NewRecorder()
+			test.auth.Require(test.scope, next)(rr, req)
+			if rr.Code != test.expected {
+				t.Errorf("Expected status code %d, got %d", test.expected, rr.Code)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_AuthenticateTenant(t *testing.T) {
+	tenant, _ := Generate()
+	instance, _ := Generate()
+	auth := NewAuthenticator("", fakeStore{
+		Hash(tenant):   {Name: "acme-ci", Scopes: []Scope{ScopeAdmin}, Organization: "acme"},
+		Hash(instance): {Name: "oncall", Scopes: []Scope{ScopeRead}},
+	})
+
+	tests := []struct {
+		name         string
+		token        string
+		organization string
+		scope        Scope
+		want         error
+	}{
+		{"own organization", tenant, "acme", ScopeAdmin, nil},
+		{"organization case", tenant, "ACME", ScopeRead, nil},
+		{"other organization", tenant, "globex", ScopeRead, ErrOtherTenant},
+		{"instance token", instance, "globex", ScopeRead, nil},
+		{"instance token scope", instance, "globex", ScopeAdmin, ErrForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/tenants/"+test.organization+"/settings", nil)
+			req.Header.Set("Authorization", "Bearer "+test.token)
+			if _, err := auth.AuthenticateTenant(req, test.organization, test.scope); !errors.Is(err, test.want) {
+				t.Errorf("AuthenticateTenant = %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	// Tenant tokens are not instance tokens
+	req := httptest.NewRequest(http.MethodGet, "/api/usage", nil)
+	req.Header.Set("Authorization", "Bearer "+tenant)
+	if _, err := auth.Authenticate(req, ScopeRead); !errors.Is(err, ErrOtherTenant) {
+		t.Errorf("Expected ErrOtherTenant, got %v", err)
+	}
+
+	// Browsers send the token as the basic auth password
+	req = httptest.NewRequest(http.MethodGet, "/admin/tenants/acme", nil)
+	req.SetBasicAuth("acme", tenant)
+	if token, err := auth.AuthenticateTenant(req, "acme", ScopeAdmin); err != nil || token.Name != "acme-ci" {
+		t.Errorf("Expected the token of the basic auth password, got %+v, %v", token, err)
+	}
+}
+
+// policyFunc adapts a function to a Policy
+type policyFunc func(request Request) (bool, error)
+
+func (f policyFunc) Allow(ctx context.Context, request Request) (bool, error) {
+	return f(request)
+}
+
+func TestAuthenticator_Policy(t *testing.T) {
+	reader, _ := Generate()
+	tenant, _ := Generate()
+	var asked Request
+	// Readers may only read, and only with GET; tenant tokens may do
+	// anything in their organization
+	auth := NewAuthenticator("", fakeStore{
+		Hash(reader): {Name: "grafana", Scopes: []Scope{ScopeRead}},
+		Hash(tenant): {Name: "acme-ci", Scopes: []Scope{ScopeRead}, Organization: "acme"},
+	}).WithPolicy(policyFunc(func(request Request) (bool, error) {
+		asked = request
+		switch {
+		case request.Path == "/api/broken":
+			return false, errors.New("connection refused")
+		case request.Token.Organization != "":
+			return true, nil
+		}
+		return request.Scope == ScopeRead && request.Method == http.MethodGet, nil
+	}))
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		want   error
+	}{
+		{"allowed", reader, http.MethodGet, "/api/events", nil},
+		{"denied", reader, http.MethodPost, "/api/events", ErrDenied},
+		{"policy unavailable", reader, http.MethodGet, "/api/broken", ErrDenied},
+		{"unauthenticated", "", http.MethodGet, "/api/events", ErrUnauthorized},
+		{"tenant token", tenant, http.MethodGet, "/api/events", ErrOtherTenant},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, nil)
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			if _, err := auth.Authenticate(req, ScopeRead); !errors.Is(err, test.want) {
+				t.Errorf("Authenticate = %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	// The policy decides in place of the token scopes, within the tenant
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/tenants/acme/settings", nil)
+	req.Header.Set("Authorization", "Bearer "+tenant)
+	if _, err := auth.AuthenticateTenant(req, "acme", ScopeAdmin); err != nil {
+		t.Errorf("Expected the policy to allow the tenant token, got %v", err)
+	}
+	if asked.Organization != "acme" || asked.Scope != ScopeAdmin || asked.Token.Name != "acme-ci" {
+		t.Errorf("Unexpected policy request %+v", asked)
+	}
+
+	rr := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/api/events", nil)
+	req.Header.Set("Authorization", "Bearer "+reader)
+	auth.Require(ScopeRead, func(w http.ResponseWriter, r *http.Request) {})(rr, req)
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), "Denied by policy") {
+		t.Errorf("Expected a 403 denied by policy, got %d %s", rr.Code, rr.Body)
+	}
+}
+
+// usersFunc is a Users signing in with a function
+type usersFunc func(username, password string) (Token, error)
+
+func (f usersFunc) SignIn(ctx context.Context, username, password string) (Token, error) {
+	return f(username, password)
+}
+
+func TestAuthenticator_Users(t *testing.T) {
+	stored, _ := Generate()
+	auth := NewAuthenticator("", fakeStore{
+		Hash(stored): {Name: "grafana", Scopes: []Scope{ScopeRead}},
+	}).WithUsers(usersFunc(func(username, password string) (Token, error) {
+		if username == "octocat" && password == "hunter2" {
+			return Token{Name: username, Scopes: []Scope{ScopeRead}}, nil
+		}
+		return Token{}, ErrUnauthorized
+	}))
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		scope    Scope
+		want     error
+	}{
+		{"user", "octocat", "hunter2", ScopeRead, nil},
+		{"user without scope", "octocat", "hunter2", ScopeAdmin, ErrForbidden},
+		{"wrong password", "octocat", "hunter3", ScopeRead, ErrUnauthorized},
+		{"stored token as password", "anyone", stored, ScopeRead, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.SetBasicAuth(test.username, test.password)
+			if _, err := auth.Authenticate(req, test.scope); !errors.Is(err, test.want) {
+				t.Errorf("Authenticate = %v, want %v", err, test.want)
+			}
+		})
+	}
+	if !auth.SignsInUsers() || NewAuthenticator("secret", nil).SignsInUsers() {
+		t.Error("Expected only the authenticator with users to sign them in")
+	}
+}
+
+// identitiesFunc is an Identities verifying with a function
+type identitiesFunc func(idToken string) (Token, error)
+
+func (f identitiesFunc) Verify(ctx context.Context, idToken string) (Token, error) {
+	return f(idToken)
+}
+
+func TestAuthenticator_Identities(t *testing.T) {
+	auth := NewAuthenticator("", nil).WithIdentities(identitiesFunc(func(idToken string) (Token, error) {
+		if idToken == "header.claims.signature" {
+			return Token{Name: "workload:repo:acme/api", Scopes: []Scope{ScopeReplay}}, nil
+		}
+		return Token{}, ErrUnauthorized
+	}))
+	if !auth.Enabled() {
+		t.Fatal("Expected an authenticator with identities to be enabled")
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		scope Scope
+		want  error
+	}{
+		{"ID token", "header.claims.signature", ScopeReplay, nil},
+		{"ID token without scope", "header.claims.signature", ScopeAdmin, ErrForbidden},
+		{"invalid ID token", "header.claims.forged", ScopeReplay, ErrUnauthorized},
+		{"API token", "cct_unknown", ScopeReplay, ErrUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/usage", nil)
+			req.Header.Set("Authorization", "Bearer "+test.token)
+			if _, err := auth.Authenticate(req, test.scope); !errors.Is(err, test.want) {
+				t.Errorf("Authenticate = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
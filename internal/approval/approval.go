@@ -0,0 +1,311 @@
+// Package approval holds route, setting and rule changes until a second
+// operator approves them, as change-management processes require for production
+// configuration. Changes are requested through the management API or the
+// admin dashboard and decided there or with ChatOps commands; changes that
+// are not decided in time expire.
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/chatops"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/settings"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultExpiry is how long a change waits for approval by default
+const DefaultExpiry = 24 * time.Hour
+
+// RuleSetting is the Setting of changes to a stored rule. Match is the rule
+// name and Value the rule's condition and actions as a JSON object, or empty
+// to delete the rule.
+const RuleSetting = "rule"
+
+// Statuses of a change
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusExpired  = "expired"
+)
+
+var (
+	// ErrNotFound is returned when deciding a change that does not exist
+	ErrNotFound = errors.New("change not found")
+	// ErrDecided is returned when deciding a change that is no longer pending
+	ErrDecided = errors.New("change already decided")
+	// ErrSelfApproval is returned when an operator approves their own change
+	ErrSelfApproval = errors.New("changes must be approved by a second operator")
+)
+
+// Change is a change to one stored setting or, with RuleSetting, to a stored
+// rule. For route lists it sets or, with an empty value, removes the route
+// for Match; for other settings it sets or removes the whole value.
+type Change struct {
+	ID          int32      `json:"id"`
+	Setting     string     `json:"setting"`
+	Match       string     `json:"match,omitempty"`
+	Value       string     `json:"value"`
+	Status      string     `json:"status"`
+	RequestedBy string     `json:"requested_by"`
+	RequestedAt time.Time  `json:"requested_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	DecidedBy   string     `json:"decided_by,omitempty"`
+	DecidedAt   *time.Time `json:"decided_at,omitempty"`
+}
+
+// Apply returns the stored value of the setting with the change made
+func (c Change) Apply(value string) string {
+	if !settings.IsRouteSetting(c.Setting) {
+		return c.Value
+	}
+	if c.Value == "" {
+		value, _ = settings.RemoveRoute(value, c.Match)
+		return value
+	}
+	return settings.SetRoute(value, c.Match, c.Value)
+}
+
+// String describes the change, e.g. "set DOCS_ROUTES wiki to https://chat.example.com"
+func (c Change) String() string {
+	target := c.Setting
+	if c.Match != "" {
+		target += " " + c.Match
+	}
+	if c.Value == "" {
+		return "remove " + target
+	}
+	return fmt.Sprintf("set %s to %s", target, c.Value)
+}
+
+// check reports why actor cannot give the change status at now, if they
+// cannot
+func (c Change) check(actor, status string, now time.Time) error {
+	if c.Status != StatusPending {
+		return fmt.Errorf("%w: change %d was %s", ErrDecided, c.ID, c.Status)
+	}
+	if !now.Before(c.ExpiresAt) {
+		return fmt.Errorf("%w: change %d expired", ErrDecided, c.ID)
+	}
+	if status == StatusApproved && actor == c.RequestedBy {
+		return ErrSelfApproval
+	}
+	return nil
+}
+
+func newChange(row db.PendingChange, now time.Time) Change {
+	change := Change{
+		ID:          row.ID,
+		Setting:     row.SettingName,
+		Match:       row.RouteMatch,
+		Value:       row.Value,
+		Status:      row.Status,
+		RequestedBy: row.RequestedBy,
+		RequestedAt: row.RequestedAt.Time,
+		ExpiresAt:   row.ExpiresAt.Time,
+		DecidedBy:   row.DecidedBy.String,
+	}
+	if row.DecidedAt.Valid {
+		change.DecidedAt = &row.DecidedAt.Time
+	}
+	if change.Status == StatusPending && !now.Before(change.ExpiresAt) {
+		change.Status = StatusExpired
+	}
+	return change
+}
+
+// Queue stores changes until they are approved, rejected or expire
+type Queue struct {
+	dbConn      *database.Connection
+	expiry      time.Duration
+	now         func() time.Time
+	reloadRules func(ctx context.Context) error
+}
+
+// NewQueue creates a queue on the pending_changes table. Changes expire
+// after expiry, or DefaultExpiry if it is zero.
+func NewQueue(dbConn *database.Connection, expiry time.Duration) *Queue {
+	if expiry <= 0 {
+		expiry = DefaultExpiry
+	}
+	return &Queue{dbConn: dbConn, expiry: expiry, now: time.Now}
+}
+
+// WithRuleReloader makes the queue call reload after approving a rule
+// change, so the rule takes effect without waiting for the next reload
+func (q *Queue) WithRuleReloader(reload func(ctx context.Context) error) *Queue {
+	q.reloadRules = reload
+	return q
+}
+
+// Request queues a change made by requestedBy
+func (q *Queue) Request(ctx context.Context, change Change, requestedBy string) (Change, error) {
+	row, err := q.dbConn.Queries().CreatePendingChange(ctx, db.CreatePendingChangeParams{
+		SettingName: change.Setting,
+		RouteMatch:  change.Match,
+		Value:       change.Value,
+		RequestedBy: requestedBy,
+		ExpiresAt:   pgtype.Timestamptz{Time: q.now().Add(q.expiry), Valid: true},
+	})
+	if err != nil {
+		return Change{}, err
+	}
+	return newChange(row, q.now()), nil
+}
+
+// List returns the changes waiting for a decision, oldest first
+func (q *Queue) List(ctx context.Context) ([]Change, error) {
+	rows, err := q.dbConn.Queries().ListPendingChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]Change, 0, len(rows))
+	for _, row := range rows {
+		changes = append(changes, newChange(row, q.now()))
+	}
+	return changes, nil
+}
+
+// Approve makes a pending change on behalf of approver, who must not be the
+// operator who requested it
+func (q *Queue) Approve(ctx context.Context, id int32, approver string) (Change, error) {
+	return q.decide(ctx, id, approver, StatusApproved)
+}
+
+// Reject discards a pending change. The requester may reject, that is
+// withdraw, their own change.
+func (q *Queue) Reject(ctx context.Context, id int32, actor string) (Change, error) {
+	return q.decide(ctx, id, actor, StatusRejected)
+}
+
+// decide records a decision, making approved changes in the same
+// transaction under the settings lock
+func (q *Queue) decide(ctx context.Context, id int32, actor, status string) (Change, error) {
+	var change Change
+	err := q.dbConn.InTx(ctx, func(queries *db.Queries) error {
+		row, err := queries.GetPendingChangeForUpdate(ctx, id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		change = newChange(row, q.now())
+		if err := change.check(actor, status, q.now()); err != nil {
+			return err
+		}
+
+		if status == StatusApproved && change.Setting == RuleSetting {
+			if err := applyRule(ctx, queries, change); err != nil {
+				return err
+			}
+		} else if status == StatusApproved {
+			if err := queries.LockInstanceSettings(ctx); err != nil {
+				return err
+			}
+			stored, err := settings.Load(ctx, queries)
+			if err != nil {
+				return err
+			}
+			err = settings.Apply(ctx, queries, settings.Diff(
+				map[string]string{change.Setting: stored[change.Setting]},
+				map[string]string{change.Setting: change.Apply(stored[change.Setting])},
+			))
+			if err != nil {
+				return err
+			}
+		}
+
+		now := q.now()
+		change.Status, change.DecidedBy, change.DecidedAt = status, actor, &now
+		return queries.DecidePendingChange(ctx, db.DecidePendingChangeParams{
+			ID:        id,
+			Status:    status,
+			DecidedBy: pgtype.Text{String: actor, Valid: true},
+		})
+	})
+	if err != nil {
+		return Change{}, err
+	}
+	if change.Status == StatusApproved && change.Setting == RuleSetting && q.reloadRules != nil {
+		if err := q.reloadRules(ctx); err != nil {
+			log.Printf("Failed to reload rules: %v", err)
+		}
+	}
+	return change, nil
+}
+
+// applyRule makes an approved rule change, replacing or deleting the rule
+func applyRule(ctx context.Context, queries *db.Queries, change Change) error {
+	if change.Value == "" {
+		_, err := queries.DeleteRule(ctx, change.Match)
+		return err
+	}
+	var rule struct {
+		Condition string          `json:"condition"`
+		Actions   json.RawMessage `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(change.Value), &rule); err != nil {
+		return fmt.Errorf("invalid rule in change %d: %w", change.ID, err)
+	}
+	_, err := queries.UpsertRule(ctx, db.UpsertRuleParams{
+		Name:      change.Match,
+		Condition: rule.Condition,
+		Actions:   rule.Actions,
+		CreatedBy: change.RequestedBy,
+	})
+	return err
+}
+
+// ParseApprovers parses a comma-separated list of the GitHub logins allowed
+// to decide changes with ChatOps commands
+func ParseApprovers(list string) map[string]bool {
+	approvers := make(map[string]bool)
+	for _, login := range strings.Split(list, ",") {
+		if login = strings.ToLower(strings.TrimSpace(login)); login != "" {
+			approvers[login] = true
+		}
+	}
+	return approvers
+}
+
+// ApproveCommand handles "/approve <id>" from the logins in approvers
+func ApproveCommand(queue *Queue, approvers map[string]bool) chatops.Handler {
+	return command(approvers, "approve", queue.Approve)
+}
+
+// RejectCommand handles "/reject <id>" from the logins in approvers
+func RejectCommand(queue *Queue, approvers map[string]bool) chatops.Handler {
+	return command(approvers, "reject", queue.Reject)
+}
+
+func command(approvers map[string]bool, name string, decide func(ctx context.Context, id int32, actor string) (Change, error)) chatops.Handler {
+	return func(ctx context.Context, invocation chatops.Invocation) error {
+		if len(invocation.Command.Args) != 1 {
+			return fmt.Errorf("usage: /%s <change id>", name)
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(invocation.Command.Args[0], "#"), 10, 32)
+		if err != nil {
+			return fmt.Errorf("usage: /%s <change id>", name)
+		}
+		if !approvers[strings.ToLower(invocation.Actor)] {
+			return fmt.Errorf("%s may not decide changes", invocation.Actor)
+		}
+		change, err := decide(ctx, int32(id), invocation.Actor)
+		if err != nil {
+			return err
+		}
+		log.Printf("Change %d (%s) %s by %s", change.ID, change, change.Status, change.DecidedBy)
+		return nil
+	}
+}
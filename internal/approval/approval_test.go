@@ -0,0 +1,122 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/chatops"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestChange_Apply(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   Change
+		value    string
+		expected string
+	}{
+		{"set route", Change{Setting: "DOCS_ROUTES", Match: "pages", Value: "https://b.example.com"}, "wiki=https://a.example.com", "wiki=https://a.example.com,pages=https://b.example.com"},
+		{"replace route", Change{Setting: "DOCS_ROUTES", Match: "wiki", Value: "https://b.example.com"}, "wiki=https://a.example.com", "wiki=https://b.example.com"},
+		{"remove route", Change{Setting: "DOCS_ROUTES", Match: "wiki"}, "wiki=https://a.example.com,pages=https://b.example.com", "pages=https://b.example.com"},
+		{"set setting", Change{Setting: "RETENTION_MODE", Value: "archive"}, "delete", "archive"},
+		{"remove setting", Change{Setting: "RETENTION_MODE"}, "delete", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.change.Apply(test.value); got != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestChange_String(t *testing.T) {
+	if got := (Change{Setting: "DOCS_ROUTES", Match: "wiki", Value: "https://chat.example.com"}).String(); got != "set DOCS_ROUTES wiki to https://chat.example.com" {
+		t.Errorf("Unexpected description %q", got)
+	}
+	if got := (Change{Setting: "RETENTION_MODE"}).String(); got != "remove RETENTION_MODE" {
+		t.Errorf("Unexpected description %q", got)
+	}
+	if got := (Change{Setting: RuleSetting, Match: "bots"}).String(); got != "remove rule bots" {
+		t.Errorf("Unexpected description %q", got)
+	}
+}
+
+func TestChange_Check(t *testing.T) {
+	now := time.Now()
+	pending := Change{ID: 1, Status: StatusPending, RequestedBy: "terraform", ExpiresAt: now.Add(time.Hour)}
+
+	tests := []struct {
+		name     string
+		change   Change
+		actor    string
+		status   string
+		expected error
+	}{
+		{"approved by a second operator", pending, "admin", StatusApproved, nil},
+		{"approved by the requester", pending, "terraform", StatusApproved, ErrSelfApproval},
+		{"withdrawn by the requester", pending, "terraform", StatusRejected, nil},
+		{"already decided", Change{ID: 1, Status: StatusRejected, ExpiresAt: now.Add(time.Hour)}, "admin", StatusApproved, ErrDecided},
+		{"expired", Change{ID: 1, Status: StatusPending, ExpiresAt: now.Add(-time.Minute)}, "admin", StatusApproved, ErrDecided},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.change.check(test.actor, test.status, now)
+			if !errors.Is(err, test.expected) || (test.expected == nil && err != nil) {
+				t.Errorf("Expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestNewChange_Expired(t *testing.T) {
+	now := time.Now()
+	row := db.PendingChange{
+		ID:          3,
+		SettingName: "DOCS_ROUTES",
+		Status:      StatusPending,
+		RequestedBy: "terraform",
+		ExpiresAt:   pgtype.Timestamptz{Time: now.Add(-time.Minute), Valid: true},
+	}
+	if change := newChange(row, now); change.Status != StatusExpired || change.DecidedAt != nil {
+		t.Errorf("Expected an expired change, got %+v", change)
+	}
+}
+
+func TestParseApprovers(t *testing.T) {
+	approvers := ParseApprovers(" Octocat, hubot ,,")
+	if len(approvers) != 2 || !approvers["octocat"] || !approvers["hubot"] {
+		t.Errorf("Unexpected approvers %v", approvers)
+	}
+}
+
+func TestApproveCommand_Rejected(t *testing.T) {
+	handler := ApproveCommand(NewQueue(nil, 0), ParseApprovers("octocat"))
+
+	tests := []struct {
+		name  string
+		actor string
+		args  []string
+	}{
+		{"no change ID", "octocat", nil},
+		{"invalid change ID", "octocat", []string{"latest"}},
+		{"not an approver", "mallory", []string{"#3"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := handler(context.Background(), chatops.Invocation{
+				Command: chatops.Command{Name: "approve", Args: test.args},
+				Actor:   test.actor,
+			})
+			if err == nil {
+				t.Error("Expected the command to fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EventType is the event type audit log entries are stored under
+const EventType = "audit_log"
+
+// Entry represents a single GitHub Enterprise audit log entry
+type Entry struct {
+	DocumentID string `json:"_document_id"`
+	Action     string `json:"action"`
+	Actor      string `json:"actor"`
+	Org        string `json:"org"`
+	Repo       string `json:"repo"`
+	User       string `json:"user"`
+	Timestamp  int64  `json:"@timestamp"`
+	CreatedAt  int64  `json:"created_at"`
+
+	// Raw holds the original JSON for the entry
+	Raw json.RawMessage `json:"-"`
+}
+
+// DeliveryID returns a stable identifier for the entry so that re-imported
+// entries map onto the same stored event
+func (e Entry) DeliveryID() string {
+	return "audit-" + e.DocumentID
+}
+
+// hecEnvelope is the Splunk HTTP Event Collector wrapper used by the HTTPS
+// audit log streaming endpoint
+type hecEnvelope struct {
+	Event json.RawMessage `json:"event"`
+}
+
+// Parse decodes an audit log request body. It accepts a single JSON object, a
+// JSON array of entries, or newline-delimited JSON (as produced by streaming
+// and by historical exports), with or without HEC envelopes.
+func Parse(body []byte) ([]Entry, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, errors.New("empty audit log payload")
+	}
+
+	var raws []json.RawMessage
+	if trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &raws); err != nil {
+			return nil, fmt.Errorf("invalid audit log array: %w", err)
+		}
+	} else {
+		// Objects may be concatenated or newline-delimited
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		for {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, fmt.Errorf("invalid audit log entry: %w", err)
+			}
+			raws = append(raws, raw)
+		}
+	}
+
+	entries := make([]Entry, 0, len(raws))
+	for i, raw := range raws {
+		entry, err := parseEntry(raw)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseEntry decodes one entry, unwrapping an HEC envelope if present
+func parseEntry(raw json.RawMessage) (Entry, error) {
+	var envelope hecEnvelope
+	if err := json.Unmarshal(raw, &envelope); err == nil && len(envelope.Event) > 0 && envelope.Event[0] == '{' {
+		raw = envelope.Event
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return Entry{}, err
+	}
+	if entry.Action == "" {
+		return Entry{}, errors.New("missing action")
+	}
+	if entry.DocumentID == "" {
+		return Entry{}, errors.New("missing _document_id")
+	}
+	entry.Raw = raw
+	return entry, nil
+}
+
+// DefaultAlertActions contains the audit actions that are flagged by default
+var DefaultAlertActions = map[string]bool{
+	"org.add_member":                   true,
+	"org.update_member":                true,
+	"team.add_member":                  true,
+	"repo.add_member":                  true,
+	"protected_branch.create":          true,
+	"protected_branch.update":          true,
+	"protected_branch.destroy":         true,
+	"protected_branch.policy_override": true,
+	"repository_ruleset.update":        true,
+	"repository_ruleset.destroy":       true,
+}
+
+// ParseAlertActions parses a comma-separated list of audit actions. An empty
+// list returns DefaultAlertActions.
+func ParseAlertActions(list string) map[string]bool {
+	actions := make(map[string]bool)
+	for _, action := range strings.Split(list, ",") {
+		if action = strings.TrimSpace(action); action != "" {
+			actions[action] = true
+		}
+	}
+	if len(actions) == 0 {
+		return DefaultAlertActions
+	}
+	return actions
+}
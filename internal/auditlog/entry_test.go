@@ -0,0 +1,82 @@
+package auditlog
+
+import (
+	"testing"
+)
+
+// TestParse_SingleObject tests parsing a single audit log entry
+func TestParse_SingleObject(t *testing.T) {
+	body := []byte(`{"_document_id":"abc","action":"org.add_member","actor":"octocat","org":"acme","@timestamp":1700000000000}`)
+
+	entries, err := Parse(body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %d", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Action != "org.add_member" || entry.Actor != "octocat" || entry.Org != "acme" {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry.DeliveryID() != "audit-abc" {
+		t.Errorf("Expected delivery ID audit-abc, got %s", entry.DeliveryID())
+	}
+	if string(entry.Raw) != string(body) {
+		t.Errorf("Expected raw entry to be preserved, got %s", entry.Raw)
+	}
+}
+
+// TestParse_Formats tests the supported body formats
+func TestParse_Formats(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"array", `[{"_document_id":"a","action":"repo.create"},{"_document_id":"b","action":"repo.destroy"}]`, 2},
+		{"ndjson", "{\"_document_id\":\"a\",\"action\":\"repo.create\"}\n{\"_document_id\":\"b\",\"action\":\"repo.destroy\"}\n", 2},
+		{"hec envelope", `{"event":{"_document_id":"a","action":"repo.create"},"sourcetype":"github:enterprise:audit"}`, 1},
+	}
+
+	for _, test := range tests {
+		entries, err := Parse([]byte(test.body))
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", test.name, err)
+			continue
+		}
+		if len(entries) != test.expected {
+			t.Errorf("%s: expected %d entries, got %d", test.name, test.expected, len(entries))
+		}
+	}
+}
+
+// TestParse_Invalid tests that malformed entries are rejected
+func TestParse_Invalid(t *testing.T) {
+	tests := []string{
+		``,
+		`not json`,
+		`{"_document_id":"a"}`,
+		`{"action":"repo.create"}`,
+		`[{"_document_id":"a","action":"repo.create"},"oops"]`,
+	}
+
+	for _, body := range tests {
+		if _, err := Parse([]byte(body)); err == nil {
+			t.Errorf("Parse(%q) expected error, got nil", body)
+		}
+	}
+}
+
+// TestParseAlertActions tests parsing the configured alert actions
+func TestParseAlertActions(t *testing.T) {
+	actions := ParseAlertActions(" org.add_member, repo.destroy ,")
+	if len(actions) != 2 || !actions["org.add_member"] || !actions["repo.destroy"] {
+		t.Errorf("Unexpected actions: %v", actions)
+	}
+
+	if defaults := ParseAlertActions(""); !defaults["protected_branch.update"] {
+		t.Error("Expected default alert actions when list is empty")
+	}
+}
@@ -0,0 +1,251 @@
+// Package authz delegates authorization decisions on the APIs and the admin
+// dashboard to a policy: an Open Policy Agent (OPA) endpoint or a condition
+// in the rules language. Decisions are cached, and a request is denied
+// whenever the policy does not explicitly allow it.
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/rules"
+)
+
+// DefaultCacheTTL is how long decisions are cached by default
+const DefaultCacheTTL = time.Minute
+
+// maxCached caps the number of cached decisions
+const maxCached = 10000
+
+// Variables available to policy conditions
+var Variables = []string{"token", "scope", "organization", "method", "path", "remote_addr"}
+
+// OPA asks an Open Policy Agent server for decisions through its data API
+type OPA struct {
+	url    string
+	client *http.Client
+}
+
+// NewOPA creates a policy asking the OPA data API endpoint of a rule, such
+// as http://localhost:8181/v1/data/choochoo/allow
+func NewOPA(endpoint string) (*OPA, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OPA URL %q", endpoint)
+	}
+	return &OPA{url: endpoint, client: &http.Client{Timeout: 5 * time.Second}}, nil
+}
+
+// Allow posts the request as the input of the rule. The request is allowed
+// if the rule is true, or is an object whose allow field is true; an
+// undefined rule denies it.
+func (o *OPA) Allow(ctx context.Context, request apitoken.Request) (bool, error) {
+	body, err := json.Marshal(map[string]apitoken.Request{"input": request})
+	if err != nil {
+		return false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "choochoo")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, o.url, bytes.TrimSpace(message))
+	}
+	var decision struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return false, fmt.Errorf("invalid response from %s: %w", o.url, err)
+	}
+	if len(decision.Result) == 0 {
+		return false, nil
+	}
+	var allowed bool
+	if err := json.Unmarshal(decision.Result, &allowed); err == nil {
+		return allowed, nil
+	}
+	var result struct {
+		Allow bool `json:"allow"`
+	}
+	if err := json.Unmarshal(decision.Result, &result); err != nil {
+		return false, fmt.Errorf("result from %s is neither a bool nor an object with allow", o.url)
+	}
+	return result.Allow, nil
+}
+
+// Condition allows the requests matching a condition in the rules language,
+// with the Variables of the request
+type Condition struct {
+	program *rules.Program
+}
+
+// NewCondition compiles a policy condition, such as
+// `"admin" in token.scopes || (scope == "read" && method == "GET")`
+func NewCondition(source string) (*Condition, error) {
+	program, err := rules.CompileWith(source, Variables)
+	if err != nil {
+		return nil, err
+	}
+	return &Condition{program: program}, nil
+}
+
+// Allow evaluates the condition on the request. Conditions that fail to
+// evaluate deny the request.
+func (c *Condition) Allow(ctx context.Context, request apitoken.Request) (bool, error) {
+	scopes := make([]interface{}, 0, len(request.Token.Scopes))
+	for _, scope := range request.Token.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+	return c.program.Eval(map[string]interface{}{
+		"token": map[string]interface{}{
+			"name":         request.Token.Name,
+			"scopes":       scopes,
+			"organization": request.Token.Organization,
+		},
+		"scope":        string(request.Scope),
+		"organization": request.Organization,
+		"method":       request.Method,
+		"path":         request.Path,
+		"remote_addr":  request.RemoteAddr,
+	})
+}
+
+// Cache remembers the decisions of a policy for a time, so the policy is not
+// asked on every request. Failures are not cached.
+type Cache struct {
+	policy apitoken.Policy
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	decisions map[string]decision
+}
+
+type decision struct {
+	allowed bool
+	expires time.Time
+}
+
+// NewCache caches the decisions of policy for ttl
+func NewCache(policy apitoken.Policy, ttl time.Duration) *Cache {
+	return &Cache{policy: policy, ttl: ttl, now: time.Now, decisions: make(map[string]decision)}
+}
+
+// Allow returns the cached decision on the request, asking the policy if
+// there is none
+func (c *Cache) Allow(ctx context.Context, request apitoken.Request) (bool, error) {
+	// Policies may decide on the client address, but its port changes
+	// with every connection and is left out of the key so decisions are
+	// shared between the connections of a client
+	keyed := request
+	keyed.RemoteAddr = remoteHost(request.RemoteAddr)
+	encoded, err := json.Marshal(keyed)
+	if err != nil {
+		return false, err
+	}
+	key := string(encoded)
+
+	now := c.now()
+	c.mu.Lock()
+	cached, ok := c.decisions[key]
+	c.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.allowed, nil
+	}
+
+	allowed, err := c.policy.Allow(ctx, request)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.decisions) >= maxCached {
+		c.prune(now)
+	}
+	c.decisions[key] = decision{allowed: allowed, expires: now.Add(c.ttl)}
+	return allowed, nil
+}
+
+// remoteHost returns the host of a remote address, or the address if it has
+// no port
+func remoteHost(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// prune drops expired decisions, or every decision if none have expired
+func (c *Cache) prune(now time.Time) {
+	for key, cached := range c.decisions {
+		if !now.Before(cached.expires) {
+			delete(c.decisions, key)
+		}
+	}
+	if len(c.decisions) >= maxCached {
+		clear(c.decisions)
+	}
+}
+
+// denyAll denies every request, reporting why
+type denyAll struct {
+	err error
+}
+
+// DenyAll creates a policy denying every request with err, for when the
+// configured policy cannot be used
+func DenyAll(err error) apitoken.Policy {
+	return denyAll{err: err}
+}
+
+// Allow denies the request
+func (d denyAll) Allow(ctx context.Context, request apitoken.Request) (bool, error) {
+	return false, d.err
+}
+
+// New creates the policy configured by an OPA URL or a condition, at most
+// one of which may be set, and returns nil if neither is. The decisions of
+// OPA are cached for cacheTTL unless it is 0.
+func New(opaURL, condition string, cacheTTL time.Duration) (apitoken.Policy, error) {
+	switch {
+	case opaURL != "" && condition != "":
+		return nil, errors.New("set either an OPA URL or a policy condition, not both")
+	case opaURL != "":
+		opa, err := NewOPA(opaURL)
+		if err != nil {
+			return nil, err
+		}
+		if cacheTTL > 0 {
+			return NewCache(opa, cacheTTL), nil
+		}
+		return opa, nil
+	case condition != "":
+		cond, err := NewCondition(condition)
+		if err != nil {
+			return nil, err
+		}
+		return cond, nil
+	}
+	return nil, nil
+}
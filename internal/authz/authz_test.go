@@ -0,0 +1,195 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+)
+
+func request(scope apitoken.Scope, method string, scopes ...apitoken.Scope) apitoken.Request {
+	return apitoken.Request{
+		Token:      apitoken.Token{Name: "ci", Scopes: scopes},
+		Scope:      scope,
+		Method:     method,
+		Path:       "/api/events",
+		RemoteAddr: "192.0.2.1:4000",
+	}
+}
+
+func TestOPA_Allow(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		want     bool
+		wantErr  bool
+	}{
+		{"allowed", http.StatusOK, `{"result": true}`, true, false},
+		{"denied", http.StatusOK, `{"result": false}`, false, false},
+		{"allow object", http.StatusOK, `{"result": {"allow": true, "reason": "on call"}}`, true, false},
+		{"undefined", http.StatusOK, `{}`, false, false},
+		{"not a decision", http.StatusOK, `{"result": "yes"}`, false, true},
+		{"server error", http.StatusInternalServerError, `{"code": "internal_error"}`, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input apitoken.Request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Input apitoken.Request `json:"input"`
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				input = body.Input
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			opa, err := NewOPA(server.URL + "/v1/data/choochoo/allow")
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			allowed, err := opa.Allow(context.Background(), request(apitoken.ScopeRead, "GET", apitoken.ScopeRead))
+			if allowed != tt.want || (err != nil) != tt.wantErr {
+				t.Errorf("Allow() = %v, %v", allowed, err)
+			}
+			if input.Token.Name != "ci" || input.Scope != apitoken.ScopeRead || input.Method != "GET" || input.Path != "/api/events" {
+				t.Errorf("Unexpected input %+v", input)
+			}
+		})
+	}
+}
+
+func TestCondition_Allow(t *testing.T) {
+	condition, err := NewCondition(`"admin" in token.scopes || (scope == "read" && method == "GET" && path.startsWith("/api/"))`)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		request apitoken.Request
+		want    bool
+	}{
+		{"admin", request(apitoken.ScopeReplay, "POST", apitoken.ScopeAdmin), true},
+		{"read", request(apitoken.ScopeRead, "GET"), true},
+		{"replay", request(apitoken.ScopeReplay, "POST", apitoken.ScopeReplay), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if allowed, err := condition.Allow(context.Background(), tt.request); allowed != tt.want || err != nil {
+				t.Errorf("Allow() = %v, %v, want %v", allowed, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewCondition(`sender == "octocat"`); err == nil {
+		t.Error("Expected an error for a condition on rule variables")
+	}
+}
+
+// countingPolicy allows reads and counts how often it is asked
+type countingPolicy struct {
+	calls int
+	err   error
+}
+
+func (p *countingPolicy) Allow(ctx context.Context, request apitoken.Request) (bool, error) {
+	p.calls++
+	return request.Scope == apitoken.ScopeRead, p.err
+}
+
+func TestCache_Allow(t *testing.T) {
+	policy := &countingPolicy{}
+	cache := NewCache(policy, time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	read := request(apitoken.ScopeRead, "GET")
+	for _, remoteAddr := range []string{"192.0.2.1:4000", "192.0.2.1:4001"} {
+		read.RemoteAddr = remoteAddr
+		if allowed, err := cache.Allow(ctx, read); !allowed || err != nil {
+			t.Errorf("Expected the read to be allowed, got %v, %v", allowed, err)
+		}
+	}
+	if allowed, _ := cache.Allow(ctx, request(apitoken.ScopeAdmin, "GET")); allowed {
+		t.Error("Expected admin to be denied")
+	}
+	if policy.calls != 2 {
+		t.Errorf("Expected the policy to be asked twice, got %d", policy.calls)
+	}
+
+	now = now.Add(time.Minute)
+	cache.Allow(ctx, read)
+	if policy.calls != 3 {
+		t.Errorf("Expected an expired decision to be asked again, got %d calls", policy.calls)
+	}
+
+	// Failures are not cached
+	policy.err = errors.New("connection refused")
+	now = now.Add(time.Minute)
+	for range 2 {
+		if _, err := cache.Allow(ctx, read); err == nil {
+			t.Error("Expected the failure to be returned")
+		}
+	}
+	if policy.calls != 5 {
+		t.Errorf("Expected failures not to be cached, got %d calls", policy.calls)
+	}
+}
+
+func TestCache_AllowByRemoteAddr(t *testing.T) {
+	condition, err := NewCondition(`remote_addr.startsWith("192.0.2.1:")`)
+	if err != nil {
+		t.Fatalf("Failed to compile the condition: %v", err)
+	}
+	cache := NewCache(condition, time.Minute)
+	ctx := context.Background()
+
+	read := request(apitoken.ScopeRead, "GET")
+	if allowed, err := cache.Allow(ctx, read); !allowed || err != nil {
+		t.Errorf("Expected 192.0.2.1 to be allowed, got %v, %v", allowed, err)
+	}
+	read.RemoteAddr = "198.51.100.7:4000"
+	if allowed, _ := cache.Allow(ctx, read); allowed {
+		t.Error("Expected 198.51.100.7 to be denied instead of served the cached decision")
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		opaURL    string
+		condition string
+		want      string
+	}{
+		{"both", "http://localhost:8181/v1/data/choochoo/allow", "true", "not both"},
+		{"bad url", "localhost:8181", "", "invalid OPA URL"},
+		{"bad condition", "", "scope ==", "invalid condition"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.opaURL, tt.condition, time.Minute); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if policy, err := New("", "", time.Minute); policy != nil || err != nil {
+		t.Errorf("Expected no policy, got %v, %v", policy, err)
+	}
+	if policy, _ := New("http://localhost:8181/v1/data/choochoo/allow", "", time.Minute); policy == nil {
+		t.Error("Expected a cached OPA policy")
+	} else if _, ok := policy.(*Cache); !ok {
+		t.Errorf("Expected OPA decisions to be cached, got %T", policy)
+	}
+}
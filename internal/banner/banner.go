@@ -0,0 +1,126 @@
+// Package banner holds the maintenance notes administrators publish to the
+// users of the server, such as "retention change this weekend". A banner is
+// shown on the admin dashboard and appended to digest notifications from
+// when it starts until it ends; every banner ends, by default a week after it
+// starts, so stale notes disappear without anyone removing them.
+package banner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Levels, from least to most urgent
+const (
+	LevelInfo     = "info"
+	LevelWarning  = "warning"
+	LevelCritical = "critical"
+)
+
+// States of a banner at a point in time
+const (
+	StateScheduled = "scheduled"
+	StateActive    = "active"
+	StateExpired   = "expired"
+)
+
+// Limits
+const (
+	// DefaultDuration is how long a banner without an end is shown
+	DefaultDuration = 7 * 24 * time.Hour
+	// MaxDuration is the longest a banner may be shown
+	MaxDuration = 90 * 24 * time.Hour
+	// MaxMessageLength limits the size of a banner
+	MaxMessageLength = 500
+)
+
+// ErrInvalid is returned for banners that do not pass validation
+var ErrInvalid = errors.New("invalid banner")
+
+// Banner is a maintenance note shown between StartsAt and EndsAt
+type Banner struct {
+	ID      int32  `json:"id"`
+	Message string `json:"message"`
+	Level   string `json:"level"`
+	// Dashboard and Digests tell where the banner is shown
+	Dashboard bool      `json:"dashboard"`
+	Digests   bool      `json:"digests"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// State returns whether the banner is scheduled, active or expired at now
+func (b Banner) State(now time.Time) string {
+	switch {
+	case now.Before(b.StartsAt):
+		return StateScheduled
+	case now.Before(b.EndsAt):
+		return StateActive
+	default:
+		return StateExpired
+	}
+}
+
+// Validate checks the banner, trimming its message and defaulting its level
+// to info, its start to now and its end to DefaultDuration after its start
+func (b *Banner) Validate(now time.Time) error {
+	b.Message = strings.TrimSpace(b.Message)
+	if b.Level == "" {
+		b.Level = LevelInfo
+	}
+	if b.StartsAt.IsZero() {
+		b.StartsAt = now
+	}
+	if b.EndsAt.IsZero() {
+		b.EndsAt = b.StartsAt.Add(DefaultDuration)
+	}
+
+	switch {
+	case b.Message == "":
+		return fmt.Errorf("%w: missing message", ErrInvalid)
+	case len(b.Message) > MaxMessageLength:
+		return fmt.Errorf("%w: message longer than %d characters", ErrInvalid, MaxMessageLength)
+	case !b.Dashboard && !b.Digests:
+		return fmt.Errorf("%w: shown neither on the dashboard nor in digests", ErrInvalid)
+	case !b.EndsAt.After(b.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalid)
+	case !b.EndsAt.After(now):
+		return fmt.Errorf("%w: ends_at is in the past", ErrInvalid)
+	case b.EndsAt.Sub(b.StartsAt) > MaxDuration:
+		return fmt.Errorf("%w: shown for longer than %d days", ErrInvalid, int(MaxDuration/(24*time.Hour)))
+	}
+	switch b.Level {
+	case LevelInfo, LevelWarning, LevelCritical:
+	default:
+		return fmt.Errorf("%w: level must be info, warning or critical, got %q", ErrInvalid, b.Level)
+	}
+	return nil
+}
+
+// LoadFunc loads the banners active at now
+type LoadFunc func(ctx context.Context, now time.Time) ([]Banner, error)
+
+// ForDashboard returns the banners shown on the dashboard
+func ForDashboard(banners []Banner) []Banner {
+	return filter(banners, func(b Banner) bool { return b.Dashboard })
+}
+
+// ForDigests returns the banners appended to digests
+func ForDigests(banners []Banner) []Banner {
+	return filter(banners, func(b Banner) bool { return b.Digests })
+}
+
+func filter(banners []Banner, keep func(Banner) bool) []Banner {
+	var kept []Banner
+	for _, b := range banners {
+		if keep(b) {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,66 @@
+package banner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBanner_Validate(t *testing.T) {
+	now := time.Date(2024, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	b := Banner{Message: "  Retention change this weekend ", Dashboard: true}
+	if err := b.Validate(now); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if b.Message != "Retention change this weekend" || b.Level != LevelInfo || !b.StartsAt.Equal(now) || !b.EndsAt.Equal(now.Add(DefaultDuration)) {
+		t.Errorf("Expected a trimmed message and defaults, got %+v", b)
+	}
+
+	tests := []struct {
+		name   string
+		banner Banner
+	}{
+		{"no message", Banner{Dashboard: true}},
+		{"long message", Banner{Message: strings.Repeat("x", MaxMessageLength+1), Dashboard: true}},
+		{"nowhere", Banner{Message: "Maintenance"}},
+		{"unknown level", Banner{Message: "Maintenance", Level: "urgent", Digests: true}},
+		{"ends before it starts", Banner{Message: "Maintenance", Digests: true, StartsAt: now.Add(time.Hour), EndsAt: now.Add(time.Minute)}},
+		{"already ended", Banner{Message: "Maintenance", Digests: true, StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)}},
+		{"too long", Banner{Message: "Maintenance", Digests: true, EndsAt: now.Add(MaxDuration + time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.banner.Validate(now); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBanner_State(t *testing.T) {
+	now := time.Date(2024, 10, 15, 12, 0, 0, 0, time.UTC)
+	b := Banner{StartsAt: now, EndsAt: now.Add(time.Hour)}
+
+	for at, state := range map[time.Time]string{
+		now.Add(-time.Minute):     StateScheduled,
+		now:                       StateActive,
+		now.Add(59 * time.Minute): StateActive,
+		now.Add(time.Hour):        StateExpired,
+	} {
+		if got := b.State(at); got != state {
+			t.Errorf("Expected %s at %v, got %s", state, at, got)
+		}
+	}
+}
+
+func TestFilters(t *testing.T) {
+	banners := []Banner{{ID: 1, Dashboard: true}, {ID: 2, Digests: true}, {ID: 3, Dashboard: true, Digests: true}}
+	if dashboard := ForDashboard(banners); len(dashboard) != 2 || dashboard[0].ID != 1 || dashboard[1].ID != 3 {
+		t.Errorf("Unexpected dashboard banners %+v", dashboard)
+	}
+	if digests := ForDigests(banners); len(digests) != 2 || digests[0].ID != 2 || digests[1].ID != 3 {
+		t.Errorf("Unexpected digest banners %+v", digests)
+	}
+}
@@ -0,0 +1,420 @@
+// Package capacity forecasts event volume and storage growth from the ingest
+// history, so operators can grow disks and budgets before they run out. The
+// forecast is a linear trend of the daily event counts with a day-of-week
+// seasonality, which suits the weekly rhythm of development activity.
+package capacity
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults of the forecast window and alert horizon
+const (
+	DefaultHistoryDays = 90
+	DefaultHorizonDays = 30
+	DefaultAlertDays   = 30
+)
+
+// GB is the unit of the disk limit and storage price, 1024³ bytes
+const GB = 1 << 30
+
+const day = 24 * time.Hour
+
+// Day is the ingest of one UTC day
+type Day struct {
+	Date   time.Time `json:"date"`
+	Events int64     `json:"events"`
+	// Bytes are the payload bytes of the events
+	Bytes int64 `json:"bytes"`
+	// PeakMinuteEvents is the number of events in the busiest minute
+	PeakMinuteEvents int64 `json:"peak_minute_events"`
+}
+
+// Storage is the current size of the database
+type Storage struct {
+	DatabaseBytes int64
+	// EventsBytes is the size of the events table including its indexes,
+	// and EventsEstimate an estimate of its rows
+	EventsBytes    int64
+	EventsEstimate int64
+}
+
+// Thresholds raise alerts when storage is projected to exceed them within
+// AlertDays. Zero thresholds are not checked.
+type Thresholds struct {
+	DiskLimitBytes int64
+	// MonthlyBudget is compared with the storage cost of a month at
+	// PricePerGB per GB-month
+	MonthlyBudget float64
+	PricePerGB    float64
+	AlertDays     int
+}
+
+// Enabled reports whether any threshold is configured
+func (t Thresholds) Enabled() bool {
+	return t.DiskLimitBytes > 0 || t.MonthlyBudget > 0
+}
+
+// ParsePrice parses a price per GB-month such as "0.115"
+func ParsePrice(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	price, err := strconv.ParseFloat(value, 64)
+	if err != nil || price < 0 || math.IsInf(price, 0) || math.IsNaN(price) {
+		return 0, fmt.Errorf("%q is not a price", value)
+	}
+	return price, nil
+}
+
+// Ingest summarizes the ingest history and its trend
+type Ingest struct {
+	// EventsPerDay averages the history
+	EventsPerDay float64 `json:"events_per_day"`
+	// TrendPerDay is how much the daily event count grows each day
+	TrendPerDay float64 `json:"trend_per_day"`
+	// ProjectedEventsPerDay is the average day at the horizon
+	ProjectedEventsPerDay float64 `json:"projected_events_per_day"`
+	// PeakRPS is the busiest minute of the history, in events per second
+	PeakRPS float64 `json:"peak_rps"`
+	// ProjectedPeakRPS is the busiest minute expected at the horizon
+	ProjectedPeakRPS float64 `json:"projected_peak_rps"`
+}
+
+// StorageForecast projects the size of the database
+type StorageForecast struct {
+	CurrentBytes int64 `json:"current_bytes"`
+	// BytesPerEvent is what a stored event takes on disk, indexes included
+	BytesPerEvent  float64 `json:"bytes_per_event"`
+	ProjectedBytes int64   `json:"projected_bytes"`
+	LimitBytes     int64   `json:"limit_bytes,omitempty"`
+	// DaysUntilLimit is set when the limit is reached within the horizon or
+	// the alert days
+	DaysUntilLimit *int `json:"days_until_limit,omitempty"`
+}
+
+// CostForecast projects the monthly storage cost
+type CostForecast struct {
+	PricePerGB       float64 `json:"price_per_gb"`
+	MonthlyBudget    float64 `json:"monthly_budget,omitempty"`
+	CurrentMonthly   float64 `json:"current_monthly"`
+	ProjectedMonthly float64 `json:"projected_monthly"`
+	DaysUntilBudget  *int    `json:"days_until_budget,omitempty"`
+}
+
+// ForecastDay is the expected ingest of a day and the storage at its end
+type ForecastDay struct {
+	Date         time.Time `json:"date"`
+	Events       float64   `json:"events"`
+	StorageBytes int64     `json:"storage_bytes"`
+}
+
+// Report is a capacity forecast
+type Report struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	HistoryDays int             `json:"history_days"`
+	HorizonDays int             `json:"horizon_days"`
+	Ingest      Ingest          `json:"ingest"`
+	Storage     StorageForecast `json:"storage"`
+	Cost        *CostForecast   `json:"cost,omitempty"`
+	Alerts      []string        `json:"alerts"`
+	History     []Day           `json:"history"`
+	Forecast    []ForecastDay   `json:"forecast"`
+}
+
+// model is a linear trend with a factor for each day of the week
+type model struct {
+	intercept float64
+	slope     float64
+	weekday   [7]float64
+}
+
+// fit fits a model to a daily series starting on start. The trend is fitted
+// to the series with the seasonality taken out, so a week that ends on a
+// quiet weekend does not read as a decline.
+func fit(series []float64, start time.Time) model {
+	m := model{weekday: [7]float64{1, 1, 1, 1, 1, 1, 1}}
+	if len(series) == 0 {
+		return m
+	}
+
+	// A centered week-long moving average has no weekly seasonality, so it
+	// is what the first estimate of the seasonality is measured against
+	first := seasonality(series, start, func(t int) float64 {
+		if t < 3 || t+3 >= len(series) {
+			return 0
+		}
+		sum := 0.0
+		for _, y := range series[t-3 : t+4] {
+			sum += y
+		}
+		return sum / 7
+	})
+	adjusted := make([]float64, len(series))
+	for t, y := range series {
+		adjusted[t] = y
+		if factor := first[start.AddDate(0, 0, t).Weekday()]; factor > 0 {
+			adjusted[t] = y / factor
+		}
+	}
+	m.intercept, m.slope = regress(adjusted)
+	m.weekday = seasonality(series, start, m.trend)
+	return m
+}
+
+// regress fits a line to a series by least squares
+func regress(series []float64) (intercept, slope float64) {
+	n := float64(len(series))
+	var sumT, sumY, sumTT, sumTY float64
+	for t, y := range series {
+		sumT += float64(t)
+		sumY += y
+		sumTT += float64(t * t)
+		sumTY += float64(t) * y
+	}
+	denominator := n*sumTT - sumT*sumT
+	if denominator == 0 {
+		return sumY / n, 0
+	}
+	slope = (n*sumTY - sumT*sumY) / denominator
+	return (sumY - slope*sumT) / n, slope
+}
+
+// seasonality returns how far each day of the week sits from trend on
+// average, normalized so a whole week follows the trend. Days of the week
+// without data get a factor of 1.
+func seasonality(series []float64, start time.Time, trend func(t int) float64) [7]float64 {
+	factors := [7]float64{1, 1, 1, 1, 1, 1, 1}
+	var ratios [7]float64
+	var counts [7]int
+	for t, y := range series {
+		if expected := trend(t); expected > 0 {
+			weekday := start.AddDate(0, 0, t).Weekday()
+			ratios[weekday] += y / expected
+			counts[weekday]++
+		}
+	}
+	var total float64
+	var seen int
+	for weekday := range ratios {
+		if counts[weekday] > 0 {
+			factors[weekday] = ratios[weekday] / float64(counts[weekday])
+			total += factors[weekday]
+			seen++
+		}
+	}
+	if seen > 0 && total > 0 {
+		for weekday := range factors {
+			if counts[weekday] > 0 {
+				factors[weekday] *= float64(seen) / total
+			}
+		}
+	}
+	return factors
+}
+
+func (m model) trend(t int) float64 {
+	return m.intercept + m.slope*float64(t)
+}
+
+// at predicts day t of the series, which falls on date
+func (m model) at(t int, date time.Time) float64 {
+	return math.Max(0, m.trend(t)*m.weekday[date.Weekday()])
+}
+
+// busiest returns the largest weekday factor
+func (m model) busiest() float64 {
+	factor := 0.0
+	for _, f := range m.weekday {
+		factor = math.Max(factor, f)
+	}
+	return factor
+}
+
+// BuildReport forecasts horizonDays ahead of now from the complete UTC days
+// of the last historyDays. days need not include days without events.
+func BuildReport(now time.Time, historyDays, horizonDays int, days []Day, storage Storage, thresholds Thresholds) Report {
+	today := now.UTC().Truncate(day)
+	start := today.AddDate(0, 0, -historyDays)
+
+	byDate := make(map[time.Time]Day, len(days))
+	for _, d := range days {
+		byDate[d.Date.UTC().Truncate(day)] = d
+	}
+
+	report := Report{
+		GeneratedAt: now.UTC(),
+		HistoryDays: historyDays,
+		HorizonDays: horizonDays,
+		Alerts:      []string{},
+		History:     make([]Day, 0, historyDays),
+	}
+
+	// Today is not over, so it is left out of the history
+	events := make([]float64, historyDays)
+	peaks := make([]float64, historyDays)
+	var totalEvents, totalBytes, peak int64
+	for t := range events {
+		date := start.AddDate(0, 0, t)
+		d := byDate[date]
+		d.Date = date
+		report.History = append(report.History, d)
+		events[t] = float64(d.Events)
+		peaks[t] = float64(d.PeakMinuteEvents)
+		totalEvents += d.Events
+		totalBytes += d.Bytes
+		if d.PeakMinuteEvents > peak {
+			peak = d.PeakMinuteEvents
+		}
+	}
+	volume, busy := fit(events, start), fit(peaks, start)
+
+	horizon := historyDays + horizonDays - 1
+	report.Ingest = Ingest{
+		EventsPerDay:          float64(totalEvents) / math.Max(1, float64(historyDays)),
+		TrendPerDay:           volume.slope,
+		ProjectedEventsPerDay: math.Max(0, volume.trend(horizon)),
+		PeakRPS:               float64(peak) / 60,
+		ProjectedPeakRPS:      math.Max(float64(peak), busy.trend(horizon)*busy.busiest()) / 60,
+	}
+
+	// What an event takes on disk, from the table when it has been analyzed
+	// and its payload otherwise
+	bytesPerEvent := 0.0
+	switch {
+	case storage.EventsEstimate > 0:
+		bytesPerEvent = float64(storage.EventsBytes) / float64(storage.EventsEstimate)
+	case totalEvents > 0:
+		bytesPerEvent = float64(totalBytes) / float64(totalEvents)
+	}
+	report.Storage = StorageForecast{
+		CurrentBytes:  storage.DatabaseBytes,
+		BytesPerEvent: bytesPerEvent,
+		LimitBytes:    thresholds.DiskLimitBytes,
+	}
+	var cost *CostForecast
+	if thresholds.PricePerGB > 0 {
+		cost = &CostForecast{
+			PricePerGB:     thresholds.PricePerGB,
+			MonthlyBudget:  thresholds.MonthlyBudget,
+			CurrentMonthly: monthly(storage.DatabaseBytes, thresholds.PricePerGB),
+		}
+		report.Cost = cost
+	}
+
+	// Project far enough to check the thresholds even past the horizon
+	size := float64(storage.DatabaseBytes)
+	for i := 1; i <= max(horizonDays, thresholds.AlertDays); i++ {
+		date := today.AddDate(0, 0, i-1)
+		expected := volume.at(historyDays+i-1, date)
+		size += expected * bytesPerEvent
+		stored := int64(size)
+
+		if i <= horizonDays {
+			report.Forecast = append(report.Forecast, ForecastDay{Date: date, Events: expected, StorageBytes: stored})
+			report.Storage.ProjectedBytes = stored
+			if cost != nil {
+				cost.ProjectedMonthly = monthly(stored, thresholds.PricePerGB)
+			}
+		}
+		if report.Storage.DaysUntilLimit == nil && thresholds.DiskLimitBytes > 0 && stored > thresholds.DiskLimitBytes {
+			days := i
+			report.Storage.DaysUntilLimit = &days
+		}
+		if cost != nil && cost.DaysUntilBudget == nil && thresholds.MonthlyBudget > 0 && monthly(stored, thresholds.PricePerGB) > thresholds.MonthlyBudget {
+			days := i
+			cost.DaysUntilBudget = &days
+		}
+	}
+	if report.Forecast == nil {
+		report.Forecast = []ForecastDay{}
+	}
+
+	if days := report.Storage.DaysUntilLimit; days != nil && *days <= thresholds.AlertDays {
+		report.Alerts = append(report.Alerts, fmt.Sprintf("storage is projected to exceed the %.1f GB disk limit %s", float64(thresholds.DiskLimitBytes)/GB, within(*days)))
+	}
+	if cost != nil && cost.DaysUntilBudget != nil && *cost.DaysUntilBudget <= thresholds.AlertDays {
+		report.Alerts = append(report.Alerts, fmt.Sprintf("monthly storage cost is projected to exceed the budget of %.2f %s", thresholds.MonthlyBudget, within(*cost.DaysUntilBudget)))
+	}
+	return report
+}
+
+// within describes when a threshold is reached
+func within(days int) string {
+	if days == 1 {
+		return "today"
+	}
+	return fmt.Sprintf("within %d days", days)
+}
+
+// monthly returns the cost of storing bytes for a month
+func monthly(bytes int64, pricePerGB float64) float64 {
+	return float64(bytes) / GB * pricePerGB
+}
+
+// LoadFunc loads the ingest of the days since a UTC day and the current
+// storage
+type LoadFunc func(ctx context.Context, since time.Time) ([]Day, Storage, error)
+
+// Monitor raises an alert when storage is projected to exceed a threshold
+type Monitor struct {
+	load        LoadFunc
+	historyDays int
+	thresholds  Thresholds
+
+	mu      sync.Mutex
+	alerted map[string]bool
+}
+
+// NewMonitor creates a monitor forecasting from historyDays of ingest
+func NewMonitor(load LoadFunc, historyDays int, thresholds Thresholds) *Monitor {
+	return &Monitor{load: load, historyDays: historyDays, thresholds: thresholds, alerted: make(map[string]bool)}
+}
+
+// Check forecasts storage and logs each alert, once a day
+func (m *Monitor) Check(ctx context.Context, now time.Time) ([]string, error) {
+	since := now.UTC().Truncate(day).AddDate(0, 0, -m.historyDays)
+	days, storage, err := m.load(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	report := BuildReport(now, m.historyDays, m.thresholds.AlertDays, days, storage, m.thresholds)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var raised []string
+	for _, alert := range report.Alerts {
+		key := now.UTC().Format("2006-01-02") + " " + alert
+		if m.alerted[key] {
+			continue
+		}
+		m.alerted[key] = true
+		raised = append(raised, alert)
+		log.Printf("ALERT: %s", alert)
+	}
+	return raised, nil
+}
+
+// Run checks the forecast every interval until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Check(ctx, time.Now()); err != nil {
+				log.Printf("Failed to check capacity: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package capacity
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// history returns days of ingest since start, growing by growth events a
+// day, with weekends at a quarter of weekdays
+func history(start time.Time, days int, base, growth float64) []Day {
+	var list []Day
+	for t := 0; t < days; t++ {
+		date := start.AddDate(0, 0, t)
+		events := base + growth*float64(t)
+		if weekday := date.Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+			events /= 4
+		}
+		list = append(list, Day{Date: date, Events: int64(events), Bytes: int64(events) * 1000, PeakMinuteEvents: int64(events / 100)})
+	}
+	return list
+}
+
+func TestFit(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) // a Monday
+	series := make([]float64, 28)
+	for i := range series {
+		series[i] = 100 + 10*float64(i)
+	}
+	m := fit(series, start)
+	if math.Abs(m.slope-10) > 1e-9 || math.Abs(m.intercept-100) > 1e-9 {
+		t.Errorf("Expected a slope of 10 from 100, got %v from %v", m.slope, m.intercept)
+	}
+	for weekday, factor := range m.weekday {
+		if math.Abs(factor-1) > 1e-9 {
+			t.Errorf("Expected no seasonality on %s, got %v", time.Weekday(weekday), factor)
+		}
+	}
+
+	weekly := history(start, 28, 1000, 0)
+	for i, d := range weekly {
+		series[i] = float64(d.Events)
+	}
+	m = fit(series, start)
+	if m.weekday[time.Saturday] >= m.weekday[time.Monday] || m.weekday[time.Saturday] <= 0 {
+		t.Errorf("Expected quieter weekends, got %v", m.weekday)
+	}
+	if got := m.at(28, start.AddDate(0, 0, 28)); math.Abs(got-1000) > 1 {
+		t.Errorf("Expected a Monday of about 1000 events, got %v", got)
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	now := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	start := now.Truncate(24*time.Hour).AddDate(0, 0, -56)
+	days := history(start, 56, 10000, 100)
+	storage := Storage{DatabaseBytes: 40 * GB, EventsBytes: 30 * GB, EventsEstimate: 10_000_000}
+
+	report := BuildReport(now, 56, 30, days, storage, Thresholds{
+		DiskLimitBytes: 41 * GB,
+		PricePerGB:     0.5,
+		MonthlyBudget:  100,
+		AlertDays:      30,
+	})
+
+	if len(report.History) != 56 || len(report.Forecast) != 30 {
+		t.Fatalf("Expected 56 days of history and 30 of forecast, got %d and %d", len(report.History), len(report.Forecast))
+	}
+	if report.Ingest.TrendPerDay <= 0 || report.Ingest.ProjectedEventsPerDay <= report.Ingest.EventsPerDay {
+		t.Errorf("Expected growing ingest, got %+v", report.Ingest)
+	}
+	if report.Ingest.ProjectedPeakRPS < report.Ingest.PeakRPS {
+		t.Errorf("Expected the projected peak to be at least the past peak, got %+v", report.Ingest)
+	}
+	if report.Storage.BytesPerEvent != float64(3*GB)/1_000_000 {
+		t.Errorf("Expected bytes per event from the table size, got %v", report.Storage.BytesPerEvent)
+	}
+	if report.Storage.ProjectedBytes <= storage.DatabaseBytes || report.Forecast[29].StorageBytes != report.Storage.ProjectedBytes {
+		t.Errorf("Expected storage to grow, got %+v", report.Storage)
+	}
+	if report.Storage.DaysUntilLimit == nil || *report.Storage.DaysUntilLimit > 30 {
+		t.Fatalf("Expected the disk limit to be reached, got %+v", report.Storage)
+	}
+	if report.Cost == nil || report.Cost.CurrentMonthly != 20 || report.Cost.DaysUntilBudget != nil {
+		t.Errorf("Expected a monthly cost of 20 within budget, got %+v", report.Cost)
+	}
+	if len(report.Alerts) != 1 || !strings.Contains(report.Alerts[0], "41.0 GB disk limit") {
+		t.Errorf("Expected a disk limit alert, got %q", report.Alerts)
+	}
+}
+
+func TestBuildReport_NoHistory(t *testing.T) {
+	now := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	report := BuildReport(now, 30, 7, nil, Storage{DatabaseBytes: GB}, Thresholds{DiskLimitBytes: 2 * GB, AlertDays: 30})
+
+	if report.Storage.ProjectedBytes != GB || report.Storage.DaysUntilLimit != nil || len(report.Alerts) != 0 {
+		t.Errorf("Expected flat storage without alerts, got %+v", report)
+	}
+	// Missing days count as days without events
+	if len(report.History) != 30 || !report.History[0].Date.Equal(time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected history %+v", report.History)
+	}
+}
+
+func TestMonitor_Check(t *testing.T) {
+	now := time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC)
+	var since time.Time
+	load := func(ctx context.Context, from time.Time) ([]Day, Storage, error) {
+		since = from
+		return history(from, 28, 10000, 0), Storage{DatabaseBytes: 10 * GB, EventsBytes: GB, EventsEstimate: 1000}, nil
+	}
+	monitor := NewMonitor(load, 28, Thresholds{DiskLimitBytes: 11 * GB, AlertDays: 30})
+
+	raised, err := monitor.Check(context.Background(), now)
+	if err != nil || len(raised) != 1 {
+		t.Fatalf("Expected one alert, got %q, %v", raised, err)
+	}
+	if !since.Equal(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 28 days of history to be loaded, got since %v", since)
+	}
+
+	// Alerts are raised once a day
+	if raised, _ := monitor.Check(context.Background(), now.Add(time.Hour)); len(raised) != 0 {
+		t.Errorf("Expected no repeated alert, got %q", raised)
+	}
+	if raised, _ := monitor.Check(context.Background(), now.Add(24*time.Hour)); len(raised) != 1 {
+		t.Errorf("Expected the alert again the next day, got %q", raised)
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	if price, err := ParsePrice("0.115"); err != nil || price != 0.115 {
+		t.Errorf("ParsePrice = %v, %v", price, err)
+	}
+	for _, value := range []string{"abc", "-1", "Inf"} {
+		if _, err := ParsePrice(value); err == nil {
+			t.Errorf("Expected an error for %q", value)
+		}
+	}
+}
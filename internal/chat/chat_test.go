@@ -0,0 +1,184 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+)
+
+var opened = forwarder.Event{
+	DeliveryID: "d1",
+	EventType:  "pull_request",
+	Action:     "opened",
+	Repository: "octo-org/hello-world",
+	Sender:     "octocat",
+	Payload:    []byte(`{"number":42,"pull_request":{"title":"Fix flaky retries in the webhook queue","html_url":"https://github.com/octo-org/hello-world/pull/42"}}`),
+}
+
+func TestTemplate_Render(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"", "pull_request.opened in octo-org/hello-world by octocat"},
+		{`{{.sender}} opened #{{.payload.number}}: {{.payload.pull_request.title | truncate 20}}`, "octocat opened #42: Fix flaky retries i…"},
+		{`{{with .payload.commits}}{{len .}} commits{{end}}`, ""},
+		{"  {{.event}}\n", "pull_request"},
+	}
+	for _, tt := range tests {
+		tmpl, err := ParseTemplate(tt.template)
+		if err != nil {
+			t.Fatalf("Expected %q to parse, got %v", tt.template, err)
+		}
+		got, err := tmpl.Render(opened)
+		if err != nil {
+			t.Fatalf("Expected %q to render, got %v", tt.template, err)
+		}
+		if got != tt.want {
+			t.Errorf("Expected %q, got %q", tt.want, got)
+		}
+	}
+
+	for _, text := range []string{"{{.event", "{{.event | shout}}"} {
+		if _, err := ParseTemplate(text); err == nil {
+			t.Errorf("Expected %q not to parse", text)
+		}
+	}
+	tmpl, _ := ParseTemplate(`{{truncate "ten" .event}}`)
+	if _, err := tmpl.Render(opened); err == nil {
+		t.Error("Expected a bad function argument to fail")
+	}
+}
+
+func TestSlack_Webhook(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		if r.Header.Get("Authorization") != "" {
+			t.Error("Expected no credentials to be sent to an incoming webhook")
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	tmpl, _ := ParseTemplate("")
+	slack, err := NewSlackWebhook(server.URL+"/services/T0/B0/secret", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(slack.Name(), "secret") {
+		t.Errorf("Expected the name not to reveal the webhook path, got %q", slack.Name())
+	}
+	if err := slack.Forward(context.Background(), opened); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got["text"] != "pull_request.opened in octo-org/hello-world by octocat" {
+		t.Errorf("Unexpected message: %v", got)
+	}
+
+	if _, err := NewSlackWebhook("hooks.slack.com/services/T0", tmpl); err == nil {
+		t.Error("Expected an invalid URL to be rejected")
+	}
+}
+
+func TestSlack_Bot(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" || r.Header.Get("Authorization") != "Bearer xoxb-token" {
+			t.Errorf("Unexpected request %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		if got["channel"] == "#archived" {
+			w.Write([]byte(`{"ok":false,"error":"is_archived"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	tmpl, _ := ParseTemplate("New PR: {{.payload.pull_request.html_url}}")
+	slack, err := NewSlackBot("xoxb-token", "#reviews", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slack.WithAPIURL(server.URL + "/")
+	if err := slack.Forward(context.Background(), opened); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got["channel"] != "#reviews" || got["text"] != "New PR: https://github.com/octo-org/hello-world/pull/42" {
+		t.Errorf("Unexpected message: %v", got)
+	}
+
+	archived, _ := NewSlackBot("xoxb-token", "#archived", tmpl)
+	archived.WithAPIURL(server.URL)
+	if err := archived.Forward(context.Background(), opened); err == nil || !strings.Contains(err.Error(), "is_archived") {
+		t.Errorf("Expected the Slack error to be returned, got %v", err)
+	}
+
+	if _, err := NewSlackBot("", "#reviews", tmpl); err == nil {
+		t.Error("Expected a bot without a token to be rejected")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	received := map[string]map[string]interface{}{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&message)
+		received[r.URL.Path] = message
+		if r.URL.Path == "/gone" {
+			http.Error(w, "Unknown Webhook", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	tmpl, _ := ParseTemplate("{{.sender}} opened {{.payload.pull_request.title}}\n{{.payload.pull_request.html_url}}")
+	discord, err := NewWebhook(PlatformDiscord, server.URL+"/discord", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	teams, err := NewWebhook(PlatformTeams, server.URL+"/teams", tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range []*Webhook{discord, teams} {
+		if err := w.Forward(context.Background(), opened); err != nil {
+			t.Errorf("Expected no error from %s, got %v", w.Name(), err)
+		}
+	}
+
+	want := "octocat opened Fix flaky retries in the webhook queue\nhttps://github.com/octo-org/hello-world/pull/42"
+	if received["/discord"]["content"] != want || received["/discord"]["allowed_mentions"] == nil {
+		t.Errorf("Unexpected Discord message: %v", received["/discord"])
+	}
+	card := received["/teams"]
+	if card["@type"] != "MessageCard" || card["text"] != want || card["summary"] != "octocat opened Fix flaky retries in the webhook queue" {
+		t.Errorf("Unexpected Teams card: %v", card)
+	}
+
+	long, _ := ParseTemplate(strings.Repeat("x", 2500))
+	discord.template = long
+	discord.Forward(context.Background(), opened)
+	if content := received["/discord"]["content"].(string); len([]rune(content)) != discordMaxLength {
+		t.Errorf("Expected the message to be cut to %d characters, got %d", discordMaxLength, len([]rune(content)))
+	}
+
+	gone, _ := NewWebhook(PlatformDiscord, server.URL+"/gone", tmpl)
+	if err := gone.Forward(context.Background(), opened); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected the status to be returned, got %v", err)
+	}
+
+	if _, err := NewWebhook("irc", server.URL, tmpl); err == nil {
+		t.Error("Expected an unknown platform to be rejected")
+	}
+	if _, err := NewWebhook(PlatformTeams, "outlook.office.com/webhook", tmpl); err == nil {
+		t.Error("Expected an invalid URL to be rejected")
+	}
+}
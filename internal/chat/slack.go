@@ -0,0 +1,132 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/tracing"
+)
+
+// SlackAPIURL is the Web API bot messages are posted through
+const SlackAPIURL = "https://slack.com/api"
+
+// Slack posts event messages to Slack, through an incoming webhook or, with
+// a bot token, the chat.postMessage API. It is a forwarder, so it can be
+// used wherever events are forwarded.
+type Slack struct {
+	webhookURL string
+	token      string
+	channel    string
+	apiURL     string
+	template   *Template
+	client     *http.Client
+}
+
+// NewSlackWebhook creates a sender posting to a Slack incoming webhook URL
+func NewSlackWebhook(webhookURL string, template *Template) (*Slack, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid Slack webhook URL %q", webhookURL)
+	}
+	return &Slack{webhookURL: webhookURL, template: template, client: &http.Client{}}, nil
+}
+
+// NewSlackBot creates a sender posting to a channel, by name or ID, as the
+// bot of token
+func NewSlackBot(token, channel string, template *Template) (*Slack, error) {
+	if token == "" {
+		return nil, fmt.Errorf("posting to Slack channel %q needs a bot token", channel)
+	}
+	if strings.TrimSpace(channel) == "" {
+		return nil, fmt.Errorf("missing Slack channel")
+	}
+	return &Slack{token: token, channel: channel, apiURL: SlackAPIURL, template: template, client: &http.Client{}}, nil
+}
+
+// WithAPIURL sets the Web API URL bot messages are posted through
+func (s *Slack) WithAPIURL(apiURL string) *Slack {
+	s.apiURL = strings.TrimSuffix(apiURL, "/")
+	return s
+}
+
+// WrapTransport wraps the transport the sender posts with
+func (s *Slack) WrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	s.client.Transport = wrap(s.client.Transport)
+}
+
+// Name identifies the sender in logs without its credentials
+func (s *Slack) Name() string {
+	if s.channel != "" {
+		return "slack:" + s.channel
+	}
+	u, _ := url.Parse(s.webhookURL)
+	return "slack:" + u.Host
+}
+
+// Forward renders the message for an event and posts it. Events the
+// template renders no message for are skipped.
+func (s *Slack) Forward(ctx context.Context, event forwarder.Event) error {
+	text, err := s.template.Render(event)
+	if err != nil || text == "" {
+		return err
+	}
+	return s.Post(ctx, text)
+}
+
+// Post posts a message
+func (s *Slack) Post(ctx context.Context, text string) error {
+	if s.channel == "" {
+		return s.post(ctx, s.webhookURL, map[string]string{"text": text})
+	}
+	return s.post(ctx, s.apiURL+"/chat.postMessage", map[string]string{"channel": s.channel, "text": text})
+}
+
+func (s *Slack) post(ctx context.Context, endpoint string, message map[string]string) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", "choochoo")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d from Slack: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if s.channel == "" {
+		return nil
+	}
+	// The Web API reports errors in the body of 200 responses
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("invalid response from Slack: %w", err)
+	}
+	if !result.OK {
+		return fmt.Errorf("slack error: %s", result.Error)
+	}
+	return nil
+}
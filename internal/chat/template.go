@@ -0,0 +1,65 @@
+// Package chat posts event notifications to chat platforms. Messages are
+// rendered from events with Go templates, which see the same variables as
+// rule conditions.
+package chat
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+)
+
+// DefaultTemplate is the message sent when no template is configured, e.g.
+// "pull_request.opened in octo-org/hello-world by octocat"
+const DefaultTemplate = `{{.event}}{{with .action}}.{{.}}{{end}}{{with .repository}} in {{.}}{{end}}{{with .sender}} by {{.}}{{end}}`
+
+// Funcs are the functions available to message templates besides the
+// builtins, shared with email templates
+var Funcs = template.FuncMap{
+	// truncate shortens s to at most n runes, ending it with "…" if cut
+	"truncate": func(n int, s string) string {
+		runes := []rune(s)
+		if n <= 0 || len(runes) <= n {
+			return s
+		}
+		return string(runes[:n-1]) + "…"
+	},
+}
+
+// Template renders chat messages from events. Templates see the variables
+// of rule conditions as fields, e.g. {{.repository}} or
+// {{.payload.pull_request.title}}. Fields an event lacks render as
+// "<no value>", so guard them with {{with}}.
+type Template struct {
+	tmpl *template.Template
+}
+
+// ParseTemplate parses a message template, or DefaultTemplate if text is
+// empty
+func ParseTemplate(text string) (*Template, error) {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultTemplate
+	}
+	tmpl, err := template.New("message").Funcs(Funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message template: %w", err)
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// Render renders the message for an event, trimmed of surrounding
+// whitespace. An empty message means nothing should be sent.
+func (t *Template) Render(event forwarder.Event) (string, error) {
+	vars, err := event.Variables()
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := t.tmpl.Execute(&out, vars); err != nil {
+		return "", fmt.Errorf("failed to render message: %w", err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
@@ -0,0 +1,114 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/tracing"
+)
+
+// Platforms with incoming webhooks messages can be posted to
+const (
+	PlatformDiscord = "discord"
+	PlatformTeams   = "teams"
+)
+
+// discordMaxLength is the longest message content Discord accepts
+const discordMaxLength = 2000
+
+// Webhook posts event messages to the incoming webhook of a Discord channel
+// or a Microsoft Teams connector. Like Slack, it is a forwarder.
+type Webhook struct {
+	platform string
+	url      string
+	template *Template
+	client   *http.Client
+}
+
+// NewWebhook creates a sender posting to an incoming webhook URL of platform
+func NewWebhook(platform, webhookURL string, template *Template) (*Webhook, error) {
+	if platform != PlatformDiscord && platform != PlatformTeams {
+		return nil, fmt.Errorf("unknown chat platform %q", platform)
+	}
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid %s webhook URL %q", platform, webhookURL)
+	}
+	return &Webhook{platform: platform, url: webhookURL, template: template, client: &http.Client{}}, nil
+}
+
+// WrapTransport wraps the transport the sender posts with
+func (w *Webhook) WrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	w.client.Transport = wrap(w.client.Transport)
+}
+
+// Name identifies the sender in logs without the webhook's secret path
+func (w *Webhook) Name() string {
+	u, _ := url.Parse(w.url)
+	return w.platform + ":" + u.Host
+}
+
+// Forward renders the message for an event and posts it. Events the
+// template renders no message for are skipped.
+func (w *Webhook) Forward(ctx context.Context, event forwarder.Event) error {
+	text, err := w.template.Render(event)
+	if err != nil || text == "" {
+		return err
+	}
+	return w.Post(ctx, text)
+}
+
+// Post posts a message in the format of the platform
+func (w *Webhook) Post(ctx context.Context, text string) error {
+	var message interface{}
+	switch w.platform {
+	case PlatformDiscord:
+		if runes := []rune(text); len(runes) > discordMaxLength {
+			text = string(runes[:discordMaxLength-1]) + "…"
+		}
+		// Mentions in event data must not ping anyone
+		message = map[string]interface{}{
+			"content":          text,
+			"allowed_mentions": map[string][]string{"parse": {}},
+		}
+	case PlatformTeams:
+		summary, _, _ := strings.Cut(text, "\n")
+		message = map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  summary,
+			"text":     text,
+		}
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "choochoo")
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, w.platform, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package chatops
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// CommandEventType is the event type command invocations are published with
+const CommandEventType = "chatops_command"
+
+// ErrUnknownCommand is returned when no handler is registered for a command
+var ErrUnknownCommand = errors.New("unknown command")
+
+// Command is a slash command found in a comment, e.g. "/notify security"
+type Command struct {
+	Name string   `json:"name"`
+	Args []string `json:"args"`
+}
+
+// Invocation is a command together with where and by whom it was issued
+type Invocation struct {
+	Command    Command `json:"command"`
+	Actor      string  `json:"actor"`
+	Repository string  `json:"repository"`
+	Source     string  `json:"source"`
+	Number     int     `json:"number"`
+	URL        string  `json:"url,omitempty"`
+
+	// Payload is the raw webhook payload the command was found in
+	Payload []byte `json:"-"`
+}
+
+// Parse extracts slash commands from a comment body. A command must start a
+// line; quoted lines and fenced code blocks are ignored so that quoting
+// someone else's command does not run it again.
+func Parse(body string) []Command {
+	var commands []Command
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence || !strings.HasPrefix(line, "/") {
+			continue
+		}
+		fields := strings.Fields(line[1:])
+		if len(fields) == 0 || !validName(fields[0]) {
+			continue
+		}
+		commands = append(commands, Command{
+			Name: strings.ToLower(fields[0]),
+			Args: fields[1:],
+		})
+	}
+	return commands
+}
+
+// validName rejects paths such as "/usr/bin" that merely start with a slash
+func validName(name string) bool {
+	for _, r := range name {
+		if !(r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// Handler runs a command
+type Handler func(ctx context.Context, invocation Invocation) error
+
+// Registry dispatches commands to their handlers
+type Registry struct {
+	handlers map[string]Handler
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]Handler)}
+}
+
+// Register adds a handler for a command name
+func (r *Registry) Register(name string, handler Handler) {
+	r.handlers[strings.ToLower(name)] = handler
+}
+
+// Has reports whether a handler is registered for a command name
+func (r *Registry) Has(name string) bool {
+	_, ok := r.handlers[name]
+	return ok
+}
+
+// Names lists the registered command names
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dispatch runs the handler registered for the invocation's command
+func (r *Registry) Dispatch(ctx context.Context, invocation Invocation) error {
+	handler, ok := r.handlers[invocation.Command.Name]
+	if !ok {
+		return ErrUnknownCommand
+	}
+	return handler(ctx, invocation)
+}
@@ -0,0 +1,66 @@
+package chatops
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	body := "Thanks!\n/notify security\n  /Label bug urgent\n> /notify quoted\n```\n/notify fenced\n```\n/usr/bin/env is a path\n/"
+
+	commands := Parse(body)
+	if len(commands) != 2 {
+		t.Fatalf("Expected 2 commands, got %+v", commands)
+	}
+	if commands[0].Name != "notify" || len(commands[0].Args) != 1 || commands[0].Args[0] != "security" {
+		t.Errorf("Unexpected first command: %+v", commands[0])
+	}
+	if commands[1].Name != "label" || len(commands[1].Args) != 2 {
+		t.Errorf("Unexpected second command: %+v", commands[1])
+	}
+}
+
+func TestRegistry_Dispatch(t *testing.T) {
+	registry := NewRegistry()
+	var ran Invocation
+	registry.Register("Notify", func(ctx context.Context, invocation Invocation) error {
+		ran = invocation
+		return nil
+	})
+
+	invocation := Invocation{Command: Command{Name: "notify", Args: []string{"security"}}, Actor: "octocat"}
+	if err := registry.Dispatch(context.Background(), invocation); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if ran.Actor != "octocat" {
+		t.Errorf("Expected handler to receive the invocation, got %+v", ran)
+	}
+
+	err := registry.Dispatch(context.Background(), Invocation{Command: Command{Name: "deploy"}})
+	if !errors.Is(err, ErrUnknownCommand) {
+		t.Errorf("Expected ErrUnknownCommand, got %v", err)
+	}
+	if names := registry.Names(); len(names) != 1 || names[0] != "notify" {
+		t.Errorf("Unexpected names: %v", names)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy(" retest=write, Deploy=Maintain,,choochoo=admin")
+	if err != nil {
+		t.Fatalf("ParsePolicy() error = %v", err)
+	}
+	if role, ok := policy.Required("deploy"); !ok || role != "maintain" {
+		t.Errorf("Required(deploy) = %q, %v", role, ok)
+	}
+	if _, ok := policy.Required("notify"); ok {
+		t.Error("Expected notify to be unavailable from comments")
+	}
+
+	for _, list := range []string{"retest", "retest=owner", "retest=none", "re/test=write", "=write"} {
+		if _, err := ParsePolicy(list); err == nil {
+			t.Errorf("ParsePolicy(%q) succeeded", list)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package chatops
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/github"
+)
+
+// Policy maps the commands that can be run from issue and pull request
+// comments to the repository role their author needs
+type Policy map[string]string
+
+// ParsePolicy parses a comma-separated list of command=role pairs, where
+// role is a repository role such as "write" or "maintain", e.g.
+// "retest=write,deploy=maintain"
+func ParsePolicy(list string) (Policy, error) {
+	policy := make(Policy)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, role, ok := strings.Cut(pair, "=")
+		name, role = strings.ToLower(strings.TrimSpace(name)), strings.ToLower(strings.TrimSpace(role))
+		if !ok || name == "" || !validName(name) {
+			return nil, fmt.Errorf("invalid comment command %q", pair)
+		}
+		if !github.ValidRole(role) || role == github.RoleNone {
+			return nil, fmt.Errorf("invalid role %q of comment command %q", role, name)
+		}
+		policy[name] = role
+	}
+	return policy, nil
+}
+
+// Required returns the role needed to run a command, and false if the
+// command cannot be run from comments
+func (p Policy) Required(name string) (string, bool) {
+	role, ok := p[name]
+	return role, ok
+}
@@ -0,0 +1,159 @@
+// Package checkrun reports the processing of pushes and pull requests as
+// GitHub check runs, so the steps choochoo ran on a commit, such as the rules
+// and the forwarders, show up on the commit and its pull request. A check run
+// is created in progress when the event arrives and completed once every
+// step has finished.
+package checkrun
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/github"
+)
+
+// Name names the check runs of the pipeline
+const Name = "choochoo/pipeline"
+
+// pullRequestActions are the pull request actions that change the head
+// commit; other actions would add a check run for every label or comment
+var pullRequestActions = []string{"opened", "reopened", "synchronize"}
+
+// payload holds the commit fields of pushes and pull requests
+type payload struct {
+	Action     string `json:"action"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	PullRequest struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+}
+
+// Reporter creates the check runs of processed events
+type Reporter struct {
+	client *github.Client
+}
+
+// New creates a reporter creating check runs with client, which must
+// authenticate as a GitHub App
+func New(client *github.Client) *Reporter {
+	return &Reporter{client: client}
+}
+
+// Start creates the in-progress check run of an event. Events other than
+// pushes and pull requests opened, reopened or synchronized, and pushes
+// deleting a branch, get none and return a nil Run, as do all events of a nil
+// reporter.
+func (r *Reporter) Start(ctx context.Context, eventType, deliveryID string, body []byte) (*Run, error) {
+	if r == nil || (eventType != "push" && eventType != "pull_request") {
+		return nil, nil
+	}
+	var event payload
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse %s event: %w", eventType, err)
+	}
+	sha := event.After
+	if eventType == "pull_request" {
+		if !slices.Contains(pullRequestActions, event.Action) {
+			return nil, nil
+		}
+		sha = event.PullRequest.Head.SHA
+	}
+	repo := event.Repository.FullName
+	if repo == "" || event.Deleted || strings.Trim(sha, "0") == "" {
+		return nil, nil
+	}
+
+	name := eventType
+	if event.Action != "" {
+		name += "." + event.Action
+	}
+	id, err := r.client.StartCheckRun(ctx, repo, github.CheckRun{
+		Name:    Name,
+		HeadSHA: sha,
+		Output: github.CheckOutput{
+			Title:   "Processing",
+			Summary: fmt.Sprintf("choochoo is processing the `%s` event of this commit (delivery `%s`).", name, deliveryID),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Run{client: r.client, repo: repo, id: id, event: name, deliveryID: deliveryID}, nil
+}
+
+// Step is the outcome of a processing step
+type Step struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+// Run is the check run of an event being processed
+type Run struct {
+	client     *github.Client
+	repo       string
+	id         int64
+	event      string
+	deliveryID string
+
+	mu    sync.Mutex
+	steps []Step
+}
+
+// Record records the outcome of a step. Steps may finish concurrently. A
+// nil Run ignores it.
+func (run *Run) Record(name string, err error, duration time.Duration) {
+	if run == nil {
+		return
+	}
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.steps = append(run.steps, Step{Name: name, Err: err, Duration: duration})
+}
+
+// Finish completes the check run with the recorded steps: successful if
+// every step succeeded and failed otherwise. A nil Run does nothing.
+func (run *Run) Finish(ctx context.Context) error {
+	if run == nil {
+		return nil
+	}
+	run.mu.Lock()
+	steps := slices.Clone(run.steps)
+	run.mu.Unlock()
+	slices.SortFunc(steps, func(a, b Step) int { return strings.Compare(a.Name, b.Name) })
+
+	failed := 0
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "choochoo processed the `%s` event of this commit (delivery `%s`).\n\n", run.event, run.deliveryID)
+	if len(steps) > 0 {
+		summary.WriteString("| Step | Outcome | Duration |\n|------|---------|----------|\n")
+	}
+	for _, step := range steps {
+		outcome := "✅ succeeded"
+		if step.Err != nil {
+			failed++
+			outcome = "❌ " + strings.ReplaceAll(step.Err.Error(), "|", `\|`)
+		}
+		fmt.Fprintf(&summary, "| `%s` | %s | %s |\n", step.Name, outcome, step.Duration.Round(time.Millisecond))
+	}
+
+	conclusion, title := github.ConclusionSuccess, "✅ processed by choochoo"
+	if failed > 0 {
+		conclusion, title = github.ConclusionFailure, fmt.Sprintf("❌ %d of %d steps failed", failed, len(steps))
+	}
+	return run.client.CompleteCheckRun(ctx, run.repo, run.id, github.CheckRun{
+		Conclusion: conclusion,
+		Output:     github.CheckOutput{Title: title, Summary: summary.String()},
+	})
+}
@@ -0,0 +1,124 @@
+package checkrun
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/github"
+)
+
+// recorder is a GitHub API recording the check runs created and completed
+type recorder struct {
+	created   []github.CheckRun
+	completed []github.CheckRun
+}
+
+func (rec *recorder) serve(t *testing.T) *github.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var run github.CheckRun
+		json.NewDecoder(r.Body).Decode(&run)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/api/check-runs":
+			rec.created = append(rec.created, run)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 42}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/acme/api/check-runs/42":
+			rec.completed = append(rec.completed, run)
+			w.Write([]byte(`{"id": 42}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return github.NewTokenClient(server.URL, "token")
+}
+
+func TestReporter_Start(t *testing.T) {
+	rec := &recorder{}
+	reporter := New(rec.serve(t))
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		eventType string
+		body      string
+		want      string
+	}{
+		{"push", "push", `{"after": "abc123", "repository": {"full_name": "acme/api"}}`, "abc123"},
+		{"opened pull request", "pull_request", `{"action": "opened", "pull_request": {"head": {"sha": "def456"}}, "repository": {"full_name": "acme/api"}}`, "def456"},
+		{"closed pull request", "pull_request", `{"action": "closed", "pull_request": {"head": {"sha": "def456"}}, "repository": {"full_name": "acme/api"}}`, ""},
+		{"deleted branch", "push", `{"after": "0000000000000000000000000000000000000000", "deleted": true, "repository": {"full_name": "acme/api"}}`, ""},
+		{"issue", "issues", `{"action": "opened", "repository": {"full_name": "acme/api"}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec.created = nil
+			run, err := reporter.Start(ctx, tt.eventType, "d1", []byte(tt.body))
+			if err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			if tt.want == "" {
+				if run != nil || len(rec.created) != 0 {
+					t.Errorf("Start() created a check run %+v", rec.created)
+				}
+				return
+			}
+			if run == nil || run.id != 42 || len(rec.created) != 1 || rec.created[0].HeadSHA != tt.want ||
+				rec.created[0].Name != Name || rec.created[0].Status != github.StatusInProgress {
+				t.Errorf("Start() = %+v, created %+v", run, rec.created)
+			}
+		})
+	}
+
+	if _, err := reporter.Start(ctx, "push", "d1", []byte(`{`)); err == nil {
+		t.Error("Start() expected an error for an invalid payload")
+	}
+	var none *Reporter
+	if run, err := none.Start(ctx, "push", "d1", []byte(tests[0].body)); run != nil || err != nil {
+		t.Errorf("Start() of a nil reporter = %v, %v", run, err)
+	}
+}
+
+func TestRun_Finish(t *testing.T) {
+	rec := &recorder{}
+	reporter := New(rec.serve(t))
+	ctx := context.Background()
+	body := []byte(`{"after": "abc123", "repository": {"full_name": "acme/api"}}`)
+
+	run, err := reporter.Start(ctx, "push", "d1", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.Record("rules", nil, 20*time.Millisecond)
+	run.Record("forwarders", nil, 5*time.Millisecond)
+	if err := run.Finish(ctx); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	completed := rec.completed[0]
+	if completed.Conclusion != github.ConclusionSuccess || completed.Status != github.StatusCompleted ||
+		strings.Index(completed.Output.Summary, "`forwarders` | ✅") > strings.Index(completed.Output.Summary, "`rules` | ✅") {
+		t.Errorf("Unexpected completed check run %+v", completed)
+	}
+
+	run, _ = reporter.Start(ctx, "push", "d2", body)
+	run.Record("push", errors.New("database | down"), time.Second)
+	run.Record("rules", nil, time.Millisecond)
+	run.Finish(ctx)
+	completed = rec.completed[1]
+	if completed.Conclusion != github.ConclusionFailure || completed.Output.Title != "❌ 1 of 2 steps failed" ||
+		!strings.Contains(completed.Output.Summary, `| ❌ database \| down | 1s |`) {
+		t.Errorf("Unexpected failed check run %+v", completed)
+	}
+
+	var none *Run
+	none.Record("rules", nil, 0)
+	if err := none.Finish(ctx); err != nil {
+		t.Errorf("Finish() of a nil run = %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package checksum computes and verifies the SHA-256 checksums stored with
+// webhook payloads. PostgreSQL re-encodes JSONB payloads, changing their
+// whitespace and key order, so the checksum is taken over the canonical
+// JSON form of a payload: object keys sorted, no insignificant whitespace and
+// numbers as written. Anyone holding a payload, stored or forwarded, can
+// canonicalize it the same way to prove it is the one that was received.
+package checksum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrMismatch is returned when a payload does not match its checksum
+var ErrMismatch = errors.New("payload does not match its checksum")
+
+// Sum returns the hex encoded SHA-256 of the canonical form of a payload.
+// Payloads that are not JSON are hashed as they are.
+func Sum(payload []byte) string {
+	sum := sha256.Sum256(Canonical(payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks a payload against the checksum stored with it
+func Verify(payload []byte, want string) error {
+	if got := Sum(payload); got != want {
+		return fmt.Errorf("%w: got %s, want %s", ErrMismatch, got, want)
+	}
+	return nil
+}
+
+// Canonical returns the canonical JSON form of a payload, or the payload
+// itself if it is not valid JSON
+func Canonical(payload []byte) []byte {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return payload
+	}
+
+	// Maps are encoded with sorted keys and json.Number as written
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return payload
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n"))
+}
@@ -0,0 +1,40 @@
+package checksum
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	tests := map[string]string{
+		`{"b":1,"a":{"d":[1.50,"x<y"],"c":null}}`: `{"a":{"c":null,"d":[1.50,"x<y"]},"b":1}`,
+		"{\n  \"ref\": \"refs/heads/main\"\n}\n":  `{"ref":"refs/heads/main"}`,
+		`{"id": 12345678901234567890, "s": "é"}`:  `{"id":12345678901234567890,"s":"é"}`,
+		`not json`: `not json`,
+		`{} {}`:    `{} {}`,
+	}
+	for payload, want := range tests {
+		if got := string(Canonical([]byte(payload))); got != want {
+			t.Errorf("Expected %s for %s, got %s", want, payload, got)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	received := []byte(`{"zen":"Keep it logically awesome.","hook_id":1}`)
+	sum := Sum(received)
+	if len(sum) != 64 {
+		t.Fatalf("Expected a hex SHA-256, got %q", sum)
+	}
+
+	// How PostgreSQL returns the payload from a JSONB column
+	stored := []byte(`{"zen": "Keep it logically awesome.", "hook_id": 1}`)
+	if err := Verify(stored, sum); err != nil {
+		t.Errorf("Expected the re-encoded payload to verify, got %v", err)
+	}
+
+	tampered := []byte(`{"zen": "Keep it logically awesome.", "hook_id": 2}`)
+	if err := Verify(tampered, sum); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Expected ErrMismatch, got %v", err)
+	}
+}
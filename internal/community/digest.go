@@ -0,0 +1,234 @@
+package community
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/banner"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/forwarder"
+)
+
+// DigestEventType is the event type digests are delivered with
+const DigestEventType = "community_digest"
+
+// Community event types
+const (
+	StarEvent        = "star"
+	WatchEvent       = "watch"
+	ForkEvent        = "fork"
+	SponsorshipEvent = "sponsorship"
+)
+
+// Digest summarizes community activity for a repository, or for an account
+// in the case of sponsorships, over a period
+type Digest struct {
+	Subject         string    `json:"subject"`
+	Since           time.Time `json:"since"`
+	Until           time.Time `json:"until"`
+	NewStars        []string  `json:"new_stars"`
+	RemovedStars    int       `json:"removed_stars"`
+	NewWatchers     []string  `json:"new_watchers"`
+	NewForks        []string  `json:"new_forks"`
+	NewSponsors     []string  `json:"new_sponsors"`
+	EndedSponsors   []string  `json:"ended_sponsors"`
+	NetStargazers   int       `json:"net_stargazers"`
+	TotalActivities int       `json:"total_activities"`
+	// Banners are the maintenance notes active when the digest is sent
+	Banners []banner.Banner `json:"banners,omitempty"`
+}
+
+// Empty reports whether nothing happened during the period
+func (d *Digest) Empty() bool {
+	return d.TotalActivities == 0
+}
+
+// BuildDigests groups community events by subject into digests
+func BuildDigests(rows []db.ListCommunityEventsSinceRow, since, until time.Time) map[string]*Digest {
+	digests := make(map[string]*Digest)
+	for _, row := range rows {
+		if row.Subject == "" {
+			continue
+		}
+		digest, ok := digests[row.Subject]
+		if !ok {
+			digest = &Digest{
+				Subject:       row.Subject,
+				Since:         since,
+				Until:         until,
+				NewStars:      []string{},
+				NewWatchers:   []string{},
+				NewForks:      []string{},
+				NewSponsors:   []string{},
+				EndedSponsors: []string{},
+			}
+			digests[row.Subject] = digest
+		}
+
+		switch {
+		case row.EventType == StarEvent && row.Action == "created":
+			digest.NewStars = append(digest.NewStars, row.SenderLogin)
+			digest.NetStargazers++
+		case row.EventType == StarEvent && row.Action == "deleted":
+			digest.RemovedStars++
+			digest.NetStargazers--
+		case row.EventType == WatchEvent && row.Action == "started":
+			digest.NewWatchers = append(digest.NewWatchers, row.SenderLogin)
+		case row.EventType == ForkEvent:
+			digest.NewForks = append(digest.NewForks, row.SenderLogin)
+		case row.EventType == SponsorshipEvent && row.Action == "created":
+			digest.NewSponsors = append(digest.NewSponsors, row.SenderLogin)
+		case row.EventType == SponsorshipEvent && row.Action == "cancelled":
+			digest.EndedSponsors = append(digest.EndedSponsors, row.SenderLogin)
+		default:
+			continue
+		}
+		digest.TotalActivities++
+	}
+	return digests
+}
+
+// Route delivers digests for repositories matching a pattern to a maintainer
+// channel. Patterns are repository full names, "owner/*" or an account login.
+type Route struct {
+	Pattern string
+	Channel forwarder.Forwarder
+}
+
+// Matches reports whether the route accepts digests for a subject
+func (r Route) Matches(subject string) bool {
+	if r.Pattern == subject {
+		return true
+	}
+	if !strings.Contains(subject, "/") {
+		// Sponsorship digests are per account
+		return r.Pattern == subject+"/*"
+	}
+	matched, _ := path.Match(r.Pattern, subject)
+	return matched
+}
+
+// ParseRoutes parses a comma-separated list of pattern=url pairs, e.g.
+// "octo-org/hello-world=https://chat.example.com/hook,octo-org/*=https://chat.example.com/org"
+func ParseRoutes(list string) ([]Route, error) {
+	var routes []Route
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		pattern, endpoint, ok := strings.Cut(pair, "=")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("invalid community digest route %q", pair)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid community digest pattern %q: %w", pattern, err)
+		}
+		channel, err := forwarder.NewHTTPForwarder(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, Route{Pattern: pattern, Channel: channel})
+	}
+	return routes, nil
+}
+
+// LoadFunc loads community events received since a point in time
+type LoadFunc func(ctx context.Context, since time.Time) ([]db.ListCommunityEventsSinceRow, error)
+
+// Digester periodically sends community digests to the repositories that
+// have opted in through a route
+type Digester struct {
+	routes  []Route
+	load    LoadFunc
+	banners banner.LoadFunc
+}
+
+// NewDigester creates a digester for the given routes
+func NewDigester(routes []Route, load LoadFunc) *Digester {
+	return &Digester{routes: routes, load: load}
+}
+
+// WithBanners sets the function loading the banners appended to digests
+func (d *Digester) WithBanners(load banner.LoadFunc) *Digester {
+	d.banners = load
+	return d
+}
+
+// Send builds digests for the period ending at until and delivers each
+// non-empty digest to its matching channels
+func (d *Digester) Send(ctx context.Context, period time.Duration, until time.Time) error {
+	since := until.Add(-period)
+	rows, err := d.load(ctx, since)
+	if err != nil {
+		return fmt.Errorf("failed to load community events: %w", err)
+	}
+
+	digests := BuildDigests(rows, since, until)
+
+	// Banners are a convenience; digests are sent without them
+	var banners []banner.Banner
+	if d.banners != nil {
+		active, err := d.banners(ctx, until)
+		if err != nil {
+			log.Printf("Failed to load banners for community digests: %v", err)
+		}
+		banners = banner.ForDigests(active)
+	}
+	subjects := make([]string, 0, len(digests))
+	for subject := range digests {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+
+	for _, subject := range subjects {
+		digest := digests[subject]
+		var channels []forwarder.Forwarder
+		for _, route := range d.routes {
+			if route.Matches(subject) {
+				channels = append(channels, route.Channel)
+			}
+		}
+		if len(channels) == 0 || digest.Empty() {
+			continue
+		}
+		digest.Banners = banners
+
+		payload, err := json.Marshal(digest)
+		if err != nil {
+			log.Printf("Failed to encode community digest for %s: %v", subject, err)
+			continue
+		}
+		log.Printf("Sending community digest for %s (%d activities)", subject, digest.TotalActivities)
+		forwarder.ForwardAll(ctx, channels, forwarder.Event{
+			DeliveryID: fmt.Sprintf("digest-%s-%s", strings.ReplaceAll(subject, "/", "-"), until.Format("20060102")),
+			EventType:  DigestEventType,
+			Repository: subject,
+			Payload:    payload,
+		})
+	}
+	return nil
+}
+
+// Run sends digests every period until ctx is cancelled
+func (d *Digester) Run(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := d.Send(ctx, period, now.UTC()); err != nil {
+				log.Printf("Community digest failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package community
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/banner"
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+var testCommunityEvents = []db.ListCommunityEventsSinceRow{
+	{Subject: "octo-org/hello-world", EventType: "star", Action: "created", SenderLogin: "alice"},
+	{Subject: "octo-org/hello-world", EventType: "star", Action: "created", SenderLogin: "bob"},
+	{Subject: "octo-org/hello-world", EventType: "star", Action: "deleted", SenderLogin: "carol"},
+	{Subject: "octo-org/hello-world", EventType: "fork", SenderLogin: "dave"},
+	{Subject: "octo-org/hello-world", EventType: "watch", Action: "started", SenderLogin: "erin"},
+	{Subject: "octo-org", EventType: "sponsorship", Action: "created", SenderLogin: "frank"},
+	{Subject: "octo-org", EventType: "sponsorship", Action: "tier_changed", SenderLogin: "grace"},
+	{Subject: "other/repo", EventType: "fork", SenderLogin: "heidi"},
+}
+
+func TestBuildDigests(t *testing.T) {
+	digests := BuildDigests(testCommunityEvents, time.Time{}, time.Time{})
+
+	repo := digests["octo-org/hello-world"]
+	if repo == nil {
+		t.Fatal("Expected digest for octo-org/hello-world")
+	}
+	if len(repo.NewStars) != 2 || repo.RemovedStars != 1 || repo.NetStargazers != 1 {
+		t.Errorf("Unexpected stars: %+v", repo)
+	}
+	if len(repo.NewForks) != 1 || len(repo.NewWatchers) != 1 || repo.TotalActivities != 5 {
+		t.Errorf("Unexpected activity: %+v", repo)
+	}
+
+	account := digests["octo-org"]
+	if account == nil || len(account.NewSponsors) != 1 || account.TotalActivities != 1 {
+		t.Errorf("Unexpected sponsorship digest: %+v", account)
+	}
+}
+
+func TestRoute_Matches(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		subject  string
+		expected bool
+	}{
+		{"octo-org/hello-world", "octo-org/hello-world", true},
+		{"octo-org/*", "octo-org/hello-world", true},
+		{"octo-org/*", "octo-org", true},
+		{"octo-org/*", "other/repo", false},
+		{"octo-org/hello-world", "octo-org", false},
+	}
+
+	for _, test := range tests {
+		route := Route{Pattern: test.pattern}
+		if got := route.Matches(test.subject); got != test.expected {
+			t.Errorf("Route(%q).Matches(%q) = %v, expected %v", test.pattern, test.subject, got, test.expected)
+		}
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes("octo-org/*=https://chat.example.com/hook, octo-org/hello-world=https://chat.example.com/repo")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(routes) != 2 || routes[0].Pattern != "octo-org/*" {
+		t.Errorf("Unexpected routes: %+v", routes)
+	}
+
+	for _, list := range []string{"octo-org", "=https://chat.example.com", "octo-org/*=not-a-url"} {
+		if _, err := ParseRoutes(list); err == nil {
+			t.Errorf("Expected error for %q", list)
+		}
+	}
+}
+
+// TestDigester_Send tests that only opted-in subjects receive digests
+func TestDigester_Send(t *testing.T) {
+	received := make(chan Digest, 4)
+	channel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var digest Digest
+		json.NewDecoder(r.Body).Decode(&digest)
+		received <- digest
+	}))
+	defer channel.Close()
+
+	routes, err := ParseRoutes("octo-org/*=" + channel.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+	load := func(ctx context.Context, since time.Time) ([]db.ListCommunityEventsSinceRow, error) {
+		return testCommunityEvents, nil
+	}
+
+	until := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	if err := NewDigester(routes, load).Send(context.Background(), 7*24*time.Hour, until); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	close(received)
+
+	var subjects []string
+	for digest := range received {
+		subjects = append(subjects, digest.Subject)
+		if !digest.Until.Equal(until) || !digest.Since.Equal(until.AddDate(0, 0, -7)) {
+			t.Errorf("Unexpected period for %s: %s - %s", digest.Subject, digest.Since, digest.Until)
+		}
+	}
+	if len(subjects) != 2 || subjects[0] != "octo-org" || subjects[1] != "octo-org/hello-world" {
+		t.Errorf("Expected digests for octo-org and octo-org/hello-world, got %v", subjects)
+	}
+}
+
+// TestDigester_SendBanners tests that digests carry the banners meant for them
+func TestDigester_SendBanners(t *testing.T) {
+	received := make(chan Digest, 4)
+	channel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var digest Digest
+		json.NewDecoder(r.Body).Decode(&digest)
+		received <- digest
+	}))
+	defer channel.Close()
+
+	routes, err := ParseRoutes("octo-org/hello-world=" + channel.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+	load := func(ctx context.Context, since time.Time) ([]db.ListCommunityEventsSinceRow, error) {
+		return testCommunityEvents, nil
+	}
+	banners := func(ctx context.Context, now time.Time) ([]banner.Banner, error) {
+		return []banner.Banner{
+			{ID: 1, Message: "Retention change this weekend", Digests: true},
+			{ID: 2, Message: "Dashboard moves to /admin", Dashboard: true},
+		}, nil
+	}
+
+	digester := NewDigester(routes, load).WithBanners(banners)
+	if err := digester.Send(context.Background(), 7*24*time.Hour, time.Now()); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	close(received)
+
+	digest := <-received
+	if len(digest.Banners) != 1 || digest.Banners[0].Message != "Retention change this weekend" {
+		t.Errorf("Expected the digest banner appended, got %+v", digest.Banners)
+	}
+}
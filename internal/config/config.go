@@ -0,0 +1,948 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/approval"
+	"github.com/deedubs/choochoo/internal/authz"
+	"github.com/deedubs/choochoo/internal/capacity"
+	"github.com/deedubs/choochoo/internal/chat"
+	"github.com/deedubs/choochoo/internal/chatops"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/deadletter"
+	"github.com/deedubs/choochoo/internal/email"
+	"github.com/deedubs/choochoo/internal/encryption"
+	"github.com/deedubs/choochoo/internal/eventchain"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/github"
+	"github.com/deedubs/choochoo/internal/indexadvisor"
+	"github.com/deedubs/choochoo/internal/ipallow"
+	"github.com/deedubs/choochoo/internal/maintenance"
+	"github.com/deedubs/choochoo/internal/metrics"
+	"github.com/deedubs/choochoo/internal/outbound"
+	"github.com/deedubs/choochoo/internal/outbox"
+	"github.com/deedubs/choochoo/internal/pathlabels"
+	"github.com/deedubs/choochoo/internal/pipeline"
+	"github.com/deedubs/choochoo/internal/privacy"
+	"github.com/deedubs/choochoo/internal/querycache"
+	"github.com/deedubs/choochoo/internal/ratelimit"
+	"github.com/deedubs/choochoo/internal/receipt"
+	"github.com/deedubs/choochoo/internal/redact"
+	"github.com/deedubs/choochoo/internal/relay"
+	"github.com/deedubs/choochoo/internal/retention"
+	"github.com/deedubs/choochoo/internal/rules"
+	"github.com/deedubs/choochoo/internal/scim"
+	"github.com/deedubs/choochoo/internal/settings"
+	"github.com/deedubs/choochoo/internal/stream"
+	"github.com/deedubs/choochoo/internal/tracing"
+	"github.com/deedubs/choochoo/internal/workload"
+	"github.com/deedubs/choochoo/internal/workqueue"
+	"gopkg.in/yaml.v3"
+)
+
+// PathEnv is the environment variable naming the configuration file
+const PathEnv = "CHOOCHOO_CONFIG"
+
+// DefaultMaxBodyBytes matches the 25 MB cap GitHub puts on webhook payloads
+const DefaultMaxBodyBytes = 25 << 20
+
+// Config is the configuration of a choochoo instance. Each field can be set
+// in the configuration file under its key tag and overridden by the
+// environment variable in its env tag.
+type Config struct {
+	Port          string `key:"port" env:"PORT"`
+	GRPCPort      string `key:"grpc_port" env:"GRPC_PORT"`
+	WebhookSecret string `key:"github_webhook_secret" env:"GITHUB_WEBHOOK_SECRET"`
+	// RequireSignature refuses to serve without a webhook secret, so
+	// unsigned deliveries are never accepted. It will become the default in
+	// the next major version.
+	RequireSignature bool `key:"require_signature" env:"CHOOCHOO_REQUIRE_SIGNATURE"`
+	// WebhookSHA1Fallback validates the legacy SHA-1 X-Hub-Signature header
+	// of deliveries without an X-Hub-Signature-256 header
+	WebhookSHA1Fallback bool `key:"webhook_sha1_fallback" env:"WEBHOOK_SHA1_FALLBACK"`
+	// WebhookRelaySecrets names the relays deliveries are accepted from, as
+	// name=secret pairs
+	WebhookRelaySecrets string `key:"webhook_relay_secrets" env:"WEBHOOK_RELAY_SECRETS"`
+	MaxBodyBytes        int64  `key:"webhook_max_body_bytes" env:"WEBHOOK_MAX_BODY_BYTES"`
+	DatabaseURL         string `key:"database_url" env:"DATABASE_URL"`
+	IgnoredEvents       string `key:"ignored_events" env:"IGNORED_EVENTS"`
+
+	TLSCertFile         string `key:"tls_cert_file" env:"TLS_CERT_FILE"`
+	TLSKeyFile          string `key:"tls_key_file" env:"TLS_KEY_FILE"`
+	TLSAutocertHosts    string `key:"tls_autocert_hosts" env:"TLS_AUTOCERT_HOSTS"`
+	TLSAutocertEmail    string `key:"tls_autocert_email" env:"TLS_AUTOCERT_EMAIL"`
+	TLSAutocertCacheDir string `key:"tls_autocert_cache_dir" env:"TLS_AUTOCERT_CACHE_DIR"`
+	TLSRedirectPort     string `key:"tls_redirect_port" env:"TLS_REDIRECT_PORT"`
+
+	AuditLogToken        string `key:"audit_log_token" env:"AUDIT_LOG_TOKEN"`
+	AuditLogAlertActions string `key:"audit_log_alert_actions" env:"AUDIT_LOG_ALERT_ACTIONS"`
+	ManagementAPIToken   string `key:"management_api_token" env:"MANAGEMENT_API_TOKEN"`
+	AdminUsername        string `key:"admin_username" env:"ADMIN_USERNAME"`
+	AdminPassword        string `key:"admin_password" env:"ADMIN_PASSWORD"`
+
+	AuthzOPAURL    string        `key:"authz_opa_url" env:"AUTHZ_OPA_URL"`
+	AuthzCondition string        `key:"authz_condition" env:"AUTHZ_CONDITION"`
+	AuthzCacheTTL  time.Duration `key:"authz_cache_ttl" env:"AUTHZ_CACHE_TTL"`
+
+	// QueryCacheTTL is how long the results of aggregate query endpoints
+	// are cached, 0 to not cache them
+	QueryCacheTTL time.Duration `key:"query_cache_ttl" env:"QUERY_CACHE_TTL"`
+
+	// IndexAdvisorSlowQuery is the duration from which query API queries on
+	// the events table count as slow for the index advisor, 0 to not track
+	// them
+	IndexAdvisorSlowQuery time.Duration `key:"index_advisor_slow_query" env:"INDEX_ADVISOR_SLOW_QUERY"`
+	// IndexAdvisorAutoCreate builds the indexes the advisor suggests
+	IndexAdvisorAutoCreate bool `key:"index_advisor_auto_create" env:"INDEX_ADVISOR_AUTO_CREATE"`
+
+	// MaintenanceInterval is how often table maintenance runs, 0 to not run
+	// it on a schedule
+	MaintenanceInterval time.Duration `key:"maintenance_interval" env:"MAINTENANCE_INTERVAL"`
+	// MaintenanceWindow is the daily UTC time range scheduled maintenance
+	// may start in, such as 02:00-05:00
+	MaintenanceWindow                string `key:"maintenance_window" env:"MAINTENANCE_WINDOW"`
+	MaintenanceVacuumDeadPercent     int    `key:"maintenance_vacuum_dead_percent" env:"MAINTENANCE_VACUUM_DEAD_PERCENT"`
+	MaintenanceAnalyzeChangedPercent int    `key:"maintenance_analyze_changed_percent" env:"MAINTENANCE_ANALYZE_CHANGED_PERCENT"`
+	MaintenanceReindexBloatPercent   int    `key:"maintenance_reindex_bloat_percent" env:"MAINTENANCE_REINDEX_BLOAT_PERCENT"`
+
+	SCIMGroupRoles string `key:"scim_group_roles" env:"SCIM_GROUP_ROLES"`
+
+	// WorkloadIdentityFile lists the OIDC issuers whose ID tokens are
+	// accepted in place of API tokens
+	WorkloadIdentityFile string `key:"workload_identity_file" env:"WORKLOAD_IDENTITY_FILE"`
+
+	ChangeApproval       bool          `key:"change_approval" env:"CHANGE_APPROVAL"`
+	ChangeApprovalExpiry time.Duration `key:"change_approval_expiry" env:"CHANGE_APPROVAL_EXPIRY"`
+	ChangeApprovers      string        `key:"change_approvers" env:"CHANGE_APPROVERS"`
+
+	RedactEmails  bool   `key:"redact_emails" env:"REDACT_EMAILS"`
+	RedactSecrets bool   `key:"redact_secrets" env:"REDACT_SECRETS"`
+	RedactPaths   string `key:"redact_paths" env:"REDACT_PATHS"`
+
+	PayloadEncryptionKeys     string `key:"payload_encryption_keys" env:"PAYLOAD_ENCRYPTION_KEYS"`
+	PayloadEncryptionKeysFile string `key:"payload_encryption_keys_file" env:"PAYLOAD_ENCRYPTION_KEYS_FILE"`
+
+	EventChainSigningKey         string        `key:"event_chain_signing_key" env:"EVENT_CHAIN_SIGNING_KEY"`
+	EventChainSigningKeyFile     string        `key:"event_chain_signing_key_file" env:"EVENT_CHAIN_SIGNING_KEY_FILE"`
+	EventChainInterval           time.Duration `key:"event_chain_interval" env:"EVENT_CHAIN_INTERVAL"`
+	EventChainCheckpointInterval time.Duration `key:"event_chain_checkpoint_interval" env:"EVENT_CHAIN_CHECKPOINT_INTERVAL"`
+
+	GitHubAPIURL            string `key:"github_api_url" env:"GITHUB_API_URL"`
+	GitHubToken             string `key:"github_token" env:"GITHUB_TOKEN"`
+	GitHubAppID             int64  `key:"github_app_id" env:"GITHUB_APP_ID"`
+	GitHubAppInstallationID int64  `key:"github_app_installation_id" env:"GITHUB_APP_INSTALLATION_ID"`
+	GitHubAppPrivateKeyPath string `key:"github_app_private_key_path" env:"GITHUB_APP_PRIVATE_KEY_PATH"`
+	RepoConfigLint          bool   `key:"repo_config_lint" env:"REPO_CONFIG_LINT"`
+	PipelineChecks          bool   `key:"pipeline_checks" env:"PIPELINE_CHECKS"`
+	PathLabels              string `key:"path_labels" env:"PATH_LABELS"`
+	CommentCommands         string `key:"comment_commands" env:"COMMENT_COMMANDS"`
+	ReceiptEvents           string `key:"receipt_events" env:"RECEIPT_EVENTS"`
+	ReceiptKind             string `key:"receipt_kind" env:"RECEIPT_KIND"`
+	ReceiptRateLimit        int    `key:"receipt_rate_limit" env:"RECEIPT_RATE_LIMIT"`
+
+	GitHubIPAllowlist        bool          `key:"github_ip_allowlist" env:"GITHUB_IP_ALLOWLIST"`
+	GitHubIPAllowlistRefresh time.Duration `key:"github_ip_allowlist_refresh" env:"GITHUB_IP_ALLOWLIST_REFRESH"`
+	TrustedProxies           string        `key:"trusted_proxies" env:"TRUSTED_PROXIES"`
+
+	RateLimitPerIP       int `key:"rate_limit_per_ip" env:"RATE_LIMIT_PER_IP"`
+	RateLimitPerIPBurst  int `key:"rate_limit_per_ip_burst" env:"RATE_LIMIT_PER_IP_BURST"`
+	RateLimitGlobal      int `key:"rate_limit_global" env:"RATE_LIMIT_GLOBAL"`
+	RateLimitGlobalBurst int `key:"rate_limit_global_burst" env:"RATE_LIMIT_GLOBAL_BURST"`
+
+	ProcessorTimeout     time.Duration `key:"processor_timeout" env:"PROCESSOR_TIMEOUT"`
+	ProcessorConcurrency int           `key:"processor_concurrency" env:"PROCESSOR_CONCURRENCY"`
+	ProcessorLimits      string        `key:"processor_limits" env:"PROCESSOR_LIMITS"`
+
+	WorkQueueWorkers           int           `key:"work_queue_workers" env:"WORK_QUEUE_WORKERS"`
+	WorkQueueVisibilityTimeout time.Duration `key:"work_queue_visibility_timeout" env:"WORK_QUEUE_VISIBILITY_TIMEOUT"`
+	WorkQueueMaxAttempts       int           `key:"work_queue_max_attempts" env:"WORK_QUEUE_MAX_ATTEMPTS"`
+	WorkQueuePollInterval      time.Duration `key:"work_queue_poll_interval" env:"WORK_QUEUE_POLL_INTERVAL"`
+	ReadinessMaxQueueDepth     int           `key:"readiness_max_queue_depth" env:"READINESS_MAX_QUEUE_DEPTH"`
+
+	EventBatchSize  int           `key:"event_batch_size" env:"EVENT_BATCH_SIZE"`
+	EventBatchDelay time.Duration `key:"event_batch_delay" env:"EVENT_BATCH_DELAY"`
+
+	OutboxEnabled      bool          `key:"outbox_enabled" env:"OUTBOX_ENABLED"`
+	OutboxPollInterval time.Duration `key:"outbox_poll_interval" env:"OUTBOX_POLL_INTERVAL"`
+	OutboxRetention    time.Duration `key:"outbox_retention" env:"OUTBOX_RETENTION"`
+
+	DeadLetterDir           string        `key:"dead_letter_dir" env:"DEAD_LETTER_DIR"`
+	DeadLetterRetryInterval time.Duration `key:"dead_letter_retry_interval" env:"DEAD_LETTER_RETRY_INTERVAL"`
+
+	NATSURL             string `key:"nats_url" env:"NATS_URL"`
+	NATSSubjectTemplate string `key:"nats_subject_template" env:"NATS_SUBJECT_TEMPLATE"`
+	NATSStream          string `key:"nats_stream" env:"NATS_STREAM"`
+	NATSStreamSubjects  string `key:"nats_stream_subjects" env:"NATS_STREAM_SUBJECTS"`
+
+	AWSPublishARN string `key:"aws_publish_arn" env:"AWS_PUBLISH_ARN"`
+
+	SecurityAlertRoutes string `key:"security_alert_routes" env:"SECURITY_ALERT_ROUTES"`
+	SecurityAlertSLA    string `key:"security_alert_sla" env:"SECURITY_ALERT_SLA"`
+
+	AccessReviewDir      string        `key:"access_review_dir" env:"ACCESS_REVIEW_DIR"`
+	AccessReviewInterval time.Duration `key:"access_review_interval" env:"ACCESS_REVIEW_INTERVAL"`
+
+	DiscussionRoutes        string        `key:"discussion_routes" env:"DISCUSSION_ROUTES"`
+	ProjectColumnRoutes     string        `key:"project_column_routes" env:"PROJECT_COLUMN_ROUTES"`
+	DocsRoutes              string        `key:"docs_routes" env:"DOCS_ROUTES"`
+	CommunityDigestRoutes   string        `key:"community_digest_routes" env:"COMMUNITY_DIGEST_ROUTES"`
+	CommunityDigestInterval time.Duration `key:"community_digest_interval" env:"COMMUNITY_DIGEST_INTERVAL"`
+	RepoHealthTargets       string        `key:"repo_health_targets" env:"REPO_HEALTH_TARGETS"`
+
+	StatsPrivacyEpsilon string `key:"stats_privacy_epsilon" env:"STATS_PRIVACY_EPSILON"`
+	StatsMinCount       int    `key:"stats_min_count" env:"STATS_MIN_COUNT"`
+
+	RulesFile           string        `key:"rules_file" env:"RULES_FILE"`
+	RulesReloadInterval time.Duration `key:"rules_reload_interval" env:"RULES_RELOAD_INTERVAL"`
+
+	SlackWebhookURL string `key:"slack_webhook_url" env:"SLACK_WEBHOOK_URL"`
+	SlackBotToken   string `key:"slack_bot_token" env:"SLACK_BOT_TOKEN"`
+	SlackChannel    string `key:"slack_channel" env:"SLACK_CHANNEL"`
+	SlackCondition  string `key:"slack_condition" env:"SLACK_CONDITION"`
+	SlackTemplate   string `key:"slack_template" env:"SLACK_TEMPLATE"`
+
+	SMTPHost     string `key:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     int    `key:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `key:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `key:"smtp_password" env:"SMTP_PASSWORD"`
+	SMTPFrom     string `key:"smtp_from" env:"SMTP_FROM"`
+	SMTPTLS      string `key:"smtp_tls" env:"SMTP_TLS"`
+
+	RetentionPolicy    string        `key:"retention_policy" env:"RETENTION_POLICY"`
+	RetentionMode      string        `key:"retention_mode" env:"RETENTION_MODE"`
+	RetentionInterval  time.Duration `key:"retention_interval" env:"RETENTION_INTERVAL"`
+	RetentionBatchSize int           `key:"retention_batch_size" env:"RETENTION_BATCH_SIZE"`
+
+	UsageAlertGrowthPercent   int           `key:"usage_alert_growth_percent" env:"USAGE_ALERT_GROWTH_PERCENT"`
+	UsageAlertMinSharePercent int           `key:"usage_alert_min_share_percent" env:"USAGE_ALERT_MIN_SHARE_PERCENT"`
+	UsageAlertInterval        time.Duration `key:"usage_alert_interval" env:"USAGE_ALERT_INTERVAL"`
+
+	CapacityHistoryDays   int           `key:"capacity_history_days" env:"CAPACITY_HISTORY_DAYS"`
+	CapacityAlertDays     int           `key:"capacity_alert_days" env:"CAPACITY_ALERT_DAYS"`
+	CapacityDiskLimitGB   int           `key:"capacity_disk_limit_gb" env:"CAPACITY_DISK_LIMIT_GB"`
+	CapacityMonthlyBudget int           `key:"capacity_monthly_budget" env:"CAPACITY_MONTHLY_BUDGET"`
+	CapacityPricePerGB    string        `key:"capacity_price_per_gb" env:"CAPACITY_PRICE_PER_GB"`
+	CapacityCheckInterval time.Duration `key:"capacity_check_interval" env:"CAPACITY_CHECK_INTERVAL"`
+
+	StatusPageEnabled bool   `key:"status_page_enabled" env:"STATUS_PAGE_ENABLED"`
+	StatusPageTitle   string `key:"status_page_title" env:"STATUS_PAGE_TITLE"`
+
+	MetricsPushURL      string        `key:"metrics_push_url" env:"METRICS_PUSH_URL"`
+	MetricsPushInterval time.Duration `key:"metrics_push_interval" env:"METRICS_PUSH_INTERVAL"`
+	MetricsPushWindow   time.Duration `key:"metrics_push_window" env:"METRICS_PUSH_WINDOW"`
+
+	OTelEndpoint    string `key:"otel_exporter_otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTelHeaders     string `key:"otel_exporter_otlp_headers" env:"OTEL_EXPORTER_OTLP_HEADERS"`
+	OTelServiceName string `key:"otel_service_name" env:"OTEL_SERVICE_NAME"`
+
+	WSClientBuffer int `key:"ws_client_buffer" env:"WS_CLIENT_BUFFER"`
+
+	OutboundLogSize      int `key:"outbound_log_size" env:"OUTBOUND_LOG_SIZE"`
+	OutboundLogBodyBytes int `key:"outbound_log_body_bytes" env:"OUTBOUND_LOG_BODY_BYTES"`
+
+	// InsecureDev is set by serve -insecure-dev to accept unsigned
+	// deliveries in strict signature mode; it has no key or variable
+	InsecureDev bool
+
+	// explicit records the environment variable names of fields set by the
+	// configuration file or the environment rather than by defaults
+	explicit map[string]bool
+}
+
+// Default returns the configuration used when nothing is set
+func Default() *Config {
+	return &Config{
+		Port:                             "8080",
+		AdminUsername:                    "admin",
+		GitHubAPIURL:                     github.DefaultBaseURL,
+		ReceiptKind:                      receipt.KindCheck,
+		ReceiptRateLimit:                 receipt.DefaultRateLimit,
+		TLSAutocertCacheDir:              "autocert",
+		GitHubIPAllowlistRefresh:         time.Hour,
+		RateLimitPerIPBurst:              ratelimit.DefaultPerIPBurst,
+		RateLimitGlobalBurst:             ratelimit.DefaultGlobalBurst,
+		MaxBodyBytes:                     DefaultMaxBodyBytes,
+		ProcessorTimeout:                 pipeline.DefaultTimeout,
+		ProcessorConcurrency:             pipeline.DefaultConcurrency,
+		WorkQueueWorkers:                 workqueue.DefaultWorkers,
+		WorkQueueVisibilityTimeout:       workqueue.DefaultVisibilityTimeout,
+		WorkQueueMaxAttempts:             workqueue.DefaultMaxAttempts,
+		WorkQueuePollInterval:            workqueue.DefaultPollInterval,
+		ReadinessMaxQueueDepth:           10000,
+		EventBatchDelay:                  database.DefaultBatchDelay,
+		OutboxPollInterval:               outbox.DefaultPollInterval,
+		OutboxRetention:                  outbox.DefaultRetention,
+		AuthzCacheTTL:                    authz.DefaultCacheTTL,
+		QueryCacheTTL:                    querycache.DefaultTTL,
+		IndexAdvisorSlowQuery:            indexadvisor.DefaultSlowQuery,
+		MaintenanceVacuumDeadPercent:     maintenance.DefaultVacuumDeadPercent,
+		MaintenanceAnalyzeChangedPercent: maintenance.DefaultAnalyzeChangedPercent,
+		MaintenanceReindexBloatPercent:   maintenance.DefaultReindexBloatPercent,
+		ChangeApprovalExpiry:             approval.DefaultExpiry,
+		DeadLetterDir:                    deadletter.DefaultDir,
+		DeadLetterRetryInterval:          time.Minute,
+		AccessReviewInterval:             7 * 24 * time.Hour,
+		CommunityDigestInterval:          7 * 24 * time.Hour,
+		RulesReloadInterval:              time.Minute,
+		RetentionInterval:                time.Hour,
+		EventChainInterval:               10 * time.Second,
+		SMTPPort:                         email.DefaultPort,
+		SMTPTLS:                          email.TLSStartTLS,
+		EventChainCheckpointInterval:     time.Hour,
+		RetentionBatchSize:               retention.DefaultBatchSize,
+		UsageAlertMinSharePercent:        5,
+		UsageAlertInterval:               time.Hour,
+		CapacityHistoryDays:              capacity.DefaultHistoryDays,
+		CapacityAlertDays:                capacity.DefaultAlertDays,
+		CapacityCheckInterval:            time.Hour,
+		StatusPageTitle:                  "choochoo status",
+		MetricsPushInterval:              time.Minute,
+		MetricsPushWindow:                24 * time.Hour,
+		OTelServiceName:                  tracing.DefaultServiceName,
+		WSClientBuffer:                   stream.DefaultBuffer,
+		OutboundLogSize:                  outbound.DefaultSize,
+		OutboundLogBodyBytes:             outbound.DefaultBodyBytes,
+		explicit:                         make(map[string]bool),
+	}
+}
+
+// Load builds the configuration from defaults, the optional file at path
+// (YAML or TOML, by extension) and environment variable overrides, in that
+// order, and validates the result
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			field, ok := fieldByKey(key)
+			if !ok {
+				return nil, fmt.Errorf("%s: unknown setting %q", path, key)
+			}
+			if _, nested := value.(map[string]interface{}); nested {
+				return nil, fmt.Errorf("%s: %s must be a value, not a table", path, key)
+			}
+			if err := cfg.set(field, stringValue(value)); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+
+	for _, field := range fields() {
+		if value, ok := os.LookupEnv(field.Tag.Get("env")); ok && value != "" {
+			if err := cfg.set(field, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// readFile decodes a YAML or TOML file into a flat map of settings
+func readFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("unsupported config file %s: use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// stringValue converts a decoded file value to the string form used by
+// environment variables, joining lists with commas
+func stringValue(value interface{}) string {
+	if list, ok := value.([]interface{}); ok {
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// Fill sets fields that were not set by the file or environment from
+// settings stored with choochooctl, keyed by environment variable name
+func (c *Config) Fill(stored map[string]string) error {
+	for _, field := range fields() {
+		name := field.Tag.Get("env")
+		value, ok := stored[name]
+		if !ok || c.explicit[name] {
+			continue
+		}
+		if err := c.set(field, value); err != nil {
+			return fmt.Errorf("stored setting: %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateServe checks the settings only the server needs. In strict
+// signature mode the server refuses to start without a webhook secret,
+// unless InsecureDev explicitly accepts unsigned deliveries for development.
+// It runs again once stored settings are filled in.
+func (c *Config) ValidateServe() error {
+	if c.RequireSignature && len(c.WebhookSecrets()) == 0 && !c.InsecureDev {
+		return fmt.Errorf("CHOOCHOO_REQUIRE_SIGNATURE requires GITHUB_WEBHOOK_SECRET; pass -insecure-dev to accept unsigned deliveries in development")
+	}
+	return nil
+}
+
+// Validate checks that the configuration is usable
+func (c *Config) Validate() error {
+	port, err := strconv.Atoi(c.Port)
+	if err != nil || port <= 0 || port > 65535 {
+		return fmt.Errorf("invalid PORT %q", c.Port)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.TLSCertFile != "" && c.TLSAutocertHosts != "" {
+		return fmt.Errorf("set either TLS_CERT_FILE or TLS_AUTOCERT_HOSTS, not both")
+	}
+	if c.TLSRedirectPort != "" {
+		if c.TLSCertFile == "" && c.TLSAutocertHosts == "" {
+			return fmt.Errorf("TLS_REDIRECT_PORT requires TLS_CERT_FILE or TLS_AUTOCERT_HOSTS")
+		}
+		redirect, err := strconv.Atoi(c.TLSRedirectPort)
+		if err != nil || redirect <= 0 || redirect > 65535 || c.TLSRedirectPort == c.Port {
+			return fmt.Errorf("invalid TLS_REDIRECT_PORT %q", c.TLSRedirectPort)
+		}
+	}
+	if _, err := c.Relays(); err != nil {
+		return err
+	}
+	if c.GRPCPort != "" {
+		grpcPort, err := strconv.Atoi(c.GRPCPort)
+		if err != nil || grpcPort <= 0 || grpcPort > 65535 || c.GRPCPort == c.Port || c.GRPCPort == c.TLSRedirectPort {
+			return fmt.Errorf("invalid GRPC_PORT %q", c.GRPCPort)
+		}
+	}
+	if _, err := ipallow.ParsePrefixes(c.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	if c.MaxBodyBytes <= 0 {
+		return fmt.Errorf("WEBHOOK_MAX_BODY_BYTES must be positive")
+	}
+	if c.RetentionBatchSize <= 0 || c.WSClientBuffer <= 0 || c.WorkQueueMaxAttempts <= 0 || c.ProcessorConcurrency <= 0 {
+		return fmt.Errorf("RETENTION_BATCH_SIZE, WS_CLIENT_BUFFER, WORK_QUEUE_MAX_ATTEMPTS and PROCESSOR_CONCURRENCY must be positive")
+	}
+	if _, err := pipeline.ParseLimits(c.ProcessorLimits, c.ProcessorDefaults()); err != nil {
+		return fmt.Errorf("invalid PROCESSOR_LIMITS: %w", err)
+	}
+	if c.WorkQueueWorkers < 0 || c.ReadinessMaxQueueDepth < 0 || c.EventBatchSize < 0 {
+		return fmt.Errorf("WORK_QUEUE_WORKERS, READINESS_MAX_QUEUE_DEPTH and EVENT_BATCH_SIZE must not be negative")
+	}
+	if c.OutboundLogSize < 0 || c.OutboundLogBodyBytes < 0 {
+		return fmt.Errorf("OUTBOUND_LOG_SIZE and OUTBOUND_LOG_BODY_BYTES must not be negative")
+	}
+	if c.RateLimitPerIP < 0 || c.RateLimitGlobal < 0 {
+		return fmt.Errorf("RATE_LIMIT_PER_IP and RATE_LIMIT_GLOBAL must not be negative")
+	}
+	if c.UsageAlertGrowthPercent < 0 || c.UsageAlertMinSharePercent < 0 || c.UsageAlertMinSharePercent > 100 {
+		return fmt.Errorf("USAGE_ALERT_GROWTH_PERCENT must not be negative and USAGE_ALERT_MIN_SHARE_PERCENT must be between 0 and 100")
+	}
+	if c.CapacityHistoryDays < 7 || c.CapacityAlertDays <= 0 {
+		return fmt.Errorf("CAPACITY_HISTORY_DAYS must be at least 7 and CAPACITY_ALERT_DAYS positive")
+	}
+	if c.CapacityDiskLimitGB < 0 || c.CapacityMonthlyBudget < 0 {
+		return fmt.Errorf("CAPACITY_DISK_LIMIT_GB and CAPACITY_MONTHLY_BUDGET must not be negative")
+	}
+	if _, err := capacity.ParsePrice(c.CapacityPricePerGB); err != nil {
+		return fmt.Errorf("invalid CAPACITY_PRICE_PER_GB: %w", err)
+	}
+	if c.StatusPageEnabled && strings.TrimSpace(c.StatusPageTitle) == "" {
+		return fmt.Errorf("STATUS_PAGE_TITLE must not be empty when STATUS_PAGE_ENABLED is set")
+	}
+	if c.CapacityMonthlyBudget > 0 && c.CapacityPricePerGB == "" {
+		return fmt.Errorf("CAPACITY_MONTHLY_BUDGET requires CAPACITY_PRICE_PER_GB")
+	}
+	if c.MetricsPushURL != "" {
+		if _, err := metrics.NewSink(c.MetricsPushURL); err != nil {
+			return fmt.Errorf("invalid METRICS_PUSH_URL: %w", err)
+		}
+	}
+	if c.OTelEndpoint != "" {
+		u, err := url.Parse(c.OTelEndpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid OTEL_EXPORTER_OTLP_ENDPOINT %q", c.OTelEndpoint)
+		}
+	}
+	if _, err := tracing.ParseHeaders(c.OTelHeaders); err != nil {
+		return fmt.Errorf("invalid OTEL_EXPORTER_OTLP_HEADERS: %w", err)
+	}
+	if c.WorkloadIdentityFile != "" {
+		if _, err := workload.LoadFile(c.WorkloadIdentityFile); err != nil {
+			return fmt.Errorf("invalid WORKLOAD_IDENTITY_FILE: %w", err)
+		}
+	}
+	if c.RulesFile != "" {
+		if _, err := rules.LoadFile(c.RulesFile); err != nil {
+			return fmt.Errorf("invalid RULES_FILE: %w", err)
+		}
+	}
+	if _, err := redact.ParsePaths(c.RedactPaths); err != nil {
+		return fmt.Errorf("invalid REDACT_PATHS: %w", err)
+	}
+	if c.PayloadEncryptionKeys != "" && c.PayloadEncryptionKeysFile != "" {
+		return fmt.Errorf("set either PAYLOAD_ENCRYPTION_KEYS or PAYLOAD_ENCRYPTION_KEYS_FILE, not both")
+	}
+	if _, err := c.PayloadKeys(); err != nil {
+		return err
+	}
+	if c.EventChainSigningKey != "" && c.EventChainSigningKeyFile != "" {
+		return fmt.Errorf("set either EVENT_CHAIN_SIGNING_KEY or EVENT_CHAIN_SIGNING_KEY_FILE, not both")
+	}
+	if _, err := c.EventChainKey(); err != nil {
+		return err
+	}
+	if c.RateLimitPerIPBurst <= 0 || c.RateLimitGlobalBurst <= 0 {
+		return fmt.Errorf("RATE_LIMIT_PER_IP_BURST and RATE_LIMIT_GLOBAL_BURST must be positive")
+	}
+	for _, interval := range []time.Duration{c.DeadLetterRetryInterval, c.AccessReviewInterval, c.CommunityDigestInterval, c.RetentionInterval, c.GitHubIPAllowlistRefresh, c.WorkQueueVisibilityTimeout, c.WorkQueuePollInterval, c.ProcessorTimeout, c.UsageAlertInterval, c.CapacityCheckInterval, c.MetricsPushInterval, c.MetricsPushWindow, c.EventBatchDelay, c.ChangeApprovalExpiry, c.RulesReloadInterval, c.EventChainInterval, c.EventChainCheckpointInterval, c.OutboxPollInterval, c.OutboxRetention} {
+		if interval <= 0 {
+			return fmt.Errorf("intervals must be positive durations")
+		}
+	}
+
+	if c.AWSPublishARN != "" {
+		if _, err := forwarder.ParseAWSARN(c.AWSPublishARN); err != nil {
+			return fmt.Errorf("invalid AWS_PUBLISH_ARN: %w", err)
+		}
+	}
+	if c.NATSURL == "" && (c.NATSStream != "" || c.NATSStreamSubjects != "" || c.NATSSubjectTemplate != "") {
+		return fmt.Errorf("NATS settings require NATS_URL")
+	}
+	if _, err := c.Slack(); err != nil {
+		return err
+	}
+	if c.AuthzCacheTTL < 0 {
+		return fmt.Errorf("AUTHZ_CACHE_TTL must not be negative")
+	}
+	if c.QueryCacheTTL < 0 {
+		return fmt.Errorf("QUERY_CACHE_TTL must not be negative")
+	}
+	if c.IndexAdvisorSlowQuery < 0 {
+		return fmt.Errorf("INDEX_ADVISOR_SLOW_QUERY must not be negative")
+	}
+	if c.IndexAdvisorAutoCreate && c.IndexAdvisorSlowQuery == 0 {
+		return fmt.Errorf("INDEX_ADVISOR_AUTO_CREATE requires INDEX_ADVISOR_SLOW_QUERY")
+	}
+	if c.MaintenanceInterval < 0 {
+		return fmt.Errorf("MAINTENANCE_INTERVAL must not be negative")
+	}
+	if _, err := maintenance.ParseWindow(c.MaintenanceWindow); err != nil {
+		return err
+	}
+	for _, percent := range []int{c.MaintenanceVacuumDeadPercent, c.MaintenanceAnalyzeChangedPercent, c.MaintenanceReindexBloatPercent} {
+		if percent < 0 || percent > 100 {
+			return fmt.Errorf("MAINTENANCE_VACUUM_DEAD_PERCENT, MAINTENANCE_ANALYZE_CHANGED_PERCENT and MAINTENANCE_REINDEX_BLOAT_PERCENT must be between 0 and 100")
+		}
+	}
+	if c.MaintenanceInterval > 0 && (c.DatabaseURL == "" || database.IsSQLite(c.DatabaseURL)) {
+		return fmt.Errorf("MAINTENANCE_INTERVAL requires a PostgreSQL DATABASE_URL")
+	}
+	if _, err := c.Authorization(); err != nil {
+		return err
+	}
+	if _, err := c.SCIMRoles(); err != nil {
+		return err
+	}
+	if _, err := c.Mailer(); err != nil {
+		return err
+	}
+	if _, err := privacy.ParseEpsilon(c.StatsPrivacyEpsilon); err != nil {
+		return fmt.Errorf("invalid STATS_PRIVACY_EPSILON: %w", err)
+	}
+	if c.StatsMinCount < 0 {
+		return fmt.Errorf("STATS_MIN_COUNT must not be negative")
+	}
+	app := c.GitHubAppID != 0 || c.GitHubAppInstallationID != 0 || c.GitHubAppPrivateKeyPath != ""
+	if app && (c.GitHubAppID == 0 || c.GitHubAppInstallationID == 0 || c.GitHubAppPrivateKeyPath == "") {
+		return fmt.Errorf("GITHUB_APP_ID, GITHUB_APP_INSTALLATION_ID and GITHUB_APP_PRIVATE_KEY_PATH must be set together")
+	}
+	if app && c.GitHubToken != "" {
+		return fmt.Errorf("set either GITHUB_TOKEN or the GITHUB_APP_* settings, not both")
+	}
+	if c.RepoConfigLint && !app {
+		// Only GitHub Apps can create check runs
+		return fmt.Errorf("REPO_CONFIG_LINT requires the GITHUB_APP_* settings")
+	}
+	if c.PipelineChecks && !app {
+		// Only GitHub Apps can create check runs
+		return fmt.Errorf("PIPELINE_CHECKS requires the GITHUB_APP_* settings")
+	}
+	if _, err := pathlabels.ParseRules(c.PathLabels); err != nil {
+		return fmt.Errorf("invalid PATH_LABELS: %w", err)
+	}
+	if c.PathLabels != "" && !app && c.GitHubToken == "" {
+		return fmt.Errorf("PATH_LABELS requires GITHUB_TOKEN or the GITHUB_APP_* settings")
+	}
+	if _, err := chatops.ParsePolicy(c.CommentCommands); err != nil {
+		return fmt.Errorf("invalid COMMENT_COMMANDS: %w", err)
+	}
+	if c.CommentCommands != "" && !app && c.GitHubToken == "" {
+		return fmt.Errorf("COMMENT_COMMANDS requires GITHUB_TOKEN or the GITHUB_APP_* settings")
+	}
+	receipts, err := c.Receipts()
+	if err != nil {
+		return err
+	}
+	if c.ReceiptKind != receipt.KindCheck && c.ReceiptKind != receipt.KindComment {
+		return fmt.Errorf("RECEIPT_KIND must be %q or %q", receipt.KindCheck, receipt.KindComment)
+	}
+	if c.ReceiptRateLimit <= 0 {
+		return fmt.Errorf("RECEIPT_RATE_LIMIT must be positive")
+	}
+	if len(receipts) > 0 && c.ReceiptKind == receipt.KindCheck && !app {
+		// Only GitHub Apps can create check runs
+		return fmt.Errorf("RECEIPT_KIND=check requires the GITHUB_APP_* settings; use RECEIPT_KIND=comment with GITHUB_TOKEN")
+	}
+	if len(receipts) > 0 && !app && c.GitHubToken == "" {
+		return fmt.Errorf("RECEIPT_EVENTS requires GITHUB_TOKEN or the GITHUB_APP_* settings")
+	}
+	if c.DatabaseURL == "" && (c.RetentionPolicy != "" || c.AccessReviewDir != "" || c.UsageAlertGrowthPercent > 0 || c.MetricsPushURL != "") {
+		return fmt.Errorf("RETENTION_POLICY, ACCESS_REVIEW_DIR, USAGE_ALERT_GROWTH_PERCENT and METRICS_PUSH_URL require DATABASE_URL")
+	}
+	if database.IsSQLite(c.DatabaseURL) && (c.RetentionPolicy != "" || c.AccessReviewDir != "" || c.UsageAlertGrowthPercent > 0 || c.MetricsPushURL != "") {
+		return fmt.Errorf("RETENTION_POLICY, ACCESS_REVIEW_DIR, USAGE_ALERT_GROWTH_PERCENT and METRICS_PUSH_URL require a PostgreSQL DATABASE_URL")
+	}
+
+	thresholds := c.CapacityDiskLimitGB > 0 || c.CapacityMonthlyBudget > 0
+	if thresholds && (c.DatabaseURL == "" || database.IsSQLite(c.DatabaseURL)) {
+		return fmt.Errorf("CAPACITY_DISK_LIMIT_GB and CAPACITY_MONTHLY_BUDGET require a PostgreSQL DATABASE_URL")
+	}
+	if c.EventBatchSize > 0 && (c.DatabaseURL == "" || database.IsSQLite(c.DatabaseURL)) {
+		return fmt.Errorf("EVENT_BATCH_SIZE requires a PostgreSQL DATABASE_URL")
+	}
+	if c.OutboxEnabled && (c.DatabaseURL == "" || database.IsSQLite(c.DatabaseURL)) {
+		return fmt.Errorf("OUTBOX_ENABLED requires a PostgreSQL DATABASE_URL")
+	}
+	if c.ChangeApproval && (c.DatabaseURL == "" || database.IsSQLite(c.DatabaseURL)) {
+		return fmt.Errorf("CHANGE_APPROVAL requires a PostgreSQL DATABASE_URL")
+	}
+	if c.SCIMGroupRoles != "" && (c.DatabaseURL == "" || database.IsSQLite(c.DatabaseURL)) {
+		return fmt.Errorf("SCIM_GROUP_ROLES requires a PostgreSQL DATABASE_URL")
+	}
+	chained := c.EventChainSigningKey != "" || c.EventChainSigningKeyFile != ""
+	if chained && (c.DatabaseURL == "" || database.IsSQLite(c.DatabaseURL)) {
+		return fmt.Errorf("EVENT_CHAIN_SIGNING_KEY requires a PostgreSQL DATABASE_URL")
+	}
+	if chained && c.RetentionPolicy != "" && c.RetentionMode != retention.ModeArchive {
+		// Deleted events would show up as tampering
+		return fmt.Errorf("EVENT_CHAIN_SIGNING_KEY requires RETENTION_MODE=archive with a RETENTION_POLICY")
+	}
+	if c.ChangeApprovers != "" && !c.ChangeApproval {
+		return fmt.Errorf("CHANGE_APPROVERS requires CHANGE_APPROVAL")
+	}
+
+	// Routes and policies are checked with the parsers the server uses
+	managed := make(map[string]string)
+	for _, name := range settings.Names() {
+		if value := c.String(name); value != "" {
+			managed[name] = value
+		}
+	}
+	return settings.Validate(managed)
+}
+
+// MaintenancePolicy returns the policy of table maintenance
+func (c *Config) MaintenancePolicy() maintenance.Policy {
+	window, _ := maintenance.ParseWindow(c.MaintenanceWindow)
+	return maintenance.Policy{
+		VacuumDeadPercent:     c.MaintenanceVacuumDeadPercent,
+		AnalyzeChangedPercent: c.MaintenanceAnalyzeChangedPercent,
+		ReindexBloatPercent:   c.MaintenanceReindexBloatPercent,
+		Window:                window,
+	}
+}
+
+// CapacityThresholds returns the thresholds of the capacity forecast
+func (c *Config) CapacityThresholds() capacity.Thresholds {
+	price, _ := capacity.ParsePrice(c.CapacityPricePerGB)
+	return capacity.Thresholds{
+		DiskLimitBytes: int64(c.CapacityDiskLimitGB) * capacity.GB,
+		MonthlyBudget:  float64(c.CapacityMonthlyBudget),
+		PricePerGB:     price,
+		AlertDays:      c.CapacityAlertDays,
+	}
+}
+
+// ProcessorDefaults returns the limits of processors without an entry in
+// PROCESSOR_LIMITS
+func (c *Config) ProcessorDefaults() pipeline.Limits {
+	return pipeline.Limits{Timeout: c.ProcessorTimeout, Concurrency: c.ProcessorConcurrency}
+}
+
+// PayloadKeys returns the keys stored payloads are encrypted with, or nil if
+// payloads are stored in plaintext
+func (c *Config) PayloadKeys() (*encryption.Keyring, error) {
+	switch {
+	case c.PayloadEncryptionKeys != "":
+		keys, err := encryption.ParseKeys(c.PayloadEncryptionKeys)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PAYLOAD_ENCRYPTION_KEYS: %w", err)
+		}
+		return keys, nil
+	case c.PayloadEncryptionKeysFile != "":
+		keys, err := encryption.ReadKeys(c.PayloadEncryptionKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PAYLOAD_ENCRYPTION_KEYS_FILE: %w", err)
+		}
+		return keys, nil
+	}
+	return nil, nil
+}
+
+// EventChainKey returns the key event chain checkpoints are signed with, or
+// nil if stored events are not chained
+func (c *Config) EventChainKey() (ed25519.PrivateKey, error) {
+	switch {
+	case c.EventChainSigningKey != "":
+		key, err := eventchain.ParseKey(c.EventChainSigningKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EVENT_CHAIN_SIGNING_KEY: %w", err)
+		}
+		return key, nil
+	case c.EventChainSigningKeyFile != "":
+		key, err := eventchain.ReadKey(c.EventChainSigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EVENT_CHAIN_SIGNING_KEY_FILE: %w", err)
+		}
+		return key, nil
+	}
+	return nil, nil
+}
+
+// Slack returns the forwarder posting the events matching SLACK_CONDITION to
+// the Slack channel of the configuration, or nil if there is none
+func (c *Config) Slack() (forwarder.Forwarder, error) {
+	if c.SlackWebhookURL == "" && c.SlackChannel == "" {
+		if c.SlackCondition != "" || c.SlackTemplate != "" {
+			return nil, fmt.Errorf("SLACK_CONDITION and SLACK_TEMPLATE require SLACK_WEBHOOK_URL or SLACK_CHANNEL")
+		}
+		return nil, nil
+	}
+	if c.SlackWebhookURL != "" && c.SlackChannel != "" {
+		return nil, fmt.Errorf("set only one of SLACK_WEBHOOK_URL and SLACK_CHANNEL")
+	}
+	template, err := chat.ParseTemplate(c.SlackTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SLACK_TEMPLATE: %w", err)
+	}
+
+	var slack *chat.Slack
+	if c.SlackWebhookURL != "" {
+		if slack, err = chat.NewSlackWebhook(c.SlackWebhookURL, template); err != nil {
+			return nil, fmt.Errorf("invalid SLACK_WEBHOOK_URL: %w", err)
+		}
+	} else if slack, err = chat.NewSlackBot(c.SlackBotToken, c.SlackChannel, template); err != nil {
+		return nil, fmt.Errorf("SLACK_CHANNEL requires SLACK_BOT_TOKEN")
+	}
+	filter, err := rules.NewFilter(c.SlackCondition, slack)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SLACK_CONDITION: %w", err)
+	}
+	return filter, nil
+}
+
+// Mailer returns the mailer of the SMTP_* settings, which email rule actions
+// send with, or nil if SMTP_HOST is not set
+func (c *Config) Mailer() (*email.Mailer, error) {
+	if c.SMTPHost == "" {
+		if c.SMTPUsername != "" || c.SMTPPassword != "" || c.SMTPFrom != "" {
+			return nil, fmt.Errorf("SMTP settings require SMTP_HOST")
+		}
+		return nil, nil
+	}
+	mailer, err := email.NewMailer(email.Config{
+		Host:     c.SMTPHost,
+		Port:     c.SMTPPort,
+		Username: c.SMTPUsername,
+		Password: c.SMTPPassword,
+		From:     c.SMTPFrom,
+		TLS:      c.SMTPTLS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP settings: %w", err)
+	}
+	return mailer, nil
+}
+
+// Authorization returns the policy deciding which scopes API and dashboard
+// requests may use, or nil to use the scopes of their tokens
+func (c *Config) Authorization() (apitoken.Policy, error) {
+	policy, err := authz.New(c.AuthzOPAURL, c.AuthzCondition, c.AuthzCacheTTL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTHZ_OPA_URL or AUTHZ_CONDITION: %w", err)
+	}
+	return policy, nil
+}
+
+// SCIMRoles returns the scopes of the groups provisioned over SCIM, keyed by
+// lowercased group name, or nil if SCIM provisioning is disabled
+func (c *Config) SCIMRoles() (map[string]apitoken.Scope, error) {
+	if c.SCIMGroupRoles == "" {
+		return nil, nil
+	}
+	roles, err := scim.ParseGroupRoles(c.SCIMGroupRoles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SCIM_GROUP_ROLES: %w", err)
+	}
+	return roles, nil
+}
+
+// Relays returns the secrets of the relays deliveries are accepted from, by
+// name, or nil if there are none
+func (c *Config) Relays() (map[string]string, error) {
+	secrets, err := relay.ParseSecrets(c.WebhookRelaySecrets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_RELAY_SECRETS: %w", err)
+	}
+	if len(secrets) == 0 {
+		return nil, nil
+	}
+	return secrets, nil
+}
+
+// Receipts returns the events that get processing receipts, or nil if
+// receipts are disabled
+func (c *Config) Receipts() ([]string, error) {
+	events, err := receipt.ParseEvents(c.ReceiptEvents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RECEIPT_EVENTS: %w", err)
+	}
+	return events, nil
+}
+
+// StatsPrivacy returns the policy protecting the activity stats served to
+// tokens with only the stats scope, or nil if there is none
+func (c *Config) StatsPrivacy() (*privacy.Policy, error) {
+	epsilon, err := privacy.ParseEpsilon(c.StatsPrivacyEpsilon)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STATS_PRIVACY_EPSILON: %w", err)
+	}
+	return privacy.NewPolicy(epsilon, int64(c.StatsMinCount))
+}
+
+// WebhookSecrets returns the accepted webhook secrets. GITHUB_WEBHOOK_SECRET
+// may list several, comma-separated, so the secret can be rotated.
+func (c *Config) WebhookSecrets() []string {
+	return ParseSecrets(c.WebhookSecret)
+}
+
+// ParseSecrets splits a comma-separated list of webhook secrets, ignoring
+// empty entries
+func ParseSecrets(list string) []string {
+	var secrets []string
+	for _, secret := range strings.Split(list, ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// Tracing returns the OTLP exporter configuration
+func (c *Config) Tracing() tracing.Config {
+	headers, _ := tracing.ParseHeaders(c.OTelHeaders)
+	return tracing.Config{Endpoint: c.OTelEndpoint, Headers: headers, ServiceName: c.OTelServiceName}
+}
+
+// String returns the value of a field by environment variable name in its
+// environment variable form
+func (c *Config) String(name string) string {
+	for _, field := range fields() {
+		if field.Tag.Get("env") != name {
+			continue
+		}
+		value := reflect.ValueOf(c).Elem().FieldByIndex(field.Index)
+		if d, ok := value.Interface().(time.Duration); ok {
+			return d.String()
+		}
+		return fmt.Sprint(value.Interface())
+	}
+	return ""
+}
+
+// set parses a value into a field and marks the field as explicitly set
+func (c *Config) set(field reflect.StructField, value string) error {
+	name := field.Tag.Get("env")
+	target := reflect.ValueOf(c).Elem().FieldByIndex(field.Index)
+
+	switch target.Interface().(type) {
+	case string:
+		target.SetString(value)
+	case int, int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: not a number", name, value)
+		}
+		target.SetInt(n)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: not a boolean", name, value)
+		}
+		target.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+		target.SetInt(int64(d))
+	}
+	c.explicit[name] = true
+	return nil
+}
+
+// fields lists the configurable fields of Config
+func fields() []reflect.StructField {
+	t := reflect.TypeOf(Config{})
+	var result []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.Tag.Get("env") != "" {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+// fieldByKey finds a field by its configuration file key
+func fieldByKey(key string) (reflect.StructField, bool) {
+	for _, field := range fields() {
+		if field.Tag.Get("key") == key {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile writes a config file into a temporary directory
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+// TestLoad_Defaults tests the configuration without a file
+func TestLoad_Defaults(t *testing.T) {
+	t.Setenv("PORT", "")
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Port != "8080" || cfg.MaxBodyBytes != DefaultMaxBodyBytes || cfg.RetentionInterval != time.Hour {
+		t.Errorf("Unexpected defaults: %+v", cfg)
+	}
+}
+
+// TestLoad_YAML tests loading a YAML file, including lists
+func TestLoad_YAML(t *testing.T) {
+	path := writeFile(t, "choochoo.yaml", `
+port: 9000
+database_url: postgres://localhost/choochoo
+retention_policy: push=30d,*=90d
+retention_interval: 30m
+audit_log_alert_actions: [org.add_member, protected_branch.update]
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Port != "9000" || cfg.RetentionInterval != 30*time.Minute || cfg.RetentionPolicy != "push=30d,*=90d" {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+	if cfg.AuditLogAlertActions != "org.add_member,protected_branch.update" {
+		t.Errorf("Expected joined alert actions, got %q", cfg.AuditLogAlertActions)
+	}
+}
+
+// TestLoad_TOML tests loading a TOML file
+func TestLoad_TOML(t *testing.T) {
+	path := writeFile(t, "choochoo.toml", `
+port = "9001"
+ws_client_buffer = 64
+docs_routes = "wiki=https://chat.example.com/hooks/docs"
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Port != "9001" || cfg.WSClientBuffer != 64 || cfg.DocsRoutes != "wiki=https://chat.example.com/hooks/docs" {
+		t.Errorf("Unexpected config: %+v", cfg)
+	}
+}
+
+// TestLoad_EnvOverride tests that environment variables win over the file
+// and that stored settings only fill what neither sets
+func TestLoad_EnvOverride(t *testing.T) {
+	path := writeFile(t, "choochoo.yaml", "port: 9000\nretention_interval: 30m\n")
+	t.Setenv("PORT", "9100")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Port != "9100" {
+		t.Errorf("Expected PORT to override the file, got %s", cfg.Port)
+	}
+
+	err = cfg.Fill(map[string]string{"RETENTION_INTERVAL": "2h", "RETENTION_MODE": "archive"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.RetentionInterval != 30*time.Minute || cfg.RetentionMode != "archive" {
+		t.Errorf("Unexpected config after fill: %+v", cfg)
+	}
+}
+
+func TestConfig_ValidateServe(t *testing.T) {
+	cfg := Default()
+	if err := cfg.ValidateServe(); err != nil {
+		t.Errorf("Expected no error without strict mode, got %v", err)
+	}
+
+	cfg.RequireSignature = true
+	if err := cfg.ValidateServe(); err == nil || !strings.Contains(err.Error(), "GITHUB_WEBHOOK_SECRET") {
+		t.Errorf("Expected strict mode to require a secret, got %v", err)
+	}
+	cfg.WebhookSecret = " , "
+	if err := cfg.ValidateServe(); err == nil {
+		t.Error("Expected strict mode to reject a secret list without secrets")
+	}
+	cfg.InsecureDev = true
+	if err := cfg.ValidateServe(); err != nil {
+		t.Errorf("Expected -insecure-dev to allow serving without a secret, got %v", err)
+	}
+
+	cfg.InsecureDev = false
+	cfg.WebhookSecret = "secret"
+	if err := cfg.ValidateServe(); err != nil {
+		t.Errorf("Expected no error with a secret, got %v", err)
+	}
+
+	// Strict mode enabled by a stored setting
+	cfg = Default()
+	if err := cfg.Fill(map[string]string{"CHOOCHOO_REQUIRE_SIGNATURE": "true"}); err != nil {
+		t.Fatalf("Expected no error filling settings, got %v", err)
+	}
+	if err := cfg.ValidateServe(); err == nil {
+		t.Error("Expected a stored strict mode to require a secret")
+	}
+}
+
+// TestLoad_Invalid tests rejecting unusable configuration
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"unknown key", "c.yaml", "prot: 80\n", "unknown setting"},
+		{"bad extension", "c.json", "{}", "unsupported config file"},
+		{"bad port", "c.yaml", "port: 70000\n", "invalid PORT"},
+		{"grpc port same as port", "c.yaml", "port: 8080\ngrpc_port: 8080\n", "invalid GRPC_PORT"},
+		{"bad duration", "c.toml", `retention_interval = "soon"`, "RETENTION_INTERVAL"},
+		{"config lint without app", "c.yaml", "repo_config_lint: true\ngithub_token: ghp_x\n", "REPO_CONFIG_LINT"},
+		{"pipeline checks without app", "c.yaml", "pipeline_checks: true\ngithub_token: ghp_x\n", "PIPELINE_CHECKS"},
+		{"path label pattern", "c.yaml", "path_labels: docs=[docs\ngithub_token: ghp_x\n", "invalid PATH_LABELS"},
+		{"path labels without github", "c.yaml", "path_labels: docs=docs/**\n", "PATH_LABELS requires"},
+		{"comment command role", "c.yaml", "comment_commands: deploy=owner\ngithub_token: ghp_x\n", "invalid COMMENT_COMMANDS"},
+		{"comment commands without github", "c.yaml", "comment_commands: retest=write\n", "COMMENT_COMMANDS requires"},
+		{"receipt event", "c.yaml", "receipt_events: [push, issues]\ngithub_token: ghp_x\nreceipt_kind: comment\n", "invalid RECEIPT_EVENTS"},
+		{"receipt check without app", "c.yaml", "receipt_events: push\ngithub_token: ghp_x\n", "RECEIPT_KIND=check"},
+		{"receipt without github", "c.yaml", "receipt_events: push\nreceipt_kind: comment\n", "requires GITHUB_TOKEN"},
+		{"receipt kind", "c.yaml", "receipt_kind: reaction\n", "RECEIPT_KIND"},
+		{"relay secret", "c.yaml", "webhook_relay_secrets: edge\n", "invalid WEBHOOK_RELAY_SECRETS"},
+		{"workload identity file", "c.yaml", "workload_identity_file: /nonexistent/identities.yaml\n", "invalid WORKLOAD_IDENTITY_FILE"},
+		{"unknown ignored event", "c.yaml", "ignored_events: [push, milestone]\n", "IGNORED_EVENTS"},
+		{"nested", "c.yaml", "nats_url:\n  host: localhost\n", "not a table"},
+		{"aws arn", "c.yaml", "aws_publish_arn: arn:aws:s3:::choochoo-events\n", "invalid AWS_PUBLISH_ARN"},
+		{"nats without url", "c.yaml", "nats_stream: CHOOCHOO\n", "require NATS_URL"},
+		{"authz both", "c.yaml", "authz_opa_url: http://localhost:8181/v1/data/choochoo/allow\nauthz_condition: 'true'\n", "AUTHZ_OPA_URL or AUTHZ_CONDITION"},
+		{"authz condition", "c.yaml", "authz_condition: sender == 'octocat'\n", "AUTHZ_OPA_URL or AUTHZ_CONDITION"},
+		{"query cache ttl", "c.yaml", "query_cache_ttl: -1m\n", "QUERY_CACHE_TTL must not be negative"},
+		{"index advisor", "c.yaml", "index_advisor_slow_query: 0s\nindex_advisor_auto_create: true\n", "INDEX_ADVISOR_AUTO_CREATE requires"},
+		{"maintenance window", "c.yaml", "maintenance_window: 2am-5am\n", "invalid maintenance window"},
+		{"maintenance percent", "c.yaml", "maintenance_reindex_bloat_percent: 150\n", "must be between 0 and 100"},
+		{"maintenance database", "c.yaml", "maintenance_interval: 1h\n", "MAINTENANCE_INTERVAL requires"},
+		{"outbox retention", "c.yaml", "outbox_enabled: true\noutbox_retention: 0s\n", "intervals must be positive"},
+		{"scim role", "c.yaml", "scim_group_roles: Choochoo Admins=owner\n", "invalid SCIM_GROUP_ROLES"},
+		{"scim on sqlite", "c.yaml", "database_url: sqlite:choochoo.db\nscim_group_roles: Choochoo Admins=admin\n", "SCIM_GROUP_ROLES requires a PostgreSQL DATABASE_URL"},
+		{"outbox on sqlite", "c.yaml", "database_url: sqlite:choochoo.db\noutbox_enabled: true\n", "OUTBOX_ENABLED requires a PostgreSQL DATABASE_URL"},
+		{"retention without database", "c.yaml", "retention_policy: '*=90d'\n", "require DATABASE_URL"},
+		{"retention on sqlite", "c.yaml", "database_url: sqlite:choochoo.db\nretention_policy: '*=90d'\n", "require a PostgreSQL DATABASE_URL"},
+		{"event batches on sqlite", "c.yaml", "database_url: sqlite:choochoo.db\nevent_batch_size: 100\n", "EVENT_BATCH_SIZE requires a PostgreSQL DATABASE_URL"},
+		{"change approval without database", "c.yaml", "change_approval: true\n", "CHANGE_APPROVAL requires a PostgreSQL DATABASE_URL"},
+		{"approvers without approval", "c.yaml", "database_url: postgres://localhost/choochoo\nchange_approvers: octocat\n", "CHANGE_APPROVERS requires CHANGE_APPROVAL"},
+		{"cert without key", "c.yaml", "tls_cert_file: cert.pem\n", "must be set together"},
+		{"redirect without tls", "c.yaml", "tls_redirect_port: 80\n", "requires TLS_CERT_FILE"},
+		{"bad boolean", "c.yaml", "github_ip_allowlist: maybe\n", "not a boolean"},
+		{"bad trusted proxy", "c.yaml", "trusted_proxies: [10.0.0.0/99]\n", "TRUSTED_PROXIES"},
+		{"negative rate limit", "c.yaml", "rate_limit_per_ip: -1\n", "must not be negative"},
+		{"negative outbound log size", "c.yaml", "outbound_log_size: -1\n", "must not be negative"},
+		{"bad processor limit", "c.yaml", "processor_limits: docs=soon\n", "PROCESSOR_LIMITS"},
+		{"bad metrics push url", "c.yaml", "database_url: postgres://localhost\nmetrics_push_url: udp://localhost:8125\n", "METRICS_PUSH_URL"},
+		{"bad otlp endpoint", "c.yaml", "otel_exporter_otlp_endpoint: collector:4318\n", "OTEL_EXPORTER_OTLP_ENDPOINT"},
+		{"bad otlp header", "c.yaml", "otel_exporter_otlp_headers: authorization\n", "OTEL_EXPORTER_OTLP_HEADERS"},
+		{"empty redaction path key", "c.yaml", "redact_paths: commits..author.email\n", "invalid REDACT_PATHS"},
+		{"invalid payload key", "c.yaml", "payload_encryption_keys: k1:c2hvcnQ=\n", "invalid PAYLOAD_ENCRYPTION_KEYS"},
+		{"payload keys twice", "c.yaml", "payload_encryption_keys: k1:AAAAAAAAAAAAAAAAAAAAAA==\npayload_encryption_keys_file: keys\n", "not both"},
+		{"invalid event chain key", "c.yaml", "database_url: postgres://localhost\nevent_chain_signing_key: c2hvcnQ=\n", "invalid EVENT_CHAIN_SIGNING_KEY"},
+		{"event chain without postgres", "c.yaml", "event_chain_signing_key: AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\n", "requires a PostgreSQL DATABASE_URL"},
+		{"event chain with deleting retention", "c.yaml", "database_url: postgres://localhost\nevent_chain_signing_key: AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=\nretention_policy: \"*=90d\"\n", "RETENTION_MODE=archive"},
+		{"budget without price", "c.yaml", "database_url: postgres://localhost\ncapacity_monthly_budget: 100\n", "requires CAPACITY_PRICE_PER_GB"},
+		{"bad storage price", "c.yaml", "capacity_price_per_gb: cheap\n", "invalid CAPACITY_PRICE_PER_GB"},
+		{"disk limit without database", "c.yaml", "capacity_disk_limit_gb: 500\n", "require a PostgreSQL DATABASE_URL"},
+		{"empty status page title", "c.yaml", "status_page_enabled: true\nstatus_page_title: \" \"\n", "STATUS_PAGE_TITLE must not be empty"},
+		{"missing rules file", "c.yaml", "rules_file: /nonexistent/rules.yaml\n", "invalid RULES_FILE"},
+		{"slack channel without token", "c.yaml", "slack_channel: \"#deploys\"\n", "SLACK_CHANNEL requires SLACK_BOT_TOKEN"},
+		{"two slack targets", "c.yaml", "slack_webhook_url: https://hooks.slack.com/services/T0/B0/x\nslack_channel: \"#deploys\"\nslack_bot_token: xoxb-1\n", "set only one of"},
+		{"slack condition without target", "c.yaml", "slack_condition: event == 'push'\n", "require SLACK_WEBHOOK_URL or SLACK_CHANNEL"},
+		{"bad slack condition", "c.yaml", "slack_webhook_url: https://hooks.slack.com/services/T0/B0/x\nslack_condition: event ==\n", "invalid SLACK_CONDITION"},
+		{"bad slack template", "c.yaml", "slack_webhook_url: https://hooks.slack.com/services/T0/B0/x\nslack_template: \"{{.event\"\n", "invalid SLACK_TEMPLATE"},
+		{"smtp without host", "c.yaml", "smtp_from: choochoo@example.com\n", "SMTP settings require SMTP_HOST"},
+		{"bad smtp tls", "c.yaml", "smtp_host: smtp.example.com\nsmtp_from: choochoo@example.com\nsmtp_tls: ssl\n", "invalid SMTP settings"},
+		{"smtp without sender", "c.yaml", "smtp_host: smtp.example.com\n", "invalid SMTP settings"},
+		{"bad stats epsilon", "c.yaml", "stats_privacy_epsilon: \"0\"\n", "invalid STATS_PRIVACY_EPSILON"},
+		{"negative stats min count", "c.yaml", "stats_min_count: -1\n", "STATS_MIN_COUNT must not be negative"},
+		{"partial github app", "c.yaml", "github_app_id: 12\n", "must be set together"},
+		{"invalid route", "c.yaml", "database_url: postgres://localhost\nretention_policy: push\n", "RETENTION_POLICY"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Load(writeFile(t, test.file, test.content))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("Expected error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+// DefaultBatchDelay is how long the first event of a batch waits for more
+// events before the batch is written
+const DefaultBatchDelay = 20 * time.Millisecond
+
+// batchWriteTimeout bounds the write of one batch
+const batchWriteTimeout = 5 * time.Second
+
+// batchSizeBuckets are the upper bounds of the ranges batch sizes are
+// counted in
+var batchSizeBuckets = []int{1, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// ErrBatchWriterStopped is returned for events written after the batch
+// writer stopped
+var ErrBatchWriterStopped = errors.New("batch writer stopped")
+
+// BatchEntry is an event waiting to be written in a batch
+type BatchEntry struct {
+	Params db.CreateWebhookEventParams
+	// Queue adds the event to the work queue once it is stored
+	Queue bool
+	// TraceParent continues the trace of the request when the event is
+	// processed from the work queue
+	TraceParent string
+}
+
+// FlushFunc writes a batch of events with distinct delivery IDs, returning
+// the delivery IDs that were stored for the first time
+type FlushFunc func(ctx context.Context, batch []BatchEntry) (map[string]bool, error)
+
+// BatchStats describes the batches a batch writer has written. Sizes counts
+// batches by size range, such as "2-5" or "1001+".
+type BatchStats struct {
+	MaxSize     int              `json:"max_size"`
+	MaxDelay    string           `json:"max_delay"`
+	Batches     int64            `json:"batches"`
+	Events      int64            `json:"events"`
+	Duplicates  int64            `json:"duplicates"`
+	Failures    int64            `json:"failures"`
+	LastSize    int              `json:"last_size"`
+	AverageSize float64          `json:"average_size"`
+	Sizes       map[string]int64 `json:"sizes"`
+	LastError   string           `json:"last_error,omitempty"`
+}
+
+// BatchWriter collects the events of concurrent requests and writes them
+// together, once maxSize events are waiting or the first has waited
+// maxDelay. Writers wait for the batch holding their event, so they still
+// learn whether it was stored or a duplicate.
+type BatchWriter struct {
+	flush    FlushFunc
+	maxSize  int
+	maxDelay time.Duration
+	requests chan batchRequest
+	stopped  chan struct{}
+
+	mu    sync.Mutex
+	stats BatchStats
+}
+
+// batchRequest is an entry and where its outcome is sent
+type batchRequest struct {
+	entry  BatchEntry
+	result chan batchResult
+}
+
+// batchResult is the outcome of writing an entry
+type batchResult struct {
+	result StoreResult
+	err    error
+}
+
+// NewBatchWriter creates a batch writer that writes batches of up to
+// maxSize events with flush. A maxDelay of zero uses DefaultBatchDelay.
+func NewBatchWriter(flush FlushFunc, maxSize int, maxDelay time.Duration) *BatchWriter {
+	if maxSize <= 0 {
+		maxSize = 1
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultBatchDelay
+	}
+	return &BatchWriter{
+		flush:    flush,
+		maxSize:  maxSize,
+		maxDelay: maxDelay,
+		requests: make(chan batchRequest, maxSize),
+		stopped:  make(chan struct{}),
+		stats: BatchStats{
+			MaxSize:  maxSize,
+			MaxDelay: maxDelay.String(),
+			Sizes:    map[string]int64{},
+		},
+	}
+}
+
+// Write adds an event to the next batch and waits until the batch is
+// written. Storage is idempotent on the delivery ID, as with
+// StoreWebhookEvent.
+func (w *BatchWriter) Write(ctx context.Context, entry BatchEntry) (StoreResult, error) {
+	select {
+	case <-w.stopped:
+		return EventStored, ErrBatchWriterStopped
+	default:
+	}
+
+	request := batchRequest{entry: entry, result: make(chan batchResult, 1)}
+	select {
+	case w.requests <- request:
+	case <-w.stopped:
+		return EventStored, ErrBatchWriterStopped
+	case <-ctx.Done():
+		return EventStored, ctx.Err()
+	}
+
+	select {
+	case result := <-request.result:
+		return result.result, result.err
+	case <-ctx.Done():
+		return EventStored, ctx.Err()
+	}
+}
+
+// Run writes batches until ctx is cancelled, then writes the events that are
+// still waiting
+func (w *BatchWriter) Run(ctx context.Context) {
+	for {
+		var batch []batchRequest
+		select {
+		case request := <-w.requests:
+			batch = append(batch, request)
+		case <-ctx.Done():
+			close(w.stopped)
+			w.drain()
+			return
+		}
+
+		timer := time.NewTimer(w.maxDelay)
+	collect:
+		for len(batch) < w.maxSize {
+			select {
+			case request := <-w.requests:
+				batch = append(batch, request)
+			case <-timer.C:
+				break collect
+			case <-ctx.Done():
+				break collect
+			}
+		}
+		timer.Stop()
+		w.write(batch)
+	}
+}
+
+// drain writes the waiting events in batches of up to maxSize
+func (w *BatchWriter) drain() {
+	for {
+		var batch []batchRequest
+	collect:
+		for len(batch) < w.maxSize {
+			select {
+			case request := <-w.requests:
+				batch = append(batch, request)
+			default:
+				break collect
+			}
+		}
+		if len(batch) == 0 {
+			return
+		}
+		w.write(batch)
+	}
+}
+
+// write flushes a batch and sends each request its outcome. A delivery can
+// be redelivered while its first attempt is still waiting, so only the first
+// entry of each delivery ID is written and the others are duplicates.
+func (w *BatchWriter) write(batch []batchRequest) {
+	entries := make([]BatchEntry, 0, len(batch))
+	seen := make(map[string]bool, len(batch))
+	for _, request := range batch {
+		if id := request.entry.Params.DeliveryID; !seen[id] {
+			seen[id] = true
+			entries = append(entries, request.entry)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), batchWriteTimeout)
+	stored, err := w.flush(ctx, entries)
+	cancel()
+
+	duplicates := 0
+	for _, request := range batch {
+		id := request.entry.Params.DeliveryID
+		if err != nil {
+			request.result <- batchResult{result: EventStored, err: err}
+			continue
+		}
+		result := EventDuplicate
+		if stored[id] {
+			result = EventStored
+			// Later entries of the same delivery are duplicates
+			delete(stored, id)
+		} else {
+			duplicates++
+		}
+		request.result <- batchResult{result: result}
+	}
+	w.record(len(batch), duplicates, err)
+}
+
+// record updates the writer's stats after a batch
+func (w *BatchWriter) record(size, duplicates int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.stats.Batches++
+	w.stats.Events += int64(size)
+	w.stats.Duplicates += int64(duplicates)
+	w.stats.LastSize = size
+	w.stats.AverageSize = float64(w.stats.Events) / float64(w.stats.Batches)
+	w.stats.Sizes[sizeBucket(size)]++
+	w.stats.LastError = ""
+	if err != nil {
+		w.stats.Failures++
+		w.stats.LastError = err.Error()
+	}
+}
+
+// Stats returns a snapshot of the writer's stats
+func (w *BatchWriter) Stats() BatchStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stats := w.stats
+	stats.Sizes = make(map[string]int64, len(w.stats.Sizes))
+	for bucket, n := range w.stats.Sizes {
+		stats.Sizes[bucket] = n
+	}
+	return stats
+}
+
+// sizeBucket returns the range of batchSizeBuckets a batch size falls in
+func sizeBucket(size int) string {
+	lower := 1
+	for _, upper := range batchSizeBuckets {
+		if size <= upper {
+			if lower == upper {
+				return fmt.Sprint(upper)
+			}
+			return fmt.Sprintf("%d-%d", lower, upper)
+		}
+		lower = upper + 1
+	}
+	return fmt.Sprintf("%d+", lower)
+}
+
+// WriteBatch stores a batch of events with distinct delivery IDs in one
+// transaction, queueing the stored events that ask for it and adding them to
+// the outbox, and returns the delivery IDs that were stored for the first
+// time. It is the FlushFunc of a BatchWriter on PostgreSQL. The events are
+// inserted with one multi-row INSERT rather than COPY, which cannot skip
+// deliveries that are already stored.
+func (c *Connection) WriteBatch(ctx context.Context, batch []BatchEntry) (map[string]bool, error) {
+	var events db.CreateWebhookEventsParams
+	for _, entry := range batch {
+		// NULL columns are sent as empty strings and stored as NULL again
+		events.DeliveryIds = append(events.DeliveryIds, entry.Params.DeliveryID)
+		events.EventTypes = append(events.EventTypes, entry.Params.EventType)
+		events.RepositoryNames = append(events.RepositoryNames, entry.Params.RepositoryName.String)
+		events.SenderLogins = append(events.SenderLogins, entry.Params.SenderLogin.String)
+		events.Actions = append(events.Actions, entry.Params.Action.String)
+		events.Payloads = append(events.Payloads, string(entry.Params.Payload))
+		events.PayloadSha256s = append(events.PayloadSha256s, entry.Params.PayloadSha256.String)
+	}
+
+	stored := make(map[string]bool, len(batch))
+	err := c.InTx(ctx, func(queries *db.Queries) error {
+		ids, err := queries.CreateWebhookEvents(ctx, events)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			stored[id] = true
+		}
+		if err := c.enqueueOutbox(ctx, queries, ids...); err != nil {
+			return err
+		}
+
+		var queued db.EnqueueWorkItemsParams
+		for _, entry := range batch {
+			if entry.Queue && stored[entry.Params.DeliveryID] {
+				queued.DeliveryIds = append(queued.DeliveryIds, entry.Params.DeliveryID)
+				queued.TraceParents = append(queued.TraceParents, entry.TraceParent)
+			}
+		}
+		if len(queued.DeliveryIds) == 0 {
+			return nil
+		}
+		return queries.EnqueueWorkItems(ctx, queued)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
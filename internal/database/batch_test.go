@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+// memoryFlush stores batches in a MemoryStore, or fails with err
+type memoryFlush struct {
+	store *MemoryStore
+	err   error
+}
+
+func (f *memoryFlush) flush(ctx context.Context, batch []BatchEntry) (map[string]bool, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	stored := make(map[string]bool)
+	for _, entry := range batch {
+		result, err := f.store.StoreWebhookEvent(ctx, entry.Params)
+		if err != nil {
+			return nil, err
+		}
+		if result == EventStored {
+			stored[entry.Params.DeliveryID] = true
+		}
+	}
+	return stored, nil
+}
+
+func batchEntry(deliveryID string) BatchEntry {
+	return BatchEntry{Params: db.CreateWebhookEventParams{
+		DeliveryID: deliveryID,
+		EventType:  "push",
+		Payload:    []byte(`{}`),
+	}}
+}
+
+func TestBatchWriter_FlushesFullBatches(t *testing.T) {
+	flush := &memoryFlush{store: NewMemoryStore()}
+	// The delay is long enough that only full batches are written
+	writer := NewBatchWriter(flush.flush, 4, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go writer.Run(ctx)
+
+	// Two of the eight writes redeliver the same event
+	ids := []string{"d1", "d2", "d3", "d1", "d4", "d5", "d6", "d5"}
+	results := make([]StoreResult, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			result, err := writer.Write(context.Background(), batchEntry(id))
+			if err != nil {
+				t.Errorf("Write(%s) failed: %v", id, err)
+			}
+			results[i] = result
+		}(i, id)
+	}
+	wg.Wait()
+
+	stored, duplicates := 0, 0
+	for _, result := range results {
+		if result == EventStored {
+			stored++
+		} else {
+			duplicates++
+		}
+	}
+	if stored != 6 || duplicates != 2 {
+		t.Errorf("Expected 6 stored and 2 duplicates, got %d and %d", stored, duplicates)
+	}
+
+	stats := writer.Stats()
+	if stats.Batches != 2 || stats.Events != 8 || stats.Duplicates != 2 || stats.AverageSize != 4 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	if stats.Sizes["2-5"] != 2 {
+		t.Errorf("Expected two batches of 2-5 events, got %v", stats.Sizes)
+	}
+}
+
+func TestBatchWriter_FlushesAfterDelay(t *testing.T) {
+	flush := &memoryFlush{store: NewMemoryStore()}
+	writer := NewBatchWriter(flush.flush, 100, 10*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go writer.Run(ctx)
+
+	result, err := writer.Write(context.Background(), batchEntry("d1"))
+	if err != nil || result != EventStored {
+		t.Fatalf("Expected the event to be stored, got %v, %v", result, err)
+	}
+	if stats := writer.Stats(); stats.LastSize != 1 || stats.Sizes["1"] != 1 {
+		t.Errorf("Expected a batch of one event, got %+v", stats)
+	}
+}
+
+func TestBatchWriter_Failure(t *testing.T) {
+	flush := &memoryFlush{store: NewMemoryStore(), err: errors.New("connection reset")}
+	writer := NewBatchWriter(flush.flush, 10, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go writer.Run(ctx)
+
+	if _, err := writer.Write(context.Background(), batchEntry("d1")); err == nil {
+		t.Fatal("Expected the flush error")
+	}
+	if stats := writer.Stats(); stats.Failures != 1 || stats.LastError != "connection reset" {
+		t.Errorf("Expected a failure in stats, got %+v", stats)
+	}
+}
+
+func TestBatchWriter_Stopped(t *testing.T) {
+	flush := &memoryFlush{store: NewMemoryStore()}
+	writer := NewBatchWriter(flush.flush, 10, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		writer.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	<-done
+
+	if _, err := writer.Write(context.Background(), batchEntry("d1")); !errors.Is(err, ErrBatchWriterStopped) {
+		t.Errorf("Expected ErrBatchWriterStopped, got %v", err)
+	}
+}
+
+func TestSizeBucket(t *testing.T) {
+	for size, expected := range map[int]string{1: "1", 2: "2-5", 5: "2-5", 6: "6-10", 1000: "501-1000", 1001: "1001+"} {
+		if got := sizeBucket(size); got != expected {
+			t.Errorf("sizeBucket(%d) = %q, want %q", size, got, expected)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateIndexConcurrently builds an index with a CREATE INDEX CONCURRENTLY
+// statement on a connection of its own, so the build does not hold one of the
+// connections of the pool. A failed build leaves an invalid index
+// that must be dropped before it is built again.
+func (c *Connection) CreateIndexConcurrently(ctx context.Context, definition string) error {
+	if !strings.HasPrefix(definition, "CREATE INDEX CONCURRENTLY ") {
+		return fmt.Errorf("not a concurrent index definition: %s", definition)
+	}
+	if err := c.execAlone(ctx, definition); err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+	return nil
+}
+
+// RunMaintenance runs a VACUUM, ANALYZE or REINDEX statement on a connection
+// of its own, like CreateIndexConcurrently, as they can run for minutes on a
+// large table and VACUUM cannot run in a transaction.
+func (c *Connection) RunMaintenance(ctx context.Context, statement string) error {
+	if !strings.HasPrefix(statement, "VACUUM ") && !strings.HasPrefix(statement, "ANALYZE ") && !strings.HasPrefix(statement, "REINDEX ") {
+		return fmt.Errorf("not a maintenance statement: %s", statement)
+	}
+	return c.execAlone(ctx, statement)
+}
+
+// execAlone runs a statement on a new connection with the settings of the
+// pool
+func (c *Connection) execAlone(ctx context.Context, statement string) error {
+	conn, err := pgx.ConnectConfig(ctx, c.pool.Config().ConnConfig.Copy())
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	_, err = conn.Exec(ctx, statement)
+	return err
+}
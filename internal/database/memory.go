@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MemoryStore keeps webhook events in memory, for tests and local
+// development of handlers without a database. Events are lost when the
+// process exits.
+type MemoryStore struct {
+	mu     sync.Mutex
+	events []db.WebhookEvent
+	byID   map[string]int
+}
+
+// NewMemoryStore creates an empty in-memory event store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{byID: make(map[string]int)}
+}
+
+// StoreWebhookEvent stores a webhook event. Storage is idempotent on the
+// delivery ID, so redeliveries report EventDuplicate rather than an error.
+func (s *MemoryStore) StoreWebhookEvent(ctx context.Context, params db.CreateWebhookEventParams) (StoreResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.byID[params.DeliveryID]; ok {
+		return EventDuplicate, nil
+	}
+	s.byID[params.DeliveryID] = len(s.events)
+	s.events = append(s.events, db.WebhookEvent{
+		ID:             int32(len(s.events) + 1),
+		DeliveryID:     params.DeliveryID,
+		EventType:      params.EventType,
+		RepositoryName: params.RepositoryName,
+		SenderLogin:    params.SenderLogin,
+		Action:         params.Action,
+		Payload:        append([]byte(nil), params.Payload...),
+		CreatedAt:      pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		PayloadSha256:  params.PayloadSha256,
+	})
+	return EventStored, nil
+}
+
+// RetryWebhookEvent stores a spooled webhook event for the dead-letter spool
+func (s *MemoryStore) RetryWebhookEvent(ctx context.Context, params db.CreateWebhookEventParams) error {
+	_, err := s.StoreWebhookEvent(ctx, params)
+	return err
+}
+
+// GetWebhookEvent loads a stored event, or returns ErrEventNotFound
+func (s *MemoryStore) GetWebhookEvent(ctx context.Context, deliveryID string) (db.WebhookEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.byID[deliveryID]
+	if !ok {
+		return db.WebhookEvent{}, ErrEventNotFound
+	}
+	return s.events[i], nil
+}
+
+// ListWebhookEvents lists the most recent events, optionally filtered by
+// event type and repository
+func (s *MemoryStore) ListWebhookEvents(ctx context.Context, params db.ListWebhookEventsParams) ([]db.WebhookEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []db.WebhookEvent
+	for i := len(s.events) - 1; i >= 0 && len(events) < int(params.RowLimit); i-- {
+		event := s.events[i]
+		if params.EventType != "" && event.EventType != params.EventType {
+			continue
+		}
+		if params.RepositoryName != "" && event.RepositoryName.String != params.RepositoryName {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// IsConnected always reports true, since there is nothing to connect to
+func (s *MemoryStore) IsConnected(ctx context.Context) bool {
+	return true
+}
+
+// Close does nothing; the events stay readable
+func (s *MemoryStore) Close(ctx context.Context) error {
+	return nil
+}
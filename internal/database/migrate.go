@@ -0,0 +1,179 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// createMigrationsTable records which migrations have been applied
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version TEXT PRIMARY KEY,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+)`
+
+// Migration is one schema migration file
+type Migration struct {
+	Version string
+	Name    string
+}
+
+// ListMigrations lists the *.sql files in migrations, named
+// <version>_<description>.sql with a zero-padded numeric version, in version
+// order
+func ListMigrations(migrations fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Migration, 0, len(names))
+	seen := make(map[string]string)
+	for _, name := range names {
+		version, _, ok := strings.Cut(strings.TrimSuffix(path.Base(name), ".sql"), "_")
+		if !ok || version == "" || strings.Trim(version, "0123456789") != "" {
+			return nil, fmt.Errorf("migration %s is not named <version>_<description>.sql", name)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %s", other, name, version)
+		}
+		seen[version] = name
+		list = append(list, Migration{Version: version, Name: name})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// appliedMigrations returns the versions recorded in schema_migrations,
+// creating it if needed
+func (c *Connection) appliedMigrations(ctx context.Context) (map[string]bool, error) {
+	if _, err := c.pool.Exec(ctx, createMigrationsTable); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return c.readMigrations(ctx)
+}
+
+// readMigrations returns the versions recorded in schema_migrations
+func (c *Connection) readMigrations(ctx context.Context) (map[string]bool, error) {
+	rows, err := c.pool.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// PendingMigrations returns the migrations that have not been applied yet,
+// without creating schema_migrations if it does not exist
+func (c *Connection) PendingMigrations(ctx context.Context, migrations fs.FS) ([]Migration, error) {
+	list, err := ListMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if err := c.pool.QueryRow(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return list, nil
+	}
+	applied, err := c.readMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range list {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies every migration that has not been applied yet, each in its
+// own transaction, and returns the ones it applied
+func (c *Connection) Migrate(ctx context.Context, migrations fs.FS) ([]Migration, error) {
+	list, err := ListMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := c.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range list {
+		if applied[migration.Version] {
+			continue
+		}
+		statements, err := fs.ReadFile(migrations, migration.Name)
+		if err != nil {
+			return done, err
+		}
+		if err := c.applyMigration(ctx, migration, string(statements)); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// applyMigration runs one migration and records it atomically
+func (c *Connection) applyMigration(ctx context.Context, migration Migration, statements string) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Without arguments pgx uses the simple protocol, which allows several
+	// statements in one call
+	if _, err := tx.Exec(ctx, statements); err != nil {
+		return fmt.Errorf("migration %s failed: %w", migration.Name, err)
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", migration.Version); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+	}
+	return tx.Commit(ctx)
+}
+
+// Baseline records every migration up to and including version as applied
+// without running it, for databases that were migrated by hand before
+// schema_migrations existed
+func (c *Connection) Baseline(ctx context.Context, migrations fs.FS, version string) ([]Migration, error) {
+	list, err := ListMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := c.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range list {
+		if migration.Version > version || applied[migration.Version] {
+			continue
+		}
+		if _, err := c.pool.Exec(ctx, "INSERT INTO schema_migrations (version) VALUES ($1)", migration.Version); err != nil {
+			return done, fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/deedubs/choochoo/sql/migrations"
+)
+
+func TestListMigrations(t *testing.T) {
+	files := fstest.MapFS{
+		"010_comments.sql":       {Data: []byte("SELECT 1;")},
+		"002_security.sql":       {Data: []byte("SELECT 1;")},
+		"001_webhook_events.sql": {Data: []byte("SELECT 1;")},
+		"README.md":              {Data: []byte("not a migration")},
+	}
+
+	list, err := ListMigrations(files)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := []Migration{
+		{Version: "001", Name: "001_webhook_events.sql"},
+		{Version: "002", Name: "002_security.sql"},
+		{Version: "010", Name: "010_comments.sql"},
+	}
+	if !reflect.DeepEqual(list, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, list)
+	}
+}
+
+func TestListMigrations_Invalid(t *testing.T) {
+	for _, files := range []fstest.MapFS{
+		{"webhook_events.sql": {}},
+		{"001_a.sql": {}, "001_b.sql": {}},
+	} {
+		if _, err := ListMigrations(files); err == nil {
+			t.Errorf("Expected error for %v", files)
+		}
+	}
+}
+
+// TestListMigrations_Embedded checks that the shipped migrations are well formed
+func TestListMigrations_Embedded(t *testing.T) {
+	list, err := ListMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(list) == 0 || list[0].Version != "001" {
+		t.Errorf("Unexpected embedded migrations: %+v", list)
+	}
+}
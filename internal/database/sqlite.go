@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/sql/sqlite"
+	"github.com/jackc/pgx/v5/pgtype"
+	_ "modernc.org/sqlite"
+)
+
+// sqliteTimeLayout stores timestamps in UTC with a fixed width, so they sort
+// as text
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000Z"
+
+// SQLiteStore stores webhook events in a SQLite database file, for
+// deployments that do not want to run PostgreSQL
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens the SQLite database of dbURL, sqlite:PATH or
+// sqlite://PATH, creating the file and its schema if needed
+func NewSQLiteStore(ctx context.Context, dbURL string) (*SQLiteStore, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(dbURL, "sqlite:"), "//")
+	if path == "" {
+		return nil, fmt.Errorf("invalid SQLite database URL %q: missing path", dbURL)
+	}
+
+	conn, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	// SQLite allows one writer at a time, and an in-memory database only
+	// lives as long as its connection
+	conn.SetMaxOpenConns(1)
+
+	if _, err := conn.ExecContext(ctx, sqlite.Schema); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create SQLite schema: %w", err)
+	}
+	// Columns added after the table was first created
+	if err := addColumn(ctx, conn, "webhook_events", "payload_sha256", "TEXT"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to update SQLite schema: %w", err)
+	}
+	return &SQLiteStore{db: conn}, nil
+}
+
+// addColumn adds a column to a table created by an earlier version of the
+// schema, since SQLite has no ADD COLUMN IF NOT EXISTS
+func addColumn(ctx context.Context, conn *sql.DB, table, column, definition string) error {
+	var exists bool
+	err := conn.QueryRowContext(ctx, `SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, `ALTER TABLE `+table+` ADD COLUMN `+column+` `+definition)
+	return err
+}
+
+// StoreWebhookEvent stores a webhook event. Storage is idempotent on the
+// delivery ID, so redeliveries report EventDuplicate rather than an error.
+func (s *SQLiteStore) StoreWebhookEvent(ctx context.Context, params db.CreateWebhookEventParams) (StoreResult, error) {
+	result, err := s.db.ExecContext(ctx, `INSERT INTO webhook_events (
+    delivery_id, event_type, repository_name, sender_login, action, payload, created_at, payload_sha256
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (delivery_id) DO NOTHING`,
+		params.DeliveryID,
+		params.EventType,
+		nullString(params.RepositoryName),
+		nullString(params.SenderLogin),
+		nullString(params.Action),
+		string(params.Payload),
+		time.Now().UTC().Format(sqliteTimeLayout),
+		nullString(params.PayloadSha256),
+	)
+	if err != nil {
+		return EventStored, err
+	}
+	stored, err := result.RowsAffected()
+	if err != nil {
+		return EventStored, err
+	}
+	if stored == 0 {
+		return EventDuplicate, nil
+	}
+	return EventStored, nil
+}
+
+// RetryWebhookEvent stores a spooled webhook event for the dead-letter spool,
+// which only needs to know whether the write succeeded
+func (s *SQLiteStore) RetryWebhookEvent(ctx context.Context, params db.CreateWebhookEventParams) error {
+	_, err := s.StoreWebhookEvent(ctx, params)
+	return err
+}
+
+// sqliteEventColumns are the columns scanned by scanEvent
+const sqliteEventColumns = `id, delivery_id, event_type, repository_name, sender_login, action, payload, created_at, payload_sha256`
+
+// GetWebhookEvent loads a stored event, or returns ErrEventNotFound
+func (s *SQLiteStore) GetWebhookEvent(ctx context.Context, deliveryID string) (db.WebhookEvent, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+sqliteEventColumns+` FROM webhook_events
+WHERE delivery_id = ?`, deliveryID)
+	event, err := scanEvent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return event, ErrEventNotFound
+	}
+	return event, err
+}
+
+// ListWebhookEvents lists the most recent events, optionally filtered by
+// event type and repository
+func (s *SQLiteStore) ListWebhookEvents(ctx context.Context, params db.ListWebhookEventsParams) ([]db.WebhookEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+sqliteEventColumns+` FROM webhook_events
+WHERE (?1 = '' OR event_type = ?1)
+  AND (?2 = '' OR repository_name = ?2)
+ORDER BY created_at DESC, id DESC
+LIMIT ?3`, params.EventType, params.RepositoryName, params.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []db.WebhookEvent
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// IsConnected checks if the database can be reached
+func (s *SQLiteStore) IsConnected(ctx context.Context) bool {
+	return s.db.PingContext(ctx) == nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close(ctx context.Context) error {
+	return s.db.Close()
+}
+
+// scanEvent scans a row of sqliteEventColumns into the type the PostgreSQL
+// queries return
+func scanEvent(row interface{ Scan(...any) error }) (db.WebhookEvent, error) {
+	var event db.WebhookEvent
+	var repositoryName, senderLogin, action, payloadSha256 sql.NullString
+	var payload, createdAt string
+	err := row.Scan(
+		&event.ID,
+		&event.DeliveryID,
+		&event.EventType,
+		&repositoryName,
+		&senderLogin,
+		&action,
+		&payload,
+		&createdAt,
+		&payloadSha256,
+	)
+	if err != nil {
+		return event, err
+	}
+	event.RepositoryName = pgtype.Text{String: repositoryName.String, Valid: repositoryName.Valid}
+	event.SenderLogin = pgtype.Text{String: senderLogin.String, Valid: senderLogin.Valid}
+	event.Action = pgtype.Text{String: action.String, Valid: action.Valid}
+	event.Payload = []byte(payload)
+	event.PayloadSha256 = pgtype.Text{String: payloadSha256.String, Valid: payloadSha256.Valid}
+
+	created, err := time.Parse(sqliteTimeLayout, createdAt)
+	if err != nil {
+		return event, fmt.Errorf("invalid created_at %q: %w", createdAt, err)
+	}
+	event.CreatedAt = pgtype.Timestamptz{Time: created, Valid: true}
+	return event, nil
+}
+
+// nullString converts an optional PostgreSQL text value for database/sql
+func nullString(text pgtype.Text) sql.NullString {
+	return sql.NullString{String: text.String, Valid: text.Valid}
+}
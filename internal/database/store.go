@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrEventNotFound is returned when loading a delivery that is not stored
+var ErrEventNotFound = errors.New("event not found")
+
+// EventStore stores received webhook events. Connection implements it on
+// PostgreSQL, SQLiteStore on SQLite and MemoryStore in memory. The latter two
+// only support storing and replaying events; features with their own tables
+// need PostgreSQL.
+type EventStore interface {
+	// StoreWebhookEvent stores an event, reporting EventDuplicate for a
+	// delivery that is already stored
+	StoreWebhookEvent(ctx context.Context, params db.CreateWebhookEventParams) (StoreResult, error)
+	// RetryWebhookEvent stores a spooled event for the dead-letter spool
+	RetryWebhookEvent(ctx context.Context, params db.CreateWebhookEventParams) error
+	// GetWebhookEvent loads a stored event, or returns ErrEventNotFound
+	GetWebhookEvent(ctx context.Context, deliveryID string) (db.WebhookEvent, error)
+	// ListWebhookEvents lists the most recent events, optionally filtered by
+	// event type and repository
+	ListWebhookEvents(ctx context.Context, params db.ListWebhookEventsParams) ([]db.WebhookEvent, error)
+	// IsConnected checks if the store is reachable
+	IsConnected(ctx context.Context) bool
+	// Close closes the store
+	Close(ctx context.Context) error
+}
+
+// IsSQLite reports whether dbURL selects the SQLite event store, with the
+// sqlite: scheme
+func IsSQLite(dbURL string) bool {
+	return strings.HasPrefix(dbURL, "sqlite:")
+}
+
+// OpenEventStore opens the event store dbURL selects by its scheme
+func OpenEventStore(ctx context.Context, dbURL string) (EventStore, error) {
+	if IsSQLite(dbURL) {
+		return NewSQLiteStore(ctx, dbURL)
+	}
+	return NewConnection(ctx, dbURL)
+}
+
+// GetWebhookEvent loads a stored event, or returns ErrEventNotFound
+func (c *Connection) GetWebhookEvent(ctx context.Context, deliveryID string) (db.WebhookEvent, error) {
+	event, err := c.queries.GetWebhookEventByDeliveryID(ctx, deliveryID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return event, ErrEventNotFound
+	}
+	return event, err
+}
+
+// ListWebhookEvents lists the most recent events, optionally filtered by
+// event type and repository
+func (c *Connection) ListWebhookEvents(ctx context.Context, params db.ListWebhookEventsParams) ([]db.WebhookEvent, error) {
+	return c.queries.ListWebhookEvents(ctx, params)
+}
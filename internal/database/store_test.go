@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// testEventStore checks the behavior every EventStore shares
+func testEventStore(t *testing.T, store EventStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	if !store.IsConnected(ctx) {
+		t.Fatal("Expected the store to be connected")
+	}
+
+	push := db.CreateWebhookEventParams{
+		DeliveryID:     "delivery-1",
+		EventType:      "push",
+		RepositoryName: pgtype.Text{String: "octo-org/api", Valid: true},
+		Payload:        []byte(`{"ref":"refs/heads/main"}`),
+		PayloadSha256:  pgtype.Text{String: "5b2bb9ac4b4c1e3b2b9e4e2e6bd2df43e0a6ad9af3d0d5a8b36db1b1c3f3e1a0", Valid: true},
+	}
+	issue := db.CreateWebhookEventParams{
+		DeliveryID: "delivery-2",
+		EventType:  "issues",
+		Action:     pgtype.Text{String: "opened", Valid: true},
+		Payload:    []byte(`{"action":"opened"}`),
+	}
+	for _, params := range []db.CreateWebhookEventParams{push, issue} {
+		if result, err := store.StoreWebhookEvent(ctx, params); err != nil || result != EventStored {
+			t.Fatalf("StoreWebhookEvent(%s) = %v, %v", params.DeliveryID, result, err)
+		}
+	}
+	if result, err := store.StoreWebhookEvent(ctx, push); err != nil || result != EventDuplicate {
+		t.Errorf("Expected a duplicate delivery, got %v, %v", result, err)
+	}
+
+	event, err := store.GetWebhookEvent(ctx, "delivery-1")
+	if err != nil {
+		t.Fatalf("GetWebhookEvent failed: %v", err)
+	}
+	if event.EventType != "push" || event.RepositoryName.String != "octo-org/api" || event.Action.Valid ||
+		string(event.Payload) != `{"ref":"refs/heads/main"}` || !event.CreatedAt.Valid || event.PayloadSha256 != push.PayloadSha256 {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	if _, err := store.GetWebhookEvent(ctx, "missing"); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound, got %v", err)
+	}
+
+	all, err := store.ListWebhookEvents(ctx, db.ListWebhookEventsParams{RowLimit: 10})
+	if err != nil || len(all) != 2 || all[0].DeliveryID != "delivery-2" {
+		t.Errorf("Expected both events, most recent first, got %+v, %v", all, err)
+	}
+	pushes, err := store.ListWebhookEvents(ctx, db.ListWebhookEventsParams{EventType: "push", RepositoryName: "octo-org/api", RowLimit: 10})
+	if err != nil || len(pushes) != 1 || pushes[0].DeliveryID != "delivery-1" {
+		t.Errorf("Expected the push event, got %+v, %v", pushes, err)
+	}
+
+}
+
+func TestMemoryStore(t *testing.T) {
+	testEventStore(t, NewMemoryStore())
+}
@@ -8,6 +8,431 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+// Member, team and organization access changes for access reviews
+type AccessChange struct {
+	ID                 int32              `json:"id"`
+	DeliveryID         string             `json:"delivery_id"`
+	Source             string             `json:"source"`
+	Action             string             `json:"action"`
+	Change             string             `json:"change"`
+	Organization       string             `json:"organization"`
+	RepositoryName     pgtype.Text        `json:"repository_name"`
+	TeamSlug           pgtype.Text        `json:"team_slug"`
+	Principal          string             `json:"principal"`
+	PrincipalType      string             `json:"principal_type"`
+	Permission         pgtype.Text        `json:"permission"`
+	PreviousPermission pgtype.Text        `json:"previous_permission"`
+	AdminGrant         bool               `json:"admin_grant"`
+	SenderLogin        pgtype.Text        `json:"sender_login"`
+	CreatedAt          pgtype.Timestamptz `json:"created_at"`
+}
+
+// Hashed API tokens with their scopes
+type ApiToken struct {
+	ID         int32              `json:"id"`
+	Name       string             `json:"name"`
+	TokenHash  string             `json:"token_hash"`
+	Scopes     []string           `json:"scopes"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	// Organization a tenant token is limited to, lowercased, or NULL for instance tokens
+	Organization pgtype.Text `json:"organization"`
+}
+
+// Maintenance notes shown on the dashboard and appended to digests while active
+type Banner struct {
+	ID        int32              `json:"id"`
+	Message   string             `json:"message"`
+	Level     string             `json:"level"`
+	Dashboard bool               `json:"dashboard"`
+	Digests   bool               `json:"digests"`
+	StartsAt  pgtype.Timestamptz `json:"starts_at"`
+	EndsAt    pgtype.Timestamptz `json:"ends_at"`
+	CreatedBy string             `json:"created_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+// History of branch protection rule and repository ruleset configurations
+type BranchProtectionHistory struct {
+	ID              int32              `json:"id"`
+	DeliveryID      string             `json:"delivery_id"`
+	RepositoryName  string             `json:"repository_name"`
+	Source          string             `json:"source"`
+	RuleID          int64              `json:"rule_id"`
+	Pattern         string             `json:"pattern"`
+	Action          string             `json:"action"`
+	Config          []byte             `json:"config"`
+	Weakened        bool               `json:"weakened"`
+	WeakenedReasons []string           `json:"weakened_reasons"`
+	SenderLogin     pgtype.Text        `json:"sender_login"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
+// Latest state of check suites, check runs and workflow runs for CI analytics
+type CiRun struct {
+	ID             int32              `json:"id"`
+	Kind           string             `json:"kind"`
+	RunID          int64              `json:"run_id"`
+	RepositoryName string             `json:"repository_name"`
+	Name           string             `json:"name"`
+	AppSlug        pgtype.Text        `json:"app_slug"`
+	HeadBranch     pgtype.Text        `json:"head_branch"`
+	HeadSha        string             `json:"head_sha"`
+	TriggerEvent   pgtype.Text        `json:"trigger_event"`
+	Status         string             `json:"status"`
+	Conclusion     pgtype.Text        `json:"conclusion"`
+	RunAttempt     int32              `json:"run_attempt"`
+	HtmlUrl        pgtype.Text        `json:"html_url"`
+	StartedAt      pgtype.Timestamptz `json:"started_at"`
+	CompletedAt    pgtype.Timestamptz `json:"completed_at"`
+	DurationMs     pgtype.Int8        `json:"duration_ms"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Issue and pull request comments normalized from issue_comment events
+type Comment struct {
+	ID             int32              `json:"id"`
+	CommentID      int64              `json:"comment_id"`
+	RepositoryName string             `json:"repository_name"`
+	IssueNumber    int32              `json:"issue_number"`
+	IsPullRequest  bool               `json:"is_pull_request"`
+	AuthorLogin    pgtype.Text        `json:"author_login"`
+	Body           string             `json:"body"`
+	HtmlUrl        pgtype.Text        `json:"html_url"`
+	Deleted        bool               `json:"deleted"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Commits normalized from push events for analytics
+type Commit struct {
+	ID             int32              `json:"id"`
+	Sha            string             `json:"sha"`
+	RepositoryName string             `json:"repository_name"`
+	Ref            string             `json:"ref"`
+	AuthorName     pgtype.Text        `json:"author_name"`
+	AuthorEmail    pgtype.Text        `json:"author_email"`
+	AuthorLogin    pgtype.Text        `json:"author_login"`
+	Message        string             `json:"message"`
+	CommittedAt    pgtype.Timestamptz `json:"committed_at"`
+	DeliveryID     string             `json:"delivery_id"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
+// Stored deliveries per repository and hour for heat maps and activity calendars
+type DeliveryActivity struct {
+	Hour           pgtype.Timestamptz `json:"hour"`
+	RepositoryName string             `json:"repository_name"`
+	Events         int64              `json:"events"`
+}
+
+// Final state of finished deployments for DORA metrics
+type Deployment struct {
+	ID             int32              `json:"id"`
+	DeploymentID   int64              `json:"deployment_id"`
+	RepositoryName string             `json:"repository_name"`
+	Environment    string             `json:"environment"`
+	Ref            string             `json:"ref"`
+	Sha            string             `json:"sha"`
+	State          string             `json:"state"`
+	FinishedAt     pgtype.Timestamptz `json:"finished_at"`
+}
+
+// Latest state of GitHub Discussions for search and notification routing
+type Discussion struct {
+	ID               int32              `json:"id"`
+	RepositoryName   string             `json:"repository_name"`
+	DiscussionNumber int32              `json:"discussion_number"`
+	Title            string             `json:"title"`
+	Body             string             `json:"body"`
+	Category         pgtype.Text        `json:"category"`
+	State            string             `json:"state"`
+	Answered         bool               `json:"answered"`
+	AuthorLogin      pgtype.Text        `json:"author_login"`
+	HtmlUrl          pgtype.Text        `json:"html_url"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Latest body of GitHub Discussions comments for search
+type DiscussionComment struct {
+	ID               int32              `json:"id"`
+	CommentID        int64              `json:"comment_id"`
+	RepositoryName   string             `json:"repository_name"`
+	DiscussionNumber int32              `json:"discussion_number"`
+	Body             string             `json:"body"`
+	AuthorLogin      pgtype.Text        `json:"author_login"`
+	HtmlUrl          pgtype.Text        `json:"html_url"`
+	Deleted          bool               `json:"deleted"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Hash chain over stored events for tamper evidence
+type EventChain struct {
+	Seq            int64              `json:"seq"`
+	EventID        int32              `json:"event_id"`
+	DeliveryID     string             `json:"delivery_id"`
+	PayloadSha256  string             `json:"payload_sha256"`
+	PrevHash       string             `json:"prev_hash"`
+	Hash           string             `json:"hash"`
+	EventCreatedAt pgtype.Timestamptz `json:"event_created_at"`
+	ChainedAt      pgtype.Timestamptz `json:"chained_at"`
+}
+
+// Signed checkpoints of the event hash chain
+type EventChainCheckpoint struct {
+	Seq       int64              `json:"seq"`
+	Hash      string             `json:"hash"`
+	KeyID     string             `json:"key_id"`
+	Signature string             `json:"signature"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+// Payload fields of stored events read by reports, in plaintext
+type EventDetail struct {
+	DeliveryID      string             `json:"delivery_id"`
+	SourceUpdatedAt pgtype.Timestamptz `json:"source_updated_at"`
+	Outcome         pgtype.Text        `json:"outcome"`
+	Subject         pgtype.Text        `json:"subject"`
+}
+
+// Signatures verified for each stored event, from GitHub through each relay
+type EventProvenance struct {
+	DeliveryID string             `json:"delivery_id"`
+	Chain      []byte             `json:"chain"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+// GitHub hooks pointing at this server, from their ping events
+type HookRegistration struct {
+	ID            int32              `json:"id"`
+	HookID        int64              `json:"hook_id"`
+	HookType      string             `json:"hook_type"`
+	Target        string             `json:"target"`
+	Active        bool               `json:"active"`
+	Events        []string           `json:"events"`
+	Url           string             `json:"url"`
+	ContentType   string             `json:"content_type"`
+	InsecureSsl   bool               `json:"insecure_ssl"`
+	Zen           string             `json:"zen"`
+	DeliveryID    string             `json:"delivery_id"`
+	HookCreatedAt pgtype.Timestamptz `json:"hook_created_at"`
+	HookUpdatedAt pgtype.Timestamptz `json:"hook_updated_at"`
+	PingedAt      pgtype.Timestamptz `json:"pinged_at"`
+}
+
+// Dynamic instance settings applied from config bundles
+type InstanceSetting struct {
+	Name      string             `json:"name"`
+	Value     string             `json:"value"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Repositories and time ranges under legal hold, whose events retention and erasure keep
+type LegalHold struct {
+	ID             int32              `json:"id"`
+	RepositoryName pgtype.Text        `json:"repository_name"`
+	StartsAt       pgtype.Timestamptz `json:"starts_at"`
+	EndsAt         pgtype.Timestamptz `json:"ends_at"`
+	Reason         string             `json:"reason"`
+	CreatedBy      string             `json:"created_by"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	ReleasedBy     pgtype.Text        `json:"released_by"`
+	ReleasedAt     pgtype.Timestamptz `json:"released_at"`
+}
+
+// Legal holds placed and released and the deletions they blocked
+type LegalHoldAudit struct {
+	ID        int64              `json:"id"`
+	Action    string             `json:"action"`
+	HoldIds   []int32            `json:"hold_ids"`
+	Actor     string             `json:"actor"`
+	Details   string             `json:"details"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+// Stored webhook events waiting to be published to forwarders
+type Outbox struct {
+	ID          int64              `json:"id"`
+	DeliveryID  string             `json:"delivery_id"`
+	Target      string             `json:"target"`
+	Attempts    int32              `json:"attempts"`
+	LastError   pgtype.Text        `json:"last_error"`
+	VisibleAt   pgtype.Timestamptz `json:"visible_at"`
+	DeliveredAt pgtype.Timestamptz `json:"delivered_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+// Route and setting changes with their approval decisions
+type PendingChange struct {
+	ID          int32              `json:"id"`
+	SettingName string             `json:"setting_name"`
+	RouteMatch  string             `json:"route_match"`
+	Value       string             `json:"value"`
+	Status      string             `json:"status"`
+	RequestedBy string             `json:"requested_by"`
+	RequestedAt pgtype.Timestamptz `json:"requested_at"`
+	ExpiresAt   pgtype.Timestamptz `json:"expires_at"`
+	DecidedBy   pgtype.Text        `json:"decided_by"`
+	DecidedAt   pgtype.Timestamptz `json:"decided_at"`
+}
+
+// Projects (v2) items moving between columns, archived or deleted
+type ProjectItemMove struct {
+	ID            int32              `json:"id"`
+	DeliveryID    string             `json:"delivery_id"`
+	Organization  string             `json:"organization"`
+	ProjectNodeID string             `json:"project_node_id"`
+	ItemNodeID    string             `json:"item_node_id"`
+	ContentNodeID pgtype.Text        `json:"content_node_id"`
+	ContentType   pgtype.Text        `json:"content_type"`
+	Action        string             `json:"action"`
+	FieldName     pgtype.Text        `json:"field_name"`
+	FromColumn    pgtype.Text        `json:"from_column"`
+	ToColumn      pgtype.Text        `json:"to_column"`
+	Actor         string             `json:"actor"`
+	MovedAt       pgtype.Timestamptz `json:"moved_at"`
+}
+
+// Latest state of pull requests normalized from pull_request events
+type PullRequest struct {
+	ID             int32              `json:"id"`
+	RepositoryName string             `json:"repository_name"`
+	PrNumber       int32              `json:"pr_number"`
+	Title          string             `json:"title"`
+	State          string             `json:"state"`
+	Draft          bool               `json:"draft"`
+	Merged         bool               `json:"merged"`
+	HeadRef        string             `json:"head_ref"`
+	BaseRef        string             `json:"base_ref"`
+	AuthorLogin    pgtype.Text        `json:"author_login"`
+	HtmlUrl        pgtype.Text        `json:"html_url"`
+	LastAction     string             `json:"last_action"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+	ClosedAt       pgtype.Timestamptz `json:"closed_at"`
+	MergedAt       pgtype.Timestamptz `json:"merged_at"`
+}
+
+// Branches and tags created and deleted, for auditing publishing activity
+type Ref struct {
+	ID             int32              `json:"id"`
+	DeliveryID     string             `json:"delivery_id"`
+	RepositoryName string             `json:"repository_name"`
+	RefType        string             `json:"ref_type"`
+	Ref            string             `json:"ref"`
+	Action         string             `json:"action"`
+	PusherType     pgtype.Text        `json:"pusher_type"`
+	SenderLogin    pgtype.Text        `json:"sender_login"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
+// Latest state of each release for changelogs
+type Release struct {
+	ID              int32              `json:"id"`
+	ReleaseID       int64              `json:"release_id"`
+	RepositoryName  string             `json:"repository_name"`
+	TagName         string             `json:"tag_name"`
+	TargetCommitish pgtype.Text        `json:"target_commitish"`
+	Name            pgtype.Text        `json:"name"`
+	Body            pgtype.Text        `json:"body"`
+	Draft           bool               `json:"draft"`
+	Prerelease      bool               `json:"prerelease"`
+	AuthorLogin     pgtype.Text        `json:"author_login"`
+	HtmlUrl         pgtype.Text        `json:"html_url"`
+	LastAction      string             `json:"last_action"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	PublishedAt     pgtype.Timestamptz `json:"published_at"`
+	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Monthly storage and processing usage per repository for cost attribution
+type RepositoryUsage struct {
+	Month          pgtype.Date `json:"month"`
+	RepositoryName string      `json:"repository_name"`
+	Events         int64       `json:"events"`
+	BytesStored    int64       `json:"bytes_stored"`
+	ProcessingUs   int64       `json:"processing_us"`
+}
+
+// Operator-defined conditions over events and the actions run when they hold
+type Rule struct {
+	Name      string             `json:"name"`
+	Condition string             `json:"condition"`
+	Actions   []byte             `json:"actions"`
+	CreatedBy string             `json:"created_by"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Groups provisioned over SCIM, mapped to roles by SCIM_GROUP_ROLES
+type ScimGroup struct {
+	ID          int32              `json:"id"`
+	ExternalID  string             `json:"external_id"`
+	DisplayName string             `json:"display_name"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Members of the groups provisioned over SCIM
+type ScimGroupMember struct {
+	GroupID int32 `json:"group_id"`
+	UserID  int32 `json:"user_id"`
+}
+
+// Dashboard users provisioned over SCIM
+type ScimUser struct {
+	ID           int32              `json:"id"`
+	ExternalID   string             `json:"external_id"`
+	UserName     string             `json:"user_name"`
+	DisplayName  string             `json:"display_name"`
+	Email        string             `json:"email"`
+	PasswordHash pgtype.Text        `json:"password_hash"`
+	Active       bool               `json:"active"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Organization, repository and branch overrides of instance settings
+type ScopedSetting struct {
+	Scope     string             `json:"scope"`
+	Name      string             `json:"name"`
+	Value     string             `json:"value"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Tracks dependabot, code scanning and secret scanning alerts for SLA reporting
+type SecurityAlert struct {
+	ID             int32              `json:"id"`
+	Kind           string             `json:"kind"`
+	RepositoryName string             `json:"repository_name"`
+	AlertNumber    int32              `json:"alert_number"`
+	Severity       string             `json:"severity"`
+	State          string             `json:"state"`
+	Summary        pgtype.Text        `json:"summary"`
+	HtmlUrl        pgtype.Text        `json:"html_url"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	ResolvedAt     pgtype.Timestamptz `json:"resolved_at"`
+	Resolution     pgtype.Text        `json:"resolution"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Incident notes shown on the public status page
+type StatusIncident struct {
+	ID         int32              `json:"id"`
+	Title      string             `json:"title"`
+	Body       string             `json:"body"`
+	Impact     string             `json:"impact"`
+	Status     string             `json:"status"`
+	CreatedBy  string             `json:"created_by"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	ResolvedAt pgtype.Timestamptz `json:"resolved_at"`
+}
+
 // Stores GitHub webhook events for push, issue_comment, and pull_request events
 type WebhookEvent struct {
 	ID             int32              `json:"id"`
@@ -18,4 +443,39 @@ type WebhookEvent struct {
 	Action         pgtype.Text        `json:"action"`
 	Payload        []byte             `json:"payload"`
 	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	PayloadSha256  pgtype.Text        `json:"payload_sha256"`
+}
+
+// Webhook events past their retention period that were archived rather than deleted
+type WebhookEventsArchive struct {
+	ID             int32              `json:"id"`
+	DeliveryID     string             `json:"delivery_id"`
+	EventType      string             `json:"event_type"`
+	RepositoryName pgtype.Text        `json:"repository_name"`
+	SenderLogin    pgtype.Text        `json:"sender_login"`
+	Action         pgtype.Text        `json:"action"`
+	Payload        []byte             `json:"payload"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	ArchivedAt     pgtype.Timestamptz `json:"archived_at"`
+	PayloadSha256  pgtype.Text        `json:"payload_sha256"`
+}
+
+// Stored webhook events waiting to be processed
+type WorkItem struct {
+	ID              int64              `json:"id"`
+	DeliveryID      string             `json:"delivery_id"`
+	Attempts        int32              `json:"attempts"`
+	LastError       pgtype.Text        `json:"last_error"`
+	VisibleAt       pgtype.Timestamptz `json:"visible_at"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	FailedProcessor pgtype.Text        `json:"failed_processor"`
+	QuarantinedAt   pgtype.Timestamptz `json:"quarantined_at"`
+	TraceParent     pgtype.Text        `json:"trace_parent"`
+}
+
+// Who paused processing of the work queue, while it is paused
+type WorkQueuePause struct {
+	Singleton bool               `json:"singleton"`
+	PausedBy  string             `json:"paused_by"`
+	PausedAt  pgtype.Timestamptz `json:"paused_at"`
 }
@@ -30,10 +30,13 @@ INSERT INTO webhook_events (
     repository_name,
     sender_login,
     action,
-    payload
+    payload,
+    payload_sha256
 ) VALUES (
-    $1, $2, $3, $4, $5, $6
-) RETURNING id, delivery_id, event_type, repository_name, sender_login, action, payload, created_at
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (delivery_id) DO NOTHING
+RETURNING id, delivery_id, event_type, repository_name, sender_login, action, payload, created_at, payload_sha256
 `
 
 type CreateWebhookEventParams struct {
@@ -43,6 +46,7 @@ type CreateWebhookEventParams struct {
 	SenderLogin    pgtype.Text `json:"sender_login"`
 	Action         pgtype.Text `json:"action"`
 	Payload        []byte      `json:"payload"`
+	PayloadSha256  pgtype.Text `json:"payload_sha256"`
 }
 
 func (q *Queries) CreateWebhookEvent(ctx context.Context, arg CreateWebhookEventParams) (WebhookEvent, error) {
@@ -53,6 +57,7 @@ func (q *Queries) CreateWebhookEvent(ctx context.Context, arg CreateWebhookEvent
 		arg.SenderLogin,
 		arg.Action,
 		arg.Payload,
+		arg.PayloadSha256,
 	)
 	var i WebhookEvent
 	err := row.Scan(
@@ -64,22 +69,95 @@ func (q *Queries) CreateWebhookEvent(ctx context.Context, arg CreateWebhookEvent
 		&i.Action,
 		&i.Payload,
 		&i.CreatedAt,
+		&i.PayloadSha256,
 	)
 	return i, err
 }
 
+const createWebhookEvents = `-- name: CreateWebhookEvents :many
+INSERT INTO webhook_events (
+    delivery_id,
+    event_type,
+    repository_name,
+    sender_login,
+    action,
+    payload,
+    payload_sha256
+)
+SELECT delivery_id, event_type, NULLIF(repository_name, ''), NULLIF(sender_login, ''), NULLIF(action, ''), payload::jsonb, NULLIF(payload_sha256, '')
+FROM unnest(
+    $1::text[],
+    $2::text[],
+    $3::text[],
+    $4::text[],
+    $5::text[],
+    $6::text[],
+    $7::text[]
+) AS batch (delivery_id, event_type, repository_name, sender_login, action, payload, payload_sha256)
+ON CONFLICT (delivery_id) DO NOTHING
+RETURNING delivery_id
+`
+
+type CreateWebhookEventsParams struct {
+	DeliveryIds     []string `json:"delivery_ids"`
+	EventTypes      []string `json:"event_types"`
+	RepositoryNames []string `json:"repository_names"`
+	SenderLogins    []string `json:"sender_logins"`
+	Actions         []string `json:"actions"`
+	Payloads        []string `json:"payloads"`
+	PayloadSha256s  []string `json:"payload_sha256s"`
+}
+
+// Stores a batch of events in one statement, returning the delivery IDs that
+// were not already stored. Empty strings are stored as NULL.
+func (q *Queries) CreateWebhookEvents(ctx context.Context, arg CreateWebhookEventsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, createWebhookEvents,
+		arg.DeliveryIds,
+		arg.EventTypes,
+		arg.RepositoryNames,
+		arg.SenderLogins,
+		arg.Actions,
+		arg.Payloads,
+		arg.PayloadSha256s,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var delivery_id string
+		if err := rows.Scan(&delivery_id); err != nil {
+			return nil, err
+		}
+		items = append(items, delivery_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const deleteOldWebhookEvents = `-- name: DeleteOldWebhookEvents :exec
 DELETE FROM webhook_events 
 WHERE created_at < $1
+  AND NOT EXISTS (
+      SELECT 1 FROM legal_holds h
+      WHERE h.released_at IS NULL
+        AND (h.repository_name IS NULL OR h.repository_name = webhook_events.repository_name)
+        AND (h.starts_at IS NULL OR webhook_events.created_at >= h.starts_at)
+        AND (h.ends_at IS NULL OR webhook_events.created_at < h.ends_at)
+  )
 `
 
+// Events under legal hold are kept.
 func (q *Queries) DeleteOldWebhookEvents(ctx context.Context, createdAt pgtype.Timestamptz) error {
 	_, err := q.db.Exec(ctx, deleteOldWebhookEvents, createdAt)
 	return err
 }
 
 const getWebhookEventByDeliveryID = `-- name: GetWebhookEventByDeliveryID :one
-SELECT id, delivery_id, event_type, repository_name, sender_login, action, payload, created_at FROM webhook_events 
+SELECT id, delivery_id, event_type, repository_name, sender_login, action, payload, created_at, payload_sha256 FROM webhook_events 
 WHERE delivery_id = $1
 `
 
@@ -95,12 +173,181 @@ func (q *Queries) GetWebhookEventByDeliveryID(ctx context.Context, deliveryID st
 		&i.Action,
 		&i.Payload,
 		&i.CreatedAt,
+		&i.PayloadSha256,
 	)
 	return i, err
 }
 
+const listCommunityEventsSince = `-- name: ListCommunityEventsSince :many
+SELECT
+    COALESCE(e.repository_name, d.subject, '')::text AS subject,
+    e.event_type,
+    COALESCE(e.action, '')::text AS action,
+    COALESCE(e.sender_login, '')::text AS sender_login,
+    e.created_at
+FROM webhook_events e
+LEFT JOIN event_details d ON d.delivery_id = e.delivery_id
+WHERE e.event_type IN ('star', 'watch', 'fork', 'sponsorship')
+  AND e.created_at >= $1
+ORDER BY e.created_at ASC
+`
+
+type ListCommunityEventsSinceRow struct {
+	Subject     string             `json:"subject"`
+	EventType   string             `json:"event_type"`
+	Action      string             `json:"action"`
+	SenderLogin string             `json:"sender_login"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+func (q *Queries) ListCommunityEventsSince(ctx context.Context, createdAt pgtype.Timestamptz) ([]ListCommunityEventsSinceRow, error) {
+	rows, err := q.db.Query(ctx, listCommunityEventsSince, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCommunityEventsSinceRow
+	for rows.Next() {
+		var i ListCommunityEventsSinceRow
+		if err := rows.Scan(
+			&i.Subject,
+			&i.EventType,
+			&i.Action,
+			&i.SenderLogin,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeliveries = `-- name: ListDeliveries :many
+SELECT
+    e.delivery_id,
+    e.event_type,
+    e.repository_name,
+    e.sender_login,
+    e.action,
+    e.created_at,
+    octet_length(e.payload::text)::int AS payload_bytes,
+    w.attempts,
+    w.last_error,
+    w.failed_processor,
+    w.quarantined_at
+FROM webhook_events e
+LEFT JOIN work_items w ON w.delivery_id = e.delivery_id
+WHERE ($1::text = '' OR e.event_type = $1::text)
+  AND ($2::text = '' OR e.repository_name = $2::text)
+ORDER BY e.created_at DESC, e.id DESC
+LIMIT $3
+`
+
+type ListDeliveriesParams struct {
+	EventType      string `json:"event_type"`
+	RepositoryName string `json:"repository_name"`
+	RowLimit       int32  `json:"row_limit"`
+}
+
+type ListDeliveriesRow struct {
+	DeliveryID      string             `json:"delivery_id"`
+	EventType       string             `json:"event_type"`
+	RepositoryName  pgtype.Text        `json:"repository_name"`
+	SenderLogin     pgtype.Text        `json:"sender_login"`
+	Action          pgtype.Text        `json:"action"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	PayloadBytes    int32              `json:"payload_bytes"`
+	Attempts        pgtype.Int4        `json:"attempts"`
+	LastError       pgtype.Text        `json:"last_error"`
+	FailedProcessor pgtype.Text        `json:"failed_processor"`
+	QuarantinedAt   pgtype.Timestamptz `json:"quarantined_at"`
+}
+
+// Most recent events with their work queue state, optionally filtered by
+// event type and repository. Events without a work item were processed.
+func (q *Queries) ListDeliveries(ctx context.Context, arg ListDeliveriesParams) ([]ListDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, listDeliveries, arg.EventType, arg.RepositoryName, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDeliveriesRow
+	for rows.Next() {
+		var i ListDeliveriesRow
+		if err := rows.Scan(
+			&i.DeliveryID,
+			&i.EventType,
+			&i.RepositoryName,
+			&i.SenderLogin,
+			&i.Action,
+			&i.CreatedAt,
+			&i.PayloadBytes,
+			&i.Attempts,
+			&i.LastError,
+			&i.FailedProcessor,
+			&i.QuarantinedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listWebhookEvents = `-- name: ListWebhookEvents :many
+SELECT id, delivery_id, event_type, repository_name, sender_login, action, payload, created_at, payload_sha256 FROM webhook_events
+WHERE ($1::text = '' OR event_type = $1::text)
+  AND ($2::text = '' OR repository_name = $2::text)
+ORDER BY created_at DESC, id DESC
+LIMIT $3
+`
+
+type ListWebhookEventsParams struct {
+	EventType      string `json:"event_type"`
+	RepositoryName string `json:"repository_name"`
+	RowLimit       int32  `json:"row_limit"`
+}
+
+// Most recent events, optionally filtered by event type and repository.
+func (q *Queries) ListWebhookEvents(ctx context.Context, arg ListWebhookEventsParams) ([]WebhookEvent, error) {
+	rows, err := q.db.Query(ctx, listWebhookEvents, arg.EventType, arg.RepositoryName, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookEvent
+	for rows.Next() {
+		var i WebhookEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.DeliveryID,
+			&i.EventType,
+			&i.RepositoryName,
+			&i.SenderLogin,
+			&i.Action,
+			&i.Payload,
+			&i.CreatedAt,
+			&i.PayloadSha256,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listWebhookEventsByRepository = `-- name: ListWebhookEventsByRepository :many
-SELECT id, delivery_id, event_type, repository_name, sender_login, action, payload, created_at FROM webhook_events 
+SELECT id, delivery_id, event_type, repository_name, sender_login, action, payload, created_at, payload_sha256 FROM webhook_events 
 WHERE repository_name = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
@@ -130,6 +377,7 @@ func (q *Queries) ListWebhookEventsByRepository(ctx context.Context, arg ListWeb
 			&i.Action,
 			&i.Payload,
 			&i.CreatedAt,
+			&i.PayloadSha256,
 		); err != nil {
 			return nil, err
 		}
@@ -142,7 +390,7 @@ func (q *Queries) ListWebhookEventsByRepository(ctx context.Context, arg ListWeb
 }
 
 const listWebhookEventsByType = `-- name: ListWebhookEventsByType :many
-SELECT id, delivery_id, event_type, repository_name, sender_login, action, payload, created_at FROM webhook_events 
+SELECT id, delivery_id, event_type, repository_name, sender_login, action, payload, created_at, payload_sha256 FROM webhook_events 
 WHERE event_type = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
@@ -172,6 +420,7 @@ func (q *Queries) ListWebhookEventsByType(ctx context.Context, arg ListWebhookEv
 			&i.Action,
 			&i.Payload,
 			&i.CreatedAt,
+			&i.PayloadSha256,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,186 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+// DefaultDir is the spool directory used when DEAD_LETTER_DIR is not set
+const DefaultDir = "deadletter"
+
+// StoreFunc writes a spooled event to the database
+type StoreFunc func(ctx context.Context, params db.CreateWebhookEventParams) error
+
+// Entry is a failed database write persisted to the spool
+type Entry struct {
+	Params   db.CreateWebhookEventParams `json:"params"`
+	Error    string                      `json:"error"`
+	FailedAt time.Time                   `json:"failed_at"`
+	Attempts int                         `json:"attempts"`
+
+	path string
+}
+
+// Spool persists failed database writes to a local directory so they can be
+// retried once the database is reachable again
+type Spool struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewSpool creates the spool directory if needed
+func NewSpool(dir string) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create dead-letter directory: %w", err)
+	}
+	return &Spool{dir: dir}, nil
+}
+
+// Dir returns the spool directory
+func (s *Spool) Dir() string {
+	return s.dir
+}
+
+// Write spools a failed write. Files are written atomically so a crash never
+// leaves a partial entry behind.
+func (s *Spool) Write(params db.CreateWebhookEventParams, cause error) error {
+	entry := Entry{
+		Params:   params,
+		FailedAt: time.Now().UTC(),
+		Attempts: 1,
+	}
+	if cause != nil {
+		entry.Error = cause.Error()
+	}
+
+	name := fmt.Sprintf("%d-%s.json", entry.FailedAt.UnixNano(), sanitize(params.DeliveryID))
+	return s.save(filepath.Join(s.dir, name), entry)
+}
+
+// List returns spooled entries, oldest first
+func (s *Spool) List() ([]Entry, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	entries := make([]Entry, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var entry Entry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			log.Printf("Skipping unreadable dead-letter entry %s: %v", path, err)
+			continue
+		}
+		entry.path = path
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Redrive retries every spooled entry, removing those that are stored
+// successfully. Entries that fail again stay in the spool with their attempt
+// count incremented.
+func (s *Spool) Redrive(ctx context.Context, store StoreFunc) (redriven, failed int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.List()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return redriven, failed, ctx.Err()
+		}
+
+		if err := store(ctx, entry.Params); err != nil {
+			failed++
+			entry.Attempts++
+			entry.Error = err.Error()
+			if saveErr := s.save(entry.path, entry); saveErr != nil {
+				log.Printf("Failed to update dead-letter entry %s: %v", entry.path, saveErr)
+			}
+			continue
+		}
+
+		if err := os.Remove(entry.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to remove redriven dead-letter entry %s: %v", entry.path, err)
+		}
+		redriven++
+	}
+	return redriven, failed, nil
+}
+
+// RunRetryLoop redrives the spool every interval until ctx is cancelled
+func (s *Spool) RunRetryLoop(ctx context.Context, interval time.Duration, store StoreFunc) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			redriven, failed, err := s.Redrive(ctx, store)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Printf("Dead-letter retry failed: %v", err)
+				continue
+			}
+			if redriven > 0 || failed > 0 {
+				log.Printf("Dead-letter retry: %d redriven, %d still failing", redriven, failed)
+			}
+		}
+	}
+}
+
+// save writes an entry to path via a temporary file and rename
+func (s *Spool) save(path string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// sanitize makes a delivery ID safe to use in a file name
+func sanitize(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, value)
+}
@@ -0,0 +1,88 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+// TestSpool_WriteList tests that written entries are listed oldest first
+func TestSpool_WriteList(t *testing.T) {
+	spool, err := NewSpool(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewSpool failed: %v", err)
+	}
+
+	for _, id := range []string{"first", "second"} {
+		params := db.CreateWebhookEventParams{DeliveryID: id, EventType: "push", Payload: []byte(`{}`)}
+		if err := spool.Write(params, errors.New("connection refused")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	entries, err := spool.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Params.DeliveryID != "first" || entries[1].Params.DeliveryID != "second" {
+		t.Errorf("Unexpected order: %s, %s", entries[0].Params.DeliveryID, entries[1].Params.DeliveryID)
+	}
+	if entries[0].Error != "connection refused" || entries[0].Attempts != 1 {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+	if string(entries[0].Params.Payload) != `{}` {
+		t.Errorf("Expected payload to round trip, got %s", entries[0].Params.Payload)
+	}
+}
+
+// TestSpool_Redrive tests that stored entries are removed and failures are kept
+func TestSpool_Redrive(t *testing.T) {
+	spool, err := NewSpool(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewSpool failed: %v", err)
+	}
+	spool.Write(db.CreateWebhookEventParams{DeliveryID: "ok", EventType: "push"}, nil)
+	spool.Write(db.CreateWebhookEventParams{DeliveryID: "bad", EventType: "push"}, nil)
+
+	store := func(ctx context.Context, params db.CreateWebhookEventParams) error {
+		if params.DeliveryID == "bad" {
+			return errors.New("still down")
+		}
+		return nil
+	}
+
+	redriven, failed, err := spool.Redrive(context.Background(), store)
+	if err != nil {
+		t.Fatalf("Redrive failed: %v", err)
+	}
+	if redriven != 1 || failed != 1 {
+		t.Errorf("Expected 1 redriven and 1 failed, got %d and %d", redriven, failed)
+	}
+
+	entries, _ := spool.List()
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 remaining entry, got %d", len(entries))
+	}
+	if entries[0].Params.DeliveryID != "bad" || entries[0].Attempts != 2 || entries[0].Error != "still down" {
+		t.Errorf("Unexpected remaining entry: %+v", entries[0])
+	}
+}
+
+// TestSanitize tests that delivery IDs are made safe for file names
+func TestSanitize(t *testing.T) {
+	tests := map[string]string{
+		"":                 "unknown",
+		"abc-123_DEF":      "abc-123_DEF",
+		"../../etc/passwd": "______etc_passwd",
+	}
+	for input, expected := range tests {
+		if got := sanitize(input); got != expected {
+			t.Errorf("sanitize(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package discussion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/chatops"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// AnyCategory is the route category that matches every discussion
+const AnyCategory = "*"
+
+// notifierAssociations are the author associations of the comments that may
+// run /notify: the repository's owner, organization members and collaborators
+var notifierAssociations = map[string]bool{"OWNER": true, "MEMBER": true, "COLLABORATOR": true}
+
+// Route sends discussion activity in a category to a channel
+type Route struct {
+	Category string
+	Channel  forwarder.Forwarder
+}
+
+// ParseRoutes parses a comma-separated list of category=url pairs, where the
+// category is a discussion category slug or "*" for all categories, e.g.
+// "q-a=https://chat.example.com/support,*=https://chat.example.com/all"
+func ParseRoutes(list string) ([]Route, error) {
+	var routes []Route
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		category, endpoint, ok := strings.Cut(pair, "=")
+		if !ok || category == "" {
+			return nil, fmt.Errorf("invalid discussion route %q", pair)
+		}
+		channel, err := forwarder.NewHTTPForwarder(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, Route{Category: strings.ToLower(category), Channel: channel})
+	}
+	return routes, nil
+}
+
+// notifiedActions are the discussion actions that notify channels
+var notifiedActions = map[string]bool{
+	webhook.DiscussionEvent + ".created":        true,
+	webhook.DiscussionEvent + ".answered":       true,
+	webhook.DiscussionCommentEvent + ".created": true,
+}
+
+// Router delivers discussion activity to the channels for its category
+type Router struct {
+	routes []Route
+}
+
+// NewRouter creates a router for the given routes
+func NewRouter(routes []Route) *Router {
+	return &Router{routes: routes}
+}
+
+// channels returns the channels for a category slug
+func (r *Router) channels(category string, includeAny bool) []forwarder.Forwarder {
+	category = strings.ToLower(category)
+	var channels []forwarder.Forwarder
+	for _, route := range r.routes {
+		if route.Category == category || (includeAny && route.Category == AnyCategory) {
+			channels = append(channels, route.Channel)
+		}
+	}
+	return channels
+}
+
+// Route delivers new discussions, answers and comments to the channels
+// routed for the discussion's category
+func (r *Router) Route(ctx context.Context, deliveryID string, activity *webhook.DiscussionActivity) {
+	if !notifiedActions[activity.EventType+"."+activity.Action] {
+		return
+	}
+	channels := r.channels(activity.Discussion.Category.Slug, true)
+	if len(channels) == 0 {
+		return
+	}
+	r.deliver(ctx, channels, deliveryID, activity)
+}
+
+// Notify delivers a discussion to the channels routed for a category,
+// regardless of the discussion's own category. It backs the /notify command.
+func (r *Router) Notify(ctx context.Context, category, deliveryID string, activity *webhook.DiscussionActivity) error {
+	channels := r.channels(category, false)
+	if len(channels) == 0 {
+		return fmt.Errorf("no discussion route for category %q", category)
+	}
+	r.deliver(ctx, channels, deliveryID, activity)
+	return nil
+}
+
+func (r *Router) deliver(ctx context.Context, channels []forwarder.Forwarder, deliveryID string, activity *webhook.DiscussionActivity) {
+	payload, err := json.Marshal(activity)
+	if err != nil {
+		log.Printf("Failed to encode discussion activity: %v", err)
+		return
+	}
+	forwarder.ForwardAll(ctx, channels, forwarder.Event{
+		DeliveryID: deliveryID,
+		EventType:  activity.EventType,
+		Action:     activity.Action,
+		Repository: activity.Repository,
+		Payload:    payload,
+	})
+}
+
+// NotifyCommand returns the handler for "/notify <category>", which sends
+// the discussion a comment was posted on to the channels for that category.
+// Like /approve and /reject it is limited to trusted authors, here the
+// repository's collaborators, as anyone may comment on a public discussion.
+func NotifyCommand(router *Router) chatops.Handler {
+	return func(ctx context.Context, invocation chatops.Invocation) error {
+		if len(invocation.Command.Args) != 1 {
+			return fmt.Errorf("usage: /notify <category>")
+		}
+		activity, err := webhook.ParseDiscussionActivity(invocation.Source, invocation.Payload)
+		if err != nil {
+			return err
+		}
+		if activity.Comment == nil || !notifierAssociations[activity.Comment.AuthorAssociation] {
+			return fmt.Errorf("%s may not notify channels", invocation.Actor)
+		}
+		deliveryID := fmt.Sprintf("notify-%s-%d", strings.ReplaceAll(activity.Repository, "/", "-"), activity.Discussion.Number)
+		return router.Notify(ctx, invocation.Command.Args[0], deliveryID, activity)
+	}
+}
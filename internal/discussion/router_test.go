@@ -0,0 +1,96 @@
+package discussion
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/chatops"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+type recordingForwarder struct {
+	events []forwarder.Event
+}
+
+func (rf *recordingForwarder) Name() string { return "recording" }
+
+func (rf *recordingForwarder) Forward(ctx context.Context, event forwarder.Event) error {
+	rf.events = append(rf.events, event)
+	return nil
+}
+
+func testActivity(eventType, action, category string) *webhook.DiscussionActivity {
+	activity := &webhook.DiscussionActivity{EventType: eventType, Action: action, Repository: "octo-org/hello-world"}
+	activity.Discussion.Number = 7
+	activity.Discussion.Category.Slug = category
+	return activity
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes("Q-A=https://chat.example.com/support, *=https://chat.example.com/all")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(routes) != 2 || routes[0].Category != "q-a" || routes[1].Category != AnyCategory {
+		t.Errorf("Unexpected routes: %+v", routes)
+	}
+
+	for _, list := range []string{"q-a", "=https://chat.example.com", "q-a=ftp://chat.example.com"} {
+		if _, err := ParseRoutes(list); err == nil {
+			t.Errorf("Expected error for %q", list)
+		}
+	}
+}
+
+func TestRouter_Route(t *testing.T) {
+	support := &recordingForwarder{}
+	all := &recordingForwarder{}
+	router := NewRouter([]Route{{Category: "q-a", Channel: support}, {Category: AnyCategory, Channel: all}})
+
+	router.Route(context.Background(), "d1", testActivity(webhook.DiscussionEvent, "created", "q-a"))
+	router.Route(context.Background(), "d2", testActivity(webhook.DiscussionEvent, "created", "ideas"))
+	router.Route(context.Background(), "d3", testActivity(webhook.DiscussionEvent, "edited", "q-a"))
+	router.Route(context.Background(), "d4", testActivity(webhook.DiscussionCommentEvent, "created", "q-a"))
+
+	if len(support.events) != 2 {
+		t.Errorf("Expected 2 q-a notifications, got %d", len(support.events))
+	}
+	if len(all.events) != 3 {
+		t.Errorf("Expected 3 notifications on the catch-all route, got %d", len(all.events))
+	}
+}
+
+func TestNotifyCommand(t *testing.T) {
+	security := &recordingForwarder{}
+	router := NewRouter([]Route{{Category: "security", Channel: security}})
+	notify := NotifyCommand(router)
+
+	payload := []byte(`{"action":"created","discussion":{"number":7},"comment":{"id":1,"body":"/notify security","author_association":"COLLABORATOR"},"repository":{"full_name":"octo-org/hello-world"}}`)
+	invocation := chatops.Invocation{
+		Command: chatops.Command{Name: "notify", Args: []string{"security"}},
+		Actor:   "octocat",
+		Source:  webhook.DiscussionCommentEvent,
+		Payload: payload,
+	}
+	if err := notify(context.Background(), invocation); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(security.events) != 1 || security.events[0].Repository != "octo-org/hello-world" {
+		t.Errorf("Expected discussion to be sent to the security channel, got %+v", security.events)
+	}
+
+	invocation.Command.Args = []string{"unknown"}
+	if err := notify(context.Background(), invocation); err == nil {
+		t.Error("Expected error for a category without routes")
+	}
+
+	invocation.Command.Args = []string{"security"}
+	invocation.Payload = []byte(`{"action":"created","discussion":{"number":7},"comment":{"id":1,"body":"/notify security","author_association":"NONE"},"repository":{"full_name":"octo-org/hello-world"}}`)
+	if err := notify(context.Background(), invocation); err == nil {
+		t.Error("Expected error for an author who is not a collaborator")
+	}
+	if len(security.events) != 1 {
+		t.Errorf("Expected no notification from a non-collaborator, got %d", len(security.events))
+	}
+}
@@ -0,0 +1,97 @@
+package docs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// Kinds of documentation notifications
+const (
+	KindWiki  = "wiki"
+	KindPages = "pages"
+)
+
+// Route sends one kind of documentation notification to a channel
+type Route struct {
+	Kind    string
+	Channel forwarder.Forwarder
+}
+
+// ParseRoutes parses a comma-separated list of kind=url pairs, where kind is
+// "wiki" for wiki page changes or "pages" for failed GitHub Pages builds, e.g.
+// "wiki=https://chat.example.com/docs,pages=https://chat.example.com/deploys"
+func ParseRoutes(list string) ([]Route, error) {
+	var routes []Route
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kind, endpoint, ok := strings.Cut(pair, "=")
+		kind = strings.ToLower(kind)
+		if !ok || (kind != KindWiki && kind != KindPages) {
+			return nil, fmt.Errorf("invalid docs route %q", pair)
+		}
+		channel, err := forwarder.NewHTTPForwarder(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, Route{Kind: kind, Channel: channel})
+	}
+	return routes, nil
+}
+
+// Router notifies channels about wiki changes and failed Pages builds
+type Router struct {
+	routes []Route
+}
+
+// NewRouter creates a router for the given routes
+func NewRouter(routes []Route) *Router {
+	return &Router{routes: routes}
+}
+
+// WikiChanged notifies the wiki channels about created and edited pages
+func (r *Router) WikiChanged(ctx context.Context, deliveryID string, change *webhook.WikiChange) {
+	r.deliver(ctx, KindWiki, deliveryID, webhook.GollumEvent, change.Repository, change.Sender, change)
+}
+
+// PageBuildFinished notifies the pages channels when a Pages build failed.
+// Successful builds are not routed.
+func (r *Router) PageBuildFinished(ctx context.Context, deliveryID string, build *webhook.PageBuild) {
+	if !build.Failed() {
+		return
+	}
+	r.deliver(ctx, KindPages, deliveryID, webhook.PageBuildEvent, build.Repository, build.Pusher, build)
+}
+
+func (r *Router) deliver(ctx context.Context, kind, deliveryID, eventType, repository, sender string, v interface{}) {
+	var channels []forwarder.Forwarder
+	for _, route := range r.routes {
+		if route.Kind == kind {
+			channels = append(channels, route.Channel)
+		}
+	}
+	if len(channels) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Failed to encode %s notification: %v", kind, err)
+		return
+	}
+	forwarder.ForwardAll(ctx, channels, forwarder.Event{
+		DeliveryID: deliveryID,
+		EventType:  eventType,
+		Repository: repository,
+		Sender:     sender,
+		Payload:    payload,
+	})
+}
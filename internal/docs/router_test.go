@@ -0,0 +1,53 @@
+package docs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+type recordingForwarder struct {
+	events []forwarder.Event
+}
+
+func (rf *recordingForwarder) Name() string { return "recording" }
+
+func (rf *recordingForwarder) Forward(ctx context.Context, event forwarder.Event) error {
+	rf.events = append(rf.events, event)
+	return nil
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes("Wiki=https://chat.example.com/docs, pages=https://chat.example.com/deploys")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(routes) != 2 || routes[0].Kind != KindWiki || routes[1].Kind != KindPages {
+		t.Errorf("Unexpected routes: %+v", routes)
+	}
+
+	for _, list := range []string{"wiki", "releases=https://chat.example.com", "pages=ftp://chat.example.com"} {
+		if _, err := ParseRoutes(list); err == nil {
+			t.Errorf("Expected error for %q", list)
+		}
+	}
+}
+
+func TestRouter(t *testing.T) {
+	wiki := &recordingForwarder{}
+	pages := &recordingForwarder{}
+	router := NewRouter([]Route{{Kind: KindWiki, Channel: wiki}, {Kind: KindPages, Channel: pages}})
+
+	router.WikiChanged(context.Background(), "d1", &webhook.WikiChange{Repository: "octo-org/hello-world", Pages: []webhook.WikiPage{{PageName: "Home"}}})
+	router.PageBuildFinished(context.Background(), "d2", &webhook.PageBuild{Status: "built"})
+	router.PageBuildFinished(context.Background(), "d3", &webhook.PageBuild{Status: "errored", Error: "Page build failed."})
+
+	if len(wiki.events) != 1 || wiki.events[0].EventType != webhook.GollumEvent {
+		t.Errorf("Expected 1 wiki notification, got %+v", wiki.events)
+	}
+	if len(pages.events) != 1 || pages.events[0].DeliveryID != "d3" {
+		t.Errorf("Expected only the failed build to be routed, got %+v", pages.events)
+	}
+}
@@ -0,0 +1,279 @@
+// Package dora computes the DORA metrics of each repository: deployment
+// frequency, lead time for changes and change failure rate. Deployments,
+// the commits of pushes and the merged pull requests are those choochoo
+// normalizes from their events.
+package dora
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+// ChangeLookback is how long before a period changes are looked for, so
+// changes pushed or merged before the period and deployed in it count
+const ChangeLookback = 30 * 24 * time.Hour
+
+// Performance levels of a metric, after the benchmarks of the DORA reports
+const (
+	LevelElite  = "elite"
+	LevelHigh   = "high"
+	LevelMedium = "medium"
+	LevelLow    = "low"
+)
+
+// Deployment is a finished deployment of a repository
+type Deployment struct {
+	Repository  string
+	ID          int64
+	Environment string
+	Ref         string
+	SHA         string
+	Failed      bool
+	At          time.Time
+}
+
+// Change is a commit pushed to a branch
+type Change struct {
+	Repository  string
+	Ref         string
+	SHA         string
+	CommittedAt time.Time
+	PushedAt    time.Time
+}
+
+// Merge is a pull request merged into a branch
+type Merge struct {
+	Repository string
+	Number     int32
+	BaseRef    string
+	MergedAt   time.Time
+}
+
+// Data is what the metrics are computed from
+type Data struct {
+	Deployments []Deployment
+	Changes     []Change
+	Merges      []Merge
+}
+
+// NewData converts the rows of the DORA queries, keeping the deployments to
+// environment unless it is empty
+func NewData(deployments []db.ListDeploymentOutcomesRow, commits []db.ListPushedCommitsRow, merges []db.ListMergedPullRequestsRow, environment string) Data {
+	var data Data
+	for _, row := range deployments {
+		if environment != "" && row.Environment != environment {
+			continue
+		}
+		data.Deployments = append(data.Deployments, Deployment{
+			Repository:  row.RepositoryName,
+			ID:          row.DeploymentID,
+			Environment: row.Environment,
+			Ref:         row.Ref,
+			SHA:         row.Sha,
+			Failed:      row.State != "success",
+			At:          row.CreatedAt.Time,
+		})
+	}
+	for _, row := range commits {
+		data.Changes = append(data.Changes, Change{
+			Repository:  row.RepositoryName,
+			Ref:         row.Ref,
+			SHA:         row.Sha,
+			CommittedAt: row.CommittedAt.Time,
+			PushedAt:    row.CreatedAt.Time,
+		})
+	}
+	for _, row := range merges {
+		data.Merges = append(data.Merges, Merge{
+			Repository: row.RepositoryName,
+			Number:     row.PrNumber,
+			BaseRef:    row.BaseRef,
+			MergedAt:   row.MergedAt.Time,
+		})
+	}
+	return data
+}
+
+// Levels rates each metric of a repository
+type Levels struct {
+	DeploymentFrequency string `json:"deployment_frequency"`
+	LeadTime            string `json:"lead_time,omitempty"`
+	ChangeFailureRate   string `json:"change_failure_rate"`
+}
+
+// Metrics are the DORA metrics of a repository over a period
+type Metrics struct {
+	Repository string `json:"repository"`
+	// Deployments counts successful deployments and FailedDeployments those
+	// that ended in failure or error
+	Deployments       int     `json:"deployments"`
+	FailedDeployments int     `json:"failed_deployments"`
+	DeploymentsPerDay float64 `json:"deployments_per_day"`
+	// Changes counts the commits deployed and PullRequests the merged pull
+	// requests deployed
+	Changes      int `json:"changes"`
+	PullRequests int `json:"pull_requests"`
+	// LeadTimeSeconds is the median time from a commit to its deployment,
+	// absent when no change was deployed
+	LeadTimeSeconds   *float64 `json:"lead_time_seconds,omitempty"`
+	ChangeFailureRate float64  `json:"change_failure_rate"`
+	Levels            Levels   `json:"levels"`
+}
+
+// Compute returns the metrics of every repository deployed from from until
+// to, by repository. A change is deployed by the first successful deployment
+// of its branch or commit after it was pushed, and a pull request by the
+// first successful deployment of its base branch after it was merged.
+func Compute(data Data, from, to time.Time) []Metrics {
+	days := to.Sub(from).Hours() / 24
+	metrics := make(map[string]*Metrics)
+	succeeded := make(map[string][]Deployment)
+	for _, deployment := range data.Deployments {
+		if deployment.At.Before(from) || !deployment.At.Before(to) {
+			continue
+		}
+		m, ok := metrics[deployment.Repository]
+		if !ok {
+			m = &Metrics{Repository: deployment.Repository}
+			metrics[deployment.Repository] = m
+		}
+		if deployment.Failed {
+			m.FailedDeployments++
+			continue
+		}
+		m.Deployments++
+		succeeded[deployment.Repository] = append(succeeded[deployment.Repository], deployment)
+	}
+	for _, deployments := range succeeded {
+		sort.Slice(deployments, func(i, j int) bool { return deployments[i].At.Before(deployments[j].At) })
+	}
+
+	// A commit pushed to several branches counts once, with its earliest
+	// deployment
+	type delivery struct {
+		at       time.Time
+		leadTime time.Duration
+	}
+	delivered := make(map[[2]string]delivery)
+	for _, change := range data.Changes {
+		deployment, ok := firstDeployment(succeeded[change.Repository], change.PushedAt, func(d Deployment) bool {
+			return branch(d.Ref) == branch(change.Ref) || d.SHA == change.SHA
+		})
+		if !ok {
+			continue
+		}
+		key := [2]string{change.Repository, change.SHA}
+		if previous, ok := delivered[key]; ok && !deployment.At.Before(previous.at) {
+			continue
+		}
+		delivered[key] = delivery{at: deployment.At, leadTime: max(deployment.At.Sub(change.CommittedAt), 0)}
+	}
+	leadTimes := make(map[string][]time.Duration)
+	for key, d := range delivered {
+		leadTimes[key[0]] = append(leadTimes[key[0]], d.leadTime)
+	}
+
+	for _, merge := range data.Merges {
+		if _, ok := firstDeployment(succeeded[merge.Repository], merge.MergedAt, func(d Deployment) bool {
+			return branch(d.Ref) == merge.BaseRef
+		}); ok {
+			metrics[merge.Repository].PullRequests++
+		}
+	}
+
+	result := make([]Metrics, 0, len(metrics))
+	for repository, m := range metrics {
+		if days > 0 {
+			m.DeploymentsPerDay = round(float64(m.Deployments) / days)
+		}
+		if total := m.Deployments + m.FailedDeployments; total > 0 {
+			m.ChangeFailureRate = round(float64(m.FailedDeployments) / float64(total))
+		}
+		if leadTimes := leadTimes[repository]; len(leadTimes) > 0 {
+			m.Changes = len(leadTimes)
+			seconds := median(leadTimes).Seconds()
+			m.LeadTimeSeconds = &seconds
+		}
+		m.Levels = rate(*m)
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Repository < result[j].Repository })
+	return result
+}
+
+// firstDeployment returns the first of deployments, sorted by time, at or
+// after since that matches
+func firstDeployment(deployments []Deployment, since time.Time, match func(Deployment) bool) (Deployment, bool) {
+	i := sort.Search(len(deployments), func(i int) bool { return !deployments[i].At.Before(since) })
+	for ; i < len(deployments); i++ {
+		if match(deployments[i]) {
+			return deployments[i], true
+		}
+	}
+	return Deployment{}, false
+}
+
+// branch returns the branch name of a ref, which pushes give in full and
+// deployments and pull requests as a name
+func branch(ref string) string {
+	return strings.TrimPrefix(ref, "refs/heads/")
+}
+
+func median(durations []time.Duration) time.Duration {
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	middle := len(durations) / 2
+	if len(durations)%2 == 0 {
+		return (durations[middle-1] + durations[middle]) / 2
+	}
+	return durations[middle]
+}
+
+// rate rates the metrics: elite teams deploy daily, deliver changes within a
+// day and fail at most 5% of their deployments; high performers deploy
+// weekly, within a week and fail at most 10%; medium performers deploy
+// monthly, within a month and fail at most 15%
+func rate(m Metrics) Levels {
+	var levels Levels
+	switch {
+	case m.DeploymentsPerDay >= 1:
+		levels.DeploymentFrequency = LevelElite
+	case m.DeploymentsPerDay >= 1.0/7:
+		levels.DeploymentFrequency = LevelHigh
+	case m.DeploymentsPerDay >= 1.0/30:
+		levels.DeploymentFrequency = LevelMedium
+	default:
+		levels.DeploymentFrequency = LevelLow
+	}
+	if m.LeadTimeSeconds != nil {
+		leadTime := time.Duration(*m.LeadTimeSeconds * float64(time.Second))
+		switch {
+		case leadTime <= 24*time.Hour:
+			levels.LeadTime = LevelElite
+		case leadTime <= 7*24*time.Hour:
+			levels.LeadTime = LevelHigh
+		case leadTime <= 30*24*time.Hour:
+			levels.LeadTime = LevelMedium
+		default:
+			levels.LeadTime = LevelLow
+		}
+	}
+	switch {
+	case m.ChangeFailureRate <= 0.05:
+		levels.ChangeFailureRate = LevelElite
+	case m.ChangeFailureRate <= 0.10:
+		levels.ChangeFailureRate = LevelHigh
+	case m.ChangeFailureRate <= 0.15:
+		levels.ChangeFailureRate = LevelMedium
+	default:
+		levels.ChangeFailureRate = LevelLow
+	}
+	return levels
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
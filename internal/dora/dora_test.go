@@ -0,0 +1,75 @@
+package dora
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompute(t *testing.T) {
+	from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 10)
+	at := func(day, hour int) time.Time { return from.Add(time.Duration(day*24+hour) * time.Hour) }
+
+	data := Data{
+		Deployments: []Deployment{
+			{Repository: "acme/api", ID: 1, Ref: "main", SHA: "b", At: at(1, 12)},
+			{Repository: "acme/api", ID: 2, Ref: "main", SHA: "c", At: at(3, 12), Failed: true},
+			{Repository: "acme/api", ID: 3, Ref: "main", SHA: "d", At: at(5, 12)},
+			{Repository: "acme/api", ID: 4, Ref: "v1.2.0", SHA: "e", At: at(6, 12)},
+			// Deployed before the period
+			{Repository: "acme/api", ID: 5, Ref: "main", SHA: "z", At: at(-1, 0)},
+			{Repository: "acme/web", ID: 6, Ref: "main", SHA: "w", At: at(2, 0), Failed: true},
+		},
+		Changes: []Change{
+			// Pushed to a branch and then main, and deployed with main
+			{Repository: "acme/api", Ref: "refs/heads/feature", SHA: "a", CommittedAt: at(0, 0), PushedAt: at(0, 1)},
+			{Repository: "acme/api", Ref: "refs/heads/main", SHA: "a", CommittedAt: at(0, 0), PushedAt: at(1, 0)},
+			{Repository: "acme/api", Ref: "refs/heads/main", SHA: "b", CommittedAt: at(1, 0), PushedAt: at(1, 0)},
+			// Deployed by the second successful deployment, the first failed
+			{Repository: "acme/api", Ref: "refs/heads/main", SHA: "c", CommittedAt: at(2, 0), PushedAt: at(2, 0)},
+			// Deployed by its commit from a tag
+			{Repository: "acme/api", Ref: "refs/heads/release", SHA: "e", CommittedAt: at(6, 0), PushedAt: at(6, 0)},
+			// Not deployed yet
+			{Repository: "acme/api", Ref: "refs/heads/main", SHA: "f", CommittedAt: at(7, 0), PushedAt: at(7, 0)},
+		},
+		Merges: []Merge{
+			{Repository: "acme/api", Number: 1, BaseRef: "main", MergedAt: at(1, 0)},
+			{Repository: "acme/api", Number: 2, BaseRef: "develop", MergedAt: at(1, 0)},
+			{Repository: "acme/api", Number: 3, BaseRef: "main", MergedAt: at(8, 0)},
+		},
+	}
+
+	metrics := Compute(data, from, to)
+	if len(metrics) != 2 {
+		t.Fatalf("Compute() = %+v, want 2 repositories", metrics)
+	}
+
+	api := metrics[0]
+	if api.Repository != "acme/api" || api.Deployments != 3 || api.FailedDeployments != 1 || api.DeploymentsPerDay != 0.3 {
+		t.Errorf("acme/api deployments = %+v", api)
+	}
+	if api.ChangeFailureRate != 0.25 || api.Levels.ChangeFailureRate != LevelLow || api.Levels.DeploymentFrequency != LevelHigh {
+		t.Errorf("acme/api change failure rate = %+v", api)
+	}
+	// Lead times of a, b, c and e are 36h, 12h, 84h and 12h
+	if api.Changes != 4 || api.PullRequests != 1 || api.LeadTimeSeconds == nil || *api.LeadTimeSeconds != 24*3600 {
+		t.Errorf("acme/api changes = %+v", api)
+	}
+	if api.Levels.LeadTime != LevelElite {
+		t.Errorf("acme/api levels = %+v", api.Levels)
+	}
+
+	web := metrics[1]
+	if web.Repository != "acme/web" || web.Deployments != 0 || web.ChangeFailureRate != 1 || web.LeadTimeSeconds != nil || web.Levels.LeadTime != "" {
+		t.Errorf("acme/web = %+v", web)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	if got := median([]time.Duration{3, 1, 2}); got != 2 {
+		t.Errorf("median() of an odd count = %v", got)
+	}
+	if got := median([]time.Duration{4, 1, 2, 3}); got != 2 {
+		t.Errorf("median() of an even count = %v", got)
+	}
+}
@@ -0,0 +1,119 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"sync"
+	"time"
+)
+
+// DigestCheckInterval is how often digests are checked for being due
+const DigestCheckInterval = time.Minute
+
+// maxDigestEvents bounds the events a digest lists. Later events are only
+// counted, as .omitted.
+const maxDigestEvents = 500
+
+// Digest is how the events of a rule action are mailed together: a digest
+// is sent Every after the first event it holds
+type Digest struct {
+	To       []*mail.Address
+	Template *Template
+	Every    time.Duration
+}
+
+// pending is a digest collecting events
+type pending struct {
+	digest  Digest
+	rule    string
+	since   time.Time
+	events  []map[string]interface{}
+	omitted int
+}
+
+// Digester collects matched events into digests and sends those that are
+// due. Digests are held in memory, so events collected by a server that
+// stops before they are due are not mailed.
+type Digester struct {
+	mailer *Mailer
+
+	mu      sync.Mutex
+	pending map[string]*pending
+}
+
+// NewDigester creates a digester sending with mailer
+func NewDigester(mailer *Mailer) *Digester {
+	return &Digester{mailer: mailer, pending: make(map[string]*pending)}
+}
+
+// Add adds the variables of an event matched by rule to the digest of key.
+// The latest digest settings of key apply when it is sent, so changed rules
+// keep collecting into the same digest.
+func (d *Digester) Add(key, rule string, digest Digest, vars map[string]interface{}, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	p, ok := d.pending[key]
+	if !ok {
+		p = &pending{since: now}
+		d.pending[key] = p
+	}
+	p.digest, p.rule = digest, rule
+	if len(p.events) < maxDigestEvents {
+		p.events = append(p.events, vars)
+	} else {
+		p.omitted++
+	}
+}
+
+// Flush sends the digests that are due by now. Digests that fail to send
+// are dropped, like failed notifications.
+func (d *Digester) Flush(ctx context.Context, now time.Time) error {
+	d.mu.Lock()
+	var due []*pending
+	for key, p := range d.pending {
+		if !now.Before(p.since.Add(p.digest.Every)) {
+			due = append(due, p)
+			delete(d.pending, key)
+		}
+	}
+	d.mu.Unlock()
+
+	var errs []error
+	for _, p := range due {
+		subject, body, err := p.digest.Template.Render(map[string]interface{}{
+			"rule":    p.rule,
+			"events":  p.events,
+			"omitted": p.omitted,
+			"since":   p.since.UTC(),
+			"until":   now.UTC(),
+		})
+		if err == nil && body != "" {
+			err = d.mailer.Send(ctx, p.digest.To, subject, body)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("digest of rule %q: %w", p.rule, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run sends the digests that are due every interval until ctx is done
+func (d *Digester) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := d.Flush(ctx, now); err != nil {
+				log.Printf("Failed to send email digests: %v", err)
+			}
+		}
+	}
+}
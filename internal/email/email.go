@@ -0,0 +1,203 @@
+// Package email sends event notifications as HTML emails over SMTP, one
+// email per event or periodic digests of the events a rule matched.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLS modes of the SMTP connection
+const (
+	// TLSStartTLS upgrades a plain connection with STARTTLS, usually on
+	// port 587, and fails if the server does not offer it
+	TLSStartTLS = "starttls"
+	// TLSImplicit connects with TLS, usually on port 465
+	TLSImplicit = "tls"
+	// TLSNone sends in plaintext, e.g. to a relay on localhost
+	TLSNone = "none"
+)
+
+// DefaultPort is the SMTP submission port
+const DefaultPort = 587
+
+// dialTimeout bounds connecting to the SMTP server
+const dialTimeout = 30 * time.Second
+
+// Config is how emails are sent
+type Config struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	TLS      string
+}
+
+// Mailer sends emails through an SMTP server
+type Mailer struct {
+	config Config
+	from   *mail.Address
+	// tlsConfig is only replaced by tests
+	tlsConfig *tls.Config
+}
+
+// NewMailer creates a mailer, checking the config
+func NewMailer(config Config) (*Mailer, error) {
+	if config.Host == "" {
+		return nil, errors.New("missing SMTP host")
+	}
+	if config.Port == 0 {
+		config.Port = DefaultPort
+	}
+	if config.Port < 1 || config.Port > 65535 {
+		return nil, fmt.Errorf("invalid SMTP port %d", config.Port)
+	}
+	switch config.TLS {
+	case "":
+		config.TLS = TLSStartTLS
+	case TLSStartTLS, TLSImplicit, TLSNone:
+	default:
+		return nil, fmt.Errorf("invalid SMTP TLS mode %q: want starttls, tls or none", config.TLS)
+	}
+	from, err := mail.ParseAddress(config.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", config.From, err)
+	}
+	if config.Username == "" && config.Password != "" {
+		return nil, errors.New("SMTP password without a username")
+	}
+	return &Mailer{config: config, from: from, tlsConfig: &tls.Config{ServerName: config.Host}}, nil
+}
+
+// Name identifies the mailer in logs
+func (m *Mailer) Name() string {
+	return "smtp:" + m.config.Host
+}
+
+// ParseRecipients parses a list of recipient addresses
+func ParseRecipients(to []string) ([]*mail.Address, error) {
+	if len(to) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	addresses := make([]*mail.Address, 0, len(to))
+	for _, recipient := range to {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", recipient, err)
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// Send sends an HTML email
+func (m *Mailer) Send(ctx context.Context, to []*mail.Address, subject, html string) error {
+	message, err := m.compose(to, subject, html, time.Now())
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var conn net.Conn
+	if m.config.TLS == TLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: m.tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if m.config.TLS == TLSStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", m.config.Host)
+		}
+		if err := client.StartTLS(m.tlsConfig); err != nil {
+			return err
+		}
+	}
+	if m.config.Username != "" {
+		// PlainAuth refuses to send credentials over plaintext connections,
+		// except to localhost
+		if err := client.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(m.from.Address); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient.Address); err != nil {
+			return fmt.Errorf("recipient %s: %w", recipient.Address, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// compose formats an HTML email, quoted-printable encoded
+func (m *Mailer) compose(to []*mail.Address, subject, html string, now time.Time) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	_, domain, _ := strings.Cut(m.from.Address, "@")
+
+	recipients := make([]string, len(to))
+	for i, address := range to {
+		recipients[i] = address.String()
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	body := quotedprintable.NewWriter(&buf)
+	if _, err := body.Write([]byte(html)); err != nil {
+		return nil, err
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,319 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// received is an email accepted by the fake SMTP server
+type received struct {
+	from string
+	to   []string
+	auth string
+	tls  bool
+	data string
+}
+
+// smtpServer is a fake SMTP server on localhost
+type smtpServer struct {
+	listener net.Listener
+	tls      *tls.Config
+	startTLS bool
+
+	mu       sync.Mutex
+	received []received
+}
+
+// newSMTPServer starts a fake SMTP server. With implicit, connections use
+// TLS from the start; with startTLS, the server offers STARTTLS.
+func newSMTPServer(t *testing.T, implicit, startTLS bool) (*smtpServer, *tls.Config) {
+	t.Helper()
+	// Borrow the certificate of an httptest server, valid for 127.0.0.1
+	https := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(https.Close)
+	serverTLS := &tls.Config{Certificates: https.TLS.Certificates}
+	clientTLS := &tls.Config{RootCAs: https.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs, ServerName: "127.0.0.1"}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if implicit {
+		listener = tls.NewListener(listener, serverTLS)
+	}
+	s := &smtpServer{listener: listener, tls: serverTLS, startTLS: startTLS}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, implicit)
+		}
+	}()
+	return s, clientTLS
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) messages() []received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]received(nil), s.received...)
+}
+
+func (s *smtpServer) serve(conn net.Conn, secure bool) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	var msg received
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO", "HELO":
+			if s.startTLS && !secure {
+				reply("250-localhost")
+				reply("250-STARTTLS")
+			} else {
+				reply("250-localhost")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, r, secure = tlsConn, bufio.NewReader(tlsConn), true
+		case "AUTH":
+			decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			msg.auth = string(decoded)
+			if strings.HasSuffix(msg.auth, "\x00wrong") {
+				reply("535 Authentication failed")
+				continue
+			}
+			reply("235 Authenticated")
+		case "MAIL":
+			msg.from = strings.TrimSuffix(strings.TrimPrefix(line, "MAIL FROM:<"), ">")
+			reply("250 OK")
+		case "RCPT":
+			msg.to = append(msg.to, strings.TrimSuffix(strings.TrimPrefix(line, "RCPT TO:<"), ">"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 Go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			msg.data, msg.tls = data.String(), secure
+			s.mu.Lock()
+			s.received = append(s.received, msg)
+			s.mu.Unlock()
+			msg = received{}
+			reply("250 Queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// body decodes the quoted-printable body of a received email
+func body(t *testing.T, data string) string {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	// The DATA terminator needs the body to end with a line break
+	return strings.TrimSuffix(string(decoded), "\r\n")
+}
+
+func TestNewMailer(t *testing.T) {
+	for name, config := range map[string]Config{
+		"no host":       {From: "choochoo@example.com"},
+		"bad port":      {Host: "smtp.example.com", Port: 70000, From: "choochoo@example.com"},
+		"bad tls":       {Host: "smtp.example.com", TLS: "ssl", From: "choochoo@example.com"},
+		"bad sender":    {Host: "smtp.example.com", From: "choochoo"},
+		"password only": {Host: "smtp.example.com", From: "choochoo@example.com", Password: "secret"},
+	} {
+		if _, err := NewMailer(config); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+
+	mailer, err := NewMailer(Config{Host: "smtp.example.com", From: "choochoo@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mailer.config.Port != DefaultPort || mailer.config.TLS != TLSStartTLS {
+		t.Errorf("Expected STARTTLS on port %d by default, got %+v", DefaultPort, mailer.config)
+	}
+}
+
+func TestMailer_Send(t *testing.T) {
+	to, err := ParseRecipients([]string{"Oncall <oncall@example.com>", "audit@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name               string
+		implicit, startTLS bool
+		config             Config
+		wantTLS            bool
+		wantErr            string
+	}{
+		{name: "plaintext with auth", config: Config{TLS: TLSNone, Username: "choochoo", Password: "secret"}},
+		{name: "starttls", startTLS: true, config: Config{Username: "choochoo", Password: "secret"}, wantTLS: true},
+		{name: "implicit tls", implicit: true, config: Config{TLS: TLSImplicit}, wantTLS: true},
+		{name: "starttls not offered", config: Config{TLS: TLSStartTLS}, wantErr: "does not support STARTTLS"},
+		{name: "wrong password", config: Config{TLS: TLSNone, Username: "choochoo", Password: "wrong"}, wantErr: "authentication failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, clientTLS := newSMTPServer(t, tt.implicit, tt.startTLS)
+			config := tt.config
+			config.Host, config.Port, config.From = "127.0.0.1", server.port(), "choochoo <choochoo@example.com>"
+			mailer, err := NewMailer(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mailer.tlsConfig = clientTLS
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err = mailer.Send(ctx, to, "Pushes to main: 3 ✓", "<p>Déployé</p>")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			messages := server.messages()
+			if len(messages) != 1 {
+				t.Fatalf("Expected one email, got %d", len(messages))
+			}
+			got := messages[0]
+			if got.from != "choochoo@example.com" || strings.Join(got.to, ",") != "oncall@example.com,audit@example.com" || got.tls != tt.wantTLS {
+				t.Errorf("Unexpected envelope: %+v", got)
+			}
+			if config.Username != "" && got.auth != "\x00choochoo\x00secret" {
+				t.Errorf("Unexpected credentials %q", got.auth)
+			}
+			msg, _ := mail.ReadMessage(strings.NewReader(got.data))
+			if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != "Pushes to main: 3 ✓" {
+				t.Errorf("Unexpected subject %q", msg.Header.Get("Subject"))
+			}
+			if msg.Header.Get("Content-Type") != "text/html; charset=UTF-8" || !strings.Contains(msg.Header.Get("To"), `"Oncall" <oncall@example.com>`) {
+				t.Errorf("Unexpected headers: %v", msg.Header)
+			}
+			if b := body(t, got.data); b != "<p>Déployé</p>" {
+				t.Errorf("Unexpected body %q", b)
+			}
+		})
+	}
+}
+
+func TestTemplate_Render(t *testing.T) {
+	tmpl, err := ParseTemplate("", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]interface{}{"event": "push", "repository": "octo-org/<script>", "sender": "octocat", "delivery_id": "d1"}
+	subject, html, err := tmpl.Render(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "push in octo-org/<script> by octocat" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if !strings.Contains(html, "octo-org/&lt;script&gt;") {
+		t.Errorf("Expected event values to be escaped, got %q", html)
+	}
+
+	tmpl, _ = ParseTemplate("{{.event}}\r\nBcc: everyone@example.com", "{{if false}}x{{end}}", false)
+	subject, html, _ = tmpl.Render(vars)
+	if subject != "push Bcc: everyone@example.com" || html != "" {
+		t.Errorf("Expected a one-line subject and an empty body, got %q and %q", subject, html)
+	}
+
+	for _, texts := range [][2]string{{"{{.event", ""}, {"", "<p>{{.event</p>"}} {
+		if _, err := ParseTemplate(texts[0], texts[1], false); err == nil {
+			t.Errorf("Expected %q to be rejected", texts)
+		}
+	}
+}
+
+func TestDigester(t *testing.T) {
+	server, _ := newSMTPServer(t, false, false)
+	mailer, err := NewMailer(Config{Host: "127.0.0.1", Port: server.port(), TLS: TLSNone, From: "choochoo@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, _ := ParseRecipients([]string{"oncall@example.com"})
+	tmpl, _ := ParseTemplate("", `{{range .events}}<li>{{.payload.head_commit.message}}</li>{{end}}`, true)
+	digest := Digest{To: to, Template: tmpl, Every: time.Hour}
+
+	start := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	digester := NewDigester(mailer)
+	for i, message := range []string{"Fix flaky retries", "Bump version"} {
+		vars := map[string]interface{}{"event": "push", "payload": map[string]interface{}{"head_commit": map[string]interface{}{"message": message}}}
+		digester.Add("protected-pushes#0", "protected-pushes", digest, vars, start.Add(time.Duration(i)*time.Minute))
+	}
+
+	if err := digester.Flush(context.Background(), start.Add(59*time.Minute)); err != nil || len(server.messages()) != 0 {
+		t.Fatalf("Expected the digest to wait for its period, got %v", err)
+	}
+	if err := digester.Flush(context.Background(), start.Add(time.Hour)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	messages := server.messages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected one digest, got %d", len(messages))
+	}
+	msg, _ := mail.ReadMessage(strings.NewReader(messages[0].data))
+	if msg.Header.Get("Subject") != "2 events matched protected-pushes" {
+		t.Errorf("Unexpected subject %q", msg.Header.Get("Subject"))
+	}
+	if b := body(t, messages[0].data); b != "<li>Fix flaky retries</li><li>Bump version</li>" {
+		t.Errorf("Unexpected body %q", b)
+	}
+
+	if err := digester.Flush(context.Background(), start.Add(3*time.Hour)); err != nil || len(server.messages()) != 1 {
+		t.Errorf("Expected a sent digest to be cleared, got %v", err)
+	}
+}
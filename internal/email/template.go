@@ -0,0 +1,72 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/deedubs/choochoo/internal/chat"
+)
+
+// Default templates of emails about one event, which see the variables of
+// rule conditions like chat messages
+const (
+	DefaultSubject = chat.DefaultTemplate
+	DefaultBody    = `<p>` + chat.DefaultTemplate + `</p><p>Delivery {{.delivery_id}}</p>`
+)
+
+// Default templates of digests, which see the matched events as .events,
+// the rule as .rule and the period as .since and .until
+const (
+	DefaultDigestSubject = `{{len .events}} events matched {{.rule}}`
+	DefaultDigestBody    = `<p>{{len .events}} events matched {{.rule}} between {{.since.Format "2006-01-02 15:04"}} and {{.until.Format "2006-01-02 15:04 MST"}}:</p>
+<ul>{{range .events}}<li>` + chat.DefaultTemplate + `</li>{{end}}</ul>`
+)
+
+// Template renders the subject and HTML body of emails. Bodies are HTML
+// templates, so values from events are escaped.
+type Template struct {
+	subject *texttemplate.Template
+	body    *htmltemplate.Template
+}
+
+// ParseTemplate parses the subject and body templates of emails about one
+// event, or of digests, using the defaults for those left empty
+func ParseTemplate(subject, body string, digest bool) (*Template, error) {
+	if strings.TrimSpace(subject) == "" {
+		subject = DefaultSubject
+		if digest {
+			subject = DefaultDigestSubject
+		}
+	}
+	if strings.TrimSpace(body) == "" {
+		body = DefaultBody
+		if digest {
+			body = DefaultDigestBody
+		}
+	}
+	s, err := texttemplate.New("subject").Funcs(chat.Funcs).Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+	b, err := htmltemplate.New("body").Funcs(htmltemplate.FuncMap(chat.Funcs)).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+	return &Template{subject: s, body: b}, nil
+}
+
+// Render renders the subject, on one line, and the body of an email. An
+// empty body means nothing should be sent.
+func (t *Template) Render(data map[string]interface{}) (string, string, error) {
+	var subject, body bytes.Buffer
+	if err := t.subject.Execute(&subject, data); err != nil {
+		return "", "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := t.body.Execute(&body, data); err != nil {
+		return "", "", fmt.Errorf("failed to render body: %w", err)
+	}
+	return strings.Join(strings.Fields(subject.String()), " "), strings.TrimSpace(body.String()), nil
+}
@@ -0,0 +1,184 @@
+// Package encryption encrypts stored webhook payloads with AES-GCM, so raw
+// webhook bodies are not readable by anyone with access to the database.
+// Each encrypted payload is tagged with the ID of its key, so keys can be
+// rotated: new payloads are encrypted with the active key while older keys
+// are kept to decrypt what they encrypted until it is re-encrypted.
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envelopeField is the only key of an encrypted payload. Payloads without it
+// are plaintext, stored before encryption was enabled.
+const envelopeField = "choochoo_encrypted"
+
+var (
+	// ErrNoKeys is returned when opening an encrypted payload without keys
+	ErrNoKeys = errors.New("payload is encrypted but no encryption keys are configured")
+	// ErrUnknownKey is returned when opening a payload encrypted with a key
+	// that is not configured
+	ErrUnknownKey = errors.New("payload is encrypted with an unknown key")
+)
+
+// envelope is how an encrypted payload is stored, so the column stays JSON
+type envelope struct {
+	KeyID string `json:"key_id"`
+	// Ciphertext is the base64 of the nonce followed by the sealed payload
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Keyring holds the keys payloads are encrypted and decrypted with
+type Keyring struct {
+	active string
+	keys   map[string]cipher.AEAD
+}
+
+// ParseKeys parses a list of keys separated by commas or newlines, each
+// written as ID:BASE64 with a base64 encoded 16, 24 or 32 byte AES key, e.g.
+// "2024-10:q3mN...=,2024-01:Zt1w...=". The first key is the active key new
+// payloads are encrypted with.
+func ParseKeys(list string) (*Keyring, error) {
+	ring := &Keyring{keys: make(map[string]cipher.AEAD)}
+	for _, entry := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid key %q: want ID:BASE64", entry)
+		}
+		if _, exists := ring.keys[id]; exists {
+			return nil, fmt.Errorf("duplicate key ID %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		if ring.active == "" {
+			ring.active = id
+		}
+		ring.keys[id] = aead
+	}
+	if ring.active == "" {
+		return nil, errors.New("no keys")
+	}
+	return ring, nil
+}
+
+// ReadKeys parses the keys in a file, such as one written by a KMS or secret
+// manager agent
+func ReadKeys(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeys(string(data))
+}
+
+// ActiveKey returns the ID of the key new payloads are encrypted with
+func (k *Keyring) ActiveKey() string {
+	return k.active
+}
+
+// Seal encrypts a payload with the active key. The payload is bound to its
+// delivery ID, so it cannot be moved to another event's row.
+func (k *Keyring) Seal(deliveryID string, payload []byte) ([]byte, error) {
+	if k == nil {
+		return payload, nil
+	}
+	aead := k.keys[k.active]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(payload)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, payload, []byte(deliveryID))
+	return json.Marshal(map[string]envelope{envelopeField: {
+		KeyID:      k.active,
+		Ciphertext: base64.StdEncoding.EncodeToString(sealed),
+	}})
+}
+
+// Open decrypts a stored payload. Plaintext payloads are returned as they
+// are, so events stored before encryption was enabled can still be read.
+func (k *Keyring) Open(deliveryID string, stored []byte) ([]byte, error) {
+	sealed, ok := parseEnvelope(stored)
+	if !ok {
+		return stored, nil
+	}
+	if k == nil {
+		return nil, ErrNoKeys
+	}
+	aead, ok := k.keys[sealed.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, sealed.KeyID)
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed.Ciphertext)
+	if err != nil || len(data) < aead.NonceSize() {
+		return nil, errors.New("malformed encrypted payload")
+	}
+	payload, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(deliveryID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt payload with key %q: %w", sealed.KeyID, err)
+	}
+	return payload, nil
+}
+
+// Rotate re-encrypts a stored payload with the active key, encrypting
+// plaintext payloads too. It reports false for payloads that already are.
+func (k *Keyring) Rotate(deliveryID string, stored []byte) ([]byte, bool, error) {
+	if KeyID(stored) == k.active {
+		return stored, false, nil
+	}
+	payload, err := k.Open(deliveryID, stored)
+	if err != nil {
+		return nil, false, err
+	}
+	sealed, err := k.Seal(deliveryID, payload)
+	if err != nil {
+		return nil, false, err
+	}
+	return sealed, true, nil
+}
+
+// KeyID returns the ID of the key a stored payload is encrypted with, or ""
+// for a plaintext payload
+func KeyID(stored []byte) string {
+	sealed, _ := parseEnvelope(stored)
+	return sealed.KeyID
+}
+
+// parseEnvelope returns the envelope of an encrypted payload
+func parseEnvelope(stored []byte) (envelope, bool) {
+	// Skip parsing the payloads that cannot be envelopes
+	if !bytes.Contains(stored, []byte(envelopeField)) {
+		return envelope{}, false
+	}
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(stored, &wrapper); err != nil || len(wrapper) != 1 {
+		return envelope{}, false
+	}
+	var sealed envelope
+	if err := json.Unmarshal(wrapper[envelopeField], &sealed); err != nil || sealed.KeyID == "" {
+		return envelope{}, false
+	}
+	return sealed, true
+}
@@ -0,0 +1,137 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	oldKey = "2024-01:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+	newKey = "2024-10:ICEiIyQlJicoKSorLC0uLzAxMjM0NTY3ODk6Ozw9Pj8="
+)
+
+func mustParseKeys(t *testing.T, list string) *Keyring {
+	t.Helper()
+	keys, err := ParseKeys(list)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return keys
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := mustParseKeys(t, newKey+",\n"+oldKey+"\n")
+	if keys.ActiveKey() != "2024-10" || len(keys.keys) != 2 {
+		t.Errorf("Unexpected keyring, active %q with %d keys", keys.ActiveKey(), len(keys.keys))
+	}
+
+	for _, list := range []string{"", "AAECAwQFBgcICQoLDA0ODxA=", "k1:not base64", "k1:c2hvcnQ=", oldKey + "," + oldKey} {
+		if _, err := ParseKeys(list); err == nil {
+			t.Errorf("Expected an error for %q", list)
+		}
+	}
+}
+
+func TestReadKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(path, []byte(newKey+"\n"+oldKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ReadKeys(path)
+	if err != nil || keys.ActiveKey() != "2024-10" {
+		t.Errorf("Unexpected keyring %+v, %v", keys, err)
+	}
+}
+
+func TestKeyring_SealOpen(t *testing.T) {
+	keys := mustParseKeys(t, newKey)
+	payload := []byte(`{"zen":"Design for failure."}`)
+
+	sealed, err := keys.Seal("delivery-1", payload)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if bytes.Contains(sealed, []byte("Design")) || KeyID(sealed) != "2024-10" {
+		t.Errorf("Expected an encrypted payload tagged with its key, got %s", sealed)
+	}
+
+	opened, err := keys.Open("delivery-1", sealed)
+	if err != nil || !bytes.Equal(opened, payload) {
+		t.Errorf("Expected the payload back, got %s, %v", opened, err)
+	}
+
+	// Payloads are bound to their delivery
+	if _, err := keys.Open("delivery-2", sealed); err == nil {
+		t.Error("Expected an error opening the payload of another delivery")
+	}
+}
+
+func TestKeyring_OpenPlaintext(t *testing.T) {
+	payload := []byte(`{"action":"opened"}`)
+	for _, keys := range []*Keyring{nil, mustParseKeys(t, newKey)} {
+		opened, err := keys.Open("delivery-1", payload)
+		if err != nil || !bytes.Equal(opened, payload) {
+			t.Errorf("Expected the plaintext payload unchanged, got %s, %v", opened, err)
+		}
+	}
+}
+
+func TestKeyring_OpenMissingKey(t *testing.T) {
+	sealed, err := mustParseKeys(t, oldKey).Seal("delivery-1", []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var none *Keyring
+	if _, err := none.Open("delivery-1", sealed); !errors.Is(err, ErrNoKeys) {
+		t.Errorf("Expected ErrNoKeys, got %v", err)
+	}
+	if _, err := mustParseKeys(t, newKey).Open("delivery-1", sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestKeyring_Rotate(t *testing.T) {
+	sealed, err := mustParseKeys(t, oldKey).Seal("delivery-1", []byte(`{"ref":"refs/heads/main"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := mustParseKeys(t, newKey+","+oldKey)
+
+	rotated, changed, err := keys.Rotate("delivery-1", sealed)
+	if err != nil || !changed || KeyID(rotated) != "2024-10" {
+		t.Fatalf("Expected the payload re-encrypted with the active key, got %s, %v, %v", rotated, changed, err)
+	}
+	if _, changed, _ := keys.Rotate("delivery-1", rotated); changed {
+		t.Error("Expected a payload encrypted with the active key to be left alone")
+	}
+
+	// Plaintext payloads are encrypted
+	encrypted, changed, err := keys.Rotate("delivery-2", []byte(`{}`))
+	if err != nil || !changed || KeyID(encrypted) != "2024-10" {
+		t.Errorf("Expected the plaintext payload encrypted, got %s, %v, %v", encrypted, changed, err)
+	}
+
+	// Only the active key is needed afterwards
+	opened, err := mustParseKeys(t, newKey).Open("delivery-1", rotated)
+	if err != nil || !strings.Contains(string(opened), "refs/heads/main") {
+		t.Errorf("Expected the rotated payload to open with the new key alone, got %s, %v", opened, err)
+	}
+}
+
+func TestKeyID_NotAnEnvelope(t *testing.T) {
+	// A payload that merely mentions the envelope field is plaintext
+	for _, payload := range []string{
+		`{"comment":{"body":"choochoo_encrypted"}}`,
+		`{"choochoo_encrypted":{"key_id":"k1"},"action":"opened"}`,
+		`not json choochoo_encrypted`,
+	} {
+		if id := KeyID([]byte(payload)); id != "" {
+			t.Errorf("Expected %s to be plaintext, got key %q", payload, id)
+		}
+	}
+}
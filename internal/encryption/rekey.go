@@ -0,0 +1,93 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+// rekeyBatchSize is how many payloads are re-encrypted per query
+const rekeyBatchSize = 500
+
+// storedPayload is a stored payload to re-encrypt
+type storedPayload struct {
+	id         int32
+	deliveryID string
+	payload    []byte
+}
+
+// Rekey re-encrypts every stored and archived payload that is not encrypted
+// with the active key, including plaintext payloads, and returns how many it
+// re-encrypted. Once it succeeds, keys other than the active key are no
+// longer needed.
+func (k *Keyring) Rekey(ctx context.Context, queries *db.Queries) (int, error) {
+	events, err := k.rekeyTable(ctx, "webhook_events",
+		func(afterID int32) ([]storedPayload, error) {
+			rows, err := queries.ListWebhookEventsToRekey(ctx, db.ListWebhookEventsToRekeyParams{
+				AfterID:  afterID,
+				KeyID:    k.active,
+				RowLimit: rekeyBatchSize,
+			})
+			payloads := make([]storedPayload, 0, len(rows))
+			for _, row := range rows {
+				payloads = append(payloads, storedPayload{row.ID, row.DeliveryID, row.Payload})
+			}
+			return payloads, err
+		},
+		func(id int32, payload []byte) error {
+			return queries.UpdateWebhookEventPayload(ctx, db.UpdateWebhookEventPayloadParams{ID: id, Payload: payload})
+		},
+	)
+	if err != nil {
+		return events, err
+	}
+
+	archived, err := k.rekeyTable(ctx, "webhook_events_archive",
+		func(afterID int32) ([]storedPayload, error) {
+			rows, err := queries.ListArchivedEventsToRekey(ctx, db.ListArchivedEventsToRekeyParams{
+				AfterID:  afterID,
+				KeyID:    k.active,
+				RowLimit: rekeyBatchSize,
+			})
+			payloads := make([]storedPayload, 0, len(rows))
+			for _, row := range rows {
+				payloads = append(payloads, storedPayload{row.ID, row.DeliveryID, row.Payload})
+			}
+			return payloads, err
+		},
+		func(id int32, payload []byte) error {
+			return queries.UpdateArchivedEventPayload(ctx, db.UpdateArchivedEventPayloadParams{ID: id, Payload: payload})
+		},
+	)
+	return events + archived, err
+}
+
+// rekeyTable re-encrypts the payloads of one table in batches, in ID order
+func (k *Keyring) rekeyTable(ctx context.Context, table string, list func(afterID int32) ([]storedPayload, error), update func(id int32, payload []byte) error) (int, error) {
+	rekeyed := 0
+	var afterID int32
+	for {
+		payloads, err := list(afterID)
+		if err != nil {
+			return rekeyed, fmt.Errorf("failed to list %s: %w", table, err)
+		}
+		if len(payloads) == 0 {
+			return rekeyed, nil
+		}
+		for _, stored := range payloads {
+			afterID = stored.id
+			sealed, changed, err := k.Rotate(stored.deliveryID, stored.payload)
+			if err != nil {
+				return rekeyed, fmt.Errorf("%s delivery %s: %w", table, stored.deliveryID, err)
+			}
+			if !changed {
+				continue
+			}
+			if err := update(stored.id, sealed); err != nil {
+				return rekeyed, fmt.Errorf("failed to update %s delivery %s: %w", table, stored.deliveryID, err)
+			}
+			rekeyed++
+		}
+	}
+}
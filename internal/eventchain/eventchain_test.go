@@ -0,0 +1,193 @@
+package eventchain
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/encryption"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestKeys(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseKey(EncodeKey(key) + "\n")
+	if err != nil {
+		t.Fatalf("Expected the encoded key to parse, got %v", err)
+	}
+	public := parsed.Public().(ed25519.PublicKey)
+	if !public.Equal(key.Public()) {
+		t.Error("Expected the parsed key to be the generated key")
+	}
+	if parsedPublic, err := ParsePublicKey(EncodePublicKey(public)); err != nil || !parsedPublic.Equal(public) {
+		t.Errorf("Expected the public key to round-trip, got %v", err)
+	}
+	if len(KeyID(public)) != 16 {
+		t.Errorf("Unexpected key ID %q", KeyID(public))
+	}
+
+	for _, encoded := range []string{"", "not base64!", "c2hvcnQ="} {
+		if _, err := ParseKey(encoded); err == nil {
+			t.Errorf("Expected %q not to parse", encoded)
+		}
+	}
+	if _, err := ParsePublicKey("c2hvcnQ="); err == nil {
+		t.Error("Expected a short public key not to parse")
+	}
+
+	signature := Sign(key, 7, Genesis)
+	if !VerifySignature(public, 7, Genesis, signature) {
+		t.Error("Expected the signature to verify")
+	}
+	if VerifySignature(public, 8, Genesis, signature) {
+		t.Error("Expected the signature not to verify another link")
+	}
+}
+
+func TestLink(t *testing.T) {
+	first := Link(Genesis, "d1", checksum.Sum([]byte(`{"a":1}`)))
+	if len(first) != 64 || first != Link(Genesis, "d1", checksum.Sum([]byte(`{"a":1}`))) {
+		t.Fatalf("Expected a stable SHA-256, got %q", first)
+	}
+	if Link(first, "d1", checksum.Sum([]byte(`{"a":1}`))) == first || Link(Genesis, "d2", checksum.Sum([]byte(`{"a":1}`))) == first {
+		t.Error("Expected the previous hash and delivery ID to change the link")
+	}
+}
+
+// chain builds n chained links over stored events with payload {"n":i}
+func chain(n int) []db.ListEventChainRow {
+	links := make([]db.ListEventChainRow, n)
+	prev := Genesis
+	for i := range links {
+		deliveryID := fmt.Sprintf("d%d", i+1)
+		payload := []byte(fmt.Sprintf(`{"n": %d}`, i+1))
+		sum := checksum.Sum(payload)
+		hash := Link(prev, deliveryID, sum)
+		links[i] = db.ListEventChainRow{
+			Seq:             int64(i + 1),
+			DeliveryID:      deliveryID,
+			PayloadSha256:   sum,
+			PrevHash:        prev,
+			Hash:            hash,
+			EventDeliveryID: pgtype.Text{String: deliveryID, Valid: true},
+			Payload:         payload,
+		}
+		prev = hash
+	}
+	return links
+}
+
+func verify(key ed25519.PrivateKey, checkpoints []db.EventChainCheckpoint, links []db.ListEventChainRow) Report {
+	v := NewVerifier(key.Public().(ed25519.PublicKey), nil, checkpoints)
+	for _, link := range links {
+		v.Add(link)
+	}
+	return v.Report()
+}
+
+func TestVerifier(t *testing.T) {
+	key, _ := GenerateKey()
+	keyID := KeyID(key.Public().(ed25519.PublicKey))
+	checkpoint := func(link db.ListEventChainRow) db.EventChainCheckpoint {
+		return db.EventChainCheckpoint{Seq: link.Seq, Hash: link.Hash, KeyID: keyID, Signature: Sign(key, link.Seq, link.Hash)}
+	}
+
+	links := chain(5)
+	checkpoints := []db.EventChainCheckpoint{checkpoint(links[2]), checkpoint(links[4])}
+	report := verify(key, checkpoints, links)
+	if len(report.Problems) != 0 {
+		t.Fatalf("Expected the chain to verify, got %+v", report.Problems)
+	}
+	if report.Events != 5 || report.HeadSeq != 5 || report.HeadHash != links[4].Hash || report.LatestCheckpoint.Seq != 5 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	tests := map[string]struct {
+		tamper func(links []db.ListEventChainRow) []db.ListEventChainRow
+		kind   string
+		seq    int64
+	}{
+		"modified payload": {func(links []db.ListEventChainRow) []db.ListEventChainRow {
+			links[1].Payload = []byte(`{"n": 20}`)
+			return links
+		}, ProblemModified, 2},
+		"swapped event": {func(links []db.ListEventChainRow) []db.ListEventChainRow {
+			links[1].EventDeliveryID.String = "d9"
+			return links
+		}, ProblemModified, 2},
+		"deleted event": {func(links []db.ListEventChainRow) []db.ListEventChainRow {
+			links[3].EventDeliveryID, links[3].Payload = pgtype.Text{}, nil
+			return links
+		}, ProblemDeleted, 4},
+		"deleted link": {func(links []db.ListEventChainRow) []db.ListEventChainRow {
+			return append(links[:1], links[2:]...)
+		}, ProblemMissing, 2},
+		"truncated chain": {func(links []db.ListEventChainRow) []db.ListEventChainRow {
+			return links[:4]
+		}, ProblemMissing, 5},
+		"altered link": {func(links []db.ListEventChainRow) []db.ListEventChainRow {
+			links[0].PayloadSha256 = checksum.Sum(links[0].Payload[:0])
+			return links
+		}, ProblemAltered, 1},
+		"rewritten chain": {func(links []db.ListEventChainRow) []db.ListEventChainRow {
+			// Dropping an event and recomputing the links keeps the chain
+			// consistent, but not with the signed checkpoints
+			rewritten := chain(5)
+			rewritten = append(rewritten[:1], rewritten[2:]...)
+			for i := 1; i < len(rewritten); i++ {
+				rewritten[i].Seq = int64(i + 1)
+				rewritten[i].PrevHash = rewritten[i-1].Hash
+				rewritten[i].Hash = Link(rewritten[i].PrevHash, rewritten[i].DeliveryID, rewritten[i].PayloadSha256)
+			}
+			return rewritten
+		}, ProblemCheckpoint, 3},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			report := verify(key, checkpoints, tt.tamper(chain(5)))
+			for _, problem := range report.Problems {
+				if problem.Kind == tt.kind && problem.Seq == tt.seq {
+					return
+				}
+			}
+			t.Errorf("Expected a %s problem at %d, got %+v", tt.kind, tt.seq, report.Problems)
+		})
+	}
+
+	forged := checkpoint(links[4])
+	forged.Hash = links[3].Hash
+	other, _ := GenerateKey()
+	rotated := checkpoint(links[2])
+	rotated.KeyID = KeyID(other.Public().(ed25519.PublicKey))
+	report = verify(key, []db.EventChainCheckpoint{forged, rotated}, links)
+	if len(report.Problems) != 2 || report.Problems[0].Kind != ProblemCheckpoint || report.Problems[1].Kind != ProblemCheckpoint || report.LatestCheckpoint != nil {
+		t.Errorf("Expected both checkpoints to be rejected, got %+v", report.Problems)
+	}
+}
+
+func TestVerifier_EncryptedPayloads(t *testing.T) {
+	keys, err := encryption.ParseKeys("k1:MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := chain(2)
+	links[0].Payload, _ = keys.Seal(links[0].DeliveryID, links[0].Payload)
+
+	key, _ := GenerateKey()
+	v := NewVerifier(key.Public().(ed25519.PublicKey), keys, nil)
+	for _, link := range links {
+		v.Add(link)
+	}
+	if problems := v.Report().Problems; len(problems) != 0 {
+		t.Errorf("Expected the decrypted payload to verify, got %+v", problems)
+	}
+
+	if report := verify(key, nil, links); len(report.Problems) != 1 || report.Problems[0].Kind != ProblemUndecryptable {
+		t.Errorf("Expected the payload to be undecryptable without keys, got %+v", report.Problems)
+	}
+}
@@ -0,0 +1,185 @@
+package eventchain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/encryption"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// batchSize is the number of events chained per transaction
+const batchSize = 500
+
+// lateCommitWindow is how long before the newest chained event the sealer
+// looks for unchained events, so events whose transactions committed after
+// later events were chained still get chained
+const lateCommitWindow = time.Hour
+
+// Sealer chains newly stored events and signs checkpoints of the chain.
+// Appends are serialized with an advisory lock, so every replica can run
+// one.
+type Sealer struct {
+	conn            *database.Connection
+	keys            *encryption.Keyring
+	key             ed25519.PrivateKey
+	checkpointEvery time.Duration
+}
+
+// NewSealer creates a sealer signing a checkpoint with key at most every
+// checkpointEvery. keys decrypt the payloads of events stored before
+// payload checksums were recorded.
+func NewSealer(conn *database.Connection, keys *encryption.Keyring, key ed25519.PrivateKey, checkpointEvery time.Duration) *Sealer {
+	return &Sealer{conn: conn, keys: keys, key: key, checkpointEvery: checkpointEvery}
+}
+
+// RunOnce chains every unchained event and signs a checkpoint if one is due.
+// It returns the number of events chained and the new checkpoint, if any.
+func (s *Sealer) RunOnce(ctx context.Context, now time.Time) (int, *db.EventChainCheckpoint, error) {
+	chained := 0
+	for {
+		n, err := s.chain(ctx)
+		chained += n
+		if err != nil {
+			return chained, nil, fmt.Errorf("failed to chain events: %w", err)
+		}
+		if n < batchSize {
+			break
+		}
+	}
+	checkpoint, err := s.checkpoint(ctx, now)
+	if err != nil {
+		return chained, nil, fmt.Errorf("failed to sign checkpoint: %w", err)
+	}
+	return chained, checkpoint, nil
+}
+
+// chain appends one batch of unchained events to the chain
+func (s *Sealer) chain(ctx context.Context) (int, error) {
+	chained := 0
+	err := s.conn.InTx(ctx, func(queries *db.Queries) error {
+		if err := queries.LockEventChain(ctx); err != nil {
+			return err
+		}
+		prev, seq := Genesis, int64(0)
+		var since time.Time
+		head, err := queries.GetEventChainHead(ctx)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+		case err != nil:
+			return err
+		default:
+			prev, seq = head.Hash, head.Seq
+			since = head.EventCreatedAt.Time.Add(-lateCommitWindow)
+		}
+
+		rows, err := queries.ListUnchainedWebhookEvents(ctx, db.ListUnchainedWebhookEventsParams{
+			Since:    pgtype.Timestamptz{Time: since, Valid: true},
+			RowLimit: batchSize,
+		})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			// The checksum taken on receipt is chained, so a payload
+			// changed before it was chained still fails verification
+			sum := row.PayloadSha256.String
+			if !row.PayloadSha256.Valid {
+				payload, err := s.keys.Open(row.DeliveryID, row.Payload)
+				if err != nil {
+					return fmt.Errorf("delivery %s: %w", row.DeliveryID, err)
+				}
+				sum = checksum.Sum(payload)
+			}
+			seq++
+			hash := Link(prev, row.DeliveryID, sum)
+			if err := queries.CreateEventChainLink(ctx, db.CreateEventChainLinkParams{
+				Seq:            seq,
+				EventID:        row.ID,
+				DeliveryID:     row.DeliveryID,
+				PayloadSha256:  sum,
+				PrevHash:       prev,
+				Hash:           hash,
+				EventCreatedAt: row.CreatedAt,
+			}); err != nil {
+				return err
+			}
+			prev = hash
+		}
+		chained = len(rows)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return chained, nil
+}
+
+// checkpoint signs the head of the chain if it moved since the latest
+// checkpoint and that is at least checkpointEvery old
+func (s *Sealer) checkpoint(ctx context.Context, now time.Time) (*db.EventChainCheckpoint, error) {
+	var signed *db.EventChainCheckpoint
+	err := s.conn.InTx(ctx, func(queries *db.Queries) error {
+		if err := queries.LockEventChain(ctx); err != nil {
+			return err
+		}
+		head, err := queries.GetEventChainHead(ctx)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		latest, err := queries.GetLatestEventChainCheckpoint(ctx)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+		case err != nil:
+			return err
+		case latest.Seq >= head.Seq || now.Sub(latest.CreatedAt.Time) < s.checkpointEvery:
+			return nil
+		}
+
+		checkpoint, err := queries.CreateEventChainCheckpoint(ctx, db.CreateEventChainCheckpointParams{
+			Seq:       head.Seq,
+			Hash:      head.Hash,
+			KeyID:     KeyID(s.key.Public().(ed25519.PublicKey)),
+			Signature: Sign(s.key, head.Seq, head.Hash),
+		})
+		if err != nil {
+			return err
+		}
+		signed = &checkpoint
+		return nil
+	})
+	return signed, err
+}
+
+// Run chains events every interval until ctx is cancelled. Checkpoints are
+// logged, so copies kept with the logs show if the chain was cut short.
+func (s *Sealer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			_, checkpoint, err := s.RunOnce(ctx, now.UTC())
+			if err != nil {
+				log.Printf("Event chain sealer failed: %v", err)
+			}
+			if checkpoint != nil {
+				log.Printf("Signed event chain checkpoint %d: %s (key: %s)", checkpoint.Seq, checkpoint.Hash, checkpoint.KeyID)
+			}
+		}
+	}
+}
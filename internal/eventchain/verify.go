@@ -0,0 +1,188 @@
+package eventchain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/encryption"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Kinds of problems verification finds
+const (
+	// ProblemMissing is a gap in the chain, from deleted links
+	ProblemMissing = "missing"
+	// ProblemBroken is a link whose previous hash is not the hash of the
+	// link before it
+	ProblemBroken = "broken"
+	// ProblemAltered is a link whose hash does not match its contents
+	ProblemAltered = "altered"
+	// ProblemDeleted is a chained event that is neither stored nor archived
+	ProblemDeleted = "deleted"
+	// ProblemModified is a chained event whose payload or delivery ID
+	// changed
+	ProblemModified = "modified"
+	// ProblemUndecryptable is a chained event whose payload cannot be
+	// decrypted with the configured keys
+	ProblemUndecryptable = "undecryptable"
+	// ProblemUnchained is an event stored before the head of the chain that
+	// is not in it
+	ProblemUnchained = "unchained"
+	// ProblemCheckpoint is a checkpoint with an invalid signature or one
+	// that does not match the chain
+	ProblemCheckpoint = "checkpoint"
+)
+
+// Problem is a sign the stored history was tampered with
+type Problem struct {
+	Kind       string `json:"kind"`
+	Seq        int64  `json:"seq,omitempty"`
+	DeliveryID string `json:"delivery_id,omitempty"`
+	Detail     string `json:"detail"`
+}
+
+// Report is the outcome of verifying the chain
+type Report struct {
+	Events           int64                    `json:"events"`
+	HeadSeq          int64                    `json:"head_seq"`
+	HeadHash         string                   `json:"head_hash,omitempty"`
+	Checkpoints      int                      `json:"checkpoints"`
+	LatestCheckpoint *db.EventChainCheckpoint `json:"latest_checkpoint,omitempty"`
+	Problems         []Problem                `json:"problems"`
+}
+
+// Verifier checks the links of the chain, in order, against each other,
+// the events they chain and the signed checkpoints
+type Verifier struct {
+	keys        *encryption.Keyring
+	checkpoints map[int64]db.EventChainCheckpoint
+	report      Report
+}
+
+// NewVerifier creates a verifier of the chain with checkpoints signed by
+// key. keys decrypt the payloads of chained events.
+func NewVerifier(key ed25519.PublicKey, keys *encryption.Keyring, checkpoints []db.EventChainCheckpoint) *Verifier {
+	v := &Verifier{
+		keys:        keys,
+		checkpoints: make(map[int64]db.EventChainCheckpoint, len(checkpoints)),
+		report:      Report{HeadHash: Genesis, Checkpoints: len(checkpoints), Problems: []Problem{}},
+	}
+	keyID := KeyID(key)
+	for _, checkpoint := range checkpoints {
+		checkpoint := checkpoint
+		switch {
+		case checkpoint.KeyID != keyID:
+			v.problem(ProblemCheckpoint, checkpoint.Seq, "", fmt.Sprintf("signed with unknown key %s", checkpoint.KeyID))
+		case !VerifySignature(key, checkpoint.Seq, checkpoint.Hash, checkpoint.Signature):
+			v.problem(ProblemCheckpoint, checkpoint.Seq, "", "invalid signature")
+		default:
+			v.checkpoints[checkpoint.Seq] = checkpoint
+			v.report.LatestCheckpoint = &checkpoint
+		}
+	}
+	return v
+}
+
+func (v *Verifier) problem(kind string, seq int64, deliveryID, detail string) {
+	v.report.Problems = append(v.report.Problems, Problem{Kind: kind, Seq: seq, DeliveryID: deliveryID, Detail: detail})
+}
+
+// Add verifies the next link of the chain
+func (v *Verifier) Add(link db.ListEventChainRow) {
+	r := &v.report
+	if link.Seq != r.HeadSeq+1 {
+		v.problem(ProblemMissing, r.HeadSeq+1, "", fmt.Sprintf("links %d to %d are missing", r.HeadSeq+1, link.Seq-1))
+	} else if link.PrevHash != r.HeadHash {
+		v.problem(ProblemBroken, link.Seq, link.DeliveryID, "previous hash does not match the link before")
+	}
+	if Link(link.PrevHash, link.DeliveryID, link.PayloadSha256) != link.Hash {
+		v.problem(ProblemAltered, link.Seq, link.DeliveryID, "hash does not match the link")
+	}
+	if checkpoint, ok := v.checkpoints[link.Seq]; ok && checkpoint.Hash != link.Hash {
+		v.problem(ProblemCheckpoint, link.Seq, link.DeliveryID, "link does not match its signed checkpoint")
+	}
+	v.checkEvent(link)
+
+	r.Events++
+	r.HeadSeq = link.Seq
+	r.HeadHash = link.Hash
+}
+
+// checkEvent checks the event a link chains against the link
+func (v *Verifier) checkEvent(link db.ListEventChainRow) {
+	switch {
+	case !link.EventDeliveryID.Valid:
+		v.problem(ProblemDeleted, link.Seq, link.DeliveryID, "event is neither stored nor archived")
+		return
+	case link.EventDeliveryID.String != link.DeliveryID:
+		v.problem(ProblemModified, link.Seq, link.DeliveryID, fmt.Sprintf("event has delivery ID %s", link.EventDeliveryID.String))
+		return
+	}
+	payload, err := v.keys.Open(link.DeliveryID, link.Payload)
+	if err != nil {
+		v.problem(ProblemUndecryptable, link.Seq, link.DeliveryID, err.Error())
+		return
+	}
+	if checksum.Verify(payload, link.PayloadSha256) != nil {
+		v.problem(ProblemModified, link.Seq, link.DeliveryID, "payload does not match its chained checksum")
+	}
+}
+
+// Report returns the outcome once every link was added. A checkpoint past
+// the end of the chain means links were cut from it.
+func (v *Verifier) Report() Report {
+	if latest := v.report.LatestCheckpoint; latest != nil && latest.Seq > v.report.HeadSeq {
+		v.problem(ProblemMissing, latest.Seq, "", fmt.Sprintf("chain ends at %d before signed checkpoint %d", v.report.HeadSeq, latest.Seq))
+	}
+	return v.report
+}
+
+// Verify verifies the whole chain in the database, and that no event
+// stored before its head was left out
+func Verify(ctx context.Context, queries *db.Queries, key ed25519.PublicKey, keys *encryption.Keyring) (Report, error) {
+	checkpoints, err := queries.ListEventChainCheckpoints(ctx)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	v := NewVerifier(key, keys, checkpoints)
+
+	var afterSeq int64
+	var head pgtype.Timestamptz
+	for {
+		links, err := queries.ListEventChain(ctx, db.ListEventChainParams{AfterSeq: afterSeq, RowLimit: batchSize})
+		if err != nil {
+			return Report{}, fmt.Errorf("failed to list the chain: %w", err)
+		}
+		for _, link := range links {
+			v.Add(link)
+			afterSeq = link.Seq
+		}
+		if len(links) < batchSize {
+			break
+		}
+	}
+
+	if afterSeq > 0 {
+		link, err := queries.GetEventChainHead(ctx)
+		if err != nil {
+			return Report{}, err
+		}
+		head = link.EventCreatedAt
+	}
+	if head.Valid {
+		// Events stored within the late commit window of the head may not
+		// have been chained yet
+		head.Time = head.Time.Add(-lateCommitWindow)
+		unchained, err := queries.CountUnchainedWebhookEvents(ctx, head)
+		if err != nil {
+			return Report{}, fmt.Errorf("failed to count unchained events: %w", err)
+		}
+		if unchained > 0 {
+			v.problem(ProblemUnchained, 0, "", fmt.Sprintf("%d events stored before the head of the chain are not in it", unchained))
+		}
+	}
+	return v.Report(), nil
+}
@@ -0,0 +1,170 @@
+package forwarder
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/deedubs/choochoo/internal/tracing"
+)
+
+// MaxAWSMessageSize is the largest message SQS and SNS accept, counting the
+// message attributes
+const MaxAWSMessageSize = 256 << 10
+
+// sqsAPI is the part of the SQS client the forwarder uses
+type sqsAPI interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// snsAPI is the part of the SNS client the forwarder uses
+type snsAPI interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// AWSForwarder publishes events to an SQS queue or an SNS topic, with the
+// event type, action, repository, sender and delivery ID as message
+// attributes so SNS subscriptions can filter on them
+type AWSForwarder struct {
+	target   arn.ARN
+	fifo     bool
+	queueURL string
+	sqs      sqsAPI
+	sns      snsAPI
+}
+
+// ParseAWSARN parses the ARN of an SQS queue or SNS topic
+func ParseAWSARN(text string) (arn.ARN, error) {
+	target, err := arn.Parse(text)
+	if err != nil {
+		return target, err
+	}
+	if target.Service != "sqs" && target.Service != "sns" {
+		return target, fmt.Errorf("%s is not an SQS queue or SNS topic", text)
+	}
+	if target.Region == "" || target.AccountID == "" || target.Resource == "" || strings.ContainsAny(target.Resource, ":/") {
+		return target, fmt.Errorf("%s is missing the region, account or %s name", text, target.Service)
+	}
+	return target, nil
+}
+
+// NewAWSForwarder creates a forwarder for the queue or topic with the given
+// ARN. Credentials come from the default AWS chain: environment variables,
+// shared config and credentials files, web identity, and the ECS or EC2
+// instance role. The queue URL of an SQS queue is looked up, which checks
+// that the queue exists and the credentials can see it.
+func NewAWSForwarder(ctx context.Context, target string) (*AWSForwarder, error) {
+	parsed, err := ParseAWSARN(target)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(parsed.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	af := &AWSForwarder{target: parsed, fifo: strings.HasSuffix(parsed.Resource, ".fifo")}
+	if parsed.Service == "sns" {
+		af.sns = sns.NewFromConfig(cfg)
+		return af, nil
+	}
+
+	client := sqs.NewFromConfig(cfg)
+	queue, err := client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{
+		QueueName:              aws.String(parsed.Resource),
+		QueueOwnerAWSAccountId: aws.String(parsed.AccountID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up SQS queue %s: %w", parsed.Resource, err)
+	}
+	af.sqs = client
+	af.queueURL = aws.ToString(queue.QueueUrl)
+	return af, nil
+}
+
+// Name identifies the forwarder in logs
+func (af *AWSForwarder) Name() string {
+	return af.target.Service + ":" + af.target.Resource
+}
+
+// Forward sends the event payload as a message. Messages to FIFO queues and
+// topics are grouped by repository and de-duplicated on the delivery ID.
+func (af *AWSForwarder) Forward(ctx context.Context, event Event) error {
+	attributes := messageAttributes(ctx, event)
+	size := len(event.Payload)
+	for name, value := range attributes {
+		size += len(name) + len(value) + len("String")
+	}
+	if size > MaxAWSMessageSize {
+		return fmt.Errorf("%w: message of %d bytes exceeds the %d byte limit of %s", ErrUndeliverable, size, MaxAWSMessageSize, af.target.Service)
+	}
+
+	var groupID, deduplicationID *string
+	if af.fifo {
+		group := event.Repository
+		if group == "" {
+			group = "unknown"
+		}
+		groupID = aws.String(group)
+		deduplicationID = aws.String(event.DeliveryID)
+	}
+
+	if af.sns != nil {
+		message := &sns.PublishInput{
+			TopicArn:               aws.String(af.target.String()),
+			Message:                aws.String(string(event.Payload)),
+			MessageAttributes:      make(map[string]snstypes.MessageAttributeValue, len(attributes)),
+			MessageGroupId:         groupID,
+			MessageDeduplicationId: deduplicationID,
+		}
+		for name, value := range attributes {
+			message.MessageAttributes[name] = snstypes.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+		}
+		_, err := af.sns.Publish(ctx, message)
+		return err
+	}
+
+	message := &sqs.SendMessageInput{
+		QueueUrl:               aws.String(af.queueURL),
+		MessageBody:            aws.String(string(event.Payload)),
+		MessageAttributes:      make(map[string]sqstypes.MessageAttributeValue, len(attributes)),
+		MessageGroupId:         groupID,
+		MessageDeduplicationId: deduplicationID,
+	}
+	for name, value := range attributes {
+		message.MessageAttributes[name] = sqstypes.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+	}
+	_, err := af.sqs.SendMessage(ctx, message)
+	return err
+}
+
+// messageAttributes returns the attributes of an event's message. Empty
+// values are left out, as SQS and SNS reject them.
+func messageAttributes(ctx context.Context, event Event) map[string]string {
+	trace := http.Header{}
+	tracing.Inject(ctx, trace)
+
+	attributes := make(map[string]string)
+	for name, value := range map[string]string{
+		"event_type":     event.EventType,
+		"action":         event.Action,
+		"repository":     event.Repository,
+		"sender":         event.Sender,
+		"delivery_id":    event.DeliveryID,
+		"payload_sha256": event.Checksum,
+		"traceparent":    trace.Get("traceparent"),
+	} {
+		if value != "" {
+			attributes[name] = value
+		}
+	}
+	return attributes
+}
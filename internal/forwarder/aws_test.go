@@ -0,0 +1,108 @@
+package forwarder
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+type fakeSQS struct {
+	sent []*sqs.SendMessageInput
+}
+
+func (f *fakeSQS) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	f.sent = append(f.sent, params)
+	return &sqs.SendMessageOutput{}, nil
+}
+
+type fakeSNS struct {
+	published []*sns.PublishInput
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.published = append(f.published, params)
+	return &sns.PublishOutput{}, nil
+}
+
+// TestParseAWSARN tests accepting only SQS queue and SNS topic ARNs
+func TestParseAWSARN(t *testing.T) {
+	for _, valid := range []string{
+		"arn:aws:sqs:us-east-1:123456789012:choochoo-events",
+		"arn:aws:sns:eu-west-1:123456789012:choochoo.fifo",
+	} {
+		if _, err := ParseAWSARN(valid); err != nil {
+			t.Errorf("ParseAWSARN(%q) returned error: %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{
+		"choochoo-events",
+		"arn:aws:s3:::choochoo-events",
+		"arn:aws:sqs::123456789012:choochoo-events",
+		"arn:aws:sns:us-east-1:123456789012:choochoo:subscription-id",
+	} {
+		if _, err := ParseAWSARN(invalid); err == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
+
+// TestAWSForwarder_SQS tests the message and attributes sent to a queue
+func TestAWSForwarder_SQS(t *testing.T) {
+	target, _ := ParseAWSARN("arn:aws:sqs:us-east-1:123456789012:choochoo-events")
+	client := &fakeSQS{}
+	af := &AWSForwarder{target: target, queueURL: "https://sqs.us-east-1.amazonaws.com/123456789012/choochoo-events", sqs: client}
+
+	event := Event{DeliveryID: "abc", EventType: "push", Repository: "acme/api", Sender: "octocat", Checksum: "0f", Payload: []byte(`{"ref":"refs/heads/main"}`)}
+	if err := af.Forward(context.Background(), event); err != nil {
+		t.Fatalf("Forward returned error: %v", err)
+	}
+	if af.Name() != "sqs:choochoo-events" || len(client.sent) != 1 {
+		t.Fatalf("Expected one message from %s, got %d", af.Name(), len(client.sent))
+	}
+
+	sent := client.sent[0]
+	if aws.ToString(sent.QueueUrl) != af.queueURL || aws.ToString(sent.MessageBody) != string(event.Payload) || sent.MessageGroupId != nil {
+		t.Errorf("Unexpected message %+v", sent)
+	}
+	for name, want := range map[string]string{"event_type": "push", "repository": "acme/api", "sender": "octocat", "delivery_id": "abc", "payload_sha256": "0f"} {
+		if got := aws.ToString(sent.MessageAttributes[name].StringValue); got != want {
+			t.Errorf("Expected attribute %s %q, got %q", name, want, got)
+		}
+	}
+	if _, ok := sent.MessageAttributes["action"]; ok {
+		t.Error("Expected the empty action to be left out")
+	}
+}
+
+// TestAWSForwarder_SNSFIFO tests grouping and de-duplicating messages to a
+// FIFO topic
+func TestAWSForwarder_SNSFIFO(t *testing.T) {
+	target, _ := ParseAWSARN("arn:aws:sns:eu-west-1:123456789012:choochoo.fifo")
+	client := &fakeSNS{}
+	af := &AWSForwarder{target: target, fifo: true, sns: client}
+
+	if err := af.Forward(context.Background(), Event{DeliveryID: "abc", EventType: "pull_request", Action: "opened", Repository: "acme/api", Payload: []byte(`{}`)}); err != nil {
+		t.Fatalf("Forward returned error: %v", err)
+	}
+	published := client.published[0]
+	if aws.ToString(published.TopicArn) != target.String() || aws.ToString(published.MessageGroupId) != "acme/api" || aws.ToString(published.MessageDeduplicationId) != "abc" {
+		t.Errorf("Unexpected message %+v", published)
+	}
+	if got := aws.ToString(published.MessageAttributes["action"].StringValue); got != "opened" {
+		t.Errorf("Expected the action attribute, got %q", got)
+	}
+
+	// Payloads over the limit are never sent
+	large := Event{DeliveryID: "big", EventType: "push", Payload: []byte(`"` + strings.Repeat("x", MaxAWSMessageSize) + `"`)}
+	if err := af.Forward(context.Background(), large); !errors.Is(err, ErrUndeliverable) {
+		t.Errorf("Expected ErrUndeliverable, got %v", err)
+	}
+	if len(client.published) != 1 {
+		t.Errorf("Expected the large event not to be published, got %d messages", len(client.published))
+	}
+}
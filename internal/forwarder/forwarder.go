@@ -0,0 +1,121 @@
+package forwarder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/model"
+	"github.com/deedubs/choochoo/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Event represents a received webhook event handed to forwarders
+type Event struct {
+	DeliveryID string `json:"delivery_id"`
+	EventType  string `json:"event_type"`
+	Action     string `json:"action,omitempty"`
+	Repository string `json:"repository,omitempty"`
+	Sender     string `json:"sender,omitempty"`
+	// Checksum is the SHA-256 of the canonical payload, if known, so
+	// consumers can verify the payload is the one received
+	Checksum string `json:"payload_sha256,omitempty"`
+	Payload  []byte `json:"-"`
+}
+
+// ChecksumHeader carries the payload checksum of forwarded events
+const ChecksumHeader = "X-Choochoo-Payload-SHA256"
+
+// ErrUndeliverable is returned for events a forwarder can never publish, such
+// as ones too large for it, so they are not retried
+var ErrUndeliverable = errors.New("event cannot be published")
+
+// Owner returns the owner part of the event repository
+func (e Event) Owner() string {
+	owner, _, _ := strings.Cut(e.Repository, "/")
+	return owner
+}
+
+// Repo returns the name part of the event repository
+func (e Event) Repo() string {
+	_, repo, _ := strings.Cut(e.Repository, "/")
+	return repo
+}
+
+// Model returns the provider-agnostic model of the event, or nil for event
+// types without one or payloads that do not parse
+func (e Event) Model() *model.Event {
+	event, err := model.Adapt(model.GitHub, e.EventType, e.Payload)
+	if err != nil {
+		return nil
+	}
+	return event
+}
+
+// Variables returns the event as the variables of rule conditions and chat
+// message templates: event, action, repository, sender, delivery_id, the
+// decoded payload and its model, null for events without one
+func (e Event) Variables() (map[string]interface{}, error) {
+	var payload interface{}
+	if err := json.Unmarshal(e.Payload, &payload); err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %w", err)
+	}
+	var modelled interface{}
+	if event := e.Model(); event != nil {
+		data, _ := json.Marshal(event)
+		json.Unmarshal(data, &modelled)
+	}
+	return map[string]interface{}{
+		"event":       e.EventType,
+		"action":      e.Action,
+		"repository":  e.Repository,
+		"sender":      e.Sender,
+		"delivery_id": e.DeliveryID,
+		"payload":     payload,
+		"model":       modelled,
+	}, nil
+}
+
+// Forwarder publishes events to an external system
+type Forwarder interface {
+	// Name identifies the forwarder in logs
+	Name() string
+	// Forward publishes a single event
+	Forward(ctx context.Context, event Event) error
+}
+
+// TransportWrapper is implemented by forwarders that send HTTP requests, so
+// the transport they send them with can be wrapped, such as to record them
+type TransportWrapper interface {
+	WrapTransport(wrap func(http.RoundTripper) http.RoundTripper)
+}
+
+// WrapTransports wraps the transport of each forwarder that sends HTTP
+// requests with wrap. Other forwarders, and nil ones, are left alone.
+func WrapTransports(forwarders []Forwarder, wrap func(http.RoundTripper) http.RoundTripper) {
+	for _, f := range forwarders {
+		if w, ok := f.(TransportWrapper); ok {
+			w.WrapTransport(wrap)
+		}
+	}
+}
+
+// ForwardAll sends an event to every forwarder, logging failures without
+// interrupting the remaining forwarders
+func ForwardAll(ctx context.Context, forwarders []Forwarder, event Event) {
+	for _, f := range forwarders {
+		fctx, span := tracing.Start(ctx, "forward "+f.Name(), attribute.String("github.delivery", event.DeliveryID))
+		fctx, cancel := context.WithTimeout(fctx, 5*time.Second)
+		err := f.Forward(fctx, event)
+		if err != nil {
+			log.Printf("Failed to forward %s event to %s (delivery: %s): %v", event.EventType, f.Name(), event.DeliveryID, err)
+		}
+		cancel()
+		tracing.End(span, err)
+	}
+}
@@ -0,0 +1,64 @@
+package forwarder
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type recordingForwarder struct {
+	name   string
+	err    error
+	events []Event
+}
+
+func (rf *recordingForwarder) Name() string { return rf.name }
+
+func (rf *recordingForwarder) Forward(ctx context.Context, event Event) error {
+	rf.events = append(rf.events, event)
+	return rf.err
+}
+
+// TestEvent_OwnerRepo tests splitting the repository full name
+func TestEvent_OwnerRepo(t *testing.T) {
+	event := Event{Repository: "octo-org/hello-world"}
+	if event.Owner() != "octo-org" || event.Repo() != "hello-world" {
+		t.Errorf("Unexpected owner/repo: %s/%s", event.Owner(), event.Repo())
+	}
+
+	empty := Event{}
+	if empty.Owner() != "" || empty.Repo() != "" {
+		t.Errorf("Expected empty owner/repo, got %s/%s", empty.Owner(), empty.Repo())
+	}
+}
+
+// TestEvent_Variables tests exposing the event model to rules and templates
+func TestEvent_Variables(t *testing.T) {
+	event := Event{EventType: "pull_request", Payload: []byte(`{"action": "opened", "pull_request": {"number": 7, "head": {"ref": "retries"}}}`)}
+	vars, err := event.Variables()
+	if err != nil {
+		t.Fatalf("Variables() error = %v", err)
+	}
+	modelled, _ := vars["model"].(map[string]interface{})
+	pr, _ := modelled["pull_request"].(map[string]interface{})
+	if modelled["provider"] != "github" || pr["head_branch"] != "retries" {
+		t.Errorf("Unexpected model: %v", vars["model"])
+	}
+
+	vars, err = Event{EventType: "star", Payload: []byte(`{}`)}.Variables()
+	if err != nil || vars["model"] != nil {
+		t.Errorf("Variables() model = %v, %v, want nil", vars["model"], err)
+	}
+}
+
+// TestForwardAll tests that a failing forwarder does not stop the others
+func TestForwardAll(t *testing.T) {
+	failing := &recordingForwarder{name: "failing", err: errors.New("boom")}
+	working := &recordingForwarder{name: "working"}
+
+	ForwardAll(context.Background(), []Forwarder{failing, working}, Event{DeliveryID: "d1", EventType: "push"})
+
+	if len(failing.events) != 1 || len(working.events) != 1 {
+		t.Errorf("Expected both forwarders to receive the event, got %d and %d", len(failing.events), len(working.events))
+	}
+}
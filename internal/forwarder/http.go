@@ -0,0 +1,64 @@
+package forwarder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/deedubs/choochoo/internal/tracing"
+)
+
+// HTTPForwarder POSTs event payloads to an HTTP endpoint
+type HTTPForwarder struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPForwarder creates a forwarder for the given endpoint URL
+func NewHTTPForwarder(endpoint string) (*HTTPForwarder, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid forwarding URL %q", endpoint)
+	}
+	return &HTTPForwarder{url: endpoint, client: &http.Client{}}, nil
+}
+
+// WrapTransport wraps the transport the forwarder sends requests with
+func (hf *HTTPForwarder) WrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	hf.client.Transport = wrap(hf.client.Transport)
+}
+
+// Name identifies the forwarder in logs
+func (hf *HTTPForwarder) Name() string {
+	u, _ := url.Parse(hf.url)
+	return "http:" + u.Host
+}
+
+// Forward POSTs the event payload, treating any non-2xx response as a failure
+func (hf *HTTPForwarder) Forward(ctx context.Context, event Event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hf.url, bytes.NewReader(event.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "choochoo")
+	req.Header.Set("X-GitHub-Event", event.EventType)
+	req.Header.Set("X-GitHub-Delivery", event.DeliveryID)
+	if event.Checksum != "" {
+		req.Header.Set(ChecksumHeader, event.Checksum)
+	}
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := hf.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, hf.url)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package forwarder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/tracing"
+)
+
+// TestHTTPForwarder_Forward tests the forwarded request, including the trace
+// context that lets the receiver continue the delivery's trace
+func TestHTTPForwarder_Forward(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+	defer server.Close()
+
+	hf, err := NewHTTPForwarder(server.URL)
+	if err != nil {
+		t.Fatalf("NewHTTPForwarder failed: %v", err)
+	}
+
+	traceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx := tracing.WithTraceParent(context.Background(), traceParent)
+	if err := hf.Forward(ctx, Event{DeliveryID: "d1", EventType: "push", Checksum: "44136fa3", Payload: []byte(`{}`)}); err != nil {
+		t.Fatalf("Forward failed: %v", err)
+	}
+
+	if got.Header.Get("X-GitHub-Event") != "push" || got.Header.Get("X-GitHub-Delivery") != "d1" {
+		t.Errorf("Unexpected GitHub headers: %v", got.Header)
+	}
+	if got.Header.Get(ChecksumHeader) != "44136fa3" {
+		t.Errorf("Expected the payload checksum to be forwarded, got %q", got.Header.Get(ChecksumHeader))
+	}
+	if got.Header.Get("Traceparent") != traceParent {
+		t.Errorf("Expected the trace context to be forwarded, got %q", got.Header.Get("Traceparent"))
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestWrapTransports(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	hf, err := NewHTTPForwarder(server.URL)
+	if err != nil {
+		t.Fatalf("NewHTTPForwarder() error = %v", err)
+	}
+	var sent int
+	WrapTransports([]Forwarder{hf, nil}, func(base http.RoundTripper) http.RoundTripper {
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			sent++
+			return base.RoundTrip(req)
+		})
+	})
+
+	if err := hf.Forward(context.Background(), Event{DeliveryID: "12345", EventType: "push", Payload: []byte(`{}`)}); err != nil {
+		t.Fatalf("Forward() error = %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("Expected the request to go through the wrapped transport, got %d requests", sent)
+	}
+}
@@ -0,0 +1,169 @@
+package forwarder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/tracing"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DefaultNATSSubjectTemplate is the subject template used when none is configured
+const DefaultNATSSubjectTemplate = "choochoo.{{.EventType}}.{{.Owner}}.{{.Repo}}"
+
+// NATSConfig configures the NATS forwarder
+type NATSConfig struct {
+	URL             string
+	SubjectTemplate string
+	// Stream is the JetStream stream to create or update. When empty, events
+	// are published with core NATS and are not persisted.
+	Stream         string
+	StreamSubjects []string
+}
+
+// NATSForwarder publishes events to NATS subjects, optionally persisting them
+// in a JetStream stream
+type NATSForwarder struct {
+	conn    *nats.Conn
+	js      jetstream.JetStream
+	subject *template.Template
+}
+
+// subjectData is the data available to subject templates
+type subjectData struct {
+	EventType string
+	Action    string
+	Owner     string
+	Repo      string
+	Sender    string
+}
+
+// ParseSubjectTemplate parses a NATS subject template
+func ParseSubjectTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultNATSSubjectTemplate
+	}
+	tmpl, err := template.New("subject").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// NewNATSForwarder connects to NATS and, if a stream is configured, ensures
+// the JetStream stream exists
+func NewNATSForwarder(ctx context.Context, cfg NATSConfig) (*NATSForwarder, error) {
+	subject, err := ParseSubjectTemplate(cfg.SubjectTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := nats.Connect(cfg.URL, nats.Name("choochoo"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	nf := &NATSForwarder{conn: conn, subject: subject}
+	if cfg.Stream == "" {
+		return nf, nil
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	subjects := cfg.StreamSubjects
+	if len(subjects) == 0 {
+		subjects = []string{"choochoo.>"}
+	}
+	streamCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if _, err := js.CreateOrUpdateStream(streamCtx, jetstream.StreamConfig{
+		Name:     cfg.Stream,
+		Subjects: subjects,
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream stream %s: %w", cfg.Stream, err)
+	}
+	nf.js = js
+
+	return nf, nil
+}
+
+// Name identifies the forwarder in logs
+func (nf *NATSForwarder) Name() string {
+	return "nats"
+}
+
+// Subject renders the subject an event is published to
+func (nf *NATSForwarder) Subject(event Event) (string, error) {
+	return renderSubject(nf.subject, event)
+}
+
+// Forward publishes the event payload to its subject. JetStream publishes use
+// the delivery ID as message ID so redeliveries are de-duplicated.
+func (nf *NATSForwarder) Forward(ctx context.Context, event Event) error {
+	subject, err := nf.Subject(event)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(subject)
+	msg.Data = event.Payload
+	msg.Header.Set("X-GitHub-Event", event.EventType)
+	msg.Header.Set("X-GitHub-Delivery", event.DeliveryID)
+	if event.Checksum != "" {
+		msg.Header.Set(ChecksumHeader, event.Checksum)
+	}
+	tracing.Inject(ctx, http.Header(msg.Header))
+
+	if nf.js == nil {
+		return nf.conn.PublishMsg(msg)
+	}
+	_, err = nf.js.PublishMsg(ctx, msg, jetstream.WithMsgID(event.DeliveryID))
+	return err
+}
+
+// Close drains and closes the NATS connection
+func (nf *NATSForwarder) Close() error {
+	return nf.conn.Drain()
+}
+
+// renderSubject executes a subject template for an event
+func renderSubject(tmpl *template.Template, event Event) (string, error) {
+	data := subjectData{
+		EventType: subjectToken(event.EventType),
+		Action:    subjectToken(event.Action),
+		Owner:     subjectToken(event.Owner()),
+		Repo:      subjectToken(event.Repo()),
+		Sender:    subjectToken(event.Sender),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render subject: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// subjectToken makes a value safe to use as a single NATS subject token
+func subjectToken(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, value)
+}
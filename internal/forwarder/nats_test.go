@@ -0,0 +1,49 @@
+package forwarder
+
+import (
+	"testing"
+)
+
+// TestRenderSubject tests subject template rendering
+func TestRenderSubject(t *testing.T) {
+	tests := []struct {
+		template string
+		event    Event
+		expected string
+	}{
+		{"", Event{EventType: "push", Repository: "octo-org/hello-world"}, "choochoo.push.octo-org.hello-world"},
+		{"", Event{EventType: "ping"}, "choochoo.ping.unknown.unknown"},
+		{"", Event{EventType: "push", Repository: "octo-org/hello.world"}, "choochoo.push.octo-org.hello_world"},
+		{"events.{{.Owner}}.{{.EventType}}.{{.Action}}", Event{EventType: "pull_request", Action: "opened", Repository: "a/b"}, "events.a.pull_request.opened"},
+	}
+
+	for _, test := range tests {
+		tmpl, err := ParseSubjectTemplate(test.template)
+		if err != nil {
+			t.Fatalf("ParseSubjectTemplate(%q) returned error: %v", test.template, err)
+		}
+		subject, err := renderSubject(tmpl, test.event)
+		if err != nil {
+			t.Errorf("renderSubject returned error: %v", err)
+			continue
+		}
+		if subject != test.expected {
+			t.Errorf("Expected subject %s, got %s", test.expected, subject)
+		}
+	}
+}
+
+// TestParseSubjectTemplate_Invalid tests that broken templates are rejected
+func TestParseSubjectTemplate_Invalid(t *testing.T) {
+	if _, err := ParseSubjectTemplate("choochoo.{{.EventType"); err == nil {
+		t.Error("Expected error for invalid template")
+	}
+
+	tmpl, err := ParseSubjectTemplate("choochoo.{{.Missing}}")
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %v", err)
+	}
+	if _, err := renderSubject(tmpl, Event{}); err == nil {
+		t.Error("Expected error for unknown template field")
+	}
+}
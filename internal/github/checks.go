@@ -0,0 +1,84 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Check run conclusions
+const (
+	ConclusionSuccess = "success"
+	ConclusionFailure = "failure"
+)
+
+// maxAnnotations is the number of annotations GitHub accepts per request
+const maxAnnotations = 50
+
+// Annotation points a check run result at lines of a file
+type Annotation struct {
+	Path      string `json:"path"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	Level     string `json:"annotation_level"`
+	Title     string `json:"title,omitempty"`
+	Message   string `json:"message"`
+}
+
+// CheckOutput is the summary and annotations of a check run
+type CheckOutput struct {
+	Title       string       `json:"title"`
+	Summary     string       `json:"summary"`
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// Check run statuses
+const (
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+)
+
+// CheckRun is a check run on a commit
+type CheckRun struct {
+	ID         int64       `json:"id,omitempty"`
+	Name       string      `json:"name,omitempty"`
+	HeadSHA    string      `json:"head_sha,omitempty"`
+	Status     string      `json:"status"`
+	Conclusion string      `json:"conclusion,omitempty"`
+	Output     CheckOutput `json:"output"`
+}
+
+// CreateCheckRun creates a completed check run on a commit of repo, an
+// "owner/name" full name, keeping the first annotations GitHub accepts. Only
+// GitHub Apps can create check runs.
+func (c *Client) CreateCheckRun(ctx context.Context, repo string, run CheckRun) error {
+	run.Status = StatusCompleted
+	if len(run.Output.Annotations) > maxAnnotations {
+		run.Output.Annotations = run.Output.Annotations[:maxAnnotations]
+	}
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/check-runs", repo), run, nil)
+	return err
+}
+
+// StartCheckRun creates an in-progress check run on a commit of repo,
+// returning its ID for CompleteCheckRun. Only GitHub Apps can create check
+// runs.
+func (c *Client) StartCheckRun(ctx context.Context, repo string, run CheckRun) (int64, error) {
+	run.Status, run.Conclusion = StatusInProgress, ""
+	var created CheckRun
+	if _, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/check-runs", repo), run, &created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// CompleteCheckRun completes check run id of repo with the conclusion and
+// output of run
+func (c *Client) CompleteCheckRun(ctx context.Context, repo string, id int64, run CheckRun) error {
+	run.ID, run.Status = 0, StatusCompleted
+	if len(run.Output.Annotations) > maxAnnotations {
+		run.Output.Annotations = run.Output.Annotations[:maxAnnotations]
+	}
+	_, err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/repos/%s/check-runs/%d", repo, id), run, nil)
+	return err
+}
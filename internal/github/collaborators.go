@@ -0,0 +1,56 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+// Repository roles, from the least to the most privileged
+const (
+	RoleNone     = "none"
+	RoleRead     = "read"
+	RoleTriage   = "triage"
+	RoleWrite    = "write"
+	RoleMaintain = "maintain"
+	RoleAdmin    = "admin"
+)
+
+// roles orders the repository roles by privilege
+var roles = []string{RoleNone, RoleRead, RoleTriage, RoleWrite, RoleMaintain, RoleAdmin}
+
+// ValidRole reports whether role is a repository role
+func ValidRole(role string) bool {
+	return slices.Contains(roles, role)
+}
+
+// RoleAllows reports whether role includes the privileges of required.
+// Unknown roles, such as custom repository roles, allow nothing but read.
+func RoleAllows(role, required string) bool {
+	have := slices.Index(roles, role)
+	if have < 0 {
+		have = slices.Index(roles, RoleRead)
+	}
+	return have >= slices.Index(roles, required)
+}
+
+// CollaboratorRole returns the role login has on repo, an "owner/name" full
+// name, or RoleNone if it has none
+func (c *Client) CollaboratorRole(ctx context.Context, repo, login string) (string, error) {
+	var permission struct {
+		Permission string `json:"permission"`
+		RoleName   string `json:"role_name"`
+	}
+	if _, err := c.get(ctx, fmt.Sprintf("/repos/%s/collaborators/%s/permission", repo, url.PathEscape(login)), &permission); err != nil {
+		return "", err
+	}
+	// role_name tells maintain and triage apart from write and read
+	if permission.RoleName != "" {
+		return permission.RoleName, nil
+	}
+	if permission.Permission == "" {
+		return RoleNone, nil
+	}
+	return permission.Permission, nil
+}
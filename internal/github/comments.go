@@ -0,0 +1,15 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// CreateCommitComment comments on commit sha of repo, an "owner/name" full
+// name
+func (c *Client) CreateCommitComment(ctx context.Context, repo, sha, body string) error {
+	in := map[string]string{"body": body}
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/commits/%s/comments", repo, sha), in, nil)
+	return err
+}
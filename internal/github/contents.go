@@ -0,0 +1,29 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// FileContents returns the contents of the file at path in repo, an
+// "owner/name" full name, at ref. It returns ErrNotFound when the file does
+// not exist.
+func (c *Client) FileContents(ctx context.Context, repo, path, ref string) ([]byte, error) {
+	var file struct {
+		Type     string `json:"type"`
+		Encoding string `json:"encoding"`
+		Content  string `json:"content"`
+	}
+	endpoint := fmt.Sprintf("/repos/%s/contents/%s?ref=%s", repo, path, url.QueryEscape(ref))
+	if _, err := c.get(ctx, endpoint, &file); err != nil {
+		return nil, err
+	}
+	if file.Type != "file" || file.Encoding != "base64" {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+	// GitHub wraps the base64 content across lines
+	return base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+}
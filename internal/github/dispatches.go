@@ -0,0 +1,30 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Reactions of comments
+const (
+	ReactionThumbsUp   = "+1"
+	ReactionThumbsDown = "-1"
+	ReactionConfused   = "confused"
+)
+
+// CreateDispatch sends a repository_dispatch event of eventType to repo, an
+// "owner/name" full name, triggering the workflows listening for it with
+// payload as their client_payload
+func (c *Client) CreateDispatch(ctx context.Context, repo, eventType string, payload interface{}) error {
+	in := map[string]interface{}{"event_type": eventType, "client_payload": payload}
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/dispatches", repo), in, nil)
+	return err
+}
+
+// AddCommentReaction reacts to issue or pull request comment id of repo
+func (c *Client) AddCommentReaction(ctx context.Context, repo string, id int64, content string) error {
+	in := map[string]string{"content": content}
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/comments/%d/reactions", repo, id), in, nil)
+	return err
+}
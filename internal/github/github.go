@@ -0,0 +1,277 @@
+// Package github calls the GitHub REST API with a personal access token or
+// as a GitHub App installation. One client is shared by every feature that
+// calls back to GitHub, so they share its rate limit, which the client waits
+// for instead of being refused, and its retries of failed requests.
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL is the API of github.com
+const DefaultBaseURL = "https://api.github.com"
+
+// Auth kinds reported by Client.Auth
+const (
+	AuthToken = "token"
+	AuthApp   = "app"
+)
+
+// App identifies a GitHub App installation
+type App struct {
+	ID             int64
+	InstallationID int64
+	PrivateKey     *rsa.PrivateKey
+}
+
+// ErrNotFound is returned when GitHub responds 404 Not Found
+var ErrNotFound = errors.New("not found")
+
+// Client calls the GitHub REST API
+type Client struct {
+	baseURL    string
+	token      string
+	app        *App
+	httpClient *http.Client
+	// retryBackoff is the delay before the second attempt of a request,
+	// doubled for each further attempt
+	retryBackoff time.Duration
+
+	// rateMu guards the rate limit of the latest response
+	rateMu    sync.Mutex
+	rateLimit RateLimit
+
+	// mu guards the cached installation token of an app client
+	mu                sync.Mutex
+	installationToken string
+	tokenExpiry       time.Time
+}
+
+// NewTokenClient creates a client that authenticates with a personal access token
+func NewTokenClient(baseURL, token string) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, httpClient: &http.Client{Timeout: 10 * time.Second}, retryBackoff: time.Second}
+}
+
+// NewAppClient creates a client that authenticates as a GitHub App
+func NewAppClient(baseURL string, app App) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), app: &app, httpClient: &http.Client{Timeout: 10 * time.Second}, retryBackoff: time.Second}
+}
+
+// WrapTransport wraps the transport the client sends API requests with
+func (c *Client) WrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	c.httpClient.Transport = wrap(c.httpClient.Transport)
+}
+
+// Auth reports how the client authenticates
+func (c *Client) Auth() string {
+	if c.app != nil {
+		return AuthApp
+	}
+	return AuthToken
+}
+
+// ParsePrivateKey parses a GitHub App private key in PKCS#1 or PKCS#8 PEM form
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// appJWT signs the short-lived JWT a GitHub App authenticates with
+func (a *App) appJWT(now time.Time) (string, error) {
+	encode := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	// Backdate the issue time to allow for clock drift, as GitHub recommends
+	unsigned := encode(map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + encode(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(a.ID, 10),
+	})
+
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.PrivateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// authorization returns the Authorization header for a request to path. Apps
+// call the /app endpoints with their JWT and everything else with an
+// installation token.
+func (c *Client) authorization(ctx context.Context, path string) (string, error) {
+	if c.app == nil {
+		return "Bearer " + c.token, nil
+	}
+	if strings.HasPrefix(path, "/app/") {
+		jwt, err := c.app.appJWT(time.Now())
+		if err != nil {
+			return "", fmt.Errorf("failed to sign app JWT: %w", err)
+		}
+		return "Bearer " + jwt, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Renew the token a few minutes before it expires, so it does not expire
+	// during a request
+	if c.installationToken == "" || time.Until(c.tokenExpiry) < 5*time.Minute {
+		var token struct {
+			Token     string    `json:"token"`
+			ExpiresAt time.Time `json:"expires_at"`
+		}
+		if _, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/app/installations/%d/access_tokens", c.app.InstallationID), nil, &token); err != nil {
+			return "", fmt.Errorf("failed to create installation token: %w", err)
+		}
+		c.installationToken, c.tokenExpiry = token.Token, token.ExpiresAt
+	}
+	return "Bearer " + c.installationToken, nil
+}
+
+// get sends an authenticated GET request and decodes the JSON response into
+// out, returning the response headers
+func (c *Client) get(ctx context.Context, path string, out interface{}) (http.Header, error) {
+	return c.do(ctx, http.MethodGet, path, nil, out)
+}
+
+// do sends an authenticated request with in encoded as the JSON body, if not
+// nil, and decodes the JSON response into out, returning the response
+// headers. Requests refused by a rate limit are sent again once it resets,
+// and requests other than POST again after server and network errors, up to
+// maxAttempts times with exponential backoff.
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) (http.Header, error) {
+	var data []byte
+	if in != nil {
+		var err error
+		if data, err = json.Marshal(in); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		// Wait for an exhausted rate limit to reset rather than be refused.
+		// App JWT requests have a rate limit of their own.
+		if !strings.HasPrefix(path, "/app/") {
+			if wait := c.exhausted(time.Now()); wait > maxRateLimitWait {
+				return nil, fmt.Errorf("%s %s: %w until %s", method, path, ErrRateLimited, time.Now().Add(wait).Format(time.RFC3339))
+			} else if err := sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+
+		header, retry, err := c.send(ctx, method, path, data, out)
+		if err == nil || retry < 0 || attempt == maxAttempts {
+			return header, err
+		}
+		if retry == 0 {
+			retry = c.retryBackoff << (attempt - 1)
+		}
+		if retry > maxRateLimitWait {
+			return header, err
+		}
+		if err := sleep(ctx, retry); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// send makes one attempt of a request. A failed attempt also returns how
+// long to wait before the next: 0 to back off, or -1 if it must not be
+// retried.
+func (c *Client) send(ctx context.Context, method, path string, data []byte, out interface{}) (http.Header, time.Duration, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, -1, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "choochoo")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	auth, err := c.authorization(ctx, path)
+	if err != nil {
+		return nil, -1, err
+	}
+	req.Header.Set("Authorization", auth)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil || !idempotent(method) {
+			return nil, -1, err
+		}
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if !strings.HasPrefix(path, "/app/") {
+		c.observeRateLimit(resp.Header)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if wait, ok := rateLimited(resp, time.Now()); ok {
+			return nil, wait, fmt.Errorf("%s %s: %w (status %d)", method, path, ErrRateLimited, resp.StatusCode)
+		}
+		var body struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&body)
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, -1, fmt.Errorf("%s %s: %w", method, path, ErrNotFound)
+		}
+		err := fmt.Errorf("%s %s: unexpected status %d: %s", method, path, resp.StatusCode, body.Message)
+		if resp.StatusCode >= 500 && idempotent(method) {
+			return nil, 0, err
+		}
+		return nil, -1, err
+	}
+	if out == nil {
+		return resp.Header, 0, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		// The request succeeded even if its response was cut short, so only
+		// requests without side effects are made again
+		err = fmt.Errorf("%s %s: failed to decode response: %w", method, path, err)
+		if idempotent(method) {
+			return resp.Header, 0, err
+		}
+		return resp.Header, -1, err
+	}
+	return resp.Header, 0, nil
+}
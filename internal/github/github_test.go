@@ -0,0 +1,464 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, block := range map[string]*pem.Block{
+		"pkcs1": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		"pkcs8": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		parsed, err := ParsePrivateKey(pem.EncodeToMemory(block))
+		if err != nil || !parsed.Equal(key) {
+			t.Errorf("%s: ParsePrivateKey() failed: %v", name, err)
+		}
+	}
+
+	if _, err := ParsePrivateKey([]byte("not a key")); err == nil {
+		t.Error("Expected an error for a non-PEM key")
+	}
+}
+
+func TestClient_Permissions_App(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/app/installations/42" {
+			http.NotFound(w, r)
+			return
+		}
+		jwt, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.Count(jwt, ".") != 2 {
+			http.Error(w, "missing JWT", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"permissions": map[string]string{"checks": "write", "contents": "read"},
+		})
+	}))
+	defer server.Close()
+
+	client := NewAppClient(server.URL, App{ID: 7, InstallationID: 42, PrivateKey: key})
+	permissions, verified, err := client.Permissions(context.Background())
+	if err != nil || !verified {
+		t.Fatalf("Permissions() failed: %v", err)
+	}
+	if !permissions.Allows("checks", Write) || !permissions.Allows("contents", Read) || permissions.Allows("contents", Write) {
+		t.Errorf("Unexpected permissions: %v", permissions)
+	}
+}
+
+func TestScopePermissions(t *testing.T) {
+	permissions := ScopePermissions("repo:status, repo, read:org")
+	if !permissions.Allows("pull_requests", Write) || !permissions.Allows("members", Read) {
+		t.Errorf("Unexpected permissions: %v", permissions)
+	}
+	if permissions.Allows("checks", Read) {
+		t.Error("Classic tokens should not be granted checks")
+	}
+}
+
+func TestClient_CreateCheckRun_App(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := 0
+	var run CheckRun
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/installations/42/access_tokens":
+			tokens++
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"token":      "installation-token",
+				"expires_at": time.Now().Add(time.Hour),
+			})
+		case "/repos/acme/api/check-runs":
+			if r.Header.Get("Authorization") != "Bearer installation-token" {
+				http.Error(w, "expected the installation token", http.StatusUnauthorized)
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&run)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewAppClient(server.URL, App{ID: 7, InstallationID: 42, PrivateKey: key})
+	for i := 0; i < 2; i++ {
+		err := client.CreateCheckRun(context.Background(), "acme/api", CheckRun{
+			Name:       "choochoo/config",
+			HeadSHA:    "abc123",
+			Conclusion: ConclusionSuccess,
+			Output:     CheckOutput{Title: "OK", Summary: "OK"},
+		})
+		if err != nil {
+			t.Fatalf("CreateCheckRun() failed: %v", err)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("Expected the installation token to be reused, created %d", tokens)
+	}
+	if run.Status != "completed" || run.HeadSHA != "abc123" {
+		t.Errorf("Unexpected check run %+v", run)
+	}
+}
+
+func TestClient_FileContents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/api/contents/.choochoo.yml" || r.URL.Query().Get("ref") != "abc123" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"type":     "file",
+			"encoding": "base64",
+			"content":  "dmVyc2lv\nbjogMQo=\n",
+		})
+	}))
+	defer server.Close()
+
+	client := NewTokenClient(server.URL, "token")
+	data, err := client.FileContents(context.Background(), "acme/api", ".choochoo.yml", "abc123")
+	if err != nil || string(data) != "version: 1\n" {
+		t.Errorf("FileContents() = %q, %v", data, err)
+	}
+	if _, err := client.FileContents(context.Background(), "acme/api", ".choochoo.yml", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClient_AddLabels(t *testing.T) {
+	var labels []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/acme/api/issues/7/labels" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			Labels []string `json:"labels"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		labels = body.Labels
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewTokenClient(server.URL, "token")
+	if err := client.AddLabels(context.Background(), "acme/api", 7, []string{"triage", "size/large"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(labels) != 2 || labels[0] != "triage" {
+		t.Errorf("Unexpected labels: %v", labels)
+	}
+	if err := client.AddLabels(context.Background(), "acme/web", 7, []string{"triage"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClient_CreateCommitStatus(t *testing.T) {
+	var status CommitStatus
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/acme/api/statuses/abc123" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&status)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := NewTokenClient(server.URL, "token")
+	err := client.CreateCommitStatus(context.Background(), "acme/api", "abc123", CommitStatus{
+		State:       StateSuccess,
+		Context:     "choochoo/received",
+		Description: strings.Repeat("x", 200),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status.State != StateSuccess || status.Context != "choochoo/received" || len([]rune(status.Description)) != maxStatusDescription {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
+
+func TestClient_StartAndCompleteCheckRun(t *testing.T) {
+	var runs []CheckRun
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var run CheckRun
+		json.NewDecoder(r.Body).Decode(&run)
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/api/check-runs":
+			runs = append(runs, run)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 99, "status": "in_progress"}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/acme/api/check-runs/99":
+			runs = append(runs, run)
+			w.Write([]byte(`{"id": 99, "status": "completed"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewTokenClient(server.URL, "token")
+	id, err := client.StartCheckRun(context.Background(), "acme/api", CheckRun{
+		Name:       "choochoo/pipeline",
+		HeadSHA:    "abc123",
+		Conclusion: ConclusionSuccess,
+		Output:     CheckOutput{Title: "Processing", Summary: "Processing"},
+	})
+	if err != nil || id != 99 {
+		t.Fatalf("StartCheckRun() = %d, %v", id, err)
+	}
+	err = client.CompleteCheckRun(context.Background(), "acme/api", id, CheckRun{
+		Conclusion: ConclusionFailure,
+		Output:     CheckOutput{Title: "Failed", Summary: "Failed"},
+	})
+	if err != nil {
+		t.Fatalf("CompleteCheckRun() failed: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Status != StatusInProgress || runs[0].Conclusion != "" || runs[0].HeadSHA != "abc123" ||
+		runs[1].Status != StatusCompleted || runs[1].Conclusion != ConclusionFailure || runs[1].Name != "" {
+		t.Errorf("Unexpected check runs %+v", runs)
+	}
+}
+
+func TestClient_PullRequestFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/api/pulls/7/files" {
+			http.NotFound(w, r)
+			return
+		}
+		// The first page is full, so the second is fetched too
+		files := []map[string]string{}
+		if r.URL.Query().Get("page") == "1" {
+			for i := 0; i < 99; i++ {
+				files = append(files, map[string]string{"filename": "src/main.go"})
+			}
+			files = append(files, map[string]string{"filename": "docs/new.md", "previous_filename": "docs/old.md"})
+		} else {
+			files = append(files, map[string]string{"filename": "README.md"})
+		}
+		json.NewEncoder(w).Encode(files)
+	}))
+	defer server.Close()
+
+	client := NewTokenClient(server.URL, "token")
+	paths, err := client.PullRequestFiles(context.Background(), "acme/api", 7)
+	if err != nil {
+		t.Fatalf("PullRequestFiles() error = %v", err)
+	}
+	if len(paths) != 102 || paths[99] != "docs/new.md" || paths[100] != "docs/old.md" || paths[101] != "README.md" {
+		t.Errorf("PullRequestFiles() = %d paths ending in %v", len(paths), paths[99:])
+	}
+	if _, err := client.PullRequestFiles(context.Background(), "acme/web", 7); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestClient_Retries(t *testing.T) {
+	var gets, posts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/api/contents/flaky":
+			// Fails once, then succeeds
+			gets++
+			if gets == 1 {
+				http.Error(w, `{"message": "unavailable"}`, http.StatusBadGateway)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"type": "file", "encoding": "base64", "content": "b2s="})
+		case "/repos/acme/api/contents/down":
+			http.Error(w, `{"message": "unavailable"}`, http.StatusServiceUnavailable)
+		case "/repos/acme/api/issues/7/labels":
+			posts++
+			http.Error(w, `{"message": "unavailable"}`, http.StatusBadGateway)
+		case "/repos/acme/api/check-runs":
+			// Creates the check run, but the response is cut short
+			posts++
+			w.Write([]byte(`{"id": 4`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewTokenClient(server.URL, "token")
+	client.retryBackoff = time.Millisecond
+	ctx := context.Background()
+
+	if data, err := client.FileContents(ctx, "acme/api", "flaky", "main"); err != nil || string(data) != "ok" {
+		t.Errorf("FileContents() = %q, %v, want the second attempt", data, err)
+	}
+	gets = 0
+	if _, err := client.FileContents(ctx, "acme/api", "down", "main"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected the last attempt's error, got %v", err)
+	}
+	// POST requests may have had their effect, so they are not retried
+	if err := client.AddLabels(ctx, "acme/api", 7, []string{"bug"}); err == nil || posts != 1 {
+		t.Errorf("AddLabels() = %v after %d attempts, want 1 failed attempt", err, posts)
+	}
+	posts = 0
+	if _, err := client.StartCheckRun(ctx, "acme/api", CheckRun{Name: "choochoo"}); err == nil || posts != 1 {
+		t.Errorf("StartCheckRun() = %v after %d attempts, want 1 attempt with a truncated response", err, posts)
+	}
+}
+
+func TestClient_RateLimit(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Unix()
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/repos/acme/api/issues/7/labels":
+			// A secondary rate limit refuses the first attempt
+			if requests == 1 {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, `{"message": "secondary rate limit"}`, http.StatusForbidden)
+				return
+			}
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "1")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+			w.Write([]byte(`[]`))
+		case "/repos/acme/api/issues/8/labels":
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+			http.Error(w, `{"message": "API rate limit exceeded"}`, http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewTokenClient(server.URL, "token")
+	client.retryBackoff = time.Millisecond
+	ctx := context.Background()
+
+	if _, ok := client.RateLimit(); ok {
+		t.Error("RateLimit() known before any response")
+	}
+	if err := client.AddLabels(ctx, "acme/api", 7, []string{"bug"}); err != nil || requests != 2 {
+		t.Fatalf("AddLabels() = %v after %d requests, want a retry", err, requests)
+	}
+	if limit, ok := client.RateLimit(); !ok || limit.Remaining != 1 || limit.Reset.Unix() != reset {
+		t.Errorf("RateLimit() = %+v, %v", limit, ok)
+	}
+
+	// The limit resets in an hour, so the request fails instead of waiting,
+	// and later requests fail without being sent
+	if err := client.AddLabels(ctx, "acme/api", 8, []string{"bug"}); !errors.Is(err, ErrRateLimited) || requests != 3 {
+		t.Errorf("Expected ErrRateLimited after 3 requests, got %v after %d", err, requests)
+	}
+	if err := client.AddLabels(ctx, "acme/api", 7, []string{"bug"}); !errors.Is(err, ErrRateLimited) || requests != 3 {
+		t.Errorf("Expected ErrRateLimited without a request, got %v after %d", err, requests)
+	}
+}
+
+func TestClient_CollaboratorRole(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/api/collaborators/octocat/permission":
+			w.Write([]byte(`{"permission": "write", "role_name": "maintain"}`))
+		case "/repos/acme/api/collaborators/legacy/permission":
+			w.Write([]byte(`{"permission": "read"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewTokenClient(server.URL, "token")
+	for login, want := range map[string]string{"octocat": RoleMaintain, "legacy": RoleRead} {
+		if role, err := client.CollaboratorRole(context.Background(), "acme/api", login); err != nil || role != want {
+			t.Errorf("CollaboratorRole(%s) = %q, %v, want %q", login, role, err, want)
+		}
+	}
+	if _, err := client.CollaboratorRole(context.Background(), "acme/web", "octocat"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	tests := []struct {
+		role, required string
+		want           bool
+	}{
+		{RoleAdmin, RoleWrite, true},
+		{RoleMaintain, RoleMaintain, true},
+		{RoleWrite, RoleMaintain, false},
+		{RoleTriage, RoleRead, true},
+		{RoleNone, RoleRead, false},
+		{"custom-reviewer", RoleRead, true},
+		{"custom-reviewer", RoleTriage, false},
+	}
+	for _, tt := range tests {
+		if got := RoleAllows(tt.role, tt.required); got != tt.want {
+			t.Errorf("RoleAllows(%q, %q) = %v, want %v", tt.role, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestClient_CreateDispatch(t *testing.T) {
+	var body struct {
+		EventType     string            `json:"event_type"`
+		ClientPayload map[string]string `json:"client_payload"`
+	}
+	var reaction map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/api/dispatches":
+			json.NewDecoder(r.Body).Decode(&body)
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/api/issues/comments/99/reactions":
+			json.NewDecoder(r.Body).Decode(&reaction)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewTokenClient(server.URL, "token")
+	if err := client.CreateDispatch(context.Background(), "acme/api", "deploy", map[string]string{"environment": "staging"}); err != nil {
+		t.Fatalf("CreateDispatch() error = %v", err)
+	}
+	if body.EventType != "deploy" || body.ClientPayload["environment"] != "staging" {
+		t.Errorf("Unexpected dispatch: %+v", body)
+	}
+	if err := client.AddCommentReaction(context.Background(), "acme/api", 99, ReactionThumbsUp); err != nil || reaction["content"] != "+1" {
+		t.Errorf("AddCommentReaction() = %v, sent %v", err, reaction)
+	}
+}
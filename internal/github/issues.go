@@ -0,0 +1,15 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// AddLabels adds labels to issue or pull request number of repo, an
+// "owner/name" full name. Labels that do not exist yet are created.
+func (c *Client) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	body := map[string][]string{"labels": labels}
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/issues/%d/labels", repo, number), body, nil)
+	return err
+}
@@ -0,0 +1,75 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Access levels of a permission
+const (
+	Read  = "read"
+	Write = "write"
+)
+
+// Permissions maps a GitHub App permission such as "checks" or
+// "pull_requests" to its access level
+type Permissions map[string]string
+
+// Allows reports whether access to permission is granted. Write access
+// includes read access.
+func (p Permissions) Allows(permission, access string) bool {
+	granted := p[permission]
+	return granted == Write || (access == Read && granted == Read)
+}
+
+// scopePermissions maps classic personal access token scopes to the
+// permissions they grant. Classic tokens cannot use the Checks API at all.
+var scopePermissions = map[string]Permissions{
+	"repo":        {"metadata": Read, "contents": Write, "pull_requests": Write, "issues": Write, "statuses": Write, "deployments": Write},
+	"public_repo": {"metadata": Read, "contents": Write, "pull_requests": Write, "issues": Write, "statuses": Write, "deployments": Write},
+	"repo:status": {"statuses": Write},
+	"workflow":    {"workflows": Write},
+	"read:org":    {"members": Read},
+	"write:org":   {"members": Write},
+	"admin:org":   {"members": Write},
+}
+
+// Permissions returns the permissions the client has been granted. verified
+// is false when GitHub does not expose them, as for fine-grained personal
+// access tokens, in which case permissions is nil.
+func (c *Client) Permissions(ctx context.Context) (permissions Permissions, verified bool, err error) {
+	if c.app != nil {
+		var installation struct {
+			Permissions Permissions `json:"permissions"`
+		}
+		if _, err := c.get(ctx, fmt.Sprintf("/app/installations/%d", c.app.InstallationID), &installation); err != nil {
+			return nil, false, err
+		}
+		return installation.Permissions, true, nil
+	}
+
+	header, err := c.get(ctx, "/rate_limit", nil)
+	if err != nil {
+		return nil, false, err
+	}
+	scopes, ok := header["X-Oauth-Scopes"]
+	if !ok {
+		return nil, false, nil
+	}
+	return ScopePermissions(strings.Join(scopes, ",")), true, nil
+}
+
+// ScopePermissions converts the comma-separated scopes of a classic personal
+// access token into permissions
+func ScopePermissions(scopes string) Permissions {
+	permissions := make(Permissions)
+	for _, scope := range strings.Split(scopes, ",") {
+		for permission, access := range scopePermissions[strings.TrimSpace(scope)] {
+			if permissions[permission] != Write {
+				permissions[permission] = access
+			}
+		}
+	}
+	return permissions
+}
@@ -0,0 +1,36 @@
+package github
+
+import (
+	"context"
+	"fmt"
+)
+
+// maxFilePages bounds the pages of changed files fetched; GitHub lists at
+// most 3000 files of a pull request, 100 per page
+const maxFilePages = 30
+
+// PullRequestFiles lists the paths of the files changed by pull request
+// number of repo, an "owner/name" full name. Renamed files are listed under
+// both their new and their previous path.
+func (c *Client) PullRequestFiles(ctx context.Context, repo string, number int) ([]string, error) {
+	var paths []string
+	for page := 1; page <= maxFilePages; page++ {
+		var files []struct {
+			Filename         string `json:"filename"`
+			PreviousFilename string `json:"previous_filename"`
+		}
+		if _, err := c.get(ctx, fmt.Sprintf("/repos/%s/pulls/%d/files?per_page=100&page=%d", repo, number, page), &files); err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			paths = append(paths, file.Filename)
+			if file.PreviousFilename != "" {
+				paths = append(paths, file.PreviousFilename)
+			}
+		}
+		if len(files) < 100 {
+			break
+		}
+	}
+	return paths, nil
+}
@@ -0,0 +1,105 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ErrRateLimited is returned when GitHub's rate limit does not reset within
+// maxRateLimitWait
+var ErrRateLimited = errors.New("rate limited")
+
+const (
+	// maxAttempts bounds the attempts of a request
+	maxAttempts = 3
+	// maxRateLimitWait is the longest a request waits for a rate limit to
+	// reset; requests that would wait longer fail with ErrRateLimited, so
+	// they are retried with the event instead of blocking a processor
+	maxRateLimitWait = time.Minute
+)
+
+// RateLimit is the primary rate limit of the client, as of its latest response
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// RateLimit returns the rate limit reported by the latest response, and
+// false before any response reported one
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.rateMu.Lock()
+	defer c.rateMu.Unlock()
+	return c.rateLimit, c.rateLimit.Limit > 0
+}
+
+// observeRateLimit records the rate limit headers of a response
+func (c *Client) observeRateLimit(header http.Header) {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return
+	}
+	remaining, _ := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+
+	c.rateMu.Lock()
+	defer c.rateMu.Unlock()
+	c.rateLimit = RateLimit{Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}
+}
+
+// exhausted returns how long until the rate limit resets, when no requests
+// remain
+func (c *Client) exhausted(now time.Time) time.Duration {
+	c.rateMu.Lock()
+	defer c.rateMu.Unlock()
+	if c.rateLimit.Limit == 0 || c.rateLimit.Remaining > 0 {
+		return 0
+	}
+	return max(c.rateLimit.Reset.Sub(now), 0)
+}
+
+// rateLimited reports whether a response was refused by the primary or a
+// secondary rate limit, and how long to wait before trying again
+func rateLimited(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+		if err != nil {
+			return 0, true
+		}
+		return max(time.Unix(reset, 0).Sub(now), 0), true
+	}
+	// Secondary rate limits without a Retry-After header ask to wait at
+	// least a minute
+	return time.Minute, resp.StatusCode == http.StatusTooManyRequests
+}
+
+// idempotent reports whether a request can be sent again after a server
+// error without repeating its effect. POST requests, which create check
+// runs and comments, are only retried when GitHub refused them.
+func idempotent(method string) bool {
+	return method != http.MethodPost
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
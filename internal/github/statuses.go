@@ -0,0 +1,38 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Commit status states
+const (
+	StatePending = "pending"
+	StateSuccess = "success"
+	StateFailure = "failure"
+	StateError   = "error"
+)
+
+// maxStatusDescription is the longest description GitHub accepts
+const maxStatusDescription = 140
+
+// CommitStatus is the state of a commit for one context, such as
+// "choochoo/received"
+type CommitStatus struct {
+	State       string `json:"state"`
+	Context     string `json:"context"`
+	Description string `json:"description,omitempty"`
+	TargetURL   string `json:"target_url,omitempty"`
+}
+
+// CreateCommitStatus sets the status of commit sha of repo, an "owner/name"
+// full name, for the status context, cutting the description to the length
+// GitHub accepts
+func (c *Client) CreateCommitStatus(ctx context.Context, repo, sha string, status CommitStatus) error {
+	if runes := []rune(status.Description); len(runes) > maxStatusDescription {
+		status.Description = string(runes[:maxStatusDescription-1]) + "…"
+	}
+	_, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/statuses/%s", repo, sha), status, nil)
+	return err
+}
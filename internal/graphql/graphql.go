@@ -0,0 +1,625 @@
+// Package graphql executes GraphQL queries against a schema of resolvers.
+// It implements the part of the language a read-only API needs: queries
+// with aliases, arguments, variables, fragments, inline fragments and the
+// @include and @skip directives. Mutations, subscriptions and introspection
+// beyond __typename are not supported, and arguments are scalars.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// MaxDepth is how deeply selection sets may be nested, so a query cannot
+// fan out into an unbounded number of resolver calls
+const MaxDepth = 8
+
+// ArgType is the type of an argument
+type ArgType int
+
+// Argument types
+const (
+	String ArgType = iota
+	Int
+	Boolean
+)
+
+func (t ArgType) String() string {
+	switch t {
+	case Int:
+		return "Int"
+	case Boolean:
+		return "Boolean"
+	}
+	return "String"
+}
+
+// Arg is an argument of a field
+type Arg struct {
+	Type     ArgType
+	Required bool
+}
+
+// Args holds the arguments a field was selected with. Optional arguments
+// that were not given, or given as null, are missing.
+type Args map[string]interface{}
+
+// String returns a String argument, or "" if it is missing
+func (a Args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Int returns an Int argument, or def if it is missing
+func (a Args) Int(name string, def int) int {
+	if n, ok := a[name].(int); ok {
+		return n
+	}
+	return def
+}
+
+// Bool returns a Boolean argument, or false if it is missing
+func (a Args) Bool(name string) bool {
+	b, _ := a[name].(bool)
+	return b
+}
+
+// ResolveFunc returns the value of a field of source, the value the parent
+// field resolved to, or nil for fields of the query type
+type ResolveFunc func(ctx context.Context, source interface{}, args Args) (interface{}, error)
+
+// Field is a field of an object type. A field with a Type resolves to a
+// value of that object type, a slice of them or nil; the others are leaves,
+// whose values are encoded as JSON as they are.
+type Field struct {
+	Type    *Object
+	Args    map[string]Arg
+	Resolve ResolveFunc
+}
+
+// Object is an object type. Fields may refer to object types that refer
+// back to it, so they are set once all the types are created.
+type Object struct {
+	Name   string
+	Fields map[string]*Field
+}
+
+// Schema is a schema with query as its root type
+type Schema struct {
+	query *Object
+}
+
+// NewSchema creates a schema with query as its root type
+func NewSchema(query *Object) *Schema {
+	return &Schema{query: query}
+}
+
+// Request is a GraphQL request as clients post it
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is missing when the request
+// could not be executed at all, and otherwise null for fields whose
+// resolvers failed, with an error for each.
+type Response struct {
+	Data   *Map    `json:"data,omitempty"`
+	Errors []Error `json:"errors,omitempty"`
+}
+
+// Error is an error in a request or a failed resolver
+type Error struct {
+	Message   string        `json:"message"`
+	Locations []Location    `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+// Location is a position in the query document
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Map is a JSON object whose keys keep the order they were selected in
+type Map struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newMap() *Map {
+	return &Map{values: make(map[string]interface{})}
+}
+
+func (m *Map) set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+// Get returns the value of key
+func (m *Map) Get(key string) interface{} {
+	return m.values[key]
+}
+
+// MarshalJSON encodes the map with its keys in order
+func (m *Map) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		b.Write(name)
+		b.WriteByte(':')
+		value, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Execute runs the query of a request. Requests that do not parse or
+// validate against the schema are answered with errors only.
+func (s *Schema) Execute(ctx context.Context, req Request) Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return s.fail(req.Query, err)
+	}
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return s.fail(req.Query, err)
+	}
+	v := &validator{doc: doc, schema: s, variables: make(map[string]variableDefinition)}
+	for _, def := range op.variables {
+		v.variables[def.name] = def
+	}
+	v.validate(s.query, op.selections, 1)
+	if len(v.errors) > 0 {
+		return s.fail(req.Query, v.errors...)
+	}
+	variables, err := coerceVariables(op.variables, req.Variables)
+	if err != nil {
+		return s.fail(req.Query, err)
+	}
+
+	e := &executor{doc: doc, variables: variables, src: req.Query}
+	data := e.executeObject(ctx, s.query, nil, op.selections, nil)
+	return Response{Data: data, Errors: e.errors}
+}
+
+// fail answers a request with errors only
+func (s *Schema) fail(src string, errs ...error) Response {
+	var resp Response
+	for _, err := range errs {
+		e := Error{Message: err.Error()}
+		if se, ok := err.(*syntaxError); ok {
+			e.Locations = []Location{location(src, se.pos)}
+		}
+		resp.Errors = append(resp.Errors, e)
+	}
+	return resp
+}
+
+// location converts an offset in src to a line and column
+func location(src string, pos int) Location {
+	if pos > len(src) {
+		pos = len(src)
+	}
+	line := strings.Count(src[:pos], "\n") + 1
+	return Location{Line: line, Column: pos - strings.LastIndex(src[:pos], "\n")}
+}
+
+// selectOperation returns the operation of a document to run
+func selectOperation(doc *document, name string) (*operation, error) {
+	var op *operation
+	switch {
+	case name != "":
+		for _, candidate := range doc.operations {
+			if candidate.name == name {
+				op = candidate
+			}
+		}
+		if op == nil {
+			return nil, fmt.Errorf("unknown operation %q", name)
+		}
+	case len(doc.operations) > 1:
+		return nil, fmt.Errorf("operationName is required for documents with several operations")
+	default:
+		op = doc.operations[0]
+	}
+	if op.kind != "query" {
+		return nil, &syntaxError{op.pos, fmt.Sprintf("%s operations are not supported", op.kind)}
+	}
+	return op, nil
+}
+
+// validator checks a query against the schema before anything is resolved
+type validator struct {
+	doc       *document
+	schema    *Schema
+	variables map[string]variableDefinition
+	// spreading holds the fragments being validated, to detect cycles
+	spreading []string
+	errors    []error
+}
+
+func (v *validator) errorf(pos int, format string, args ...interface{}) {
+	v.errors = append(v.errors, &syntaxError{pos, fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(object *Object, selections []*selection, depth int) {
+	if depth > MaxDepth {
+		v.errorf(selections[0].pos, "selections are nested more than %d levels deep", MaxDepth)
+		return
+	}
+	for _, s := range selections {
+		v.validateDirectives(s)
+		switch {
+		case s.spread != "":
+			f, ok := v.doc.fragments[s.spread]
+			if !ok {
+				v.errorf(s.pos, "unknown fragment %q", s.spread)
+				continue
+			}
+			for _, name := range v.spreading {
+				if name == s.spread {
+					v.errorf(s.pos, "fragment %q spreads itself", s.spread)
+					return
+				}
+			}
+			if !v.validTypeCondition(f.typeCondition, f.pos) {
+				continue
+			}
+			v.spreading = append(v.spreading, s.spread)
+			v.validate(v.objectFor(f.typeCondition, object), f.selections, depth)
+			v.spreading = v.spreading[:len(v.spreading)-1]
+		case s.inline:
+			if s.typeCondition != "" && !v.validTypeCondition(s.typeCondition, s.pos) {
+				continue
+			}
+			v.validate(v.objectFor(s.typeCondition, object), s.selections, depth)
+		case s.name == "__typename":
+			if s.selections != nil {
+				v.errorf(s.pos, "field \"__typename\" has no fields to select")
+			}
+		default:
+			field, ok := object.Fields[s.name]
+			if !ok {
+				v.errorf(s.pos, "unknown field %q on type %s", s.name, object.Name)
+				continue
+			}
+			v.validateArgs(fmt.Sprintf("field %q", s.name), field.Args, s.args, s.pos)
+			switch {
+			case field.Type == nil && s.selections != nil:
+				v.errorf(s.pos, "field %q has no fields to select", s.name)
+			case field.Type != nil && s.selections == nil:
+				v.errorf(s.pos, "field %q of type %s needs a selection of fields", s.name, field.Type.Name)
+			case field.Type != nil:
+				v.validate(field.Type, s.selections, depth+1)
+			}
+		}
+	}
+}
+
+// validTypeCondition reports whether a fragment's type condition names an
+// object type of the schema
+func (v *validator) validTypeCondition(name string, pos int) bool {
+	if v.findObject(v.schema.query, name, map[*Object]bool{}) == nil {
+		v.errorf(pos, "unknown type %q", name)
+		return false
+	}
+	return true
+}
+
+// objectFor returns the type the selections of a fragment on typeCondition
+// are validated against, when spread in a selection on object
+func (v *validator) objectFor(typeCondition string, object *Object) *Object {
+	if typeCondition == "" {
+		return object
+	}
+	return v.findObject(v.schema.query, typeCondition, map[*Object]bool{})
+}
+
+func (v *validator) findObject(from *Object, name string, seen map[*Object]bool) *Object {
+	if from.Name == name {
+		return from
+	}
+	seen[from] = true
+	for _, field := range from.Fields {
+		if field.Type != nil && !seen[field.Type] {
+			if found := v.findObject(field.Type, name, seen); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+func (v *validator) validateDirectives(s *selection) {
+	for _, d := range s.directives {
+		if d.name != "include" && d.name != "skip" {
+			v.errorf(d.pos, "unknown directive @%s", d.name)
+			continue
+		}
+		v.validateArgs("directive @"+d.name, map[string]Arg{"if": {Type: Boolean, Required: true}}, d.args, d.pos)
+	}
+}
+
+func (v *validator) validateArgs(of string, defs map[string]Arg, args []argument, pos int) {
+	given := make(map[string]bool)
+	for _, arg := range args {
+		given[arg.name] = true
+		def, ok := defs[arg.name]
+		if !ok {
+			v.errorf(arg.pos, "unknown argument %q of %s", arg.name, of)
+			continue
+		}
+		if name, ok := arg.value.(variable); ok {
+			vd, ok := v.variables[string(name)]
+			if !ok {
+				v.errorf(arg.pos, "undefined variable $%s", name)
+			} else if vd.typ.elem != nil || vd.typ.name != def.Type.String() {
+				v.errorf(arg.pos, "variable $%s of type %s cannot be used for argument %q of type %s", name, vd.typ, arg.name, def.Type)
+			}
+			continue
+		}
+		if _, err := coerce(def.Type, arg.value); err != nil {
+			v.errorf(arg.pos, "argument %q of %s: %v", arg.name, of, err)
+		} else if def.Required && arg.value == nil {
+			v.errorf(arg.pos, "argument %q of %s cannot be null", arg.name, of)
+		}
+	}
+	for name, def := range defs {
+		if def.Required && !given[name] {
+			v.errorf(pos, "missing argument %q of %s", name, of)
+		}
+	}
+}
+
+// coerceVariables checks the variables of a request against their
+// definitions, applying defaults
+func coerceVariables(defs []variableDefinition, values map[string]interface{}) (map[string]interface{}, error) {
+	coerced := make(map[string]interface{})
+	for _, def := range defs {
+		if def.typ.elem != nil {
+			return nil, &syntaxError{def.pos, fmt.Sprintf("variable $%s: list variables are not supported", def.name)}
+		}
+		var typ ArgType
+		switch def.typ.name {
+		case "String", "ID":
+			typ = String
+		case "Int":
+			typ = Int
+		case "Boolean":
+			typ = Boolean
+		default:
+			return nil, &syntaxError{def.pos, fmt.Sprintf("variable $%s: unknown type %s", def.name, def.typ.name)}
+		}
+		value, ok := values[def.name]
+		if !ok {
+			value = def.def
+		}
+		if value == nil {
+			if def.typ.nonNull {
+				return nil, &syntaxError{def.pos, fmt.Sprintf("variable $%s of type %s is required", def.name, def.typ)}
+			}
+			continue
+		}
+		v, err := coerce(typ, value)
+		if err != nil {
+			return nil, &syntaxError{def.pos, fmt.Sprintf("variable $%s: %v", def.name, err)}
+		}
+		coerced[def.name] = v
+	}
+	return coerced, nil
+}
+
+// coerce converts a literal or a variable decoded from JSON to the Go value
+// of an argument: string, int or bool. null is returned as nil.
+func coerce(typ ArgType, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch typ {
+	case String:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case Int:
+		var f float64
+		switch n := value.(type) {
+		case int:
+			return n, nil
+		case int64:
+			f = float64(n)
+		case float64:
+			if n != math.Trunc(n) {
+				return nil, fmt.Errorf("%v is not an integer", n)
+			}
+			f = n
+		case json.Number:
+			i, err := n.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("%s is not an integer", n)
+			}
+			f = float64(i)
+		default:
+			return nil, fmt.Errorf("expected Int, found %s", describe(value))
+		}
+		if f < math.MinInt32 || f > math.MaxInt32 {
+			return nil, fmt.Errorf("%v does not fit in an Int", f)
+		}
+		return int(f), nil
+	case Boolean:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("expected %s, found %s", typ, describe(value))
+}
+
+// describe names the kind of a value for errors
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return "a string"
+	case int64, float64, json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case enum:
+		return "enum value " + string(v)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// executor resolves the fields of a validated query
+type executor struct {
+	doc       *document
+	variables map[string]interface{}
+	src       string
+	errors    []Error
+}
+
+// executeObject resolves the selections on a value of object, which is
+// source for the fields' resolvers
+func (e *executor) executeObject(ctx context.Context, object *Object, source interface{}, selections []*selection, path []interface{}) *Map {
+	result := newMap()
+	keys, fields := e.collectFields(object, selections, nil, make(map[string][]*selection))
+	for _, key := range keys {
+		nodes := fields[key]
+		s := nodes[0]
+		if s.name == "__typename" {
+			result.set(key, object.Name)
+			continue
+		}
+		field := object.Fields[s.name]
+		fieldPath := append(append([]interface{}{}, path...), key)
+
+		args, err := e.args(field.Args, s.args)
+		var value interface{}
+		if err == nil {
+			value, err = field.Resolve(ctx, source, args)
+		}
+		if err != nil {
+			e.errors = append(e.errors, Error{Message: err.Error(), Locations: []Location{location(e.src, s.pos)}, Path: fieldPath})
+			result.set(key, nil)
+			continue
+		}
+		if field.Type == nil {
+			result.set(key, value)
+			continue
+		}
+
+		var merged []*selection
+		for _, node := range nodes {
+			merged = append(merged, node.selections...)
+		}
+		result.set(key, e.executeValue(ctx, field.Type, value, merged, fieldPath))
+	}
+	return result
+}
+
+// executeValue resolves the selections on a value of object, or on each
+// value of a slice of them
+func (e *executor) executeValue(ctx context.Context, object *Object, value interface{}, selections []*selection, path []interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice:
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = e.executeValue(ctx, object, rv.Index(i).Interface(), selections, append(path, i))
+		}
+		return list
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+	return e.executeObject(ctx, object, value, selections, path)
+}
+
+// collectFields gathers the fields selected on object by response key, in
+// order, expanding fragments and applying @include and @skip
+func (e *executor) collectFields(object *Object, selections []*selection, keys []string, fields map[string][]*selection) ([]string, map[string][]*selection) {
+	for _, s := range selections {
+		if !e.included(s) {
+			continue
+		}
+		switch {
+		case s.spread != "":
+			f := e.doc.fragments[s.spread]
+			if f.typeCondition == object.Name {
+				keys, fields = e.collectFields(object, f.selections, keys, fields)
+			}
+		case s.inline:
+			if s.typeCondition == "" || s.typeCondition == object.Name {
+				keys, fields = e.collectFields(object, s.selections, keys, fields)
+			}
+		default:
+			key := s.key()
+			if _, ok := fields[key]; !ok {
+				keys = append(keys, key)
+			}
+			fields[key] = append(fields[key], s)
+		}
+	}
+	return keys, fields
+}
+
+// included applies the @include and @skip directives of a selection
+func (e *executor) included(s *selection) bool {
+	for _, d := range s.directives {
+		cond, _ := e.value(d.args[0].value).(bool)
+		if (d.name == "include") != cond {
+			return false
+		}
+	}
+	return true
+}
+
+// value resolves a variable to its value
+func (e *executor) value(value interface{}) interface{} {
+	if name, ok := value.(variable); ok {
+		return e.variables[string(name)]
+	}
+	return value
+}
+
+// args coerces the arguments of a field selection
+func (e *executor) args(defs map[string]Arg, args []argument) (Args, error) {
+	coerced := make(Args)
+	for _, arg := range args {
+		value, err := coerce(defs[arg.name].Type, e.value(arg.value))
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %v", arg.name, err)
+		}
+		if value == nil {
+			if defs[arg.name].Required {
+				return nil, fmt.Errorf("argument %q cannot be null", arg.name)
+			}
+			continue
+		}
+		coerced[arg.name] = value
+	}
+	return coerced, nil
+}
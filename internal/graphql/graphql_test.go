@@ -0,0 +1,188 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testRepo struct {
+	Name  string
+	Stars int
+	Pulls []testPull
+}
+
+type testPull struct {
+	Number int
+	Title  string
+}
+
+func testSchema() *Schema {
+	repo := &Object{Name: "Repository"}
+	pull := &Object{Name: "PullRequest"}
+	repos := []testRepo{
+		{Name: "octo-org/api", Stars: 3, Pulls: []testPull{{1, "Fix"}, {2, "Feature"}}},
+		{Name: "octo-org/web", Stars: 1},
+	}
+	repo.Fields = map[string]*Field{
+		"name": {Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return source.(testRepo).Name, nil
+		}},
+		"stars": {Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return source.(testRepo).Stars, nil
+		}},
+		"pulls": {Type: pull, Args: map[string]Arg{"limit": {Type: Int}}, Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			pulls := source.(testRepo).Pulls
+			if limit := args.Int("limit", len(pulls)); limit < len(pulls) {
+				pulls = pulls[:limit]
+			}
+			return pulls, nil
+		}},
+		"broken": {Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return nil, errors.New("resolver failed")
+		}},
+	}
+	pull.Fields = map[string]*Field{
+		"number": {Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return source.(testPull).Number, nil
+		}},
+		"title": {Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return source.(testPull).Title, nil
+		}},
+	}
+	return NewSchema(&Object{Name: "Query", Fields: map[string]*Field{
+		"repositories": {Type: repo, Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return repos, nil
+		}},
+		"repository": {Type: repo, Args: map[string]Arg{"name": {Type: String, Required: true}}, Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			for _, r := range repos {
+				if r.Name == args.String("name") {
+					return r, nil
+				}
+			}
+			return nil, nil
+		}},
+	}})
+}
+
+func execute(t *testing.T, req Request) string {
+	t.Helper()
+	body, err := json.Marshal(testSchema().Execute(context.Background(), req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name string
+		req  Request
+		want string
+	}{
+		{
+			name: "nested lists keep the selection order",
+			req:  Request{Query: `{ repositories { stars name pulls(limit: 1) { title number } } }`},
+			want: `{"data":{"repositories":[{"stars":3,"name":"octo-org/api","pulls":[{"title":"Fix","number":1}]},{"stars":1,"name":"octo-org/web","pulls":[]}]}}`,
+		},
+		{
+			name: "aliases, variables and __typename",
+			req: Request{
+				Query:     `query Repo($name: String!, $limit: Int = 5) { repo: repository(name: $name) { __typename name pulls(limit: $limit) { number } } missing: repository(name: "none") { name } }`,
+				Variables: map[string]interface{}{"name": "octo-org/api", "limit": float64(1)},
+			},
+			want: `{"data":{"repo":{"__typename":"Repository","name":"octo-org/api","pulls":[{"number":1}]},"missing":null}}`,
+		},
+		{
+			name: "fragments and directives",
+			req: Request{
+				Query: `query ($withPulls: Boolean!) {
+					repository(name: "octo-org/api") {
+						...Names
+						... on Repository { stars }
+						pulls @include(if: $withPulls) { number }
+						broken @skip(if: true)
+					}
+				}
+				fragment Names on Repository { name }`,
+				Variables: map[string]interface{}{"withPulls": false},
+			},
+			want: `{"data":{"repository":{"name":"octo-org/api","stars":3}}}`,
+		},
+		{
+			name: "operation name selects the operation",
+			req:  Request{Query: `query A { repositories { name } } query B { repository(name: "octo-org/web") { stars } }`, OperationName: "B"},
+			want: `{"data":{"repository":{"stars":1}}}`,
+		},
+		{
+			name: "failed resolvers are null with an error",
+			req:  Request{Query: "{\n  repository(name: \"octo-org/api\") { name broken }\n}"},
+			want: `{"data":{"repository":{"name":"octo-org/api","broken":null}},"errors":[{"message":"resolver failed","locations":[{"line":2,"column":43}],"path":["repository","broken"]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := execute(t, tt.req); got != tt.want {
+				t.Errorf("Execute() = %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecute_Invalid(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{`{ repositories { name }`, `expected a name, found end of document`},
+		{`{ repositories }`, `field "repositories" of type Repository needs a selection of fields`},
+		{`{ repositories { name { length } } }`, `field "name" has no fields to select`},
+		{`{ repositories { owner } }`, `unknown field "owner" on type Repository`},
+		{`{ repository { name } }`, `missing argument "name" of field "repository"`},
+		{`{ repository(name: 1) { name } }`, `argument "name" of field "repository": expected String, found a number`},
+		{`{ repositories { pulls(limit: 3000000000) { number } } }`, `does not fit in an Int`},
+		{`{ repository(name: $name) { name } }`, `undefined variable $name`},
+		{`query ($limit: String) { repositories { pulls(limit: $limit) { number } } }`, `variable $limit of type String cannot be used`},
+		{`{ repositories { ...Missing } }`, `unknown fragment "Missing"`},
+		{`{ repositories { ...A } } fragment A on Repository { ...A }`, `fragment "A" spreads itself`},
+		{`{ repositories { name @defer } }`, `unknown directive @defer`},
+		{`mutation { repositories { name } }`, `mutation operations are not supported`},
+		{`query A { repositories { name } } query B { repositories { name } }`, `operationName is required`},
+		{`{ repositories { name } } {`, `expected a name, found end of document`},
+	}
+	for _, tt := range tests {
+		resp := testSchema().Execute(context.Background(), Request{Query: tt.query})
+		if resp.Data != nil || len(resp.Errors) == 0 || !strings.Contains(resp.Errors[0].Message, tt.err) {
+			t.Errorf("Execute(%s) = %+v, want error %q", tt.query, resp, tt.err)
+		}
+	}
+
+	resp := testSchema().Execute(context.Background(), Request{Query: `query ($name: String!) { repository(name: $name) { name } }`})
+	if resp.Data != nil || len(resp.Errors) != 1 || resp.Errors[0].Message != "variable $name of type String! is required" {
+		t.Errorf("Execute() without a required variable = %+v", resp)
+	}
+}
+
+func TestExecute_MaxDepth(t *testing.T) {
+	query := &Object{Name: "Query"}
+	query.Fields = map[string]*Field{
+		"self": {Type: query, Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return struct{}{}, nil
+		}},
+		"ok": {Resolve: func(ctx context.Context, source interface{}, args Args) (interface{}, error) {
+			return true, nil
+		}},
+	}
+	schema := NewSchema(query)
+	nested := func(depth int) string {
+		return strings.Repeat("{ self ", depth-1) + "{ ok }" + strings.Repeat(" }", depth-1)
+	}
+	if resp := schema.Execute(context.Background(), Request{Query: nested(MaxDepth)}); resp.Errors != nil {
+		t.Errorf("Execute() at the maximum depth = %+v", resp.Errors)
+	}
+	if resp := schema.Execute(context.Background(), Request{Query: nested(MaxDepth + 1)}); resp.Data != nil || len(resp.Errors) != 1 {
+		t.Errorf("Execute() past the maximum depth = %+v", resp)
+	}
+}
@@ -0,0 +1,617 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokName
+	tokString
+	tokInt
+	tokFloat
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	str  string
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of document"
+	}
+	return strconv.Quote(t.text)
+}
+
+// punctuation lists the punctuators, longest first
+var punctuation = []string{"...", "!", "$", "&", "(", ")", ":", "=", "@", "[", "]", "{", "|", "}"}
+
+// syntaxError is an error at an offset of the document
+type syntaxError struct {
+	pos int
+	msg string
+}
+
+func (e *syntaxError) Error() string {
+	return e.msg
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+		case c == '"':
+			if strings.HasPrefix(src[i:], `"""`) {
+				return nil, &syntaxError{i, "block strings are not supported"}
+			}
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, &syntaxError{i, err.Error()}
+			}
+			tokens = append(tokens, token{kind: tokString, text: src[i : i+n], pos: i, str: s})
+			i += n
+		case c == '-' || c >= '0' && c <= '9':
+			start := i
+			kind := tokInt
+			i++
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.' || src[i] == 'e' || src[i] == 'E' ||
+				(src[i] == '+' || src[i] == '-') && (src[i-1] == 'e' || src[i-1] == 'E')) {
+				if src[i] == '.' || src[i] == 'e' || src[i] == 'E' {
+					kind = tokFloat
+				}
+				i++
+			}
+			text := src[start:i]
+			var err error
+			if kind == tokInt {
+				_, err = strconv.ParseInt(text, 10, 64)
+			} else {
+				_, err = strconv.ParseFloat(text, 64)
+			}
+			if err != nil {
+				return nil, &syntaxError{start, fmt.Sprintf("invalid number %q", text)}
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: start})
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] >= 'a' && src[i] <= 'z' || src[i] >= 'A' && src[i] <= 'Z' || src[i] >= '0' && src[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokName, text: src[start:i], pos: start})
+		default:
+			matched := false
+			for _, p := range punctuation {
+				if strings.HasPrefix(src[i:], p) {
+					tokens = append(tokens, token{kind: tokPunct, text: p, pos: i})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &syntaxError{i, fmt.Sprintf("unexpected character %q", c)}
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// lexString reads a quoted string at the start of src, returning its value
+// and length
+func lexString(src string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '"':
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(src):
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case '\\', '"', '/':
+				b.WriteByte(src[i])
+			case 'u':
+				if i+4 >= len(src) {
+					return "", 0, fmt.Errorf("invalid escape \\u")
+				}
+				r, err := strconv.ParseUint(src[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", 0, fmt.Errorf("invalid escape \\u%s", src[i+1:i+5])
+				}
+				b.WriteRune(rune(r))
+				i += 4
+			default:
+				return "", 0, fmt.Errorf("invalid escape \\%c", src[i])
+			}
+		case c == '\n' || c == '\r':
+			return "", 0, fmt.Errorf("unterminated string")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// document is a parsed GraphQL document
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string
+	name       string
+	variables  []variableDefinition
+	selections []*selection
+	pos        int
+}
+
+type variableDefinition struct {
+	name string
+	typ  typeRef
+	// def is the default value, nil when there is none
+	def interface{}
+	pos int
+}
+
+// typeRef is the type of a variable
+type typeRef struct {
+	name    string
+	elem    *typeRef
+	nonNull bool
+}
+
+func (t typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	selections    []*selection
+	pos           int
+}
+
+// selection is a field, a fragment spread or an inline fragment
+type selection struct {
+	alias      string
+	name       string
+	args       []argument
+	directives []directive
+	selections []*selection
+
+	// spread is the name of the fragment a fragment spread refers to
+	spread string
+	// inline marks inline fragments, with an optional type condition
+	inline        bool
+	typeCondition string
+
+	pos int
+}
+
+// key returns the name a field is reported under
+func (s *selection) key() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+type argument struct {
+	name  string
+	value interface{}
+	pos   int
+}
+
+type directive struct {
+	name string
+	args []argument
+	pos  int
+}
+
+// Values are parsed to nil, bool, string, int64, float64, enum, variable,
+// []interface{} or map[string]interface{}
+type (
+	enum     string
+	variable string
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func parse(src string) (*document, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	doc := &document{fragments: make(map[string]*fragment)}
+	for p.peek().kind != tokEOF {
+		switch t := p.peek(); {
+		case t.kind == tokPunct && t.text == "{":
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: selections, pos: t.pos})
+		case t.kind == tokName && (t.text == "query" || t.text == "mutation" || t.text == "subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case t.kind == tokName && t.text == "fragment":
+			f, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[f.name]; ok {
+				return nil, &syntaxError{f.pos, fmt.Sprintf("fragment %q is defined more than once", f.name)}
+			}
+			doc.fragments[f.name] = f
+		default:
+			return nil, p.errorf("unexpected %s", t)
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, p.errorf("document has no operation")
+	}
+	return doc, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &syntaxError{p.peek().pos, fmt.Sprintf(format, args...)}
+}
+
+// accept consumes the punctuator text if it is next
+func (p *parser) accept(text string) bool {
+	if t := p.peek(); t.kind == tokPunct && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.errorf("expected %q, found %s", text, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) expectName() (token, error) {
+	t := p.peek()
+	if t.kind != tokName {
+		return t, p.errorf("expected a name, found %s", t)
+	}
+	return p.next(), nil
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	t := p.next()
+	op := &operation{kind: t.text, pos: t.pos}
+	if p.peek().kind == tokName {
+		op.name = p.next().text
+	}
+	if p.accept("(") {
+		for !p.accept(")") {
+			def, err := p.parseVariableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			op.variables = append(op.variables, def)
+		}
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = selections
+	return op, nil
+}
+
+func (p *parser) parseVariableDefinition() (variableDefinition, error) {
+	pos := p.peek().pos
+	if err := p.expect("$"); err != nil {
+		return variableDefinition{}, err
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return variableDefinition{}, err
+	}
+	if err := p.expect(":"); err != nil {
+		return variableDefinition{}, err
+	}
+	typ, err := p.parseType()
+	if err != nil {
+		return variableDefinition{}, err
+	}
+	def := variableDefinition{name: name.text, typ: typ, pos: pos}
+	if p.accept("=") {
+		if def.def, err = p.parseValue(true); err != nil {
+			return def, err
+		}
+	}
+	return def, nil
+}
+
+func (p *parser) parseType() (typeRef, error) {
+	var typ typeRef
+	if p.accept("[") {
+		elem, err := p.parseType()
+		if err != nil {
+			return typ, err
+		}
+		if err := p.expect("]"); err != nil {
+			return typ, err
+		}
+		typ.elem = &elem
+	} else {
+		name, err := p.expectName()
+		if err != nil {
+			return typ, err
+		}
+		typ.name = name.text
+	}
+	typ.nonNull = p.accept("!")
+	return typ, nil
+}
+
+func (p *parser) parseFragment() (*fragment, error) {
+	pos := p.next().pos
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if name.text == "on" {
+		return nil, &syntaxError{name.pos, "a fragment cannot be named \"on\""}
+	}
+	if on, err := p.expectName(); err != nil || on.text != "on" {
+		return nil, &syntaxError{on.pos, fmt.Sprintf("expected \"on\", found %s", on)}
+	}
+	typeCondition, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &fragment{name: name.text, typeCondition: typeCondition.text, selections: selections, pos: pos}, nil
+}
+
+func (p *parser) parseSelectionSet() ([]*selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []*selection
+	for !p.accept("}") {
+		s, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return selections, nil
+}
+
+func (p *parser) parseSelection() (*selection, error) {
+	pos := p.peek().pos
+	if p.accept("...") {
+		s := &selection{pos: pos}
+		if t := p.peek(); t.kind == tokName && t.text != "on" {
+			s.spread = p.next().text
+			directives, err := p.parseDirectives()
+			s.directives = directives
+			return s, err
+		}
+		s.inline = true
+		if t := p.peek(); t.kind == tokName && t.text == "on" {
+			p.next()
+			typeCondition, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			s.typeCondition = typeCondition.text
+		}
+		var err error
+		if s.directives, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		s.selections, err = p.parseSelectionSet()
+		return s, err
+	}
+
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	s := &selection{name: name.text, pos: pos}
+	if p.accept(":") {
+		field, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		s.alias, s.name = s.name, field.text
+	}
+	if s.args, err = p.parseArguments(); err != nil {
+		return nil, err
+	}
+	if s.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind == tokPunct && t.text == "{" {
+		if s.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (p *parser) parseArguments() ([]argument, error) {
+	if !p.accept("(") {
+		return nil, nil
+	}
+	var args []argument
+	for !p.accept(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		for _, arg := range args {
+			if arg.name == name.text {
+				return nil, &syntaxError{name.pos, fmt.Sprintf("argument %q is given more than once", name.text)}
+			}
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue(false)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, argument{name: name.text, value: value, pos: name.pos})
+	}
+	if len(args) == 0 {
+		return nil, p.errorf("empty argument list")
+	}
+	return args, nil
+}
+
+func (p *parser) parseDirectives() ([]directive, error) {
+	var directives []directive
+	for {
+		pos := p.peek().pos
+		if !p.accept("@") {
+			return directives, nil
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, directive{name: name.text, args: args, pos: pos})
+	}
+}
+
+// parseValue parses a value, a constant one in variable defaults
+func (p *parser) parseValue(constant bool) (interface{}, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokPunct && t.text == "$" && !constant:
+		p.next()
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		return variable(name.text), nil
+	case t.kind == tokString:
+		p.next()
+		return t.str, nil
+	case t.kind == tokInt:
+		p.next()
+		n, _ := strconv.ParseInt(t.text, 10, 64)
+		return n, nil
+	case t.kind == tokFloat:
+		p.next()
+		f, _ := strconv.ParseFloat(t.text, 64)
+		return f, nil
+	case t.kind == tokName:
+		p.next()
+		switch t.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return enum(t.text), nil
+	case t.kind == tokPunct && t.text == "[":
+		p.next()
+		list := []interface{}{}
+		for !p.accept("]") {
+			v, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	case t.kind == tokPunct && t.text == "{":
+		p.next()
+		object := map[string]interface{}{}
+		for !p.accept("}") {
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if object[name.text], err = p.parseValue(constant); err != nil {
+				return nil, err
+			}
+		}
+		return object, nil
+	}
+	return nil, p.errorf("expected a value, found %s", t)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/access"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// AccessHandler serves access reviews built from member and team events
+type AccessHandler struct {
+	dbConn *database.Connection
+}
+
+// NewAccessHandler creates a new access handler
+func NewAccessHandler(dbConn *database.Connection) *AccessHandler {
+	return &AccessHandler{dbConn: dbConn}
+}
+
+// HandleReview exports an access review. Query parameters: organization,
+// days (change window, default 90) and format=csv for a CSV of current grants.
+func (ah *AccessHandler) HandleReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	days := 90
+	if value := query.Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid days parameter", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "Invalid format parameter", http.StatusBadRequest)
+		return
+	}
+
+	if ah.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	organization := query.Get("organization")
+	rows, err := ah.dbConn.Queries().ListAccessChanges(ctx, organization)
+	if err != nil {
+		log.Printf("Failed to list access changes: %v", err)
+		http.Error(w, "Failed to load access changes", http.StatusInternalServerError)
+		return
+	}
+
+	review := access.BuildReview(rows, organization, days, time.Now().UTC())
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="access-review.csv"`)
+		w.WriteHeader(http.StatusOK)
+		access.WriteCSV(w, review)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
+// processAccessChange records a member or team access change and raises an
+// alert when admin access is granted
+func (wh *WebhookHandler) processAccessChange(ctx context.Context, eventType, deliveryID, senderLogin string, body []byte) error {
+	change, err := webhook.ParseAccessChange(eventType, body)
+	if err != nil {
+		return fmt.Errorf("failed to parse access change: %w", err)
+	}
+	if change == nil {
+		return nil
+	}
+
+	if change.IsAdminGrant() {
+		log.Printf("ALERT: admin access granted to %s %s on %s by %s", change.PrincipalType, change.Principal, change.Resource(), senderLogin)
+		if wh.securityRouter != nil {
+			payload, _ := json.Marshal(map[string]interface{}{
+				"change": change,
+				"sender": senderLogin,
+			})
+			wh.securityRouter.RouteEvent(ctx, webhook.SeverityHigh, forwarder.Event{
+				DeliveryID: deliveryID,
+				EventType:  eventType,
+				Action:     change.Action,
+				Repository: change.Repository,
+				Sender:     senderLogin,
+				Payload:    payload,
+			})
+		}
+	}
+
+	if wh.dbConn == nil {
+		return nil
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err = wh.dbConn.Queries().InsertAccessChange(dbCtx, db.InsertAccessChangeParams{
+		DeliveryID:         deliveryID,
+		Source:             change.Source,
+		Action:             change.Action,
+		Change:             change.Change,
+		Organization:       change.Organization,
+		RepositoryName:     optionalText(change.Repository),
+		TeamSlug:           optionalText(change.Team),
+		Principal:          change.Principal,
+		PrincipalType:      change.PrincipalType,
+		Permission:         optionalText(change.Permission),
+		PreviousPermission: optionalText(change.PreviousPermission),
+		AdminGrant:         change.IsAdminGrant(),
+		SenderLogin:        optionalText(senderLogin),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store access change: %w", err)
+	}
+	return nil
+}
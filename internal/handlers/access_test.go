@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/security"
+)
+
+func TestAccessHandler_HandleReview_InvalidMethod(t *testing.T) {
+	handler := NewAccessHandler(nil)
+
+	req := httptest.NewRequest("POST", "/api/access/review", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleReview(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestAccessHandler_HandleReview_InvalidParameters(t *testing.T) {
+	handler := NewAccessHandler(nil)
+
+	for _, target := range []string{"/api/access/review?days=0", "/api/access/review?format=xml"} {
+		req := httptest.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+
+		handler.HandleReview(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusBadRequest, status)
+		}
+	}
+}
+
+func TestAccessHandler_HandleReview_NoDatabase(t *testing.T) {
+	handler := NewAccessHandler(nil)
+
+	req := httptest.NewRequest("GET", "/api/access/review?format=csv", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleReview(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_AdminGrantAlert(t *testing.T) {
+	alerts := make(chan string, 1)
+	channel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alerts <- r.Header.Get("X-GitHub-Event")
+	}))
+	defer channel.Close()
+
+	routes, err := security.ParseRoutes("high=" + channel.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+	handler := NewWebhookHandler("", nil).WithSecurityRouter(security.NewRouter(routes))
+
+	payload := `{"action":"added","member":{"login":"newadmin"},"changes":{"permission":{"to":"admin"}},"repository":{"full_name":"test/repo"},"sender":{"login":"testuser"}}`
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+	req.Header.Set("X-GitHub-Event", "member")
+	req.Header.Set("X-GitHub-Delivery", "test-delivery-id")
+	rr := httptest.NewRecorder()
+
+	handler.HandleWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	select {
+	case eventType := <-alerts:
+		if eventType != "member" {
+			t.Errorf("Expected member alert, got %s", eventType)
+		}
+	default:
+		t.Error("Expected admin grant to be routed to the security channel")
+	}
+}
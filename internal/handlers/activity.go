@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/activity"
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/privacy"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ActivityHandler serves delivery heat maps and activity calendars to
+// tokens with the stats scope. Counts served to tokens without the read
+// scope are protected by the privacy policy, if there is one.
+type ActivityHandler struct {
+	auth    *apitoken.Authenticator
+	dbConn  *database.Connection
+	privacy *privacy.Policy
+}
+
+// NewActivityHandler creates a new activity handler
+func NewActivityHandler(auth *apitoken.Authenticator, dbConn *database.Connection, policy *privacy.Policy) *ActivityHandler {
+	return &ActivityHandler{auth: auth, dbConn: dbConn, privacy: policy}
+}
+
+// HandleHeatmap reports deliveries per hour of the week. Query parameters:
+// repository or owner, days (default 28) and tz, an IANA time zone (default
+// UTC).
+func (ah *ActivityHandler) HandleHeatmap(w http.ResponseWriter, r *http.Request) {
+	filter, period, hours, policy, ok := ah.load(w, r, activity.DefaultHeatmapDays)
+	if !ok {
+		return
+	}
+	heatmap := activity.BuildHeatmap(filter, period, hours)
+	if policy != nil {
+		heatmap = heatmap.Protect(policy)
+	}
+	writeJSON(w, http.StatusOK, heatmap)
+}
+
+// HandleCalendar reports deliveries per day. Query parameters: repository or
+// owner, days (default 365) and tz, an IANA time zone (default UTC).
+func (ah *ActivityHandler) HandleCalendar(w http.ResponseWriter, r *http.Request) {
+	filter, period, hours, policy, ok := ah.load(w, r, activity.DefaultCalendarDays)
+	if !ok {
+		return
+	}
+	calendar := activity.BuildCalendar(filter, period, hours)
+	if policy != nil {
+		calendar = calendar.Protect(policy)
+	}
+	writeJSON(w, http.StatusOK, calendar)
+}
+
+// load reads the hourly activity selected by the query parameters and the
+// privacy policy that applies to the token, writing an error response and
+// returning false if it cannot
+func (ah *ActivityHandler) load(w http.ResponseWriter, r *http.Request, defaultDays int) (activity.Filter, activity.Period, []activity.Hour, *privacy.Policy, bool) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return activity.Filter{}, activity.Period{}, nil, nil, false
+	}
+	token, ok := ah.auth.AuthorizeToken(w, r, apitoken.ScopeStats)
+	if !ok {
+		return activity.Filter{}, activity.Period{}, nil, nil, false
+	}
+	var policy *privacy.Policy
+	if !token.Allows(apitoken.ScopeRead) {
+		policy = ah.privacy
+	}
+
+	query := r.URL.Query()
+	filter := activity.Filter{Repository: query.Get("repository"), Owner: query.Get("owner")}
+	if filter.Repository != "" && filter.Owner != "" {
+		http.Error(w, "Specify repository or owner, not both", http.StatusBadRequest)
+		return filter, activity.Period{}, nil, nil, false
+	}
+
+	days, err := activity.ParseDays(query.Get("days"), defaultDays)
+	if err != nil {
+		http.Error(w, "Invalid days parameter", http.StatusBadRequest)
+		return filter, activity.Period{}, nil, nil, false
+	}
+
+	loc := time.UTC
+	if tz := query.Get("tz"); tz != "" {
+		if loc, err = time.LoadLocation(tz); err != nil {
+			http.Error(w, "Invalid tz parameter", http.StatusBadRequest)
+			return filter, activity.Period{}, nil, nil, false
+		}
+	}
+	period := activity.NewPeriod(time.Now(), loc, days)
+
+	if ah.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return filter, period, nil, nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	hours, err := loadActivity(ctx, ah.dbConn.Queries(), filter, period)
+	if err != nil {
+		log.Printf("Failed to load delivery activity: %v", err)
+		http.Error(w, "Failed to load delivery activity", http.StatusInternalServerError)
+		return filter, period, nil, nil, false
+	}
+	return filter, period, hours, policy, true
+}
+
+// loadActivity reads the hourly activity selected by filter over period
+func loadActivity(ctx context.Context, queries *db.Queries, filter activity.Filter, period activity.Period) ([]activity.Hour, error) {
+	rows, err := queries.ListDeliveryActivity(ctx, db.ListDeliveryActivityParams{
+		TimeZone:       period.TimeZone,
+		Since:          pgtype.Timestamptz{Time: period.Since, Valid: true},
+		RepositoryName: filter.Repository,
+		Owner:          filter.Owner,
+	})
+	if err != nil {
+		return nil, err
+	}
+	hours := make([]activity.Hour, 0, len(rows))
+	for _, row := range rows {
+		hours = append(hours, activity.Hour{Date: row.Day.Time, Hour: int(row.HourOfDay), Events: row.Events})
+	}
+	return hours, nil
+}
+
+// recordActivity counts a stored delivery in the delivery_activity
+// projection
+func (wh *WebhookHandler) recordActivity(ctx context.Context, repoName string) {
+	if wh.dbConn == nil {
+		return
+	}
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if err := wh.dbConn.Queries().AddDeliveryActivity(dbCtx, optionalText(repoName).String); err != nil {
+		log.Printf("Failed to record delivery activity of %s: %v", repoName, err)
+	}
+}
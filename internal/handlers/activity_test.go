@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+)
+
+func TestActivityHandler_InvalidMethod(t *testing.T) {
+	handler := NewActivityHandler(apitoken.NewAuthenticator("secret", nil), nil, nil)
+
+	for target, handle := range map[string]http.HandlerFunc{
+		"/api/activity/heatmap":  handler.HandleHeatmap,
+		"/api/activity/calendar": handler.HandleCalendar,
+	} {
+		req := httptest.NewRequest("POST", target, nil)
+		rr := httptest.NewRecorder()
+
+		handle(rr, req)
+
+		if status := rr.Code; status != http.StatusMethodNotAllowed {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusMethodNotAllowed, status)
+		}
+	}
+}
+
+func TestActivityHandler_InvalidParameters(t *testing.T) {
+	handler := NewActivityHandler(apitoken.NewAuthenticator("secret", nil), nil, nil)
+
+	for _, target := range []string{
+		"/api/activity/heatmap?days=0",
+		"/api/activity/heatmap?days=400",
+		"/api/activity/heatmap?tz=Mars/Olympus_Mons",
+		"/api/activity/calendar?repository=octo-org/hello-world&owner=octo-org",
+	} {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		rr := httptest.NewRecorder()
+
+		handler.HandleHeatmap(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusBadRequest, status)
+		}
+	}
+}
+
+func TestActivityHandler_NoDatabase(t *testing.T) {
+	handler := NewActivityHandler(apitoken.NewAuthenticator("secret", nil), nil, nil)
+
+	req := httptest.NewRequest("GET", "/api/activity/calendar?owner=octo-org&days=90&tz=UTC", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+
+	handler.HandleCalendar(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestActivityHandler_Unauthorized(t *testing.T) {
+	handler := NewActivityHandler(apitoken.NewAuthenticator("secret", nil), nil, nil)
+
+	req := httptest.NewRequest("GET", "/api/activity/heatmap", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleHeatmap(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, status)
+	}
+}
@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/activity"
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/approval"
+	"github.com/deedubs/choochoo/internal/banner"
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/encryption"
+	"github.com/deedubs/choochoo/internal/indexadvisor"
+	"github.com/deedubs/choochoo/internal/relay"
+	"github.com/deedubs/choochoo/internal/rules"
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed templates/admin/*.html
+var adminFiles embed.FS
+
+var adminTemplates = template.Must(template.ParseFS(adminFiles, "templates/admin/*.html"))
+
+// Processing states of a delivery shown on the dashboard
+const (
+	deliveryProcessed   = "processed"
+	deliveryQueued      = "queued"
+	deliveryRetrying    = "retrying"
+	deliveryQuarantined = "quarantined"
+)
+
+// AdminHandler serves the admin dashboard of recent deliveries
+type AdminHandler struct {
+	username  string
+	password  string
+	auth      *apitoken.Authenticator
+	dbConn    *database.Connection
+	saveRule  func(ctx context.Context, rule rules.Rule, operator string) (storedRule, *approval.Change, error)
+	approvals *approval.Queue
+	keys      *encryption.Keyring
+	banners   banner.LoadFunc
+	advisor   *indexadvisor.Advisor
+}
+
+// NewAdminHandler creates a new admin dashboard handler. Requests must
+// present password with basic auth, or a bearer token or the credentials of a
+// provisioned user with the admin scope; the dashboard is disabled when
+// neither is possible.
+func NewAdminHandler(username, password string, auth *apitoken.Authenticator, dbConn *database.Connection) *AdminHandler {
+	return &AdminHandler{username: username, password: password, auth: auth, dbConn: dbConn}
+}
+
+// WithRuleSaver sets the function the rule builder saves rules with,
+// normally ManagementHandler.SaveRule
+func (ah *AdminHandler) WithRuleSaver(save func(ctx context.Context, rule rules.Rule, operator string) (storedRule, *approval.Change, error)) *AdminHandler {
+	ah.saveRule = save
+	return ah
+}
+
+// WithApprovals sets the queue of changes waiting for approval, which the
+// dashboard lists for operators to approve or reject
+func (ah *AdminHandler) WithApprovals(queue *approval.Queue) *AdminHandler {
+	ah.approvals = queue
+	return ah
+}
+
+// WithEncryption sets the keys encrypted payloads are shown with
+func (ah *AdminHandler) WithEncryption(keys *encryption.Keyring) *AdminHandler {
+	ah.keys = keys
+	return ah
+}
+
+// WithBanners sets the function loading the banners shown on every page
+func (ah *AdminHandler) WithBanners(load banner.LoadFunc) *AdminHandler {
+	ah.banners = load
+	return ah
+}
+
+// WithIndexAdvisor makes the dashboard report the queries it makes on the
+// events table to advisor
+func (ah *AdminHandler) WithIndexAdvisor(advisor *indexadvisor.Advisor) *AdminHandler {
+	ah.advisor = advisor
+	return ah
+}
+
+// adminDelivery is a delivery as shown on the dashboard
+type adminDelivery struct {
+	DeliveryID string
+	EventType  string
+	Action     string
+	Repository string
+	Sender     string
+	ReceivedAt time.Time
+	Bytes      int32
+	Status     string
+	Attempts   int32
+	Processor  string
+	Error      string
+}
+
+// authorize checks basic auth credentials or the bearer token, writing an
+// error response if the request is not allowed
+func (ah *AdminHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	_, ok := ah.operator(w, r)
+	return ok
+}
+
+// operator is authorize returning who made the request: the basic auth
+// username or the name of the token
+func (ah *AdminHandler) operator(w http.ResponseWriter, r *http.Request) (string, bool) {
+	return ah.signIn(w, r, "")
+}
+
+// signIn checks basic auth credentials or the token of the request like
+// operator. With an organization, tokens of that tenant are accepted too.
+func (ah *AdminHandler) signIn(w http.ResponseWriter, r *http.Request, organization string) (string, bool) {
+	if ah.password == "" && !ah.auth.Enabled() {
+		http.Error(w, "Admin dashboard not configured", http.StatusServiceUnavailable)
+		return "", false
+	}
+	if username, password, ok := r.BasicAuth(); ok && ah.password != "" {
+		if subtle.ConstantTimeCompare([]byte(username), []byte(ah.username)) == 1 &&
+			subtle.ConstantTimeCompare([]byte(password), []byte(ah.password)) == 1 {
+			// The admin user has every scope, unless a policy says otherwise
+			user := apitoken.Token{Name: username, Scopes: []apitoken.Scope{apitoken.ScopeAdmin}}
+			if err := ah.auth.Allow(r, user, organization, apitoken.ScopeAdmin); err != nil {
+				log.Printf("Policy denied admin user %s from %s: %v", username, r.RemoteAddr, err)
+				http.Error(w, "Denied by policy", http.StatusForbidden)
+				return "", false
+			}
+			return username, true
+		}
+	}
+	if ah.auth.Enabled() {
+		token, err := ah.auth.Authenticate(r, apitoken.ScopeAdmin)
+		if organization != "" {
+			token, err = ah.auth.AuthenticateTenant(r, organization, apitoken.ScopeAdmin)
+		}
+		if err == nil {
+			return token.Name, true
+		}
+		if errors.Is(err, apitoken.ErrForbidden) {
+			http.Error(w, "Token lacks the admin scope", http.StatusForbidden)
+			return "", false
+		}
+		if errors.Is(err, apitoken.ErrDenied) {
+			log.Printf("Policy denied API token %s the admin dashboard from %s: %v", token.Name, r.RemoteAddr, err)
+			http.Error(w, "Denied by policy", http.StatusForbidden)
+			return "", false
+		}
+		if errors.Is(err, apitoken.ErrOtherTenant) {
+			http.Error(w, "Token is limited to the "+token.Organization+" organization", http.StatusForbidden)
+			return "", false
+		}
+		if !errors.Is(err, apitoken.ErrUnauthorized) {
+			log.Printf("Failed to look up API token: %v", err)
+			http.Error(w, "Failed to check token", http.StatusInternalServerError)
+			return "", false
+		}
+	}
+	if r.Header.Get("Authorization") != "" {
+		log.Printf("Invalid admin dashboard credentials from %s", r.RemoteAddr)
+	}
+	if ah.password != "" || ah.auth.SignsInUsers() {
+		w.Header().Set("WWW-Authenticate", `Basic realm="choochoo admin", charset="UTF-8"`)
+	}
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	return "", false
+}
+
+// HandleDeliveries lists recent deliveries with their processing status,
+// optionally filtered by event_type and repository
+func (ah *AdminHandler) HandleDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !ah.authorize(w, r) {
+		return
+	}
+	if ah.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			http.Error(w, "limit must be between 1 and 1000", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	eventType := r.URL.Query().Get("event_type")
+	repository := r.URL.Query().Get("repository")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pattern := indexadvisor.Pattern{Name: "deliveries", Range: "created_at"}
+	if eventType != "" {
+		pattern.Keys = append(pattern.Keys, "event_type")
+	}
+	if repository != "" {
+		pattern.Keys = append(pattern.Keys, "repository_name")
+	}
+	observe := ah.advisor.Time(pattern)
+	rows, err := ah.dbConn.Queries().ListDeliveries(ctx, db.ListDeliveriesParams{
+		EventType:      eventType,
+		RepositoryName: repository,
+		RowLimit:       int32(limit),
+	})
+	observe()
+	if err != nil {
+		log.Printf("Failed to list deliveries: %v", err)
+		http.Error(w, "Failed to list deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	deliveries := make([]adminDelivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, newAdminDelivery(row))
+	}
+
+	// The heat map is a convenience; the page works without it
+	filter := activity.Filter{Repository: repository}
+	period := activity.NewPeriod(time.Now(), time.UTC, activity.DefaultHeatmapDays)
+	var heatmap *activity.Heatmap
+	if hours, err := loadActivity(ctx, ah.dbConn.Queries(), filter, period); err != nil {
+		log.Printf("Failed to load delivery activity: %v", err)
+	} else {
+		built := activity.BuildHeatmap(filter, period, hours)
+		heatmap = &built
+	}
+
+	renderAdmin(w, "deliveries.html", map[string]interface{}{
+		"Deliveries": deliveries,
+		"EventType":  eventType,
+		"Repository": repository,
+		"Limit":      limit,
+		"Heatmap":    heatmap,
+		"Banners":    ah.dashboardBanners(r.Context()),
+	})
+}
+
+// HandleDelivery shows a stored delivery with its pretty-printed payload and
+// whether the payload matches its checksum
+func (ah *AdminHandler) HandleDelivery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !ah.authorize(w, r) {
+		return
+	}
+	if ah.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	event, err := ah.dbConn.Queries().GetWebhookEventByDeliveryID(ctx, r.PathValue("delivery_id"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load delivery %s: %v", r.PathValue("delivery_id"), err)
+		http.Error(w, "Failed to load delivery", http.StatusInternalServerError)
+		return
+	}
+	payload, err := ah.keys.Open(event.DeliveryID, event.Payload)
+	if err != nil {
+		log.Printf("Failed to decrypt delivery %s: %v", event.DeliveryID, err)
+		http.Error(w, "Failed to decrypt delivery", http.StatusInternalServerError)
+		return
+	}
+
+	// Events received unsigned or before chains were recorded have none
+	var chain relay.Chain
+	if provenance, err := ah.dbConn.Queries().GetEventProvenance(ctx, event.DeliveryID); err == nil {
+		json.Unmarshal(provenance.Chain, &chain)
+	}
+
+	renderAdmin(w, "delivery.html", map[string]interface{}{
+		"Event":      event,
+		"Payload":    prettyJSON(payload),
+		"Verified":   event.PayloadSha256.Valid && checksum.Verify(payload, event.PayloadSha256.String) == nil,
+		"Provenance": chain,
+		"Banners":    ah.dashboardBanners(r.Context()),
+	})
+}
+
+// newAdminDelivery derives the processing status of a delivery from its
+// work item, if it still has one
+func newAdminDelivery(row db.ListDeliveriesRow) adminDelivery {
+	delivery := adminDelivery{
+		DeliveryID: row.DeliveryID,
+		EventType:  row.EventType,
+		Action:     row.Action.String,
+		Repository: row.RepositoryName.String,
+		Sender:     row.SenderLogin.String,
+		ReceivedAt: row.CreatedAt.Time,
+		Bytes:      row.PayloadBytes,
+		Status:     deliveryProcessed,
+		Attempts:   row.Attempts.Int32,
+		Processor:  row.FailedProcessor.String,
+		Error:      row.LastError.String,
+	}
+	switch {
+	case row.QuarantinedAt.Valid:
+		delivery.Status = deliveryQuarantined
+	case row.LastError.Valid:
+		delivery.Status = deliveryRetrying
+	case row.Attempts.Valid:
+		delivery.Status = deliveryQueued
+	}
+	return delivery
+}
+
+// prettyJSON indents a JSON payload, returning it as it is if it cannot be
+// parsed
+func prettyJSON(payload []byte) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, payload, "", "  "); err != nil {
+		return string(payload)
+	}
+	return out.String()
+}
+
+// renderAdmin renders a dashboard page, buffering it so a template error
+// results in a 500 rather than a partial page
+func renderAdmin(w http.ResponseWriter, name string, data interface{}) {
+	renderAdminStatus(w, http.StatusOK, name, data)
+}
+
+// renderAdminStatus renders a dashboard page with a status code, such as a
+// form shown again with an error
+func renderAdminStatus(w http.ResponseWriter, status int, name string, data interface{}) {
+	var page bytes.Buffer
+	if err := adminTemplates.ExecuteTemplate(&page, name, data); err != nil {
+		log.Printf("Failed to render admin page %s: %v", name, err)
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	page.WriteTo(w)
+}
+
+// HandleChanges lists the changes waiting for approval. POST approves or
+// rejects the change in the form on behalf of the signed-in operator.
+func (ah *AdminHandler) HandleChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := ah.operator(w, r)
+	if !ok {
+		return
+	}
+	if r.Method == http.MethodPost && !sameOrigin(r) {
+		http.Error(w, "Cross-origin request", http.StatusForbidden)
+		return
+	}
+	if ah.approvals == nil {
+		http.Error(w, "Change approval not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	status, message := http.StatusOK, ""
+	if r.Method == http.MethodPost {
+		id, err := strconv.ParseInt(r.FormValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid change ID", http.StatusBadRequest)
+			return
+		}
+		var change approval.Change
+		switch r.FormValue("decision") {
+		case "approve":
+			change, err = ah.approvals.Approve(ctx, int32(id), operator)
+		case "reject":
+			change, err = ah.approvals.Reject(ctx, int32(id), operator)
+		default:
+			http.Error(w, "Invalid decision", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case err == nil:
+			log.Printf("Change %d (%s) %s by %s", change.ID, change, change.Status, operator)
+			http.Redirect(w, r, "/admin/changes", http.StatusSeeOther)
+			return
+		case errors.Is(err, approval.ErrNotFound):
+			status, message = http.StatusNotFound, err.Error()
+		case errors.Is(err, approval.ErrSelfApproval):
+			status, message = http.StatusForbidden, err.Error()
+		case errors.Is(err, approval.ErrDecided):
+			status, message = http.StatusConflict, err.Error()
+		default:
+			log.Printf("Failed to decide change %d: %v", id, err)
+			http.Error(w, "Failed to decide change", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	changes, err := ah.approvals.List(ctx)
+	if err != nil {
+		log.Printf("Failed to list pending changes: %v", err)
+		http.Error(w, "Failed to list changes", http.StatusInternalServerError)
+		return
+	}
+	renderAdminStatus(w, status, "changes.html", map[string]interface{}{
+		"Changes":  changes,
+		"Operator": operator,
+		"Error":    message,
+		"Banners":  ah.dashboardBanners(r.Context()),
+	})
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/activity"
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/approval"
+	"github.com/deedubs/choochoo/internal/banner"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestAdminHandler_Authorize(t *testing.T) {
+	basic := func(username, password string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.SetBasicAuth(username, password)
+		return req
+	}
+	bearer := func(token string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req
+	}
+
+	tests := []struct {
+		name     string
+		handler  *AdminHandler
+		req      *http.Request
+		expected int
+	}{
+		{"not configured", NewAdminHandler("admin", "", apitoken.NewAuthenticator("", nil), nil), basic("admin", ""), http.StatusServiceUnavailable},
+		{"basic auth", NewAdminHandler("admin", "hunter2", apitoken.NewAuthenticator("", nil), nil), basic("admin", "hunter2"), http.StatusServiceUnavailable},
+		{"wrong password", NewAdminHandler("admin", "hunter2", apitoken.NewAuthenticator("", nil), nil), basic("admin", "wrong"), http.StatusUnauthorized},
+		{"wrong username", NewAdminHandler("admin", "hunter2", apitoken.NewAuthenticator("", nil), nil), basic("root", "hunter2"), http.StatusUnauthorized},
+		{"token", NewAdminHandler("admin", "", apitoken.NewAuthenticator("secret", nil), nil), bearer("secret"), http.StatusServiceUnavailable},
+		{"wrong token", NewAdminHandler("admin", "hunter2", apitoken.NewAuthenticator("secret", nil), nil), bearer("wrong"), http.StatusUnauthorized},
+		{"no credentials", NewAdminHandler("admin", "hunter2", apitoken.NewAuthenticator("secret", nil), nil), httptest.NewRequest(http.MethodGet, "/admin", nil), http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			test.handler.HandleDeliveries(rr, test.req)
+
+			// Authorized requests get as far as the missing database
+			if rr.Code != test.expected {
+				t.Errorf("Expected status code %d, got %d", test.expected, rr.Code)
+			}
+			if rr.Code == http.StatusUnauthorized && test.handler.password != "" && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected a basic auth challenge")
+			}
+		})
+	}
+}
+
+func TestNewAdminDelivery(t *testing.T) {
+	tests := []struct {
+		name string
+		row  db.ListDeliveriesRow
+		want string
+	}{
+		{"processed", db.ListDeliveriesRow{}, deliveryProcessed},
+		{"queued", db.ListDeliveriesRow{Attempts: pgtype.Int4{Int32: 0, Valid: true}}, deliveryQueued},
+		{"retrying", db.ListDeliveriesRow{Attempts: pgtype.Int4{Int32: 2, Valid: true}, LastError: pgtype.Text{String: "boom", Valid: true}}, deliveryRetrying},
+		{"quarantined", db.ListDeliveriesRow{LastError: pgtype.Text{String: "boom", Valid: true}, QuarantinedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}}, deliveryQuarantined},
+	}
+	for _, test := range tests {
+		if got := newAdminDelivery(test.row).Status; got != test.want {
+			t.Errorf("%s: status = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRenderAdmin(t *testing.T) {
+	heatmap := activity.BuildHeatmap(activity.Filter{}, activity.NewPeriod(time.Now(), time.UTC, 28), []activity.Hour{
+		{Date: time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), Hour: 9, Events: 8},
+	})
+	rr := httptest.NewRecorder()
+	renderAdmin(rr, "deliveries.html", map[string]interface{}{
+		"Deliveries": []adminDelivery{{
+			DeliveryID: "abc-123",
+			EventType:  "push",
+			Repository: "octo-org/<api>",
+			Status:     deliveryQuarantined,
+			Attempts:   5,
+			Processor:  "docs",
+			Error:      "timed out",
+		}},
+		"Limit":   100,
+		"Heatmap": &heatmap,
+		"Banners": []banner.Banner{{Message: "Retention change this weekend", Level: banner.LevelWarning, EndsAt: time.Date(2024, 5, 11, 18, 0, 0, 0, time.UTC)}},
+	})
+
+	body := rr.Body.String()
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Fatalf("Unexpected response %d: %s", rr.Code, body)
+	}
+	for _, want := range []string{`href="/admin/deliveries/abc-123"`, "octo-org/&lt;api&gt;", "quarantined</span> after 5 attempts", "docs: timed out",
+		"<th>Monday</th>", `class="level-4" title="9:00 8"`, "8 deliveries per hour of the week",
+		`class="banner banner-warning">Retention change this weekend <small>(until 2024-05-11 18:00 UTC)`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected page to contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestAdminHandler_HandleChanges(t *testing.T) {
+	handler := NewAdminHandler("admin", "hunter2", apitoken.NewAuthenticator("", nil), nil)
+
+	tests := []struct {
+		name     string
+		method   string
+		origin   string
+		expected int
+	}{
+		{"cross-origin decision", http.MethodPost, "https://evil.example.com", http.StatusForbidden},
+		{"approval not enabled", http.MethodGet, "", http.StatusServiceUnavailable},
+		{"wrong method", http.MethodPut, "", http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, "/admin/changes", nil)
+			req.SetBasicAuth("admin", "hunter2")
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+			rr := httptest.NewRecorder()
+			handler.HandleChanges(rr, req)
+
+			if rr.Code != test.expected {
+				t.Errorf("Expected status code %d, got %d", test.expected, rr.Code)
+			}
+		})
+	}
+}
+
+func TestRenderAdmin_Changes(t *testing.T) {
+	rr := httptest.NewRecorder()
+	renderAdmin(rr, "changes.html", map[string]interface{}{
+		"Changes": []approval.Change{
+			{ID: 7, Setting: "DOCS_ROUTES", Match: "wiki", Value: "https://chat.example.com/docs", RequestedBy: "terraform"},
+			{ID: 8, Setting: "RETENTION_MODE", RequestedBy: "admin"},
+		},
+		"Operator": "admin",
+	})
+
+	body := rr.Body.String()
+	for _, want := range []string{"DOCS_ROUTES wiki", `value="approve">Approve`, "<em>removed</em>", `value="reject">Withdraw`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected page to contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Count(body, `value="approve"`) != 1 {
+		t.Errorf("Expected operators not to be offered approval of their own change:\n%s", body)
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	if got := prettyJSON([]byte(`{"ref":"main","commits":[]}`)); got != "{\n  \"ref\": \"main\",\n  \"commits\": []\n}" {
+		t.Errorf("Unexpected pretty JSON %q", got)
+	}
+	if got := prettyJSON([]byte("not json")); got != "not json" {
+		t.Errorf("Expected invalid JSON as it is, got %q", got)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/auditlog"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// AuditLogHandler handles GitHub Enterprise audit log streaming requests
+type AuditLogHandler struct {
+	token       string
+	maxBodySize int64
+	ingest      func(ctx context.Context, eventType, deliveryID, action, repoName, senderLogin string, body []byte) (database.StoreResult, error)
+}
+
+// NewAuditLogHandler creates a new audit log handler passing each entry to
+// ingest, normally WebhookHandler.Ingest, which may be nil
+func NewAuditLogHandler(token string, ingest func(ctx context.Context, eventType, deliveryID, action, repoName, senderLogin string, body []byte) (database.StoreResult, error)) *AuditLogHandler {
+	return &AuditLogHandler{
+		token:       token,
+		maxBodySize: DefaultMaxBodySize,
+		ingest:      ingest,
+	}
+}
+
+// WithMaxBodySize sets the largest request body accepted, in bytes, which
+// also caps the body once decompressed
+func (ah *AuditLogHandler) WithMaxBodySize(limit int64) *AuditLogHandler {
+	ah.maxBodySize = limit
+	return ah
+}
+
+// validateToken checks the Authorization header against the configured token.
+// Both "Bearer <token>" and the HEC style "Splunk <token>" schemes are accepted.
+// Every request is rejected when no token is set.
+func (ah *AuditLogHandler) validateToken(header string) bool {
+	if ah.token == "" {
+		return false
+	}
+
+	var provided string
+	for _, scheme := range []string{"Bearer ", "Splunk "} {
+		if strings.HasPrefix(header, scheme) {
+			provided = strings.TrimPrefix(header, scheme)
+			break
+		}
+	}
+	if provided == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(ah.token)) == 1
+}
+
+// HandleAuditLog ingests audit log entries, storing and processing each as
+// an audit_log event
+func (ah *AuditLogHandler) HandleAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !ah.validateToken(r.Header.Get("Authorization")) {
+		log.Printf("Invalid audit log token from %s", r.RemoteAddr)
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	requestBody := http.MaxBytesReader(w, r.Body, ah.maxBodySize)
+	defer requestBody.Close()
+
+	var reader io.Reader = requestBody
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(requestBody)
+		if err != nil {
+			log.Printf("Error decompressing audit log body: %v", err)
+			http.Error(w, "Invalid gzip body", http.StatusBadRequest)
+			return
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	// Read one byte past the limit to tell a body of exactly the limit from
+	// a larger one once decompressed
+	body, err := io.ReadAll(io.LimitReader(reader, ah.maxBodySize+1))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Printf("Audit log request body exceeds %d bytes", tooLarge.Limit)
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.Printf("Error reading request body: %v", err)
+		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > ah.maxBodySize {
+		log.Printf("Decompressed audit log body exceeds %d bytes", ah.maxBodySize)
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	entries, err := auditlog.Parse(body)
+	if err != nil {
+		log.Printf("Error parsing audit log payload: %v", err)
+		http.Error(w, "Invalid audit log payload", http.StatusBadRequest)
+		return
+	}
+
+	stored, duplicates := 0, 0
+	for _, entry := range entries {
+		if ah.ingest == nil {
+			continue
+		}
+		result, err := ah.ingest(r.Context(), auditlog.EventType, entry.DeliveryID(), entry.Action, entry.Repo, entry.Actor, entry.Raw)
+		if err != nil {
+			log.Printf("Failed to store audit log entry %s: %v", entry.DocumentID, err)
+			continue
+		}
+		if result == database.EventDuplicate {
+			duplicates++
+			continue
+		}
+		stored++
+	}
+
+	log.Printf("Received %d audit log entries (%d stored, %d duplicates)", len(entries), stored, duplicates)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	response := map[string]interface{}{
+		"status":     "success",
+		"received":   len(entries),
+		"stored":     stored,
+		"duplicates": duplicates,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// processAuditAlert routes an audit log entry with one of the alert actions
+// to the security channels
+func (wh *WebhookHandler) processAuditAlert(ctx context.Context, deliveryID, action, repoName, senderLogin string, body []byte) error {
+	if wh.securityRouter == nil {
+		return nil
+	}
+	wh.securityRouter.RouteEvent(ctx, webhook.SeverityHigh, forwarder.Event{
+		DeliveryID: deliveryID,
+		EventType:  auditlog.EventType,
+		Action:     action,
+		Repository: repoName,
+		Sender:     senderLogin,
+		Payload:    body,
+	})
+	return nil
+}
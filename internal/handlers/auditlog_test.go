@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/auditlog"
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/redact"
+	"github.com/deedubs/choochoo/internal/security"
+)
+
+const testAuditEntry = `{"_document_id":"doc-1","action":"org.add_member","actor":"octocat","org":"acme"}`
+
+func TestAuditLogHandler_ValidateToken(t *testing.T) {
+	handler := NewAuditLogHandler("test-token", nil)
+
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{"Bearer test-token", true},
+		{"Splunk test-token", true},
+		{"Bearer wrong-token", false},
+		{"test-token", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if result := handler.validateToken(test.header); result != test.expected {
+			t.Errorf("validateToken(%q) = %v, expected %v", test.header, result, test.expected)
+		}
+	}
+
+	if NewAuditLogHandler("test-token", nil).validateToken("") {
+		t.Error("Expected validation to fail when no token is set")
+	}
+}
+
+func TestAuditLogHandler_HandleAuditLog_InvalidMethod(t *testing.T) {
+	handler := NewAuditLogHandler("test-token", nil)
+
+	req := httptest.NewRequest("GET", "/audit-log", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleAuditLog(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestAuditLogHandler_HandleAuditLog_InvalidToken(t *testing.T) {
+	handler := NewAuditLogHandler("test-token", nil)
+
+	req := httptest.NewRequest("POST", "/audit-log", bytes.NewBufferString(testAuditEntry))
+	req.Header.Set("Authorization", "Bearer wrong-token")
+	rr := httptest.NewRecorder()
+
+	handler.HandleAuditLog(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, status)
+	}
+}
+
+func TestAuditLogHandler_HandleAuditLog_NoToken(t *testing.T) {
+	handler := NewAuditLogHandler("", nil)
+
+	req := httptest.NewRequest("POST", "/audit-log", bytes.NewBufferString(testAuditEntry))
+	rr := httptest.NewRecorder()
+
+	handler.HandleAuditLog(rr, req)
+
+	if status := rr.Code; status != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, status)
+	}
+}
+
+func TestAuditLogHandler_HandleAuditLog_ValidRequest(t *testing.T) {
+	handler := NewAuditLogHandler("test-token", nil)
+
+	body := testAuditEntry + "\n" + `{"_document_id":"doc-2","action":"repo.create","actor":"octocat","repo":"acme/widgets"}`
+	req := httptest.NewRequest("POST", "/audit-log", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Splunk test-token")
+	rr := httptest.NewRecorder()
+
+	handler.HandleAuditLog(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to parse response JSON: %v", err)
+	}
+	if response["received"] != float64(2) {
+		t.Errorf("Expected 2 received entries, got %v", response["received"])
+	}
+}
+
+func TestAuditLogHandler_HandleAuditLog_Gzip(t *testing.T) {
+	handler := NewAuditLogHandler("test-token", nil)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(testAuditEntry))
+	gz.Close()
+
+	req := httptest.NewRequest("POST", "/audit-log", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Authorization", "Bearer test-token")
+	rr := httptest.NewRecorder()
+
+	handler.HandleAuditLog(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+}
+
+func TestAuditLogHandler_HandleAuditLog_InvalidPayload(t *testing.T) {
+	handler := NewAuditLogHandler("test-token", nil)
+
+	req := httptest.NewRequest("POST", "/audit-log", bytes.NewBufferString(`{"action":"org.add_member"}`))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rr := httptest.NewRecorder()
+
+	handler.HandleAuditLog(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestAuditLogHandler_HandleAuditLog_GzipTooLarge(t *testing.T) {
+	handler := NewAuditLogHandler("test-token", nil).WithMaxBodySize(1024)
+
+	// Compresses to far less than the limit, but decompresses past it
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(testAuditEntry + "\n" + strings.Repeat(" ", 4096)))
+	gz.Close()
+	if buf.Len() > 1024 {
+		t.Fatalf("Compressed body of %d bytes is over the limit", buf.Len())
+	}
+
+	req := httptest.NewRequest("POST", "/audit-log", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Authorization", "Bearer test-token")
+	rr := httptest.NewRecorder()
+
+	handler.HandleAuditLog(rr, req)
+
+	if status := rr.Code; status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, status)
+	}
+}
+
+func TestAuditLogHandler_HandleAuditLog_TooLarge(t *testing.T) {
+	handler := NewAuditLogHandler("test-token", nil).WithMaxBodySize(16)
+
+	req := httptest.NewRequest("POST", "/audit-log", bytes.NewBufferString(testAuditEntry))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rr := httptest.NewRecorder()
+
+	handler.HandleAuditLog(rr, req)
+
+	if status := rr.Code; status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, status)
+	}
+}
+
+func TestAuditLogHandler_HandleAuditLog_Pipeline(t *testing.T) {
+	alerts := make(chan string, 2)
+	channel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alerts <- r.Header.Get("X-GitHub-Event")
+	}))
+	defer channel.Close()
+
+	routes, err := security.ParseRoutes("high=" + channel.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+	paths, _ := redact.ParsePaths("actor_ip")
+	store := database.NewMemoryStore()
+	rec := &recordingForwarder{}
+	webhookHandler := NewWebhookHandler("", nil).WithEventStore(store).WithForwarders(rec).
+		WithSecurityRouter(security.NewRouter(routes)).
+		WithRedactor(redact.New(false, false, paths))
+	handler := NewAuditLogHandler("test-token", webhookHandler.Ingest)
+
+	body := `{"_document_id":"doc-1","action":"org.add_member","actor":"octocat","org":"acme","actor_ip":"203.0.113.7"}` + "\n" +
+		`{"_document_id":"doc-2","action":"repo.create","actor":"octocat","repo":"acme/widgets"}`
+	send := func() map[string]interface{} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/audit-log", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		handler.HandleAuditLog(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+		var response map[string]interface{}
+		json.NewDecoder(rr.Body).Decode(&response)
+		return response
+	}
+
+	if response := send(); response["stored"] != float64(2) {
+		t.Errorf("Expected 2 stored entries, got %v", response)
+	}
+	if response := send(); response["duplicates"] != float64(2) {
+		t.Errorf("Expected 2 duplicate entries on redelivery, got %v", response)
+	}
+
+	event, err := store.GetWebhookEvent(context.Background(), "audit-doc-1")
+	if err != nil || event.EventType != auditlog.EventType {
+		t.Fatalf("Unexpected stored entry %+v, %v", event, err)
+	}
+	if strings.Contains(string(event.Payload), "203.0.113.7") {
+		t.Errorf("Expected the stored entry to be redacted, got %s", event.Payload)
+	}
+	if event.PayloadSha256.String != checksum.Sum(event.Payload) {
+		t.Errorf("Expected the checksum of the redacted entry to be stored, got %q", event.PayloadSha256.String)
+	}
+	if len(rec.events) != 2 || rec.events[0].EventType != auditlog.EventType {
+		t.Errorf("Expected both entries to be forwarded once, got %+v", rec.events)
+	}
+
+	// Only the member addition is an alert action
+	select {
+	case eventType := <-alerts:
+		if eventType != auditlog.EventType {
+			t.Errorf("Expected audit_log alert, got %s", eventType)
+		}
+	default:
+		t.Error("Expected the member addition to be routed to the security channel")
+	}
+	select {
+	case <-alerts:
+		t.Error("Expected a single alert")
+	default:
+	}
+}
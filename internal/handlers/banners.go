@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/banner"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// storedBanner is a banner as returned by the management API
+type storedBanner struct {
+	banner.Banner
+	State string `json:"state"`
+}
+
+// newBanner converts a stored banner
+func newBanner(row db.Banner) banner.Banner {
+	return banner.Banner{
+		ID:        row.ID,
+		Message:   row.Message,
+		Level:     row.Level,
+		Dashboard: row.Dashboard,
+		Digests:   row.Digests,
+		StartsAt:  row.StartsAt.Time,
+		EndsAt:    row.EndsAt.Time,
+		CreatedBy: row.CreatedBy,
+		CreatedAt: row.CreatedAt.Time,
+	}
+}
+
+// LoadBanners returns a banner.LoadFunc reading the banners table
+func LoadBanners(queries *db.Queries) banner.LoadFunc {
+	return func(ctx context.Context, now time.Time) ([]banner.Banner, error) {
+		rows, err := queries.ListActiveBanners(ctx, pgtype.Timestamptz{Time: now, Valid: true})
+		if err != nil {
+			return nil, err
+		}
+		banners := make([]banner.Banner, 0, len(rows))
+		for _, row := range rows {
+			banners = append(banners, newBanner(row))
+		}
+		return banners, nil
+	}
+}
+
+// HandleBanners lists every banner, newest first, or publishes a new one.
+// A banner is shown on the dashboard and in digests unless dashboard or
+// digests is false, from starts_at, by default now, until ends_at, by
+// default a week later.
+func (mh *ManagementHandler) HandleBanners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := mh.operator(w, r)
+	if !ok {
+		return
+	}
+	if mh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	now := time.Now()
+
+	if r.Method == http.MethodGet {
+		rows, err := mh.dbConn.Queries().ListBanners(ctx)
+		if err != nil {
+			log.Printf("Failed to list banners: %v", err)
+			http.Error(w, "Failed to list banners", http.StatusInternalServerError)
+			return
+		}
+		banners := make([]storedBanner, 0, len(rows))
+		for _, row := range rows {
+			b := newBanner(row)
+			banners = append(banners, storedBanner{Banner: b, State: b.State(now)})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"banners": banners})
+		return
+	}
+
+	var body struct {
+		Message   string    `json:"message"`
+		Level     string    `json:"level"`
+		Dashboard *bool     `json:"dashboard"`
+		Digests   *bool     `json:"digests"`
+		StartsAt  time.Time `json:"starts_at"`
+		EndsAt    time.Time `json:"ends_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	b := banner.Banner{
+		Message:   body.Message,
+		Level:     body.Level,
+		Dashboard: body.Dashboard == nil || *body.Dashboard,
+		Digests:   body.Digests == nil || *body.Digests,
+		StartsAt:  body.StartsAt,
+		EndsAt:    body.EndsAt,
+	}
+	if err := b.Validate(now); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	row, err := mh.dbConn.Queries().CreateBanner(ctx, db.CreateBannerParams{
+		Message:   b.Message,
+		Level:     b.Level,
+		Dashboard: b.Dashboard,
+		Digests:   b.Digests,
+		StartsAt:  pgtype.Timestamptz{Time: b.StartsAt, Valid: true},
+		EndsAt:    pgtype.Timestamptz{Time: b.EndsAt, Valid: true},
+		CreatedBy: operator,
+	})
+	if err != nil {
+		log.Printf("Failed to create banner: %v", err)
+		http.Error(w, "Failed to create banner", http.StatusInternalServerError)
+		return
+	}
+	created := newBanner(row)
+	log.Printf("Banner %d (%s) published by %s, shown from %s until %s", created.ID, created.Level, operator,
+		created.StartsAt.UTC().Format(time.RFC3339), created.EndsAt.UTC().Format(time.RFC3339))
+	writeJSON(w, http.StatusCreated, storedBanner{Banner: created, State: created.State(now)})
+}
+
+// HandleBanner withdraws a banner by ending it now. A scheduled banner is
+// never shown. The banner stays listed as expired.
+func (mh *ManagementHandler) HandleBanner(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Only DELETE method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := mh.operator(w, r)
+	if !ok {
+		return
+	}
+	if mh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid banner ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	ended, err := mh.dbConn.Queries().EndBanner(ctx, db.EndBannerParams{
+		At: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		ID: int32(id),
+	})
+	if err != nil {
+		log.Printf("Failed to end banner %d: %v", id, err)
+		http.Error(w, "Failed to end banner", http.StatusInternalServerError)
+		return
+	}
+	if ended == 0 {
+		http.Error(w, "Banner not found or already ended", http.StatusNotFound)
+		return
+	}
+	log.Printf("Banner %d withdrawn by %s", id, operator)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// dashboardBanners loads the banners shown on the dashboard. Banners are a
+// convenience; the dashboard works without them.
+func (ah *AdminHandler) dashboardBanners(ctx context.Context) []banner.Banner {
+	if ah.banners == nil {
+		return nil
+	}
+	banners, err := ah.banners(ctx, time.Now())
+	if err != nil {
+		log.Printf("Failed to load banners: %v", err)
+	}
+	return banner.ForDashboard(banners)
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+)
+
+func TestManagementHandler_HandleBanners_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		req    *http.Request
+		handle func(*ManagementHandler) http.HandlerFunc
+		status int
+	}{
+		{"not configured", "", managementRequest("GET", "/api/v1/banners", "", "secret"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleBanners }, http.StatusServiceUnavailable},
+		{"invalid token", "secret", managementRequest("GET", "/api/v1/banners", "", "wrong"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleBanners }, http.StatusUnauthorized},
+		{"invalid method", "secret", managementRequest("PUT", "/api/v1/banners", "", "secret"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleBanners }, http.StatusMethodNotAllowed},
+		{"withdraw with GET", "secret", managementRequest("GET", "/api/v1/banners/1", "", "secret", "id", "1"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleBanner }, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewManagementHandler(apitoken.NewAuthenticator(tt.token, nil), nil)
+			rr := httptest.NewRecorder()
+			tt.handle(handler)(rr, tt.req)
+			if rr.Code != tt.status {
+				t.Errorf("Expected status code %d, got %d", tt.status, rr.Code)
+			}
+		})
+	}
+}
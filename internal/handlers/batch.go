@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/deedubs/choochoo/internal/database"
+)
+
+// BatchHandler reports the sizes of the batches events are stored in
+type BatchHandler struct {
+	writer *database.BatchWriter
+}
+
+// NewBatchHandler creates a new batch handler. writer is nil when events are
+// stored one at a time.
+func NewBatchHandler(writer *database.BatchWriter) *BatchHandler {
+	return &BatchHandler{writer: writer}
+}
+
+// HandleStats reports the batch limits and how many events were written in
+// batches of each size
+func (bh *BatchHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if bh.writer == nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"enabled": false})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": true,
+		"stats":   bh.writer.Stats(),
+	})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+)
+
+func TestBatchHandler_HandleStats_Disabled(t *testing.T) {
+	handler := NewBatchHandler(nil)
+
+	req := httptest.NewRequest("GET", "/api/events/batches", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleStats(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	if body := rr.Body.String(); body != "{\"enabled\":false}\n" {
+		t.Errorf("Unexpected body: %s", body)
+	}
+}
+
+func TestBatchHandler_HandleStats(t *testing.T) {
+	flush := func(ctx context.Context, batch []database.BatchEntry) (map[string]bool, error) {
+		return map[string]bool{batch[0].Params.DeliveryID: true}, nil
+	}
+	writer := database.NewBatchWriter(flush, 10, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go writer.Run(ctx)
+
+	entry := database.BatchEntry{}
+	entry.Params.DeliveryID = "delivery-1"
+	if _, err := writer.Write(context.Background(), entry); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler := NewBatchHandler(writer)
+
+	req := httptest.NewRequest("GET", "/api/events/batches", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleStats(rr, req)
+
+	var response struct {
+		Enabled bool                `json:"enabled"`
+		Stats   database.BatchStats `json:"stats"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.Enabled || response.Stats.Batches != 1 || response.Stats.Sizes["1"] != 1 || response.Stats.MaxSize != 10 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/capacity"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CapacityHandler serves the capacity forecast
+type CapacityHandler struct {
+	dbConn      *database.Connection
+	historyDays int
+	thresholds  capacity.Thresholds
+}
+
+// NewCapacityHandler creates a new capacity handler forecasting from
+// historyDays of ingest by default and checking thresholds
+func NewCapacityHandler(dbConn *database.Connection, historyDays int, thresholds capacity.Thresholds) *CapacityHandler {
+	return &CapacityHandler{dbConn: dbConn, historyDays: historyDays, thresholds: thresholds}
+}
+
+// HandleReport forecasts event volume, peak rate and storage. Query
+// parameters: history, the days of ingest to forecast from, and horizon, the
+// days to forecast.
+func (ch *CapacityHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	history, ok := dayParam(query.Get("history"), ch.historyDays, 7, 730)
+	if !ok {
+		http.Error(w, "Invalid history parameter, expected 7 to 730 days", http.StatusBadRequest)
+		return
+	}
+	horizon, ok := dayParam(query.Get("horizon"), capacity.DefaultHorizonDays, 1, 365)
+	if !ok {
+		http.Error(w, "Invalid horizon parameter, expected 1 to 365 days", http.StatusBadRequest)
+		return
+	}
+
+	if ch.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	days, storage, err := LoadCapacity(ch.dbConn.Queries())(ctx, now.Truncate(24*time.Hour).AddDate(0, 0, -history))
+	if err != nil {
+		log.Printf("Failed to load ingest history: %v", err)
+		http.Error(w, "Failed to load ingest history", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, capacity.BuildReport(now, history, horizon, days, storage, ch.thresholds))
+}
+
+// dayParam parses a number of days between min and max, defaulting to def
+func dayParam(value string, def, min, max int) (int, bool) {
+	if value == "" {
+		return def, true
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < min || days > max {
+		return 0, false
+	}
+	return days, true
+}
+
+// LoadCapacity returns a capacity.LoadFunc reading the webhook_events table
+// and the size of the database
+func LoadCapacity(queries *db.Queries) capacity.LoadFunc {
+	return func(ctx context.Context, since time.Time) ([]capacity.Day, capacity.Storage, error) {
+		rows, err := queries.ListDailyIngest(ctx, pgtype.Timestamptz{Time: since, Valid: true})
+		if err != nil {
+			return nil, capacity.Storage{}, err
+		}
+		size, err := queries.GetStorageSize(ctx)
+		if err != nil {
+			return nil, capacity.Storage{}, err
+		}
+
+		days := make([]capacity.Day, 0, len(rows))
+		for _, row := range rows {
+			days = append(days, capacity.Day{
+				Date:             row.Day.Time,
+				Events:           row.Events,
+				Bytes:            row.Bytes,
+				PeakMinuteEvents: row.PeakMinuteEvents,
+			})
+		}
+		return days, capacity.Storage{
+			DatabaseBytes:  size.DatabaseBytes,
+			EventsBytes:    size.EventsBytes,
+			EventsEstimate: size.EventsEstimate,
+		}, nil
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/capacity"
+)
+
+func TestCapacityHandler_HandleReport_InvalidMethod(t *testing.T) {
+	handler := NewCapacityHandler(nil, capacity.DefaultHistoryDays, capacity.Thresholds{})
+
+	req := httptest.NewRequest("POST", "/api/capacity", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleReport(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestCapacityHandler_HandleReport_InvalidParameters(t *testing.T) {
+	handler := NewCapacityHandler(nil, capacity.DefaultHistoryDays, capacity.Thresholds{})
+
+	for _, target := range []string{"/api/capacity?history=3", "/api/capacity?history=month", "/api/capacity?horizon=0", "/api/capacity?horizon=400"} {
+		req := httptest.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+
+		handler.HandleReport(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusBadRequest, status)
+		}
+	}
+}
+
+func TestCapacityHandler_HandleReport_NoDatabase(t *testing.T) {
+	handler := NewCapacityHandler(nil, capacity.DefaultHistoryDays, capacity.Thresholds{})
+
+	req := httptest.NewRequest("GET", "/api/capacity?history=30&horizon=90", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleReport(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
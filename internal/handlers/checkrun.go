@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/checkrun"
+)
+
+// startCheck creates the in-progress pipeline check run of an event, if it
+// gets one. Like receipts, check runs are a courtesy, so a failure is logged
+// and the event is processed without one.
+func (wh *WebhookHandler) startCheck(ctx context.Context, eventType, deliveryID, repoName string, body []byte) *checkrun.Run {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	check, err := wh.pipelineChecks.Start(ctx, eventType, deliveryID, body)
+	if err != nil {
+		log.Printf("Failed to create pipeline check run on %s (delivery: %s): %v", repoName, deliveryID, err)
+	}
+	return check
+}
+
+// finishCheck completes the pipeline check run of an event with the
+// outcomes of its steps
+func (wh *WebhookHandler) finishCheck(ctx context.Context, check *checkrun.Run, deliveryID, repoName string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if err := check.Finish(ctx); err != nil {
+		log.Printf("Failed to complete pipeline check run on %s (delivery: %s): %v", repoName, deliveryID, err)
+	}
+}
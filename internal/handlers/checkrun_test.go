@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/checkrun"
+	"github.com/deedubs/choochoo/internal/github"
+	"github.com/deedubs/choochoo/internal/rules"
+)
+
+func TestWebhookHandler_ProcessReportsPipelineChecks(t *testing.T) {
+	var runs []github.CheckRun
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var run github.CheckRun
+		json.NewDecoder(r.Body).Decode(&run)
+		runs = append(runs, run)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 7}`))
+	}))
+	defer server.Close()
+
+	// The labeler fails, failing the rules step and the check run
+	labeler := func(ctx context.Context, repo string, number int, labels []string) error {
+		return errors.New("forbidden")
+	}
+	engine := rules.NewEngine([]rules.Rule{{
+		Name:      "label",
+		Condition: `event == "pull_request"`,
+		Actions:   []rules.Action{{Type: rules.ActionLabel, Labels: []string{"triage"}}},
+	}}, nil).WithLabeler(labeler)
+	handler := NewWebhookHandler("", nil).WithPipelineChecks(checkrun.New(github.NewTokenClient(server.URL, "token"))).WithRules(engine)
+
+	body := []byte(`{"action": "opened", "number": 3, "pull_request": {"number": 3, "head": {"sha": "abc123"}}, "repository": {"full_name": "acme/api"}}`)
+	if err := handler.process(context.Background(), "pull_request", "delivery", "opened", "acme/api", "octocat", body, false); err == nil {
+		t.Fatal("Expected the rules to fail")
+	}
+	if len(runs) != 2 || runs[0].Status != github.StatusInProgress || runs[0].HeadSHA != "abc123" ||
+		runs[1].Conclusion != github.ConclusionFailure || !strings.Contains(runs[1].Output.Summary, "`rules` | ❌") {
+		t.Errorf("Unexpected check runs %+v", runs)
+	}
+
+	// Events about no head commit get no check run
+	runs = nil
+	body = []byte(`{"action": "labeled", "pull_request": {"head": {"sha": "abc123"}}, "repository": {"full_name": "acme/api"}}`)
+	handler.WithRules(nil).process(context.Background(), "pull_request", "delivery", "labeled", "acme/api", "octocat", body, false)
+	if len(runs) != 0 {
+		t.Errorf("Expected no check run for a labeled pull request, got %+v", runs)
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/webhook"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// processCIRun keeps the ci_runs table in sync with the latest state of each
+// check suite, check run and workflow run
+func (wh *WebhookHandler) processCIRun(ctx context.Context, eventType string, body []byte) error {
+	if wh.dbConn == nil {
+		return nil
+	}
+
+	run, err := webhook.ParseCIRun(eventType, body)
+	if err != nil {
+		return fmt.Errorf("failed to parse CI run: %w", err)
+	}
+
+	var duration pgtype.Int8
+	if elapsed, ok := run.Duration(); ok {
+		duration = pgtype.Int8{Int64: elapsed.Milliseconds(), Valid: true}
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err = wh.dbConn.Queries().UpsertCIRun(dbCtx, db.UpsertCIRunParams{
+		Kind:           run.Kind,
+		RunID:          run.ID,
+		RepositoryName: run.Repository,
+		Name:           run.Name,
+		AppSlug:        optionalText(run.App),
+		HeadBranch:     optionalText(run.HeadBranch),
+		HeadSha:        run.HeadSHA,
+		TriggerEvent:   optionalText(run.Event),
+		Status:         run.Status,
+		Conclusion:     optionalText(run.Conclusion),
+		RunAttempt:     int32(run.Attempt),
+		HtmlUrl:        optionalText(run.HTMLURL),
+		StartedAt:      optionalTimestamp(run.StartedAt),
+		CompletedAt:    optionalTimestamp(run.CompletedAt),
+		DurationMs:     duration,
+		UpdatedAt:      timestampOrNow(run.UpdatedAt),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store CI run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/chatops"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/github"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// builtinCommand is the command choochoo runs itself from comments; every
+// other command is dispatched to the repository's workflows
+const builtinCommand = "choochoo"
+
+// commandReplayKey marks the context of a delivery replayed by a command, so
+// the commands of a replayed comment do not run again
+type commandReplayKey struct{}
+
+// commentCommands lists the commands of a new issue or pull request comment
+// that can be run from comments
+func (wh *WebhookHandler) commentCommands(activity *webhook.IssueCommentActivity) []chatops.Command {
+	if activity.Action != "created" {
+		return nil
+	}
+	var commands []chatops.Command
+	for _, command := range chatops.Parse(activity.Comment.Body) {
+		if _, ok := wh.commentPolicy.Required(command.Name); ok {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+// processCommentCommands runs the slash commands of a new issue or pull
+// request comment whose author has the repository role each one needs, and
+// reacts to the comment with the outcome. Only a failure to look up the
+// author's role fails the step, as commands that ran would run again when
+// the event is retried.
+func (wh *WebhookHandler) processCommentCommands(ctx context.Context, deliveryID string, body []byte) error {
+	if ctx.Value(commandReplayKey{}) != nil {
+		return nil
+	}
+	activity, err := webhook.ParseIssueCommentActivity(body)
+	if err != nil {
+		return fmt.Errorf("failed to parse issue comment: %w", err)
+	}
+	commands := wh.commentCommands(activity)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	actor := activity.Comment.User.Login
+	role, err := wh.commentClient.CollaboratorRole(ctx, activity.Repository, actor)
+	if errors.Is(err, github.ErrNotFound) {
+		role = github.RoleNone
+	} else if err != nil {
+		return fmt.Errorf("failed to look up the role of %s: %w", actor, err)
+	}
+
+	for i, command := range commands {
+		required, _ := wh.commentPolicy.Required(command.Name)
+		if !github.RoleAllows(role, required) {
+			log.Printf("Command /%s from %s on %s #%d denied: needs %s, has %s (delivery: %s)", command.Name, actor, activity.Repository, activity.IssueNumber, required, role, deliveryID)
+			wh.reactToComment(ctx, activity, github.ReactionThumbsDown, deliveryID)
+			continue
+		}
+
+		invocation := chatops.Invocation{
+			Command:    command,
+			Actor:      actor,
+			Repository: activity.Repository,
+			Source:     webhook.IssueCommentEvent,
+			Number:     activity.IssueNumber,
+			URL:        activity.Comment.HTMLURL,
+			Payload:    body,
+		}
+		log.Printf("Command /%s from %s on %s #%d", command.Name, actor, activity.Repository, activity.IssueNumber)
+		wh.publishCommand(ctx, deliveryID, i, invocation)
+
+		reaction := github.ReactionThumbsUp
+		if err := wh.runCommentCommand(ctx, deliveryID, invocation, activity); err != nil {
+			log.Printf("Command /%s failed (delivery: %s): %v", command.Name, deliveryID, err)
+			reaction = github.ReactionConfused
+		}
+		wh.reactToComment(ctx, activity, reaction, deliveryID)
+	}
+	return nil
+}
+
+// runCommentCommand runs the built-in command, or sends any other as a
+// repository_dispatch event of the same name, with the command and where it
+// was posted as its client_payload
+func (wh *WebhookHandler) runCommentCommand(ctx context.Context, deliveryID string, invocation chatops.Invocation, activity *webhook.IssueCommentActivity) error {
+	if invocation.Command.Name == builtinCommand {
+		return wh.runBuiltinCommand(ctx, invocation)
+	}
+	return wh.commentClient.CreateDispatch(ctx, invocation.Repository, invocation.Command.Name, map[string]interface{}{
+		"command":      invocation.Command.Name,
+		"args":         invocation.Command.Args,
+		"actor":        invocation.Actor,
+		"number":       invocation.Number,
+		"pull_request": activity.IsPullRequest,
+		"comment_url":  invocation.URL,
+		"delivery_id":  deliveryID,
+	})
+}
+
+// runBuiltinCommand handles "/choochoo replay <delivery id>", which replays
+// a stored delivery of the repository the command was posted in
+func (wh *WebhookHandler) runBuiltinCommand(ctx context.Context, invocation chatops.Invocation) error {
+	args := invocation.Command.Args
+	if len(args) != 2 || args[0] != "replay" {
+		return fmt.Errorf("usage: /%s replay <delivery id>", builtinCommand)
+	}
+	if wh.events == nil {
+		return errors.New("no event store to replay from")
+	}
+	event, err := wh.events.GetWebhookEvent(ctx, args[1])
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(event.RepositoryName.String, invocation.Repository) {
+		return fmt.Errorf("delivery %s is not from %s", args[1], invocation.Repository)
+	}
+	return wh.Replay(context.WithValue(ctx, commandReplayKey{}, true), args[1])
+}
+
+// publishCommand publishes a command invocation to the forwarders, so other
+// services can implement their own commands
+func (wh *WebhookHandler) publishCommand(ctx context.Context, deliveryID string, i int, invocation chatops.Invocation) {
+	if len(wh.forwarders) == 0 {
+		return
+	}
+	payload, _ := json.Marshal(invocation)
+	forwarder.ForwardAll(ctx, wh.forwarders, forwarder.Event{
+		DeliveryID: deliveryID + "-command-" + strconv.Itoa(i),
+		EventType:  chatops.CommandEventType,
+		Action:     invocation.Command.Name,
+		Repository: invocation.Repository,
+		Sender:     invocation.Actor,
+		Payload:    payload,
+	})
+}
+
+// reactToComment reacts to a command's comment. Reactions are a courtesy, so
+// a failure is only logged.
+func (wh *WebhookHandler) reactToComment(ctx context.Context, activity *webhook.IssueCommentActivity, reaction, deliveryID string) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := wh.commentClient.AddCommentReaction(ctx, activity.Repository, activity.Comment.ID, reaction); err != nil {
+		log.Printf("Failed to react to comment on %s #%d (delivery: %s): %v", activity.Repository, activity.IssueNumber, deliveryID, err)
+	}
+}
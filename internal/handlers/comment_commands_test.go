@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/chatops"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/github"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// commandAPI is a GitHub API recording the dispatches and reactions of
+// comment commands
+type commandAPI struct {
+	mu          sync.Mutex
+	dispatches  []string
+	reactions   []string
+	roleLookups int
+}
+
+func (api *commandAPI) serve(t *testing.T) *github.Client {
+	roles := map[string]string{"maintainer": "maintain", "contributor": "read"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/repos/acme/api/collaborators/"):
+			api.roleLookups++
+			login := strings.Split(r.URL.Path, "/")[5]
+			role, ok := roles[login]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"role_name": role})
+		case r.URL.Path == "/repos/acme/api/dispatches":
+			args, _ := json.Marshal(body["client_payload"].(map[string]interface{})["args"])
+			api.dispatches = append(api.dispatches, body["event_type"].(string)+" "+string(args))
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/repos/acme/api/issues/comments/99/reactions":
+			api.reactions = append(api.reactions, body["content"].(string))
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return github.NewTokenClient(server.URL, "token")
+}
+
+func commentBody(author, comment string) []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"action":     "created",
+		"issue":      map[string]interface{}{"number": 7, "pull_request": map[string]string{}},
+		"comment":    map[string]interface{}{"id": 99, "body": comment, "user": map[string]string{"login": author}},
+		"repository": map[string]string{"full_name": "acme/api"},
+		"sender":     map[string]string{"login": author},
+	})
+	return body
+}
+
+func TestWebhookHandler_ProcessCommentCommands(t *testing.T) {
+	policy, err := chatops.ParsePolicy("retest=triage,deploy=maintain,choochoo=admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		author     string
+		comment    string
+		dispatches []string
+		reactions  []string
+	}{
+		{"maintainer", "maintainer", "/retest\n/deploy staging\n/notify security", []string{"retest []", `deploy ["staging"]`}, []string{"+1", "+1"}},
+		{"contributor", "contributor", "/retest\n/deploy staging", nil, []string{"-1", "-1"}},
+		{"outsider", "outsider", "/retest", nil, []string{"-1"}},
+		{"quoted", "maintainer", "> /deploy production", nil, nil},
+		{"builtin without admin", "maintainer", "/choochoo replay abc", nil, []string{"-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &commandAPI{}
+			handler := NewWebhookHandler("", nil).WithCommentCommands(policy, api.serve(t))
+			if err := handler.processCommentCommands(context.Background(), "delivery", commentBody(tt.author, tt.comment)); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if strings.Join(api.dispatches, "|") != strings.Join(tt.dispatches, "|") {
+				t.Errorf("Dispatches = %v, want %v", api.dispatches, tt.dispatches)
+			}
+			if strings.Join(api.reactions, ",") != strings.Join(tt.reactions, ",") {
+				t.Errorf("Reactions = %v, want %v", api.reactions, tt.reactions)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_ProcessCommentCommands_Replay(t *testing.T) {
+	policy, _ := chatops.ParsePolicy("choochoo=maintain")
+	api := &commandAPI{}
+	store := database.NewMemoryStore()
+	handler := NewWebhookHandler("", nil).WithEventStore(store).WithCommentCommands(policy, api.serve(t))
+
+	stored := func(deliveryID, eventType, repo string, payload []byte) {
+		store.StoreWebhookEvent(context.Background(), db.CreateWebhookEventParams{
+			DeliveryID:     deliveryID,
+			EventType:      eventType,
+			RepositoryName: pgtype.Text{String: repo, Valid: true},
+			Payload:        payload,
+		})
+	}
+	stored("api-push", "push", "acme/api", []byte(`{"ref": "refs/heads/main", "repository": {"full_name": "acme/api"}}`))
+	stored("web-push", "push", "acme/web", []byte(`{"ref": "refs/heads/main", "repository": {"full_name": "acme/web"}}`))
+	// A comment replaying itself does not replay again when replayed
+	loop := commentBody("maintainer", "/choochoo replay loop")
+	stored("loop", "issue_comment", "acme/api", loop)
+
+	for _, comment := range []string{"/choochoo replay api-push", "/choochoo replay web-push", "/choochoo replay missing", "/choochoo replay", "/choochoo"} {
+		if err := handler.processCommentCommands(context.Background(), "delivery", commentBody("maintainer", comment)); err != nil {
+			t.Fatalf("%s: expected no error, got %v", comment, err)
+		}
+	}
+	if err := handler.processCommentCommands(context.Background(), "loop", loop); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	want := "+1,confused,confused,confused,confused,+1"
+	if strings.Join(api.reactions, ",") != want {
+		t.Errorf("Reactions = %v, want %s", api.reactions, want)
+	}
+	if len(api.dispatches) != 0 {
+		t.Errorf("Built-in commands were dispatched: %v", api.dispatches)
+	}
+	if api.roleLookups != 6 {
+		t.Errorf("Expected one role lookup per comment, got %d", api.roleLookups)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/deedubs/choochoo/internal/github"
+	"github.com/deedubs/choochoo/internal/settings"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// configLintCheck names the check run reporting on a repository's settings
+// file
+const configLintCheck = "choochoo/config"
+
+// processConfigLint lints the settings file of a repository when a push
+// changes it, and reports the result as a check run on the pushed commit
+func (wh *WebhookHandler) processConfigLint(ctx context.Context, deliveryID string, body []byte) error {
+	push, err := webhook.ParsePush(body)
+	if err != nil {
+		return fmt.Errorf("failed to parse push: %w", err)
+	}
+	if push.Deleted || !push.Changes(settings.RepoFile) {
+		return nil
+	}
+
+	data, err := wh.configLint.FileContents(ctx, push.Repository, settings.RepoFile, push.After)
+	if errors.Is(err, github.ErrNotFound) {
+		// A later commit of the push removed the file again
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", settings.RepoFile, err)
+	}
+
+	run := github.CheckRun{
+		Name:       configLintCheck,
+		HeadSHA:    push.After,
+		Conclusion: github.ConclusionSuccess,
+		Output: github.CheckOutput{
+			Title:   settings.RepoFile + " is valid",
+			Summary: "No problems found in " + settings.RepoFile + ".",
+		},
+	}
+	if problems := settings.LintRepoFile(data); len(problems) > 0 {
+		log.Printf("%s of %s has %d problems (delivery: %s)", settings.RepoFile, push.Repository, len(problems), deliveryID)
+		run.Conclusion = github.ConclusionFailure
+		run.Output.Title = fmt.Sprintf("%s has %d problems", settings.RepoFile, len(problems))
+		run.Output.Summary = "See the annotations on " + settings.RepoFile + " for each problem."
+		for _, problem := range problems {
+			run.Output.Annotations = append(run.Output.Annotations, github.Annotation{
+				Path:      settings.RepoFile,
+				StartLine: problem.Line,
+				EndLine:   problem.Line,
+				Level:     "failure",
+				Message:   problem.Message,
+			})
+		}
+	}
+
+	if err := wh.configLint.CreateCheckRun(ctx, push.Repository, run); err != nil {
+		return fmt.Errorf("failed to create check run: %w", err)
+	}
+	return nil
+}
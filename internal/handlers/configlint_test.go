@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/github"
+)
+
+func TestWebhookHandler_ProcessConfigLint(t *testing.T) {
+	files := map[string]string{
+		"good": "flags:\n  ignored_events: [push]\n",
+		"bad":  "flags:\n  ignored_events: [milestone]\n",
+	}
+	var runs []github.CheckRun
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/api/contents/.choochoo.yml":
+			content, ok := files[r.URL.Query().Get("ref")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{
+				"type":     "file",
+				"encoding": "base64",
+				"content":  base64.StdEncoding.EncodeToString([]byte(content)),
+			})
+		case "/repos/acme/api/check-runs":
+			var run github.CheckRun
+			json.NewDecoder(r.Body).Decode(&run)
+			runs = append(runs, run)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	handler := NewWebhookHandler("", nil).WithConfigLint(github.NewTokenClient(server.URL, "token"))
+	push := func(after, changed string) []byte {
+		return []byte(`{"ref": "refs/heads/main", "after": "` + after + `", "repository": {"full_name": "acme/api"},
+			"commits": [{"id": "` + after + `", "modified": ["` + changed + `"]}]}`)
+	}
+
+	for _, body := range [][]byte{push("good", ".choochoo.yml"), push("bad", ".choochoo.yml"), push("removed", ".choochoo.yml"), push("other", "README.md")} {
+		if err := handler.processConfigLint(context.Background(), "delivery", body); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if len(runs) != 2 {
+		t.Fatalf("Expected 2 check runs, got %d", len(runs))
+	}
+	if runs[0].HeadSHA != "good" || runs[0].Conclusion != github.ConclusionSuccess {
+		t.Errorf("Unexpected check run for a valid file: %+v", runs[0])
+	}
+	bad := runs[1]
+	if bad.Conclusion != github.ConclusionFailure || len(bad.Output.Annotations) != 1 || bad.Output.Annotations[0].StartLine != 2 {
+		t.Errorf("Unexpected check run for an invalid file: %+v", bad)
+	}
+}
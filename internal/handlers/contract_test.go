@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/model"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// TestWebhookContracts runs the example payloads of every supported event
+// type, kept in testdata/webhooks/<event type>, through signature
+// validation, the parsers of the processors and the event model, and
+// storage and replay, so payloads the models cannot take fail here rather
+// than in production. The committed examples are written by hand, one or two
+// actions per event type, and are not GitHub's; make webhook-examples
+// replaces those of each event type with the examples of GitHub's OpenAPI
+// description.
+func TestWebhookContracts(t *testing.T) {
+	ctx := context.Background()
+	store, err := database.NewSQLiteStore(ctx, "sqlite://"+filepath.Join(t.TempDir(), "choochoo.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore failed: %v", err)
+	}
+	defer store.Close(ctx)
+	const secret = "contract-secret"
+	handler := NewWebhookHandler(secret, nil).WithEventStore(store)
+
+	var events []string
+	for event := range webhook.SupportedEventTypes {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+
+	for _, eventType := range events {
+		paths, err := filepath.Glob(filepath.Join("testdata", "webhooks", eventType, "*.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(paths) == 0 {
+			t.Errorf("No examples of %s events in testdata/webhooks/%s", eventType, eventType)
+			continue
+		}
+		for _, path := range paths {
+			deliveryID := eventType + "/" + strings.TrimSuffix(filepath.Base(path), ".json")
+			t.Run(deliveryID, func(t *testing.T) {
+				body, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for _, p := range projections {
+					if !p.applies(eventType) {
+						continue
+					}
+					if _, err := p.parse(eventType, body); err != nil {
+						t.Errorf("Processor %s cannot parse the example: %v", p.processor, err)
+					}
+				}
+				if _, err := model.Adapt(model.GitHub, eventType, body); err != nil {
+					t.Errorf("The example has no event model: %v", err)
+				}
+
+				req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("X-GitHub-Event", eventType)
+				req.Header.Set("X-GitHub-Delivery", deliveryID)
+				req.Header.Set("X-Hub-Signature-256", generateSignature(body, secret))
+				rr := httptest.NewRecorder()
+				handler.HandleWebhook(rr, req)
+				if rr.Code != 200 || rr.Header().Get(processingHeader) != processingProcessed {
+					t.Fatalf("Delivery answered %d (%s): %s", rr.Code, rr.Header().Get(processingHeader), rr.Body)
+				}
+
+				var event webhook.GitHubEvent
+				if err := json.Unmarshal(body, &event); err != nil {
+					t.Fatal(err)
+				}
+				repository, _ := event.Repository["full_name"].(string)
+				sender, _ := event.Sender["login"].(string)
+				stored, err := store.GetWebhookEvent(ctx, deliveryID)
+				if err != nil {
+					t.Fatalf("The delivery was not stored: %v", err)
+				}
+				if stored.EventType != eventType || stored.Action.String != event.Action ||
+					(repository != "" && stored.RepositoryName.String != repository) || stored.SenderLogin.String != sender {
+					t.Errorf("Stored %s %q from %q by %q, want %s %q from %q by %q", stored.EventType, stored.Action.String,
+						stored.RepositoryName.String, stored.SenderLogin.String, eventType, event.Action, repository, sender)
+				}
+				if !bytes.Equal(stored.Payload, body) || stored.PayloadSha256.String != checksum.Sum(body) {
+					t.Errorf("The stored payload differs from the example")
+				}
+				if err := handler.Replay(ctx, deliveryID); err != nil {
+					t.Errorf("Replay failed: %v", err)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// processDeployment keeps the deployments table in sync with the final state
+// of each finished deployment for the DORA metrics
+func (wh *WebhookHandler) processDeployment(ctx context.Context, body []byte) error {
+	if wh.dbConn == nil {
+		return nil
+	}
+
+	status, err := webhook.ParseDeploymentStatus(body)
+	if err != nil {
+		return fmt.Errorf("failed to parse deployment status: %w", err)
+	}
+	if !status.Finished() || status.Repository == "" {
+		return nil
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err = wh.dbConn.Queries().UpsertDeployment(dbCtx, db.UpsertDeploymentParams{
+		DeploymentID:   status.DeploymentID,
+		RepositoryName: status.Repository,
+		Environment:    status.Environment,
+		Ref:            status.Ref,
+		Sha:            status.SHA,
+		State:          status.State,
+		FinishedAt:     timestampOrNow(status.CreatedAt),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store deployment: %w", err)
+	}
+	return nil
+}
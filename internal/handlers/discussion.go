@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/chatops"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/webhook"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DiscussionHandler serves search over stored discussions
+type DiscussionHandler struct {
+	dbConn *database.Connection
+}
+
+// NewDiscussionHandler creates a new discussion handler
+func NewDiscussionHandler(dbConn *database.Connection) *DiscussionHandler {
+	return &DiscussionHandler{dbConn: dbConn}
+}
+
+// discussionSearchResult is a discussion matching a search as returned by the API
+type discussionSearchResult struct {
+	Repository string    `json:"repository"`
+	Number     int32     `json:"number"`
+	Title      string    `json:"title"`
+	Category   string    `json:"category,omitempty"`
+	State      string    `json:"state"`
+	Answered   bool      `json:"answered"`
+	Author     string    `json:"author,omitempty"`
+	HTMLURL    string    `json:"html_url,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// HandleSearch searches discussion titles, bodies and comments, most recently
+// updated first. Query parameters: q (required), repository, limit.
+func (dh *DiscussionHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	q := query.Get("q")
+	if q == "" {
+		http.Error(w, "Missing q parameter", http.StatusBadRequest)
+		return
+	}
+
+	limit := 25
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 100 {
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	if dh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := dh.dbConn.Queries().SearchDiscussions(ctx, db.SearchDiscussionsParams{
+		RepositoryName: query.Get("repository"),
+		Query:          q,
+		RowLimit:       int32(limit),
+	})
+	if err != nil {
+		log.Printf("Failed to search discussions: %v", err)
+		http.Error(w, "Failed to search discussions", http.StatusInternalServerError)
+		return
+	}
+
+	results := make([]discussionSearchResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, discussionSearchResult{
+			Repository: row.RepositoryName,
+			Number:     row.DiscussionNumber,
+			Title:      row.Title,
+			Category:   row.Category.String,
+			State:      row.State,
+			Answered:   row.Answered,
+			Author:     row.AuthorLogin.String,
+			HTMLURL:    row.HtmlUrl.String,
+			UpdatedAt:  row.UpdatedAt.Time,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   q,
+		"results": results,
+	})
+}
+
+// processDiscussion stores discussion activity, routes it to the channels
+// for its category and runs slash commands posted in new comments
+func (wh *WebhookHandler) processDiscussion(ctx context.Context, eventType, deliveryID, senderLogin string, body []byte) error {
+	activity, err := webhook.ParseDiscussionActivity(eventType, body)
+	if err != nil {
+		return fmt.Errorf("failed to parse discussion activity: %w", err)
+	}
+
+	// Route and run commands even if storing fails, reporting the failure after
+	var failed error
+	if wh.dbConn != nil {
+		if err := storeDiscussionActivity(ctx, wh.dbConn, activity); err != nil {
+			failed = fmt.Errorf("failed to store discussion activity: %w", err)
+		}
+	}
+
+	if wh.discussionRouter != nil {
+		wh.discussionRouter.Route(ctx, deliveryID, activity)
+	}
+
+	if activity.Comment != nil && activity.Action == "created" {
+		wh.runCommands(ctx, eventType, deliveryID, senderLogin, activity, body)
+	}
+	return failed
+}
+
+// runCommands publishes every slash command in a new comment to the
+// forwarders and runs those with a registered handler
+func (wh *WebhookHandler) runCommands(ctx context.Context, eventType, deliveryID, senderLogin string, activity *webhook.DiscussionActivity, body []byte) {
+	for i, command := range chatops.Parse(activity.Comment.Body) {
+		invocation := chatops.Invocation{
+			Command:    command,
+			Actor:      senderLogin,
+			Repository: activity.Repository,
+			Source:     eventType,
+			Number:     activity.Discussion.Number,
+			URL:        activity.Comment.HTMLURL,
+			Payload:    body,
+		}
+		log.Printf("Command /%s from %s on %s discussion #%d", command.Name, senderLogin, activity.Repository, activity.Discussion.Number)
+
+		wh.publishCommand(ctx, deliveryID, i, invocation)
+
+		if wh.commands == nil {
+			continue
+		}
+		if err := wh.commands.Dispatch(ctx, invocation); err != nil && !errors.Is(err, chatops.ErrUnknownCommand) {
+			log.Printf("Command /%s failed (delivery: %s): %v", command.Name, deliveryID, err)
+		}
+	}
+}
+
+// storeDiscussionActivity upserts the discussion and, for comment events, the comment
+func storeDiscussionActivity(ctx context.Context, dbConn *database.Connection, activity *webhook.DiscussionActivity) error {
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	d := activity.Discussion
+	state := d.State
+	if activity.EventType == webhook.DiscussionEvent && activity.Action == "deleted" {
+		state = "deleted"
+	}
+	err := dbConn.Queries().UpsertDiscussion(dbCtx, db.UpsertDiscussionParams{
+		RepositoryName:   activity.Repository,
+		DiscussionNumber: int32(d.Number),
+		Title:            d.Title,
+		Body:             d.Body,
+		Category:         optionalText(d.Category.Slug),
+		State:            state,
+		Answered:         d.Answered(),
+		AuthorLogin:      optionalText(d.User.Login),
+		HtmlUrl:          optionalText(d.HTMLURL),
+		CreatedAt:        timestampOrNow(d.CreatedAt),
+		UpdatedAt:        timestampOrNow(d.UpdatedAt),
+	})
+	if err != nil || activity.Comment == nil {
+		return err
+	}
+
+	c := activity.Comment
+	return dbConn.Queries().UpsertDiscussionComment(dbCtx, db.UpsertDiscussionCommentParams{
+		CommentID:        c.ID,
+		RepositoryName:   activity.Repository,
+		DiscussionNumber: int32(d.Number),
+		Body:             c.Body,
+		AuthorLogin:      optionalText(c.User.Login),
+		HtmlUrl:          optionalText(c.HTMLURL),
+		Deleted:          activity.Action == "deleted",
+		CreatedAt:        timestampOrNow(c.CreatedAt),
+		UpdatedAt:        timestampOrNow(c.UpdatedAt),
+	})
+}
+
+// timestampOrNow converts a payload timestamp, using the current time when
+// the payload omits it
+func timestampOrNow(t time.Time) pgtype.Timestamptz {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
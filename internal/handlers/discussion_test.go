@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/chatops"
+)
+
+func TestDiscussionHandler_HandleSearch_InvalidMethod(t *testing.T) {
+	handler := NewDiscussionHandler(nil)
+
+	req := httptest.NewRequest("POST", "/api/discussions/search?q=deploy", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleSearch(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestDiscussionHandler_HandleSearch_InvalidParameters(t *testing.T) {
+	handler := NewDiscussionHandler(nil)
+
+	for _, target := range []string{"/api/discussions/search", "/api/discussions/search?q=deploy&limit=1000"} {
+		req := httptest.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+
+		handler.HandleSearch(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusBadRequest, status)
+		}
+	}
+}
+
+func TestDiscussionHandler_HandleSearch_NoDatabase(t *testing.T) {
+	handler := NewDiscussionHandler(nil)
+
+	req := httptest.NewRequest("GET", "/api/discussions/search?q=deploy", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleSearch(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_DiscussionCommands(t *testing.T) {
+	rec := &recordingForwarder{}
+	handler := NewWebhookHandler("", nil).WithForwarders(rec)
+
+	payload := `{"action":"created","discussion":{"number":7},"comment":{"id":1,"body":"Escalating\n/notify security"},"repository":{"full_name":"test/repo"},"sender":{"login":"testuser"}}`
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+	req.Header.Set("X-GitHub-Event", "discussion_comment")
+	req.Header.Set("X-GitHub-Delivery", "test-delivery-id")
+	rr := httptest.NewRecorder()
+
+	handler.HandleWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	if len(rec.events) != 2 {
+		t.Fatalf("Expected the command and the event to be forwarded, got %d events", len(rec.events))
+	}
+	// The command and forwarders processors run concurrently, in any order
+	command := rec.events[0]
+	if command.EventType != chatops.CommandEventType {
+		command = rec.events[1]
+	}
+	if command.EventType != chatops.CommandEventType || command.Action != "notify" || command.Sender != "testuser" {
+		t.Errorf("Unexpected command event: %+v", command)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// processDocs notifies channels about wiki page changes and failed GitHub
+// Pages builds
+func (wh *WebhookHandler) processDocs(ctx context.Context, eventType, deliveryID string, body []byte) error {
+	switch eventType {
+	case webhook.GollumEvent:
+		change, err := webhook.ParseWikiChange(body)
+		if err != nil {
+			return fmt.Errorf("failed to parse wiki change: %w", err)
+		}
+		for _, page := range change.Pages {
+			log.Printf("Wiki page %q %s on %s by %s", page.Title, page.Action, change.Repository, change.Sender)
+		}
+		if wh.docsRouter != nil {
+			wh.docsRouter.WikiChanged(ctx, deliveryID, change)
+		}
+
+	case webhook.PageBuildEvent:
+		build, err := webhook.ParsePageBuild(body)
+		if err != nil {
+			return fmt.Errorf("failed to parse page build: %w", err)
+		}
+		if build.Failed() {
+			log.Printf("GitHub Pages build failed for %s at %s: %s", build.Repository, build.Commit, build.Error)
+		}
+		if wh.docsRouter != nil {
+			wh.docsRouter.PageBuildFinished(ctx, deliveryID, build)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/dora"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DORAHandler serves the DORA metrics of each repository
+type DORAHandler struct {
+	dbConn *database.Connection
+}
+
+// NewDORAHandler creates a new DORA metrics handler
+func NewDORAHandler(dbConn *database.Connection) *DORAHandler {
+	return &DORAHandler{dbConn: dbConn}
+}
+
+// HandleMetrics reports deployment frequency, lead time for changes and
+// change failure rate per repository. Query parameters: days (default 30),
+// environment to only count deployments to it, and repository.
+func (dh *DORAHandler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	days, ok := parseDORADays(query.Get("days"))
+	if !ok {
+		http.Error(w, "Invalid days parameter", http.StatusBadRequest)
+		return
+	}
+	environment := query.Get("environment")
+	repository := query.Get("repository")
+
+	if dh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -days)
+	metrics, err := loadDORA(ctx, dh.dbConn.Queries(), from, to, environment)
+	if err != nil {
+		log.Printf("Failed to load DORA metrics: %v", err)
+		http.Error(w, "Failed to load DORA metrics", http.StatusInternalServerError)
+		return
+	}
+	if repository != "" {
+		filtered := []dora.Metrics{}
+		for _, m := range metrics {
+			if m.Repository == repository {
+				filtered = append(filtered, m)
+			}
+		}
+		metrics = filtered
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"days":         days,
+		"from":         from,
+		"to":           to,
+		"environment":  environment,
+		"repositories": metrics,
+	})
+}
+
+// parseDORADays parses the days parameter, 30 when it is empty, reporting
+// whether it is valid
+func parseDORADays(value string) (int, bool) {
+	if value == "" {
+		return 30, true
+	}
+	days, err := strconv.Atoi(value)
+	return days, err == nil && days > 0 && days <= 366
+}
+
+// loadDORA computes the DORA metrics of the deployments from from until to,
+// with the changes pushed and merged up to dora.ChangeLookback earlier
+func loadDORA(ctx context.Context, queries *db.Queries, from, to time.Time, environment string) ([]dora.Metrics, error) {
+	until := pgtype.Timestamptz{Time: to, Valid: true}
+	changesSince := pgtype.Timestamptz{Time: from.Add(-dora.ChangeLookback), Valid: true}
+
+	deployments, err := queries.ListDeploymentOutcomes(ctx, db.ListDeploymentOutcomesParams{
+		Since: pgtype.Timestamptz{Time: from, Valid: true},
+		Until: until,
+	})
+	if err != nil {
+		return nil, err
+	}
+	commits, err := queries.ListPushedCommits(ctx, db.ListPushedCommitsParams{Since: changesSince, Until: until})
+	if err != nil {
+		return nil, err
+	}
+	merges, err := queries.ListMergedPullRequests(ctx, db.ListMergedPullRequestsParams{Since: changesSince, Until: until})
+	if err != nil {
+		return nil, err
+	}
+	return dora.Compute(dora.NewData(deployments, commits, merges, environment), from, to), nil
+}
+
+// HandleDORA shows the DORA metrics of each repository on the dashboard.
+// Query parameters: days (default 30) and environment.
+func (ah *AdminHandler) HandleDORA(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !ah.authorize(w, r) {
+		return
+	}
+	days, ok := parseDORADays(r.URL.Query().Get("days"))
+	if !ok {
+		http.Error(w, "Invalid days parameter", http.StatusBadRequest)
+		return
+	}
+	environment := r.URL.Query().Get("environment")
+	if ah.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	to := time.Now().UTC()
+	metrics, err := loadDORA(ctx, ah.dbConn.Queries(), to.AddDate(0, 0, -days), to, environment)
+	if err != nil {
+		log.Printf("Failed to load DORA metrics: %v", err)
+		http.Error(w, "Failed to load DORA metrics", http.StatusInternalServerError)
+		return
+	}
+	rows := make([]adminDORA, 0, len(metrics))
+	for _, m := range metrics {
+		rows = append(rows, newAdminDORA(m))
+	}
+	renderAdmin(w, "dora.html", map[string]interface{}{
+		"Metrics":     rows,
+		"Days":        days,
+		"Environment": environment,
+		"Banners":     ah.dashboardBanners(r.Context()),
+	})
+}
+
+// adminDORA is the DORA metrics of a repository as shown on the dashboard
+type adminDORA struct {
+	dora.Metrics
+	LeadTime    string
+	FailureRate string
+}
+
+func newAdminDORA(m dora.Metrics) adminDORA {
+	row := adminDORA{Metrics: m, FailureRate: strconv.FormatFloat(m.ChangeFailureRate*100, 'f', 1, 64) + "%"}
+	if m.LeadTimeSeconds != nil {
+		row.LeadTime = time.Duration(*m.LeadTimeSeconds * float64(time.Second)).Round(time.Minute).String()
+	}
+	return row
+}
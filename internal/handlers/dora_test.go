@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/dora"
+)
+
+func TestDORAHandler_HandleMetrics(t *testing.T) {
+	handler := NewDORAHandler(nil)
+
+	tests := []struct {
+		method   string
+		target   string
+		expected int
+	}{
+		{http.MethodPost, "/api/metrics/dora", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/metrics/dora?days=0", http.StatusBadRequest},
+		{http.MethodGet, "/api/metrics/dora?days=a", http.StatusBadRequest},
+		{http.MethodGet, "/api/metrics/dora?days=7&environment=production", http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		rr := httptest.NewRecorder()
+		handler.HandleMetrics(rr, httptest.NewRequest(test.method, test.target, nil))
+
+		if rr.Code != test.expected {
+			t.Errorf("%s %s: expected status code %d, got %d", test.method, test.target, test.expected, rr.Code)
+		}
+	}
+}
+
+func TestAdminHandler_HandleDORA(t *testing.T) {
+	handler := NewAdminHandler("admin", "hunter2", apitoken.NewAuthenticator("", nil), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/dora", nil)
+	rr := httptest.NewRecorder()
+	handler.HandleDORA(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d without credentials, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	req.SetBasicAuth("admin", "hunter2")
+	rr = httptest.NewRecorder()
+	handler.HandleDORA(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+}
+
+func TestRenderAdmin_DORA(t *testing.T) {
+	leadTime := 5400.0
+	rr := httptest.NewRecorder()
+	renderAdmin(rr, "dora.html", map[string]interface{}{
+		"Metrics": []adminDORA{
+			newAdminDORA(dora.Metrics{
+				Repository: "acme/api", Deployments: 12, DeploymentsPerDay: 1.2, Changes: 30, PullRequests: 9, LeadTimeSeconds: &leadTime,
+				ChangeFailureRate: 0.077, FailedDeployments: 1,
+				Levels: dora.Levels{DeploymentFrequency: dora.LevelElite, LeadTime: dora.LevelElite, ChangeFailureRate: dora.LevelHigh},
+			}),
+			newAdminDORA(dora.Metrics{Repository: "acme/web", FailedDeployments: 2, ChangeFailureRate: 1, Levels: dora.Levels{DeploymentFrequency: dora.LevelLow, ChangeFailureRate: dora.LevelLow}}),
+		},
+		"Days": 30,
+	})
+
+	body := rr.Body.String()
+	for _, want := range []string{"<td>acme/api</td>", "elite</span> 1.2", "elite</span> 1h30m0s <small>(30 commits, 9 pull requests)", "high</span> 7.7%", "<td>-</td>", "low</span> 100.0%"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected page to contain %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/model"
+	"github.com/deedubs/choochoo/internal/pathlabels"
+	"github.com/deedubs/choochoo/internal/settings"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// Outcomes of a dry run step
+const (
+	outcomePass    = "pass"
+	outcomeFail    = "fail"
+	outcomeSkip    = "skip"
+	outcomeMatch   = "match"
+	outcomeNoMatch = "no match"
+)
+
+// TraceStep is one step a delivery goes through, as explained by a dry run
+type TraceStep struct {
+	Step    string `json:"step"`
+	Outcome string `json:"outcome"`
+	Detail  string `json:"detail,omitempty"`
+	// Result is what the step parsed or decided, such as the projection of
+	// a processor or the outcome of a rule
+	Result interface{} `json:"result,omitempty"`
+}
+
+// DryRun explains how a delivery would be handled
+type DryRun struct {
+	EventType  string `json:"event_type"`
+	DeliveryID string `json:"delivery_id"`
+	Action     string `json:"action,omitempty"`
+	Repository string `json:"repository,omitempty"`
+	Sender     string `json:"sender,omitempty"`
+	Branch     string `json:"branch,omitempty"`
+	Checksum   string `json:"checksum,omitempty"`
+	// Model is the provider-agnostic model rules and forwarders see
+	Model *model.Event `json:"model,omitempty"`
+	// Accepted reports whether /webhook would accept the delivery
+	Accepted bool `json:"accepted"`
+	// Stored reports whether the event would be stored
+	Stored bool `json:"stored"`
+	// Dropped reports whether a rule would drop the event
+	Dropped bool        `json:"dropped"`
+	Trace   []TraceStep `json:"trace"`
+}
+
+func (d *DryRun) step(step, outcome, detail string, result interface{}) {
+	d.Trace = append(d.Trace, TraceStep{Step: step, Outcome: outcome, Detail: detail, Result: result})
+}
+
+// projection parses an event the way a processor does before acting on it
+type projection struct {
+	processor string
+	applies   func(eventType string) bool
+	parse     func(eventType string, body []byte) (interface{}, error)
+}
+
+func is(eventType string) func(string) bool {
+	return func(t string) bool { return t == eventType }
+}
+
+// projections lists the processors of WebhookHandler.process with their
+// parsers, in the same order
+var projections = []projection{
+	{"push", is(webhook.PushEvent), func(_ string, body []byte) (interface{}, error) { return webhook.ParsePush(body) }},
+	{"pull_request", is(webhook.PullRequestEvent), func(_ string, body []byte) (interface{}, error) {
+		return webhook.ParsePullRequestActivity(body)
+	}},
+	{"ci_run", webhook.IsCIEvent, func(eventType string, body []byte) (interface{}, error) {
+		return webhook.ParseCIRun(eventType, body)
+	}},
+	{"deployment", is(webhook.DeploymentStatusEvent), func(_ string, body []byte) (interface{}, error) {
+		return webhook.ParseDeploymentStatus(body)
+	}},
+	{"release", is(webhook.ReleaseEvent), func(_ string, body []byte) (interface{}, error) { return webhook.ParseRelease(body) }},
+	{"ref", webhook.IsRefEvent, func(eventType string, body []byte) (interface{}, error) {
+		return webhook.ParseRefChange(eventType, body)
+	}},
+	{"issue_comment", is(webhook.IssueCommentEvent), func(_ string, body []byte) (interface{}, error) {
+		return webhook.ParseIssueCommentActivity(body)
+	}},
+	{"security_alert", webhook.IsSecurityAlertEvent, func(eventType string, body []byte) (interface{}, error) {
+		return webhook.ParseSecurityAlert(eventType, body)
+	}},
+	{"branch_protection", webhook.IsProtectionEvent, func(eventType string, body []byte) (interface{}, error) {
+		return webhook.ParseProtectionChange(eventType, body)
+	}},
+	{"access", webhook.IsAccessEvent, func(eventType string, body []byte) (interface{}, error) {
+		return webhook.ParseAccessChange(eventType, body)
+	}},
+	{"discussion", webhook.IsDiscussionEvent, func(eventType string, body []byte) (interface{}, error) {
+		return webhook.ParseDiscussionActivity(eventType, body)
+	}},
+	{"project", is(webhook.ProjectsV2ItemEvent), func(_ string, body []byte) (interface{}, error) {
+		return webhook.ParseProjectItemChange(body)
+	}},
+	{"docs", webhook.IsDocsEvent, func(eventType string, body []byte) (interface{}, error) {
+		if eventType == webhook.GollumEvent {
+			return webhook.ParseWikiChange(body)
+		}
+		return webhook.ParsePageBuild(body)
+	}},
+}
+
+// HandleDryRun runs a payload through validation, parsing, rules and the
+// projections of the processors without storing it or acting on it, and
+// responds with the trace of every step. Requests are sent like deliveries,
+// with the X-GitHub-Event header and, to check a signature, the
+// X-Hub-Signature-256 header.
+func (wh *WebhookHandler) HandleDryRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	eventType := r.Header.Get("X-GitHub-Event")
+	if eventType == "" {
+		http.Error(w, "X-GitHub-Event header is required", http.StatusBadRequest)
+		return
+	}
+	deliveryID := r.Header.Get("X-GitHub-Delivery")
+	if deliveryID == "" {
+		deliveryID = "dry-run"
+	}
+
+	// Read past the delivery limit, so oversized payloads can be explained
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 2*wh.maxBodySize))
+	if err != nil {
+		http.Error(w, "Error reading request body", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, wh.dryRun(eventType, deliveryID, r.Header, body))
+}
+
+// dryRun explains how HandleWebhook and process would handle a delivery
+func (wh *WebhookHandler) dryRun(eventType, deliveryID string, header http.Header, body []byte) *DryRun {
+	run := &DryRun{EventType: eventType, DeliveryID: deliveryID, Accepted: true}
+	reject := func(step, detail string) {
+		run.Accepted = false
+		run.step(step, outcomeFail, detail, nil)
+	}
+
+	formEncoded := false
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		switch {
+		case err == nil && mediaType == "application/json":
+		case err == nil && mediaType == "application/x-www-form-urlencoded":
+			formEncoded = true
+		default:
+			reject("content_type", fmt.Sprintf("%q is not supported; rejected with 415", contentType))
+			return run
+		}
+	}
+
+	if int64(len(body)) > wh.maxBodySize {
+		reject("size", fmt.Sprintf("%d bytes, more than the %d byte limit; rejected with 413", len(body), wh.maxBodySize))
+		return run
+	}
+	run.step("size", outcomePass, fmt.Sprintf("%d bytes", len(body)), nil)
+
+	chain, err := wh.verifyProvenance(body, header)
+	switch {
+	case err != nil:
+		reject("signature", err.Error()+"; rejected with 401")
+	case len(chain) == 0:
+		run.step("signature", outcomeSkip, "GITHUB_WEBHOOK_SECRET not set; deliveries are not verified", nil)
+	default:
+		run.step("signature", outcomePass, "", chain)
+	}
+
+	if formEncoded {
+		form, err := url.ParseQuery(string(body))
+		if err != nil || form.Get("payload") == "" {
+			reject("form", "no payload field; rejected with 400")
+			return run
+		}
+		body = []byte(form.Get("payload"))
+	}
+
+	var event webhook.GitHubEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		reject("json", fmt.Sprintf("%v; rejected with 400", err))
+		return run
+	}
+	run.Action = event.Action
+	run.Repository, _ = event.Repository["full_name"].(string)
+	run.Sender, _ = event.Sender["login"].(string)
+	run.Branch = webhook.ParseBranch(body)
+	run.step("json", outcomePass, "", nil)
+
+	if wh.redactor.Enabled() {
+		redacted, err := wh.redactor.Redact(body)
+		if err != nil {
+			reject("redaction", fmt.Sprintf("%v; rejected with 400", err))
+			return run
+		}
+		body = redacted
+		run.step("redaction", outcomePass, "the payload is redacted before it is stored or processed", nil)
+	}
+	run.Checksum = checksum.Sum(body)
+
+	if eventType == webhook.PingEvent {
+		hook, err := webhook.ParsePing(body)
+		if err != nil {
+			run.step("ping", outcomeFail, err.Error(), nil)
+		} else {
+			run.step("ping", outcomePass, "answered with the hook it describes; not processed", hook)
+		}
+		return run
+	}
+
+	repoName := run.Repository
+	if repoName == "" {
+		repoName = "unknown"
+	}
+	switch {
+	case !webhook.IsSupportedEvent(eventType):
+		run.step("store", outcomeSkip, eventType+" events are not stored", nil)
+	case wh.settings != nil && wh.settings.IgnoresEvent(repoName, run.Branch, eventType):
+		run.step("store", outcomeSkip, fmt.Sprintf("%s ignores %s events", settings.RepoFile, eventType), nil)
+	case wh.events == nil:
+		run.step("store", outcomeSkip, "DATABASE_URL not set", nil)
+	case wh.notifyQueue != nil:
+		run.Stored = true
+		run.step("store", outcomePass, "stored and processed by a work queue worker", nil)
+	default:
+		run.Stored = true
+		run.step("store", outcomePass, "stored and processed during the request", nil)
+	}
+
+	for _, p := range projections {
+		if !p.applies(eventType) {
+			continue
+		}
+		result, err := p.parse(eventType, body)
+		if err != nil {
+			run.step("processor "+p.processor, outcomeFail, err.Error(), nil)
+			continue
+		}
+		run.step("processor "+p.processor, outcomePass, "", result)
+	}
+	if eventType == webhook.PushEvent && wh.configLint != nil {
+		if push, err := webhook.ParsePush(body); err == nil && !push.Deleted && push.Changes(settings.RepoFile) {
+			run.step("processor config_lint", outcomePass, fmt.Sprintf("%s would be linted at %s", settings.RepoFile, push.After), nil)
+		}
+	}
+	if eventType == webhook.PullRequestEvent && wh.pathLabels != nil && pathlabels.Applies(body) {
+		run.step("processor path_labels", outcomePass, "the changed files would be fetched and labeled by path", nil)
+	}
+	if eventType == webhook.IssueCommentEvent && wh.commentClient != nil && len(wh.commentPolicy) > 0 {
+		if activity, err := webhook.ParseIssueCommentActivity(body); err == nil {
+			var names []string
+			for _, command := range wh.commentCommands(activity) {
+				names = append(names, "/"+command.Name)
+			}
+			if len(names) > 0 {
+				run.step("processor comment_commands", outcomePass, fmt.Sprintf("%s would run if %s has the role each needs", strings.Join(names, ", "), activity.Comment.User.Login), nil)
+			}
+		}
+	}
+
+	forwarded := forwarder.Event{
+		DeliveryID: deliveryID,
+		EventType:  eventType,
+		Action:     event.Action,
+		Repository: repoName,
+		Sender:     run.Sender,
+		Checksum:   run.Checksum,
+		Payload:    body,
+	}
+	run.Model = forwarded.Model()
+	if wh.rules != nil {
+		explanations, err := wh.rules.Explain(forwarded)
+		if err != nil {
+			run.step("rules", outcomeFail, err.Error(), nil)
+		}
+		for _, explanation := range explanations {
+			outcome := outcomeNoMatch
+			switch {
+			case explanation.Skipped:
+				outcome = outcomeSkip
+			case explanation.Error != "":
+				outcome = outcomeFail
+			case explanation.Matched:
+				outcome = outcomeMatch
+			}
+			run.Dropped = run.Dropped || (explanation.Matched && slices.Contains(explanation.Actions, "drop"))
+			run.step("rule "+explanation.Rule, outcome, explanation.Error, explanation)
+		}
+	}
+
+	var names []string
+	for _, f := range append(wh.forwarders, wh.outbox...) {
+		names = append(names, f.Name())
+	}
+	switch {
+	case len(names) == 0:
+	case run.Dropped:
+		run.step("forwarders", outcomeSkip, "dropped by a rule", names)
+	default:
+		run.step("forwarders", outcomePass, "", names)
+	}
+
+	if wh.receipts != nil && wh.receipts.Matches(eventType, event.Action) {
+		run.step("receipt", outcomePass, "a processing receipt would be posted to GitHub", nil)
+	}
+	return run
+}
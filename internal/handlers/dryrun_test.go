@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/rules"
+)
+
+func dryRunRequest(eventType, payload, signature string) *http.Request {
+	req := httptest.NewRequest("POST", "/api/v1/ingest/dry-run", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if eventType != "" {
+		req.Header.Set("X-GitHub-Event", eventType)
+	}
+	if signature != "" {
+		req.Header.Set("X-Hub-Signature-256", signature)
+	}
+	return req
+}
+
+func outcomes(run DryRun) map[string]string {
+	steps := make(map[string]string)
+	for _, step := range run.Trace {
+		steps[step.Step] = step.Outcome
+	}
+	return steps
+}
+
+func TestWebhookHandler_HandleDryRun(t *testing.T) {
+	engine := rules.NewEngine([]rules.Rule{
+		{Name: "bots", Condition: `sender.endsWith("[bot]")`, Actions: []rules.Action{{Type: rules.ActionDrop}}},
+		{Name: "all", Condition: `true`, Actions: []rules.Action{{Type: rules.ActionDrop}}},
+	}, nil)
+	handler := NewWebhookHandler("test-secret", nil).WithRules(engine)
+	payload := `{"ref": "refs/heads/main", "after": "abc123", "repository": {"full_name": "acme/api"}, "sender": {"login": "deploy[bot]"}}`
+
+	rr := httptest.NewRecorder()
+	handler.HandleDryRun(rr, dryRunRequest("push", payload, generateSignature([]byte(payload), "test-secret")))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	var run DryRun
+	if err := json.NewDecoder(rr.Body).Decode(&run); err != nil {
+		t.Fatalf("Failed to decode the dry run: %v", err)
+	}
+	if !run.Accepted || run.Stored || !run.Dropped || run.Repository != "acme/api" || run.Branch != "main" {
+		t.Errorf("Unexpected dry run %+v", run)
+	}
+	want := map[string]string{
+		"size":           outcomePass,
+		"signature":      outcomePass,
+		"json":           outcomePass,
+		"store":          outcomeSkip,
+		"processor push": outcomePass,
+		"rule bots":      outcomeMatch,
+		"rule all":       outcomeSkip,
+	}
+	steps := outcomes(run)
+	for step, outcome := range want {
+		if steps[step] != outcome {
+			t.Errorf("Expected step %s to %s, got %q", step, outcome, steps[step])
+		}
+	}
+
+	// Unsigned payloads are explained as rejected
+	rr = httptest.NewRecorder()
+	handler.HandleDryRun(rr, dryRunRequest("push", payload, ""))
+	run = DryRun{}
+	json.NewDecoder(rr.Body).Decode(&run)
+	if run.Accepted || outcomes(run)["signature"] != outcomeFail {
+		t.Errorf("Expected an unsigned payload to be rejected, got %+v", run)
+	}
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{"invalid method", httptest.NewRequest("GET", "/api/v1/ingest/dry-run", nil), http.StatusMethodNotAllowed},
+		{"missing event", dryRunRequest("", payload, ""), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.HandleDryRun(rr, tt.req)
+			if rr.Code != tt.status {
+				t.Errorf("Expected status code %d, got %d", tt.status, rr.Code)
+			}
+		})
+	}
+}
+
+func TestWebhookHandler_HandleDryRun_InvalidJSON(t *testing.T) {
+	handler := NewWebhookHandler("", nil)
+	rr := httptest.NewRecorder()
+	handler.HandleDryRun(rr, dryRunRequest("push", `{"ref":`, ""))
+	var run DryRun
+	if err := json.NewDecoder(rr.Body).Decode(&run); err != nil {
+		t.Fatalf("Failed to decode the dry run: %v", err)
+	}
+	steps := outcomes(run)
+	if run.Accepted || steps["signature"] != outcomeSkip || steps["json"] != outcomeFail {
+		t.Errorf("Unexpected dry run %+v", run)
+	}
+}
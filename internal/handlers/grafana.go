@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/deedubs/choochoo/internal/metrics"
+)
+
+// HandleGrafanaDashboard serves a Grafana dashboard of the pushed business
+// metrics, ready to import
+func HandleGrafanaDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	dashboard, err := metrics.GrafanaDashboard()
+	if err != nil {
+		log.Printf("Failed to generate Grafana dashboard: %v", err)
+		http.Error(w, "Failed to generate dashboard", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="choochoo-dashboard.json"`)
+	w.Write(dashboard)
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGrafanaDashboard(t *testing.T) {
+	rr := httptest.NewRecorder()
+	HandleGrafanaDashboard(rr, httptest.NewRequest(http.MethodGet, "/api/grafana/dashboard", nil))
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+	var dashboard struct {
+		UID    string        `json:"uid"`
+		Panels []interface{} `json:"panels"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &dashboard); err != nil || dashboard.UID == "" || len(dashboard.Panels) == 0 {
+		t.Errorf("Expected a dashboard, got %v: %s", err, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	HandleGrafanaDashboard(rr, httptest.NewRequest(http.MethodPost, "/api/grafana/dashboard", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, rr.Code)
+	}
+}
@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/encryption"
+	"github.com/deedubs/choochoo/internal/graphql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Limits on the lists a GraphQL field returns
+const (
+	defaultGraphQLLimit = 20
+	maxGraphQLLimit     = 100
+)
+
+// maxGraphQLRequestBytes bounds the size of posted GraphQL requests
+const maxGraphQLRequestBytes = 64 << 10
+
+// graphQLQueries are the queries the GraphQL resolvers use
+type graphQLQueries interface {
+	SearchWebhookEvents(ctx context.Context, arg db.SearchWebhookEventsParams) ([]db.WebhookEvent, error)
+	GetWebhookEventByDeliveryID(ctx context.Context, deliveryID string) (db.WebhookEvent, error)
+	ListRepositorySummaries(ctx context.Context, arg db.ListRepositorySummariesParams) ([]db.ListRepositorySummariesRow, error)
+	SearchPullRequests(ctx context.Context, arg db.SearchPullRequestsParams) ([]db.PullRequest, error)
+	GetPullRequest(ctx context.Context, arg db.GetPullRequestParams) (db.PullRequest, error)
+	SearchCommits(ctx context.Context, arg db.SearchCommitsParams) ([]db.Commit, error)
+}
+
+// GraphQLHandler serves a GraphQL API over the stored events and the
+// repositories, pull requests and commits derived from them
+type GraphQLHandler struct {
+	dbConn *database.Connection
+	keys   *encryption.Keyring
+}
+
+// NewGraphQLHandler creates a new GraphQL handler
+func NewGraphQLHandler(dbConn *database.Connection) *GraphQLHandler {
+	return &GraphQLHandler{dbConn: dbConn}
+}
+
+// WithEncryption sets the keys encrypted payloads are returned with
+func (gh *GraphQLHandler) WithEncryption(keys *encryption.Keyring) *GraphQLHandler {
+	gh.keys = keys
+	return gh
+}
+
+// HandleQuery runs a GraphQL query, posted as JSON with query,
+// operationName and variables, or passed in the query parameters of the
+// same names in a GET request. Errors in the query are reported in the
+// errors of the response, as GraphQL clients expect.
+func (gh *GraphQLHandler) HandleQuery(w http.ResponseWriter, r *http.Request) {
+	var req graphql.Request
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				http.Error(w, "Invalid variables parameter", http.StatusBadRequest)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLRequestBytes)).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON request", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if req.Query == "" {
+		http.Error(w, "Missing query", http.StatusBadRequest)
+		return
+	}
+	if gh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	resp := newGraphQLSchema(gh.dbConn.Queries(), gh.keys).Execute(ctx, req)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// newGraphQLSchema builds the schema of the GraphQL API:
+//
+//	type Query {
+//	  events(eventType, repository, sender, action, since, until: String, limit: Int): [Event]
+//	  event(deliveryId: String!): Event
+//	  repositories(limit: Int): [Repository]
+//	  repository(name: String!): Repository
+//	  pullRequests(repository, state, author: String, limit: Int): [PullRequest]
+//	  pullRequest(repository: String!, number: Int!): PullRequest
+//	  commits(repository, ref, author: String, limit: Int): [Commit]
+//	}
+func newGraphQLSchema(queries graphQLQueries, keys *encryption.Keyring) *graphql.Schema {
+	event := &graphql.Object{Name: "Event"}
+	repository := &graphql.Object{Name: "Repository"}
+	pullRequest := &graphql.Object{Name: "PullRequest"}
+	commit := &graphql.Object{Name: "Commit"}
+
+	eventArgs := map[string]graphql.Arg{
+		"eventType": {Type: graphql.String},
+		"sender":    {Type: graphql.String},
+		"action":    {Type: graphql.String},
+		"since":     {Type: graphql.String},
+		"until":     {Type: graphql.String},
+		"limit":     {Type: graphql.Int},
+	}
+	searchEvents := func(ctx context.Context, repositoryName string, args graphql.Args) (interface{}, error) {
+		params := db.SearchWebhookEventsParams{
+			EventType:      args.String("eventType"),
+			RepositoryName: repositoryName,
+			SenderLogin:    args.String("sender"),
+			Action:         args.String("action"),
+		}
+		var err error
+		if params.Since, err = graphQLTime(args.String("since"), false); err != nil {
+			return nil, err
+		}
+		if params.Until, err = graphQLTime(args.String("until"), true); err != nil {
+			return nil, err
+		}
+		if params.RowLimit, err = graphQLLimit(args); err != nil {
+			return nil, err
+		}
+		return queries.SearchWebhookEvents(ctx, params)
+	}
+	searchPullRequests := func(ctx context.Context, repositoryName string, args graphql.Args) (interface{}, error) {
+		limit, err := graphQLLimit(args)
+		if err != nil {
+			return nil, err
+		}
+		return queries.SearchPullRequests(ctx, db.SearchPullRequestsParams{
+			RepositoryName: repositoryName,
+			State:          args.String("state"),
+			AuthorLogin:    args.String("author"),
+			RowLimit:       limit,
+		})
+	}
+	searchCommits := func(ctx context.Context, params db.SearchCommitsParams, args graphql.Args) (interface{}, error) {
+		limit, err := graphQLLimit(args)
+		if err != nil {
+			return nil, err
+		}
+		params.Ref = args.String("ref")
+		params.AuthorLogin = args.String("author")
+		params.RowLimit = limit
+		return queries.SearchCommits(ctx, params)
+	}
+	getRepository := func(ctx context.Context, name string) (interface{}, error) {
+		if name == "" {
+			return nil, nil
+		}
+		rows, err := queries.ListRepositorySummaries(ctx, db.ListRepositorySummariesParams{RepositoryName: name, RowLimit: 1})
+		if err != nil || len(rows) == 0 {
+			return nil, err
+		}
+		return rows[0], nil
+	}
+	getPullRequest := func(ctx context.Context, repositoryName string, number int) (interface{}, error) {
+		pr, err := queries.GetPullRequest(ctx, db.GetPullRequestParams{RepositoryName: repositoryName, PrNumber: int32(number)})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return pr, err
+	}
+	getEvent := func(ctx context.Context, deliveryID string) (interface{}, error) {
+		e, err := queries.GetWebhookEventByDeliveryID(ctx, deliveryID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return e, err
+	}
+	payload := func(e db.WebhookEvent) (json.RawMessage, error) {
+		payload, err := keys.Open(e.DeliveryID, e.Payload)
+		if err != nil {
+			log.Printf("Failed to decrypt delivery %s: %v", e.DeliveryID, err)
+			return nil, fmt.Errorf("failed to decrypt the payload")
+		}
+		return payload, nil
+	}
+
+	event.Fields = map[string]*graphql.Field{
+		"deliveryId":     leaf(func(e db.WebhookEvent) interface{} { return e.DeliveryID }),
+		"eventType":      leaf(func(e db.WebhookEvent) interface{} { return e.EventType }),
+		"action":         leaf(func(e db.WebhookEvent) interface{} { return graphQLText(e.Action) }),
+		"repositoryName": leaf(func(e db.WebhookEvent) interface{} { return graphQLText(e.RepositoryName) }),
+		"sender":         leaf(func(e db.WebhookEvent) interface{} { return graphQLText(e.SenderLogin) }),
+		"createdAt":      leaf(func(e db.WebhookEvent) interface{} { return graphQLTimestamp(e.CreatedAt) }),
+		"payload": {Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return payload(source.(db.WebhookEvent))
+		}},
+		"repository": {Type: repository, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return getRepository(ctx, source.(db.WebhookEvent).RepositoryName.String)
+		}},
+		// The pull request of pull_request, pull_request_review and
+		// pull_request_review_comment events
+		"pullRequest": {Type: pullRequest, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			e := source.(db.WebhookEvent)
+			raw, err := payload(e)
+			if err != nil {
+				return nil, err
+			}
+			var body struct {
+				PullRequest *struct {
+					Number int `json:"number"`
+				} `json:"pull_request"`
+			}
+			if json.Unmarshal(raw, &body) != nil || body.PullRequest == nil || !e.RepositoryName.Valid {
+				return nil, nil
+			}
+			return getPullRequest(ctx, e.RepositoryName.String, body.PullRequest.Number)
+		}},
+		// The commits of push events
+		"commits": {Type: commit, Args: map[string]graphql.Arg{"limit": {Type: graphql.Int}}, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return searchCommits(ctx, db.SearchCommitsParams{DeliveryID: source.(db.WebhookEvent).DeliveryID}, args)
+		}},
+	}
+
+	repository.Fields = map[string]*graphql.Field{
+		"name":         leaf(func(r db.ListRepositorySummariesRow) interface{} { return r.RepositoryName }),
+		"eventCount":   leaf(func(r db.ListRepositorySummariesRow) interface{} { return r.Events }),
+		"firstEventAt": leaf(func(r db.ListRepositorySummariesRow) interface{} { return graphQLTimestamp(r.FirstEventAt) }),
+		"lastEventAt":  leaf(func(r db.ListRepositorySummariesRow) interface{} { return graphQLTimestamp(r.LastEventAt) }),
+		"events": {Type: event, Args: eventArgs, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return searchEvents(ctx, source.(db.ListRepositorySummariesRow).RepositoryName, args)
+		}},
+		"pullRequests": {Type: pullRequest, Args: map[string]graphql.Arg{
+			"state":  {Type: graphql.String},
+			"author": {Type: graphql.String},
+			"limit":  {Type: graphql.Int},
+		}, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return searchPullRequests(ctx, source.(db.ListRepositorySummariesRow).RepositoryName, args)
+		}},
+		"commits": {Type: commit, Args: map[string]graphql.Arg{
+			"ref":    {Type: graphql.String},
+			"author": {Type: graphql.String},
+			"limit":  {Type: graphql.Int},
+		}, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return searchCommits(ctx, db.SearchCommitsParams{RepositoryName: source.(db.ListRepositorySummariesRow).RepositoryName}, args)
+		}},
+	}
+
+	pullRequest.Fields = map[string]*graphql.Field{
+		"repositoryName": leaf(func(pr db.PullRequest) interface{} { return pr.RepositoryName }),
+		"number":         leaf(func(pr db.PullRequest) interface{} { return pr.PrNumber }),
+		"title":          leaf(func(pr db.PullRequest) interface{} { return pr.Title }),
+		"state":          leaf(func(pr db.PullRequest) interface{} { return pr.State }),
+		"draft":          leaf(func(pr db.PullRequest) interface{} { return pr.Draft }),
+		"merged":         leaf(func(pr db.PullRequest) interface{} { return pr.Merged }),
+		"headRef":        leaf(func(pr db.PullRequest) interface{} { return pr.HeadRef }),
+		"baseRef":        leaf(func(pr db.PullRequest) interface{} { return pr.BaseRef }),
+		"author":         leaf(func(pr db.PullRequest) interface{} { return graphQLText(pr.AuthorLogin) }),
+		"url":            leaf(func(pr db.PullRequest) interface{} { return graphQLText(pr.HtmlUrl) }),
+		"lastAction":     leaf(func(pr db.PullRequest) interface{} { return pr.LastAction }),
+		"createdAt":      leaf(func(pr db.PullRequest) interface{} { return graphQLTimestamp(pr.CreatedAt) }),
+		"updatedAt":      leaf(func(pr db.PullRequest) interface{} { return graphQLTimestamp(pr.UpdatedAt) }),
+		"closedAt":       leaf(func(pr db.PullRequest) interface{} { return graphQLTimestamp(pr.ClosedAt) }),
+		"mergedAt":       leaf(func(pr db.PullRequest) interface{} { return graphQLTimestamp(pr.MergedAt) }),
+		"repository": {Type: repository, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return getRepository(ctx, source.(db.PullRequest).RepositoryName)
+		}},
+	}
+
+	commit.Fields = map[string]*graphql.Field{
+		"sha":            leaf(func(c db.Commit) interface{} { return c.Sha }),
+		"repositoryName": leaf(func(c db.Commit) interface{} { return c.RepositoryName }),
+		"ref":            leaf(func(c db.Commit) interface{} { return c.Ref }),
+		"message":        leaf(func(c db.Commit) interface{} { return c.Message }),
+		"authorName":     leaf(func(c db.Commit) interface{} { return graphQLText(c.AuthorName) }),
+		"authorEmail":    leaf(func(c db.Commit) interface{} { return graphQLText(c.AuthorEmail) }),
+		"authorLogin":    leaf(func(c db.Commit) interface{} { return graphQLText(c.AuthorLogin) }),
+		"committedAt":    leaf(func(c db.Commit) interface{} { return graphQLTimestamp(c.CommittedAt) }),
+		"deliveryId":     leaf(func(c db.Commit) interface{} { return c.DeliveryID }),
+		"repository": {Type: repository, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return getRepository(ctx, source.(db.Commit).RepositoryName)
+		}},
+		// The push event that delivered the commit, unless it was pruned
+		"event": {Type: event, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return getEvent(ctx, source.(db.Commit).DeliveryID)
+		}},
+	}
+
+	repositoryEventArgs := map[string]graphql.Arg{"repository": {Type: graphql.String}}
+	for name, arg := range eventArgs {
+		repositoryEventArgs[name] = arg
+	}
+	query := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"events": {Type: event, Args: repositoryEventArgs, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return searchEvents(ctx, args.String("repository"), args)
+		}},
+		"event": {Type: event, Args: map[string]graphql.Arg{"deliveryId": {Type: graphql.String, Required: true}}, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return getEvent(ctx, args.String("deliveryId"))
+		}},
+		"repositories": {Type: repository, Args: map[string]graphql.Arg{"limit": {Type: graphql.Int}}, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			limit, err := graphQLLimit(args)
+			if err != nil {
+				return nil, err
+			}
+			return queries.ListRepositorySummaries(ctx, db.ListRepositorySummariesParams{RowLimit: limit})
+		}},
+		"repository": {Type: repository, Args: map[string]graphql.Arg{"name": {Type: graphql.String, Required: true}}, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return getRepository(ctx, args.String("name"))
+		}},
+		"pullRequests": {Type: pullRequest, Args: map[string]graphql.Arg{
+			"repository": {Type: graphql.String},
+			"state":      {Type: graphql.String},
+			"author":     {Type: graphql.String},
+			"limit":      {Type: graphql.Int},
+		}, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return searchPullRequests(ctx, args.String("repository"), args)
+		}},
+		"pullRequest": {Type: pullRequest, Args: map[string]graphql.Arg{
+			"repository": {Type: graphql.String, Required: true},
+			"number":     {Type: graphql.Int, Required: true},
+		}, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return getPullRequest(ctx, args.String("repository"), args.Int("number", 0))
+		}},
+		"commits": {Type: commit, Args: map[string]graphql.Arg{
+			"repository": {Type: graphql.String},
+			"ref":        {Type: graphql.String},
+			"author":     {Type: graphql.String},
+			"limit":      {Type: graphql.Int},
+		}, Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+			return searchCommits(ctx, db.SearchCommitsParams{RepositoryName: args.String("repository")}, args)
+		}},
+	}}
+	return graphql.NewSchema(query)
+}
+
+// leaf creates a field resolved from a value of its object type by get
+func leaf[T any](get func(T) interface{}) *graphql.Field {
+	return &graphql.Field{Resolve: func(ctx context.Context, source interface{}, args graphql.Args) (interface{}, error) {
+		return get(source.(T)), nil
+	}}
+}
+
+// graphQLLimit reads the limit argument of a list field
+func graphQLLimit(args graphql.Args) (int32, error) {
+	limit := args.Int("limit", defaultGraphQLLimit)
+	if limit < 1 || limit > maxGraphQLLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", maxGraphQLLimit)
+	}
+	return int32(limit), nil
+}
+
+// graphQLTime parses a since or until argument, a date or an RFC 3339 time.
+// A date until is the end of the day.
+func graphQLTime(value string, until bool) (pgtype.Timestamptz, error) {
+	if value == "" {
+		return pgtype.Timestamptz{}, nil
+	}
+	t, date, ok := parseStatsTime(value)
+	if !ok {
+		return pgtype.Timestamptz{}, fmt.Errorf("invalid time %q, expected YYYY-MM-DD or RFC 3339", value)
+	}
+	if date && until {
+		t = t.AddDate(0, 0, 1)
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}, nil
+}
+
+// graphQLText returns a nullable text column as a string or null
+func graphQLText(text pgtype.Text) interface{} {
+	if !text.Valid {
+		return nil
+	}
+	return text.String
+}
+
+// graphQLTimestamp returns a nullable time column as RFC 3339 or null
+func graphQLTimestamp(ts pgtype.Timestamptz) interface{} {
+	if !ts.Valid {
+		return nil
+	}
+	return ts.Time.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/graphql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeGraphQLQueries serves the GraphQL resolvers from fixed rows
+type fakeGraphQLQueries struct {
+	events  []db.WebhookEvent
+	pulls   []db.PullRequest
+	commits []db.Commit
+	// eventSearches records the parameters events were searched with
+	eventSearches []db.SearchWebhookEventsParams
+}
+
+func (f *fakeGraphQLQueries) SearchWebhookEvents(ctx context.Context, arg db.SearchWebhookEventsParams) ([]db.WebhookEvent, error) {
+	f.eventSearches = append(f.eventSearches, arg)
+	var events []db.WebhookEvent
+	for _, e := range f.events {
+		if (arg.EventType == "" || e.EventType == arg.EventType) && (arg.RepositoryName == "" || e.RepositoryName.String == arg.RepositoryName) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (f *fakeGraphQLQueries) GetWebhookEventByDeliveryID(ctx context.Context, deliveryID string) (db.WebhookEvent, error) {
+	for _, e := range f.events {
+		if e.DeliveryID == deliveryID {
+			return e, nil
+		}
+	}
+	return db.WebhookEvent{}, pgx.ErrNoRows
+}
+
+func (f *fakeGraphQLQueries) ListRepositorySummaries(ctx context.Context, arg db.ListRepositorySummariesParams) ([]db.ListRepositorySummariesRow, error) {
+	counts := make(map[string]int64)
+	var rows []db.ListRepositorySummariesRow
+	for _, e := range f.events {
+		if arg.RepositoryName != "" && e.RepositoryName.String != arg.RepositoryName {
+			continue
+		}
+		if counts[e.RepositoryName.String] == 0 {
+			rows = append(rows, db.ListRepositorySummariesRow{RepositoryName: e.RepositoryName.String})
+		}
+		counts[e.RepositoryName.String]++
+	}
+	for i := range rows {
+		rows[i].Events = counts[rows[i].RepositoryName]
+	}
+	return rows, nil
+}
+
+func (f *fakeGraphQLQueries) SearchPullRequests(ctx context.Context, arg db.SearchPullRequestsParams) ([]db.PullRequest, error) {
+	var pulls []db.PullRequest
+	for _, pr := range f.pulls {
+		if (arg.RepositoryName == "" || pr.RepositoryName == arg.RepositoryName) && (arg.State == "" || pr.State == arg.State) {
+			pulls = append(pulls, pr)
+		}
+	}
+	return pulls, nil
+}
+
+func (f *fakeGraphQLQueries) GetPullRequest(ctx context.Context, arg db.GetPullRequestParams) (db.PullRequest, error) {
+	for _, pr := range f.pulls {
+		if pr.RepositoryName == arg.RepositoryName && pr.PrNumber == arg.PrNumber {
+			return pr, nil
+		}
+	}
+	return db.PullRequest{}, pgx.ErrNoRows
+}
+
+func (f *fakeGraphQLQueries) SearchCommits(ctx context.Context, arg db.SearchCommitsParams) ([]db.Commit, error) {
+	var commits []db.Commit
+	for _, c := range f.commits {
+		if (arg.RepositoryName == "" || c.RepositoryName == arg.RepositoryName) && (arg.DeliveryID == "" || c.DeliveryID == arg.DeliveryID) {
+			commits = append(commits, c)
+		}
+	}
+	return commits, nil
+}
+
+func newFakeGraphQLQueries() *fakeGraphQLQueries {
+	at := pgtype.Timestamptz{Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Valid: true}
+	repo := pgtype.Text{String: "octo-org/api", Valid: true}
+	return &fakeGraphQLQueries{
+		events: []db.WebhookEvent{
+			{DeliveryID: "d1", EventType: "pull_request", Action: pgtype.Text{String: "opened", Valid: true}, RepositoryName: repo, Payload: []byte(`{"pull_request":{"number":7}}`), CreatedAt: at},
+			{DeliveryID: "d2", EventType: "push", RepositoryName: repo, Payload: []byte(`{"ref":"refs/heads/main"}`), CreatedAt: at},
+		},
+		pulls:   []db.PullRequest{{RepositoryName: "octo-org/api", PrNumber: 7, Title: "Add GraphQL", State: "open", CreatedAt: at}},
+		commits: []db.Commit{{Sha: "abc123", RepositoryName: "octo-org/api", Ref: "refs/heads/main", Message: "Initial commit", DeliveryID: "d2", CommittedAt: at}},
+	}
+}
+
+func TestGraphQLSchema(t *testing.T) {
+	queries := newFakeGraphQLQueries()
+	schema := newGraphQLSchema(queries, nil)
+
+	resp := schema.Execute(context.Background(), graphql.Request{Query: `{
+		repository(name: "octo-org/api") {
+			name
+			eventCount
+			pullRequests(state: "open") { number title mergedAt }
+		}
+		events(eventType: "pull_request", since: "2024-05-01") {
+			deliveryId
+			action
+			payload
+			pullRequest { title repository { name } }
+		}
+		commits(repository: "octo-org/api") { sha event { deliveryId commits { sha } } }
+	}`})
+	body, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"data":{` +
+		`"repository":{"name":"octo-org/api","eventCount":2,"pullRequests":[{"number":7,"title":"Add GraphQL","mergedAt":null}]},` +
+		`"events":[{"deliveryId":"d1","action":"opened","payload":{"pull_request":{"number":7}},"pullRequest":{"title":"Add GraphQL","repository":{"name":"octo-org/api"}}}],` +
+		`"commits":[{"sha":"abc123","event":{"deliveryId":"d2","commits":[{"sha":"abc123"}]}}]}}`
+	if string(body) != want {
+		t.Errorf("Execute() = %s\nwant %s", body, want)
+	}
+	if len(queries.eventSearches) != 1 || !queries.eventSearches[0].Since.Time.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || queries.eventSearches[0].Until.Valid || queries.eventSearches[0].RowLimit != defaultGraphQLLimit {
+		t.Errorf("Events searched with %+v", queries.eventSearches)
+	}
+
+	resp = schema.Execute(context.Background(), graphql.Request{Query: `{ events(limit: 1000) { deliveryId } pushes: events(until: "yesterday") { deliveryId } }`})
+	if len(resp.Errors) != 2 || resp.Errors[0].Message != "limit must be between 1 and 100" || !strings.Contains(resp.Errors[1].Message, "expected YYYY-MM-DD or RFC 3339") {
+		t.Errorf("Execute() with invalid arguments = %+v", resp.Errors)
+	}
+}
+
+func TestGraphQLHandler_HandleQuery(t *testing.T) {
+	handler := NewGraphQLHandler(nil)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{"wrong method", http.MethodDelete, "/api/graphql", "", http.StatusMethodNotAllowed},
+		{"invalid JSON", http.MethodPost, "/api/graphql", "{", http.StatusBadRequest},
+		{"missing query", http.MethodPost, "/api/graphql", `{"variables":{}}`, http.StatusBadRequest},
+		{"invalid variables", http.MethodGet, "/api/graphql?query=%7Bevents%7BdeliveryId%7D%7D&variables=nope", "", http.StatusBadRequest},
+		{"no database", http.MethodPost, "/api/graphql", `{"query":"{ events { deliveryId } }"}`, http.StatusServiceUnavailable},
+		{"no database with GET", http.MethodGet, "/api/graphql?query=%7Bevents%7BdeliveryId%7D%7D", "", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.HandleQuery(rr, httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body)))
+			if rr.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rr.Code)
+			}
+		})
+	}
+}
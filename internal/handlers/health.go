@@ -1,25 +1,102 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 )
 
+// ReadinessCheck returns an error when a component is not ready to serve
+type ReadinessCheck func(ctx context.Context) error
+
+// readinessComponent is a named readiness check
+type readinessComponent struct {
+	name  string
+	check ReadinessCheck
+}
+
+// componentStatus is the readiness of one component as returned by the API
+type componentStatus struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
 // HealthHandler handles health check requests
-type HealthHandler struct{}
+type HealthHandler struct {
+	components []readinessComponent
+}
 
 // NewHealthHandler creates a new health handler
 func NewHealthHandler() *HealthHandler {
 	return &HealthHandler{}
 }
 
+// WithCheck adds a component that must pass its check for the server to be
+// ready
+func (hh *HealthHandler) WithCheck(name string, check ReadinessCheck) *HealthHandler {
+	hh.components = append(hh.components, readinessComponent{name: name, check: check})
+	return hh
+}
+
 // HandleHealth provides a health check endpoint
 func (hh *HealthHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	response := map[string]string{
-		"status": "healthy",
+		"status":  "healthy",
 		"service": "choochoo-webhook-server",
 	}
 	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+}
+
+// HandleLiveness reports that the process is alive and serving requests. It
+// checks no dependencies, so an outage elsewhere does not get the server
+// restarted.
+func (hh *HealthHandler) HandleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "alive"})
+}
+
+// HandleReadiness runs every readiness check and responds with 503 unless
+// they all pass, reporting the status of each component
+func (hh *HealthHandler) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	components, ready := hh.check(ctx)
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]interface{}{"status": status, "components": components})
+}
+
+// Ready runs every readiness check, returning the first failure, for
+// readiness probes other than /readyz
+func (hh *HealthHandler) Ready(ctx context.Context) error {
+	for _, component := range hh.components {
+		if err := component.check(ctx); err != nil {
+			return fmt.Errorf("%s: %w", component.name, err)
+		}
+	}
+	return nil
+}
+
+// check runs every readiness check, reporting the status of each component
+// and whether they all passed
+func (hh *HealthHandler) check(ctx context.Context) ([]componentStatus, bool) {
+	ready := true
+	components := make([]componentStatus, 0, len(hh.components))
+	for _, component := range hh.components {
+		result := componentStatus{Name: component.name, Status: "ok"}
+		if err := component.check(ctx); err != nil {
+			ready = false
+			result.Status = "failed"
+			result.Error = err.Error()
+		}
+		components = append(components, result)
+	}
+	return components, ready
+}
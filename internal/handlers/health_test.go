@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -29,4 +32,68 @@ func TestHealthHandler_HandleHealth(t *testing.T) {
 	if contentType != "application/json" {
 		t.Errorf("Expected Content-Type application/json, got %s", contentType)
 	}
-}
\ No newline at end of file
+}
+func TestHealthHandler_HandleLiveness(t *testing.T) {
+	handler := NewHealthHandler().WithCheck("database", func(context.Context) error {
+		return errors.New("database unreachable")
+	})
+
+	rr := httptest.NewRecorder()
+	handler.HandleLiveness(rr, httptest.NewRequest("GET", "/healthz", nil))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+}
+
+func TestHealthHandler_HandleReadiness(t *testing.T) {
+	var dbErr error
+	handler := NewHealthHandler().
+		WithCheck("database", func(context.Context) error { return dbErr }).
+		WithCheck("migrations", func(context.Context) error { return nil })
+
+	rr := httptest.NewRecorder()
+	handler.HandleReadiness(rr, httptest.NewRequest("GET", "/readyz", nil))
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+
+	dbErr = errors.New("database unreachable")
+	rr = httptest.NewRecorder()
+	handler.HandleReadiness(rr, httptest.NewRequest("GET", "/readyz", nil))
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+
+	var response struct {
+		Status     string            `json:"status"`
+		Components []componentStatus `json:"components"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Status != "not_ready" || len(response.Components) != 2 {
+		t.Fatalf("Unexpected response: %+v", response)
+	}
+	if db := response.Components[0]; db.Status != "failed" || db.Error != "database unreachable" {
+		t.Errorf("Unexpected database status: %+v", db)
+	}
+	if migrations := response.Components[1]; migrations.Status != "ok" {
+		t.Errorf("Unexpected migrations status: %+v", migrations)
+	}
+}
+
+func TestHealthHandler_Ready(t *testing.T) {
+	dbErr := errors.New("database unreachable")
+	handler := NewHealthHandler().
+		WithCheck("database", func(context.Context) error { return dbErr }).
+		WithCheck("migrations", func(context.Context) error { return nil })
+
+	if err := handler.Ready(context.Background()); !errors.Is(err, dbErr) || !strings.HasPrefix(err.Error(), "database: ") {
+		t.Errorf("Expected the database failure, got %v", err)
+	}
+	dbErr = nil
+	if err := handler.Ready(context.Background()); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// HooksHandler lists the hooks pointing at this server
+type HooksHandler struct {
+	dbConn *database.Connection
+}
+
+// NewHooksHandler creates a new hooks handler
+func NewHooksHandler(dbConn *database.Connection) *HooksHandler {
+	return &HooksHandler{dbConn: dbConn}
+}
+
+// hookRegistration is a captured hook as returned by the API
+type hookRegistration struct {
+	webhook.HookRegistration
+	DeliveryID string    `json:"delivery_id"`
+	PingedAt   time.Time `json:"pinged_at"`
+}
+
+// HandleList lists the hooks that pinged this server, most recently pinged
+// first
+func (hh *HooksHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if hh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := hh.dbConn.Queries().ListHookRegistrations(ctx)
+	if err != nil {
+		log.Printf("Failed to list hook registrations: %v", err)
+		http.Error(w, "Failed to list hook registrations", http.StatusInternalServerError)
+		return
+	}
+
+	hooks := make([]hookRegistration, 0, len(rows))
+	for _, row := range rows {
+		hook := hookRegistration{
+			HookRegistration: webhook.HookRegistration{
+				HookID:      row.HookID,
+				Type:        row.HookType,
+				Target:      row.Target,
+				Active:      row.Active,
+				Events:      row.Events,
+				URL:         row.Url,
+				ContentType: row.ContentType,
+				InsecureSSL: row.InsecureSsl,
+				Zen:         row.Zen,
+			},
+			DeliveryID: row.DeliveryID,
+			PingedAt:   row.PingedAt.Time,
+		}
+		if row.HookCreatedAt.Valid {
+			hook.CreatedAt = &row.HookCreatedAt.Time
+		}
+		if row.HookUpdatedAt.Valid {
+			hook.UpdatedAt = &row.HookUpdatedAt.Time
+		}
+		hooks = append(hooks, hook)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"hooks": hooks})
+}
+
+// handlePing answers a ping event with the metadata of its hook, capturing
+// the hook's registration so operators can see which hooks point at this
+// server. Pings are not stored as events nor processed.
+func (wh *WebhookHandler) handlePing(ctx context.Context, w http.ResponseWriter, deliveryID string, body []byte) {
+	response := map[string]interface{}{
+		"status":  "success",
+		"message": "Pong",
+	}
+
+	registration, err := webhook.ParsePing(body)
+	if err != nil {
+		// Still acknowledge the ping so GitHub marks the hook as working
+		log.Printf("Failed to parse ping (delivery: %s): %v", deliveryID, err)
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+	log.Printf("Hook %d (%s %s) pinged for events %v", registration.HookID, registration.Type, registration.Target, registration.Events)
+	response["hook"] = registration
+
+	if wh.dbConn != nil {
+		dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := storeHookRegistration(dbCtx, wh.dbConn.Queries(), deliveryID, registration)
+		cancel()
+		if err != nil {
+			log.Printf("Failed to store hook registration %d: %v", registration.HookID, err)
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// storeHookRegistration inserts or refreshes a hook's registration
+func storeHookRegistration(ctx context.Context, queries *db.Queries, deliveryID string, registration *webhook.HookRegistration) error {
+	return queries.UpsertHookRegistration(ctx, db.UpsertHookRegistrationParams{
+		HookID:        registration.HookID,
+		HookType:      registration.Type,
+		Target:        registration.Target,
+		Active:        registration.Active,
+		Events:        registration.Events,
+		Url:           registration.URL,
+		ContentType:   registration.ContentType,
+		InsecureSsl:   registration.InsecureSSL,
+		Zen:           registration.Zen,
+		DeliveryID:    deliveryID,
+		HookCreatedAt: optionalTimestamp(registration.CreatedAt),
+		HookUpdatedAt: optionalTimestamp(registration.UpdatedAt),
+	})
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookHandler_HandleWebhook_Ping(t *testing.T) {
+	rec := &recordingForwarder{}
+	handler := NewWebhookHandler("", nil).WithForwarders(rec)
+
+	payload := `{
+		"zen": "Design for failure.",
+		"hook_id": 42,
+		"hook": {"type": "Repository", "id": 42, "active": true, "events": ["push"], "config": {"content_type": "json", "insecure_ssl": "0", "url": "https://choochoo.example.com/webhook"}},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "ping")
+	req.Header.Set("X-GitHub-Delivery", "test-delivery-id")
+	rr := httptest.NewRecorder()
+
+	handler.HandleWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	var response struct {
+		Message string `json:"message"`
+		Hook    struct {
+			HookID int64    `json:"hook_id"`
+			Target string   `json:"target"`
+			Events []string `json:"events"`
+			Zen    string   `json:"zen"`
+		} `json:"hook"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Message != "Pong" || response.Hook.HookID != 42 || response.Hook.Target != "octo-org/hello-world" ||
+		len(response.Hook.Events) != 1 || response.Hook.Zen != "Design for failure." {
+		t.Errorf("Unexpected response: %s", rr.Body.String())
+	}
+	if len(rec.events) != 0 {
+		t.Errorf("Expected pings not to be forwarded, got %d events", len(rec.events))
+	}
+}
+
+func TestHooksHandler_HandleList_InvalidMethod(t *testing.T) {
+	handler := NewHooksHandler(nil)
+
+	req := httptest.NewRequest("POST", "/api/hooks", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleList(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestHooksHandler_HandleList_NoDatabase(t *testing.T) {
+	handler := NewHooksHandler(nil)
+
+	req := httptest.NewRequest("GET", "/api/hooks", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleList(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
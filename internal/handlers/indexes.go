@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/indexadvisor"
+)
+
+// IndexHandler reports the indexes of the events table and the indexes the
+// advisor suggests for slow query API patterns
+type IndexHandler struct {
+	advisor    *indexadvisor.Advisor
+	list       indexadvisor.ListFunc
+	autoCreate bool
+}
+
+// NewIndexHandler creates a new index handler. advisor is nil when slow
+// queries are not tracked, and list when there is no database.
+func NewIndexHandler(advisor *indexadvisor.Advisor, list indexadvisor.ListFunc, autoCreate bool) *IndexHandler {
+	return &IndexHandler{advisor: advisor, list: list, autoCreate: autoCreate}
+}
+
+// HandleReport reports the usage and estimated bloat of each index, what
+// the advisor saw of each query pattern and the indexes it suggests
+func (ih *IndexHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if ih.list == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	indexes, err := ih.list(ctx)
+	if err != nil {
+		log.Printf("Failed to list indexes: %v", err)
+		http.Error(w, "Failed to list indexes", http.StatusInternalServerError)
+		return
+	}
+
+	report := map[string]interface{}{
+		"indexes":     indexes,
+		"enabled":     ih.advisor != nil,
+		"auto_create": ih.autoCreate && ih.advisor != nil,
+		"patterns":    []indexadvisor.Usage{},
+		"suggestions": []indexadvisor.Suggestion{},
+	}
+	if ih.advisor != nil {
+		report["slow_query_ms"] = ih.advisor.SlowQuery().Milliseconds()
+		report["patterns"] = ih.advisor.Usage()
+		if suggestions := ih.advisor.Suggest(indexes); suggestions != nil {
+			report["suggestions"] = suggestions
+		}
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/indexadvisor"
+)
+
+func TestIndexHandler_HandleReport_NoDatabase(t *testing.T) {
+	rr := httptest.NewRecorder()
+	NewIndexHandler(nil, nil, false).HandleReport(rr, httptest.NewRequest("GET", "/api/v1/indexes", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rr.Code)
+	}
+}
+
+func TestIndexHandler_HandleReport(t *testing.T) {
+	advisor := indexadvisor.New(time.Millisecond)
+	for i := 0; i < indexadvisor.MinSlowCalls; i++ {
+		advisor.Observe(indexadvisor.Pattern{Name: "deliveries", Keys: []string{"repository_name"}, Range: "created_at"}, time.Second)
+	}
+	list := func(ctx context.Context) ([]indexadvisor.Index, error) {
+		return []indexadvisor.Index{{Name: "idx_webhook_events_sender", Unused: true, Valid: true}}, nil
+	}
+
+	rr := httptest.NewRecorder()
+	NewIndexHandler(advisor, list, true).HandleReport(rr, httptest.NewRequest("GET", "/api/v1/indexes", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var report struct {
+		AutoCreate  bool                      `json:"auto_create"`
+		Indexes     []indexadvisor.Index      `json:"indexes"`
+		Patterns    []indexadvisor.Usage      `json:"patterns"`
+		Suggestions []indexadvisor.Suggestion `json:"suggestions"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.AutoCreate || len(report.Indexes) != 1 || !report.Indexes[0].Unused || len(report.Patterns) != 1 || len(report.Suggestions) != 1 {
+		t.Errorf("Unexpected report: %s", rr.Body)
+	}
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// processIssueComment keeps the comments table in sync as issue and pull
+// request comments are created, edited and deleted
+func (wh *WebhookHandler) processIssueComment(ctx context.Context, deliveryID string, body []byte) error {
+	if wh.dbConn == nil {
+		return nil
+	}
+
+	activity, err := webhook.ParseIssueCommentActivity(body)
+	if err != nil {
+		return fmt.Errorf("failed to parse issue comment: %w", err)
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	c := activity.Comment
+	err = wh.dbConn.Queries().UpsertComment(dbCtx, db.UpsertCommentParams{
+		CommentID:      c.ID,
+		RepositoryName: activity.Repository,
+		IssueNumber:    int32(activity.IssueNumber),
+		IsPullRequest:  activity.IsPullRequest,
+		AuthorLogin:    optionalText(c.User.Login),
+		Body:           c.Body,
+		HtmlUrl:        optionalText(c.HTMLURL),
+		Deleted:        activity.Action == "deleted",
+		CreatedAt:      timestampOrNow(c.CreatedAt),
+		UpdatedAt:      timestampOrNow(c.UpdatedAt),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store issue comment: %w", err)
+	}
+	return nil
+}
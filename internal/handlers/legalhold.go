@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/legalhold"
+)
+
+// auditEntry is a legal hold audit log entry as returned by the management API
+type auditEntry struct {
+	ID        int64     `json:"id"`
+	Action    string    `json:"action"`
+	HoldIDs   []int32   `json:"hold_ids"`
+	Actor     string    `json:"actor"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HandleLegalHolds lists every legal hold, those in force first, or places
+// a new one on a repository, a time range of event creation or both
+func (mh *ManagementHandler) HandleLegalHolds(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := mh.operator(w, r)
+	if !ok {
+		return
+	}
+	if mh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method == http.MethodGet {
+		rows, err := mh.dbConn.Queries().ListLegalHolds(ctx)
+		if err != nil {
+			log.Printf("Failed to list legal holds: %v", err)
+			http.Error(w, "Failed to list legal holds", http.StatusInternalServerError)
+			return
+		}
+		holds := make([]legalhold.Hold, 0, len(rows))
+		for _, row := range rows {
+			holds = append(holds, legalhold.FromRow(row))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"legal_holds": holds})
+		return
+	}
+
+	var hold legalhold.Hold
+	if err := json.NewDecoder(r.Body).Decode(&hold); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := hold.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	placed, err := legalhold.Place(ctx, mh.dbConn, hold, operator)
+	if err != nil {
+		log.Printf("Failed to place legal hold: %v", err)
+		http.Error(w, "Failed to place legal hold", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Legal hold %d on %s placed by %s", placed.ID, placed.Describe(), operator)
+	writeJSON(w, http.StatusCreated, placed)
+}
+
+// HandleLegalHold releases a legal hold. The hold stays listed as released.
+func (mh *ManagementHandler) HandleLegalHold(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Only DELETE method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := mh.operator(w, r)
+	if !ok {
+		return
+	}
+	if mh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid legal hold ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	released, err := legalhold.Release(ctx, mh.dbConn, int32(id), operator)
+	if err != nil {
+		log.Printf("Failed to release legal hold %d: %v", id, err)
+		http.Error(w, "Failed to release legal hold", http.StatusInternalServerError)
+		return
+	}
+	if !released {
+		http.Error(w, "Legal hold not found or already released", http.StatusNotFound)
+		return
+	}
+	log.Printf("Legal hold %d released by %s", id, operator)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleLegalHoldAudit lists the legal hold audit log, newest first
+func (mh *ManagementHandler) HandleLegalHoldAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := mh.operator(w, r); !ok {
+		return
+	}
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+	if mh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := mh.dbConn.Queries().ListLegalHoldAudit(ctx, int32(limit))
+	if err != nil {
+		log.Printf("Failed to list legal hold audit log: %v", err)
+		http.Error(w, "Failed to list legal hold audit log", http.StatusInternalServerError)
+		return
+	}
+	entries := make([]auditEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, auditEntry{
+			ID:        row.ID,
+			Action:    row.Action,
+			HoldIDs:   row.HoldIds,
+			Actor:     row.Actor,
+			Details:   row.Details,
+			CreatedAt: row.CreatedAt.Time,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"entries": entries})
+}
+
+// HandleEraseSender erases the stored and archived events sent by a GitHub
+// login, for data subject erasure requests. Erasures are refused with 409
+// Conflict while legal holds cover any of the events.
+func (mh *ManagementHandler) HandleEraseSender(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Only DELETE method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := mh.operator(w, r)
+	if !ok {
+		return
+	}
+	if mh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+	sender := r.PathValue("login")
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	erasure, err := legalhold.EraseSender(ctx, mh.dbConn, sender, operator)
+	var held *legalhold.HeldError
+	if errors.As(err, &held) {
+		log.Printf("Erasure of the events of sender %s by %s blocked: %v", sender, operator, err)
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"error": err.Error(), "legal_holds": held.Holds})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to erase the events of sender %s: %v", sender, err)
+		http.Error(w, "Failed to erase events", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Erased %d events and %d archived events of sender %s for %s", erasure.Events, erasure.Archived, sender, operator)
+	writeJSON(w, http.StatusOK, erasure)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+)
+
+func TestManagementHandler_HandleLegalHolds_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		req    *http.Request
+		handle func(*ManagementHandler) http.HandlerFunc
+		status int
+	}{
+		{"not configured", "", managementRequest("GET", "/api/v1/legal-holds", "", "secret"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleLegalHolds }, http.StatusServiceUnavailable},
+		{"invalid token", "secret", managementRequest("POST", "/api/v1/legal-holds", `{"repository":"octo-org/hello-world","reason":"Litigation"}`, "wrong"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleLegalHolds }, http.StatusUnauthorized},
+		{"invalid method", "secret", managementRequest("PUT", "/api/v1/legal-holds", "", "secret"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleLegalHolds }, http.StatusMethodNotAllowed},
+		{"no database", "secret", managementRequest("GET", "/api/v1/legal-holds", "", "secret"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleLegalHolds }, http.StatusServiceUnavailable},
+		{"release with GET", "secret", managementRequest("GET", "/api/v1/legal-holds/1", "", "secret", "id", "1"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleLegalHold }, http.StatusMethodNotAllowed},
+		{"audit with invalid limit", "secret", managementRequest("GET", "/api/v1/legal-holds/audit?limit=0", "", "secret"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleLegalHoldAudit }, http.StatusBadRequest},
+		{"erase without token", "secret", managementRequest("DELETE", "/api/v1/senders/octocat/events", "", "", "login", "octocat"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleEraseSender }, http.StatusUnauthorized},
+		{"erase with GET", "secret", managementRequest("GET", "/api/v1/senders/octocat/events", "", "secret", "login", "octocat"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleEraseSender }, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewManagementHandler(apitoken.NewAuthenticator(tt.token, nil), nil)
+			rr := httptest.NewRecorder()
+			tt.handle(handler)(rr, tt.req)
+			if rr.Code != tt.status {
+				t.Errorf("Expected status code %d, got %d", tt.status, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/maintenance"
+)
+
+// MaintenanceHandler reports the maintenance state of the tables and runs
+// table maintenance on demand
+type MaintenanceHandler struct {
+	job      *maintenance.Job
+	interval time.Duration
+}
+
+// NewMaintenanceHandler creates a new maintenance handler. job is nil when
+// there is no database, and interval 0 when maintenance is not scheduled.
+func NewMaintenanceHandler(job *maintenance.Job, interval time.Duration) *MaintenanceHandler {
+	return &MaintenanceHandler{job: job, interval: interval}
+}
+
+// HandleReport reports the policy, the vacuum, analyze and size statistics
+// of each table, the tasks the policy calls for now and what the job has
+// done
+func (mh *MaintenanceHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mh.job == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tables, tasks, err := mh.job.Plan(ctx)
+	if err != nil {
+		log.Printf("Failed to plan table maintenance: %v", err)
+		http.Error(w, "Failed to load table statistics", http.StatusInternalServerError)
+		return
+	}
+	if tasks == nil {
+		tasks = []maintenance.Task{}
+	}
+
+	policy := mh.job.Policy()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"scheduled": mh.interval > 0,
+		"interval":  mh.interval.String(),
+		"policy": map[string]interface{}{
+			"window":                  policy.Window.String(),
+			"vacuum_dead_percent":     policy.VacuumDeadPercent,
+			"analyze_changed_percent": policy.AnalyzeChangedPercent,
+			"reindex_bloat_percent":   policy.ReindexBloatPercent,
+		},
+		"tables":  tables,
+		"pending": tasks,
+		"stats":   mh.job.Stats(),
+	})
+}
+
+// HandleRun starts the tasks the policy calls for in the background, outside
+// the window too
+func (mh *MaintenanceHandler) HandleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if mh.job == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := mh.job.Start(time.Now().UTC()); err != nil {
+		if errors.Is(err, maintenance.ErrRunning) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		log.Printf("Failed to start table maintenance: %v", err)
+		http.Error(w, "Failed to start maintenance", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
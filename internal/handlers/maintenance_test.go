@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/indexadvisor"
+	"github.com/deedubs/choochoo/internal/maintenance"
+)
+
+func TestMaintenanceHandler_NoDatabase(t *testing.T) {
+	handler := NewMaintenanceHandler(nil, 0)
+
+	rr := httptest.NewRecorder()
+	handler.HandleReport(rr, httptest.NewRequest("GET", "/api/v1/maintenance", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rr.Code)
+	}
+	rr = httptest.NewRecorder()
+	handler.HandleRun(rr, httptest.NewRequest("POST", "/api/v1/maintenance/run", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rr.Code)
+	}
+}
+
+func TestMaintenanceHandler_HandleReport(t *testing.T) {
+	window, _ := maintenance.ParseWindow("02:00-05:00")
+	job := maintenance.NewJob(maintenance.Policy{VacuumDeadPercent: 10, Window: window},
+		func(ctx context.Context) ([]maintenance.Table, error) {
+			return []maintenance.Table{maintenance.NewTable(db.ListTableMaintenanceStatsRow{Name: "webhook_events", LiveTuples: 9000, DeadTuples: 3000, HeapBytes: 100, ToastBytes: 300})}, nil
+		},
+		func(ctx context.Context) ([]indexadvisor.Index, error) { return nil, nil },
+		func(ctx context.Context, statement string) error { return nil })
+
+	rr := httptest.NewRecorder()
+	NewMaintenanceHandler(job, time.Hour).HandleReport(rr, httptest.NewRequest("GET", "/api/v1/maintenance", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var report struct {
+		Scheduled bool                `json:"scheduled"`
+		Policy    map[string]any      `json:"policy"`
+		Tables    []maintenance.Table `json:"tables"`
+		Pending   []maintenance.Task  `json:"pending"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Scheduled || report.Policy["window"] != "02:00-05:00" || len(report.Tables) != 1 || report.Tables[0].ToastPercent != 75 {
+		t.Errorf("Unexpected report: %s", rr.Body)
+	}
+	if len(report.Pending) != 1 || report.Pending[0].Kind != maintenance.KindVacuum {
+		t.Errorf("Unexpected pending tasks: %s", rr.Body)
+	}
+
+	rr = httptest.NewRecorder()
+	NewMaintenanceHandler(job, time.Hour).HandleRun(rr, httptest.NewRequest("GET", "/api/v1/maintenance/run", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,631 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/approval"
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/notifier"
+	"github.com/deedubs/choochoo/internal/rules"
+	"github.com/deedubs/choochoo/internal/settings"
+)
+
+var (
+	// errNotFound is returned by updates that remove something that is not stored
+	errNotFound = errors.New("not found")
+	// errNoDatabase is returned by updates when no database is configured
+	errNoDatabase = errors.New("database not configured")
+)
+
+// invalidRouteError is returned by SaveRoute for routes that do not pass
+// validation. Its message is meant for the user.
+type invalidRouteError struct {
+	err error
+}
+
+func (e invalidRouteError) Error() string {
+	return e.err.Error()
+}
+
+// ManagementHandler serves the configuration API used by infrastructure as
+// code tools such as the Terraform provider in contrib/. Every resource has a
+// stable ID in its path, PUT creates or replaces a resource idempotently and
+// GET by ID supports importing existing resources.
+type ManagementHandler struct {
+	auth      *apitoken.Authenticator
+	dbConn    *database.Connection
+	replay    func(ctx context.Context, deliveryID string) error
+	notifiers *notifier.Set
+	settings  *settings.Bundle
+	approvals *approval.Queue
+	rules     *rules.Engine
+}
+
+// NewManagementHandler creates a new management handler. Requests need a
+// token with the admin scope, or the replay scope to replay and release
+// deliveries. The API is disabled when auth accepts no tokens.
+func NewManagementHandler(auth *apitoken.Authenticator, dbConn *database.Connection) *ManagementHandler {
+	return &ManagementHandler{auth: auth, dbConn: dbConn}
+}
+
+// WithReplay sets the function that runs stored deliveries through the
+// processing pipeline again, normally WebhookHandler.Replay
+func (mh *ManagementHandler) WithReplay(replay func(ctx context.Context, deliveryID string) error) *ManagementHandler {
+	mh.replay = replay
+	return mh
+}
+
+// WithNotifiers sets the notifiers that can be sent test notifications
+func (mh *ManagementHandler) WithNotifiers(notifiers *notifier.Set) *ManagementHandler {
+	mh.notifiers = notifiers
+	return mh
+}
+
+// WithSettings sets the settings hierarchy the server runs with, which the
+// effective settings API explains
+func (mh *ManagementHandler) WithSettings(bundle *settings.Bundle) *ManagementHandler {
+	mh.settings = bundle
+	return mh
+}
+
+// WithApprovals holds route and setting changes in queue until a second
+// operator approves them, rather than making them right away
+func (mh *ManagementHandler) WithApprovals(queue *approval.Queue) *ManagementHandler {
+	mh.approvals = queue
+	return mh
+}
+
+// WithRules sets the rules engine whose rules the rules API lists and
+// reloads after changes
+func (mh *ManagementHandler) WithRules(engine *rules.Engine) *ManagementHandler {
+	mh.rules = engine
+	return mh
+}
+
+// storedSetting is a setting as returned by the API
+type storedSetting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// authorize checks the request has a token with the admin scope, writing
+// an error response if it does not
+func (mh *ManagementHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	return mh.authorizeScope(w, r, apitoken.ScopeAdmin)
+}
+
+// authorizeScope checks the request has a token allowed to use scope,
+// writing an error response if it does not
+func (mh *ManagementHandler) authorizeScope(w http.ResponseWriter, r *http.Request, scope apitoken.Scope) bool {
+	if !mh.auth.Enabled() {
+		http.Error(w, "Management API not configured", http.StatusServiceUnavailable)
+		return false
+	}
+	return mh.auth.Authorize(w, r, scope)
+}
+
+// operator checks the request has a token with the admin scope like
+// authorize, returning the name of the token as who made the request
+func (mh *ManagementHandler) operator(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if !mh.auth.Enabled() {
+		http.Error(w, "Management API not configured", http.StatusServiceUnavailable)
+		return "", false
+	}
+	token, ok := mh.auth.AuthorizeToken(w, r, apitoken.ScopeAdmin)
+	return token.Name, ok
+}
+
+// HandleRoutes lists every stored route
+func (mh *ManagementHandler) HandleRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !mh.authorize(w, r) {
+		return
+	}
+
+	stored, ok := mh.load(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, settings.ListRoutes(stored))
+}
+
+// HandleRoute reads, creates or replaces, and deletes the route with the ID
+// {kind}/{match}. PUT takes a JSON body with the route url.
+func (mh *ManagementHandler) HandleRoute(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Only GET, PUT and DELETE methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := mh.operator(w, r)
+	if !ok {
+		return
+	}
+
+	kind, match := r.PathValue("kind"), r.PathValue("match")
+	name, ok := settings.RouteKinds[kind]
+	if !ok {
+		http.Error(w, "Unknown route kind", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		stored, ok := mh.load(w, r)
+		if !ok {
+			return
+		}
+		route, found := settings.FindRoute(stored, kind, match)
+		if !found {
+			http.Error(w, "Route not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, route)
+
+	case http.MethodPut:
+		var body struct {
+			URL string `json:"url"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+
+		pending, err := mh.SaveRoute(r.Context(), kind, match, body.URL, operator)
+		var invalid invalidRouteError
+		if errors.As(err, &invalid) {
+			http.Error(w, invalid.Error(), http.StatusBadRequest)
+			return
+		}
+		if !mh.updated(w, err, "Route not found") {
+			return
+		}
+		if pending != nil {
+			writeJSON(w, http.StatusAccepted, pending)
+			return
+		}
+		writeJSON(w, http.StatusOK, settings.StoredRoute{ID: settings.RouteID(kind, match), Kind: kind, Match: match, URL: body.URL})
+
+	case http.MethodDelete:
+		if mh.approvals != nil {
+			stored, ok := mh.load(w, r)
+			if !ok {
+				return
+			}
+			if _, found := settings.FindRoute(stored, kind, match); !found {
+				http.Error(w, "Route not found", http.StatusNotFound)
+				return
+			}
+			mh.propose(w, r, approval.Change{Setting: name, Match: match}, operator)
+			return
+		}
+		err := mh.update(r.Context(), name, func(value string) (string, error) {
+			value, found := settings.RemoveRoute(value, match)
+			if !found {
+				return "", errNotFound
+			}
+			return value, nil
+		})
+		if !mh.updated(w, err, "Route not found") {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SaveRoute creates or replaces the route kind/match on behalf of operator,
+// validating it first. It backs PUT of the routes API. When changes need
+// approval the route is not saved but queued, and the pending change is
+// returned.
+func (mh *ManagementHandler) SaveRoute(ctx context.Context, kind, match, url, operator string) (*approval.Change, error) {
+	name, ok := settings.RouteKinds[kind]
+	if !ok {
+		return nil, invalidRouteError{fmt.Errorf("unknown route kind %q", kind)}
+	}
+	if err := settings.CheckRoute(match, url); err != nil {
+		return nil, invalidRouteError{err}
+	}
+	if err := settings.Validate(map[string]string{name: match + "=" + url}); err != nil {
+		return nil, invalidRouteError{err}
+	}
+
+	if mh.approvals != nil {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		change, err := mh.approvals.Request(ctx, approval.Change{Setting: name, Match: match, Value: url}, operator)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Change %d (%s) requested by %s", change.ID, change, operator)
+		return &change, nil
+	}
+	return nil, mh.update(ctx, name, func(value string) (string, error) {
+		return settings.SetRoute(value, match, url), nil
+	})
+}
+
+// HandleSettings lists the stored policies and flags. Route lists are
+// managed through the routes API instead.
+func (mh *ManagementHandler) HandleSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !mh.authorize(w, r) {
+		return
+	}
+
+	stored, ok := mh.load(w, r)
+	if !ok {
+		return
+	}
+	list := []storedSetting{}
+	for _, name := range settings.Names() {
+		if value, ok := stored[name]; ok && !settings.IsRouteSetting(name) {
+			list = append(list, storedSetting{Name: name, Value: value})
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// HandleSetting reads, creates or replaces, and deletes the setting {name}.
+// PUT takes a JSON body with the setting value.
+func (mh *ManagementHandler) HandleSetting(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Only GET, PUT and DELETE methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := mh.operator(w, r)
+	if !ok {
+		return
+	}
+
+	name := r.PathValue("name")
+	if !managedSetting(name) {
+		http.Error(w, "Unknown setting", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		stored, ok := mh.load(w, r)
+		if !ok {
+			return
+		}
+		value, found := stored[name]
+		if !found {
+			http.Error(w, "Setting not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, storedSetting{Name: name, Value: value})
+
+	case http.MethodPut:
+		var body struct {
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if body.Value == "" {
+			http.Error(w, "Missing value", http.StatusBadRequest)
+			return
+		}
+		if err := settings.Validate(map[string]string{name: body.Value}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if mh.approvals != nil {
+			mh.propose(w, r, approval.Change{Setting: name, Value: body.Value}, operator)
+			return
+		}
+
+		err := mh.update(r.Context(), name, func(string) (string, error) { return body.Value, nil })
+		if !mh.updated(w, err, "Setting not found") {
+			return
+		}
+		writeJSON(w, http.StatusOK, storedSetting{Name: name, Value: body.Value})
+
+	case http.MethodDelete:
+		if mh.approvals != nil {
+			stored, ok := mh.load(w, r)
+			if !ok {
+				return
+			}
+			if _, found := stored[name]; !found {
+				http.Error(w, "Setting not found", http.StatusNotFound)
+				return
+			}
+			mh.propose(w, r, approval.Change{Setting: name}, operator)
+			return
+		}
+		err := mh.update(r.Context(), name, func(value string) (string, error) {
+			if value == "" {
+				return "", errNotFound
+			}
+			return "", nil
+		})
+		if !mh.updated(w, err, "Setting not found") {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// effectiveSettings is the response of the effective settings API
+type effectiveSettings struct {
+	Repository string           `json:"repository,omitempty"`
+	Branch     string           `json:"branch,omitempty"`
+	Settings   []settings.Value `json:"settings"`
+}
+
+// HandleEffectiveSettings explains the settings that apply to the repository
+// and branch given by ?repository= and ?branch=, listing for each setting
+// whether it comes from the instance or the override of an organization,
+// repository or branch. Without a repository only instance settings apply.
+func (mh *ManagementHandler) HandleEffectiveSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !mh.authorize(w, r) {
+		return
+	}
+	if mh.settings == nil {
+		http.Error(w, "Settings not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	repository := r.URL.Query().Get("repository")
+	branch := r.URL.Query().Get("branch")
+	if branch != "" && repository == "" {
+		http.Error(w, "branch requires repository", http.StatusBadRequest)
+		return
+	}
+	if repository != "" {
+		if level, err := settings.ScopeLevel(repository); err != nil || level == settings.LevelBranch {
+			http.Error(w, "Invalid repository", http.StatusBadRequest)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, effectiveSettings{
+		Repository: repository,
+		Branch:     branch,
+		Settings:   mh.settings.Explain(repository, branch),
+	})
+}
+
+// HandleReplay runs the stored delivery {delivery_id} through the processing
+// pipeline again, as if it had just arrived. Payloads that no longer match
+// their checksum are not replayed.
+func (mh *ManagementHandler) HandleReplay(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !mh.authorizeScope(w, r, apitoken.ScopeReplay) {
+		return
+	}
+	if mh.replay == nil {
+		http.Error(w, "Replay not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	deliveryID := r.PathValue("delivery_id")
+	err := mh.replay(r.Context(), deliveryID)
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"delivery_id": deliveryID, "replayed": true})
+	case errors.Is(err, ErrEventNotFound):
+		http.Error(w, "Event not found", http.StatusNotFound)
+	case errors.Is(err, errNoDatabase):
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+	case errors.Is(err, checksum.ErrMismatch):
+		log.Printf("Refused to replay delivery %s: %v", deliveryID, err)
+		http.Error(w, "Stored payload does not match its checksum", http.StatusConflict)
+	default:
+		log.Printf("Failed to replay delivery %s: %v", deliveryID, err)
+		http.Error(w, "Failed to replay event", http.StatusInternalServerError)
+	}
+}
+
+// HandleChanges lists the route and setting changes waiting for approval
+func (mh *ManagementHandler) HandleChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !mh.authorize(w, r) {
+		return
+	}
+	if mh.approvals == nil {
+		http.Error(w, "Change approval not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	changes, err := mh.approvals.List(ctx)
+	if err != nil {
+		log.Printf("Failed to list pending changes: %v", err)
+		http.Error(w, "Failed to list changes", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
+// HandleApprove makes the pending change {id}. It must be approved with a
+// different token than the one that requested it.
+func (mh *ManagementHandler) HandleApprove(w http.ResponseWriter, r *http.Request) {
+	mh.handleDecision(w, r, (*approval.Queue).Approve)
+}
+
+// HandleReject discards the pending change {id}
+func (mh *ManagementHandler) HandleReject(w http.ResponseWriter, r *http.Request) {
+	mh.handleDecision(w, r, (*approval.Queue).Reject)
+}
+
+// handleDecision approves or rejects the pending change {id} with decide on
+// behalf of the token's operator
+func (mh *ManagementHandler) handleDecision(w http.ResponseWriter, r *http.Request, decide func(*approval.Queue, context.Context, int32, string) (approval.Change, error)) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := mh.operator(w, r)
+	if !ok {
+		return
+	}
+	if mh.approvals == nil {
+		http.Error(w, "Change approval not enabled", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid change ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	change, err := decide(mh.approvals, ctx, int32(id), operator)
+	if err != nil {
+		writeDecisionError(w, int32(id), err)
+		return
+	}
+	log.Printf("Change %d (%s) %s by %s", change.ID, change, change.Status, operator)
+	writeJSON(w, http.StatusOK, change)
+}
+
+// writeDecisionError writes the error response for a failed decision
+func writeDecisionError(w http.ResponseWriter, id int32, err error) {
+	switch {
+	case errors.Is(err, approval.ErrNotFound):
+		http.Error(w, "Change not found", http.StatusNotFound)
+	case errors.Is(err, approval.ErrSelfApproval):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, approval.ErrDecided):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("Failed to decide change %d: %v", id, err)
+		http.Error(w, "Failed to decide change", http.StatusInternalServerError)
+	}
+}
+
+// propose queues a change for approval on behalf of operator, writing the
+// pending change as the response
+func (mh *ManagementHandler) propose(w http.ResponseWriter, r *http.Request, change approval.Change, operator string) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	pending, err := mh.approvals.Request(ctx, change, operator)
+	if err != nil {
+		log.Printf("Failed to queue change: %v", err)
+		http.Error(w, "Failed to queue change", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Change %d (%s) requested by %s", pending.ID, pending, operator)
+	writeJSON(w, http.StatusAccepted, pending)
+}
+
+// managedSetting reports whether name is a policy or flag the settings API manages
+func managedSetting(name string) bool {
+	if settings.IsRouteSetting(name) {
+		return false
+	}
+	for _, known := range settings.Names() {
+		if known == name {
+			return true
+		}
+	}
+	return false
+}
+
+// load reads the stored settings, writing an error response on failure
+func (mh *ManagementHandler) load(w http.ResponseWriter, r *http.Request) (map[string]string, bool) {
+	if mh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	stored, err := settings.Load(ctx, mh.dbConn.Queries())
+	if err != nil {
+		log.Printf("Failed to load settings: %v", err)
+		http.Error(w, "Failed to load settings", http.StatusInternalServerError)
+		return nil, false
+	}
+	return stored, true
+}
+
+// update replaces the stored value of one setting with the result of change,
+// holding the settings lock so concurrent updates to the same route list are
+// not lost. An empty result removes the setting.
+func (mh *ManagementHandler) update(ctx context.Context, name string, change func(value string) (string, error)) error {
+	if mh.dbConn == nil {
+		return errNoDatabase
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return mh.dbConn.InTx(ctx, func(queries *db.Queries) error {
+		if err := queries.LockInstanceSettings(ctx); err != nil {
+			return err
+		}
+		stored, err := settings.Load(ctx, queries)
+		if err != nil {
+			return err
+		}
+		value, err := change(stored[name])
+		if err != nil {
+			return err
+		}
+		return settings.Apply(ctx, queries, settings.Diff(
+			map[string]string{name: stored[name]},
+			map[string]string{name: value},
+		))
+	})
+}
+
+// updated writes an error response for a failed update and reports whether
+// the update succeeded
+func (mh *ManagementHandler) updated(w http.ResponseWriter, err error, notFound string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errNotFound):
+		http.Error(w, notFound, http.StatusNotFound)
+	case errors.Is(err, errNoDatabase):
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+	default:
+		log.Printf("Failed to update settings: %v", err)
+		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+	}
+	return false
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
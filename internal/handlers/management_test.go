@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/approval"
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/settings"
+)
+
+// managementRequest builds a management API request with path values set as
+// the server's mux would
+func managementRequest(method, target, body, token string, pathValues ...string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(pathValues); i += 2 {
+		req.SetPathValue(pathValues[i], pathValues[i+1])
+	}
+	return req
+}
+
+func TestManagementHandler_Disabled(t *testing.T) {
+	handler := NewManagementHandler(apitoken.NewAuthenticator("", nil), nil)
+
+	rr := httptest.NewRecorder()
+	handler.HandleRoutes(rr, managementRequest("GET", "/api/v1/routes", "", "secret"))
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestManagementHandler_InvalidToken(t *testing.T) {
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil)
+
+	for _, token := range []string{"", "wrong"} {
+		rr := httptest.NewRecorder()
+		handler.HandleSettings(rr, managementRequest("GET", "/api/v1/settings", "", token))
+
+		if status := rr.Code; status != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d for token %q, got %d", http.StatusUnauthorized, token, status)
+		}
+	}
+}
+
+func TestManagementHandler_InvalidMethod(t *testing.T) {
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil)
+
+	rr := httptest.NewRecorder()
+	handler.HandleRoute(rr, managementRequest("POST", "/api/v1/routes/docs/wiki", "", "secret", "kind", "docs", "match", "wiki"))
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestManagementHandler_InvalidRequests(t *testing.T) {
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil)
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		handle   http.HandlerFunc
+		expected int
+	}{
+		{"unknown route kind", managementRequest("GET", "/api/v1/routes/pagers/x", "", "secret", "kind", "pagers", "match", "x"), handler.HandleRoute, http.StatusNotFound},
+		{"invalid route body", managementRequest("PUT", "/api/v1/routes/docs/wiki", "{", "secret", "kind", "docs", "match", "wiki"), handler.HandleRoute, http.StatusBadRequest},
+		{"invalid route url", managementRequest("PUT", "/api/v1/routes/docs/wiki", `{"url": ""}`, "secret", "kind", "docs", "match", "wiki"), handler.HandleRoute, http.StatusBadRequest},
+		{"invalid route match", managementRequest("PUT", "/api/v1/routes/docs/blog", `{"url": "https://chat.example.com"}`, "secret", "kind", "docs", "match", "blog"), handler.HandleRoute, http.StatusBadRequest},
+		{"unknown setting", managementRequest("GET", "/api/v1/settings/PORT", "", "secret", "name", "PORT"), handler.HandleSetting, http.StatusNotFound},
+		{"route list setting", managementRequest("GET", "/api/v1/settings/DOCS_ROUTES", "", "secret", "name", "DOCS_ROUTES"), handler.HandleSetting, http.StatusNotFound},
+		{"invalid setting value", managementRequest("PUT", "/api/v1/settings/RETENTION_MODE", `{"value": "shred"}`, "secret", "name", "RETENTION_MODE"), handler.HandleSetting, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			test.handle(rr, test.req)
+
+			if status := rr.Code; status != test.expected {
+				t.Errorf("Expected status code %d, got %d: %s", test.expected, status, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestManagementHandler_SaveRoute(t *testing.T) {
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil)
+
+	var invalid invalidRouteError
+	if _, err := handler.SaveRoute(context.Background(), "pagers", "x", "https://chat.example.com", "terraform"); !errors.As(err, &invalid) {
+		t.Errorf("Expected an invalid route for an unknown kind, got %v", err)
+	}
+	if _, err := handler.SaveRoute(context.Background(), "security_alerts", "urgent", "https://chat.example.com", "terraform"); !errors.As(err, &invalid) {
+		t.Errorf("Expected an invalid route for an unknown severity, got %v", err)
+	}
+	if _, err := handler.SaveRoute(context.Background(), "security_alerts", "high", "https://chat.example.com", "terraform"); !errors.Is(err, errNoDatabase) {
+		t.Errorf("Expected errNoDatabase for a valid route, got %v", err)
+	}
+}
+
+func TestManagementHandler_ApprovalNotEnabled(t *testing.T) {
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil)
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		handle http.HandlerFunc
+	}{
+		{"list changes", managementRequest("GET", "/api/v1/changes", "", "secret"), handler.HandleChanges},
+		{"approve", managementRequest("POST", "/api/v1/changes/1/approve", "", "secret", "id", "1"), handler.HandleApprove},
+		{"reject", managementRequest("POST", "/api/v1/changes/1/reject", "", "secret", "id", "1"), handler.HandleReject},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			test.handle(rr, test.req)
+
+			if status := rr.Code; status != http.StatusServiceUnavailable {
+				t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+			}
+		})
+	}
+}
+
+func TestWriteDecisionError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected int
+	}{
+		{approval.ErrNotFound, http.StatusNotFound},
+		{approval.ErrSelfApproval, http.StatusForbidden},
+		{fmt.Errorf("%w: change 3 expired", approval.ErrDecided), http.StatusConflict},
+		{errors.New("connection reset"), http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		rr := httptest.NewRecorder()
+		writeDecisionError(rr, 3, test.err)
+		if rr.Code != test.expected {
+			t.Errorf("Expected status code %d for %v, got %d", test.expected, test.err, rr.Code)
+		}
+	}
+}
+
+func TestManagementHandler_NoDatabase(t *testing.T) {
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil)
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		handle http.HandlerFunc
+	}{
+		{"list routes", managementRequest("GET", "/api/v1/routes", "", "secret"), handler.HandleRoutes},
+		{"put route", managementRequest("PUT", "/api/v1/routes/docs/wiki", `{"url": "https://chat.example.com"}`, "secret", "kind", "docs", "match", "wiki"), handler.HandleRoute},
+		{"delete setting", managementRequest("DELETE", "/api/v1/settings/RETENTION_MODE", "", "secret", "name", "RETENTION_MODE"), handler.HandleSetting},
+		{"publish banner", managementRequest("POST", "/api/v1/banners", `{"message": "Maintenance"}`, "secret"), handler.HandleBanners},
+		{"withdraw banner", managementRequest("DELETE", "/api/v1/banners/1", "", "secret", "id", "1"), handler.HandleBanner},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			test.handle(rr, test.req)
+
+			if status := rr.Code; status != http.StatusServiceUnavailable {
+				t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+			}
+		})
+	}
+}
+
+func TestManagementHandler_HandleReplay(t *testing.T) {
+	var replayed []string
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil).WithReplay(func(ctx context.Context, deliveryID string) error {
+		switch deliveryID {
+		case "missing":
+			return ErrEventNotFound
+		case "tampered":
+			return fmt.Errorf("delivery %s: %w", deliveryID, checksum.ErrMismatch)
+		}
+		replayed = append(replayed, deliveryID)
+		return nil
+	})
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		expected int
+	}{
+		{"replayed", managementRequest("POST", "/api/events/abc/replay", "", "secret", "delivery_id", "abc"), http.StatusOK},
+		{"not found", managementRequest("POST", "/api/events/missing/replay", "", "secret", "delivery_id", "missing"), http.StatusNotFound},
+		{"checksum mismatch", managementRequest("POST", "/api/events/tampered/replay", "", "secret", "delivery_id", "tampered"), http.StatusConflict},
+		{"invalid token", managementRequest("POST", "/api/events/abc/replay", "", "wrong", "delivery_id", "abc"), http.StatusUnauthorized},
+		{"invalid method", managementRequest("GET", "/api/events/abc/replay", "", "secret", "delivery_id", "abc"), http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.HandleReplay(rr, test.req)
+
+			if status := rr.Code; status != test.expected {
+				t.Errorf("Expected status code %d, got %d: %s", test.expected, status, rr.Body.String())
+			}
+		})
+	}
+
+	if len(replayed) != 1 || replayed[0] != "abc" {
+		t.Errorf("Expected delivery abc to be replayed once, got %v", replayed)
+	}
+}
+
+func TestManagementHandler_HandleReplay_NoDatabase(t *testing.T) {
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil).WithReplay(NewWebhookHandler("", nil).Replay)
+
+	rr := httptest.NewRecorder()
+	handler.HandleReplay(rr, managementRequest("POST", "/api/events/abc/replay", "", "secret", "delivery_id", "abc"))
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestManagementHandler_HandleEffectiveSettings(t *testing.T) {
+	bundle, err := settings.ParseBundle([]byte(`
+version: 1
+policies:
+  retention_mode: delete
+overrides:
+  acme/api:
+    policies:
+      retention_mode: archive
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil).WithSettings(bundle)
+
+	tests := []struct {
+		name     string
+		target   string
+		expected int
+		level    string
+	}{
+		{"instance", "/api/v1/settings/effective", http.StatusOK, settings.LevelInstance},
+		{"repository", "/api/v1/settings/effective?repository=acme/api&branch=main", http.StatusOK, settings.LevelRepository},
+		{"branch without repository", "/api/v1/settings/effective?branch=main", http.StatusBadRequest, ""},
+		{"invalid repository", "/api/v1/settings/effective?repository=acme/api@main", http.StatusBadRequest, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.HandleEffectiveSettings(rr, managementRequest("GET", test.target, "", "secret"))
+
+			if status := rr.Code; status != test.expected {
+				t.Fatalf("Expected status code %d, got %d: %s", test.expected, status, rr.Body.String())
+			}
+			if test.level == "" {
+				return
+			}
+			var response effectiveSettings
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Settings) != 1 || response.Settings[0].Level != test.level {
+				t.Errorf("Expected RETENTION_MODE from the %s level, got %+v", test.level, response.Settings)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/notifier"
+)
+
+// notifierTest is the outcome of a test notification as returned by the API
+type notifierTest struct {
+	Notifier string            `json:"notifier"`
+	OK       bool              `json:"ok"`
+	Results  []notifier.Result `json:"results"`
+}
+
+// HandleNotifierTest sends a test notification through every channel of the
+// notifier {name}, one of the route lists such as security_alerts, or only
+// the channel of the route selected by the match query parameter. Failed
+// deliveries are reported with 502 and the transport error of each channel.
+func (mh *ManagementHandler) HandleNotifierTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !mh.authorize(w, r) {
+		return
+	}
+
+	if mh.notifiers == nil {
+		http.Error(w, "Notifier not found", http.StatusNotFound)
+		return
+	}
+	name := r.PathValue("name")
+
+	// Each channel has its own timeout, so allow for a few slow ones
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	results, err := mh.notifiers.Test(ctx, name, r.URL.Query().Get("match"))
+	if errors.Is(err, notifier.ErrUnknown) {
+		http.Error(w, "Notifier not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to test notifier %s: %v", name, err)
+		http.Error(w, "Failed to test notifier", http.StatusInternalServerError)
+		return
+	}
+
+	test := notifierTest{Notifier: name, OK: true, Results: results}
+	for _, result := range results {
+		if !result.OK {
+			test.OK = false
+			log.Printf("Test notification for %s to %s failed: %s", name, result.Channel, result.Error)
+		}
+	}
+	status := http.StatusOK
+	if !test.OK {
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, test)
+}
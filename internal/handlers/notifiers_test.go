@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/notifier"
+)
+
+func TestManagementHandler_NotifierTest(t *testing.T) {
+	var events []string
+	channel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events = append(events, r.Header.Get("X-GitHub-Event"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer channel.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer broken.Close()
+
+	notifiers := notifier.NewSet()
+	working, _ := forwarder.NewHTTPForwarder(channel.URL)
+	failing, _ := forwarder.NewHTTPForwarder(broken.URL)
+	notifiers.Add("security_alerts", "high", working)
+	notifiers.Add("docs", "wiki", working)
+	notifiers.Add("docs", "pages", failing)
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil).WithNotifiers(notifiers)
+
+	tests := []struct {
+		name     string
+		target   string
+		notifier string
+		expected int
+		results  int
+	}{
+		{"delivered", "/api/v1/notifiers/security_alerts/test", "security_alerts", http.StatusOK, 1},
+		{"transport error", "/api/v1/notifiers/docs/test", "docs", http.StatusBadGateway, 2},
+		{"single route", "/api/v1/notifiers/docs/test?match=wiki", "docs", http.StatusOK, 1},
+		{"unknown route", "/api/v1/notifiers/docs/test?match=other", "docs", http.StatusNotFound, 0},
+		{"unknown notifier", "/api/v1/notifiers/discussions/test", "discussions", http.StatusNotFound, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.HandleNotifierTest(rr, managementRequest("POST", test.target, "", "secret", "name", test.notifier))
+
+			if rr.Code != test.expected {
+				t.Fatalf("Expected status code %d, got %d: %s", test.expected, rr.Code, rr.Body.String())
+			}
+			if test.results == 0 {
+				return
+			}
+			var response notifierTest
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Results) != test.results || response.OK != (test.expected == http.StatusOK) {
+				t.Errorf("Unexpected response %+v", response)
+			}
+		})
+	}
+
+	if len(events) == 0 || events[0] != notifier.TestEvent {
+		t.Errorf("Expected %s events, got %v", notifier.TestEvent, events)
+	}
+}
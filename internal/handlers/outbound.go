@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/deedubs/choochoo/internal/outbound"
+)
+
+// OutboundHandler serves the log of recent outbound requests
+type OutboundHandler struct {
+	log *outbound.Log
+}
+
+// NewOutboundHandler creates a new outbound request log handler. The log is
+// nil when it is disabled.
+func NewOutboundHandler(log *outbound.Log) *OutboundHandler {
+	return &OutboundHandler{log: log}
+}
+
+// HandleRequests lists the recent requests to every target host, most recent
+// first, or only those to the host given by the target query parameter
+func (oh *OutboundHandler) HandleRequests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if oh.log == nil {
+		http.Error(w, "Outbound request log disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	targets := oh.log.Targets()
+	if target := r.URL.Query().Get("target"); target != "" {
+		targets = []string{target}
+	}
+	requests := make(map[string][]outbound.Request, len(targets))
+	for _, target := range targets {
+		requests[target] = oh.log.Requests(target)
+	}
+	writeJSON(w, http.StatusOK, requests)
+}
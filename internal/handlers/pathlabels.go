@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// processPathLabels adds the labels of the paths a pull request changes
+func (wh *WebhookHandler) processPathLabels(ctx context.Context, deliveryID, repoName string, body []byte) error {
+	labels, err := wh.pathLabels.Label(ctx, body)
+	if err != nil {
+		return err
+	}
+	if len(labels) > 0 {
+		log.Printf("Labeled pull request of %s with %s (delivery: %s)", repoName, strings.Join(labels, ", "), deliveryID)
+	}
+	return nil
+}
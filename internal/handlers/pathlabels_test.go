@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/github"
+	"github.com/deedubs/choochoo/internal/pathlabels"
+)
+
+func TestWebhookHandler_ProcessPathLabels(t *testing.T) {
+	var labels []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/api/pulls/7/files":
+			w.Write([]byte(`[{"filename": "web/app.ts"}, {"filename": "docs/intro.md"}]`))
+		case "/repos/acme/api/issues/7/labels":
+			var body struct {
+				Labels []string `json:"labels"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			labels = body.Labels
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	rules := []pathlabels.Rule{{Label: "docs", Pattern: "docs/**"}, {Label: "frontend", Pattern: "web/**"}, {Label: "deps", Pattern: "go.sum"}}
+	handler := NewWebhookHandler("", nil).WithPathLabels(pathlabels.New(rules, github.NewTokenClient(server.URL, "token")))
+	body := []byte(`{"action": "opened", "pull_request": {"number": 7}, "repository": {"full_name": "acme/api"}}`)
+
+	if err := handler.process(context.Background(), "pull_request", "delivery", "opened", "acme/api", "octocat", body, false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(labels, ",") != "docs,frontend" {
+		t.Errorf("Unexpected labels: %v", labels)
+	}
+
+	run := handler.dryRun("pull_request", "delivery", http.Header{}, body)
+	found := false
+	for _, step := range run.Trace {
+		found = found || step.Step == "processor path_labels"
+	}
+	if !found {
+		t.Errorf("Dry run does not trace the path labels: %+v", run.Trace)
+	}
+
+	// Pull requests whose files are not fetched fail the step
+	other := []byte(`{"action": "synchronize", "pull_request": {"number": 8}, "repository": {"full_name": "acme/api"}}`)
+	if err := handler.process(context.Background(), "pull_request", "delivery", "synchronize", "acme/api", "octocat", other, false); err == nil {
+		t.Error("Expected the path_labels step to fail")
+	}
+}
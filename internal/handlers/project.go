@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// ProjectHandler serves reports over Projects (v2) item movement
+type ProjectHandler struct {
+	dbConn *database.Connection
+}
+
+// NewProjectHandler creates a new project handler
+func NewProjectHandler(dbConn *database.Connection) *ProjectHandler {
+	return &ProjectHandler{dbConn: dbConn}
+}
+
+// columnCycleTime is the time items spend in a project column as returned by the API
+type columnCycleTime struct {
+	Column        string  `json:"column"`
+	Items         int64   `json:"items"`
+	Transitions   int64   `json:"transitions"`
+	AvgSeconds    float64 `json:"avg_seconds"`
+	MedianSeconds float64 `json:"median_seconds"`
+}
+
+// HandleCycleTime reports how long items spend in each column of a project.
+// Query parameters: project (required project node ID), field (defaults to Status).
+func (ph *ProjectHandler) HandleCycleTime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	projectID := query.Get("project")
+	if projectID == "" {
+		http.Error(w, "Missing project parameter", http.StatusBadRequest)
+		return
+	}
+	field := query.Get("field")
+	if field == "" {
+		field = "Status"
+	}
+
+	if ph.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := ph.dbConn.Queries().ListProjectColumnCycleTimes(ctx, db.ListProjectColumnCycleTimesParams{
+		ProjectNodeID: projectID,
+		FieldName:     field,
+	})
+	if err != nil {
+		log.Printf("Failed to query project cycle times: %v", err)
+		http.Error(w, "Failed to query project cycle times", http.StatusInternalServerError)
+		return
+	}
+
+	columns := make([]columnCycleTime, 0, len(rows))
+	for _, row := range rows {
+		columns = append(columns, columnCycleTime{
+			Column:        row.ColumnName,
+			Items:         row.Items,
+			Transitions:   row.Transitions,
+			AvgSeconds:    row.AvgSeconds,
+			MedianSeconds: row.MedianSeconds,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"project": projectID,
+		"field":   field,
+		"columns": columns,
+	})
+}
+
+// processProjectItem records a project item moving between columns or
+// leaving the board, and notifies the channels routed for the column it entered
+func (wh *WebhookHandler) processProjectItem(ctx context.Context, deliveryID, senderLogin string, body []byte) error {
+	change, err := webhook.ParseProjectItemChange(body)
+	if err != nil {
+		return fmt.Errorf("failed to parse project item change: %w", err)
+	}
+	if change == nil {
+		return nil
+	}
+
+	if change.To != "" {
+		log.Printf("Project item %s moved from %q to %q by %s", change.ItemNodeID, change.From, change.To, senderLogin)
+	}
+	if wh.projectRouter != nil {
+		wh.projectRouter.Route(ctx, deliveryID, change)
+	}
+
+	if wh.dbConn == nil {
+		return nil
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err = wh.dbConn.Queries().InsertProjectItemMove(dbCtx, db.InsertProjectItemMoveParams{
+		DeliveryID:    deliveryID,
+		Organization:  change.Organization,
+		ProjectNodeID: change.ProjectNodeID,
+		ItemNodeID:    change.ItemNodeID,
+		ContentNodeID: optionalText(change.ContentNodeID),
+		ContentType:   optionalText(change.ContentType),
+		Action:        change.Action,
+		FieldName:     optionalText(change.FieldName),
+		FromColumn:    optionalText(change.From),
+		ToColumn:      optionalText(change.To),
+		Actor:         senderLogin,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store project item change: %w", err)
+	}
+	return nil
+}
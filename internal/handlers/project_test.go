@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/project"
+)
+
+func TestProjectHandler_HandleCycleTime_InvalidMethod(t *testing.T) {
+	handler := NewProjectHandler(nil)
+
+	req := httptest.NewRequest("POST", "/api/projects/cycle-time?project=PVT_1", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleCycleTime(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestProjectHandler_HandleCycleTime_MissingProject(t *testing.T) {
+	handler := NewProjectHandler(nil)
+
+	req := httptest.NewRequest("GET", "/api/projects/cycle-time", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleCycleTime(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestProjectHandler_HandleCycleTime_NoDatabase(t *testing.T) {
+	handler := NewProjectHandler(nil)
+
+	req := httptest.NewRequest("GET", "/api/projects/cycle-time?project=PVT_1", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleCycleTime(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_ProjectColumnNotification(t *testing.T) {
+	notifications := make(chan string, 1)
+	channel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notifications <- r.Header.Get("X-GitHub-Event")
+	}))
+	defer channel.Close()
+
+	routes, err := project.ParseRoutes("Blocked=" + channel.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+	handler := NewWebhookHandler("", nil).WithProjectRouter(project.NewRouter(routes))
+
+	payload := `{"action":"edited","projects_v2_item":{"node_id":"PVTI_1","project_node_id":"PVT_1"},"changes":{"field_value":{"field_name":"Status","field_type":"single_select","from":{"name":"Todo"},"to":{"name":"Blocked"}}},"organization":{"login":"test"},"sender":{"login":"testuser"}}`
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+	req.Header.Set("X-GitHub-Event", "projects_v2_item")
+	req.Header.Set("X-GitHub-Delivery", "test-delivery-id")
+	rr := httptest.NewRecorder()
+
+	handler.HandleWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	select {
+	case eventType := <-notifications:
+		if eventType != "projects_v2_item" {
+			t.Errorf("Expected projects_v2_item notification, got %s", eventType)
+		}
+	default:
+		t.Error("Expected the item entering Blocked to be routed to the channel")
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/webhook"
+	"github.com/jackc/pgx/v5"
+)
+
+// ProtectionHandler serves the branch protection compliance trail
+type ProtectionHandler struct {
+	dbConn *database.Connection
+}
+
+// NewProtectionHandler creates a new protection handler
+func NewProtectionHandler(dbConn *database.Connection) *ProtectionHandler {
+	return &ProtectionHandler{dbConn: dbConn}
+}
+
+// protectionHistoryEntry is a branch protection history row as returned by the API
+type protectionHistoryEntry struct {
+	DeliveryID      string                   `json:"delivery_id"`
+	Repository      string                   `json:"repository"`
+	Source          string                   `json:"source"`
+	RuleID          int64                    `json:"rule_id"`
+	Pattern         string                   `json:"pattern"`
+	Action          string                   `json:"action"`
+	Config          webhook.ProtectionConfig `json:"config"`
+	Weakened        bool                     `json:"weakened"`
+	WeakenedReasons []string                 `json:"weakened_reasons"`
+	Sender          string                   `json:"sender,omitempty"`
+	CreatedAt       time.Time                `json:"created_at"`
+}
+
+// HandleHistory lists protection changes for a repository, newest first.
+// Query parameters: repository (required), branch, weakened=true, limit.
+func (ph *ProtectionHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	repository := query.Get("repository")
+	if repository == "" {
+		http.Error(w, "Missing repository parameter", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 500 {
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	if ph.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := ph.dbConn.Queries().ListBranchProtectionHistory(ctx, db.ListBranchProtectionHistoryParams{
+		RepositoryName: repository,
+		Pattern:        query.Get("branch"),
+		WeakenedOnly:   query.Get("weakened") == "true",
+		RowLimit:       int32(limit),
+	})
+	if err != nil {
+		log.Printf("Failed to list branch protection history: %v", err)
+		http.Error(w, "Failed to load branch protection history", http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]protectionHistoryEntry, 0, len(rows))
+	for _, row := range rows {
+		entry := protectionHistoryEntry{
+			DeliveryID:      row.DeliveryID,
+			Repository:      row.RepositoryName,
+			Source:          row.Source,
+			RuleID:          row.RuleID,
+			Pattern:         row.Pattern,
+			Action:          row.Action,
+			Weakened:        row.Weakened,
+			WeakenedReasons: row.WeakenedReasons,
+			Sender:          row.SenderLogin.String,
+			CreatedAt:       row.CreatedAt.Time,
+		}
+		json.Unmarshal(row.Config, &entry.Config)
+		entries = append(entries, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"repository": repository,
+		"history":    entries,
+	})
+}
+
+// processProtectionChange records a branch protection change in the history
+// and raises an alert when protections are weakened
+func (wh *WebhookHandler) processProtectionChange(ctx context.Context, eventType, deliveryID, senderLogin string, body []byte) error {
+	change, err := webhook.ParseProtectionChange(eventType, body)
+	if err != nil {
+		return fmt.Errorf("failed to parse branch protection change: %w", err)
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Prefer the previous values included in the event and fall back to the
+	// last recorded configuration for this rule
+	previous := change.Previous
+	if previous == nil && wh.dbConn != nil {
+		latest, err := wh.dbConn.Queries().GetLatestBranchProtection(dbCtx, db.GetLatestBranchProtectionParams{
+			RepositoryName: change.Repository,
+			Source:         change.Source,
+			RuleID:         change.RuleID,
+		})
+		if err == nil {
+			var cfg webhook.ProtectionConfig
+			if json.Unmarshal(latest.Config, &cfg) == nil {
+				previous = &cfg
+			}
+		} else if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Failed to load previous branch protection (delivery: %s): %v", deliveryID, err)
+		}
+	}
+
+	var reasons []string
+	switch {
+	case previous != nil:
+		reasons = webhook.Weakenings(*previous, change.Config)
+	case change.Action == "deleted":
+		reasons = []string{"protection rule deleted"}
+	}
+
+	if len(reasons) > 0 {
+		log.Printf("ALERT: branch protection weakened on %s (%s) by %s: %s", change.Repository, change.Pattern, senderLogin, strings.Join(reasons, "; "))
+		if wh.securityRouter != nil {
+			payload, _ := json.Marshal(map[string]interface{}{
+				"change":  change,
+				"reasons": reasons,
+				"sender":  senderLogin,
+			})
+			wh.securityRouter.RouteEvent(ctx, webhook.SeverityHigh, forwarder.Event{
+				DeliveryID: deliveryID,
+				EventType:  eventType,
+				Action:     change.Action,
+				Repository: change.Repository,
+				Sender:     senderLogin,
+				Payload:    payload,
+			})
+		}
+	}
+
+	if wh.dbConn == nil {
+		return nil
+	}
+
+	config, err := json.Marshal(change.Config)
+	if err != nil {
+		return fmt.Errorf("failed to encode branch protection config: %w", err)
+	}
+	if reasons == nil {
+		reasons = []string{}
+	}
+	_, err = wh.dbConn.Queries().InsertBranchProtectionChange(dbCtx, db.InsertBranchProtectionChangeParams{
+		DeliveryID:      deliveryID,
+		RepositoryName:  change.Repository,
+		Source:          change.Source,
+		RuleID:          change.RuleID,
+		Pattern:         change.Pattern,
+		Action:          change.Action,
+		Config:          config,
+		Weakened:        len(reasons) > 0,
+		WeakenedReasons: reasons,
+		SenderLogin:     optionalText(senderLogin),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store branch protection change: %w", err)
+	}
+	return nil
+}
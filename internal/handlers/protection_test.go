@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/security"
+)
+
+func TestProtectionHandler_HandleHistory_InvalidMethod(t *testing.T) {
+	handler := NewProtectionHandler(nil)
+
+	req := httptest.NewRequest("POST", "/api/protection/history?repository=a/b", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleHistory(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestProtectionHandler_HandleHistory_InvalidParameters(t *testing.T) {
+	handler := NewProtectionHandler(nil)
+
+	for _, target := range []string{"/api/protection/history", "/api/protection/history?repository=a/b&limit=0"} {
+		req := httptest.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+
+		handler.HandleHistory(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusBadRequest, status)
+		}
+	}
+}
+
+func TestProtectionHandler_HandleHistory_NoDatabase(t *testing.T) {
+	handler := NewProtectionHandler(nil)
+
+	req := httptest.NewRequest("GET", "/api/protection/history?repository=a/b", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleHistory(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_ProtectionWeakenedAlert(t *testing.T) {
+	alerts := make(chan string, 1)
+	channel := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alerts <- r.Header.Get("X-GitHub-Event")
+	}))
+	defer channel.Close()
+
+	routes, err := security.ParseRoutes("high=" + channel.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse routes: %v", err)
+	}
+	handler := NewWebhookHandler("", nil).WithSecurityRouter(security.NewRouter(routes))
+
+	payload := `{"action":"deleted","rule":{"id":1,"name":"main"},"repository":{"full_name":"test/repo"},"sender":{"login":"testuser"}}`
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+	req.Header.Set("X-GitHub-Event", "branch_protection_rule")
+	req.Header.Set("X-GitHub-Delivery", "test-delivery-id")
+	rr := httptest.NewRecorder()
+
+	handler.HandleWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	select {
+	case eventType := <-alerts:
+		if eventType != "branch_protection_rule" {
+			t.Errorf("Expected branch_protection_rule alert, got %s", eventType)
+		}
+	default:
+		t.Error("Expected weakened protection to be routed to the security channel")
+	}
+}
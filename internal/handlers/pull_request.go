@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/webhook"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// processPullRequest keeps the pull_requests table in sync with the latest
+// state of each pull request
+func (wh *WebhookHandler) processPullRequest(ctx context.Context, deliveryID string, body []byte) error {
+	if wh.dbConn == nil {
+		return nil
+	}
+
+	activity, err := webhook.ParsePullRequestActivity(body)
+	if err != nil {
+		return fmt.Errorf("failed to parse pull request: %w", err)
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	pr := activity.PullRequest
+	err = wh.dbConn.Queries().UpsertPullRequest(dbCtx, db.UpsertPullRequestParams{
+		RepositoryName: activity.Repository,
+		PrNumber:       int32(pr.Number),
+		Title:          pr.Title,
+		State:          pr.State,
+		Draft:          pr.Draft,
+		Merged:         pr.Merged,
+		HeadRef:        pr.Head.Ref,
+		BaseRef:        pr.Base.Ref,
+		AuthorLogin:    optionalText(pr.User.Login),
+		HtmlUrl:        optionalText(pr.HTMLURL),
+		LastAction:     activity.Action,
+		CreatedAt:      timestampOrNow(pr.CreatedAt),
+		UpdatedAt:      timestampOrNow(pr.UpdatedAt),
+		ClosedAt:       optionalTimestamp(pr.ClosedAt),
+		MergedAt:       optionalTimestamp(pr.MergedAt),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store pull request: %w", err)
+	}
+	return nil
+}
+
+// optionalTimestamp converts a nullable payload timestamp
+func optionalTimestamp(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
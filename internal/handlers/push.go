@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// processPush normalizes the commits in a push event into the commits table
+func (wh *WebhookHandler) processPush(ctx context.Context, deliveryID string, body []byte) error {
+	if wh.dbConn == nil {
+		return nil
+	}
+
+	push, err := webhook.ParsePush(body)
+	if err != nil {
+		return fmt.Errorf("failed to parse push event: %w", err)
+	}
+
+	if err := storeCommits(ctx, wh.dbConn, deliveryID, push); err != nil {
+		return fmt.Errorf("failed to store commits: %w", err)
+	}
+	return nil
+}
+
+// storeCommits inserts every commit in a push, skipping commits already
+// recorded for the same repository and ref
+func storeCommits(ctx context.Context, dbConn *database.Connection, deliveryID string, push *webhook.Push) error {
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	for _, commit := range push.Commits {
+		err := dbConn.Queries().InsertCommit(dbCtx, db.InsertCommitParams{
+			Sha:            commit.ID,
+			RepositoryName: push.Repository,
+			Ref:            push.Ref,
+			AuthorName:     optionalText(commit.Author.Name),
+			AuthorEmail:    optionalText(commit.Author.Email),
+			AuthorLogin:    optionalText(commit.Author.Username),
+			Message:        commit.Message,
+			CommittedAt:    timestampOrNow(commit.Timestamp),
+			DeliveryID:     deliveryID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+)
+
+// quarantinedEvent is a quarantined work item as returned by the API
+type quarantinedEvent struct {
+	DeliveryID      string     `json:"delivery_id"`
+	EventType       string     `json:"event_type"`
+	Repository      string     `json:"repository,omitempty"`
+	FailedProcessor string     `json:"failed_processor,omitempty"`
+	LastError       string     `json:"last_error"`
+	Attempts        int        `json:"attempts"`
+	QuarantinedAt   *time.Time `json:"quarantined_at"`
+}
+
+// HandleQuarantine lists the events the work queue stopped retrying after
+// they failed too many times
+func (mh *ManagementHandler) HandleQuarantine(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !mh.authorize(w, r) {
+		return
+	}
+	if mh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	rows, err := mh.dbConn.Queries().ListQuarantinedWorkItems(ctx)
+	if err != nil {
+		log.Printf("Failed to list quarantined events: %v", err)
+		http.Error(w, "Failed to list quarantined events", http.StatusInternalServerError)
+		return
+	}
+
+	events := make([]quarantinedEvent, 0, len(rows))
+	for _, row := range rows {
+		event := quarantinedEvent{
+			DeliveryID:      row.DeliveryID,
+			EventType:       row.EventType,
+			Repository:      row.RepositoryName.String,
+			FailedProcessor: row.FailedProcessor.String,
+			LastError:       row.LastError.String,
+			Attempts:        int(row.Attempts),
+		}
+		if row.QuarantinedAt.Valid {
+			event.QuarantinedAt = &row.QuarantinedAt.Time
+		}
+		events = append(events, event)
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// HandleRelease returns the quarantined delivery {delivery_id} to the work
+// queue with its attempts reset, normally after the failing processor was
+// fixed
+func (mh *ManagementHandler) HandleRelease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !mh.authorizeScope(w, r, apitoken.ScopeReplay) {
+		return
+	}
+	if mh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	deliveryID := r.PathValue("delivery_id")
+	released, err := mh.dbConn.Queries().ReleaseWorkItem(ctx, deliveryID)
+	if err != nil {
+		log.Printf("Failed to release delivery %s: %v", deliveryID, err)
+		http.Error(w, "Failed to release event", http.StatusInternalServerError)
+		return
+	}
+	if released == 0 {
+		http.Error(w, "Event not quarantined", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("Released quarantined delivery %s", deliveryID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"delivery_id": deliveryID, "released": true})
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+)
+
+func TestManagementHandler_Quarantine(t *testing.T) {
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil)
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		handle   http.HandlerFunc
+		expected int
+	}{
+		{"list without database", managementRequest("GET", "/api/v1/quarantine", "", "secret"), handler.HandleQuarantine, http.StatusServiceUnavailable},
+		{"list invalid token", managementRequest("GET", "/api/v1/quarantine", "", "wrong"), handler.HandleQuarantine, http.StatusUnauthorized},
+		{"list invalid method", managementRequest("POST", "/api/v1/quarantine", "", "secret"), handler.HandleQuarantine, http.StatusMethodNotAllowed},
+		{"release without database", managementRequest("POST", "/api/v1/quarantine/abc/release", "", "secret", "delivery_id", "abc"), handler.HandleRelease, http.StatusServiceUnavailable},
+		{"release invalid token", managementRequest("POST", "/api/v1/quarantine/abc/release", "", "", "delivery_id", "abc"), handler.HandleRelease, http.StatusUnauthorized},
+		{"release invalid method", managementRequest("GET", "/api/v1/quarantine/abc/release", "", "secret", "delivery_id", "abc"), handler.HandleRelease, http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			test.handle(rr, test.req)
+
+			if status := rr.Code; status != test.expected {
+				t.Errorf("Expected status code %d, got %d", test.expected, status)
+			}
+		})
+	}
+}
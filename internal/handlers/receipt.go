@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"context"
+	"log"
+)
+
+// postReceipt posts the processing receipt of an event that was processed
+// without errors. Receipts are a courtesy to repository members, so a failed
+// receipt is logged rather than failing the event, which would process it
+// again.
+func (wh *WebhookHandler) postReceipt(ctx context.Context, eventType, deliveryID, repoName string, body []byte) {
+	posted, err := wh.receipts.Post(ctx, eventType, deliveryID, body)
+	if err != nil {
+		log.Printf("Failed to post receipt to %s (delivery: %s): %v", repoName, deliveryID, err)
+		return
+	}
+	if posted {
+		log.Printf("Posted receipt to %s (delivery: %s)", repoName, deliveryID)
+	}
+}
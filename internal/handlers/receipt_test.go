@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/github"
+	"github.com/deedubs/choochoo/internal/receipt"
+)
+
+func TestWebhookHandler_ProcessPostsReceipts(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	poster := receipt.New([]string{"deployment"}, receipt.KindComment, receipt.DefaultRateLimit, github.NewTokenClient(server.URL, "token"))
+	handler := NewWebhookHandler("", nil).WithReceipts(poster)
+	body := []byte(`{"action": "created", "deployment": {"sha": "abc123"}, "repository": {"full_name": "acme/api"}}`)
+
+	if err := handler.process(context.Background(), "deployment", "delivery", "created", "acme/api", "octocat", body, false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(paths) != 1 || paths[0] != "/repos/acme/api/commits/abc123/comments" {
+		t.Errorf("Expected a commit comment, got requests to %v", paths)
+	}
+
+	// Receipt failures do not fail the event
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	body = []byte(`{"action": "created", "deployment": {"sha": "def456"}, "repository": {"full_name": "acme/api"}}`)
+	if err := handler.process(context.Background(), "deployment", "delivery", "created", "acme/api", "octocat", body, false); err != nil {
+		t.Errorf("Expected no error from a failed receipt, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/webhook"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// processRelease keeps the releases table in sync with the latest state of
+// each release
+func (wh *WebhookHandler) processRelease(ctx context.Context, body []byte) error {
+	if wh.dbConn == nil {
+		return nil
+	}
+
+	change, err := webhook.ParseRelease(body)
+	if err != nil {
+		return fmt.Errorf("failed to parse release: %w", err)
+	}
+
+	var deletedAt pgtype.Timestamptz
+	if change.Deleted() {
+		deletedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	release := change.Release
+	err = wh.dbConn.Queries().UpsertRelease(dbCtx, db.UpsertReleaseParams{
+		ReleaseID:       release.ID,
+		RepositoryName:  change.Repository,
+		TagName:         release.TagName,
+		TargetCommitish: optionalText(release.TargetCommitish),
+		Name:            optionalText(release.Name),
+		Body:            optionalText(release.Body),
+		Draft:           release.Draft,
+		Prerelease:      release.Prerelease,
+		AuthorLogin:     optionalText(release.Author.Login),
+		HtmlUrl:         optionalText(release.HTMLURL),
+		LastAction:      change.Action,
+		CreatedAt:       optionalTimestamp(release.CreatedAt),
+		PublishedAt:     optionalTimestamp(release.PublishedAt),
+		DeletedAt:       deletedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store release: %w", err)
+	}
+	return nil
+}
+
+// processRefChange records a branch or tag being created or deleted in the
+// refs table
+func (wh *WebhookHandler) processRefChange(ctx context.Context, eventType, deliveryID string, body []byte) error {
+	if wh.dbConn == nil {
+		return nil
+	}
+
+	change, err := webhook.ParseRefChange(eventType, body)
+	if err != nil {
+		return fmt.Errorf("failed to parse ref change: %w", err)
+	}
+
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err = wh.dbConn.Queries().InsertRefChange(dbCtx, db.InsertRefChangeParams{
+		DeliveryID:     deliveryID,
+		RepositoryName: change.Repository,
+		RefType:        change.RefType,
+		Ref:            change.Ref,
+		Action:         change.Action,
+		PusherType:     optionalText(change.PusherType),
+		SenderLogin:    optionalText(change.Sender),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store ref change: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/indexadvisor"
+	"github.com/deedubs/choochoo/internal/repohealth"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RepoHealthHandler serves per-repository delivery health scores
+type RepoHealthHandler struct {
+	dbConn  *database.Connection
+	targets repohealth.Targets
+	advisor *indexadvisor.Advisor
+}
+
+// NewRepoHealthHandler creates a new repository health handler
+func NewRepoHealthHandler(dbConn *database.Connection, targets repohealth.Targets) *RepoHealthHandler {
+	return &RepoHealthHandler{dbConn: dbConn, targets: targets}
+}
+
+// WithIndexAdvisor makes the handler report the queries it makes on the
+// events table to advisor
+func (rh *RepoHealthHandler) WithIndexAdvisor(advisor *indexadvisor.Advisor) *RepoHealthHandler {
+	rh.advisor = advisor
+	return rh
+}
+
+// Patterns of the health signal queries on the events table
+var (
+	latencyPattern = indexadvisor.Pattern{
+		Name:       "repository health latency",
+		Conditions: []string{"event_type = 'pull_request'", "repository_name IS NOT NULL"},
+		Range:      "created_at",
+	}
+	ciOutcomesPattern = indexadvisor.Pattern{
+		Name:       "repository health CI outcomes",
+		Conditions: []string{"event_type = 'workflow_run'", "action = 'completed'", "repository_name IS NOT NULL"},
+		Range:      "created_at",
+	}
+	deployOutcomesPattern = indexadvisor.Pattern{
+		Name:       "repository health deploy outcomes",
+		Conditions: []string{"event_type IN ('deployment_status', 'page_build')", "repository_name IS NOT NULL"},
+		Range:      "created_at",
+	}
+)
+
+// HandleScores reports the delivery health of every repository, least
+// healthy first. Query parameters: days (default 30) and format=html for a
+// heatmap.
+func (rh *RepoHealthHandler) HandleScores(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	days := 30
+	if value := query.Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid days parameter", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "html" {
+		http.Error(w, "Invalid format parameter", http.StatusBadRequest)
+		return
+	}
+
+	if rh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	signals, err := rh.loadSignals(ctx, time.Now().UTC().AddDate(0, 0, -days))
+	if err != nil {
+		log.Printf("Failed to load repository health signals: %v", err)
+		http.Error(w, "Failed to load repository health", http.StatusInternalServerError)
+		return
+	}
+	reports := repohealth.Build(signals, rh.targets)
+
+	if format == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if err := repohealth.WriteHeatmap(w, reports, days); err != nil {
+			log.Printf("Failed to render repository health heatmap: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":         days,
+		"repositories": reports,
+	})
+}
+
+// loadSignals queries every health signal since a point in time
+func (rh *RepoHealthHandler) loadSignals(ctx context.Context, since time.Time) (repohealth.Signals, error) {
+	queries := rh.dbConn.Queries()
+	sinceTS := pgtype.Timestamptz{Time: since, Valid: true}
+
+	var signals repohealth.Signals
+	var err error
+	observe := rh.advisor.Time(latencyPattern)
+	if signals.Latency, err = queries.ListWebhookLatencyByRepository(ctx, sinceTS); err != nil {
+		return signals, err
+	}
+	observe()
+	observe = rh.advisor.Time(ciOutcomesPattern)
+	if signals.CI, err = queries.ListCIOutcomesByRepository(ctx, sinceTS); err != nil {
+		return signals, err
+	}
+	observe()
+	if signals.MergeWait, err = queries.ListMergeWaitByRepository(ctx, sinceTS); err != nil {
+		return signals, err
+	}
+	observe = rh.advisor.Time(deployOutcomesPattern)
+	if signals.Deploys, err = queries.ListDeployOutcomesByRepository(ctx, sinceTS); err != nil {
+		return signals, err
+	}
+	observe()
+	return signals, nil
+}
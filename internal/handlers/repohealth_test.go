@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/repohealth"
+)
+
+func TestRepoHealthHandler_HandleScores_InvalidMethod(t *testing.T) {
+	handler := NewRepoHealthHandler(nil, repohealth.DefaultTargets)
+
+	req := httptest.NewRequest("POST", "/api/repositories/health", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleScores(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestRepoHealthHandler_HandleScores_InvalidParameters(t *testing.T) {
+	handler := NewRepoHealthHandler(nil, repohealth.DefaultTargets)
+
+	for _, target := range []string{"/api/repositories/health?days=0", "/api/repositories/health?format=pdf"} {
+		req := httptest.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+
+		handler.HandleScores(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusBadRequest, status)
+		}
+	}
+}
+
+func TestRepoHealthHandler_HandleScores_NoDatabase(t *testing.T) {
+	handler := NewRepoHealthHandler(nil, repohealth.DefaultTargets)
+
+	req := httptest.NewRequest("GET", "/api/repositories/health", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleScores(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
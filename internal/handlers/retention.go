@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/deedubs/choochoo/internal/retention"
+)
+
+// RetentionHandler reports what the retention janitor has pruned
+type RetentionHandler struct {
+	janitor *retention.Janitor
+}
+
+// NewRetentionHandler creates a new retention handler. janitor is nil when no
+// retention policy is configured.
+func NewRetentionHandler(janitor *retention.Janitor) *RetentionHandler {
+	return &RetentionHandler{janitor: janitor}
+}
+
+// HandleStats reports the retention policy and pruned event counts
+func (rh *RetentionHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if rh.janitor == nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"enabled": false})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": true,
+		"stats":   rh.janitor.Stats(),
+	})
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/retention"
+)
+
+func TestRetentionHandler_HandleStats_Disabled(t *testing.T) {
+	handler := NewRetentionHandler(nil)
+
+	req := httptest.NewRequest("GET", "/api/retention", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleStats(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	if body := rr.Body.String(); body != "{\"enabled\":false}\n" {
+		t.Errorf("Unexpected body: %s", body)
+	}
+}
+
+func TestRetentionHandler_HandleStats(t *testing.T) {
+	prune := func(ctx context.Context, batch retention.Batch) (int64, error) { return 3, nil }
+	janitor := retention.NewJanitor(retention.Policy{"push": time.Hour}, retention.ModeDelete, 10, prune)
+	janitor.RunOnce(context.Background(), time.Now())
+	handler := NewRetentionHandler(janitor)
+
+	req := httptest.NewRequest("GET", "/api/retention", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleStats(rr, req)
+
+	var response struct {
+		Enabled bool            `json:"enabled"`
+		Stats   retention.Stats `json:"stats"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !response.Enabled || response.Stats.TotalPruned["push"] != 3 || response.Stats.Runs != 1 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+}
@@ -5,11 +5,26 @@ import (
 	"net/http"
 )
 
-// HandleRoot provides information about the server endpoints
-func HandleRoot(w http.ResponseWriter, r *http.Request) {
+// RootHandler provides information about the server endpoints
+type RootHandler struct {
+	auditLog bool
+}
+
+// NewRootHandler creates a root handler, listing the audit log endpoint
+// when it is registered
+func NewRootHandler(auditLog bool) *RootHandler {
+	return &RootHandler{auditLog: auditLog}
+}
+
+// HandleRoot lists the server endpoints
+func (rh *RootHandler) HandleRoot(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
 		return
 	}
-	fmt.Fprintf(w, "Choochoo GitHub Webhook Server\nEndpoints:\n- POST /webhook - GitHub webhook endpoint\n- GET /health - Health check\n")
-}
\ No newline at end of file
+	fmt.Fprintf(w, "Choochoo GitHub Webhook Server\nEndpoints:\n- POST /webhook - GitHub webhook endpoint\n")
+	if rh.auditLog {
+		fmt.Fprintf(w, "- POST /audit-log - GitHub Enterprise audit log stream endpoint\n")
+	}
+	fmt.Fprintf(w, "- GET /healthz - Liveness check\n- GET /readyz - Readiness check\n")
+}
@@ -9,14 +9,27 @@ import (
 func TestHandleRoot_ValidPath(t *testing.T) {
 	req := httptest.NewRequest("GET", "/", nil)
 	rr := httptest.NewRecorder()
-	
-	HandleRoot(rr, req)
-	
+
+	NewRootHandler(true).HandleRoot(rr, req)
+
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
 	}
-	
-	expected := "Choochoo GitHub Webhook Server\nEndpoints:\n- POST /webhook - GitHub webhook endpoint\n- GET /health - Health check\n"
+
+	expected := "Choochoo GitHub Webhook Server\nEndpoints:\n- POST /webhook - GitHub webhook endpoint\n- POST /audit-log - GitHub Enterprise audit log stream endpoint\n- GET /healthz - Liveness check\n- GET /readyz - Readiness check\n"
+	body := rr.Body.String()
+	if body != expected {
+		t.Errorf("Expected body %s, got %s", expected, body)
+	}
+}
+
+func TestHandleRoot_NoAuditLog(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+
+	NewRootHandler(false).HandleRoot(rr, req)
+
+	expected := "Choochoo GitHub Webhook Server\nEndpoints:\n- POST /webhook - GitHub webhook endpoint\n- GET /healthz - Liveness check\n- GET /readyz - Readiness check\n"
 	body := rr.Body.String()
 	if body != expected {
 		t.Errorf("Expected body %s, got %s", expected, body)
@@ -26,10 +39,10 @@ func TestHandleRoot_ValidPath(t *testing.T) {
 func TestHandleRoot_InvalidPath(t *testing.T) {
 	req := httptest.NewRequest("GET", "/invalid", nil)
 	rr := httptest.NewRecorder()
-	
-	HandleRoot(rr, req)
-	
+
+	NewRootHandler(false).HandleRoot(rr, req)
+
 	if status := rr.Code; status != http.StatusNotFound {
 		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, status)
 	}
-}
\ No newline at end of file
+}
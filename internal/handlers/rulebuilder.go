@@ -0,0 +1,474 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/rules"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// ruleTestEvents is how many recent events a condition is tested against
+const ruleTestEvents = 50
+
+// maxFieldValues caps the values of a field offered as suggestions
+const maxFieldValues = 10
+
+// Kinds of the values of payload fields
+const (
+	fieldString = "string"
+	fieldNumber = "number"
+	fieldBool   = "bool"
+)
+
+// ruleOperators are the comparisons the rule builder offers
+var ruleOperators = []string{"==", "!=", "contains", "startsWith", "endsWith", "matches", ">", ">=", "<", "<=", "has"}
+
+// ruleActions are the action types the rule builder creates. Other actions
+// are created through the rules API.
+var ruleActions = []string{rules.ActionNotify, rules.ActionForward, rules.ActionLabel, rules.ActionDrop}
+
+// ruleVariables are the variables of rule conditions fields are selected from
+var ruleVariables = map[string]bool{"action": true, "repository": true, "sender": true, "delivery_id": true, "payload": true, "model": true}
+
+// identifierPattern matches the field names usable in a selection
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ruleField is a field of the events of a type that conditions can be built
+// on, with the values seen in recent events
+type ruleField struct {
+	Path   string
+	Kind   string
+	Values []string
+}
+
+// ruleCondition is one comparison of the condition being built
+type ruleCondition struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// ruleTestEvent is a recent event as tested on the rule builder
+type ruleTestEvent struct {
+	DeliveryID string
+	EventType  string
+	Action     string
+	Repository string
+	ReceivedAt time.Time
+	Matched    bool
+	Error      string
+}
+
+// ruleFields lists the fields of events: action, repository and sender, then
+// the scalar payload fields seen in any of them, sorted by path. Fields of
+// objects in lists, and names that cannot be selected, are left out.
+func ruleFields(events []db.WebhookEvent) []ruleField {
+	fields := make(map[string]*ruleField)
+	add := func(path, kind, value string) {
+		field, ok := fields[path]
+		if !ok {
+			field = &ruleField{Path: path, Kind: kind}
+			fields[path] = field
+		}
+		if field.Kind != kind {
+			// Mixed kinds are compared as strings
+			field.Kind = fieldString
+		}
+		if len(field.Values) < maxFieldValues && value != "" && !slices.Contains(field.Values, value) {
+			field.Values = append(field.Values, value)
+		}
+	}
+
+	for _, event := range events {
+		repoName, senderLogin := storedNames(event)
+		add("action", fieldString, event.Action.String)
+		add("repository", fieldString, repoName)
+		add("sender", fieldString, senderLogin)
+
+		var payload map[string]interface{}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			continue
+		}
+		walkFields("payload", payload, add)
+	}
+
+	listed := make([]ruleField, 0, len(fields))
+	for _, field := range fields {
+		sort.Strings(field.Values)
+		listed = append(listed, *field)
+	}
+	sort.Slice(listed, func(i, j int) bool {
+		top := func(path string) bool { return !strings.HasPrefix(path, "payload.") }
+		if top(listed[i].Path) != top(listed[j].Path) {
+			return top(listed[i].Path)
+		}
+		return listed[i].Path < listed[j].Path
+	})
+	return listed
+}
+
+// walkFields calls add with the path, kind and value of every scalar field
+// under object
+func walkFields(prefix string, object map[string]interface{}, add func(path, kind, value string)) {
+	for name, value := range object {
+		if !identifierPattern.MatchString(name) {
+			continue
+		}
+		path := prefix + "." + name
+		switch v := value.(type) {
+		case map[string]interface{}:
+			walkFields(path, v, add)
+		case string:
+			add(path, fieldString, v)
+		case float64:
+			add(path, fieldNumber, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			add(path, fieldBool, strconv.FormatBool(v))
+		}
+	}
+}
+
+// quoteString quotes s as a string literal of the rules language
+func quoteString(s string) (string, error) {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < ' ' {
+				return "", fmt.Errorf("value %q has a control character", s)
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String(), nil
+}
+
+// buildCondition writes the condition matching events of eventType for which
+// every comparison holds. Values are compared as the kind of their field,
+// and as strings for fields that were not seen.
+func buildCondition(eventType string, conditions []ruleCondition, fields []ruleField) (string, error) {
+	kinds := make(map[string]string, len(fields))
+	for _, field := range fields {
+		kinds[field.Path] = field.Kind
+	}
+
+	var parts []string
+	if eventType != "" {
+		quoted, err := quoteString(eventType)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, "event == "+quoted)
+	}
+	for _, condition := range conditions {
+		if condition.Field == "" {
+			continue
+		}
+		names := strings.Split(condition.Field, ".")
+		if !ruleVariables[names[0]] {
+			return "", fmt.Errorf("unknown field %q", condition.Field)
+		}
+		for _, name := range names {
+			if !identifierPattern.MatchString(name) {
+				return "", fmt.Errorf("invalid field %q", condition.Field)
+			}
+		}
+
+		if condition.Operator == "has" {
+			if !strings.Contains(condition.Field, ".") {
+				return "", fmt.Errorf("has needs a payload field, not %q", condition.Field)
+			}
+			parts = append(parts, "has("+condition.Field+")")
+			continue
+		}
+
+		var value string
+		switch condition.Operator {
+		case ">", ">=", "<", "<=":
+			if _, err := strconv.ParseFloat(condition.Value, 64); err != nil {
+				return "", fmt.Errorf("%s %s needs a number, not %q", condition.Field, condition.Operator, condition.Value)
+			}
+			value = condition.Value
+		case "==", "!=":
+			switch kinds[condition.Field] {
+			case fieldNumber:
+				if _, err := strconv.ParseFloat(condition.Value, 64); err != nil {
+					return "", fmt.Errorf("%s is a number, not %q", condition.Field, condition.Value)
+				}
+				value = condition.Value
+			case fieldBool:
+				if condition.Value != "true" && condition.Value != "false" {
+					return "", fmt.Errorf("%s is true or false, not %q", condition.Field, condition.Value)
+				}
+				value = condition.Value
+			}
+		case "contains", "startsWith", "endsWith", "matches":
+		default:
+			return "", fmt.Errorf("unknown operator %q", condition.Operator)
+		}
+		if value == "" {
+			quoted, err := quoteString(condition.Value)
+			if err != nil {
+				return "", err
+			}
+			value = quoted
+		}
+
+		switch condition.Operator {
+		case "contains", "startsWith", "endsWith", "matches":
+			parts = append(parts, condition.Field+"."+condition.Operator+"("+value+")")
+		default:
+			parts = append(parts, condition.Field+" "+condition.Operator+" "+value)
+		}
+	}
+	if len(parts) == 0 {
+		return "", errors.New("choose an event type or a field")
+	}
+	return strings.Join(parts, " && "), nil
+}
+
+// testCondition evaluates a compiled condition on events, as the rules
+// engine does, newest first
+func testCondition(program *rules.Program, events []db.WebhookEvent) []ruleTestEvent {
+	tested := make([]ruleTestEvent, 0, len(events))
+	for _, event := range events {
+		repoName, senderLogin := storedNames(event)
+		result := ruleTestEvent{
+			DeliveryID: event.DeliveryID,
+			EventType:  event.EventType,
+			Action:     event.Action.String,
+			Repository: event.RepositoryName.String,
+			ReceivedAt: event.CreatedAt.Time,
+		}
+		vars, err := forwarder.Event{
+			DeliveryID: event.DeliveryID,
+			EventType:  event.EventType,
+			Action:     event.Action.String,
+			Repository: repoName,
+			Sender:     senderLogin,
+			Payload:    event.Payload,
+		}.Variables()
+		if err == nil {
+			result.Matched, err = program.Eval(vars)
+		}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		tested = append(tested, result)
+	}
+	sort.SliceStable(tested, func(i, j int) bool {
+		return tested[i].ReceivedAt.After(tested[j].ReceivedAt)
+	})
+	if len(tested) > ruleTestEvents {
+		tested = tested[:ruleTestEvents]
+	}
+	return tested
+}
+
+// formConditions reads the comparisons of the builder form, whose rows are
+// the field, operator and value parameters in order
+func formConditions(form url.Values) []ruleCondition {
+	fields, operators, values := form["field"], form["operator"], form["value"]
+	var conditions []ruleCondition
+	for i, field := range fields {
+		condition := ruleCondition{Field: strings.TrimSpace(field), Operator: "=="}
+		if i < len(operators) && operators[i] != "" {
+			condition.Operator = operators[i]
+		}
+		if i < len(values) {
+			condition.Value = values[i]
+		}
+		if condition.Field != "" {
+			conditions = append(conditions, condition)
+		}
+	}
+	return conditions
+}
+
+// formAction reads the action of the builder form
+func formAction(form url.Values) rules.Action {
+	action := rules.Action{
+		Type:    form.Get("action"),
+		URL:     strings.TrimSpace(form.Get("url")),
+		Message: form.Get("message"),
+	}
+	for _, label := range strings.Split(form.Get("labels"), ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			action.Labels = append(action.Labels, label)
+		}
+	}
+	return action
+}
+
+// sameOrigin reports whether a browser request was sent by a page of the
+// dashboard itself rather than a form on another site
+func sameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin"
+	}
+	if origin := r.Header.Get("Origin"); origin != "" {
+		parsed, err := url.Parse(origin)
+		return err == nil && parsed.Host == r.Host
+	}
+	return true
+}
+
+// HandleRules serves the rule builder. GET offers the fields of recent
+// events of a type, builds a condition from the comparisons in the query and
+// tests it against those events; POST saves the rule in the form through
+// the rules API.
+func (ah *AdminHandler) HandleRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := ah.operator(w, r)
+	if !ok {
+		return
+	}
+	if r.Method == http.MethodPost && !sameOrigin(r) {
+		http.Error(w, "Cross-origin request", http.StatusForbidden)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+	eventType := r.Form.Get("event")
+	if eventType == "" {
+		eventType = webhook.PullRequestEvent
+	}
+	if !webhook.IsSupportedEvent(eventType) {
+		http.Error(w, "Unknown event type", http.StatusBadRequest)
+		return
+	}
+	if ah.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	stored, err := ah.dbConn.Queries().ListWebhookEvents(ctx, db.ListWebhookEventsParams{
+		EventType: eventType,
+		RowLimit:  ruleTestEvents,
+	})
+	if err != nil {
+		log.Printf("Failed to list %s events: %v", eventType, err)
+		http.Error(w, "Failed to list events", http.StatusInternalServerError)
+		return
+	}
+	var events []db.WebhookEvent
+	for _, row := range stored {
+		// Events that cannot be decrypted are left out of the test
+		if row.Payload, err = ah.keys.Open(row.DeliveryID, row.Payload); err == nil {
+			events = append(events, row)
+		}
+	}
+	fields := ruleFields(events)
+
+	name := strings.TrimSpace(r.Form.Get("name"))
+	conditions := formConditions(r.Form)
+	action := formAction(r.Form)
+	if action.Type == "" {
+		action.Type = rules.ActionNotify
+	}
+
+	status, message := http.StatusOK, ""
+	condition, buildErr := buildCondition(eventType, conditions, fields)
+	var tested []ruleTestEvent
+	if buildErr != nil {
+		status, message = http.StatusBadRequest, buildErr.Error()
+	} else if program, err := rules.Compile(condition); err != nil {
+		status, message = http.StatusBadRequest, err.Error()
+	} else {
+		tested = testCondition(program, events)
+	}
+
+	if r.Method == http.MethodPost && message == "" {
+		if ah.saveRule == nil {
+			http.Error(w, "Rule saving not configured", http.StatusServiceUnavailable)
+			return
+		}
+		rule := rules.Rule{Name: name, Condition: condition, Actions: []rules.Action{action}}
+		_, pending, err := ah.saveRule(r.Context(), rule, operator)
+		var invalid invalidRuleError
+		switch {
+		case err == nil:
+			query := url.Values{"event": {eventType}, "name": {name}, "saved": {"1"}}
+			if pending != nil {
+				query = url.Values{"event": {eventType}, "name": {name}, "pending": {strconv.Itoa(int(pending.ID))}}
+			}
+			for _, c := range conditions {
+				query.Add("field", c.Field)
+				query.Add("operator", c.Operator)
+				query.Add("value", c.Value)
+			}
+			http.Redirect(w, r, "/admin/rules?"+query.Encode(), http.StatusSeeOther)
+			return
+		case errors.As(err, &invalid):
+			status, message = http.StatusBadRequest, invalid.Error()
+		case errors.Is(err, errFileRule):
+			status, message = http.StatusConflict, "A rule of the rules file has this name"
+		case errors.Is(err, errNoRules), errors.Is(err, errNoDatabase):
+			http.Error(w, "Rule saving not configured", http.StatusServiceUnavailable)
+			return
+		default:
+			http.Error(w, "Failed to save rule", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	eventTypes := make([]string, 0, len(webhook.SupportedEventTypes))
+	for supported := range webhook.SupportedEventTypes {
+		eventTypes = append(eventTypes, supported)
+	}
+	sort.Strings(eventTypes)
+
+	// Offer an empty row to add a comparison
+	rows := append(conditions, ruleCondition{Operator: "=="})
+
+	renderAdminStatus(w, status, "rules.html", map[string]interface{}{
+		"EventTypes": eventTypes,
+		"Event":      eventType,
+		"Name":       name,
+		"Fields":     fields,
+		"Operators":  ruleOperators,
+		"Conditions": rows,
+		"Condition":  condition,
+		"Actions":    ruleActions,
+		"Action":     action,
+		"Labels":     strings.Join(action.Labels, ", "),
+		"Events":     tested,
+		"Saved":      r.Form.Get("saved") != "",
+		"Pending":    r.Form.Get("pending"),
+		"Error":      message,
+		"Banners":    ah.dashboardBanners(r.Context()),
+	})
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/rules"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func storedEvent(deliveryID, eventType, repository, payload string, age time.Duration) db.WebhookEvent {
+	return db.WebhookEvent{
+		DeliveryID:     deliveryID,
+		EventType:      eventType,
+		RepositoryName: pgtype.Text{String: repository, Valid: repository != ""},
+		Payload:        []byte(payload),
+		CreatedAt:      pgtype.Timestamptz{Time: time.Now().Add(-age), Valid: true},
+	}
+}
+
+func TestRuleFields(t *testing.T) {
+	fields := ruleFields([]db.WebhookEvent{
+		storedEvent("d1", "pull_request", "octo-org/api", `{"number":1,"pull_request":{"draft":false,"base":{"ref":"main"},"labels":[{"name":"bug"}]},"x-y":1}`, time.Minute),
+		storedEvent("d2", "pull_request", "octo-org/web", `{"number":2,"pull_request":{"draft":true,"base":{"ref":"main"}}}`, time.Minute),
+	})
+
+	var paths []string
+	byPath := make(map[string]ruleField)
+	for _, field := range fields {
+		paths = append(paths, field.Path)
+		byPath[field.Path] = field
+	}
+	expected := "action,repository,sender,payload.number,payload.pull_request.base.ref,payload.pull_request.draft"
+	if strings.Join(paths, ",") != expected {
+		t.Errorf("Expected fields %s, got %v", expected, paths)
+	}
+	if field := byPath["payload.number"]; field.Kind != fieldNumber || strings.Join(field.Values, ",") != "1,2" {
+		t.Errorf("Unexpected number field %+v", field)
+	}
+	if field := byPath["payload.pull_request.draft"]; field.Kind != fieldBool {
+		t.Errorf("Unexpected bool field %+v", field)
+	}
+	if field := byPath["repository"]; strings.Join(field.Values, ",") != "octo-org/api,octo-org/web" {
+		t.Errorf("Unexpected repository field %+v", field)
+	}
+}
+
+func TestBuildCondition(t *testing.T) {
+	fields := []ruleField{
+		{Path: "payload.number", Kind: fieldNumber},
+		{Path: "payload.pull_request.draft", Kind: fieldBool},
+		{Path: "payload.pull_request.title", Kind: fieldString},
+	}
+
+	tests := []struct {
+		name       string
+		conditions []ruleCondition
+		expected   string
+		err        bool
+	}{
+		{"event type only", nil, `event == "pull_request"`, false},
+		{"kinds of fields", []ruleCondition{
+			{Field: "action", Operator: "==", Value: "opened"},
+			{Field: "payload.number", Operator: "==", Value: "7"},
+			{Field: "payload.pull_request.draft", Operator: "!=", Value: "true"},
+		}, `event == "pull_request" && action == "opened" && payload.number == 7 && payload.pull_request.draft != true`, false},
+		{"string functions", []ruleCondition{
+			{Field: "payload.pull_request.title", Operator: "contains", Value: `say "hi"`},
+			{Field: "sender", Operator: "endsWith", Value: "[bot]"},
+		}, `event == "pull_request" && payload.pull_request.title.contains("say \"hi\"") && sender.endsWith("[bot]")`, false},
+		{"presence", []ruleCondition{{Field: "payload.pull_request.title", Operator: "has"}}, `event == "pull_request" && has(payload.pull_request.title)`, false},
+		{"unseen fields are strings", []ruleCondition{{Field: "payload.label.name", Operator: "==", Value: "7"}}, `event == "pull_request" && payload.label.name == "7"`, false},
+		{"number comparison", []ruleCondition{{Field: "payload.number", Operator: ">", Value: "abc"}}, "", true},
+		{"bool value", []ruleCondition{{Field: "payload.pull_request.draft", Operator: "==", Value: "yes"}}, "", true},
+		{"unknown variable", []ruleCondition{{Field: "secret", Operator: "==", Value: "x"}}, "", true},
+		{"invalid field", []ruleCondition{{Field: "payload.a b", Operator: "==", Value: "x"}}, "", true},
+		{"unknown operator", []ruleCondition{{Field: "action", Operator: "~", Value: "x"}}, "", true},
+		{"presence of a variable", []ruleCondition{{Field: "action", Operator: "has"}}, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			condition, err := buildCondition("pull_request", test.conditions, fields)
+			if (err != nil) != test.err {
+				t.Fatalf("Expected error %v, got %v", test.err, err)
+			}
+			if condition != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, condition)
+			}
+			if err == nil {
+				if _, err := rules.Compile(condition); err != nil {
+					t.Errorf("Built condition does not compile: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestTestCondition(t *testing.T) {
+	program, err := rules.Compile(`event == "pull_request" && payload.pull_request.base.ref == "main"`)
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+	tested := testCondition(program, []db.WebhookEvent{
+		storedEvent("old", "pull_request", "octo-org/api", `{"pull_request":{"base":{"ref":"main"}}}`, time.Hour),
+		storedEvent("new", "pull_request", "octo-org/api", `{"pull_request":{"base":{"ref":"dev"}}}`, time.Minute),
+		storedEvent("bare", "pull_request", "octo-org/api", `{}`, 2*time.Hour),
+	})
+	if len(tested) != 3 || tested[0].DeliveryID != "new" || tested[1].DeliveryID != "old" {
+		t.Fatalf("Expected the events newest first, got %+v", tested)
+	}
+	if tested[0].Matched || !tested[1].Matched {
+		t.Errorf("Expected only the pull request to main to match, got %+v", tested)
+	}
+	if tested[2].Matched || tested[2].Error == "" {
+		t.Errorf("Expected the event without the field to fail, got %+v", tested[2])
+	}
+}
+
+func TestFormConditions(t *testing.T) {
+	conditions := formConditions(map[string][]string{
+		"field":    {"action", "", "sender"},
+		"operator": {"!=", "==", ""},
+		"value":    {"closed", "", "octocat"},
+	})
+	if len(conditions) != 2 || conditions[0] != (ruleCondition{"action", "!=", "closed"}) || conditions[1] != (ruleCondition{"sender", "==", "octocat"}) {
+		t.Errorf("Unexpected conditions %+v", conditions)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected bool
+	}{
+		{"fetch metadata", map[string]string{"Sec-Fetch-Site": "same-origin"}, true},
+		{"cross-site fetch metadata", map[string]string{"Sec-Fetch-Site": "cross-site"}, false},
+		{"origin", map[string]string{"Origin": "http://choochoo.example.com"}, true},
+		{"other origin", map[string]string{"Origin": "https://evil.example.com"}, false},
+		{"no headers", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://choochoo.example.com/admin/rules", nil)
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+			if got := sameOrigin(req); got != test.expected {
+				t.Errorf("Expected %v, got %v", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_HandleRules(t *testing.T) {
+	handler := NewAdminHandler("admin", "hunter2", apitoken.NewAuthenticator("", nil), nil)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		origin   string
+		expected int
+	}{
+		{"cross-origin save", http.MethodPost, "/admin/rules", "https://evil.example.com", http.StatusForbidden},
+		{"unknown event type", http.MethodGet, "/admin/rules?event=pushes", "", http.StatusBadRequest},
+		{"no database", http.MethodGet, "/admin/rules?event=push&field=action&value=x", "", http.StatusServiceUnavailable},
+		{"wrong method", http.MethodDelete, "/admin/rules", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.target, nil)
+			req.SetBasicAuth("admin", "hunter2")
+			if test.origin != "" {
+				req.Header.Set("Origin", test.origin)
+			}
+			rr := httptest.NewRecorder()
+			handler.HandleRules(rr, req)
+
+			if rr.Code != test.expected {
+				t.Errorf("Expected status code %d, got %d: %s", test.expected, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRenderAdmin_Rules(t *testing.T) {
+	rr := httptest.NewRecorder()
+	renderAdminStatus(rr, http.StatusBadRequest, "rules.html", map[string]interface{}{
+		"EventTypes": []string{"pull_request", "push"},
+		"Event":      "pull_request",
+		"Fields":     []ruleField{{Path: "action", Kind: fieldString, Values: []string{"opened", "closed"}}},
+		"Operators":  ruleOperators,
+		"Conditions": []ruleCondition{{Field: "action", Operator: "!=", Value: "closed"}, {Operator: "=="}},
+		"Condition":  `event == "pull_request" && action != "closed"`,
+		"Actions":    ruleActions,
+		"Action":     rules.Action{Type: rules.ActionDrop},
+		"Events": []ruleTestEvent{{
+			DeliveryID: "abc-123",
+			EventType:  "pull_request",
+			Matched:    true,
+		}},
+		"Pending": "7",
+		"Error":   `invalid rule name ""`,
+	})
+
+	body := rr.Body.String()
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("Unexpected response %d: %s", rr.Code, body)
+	}
+	for _, want := range []string{
+		`<option value="pull_request" selected>`,
+		`<option value="action" selected>`,
+		`<option value="!=" selected>`,
+		`<option value="opened">`,
+		`<option value="drop" selected>`,
+		`action != &#34;closed&#34;`,
+		`processed">yes</span>`,
+		"invalid rule name &#34;&#34;",
+		`Change 7 is waiting for <a href="/admin/changes">approval</a>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected page to contain %q:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/approval"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/rules"
+)
+
+var (
+	// errNoRules is returned by SaveRule when no rules engine is configured
+	errNoRules = errors.New("rules not configured")
+	// errFileRule is returned by SaveRule for rules of the rules file
+	errFileRule = errors.New("rule is defined in the rules file")
+)
+
+// invalidRuleError is returned by SaveRule for rules that do not pass
+// validation. Its message is meant for the user.
+type invalidRuleError struct {
+	err error
+}
+
+func (e invalidRuleError) Error() string {
+	return e.err.Error()
+}
+
+// storedRule is a rule as returned by the management API
+type storedRule struct {
+	rules.Rule
+	CreatedBy string    `json:"created_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// newRule converts a stored rule
+func newRule(row db.Rule) (rules.Rule, error) {
+	r := rules.Rule{Name: row.Name, Condition: row.Condition, Source: rules.SourceDatabase}
+	if err := json.Unmarshal(row.Actions, &r.Actions); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// LoadRules returns a rules.LoadFunc reading the rules table
+func LoadRules(queries *db.Queries) rules.LoadFunc {
+	return func(ctx context.Context) ([]rules.Rule, error) {
+		rows, err := queries.ListRules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		stored := make([]rules.Rule, 0, len(rows))
+		for _, row := range rows {
+			r, err := newRule(row)
+			if err != nil {
+				log.Printf("Skipping stored rule %q: invalid actions: %v", row.Name, err)
+				continue
+			}
+			stored = append(stored, r)
+		}
+		return stored, nil
+	}
+}
+
+// HandleRules lists the rules in evaluation order, those of the rules file
+// first, or creates or replaces a stored rule. Rules of the rules file
+// cannot be replaced.
+func (mh *ManagementHandler) HandleRules(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := mh.operator(w, r)
+	if !ok {
+		return
+	}
+	if mh.rules == nil {
+		http.Error(w, "Rules not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"rules": mh.rules.Rules()})
+		return
+	}
+
+	if mh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+	var rule rules.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	mh.writeSavedRule(w, r, rule, operator)
+}
+
+// SaveRule creates or replaces a stored rule on behalf of operator,
+// validating it first. It backs POST and PUT of the rules API and the
+// dashboard's rule builder. When changes need approval the rule is not saved
+// but queued, and the pending change is returned.
+func (mh *ManagementHandler) SaveRule(ctx context.Context, rule rules.Rule, operator string) (storedRule, *approval.Change, error) {
+	if mh.rules == nil {
+		return storedRule{}, nil, errNoRules
+	}
+	rule.Source = rules.SourceDatabase
+	if err := rule.Validate(); err != nil {
+		return storedRule{}, nil, invalidRuleError{err}
+	}
+	if mh.rules.FileRule(rule.Name) {
+		return storedRule{}, nil, errFileRule
+	}
+	if mh.dbConn == nil {
+		return storedRule{}, nil, errNoDatabase
+	}
+	actions, err := json.Marshal(rule.Actions)
+	if err != nil {
+		return storedRule{}, nil, invalidRuleError{errors.New("invalid actions")}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if mh.approvals != nil {
+		value, _ := json.Marshal(map[string]interface{}{"condition": rule.Condition, "actions": json.RawMessage(actions)})
+		change, err := mh.approvals.Request(ctx, approval.Change{Setting: approval.RuleSetting, Match: rule.Name, Value: string(value)}, operator)
+		if err != nil {
+			log.Printf("Failed to queue rule %q: %v", rule.Name, err)
+			return storedRule{}, nil, err
+		}
+		log.Printf("Change %d (%s) requested by %s", change.ID, change, operator)
+		return storedRule{}, &change, nil
+	}
+
+	row, err := mh.dbConn.Queries().UpsertRule(ctx, db.UpsertRuleParams{
+		Name:      rule.Name,
+		Condition: rule.Condition,
+		Actions:   actions,
+		CreatedBy: operator,
+	})
+	if err != nil {
+		log.Printf("Failed to save rule %q: %v", rule.Name, err)
+		return storedRule{}, nil, err
+	}
+	if err := mh.rules.Reload(ctx); err != nil {
+		log.Printf("Failed to reload rules: %v", err)
+	}
+	log.Printf("Rule %q saved by %s", rule.Name, operator)
+	return storedRule{Rule: rule, CreatedBy: row.CreatedBy, UpdatedAt: row.UpdatedAt.Time}, nil, nil
+}
+
+// HandleRule reads, creates or replaces, and deletes the rule {name}. PUT
+// takes a JSON body with the rule condition and actions; rules of the rules
+// file can be read but not replaced or deleted.
+func (mh *ManagementHandler) HandleRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Only GET, PUT and DELETE methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := mh.operator(w, r)
+	if !ok {
+		return
+	}
+	if mh.rules == nil {
+		http.Error(w, "Rules not configured", http.StatusServiceUnavailable)
+		return
+	}
+	name := r.PathValue("name")
+
+	switch r.Method {
+	case http.MethodGet:
+		for _, rule := range mh.rules.Rules() {
+			if rule.Name == name {
+				writeJSON(w, http.StatusOK, rule)
+				return
+			}
+		}
+		http.Error(w, "Rule not found", http.StatusNotFound)
+
+	case http.MethodPut:
+		if mh.dbConn == nil {
+			http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+			return
+		}
+		var rule rules.Rule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if rule.Name != "" && rule.Name != name {
+			http.Error(w, "Rule name does not match the path", http.StatusBadRequest)
+			return
+		}
+		rule.Name = name
+		mh.writeSavedRule(w, r, rule, operator)
+
+	case http.MethodDelete:
+		mh.deleteRule(w, r, name, operator)
+	}
+}
+
+// writeSavedRule saves rule with SaveRule, writing the saved rule, the
+// pending change or the error as the response
+func (mh *ManagementHandler) writeSavedRule(w http.ResponseWriter, r *http.Request, rule rules.Rule, operator string) {
+	saved, pending, err := mh.SaveRule(r.Context(), rule, operator)
+	var invalid invalidRuleError
+	switch {
+	case err == nil && pending != nil:
+		writeJSON(w, http.StatusAccepted, pending)
+	case err == nil:
+		writeJSON(w, http.StatusOK, saved)
+	case errors.As(err, &invalid):
+		http.Error(w, invalid.Error(), http.StatusBadRequest)
+	case errors.Is(err, errFileRule):
+		http.Error(w, "Rule is defined in the rules file", http.StatusConflict)
+	default:
+		http.Error(w, "Failed to save rule", http.StatusInternalServerError)
+	}
+}
+
+// deleteRule deletes the stored rule name
+func (mh *ManagementHandler) deleteRule(w http.ResponseWriter, r *http.Request, name, operator string) {
+	if mh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+	if mh.rules.FileRule(name) {
+		http.Error(w, "Rule is defined in the rules file", http.StatusConflict)
+		return
+	}
+	if mh.approvals != nil {
+		if !mh.hasStoredRule(name) {
+			http.Error(w, "Rule not found", http.StatusNotFound)
+			return
+		}
+		mh.propose(w, r, approval.Change{Setting: approval.RuleSetting, Match: name}, operator)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	deleted, err := mh.dbConn.Queries().DeleteRule(ctx, name)
+	if err != nil {
+		log.Printf("Failed to delete rule %q: %v", name, err)
+		http.Error(w, "Failed to delete rule", http.StatusInternalServerError)
+		return
+	}
+	if deleted == 0 {
+		http.Error(w, "Rule not found", http.StatusNotFound)
+		return
+	}
+	if err := mh.rules.Reload(ctx); err != nil {
+		log.Printf("Failed to reload rules: %v", err)
+	}
+	log.Printf("Rule %q deleted by %s", name, operator)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// hasStoredRule reports whether the rules engine has loaded the stored rule
+// name
+func (mh *ManagementHandler) hasStoredRule(name string) bool {
+	for _, rule := range mh.rules.Rules() {
+		if rule.Name == name && rule.Source == rules.SourceDatabase {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/rules"
+)
+
+func TestManagementHandler_HandleRules(t *testing.T) {
+	engine := rules.NewEngine([]rules.Rule{
+		{Name: "bots", Condition: `sender.endsWith("[bot]")`, Actions: []rules.Action{{Type: rules.ActionDrop}}, Source: rules.SourceFile},
+	}, nil)
+
+	tests := []struct {
+		name   string
+		engine *rules.Engine
+		req    *http.Request
+		handle func(*ManagementHandler) http.HandlerFunc
+		status int
+	}{
+		{"not configured", nil, managementRequest("GET", "/api/v1/rules", "", "secret"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleRules }, http.StatusServiceUnavailable},
+		{"invalid token", engine, managementRequest("GET", "/api/v1/rules", "", "wrong"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleRules }, http.StatusUnauthorized},
+		{"invalid method", engine, managementRequest("PUT", "/api/v1/rules", "", "secret"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleRules }, http.StatusMethodNotAllowed},
+		{"create without database", engine, managementRequest("POST", "/api/v1/rules", `{"name": "a", "condition": "true", "actions": [{"type": "drop"}]}`, "secret"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleRules }, http.StatusServiceUnavailable},
+		{"rule with PATCH", engine, managementRequest("PATCH", "/api/v1/rules/a", "", "secret", "name", "a"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleRule }, http.StatusMethodNotAllowed},
+		{"get file rule", engine, managementRequest("GET", "/api/v1/rules/bots", "", "secret", "name", "bots"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleRule }, http.StatusOK},
+		{"get unknown rule", engine, managementRequest("GET", "/api/v1/rules/a", "", "secret", "name", "a"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleRule }, http.StatusNotFound},
+		{"put without database", engine, managementRequest("PUT", "/api/v1/rules/a", `{"condition": "true", "actions": [{"type": "drop"}]}`, "secret", "name", "a"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleRule }, http.StatusServiceUnavailable},
+		{"delete without database", engine, managementRequest("DELETE", "/api/v1/rules/a", "", "secret", "name", "a"),
+			func(mh *ManagementHandler) http.HandlerFunc { return mh.HandleRule }, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil).WithRules(tt.engine)
+			rr := httptest.NewRecorder()
+			tt.handle(handler)(rr, tt.req)
+			if rr.Code != tt.status {
+				t.Errorf("Expected status code %d, got %d", tt.status, rr.Code)
+			}
+		})
+	}
+
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil).WithRules(engine)
+	rr := httptest.NewRecorder()
+	handler.HandleRules(rr, managementRequest("GET", "/api/v1/rules", "", "secret"))
+	var body struct {
+		Rules []rules.Rule `json:"rules"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil || len(body.Rules) != 1 || body.Rules[0].Source != rules.SourceFile {
+		t.Errorf("Expected the file rule to be listed, got %s", rr.Body)
+	}
+}
+
+func TestManagementHandler_SaveRule(t *testing.T) {
+	engine := rules.NewEngine([]rules.Rule{
+		{Name: "bots", Condition: `sender.endsWith("[bot]")`, Actions: []rules.Action{{Type: rules.ActionDrop}}, Source: rules.SourceFile},
+	}, nil)
+	handler := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil).WithRules(engine)
+	drop := []rules.Action{{Type: rules.ActionDrop}}
+
+	if _, _, err := NewManagementHandler(apitoken.NewAuthenticator("secret", nil), nil).SaveRule(context.Background(), rules.Rule{Name: "a", Condition: "true", Actions: drop}, "admin"); !errors.Is(err, errNoRules) {
+		t.Errorf("Expected errNoRules without an engine, got %v", err)
+	}
+	var invalid invalidRuleError
+	if _, _, err := handler.SaveRule(context.Background(), rules.Rule{Name: "a", Condition: "event ==", Actions: drop}, "admin"); !errors.As(err, &invalid) {
+		t.Errorf("Expected an invalid rule for a bad condition, got %v", err)
+	}
+	if _, _, err := handler.SaveRule(context.Background(), rules.Rule{Name: "bots", Condition: "true", Actions: drop}, "admin"); !errors.Is(err, errFileRule) {
+		t.Errorf("Expected errFileRule for a rule of the rules file, got %v", err)
+	}
+	if _, _, err := handler.SaveRule(context.Background(), rules.Rule{Name: "a", Condition: "true", Actions: drop}, "admin"); !errors.Is(err, errNoDatabase) {
+		t.Errorf("Expected errNoDatabase for a valid rule, got %v", err)
+	}
+}
+
+func TestWebhookHandler_Rules_Drop(t *testing.T) {
+	rec := &recordingForwarder{}
+	engine := rules.NewEngine([]rules.Rule{
+		{Name: "bots", Condition: `sender.endsWith("[bot]")`, Actions: []rules.Action{{Type: rules.ActionDrop}}},
+	}, nil)
+	handler := NewWebhookHandler("", nil).WithForwarders(rec).WithRules(engine)
+
+	for _, sender := range []string{"dependabot[bot]", "octocat"} {
+		payload := `{"action":"opened","repository":{"full_name":"test/repo"},"sender":{"login":"` + sender + `"}}`
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", "pull_request")
+		req.Header.Set("X-GitHub-Delivery", "delivery-"+sender)
+
+		rr := httptest.NewRecorder()
+		handler.HandleWebhook(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	}
+
+	if len(rec.events) != 1 || rec.events[0].Sender != "octocat" {
+		t.Errorf("Expected only the event not dropped to be forwarded, got %+v", rec.events)
+	}
+}
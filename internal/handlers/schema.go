@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/deedubs/choochoo/internal/schema"
+)
+
+// HandleSchemas lists the paths of the published JSON Schemas
+func HandleSchemas(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	paths := make([]string, 0, len(schema.Names()))
+	for _, name := range schema.Names() {
+		paths = append(paths, "/schemas/"+name)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"schemas": paths})
+}
+
+// HandleSchema serves the JSON Schema named by the path, for editors and
+// validators of configuration files
+func HandleSchema(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := schema.Generate(r.PathValue("name"))
+	if errors.Is(err, schema.ErrNotFound) {
+		http.Error(w, "Schema not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to generate schema %s: %v", r.PathValue("name"), err)
+		http.Error(w, "Failed to generate schema", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(data)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleSchemas(t *testing.T) {
+	rr := httptest.NewRecorder()
+	HandleSchemas(rr, httptest.NewRequest(http.MethodGet, "/schemas", nil))
+
+	var response struct {
+		Schemas []string `json:"schemas"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil || len(response.Schemas) != 4 {
+		t.Errorf("Expected four schemas, got %v: %s", err, rr.Body.String())
+	}
+}
+
+func TestHandleSchema(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/schemas/{name}", HandleSchema)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/schemas/choochoo.schema.json", nil))
+	if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/schema+json" {
+		t.Fatalf("Unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+	var schema struct {
+		ID    string `json:"$id"`
+		Title string `json:"title"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &schema); err != nil || schema.ID != "/schemas/choochoo.schema.json" {
+		t.Errorf("Expected the repository settings file schema, got %v: %s", err, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/schemas/unknown.schema.json", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
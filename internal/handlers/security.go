@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/security"
+	"github.com/deedubs/choochoo/internal/webhook"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// SecurityHandler serves the security posture report
+type SecurityHandler struct {
+	dbConn *database.Connection
+	sla    security.SLA
+}
+
+// NewSecurityHandler creates a new security handler
+func NewSecurityHandler(dbConn *database.Connection, sla security.SLA) *SecurityHandler {
+	if sla == nil {
+		sla = security.DefaultSLA
+	}
+	return &SecurityHandler{
+		dbConn: dbConn,
+		sla:    sla,
+	}
+}
+
+// HandlePosture reports open alerts, SLA breaches and time-to-resolve stats.
+// The optional days parameter controls the resolution window (default 90).
+func (sh *SecurityHandler) HandlePosture(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	days := 90
+	if value := r.URL.Query().Get("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid days parameter", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	if sh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	open, err := sh.dbConn.Queries().ListOpenSecurityAlerts(ctx)
+	if err != nil {
+		log.Printf("Failed to list open security alerts: %v", err)
+		http.Error(w, "Failed to load security alerts", http.StatusInternalServerError)
+		return
+	}
+
+	since := pgtype.Timestamptz{Time: now.AddDate(0, 0, -days), Valid: true}
+	stats, err := sh.dbConn.Queries().GetSecurityAlertResolutionStats(ctx, since)
+	if err != nil {
+		log.Printf("Failed to load security alert resolution stats: %v", err)
+		http.Error(w, "Failed to load security alerts", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(security.BuildPosture(open, stats, sh.sla, days, now))
+}
+
+// storeSecurityAlert records the current state of a security alert
+func storeSecurityAlert(ctx context.Context, dbConn *database.Connection, alert *webhook.SecurityAlert) error {
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	createdAt := alert.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	params := db.UpsertSecurityAlertParams{
+		Kind:           alert.Kind,
+		RepositoryName: alert.Repository,
+		AlertNumber:    int32(alert.Number),
+		Severity:       alert.Severity,
+		State:          alert.State,
+		Summary:        optionalText(alert.Summary),
+		HtmlUrl:        optionalText(alert.HTMLURL),
+		CreatedAt:      pgtype.Timestamptz{Time: createdAt, Valid: true},
+		Resolution:     optionalText(alert.Resolution),
+	}
+	if alert.ResolvedAt != nil {
+		params.ResolvedAt = pgtype.Timestamptz{Time: *alert.ResolvedAt, Valid: true}
+	}
+
+	_, err := dbConn.Queries().UpsertSecurityAlert(dbCtx, params)
+	return err
+}
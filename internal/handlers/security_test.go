@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHandler_HandlePosture_InvalidMethod(t *testing.T) {
+	handler := NewSecurityHandler(nil, nil)
+
+	req := httptest.NewRequest("POST", "/api/security/posture", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandlePosture(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestSecurityHandler_HandlePosture_InvalidDays(t *testing.T) {
+	handler := NewSecurityHandler(nil, nil)
+
+	req := httptest.NewRequest("GET", "/api/security/posture?days=-1", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandlePosture(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestSecurityHandler_HandlePosture_NoDatabase(t *testing.T) {
+	handler := NewSecurityHandler(nil, nil)
+
+	req := httptest.NewRequest("GET", "/api/security/posture", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandlePosture(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
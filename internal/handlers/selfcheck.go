@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/selfcheck"
+)
+
+// SelfCheckHandler reports whether the GitHub credentials grant the
+// permissions of every enabled feature
+type SelfCheckHandler struct {
+	checker *selfcheck.Checker
+	auth    *apitoken.Authenticator
+}
+
+// NewSelfCheckHandler creates a new self-check handler. The report describes
+// the credentials, so reading it needs a token with the read scope, and
+// running the check again spends GitHub API requests, so it needs the admin
+// scope. The endpoint is disabled when auth accepts no tokens.
+func NewSelfCheckHandler(checker *selfcheck.Checker, auth *apitoken.Authenticator) *SelfCheckHandler {
+	return &SelfCheckHandler{checker: checker, auth: auth}
+}
+
+// HandleSelfCheck returns the latest self-check report. The check runs again
+// with refresh=true, or if it has not completed since startup.
+func (sh *SelfCheckHandler) HandleSelfCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	refresh := r.URL.Query().Get("refresh") == "true"
+	scope := apitoken.ScopeRead
+	if refresh {
+		scope = apitoken.ScopeAdmin
+	}
+	if !sh.auth.Enabled() {
+		http.Error(w, "Management API not configured", http.StatusServiceUnavailable)
+		return
+	}
+	if !sh.auth.Authorize(w, r, scope) {
+		return
+	}
+
+	report := sh.checker.Last()
+	if refresh || report.CheckedAt.IsZero() {
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		report = sh.checker.Run(ctx, time.Now())
+	}
+	writeJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/selfcheck"
+)
+
+func TestSelfCheckHandler_HandleSelfCheck(t *testing.T) {
+	requirements := []selfcheck.Requirement{{Feature: "check runs", Permission: "checks", Access: "write"}}
+	handler := NewSelfCheckHandler(selfcheck.NewChecker(nil, requirements), apitoken.NewAuthenticator("secret", nil))
+
+	req := httptest.NewRequest("GET", "/api/github/self-check", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+
+	handler.HandleSelfCheck(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	var report selfcheck.Report
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Configured || len(report.Features) != 1 || report.Features[0].Status != selfcheck.StatusDegraded {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestSelfCheckHandler_MethodNotAllowed(t *testing.T) {
+	handler := NewSelfCheckHandler(selfcheck.NewChecker(nil, nil), apitoken.NewAuthenticator("secret", nil))
+
+	req := httptest.NewRequest("POST", "/api/github/self-check", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleSelfCheck(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestSelfCheckHandler_RequiresToken(t *testing.T) {
+	checker := selfcheck.NewChecker(nil, nil)
+	handler := NewSelfCheckHandler(checker, apitoken.NewAuthenticator("secret", nil))
+
+	for _, target := range []string{"/api/github/self-check", "/api/github/self-check?refresh=true"} {
+		rr := httptest.NewRecorder()
+		handler.HandleSelfCheck(rr, httptest.NewRequest("GET", target, nil))
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status code %d for %s without a token, got %d", http.StatusUnauthorized, target, rr.Code)
+		}
+	}
+	if !checker.Last().CheckedAt.IsZero() {
+		t.Error("Anonymous request ran the check")
+	}
+
+	rr := httptest.NewRecorder()
+	NewSelfCheckHandler(checker, apitoken.NewAuthenticator("", nil)).HandleSelfCheck(rr, httptest.NewRequest("GET", "/api/github/self-check", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d without a management token, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+}
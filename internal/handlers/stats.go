@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/indexadvisor"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Limits of the stats time range and of the most active lists
+const (
+	defaultStatsDays  = 30
+	maxStatsDays      = 366
+	defaultStatsLimit = 10
+	maxStatsLimit     = 100
+)
+
+// Patterns of the stats queries on the events table
+var (
+	statsPerDayPattern       = indexadvisor.Pattern{Name: "stats events per day", Range: "created_at"}
+	statsRepositoriesPattern = indexadvisor.Pattern{Name: "stats repositories", Conditions: []string{"repository_name IS NOT NULL"}, Range: "created_at"}
+	statsSendersPattern      = indexadvisor.Pattern{Name: "stats senders", Conditions: []string{"sender_login IS NOT NULL"}, Range: "created_at"}
+)
+
+// StatsHandler serves aggregate event analytics
+type StatsHandler struct {
+	dbConn  *database.Connection
+	advisor *indexadvisor.Advisor
+}
+
+// NewStatsHandler creates a new stats handler
+func NewStatsHandler(dbConn *database.Connection) *StatsHandler {
+	return &StatsHandler{dbConn: dbConn}
+}
+
+// WithIndexAdvisor makes the handler report the queries it makes on the
+// events table to advisor
+func (sh *StatsHandler) WithIndexAdvisor(advisor *indexadvisor.Advisor) *StatsHandler {
+	sh.advisor = advisor
+	return sh
+}
+
+// dayCount is the number of events of a type stored on a day
+type dayCount struct {
+	Day       string `json:"day"`
+	EventType string `json:"event_type"`
+	Events    int64  `json:"events"`
+}
+
+// activeCount is the number of events of a repository or sender
+type activeCount struct {
+	Name   string `json:"name"`
+	Events int64  `json:"events"`
+}
+
+// statsReport is the response of GET /api/stats
+type statsReport struct {
+	From          time.Time                      `json:"from"`
+	To            time.Time                      `json:"to"`
+	EventsPerDay  []dayCount                     `json:"events_per_day"`
+	Repositories  []activeCount                  `json:"repositories"`
+	Senders       []activeCount                  `json:"senders"`
+	PullRequests  db.CountPullRequestActivityRow `json:"pull_requests"`
+	TotalEvents   int64                          `json:"total_events"`
+	EventsPerType map[string]int64               `json:"events_per_type"`
+}
+
+// HandleStats reports events per UTC day and type, the most active
+// repositories and senders, and pull requests opened, merged and closed in a
+// time range. Query parameters: from and to, as YYYY-MM-DD or RFC 3339, to
+// inclusive for dates (default the last 30 days), and limit, the length of
+// the most active lists (default 10).
+func (sh *StatsHandler) HandleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	from, to, err := parseStatsRange(query.Get("from"), query.Get("to"), time.Now().UTC())
+	switch {
+	case errors.Is(err, errInvalidStatsFrom):
+		http.Error(w, "Invalid from parameter, expected YYYY-MM-DD or RFC 3339", http.StatusBadRequest)
+		return
+	case errors.Is(err, errInvalidStatsTo):
+		http.Error(w, "Invalid to parameter, expected YYYY-MM-DD or RFC 3339", http.StatusBadRequest)
+		return
+	case errors.Is(err, errEmptyStatsRange):
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	case errors.Is(err, errLongStatsRange):
+		http.Error(w, fmt.Sprintf("The range must not exceed %d days", maxStatsDays), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, "Invalid range", http.StatusBadRequest)
+		return
+	}
+	limit := defaultStatsLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxStatsLimit {
+			http.Error(w, "limit must be between 1 and 100", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	if sh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	report, err := sh.load(ctx, from, to, int32(limit))
+	if err != nil {
+		log.Printf("Failed to load event stats: %v", err)
+		http.Error(w, "Failed to load stats", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// load runs the stats queries for a time range
+func (sh *StatsHandler) load(ctx context.Context, from, to time.Time, limit int32) (statsReport, error) {
+	queries := sh.dbConn.Queries()
+	since := pgtype.Timestamptz{Time: from, Valid: true}
+	until := pgtype.Timestamptz{Time: to, Valid: true}
+	report := statsReport{
+		From:          from,
+		To:            to,
+		EventsPerDay:  []dayCount{},
+		Repositories:  []activeCount{},
+		Senders:       []activeCount{},
+		EventsPerType: make(map[string]int64),
+	}
+
+	observe := sh.advisor.Time(statsPerDayPattern)
+	days, err := queries.CountEventsPerDayByType(ctx, db.CountEventsPerDayByTypeParams{Since: since, Until: until})
+	if err != nil {
+		return report, err
+	}
+	observe()
+	for _, row := range days {
+		report.EventsPerDay = append(report.EventsPerDay, dayCount{Day: row.Day.Time.Format("2006-01-02"), EventType: row.EventType, Events: row.Events})
+		report.EventsPerType[row.EventType] += row.Events
+		report.TotalEvents += row.Events
+	}
+
+	observe = sh.advisor.Time(statsRepositoriesPattern)
+	repositories, err := queries.ListMostActiveRepositories(ctx, db.ListMostActiveRepositoriesParams{Since: since, Until: until, RowLimit: limit})
+	if err != nil {
+		return report, err
+	}
+	observe()
+	for _, row := range repositories {
+		report.Repositories = append(report.Repositories, activeCount{Name: row.RepositoryName, Events: row.Events})
+	}
+
+	observe = sh.advisor.Time(statsSendersPattern)
+	senders, err := queries.ListMostActiveSenders(ctx, db.ListMostActiveSendersParams{Since: since, Until: until, RowLimit: limit})
+	if err != nil {
+		return report, err
+	}
+	observe()
+	for _, row := range senders {
+		report.Senders = append(report.Senders, activeCount{Name: row.SenderLogin, Events: row.Events})
+	}
+
+	report.PullRequests, err = queries.CountPullRequestActivity(ctx, db.CountPullRequestActivityParams{Since: since, Until: until})
+	return report, err
+}
+
+// Errors of parseStatsRange
+var (
+	errInvalidStatsFrom = errors.New("invalid from parameter")
+	errInvalidStatsTo   = errors.New("invalid to parameter")
+	errEmptyStatsRange  = errors.New("from is not before to")
+	errLongStatsRange   = errors.New("range too long")
+)
+
+// parseStatsRange parses the from and to parameters of the stats endpoint,
+// returning the half-open range [from, to). Dates are UTC days, and a to
+// date includes its day.
+func parseStatsRange(fromValue, toValue string, now time.Time) (time.Time, time.Time, error) {
+	to := now
+	if toValue != "" {
+		parsed, date, ok := parseStatsTime(toValue)
+		if !ok {
+			return time.Time{}, time.Time{}, errInvalidStatsTo
+		}
+		to = parsed
+		if date {
+			to = to.AddDate(0, 0, 1)
+		}
+	}
+	from := to.AddDate(0, 0, -defaultStatsDays)
+	if fromValue != "" {
+		parsed, _, ok := parseStatsTime(fromValue)
+		if !ok {
+			return time.Time{}, time.Time{}, errInvalidStatsFrom
+		}
+		from = parsed
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, errEmptyStatsRange
+	}
+	if to.Sub(from) > maxStatsDays*24*time.Hour {
+		return time.Time{}, time.Time{}, errLongStatsRange
+	}
+	return from, to, nil
+}
+
+// parseStatsTime parses a YYYY-MM-DD date or an RFC 3339 time, reporting
+// whether it was a date
+func parseStatsTime(value string) (time.Time, bool, bool) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), false, true
+	}
+	return time.Time{}, false, false
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStatsHandler_HandleStats_InvalidMethod(t *testing.T) {
+	handler := NewStatsHandler(nil)
+
+	req := httptest.NewRequest("POST", "/api/stats", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleStats(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestStatsHandler_HandleStats_InvalidParameters(t *testing.T) {
+	handler := NewStatsHandler(nil)
+
+	for _, target := range []string{
+		"/api/stats?from=May",
+		"/api/stats?to=2024-13-01",
+		"/api/stats?from=2024-05-10&to=2024-05-01",
+		"/api/stats?from=2023-01-01&to=2024-05-01",
+		"/api/stats?limit=0",
+		"/api/stats?limit=101",
+	} {
+		req := httptest.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+
+		handler.HandleStats(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusBadRequest, status)
+		}
+	}
+}
+
+func TestStatsHandler_HandleStats_NoDatabase(t *testing.T) {
+	handler := NewStatsHandler(nil)
+
+	req := httptest.NewRequest("GET", "/api/stats?from=2024-05-01&to=2024-05-31&limit=5", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleStats(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestParseStatsRange(t *testing.T) {
+	now := time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+
+	from, to, err := parseStatsRange("", "", now)
+	if err != nil || !to.Equal(now) || !from.Equal(now.AddDate(0, 0, -30)) {
+		t.Errorf("parseStatsRange() default = %v, %v, %v", from, to, err)
+	}
+
+	// A to date includes its day
+	from, to, err = parseStatsRange("2024-05-01", "2024-05-01", now)
+	if err != nil || !from.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseStatsRange() of a day = %v, %v, %v", from, to, err)
+	}
+
+	from, to, err = parseStatsRange("2024-05-01T08:00:00+02:00", "2024-05-01T18:00:00Z", now)
+	if err != nil || !from.Equal(time.Date(2024, 5, 1, 6, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("parseStatsRange() of times = %v, %v, %v", from, to, err)
+	}
+
+	for _, tt := range []struct {
+		from, to string
+		err      error
+	}{
+		{"May", "", errInvalidStatsFrom},
+		{"", "2024-13-01", errInvalidStatsTo},
+		{"2024-05-10", "2024-05-01", errEmptyStatsRange},
+		{"2023-01-01", "2024-05-01", errLongStatsRange},
+	} {
+		if _, _, err := parseStatsRange(tt.from, tt.to, now); !errors.Is(err, tt.err) {
+			t.Errorf("parseStatsRange(%q, %q) error = %v, expected %v", tt.from, tt.to, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/status"
+)
+
+// StatusHandler reports the operational state of each subsystem
+type StatusHandler struct {
+	features *status.Matrix
+}
+
+// NewStatusHandler creates a new status handler
+func NewStatusHandler(features *status.Matrix) *StatusHandler {
+	return &StatusHandler{features: features}
+}
+
+// HandleFeatures reports whether each feature is ok, degraded or disabled,
+// and why
+func (sh *StatusHandler) HandleFeatures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	writeJSON(w, http.StatusOK, sh.features.Report(ctx))
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/status"
+)
+
+func TestStatusHandler_HandleFeatures(t *testing.T) {
+	features := status.NewMatrix()
+	features.Set("nats", status.Disabled, "NATS_URL not set")
+	features.Set("dead_letter", status.Degraded, "spool directory unavailable")
+	handler := NewStatusHandler(features)
+
+	req := httptest.NewRequest("GET", "/api/v1/status/features", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleFeatures(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	var report status.Report
+	if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.State != status.Degraded || len(report.Features) != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestStatusHandler_MethodNotAllowed(t *testing.T) {
+	handler := NewStatusHandler(status.NewMatrix())
+
+	req := httptest.NewRequest("POST", "/api/v1/status/features", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleFeatures(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/status"
+	"github.com/deedubs/choochoo/internal/statuspage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+//go:embed templates/status/*.html
+var statusPageFiles embed.FS
+
+var statusPageTemplate = template.Must(template.ParseFS(statusPageFiles, "templates/status/page.html"))
+
+// StatusPageHandler serves the public status page and the admin API that
+// manages its incidents. The page needs no token: it only tells whether
+// deliveries are accepted and processed, never what they contain.
+type StatusPageHandler struct {
+	title    string
+	tracker  *statuspage.Tracker
+	features *status.Matrix
+	auth     *apitoken.Authenticator
+	dbConn   *database.Connection
+	now      func() time.Time
+}
+
+// NewStatusPageHandler creates a new status page handler. Managing incidents
+// needs a token with the admin scope and a database.
+func NewStatusPageHandler(title string, tracker *statuspage.Tracker, features *status.Matrix, auth *apitoken.Authenticator, dbConn *database.Connection) *StatusPageHandler {
+	return &StatusPageHandler{title: title, tracker: tracker, features: features, auth: auth, dbConn: dbConn, now: time.Now}
+}
+
+// statusPageView is the data of the HTML status page
+type statusPageView struct {
+	Page         statuspage.Page
+	Summary      string
+	Availability string
+	Latency      string
+	LatencyP95   string
+	Hours        []statusPageHour
+}
+
+// statusPageHour is one bar of the last 24 hours
+type statusPageHour struct {
+	Class string
+	Title string
+}
+
+// HandlePage serves the status page as HTML
+func (sh *StatusPageHandler) HandlePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	page := sh.build(r.Context())
+	view := statusPageView{
+		Page:         page,
+		Summary:      statusPageSummary(page.State),
+		Availability: formatPercent(page.Deliveries.Availability),
+		Latency:      formatSeconds(page.Deliveries.LatencySeconds),
+		LatencyP95:   formatSeconds(page.Deliveries.LatencyP95Seconds),
+	}
+	for _, hour := range page.Deliveries.Hours {
+		class := "none"
+		if hour.Availability != nil {
+			class = statuspage.Operational
+			if *hour.Availability < statuspage.DegradedAvailability {
+				class = statuspage.Degraded
+			}
+		}
+		view.Hours = append(view.Hours, statusPageHour{
+			Class: class,
+			Title: fmt.Sprintf("%s: %d deliveries, %s available", hour.Hour.Format("15:04 MST"), hour.Requests, formatPercent(hour.Availability)),
+		})
+	}
+
+	var body bytes.Buffer
+	if err := statusPageTemplate.Execute(&body, view); err != nil {
+		log.Printf("Failed to render status page: %v", err)
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	body.WriteTo(w)
+}
+
+// HandleJSON serves the status page as JSON
+func (sh *StatusPageHandler) HandleJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	writeJSON(w, http.StatusOK, sh.build(r.Context()))
+}
+
+// build assembles the page. Incidents that cannot be loaded are left out
+// rather than taking the page down with the database.
+func (sh *StatusPageHandler) build(ctx context.Context) statuspage.Page {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := sh.now()
+	var incidents []statuspage.Incident
+	if sh.dbConn != nil {
+		rows, err := sh.dbConn.Queries().ListStatusIncidents(ctx, pgtype.Timestamptz{Time: now.Add(-statuspage.ResolvedFor), Valid: true})
+		if err != nil {
+			log.Printf("Failed to list status incidents: %v", err)
+		}
+		for _, row := range rows {
+			incidents = append(incidents, statusIncident(row))
+		}
+	}
+	return statuspage.Build(sh.title, now, sh.tracker.Summary(), sh.features.Report(ctx), incidents)
+}
+
+// statusPageSummary is the banner shown for a page state
+func statusPageSummary(state string) string {
+	switch state {
+	case statuspage.Outage:
+		return "Major outage"
+	case statuspage.Degraded:
+		return "Degraded service"
+	default:
+		return "All systems operational"
+	}
+}
+
+// formatPercent formats an optional percentage for the page
+func formatPercent(percent *float64) string {
+	if percent == nil {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.2f%%", *percent)
+}
+
+// formatSeconds formats an optional latency for the page
+func formatSeconds(seconds *float64) string {
+	if seconds == nil {
+		return "n/a"
+	}
+	return (time.Duration(*seconds * float64(time.Second))).Round(time.Millisecond).String()
+}
+
+// statusIncident converts a stored incident
+func statusIncident(row db.StatusIncident) statuspage.Incident {
+	incident := statuspage.Incident{
+		ID:        row.ID,
+		Title:     row.Title,
+		Body:      row.Body,
+		Impact:    row.Impact,
+		Status:    row.Status,
+		CreatedBy: row.CreatedBy,
+		CreatedAt: row.CreatedAt.Time,
+		UpdatedAt: row.UpdatedAt.Time,
+	}
+	if row.ResolvedAt.Valid {
+		incident.ResolvedAt = &row.ResolvedAt.Time
+	}
+	return incident
+}
+
+// operator checks the request has a token with the admin scope and that a
+// database is configured, returning the name of the token as who made the
+// request
+func (sh *StatusPageHandler) operator(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if !sh.auth.Enabled() {
+		http.Error(w, "Management API not configured", http.StatusServiceUnavailable)
+		return "", false
+	}
+	token, ok := sh.auth.AuthorizeToken(w, r, apitoken.ScopeAdmin)
+	if !ok {
+		return "", false
+	}
+	if sh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return "", false
+	}
+	return token.Name, true
+}
+
+// incidentUpdate is the body of incident requests. Fields left out of a
+// PATCH keep their value.
+type incidentUpdate struct {
+	Title  *string `json:"title"`
+	Body   *string `json:"body"`
+	Impact *string `json:"impact"`
+	Status *string `json:"status"`
+}
+
+// apply sets the fields of the update on incident
+func (u incidentUpdate) apply(incident *statuspage.Incident) {
+	if u.Title != nil {
+		incident.Title = *u.Title
+	}
+	if u.Body != nil {
+		incident.Body = *u.Body
+	}
+	if u.Impact != nil {
+		incident.Impact = *u.Impact
+	}
+	if u.Status != nil {
+		incident.Status = *u.Status
+	}
+}
+
+// HandleIncidents lists every incident, newest first, or posts a new one
+func (sh *StatusPageHandler) HandleIncidents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := sh.operator(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method == http.MethodGet {
+		// Every resolved incident was resolved after the zero time
+		rows, err := sh.dbConn.Queries().ListStatusIncidents(ctx, pgtype.Timestamptz{Valid: true})
+		if err != nil {
+			log.Printf("Failed to list status incidents: %v", err)
+			http.Error(w, "Failed to list incidents", http.StatusInternalServerError)
+			return
+		}
+		incidents := make([]statuspage.Incident, 0, len(rows))
+		for _, row := range rows {
+			incidents = append(incidents, statusIncident(row))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"incidents": incidents})
+		return
+	}
+
+	var update incidentUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	var incident statuspage.Incident
+	update.apply(&incident)
+	if err := incident.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	row, err := sh.dbConn.Queries().CreateStatusIncident(ctx, db.CreateStatusIncidentParams{
+		Title:     incident.Title,
+		Body:      incident.Body,
+		Impact:    incident.Impact,
+		Status:    incident.Status,
+		CreatedBy: operator,
+	})
+	if err != nil {
+		log.Printf("Failed to create status incident: %v", err)
+		http.Error(w, "Failed to create incident", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Status incident %d (%s, %s) posted by %s", row.ID, row.Impact, row.Status, operator)
+	writeJSON(w, http.StatusCreated, statusIncident(row))
+}
+
+// HandleIncident gets, updates or deletes an incident. PATCH changes the
+// fields given; resolving the incident records when, reopening it clears
+// that.
+func (sh *StatusPageHandler) HandleIncident(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPatch && r.Method != http.MethodDelete {
+		http.Error(w, "Only GET, PATCH and DELETE methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	operator, ok := sh.operator(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+	if err != nil {
+		http.Error(w, "Invalid incident ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	queries := sh.dbConn.Queries()
+
+	if r.Method == http.MethodDelete {
+		deleted, err := queries.DeleteStatusIncident(ctx, int32(id))
+		if err != nil {
+			log.Printf("Failed to delete status incident %d: %v", id, err)
+			http.Error(w, "Failed to delete incident", http.StatusInternalServerError)
+			return
+		}
+		if deleted == 0 {
+			http.Error(w, "Incident not found", http.StatusNotFound)
+			return
+		}
+		log.Printf("Status incident %d deleted by %s", id, operator)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	row, err := queries.GetStatusIncident(ctx, int32(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Incident not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to load status incident %d: %v", id, err)
+		http.Error(w, "Failed to load incident", http.StatusInternalServerError)
+		return
+	}
+	incident := statusIncident(row)
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, incident)
+		return
+	}
+
+	var update incidentUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	update.apply(&incident)
+	if err := incident.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	row, err = queries.UpdateStatusIncident(ctx, db.UpdateStatusIncidentParams{
+		Title:  incident.Title,
+		Body:   incident.Body,
+		Impact: incident.Impact,
+		Status: incident.Status,
+		ID:     int32(id),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Incident not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to update status incident %d: %v", id, err)
+		http.Error(w, "Failed to update incident", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Status incident %d (%s, %s) updated by %s", row.ID, row.Impact, row.Status, operator)
+	writeJSON(w, http.StatusOK, statusIncident(row))
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/status"
+	"github.com/deedubs/choochoo/internal/statuspage"
+)
+
+func newTestStatusPageHandler(token string) (*StatusPageHandler, *statuspage.Tracker) {
+	tracker := statuspage.NewTracker()
+	features := status.NewMatrix()
+	features.Set("storage", status.OK, "")
+	return NewStatusPageHandler("Example status", tracker, features, apitoken.NewAuthenticator(token, nil), nil), tracker
+}
+
+func TestStatusPageHandler_HandleJSON(t *testing.T) {
+	handler, tracker := newTestStatusPageHandler("")
+	tracker.ObserveDelivery(http.StatusOK)
+	tracker.ObserveDelivery(http.StatusInternalServerError)
+
+	rr := httptest.NewRecorder()
+	handler.HandleJSON(rr, httptest.NewRequest("GET", "/status.json", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	var page statuspage.Page
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if page.Title != "Example status" || page.State != statuspage.Degraded || page.Ingest != statuspage.Degraded || page.Deliveries.Requests != 2 {
+		t.Errorf("Expected degraded ingest after a failed delivery, got %+v", page)
+	}
+}
+
+func TestStatusPageHandler_HandlePage(t *testing.T) {
+	handler, _ := newTestStatusPageHandler("")
+
+	rr := httptest.NewRecorder()
+	handler.HandlePage(rr, httptest.NewRequest("GET", "/status", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected an HTML page, got %s", ct)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"Example status", "All systems operational", "No active incidents."} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the page to contain %q", want)
+		}
+	}
+
+	rr = httptest.NewRecorder()
+	handler.HandlePage(rr, httptest.NewRequest("POST", "/status", nil))
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, rr.Code)
+	}
+}
+
+func TestStatusPageHandler_Incidents(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		req    *http.Request
+		status int
+	}{
+		{"not configured", "", managementRequest("GET", "/api/v1/status/incidents", "", "secret"), http.StatusServiceUnavailable},
+		{"invalid token", "secret", managementRequest("GET", "/api/v1/status/incidents", "", "wrong"), http.StatusUnauthorized},
+		{"invalid method", "secret", managementRequest("PUT", "/api/v1/status/incidents", "", "secret"), http.StatusMethodNotAllowed},
+		{"no database", "secret", managementRequest("POST", "/api/v1/status/incidents", `{"title":"Delayed processing"}`, "secret"), http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newTestStatusPageHandler(tt.token)
+			rr := httptest.NewRecorder()
+			handler.HandleIncidents(rr, tt.req)
+			if rr.Code != tt.status {
+				t.Errorf("Expected status code %d, got %d", tt.status, rr.Code)
+			}
+		})
+	}
+
+	handler, _ := newTestStatusPageHandler("secret")
+	rr := httptest.NewRecorder()
+	handler.HandleIncident(rr, managementRequest("DELETE", "/api/v1/status/incidents/1", "", "secret", "id", "1"))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d without a database, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/stream"
+)
+
+// sseHeartbeatInterval is how often a keep-alive comment is sent to idle clients
+const sseHeartbeatInterval = 15 * time.Second
+
+// StreamHandler streams received events to clients as Server-Sent Events
+type StreamHandler struct {
+	hub *stream.Hub
+}
+
+// NewStreamHandler creates a new stream handler
+func NewStreamHandler(hub *stream.Hub) *StreamHandler {
+	return &StreamHandler{hub: hub}
+}
+
+// HandleStream streams newly received events. The optional event_type
+// (comma-separated) and repository query parameters filter the stream.
+func (sh *StreamHandler) HandleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := stream.ParseFilter(query.Get("event_type"), query.Get("repository"))
+
+	sub := sh.hub.Subscribe(filter, stream.DefaultBuffer)
+	defer sh.hub.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	log.Printf("Event stream client connected from %s", r.RemoteAddr)
+	defer log.Printf("Event stream client from %s disconnected", r.RemoteAddr)
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case msg := <-sub.C:
+			data, err := json.Marshal(msg)
+			if err != nil {
+				log.Printf("Failed to encode stream message: %v", err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", msg.DeliveryID, msg.EventType, data)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/stream"
+)
+
+func TestStreamHandler_HandleStream_InvalidMethod(t *testing.T) {
+	handler := NewStreamHandler(stream.NewHub())
+
+	req := httptest.NewRequest("POST", "/api/events/stream", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleStream(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestStreamHandler_HandleStream_DeliversFilteredEvents(t *testing.T) {
+	hub := stream.NewHub()
+	server := httptest.NewServer(http.HandlerFunc(NewStreamHandler(hub).HandleStream))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?event_type=push&repository=test/repo")
+	if err != nil {
+		t.Fatalf("Failed to connect to stream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Expected Content-Type text/event-stream, got %s", contentType)
+	}
+
+	// Wait for the subscription to register before publishing
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Subscribers() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	hub.Publish(stream.Message{DeliveryID: "skipped", EventType: "issues", Repository: "test/repo"})
+	hub.Publish(stream.Message{DeliveryID: "wanted", EventType: "push", Repository: "test/repo"})
+
+	reader := bufio.NewReader(resp.Body)
+	var id, event, data string
+	for data == "" {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream: %v", err)
+		}
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimSpace(strings.TrimPrefix(line, "id: "))
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event: "))
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimSpace(strings.TrimPrefix(line, "data: "))
+		}
+	}
+
+	if id != "wanted" || event != "push" {
+		t.Errorf("Expected wanted push event, got id=%s event=%s", id, event)
+	}
+
+	var msg stream.Message
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		t.Fatalf("Failed to parse event data: %v", err)
+	}
+	if msg.Repository != "test/repo" {
+		t.Errorf("Expected repository test/repo, got %s", msg.Repository)
+	}
+}
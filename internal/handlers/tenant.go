@@ -0,0 +1,400 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/tenant"
+)
+
+// TenantHandler serves the self-service API of tenants: the overrides of an
+// organization's own scopes and its API tokens. Tokens limited to the
+// organization and instance tokens may use it.
+type TenantHandler struct {
+	auth   *apitoken.Authenticator
+	dbConn *database.Connection
+}
+
+// NewTenantHandler creates a new tenant handler. Reads need a token with the
+// read scope and changes the admin scope.
+func NewTenantHandler(auth *apitoken.Authenticator, dbConn *database.Connection) *TenantHandler {
+	return &TenantHandler{auth: auth, dbConn: dbConn}
+}
+
+// createdToken is a new tenant token as returned by the API, the only time
+// the token is shown
+type createdToken struct {
+	Name         string   `json:"name"`
+	Organization string   `json:"organization"`
+	Scopes       []string `json:"scopes"`
+	Token        string   `json:"token"`
+}
+
+// authorize checks the request has a token allowed to use scope for the
+// organization in the path, returning the organization and the token
+func (th *TenantHandler) authorize(w http.ResponseWriter, r *http.Request, scope apitoken.Scope) (string, apitoken.Token, bool) {
+	if !th.auth.Enabled() {
+		http.Error(w, "Management API not configured", http.StatusServiceUnavailable)
+		return "", apitoken.Token{}, false
+	}
+	org := r.PathValue("org")
+	token, ok := th.auth.AuthorizeTenant(w, r, org, scope)
+	return org, token, ok
+}
+
+// database checks that a database is configured, writing an error response
+// if it is not
+func (th *TenantHandler) database(w http.ResponseWriter) bool {
+	if th.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// HandleSettings lists the overrides of every scope of the organization
+func (th *TenantHandler) HandleSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	org, _, ok := th.authorize(w, r, apitoken.ScopeRead)
+	if !ok || !th.database(w) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	overrides, err := tenant.ListOverrides(ctx, th.dbConn.Queries(), org)
+	if err != nil {
+		log.Printf("Failed to list overrides of %s: %v", org, err)
+		http.Error(w, "Failed to list overrides", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, overrides)
+}
+
+// HandleSetting reads, creates or replaces, and deletes the override of the
+// setting {name} at the scope given by ?scope=, the organization itself by
+// default. PUT takes a JSON body with the value.
+func (th *TenantHandler) HandleSetting(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		http.Error(w, "Only GET, PUT and DELETE methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	scope := apitoken.ScopeAdmin
+	if r.Method == http.MethodGet {
+		scope = apitoken.ScopeRead
+	}
+	org, token, ok := th.authorize(w, r, scope)
+	if !ok {
+		return
+	}
+
+	name := r.PathValue("name")
+	if !tenant.Allowed(name) {
+		http.Error(w, "Unknown setting", http.StatusNotFound)
+		return
+	}
+	overrideScope := r.URL.Query().Get("scope")
+	if overrideScope == "" {
+		overrideScope = org
+	}
+	override := tenant.Override{Scope: overrideScope, Name: name}
+	if !checkOverride(w, org, override) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		if !th.database(w) {
+			return
+		}
+		overrides, err := tenant.ListOverrides(ctx, th.dbConn.Queries(), org)
+		if err != nil {
+			log.Printf("Failed to list overrides of %s: %v", org, err)
+			http.Error(w, "Failed to list overrides", http.StatusInternalServerError)
+			return
+		}
+		for _, stored := range overrides {
+			if stored.Scope == override.Scope && stored.Name == override.Name {
+				writeJSON(w, http.StatusOK, stored)
+				return
+			}
+		}
+		http.Error(w, "Override not found", http.StatusNotFound)
+
+	case http.MethodPut:
+		var body struct {
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if body.Value == "" {
+			http.Error(w, "Missing value", http.StatusBadRequest)
+			return
+		}
+		override.Value = body.Value
+		if !checkOverride(w, org, override) {
+			return
+		}
+		if !th.set(ctx, w, org, override, token) {
+			return
+		}
+		writeJSON(w, http.StatusOK, override)
+
+	case http.MethodDelete:
+		if !th.set(ctx, w, org, override, token) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// checkOverride checks that org may store override, writing an error
+// response if it may not
+func checkOverride(w http.ResponseWriter, org string, override tenant.Override) bool {
+	err := tenant.CheckOverride(org, override.Scope, override.Name, override.Value)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, tenant.ErrOtherScope), errors.Is(err, tenant.ErrNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+	return false
+}
+
+// set stores override, or removes it without a value, writing an error
+// response on failure
+func (th *TenantHandler) set(ctx context.Context, w http.ResponseWriter, org string, override tenant.Override, token apitoken.Token) bool {
+	if !th.database(w) {
+		return false
+	}
+	err := tenant.SetOverride(ctx, th.dbConn, org, override.Scope, override.Name, override.Value)
+	switch {
+	case err == nil:
+		log.Printf("Override %s %s of %s changed by %s", override.Scope, override.Name, org, token.Name)
+		return true
+	case errors.Is(err, tenant.ErrNotFound):
+		http.Error(w, "Override not found", http.StatusNotFound)
+	default:
+		log.Printf("Failed to update overrides of %s: %v", org, err)
+		http.Error(w, "Failed to update overrides", http.StatusInternalServerError)
+	}
+	return false
+}
+
+// HandleTokens lists the API tokens of the organization. POST creates one
+// from a JSON body with its name and scopes, returning the token once.
+func (th *TenantHandler) HandleTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// Listing tokens is administration, even without their secrets
+	org, token, ok := th.authorize(w, r, apitoken.ScopeAdmin)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method == http.MethodGet {
+		if !th.database(w) {
+			return
+		}
+		tokens, err := tenant.ListTokens(ctx, th.dbConn.Queries(), org)
+		if err != nil {
+			log.Printf("Failed to list tokens of %s: %v", org, err)
+			http.Error(w, "Failed to list tokens", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, tokens)
+		return
+	}
+
+	var body struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if body.Name == "" {
+		http.Error(w, "Missing name", http.StatusBadRequest)
+		return
+	}
+	scopes, err := apitoken.ParseScopes(strings.Join(body.Scopes, ","))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !th.database(w) {
+		return
+	}
+
+	secret, err := apitoken.CreateTenant(ctx, th.dbConn.Queries(), org, body.Name, scopes)
+	if err != nil {
+		log.Printf("Failed to create token %s of %s: %v", body.Name, org, err)
+		http.Error(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Token %s of %s created by %s", body.Name, org, token.Name)
+	writeJSON(w, http.StatusCreated, createdToken{
+		Name:         body.Name,
+		Organization: org,
+		Scopes:       body.Scopes,
+		Token:        secret,
+	})
+}
+
+// HandleToken revokes the API token {name} of the organization
+func (th *TenantHandler) HandleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Only DELETE method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	org, token, ok := th.authorize(w, r, apitoken.ScopeAdmin)
+	if !ok || !th.database(w) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	name := r.PathValue("name")
+	err := apitoken.RevokeTenant(ctx, th.dbConn.Queries(), org, name)
+	if errors.Is(err, apitoken.ErrNotFound) {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to revoke token %s of %s: %v", name, org, err)
+		http.Error(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Token %s of %s revoked by %s", name, org, token.Name)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleTenant is the dashboard page of the tenant {org}: the overrides of
+// its scopes and its API tokens. Tenants sign in with one of their tokens
+// with the admin scope as the basic auth password, instance operators as
+// for the rest of the dashboard. POST makes the change in the form.
+func (ah *AdminHandler) HandleTenant(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Only GET and POST methods are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	org := r.PathValue("org")
+	operator, ok := ah.signIn(w, r, org)
+	if !ok {
+		return
+	}
+	if r.Method == http.MethodPost && !sameOrigin(r) {
+		http.Error(w, "Cross-origin request", http.StatusForbidden)
+		return
+	}
+	if ah.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	status, message, created := http.StatusOK, "", ""
+	if r.Method == http.MethodPost {
+		var err error
+		switch r.FormValue("action") {
+		case "set", "remove":
+			value := ""
+			if r.FormValue("action") == "set" {
+				value = r.FormValue("value")
+			}
+			scope, name := r.FormValue("scope"), r.FormValue("name")
+			if err = tenant.CheckOverride(org, scope, name, value); err != nil {
+				status, message = http.StatusBadRequest, err.Error()
+				break
+			}
+			err = tenant.SetOverride(ctx, ah.dbConn, org, scope, name, value)
+			if err == nil {
+				log.Printf("Override %s %s of %s changed by %s", scope, name, org, operator)
+			}
+		case "create-token":
+			var scopes []apitoken.Scope
+			scopes, err = apitoken.ParseScopes(strings.Join(r.Form["scopes"], ","))
+			if err != nil || r.FormValue("token") == "" {
+				status, message = http.StatusBadRequest, "A token needs a name and at least one scope"
+				break
+			}
+			created, err = apitoken.CreateTenant(ctx, ah.dbConn.Queries(), org, r.FormValue("token"), scopes)
+			if err == nil {
+				log.Printf("Token %s of %s created by %s", r.FormValue("token"), org, operator)
+			}
+		case "revoke-token":
+			err = apitoken.RevokeTenant(ctx, ah.dbConn.Queries(), org, r.FormValue("token"))
+			if err == nil {
+				log.Printf("Token %s of %s revoked by %s", r.FormValue("token"), org, operator)
+			}
+		default:
+			http.Error(w, "Invalid action", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case message != "":
+		case err == nil && created == "":
+			http.Redirect(w, r, "/admin/tenants/"+url.PathEscape(org), http.StatusSeeOther)
+			return
+		case err == nil:
+			// The new token is shown once, so the page is not redirected
+		case errors.Is(err, tenant.ErrNotFound), errors.Is(err, apitoken.ErrNotFound):
+			status, message = http.StatusNotFound, err.Error()
+		default:
+			log.Printf("Failed to update tenant %s: %v", org, err)
+			http.Error(w, "Failed to update tenant", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	overrides, err := tenant.ListOverrides(ctx, ah.dbConn.Queries(), org)
+	if err != nil {
+		log.Printf("Failed to list overrides of %s: %v", org, err)
+		http.Error(w, "Failed to list overrides", http.StatusInternalServerError)
+		return
+	}
+	tokens, err := tenant.ListTokens(ctx, ah.dbConn.Queries(), org)
+	if err != nil {
+		log.Printf("Failed to list tokens of %s: %v", org, err)
+		http.Error(w, "Failed to list tokens", http.StatusInternalServerError)
+		return
+	}
+	renderAdminStatus(w, status, "tenant.html", map[string]interface{}{
+		"Organization": org,
+		"Overrides":    overrides,
+		"Settings":     tenant.Settings,
+		"Tokens":       tokens,
+		"Scopes":       apitoken.Scopes,
+		"Created":      created,
+		"Error":        message,
+		"Banners":      ah.dashboardBanners(r.Context()),
+	})
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/tenant"
+)
+
+// tenantStore is a token store with one token of the acme tenant
+type tenantStore struct {
+	token string
+}
+
+func (s tenantStore) Lookup(ctx context.Context, hash string) (apitoken.Token, error) {
+	if hash != apitoken.Hash(s.token) {
+		return apitoken.Token{}, apitoken.ErrUnauthorized
+	}
+	return apitoken.Token{Name: "acme-admin", Scopes: []apitoken.Scope{apitoken.ScopeAdmin}, Organization: "acme"}, nil
+}
+
+func TestTenantHandler_HandleSetting(t *testing.T) {
+	token, _ := apitoken.Generate()
+	handler := NewTenantHandler(apitoken.NewAuthenticator("secret", tenantStore{token}), nil)
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     string
+		token    string
+		org      string
+		setting  string
+		expected int
+	}{
+		{"other tenant", "GET", "/api/v1/tenants/globex/settings/IGNORED_EVENTS", "", token, "globex", "IGNORED_EVENTS", http.StatusForbidden},
+		{"scope of another tenant", "GET", "/api/v1/tenants/acme/settings/IGNORED_EVENTS?scope=globex/api", "", token, "acme", "IGNORED_EVENTS", http.StatusForbidden},
+		{"instance setting", "GET", "/api/v1/tenants/acme/settings/RETENTION_INTERVAL", "", token, "acme", "RETENTION_INTERVAL", http.StatusNotFound},
+		{"invalid scope", "GET", "/api/v1/tenants/acme/settings/IGNORED_EVENTS?scope=acme/api/x", "", token, "acme", "IGNORED_EVENTS", http.StatusBadRequest},
+		{"invalid value", "PUT", "/api/v1/tenants/acme/settings/RETENTION_MODE?scope=acme/api", `{"value":"shred"}`, token, "acme", "RETENTION_MODE", http.StatusBadRequest},
+		{"missing value", "PUT", "/api/v1/tenants/acme/settings/RETENTION_MODE", `{}`, token, "acme", "RETENTION_MODE", http.StatusBadRequest},
+		{"own tenant", "PUT", "/api/v1/tenants/acme/settings/RETENTION_MODE?scope=acme/api", `{"value":"archive"}`, token, "acme", "RETENTION_MODE", http.StatusServiceUnavailable},
+		{"instance token", "DELETE", "/api/v1/tenants/globex/settings/IGNORED_EVENTS", "", "secret", "globex", "IGNORED_EVENTS", http.StatusServiceUnavailable},
+		{"no token", "GET", "/api/v1/tenants/acme/settings/IGNORED_EVENTS", "", "", "acme", "IGNORED_EVENTS", http.StatusUnauthorized},
+		{"invalid method", "POST", "/api/v1/tenants/acme/settings/IGNORED_EVENTS", "", token, "acme", "IGNORED_EVENTS", http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.HandleSetting(rr, managementRequest(test.method, test.target, test.body, test.token, "org", test.org, "name", test.setting))
+			if rr.Code != test.expected {
+				t.Errorf("Expected status code %d, got %d: %s", test.expected, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestTenantHandler_HandleTokens(t *testing.T) {
+	token, _ := apitoken.Generate()
+	handler := NewTenantHandler(apitoken.NewAuthenticator("", tenantStore{token}), nil)
+
+	tests := []struct {
+		name     string
+		body     string
+		org      string
+		expected int
+	}{
+		{"other tenant", `{"name":"ci","scopes":["read"]}`, "globex", http.StatusForbidden},
+		{"missing name", `{"scopes":["read"]}`, "acme", http.StatusBadRequest},
+		{"unknown scope", `{"name":"ci","scopes":["write"]}`, "acme", http.StatusBadRequest},
+		{"valid", `{"name":"ci","scopes":["read"]}`, "acme", http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.HandleTokens(rr, managementRequest("POST", "/api/v1/tenants/"+test.org+"/tokens", test.body, token, "org", test.org))
+			if rr.Code != test.expected {
+				t.Errorf("Expected status code %d, got %d: %s", test.expected, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAdminHandler_HandleTenant(t *testing.T) {
+	token, _ := apitoken.Generate()
+	handler := NewAdminHandler("admin", "hunter2", apitoken.NewAuthenticator("", tenantStore{token}), nil)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		org      string
+		expected int
+	}{
+		{"tenant token", "acme", token, "acme", http.StatusServiceUnavailable},
+		{"other tenant", "acme", token, "globex", http.StatusForbidden},
+		{"instance operator", "admin", "hunter2", "globex", http.StatusServiceUnavailable},
+		{"wrong password", "admin", "wrong", "acme", http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/admin/tenants/"+test.org, nil)
+			req.SetPathValue("org", test.org)
+			req.SetBasicAuth(test.username, test.password)
+			rr := httptest.NewRecorder()
+			handler.HandleTenant(rr, req)
+			if rr.Code != test.expected {
+				t.Errorf("Expected status code %d, got %d: %s", test.expected, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	// Tenant tokens do not open the rest of the dashboard
+	req := httptest.NewRequest("GET", "/admin", nil)
+	req.SetBasicAuth("acme", token)
+	rr := httptest.NewRecorder()
+	handler.HandleDeliveries(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected status code %d, got %d", http.StatusForbidden, rr.Code)
+	}
+}
+
+func TestRenderAdmin_Tenant(t *testing.T) {
+	revoked := time.Date(2024, 10, 2, 9, 0, 0, 0, time.UTC)
+	rr := httptest.NewRecorder()
+	renderAdmin(rr, "tenant.html", map[string]interface{}{
+		"Organization": "acme",
+		"Overrides":    []tenant.Override{{Scope: "acme/api", Name: "RETENTION_MODE", Value: "archive"}},
+		"Settings":     tenant.Settings,
+		"Tokens": []tenant.Token{
+			{Name: "ci", Scopes: []string{"read", "replay"}, CreatedAt: revoked.Add(-time.Hour)},
+			{Name: "old", Scopes: []string{"admin"}, CreatedAt: revoked.Add(-time.Hour), RevokedAt: &revoked},
+		},
+		"Scopes":  apitoken.Scopes,
+		"Created": "cct_new",
+	})
+
+	body := rr.Body.String()
+	for _, want := range []string{"acme/api", "read, replay", "revoked 2024-10-02 09:00:00", "<code>cct_new</code>", `value="revoke-token"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected page to contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Count(body, `value="revoke-token"`) != 1 {
+		t.Errorf("Expected only active tokens to be revocable:\n%s", body)
+	}
+}
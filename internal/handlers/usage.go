@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/usage"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// UsageHandler serves cost attribution reports
+type UsageHandler struct {
+	dbConn *database.Connection
+	alerts usage.Alerts
+}
+
+// NewUsageHandler creates a new usage handler. alerts decides which rows are
+// flagged as growing abnormally.
+func NewUsageHandler(dbConn *database.Connection, alerts usage.Alerts) *UsageHandler {
+	return &UsageHandler{dbConn: dbConn, alerts: alerts}
+}
+
+// HandleReport attributes a month's storage and processing to repositories.
+// Query parameters: month (YYYY-MM, default the current month),
+// group=repository|owner (default repository) and format=csv.
+func (uh *UsageHandler) HandleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	month, err := usage.ParseMonth(query.Get("month"), time.Now().UTC())
+	if err != nil {
+		http.Error(w, "Invalid month parameter", http.StatusBadRequest)
+		return
+	}
+
+	group := query.Get("group")
+	if group == "" {
+		group = usage.GroupRepository
+	}
+	if group != usage.GroupRepository && group != usage.GroupOwner {
+		http.Error(w, "Invalid group parameter", http.StatusBadRequest)
+		return
+	}
+
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		http.Error(w, "Invalid format parameter", http.StatusBadRequest)
+		return
+	}
+
+	if uh.dbConn == nil {
+		http.Error(w, "Database not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	load := LoadUsage(uh.dbConn.Queries())
+	current, err := load(ctx, month)
+	var previous []db.RepositoryUsage
+	if err == nil {
+		previous, err = load(ctx, month.AddDate(0, -1, 0))
+	}
+	if err != nil {
+		log.Printf("Failed to load repository usage: %v", err)
+		http.Error(w, "Failed to load usage", http.StatusInternalServerError)
+		return
+	}
+
+	report := usage.BuildReport(month, group, current, previous, uh.alerts)
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="usage-`+report.Month+`.csv"`)
+		w.WriteHeader(http.StatusOK)
+		usage.WriteCSV(w, report)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// LoadUsage returns a usage.LoadFunc reading the repository_usage table
+func LoadUsage(queries *db.Queries) usage.LoadFunc {
+	return func(ctx context.Context, month time.Time) ([]db.RepositoryUsage, error) {
+		return queries.ListRepositoryUsage(ctx, pgtype.Date{Time: month, Valid: true})
+	}
+}
+
+// recordUsage adds to the usage attributed to a repository. Failures are
+// only logged, since attribution must not hold up processing.
+func (wh *WebhookHandler) recordUsage(ctx context.Context, repoName string, events, bytesStored int64, processing time.Duration) {
+	if wh.dbConn == nil {
+		return
+	}
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	err := wh.dbConn.Queries().AddRepositoryUsage(dbCtx, db.AddRepositoryUsageParams{
+		RepositoryName: optionalText(repoName).String,
+		Events:         events,
+		BytesStored:    bytesStored,
+		ProcessingUs:   processing.Microseconds(),
+	})
+	if err != nil {
+		log.Printf("Failed to record usage of %s: %v", repoName, err)
+	}
+}
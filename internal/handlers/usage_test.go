@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/usage"
+)
+
+func TestUsageHandler_HandleReport_InvalidMethod(t *testing.T) {
+	handler := NewUsageHandler(nil, usage.Alerts{})
+
+	req := httptest.NewRequest("POST", "/api/usage", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleReport(rr, req)
+
+	if status := rr.Code; status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, status)
+	}
+}
+
+func TestUsageHandler_HandleReport_InvalidParameters(t *testing.T) {
+	handler := NewUsageHandler(nil, usage.Alerts{})
+
+	for _, target := range []string{"/api/usage?month=May", "/api/usage?group=team", "/api/usage?format=xml"} {
+		req := httptest.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+
+		handler.HandleReport(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", target, http.StatusBadRequest, status)
+		}
+	}
+}
+
+func TestUsageHandler_HandleReport_NoDatabase(t *testing.T) {
+	handler := NewUsageHandler(nil, usage.Alerts{})
+
+	req := httptest.NewRequest("GET", "/api/usage?month=2024-05&group=owner&format=csv", nil)
+	rr := httptest.NewRecorder()
+
+	handler.HandleReport(rr, req)
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
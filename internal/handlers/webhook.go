@@ -3,64 +3,453 @@ package handlers
 import (
 	"context"
 	"crypto/hmac"
+	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
 	"io"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/deedubs/choochoo/internal/auditlog"
+	"github.com/deedubs/choochoo/internal/chatops"
+	"github.com/deedubs/choochoo/internal/checkrun"
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/config"
 	"github.com/deedubs/choochoo/internal/database"
 	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/deadletter"
+	"github.com/deedubs/choochoo/internal/discussion"
+	"github.com/deedubs/choochoo/internal/docs"
+	"github.com/deedubs/choochoo/internal/encryption"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/github"
+	"github.com/deedubs/choochoo/internal/outbox"
+	"github.com/deedubs/choochoo/internal/pathlabels"
+	"github.com/deedubs/choochoo/internal/pipeline"
+	"github.com/deedubs/choochoo/internal/project"
+	"github.com/deedubs/choochoo/internal/receipt"
+	"github.com/deedubs/choochoo/internal/redact"
+	"github.com/deedubs/choochoo/internal/relay"
+	"github.com/deedubs/choochoo/internal/rules"
+	"github.com/deedubs/choochoo/internal/security"
+	"github.com/deedubs/choochoo/internal/settings"
+	"github.com/deedubs/choochoo/internal/tracing"
 	"github.com/deedubs/choochoo/internal/webhook"
+	"github.com/deedubs/choochoo/internal/workqueue"
 	"github.com/jackc/pgx/v5/pgtype"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// DefaultMaxBodySize matches the 25 MB cap GitHub puts on webhook payloads
+const DefaultMaxBodySize = 25 << 20
+
+// ErrEventNotFound is returned when replaying a delivery that is not stored
+var ErrEventNotFound = database.ErrEventNotFound
+
 // WebhookHandler handles GitHub webhook requests
 type WebhookHandler struct {
-	webhookSecret string
-	dbConn        *database.Connection
+	// webhookSecrets holds every accepted secret, so the secret can be
+	// rotated without rejecting deliveries signed with the old one
+	webhookSecrets []string
+	// sha1Fallback accepts the legacy SHA-1 X-Hub-Signature header on
+	// deliveries without an X-Hub-Signature-256 header
+	sha1Fallback bool
+	// relays verifies deliveries signed by an edge relay or another
+	// instance, if any
+	relays         *relay.Verifier
+	maxBodySize    int64
+	dbConn         *database.Connection
+	events         database.EventStore
+	forwarders     []forwarder.Forwarder
+	securityRouter *security.Router
+	deadLetter     *deadletter.Spool
+	// auditAlerts lists the audit log actions routed to the security
+	// channels
+	auditAlerts map[string]bool
+
+	discussionRouter *discussion.Router
+	commands         *chatops.Registry
+	projectRouter    *project.Router
+	docsRouter       *docs.Router
+
+	// notifyQueue is set when stored events are processed by the work queue
+	notifyQueue func()
+	// outbox holds the forwarders that stored events are published to by
+	// the outbox relay, which notifyOutbox wakes
+	outbox       []forwarder.Forwarder
+	notifyOutbox func()
+	// batch writes stored events together with those of concurrent requests
+	batch *database.BatchWriter
+	// processors runs each processing step within its own limits
+	processors *pipeline.Runner
+	// settings holds the organization, repository and branch overrides
+	settings *settings.Bundle
+	// configLint reports problems in repository settings files as check runs
+	configLint *github.Client
+	// receipts posts processing receipts back to GitHub
+	receipts *receipt.Poster
+	// pipelineChecks reports the processing of pushes and pull requests as
+	// check runs
+	pipelineChecks *checkrun.Reporter
+	// pathLabels labels pull requests by the paths they change
+	pathLabels *pathlabels.Labeler
+	// commentPolicy lists the slash commands that can be run from issue and
+	// pull request comments, with the repository role each needs
+	commentPolicy chatops.Policy
+	// commentClient checks the roles of commenters and dispatches their
+	// commands
+	commentClient *github.Client
+	// redactor scrubs personal data and secrets from payloads before they
+	// are stored or processed
+	redactor *redact.Redactor
+	// keys encrypts payloads before they are stored
+	keys *encryption.Keyring
+	// rules runs the operator-defined rules on each processed event
+	rules *rules.Engine
+	// observeLatency is told how long each event took from being received
+	// to being processed
+	observeLatency func(time.Duration)
+	// queueDepth reads the number of events waiting in the work queue
+	queueDepth *workqueue.Depth
+	// invalidate is told of each stored and processed event, to drop the
+	// cached query results that depend on it
+	invalidate func(eventType, repository string)
 }
 
-// NewWebhookHandler creates a new webhook handler
+// Diagnostic headers of webhook responses, which GitHub shows in the
+// delivery log of each hook
+const (
+	// eventIDHeader is the delivery ID the event was stored under, set only
+	// when it was stored
+	eventIDHeader = "X-Choochoo-Event-ID"
+	// processingHeader tells how the delivery was handled
+	processingHeader = "X-Choochoo-Processing"
+	// queueDepthHeader is the number of events waiting in the work queue
+	queueDepthHeader = "X-Choochoo-Queue-Depth"
+)
+
+// Values of the processing header
+const (
+	processingRejected  = "rejected"
+	processingPing      = "ping"
+	processingDuplicate = "duplicate"
+	processingQueued    = "queued"
+	processingProcessed = "processed"
+	processingFailed    = "failed"
+)
+
+// NewWebhookHandler creates a new webhook handler. secret may list several
+// comma-separated secrets, any of which is accepted.
 func NewWebhookHandler(secret string, dbConn *database.Connection) *WebhookHandler {
-	return &WebhookHandler{
-		webhookSecret: secret,
-		dbConn:        dbConn,
+	wh := &WebhookHandler{
+		webhookSecrets: config.ParseSecrets(secret),
+		maxBodySize:    DefaultMaxBodySize,
+		dbConn:         dbConn,
+		auditAlerts:    auditlog.DefaultAlertActions,
+		processors:     pipeline.NewRunner(pipeline.Limits{Timeout: pipeline.DefaultTimeout, Concurrency: pipeline.DefaultConcurrency}, nil),
+	}
+	if dbConn != nil {
+		wh.events = dbConn
 	}
+	return wh
 }
 
-// validateSignature validates the GitHub webhook signature
-func (wh *WebhookHandler) validateSignature(payload []byte, signature string) bool {
-	if wh.webhookSecret == "" {
-		return true // Skip validation if no secret is set
+// WithEventStore sets where received events are stored and replayed from,
+// such as a SQLite store when there is no PostgreSQL connection, or a memory
+// store in tests
+func (wh *WebhookHandler) WithEventStore(events database.EventStore) *WebhookHandler {
+	wh.events = events
+	return wh
+}
+
+// WithSettings sets the settings hierarchy that decides, per repository and
+// branch, which events are stored
+func (wh *WebhookHandler) WithSettings(bundle *settings.Bundle) *WebhookHandler {
+	wh.settings = bundle
+	return wh
+}
+
+// WithConfigLint lints repository settings files when pushes change them,
+// reporting the problems as check runs through client
+func (wh *WebhookHandler) WithConfigLint(client *github.Client) *WebhookHandler {
+	wh.configLint = client
+	return wh
+}
+
+// WithReceipts posts a receipt to GitHub for each processed event that
+// matches the events of poster
+func (wh *WebhookHandler) WithReceipts(poster *receipt.Poster) *WebhookHandler {
+	wh.receipts = poster
+	return wh
+}
+
+// WithPipelineChecks reports the processing of each push and pull request on
+// a check run of its commit
+func (wh *WebhookHandler) WithPipelineChecks(reporter *checkrun.Reporter) *WebhookHandler {
+	wh.pipelineChecks = reporter
+	return wh
+}
+
+// WithPathLabels labels opened, reopened and synchronized pull requests by
+// the paths they change
+func (wh *WebhookHandler) WithPathLabels(labeler *pathlabels.Labeler) *WebhookHandler {
+	wh.pathLabels = labeler
+	return wh
+}
+
+// WithCommentCommands runs the slash commands of policy posted in new issue
+// and pull request comments by authors with the role each needs, checked
+// and dispatched through client
+func (wh *WebhookHandler) WithCommentCommands(policy chatops.Policy, client *github.Client) *WebhookHandler {
+	wh.commentPolicy = policy
+	wh.commentClient = client
+	return wh
+}
+
+// WithRedactor scrubs payloads with redactor as soon as they are parsed, so
+// neither the stored events nor the processors and forwarders see what it
+// removes
+func (wh *WebhookHandler) WithRedactor(redactor *redact.Redactor) *WebhookHandler {
+	wh.redactor = redactor
+	return wh
+}
+
+// WithEncryption encrypts payloads with keys before they are stored, and
+// decrypts them when stored events are processed
+func (wh *WebhookHandler) WithEncryption(keys *encryption.Keyring) *WebhookHandler {
+	wh.keys = keys
+	return wh
+}
+
+// WithMaxBodySize sets the largest request body that is accepted
+func (wh *WebhookHandler) WithMaxBodySize(limit int64) *WebhookHandler {
+	wh.maxBodySize = limit
+	return wh
+}
+
+// WithProcessors sets the runner that applies per-processor timeouts and
+// concurrency caps. It should be shared by every handler of a server so the
+// caps hold across requests and work queue workers.
+func (wh *WebhookHandler) WithProcessors(runner *pipeline.Runner) *WebhookHandler {
+	wh.processors = runner
+	return wh
+}
+
+// WithForwarders sets the forwarders that received events are published to
+func (wh *WebhookHandler) WithForwarders(forwarders ...forwarder.Forwarder) *WebhookHandler {
+	wh.forwarders = forwarders
+	return wh
+}
+
+// WithSecurityRouter sets the router used to deliver security alerts to
+// security channels
+func (wh *WebhookHandler) WithSecurityRouter(router *security.Router) *WebhookHandler {
+	wh.securityRouter = router
+	return wh
+}
+
+// WithAuditAlerts routes audit log entries with one of actions to the
+// security channels
+func (wh *WebhookHandler) WithAuditAlerts(actions map[string]bool) *WebhookHandler {
+	wh.auditAlerts = actions
+	return wh
+}
+
+// WithDeadLetter sets the spool that events are written to when they cannot
+// be stored in the database
+func (wh *WebhookHandler) WithDeadLetter(spool *deadletter.Spool) *WebhookHandler {
+	wh.deadLetter = spool
+	return wh
+}
+
+// WithDiscussionRouter sets the router used to deliver discussion activity to
+// the channels for each discussion category
+func (wh *WebhookHandler) WithDiscussionRouter(router *discussion.Router) *WebhookHandler {
+	wh.discussionRouter = router
+	return wh
+}
+
+// WithProjectRouter sets the router used to notify channels when project
+// items enter a column
+func (wh *WebhookHandler) WithProjectRouter(router *project.Router) *WebhookHandler {
+	wh.projectRouter = router
+	return wh
+}
+
+// WithDocsRouter sets the router used to notify channels about wiki changes
+// and failed GitHub Pages builds
+func (wh *WebhookHandler) WithDocsRouter(router *docs.Router) *WebhookHandler {
+	wh.docsRouter = router
+	return wh
+}
+
+// WithWorkQueue makes stored events go through the work queue instead of
+// being processed during the request. notify wakes the queue's workers.
+func (wh *WebhookHandler) WithWorkQueue(notify func()) *WebhookHandler {
+	wh.notifyQueue = notify
+	return wh
+}
+
+// WithOutbox makes stored events be published to forwarders by the outbox
+// relay, which notify wakes, instead of during processing. Events that are
+// not stored are still published to them during processing.
+func (wh *WebhookHandler) WithOutbox(notify func(), forwarders ...forwarder.Forwarder) *WebhookHandler {
+	wh.notifyOutbox = notify
+	wh.outbox = forwarders
+	return wh
+}
+
+// WithBatchWriter makes events be stored in batches with the events of
+// concurrent requests, on PostgreSQL
+func (wh *WebhookHandler) WithBatchWriter(batch *database.BatchWriter) *WebhookHandler {
+	wh.batch = batch
+	return wh
+}
+
+// WithCommands sets the registry of slash commands that can be run from
+// discussion comments
+func (wh *WebhookHandler) WithCommands(commands *chatops.Registry) *WebhookHandler {
+	wh.commands = commands
+	return wh
+}
+
+// WithLatencyObserver sets a function told how long each event took from
+// being received to being processed, through the queue when there is one.
+// Replays are not observed.
+func (wh *WebhookHandler) WithLatencyObserver(observe func(time.Duration)) *WebhookHandler {
+	wh.observeLatency = observe
+	return wh
+}
+
+// WithQueueDepth reports the depth of the work queue in the response
+// headers of each delivery
+func (wh *WebhookHandler) WithQueueDepth(depth *workqueue.Depth) *WebhookHandler {
+	wh.queueDepth = depth
+	return wh
+}
+
+// WithRules runs the operator-defined rules on each processed event
+func (wh *WebhookHandler) WithRules(engine *rules.Engine) *WebhookHandler {
+	wh.rules = engine
+	return wh
+}
+
+// WithSHA1Fallback sets whether the legacy SHA-1 X-Hub-Signature header is
+// validated on deliveries that lack the X-Hub-Signature-256 header, as sent
+// by older GitHub Enterprise Server versions and some proxies
+func (wh *WebhookHandler) WithSHA1Fallback(enabled bool) *WebhookHandler {
+	wh.sha1Fallback = enabled
+	return wh
+}
+
+// WithRelays accepts deliveries relayed by the relays of verifier, verifying
+// both the relay signature and GitHub's
+func (wh *WebhookHandler) WithRelays(verifier *relay.Verifier) *WebhookHandler {
+	wh.relays = verifier
+	return wh
+}
+
+// WithInvalidation sets a function told of each event once it is stored and
+// again once it is processed, such as the invalidation of a query cache
+func (wh *WebhookHandler) WithInvalidation(invalidate func(eventType, repository string)) *WebhookHandler {
+	wh.invalidate = invalidate
+	return wh
+}
+
+// verifyProvenance verifies the signatures of a delivery and returns its
+// verification chain, empty for unsigned deliveries accepted without a
+// secret. A delivery signed by a relay must still prove it came from GitHub,
+// by GitHub's signature of the payload or by a GitHub link in the chain the
+// relay signed.
+func (wh *WebhookHandler) verifyProvenance(payload []byte, header http.Header) (relay.Chain, error) {
+	signature := header.Get("X-Hub-Signature-256")
+	if signature == "" && wh.sha1Fallback {
+		signature = header.Get("X-Hub-Signature")
 	}
 
-	if !strings.HasPrefix(signature, "sha256=") {
-		return false
+	var chain relay.Chain
+	if wh.relays != nil && header.Get(relay.SignatureHeader) != "" {
+		var err error
+		if chain, err = wh.relays.Verify(payload, header); err != nil {
+			return nil, err
+		}
+		if signature == "" && len(wh.webhookSecrets) > 0 && !chain.FromGitHub() {
+			return nil, errors.New("relayed delivery carries no GitHub signature")
+		}
+	}
+	if signature == "" && chain != nil {
+		return chain, nil
+	}
+	if !wh.verifySignature(payload, header) {
+		return nil, errors.New("invalid signature")
 	}
+	if len(wh.webhookSecrets) == 0 {
+		return chain, nil
+	}
+	return append(relay.Chain{{Signer: relay.GitHub, Signature: signature}}, chain...), nil
+}
+
+// verifySignature validates the signature headers of a delivery, falling
+// back to the SHA-1 signature when enabled and the SHA-256 one is absent
+func (wh *WebhookHandler) verifySignature(payload []byte, header http.Header) bool {
+	signature := header.Get("X-Hub-Signature-256")
+	if legacy := header.Get("X-Hub-Signature"); signature == "" && legacy != "" && wh.sha1Fallback {
+		return wh.validateLegacySignature(payload, legacy)
+	}
+	return wh.validateSignature(payload, signature)
+}
+
+// validateSignature validates the GitHub webhook signature against each
+// configured secret
+func (wh *WebhookHandler) validateSignature(payload []byte, signature string) bool {
+	return wh.matchSignature(payload, signature, "sha256=", sha256.New)
+}
 
-	// Remove "sha256=" prefix
-	providedSignature := signature[7:]
+// validateLegacySignature validates a SHA-1 X-Hub-Signature against each
+// configured secret
+func (wh *WebhookHandler) validateLegacySignature(payload []byte, signature string) bool {
+	return wh.matchSignature(payload, signature, "sha1=", sha1.New)
+}
 
-	// Compute the expected signature
-	mac := hmac.New(sha256.New, []byte(wh.webhookSecret))
-	mac.Write(payload)
-	expectedSignature := hex.EncodeToString(mac.Sum(nil))
+// matchSignature reports whether signature, the hex HMAC of payload with
+// prefix, was made with any configured secret
+func (wh *WebhookHandler) matchSignature(payload []byte, signature, prefix string, hash func() hash.Hash) bool {
+	if len(wh.webhookSecrets) == 0 {
+		return true // Skip validation if no secret is set
+	}
 
-	// Compare signatures using hmac.Equal for constant-time comparison
-	providedBytes, err := hex.DecodeString(providedSignature)
-	if err != nil {
+	if !strings.HasPrefix(signature, prefix) {
 		return false
 	}
-	expectedBytes, err := hex.DecodeString(expectedSignature)
+
+	// Remove the algorithm prefix
+	providedBytes, err := hex.DecodeString(signature[len(prefix):])
 	if err != nil {
 		return false
 	}
 
-	return hmac.Equal(providedBytes, expectedBytes)
+	for _, secret := range wh.webhookSecrets {
+		// Compute the expected signature
+		mac := hmac.New(hash, []byte(secret))
+		mac.Write(payload)
+
+		// Compare signatures using hmac.Equal for constant-time comparison
+		if hmac.Equal(providedBytes, mac.Sum(nil)) {
+			return true
+		}
+	}
+	return false
 }
 
 // HandleWebhook processes incoming GitHub webhook requests
@@ -69,10 +458,41 @@ func (wh *WebhookHandler) HandleWebhook(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	received := time.Now()
+	// Error responses before the delivery is accepted report it as rejected
+	w.Header().Set(processingHeader, processingRejected)
+
+	// Continue the sender's trace, if any, so the delivery can be followed
+	// through storage, processing and fan-out
+	ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), "webhook.receive")
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	// GitHub sends JSON, or a form with the JSON in its payload field when
+	// the hook is configured with the application/x-www-form-urlencoded type
+	formEncoded := false
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		switch {
+		case err == nil && mediaType == "application/json":
+		case err == nil && mediaType == "application/x-www-form-urlencoded":
+			formEncoded = true
+		default:
+			log.Printf("Unsupported content type %q", contentType)
+			http.Error(w, "Content-Type must be application/json or application/x-www-form-urlencoded", http.StatusUnsupportedMediaType)
+			return
+		}
+	}
 
 	// Read the request body
-	body, err := io.ReadAll(r.Body)
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, wh.maxBodySize))
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Printf("Request body exceeds %d bytes", tooLarge.Limit)
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		log.Printf("Error reading request body: %v", err)
 		http.Error(w, "Error reading request body", http.StatusBadRequest)
 		return
@@ -82,15 +502,27 @@ func (wh *WebhookHandler) HandleWebhook(w http.ResponseWriter, r *http.Request)
 	// Get GitHub headers
 	eventType := r.Header.Get("X-GitHub-Event")
 	deliveryID := r.Header.Get("X-GitHub-Delivery")
-	signature := r.Header.Get("X-Hub-Signature-256")
+	span.SetAttributes(attribute.String("github.event", eventType), attribute.String("github.delivery", deliveryID))
 
 	// Validate signature if webhook secret is configured
-	if !wh.validateSignature(body, signature) {
-		log.Printf("Invalid signature for delivery %s", deliveryID)
+	chain, err := wh.verifyProvenance(body, r.Header)
+	if err != nil {
+		log.Printf("Invalid signature for delivery %s: %v", deliveryID, err)
 		http.Error(w, "Invalid signature", http.StatusUnauthorized)
 		return
 	}
 
+	// The signature covers the form as sent, so decode it only once verified
+	if formEncoded {
+		form, err := url.ParseQuery(string(body))
+		if err != nil || form.Get("payload") == "" {
+			log.Printf("Form-encoded delivery %s has no payload field", deliveryID)
+			http.Error(w, "Missing payload form field", http.StatusBadRequest)
+			return
+		}
+		body = []byte(form.Get("payload"))
+	}
+
 	// Parse the JSON payload
 	var event webhook.GitHubEvent
 	if err := json.Unmarshal(body, &event); err != nil {
@@ -99,6 +531,17 @@ func (wh *WebhookHandler) HandleWebhook(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Scrub the payload before anything stores it
+	if wh.redactor.Enabled() {
+		redacted, err := wh.redactor.Redact(body)
+		if err != nil {
+			log.Printf("Error redacting payload: %v", err)
+			http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+			return
+		}
+		body = redacted
+	}
+
 	// Log the webhook event
 	repoName := "unknown"
 	if event.Repository != nil {
@@ -114,67 +557,642 @@ func (wh *WebhookHandler) HandleWebhook(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	log.Printf("Received %s event from %s (delivery: %s, sender: %s)", 
+	span.SetAttributes(attribute.String("github.repository", repoName))
+	log.Printf("Received %s event from %s (delivery: %s, sender: %s)",
 		eventType, repoName, deliveryID, senderLogin)
 
 	if event.Action != "" {
 		log.Printf("Event action: %s", event.Action)
 	}
 
-	// Store supported events in database
-	if wh.dbConn != nil && webhook.IsSupportedEvent(eventType) {
-		if err := wh.storeWebhookEvent(r.Context(), eventType, deliveryID, repoName, senderLogin, event.Action, body); err != nil {
+	// Answer pings with the hook they describe
+	if eventType == webhook.PingEvent {
+		w.Header().Set(processingHeader, processingPing)
+		wh.handlePing(r.Context(), w, deliveryID, body)
+		return
+	}
+
+	// Store supported events in database, unless the settings of the
+	// repository or branch ignore them
+	queued, outboxed := false, false
+	if wh.settings != nil && webhook.IsSupportedEvent(eventType) &&
+		wh.settings.IgnoresEvent(repoName, webhook.ParseBranch(body), eventType) {
+		log.Printf("Event type %s is ignored for %s", eventType, repoName)
+	} else if wh.events != nil && webhook.IsSupportedEvent(eventType) {
+		result, err := wh.storeWebhookEvent(r.Context(), eventType, deliveryID, repoName, senderLogin, event.Action, body)
+		switch {
+		case err != nil:
 			log.Printf("Failed to store webhook event in database: %v", err)
 			// Don't fail the webhook processing if database storage fails
-		} else {
+		case result == database.EventDuplicate:
+			// Another attempt or replica already processed this delivery
+			log.Printf("Delivery %s already stored, skipping duplicate", deliveryID)
+			w.Header().Set(eventIDHeader, deliveryID)
+			w.Header().Set(processingHeader, processingDuplicate)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(map[string]string{
+				"status":  "success",
+				"message": "Duplicate delivery ignored",
+			})
+			return
+		case wh.notifyQueue != nil:
+			log.Printf("Queued %s event for processing (delivery: %s)", eventType, deliveryID)
+			queued = true
+			wh.notifyQueue()
+		default:
 			log.Printf("Successfully stored %s event in database (delivery: %s)", eventType, deliveryID)
 		}
+		if err == nil {
+			w.Header().Set(eventIDHeader, deliveryID)
+			wh.recordProvenance(r.Context(), deliveryID, chain)
+			if wh.invalidate != nil {
+				wh.invalidate(eventType, repoName)
+			}
+		}
+		if err == nil && wh.notifyOutbox != nil {
+			outboxed = true
+			wh.notifyOutbox()
+		}
 	} else if !webhook.IsSupportedEvent(eventType) {
-		log.Printf("Event type %s is not stored in database (only push, issue_comment, and pull_request events are stored)", eventType)
+		log.Printf("Event type %s is not stored in database", eventType)
+	}
+
+	// Run the event through the processing pipeline and forwarders, unless a
+	// worker will pick it up from the queue. Events that could not be stored
+	// are still processed here.
+	message := "Webhook received and queued"
+	processing := processingQueued
+	if !queued {
+		err := wh.process(r.Context(), eventType, deliveryID, event.Action, repoName, senderLogin, body, outboxed)
+		message = "Webhook received and processed"
+		processing = processingProcessed
+		if err != nil {
+			processing = processingFailed + ": " + strings.Join(failedProcessors(err), ", ")
+		}
+		if wh.observeLatency != nil {
+			wh.observeLatency(time.Since(received))
+		}
 	}
+	w.Header().Set(processingHeader, processing)
+	wh.setQueueDepth(r.Context(), w)
 
 	// Send successful response
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	response := map[string]string{
 		"status":  "success",
-		"message": "Webhook received and processed",
+		"message": message,
 	}
 	json.NewEncoder(w).Encode(response)
 }
 
-// storeWebhookEvent stores a webhook event in the database
-func (wh *WebhookHandler) storeWebhookEvent(ctx context.Context, eventType, deliveryID, repoName, senderLogin, action string, payload []byte) error {
-	// Create a context with timeout for database operations
+// ProcessorError identifies the processing step that failed on an event
+type ProcessorError struct {
+	Processor string
+	Err       error
+}
+
+func (e *ProcessorError) Error() string {
+	return e.Processor + ": " + e.Err.Error()
+}
+
+func (e *ProcessorError) Unwrap() error {
+	return e.Err
+}
+
+// FailedProcessor names the failed step for the work queue's quarantine
+func (e *ProcessorError) FailedProcessor() string {
+	return e.Processor
+}
+
+// failedProcessors names the processors whose ProcessorErrors err holds
+func failedProcessors(err error) []string {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	var names []string
+	for _, err := range errs {
+		var failed *ProcessorError
+		if errors.As(err, &failed) {
+			names = append(names, failed.Processor)
+		}
+	}
+	return names
+}
+
+// setQueueDepth reports the depth of the work queue in the response headers,
+// leaving it out if the queue cannot be read
+func (wh *WebhookHandler) setQueueDepth(ctx context.Context, w http.ResponseWriter) {
+	if wh.queueDepth == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	depth, err := wh.queueDepth.Get(ctx)
+	if err != nil {
+		log.Printf("Failed to read the work queue depth: %v", err)
+		return
+	}
+	w.Header().Set(queueDepthHeader, strconv.FormatInt(depth, 10))
+}
+
+// runProcessor runs one processing step within its limits, so a slow or
+// panicking step cannot take down the server or hold up the other steps
+func (wh *WebhookHandler) runProcessor(ctx context.Context, name, deliveryID string, step func(ctx context.Context) error) error {
+	ctx, span := tracing.Start(ctx, "processor "+name)
+	err := wh.processors.Run(ctx, name, step)
+	tracing.End(span, err)
+	if err != nil {
+		log.Printf("Processor %s failed (delivery: %s): %v", name, deliveryID, err)
+		return &ProcessorError{Processor: name, Err: err}
+	}
+	return nil
+}
+
+// process runs an event through every processing step concurrently and
+// publishes it to the forwarders, leaving the outbox forwarders to the relay
+// when outboxed is set. A failing step does not stop the others; the
+// failures are returned as ProcessorErrors. Steps writing to the database
+// never share a connection: PostgreSQL queries each take one from the pool,
+// and SQLite ones wait for its single connection.
+func (wh *WebhookHandler) process(ctx context.Context, eventType, deliveryID, action, repoName, senderLogin string, body []byte, outboxed bool) (err error) {
+	ctx, span := tracing.Start(ctx, "webhook.process",
+		attribute.String("github.event", eventType),
+		attribute.String("github.delivery", deliveryID),
+		attribute.String("github.repository", repoName),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	// Report the steps on a check run of the commit of pushes and pull
+	// requests
+	check := wh.startCheck(ctx, eventType, deliveryID, repoName, body)
+
+	start := time.Now()
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	run := func(name string, step func(ctx context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started := time.Now()
+			err := wh.runProcessor(ctx, name, deliveryID, step)
+			check.Record(name, errors.Unwrap(err), time.Since(started))
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	// Normalize pushed commits for analytics
+	if eventType == webhook.PushEvent {
+		run("push", func(ctx context.Context) error { return wh.processPush(ctx, deliveryID, body) })
+	}
+
+	// Lint repository settings files changed by the push
+	if eventType == webhook.PushEvent && wh.configLint != nil {
+		run("config_lint", func(ctx context.Context) error { return wh.processConfigLint(ctx, deliveryID, body) })
+	}
+
+	// Keep the latest state of each pull request for analytics
+	if eventType == webhook.PullRequestEvent {
+		run("pull_request", func(ctx context.Context) error { return wh.processPullRequest(ctx, deliveryID, body) })
+	}
+
+	// Label pull requests by the paths they change
+	if eventType == webhook.PullRequestEvent && wh.pathLabels != nil {
+		run("path_labels", func(ctx context.Context) error { return wh.processPathLabels(ctx, deliveryID, repoName, body) })
+	}
+
+	// Keep the latest state of check suites, check runs and workflow runs
+	if webhook.IsCIEvent(eventType) {
+		run("ci_run", func(ctx context.Context) error { return wh.processCIRun(ctx, eventType, body) })
+	}
+
+	// Keep the final state of finished deployments for the DORA metrics
+	if eventType == webhook.DeploymentStatusEvent {
+		run("deployment", func(ctx context.Context) error { return wh.processDeployment(ctx, body) })
+	}
+
+	// Keep releases and created or deleted branches and tags for changelogs
+	// and audits
+	if eventType == webhook.ReleaseEvent {
+		run("release", func(ctx context.Context) error { return wh.processRelease(ctx, body) })
+	}
+	if webhook.IsRefEvent(eventType) {
+		run("ref", func(ctx context.Context) error { return wh.processRefChange(ctx, eventType, deliveryID, body) })
+	}
+
+	// Keep issue and pull request comment history queryable
+	if eventType == webhook.IssueCommentEvent {
+		run("issue_comment", func(ctx context.Context) error { return wh.processIssueComment(ctx, deliveryID, body) })
+	}
+
+	// Run slash commands posted in issue and pull request comments
+	if eventType == webhook.IssueCommentEvent && wh.commentClient != nil && len(wh.commentPolicy) > 0 {
+		run("comment_commands", func(ctx context.Context) error { return wh.processCommentCommands(ctx, deliveryID, body) })
+	}
+
+	// Track and route security alerts
+	if webhook.IsSecurityAlertEvent(eventType) {
+		run("security_alert", func(ctx context.Context) error {
+			return wh.processSecurityAlert(ctx, eventType, deliveryID, body)
+		})
+	}
+
+	// Record branch protection changes and alert on weakened protections
+	if webhook.IsProtectionEvent(eventType) {
+		run("branch_protection", func(ctx context.Context) error {
+			return wh.processProtectionChange(ctx, eventType, deliveryID, senderLogin, body)
+		})
+	}
+
+	// Track member and team access changes and alert on admin grants
+	if webhook.IsAccessEvent(eventType) {
+		run("access", func(ctx context.Context) error {
+			return wh.processAccessChange(ctx, eventType, deliveryID, senderLogin, body)
+		})
+	}
+
+	// Store, route and run commands from GitHub Discussions activity
+	if webhook.IsDiscussionEvent(eventType) {
+		run("discussion", func(ctx context.Context) error {
+			return wh.processDiscussion(ctx, eventType, deliveryID, senderLogin, body)
+		})
+	}
+
+	// Track project items across columns and notify when they enter a column
+	if eventType == webhook.ProjectsV2ItemEvent {
+		run("project", func(ctx context.Context) error { return wh.processProjectItem(ctx, deliveryID, senderLogin, body) })
+	}
+
+	// Notify channels about documentation changes and Pages deploy failures
+	if webhook.IsDocsEvent(eventType) {
+		run("docs", func(ctx context.Context) error { return wh.processDocs(ctx, eventType, deliveryID, body) })
+	}
+
+	// Alert on audit log actions such as member additions
+	if eventType == auditlog.EventType && wh.auditAlerts[action] {
+		run("audit_alert", func(ctx context.Context) error {
+			return wh.processAuditAlert(ctx, deliveryID, action, repoName, senderLogin, body)
+		})
+	}
+
+	event := forwarder.Event{
+		DeliveryID: deliveryID,
+		EventType:  eventType,
+		Action:     action,
+		Repository: repoName,
+		Sender:     senderLogin,
+		Checksum:   checksum.Sum(body),
+		Payload:    body,
+	}
+
+	// Run the operator-defined rules. A rule that drops the event keeps it
+	// from later rules and the forwarders.
+	dropped := false
+	if wh.rules != nil {
+		decision := wh.rules.Evaluate(event)
+		for _, err := range decision.Errors {
+			log.Printf("Rule not evaluated (delivery: %s): %v", deliveryID, err)
+		}
+		if decision.Drop {
+			dropped = true
+			log.Printf("Rule %s dropped %s event (delivery: %s)", decision.Matches[len(decision.Matches)-1].Name(), eventType, deliveryID)
+		}
+		if len(decision.Matches) > 0 {
+			run("rules", func(ctx context.Context) error { return wh.rules.Apply(ctx, event, decision) })
+		}
+	}
+
+	// Publish the event to any configured forwarders
+	forwarders := wh.forwarders
+	if !outboxed {
+		forwarders = append(slices.Clip(forwarders), wh.outbox...)
+	}
+	if len(forwarders) > 0 && !dropped {
+		run("forwarders", func(ctx context.Context) error {
+			forwarder.ForwardAll(ctx, forwarders, event)
+			return nil
+		})
+	}
+
+	wg.Wait()
+	wh.finishCheck(ctx, check, deliveryID, repoName)
+	wh.recordUsage(ctx, repoName, 0, 0, time.Since(start))
+	if wh.invalidate != nil {
+		wh.invalidate(eventType, repoName)
+	}
+	if len(errs) == 0 && wh.receipts != nil {
+		wh.postReceipt(ctx, eventType, deliveryID, repoName, body)
+	}
+	return errors.Join(errs...)
+}
+
+// Ingest runs an event that did not arrive as a webhook delivery, such as an
+// audit log entry, through the path deliveries take: the payload is redacted,
+// stored with its checksum and processed, by the work queue when there is
+// one. Events that could not be stored are still processed, and the storage
+// error is returned.
+func (wh *WebhookHandler) Ingest(ctx context.Context, eventType, deliveryID, action, repoName, senderLogin string, body []byte) (database.StoreResult, error) {
+	if wh.redactor.Enabled() {
+		redacted, err := wh.redactor.Redact(body)
+		if err != nil {
+			return database.EventStored, fmt.Errorf("failed to redact payload: %w", err)
+		}
+		body = redacted
+	}
+
+	// Events ignored by the settings of the repository or branch are
+	// processed without being stored, as deliveries are
+	var storeErr error
+	queued, outboxed := false, false
+	if wh.settings != nil && wh.settings.IgnoresEvent(repoName, webhook.ParseBranch(body), eventType) {
+		log.Printf("Event type %s is ignored for %s", eventType, repoName)
+	} else if wh.events != nil {
+		result, err := wh.storeWebhookEvent(ctx, eventType, deliveryID, repoName, senderLogin, action, body)
+		switch {
+		case err != nil:
+			log.Printf("Failed to store %s event in database (delivery: %s): %v", eventType, deliveryID, err)
+			storeErr = err
+		case result == database.EventDuplicate:
+			return result, nil
+		case wh.notifyQueue != nil:
+			queued = true
+			wh.notifyQueue()
+		}
+		if err == nil && wh.notifyOutbox != nil {
+			outboxed = true
+			wh.notifyOutbox()
+		}
+	}
+	if !queued {
+		// Failed steps are logged by runProcessor
+		wh.process(ctx, eventType, deliveryID, action, repoName, senderLogin, body, outboxed)
+	}
+	return database.EventStored, storeErr
+}
+
+// Replay runs a stored event through the processing pipeline and forwarders
+// again, as if it had just arrived, to recover from downstream outages. The
+// stored event is left as it is, and is published to the outbox forwarders
+// directly rather than through the outbox.
+func (wh *WebhookHandler) Replay(ctx context.Context, deliveryID string) error {
+	return wh.processStored(ctx, deliveryID, "Replaying", false, false)
+}
+
+// ProcessQueued runs a stored event taken from the work queue through the
+// processing pipeline and forwarders
+func (wh *WebhookHandler) ProcessQueued(ctx context.Context, deliveryID string) error {
+	err := wh.processStored(ctx, deliveryID, "Processing queued", true, wh.notifyOutbox != nil)
+	if errors.Is(err, ErrEventNotFound) {
+		// Pruned before a worker got to it
+		return fmt.Errorf("%w: %v", workqueue.ErrSkip, err)
+	}
+	return err
+}
+
+// processStored loads a stored event and runs it through the pipeline. When
+// observe is set and processing succeeds, the time since the event was stored
+// is observed as its processing latency.
+func (wh *WebhookHandler) processStored(ctx context.Context, deliveryID, verb string, observe, outboxed bool) error {
+	event, payload, err := wh.loadStored(ctx, deliveryID)
+	if err != nil {
+		return err
+	}
+	repoName, senderLogin := storedNames(event)
+
+	log.Printf("%s %s event from %s (delivery: %s, sender: %s)", verb, event.EventType, repoName, deliveryID, senderLogin)
+	err = wh.process(ctx, event.EventType, deliveryID, event.Action.String, repoName, senderLogin, payload, outboxed)
+	if err == nil && observe && wh.observeLatency != nil && event.CreatedAt.Valid {
+		wh.observeLatency(time.Since(event.CreatedAt.Time))
+	}
+	return err
+}
+
+// Publish publishes a stored event to the outbox forwarder named target, for
+// the outbox relay. Events dropped by a rule are not published.
+func (wh *WebhookHandler) Publish(ctx context.Context, deliveryID, target string) error {
+	var to forwarder.Forwarder
+	for _, f := range wh.outbox {
+		if f.Name() == target {
+			to = f
+			break
+		}
+	}
+	if to == nil {
+		return fmt.Errorf("%w: no forwarder %s", outbox.ErrSkip, target)
+	}
+
+	stored, payload, err := wh.loadStored(ctx, deliveryID)
+	if errors.Is(err, ErrEventNotFound) {
+		// Pruned or erased before the relay got to it
+		return fmt.Errorf("%w: %v", outbox.ErrSkip, err)
+	}
+	if err != nil {
+		return err
+	}
+	repoName, senderLogin := storedNames(stored)
+	event := forwarder.Event{
+		DeliveryID: deliveryID,
+		EventType:  stored.EventType,
+		Action:     stored.Action.String,
+		Repository: repoName,
+		Sender:     senderLogin,
+		Checksum:   checksum.Sum(payload),
+		Payload:    payload,
+	}
+	if wh.rules != nil && wh.rules.Evaluate(event).Drop {
+		return nil
+	}
+	err = to.Forward(ctx, event)
+	if errors.Is(err, forwarder.ErrUndeliverable) {
+		return fmt.Errorf("%w: %v", outbox.ErrSkip, err)
+	}
+	return err
+}
+
+// loadStored loads a stored event and its decrypted, verified payload
+func (wh *WebhookHandler) loadStored(ctx context.Context, deliveryID string) (db.WebhookEvent, []byte, error) {
+	if wh.events == nil {
+		return db.WebhookEvent{}, nil, errNoDatabase
+	}
+
 	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	event, err := wh.events.GetWebhookEvent(dbCtx, deliveryID)
+	cancel()
+	if errors.Is(err, ErrEventNotFound) {
+		return event, nil, ErrEventNotFound
+	}
+	if err != nil {
+		return event, nil, fmt.Errorf("failed to load delivery %s: %w", deliveryID, err)
+	}
+	payload, err := wh.keys.Open(deliveryID, event.Payload)
+	if err != nil {
+		return event, nil, fmt.Errorf("failed to decrypt delivery %s: %w", deliveryID, err)
+	}
+	// Events stored before checksums were recorded cannot be verified
+	if event.PayloadSha256.Valid {
+		if err := checksum.Verify(payload, event.PayloadSha256.String); err != nil {
+			return event, nil, fmt.Errorf("delivery %s: %w", deliveryID, err)
+		}
+	}
+	return event, payload, nil
+}
+
+// storedNames returns the repository and sender of a stored event
+func storedNames(event db.WebhookEvent) (repoName, senderLogin string) {
+	repoName, senderLogin = "unknown", "unknown"
+	if event.RepositoryName.Valid {
+		repoName = event.RepositoryName.String
+	}
+	if event.SenderLogin.Valid {
+		senderLogin = event.SenderLogin.String
+	}
+	return repoName, senderLogin
+}
+
+// processSecurityAlert records a security alert for SLA tracking and routes it
+// to the security channels matching its severity
+func (wh *WebhookHandler) processSecurityAlert(ctx context.Context, eventType, deliveryID string, body []byte) error {
+	alert, err := webhook.ParseSecurityAlert(eventType, body)
+	if err != nil {
+		return fmt.Errorf("failed to parse security alert: %w", err)
+	}
+
+	log.Printf("Security alert %s #%d in %s: severity %s, state %s", alert.Kind, alert.Number, alert.Repository, alert.Severity, alert.State)
+
+	// Route the alert even if storing fails, reporting the failure after
+	var failed error
+	if wh.dbConn != nil {
+		if err := storeSecurityAlert(ctx, wh.dbConn, alert); err != nil {
+			failed = fmt.Errorf("failed to store security alert: %w", err)
+		}
+	}
+
+	if wh.securityRouter != nil {
+		wh.securityRouter.Route(ctx, deliveryID, alert)
+	}
+	return failed
+}
+
+// recordProvenance stores the verification chain of a stored event
+func (wh *WebhookHandler) recordProvenance(ctx context.Context, deliveryID string, chain relay.Chain) {
+	if wh.dbConn == nil || len(chain) == 0 {
+		return
+	}
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
 	defer cancel()
 
-	// Convert optional strings to pgtype.Text
-	var repositoryName pgtype.Text
-	if repoName != "unknown" && repoName != "" {
-		repositoryName = pgtype.Text{String: repoName, Valid: true}
+	data, _ := json.Marshal(chain)
+	if err := wh.dbConn.Queries().CreateEventProvenance(dbCtx, db.CreateEventProvenanceParams{DeliveryID: deliveryID, Chain: data}); err != nil {
+		log.Printf("Failed to store the verification chain of delivery %s: %v", deliveryID, err)
 	}
+}
 
-	var senderLoginPG pgtype.Text
-	if senderLogin != "unknown" && senderLogin != "" {
-		senderLoginPG = pgtype.Text{String: senderLogin, Valid: true}
+// recordDetails stores the payload fields of a stored event that reports
+// read, so they need not read payloads, which may be encrypted
+func (wh *WebhookHandler) recordDetails(ctx context.Context, eventType, deliveryID string, payload []byte) {
+	details := webhook.ParseEventDetails(eventType, payload)
+	if wh.dbConn == nil || details.Empty() {
+		return
 	}
+	dbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
 
-	var actionPG pgtype.Text
-	if action != "" {
-		actionPG = pgtype.Text{String: action, Valid: true}
+	params := db.CreateEventDetailsParams{
+		DeliveryID: deliveryID,
+		Outcome:    pgtype.Text{String: details.Outcome, Valid: details.Outcome != ""},
+		Subject:    pgtype.Text{String: details.Subject, Valid: details.Subject != ""},
 	}
+	if details.SourceUpdatedAt != nil {
+		params.SourceUpdatedAt = pgtype.Timestamptz{Time: *details.SourceUpdatedAt, Valid: true}
+	}
+	if err := wh.dbConn.Queries().CreateEventDetails(dbCtx, params); err != nil {
+		log.Printf("Failed to store the details of delivery %s: %v", deliveryID, err)
+	}
+}
 
-	// Store the webhook event
+// storeWebhookEvent stores a webhook event and the checksum of its payload in
+// the database, spooling it to the dead-letter directory if the write fails
+func (wh *WebhookHandler) storeWebhookEvent(ctx context.Context, eventType, deliveryID, repoName, senderLogin, action string, payload []byte) (database.StoreResult, error) {
+	ctx, span := tracing.Start(ctx, "webhook.store")
+	// The dead-letter spool keeps the encrypted payload too
+	stored, err := wh.keys.Seal(deliveryID, payload)
+	if err != nil {
+		tracing.End(span, err)
+		return database.EventStored, fmt.Errorf("failed to encrypt payload: %w", err)
+	}
 	params := db.CreateWebhookEventParams{
 		DeliveryID:     deliveryID,
 		EventType:      eventType,
-		RepositoryName: repositoryName,
-		SenderLogin:    senderLoginPG,
-		Action:         actionPG,
-		Payload:        payload,
+		RepositoryName: optionalText(repoName),
+		SenderLogin:    optionalText(senderLogin),
+		Action:         optionalText(action),
+		Payload:        stored,
+		PayloadSha256:  pgtype.Text{String: checksum.Sum(payload), Valid: true},
+	}
+	var result database.StoreResult
+	if wh.batch != nil {
+		dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		result, err = wh.batch.Write(dbCtx, database.BatchEntry{
+			Params:      params,
+			Queue:       wh.notifyQueue != nil,
+			TraceParent: tracing.TraceParent(ctx),
+		})
+		cancel()
+	} else if wh.notifyQueue != nil {
+		dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		result, err = wh.dbConn.QueueWebhookEvent(dbCtx, params)
+		cancel()
+	} else {
+		result, err = storeEvent(ctx, wh.events, params)
+	}
+	tracing.End(span, err)
+	if err != nil {
+		spoolFailedEvent(wh.deadLetter, params, err)
 	}
+	if err == nil && result == database.EventStored {
+		wh.recordUsage(ctx, repoName, 1, int64(len(payload)), 0)
+		wh.recordActivity(ctx, repoName)
+		wh.recordDetails(ctx, eventType, deliveryID, payload)
+	}
+	return result, err
+}
 
-	_, err := wh.dbConn.Queries().CreateWebhookEvent(dbCtx, params)
-	return err
-}
\ No newline at end of file
+// storeEvent writes an event row using a bounded database context
+func storeEvent(ctx context.Context, events database.EventStore, params db.CreateWebhookEventParams) (database.StoreResult, error) {
+	// Create a context with timeout for database operations
+	dbCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	return events.StoreWebhookEvent(dbCtx, params)
+}
+
+// spoolFailedEvent writes an event that could not be stored to the dead-letter
+// spool
+func spoolFailedEvent(spool *deadletter.Spool, params db.CreateWebhookEventParams, cause error) {
+	if spool == nil {
+		return
+	}
+	if err := spool.Write(params, cause); err != nil {
+		log.Printf("Failed to write delivery %s to dead-letter spool, event lost: %v", params.DeliveryID, err)
+		return
+	}
+	log.Printf("Spooled delivery %s to dead-letter directory for retry", params.DeliveryID)
+}
+
+// optionalText converts an optional string to pgtype.Text, treating empty
+// and "unknown" values as NULL
+func optionalText(value string) pgtype.Text {
+	if value == "" || value == "unknown" {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: value, Valid: true}
+}
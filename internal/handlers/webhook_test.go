@@ -2,13 +2,32 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
+	"crypto/sha1"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/encryption"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/outbox"
+	"github.com/deedubs/choochoo/internal/redact"
+	"github.com/deedubs/choochoo/internal/relay"
+	"github.com/deedubs/choochoo/internal/rules"
+	"github.com/deedubs/choochoo/internal/settings"
+	"github.com/deedubs/choochoo/internal/workqueue"
 )
 
 // Test helper functions
@@ -44,6 +63,20 @@ func TestWebhookHandler_ValidateSignature_ValidSignature(t *testing.T) {
 	}
 }
 
+func TestWebhookHandler_ValidateSignature_RotatedSecrets(t *testing.T) {
+	handler := NewWebhookHandler("new-secret, old-secret", nil)
+	payload := []byte(`{"test": "data"}`)
+
+	for _, secret := range []string{"new-secret", "old-secret"} {
+		if !handler.validateSignature(payload, generateSignature(payload, secret)) {
+			t.Errorf("Expected validation to pass with a signature from %s", secret)
+		}
+	}
+	if handler.validateSignature(payload, generateSignature(payload, "retired-secret")) {
+		t.Error("Expected validation to fail with a secret that is not listed")
+	}
+}
+
 func TestWebhookHandler_ValidateSignature_InvalidSignature(t *testing.T) {
 	handler := NewWebhookHandler("test-secret", nil)
 	payload := []byte(`{"test": "data"}`)
@@ -74,6 +107,105 @@ func TestWebhookHandler_ValidateSignature_InvalidHex(t *testing.T) {
 	}
 }
 
+func TestWebhookHandler_VerifySignature_SHA1Fallback(t *testing.T) {
+	payload := []byte(`{"test": "data"}`)
+	mac := hmac.New(sha1.New, []byte("test-secret"))
+	mac.Write(payload)
+	legacy := "sha1=" + hex.EncodeToString(mac.Sum(nil))
+
+	headers := func(sha256, sha1 string) http.Header {
+		header := http.Header{}
+		if sha256 != "" {
+			header.Set("X-Hub-Signature-256", sha256)
+		}
+		if sha1 != "" {
+			header.Set("X-Hub-Signature", sha1)
+		}
+		return header
+	}
+
+	handler := NewWebhookHandler("test-secret", nil)
+	if handler.verifySignature(payload, headers("", legacy)) {
+		t.Error("Expected a SHA-1 signature to fail without the fallback")
+	}
+
+	handler.WithSHA1Fallback(true)
+	if !handler.verifySignature(payload, headers("", legacy)) {
+		t.Error("Expected a SHA-1 signature to pass with the fallback")
+	}
+	if handler.verifySignature(payload, headers("", "sha1=0000")) {
+		t.Error("Expected an invalid SHA-1 signature to fail")
+	}
+	// The SHA-256 signature takes precedence whenever it is sent
+	if handler.verifySignature(payload, headers("sha256=invalid", legacy)) {
+		t.Error("Expected an invalid SHA-256 signature to fail despite a valid SHA-1 one")
+	}
+	if !handler.verifySignature(payload, headers(generateSignature(payload, "test-secret"), "sha1=0000")) {
+		t.Error("Expected a valid SHA-256 signature to pass")
+	}
+}
+
+func TestWebhookHandler_VerifyProvenance(t *testing.T) {
+	payload := []byte(`{"zen": "Design for failure."}`)
+	github := generateSignature(payload, "test-secret")
+	attested := relay.Chain{{Signer: relay.GitHub, Signature: github}}.Header()
+
+	tests := []struct {
+		name    string
+		header  map[string]string
+		signers []string
+		wantErr bool
+	}{
+		{"direct", map[string]string{"X-Hub-Signature-256": github}, []string{"github"}, false},
+		{"relayed with the GitHub signature", map[string]string{
+			"X-Hub-Signature-256": github,
+			relay.SignatureHeader: relay.Sign("edge-secret", "", payload),
+		}, []string{"github", "edge"}, false},
+		{"relayed with an attested GitHub signature", map[string]string{
+			relay.ChainHeader:     attested,
+			relay.SignatureHeader: relay.Sign("edge-secret", attested, payload),
+		}, []string{"github", "edge"}, false},
+		{"relayed without a GitHub signature", map[string]string{
+			relay.SignatureHeader: relay.Sign("edge-secret", "", payload),
+		}, nil, true},
+		{"relayed with an invalid GitHub signature", map[string]string{
+			"X-Hub-Signature-256": generateSignature(payload, "wrong"),
+			relay.SignatureHeader: relay.Sign("edge-secret", "", payload),
+		}, nil, true},
+		{"unknown relay", map[string]string{
+			"X-Hub-Signature-256": github,
+			relay.SignatureHeader: relay.Sign("other-secret", "", payload),
+		}, nil, true},
+	}
+	handler := NewWebhookHandler("test-secret", nil).WithRelays(relay.NewVerifier(map[string]string{"edge": "edge-secret"}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for key, value := range tt.header {
+				header.Set(key, value)
+			}
+			chain, err := handler.verifyProvenance(payload, header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyProvenance() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var signers []string
+			for _, link := range chain {
+				signers = append(signers, link.Signer)
+			}
+			if !slices.Equal(signers, tt.signers) {
+				t.Errorf("verifyProvenance() signers = %v, want %v", signers, tt.signers)
+			}
+		})
+	}
+
+	// Relay headers are not trusted without relay secrets
+	header := http.Header{}
+	header.Set(relay.SignatureHeader, relay.Sign("edge-secret", "", payload))
+	if _, err := NewWebhookHandler("test-secret", nil).verifyProvenance(payload, header); err == nil {
+		t.Error("Expected a relayed delivery to be rejected without relay secrets")
+	}
+}
+
 func TestWebhookHandler_HandleWebhook_InvalidMethod(t *testing.T) {
 	handler := NewWebhookHandler("", nil)
 	
@@ -200,6 +332,84 @@ func TestWebhookHandler_HandleWebhook_EmptyPayload(t *testing.T) {
 	}
 }
 
+func TestWebhookHandler_HandleWebhook_BodyTooLarge(t *testing.T) {
+	handler := NewWebhookHandler("", nil).WithMaxBodySize(16)
+
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(`{"action":"opened","repository":{"full_name":"test/repo"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test-delivery-id")
+
+	rr := httptest.NewRecorder()
+
+	handler.HandleWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, status)
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_UnsupportedContentType(t *testing.T) {
+	handler := NewWebhookHandler("", nil)
+
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(`<push/>`))
+	req.Header.Set("Content-Type", "text/xml")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test-delivery-id")
+
+	rr := httptest.NewRecorder()
+
+	handler.HandleWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnsupportedMediaType, status)
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_FormEncoded(t *testing.T) {
+	secret := "test-secret"
+	rec := &recordingForwarder{}
+	handler := NewWebhookHandler(secret, nil).WithForwarders(rec)
+
+	payload := `{"action":"opened","repository":{"full_name":"test/repo"},"sender":{"login":"testuser"}}`
+	form := url.Values{"payload": {payload}}.Encode()
+	signature := generateSignature([]byte(form), secret)
+
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	req.Header.Set("X-GitHub-Delivery", "test-delivery-id")
+	req.Header.Set("X-Hub-Signature-256", signature)
+
+	rr := httptest.NewRecorder()
+
+	handler.HandleWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	if len(rec.events) != 1 || string(rec.events[0].Payload) != payload || rec.events[0].Repository != "test/repo" {
+		t.Errorf("Expected the decoded JSON payload to be forwarded, got %+v", rec.events)
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_FormEncodedWithoutPayload(t *testing.T) {
+	handler := NewWebhookHandler("", nil)
+
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString("other=value"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "test-delivery-id")
+
+	rr := httptest.NewRecorder()
+
+	handler.HandleWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, status)
+	}
+}
+
 func TestWebhookHandler_HandleWebhook_GitHubEvent_OptionalFields(t *testing.T) {
 	handler := NewWebhookHandler("", nil)
 	
@@ -216,4 +426,399 @@ func TestWebhookHandler_HandleWebhook_GitHubEvent_OptionalFields(t *testing.T) {
 	if status := rr.Code; status != http.StatusOK {
 		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
 	}
-}
\ No newline at end of file
+}
+type recordingForwarder struct {
+	mu     sync.Mutex
+	events []forwarder.Event
+}
+
+func (rf *recordingForwarder) Name() string { return "recording" }
+
+// Forward may be called concurrently by processors running in parallel
+func (rf *recordingForwarder) Forward(ctx context.Context, event forwarder.Event) error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	rf.events = append(rf.events, event)
+	return nil
+}
+
+func TestWebhookHandler_HandleWebhook_Forwarders(t *testing.T) {
+	rec := &recordingForwarder{}
+	handler := NewWebhookHandler("", nil).WithForwarders(rec)
+
+	payload := `{"action":"opened","repository":{"full_name":"test/repo"},"sender":{"login":"testuser"}}`
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	req.Header.Set("X-GitHub-Delivery", "test-delivery-id")
+
+	rr := httptest.NewRecorder()
+
+	handler.HandleWebhook(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, status)
+	}
+	if len(rec.events) != 1 {
+		t.Fatalf("Expected 1 forwarded event, got %d", len(rec.events))
+	}
+
+	event := rec.events[0]
+	if event.EventType != "pull_request" || event.Action != "opened" || event.Repository != "test/repo" || event.DeliveryID != "test-delivery-id" {
+		t.Errorf("Unexpected forwarded event: %+v", event)
+	}
+	if string(event.Payload) != payload {
+		t.Errorf("Expected raw payload to be forwarded, got %s", event.Payload)
+	}
+}
+
+func TestWebhookHandler_Replay_NoDatabase(t *testing.T) {
+	rec := &recordingForwarder{}
+	handler := NewWebhookHandler("", nil).WithForwarders(rec)
+
+	if err := handler.Replay(context.Background(), "test-delivery-id"); err == nil {
+		t.Error("Expected error replaying without a database")
+	}
+	if len(rec.events) != 0 {
+		t.Errorf("Expected no forwarded events, got %d", len(rec.events))
+	}
+}
+
+func TestWebhookHandler_RunProcessor(t *testing.T) {
+	handler := NewWebhookHandler("", nil)
+	ctx := context.Background()
+
+	if err := handler.runProcessor(ctx, "push", "abc", func(context.Context) error { return nil }); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	err := handler.runProcessor(ctx, "docs", "abc", func(context.Context) error { panic("nil map") })
+	var failed *ProcessorError
+	if !errors.As(err, &failed) || failed.FailedProcessor() != "docs" || failed.Err.Error() != "panic: nil map" {
+		t.Errorf("Expected a docs processor error, got %v", err)
+	}
+
+	joined := errors.Join(errors.New("unrelated"), handler.runProcessor(ctx, "access", "abc", func(context.Context) error {
+		return errors.New("store failed")
+	}))
+	if !errors.As(joined, &failed) || failed.Processor != "access" {
+		t.Errorf("Expected the access processor to be identified, got %v", joined)
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_IgnoredEvents(t *testing.T) {
+	store := database.NewMemoryStore()
+	bundle, err := settings.ParseBundle([]byte("version: 1\noverrides:\n  acme/api:\n    flags:\n      ignored_events: [push]\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler := NewWebhookHandler("", nil).WithEventStore(store).WithSettings(bundle)
+
+	for deliveryID, repo := range map[string]string{"ignored": "acme/api", "stored": "acme/web"} {
+		payload := `{"ref":"refs/heads/main","repository":{"full_name":"` + repo + `"}}`
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("X-GitHub-Event", "push")
+		req.Header.Set("X-GitHub-Delivery", deliveryID)
+		rr := httptest.NewRecorder()
+		handler.HandleWebhook(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	}
+
+	if _, err := store.GetWebhookEvent(context.Background(), "ignored"); !errors.Is(err, database.ErrEventNotFound) {
+		t.Errorf("Expected the ignored push not to be stored, got %v", err)
+	}
+	if _, err := store.GetWebhookEvent(context.Background(), "stored"); err != nil {
+		t.Errorf("Expected the push to acme/web to be stored, got %v", err)
+	}
+}
+
+func TestWebhookHandler_Ingest_IgnoredEvents(t *testing.T) {
+	store := database.NewMemoryStore()
+	bundle, err := settings.ParseBundle([]byte("version: 1\noverrides:\n  acme/api:\n    flags:\n      ignored_events: [audit_log]\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	handler := NewWebhookHandler("", nil).WithEventStore(store).WithSettings(bundle)
+
+	for deliveryID, repo := range map[string]string{"ignored": "acme/api", "stored": "acme/web"} {
+		body := []byte(`{"_document_id":"` + deliveryID + `","action":"repo.create","repo":"` + repo + `"}`)
+		if _, err := handler.Ingest(context.Background(), "audit_log", deliveryID, "repo.create", repo, "octocat", body); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if _, err := store.GetWebhookEvent(context.Background(), "ignored"); !errors.Is(err, database.ErrEventNotFound) {
+		t.Errorf("Expected the ignored entry not to be stored, got %v", err)
+	}
+	if _, err := store.GetWebhookEvent(context.Background(), "stored"); err != nil {
+		t.Errorf("Expected the entry for acme/web to be stored, got %v", err)
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_DiagnosticHeaders(t *testing.T) {
+	depth := workqueue.NewDepth(func(ctx context.Context) (int64, error) { return 7, nil }, time.Second)
+	handler := NewWebhookHandler("", nil).WithEventStore(database.NewMemoryStore()).WithQueueDepth(depth)
+	send := func(handler *WebhookHandler, eventType string) http.Header {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(`{"repository":{"full_name":"acme/api"}}`))
+		req.Header.Set("X-GitHub-Event", eventType)
+		req.Header.Set("X-GitHub-Delivery", "delivery-1")
+		rr := httptest.NewRecorder()
+		handler.HandleWebhook(rr, req)
+		return rr.Header()
+	}
+
+	tests := []struct {
+		name       string
+		handler    *WebhookHandler
+		eventType  string
+		eventID    string
+		processing string
+		depth      string
+	}{
+		{"stored", handler, "pull_request", "delivery-1", processingProcessed, "7"},
+		{"duplicate", handler, "pull_request", "delivery-1", processingDuplicate, ""},
+		{"not stored", handler, "meta", "", processingProcessed, "7"},
+		{"unsigned", NewWebhookHandler("secret", nil), "pull_request", "", processingRejected, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := send(tt.handler, tt.eventType)
+			if got := header.Get(eventIDHeader); got != tt.eventID {
+				t.Errorf("%s = %q, want %q", eventIDHeader, got, tt.eventID)
+			}
+			if got := header.Get(processingHeader); got != tt.processing {
+				t.Errorf("%s = %q, want %q", processingHeader, got, tt.processing)
+			}
+			if got := header.Get(queueDepthHeader); got != tt.depth {
+				t.Errorf("%s = %q, want %q", queueDepthHeader, got, tt.depth)
+			}
+		})
+	}
+}
+
+func TestFailedProcessors(t *testing.T) {
+	err := errors.Join(&ProcessorError{Processor: "docs", Err: errors.New("timeout")}, &ProcessorError{Processor: "rules", Err: errors.New("boom")})
+	if names := failedProcessors(err); len(names) != 2 || names[0] != "docs" || names[1] != "rules" {
+		t.Errorf("failedProcessors() = %v, want [docs rules]", names)
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_StoreAndReplay(t *testing.T) {
+	store := database.NewMemoryStore()
+	rec := &recordingForwarder{}
+	handler := NewWebhookHandler("", nil).WithEventStore(store).WithForwarders(rec)
+
+	payload := `{"action":"opened","repository":{"full_name":"acme/api"},"sender":{"login":"octocat"}}`
+	send := func() map[string]string {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("X-GitHub-Event", "pull_request")
+		req.Header.Set("X-GitHub-Delivery", "delivery-1")
+		rr := httptest.NewRecorder()
+		handler.HandleWebhook(rr, req)
+
+		var response map[string]string
+		json.NewDecoder(rr.Body).Decode(&response)
+		return response
+	}
+
+	if response := send(); response["message"] != "Webhook received and processed" {
+		t.Errorf("Unexpected response to the first delivery: %v", response)
+	}
+	if response := send(); response["message"] != "Duplicate delivery ignored" {
+		t.Errorf("Unexpected response to the redelivery: %v", response)
+	}
+
+	event, err := store.GetWebhookEvent(context.Background(), "delivery-1")
+	if err != nil || event.SenderLogin.String != "octocat" || event.Action.String != "opened" {
+		t.Fatalf("Unexpected stored event %+v, %v", event, err)
+	}
+	if event.PayloadSha256.String != checksum.Sum([]byte(payload)) {
+		t.Errorf("Expected the checksum of the payload to be stored, got %q", event.PayloadSha256.String)
+	}
+
+	if err := handler.Replay(context.Background(), "delivery-1"); err != nil {
+		t.Errorf("Replay failed: %v", err)
+	}
+	if err := handler.Replay(context.Background(), "missing"); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("Expected ErrEventNotFound, got %v", err)
+	}
+	if len(rec.events) != 2 || rec.events[1].Checksum != event.PayloadSha256.String {
+		t.Errorf("Expected the delivery and its checksum to be forwarded when received and replayed, got %+v", rec.events)
+	}
+
+	// A payload changed after it was stored is not replayed
+	store.StoreWebhookEvent(context.Background(), db.CreateWebhookEventParams{
+		DeliveryID:    "tampered",
+		EventType:     "pull_request",
+		Payload:       []byte(`{"action":"closed"}`),
+		PayloadSha256: event.PayloadSha256,
+	})
+	if err := handler.Replay(context.Background(), "tampered"); !errors.Is(err, checksum.ErrMismatch) {
+		t.Errorf("Expected checksum.ErrMismatch, got %v", err)
+	}
+	if len(rec.events) != 2 {
+		t.Errorf("Expected the tampered delivery not to be forwarded, got %d events", len(rec.events))
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_Redacted(t *testing.T) {
+	store := database.NewMemoryStore()
+	rec := &recordingForwarder{}
+	paths, _ := redact.ParsePaths("pusher.name")
+	handler := NewWebhookHandler("", nil).WithEventStore(store).WithForwarders(rec).
+		WithRedactor(redact.New(true, false, paths))
+
+	payload := `{"ref":"refs/heads/main","repository":{"full_name":"acme/api"},"pusher":{"name":"Mona","email":"mona@example.com"}}`
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "delivery-1")
+	rr := httptest.NewRecorder()
+	handler.HandleWebhook(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	event, err := store.GetWebhookEvent(context.Background(), "delivery-1")
+	if err != nil {
+		t.Fatalf("Expected the push to be stored, got %v", err)
+	}
+	expected := `{"pusher":{"email":"[REDACTED]","name":"[REDACTED]"},"ref":"refs/heads/main","repository":{"full_name":"acme/api"}}`
+	if string(event.Payload) != expected {
+		t.Errorf("Expected stored payload %s, got %s", expected, event.Payload)
+	}
+	if len(rec.events) != 1 || string(rec.events[0].Payload) != expected {
+		t.Errorf("Expected the redacted payload to be forwarded, got %+v", rec.events)
+	}
+}
+
+func TestWebhookHandler_Outbox(t *testing.T) {
+	store := database.NewMemoryStore()
+	direct, relayed := &recordingForwarder{}, &recordingForwarder{}
+	notified := 0
+	engine := rules.NewEngine([]rules.Rule{
+		{Name: "bots", Condition: `sender.endsWith("[bot]")`, Actions: []rules.Action{{Type: rules.ActionDrop}}},
+	}, nil)
+	handler := NewWebhookHandler("", nil).WithEventStore(store).WithForwarders(direct).
+		WithOutbox(func() { notified++ }, relayed).
+		WithRules(engine)
+
+	for _, sender := range []string{"octocat", "dependabot[bot]"} {
+		payload := `{"action":"opened","repository":{"full_name":"acme/api"},"sender":{"login":"` + sender + `"}}`
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+		req.Header.Set("X-GitHub-Event", "pull_request")
+		req.Header.Set("X-GitHub-Delivery", "delivery-"+sender)
+		rr := httptest.NewRecorder()
+		handler.HandleWebhook(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+	}
+	if notified != 2 || len(direct.events) != 1 || len(relayed.events) != 0 {
+		t.Fatalf("Expected stored events to be left to the relay, got %d notifications, %d direct and %d relayed events", notified, len(direct.events), len(relayed.events))
+	}
+
+	ctx := context.Background()
+	if err := handler.Publish(ctx, "delivery-octocat", "recording"); err != nil {
+		t.Errorf("Publish failed: %v", err)
+	}
+	if err := handler.Publish(ctx, "delivery-dependabot[bot]", "recording"); err != nil {
+		t.Errorf("Expected a dropped event to be skipped, got %v", err)
+	}
+	if len(relayed.events) != 1 || relayed.events[0].Sender != "octocat" || relayed.events[0].Checksum == "" {
+		t.Errorf("Expected only the event not dropped to be published, got %+v", relayed.events)
+	}
+	if err := handler.Publish(ctx, "missing", "recording"); !errors.Is(err, outbox.ErrSkip) {
+		t.Errorf("Expected outbox.ErrSkip for a missing event, got %v", err)
+	}
+	if err := handler.Publish(ctx, "delivery-octocat", "nats"); !errors.Is(err, outbox.ErrSkip) {
+		t.Errorf("Expected outbox.ErrSkip for a removed forwarder, got %v", err)
+	}
+
+	// Replays skip the outbox
+	if err := handler.Replay(ctx, "delivery-octocat"); err != nil {
+		t.Errorf("Replay failed: %v", err)
+	}
+	if len(direct.events) != 2 || len(relayed.events) != 2 {
+		t.Errorf("Expected the replay to be published directly, got %d direct and %d relayed events", len(direct.events), len(relayed.events))
+	}
+}
+
+func TestWebhookHandler_HandleWebhook_Encrypted(t *testing.T) {
+	keys, err := encryption.ParseKeys("k1:AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8=")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := database.NewMemoryStore()
+	rec := &recordingForwarder{}
+	handler := NewWebhookHandler("", nil).WithEventStore(store).WithForwarders(rec).WithEncryption(keys)
+
+	payload := `{"action":"opened","repository":{"full_name":"acme/api"},"sender":{"login":"octocat"}}`
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(payload))
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	req.Header.Set("X-GitHub-Delivery", "delivery-1")
+	rr := httptest.NewRecorder()
+	handler.HandleWebhook(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	event, err := store.GetWebhookEvent(context.Background(), "delivery-1")
+	if err != nil {
+		t.Fatalf("Expected the event to be stored, got %v", err)
+	}
+	if encryption.KeyID(event.Payload) != "k1" || event.SenderLogin.String != "octocat" {
+		t.Errorf("Expected an encrypted payload with plaintext columns, got %+v", event)
+	}
+
+	if err := handler.Replay(context.Background(), "delivery-1"); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if len(rec.events) != 2 || string(rec.events[1].Payload) != payload {
+		t.Errorf("Expected the decrypted payload to be replayed, got %+v", rec.events)
+	}
+}
+
+func TestWebhookHandler_LatencyObserver(t *testing.T) {
+	store := database.NewMemoryStore()
+	var observed []time.Duration
+	handler := NewWebhookHandler("", nil).WithEventStore(store).
+		WithLatencyObserver(func(latency time.Duration) { observed = append(observed, latency) })
+
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(`{"repository":{"full_name":"acme/api"}}`))
+	req.Header.Set("X-GitHub-Event", "push")
+	req.Header.Set("X-GitHub-Delivery", "delivery-1")
+	handler.HandleWebhook(httptest.NewRecorder(), req)
+
+	if err := handler.ProcessQueued(context.Background(), "delivery-1"); err != nil {
+		t.Fatalf("ProcessQueued failed: %v", err)
+	}
+	// Replays are not observed
+	if err := handler.Replay(context.Background(), "delivery-1"); err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if len(observed) != 2 {
+		t.Errorf("Expected the processed and queued deliveries to be observed, got %v", observed)
+	}
+}
+
+func TestWebhookHandler_Invalidation(t *testing.T) {
+	var invalidated []string
+	handler := NewWebhookHandler("", nil).WithEventStore(database.NewMemoryStore()).
+		WithInvalidation(func(eventType, repository string) { invalidated = append(invalidated, eventType+" "+repository) })
+
+	send := func(deliveryID string) {
+		req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(`{"repository":{"full_name":"acme/api"}}`))
+		req.Header.Set("X-GitHub-Event", "push")
+		req.Header.Set("X-GitHub-Delivery", deliveryID)
+		handler.HandleWebhook(httptest.NewRecorder(), req)
+	}
+	send("delivery-1")
+	// Duplicates change nothing
+	send("delivery-1")
+
+	if len(invalidated) != 2 || invalidated[0] != "push acme/api" {
+		t.Errorf("Expected invalidation once stored and once processed, got %v", invalidated)
+	}
+}
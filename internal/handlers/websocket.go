@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/deedubs/choochoo/internal/stream"
+)
+
+const (
+	// wsWriteTimeout bounds each write; clients that cannot keep up are disconnected
+	wsWriteTimeout = 10 * time.Second
+	// wsReadLimit caps the size of client filter messages
+	wsReadLimit = 4096
+	// wsPingInterval is how often idle connections are checked for liveness
+	wsPingInterval = 30 * time.Second
+)
+
+// WebSocketHandler delivers received events to WebSocket subscribers
+type WebSocketHandler struct {
+	hub    *stream.Hub
+	buffer int
+}
+
+// NewWebSocketHandler creates a new WebSocket handler. buffer is the number
+// of events queued per connection before further events are dropped.
+func NewWebSocketHandler(hub *stream.Hub, buffer int) *WebSocketHandler {
+	if buffer <= 0 {
+		buffer = stream.DefaultBuffer
+	}
+	return &WebSocketHandler{hub: hub, buffer: buffer}
+}
+
+// wsClientMessage is a message sent by a WebSocket client
+type wsClientMessage struct {
+	Type       string   `json:"type"`
+	EventTypes []string `json:"event_types,omitempty"`
+	Repository string   `json:"repository,omitempty"`
+}
+
+// wsServerMessage is a message sent to a WebSocket client
+type wsServerMessage struct {
+	Type    string          `json:"type"`
+	Event   *stream.Message `json:"event,omitempty"`
+	Filter  *stream.Filter  `json:"filter,omitempty"`
+	Dropped uint64          `json:"dropped,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// HandleWebSocket upgrades the connection and streams matching events. The
+// event_type and repository query parameters set the initial filter; clients
+// change it at any time by sending {"type":"subscribe","event_types":[...],"repository":"..."}.
+func (wsh *WebSocketHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed for %s: %v", r.RemoteAddr, err)
+		return
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(wsReadLimit)
+
+	query := r.URL.Query()
+	filter := stream.ParseFilter(query.Get("event_type"), query.Get("repository"))
+	sub := wsh.hub.Subscribe(filter, wsh.buffer)
+	defer wsh.hub.Unsubscribe(sub)
+
+	log.Printf("WebSocket client connected from %s", r.RemoteAddr)
+	defer log.Printf("WebSocket client from %s disconnected", r.RemoteAddr)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Filter updates are written by the reader goroutine, so writes are
+	// funnelled through a single channel to keep them ordered
+	replies := make(chan wsServerMessage, 1)
+	go wsh.readFilters(ctx, cancel, conn, sub, replies)
+
+	if err := wsh.write(ctx, conn, wsServerMessage{Type: "subscribed", Filter: &filter}); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	var reportedDrops uint64
+	for {
+		select {
+		case <-ctx.Done():
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+		case reply := <-replies:
+			if err := wsh.write(ctx, conn, reply); err != nil {
+				return
+			}
+		case <-ping.C:
+			pingCtx, pingCancel := context.WithTimeout(ctx, wsWriteTimeout)
+			err := conn.Ping(pingCtx)
+			pingCancel()
+			if err != nil {
+				return
+			}
+		case msg := <-sub.C:
+			// Let the client know it has fallen behind before sending more events
+			if dropped := sub.Dropped(); dropped > reportedDrops {
+				if err := wsh.write(ctx, conn, wsServerMessage{Type: "dropped", Dropped: dropped - reportedDrops}); err != nil {
+					return
+				}
+				reportedDrops = dropped
+			}
+			if err := wsh.write(ctx, conn, wsServerMessage{Type: "event", Event: &msg}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readFilters applies filter updates sent by the client until the connection closes
+func (wsh *WebSocketHandler) readFilters(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, sub *stream.Subscription, replies chan<- wsServerMessage) {
+	defer cancel()
+
+	reply := func(msg wsServerMessage) bool {
+		select {
+		case replies <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+
+		var msg wsClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			if !reply(wsServerMessage{Type: "error", Error: "invalid JSON message"}) {
+				return
+			}
+			continue
+		}
+		if msg.Type != "subscribe" {
+			if !reply(wsServerMessage{Type: "error", Error: "unknown message type"}) {
+				return
+			}
+			continue
+		}
+
+		filter := stream.Filter{EventTypes: msg.EventTypes, Repository: msg.Repository}
+		sub.SetFilter(filter)
+		if !reply(wsServerMessage{Type: "subscribed", Filter: &filter}) {
+			return
+		}
+	}
+}
+
+// write sends a message with a bounded timeout
+func (wsh *WebSocketHandler) write(ctx context.Context, conn *websocket.Conn, msg wsServerMessage) error {
+	writeCtx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+	defer cancel()
+	return wsjson.Write(writeCtx, conn, msg)
+}
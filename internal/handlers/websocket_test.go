@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/deedubs/choochoo/internal/stream"
+)
+
+func dialTestWebSocket(t *testing.T, url string) (*websocket.Conn, context.Context) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(url, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial WebSocket: %v", err)
+	}
+	t.Cleanup(func() { conn.CloseNow() })
+	return conn, ctx
+}
+
+func readServerMessage(t *testing.T, ctx context.Context, conn *websocket.Conn) wsServerMessage {
+	t.Helper()
+	var msg wsServerMessage
+	if err := wsjson.Read(ctx, conn, &msg); err != nil {
+		t.Fatalf("Failed to read WebSocket message: %v", err)
+	}
+	return msg
+}
+
+func TestWebSocketHandler_SubscribeAndReceive(t *testing.T) {
+	hub := stream.NewHub()
+	server := httptest.NewServer(http.HandlerFunc(NewWebSocketHandler(hub, 4).HandleWebSocket))
+	defer server.Close()
+
+	conn, ctx := dialTestWebSocket(t, server.URL)
+
+	if msg := readServerMessage(t, ctx, conn); msg.Type != "subscribed" {
+		t.Fatalf("Expected initial subscribed message, got %+v", msg)
+	}
+
+	if err := wsjson.Write(ctx, conn, wsClientMessage{Type: "subscribe", EventTypes: []string{"push"}}); err != nil {
+		t.Fatalf("Failed to send filter: %v", err)
+	}
+	msg := readServerMessage(t, ctx, conn)
+	if msg.Type != "subscribed" || msg.Filter == nil || len(msg.Filter.EventTypes) != 1 {
+		t.Fatalf("Expected filter acknowledgement, got %+v", msg)
+	}
+
+	hub.Publish(stream.Message{DeliveryID: "skipped", EventType: "issues"})
+	hub.Publish(stream.Message{DeliveryID: "wanted", EventType: "push"})
+
+	msg = readServerMessage(t, ctx, conn)
+	if msg.Type != "event" || msg.Event == nil || msg.Event.DeliveryID != "wanted" {
+		t.Errorf("Expected wanted push event, got %+v", msg)
+	}
+}
+
+func TestWebSocketHandler_InvalidMessage(t *testing.T) {
+	hub := stream.NewHub()
+	server := httptest.NewServer(http.HandlerFunc(NewWebSocketHandler(hub, 0).HandleWebSocket))
+	defer server.Close()
+
+	conn, ctx := dialTestWebSocket(t, server.URL)
+	readServerMessage(t, ctx, conn)
+
+	if err := conn.Write(ctx, websocket.MessageText, []byte("not json")); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	if msg := readServerMessage(t, ctx, conn); msg.Type != "error" {
+		t.Errorf("Expected error message, got %+v", msg)
+	}
+
+	if err := wsjson.Write(ctx, conn, wsClientMessage{Type: "unsubscribe"}); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	if msg := readServerMessage(t, ctx, conn); msg.Type != "error" {
+		t.Errorf("Expected error message, got %+v", msg)
+	}
+}
+
+func TestWebSocketHandler_ReportsDroppedEvents(t *testing.T) {
+	hub := stream.NewHub()
+	server := httptest.NewServer(http.HandlerFunc(NewWebSocketHandler(hub, 1).HandleWebSocket))
+	defer server.Close()
+
+	conn, ctx := dialTestWebSocket(t, server.URL)
+	readServerMessage(t, ctx, conn)
+
+	// Publish bursts faster than the single-slot buffer can be drained
+	var dropped uint64
+	deadline := time.Now().Add(3 * time.Second)
+	for dropped == 0 && time.Now().Before(deadline) {
+		for j := 0; j < 50; j++ {
+			hub.Publish(stream.Message{DeliveryID: "flood", EventType: "push"})
+		}
+		for {
+			readCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			var msg wsServerMessage
+			err := wsjson.Read(readCtx, conn, &msg)
+			cancel()
+			if err != nil {
+				break
+			}
+			if msg.Type == "dropped" {
+				dropped = msg.Dropped
+				break
+			}
+		}
+	}
+
+	if dropped == 0 {
+		t.Error("Expected a dropped notice after flooding a slow client")
+	}
+}
@@ -0,0 +1,366 @@
+// Package indexadvisor watches the query API's queries on the events table
+// and suggests indexes for the patterns that are often slow: partial indexes
+// for conditions on constants and expression indexes for JSONB fields.
+package indexadvisor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+// Table is the table the advisor suggests indexes for
+const Table = "webhook_events"
+
+// DefaultSlowQuery is the duration from which a query counts as slow by
+// default
+const DefaultSlowQuery = 250 * time.Millisecond
+
+// MinSlowCalls is the number of slow calls of a pattern from which an index
+// is suggested for it
+const MinSlowCalls = 5
+
+// NamePrefix starts the names of the indexes the advisor suggests
+const NamePrefix = "choochoo_advisor_"
+
+// Pattern is the shape of a query on the events table
+type Pattern struct {
+	// Name identifies the query, e.g. "deliveries"
+	Name string `json:"name"`
+	// Keys are the columns and JSONB expressions compared with parameters,
+	// e.g. repository_name or payload->>'action'
+	Keys []string `json:"keys,omitempty"`
+	// Conditions compare columns and JSONB expressions with constants,
+	// e.g. event_type = 'push', and suit a partial index
+	Conditions []string `json:"conditions,omitempty"`
+	// Range is the column scanned by range or sorted on, if any
+	Range string `json:"range,omitempty"`
+}
+
+// key identifies the pattern, whatever its parameters
+func (p Pattern) key() string {
+	return p.Name + "|" + strings.Join(p.Keys, ",") + "|" + strings.Join(p.Conditions, " AND ") + "|" + p.Range
+}
+
+// Usage is what the advisor saw of a pattern
+type Usage struct {
+	Pattern   Pattern       `json:"pattern"`
+	Calls     int           `json:"calls"`
+	SlowCalls int           `json:"slow_calls"`
+	Total     time.Duration `json:"total_ns"`
+	Max       time.Duration `json:"max_ns"`
+}
+
+// Index is an existing index of the events table
+type Index struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+	Scans      int64  `json:"scans"`
+	TuplesRead int64  `json:"tuples_read"`
+	SizeBytes  int64  `json:"size_bytes"`
+	// EstimatedBloatBytes is how much larger the index is than its entries
+	// need, estimated from the planner's statistics
+	EstimatedBloatBytes int64 `json:"estimated_bloat_bytes"`
+	Unique              bool  `json:"unique"`
+	// Valid is false for indexes whose concurrent build failed
+	Valid bool `json:"valid"`
+	// Unused reports non-unique indexes never scanned since the statistics
+	// were reset
+	Unused bool `json:"unused"`
+	// Advised reports indexes created from a suggestion of the advisor
+	Advised bool `json:"advised"`
+}
+
+// EstimateBloat estimates the bytes a B-tree index takes beyond what its
+// entries of keyWidth bytes need at the default fill factor
+func EstimateBloat(sizeBytes, entries, keyWidth int64) int64 {
+	const (
+		pageBytes     = 8192
+		entryOverhead = 12 // tuple header and line pointer
+		fillFactor    = 0.9
+	)
+	needed := int64(float64(entries*(keyWidth+entryOverhead))/fillFactor) + pageBytes
+	if sizeBytes <= needed {
+		return 0
+	}
+	return sizeBytes - needed
+}
+
+// Suggestion is an index the advisor suggests
+type Suggestion struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+	// Reason names the pattern and how often it was slow
+	Reason string `json:"reason"`
+}
+
+// Advisor records the patterns of queries on the events table
+type Advisor struct {
+	slow time.Duration
+
+	mu       sync.Mutex
+	patterns map[string]*Usage
+}
+
+// New creates an advisor counting queries taking at least slow as slow
+func New(slow time.Duration) *Advisor {
+	return &Advisor{slow: slow, patterns: make(map[string]*Usage)}
+}
+
+// SlowQuery returns the duration from which queries count as slow
+func (a *Advisor) SlowQuery() time.Duration {
+	return a.slow
+}
+
+// Observe records a query of pattern that took elapsed. It does nothing on
+// a nil advisor.
+func (a *Advisor) Observe(pattern Pattern, elapsed time.Duration) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	usage, ok := a.patterns[pattern.key()]
+	if !ok {
+		usage = &Usage{Pattern: pattern}
+		a.patterns[pattern.key()] = usage
+	}
+	usage.Calls++
+	usage.Total += elapsed
+	if elapsed > usage.Max {
+		usage.Max = elapsed
+	}
+	if elapsed >= a.slow {
+		usage.SlowCalls++
+	}
+}
+
+// Time returns a function observing pattern with the time since Time was
+// called, for deferring around a query
+func (a *Advisor) Time(pattern Pattern) func() {
+	start := time.Now()
+	return func() { a.Observe(pattern, time.Since(start)) }
+}
+
+// Usage returns what the advisor saw of each pattern, the slowest first
+func (a *Advisor) Usage() []Usage {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	usage := make([]Usage, 0, len(a.patterns))
+	for _, u := range a.patterns {
+		usage = append(usage, *u)
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].SlowCalls != usage[j].SlowCalls {
+			return usage[i].SlowCalls > usage[j].SlowCalls
+		}
+		return usage[i].Pattern.key() < usage[j].Pattern.key()
+	})
+	return usage
+}
+
+// Suggest returns indexes for the patterns that were slow at least
+// MinSlowCalls times and that no existing index serves, the most needed
+// first
+func (a *Advisor) Suggest(existing []Index) []Suggestion {
+	var suggestions []Suggestion
+	seen := make(map[string]bool)
+	for _, usage := range a.Usage() {
+		if usage.SlowCalls < MinSlowCalls {
+			continue
+		}
+		suggestion, ok := suggest(usage.Pattern)
+		if !ok || seen[suggestion.Name] || served(suggestion, usage.Pattern, existing) {
+			continue
+		}
+		seen[suggestion.Name] = true
+		suggestion.Reason = fmt.Sprintf("%s was slower than %s %d of %d times", usage.Pattern.Name, a.slow, usage.SlowCalls, usage.Calls)
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions
+}
+
+// suggest builds the index serving pattern
+func suggest(pattern Pattern) (Suggestion, bool) {
+	var keys []string
+	for _, key := range pattern.Keys {
+		keys = append(keys, indexKey(key))
+	}
+	if pattern.Range != "" && !containsKey(pattern.Keys, pattern.Range) {
+		keys = append(keys, indexKey(pattern.Range))
+	}
+	if len(keys) == 0 {
+		return Suggestion{}, false
+	}
+
+	body := fmt.Sprintf("ON %s (%s)", Table, strings.Join(keys, ", "))
+	if len(pattern.Conditions) > 0 {
+		body += " WHERE " + strings.Join(pattern.Conditions, " AND ")
+	}
+	sum := sha256.Sum256([]byte(body))
+	name := NamePrefix + hex.EncodeToString(sum[:4])
+	return Suggestion{Name: name, Definition: "CREATE INDEX CONCURRENTLY " + name + " " + body}, true
+}
+
+// indexKey returns a key of an index definition: columns as they are and
+// expressions in parentheses
+func indexKey(key string) string {
+	if isColumn(key) {
+		return key
+	}
+	return "(" + key + ")"
+}
+
+var columnPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+func isColumn(key string) bool {
+	return columnPattern.MatchString(key)
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// definitionPattern splits an index definition as PostgreSQL reports it
+// into its keys and its predicate
+var definitionPattern = regexp.MustCompile(`USING \w+ \((.*?)\)(?: WHERE (.*))?$`)
+
+// served reports whether an existing index serves the suggestion: one of
+// the same name, or one without a predicate whose leading columns are those
+// of a pattern without conditions
+func served(suggestion Suggestion, pattern Pattern, existing []Index) bool {
+	var columns []string
+	for _, key := range append(append([]string{}, pattern.Keys...), pattern.Range) {
+		if key != "" && !containsKey(columns, key) {
+			columns = append(columns, key)
+		}
+	}
+	for _, index := range existing {
+		if index.Name == suggestion.Name {
+			return true
+		}
+		if len(pattern.Conditions) > 0 {
+			continue
+		}
+		match := definitionPattern.FindStringSubmatch(index.Definition)
+		if match == nil || match[2] != "" {
+			continue
+		}
+		keys := strings.Split(match[1], ", ")
+		if len(keys) < len(columns) {
+			continue
+		}
+		prefix := true
+		for i, column := range columns {
+			if !isColumn(column) || strings.TrimSuffix(keys[i], " DESC") != column {
+				prefix = false
+				break
+			}
+		}
+		if prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// NewIndex describes an index of the events table from its statistics
+func NewIndex(row db.ListEventIndexesRow) Index {
+	return Index{
+		Name:                row.Name,
+		Definition:          row.Definition,
+		Scans:               row.Scans,
+		TuplesRead:          row.TuplesRead,
+		SizeBytes:           row.SizeBytes,
+		EstimatedBloatBytes: EstimateBloat(row.SizeBytes, row.Entries, row.KeyWidth),
+		Unique:              row.IsUnique,
+		Valid:               row.IsValid,
+		Unused:              row.Scans == 0 && !row.IsUnique,
+		Advised:             strings.HasPrefix(row.Name, NamePrefix),
+	}
+}
+
+// ListFunc lists the indexes of the events table
+type ListFunc func(ctx context.Context) ([]Index, error)
+
+// LoadIndexes lists the indexes of the events table with queries
+func LoadIndexes(queries *db.Queries) ListFunc {
+	return func(ctx context.Context) ([]Index, error) {
+		rows, err := queries.ListEventIndexes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		indexes := make([]Index, 0, len(rows))
+		for _, row := range rows {
+			indexes = append(indexes, NewIndex(row))
+		}
+		return indexes, nil
+	}
+}
+
+// CreateFunc builds an index from its definition
+type CreateFunc func(ctx context.Context, definition string) error
+
+// Creator builds the indexes an advisor suggests, one at a time
+type Creator struct {
+	advisor *Advisor
+	list    ListFunc
+	create  CreateFunc
+}
+
+// NewCreator creates a creator of the suggestions of advisor
+func NewCreator(advisor *Advisor, list ListFunc, create CreateFunc) *Creator {
+	return &Creator{advisor: advisor, list: list, create: create}
+}
+
+// Check builds the most needed suggestion, if any, and returns it
+func (c *Creator) Check(ctx context.Context) (*Suggestion, error) {
+	indexes, err := c.list(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	suggestions := c.advisor.Suggest(indexes)
+	if len(suggestions) == 0 {
+		return nil, nil
+	}
+	suggestion := suggestions[0]
+	log.Printf("Creating index %s: %s", suggestion.Name, suggestion.Reason)
+	if err := c.create(ctx, suggestion.Definition); err != nil {
+		return nil, err
+	}
+	return &suggestion, nil
+}
+
+// Run builds a suggested index every interval until ctx is done
+func (c *Creator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.Check(ctx); err != nil {
+				log.Printf("Failed to create a suggested index: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package indexadvisor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+func TestAdvisor_Suggest(t *testing.T) {
+	advisor := New(100 * time.Millisecond)
+	deliveries := Pattern{Name: "deliveries", Keys: []string{"repository_name"}, Range: "created_at"}
+	ci := Pattern{
+		Name:       "repository health",
+		Conditions: []string{"event_type = 'workflow_run'", "action = 'completed'"},
+		Keys:       []string{"payload->'workflow_run'->>'conclusion'"},
+		Range:      "created_at",
+	}
+	fast := Pattern{Name: "by type", Keys: []string{"event_type"}}
+	for i := 0; i < MinSlowCalls; i++ {
+		advisor.Observe(deliveries, time.Second)
+		advisor.Observe(ci, 200*time.Millisecond)
+		advisor.Observe(fast, time.Millisecond)
+	}
+	advisor.Observe(ci, 300*time.Millisecond)
+
+	usage := advisor.Usage()
+	if len(usage) != 3 || usage[0].Pattern.Name != "repository health" || usage[0].SlowCalls != 6 || usage[0].Max != 300*time.Millisecond {
+		t.Fatalf("Usage() = %+v", usage)
+	}
+
+	suggestions := advisor.Suggest(nil)
+	if len(suggestions) != 2 {
+		t.Fatalf("Suggest() = %+v, want 2 suggestions", suggestions)
+	}
+	want := "ON webhook_events ((payload->'workflow_run'->>'conclusion'), created_at) WHERE event_type = 'workflow_run' AND action = 'completed'"
+	if !strings.HasPrefix(suggestions[0].Definition, "CREATE INDEX CONCURRENTLY "+NamePrefix) || !strings.HasSuffix(suggestions[0].Definition, want) {
+		t.Errorf("Suggest()[0] = %s", suggestions[0].Definition)
+	}
+	if !strings.HasSuffix(suggestions[1].Definition, "ON webhook_events (repository_name, created_at)") {
+		t.Errorf("Suggest()[1] = %s", suggestions[1].Definition)
+	}
+
+	// Existing indexes serve the patterns
+	existing := []Index{
+		{Name: suggestions[0].Name},
+		{Name: "idx_repository_created", Definition: "CREATE INDEX idx_repository_created ON public.webhook_events USING btree (repository_name, created_at DESC)"},
+	}
+	if suggestions := advisor.Suggest(existing); len(suggestions) != 0 {
+		t.Errorf("Suggest() with existing indexes = %+v", suggestions)
+	}
+	// Partial indexes do not serve other queries
+	existing[1].Definition += " WHERE (event_type = 'push'::text)"
+	if suggestions := advisor.Suggest(existing); len(suggestions) != 1 {
+		t.Errorf("Suggest() with a partial index = %+v", suggestions)
+	}
+}
+
+func TestAdvisor_Nil(t *testing.T) {
+	var advisor *Advisor
+	advisor.Observe(Pattern{Name: "deliveries"}, time.Second)
+	if usage := advisor.Usage(); usage != nil {
+		t.Errorf("Usage() = %+v", usage)
+	}
+}
+
+func TestEstimateBloat(t *testing.T) {
+	// 1000 entries of 20 bytes need about 35KB
+	if bloat := EstimateBloat(100000, 1000, 20); bloat < 55000 || bloat > 60000 {
+		t.Errorf("EstimateBloat() = %d", bloat)
+	}
+	if bloat := EstimateBloat(16384, 1000, 20); bloat != 0 {
+		t.Errorf("EstimateBloat() of a compact index = %d", bloat)
+	}
+}
+
+func TestCreator_Check(t *testing.T) {
+	advisor := New(time.Millisecond)
+	var existing []Index
+	var created []string
+	creator := NewCreator(advisor,
+		func(ctx context.Context) ([]Index, error) { return existing, nil },
+		func(ctx context.Context, definition string) error {
+			created = append(created, definition)
+			return nil
+		})
+
+	if suggestion, err := creator.Check(context.Background()); suggestion != nil || err != nil {
+		t.Fatalf("Check() without slow queries = %+v, %v", suggestion, err)
+	}
+	for i := 0; i < MinSlowCalls; i++ {
+		advisor.Observe(Pattern{Name: "deliveries", Keys: []string{"repository_name"}}, time.Second)
+	}
+	suggestion, err := creator.Check(context.Background())
+	if err != nil || suggestion == nil || len(created) != 1 || created[0] != suggestion.Definition {
+		t.Fatalf("Check() = %+v, %v, created %v", suggestion, err, created)
+	}
+
+	// The index is not built again once it exists
+	existing = append(existing, Index{Name: suggestion.Name})
+	if suggestion, err := creator.Check(context.Background()); suggestion != nil || err != nil || len(created) != 1 {
+		t.Errorf("Check() after the index was built = %+v, %v", suggestion, err)
+	}
+}
+
+func TestNewIndex(t *testing.T) {
+	index := NewIndex(db.ListEventIndexesRow{Name: NamePrefix + "abcd", SizeBytes: 8192, Entries: 10, KeyWidth: 8, IsValid: true})
+	if !index.Advised || !index.Unused || index.EstimatedBloatBytes != 0 {
+		t.Errorf("NewIndex() = %+v", index)
+	}
+	if unique := NewIndex(db.ListEventIndexesRow{Name: "webhook_events_pkey", IsUnique: true}); unique.Unused || unique.Advised {
+		t.Errorf("NewIndex() of the primary key = %+v", unique)
+	}
+}
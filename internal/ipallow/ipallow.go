@@ -0,0 +1,222 @@
+// Package ipallow restricts webhook deliveries to the address ranges GitHub
+// sends hooks from, as published by the meta API, as defense in depth on top
+// of signature validation.
+package ipallow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FetchFunc returns the address ranges deliveries may come from
+type FetchFunc func(ctx context.Context) ([]netip.Prefix, error)
+
+// MetaFetcher fetches the hooks ranges from the meta API at baseURL, e.g.
+// https://api.github.com
+func MetaFetcher(baseURL string) FetchFunc {
+	client := &http.Client{Timeout: 10 * time.Second}
+	url := strings.TrimSuffix(baseURL, "/") + "/meta"
+
+	return func(ctx context.Context) ([]netip.Prefix, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("User-Agent", "choochoo")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GET %s: unexpected status %d", url, resp.StatusCode)
+		}
+
+		var meta struct {
+			Hooks []string `json:"hooks"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+			return nil, fmt.Errorf("invalid meta response: %w", err)
+		}
+		if len(meta.Hooks) == 0 {
+			return nil, fmt.Errorf("meta response lists no hooks ranges")
+		}
+		return ParsePrefixes(strings.Join(meta.Hooks, ","))
+	}
+}
+
+// ParsePrefixes parses a comma-separated list of CIDR ranges. Bare addresses
+// are treated as single-address ranges.
+func ParsePrefixes(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			addr, err := netip.ParseAddr(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", item)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", item)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// contains reports whether addr is in any of prefixes
+func contains(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowlist holds the cached GitHub hooks ranges
+type Allowlist struct {
+	fetch   FetchFunc
+	trusted []netip.Prefix
+
+	mu      sync.RWMutex
+	ranges  []netip.Prefix
+	updated time.Time
+}
+
+// NewAllowlist creates an allowlist that loads its ranges with fetch.
+// Requests from trusted proxies are attributed to the address they forwarded
+// for in X-Forwarded-For.
+func NewAllowlist(fetch FetchFunc, trusted []netip.Prefix) *Allowlist {
+	return &Allowlist{fetch: fetch, trusted: trusted}
+}
+
+// Refresh fetches the ranges again. The previous ranges are kept if the
+// fetch fails.
+func (a *Allowlist) Refresh(ctx context.Context) error {
+	ranges, err := a.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ranges = ranges
+	a.updated = time.Now()
+	return nil
+}
+
+// Run refreshes the ranges immediately and then every interval until ctx is
+// cancelled
+func (a *Allowlist) Run(ctx context.Context, interval time.Duration) {
+	refresh := func() {
+		if err := a.Refresh(ctx); err != nil {
+			log.Printf("Failed to refresh GitHub hook IP ranges: %v", err)
+		}
+	}
+	refresh()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// Loaded reports whether ranges have been fetched and when
+func (a *Allowlist) Loaded() (bool, time.Time) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.ranges != nil, a.updated
+}
+
+// Allowed reports whether addr is in the GitHub hooks ranges
+func (a *Allowlist) Allowed(addr netip.Addr) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return contains(a.ranges, addr)
+}
+
+// ClientAddr returns the address a request came from, honoring
+// X-Forwarded-For from the trusted proxies of the allowlist
+func (a *Allowlist) ClientAddr(r *http.Request) (netip.Addr, bool) {
+	return ClientAddr(r, a.trusted)
+}
+
+// ClientAddr returns the address a request came from. X-Forwarded-For is
+// only honored for requests from trusted proxies, taking the right-most
+// address that is not itself a trusted proxy.
+func ClientAddr(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	values := r.Header.Values("X-Forwarded-For")
+	if !contains(trusted, addr) || len(values) == 0 {
+		return addr, true
+	}
+
+	forwarded := strings.Split(strings.Join(values, ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		if !contains(trusted, hop) {
+			return hop, true
+		}
+	}
+	return addr, true
+}
+
+// Middleware rejects POST requests from outside the GitHub hooks ranges with
+// 403. Until the ranges have been loaded every POST is rejected with 503, so
+// a failed fetch does not open the endpoint.
+func (a *Allowlist) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next(w, r)
+			return
+		}
+		if loaded, _ := a.Loaded(); !loaded {
+			http.Error(w, "GitHub IP ranges not loaded", http.StatusServiceUnavailable)
+			return
+		}
+
+		addr, ok := a.ClientAddr(r)
+		if !ok || !a.Allowed(addr) {
+			log.Printf("Rejected webhook from %s: not a GitHub hooks address", r.RemoteAddr)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
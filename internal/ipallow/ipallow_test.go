@@ -0,0 +1,105 @@
+package ipallow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestParsePrefixes(t *testing.T) {
+	prefixes, err := ParsePrefixes("192.30.252.0/22, 2a0a:a440::/29,10.0.0.1")
+	if err != nil {
+		t.Fatalf("ParsePrefixes() failed: %v", err)
+	}
+	if len(prefixes) != 3 || prefixes[2].String() != "10.0.0.1/32" {
+		t.Errorf("Unexpected prefixes: %v", prefixes)
+	}
+
+	if _, err := ParsePrefixes("192.30.252.0/99"); err == nil {
+		t.Error("Expected an error for an invalid range")
+	}
+}
+
+func TestMetaFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/meta" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"hooks": ["192.30.252.0/22", "185.199.108.0/22"], "web": ["140.82.112.0/20"]}`))
+	}))
+	defer server.Close()
+
+	prefixes, err := MetaFetcher(server.URL)(context.Background())
+	if err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if len(prefixes) != 2 || prefixes[0].String() != "192.30.252.0/22" {
+		t.Errorf("Unexpected prefixes: %v", prefixes)
+	}
+}
+
+func TestAllowlist_Middleware(t *testing.T) {
+	hooks := netip.MustParsePrefix("192.30.252.0/22")
+	fail := false
+	fetch := func(ctx context.Context) ([]netip.Prefix, error) {
+		if fail {
+			return nil, errors.New("meta unavailable")
+		}
+		return []netip.Prefix{hooks}, nil
+	}
+	allowlist := NewAllowlist(fetch, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+	handler := allowlist.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := func(method, remoteAddr, forwardedFor string) int {
+		req := httptest.NewRequest(method, "/webhook", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr.Code
+	}
+
+	if code := request("POST", "192.30.252.10:1234", ""); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected %d before the ranges are loaded, got %d", http.StatusServiceUnavailable, code)
+	}
+
+	if err := allowlist.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	fail = true
+	if err := allowlist.Refresh(context.Background()); err == nil {
+		t.Fatal("Expected the refresh to fail")
+	}
+
+	tests := []struct {
+		name         string
+		method       string
+		remoteAddr   string
+		forwardedFor string
+		expected     int
+	}{
+		{"github", "POST", "192.30.252.10:1234", "", http.StatusOK},
+		{"github ipv4-mapped", "POST", "[::ffff:192.30.252.10]:1234", "", http.StatusOK},
+		{"other", "POST", "203.0.113.7:1234", "", http.StatusForbidden},
+		{"spoofed forwarded-for", "POST", "203.0.113.7:1234", "192.30.252.10", http.StatusForbidden},
+		{"github via trusted proxy", "POST", "10.1.2.3:1234", "203.0.113.7, 192.30.252.10, 10.0.0.5", http.StatusOK},
+		{"other via trusted proxy", "POST", "10.1.2.3:1234", "192.30.252.10, 203.0.113.7", http.StatusForbidden},
+		{"non-post", "GET", "203.0.113.7:1234", "", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if code := request(test.method, test.remoteAddr, test.forwardedFor); code != test.expected {
+				t.Errorf("Expected status code %d, got %d", test.expected, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Package legalhold keeps the stored events of repositories or time ranges
+// under legal hold. Retention neither deletes nor archives held events, and
+// erasing a sender's events is refused, with an audit entry, while any of
+// them is held. A hold lasts until it is released; released holds and the
+// deletions holds blocked stay on record.
+package legalhold
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Actions recorded in the legal hold audit log
+const (
+	ActionPlaced         = "hold_placed"
+	ActionReleased       = "hold_released"
+	ActionErased         = "sender_erased"
+	ActionErasureBlocked = "erasure_blocked"
+)
+
+// MaxReasonLength limits the size of a hold's reason
+const MaxReasonLength = 500
+
+// ErrInvalid is returned for holds that do not pass validation
+var ErrInvalid = errors.New("invalid legal hold")
+
+// Hold preserves the events of Repository, or of every repository if empty,
+// created from StartsAt until EndsAt. Either bound may be open, but a hold
+// names a repository or a bound so that it never holds everything by
+// accident.
+type Hold struct {
+	ID         int32      `json:"id"`
+	Repository string     `json:"repository,omitempty"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+	Reason     string     `json:"reason"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReleasedBy string     `json:"released_by,omitempty"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+}
+
+// FromRow converts a stored hold
+func FromRow(row db.LegalHold) Hold {
+	return Hold{
+		ID:         row.ID,
+		Repository: row.RepositoryName.String,
+		StartsAt:   timePtr(row.StartsAt),
+		EndsAt:     timePtr(row.EndsAt),
+		Reason:     row.Reason,
+		CreatedBy:  row.CreatedBy,
+		CreatedAt:  row.CreatedAt.Time,
+		ReleasedBy: row.ReleasedBy.String,
+		ReleasedAt: timePtr(row.ReleasedAt),
+	}
+}
+
+// Validate checks the hold, trimming its repository and reason
+func (h *Hold) Validate() error {
+	h.Repository = strings.TrimSpace(h.Repository)
+	h.Reason = strings.TrimSpace(h.Reason)
+	if owner, name, ok := strings.Cut(h.Repository, "/"); h.Repository != "" && (!ok || owner == "" || name == "" || strings.Contains(name, "/")) {
+		return fmt.Errorf("%w: repository must be owner/name, got %q", ErrInvalid, h.Repository)
+	}
+
+	switch {
+	case h.Reason == "":
+		return fmt.Errorf("%w: missing reason", ErrInvalid)
+	case len(h.Reason) > MaxReasonLength:
+		return fmt.Errorf("%w: reason longer than %d characters", ErrInvalid, MaxReasonLength)
+	case h.Repository == "" && h.StartsAt == nil && h.EndsAt == nil:
+		return fmt.Errorf("%w: a hold needs a repository, starts_at or ends_at", ErrInvalid)
+	case h.StartsAt != nil && h.EndsAt != nil && !h.EndsAt.After(*h.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalid)
+	}
+	return nil
+}
+
+// Describe summarizes what the hold covers for logs and audit entries
+func (h Hold) Describe() string {
+	scope := "every repository"
+	if h.Repository != "" {
+		scope = h.Repository
+	}
+	if h.StartsAt != nil {
+		scope += " from " + h.StartsAt.UTC().Format(time.RFC3339)
+	}
+	if h.EndsAt != nil {
+		scope += " until " + h.EndsAt.UTC().Format(time.RFC3339)
+	}
+	return scope
+}
+
+func timePtr(t pgtype.Timestamptz) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,50 @@
+package legalhold
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHold_Validate(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(30 * 24 * time.Hour)
+
+	hold := Hold{Repository: " octo-org/hello-world ", Reason: " Litigation 2026-17 "}
+	if err := hold.Validate(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if hold.Repository != "octo-org/hello-world" || hold.Reason != "Litigation 2026-17" {
+		t.Errorf("Expected trimmed fields, got %+v", hold)
+	}
+	if err := (&Hold{StartsAt: &start, EndsAt: &end, Reason: "Audit"}).Validate(); err != nil {
+		t.Errorf("Expected a time range to be enough, got %v", err)
+	}
+
+	for name, hold := range map[string]Hold{
+		"no reason":      {Repository: "octo-org/hello-world"},
+		"long reason":    {Repository: "octo-org/hello-world", Reason: string(make([]byte, MaxReasonLength+1))},
+		"everything":     {Reason: "Litigation"},
+		"bad repository": {Repository: "hello-world", Reason: "Litigation"},
+		"nested":         {Repository: "octo-org/hello/world", Reason: "Litigation"},
+		"empty range":    {StartsAt: &end, EndsAt: &start, Reason: "Litigation"},
+	} {
+		if err := hold.Validate(); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Expected %s to be invalid, got %v", name, err)
+		}
+	}
+}
+
+func TestHold_Describe(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := (Hold{Repository: "octo-org/hello-world"}).Describe(); got != "octo-org/hello-world" {
+		t.Errorf("Unexpected description %q", got)
+	}
+	if got := (Hold{StartsAt: &start}).Describe(); got != "every repository from 2026-01-01T00:00:00Z" {
+		t.Errorf("Unexpected description %q", got)
+	}
+	err := &HeldError{Holds: []Hold{{ID: 3}, {ID: 7}}}
+	if err.Error() != "events are under legal hold 3, 7" {
+		t.Errorf("Unexpected error %q", err.Error())
+	}
+}
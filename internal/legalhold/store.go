@@ -0,0 +1,136 @@
+package legalhold
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// HeldError is returned for erasures refused because holds in force cover
+// some of the events
+type HeldError struct {
+	Holds []Hold
+}
+
+func (e *HeldError) Error() string {
+	ids := make([]string, len(e.Holds))
+	for i, h := range e.Holds {
+		ids[i] = fmt.Sprint(h.ID)
+	}
+	return "events are under legal hold " + strings.Join(ids, ", ")
+}
+
+// Erasure counts the events an erasure deleted
+type Erasure struct {
+	Sender   string `json:"sender"`
+	Events   int64  `json:"events"`
+	Archived int64  `json:"archived_events"`
+}
+
+// Place stores a validated hold, recording who placed it in the audit log
+func Place(ctx context.Context, conn *database.Connection, hold Hold, actor string) (Hold, error) {
+	var placed Hold
+	err := conn.InTx(ctx, func(q *db.Queries) error {
+		row, err := q.CreateLegalHold(ctx, db.CreateLegalHoldParams{
+			RepositoryName: pgtype.Text{String: hold.Repository, Valid: hold.Repository != ""},
+			StartsAt:       timestamp(hold.StartsAt),
+			EndsAt:         timestamp(hold.EndsAt),
+			Reason:         hold.Reason,
+			CreatedBy:      actor,
+		})
+		if err != nil {
+			return err
+		}
+		placed = FromRow(row)
+		return q.CreateLegalHoldAuditEntry(ctx, db.CreateLegalHoldAuditEntryParams{
+			Action:  ActionPlaced,
+			HoldIds: []int32{placed.ID},
+			Actor:   actor,
+			Details: placed.Describe() + ": " + placed.Reason,
+		})
+	})
+	return placed, err
+}
+
+// Release releases a hold in force, recording who released it in the audit
+// log. It returns false if there is no such hold.
+func Release(ctx context.Context, conn *database.Connection, id int32, actor string) (bool, error) {
+	released := false
+	err := conn.InTx(ctx, func(q *db.Queries) error {
+		n, err := q.ReleaseLegalHold(ctx, db.ReleaseLegalHoldParams{
+			ReleasedBy: pgtype.Text{String: actor, Valid: true},
+			ID:         id,
+		})
+		if err != nil || n == 0 {
+			return err
+		}
+		released = true
+		return q.CreateLegalHoldAuditEntry(ctx, db.CreateLegalHoldAuditEntryParams{
+			Action:  ActionReleased,
+			HoldIds: []int32{id},
+			Actor:   actor,
+		})
+	})
+	return released, err
+}
+
+// EraseSender deletes the stored and archived events sent by sender, for
+// data subject erasure requests. If holds in force cover any of them,
+// nothing is deleted and a *HeldError is returned. Both outcomes are
+// recorded in the audit log.
+func EraseSender(ctx context.Context, conn *database.Connection, sender, actor string) (Erasure, error) {
+	erasure := Erasure{Sender: sender}
+	var held []Hold
+	err := conn.InTx(ctx, func(q *db.Queries) error {
+		login := pgtype.Text{String: sender, Valid: true}
+		rows, err := q.ListLegalHoldsOnSender(ctx, login)
+		if err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			ids := make([]int32, len(rows))
+			for i, row := range rows {
+				ids[i] = row.ID
+				held = append(held, FromRow(row))
+			}
+			return q.CreateLegalHoldAuditEntry(ctx, db.CreateLegalHoldAuditEntryParams{
+				Action:  ActionErasureBlocked,
+				HoldIds: ids,
+				Actor:   actor,
+				Details: "erasure of the events of sender " + sender,
+			})
+		}
+
+		if erasure.Events, err = q.EraseSenderWebhookEvents(ctx, login); err != nil {
+			return err
+		}
+		if erasure.Archived, err = q.EraseSenderArchivedWebhookEvents(ctx, login); err != nil {
+			return err
+		}
+		return q.CreateLegalHoldAuditEntry(ctx, db.CreateLegalHoldAuditEntryParams{
+			Action:  ActionErased,
+			HoldIds: []int32{},
+			Actor:   actor,
+			Details: fmt.Sprintf("erased %d events and %d archived events of sender %s", erasure.Events, erasure.Archived, sender),
+		})
+	})
+	if err != nil {
+		return erasure, err
+	}
+	if len(held) > 0 {
+		return erasure, &HeldError{Holds: held}
+	}
+	return erasure, nil
+}
+
+func timestamp(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
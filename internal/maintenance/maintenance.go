@@ -0,0 +1,444 @@
+// Package maintenance keeps the tables of a busy install healthy without
+// Postgres expertise: it vacuums tables many rows were deleted or updated
+// in, analyzes tables whose rows changed since their statistics were
+// gathered, rebuilds bloated indexes of the events table, and reports how
+// much of each table is stored out of line in its TOAST table.
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/indexadvisor"
+	"github.com/jackc/pgx/v5"
+)
+
+// Kinds of maintenance task
+const (
+	KindVacuum  = "vacuum"
+	KindAnalyze = "analyze"
+	KindReindex = "reindex"
+)
+
+// Below these sizes tables and indexes are left to autovacuum, which keeps
+// small tables in shape on its own
+const (
+	MinDeadTuples     = 1000
+	MinModifiedTuples = 1000
+	MinReindexBytes   = 64 << 20
+)
+
+// Default thresholds of the policy, in percent
+const (
+	DefaultVacuumDeadPercent     = 10
+	DefaultAnalyzeChangedPercent = 10
+	DefaultReindexBloatPercent   = 50
+)
+
+// ErrRunning is returned when maintenance is asked to run while it already is
+var ErrRunning = errors.New("maintenance is already running")
+
+// Window is a daily time range, in UTC, in which scheduled maintenance may
+// start. The zero window allows any time.
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseWindow parses a window such as "02:00-05:00", which may wrap past
+// midnight, as in "22:00-04:00". An empty value allows any time.
+func ParseWindow(value string) (Window, error) {
+	if value == "" {
+		return Window{}, nil
+	}
+	start, end, ok := strings.Cut(value, "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid maintenance window %q, expected HH:MM-HH:MM", value)
+	}
+	var window Window
+	var err error
+	if window.Start, err = parseClock(start); err != nil {
+		return Window{}, fmt.Errorf("invalid maintenance window %q: %w", value, err)
+	}
+	if window.End, err = parseClock(end); err != nil {
+		return Window{}, fmt.Errorf("invalid maintenance window %q: %w", value, err)
+	}
+	if window.Start == window.End {
+		return Window{}, fmt.Errorf("invalid maintenance window %q: empty", value)
+	}
+	return window, nil
+}
+
+// parseClock parses an HH:MM time of day
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Contains reports whether maintenance may start at t
+func (w Window) Contains(t time.Time) bool {
+	if w == (Window{}) {
+		return true
+	}
+	t = t.UTC()
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	if w.Start < w.End {
+		return clock >= w.Start && clock < w.End
+	}
+	return clock >= w.Start || clock < w.End
+}
+
+// String formats the window as it is parsed, empty for any time
+func (w Window) String() string {
+	if w == (Window{}) {
+		return ""
+	}
+	clock := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return clock(w.Start) + "-" + clock(w.End)
+}
+
+// Policy decides which tables and indexes are maintained
+type Policy struct {
+	// VacuumDeadPercent vacuums tables whose dead rows are at least this
+	// share of their rows; 0 disables vacuuming
+	VacuumDeadPercent int
+	// AnalyzeChangedPercent analyzes tables whose rows changed since they
+	// were last analyzed are at least this share of their rows; 0 disables
+	// analyzing
+	AnalyzeChangedPercent int
+	// ReindexBloatPercent rebuilds indexes of the events table whose
+	// estimated bloat is at least this share of their size; 0 disables
+	// reindexing
+	ReindexBloatPercent int
+	// Window is when scheduled maintenance may start
+	Window Window
+}
+
+// Table is the maintenance state of a table
+type Table struct {
+	Name                 string     `json:"name"`
+	LiveTuples           int64      `json:"live_tuples"`
+	DeadTuples           int64      `json:"dead_tuples"`
+	DeadPercent          float64    `json:"dead_percent"`
+	ModifiedSinceAnalyze int64      `json:"modified_since_analyze"`
+	LastVacuum           *time.Time `json:"last_vacuum,omitempty"`
+	LastAnalyze          *time.Time `json:"last_analyze,omitempty"`
+	Vacuums              int64      `json:"vacuums"`
+	Autovacuums          int64      `json:"autovacuums"`
+	Analyzes             int64      `json:"analyzes"`
+	Autoanalyzes         int64      `json:"autoanalyzes"`
+	HeapBytes            int64      `json:"heap_bytes"`
+	ToastBytes           int64      `json:"toast_bytes"`
+	IndexBytes           int64      `json:"index_bytes"`
+	// ToastPercent is the share of the table's data, without its indexes,
+	// stored in its TOAST table. Large payloads are stored there, so a
+	// growing share means payloads are growing.
+	ToastPercent float64 `json:"toast_percent"`
+}
+
+// NewTable describes a table from its statistics
+func NewTable(row db.ListTableMaintenanceStatsRow) Table {
+	table := Table{
+		Name:                 row.Name,
+		LiveTuples:           row.LiveTuples,
+		DeadTuples:           row.DeadTuples,
+		ModifiedSinceAnalyze: row.ModifiedSinceAnalyze,
+		Vacuums:              row.VacuumCount,
+		Autovacuums:          row.AutovacuumCount,
+		Analyzes:             row.AnalyzeCount,
+		Autoanalyzes:         row.AutoanalyzeCount,
+		HeapBytes:            row.HeapBytes,
+		ToastBytes:           row.ToastBytes,
+		IndexBytes:           row.IndexBytes,
+	}
+	if row.LastVacuum.Valid {
+		table.LastVacuum = &row.LastVacuum.Time
+	}
+	if row.LastAnalyze.Valid {
+		table.LastAnalyze = &row.LastAnalyze.Time
+	}
+	if rows := row.LiveTuples + row.DeadTuples; rows > 0 {
+		table.DeadPercent = percent(row.DeadTuples, rows)
+	}
+	if size := row.HeapBytes + row.ToastBytes; size > 0 {
+		table.ToastPercent = percent(row.ToastBytes, size)
+	}
+	return table
+}
+
+// Task is a maintenance statement to run
+type Task struct {
+	Kind      string `json:"kind"`
+	Target    string `json:"target"`
+	Statement string `json:"statement"`
+	Reason    string `json:"reason"`
+}
+
+// Plan returns the tasks policy calls for: a vacuum, which also analyzes,
+// or an analyze of each table that needs it, then a rebuild of each bloated
+// index
+func Plan(tables []Table, indexes []indexadvisor.Index, policy Policy) []Task {
+	var tasks []Task
+	for _, table := range tables {
+		name := pgx.Identifier{table.Name}.Sanitize()
+		rows := table.LiveTuples + table.DeadTuples
+		if policy.VacuumDeadPercent > 0 && table.DeadTuples >= MinDeadTuples && table.DeadPercent >= float64(policy.VacuumDeadPercent) {
+			tasks = append(tasks, Task{
+				Kind:      KindVacuum,
+				Target:    table.Name,
+				Statement: "VACUUM (ANALYZE) " + name,
+				Reason:    fmt.Sprintf("%d dead rows, %s%% of the table", table.DeadTuples, formatPercent(table.DeadPercent)),
+			})
+			continue
+		}
+		if policy.AnalyzeChangedPercent > 0 && rows > 0 && table.ModifiedSinceAnalyze >= MinModifiedTuples {
+			if changed := percent(table.ModifiedSinceAnalyze, rows); changed >= float64(policy.AnalyzeChangedPercent) {
+				tasks = append(tasks, Task{
+					Kind:      KindAnalyze,
+					Target:    table.Name,
+					Statement: "ANALYZE " + name,
+					Reason:    fmt.Sprintf("%d rows changed since the last analyze, %s%% of the table", table.ModifiedSinceAnalyze, formatPercent(changed)),
+				})
+			}
+		}
+	}
+	if policy.ReindexBloatPercent > 0 {
+		for _, index := range indexes {
+			if !index.Valid || index.SizeBytes < MinReindexBytes {
+				continue
+			}
+			if bloat := percent(index.EstimatedBloatBytes, index.SizeBytes); bloat >= float64(policy.ReindexBloatPercent) {
+				tasks = append(tasks, Task{
+					Kind:      KindReindex,
+					Target:    index.Name,
+					Statement: "REINDEX INDEX CONCURRENTLY " + pgx.Identifier{index.Name}.Sanitize(),
+					Reason:    fmt.Sprintf("an estimated %d of %d bytes bloated, %s%%", index.EstimatedBloatBytes, index.SizeBytes, formatPercent(bloat)),
+				})
+			}
+		}
+	}
+	return tasks
+}
+
+func percent(part, whole int64) float64 {
+	return float64(part*1000/whole) / 10
+}
+
+func formatPercent(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Result is the outcome of a task
+type Result struct {
+	Task
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration_ns"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Stats describes what the job has done
+type Stats struct {
+	Runs int64 `json:"runs"`
+	// Skipped counts the scheduled runs outside the window
+	Skipped     int64            `json:"skipped"`
+	Running     bool             `json:"running"`
+	LastRun     *time.Time       `json:"last_run,omitempty"`
+	LastResults []Result         `json:"last_results"`
+	Completed   map[string]int64 `json:"completed"`
+	Failed      map[string]int64 `json:"failed"`
+	LastError   string           `json:"last_error,omitempty"`
+}
+
+// LoadFunc loads the maintenance state of the tables
+type LoadFunc func(ctx context.Context) ([]Table, error)
+
+// LoadTables loads the maintenance state of the tables with queries
+func LoadTables(queries *db.Queries) LoadFunc {
+	return func(ctx context.Context) ([]Table, error) {
+		rows, err := queries.ListTableMaintenanceStats(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tables := make([]Table, 0, len(rows))
+		for _, row := range rows {
+			tables = append(tables, NewTable(row))
+		}
+		return tables, nil
+	}
+}
+
+// ExecFunc runs a maintenance statement
+type ExecFunc func(ctx context.Context, statement string) error
+
+// Job runs the maintenance a policy calls for, one statement at a time
+type Job struct {
+	policy  Policy
+	load    LoadFunc
+	indexes indexadvisor.ListFunc
+	exec    ExecFunc
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// NewJob creates a maintenance job. indexes lists the indexes considered for
+// rebuilding.
+func NewJob(policy Policy, load LoadFunc, indexes indexadvisor.ListFunc, exec ExecFunc) *Job {
+	return &Job{
+		policy:  policy,
+		load:    load,
+		indexes: indexes,
+		exec:    exec,
+		stats:   Stats{LastResults: []Result{}, Completed: map[string]int64{}, Failed: map[string]int64{}},
+	}
+}
+
+// Policy returns the policy of the job
+func (j *Job) Policy() Policy {
+	return j.policy
+}
+
+// Plan loads the state of the tables and returns it with the tasks the
+// policy calls for
+func (j *Job) Plan(ctx context.Context) ([]Table, []Task, error) {
+	tables, err := j.load(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load table statistics: %w", err)
+	}
+	indexes, err := j.indexes(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	return tables, Plan(tables, indexes, j.policy), nil
+}
+
+// RunOnce runs the tasks the policy calls for. Unless force is set, it does
+// nothing outside the window. A failed task does not stop the others.
+func (j *Job) RunOnce(ctx context.Context, now time.Time, force bool) ([]Result, error) {
+	if ok, err := j.claim(now, force); !ok {
+		return nil, err
+	}
+	return j.run(ctx, now)
+}
+
+// Start runs the tasks in the background, whatever the window, returning
+// ErrRunning if maintenance is already running
+func (j *Job) Start(now time.Time) error {
+	if _, err := j.claim(now, true); err != nil {
+		return err
+	}
+	go func() {
+		if _, err := j.run(context.Background(), now); err != nil {
+			log.Printf("Maintenance failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+// claim marks the job as running, reporting whether it may run at now
+func (j *Job) claim(now time.Time, force bool) (bool, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.stats.Running {
+		return false, ErrRunning
+	}
+	if !force && !j.policy.Window.Contains(now) {
+		j.stats.Skipped++
+		return false, nil
+	}
+	j.stats.Running = true
+	return true, nil
+}
+
+// run runs the planned tasks one at a time and records the results
+func (j *Job) run(ctx context.Context, now time.Time) ([]Result, error) {
+	results := []Result{}
+	_, tasks, err := j.Plan(ctx)
+	for _, task := range tasks {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		result := Result{Task: task, StartedAt: time.Now()}
+		if execErr := j.exec(ctx, task.Statement); execErr != nil {
+			result.Error = execErr.Error()
+			log.Printf("Maintenance failed to %s %s: %v", task.Kind, task.Target, execErr)
+		} else {
+			log.Printf("Maintenance ran %s: %s", task.Statement, task.Reason)
+		}
+		result.Duration = time.Since(result.StartedAt)
+		results = append(results, result)
+	}
+
+	j.record(now, results, err)
+	return results, err
+}
+
+// record updates the job's stats after a run
+func (j *Job) record(now time.Time, results []Result, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.stats.Running = false
+	j.stats.Runs++
+	j.stats.LastRun = &now
+	j.stats.LastResults = results
+	for _, result := range results {
+		if result.Error != "" {
+			j.stats.Failed[result.Kind]++
+		} else {
+			j.stats.Completed[result.Kind]++
+		}
+	}
+	j.stats.LastError = ""
+	if err != nil {
+		j.stats.LastError = err.Error()
+	}
+}
+
+// Stats returns a snapshot of the job's stats
+func (j *Job) Stats() Stats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stats := j.stats
+	stats.LastResults = append([]Result{}, j.stats.LastResults...)
+	stats.Completed = make(map[string]int64, len(j.stats.Completed))
+	for kind, n := range j.stats.Completed {
+		stats.Completed[kind] = n
+	}
+	stats.Failed = make(map[string]int64, len(j.stats.Failed))
+	for kind, n := range j.stats.Failed {
+		stats.Failed[kind] = n
+	}
+	return stats
+}
+
+// Run runs the maintenance every interval, within the window, until ctx is
+// cancelled
+func (j *Job) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := j.RunOnce(ctx, now.UTC(), false); err != nil && !errors.Is(err, ErrRunning) {
+				log.Printf("Maintenance failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/indexadvisor"
+)
+
+func TestParseWindow(t *testing.T) {
+	window, err := ParseWindow("22:00-04:30")
+	if err != nil || window.String() != "22:00-04:30" {
+		t.Fatalf("ParseWindow() = %v, %v", window, err)
+	}
+	for clock, want := range map[string]bool{"21:59": false, "22:00": true, "02:00": true, "04:29": true, "04:30": false, "12:00": false} {
+		at, _ := time.Parse("15:04", clock)
+		if got := window.Contains(at); got != want {
+			t.Errorf("Contains(%s) = %v, want %v", clock, got, want)
+		}
+	}
+
+	daytime, _ := ParseWindow("09:00-17:00")
+	if daytime.Contains(time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)) || !daytime.Contains(time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("Contains() of a daytime window = %v", daytime)
+	}
+	if anytime, err := ParseWindow(""); err != nil || !anytime.Contains(time.Now()) {
+		t.Errorf("ParseWindow(\"\") = %v, %v", anytime, err)
+	}
+	for _, value := range []string{"02:00", "2am-5am", "02:00-02:00", "25:00-01:00"} {
+		if _, err := ParseWindow(value); err == nil {
+			t.Errorf("ParseWindow(%q) succeeded", value)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	tables := []Table{
+		NewTable(db.ListTableMaintenanceStatsRow{Name: "webhook_events", LiveTuples: 80000, DeadTuples: 20000, HeapBytes: 600, ToastBytes: 400}),
+		NewTable(db.ListTableMaintenanceStatsRow{Name: "commits", LiveTuples: 10000, DeadTuples: 100, ModifiedSinceAnalyze: 5000}),
+		// Small enough to leave to autovacuum
+		NewTable(db.ListTableMaintenanceStatsRow{Name: "banners", LiveTuples: 5, DeadTuples: 50, ModifiedSinceAnalyze: 50}),
+		NewTable(db.ListTableMaintenanceStatsRow{Name: "pull_requests", LiveTuples: 100000, DeadTuples: 1500, ModifiedSinceAnalyze: 2000}),
+	}
+	if tables[0].DeadPercent != 20 || tables[0].ToastPercent != 40 {
+		t.Errorf("NewTable() = %+v", tables[0])
+	}
+	indexes := []indexadvisor.Index{
+		{Name: "idx_bloated", SizeBytes: 100 << 20, EstimatedBloatBytes: 60 << 20, Valid: true},
+		{Name: "idx_compact", SizeBytes: 100 << 20, EstimatedBloatBytes: 10 << 20, Valid: true},
+		{Name: "idx_small", SizeBytes: 1 << 20, EstimatedBloatBytes: 1 << 19, Valid: true},
+		{Name: "idx_invalid", SizeBytes: 100 << 20, EstimatedBloatBytes: 90 << 20},
+	}
+	policy := Policy{VacuumDeadPercent: DefaultVacuumDeadPercent, AnalyzeChangedPercent: DefaultAnalyzeChangedPercent, ReindexBloatPercent: DefaultReindexBloatPercent}
+
+	tasks := Plan(tables, indexes, policy)
+	want := []string{`VACUUM (ANALYZE) "webhook_events"`, `ANALYZE "commits"`, `REINDEX INDEX CONCURRENTLY "idx_bloated"`}
+	if len(tasks) != len(want) {
+		t.Fatalf("Plan() = %+v", tasks)
+	}
+	for i, statement := range want {
+		if tasks[i].Statement != statement {
+			t.Errorf("Plan()[%d] = %s, want %s", i, tasks[i].Statement, statement)
+		}
+	}
+	if tasks[0].Reason != "20000 dead rows, 20% of the table" {
+		t.Errorf("Plan()[0].Reason = %s", tasks[0].Reason)
+	}
+
+	if tasks := Plan(tables, indexes, Policy{}); len(tasks) != 0 {
+		t.Errorf("Plan() with everything disabled = %+v", tasks)
+	}
+}
+
+func TestJob_RunOnce(t *testing.T) {
+	tables := []Table{NewTable(db.ListTableMaintenanceStatsRow{Name: "webhook_events", LiveTuples: 1000, DeadTuples: 1000, ModifiedSinceAnalyze: 1000})}
+	var statements []string
+	exec := func(ctx context.Context, statement string) error {
+		statements = append(statements, statement)
+		return errors.New("canceling statement due to lock timeout")
+	}
+	window, _ := ParseWindow("02:00-05:00")
+	job := NewJob(Policy{VacuumDeadPercent: 10, Window: window},
+		func(ctx context.Context) ([]Table, error) { return tables, nil },
+		func(ctx context.Context) ([]indexadvisor.Index, error) { return nil, nil },
+		exec)
+
+	// Outside the window nothing runs, unless forced
+	noon := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if results, err := job.RunOnce(context.Background(), noon, false); results != nil || err != nil || len(statements) != 0 {
+		t.Fatalf("RunOnce() outside the window = %+v, %v", results, err)
+	}
+	results, err := job.RunOnce(context.Background(), noon, true)
+	if err != nil || len(results) != 1 || results[0].Error == "" || len(statements) != 1 {
+		t.Fatalf("RunOnce() forced = %+v, %v", results, err)
+	}
+
+	stats := job.Stats()
+	if stats.Runs != 1 || stats.Skipped != 1 || stats.Failed[KindVacuum] != 1 || stats.Running || len(stats.LastResults) != 1 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DashboardUID is the stable UID of the generated Grafana dashboard, so
+// importing it again replaces the earlier import
+const DashboardUID = "choochoo-business-metrics"
+
+// grafanaPanel is a time series panel of a Grafana dashboard
+type grafanaPanel struct {
+	ID          int                `json:"id"`
+	Title       string             `json:"title"`
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type"`
+	Datasource  grafanaDatasource  `json:"datasource"`
+	GridPos     grafanaGridPos     `json:"gridPos"`
+	FieldConfig grafanaFieldConfig `json:"fieldConfig"`
+	Targets     []grafanaTarget    `json:"targets"`
+}
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaGridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type grafanaFieldConfig struct {
+	Defaults struct {
+		Unit string `json:"unit,omitempty"`
+	} `json:"defaults"`
+	Overrides []interface{} `json:"overrides"`
+}
+
+type grafanaTarget struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat"`
+}
+
+// datasource refers to the dashboard's datasource variable
+var datasource = grafanaDatasource{Type: "prometheus", UID: "${datasource}"}
+
+// repositoryFilter selects the repositories chosen in the dashboard's
+// repository variable
+var repositoryFilter = fmt.Sprintf(`%s=~"$repository"`, LabelRepository)
+
+// GrafanaDashboard generates a Grafana dashboard of the pushed business
+// metrics, for a Prometheus datasource picked when it is imported
+func GrafanaDashboard() ([]byte, error) {
+	panel := func(id int, title, description, unit string, x, y int, targets ...grafanaTarget) grafanaPanel {
+		p := grafanaPanel{
+			ID:          id,
+			Title:       title,
+			Description: description,
+			Type:        "timeseries",
+			Datasource:  datasource,
+			GridPos:     grafanaGridPos{H: 8, W: 12, X: x, Y: y},
+			Targets:     targets,
+		}
+		p.FieldConfig.Defaults.Unit = unit
+		p.FieldConfig.Overrides = []interface{}{}
+		return p
+	}
+	target := func(refID, expr, legend string) grafanaTarget {
+		return grafanaTarget{RefID: refID, Expr: expr, LegendFormat: legend}
+	}
+
+	panels := []grafanaPanel{
+		panel(1, "Merged pull requests", "Pull requests merged over the push window", "short", 0, 0,
+			target("A", fmt.Sprintf("sum by (%s) (%s{%s})", LabelRepository, MergedPullRequests, repositoryFilter), "{{"+LabelRepository+"}}")),
+		panel(2, "Pull request cycle time", "Average time from opening to merging the pull requests merged over the push window", "s", 12, 0,
+			target("A", fmt.Sprintf("max by (%s) (%s{%s})", LabelRepository, PullRequestCycleTime, repositoryFilter), "{{"+LabelRepository+"}}")),
+		panel(3, "Deployments", "Deployment statuses and Pages builds over the push window", "short", 0, 8,
+			target("A", fmt.Sprintf("sum by (%s) (%s{%s})", LabelOutcome, Deployments, repositoryFilter), "{{"+LabelOutcome+"}}")),
+		panel(4, "Deployment failure rate", "Share of deployments that failed over the push window", "percentunit", 12, 8,
+			target("A", fmt.Sprintf(`sum by (%[1]s) (%[2]s{%[3]s,%[4]s="failure"}) / sum by (%[1]s) (%[2]s{%[3]s})`, LabelRepository, Deployments, repositoryFilter, LabelOutcome), "{{"+LabelRepository+"}}")),
+	}
+
+	dashboard := map[string]interface{}{
+		"uid":           DashboardUID,
+		"title":         "choochoo business metrics",
+		"tags":          []string{"choochoo"},
+		"timezone":      "browser",
+		"schemaVersion": 39,
+		"version":       1,
+		"editable":      true,
+		"refresh":       "1m",
+		"time":          map[string]string{"from": "now-7d", "to": "now"},
+		"panels":        panels,
+		"templating": map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{
+					"name":  "datasource",
+					"label": "Datasource",
+					"type":  "datasource",
+					"query": "prometheus",
+				},
+				map[string]interface{}{
+					"name":       "repository",
+					"label":      "Repository",
+					"type":       "query",
+					"datasource": datasource,
+					"query":      fmt.Sprintf("label_values(%s, %s)", MergedPullRequests, LabelRepository),
+					"refresh":    2,
+					"includeAll": true,
+					"multi":      true,
+					"allValue":   ".*",
+					"current":    map[string]interface{}{"text": "All", "value": "$__all"},
+				},
+			},
+		},
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
@@ -0,0 +1,175 @@
+// Package metrics pushes business metrics derived from stored events to an
+// external time series database on a schedule, for installs without a
+// Prometheus scraper. Metrics are sent with Prometheus remote-write or as
+// statsd gauges.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/maintenance"
+)
+
+// Names of the pushed metrics
+const (
+	MergedPullRequests   = "choochoo_merged_pull_requests"
+	PullRequestCycleTime = "choochoo_pull_request_cycle_time_seconds"
+	Deployments          = "choochoo_deployments"
+	// Table metrics, to watch the tables and their maintenance
+	TableDeadTuples  = "choochoo_table_dead_tuples"
+	TableBytes       = "choochoo_table_bytes"
+	TableLastVacuum  = "choochoo_table_last_vacuum_timestamp_seconds"
+	MaintenanceTasks = "choochoo_maintenance_tasks"
+)
+
+// Labels of the pushed metrics
+const (
+	LabelRepository = "repository"
+	// LabelOutcome is success or failure on deployments and maintenance
+	// tasks
+	LabelOutcome = "outcome"
+	LabelTable   = "table"
+	// LabelPart is heap, toast or index on table sizes
+	LabelPart = "part"
+	// LabelKind is vacuum, analyze or reindex on maintenance tasks
+	LabelKind = "kind"
+)
+
+// Sample is one value of a metric
+type Sample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Sink sends samples to a time series database
+type Sink interface {
+	Push(ctx context.Context, samples []Sample, at time.Time) error
+}
+
+// NewSink creates the sink for a push URL: an http or https Prometheus
+// remote-write endpoint, or statsd://host:port for a statsd server
+func NewSink(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid push URL %q", rawURL)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return NewRemoteWrite(rawURL), nil
+	case "statsd":
+		return NewStatsd(u.Host), nil
+	}
+	return nil, fmt.Errorf("invalid push URL %q, expected http, https or statsd scheme", rawURL)
+}
+
+// Signals are the stored activity metrics are derived from
+type Signals struct {
+	MergeWait []db.ListMergeWaitByRepositoryRow
+	Deploys   []db.ListDeployOutcomesByRepositoryRow
+	// Tables are the statistics of the tables, and Maintenance what table
+	// maintenance has done since startup, if it is set up
+	Tables      []db.ListTableMaintenanceStatsRow
+	Maintenance *maintenance.Stats
+}
+
+// Derive turns signals into samples, per repository: pull requests merged
+// and their average cycle time from opening to merge, and deployments by
+// outcome; and per table: dead rows, size by part and the time of the last
+// vacuum, and maintenance tasks run by kind and outcome
+func Derive(signals Signals) []Sample {
+	var samples []Sample
+	for _, row := range signals.MergeWait {
+		labels := map[string]string{LabelRepository: row.RepositoryName}
+		samples = append(samples,
+			Sample{Name: MergedPullRequests, Labels: labels, Value: float64(row.Samples)},
+			Sample{Name: PullRequestCycleTime, Labels: labels, Value: row.AvgSeconds},
+		)
+	}
+	for _, row := range signals.Deploys {
+		samples = append(samples,
+			Sample{Name: Deployments, Labels: map[string]string{LabelRepository: row.RepositoryName, LabelOutcome: "success"}, Value: float64(row.Succeeded)},
+			Sample{Name: Deployments, Labels: map[string]string{LabelRepository: row.RepositoryName, LabelOutcome: "failure"}, Value: float64(row.Failed)},
+		)
+	}
+	for _, row := range signals.Tables {
+		table := map[string]string{LabelTable: row.Name}
+		samples = append(samples,
+			Sample{Name: TableDeadTuples, Labels: table, Value: float64(row.DeadTuples)},
+			Sample{Name: TableBytes, Labels: map[string]string{LabelTable: row.Name, LabelPart: "heap"}, Value: float64(row.HeapBytes)},
+			Sample{Name: TableBytes, Labels: map[string]string{LabelTable: row.Name, LabelPart: "toast"}, Value: float64(row.ToastBytes)},
+			Sample{Name: TableBytes, Labels: map[string]string{LabelTable: row.Name, LabelPart: "index"}, Value: float64(row.IndexBytes)},
+		)
+		if row.LastVacuum.Valid {
+			samples = append(samples, Sample{Name: TableLastVacuum, Labels: table, Value: float64(row.LastVacuum.Time.Unix())})
+		}
+	}
+	if stats := signals.Maintenance; stats != nil {
+		for _, kind := range []string{maintenance.KindVacuum, maintenance.KindAnalyze, maintenance.KindReindex} {
+			samples = append(samples,
+				Sample{Name: MaintenanceTasks, Labels: map[string]string{LabelKind: kind, LabelOutcome: "success"}, Value: float64(stats.Completed[kind])},
+				Sample{Name: MaintenanceTasks, Labels: map[string]string{LabelKind: kind, LabelOutcome: "failure"}, Value: float64(stats.Failed[kind])},
+			)
+		}
+	}
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Name < samples[j].Name })
+	return samples
+}
+
+// LoadFunc loads the signals of activity since a time
+type LoadFunc func(ctx context.Context, since time.Time) (Signals, error)
+
+// Pusher periodically derives metrics over a trailing window and pushes
+// them to a sink
+type Pusher struct {
+	load   LoadFunc
+	sink   Sink
+	window time.Duration
+}
+
+// NewPusher creates a pusher of the metrics of the last window
+func NewPusher(load LoadFunc, sink Sink, window time.Duration) *Pusher {
+	return &Pusher{load: load, sink: sink, window: window}
+}
+
+// Push derives and pushes the metrics as of now, returning the number of
+// samples pushed
+func (p *Pusher) Push(ctx context.Context, now time.Time) (int, error) {
+	signals, err := p.load(ctx, now.Add(-p.window))
+	if err != nil {
+		return 0, fmt.Errorf("failed to load signals: %w", err)
+	}
+	samples := Derive(signals)
+	if len(samples) == 0 {
+		return 0, nil
+	}
+	if err := p.sink.Push(ctx, samples, now); err != nil {
+		return 0, fmt.Errorf("failed to push metrics: %w", err)
+	}
+	return len(samples), nil
+}
+
+// Run pushes metrics every interval until ctx is cancelled
+func (p *Pusher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pushCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			if _, err := p.Push(pushCtx, time.Now()); err != nil {
+				log.Printf("Failed to push business metrics: %v", err)
+			}
+			cancel()
+		}
+	}
+}
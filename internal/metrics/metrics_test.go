@@ -0,0 +1,267 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/maintenance"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func testSignals() Signals {
+	return Signals{
+		MergeWait: []db.ListMergeWaitByRepositoryRow{{RepositoryName: "octo-org/api", AvgSeconds: 7200, Samples: 4}},
+		Deploys:   []db.ListDeployOutcomesByRepositoryRow{{RepositoryName: "octo-org/api", Succeeded: 3, Failed: 1}},
+	}
+}
+
+func TestDerive(t *testing.T) {
+	samples := Derive(testSignals())
+	if len(samples) != 4 {
+		t.Fatalf("Expected 4 samples, got %+v", samples)
+	}
+	values := make(map[string]float64)
+	for _, s := range samples {
+		values[s.Name+"/"+s.Labels["outcome"]] = s.Value
+	}
+	want := map[string]float64{
+		MergedPullRequests + "/":   4,
+		PullRequestCycleTime + "/": 7200,
+		Deployments + "/success":   3,
+		Deployments + "/failure":   1,
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("%s = %v, want %v", key, values[key], value)
+		}
+	}
+}
+
+func TestDerive_Tables(t *testing.T) {
+	vacuumed := time.Date(2024, 5, 1, 3, 0, 0, 0, time.UTC)
+	samples := Derive(Signals{
+		Tables: []db.ListTableMaintenanceStatsRow{
+			{Name: "webhook_events", DeadTuples: 500, HeapBytes: 1000, ToastBytes: 4000, IndexBytes: 800, LastVacuum: pgtype.Timestamptz{Time: vacuumed, Valid: true}},
+			{Name: "banners"},
+		},
+		Maintenance: &maintenance.Stats{Completed: map[string]int64{maintenance.KindVacuum: 2}, Failed: map[string]int64{maintenance.KindReindex: 1}},
+	})
+
+	values := make(map[string]float64)
+	for _, s := range samples {
+		values[s.Name+"/"+s.Labels["table"]+s.Labels["part"]+s.Labels["kind"]+s.Labels["outcome"]] = s.Value
+	}
+	want := map[string]float64{
+		TableDeadTuples + "/webhook_events":  500,
+		TableBytes + "/webhook_eventstoast":  4000,
+		TableLastVacuum + "/webhook_events":  float64(vacuumed.Unix()),
+		MaintenanceTasks + "/vacuumsuccess":  2,
+		MaintenanceTasks + "/reindexfailure": 1,
+		MaintenanceTasks + "/analyzesuccess": 0,
+	}
+	for key, value := range want {
+		if v, ok := values[key]; !ok || v != value {
+			t.Errorf("%s = %v, want %v", key, v, value)
+		}
+	}
+	if _, ok := values[TableLastVacuum+"/banners"]; ok {
+		t.Error("Expected no last vacuum of a table never vacuumed")
+	}
+}
+
+func TestNewSink(t *testing.T) {
+	if _, ok := mustSink(t, "https://prometheus.example.com/api/v1/write").(*RemoteWrite); !ok {
+		t.Error("Expected a remote-write sink for an https URL")
+	}
+	if _, ok := mustSink(t, "statsd://localhost:8125").(*Statsd); !ok {
+		t.Error("Expected a statsd sink for a statsd URL")
+	}
+	for _, rawURL := range []string{"ftp://example.com", "localhost:8125", "statsd://"} {
+		if _, err := NewSink(rawURL); err == nil {
+			t.Errorf("Expected an error for %q", rawURL)
+		}
+	}
+}
+
+func mustSink(t *testing.T, rawURL string) Sink {
+	t.Helper()
+	sink, err := NewSink(rawURL)
+	if err != nil {
+		t.Fatalf("NewSink(%q) failed: %v", rawURL, err)
+	}
+	return sink
+}
+
+func TestRemoteWrite_Push(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	at := time.UnixMilli(1700000000000)
+	sample := Sample{Name: Deployments, Labels: map[string]string{"repository": "octo-org/api", "outcome": "success"}, Value: 3}
+	if err := NewRemoteWrite(server.URL).Push(context.Background(), []Sample{sample}, at); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	if header.Get("Content-Encoding") != "snappy" || header.Get("X-Prometheus-Remote-Write-Version") != "0.1.0" {
+		t.Errorf("Unexpected headers: %v", header)
+	}
+	request, err := snappy.Decode(nil, body)
+	if err != nil {
+		t.Fatalf("Body is not snappy-compressed: %v", err)
+	}
+
+	series := field(t, request, 1)
+
+	// Labels are sorted with the metric name first
+	decoded := decodeLabels(t, series)
+	if strings.Join(decoded, ",") != "__name__=choochoo_deployments,outcome=success,repository=octo-org/api" {
+		t.Errorf("Unexpected labels: %v", decoded)
+	}
+
+	point := field(t, series, 2)
+	if v, _ := protowire.ConsumeFixed64(point[1:]); math.Float64frombits(v) != 3 {
+		t.Errorf("Unexpected value %v", math.Float64frombits(v))
+	}
+	if ts, _ := protowire.ConsumeVarint(point[10:]); int64(ts) != at.UnixMilli() {
+		t.Errorf("Unexpected timestamp %d", ts)
+	}
+}
+
+// field returns the first length-delimited field num of a message
+func field(t *testing.T, message []byte, num protowire.Number) []byte {
+	t.Helper()
+	for len(message) > 0 {
+		n, typ, length := protowire.ConsumeTag(message)
+		message = message[length:]
+		if n == num && typ == protowire.BytesType {
+			value, _ := protowire.ConsumeBytes(message)
+			return value
+		}
+		length = protowire.ConsumeFieldValue(n, typ, message)
+		message = message[length:]
+	}
+	t.Fatalf("Field %d not found", num)
+	return nil
+}
+
+// decodeLabels returns the labels of a time series as name=value
+func decodeLabels(t *testing.T, series []byte) []string {
+	t.Helper()
+	var labels []string
+	for len(series) > 0 {
+		num, typ, n := protowire.ConsumeTag(series)
+		series = series[n:]
+		if num == 1 {
+			label, n := protowire.ConsumeBytes(series)
+			labels = append(labels, string(field(t, label, 1))+"="+string(field(t, label, 2)))
+			series = series[n:]
+			continue
+		}
+		series = series[protowire.ConsumeFieldValue(num, typ, series):]
+	}
+	return labels
+}
+
+func TestStatsd_Push(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer conn.Close()
+
+	samples := Derive(testSignals())
+	if err := NewStatsd(conn.LocalAddr().String()).Push(context.Background(), samples, time.Now()); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	buf := make([]byte, maxPacketSize)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	lines := strings.Split(string(buf[:n]), "\n")
+	if len(lines) != 4 || lines[0] != "choochoo.deployments:3|g|#outcome:success,repository:octo-org/api" {
+		t.Errorf("Unexpected packet: %q", lines)
+	}
+}
+
+func TestPusher_Push(t *testing.T) {
+	var since time.Time
+	load := func(ctx context.Context, from time.Time) (Signals, error) {
+		since = from
+		return testSignals(), nil
+	}
+	sink := &recordingSink{}
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+
+	n, err := NewPusher(load, sink, 24*time.Hour).Push(context.Background(), now)
+	if err != nil || n != 4 {
+		t.Fatalf("Push = %d, %v", n, err)
+	}
+	if !since.Equal(now.Add(-24*time.Hour)) || len(sink.samples) != 4 || !sink.at.Equal(now) {
+		t.Errorf("Unexpected push since %v: %+v", since, sink)
+	}
+}
+
+type recordingSink struct {
+	samples []Sample
+	at      time.Time
+}
+
+func (s *recordingSink) Push(ctx context.Context, samples []Sample, at time.Time) error {
+	s.samples, s.at = samples, at
+	return nil
+}
+
+func TestGrafanaDashboard(t *testing.T) {
+	data, err := GrafanaDashboard()
+	if err != nil {
+		t.Fatalf("GrafanaDashboard failed: %v", err)
+	}
+	var dashboard struct {
+		UID    string `json:"uid"`
+		Panels []struct {
+			Targets []struct {
+				Expr string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	if err := json.Unmarshal(data, &dashboard); err != nil {
+		t.Fatalf("Dashboard is not valid JSON: %v", err)
+	}
+	if dashboard.UID != DashboardUID {
+		t.Errorf("Unexpected UID %q", dashboard.UID)
+	}
+
+	// Every pushed metric is charted, filtered by the repository variable
+	var exprs []string
+	for _, panel := range dashboard.Panels {
+		for _, target := range panel.Targets {
+			exprs = append(exprs, target.Expr)
+		}
+	}
+	joined := strings.Join(exprs, "\n")
+	for _, name := range []string{MergedPullRequests, PullRequestCycleTime, Deployments, `repository=~"$repository"`} {
+		if !strings.Contains(joined, name) {
+			t.Errorf("Expected a panel querying %s, got:\n%s", name, joined)
+		}
+	}
+}
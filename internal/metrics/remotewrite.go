@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// RemoteWrite pushes samples to a Prometheus remote-write endpoint
+type RemoteWrite struct {
+	url    string
+	client *http.Client
+}
+
+// NewRemoteWrite creates a sink for a remote-write endpoint URL
+func NewRemoteWrite(endpoint string) *RemoteWrite {
+	return &RemoteWrite{url: endpoint, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Push sends samples as one snappy-compressed WriteRequest
+func (rw *RemoteWrite) Push(ctx context.Context, samples []Sample, at time.Time) error {
+	body := snappy.Encode(nil, encodeWriteRequest(samples, at))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rw.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("User-Agent", "choochoo")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := rw.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("unexpected status %d from %s: %s", resp.StatusCode, rw.url, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// encodeWriteRequest encodes samples as a Prometheus WriteRequest protobuf
+// message, with a time series per sample:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+func encodeWriteRequest(samples []Sample, at time.Time) []byte {
+	var request []byte
+	for _, sample := range samples {
+		var series []byte
+		for _, label := range sortedLabels(sample) {
+			var encoded []byte
+			encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label[0])
+			encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label[1])
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, encoded)
+		}
+
+		var value []byte
+		value = protowire.AppendTag(value, 1, protowire.Fixed64Type)
+		value = protowire.AppendFixed64(value, math.Float64bits(sample.Value))
+		value = protowire.AppendTag(value, 2, protowire.VarintType)
+		value = protowire.AppendVarint(value, uint64(at.UnixMilli()))
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, value)
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, series)
+	}
+	return request
+}
+
+// sortedLabels returns the labels of a sample with its __name__, sorted by
+// name as remote-write requires
+func sortedLabels(sample Sample) [][2]string {
+	labels := [][2]string{{"__name__", sample.Name}}
+	for name, value := range sample.Labels {
+		labels = append(labels, [2]string{name, value})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i][0] < labels[j][0] })
+	return labels
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPacketSize keeps statsd packets within a typical network MTU
+const maxPacketSize = 1432
+
+// Statsd sends samples to a statsd server over UDP as gauges. Labels are
+// sent as DogStatsD tags, which Telegraf and the Datadog agent understand.
+type Statsd struct {
+	addr string
+}
+
+// NewStatsd creates a sink for a statsd server at host:port
+func NewStatsd(addr string) *Statsd {
+	return &Statsd{addr: addr}
+}
+
+// Push sends samples, batching lines into packets. statsd has no
+// timestamps, so at is not sent.
+func (s *Statsd) Push(ctx context.Context, samples []Sample, at time.Time) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", s.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var packet []byte
+	for _, sample := range samples {
+		line := statsdLine(sample)
+		if len(packet) > 0 && len(packet)+1+len(line) > maxPacketSize {
+			if _, err := conn.Write(packet); err != nil {
+				return err
+			}
+			packet = packet[:0]
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	if len(packet) > 0 {
+		_, err = conn.Write(packet)
+	}
+	return err
+}
+
+// statsdLine formats a sample as a gauge under a choochoo. namespace, such as
+// choochoo.deployments:3|g|#outcome:success
+func statsdLine(sample Sample) string {
+	line := strings.Replace(sample.Name, "_", ".", 1) + ":" + strconv.FormatFloat(sample.Value, 'f', -1, 64) + "|g"
+
+	names := make([]string, 0, len(sample.Labels))
+	for name := range sample.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, name+":"+sanitizeTag(sample.Labels[name]))
+	}
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line
+}
+
+// sanitizeTag replaces the characters that separate statsd fields and tags
+func sanitizeTag(value string) string {
+	return strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_").Replace(value)
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// GitHub is the provider name of GitHub webhooks
+const GitHub = "github"
+
+func init() {
+	Register(gitHubAdapter{})
+}
+
+// gitHubAdapter models push, pull_request and issue_comment events
+type gitHubAdapter struct{}
+
+func (gitHubAdapter) Provider() string { return GitHub }
+
+func (gitHubAdapter) Adapt(eventType string, body []byte) (*Event, error) {
+	switch eventType {
+	case webhook.PushEvent:
+		push, err := webhook.ParsePush(body)
+		if err != nil {
+			return nil, err
+		}
+		model := &Push{
+			Repository: push.Repository,
+			Ref:        push.Ref,
+			Before:     push.Before,
+			After:      push.After,
+			Deleted:    push.Deleted,
+			Commits:    make([]Commit, 0, len(push.Commits)),
+		}
+		for _, commit := range push.Commits {
+			model.Commits = append(model.Commits, Commit{
+				ID:        commit.ID,
+				Message:   commit.Message,
+				URL:       commit.URL,
+				Timestamp: commit.Timestamp,
+				Author:    Actor{Login: commit.Author.Username, Name: commit.Author.Name, Email: commit.Author.Email},
+			})
+		}
+		return &Event{Kind: KindPush, Push: model}, nil
+
+	case webhook.PullRequestEvent:
+		activity, err := webhook.ParsePullRequestActivity(body)
+		if err != nil {
+			return nil, err
+		}
+		pr := activity.PullRequest
+		return &Event{Kind: KindPullRequest, PullRequest: &PullRequest{
+			Repository: activity.Repository,
+			Action:     activity.Action,
+			Number:     pr.Number,
+			Title:      pr.Title,
+			State:      pr.State,
+			Draft:      pr.Draft,
+			Merged:     pr.Merged,
+			URL:        pr.HTMLURL,
+			HeadBranch: pr.Head.Ref,
+			HeadSHA:    pr.Head.SHA,
+			BaseBranch: pr.Base.Ref,
+			Author:     Actor{Login: pr.User.Login},
+			CreatedAt:  pr.CreatedAt,
+			UpdatedAt:  pr.UpdatedAt,
+			ClosedAt:   pr.ClosedAt,
+			MergedAt:   pr.MergedAt,
+		}}, nil
+
+	case webhook.IssueCommentEvent:
+		activity, err := webhook.ParseIssueCommentActivity(body)
+		if err != nil {
+			return nil, err
+		}
+		comment := activity.Comment
+		return &Event{Kind: KindComment, Comment: &Comment{
+			Repository:    activity.Repository,
+			Action:        activity.Action,
+			ID:            comment.ID,
+			Number:        activity.IssueNumber,
+			OnPullRequest: activity.IsPullRequest,
+			Body:          comment.Body,
+			URL:           comment.HTMLURL,
+			Author:        Actor{Login: comment.User.Login},
+			CreatedAt:     comment.CreatedAt,
+			UpdatedAt:     comment.UpdatedAt,
+		}}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,139 @@
+// Package model defines provider-agnostic pushes, pull requests and comments.
+// Each provider has an adapter turning its webhook payloads into the model, so
+// rules, forwarders and the query API see one schema whatever the source.
+package model
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kinds of modelled events
+const (
+	KindPush        = "push"
+	KindPullRequest = "pull_request"
+	KindComment     = "comment"
+)
+
+// Actor is the account behind a change
+type Actor struct {
+	Login string `json:"login,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// Commit is a commit included in a push
+type Commit struct {
+	ID        string    `json:"id"`
+	Message   string    `json:"message"`
+	URL       string    `json:"url,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	Author    Actor     `json:"author"`
+}
+
+// Push is a push of commits to a branch or tag
+type Push struct {
+	Repository string `json:"repository"`
+	// Ref is the full ref pushed, e.g. refs/heads/main
+	Ref     string   `json:"ref"`
+	Before  string   `json:"before"`
+	After   string   `json:"after"`
+	Deleted bool     `json:"deleted"`
+	Commits []Commit `json:"commits"`
+}
+
+// PullRequest is activity on a pull or merge request
+type PullRequest struct {
+	Repository string     `json:"repository"`
+	Action     string     `json:"action"`
+	Number     int        `json:"number"`
+	Title      string     `json:"title"`
+	State      string     `json:"state"`
+	Draft      bool       `json:"draft"`
+	Merged     bool       `json:"merged"`
+	URL        string     `json:"url,omitempty"`
+	HeadBranch string     `json:"head_branch"`
+	HeadSHA    string     `json:"head_sha"`
+	BaseBranch string     `json:"base_branch"`
+	Author     Actor      `json:"author"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
+	MergedAt   *time.Time `json:"merged_at,omitempty"`
+}
+
+// Comment is activity on a comment on an issue or pull request
+type Comment struct {
+	Repository string `json:"repository"`
+	Action     string `json:"action"`
+	ID         int64  `json:"id"`
+	// Number is the number of the issue or pull request commented on
+	Number        int       `json:"number"`
+	OnPullRequest bool      `json:"on_pull_request"`
+	Body          string    `json:"body"`
+	URL           string    `json:"url,omitempty"`
+	Author        Actor     `json:"author"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Event is the model of a webhook event. Exactly one of Push, PullRequest and
+// Comment is set, as named by Kind.
+type Event struct {
+	Provider    string       `json:"provider"`
+	Kind        string       `json:"kind"`
+	Push        *Push        `json:"push,omitempty"`
+	PullRequest *PullRequest `json:"pull_request,omitempty"`
+	Comment     *Comment     `json:"comment,omitempty"`
+}
+
+// Adapter turns the webhook payloads of a provider into the model
+type Adapter interface {
+	// Provider names the provider, e.g. "github"
+	Provider() string
+	// Adapt returns the model of an event, or nil for event types without one
+	Adapt(eventType string, body []byte) (*Event, error)
+}
+
+var (
+	mu       sync.RWMutex
+	adapters = make(map[string]Adapter)
+)
+
+// Register makes an adapter available to Adapt, replacing any adapter
+// registered for the same provider
+func Register(adapter Adapter) {
+	mu.Lock()
+	defer mu.Unlock()
+	adapters[adapter.Provider()] = adapter
+}
+
+// Providers returns the names of the registered providers, sorted
+func Providers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(adapters))
+	for name := range adapters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Adapt returns the model of an event received from provider, or nil for
+// event types without one
+func Adapt(provider, eventType string, body []byte) (*Event, error) {
+	mu.RLock()
+	adapter, ok := adapters[provider]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no adapter for provider %q", provider)
+	}
+	event, err := adapter.Adapt(eventType, body)
+	if event != nil {
+		event.Provider = provider
+	}
+	return event, err
+}
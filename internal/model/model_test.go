@@ -0,0 +1,80 @@
+package model
+
+import (
+	"testing"
+)
+
+// TestAdapt_GitHub tests modelling GitHub pushes, pull requests and comments
+func TestAdapt_GitHub(t *testing.T) {
+	push, err := Adapt(GitHub, "push", []byte(`{
+		"ref": "refs/heads/main", "before": "a1", "after": "b2",
+		"commits": [{"id": "b2", "message": "Fix retries", "author": {"name": "Mona", "email": "mona@example.com", "username": "octocat"}}],
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`))
+	if err != nil || push == nil || push.Kind != KindPush || push.Provider != GitHub {
+		t.Fatalf("Adapt(push) = %+v, %v", push, err)
+	}
+	if push.Push.Repository != "octo-org/hello-world" || len(push.Push.Commits) != 1 || push.Push.Commits[0].Author.Login != "octocat" {
+		t.Errorf("Unexpected push: %+v", push.Push)
+	}
+
+	pr, err := Adapt(GitHub, "pull_request", []byte(`{
+		"action": "opened",
+		"pull_request": {"number": 7, "title": "Add retries", "state": "open", "head": {"ref": "retries", "sha": "c3"}, "base": {"ref": "main"}, "user": {"login": "octocat"}},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`))
+	if err != nil || pr == nil || pr.Kind != KindPullRequest {
+		t.Fatalf("Adapt(pull_request) = %+v, %v", pr, err)
+	}
+	if pr.PullRequest.Number != 7 || pr.PullRequest.HeadBranch != "retries" || pr.PullRequest.BaseBranch != "main" || pr.PullRequest.Author.Login != "octocat" {
+		t.Errorf("Unexpected pull request: %+v", pr.PullRequest)
+	}
+
+	comment, err := Adapt(GitHub, "issue_comment", []byte(`{
+		"action": "created",
+		"issue": {"number": 7, "pull_request": {}},
+		"comment": {"id": 9, "body": "LGTM", "user": {"login": "hubot"}}
+	}`))
+	if err != nil || comment == nil || comment.Kind != KindComment {
+		t.Fatalf("Adapt(issue_comment) = %+v, %v", comment, err)
+	}
+	if comment.Comment.Number != 7 || !comment.Comment.OnPullRequest || comment.Comment.Author.Login != "hubot" {
+		t.Errorf("Unexpected comment: %+v", comment.Comment)
+	}
+
+	if event, err := Adapt(GitHub, "star", []byte(`{}`)); event != nil || err != nil {
+		t.Errorf("Adapt(star) = %+v, %v, want no model", event, err)
+	}
+	if _, err := Adapt(GitHub, "push", []byte(`{}`)); err == nil {
+		t.Error("Expected an error for a push without a ref")
+	}
+}
+
+type fakeAdapter struct{}
+
+func (fakeAdapter) Provider() string { return "gitea" }
+
+func (fakeAdapter) Adapt(eventType string, body []byte) (*Event, error) {
+	return &Event{Kind: KindPush, Push: &Push{Ref: "refs/heads/main"}}, nil
+}
+
+// TestRegister tests adding adapters for other providers
+func TestRegister(t *testing.T) {
+	if _, err := Adapt("gitea", "push", nil); err == nil {
+		t.Error("Expected an error for a provider without an adapter")
+	}
+	Register(fakeAdapter{})
+	defer func() {
+		mu.Lock()
+		delete(adapters, "gitea")
+		mu.Unlock()
+	}()
+
+	event, err := Adapt("gitea", "push", nil)
+	if err != nil || event.Provider != "gitea" || event.Push.Ref != "refs/heads/main" {
+		t.Errorf("Adapt() = %+v, %v", event, err)
+	}
+	if providers := Providers(); len(providers) != 2 || providers[0] != "gitea" || providers[1] != GitHub {
+		t.Errorf("Providers() = %v", providers)
+	}
+}
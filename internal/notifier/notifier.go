@@ -0,0 +1,117 @@
+// Package notifier sends test notifications through the channels of each
+// route list, so operators can check a channel URL works without waiting for
+// a real event to be routed to it.
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+)
+
+// TestEvent is the event type of test notifications. Like GitHub's ping
+// event, receivers can acknowledge it without acting on it.
+const TestEvent = "ping"
+
+// ErrUnknown is returned when testing a notifier with no channels
+var ErrUnknown = errors.New("unknown notifier")
+
+// Channel is a channel of a notifier, with the route match that selects it
+type Channel struct {
+	Match     string
+	Forwarder forwarder.Forwarder
+}
+
+// Result is the outcome of sending a test notification to one channel
+type Result struct {
+	Match     string `json:"match"`
+	Channel   string `json:"channel"`
+	OK        bool   `json:"ok"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// Set holds the channels of each notifier, named after the route lists of a
+// config bundle such as "security_alerts"
+type Set struct {
+	channels map[string][]Channel
+}
+
+// NewSet creates an empty set of notifiers
+func NewSet() *Set {
+	return &Set{channels: make(map[string][]Channel)}
+}
+
+// Add adds a channel to the notifier called name
+func (s *Set) Add(name, match string, f forwarder.Forwarder) {
+	s.channels[name] = append(s.channels[name], Channel{Match: match, Forwarder: f})
+}
+
+// WrapTransports wraps the transport of every channel that sends HTTP
+// requests with wrap
+func (s *Set) WrapTransports(wrap func(http.RoundTripper) http.RoundTripper) {
+	for _, channels := range s.channels {
+		for _, channel := range channels {
+			forwarder.WrapTransports([]forwarder.Forwarder{channel.Forwarder}, wrap)
+		}
+	}
+}
+
+// Test sends a test notification to every channel of the notifier called
+// name, or only to the channel of the route matching match when it is not
+// empty. Transport errors are reported in the results rather than returned.
+func (s *Set) Test(ctx context.Context, name, match string) ([]Result, error) {
+	channels := s.channels[name]
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("%w %q", ErrUnknown, name)
+	}
+
+	results := []Result{}
+	for _, channel := range channels {
+		if match != "" && channel.Match != match {
+			continue
+		}
+		results = append(results, send(ctx, name, channel))
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w route %q of %s", ErrUnknown, match, name)
+	}
+	return results, nil
+}
+
+// send delivers a test notification to a channel
+func send(ctx context.Context, name string, channel Channel) Result {
+	now := time.Now()
+	payload, _ := json.Marshal(map[string]interface{}{
+		"zen":      "Test notification from choochoo",
+		"test":     true,
+		"notifier": name,
+		"match":    channel.Match,
+		"sent_at":  now.UTC().Format(time.RFC3339),
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	err := channel.Forwarder.Forward(ctx, forwarder.Event{
+		DeliveryID: fmt.Sprintf("test-%s-%d", name, now.UnixNano()),
+		EventType:  TestEvent,
+		Action:     name,
+		Payload:    payload,
+	})
+
+	result := Result{
+		Match:     channel.Match,
+		Channel:   channel.Forwarder.Name(),
+		OK:        err == nil,
+		LatencyMS: time.Since(now).Milliseconds(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
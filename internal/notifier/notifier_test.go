@@ -0,0 +1,51 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+)
+
+type fakeChannel struct {
+	err    error
+	events []forwarder.Event
+}
+
+func (c *fakeChannel) Name() string { return "fake" }
+
+func (c *fakeChannel) Forward(ctx context.Context, event forwarder.Event) error {
+	c.events = append(c.events, event)
+	return c.err
+}
+
+func TestSet_Test(t *testing.T) {
+	working := &fakeChannel{}
+	failing := &fakeChannel{err: errors.New("connection refused")}
+	set := NewSet()
+	set.Add("project_columns", "done", working)
+	set.Add("project_columns", "review", failing)
+
+	results, err := set.Test(context.Background(), "project_columns", "")
+	if err != nil || len(results) != 2 {
+		t.Fatalf("Test = %+v, %v", results, err)
+	}
+	if !results[0].OK || results[1].OK || results[1].Error != "connection refused" || results[1].Match != "review" {
+		t.Errorf("Unexpected results %+v", results)
+	}
+
+	event := working.events[0]
+	var payload map[string]interface{}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		t.Fatalf("Payload is not JSON: %v", err)
+	}
+	if event.EventType != TestEvent || payload["test"] != true || payload["notifier"] != "project_columns" {
+		t.Errorf("Unexpected test event %+v with payload %v", event, payload)
+	}
+
+	if _, err := set.Test(context.Background(), "discussions", ""); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Expected ErrUnknown for a notifier without channels, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+// Package outbound records the most recent outbound HTTP requests to each
+// target host, so it is possible to tell what was sent to a downstream
+// system, when, and what it answered.
+package outbound
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults for the size of the log
+const (
+	DefaultSize      = 50
+	DefaultBodyBytes = 4096
+)
+
+// redactedHeaders are not recorded, since they carry credentials
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"X-Hub-Signature":     true,
+	"X-Hub-Signature-256": true,
+}
+
+// Request is a recorded outbound request and its outcome
+type Request struct {
+	Time          time.Time           `json:"time"`
+	Method        string              `json:"method"`
+	URL           string              `json:"url"`
+	Headers       map[string][]string `json:"headers"`
+	Body          string              `json:"body,omitempty"`
+	BodyTruncated bool                `json:"body_truncated,omitempty"`
+	Status        int                 `json:"status,omitempty"`
+	Error         string              `json:"error,omitempty"`
+	LatencyMS     int64               `json:"latency_ms"`
+}
+
+// ring holds the latest requests to a target, overwriting the oldest once
+// it is full
+type ring struct {
+	requests []Request
+	next     int
+}
+
+func (r *ring) add(request Request, size int) {
+	if len(r.requests) < size {
+		r.requests = append(r.requests, request)
+		return
+	}
+	r.requests[r.next] = request
+	r.next = (r.next + 1) % size
+}
+
+// newestFirst returns a copy of the requests, most recent first
+func (r *ring) newestFirst() []Request {
+	requests := make([]Request, 0, len(r.requests))
+	for i := len(r.requests) - 1; i >= 0; i-- {
+		requests = append(requests, r.requests[(r.next+i)%len(r.requests)])
+	}
+	return requests
+}
+
+// Log keeps the last size requests to each target host, with at most
+// bodyBytes of each request body
+type Log struct {
+	size      int
+	bodyBytes int
+
+	mu      sync.Mutex
+	targets map[string]*ring
+}
+
+// NewLog creates a log of the last size requests per target
+func NewLog(size, bodyBytes int) *Log {
+	return &Log{size: size, bodyBytes: bodyBytes, targets: make(map[string]*ring)}
+}
+
+// record adds a request to the log of its target
+func (l *Log) record(target string, request Request) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.targets[target]
+	if !ok {
+		r = &ring{}
+		l.targets[target] = r
+	}
+	r.add(request, l.size)
+}
+
+// Targets lists the hosts requests were sent to
+func (l *Log) Targets() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	targets := make([]string, 0, len(l.targets))
+	for target := range l.targets {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// Requests returns the recorded requests to target, most recent first
+func (l *Log) Requests(target string) []Request {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r, ok := l.targets[target]
+	if !ok {
+		return []Request{}
+	}
+	return r.newestFirst()
+}
+
+// Transport wraps base, or http.DefaultTransport if it is nil, so every
+// request it sends is recorded
+func (l *Log) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{log: l, base: base}
+}
+
+type transport struct {
+	log  *Log
+	base http.RoundTripper
+}
+
+// RoundTrip sends the request with the base transport and records it
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	request := Request{
+		Time:    time.Now(),
+		Method:  req.Method,
+		URL:     redactURL(req),
+		Headers: make(map[string][]string, len(req.Header)),
+	}
+	for name, values := range req.Header {
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			values = []string{"REDACTED"}
+		}
+		request.Headers[name] = values
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		// Capture the start of the body and send it followed by the rest
+		head := make([]byte, t.log.bodyBytes)
+		n, err := io.ReadFull(req.Body, head)
+		head = head[:n]
+		request.Body = string(head)
+		if err == nil {
+			var peek [1]byte
+			m, _ := req.Body.Read(peek[:])
+			if m > 0 {
+				head = append(head, peek[0])
+				request.BodyTruncated = true
+			}
+		}
+		body := req.Body
+		req = req.Clone(req.Context())
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head), body), body}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	request.LatencyMS = time.Since(request.Time).Milliseconds()
+	if err != nil {
+		request.Error = err.Error()
+	} else {
+		request.Status = resp.StatusCode
+	}
+	t.log.record(req.URL.Host, request)
+	return resp, err
+}
+
+// redactURL returns the request URL without user info, which holds
+// credentials
+func redactURL(req *http.Request) string {
+	u := *req.URL
+	u.User = nil
+	return u.String()
+}
@@ -0,0 +1,84 @@
+package outbound
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransport_Records(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	log := NewLog(2, 8)
+	client := &http.Client{Transport: log.Transport(http.DefaultTransport)}
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/hooks", strings.NewReader(`{"action":"opened"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-GitHub-Event", "pull_request")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// The whole body is sent even though only the start is recorded
+	if received != `{"action":"opened"}` {
+		t.Errorf("Downstream received %q", received)
+	}
+
+	target := strings.TrimPrefix(server.URL, "http://")
+	requests := log.Requests(target)
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request to %s, got %+v", target, log.Targets())
+	}
+	request := requests[0]
+	if request.Method != http.MethodPost || request.URL != server.URL+"/hooks" || request.Status != http.StatusAccepted {
+		t.Errorf("Unexpected request %+v", request)
+	}
+	if request.Body != `{"action` || !request.BodyTruncated {
+		t.Errorf("Expected a truncated body, got %q (truncated %v)", request.Body, request.BodyTruncated)
+	}
+	if request.Headers["Authorization"][0] != "REDACTED" || request.Headers["X-Github-Event"][0] != "pull_request" {
+		t.Errorf("Unexpected headers %v", request.Headers)
+	}
+}
+
+func TestTransport_RecordsErrors(t *testing.T) {
+	log := NewLog(2, 64)
+	client := &http.Client{Transport: log.Transport(http.DefaultTransport)}
+
+	if _, err := client.Get("http://127.0.0.1:1/unreachable"); err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	requests := log.Requests("127.0.0.1:1")
+	if len(requests) != 1 || requests[0].Error == "" || requests[0].Status != 0 {
+		t.Errorf("Expected a failed request, got %+v", requests)
+	}
+}
+
+func TestLog_KeepsLatest(t *testing.T) {
+	log := NewLog(3, 0)
+	for _, path := range []string{"/1", "/2", "/3", "/4", "/5"} {
+		log.record("example.com", Request{URL: "https://example.com" + path})
+	}
+
+	requests := log.Requests("example.com")
+	var paths []string
+	for _, request := range requests {
+		paths = append(paths, strings.TrimPrefix(request.URL, "https://example.com"))
+	}
+	if strings.Join(paths, ",") != "/5,/4,/3" {
+		t.Errorf("Expected the latest requests first, got %v", paths)
+	}
+	if len(log.Requests("other.example.com")) != 0 {
+		t.Error("Expected no requests to an unknown target")
+	}
+}
@@ -0,0 +1,250 @@
+// Package outbox publishes stored webhook events to forwarders from a
+// Postgres-backed outbox. An entry for each forwarder is added in the
+// transaction that stores an event, so a publish that fails after the event
+// was stored is retried rather than lost: events are delivered at least once.
+// Relays claim entries with SELECT ... FOR UPDATE SKIP LOCKED, so replicas
+// sharing a database share the outbox, and mark them delivered once
+// published. Failed entries are retried with an exponential backoff until
+// they are published.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Defaults for Config
+const (
+	DefaultPollInterval = time.Second
+	DefaultRetention    = 24 * time.Hour
+)
+
+const (
+	// batchSize is how many entries a relay claims at once
+	batchSize = 10
+	// publishTimeout bounds publishing an entry, and hides a claimed entry
+	// from other relays for as long
+	publishTimeout = 30 * time.Second
+	// maxBackoff caps the delay before a failed entry is retried
+	maxBackoff = 5 * time.Minute
+	// pruneInterval is how often delivered entries past the retention are
+	// deleted
+	pruneInterval = time.Hour
+)
+
+// ErrSkip is returned by a PublishFunc for entries that cannot be published
+// and should be marked delivered without retrying, such as events that were
+// pruned after being stored
+var ErrSkip = errors.New("skip outbox entry")
+
+// Entry is a claimed outbox entry
+type Entry struct {
+	ID         int64
+	DeliveryID string
+	// Target names the forwarder the event is published to
+	Target string
+	// Attempts counts this attempt
+	Attempts int
+}
+
+// Store holds the outbox
+type Store interface {
+	// Claim claims up to limit undelivered entries that are visible
+	Claim(ctx context.Context, limit int, visibility time.Duration) ([]Entry, error)
+	// Deliver marks a published entry delivered
+	Deliver(ctx context.Context, id int64) error
+	// Fail records why publishing an entry failed and hides it until
+	// retryAfter passes
+	Fail(ctx context.Context, id int64, err error, retryAfter time.Duration) error
+	// Prune deletes entries delivered before a time
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PublishFunc publishes the stored event of an entry to its target
+type PublishFunc func(ctx context.Context, entry Entry) error
+
+// Config tunes a relay
+type Config struct {
+	PollInterval time.Duration
+	// Retention is how long delivered entries are kept
+	Retention time.Duration
+}
+
+// Relay publishes the entries of an outbox
+type Relay struct {
+	store   Store
+	publish PublishFunc
+	config  Config
+	wake    chan struct{}
+}
+
+// New creates a relay
+func New(store Store, publish PublishFunc, config Config) *Relay {
+	return &Relay{store: store, publish: publish, config: config, wake: make(chan struct{}, 1)}
+}
+
+// Notify wakes the relay after an event was stored, instead of leaving its
+// entries for the next poll
+func (r *Relay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes entries until ctx is cancelled, waiting for a poll or a
+// notification whenever the outbox is empty
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PollInterval)
+	defer ticker.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	for {
+		for r.RunOnce(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-prune.C:
+			r.prune(ctx)
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// RunOnce claims and publishes a batch of entries, reporting whether there
+// were any
+func (r *Relay) RunOnce(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	entries, err := r.store.Claim(ctx, batchSize, publishTimeout)
+	if err != nil {
+		log.Printf("Failed to claim outbox entries: %v", err)
+		return false
+	}
+	for _, entry := range entries {
+		r.process(ctx, entry)
+	}
+	return len(entries) > 0
+}
+
+// process publishes an entry and records the outcome
+func (r *Relay) process(ctx context.Context, entry Entry) {
+	err := r.run(ctx, entry)
+
+	// Record the outcome even if ctx was cancelled during shutdown
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	if err == nil || errors.Is(err, ErrSkip) {
+		if err != nil {
+			log.Printf("Skipping outbox entry for delivery %s to %s: %v", entry.DeliveryID, entry.Target, err)
+		}
+		if err := r.store.Deliver(storeCtx, entry.ID); err != nil {
+			log.Printf("Failed to mark delivery %s delivered to %s: %v", entry.DeliveryID, entry.Target, err)
+		}
+		return
+	}
+
+	log.Printf("Publishing delivery %s to %s failed (attempt %d): %v", entry.DeliveryID, entry.Target, entry.Attempts, err)
+	if err := r.store.Fail(storeCtx, entry.ID, err, backoff(entry.Attempts)); err != nil {
+		log.Printf("Failed to record failure of delivery %s to %s: %v", entry.DeliveryID, entry.Target, err)
+	}
+}
+
+// run publishes an entry, turning a panic into an error so one malformed
+// payload cannot take down the relay
+func (r *Relay) run(ctx context.Context, entry Entry) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	return r.publish(ctx, entry)
+}
+
+// prune deletes delivered entries past the retention
+func (r *Relay) prune(ctx context.Context) {
+	deleted, err := r.store.Prune(ctx, time.Now().Add(-r.config.Retention))
+	if err != nil {
+		log.Printf("Failed to prune delivered outbox entries: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Pruned %d delivered outbox entries", deleted)
+	}
+}
+
+// backoff doubles the retry delay with each attempt, up to maxBackoff
+func backoff(attempts int) time.Duration {
+	delay := time.Second << min(attempts, 20)
+	return min(delay, maxBackoff)
+}
+
+// postgresStore keeps the outbox in the outbox table
+type postgresStore struct {
+	queries *db.Queries
+}
+
+// NewPostgresStore creates a store on the outbox table
+func NewPostgresStore(queries *db.Queries) Store {
+	return &postgresStore{queries: queries}
+}
+
+// Claim claims up to limit undelivered entries that are visible
+func (s *postgresStore) Claim(ctx context.Context, limit int, visibility time.Duration) ([]Entry, error) {
+	rows, err := s.queries.ClaimOutboxEntries(ctx, db.ClaimOutboxEntriesParams{
+		VisibilitySeconds: seconds(visibility),
+		BatchSize:         int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, Entry{
+			ID:         row.ID,
+			DeliveryID: row.DeliveryID,
+			Target:     row.Target,
+			Attempts:   int(row.Attempts),
+		})
+	}
+	return entries, nil
+}
+
+// Deliver marks a published entry delivered
+func (s *postgresStore) Deliver(ctx context.Context, id int64) error {
+	return s.queries.MarkOutboxEntryDelivered(ctx, id)
+}
+
+// Fail records why publishing an entry failed and hides it until retryAfter
+// passes
+func (s *postgresStore) Fail(ctx context.Context, id int64, err error, retryAfter time.Duration) error {
+	return s.queries.FailOutboxEntry(ctx, db.FailOutboxEntryParams{
+		LastError:    pgtype.Text{String: err.Error(), Valid: true},
+		RetrySeconds: seconds(retryAfter),
+		ID:           id,
+	})
+}
+
+// Prune deletes entries delivered before a time
+func (s *postgresStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	return s.queries.DeleteDeliveredOutboxEntries(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+}
+
+// seconds rounds a duration up to whole seconds
+func seconds(d time.Duration) int32 {
+	return int32((d + time.Second - 1) / time.Second)
+}
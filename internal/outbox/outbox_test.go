@@ -0,0 +1,157 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore is a Store that keeps entries in memory
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[int64]*memoryEntry
+}
+
+type memoryEntry struct {
+	Entry
+	visibleAt time.Time
+	delivered bool
+	lastError error
+}
+
+func newMemoryStore(targets ...string) *memoryStore {
+	store := &memoryStore{entries: make(map[int64]*memoryEntry)}
+	for i, target := range targets {
+		id := int64(i + 1)
+		store.entries[id] = &memoryEntry{Entry: Entry{ID: id, DeliveryID: "delivery-1", Target: target}}
+	}
+	return store
+}
+
+func (s *memoryStore) Claim(ctx context.Context, limit int, visibility time.Duration) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []Entry
+	for _, id := range slices.Sorted(maps.Keys(s.entries)) {
+		entry := s.entries[id]
+		if len(claimed) == limit || entry.delivered || time.Now().Before(entry.visibleAt) {
+			continue
+		}
+		entry.Attempts++
+		entry.visibleAt = time.Now().Add(visibility)
+		claimed = append(claimed, entry.Entry)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) Deliver(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[id].delivered = true
+	return nil
+}
+
+func (s *memoryStore) Fail(ctx context.Context, id int64, err error, retryAfter time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[id].lastError = err
+	// Make failed entries visible immediately so tests do not wait
+	s.entries[id].visibleAt = time.Time{}
+	return nil
+}
+
+func (s *memoryStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestRelay_PublishesEachTarget(t *testing.T) {
+	store := newMemoryStore("nats", "slack:#events")
+	var published []string
+	relay := New(store, func(ctx context.Context, entry Entry) error {
+		published = append(published, entry.Target)
+		return nil
+	}, Config{PollInterval: time.Second})
+
+	for relay.RunOnce(context.Background()) {
+	}
+
+	if !slices.Equal(published, []string{"nats", "slack:#events"}) {
+		t.Errorf("published to %v", published)
+	}
+	for id, entry := range store.entries {
+		if !entry.delivered {
+			t.Errorf("entry %d not marked delivered", id)
+		}
+	}
+}
+
+func TestRelay_RetriesUntilPublished(t *testing.T) {
+	store := newMemoryStore("nats")
+	failures := 2
+	relay := New(store, func(ctx context.Context, entry Entry) error {
+		if failures > 0 {
+			failures--
+			return errors.New("no responders")
+		}
+		return nil
+	}, Config{PollInterval: time.Second})
+
+	for relay.RunOnce(context.Background()) {
+	}
+
+	entry := store.entries[1]
+	if !entry.delivered {
+		t.Fatal("entry not delivered after retries")
+	}
+	if entry.Attempts != 3 {
+		t.Errorf("attempts = %d, want 3", entry.Attempts)
+	}
+	if entry.lastError == nil || entry.lastError.Error() != "no responders" {
+		t.Errorf("last error = %v", entry.lastError)
+	}
+}
+
+func TestRelay_SkipAndPanic(t *testing.T) {
+	store := newMemoryStore("skipped", "panics")
+	relay := New(store, func(ctx context.Context, entry Entry) error {
+		if entry.Target == "panics" && entry.Attempts == 1 {
+			panic("malformed payload")
+		}
+		return ErrSkip
+	}, Config{PollInterval: time.Second})
+
+	for relay.RunOnce(context.Background()) {
+	}
+
+	if !store.entries[1].delivered {
+		t.Error("skipped entry not marked delivered")
+	}
+	if err := store.entries[2].lastError; err == nil || err.Error() != "panic: malformed payload" {
+		t.Errorf("panic recorded as %v", err)
+	}
+	if !store.entries[2].delivered {
+		t.Error("entry not delivered after recovering from a panic")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 2 * time.Second},
+		{3, 8 * time.Second},
+		{8, 256 * time.Second},
+		{9, maxBackoff},
+		{100, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package pathlabels labels pull requests by the paths they change, like the
+// labeler action but on the server, so repositories need no workflow or
+// token of their own for it.
+package pathlabels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/github"
+)
+
+// pullRequestActions are the pull request actions that change the files of
+// a pull request
+var pullRequestActions = []string{"opened", "reopened", "synchronize"}
+
+// Rule applies a label to pull requests changing a path matching a pattern
+type Rule struct {
+	Label   string
+	Pattern string
+}
+
+// ParseRules parses a comma-separated list of label=pattern pairs, e.g.
+// "docs=docs/**,docs=**/*.md,frontend=web/**". A label is applied when any
+// of its patterns matches a changed path.
+func ParseRules(list string) ([]Rule, error) {
+	var rules []Rule
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		label, pattern, ok := strings.Cut(pair, "=")
+		label, pattern = strings.TrimSpace(label), strings.TrimSpace(pattern)
+		if !ok || label == "" || pattern == "" {
+			return nil, fmt.Errorf("invalid path label %q", pair)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid path label pattern %q: %w", pattern, err)
+		}
+		rules = append(rules, Rule{Label: label, Pattern: pattern})
+	}
+	return rules, nil
+}
+
+// Match reports whether a slash-separated path matches a pattern. Patterns
+// match the whole path: each segment is a path.Match pattern, and a "**"
+// segment matches any number of segments, so "docs/**" matches everything
+// under docs and "**/*.md" every Markdown file.
+func Match(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// Labels returns the labels of the rules matching any of paths, in the order
+// of the rules
+func Labels(rules []Rule, paths []string) []string {
+	var labels []string
+	for _, rule := range rules {
+		if slices.Contains(labels, rule.Label) {
+			continue
+		}
+		for _, name := range paths {
+			if Match(rule.Pattern, name) {
+				labels = append(labels, rule.Label)
+				break
+			}
+		}
+	}
+	return labels
+}
+
+// payload holds the pull request fields of a pull_request event
+type payload struct {
+	Action     string `json:"action"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	PullRequest struct {
+		Number int `json:"number"`
+	} `json:"pull_request"`
+}
+
+// Labeler labels pull requests through the GitHub API
+type Labeler struct {
+	rules  []Rule
+	client *github.Client
+}
+
+// New creates a labeler applying rules with client
+func New(rules []Rule, client *github.Client) *Labeler {
+	return &Labeler{rules: rules, client: client}
+}
+
+// Applies reports whether a pull_request event is labeled: pull requests
+// opened, reopened or synchronized, whose files may have changed
+func Applies(body []byte) bool {
+	var event payload
+	if err := json.Unmarshal(body, &event); err != nil {
+		return false
+	}
+	return slices.Contains(pullRequestActions, event.Action)
+}
+
+// Label fetches the files changed by the pull request of a pull_request
+// event and adds the labels of the matching rules, returning them. Other
+// actions than those of Applies are ignored. Labels are only added, so
+// labels of paths a later push no longer changes are kept.
+func (l *Labeler) Label(ctx context.Context, body []byte) ([]string, error) {
+	var event payload
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse pull_request event: %w", err)
+	}
+	repo, number := event.Repository.FullName, event.PullRequest.Number
+	if !slices.Contains(pullRequestActions, event.Action) || repo == "" || number == 0 {
+		return nil, nil
+	}
+
+	paths, err := l.client.PullRequestFiles(ctx, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed files: %w", err)
+	}
+	labels := Labels(l.rules, paths)
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	if err := l.client.AddLabels(ctx, repo, number, labels); err != nil {
+		return nil, fmt.Errorf("failed to add labels: %w", err)
+	}
+	return labels, nil
+}
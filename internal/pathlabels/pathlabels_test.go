@@ -0,0 +1,109 @@
+package pathlabels
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/github"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(" docs=docs/** , docs=**/*.md,frontend=web/**,")
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	want := []Rule{{"docs", "docs/**"}, {"docs", "**/*.md"}, {"frontend", "web/**"}}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("ParseRules() = %+v, want %+v", rules, want)
+	}
+
+	for _, list := range []string{"docs", "=docs/**", "docs=", "docs=[a"} {
+		if _, err := ParseRules(list); err == nil {
+			t.Errorf("ParseRules(%q) succeeded", list)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"docs/**", "docs/guide/intro.md", true},
+		{"docs/**", "docs", true},
+		{"docs/**", "src/docs/intro.md", false},
+		{"**/*.md", "README.md", true},
+		{"**/*.md", "docs/guide/intro.md", true},
+		{"**/*.md", "docs/guide/intro.go", false},
+		{"*.md", "docs/intro.md", false},
+		{"src/*/main.go", "src/api/main.go", true},
+		{"src/*/main.go", "src/api/cmd/main.go", false},
+		{"src/**/test/*.go", "src/test/a.go", true},
+		{"src/**/test/*.go", "src/a/b/test/a.go", true},
+		{"go.mod", "go.mod", true},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	rules := []Rule{{"frontend", "web/**"}, {"docs", "docs/**"}, {"docs", "**/*.md"}, {"deps", "go.sum"}}
+	got := Labels(rules, []string{"README.md", "docs/intro.md", "web/app.ts"})
+	if want := []string{"frontend", "docs"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Labels() = %v, want %v", got, want)
+	}
+}
+
+func TestLabeler_Label(t *testing.T) {
+	var added []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/api/pulls/7/files":
+			w.Write([]byte(`[{"filename": "docs/intro.md"}, {"filename": "main.go"}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/api/issues/7/labels":
+			var body struct {
+				Labels []string `json:"labels"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			added = append(added, body.Labels...)
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	labeler := New([]Rule{{"docs", "docs/**"}, {"frontend", "web/**"}}, github.NewTokenClient(server.URL, "token"))
+
+	body := []byte(`{"action": "synchronize", "number": 7, "pull_request": {"number": 7}, "repository": {"full_name": "acme/api"}}`)
+	if !Applies(body) {
+		t.Error("Applies() = false for a synchronized pull request")
+	}
+	labels, err := labeler.Label(context.Background(), body)
+	if err != nil {
+		t.Fatalf("Label() error = %v", err)
+	}
+	if !reflect.DeepEqual(labels, []string{"docs"}) || !reflect.DeepEqual(added, []string{"docs"}) {
+		t.Errorf("Label() = %v, added %v", labels, added)
+	}
+
+	closed := []byte(`{"action": "closed", "pull_request": {"number": 7}, "repository": {"full_name": "acme/api"}}`)
+	if Applies(closed) {
+		t.Error("Applies() = true for a closed pull request")
+	}
+	if labels, err := labeler.Label(context.Background(), closed); err != nil || labels != nil {
+		t.Errorf("Label() of a closed pull request = %v, %v", labels, err)
+	}
+
+	missing := []byte(`{"action": "opened", "pull_request": {"number": 7}, "repository": {"full_name": "acme/web"}}`)
+	if _, err := labeler.Label(context.Background(), missing); err == nil {
+		t.Error("Label() succeeded without the pull request")
+	}
+}
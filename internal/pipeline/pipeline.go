@@ -0,0 +1,137 @@
+// Package pipeline runs the processing steps of an event in isolation: each
+// step gets its own timeout, a cap on how many of its runs may be in flight,
+// and recovers from panics, so one slow or broken step, such as a chat
+// notifier during an outage, cannot hold up the others.
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for Limits
+const (
+	DefaultTimeout     = 30 * time.Second
+	DefaultConcurrency = 16
+)
+
+// Limits bound the runs of one step
+type Limits struct {
+	// Timeout bounds a run, including the wait for a free slot
+	Timeout time.Duration
+	// Concurrency caps the runs in flight, counting runs that timed out
+	// but have not returned yet
+	Concurrency int
+}
+
+// ParseLimits parses a comma-separated list of step=timeout[:concurrency]
+// overrides, e.g. "docs=10s:2,forwarders=5s". A step without a concurrency
+// keeps the default one.
+func ParseLimits(list string, defaults Limits) (map[string]Limits, error) {
+	limits := make(map[string]Limits)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid processor limit %q", pair)
+		}
+		timeout, concurrency, hasConcurrency := strings.Cut(value, ":")
+
+		limit := defaults
+		var err error
+		if limit.Timeout, err = time.ParseDuration(timeout); err != nil || limit.Timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout for processor %s: %q", name, timeout)
+		}
+		if hasConcurrency {
+			if limit.Concurrency, err = strconv.Atoi(concurrency); err != nil || limit.Concurrency <= 0 {
+				return nil, fmt.Errorf("invalid concurrency for processor %s: %q", name, concurrency)
+			}
+		}
+		limits[name] = limit
+	}
+	return limits, nil
+}
+
+// Runner runs steps within their limits
+type Runner struct {
+	defaults  Limits
+	overrides map[string]Limits
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// NewRunner creates a runner that applies overrides by step name and
+// defaults to every other step
+func NewRunner(defaults Limits, overrides map[string]Limits) *Runner {
+	return &Runner{defaults: defaults, overrides: overrides, slots: make(map[string]chan struct{})}
+}
+
+// Limits returns the limits of a step
+func (r *Runner) Limits(name string) Limits {
+	if limits, ok := r.overrides[name]; ok {
+		return limits
+	}
+	return r.defaults
+}
+
+// semaphore returns the slots of a step, creating them on first use
+func (r *Runner) semaphore(name string) chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	slots, ok := r.slots[name]
+	if !ok {
+		slots = make(chan struct{}, r.Limits(name).Concurrency)
+		r.slots[name] = slots
+	}
+	return slots
+}
+
+// Run runs a step once a slot is free, turning a panic into an error. A run
+// that outlives its timeout is abandoned with an error but keeps its slot
+// until it returns, so a hung step can only tie up as many goroutines as
+// its concurrency allows.
+func (r *Runner) Run(ctx context.Context, name string, step func(ctx context.Context) error) error {
+	limits := r.Limits(name)
+	ctx, cancel := context.WithTimeout(ctx, limits.Timeout)
+
+	slots := r.semaphore(name)
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		cancel()
+		return fmt.Errorf("no free slot within %s: %d runs in flight", limits.Timeout, limits.Concurrency)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() { <-slots }()
+		defer cancel()
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		done <- step(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// Prefer the step's own result if it finished at the deadline
+		select {
+		case err := <-done:
+			return err
+		default:
+		}
+		return fmt.Errorf("timed out after %s", limits.Timeout)
+	}
+}
@@ -0,0 +1,75 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLimits(t *testing.T) {
+	defaults := Limits{Timeout: time.Minute, Concurrency: 4}
+	limits, err := ParseLimits("docs=10s:2, forwarders=5s", defaults)
+	if err != nil {
+		t.Fatalf("ParseLimits() failed: %v", err)
+	}
+	if limits["docs"] != (Limits{Timeout: 10 * time.Second, Concurrency: 2}) {
+		t.Errorf("Unexpected docs limits: %+v", limits["docs"])
+	}
+	if limits["forwarders"] != (Limits{Timeout: 5 * time.Second, Concurrency: 4}) {
+		t.Errorf("Unexpected forwarders limits: %+v", limits["forwarders"])
+	}
+
+	for _, list := range []string{"docs", "docs=soon", "docs=10s:0", "=10s"} {
+		if _, err := ParseLimits(list, defaults); err == nil {
+			t.Errorf("Expected an error for %q", list)
+		}
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	runner := NewRunner(Limits{Timeout: time.Second, Concurrency: 1}, map[string]Limits{
+		"slow": {Timeout: 20 * time.Millisecond, Concurrency: 1},
+	})
+	ctx := context.Background()
+
+	if err := runner.Run(ctx, "push", func(context.Context) error { return nil }); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	failed := errors.New("store failed")
+	if err := runner.Run(ctx, "push", func(context.Context) error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("Expected the step's error, got %v", err)
+	}
+	err := runner.Run(ctx, "push", func(context.Context) error { panic("nil map") })
+	if err == nil || err.Error() != "panic: nil map" {
+		t.Errorf("Expected a panic error, got %v", err)
+	}
+
+	// A hung step times out and keeps its only slot until it returns
+	release := make(chan struct{})
+	err = runner.Run(ctx, "slow", func(context.Context) error {
+		<-release
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Expected a timeout, got %v", err)
+	}
+	err = runner.Run(ctx, "slow", func(context.Context) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "no free slot") {
+		t.Errorf("Expected no free slot, got %v", err)
+	}
+
+	// Other steps are not held up
+	if err := runner.Run(ctx, "push", func(context.Context) error { return nil }); err != nil {
+		t.Errorf("Expected another step to run, got %v", err)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for runner.Run(ctx, "slow", func(context.Context) error { return nil }) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the slot to be freed once the hung step returned")
+		}
+	}
+}
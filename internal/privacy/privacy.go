@@ -0,0 +1,81 @@
+// Package privacy protects aggregate counts shared beyond the people who may
+// see the underlying events. Counts get Laplace noise, for ε-differential
+// privacy with respect to any single delivery, and noisy counts below a
+// minimum are suppressed, so a quiet repository does not reveal when its
+// one or two contributors work.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Policy adds noise with privacy budget Epsilon to counts and suppresses
+// those below MinCount. Noise is derived from a random key and the name and
+// value of each count: asking again for a count returns the same noise
+// rather than noise that averages out, and subtracting overlapping counts
+// does not cancel their noise unless they are equal.
+type Policy struct {
+	Epsilon  float64 `json:"epsilon,omitempty"`
+	MinCount int64   `json:"min_count,omitempty"`
+	key      []byte
+}
+
+// ParseEpsilon parses a privacy budget, a positive number such as "0.5".
+// An empty budget means no noise.
+func ParseEpsilon(value string) (float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, nil
+	}
+	epsilon, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || epsilon <= 0 || math.IsInf(epsilon, 0) {
+		return 0, fmt.Errorf("expected a positive number, got %q", value)
+	}
+	return epsilon, nil
+}
+
+// NewPolicy creates a policy with a new random noise key. It returns nil
+// if neither noise nor a minimum count is configured.
+func NewPolicy(epsilon float64, minCount int64) (*Policy, error) {
+	if epsilon < 0 || minCount < 0 {
+		return nil, fmt.Errorf("epsilon and the minimum count must not be negative")
+	}
+	if epsilon == 0 && minCount == 0 {
+		return nil, nil
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return &Policy{Epsilon: epsilon, MinCount: minCount, key: key}, nil
+}
+
+// Count returns the protected value of the count called name: n plus noise,
+// rounded and never negative, or 0 if that is below the minimum count
+func (p *Policy) Count(name string, n int64) int64 {
+	if p.Epsilon > 0 {
+		n += int64(math.Round(p.noise(name, n)))
+		n = max(n, 0)
+	}
+	if n < p.MinCount {
+		return 0
+	}
+	return n
+}
+
+// noise draws Laplace noise of scale 1/Epsilon, the sensitivity of a count
+// to one delivery, deterministically from the name and value of the count
+func (p *Policy) noise(name string, n int64) float64 {
+	mac := hmac.New(sha256.New, p.key)
+	fmt.Fprintf(mac, "%s\x00%d", name, n)
+	sum := mac.Sum(nil)
+	// A uniform draw in (-0.5, 0.5)
+	u := (float64(binary.BigEndian.Uint64(sum)>>11)+0.5)/(1<<53) - 0.5
+	return -math.Copysign(1/p.Epsilon, u) * math.Log(1-2*math.Abs(u))
+}
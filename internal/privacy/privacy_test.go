@@ -0,0 +1,66 @@
+package privacy
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestParseEpsilon(t *testing.T) {
+	if epsilon, err := ParseEpsilon(" 0.5 "); err != nil || epsilon != 0.5 {
+		t.Errorf("ParseEpsilon = %v, %v", epsilon, err)
+	}
+	if epsilon, err := ParseEpsilon(""); err != nil || epsilon != 0 {
+		t.Errorf("Expected no noise by default, got %v, %v", epsilon, err)
+	}
+	for _, value := range []string{"0", "-1", "lots", "Inf"} {
+		if _, err := ParseEpsilon(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestNewPolicy(t *testing.T) {
+	if p, err := NewPolicy(0, 0); p != nil || err != nil {
+		t.Errorf("Expected no policy without settings, got %+v, %v", p, err)
+	}
+	if _, err := NewPolicy(1, -1); err == nil {
+		t.Error("Expected a negative minimum count to be rejected")
+	}
+}
+
+func TestPolicy_Count(t *testing.T) {
+	threshold, _ := NewPolicy(0, 5)
+	if threshold.Count("a", 4) != 0 || threshold.Count("a", 5) != 5 {
+		t.Errorf("Expected counts below 5 to be suppressed")
+	}
+
+	noisy, _ := NewPolicy(0.5, 0)
+	if noisy.Count("cell", 100) != noisy.Count("cell", 100) {
+		t.Error("Expected repeated counts to get the same noise")
+	}
+	var sum, abs float64
+	changed := 0
+	const draws = 4000
+	for i := 0; i < draws; i++ {
+		n := noisy.Count(fmt.Sprint("cell ", i), 1000)
+		if n != 1000 {
+			changed++
+		}
+		sum += float64(n - 1000)
+		abs += math.Abs(float64(n - 1000))
+	}
+	// Laplace noise of scale 2 has mean 0 and mean absolute deviation 2
+	if mean := sum / draws; math.Abs(mean) > 0.3 {
+		t.Errorf("Expected unbiased noise, got a mean of %.2f", mean)
+	}
+	if deviation := abs / draws; deviation < 1.5 || deviation > 2.5 {
+		t.Errorf("Expected a mean absolute deviation near 2, got %.2f", deviation)
+	}
+	if changed < draws/2 {
+		t.Errorf("Expected most counts to change, got %d of %d", changed, draws)
+	}
+	if noisy.Count("zero", 0) < 0 {
+		t.Error("Expected counts never to be negative")
+	}
+}
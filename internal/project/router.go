@@ -0,0 +1,86 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// ColumnEnteredAction is the forwarded action for an item entering a column
+const ColumnEnteredAction = "column_entered"
+
+// Route notifies a channel when an item enters a column
+type Route struct {
+	Column  string
+	Channel forwarder.Forwarder
+}
+
+// ParseRoutes parses a comma-separated list of column=url pairs, e.g.
+// "Blocked=https://chat.example.com/blocked". Column names are matched
+// case-insensitively.
+func ParseRoutes(list string) ([]Route, error) {
+	var routes []Route
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		column, endpoint, ok := strings.Cut(pair, "=")
+		column = strings.TrimSpace(column)
+		if !ok || column == "" {
+			return nil, fmt.Errorf("invalid project column route %q", pair)
+		}
+		channel, err := forwarder.NewHTTPForwarder(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, Route{Column: column, Channel: channel})
+	}
+	return routes, nil
+}
+
+// Router notifies channels when project items enter a column
+type Router struct {
+	routes []Route
+}
+
+// NewRouter creates a router for the given routes
+func NewRouter(routes []Route) *Router {
+	return &Router{routes: routes}
+}
+
+// Route delivers an item move to the channels routed for the column the item
+// entered. Archived and deleted items have not entered a column and are
+// never routed.
+func (r *Router) Route(ctx context.Context, deliveryID string, change *webhook.ProjectItemChange) {
+	if change.To == "" {
+		return
+	}
+	var channels []forwarder.Forwarder
+	for _, route := range r.routes {
+		if strings.EqualFold(route.Column, change.To) {
+			channels = append(channels, route.Channel)
+		}
+	}
+	if len(channels) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(change)
+	if err != nil {
+		log.Printf("Failed to encode project item change: %v", err)
+		return
+	}
+	forwarder.ForwardAll(ctx, channels, forwarder.Event{
+		DeliveryID: deliveryID,
+		EventType:  webhook.ProjectsV2ItemEvent,
+		Action:     ColumnEnteredAction,
+		Repository: change.Organization,
+		Payload:    payload,
+	})
+}
@@ -0,0 +1,52 @@
+package project
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+type recordingForwarder struct {
+	events []forwarder.Event
+}
+
+func (rf *recordingForwarder) Name() string { return "recording" }
+
+func (rf *recordingForwarder) Forward(ctx context.Context, event forwarder.Event) error {
+	rf.events = append(rf.events, event)
+	return nil
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes("Blocked=https://chat.example.com/blocked, In Review=https://chat.example.com/review")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(routes) != 2 || routes[0].Column != "Blocked" || routes[1].Column != "In Review" {
+		t.Errorf("Unexpected routes: %+v", routes)
+	}
+
+	for _, list := range []string{"Blocked", "=https://chat.example.com", "Blocked=ftp://chat.example.com"} {
+		if _, err := ParseRoutes(list); err == nil {
+			t.Errorf("Expected error for %q", list)
+		}
+	}
+}
+
+func TestRouter_Route(t *testing.T) {
+	blocked := &recordingForwarder{}
+	router := NewRouter([]Route{{Column: "Blocked", Channel: blocked}})
+
+	router.Route(context.Background(), "d1", &webhook.ProjectItemChange{Action: "edited", From: "In Progress", To: "blocked"})
+	router.Route(context.Background(), "d2", &webhook.ProjectItemChange{Action: "edited", From: "Blocked", To: "Done"})
+	router.Route(context.Background(), "d3", &webhook.ProjectItemChange{Action: "archived"})
+
+	if len(blocked.events) != 1 {
+		t.Fatalf("Expected 1 notification, got %d", len(blocked.events))
+	}
+	if blocked.events[0].Action != ColumnEnteredAction || blocked.events[0].DeliveryID != "d1" {
+		t.Errorf("Unexpected event: %+v", blocked.events[0])
+	}
+}
@@ -0,0 +1,165 @@
+// Package querycache caches the responses of aggregate query endpoints in
+// memory, dropping them when an event they depend on arrives so dashboards
+// stay fast without showing stale numbers.
+package querycache
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long responses are cached by default
+const DefaultTTL = time.Minute
+
+// Header reports whether a response was served from the cache, "hit", or
+// computed, "miss"
+const Header = "X-Choochoo-Cache"
+
+// maxEntries caps the number of cached responses
+const maxEntries = 1000
+
+// maxBody caps the size of the responses cached
+const maxBody = 4 << 20
+
+// Depends reports whether an endpoint's results depend on events of a type.
+// A nil Depends depends on every event.
+type Depends func(eventType string) bool
+
+// Types depends on the events of the given types
+func Types(eventTypes ...string) Depends {
+	return func(eventType string) bool {
+		return slices.Contains(eventTypes, eventType)
+	}
+}
+
+// Cache holds the responses of query endpoints for a time
+type Cache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	depends Depends
+	// repository is the repository the query was filtered by, if any
+	repository  string
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// New creates a cache keeping responses for ttl
+func New(ttl time.Duration) *Cache {
+	return &Cache{ttl: ttl, now: time.Now, entries: make(map[string]*entry)}
+}
+
+// Handler wraps next so its successful GET responses are cached by path and
+// query until the ttl passes or an event next depends on arrives. Responses
+// must not vary by token, so next is wrapped after authentication.
+func (c *Cache) Handler(depends Depends, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			next(w, r)
+			return
+		}
+		key := r.URL.Path + "?" + r.URL.Query().Encode()
+		now := c.now()
+
+		c.mu.Lock()
+		cached, ok := c.entries[key]
+		c.mu.Unlock()
+		if ok && now.Before(cached.expires) {
+			w.Header().Set("Content-Type", cached.contentType)
+			w.Header().Set(Header, "hit")
+			w.Write(cached.body)
+			return
+		}
+
+		w.Header().Set(Header, "miss")
+		recorder := &recorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		if recorder.status != http.StatusOK || recorder.overflow {
+			return
+		}
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if len(c.entries) >= maxEntries {
+			c.prune(now)
+		}
+		c.entries[key] = &entry{
+			depends:     depends,
+			repository:  r.URL.Query().Get("repository"),
+			contentType: w.Header().Get("Content-Type"),
+			body:        recorder.body.Bytes(),
+			expires:     now.Add(c.ttl),
+		}
+	}
+}
+
+// Invalidate drops the responses that depend on an event of eventType in
+// repository: those of endpoints depending on the type, unless they were
+// filtered by another repository
+func (c *Cache) Invalidate(eventType, repository string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, cached := range c.entries {
+		if cached.depends != nil && !cached.depends(eventType) {
+			continue
+		}
+		if cached.repository != "" && !strings.EqualFold(cached.repository, repository) {
+			continue
+		}
+		delete(c.entries, key)
+	}
+}
+
+// Len returns the number of cached responses
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// prune drops expired responses, or every response if none have expired
+func (c *Cache) prune(now time.Time) {
+	for key, cached := range c.entries {
+		if !now.Before(cached.expires) {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) >= maxEntries {
+		clear(c.entries)
+	}
+}
+
+// recorder passes a response on while keeping a copy of it
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	if !r.overflow {
+		if r.body.Len()+len(data) > maxBody {
+			r.overflow = true
+			r.body.Reset()
+		} else {
+			r.body.Write(data)
+		}
+	}
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,87 @@
+package querycache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCache_Handler(t *testing.T) {
+	cache := New(time.Minute)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	calls := 0
+	handler := cache.Handler(Types("push"), func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Query().Get("days") == "0" {
+			http.Error(w, "Invalid days parameter", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"calls": %d}`, calls)
+	})
+	get := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	if rec := get("/api/stats?days=7"); rec.Header().Get(Header) != "miss" || rec.Body.String() != `{"calls": 1}` {
+		t.Fatalf("first response = %s %s", rec.Header().Get(Header), rec.Body)
+	}
+	rec := get("/api/stats?days=7")
+	if rec.Header().Get(Header) != "hit" || rec.Body.String() != `{"calls": 1}` || rec.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("second response = %s %s", rec.Header().Get(Header), rec.Body)
+	}
+	if get("/api/stats?days=30"); calls != 2 {
+		t.Errorf("other query made %d calls, want 2", calls)
+	}
+
+	// Errors are not cached
+	get("/api/stats?days=0")
+	get("/api/stats?days=0")
+	if calls != 4 {
+		t.Errorf("errors made %d calls, want 4", calls)
+	}
+
+	// Events the endpoint does not depend on keep the responses
+	cache.Invalidate("star", "acme/api")
+	if cache.Len() != 2 {
+		t.Errorf("Len() after an unrelated event = %d, want 2", cache.Len())
+	}
+	cache.Invalidate("push", "acme/api")
+	if cache.Len() != 0 {
+		t.Errorf("Len() after a push = %d, want 0", cache.Len())
+	}
+
+	// Responses expire
+	get("/api/stats?days=7")
+	now = now.Add(2 * time.Minute)
+	if rec := get("/api/stats?days=7"); rec.Header().Get(Header) != "miss" {
+		t.Errorf("expired response = %s", rec.Header().Get(Header))
+	}
+}
+
+func TestCache_InvalidateRepository(t *testing.T) {
+	cache := New(time.Minute)
+	handler := cache.Handler(nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	})
+	for _, target := range []string{"/api/stats", "/api/stats?repository=acme/api", "/api/stats?repository=acme/web"} {
+		handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	// Only the unfiltered response and that of the repository are dropped
+	cache.Invalidate("issues", "Acme/API")
+	if cache.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", cache.Len())
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/stats?repository=acme/web", nil))
+	if rec.Header().Get(Header) != "hit" {
+		t.Errorf("response of another repository = %s, want hit", rec.Header().Get(Header))
+	}
+}
@@ -0,0 +1,164 @@
+// Package ratelimit limits how fast clients may call the server with token
+// buckets, one per client address and one shared by every client, so a
+// flood of requests cannot overwhelm the database.
+package ratelimit
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Defaults for Config
+const (
+	DefaultPerIPBurst  = 20
+	DefaultGlobalBurst = 100
+)
+
+// sweepInterval is how often buckets of idle clients are dropped
+const sweepInterval = time.Minute
+
+// Config sets the limits. A rate of 0 disables that limit.
+type Config struct {
+	// PerIP is the number of requests per minute a client address may make
+	PerIP int
+	// PerIPBurst is the number of requests a client may make at once
+	PerIPBurst int
+	// Global is the number of requests per minute across all clients
+	Global int
+	// GlobalBurst is the number of requests all clients may make at once
+	GlobalBurst int
+}
+
+// Enabled reports whether any limit is set
+func (c Config) Enabled() bool {
+	return c.PerIP > 0 || c.Global > 0
+}
+
+// bucket is a token bucket that refills at rate tokens per second up to burst
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(perMinute, burst int, now time.Time) *bucket {
+	return &bucket{rate: float64(perMinute) / 60, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+// refill adds the tokens earned since the last call
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// wait returns how long until a token is available, 0 if one is now
+func (b *bucket) wait(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// take removes a token, which must be available
+func (b *bucket) take() {
+	b.tokens--
+}
+
+// Limiter applies the limits of a Config
+type Limiter struct {
+	config     Config
+	clientAddr func(r *http.Request) (netip.Addr, bool)
+	now        func() time.Time
+
+	mu        sync.Mutex
+	global    *bucket
+	clients   map[netip.Addr]*bucket
+	lastSweep time.Time
+}
+
+// New creates a limiter. clientAddr returns the address a request is
+// limited as, normally honoring X-Forwarded-For from trusted proxies.
+func New(config Config, clientAddr func(r *http.Request) (netip.Addr, bool)) *Limiter {
+	return newLimiter(config, clientAddr, time.Now)
+}
+
+func newLimiter(config Config, clientAddr func(r *http.Request) (netip.Addr, bool), now func() time.Time) *Limiter {
+	l := &Limiter{config: config, clientAddr: clientAddr, now: now, clients: make(map[netip.Addr]*bucket), lastSweep: now()}
+	if config.Global > 0 {
+		l.global = newBucket(config.Global, config.GlobalBurst, now())
+	}
+	return l
+}
+
+// Allow takes a token for a request from addr, or returns how long the
+// client should wait before retrying. A request is only counted against the
+// global limit once its client is within its own limit, so one abusive
+// client cannot use up the global budget.
+func (l *Limiter) Allow(addr netip.Addr) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	var client *bucket
+	if l.config.PerIP > 0 {
+		client = l.clients[addr]
+		if client == nil {
+			client = newBucket(l.config.PerIP, l.config.PerIPBurst, now)
+			l.clients[addr] = client
+		}
+		if wait := client.wait(now); wait > 0 {
+			return false, wait
+		}
+	}
+	if l.global != nil {
+		if wait := l.global.wait(now); wait > 0 {
+			return false, wait
+		}
+		l.global.take()
+	}
+	if client != nil {
+		client.take()
+	}
+	return true, 0
+}
+
+// sweep drops the buckets of clients that have been idle long enough to
+// refill, which behave exactly like new ones
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for addr, client := range l.clients {
+		if client.refill(now); client.tokens >= client.burst {
+			delete(l.clients, addr)
+		}
+	}
+}
+
+// Middleware rejects requests over the limits with 429 and a Retry-After
+// header
+func (l *Limiter) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addr, _ := l.clientAddr(r)
+		allowed, wait := l.Allow(addr)
+		if !allowed {
+			log.Printf("Rate limited %s %s from %s", r.Method, r.URL.Path, addr)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
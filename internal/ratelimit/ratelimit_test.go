@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// testLimiter creates a limiter on a clock the test controls
+func testLimiter(config Config) (*Limiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return newLimiter(config, nil, func() time.Time { return now }), &now
+}
+
+func TestLimiter_PerIP(t *testing.T) {
+	l, now := testLimiter(Config{PerIP: 60, PerIPBurst: 2})
+	a := netip.MustParseAddr("192.0.2.1")
+	b := netip.MustParseAddr("192.0.2.2")
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow(a); !ok {
+			t.Fatalf("Request %d within the burst was limited", i+1)
+		}
+	}
+	ok, wait := l.Allow(a)
+	if ok || wait != time.Second {
+		t.Errorf("Expected a to wait 1s, got allowed=%v wait=%v", ok, wait)
+	}
+	if ok, _ := l.Allow(b); !ok {
+		t.Error("Expected another client to be allowed")
+	}
+
+	*now = now.Add(time.Second)
+	if ok, _ := l.Allow(a); !ok {
+		t.Error("Expected a token to be refilled after 1s")
+	}
+}
+
+func TestLimiter_Global(t *testing.T) {
+	l, now := testLimiter(Config{PerIP: 60, PerIPBurst: 1, Global: 120, GlobalBurst: 2})
+	a := netip.MustParseAddr("192.0.2.1")
+
+	if ok, _ := l.Allow(a); !ok {
+		t.Fatal("Expected the first request to be allowed")
+	}
+	// a is over its own limit, which must not use up the global budget
+	for i := 0; i < 3; i++ {
+		l.Allow(a)
+	}
+	if ok, _ := l.Allow(netip.MustParseAddr("192.0.2.2")); !ok {
+		t.Fatal("Expected another client to be allowed")
+	}
+	ok, wait := l.Allow(netip.MustParseAddr("192.0.2.3"))
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("Expected the global limit to apply, got allowed=%v wait=%v", ok, wait)
+	}
+
+	*now = now.Add(2 * time.Minute)
+	l.Allow(netip.MustParseAddr("192.0.2.4"))
+	if len(l.clients) != 1 {
+		t.Errorf("Expected idle clients to be swept, got %d", len(l.clients))
+	}
+}
+
+func TestLimiter_Middleware(t *testing.T) {
+	l := New(Config{PerIP: 1, PerIPBurst: 1}, func(r *http.Request) (netip.Addr, bool) {
+		return netip.MustParseAddr("192.0.2.1"), true
+	})
+	handler := l.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	rr := httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("POST", "/webhook", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	handler(rr, httptest.NewRequest("POST", "/webhook", nil))
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status code %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+	if retryAfter := rr.Header().Get("Retry-After"); retryAfter != "60" {
+		t.Errorf("Expected Retry-After 60, got %q", retryAfter)
+	}
+}
@@ -0,0 +1,222 @@
+// Package receipt posts processing receipts back to GitHub, so repository
+// members can see at a glance that choochoo observed their change. A receipt
+// is a check run or a commit comment on the commit an event is about, posted
+// once the event has been processed.
+package receipt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/github"
+)
+
+// Kinds of receipt
+const (
+	KindCheck   = "check"
+	KindComment = "comment"
+)
+
+// CheckName names the check runs posted as receipts
+const CheckName = "choochoo"
+
+// DefaultRateLimit is the default number of receipts per minute per
+// repository
+const DefaultRateLimit = 10
+
+// dedupWindow is how long a commit is remembered, so an event delivered or
+// replayed again does not post a second receipt
+const dedupWindow = time.Hour
+
+// headSHAs finds the commit each supported event is about
+var headSHAs = map[string]func(payload) string{
+	"push":              func(p payload) string { return p.After },
+	"pull_request":      func(p payload) string { return p.PullRequest.Head.SHA },
+	"check_suite":       func(p payload) string { return p.CheckSuite.HeadSHA },
+	"workflow_run":      func(p payload) string { return p.WorkflowRun.HeadSHA },
+	"deployment":        func(p payload) string { return p.Deployment.SHA },
+	"deployment_status": func(p payload) string { return p.Deployment.SHA },
+}
+
+// payload holds the commit fields of the supported events
+type payload struct {
+	Action     string `json:"action"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+	PullRequest struct {
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	CheckSuite struct {
+		HeadSHA string `json:"head_sha"`
+	} `json:"check_suite"`
+	WorkflowRun struct {
+		HeadSHA string `json:"head_sha"`
+	} `json:"workflow_run"`
+	Deployment struct {
+		SHA string `json:"sha"`
+	} `json:"deployment"`
+}
+
+// ParseEvents parses a comma-separated list of event types, each optionally
+// narrowed to an action as "event.action", such as
+// "push,pull_request.closed". Only events about a commit are accepted.
+func ParseEvents(list string) ([]string, error) {
+	var events []string
+	for _, event := range strings.Split(list, ",") {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+		eventType, _, _ := strings.Cut(event, ".")
+		if _, ok := headSHAs[eventType]; !ok {
+			return nil, fmt.Errorf("receipts cannot be posted for %s events; use one of %s", eventType, strings.Join(slices.Sorted(maps.Keys(headSHAs)), ", "))
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Poster posts receipts for the configured events
+type Poster struct {
+	events    []string
+	kind      string
+	rateLimit int
+	client    *github.Client
+	now       func() time.Time
+
+	mu sync.Mutex
+	// windows counts the receipts of each repository in the current minute
+	windows map[string]*window
+	// posted holds when each "repo@sha" last got a receipt
+	posted map[string]time.Time
+	swept  time.Time
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// New creates a poster of receipts of kind for events, as parsed by
+// ParseEvents, posting at most rateLimit receipts per minute to each
+// repository
+func New(events []string, kind string, rateLimit int, client *github.Client) *Poster {
+	return &Poster{
+		events:    events,
+		kind:      kind,
+		rateLimit: rateLimit,
+		client:    client,
+		now:       time.Now,
+		windows:   make(map[string]*window),
+		posted:    make(map[string]time.Time),
+	}
+}
+
+// Matches reports whether events of eventType with action get receipts
+func (p *Poster) Matches(eventType, action string) bool {
+	return slices.Contains(p.events, eventType) || (action != "" && slices.Contains(p.events, eventType+"."+action))
+}
+
+// Post posts the receipt of a processed event, if it matches. Events without
+// a commit, commits that already got a receipt and receipts over the rate
+// limit are skipped, reporting false.
+func (p *Poster) Post(ctx context.Context, eventType, deliveryID string, body []byte) (bool, error) {
+	headSHA, ok := headSHAs[eventType]
+	if !ok {
+		return false, nil
+	}
+	var event payload
+	if err := json.Unmarshal(body, &event); err != nil {
+		return false, fmt.Errorf("failed to parse %s event: %w", eventType, err)
+	}
+	if !p.Matches(eventType, event.Action) {
+		return false, nil
+	}
+	repo, sha := event.Repository.FullName, headSHA(event)
+	if repo == "" || sha == "" || event.Deleted || strings.Trim(sha, "0") == "" {
+		return false, nil
+	}
+	if !p.reserve(repo, sha) {
+		return false, nil
+	}
+
+	name := eventType
+	if event.Action != "" {
+		name += "." + event.Action
+	}
+	var err error
+	switch p.kind {
+	case KindComment:
+		err = p.client.CreateCommitComment(ctx, repo, sha, fmt.Sprintf("✅ processed by choochoo (`%s`, delivery `%s`)", name, deliveryID))
+	default:
+		err = p.client.CreateCheckRun(ctx, repo, github.CheckRun{
+			Name:       CheckName,
+			HeadSHA:    sha,
+			Conclusion: github.ConclusionSuccess,
+			Output: github.CheckOutput{
+				Title:   "✅ processed by choochoo",
+				Summary: fmt.Sprintf("choochoo processed the `%s` event of this commit (delivery `%s`).", name, deliveryID),
+			},
+		})
+	}
+	if err != nil {
+		p.release(repo, sha)
+		return false, err
+	}
+	return true, nil
+}
+
+// reserve records a receipt for sha of repo, reporting false if the commit
+// already got one or the repository is over its rate limit
+func (p *Poster) reserve(repo, sha string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	if now.Sub(p.swept) >= time.Minute {
+		for key, at := range p.posted {
+			if now.Sub(at) >= dedupWindow {
+				delete(p.posted, key)
+			}
+		}
+		for key, w := range p.windows {
+			if now.Sub(w.start) >= time.Minute {
+				delete(p.windows, key)
+			}
+		}
+		p.swept = now
+	}
+
+	key := repo + "@" + sha
+	if at, ok := p.posted[key]; ok && now.Sub(at) < dedupWindow {
+		return false
+	}
+	w := p.windows[repo]
+	if w == nil || now.Sub(w.start) >= time.Minute {
+		w = &window{start: now}
+		p.windows[repo] = w
+	}
+	if w.count >= p.rateLimit {
+		return false
+	}
+	w.count++
+	p.posted[key] = now
+	return true
+}
+
+// release forgets a receipt that failed to post, so a retry may post it
+func (p *Poster) release(repo, sha string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.posted, repo+"@"+sha)
+}
@@ -0,0 +1,128 @@
+package receipt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/github"
+)
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents("push, pull_request.closed,")
+	if err != nil {
+		t.Fatalf("ParseEvents() error = %v", err)
+	}
+	if !slices.Equal(events, []string{"push", "pull_request.closed"}) {
+		t.Errorf("ParseEvents() = %v", events)
+	}
+	if _, err := ParseEvents("push,issues"); err == nil {
+		t.Error("ParseEvents() expected an error for an event without a commit")
+	}
+}
+
+// recorder is a GitHub API recording the receipts posted to it
+type recorder struct {
+	checks   []github.CheckRun
+	comments []string
+	fail     bool
+}
+
+func (rec *recorder) serve(t *testing.T) *github.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rec.fail {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		switch r.URL.Path {
+		case "/repos/acme/api/check-runs":
+			var run github.CheckRun
+			json.NewDecoder(r.Body).Decode(&run)
+			rec.checks = append(rec.checks, run)
+		case "/repos/acme/api/commits/abc123/comments":
+			var comment struct {
+				Body string `json:"body"`
+			}
+			json.NewDecoder(r.Body).Decode(&comment)
+			rec.comments = append(rec.comments, comment.Body)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+	return github.NewTokenClient(server.URL, "token")
+}
+
+func TestPoster_Post(t *testing.T) {
+	rec := &recorder{}
+	poster := New([]string{"push", "pull_request.closed"}, KindCheck, 2, rec.serve(t))
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	poster.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	push := func(after string) []byte {
+		return []byte(`{"after": "` + after + `", "repository": {"full_name": "acme/api"}}`)
+	}
+	pull := func(action, sha string) []byte {
+		return []byte(`{"action": "` + action + `", "pull_request": {"head": {"sha": "` + sha + `"}}, "repository": {"full_name": "acme/api"}}`)
+	}
+
+	tests := []struct {
+		name      string
+		eventType string
+		body      []byte
+		want      bool
+	}{
+		{"push", "push", push("abc123"), true},
+		{"same commit", "push", push("abc123"), false},
+		{"branch deleted", "push", push("0000000000000000000000000000000000000000"), false},
+		{"unmatched action", "pull_request", pull("opened", "def456"), false},
+		{"matched action", "pull_request", pull("closed", "def456"), true},
+		{"over the rate limit", "push", push("fed789"), false},
+		{"unsupported event", "issues", []byte(`{}`), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			posted, err := poster.Post(ctx, tt.eventType, "delivery", tt.body)
+			if err != nil {
+				t.Fatalf("Post() error = %v", err)
+			}
+			if posted != tt.want {
+				t.Errorf("Post() = %v, want %v", posted, tt.want)
+			}
+		})
+	}
+	if len(rec.checks) != 2 || rec.checks[0].HeadSHA != "abc123" || rec.checks[0].Name != CheckName || rec.checks[1].HeadSHA != "def456" {
+		t.Errorf("check runs = %+v", rec.checks)
+	}
+
+	// The limit applies per minute
+	now = now.Add(time.Minute)
+	if posted, _ := poster.Post(ctx, "push", "delivery", push("fed789")); !posted {
+		t.Error("Post() expected a receipt in the next minute")
+	}
+}
+
+func TestPoster_Post_Comment(t *testing.T) {
+	rec := &recorder{fail: true}
+	poster := New([]string{"push"}, KindComment, DefaultRateLimit, rec.serve(t))
+	body := []byte(`{"after": "abc123", "repository": {"full_name": "acme/api"}}`)
+
+	if _, err := poster.Post(context.Background(), "push", "d1", body); err == nil {
+		t.Fatal("Post() expected an error from GitHub")
+	}
+	// A failed receipt is posted by the retry
+	rec.fail = false
+	if posted, err := poster.Post(context.Background(), "push", "d1", body); err != nil || !posted {
+		t.Fatalf("Post() = %v, %v", posted, err)
+	}
+	if len(rec.comments) != 1 || rec.comments[0] != "✅ processed by choochoo (`push`, delivery `d1`)" {
+		t.Errorf("comments = %q", rec.comments)
+	}
+}
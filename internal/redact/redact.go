@@ -0,0 +1,184 @@
+// Package redact scrubs personal data and secrets from webhook payloads
+// before they are stored, for deployments that must not keep them. Email
+// addresses and strings that look like credentials are replaced wherever
+// they appear, and configured JSON paths are replaced as a whole.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Placeholder replaces redacted strings
+const Placeholder = "[REDACTED]"
+
+// emailPattern matches email addresses, including GitHub's noreply addresses
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)
+
+// secretPatterns match credentials that are commonly pasted into commit
+// messages, issues and comments
+var secretPatterns = []*regexp.Regexp{
+	// GitHub personal access, OAuth, app and refresh tokens
+	regexp.MustCompile(`\b(ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,}\b`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}\b`),
+	// AWS access key IDs
+	regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`),
+	// Slack tokens
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`),
+	// Google API keys
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),
+	// Stripe secret keys
+	regexp.MustCompile(`\b[sr]k_live_[0-9A-Za-z]{20,}\b`),
+	// choochoo API tokens
+	regexp.MustCompile(`\bcct_[A-Za-z0-9_-]{43}`),
+	// Private keys in PEM form
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+}
+
+// Redactor scrubs payloads. The zero value changes nothing.
+type Redactor struct {
+	emails  bool
+	secrets bool
+	paths   [][]string
+}
+
+// New creates a redactor that scrubs email addresses and secrets if asked,
+// and the values at paths
+func New(emails, secrets bool, paths [][]string) *Redactor {
+	return &Redactor{emails: emails, secrets: secrets, paths: paths}
+}
+
+// Enabled reports whether the redactor changes anything
+func (r *Redactor) Enabled() bool {
+	return r != nil && (r.emails || r.secrets || len(r.paths) > 0)
+}
+
+// ParsePaths parses a comma-separated list of JSON paths, written as keys
+// separated by dots, where * matches every key of an object or element of
+// an array, e.g. "commits.*.author.email,pusher.email"
+func ParsePaths(list string) ([][]string, error) {
+	var paths [][]string
+	for _, path := range strings.Split(list, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		keys := strings.Split(path, ".")
+		for _, key := range keys {
+			if key == "" {
+				return nil, fmt.Errorf("invalid redaction path %q", path)
+			}
+		}
+		paths = append(paths, keys)
+	}
+	return paths, nil
+}
+
+// Redact returns the payload with email addresses, secrets and the values
+// at the redactor's paths replaced. Strings become Placeholder and other
+// values null, so the payload still parses into the same types. A payload
+// with nothing to redact is returned as it is.
+func (r *Redactor) Redact(payload []byte) ([]byte, error) {
+	if !r.Enabled() {
+		return payload, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	changed := false
+	for _, path := range r.paths {
+		document = redactPath(document, path, &changed)
+	}
+	if r.emails || r.secrets {
+		document = r.redactStrings(document, &changed)
+	}
+	if !changed {
+		return payload, nil
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+// redactPath replaces the values at path in value
+func redactPath(value interface{}, path []string, changed *bool) interface{} {
+	if len(path) == 0 {
+		if value == nil {
+			return nil
+		}
+		*changed = true
+		if _, ok := value.(string); ok {
+			return Placeholder
+		}
+		return nil
+	}
+
+	key, rest := path[0], path[1:]
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, child := range v {
+			if key == "*" || key == name {
+				v[name] = redactPath(child, rest, changed)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if key == "*" {
+				v[i] = redactPath(child, rest, changed)
+			}
+		}
+	}
+	return value
+}
+
+// redactStrings replaces the email addresses and secrets in every string of
+// value, including object keys
+func (r *Redactor) redactStrings(value interface{}, changed *bool) interface{} {
+	switch v := value.(type) {
+	case string:
+		return r.redactString(v, changed)
+	case map[string]interface{}:
+		for name, child := range v {
+			redacted := r.redactStrings(child, changed)
+			if key := r.redactString(name, changed); key != name {
+				delete(v, name)
+				name = key
+			}
+			v[name] = redacted
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = r.redactStrings(child, changed)
+		}
+	}
+	return value
+}
+
+func (r *Redactor) redactString(s string, changed *bool) string {
+	redacted := s
+	if r.secrets {
+		for _, pattern := range secretPatterns {
+			redacted = pattern.ReplaceAllString(redacted, Placeholder)
+		}
+	}
+	if r.emails {
+		redacted = emailPattern.ReplaceAllString(redacted, Placeholder)
+	}
+	if redacted != s {
+		*changed = true
+	}
+	return redacted
+}
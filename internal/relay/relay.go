@@ -0,0 +1,136 @@
+// Package relay verifies deliveries that reach choochoo through an edge relay
+// or another instance rather than straight from GitHub. A relay signs the
+// payload with its own secret and passes on the chain of signatures verified
+// before it, so the provenance of an event stays provable through every
+// intermediary.
+package relay
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SignatureHeader carries the relay signature, "sha256=" and the hex
+// HMAC-SHA256 of the chain header, a newline and the payload
+const SignatureHeader = "X-Choochoo-Relay-Signature"
+
+// ChainHeader carries the chain verified before the relay as compact JSON,
+// if any
+const ChainHeader = "X-Choochoo-Relay-Chain"
+
+// GitHub is the signer of links for GitHub's own signature
+const GitHub = "github"
+
+// ErrUnverified is returned for relayed deliveries whose signature does not
+// match any relay secret
+var ErrUnverified = errors.New("relay signature does not match any relay secret")
+
+// Link is one verified signature of a delivery
+type Link struct {
+	// Signer is GitHub or the name of a relay
+	Signer    string `json:"signer"`
+	Signature string `json:"signature"`
+	// VerifiedBy names the relay that verified the link, empty when it was
+	// verified by this instance
+	VerifiedBy string `json:"verified_by,omitempty"`
+}
+
+// Chain lists the signatures of a delivery from GitHub to the last relay
+type Chain []Link
+
+// FromGitHub reports whether the chain holds GitHub's signature
+func (c Chain) FromGitHub() bool {
+	for _, link := range c {
+		if link.Signer == GitHub {
+			return true
+		}
+	}
+	return false
+}
+
+// Header encodes the chain as the ChainHeader of a delivery relayed on
+func (c Chain) Header() string {
+	if len(c) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(c)
+	return string(data)
+}
+
+// Sign returns the SignatureHeader of payload relayed with chainHeader
+func Sign(secret, chainHeader string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(chainHeader + "\n"))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseSecrets parses a comma-separated list of name=secret pairs naming the
+// relays deliveries are accepted from, e.g. "edge=s3cret,eu-edge=0ther"
+func ParseSecrets(list string) (map[string]string, error) {
+	secrets := make(map[string]string)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, secret, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || secret == "" {
+			return nil, fmt.Errorf("invalid relay secret %q, expected name=secret", name)
+		}
+		if name == GitHub {
+			return nil, fmt.Errorf("relay name %q is reserved", GitHub)
+		}
+		secrets[name] = secret
+	}
+	return secrets, nil
+}
+
+// Verifier verifies relay signatures against the secrets of named relays
+type Verifier struct {
+	secrets map[string]string
+}
+
+// NewVerifier creates a verifier for the relays of secrets, as parsed by
+// ParseSecrets
+func NewVerifier(secrets map[string]string) *Verifier {
+	return &Verifier{secrets: secrets}
+}
+
+// Verify verifies the relay signature of a delivery and returns its chain:
+// the links the relay verified, then the relay's own
+func (v *Verifier) Verify(payload []byte, header http.Header) (Chain, error) {
+	signature := header.Get(SignatureHeader)
+	provided, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return nil, ErrUnverified
+	}
+	chainHeader := header.Get(ChainHeader)
+	for name, secret := range v.secrets {
+		expected, _ := hex.DecodeString(strings.TrimPrefix(Sign(secret, chainHeader, payload), "sha256="))
+		if !hmac.Equal(provided, expected) {
+			continue
+		}
+
+		var chain Chain
+		if chainHeader != "" {
+			if err := json.Unmarshal([]byte(chainHeader), &chain); err != nil {
+				return nil, fmt.Errorf("invalid %s header: %w", ChainHeader, err)
+			}
+		}
+		for i := range chain {
+			if chain[i].VerifiedBy == "" {
+				chain[i].VerifiedBy = name
+			}
+		}
+		return append(chain, Link{Signer: name, Signature: signature}), nil
+	}
+	return nil, ErrUnverified
+}
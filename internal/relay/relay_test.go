@@ -0,0 +1,56 @@
+package relay
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestParseSecrets(t *testing.T) {
+	secrets, err := ParseSecrets("edge=one, eu-edge=two,")
+	if err != nil {
+		t.Fatalf("ParseSecrets() error = %v", err)
+	}
+	if len(secrets) != 2 || secrets["edge"] != "one" || secrets["eu-edge"] != "two" {
+		t.Errorf("ParseSecrets() = %v", secrets)
+	}
+	for _, list := range []string{"edge", "=secret", "edge=", "github=secret"} {
+		if _, err := ParseSecrets(list); err == nil {
+			t.Errorf("ParseSecrets(%q) expected an error", list)
+		}
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	verifier := NewVerifier(map[string]string{"edge": "edge-secret", "hub": "hub-secret"})
+	payload := []byte(`{"zen": "Keep it logically awesome."}`)
+
+	// The edge relay verified GitHub's signature and passed it on
+	upstream := Chain{{Signer: GitHub, Signature: "sha256=abc"}}.Header()
+	header := http.Header{}
+	header.Set(ChainHeader, upstream)
+	header.Set(SignatureHeader, Sign("edge-secret", upstream, payload))
+
+	chain, err := verifier.Verify(payload, header)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if len(chain) != 2 || chain[0].Signer != GitHub || chain[0].VerifiedBy != "edge" || chain[1].Signer != "edge" || chain[1].VerifiedBy != "" {
+		t.Errorf("Verify() = %+v", chain)
+	}
+	if !chain.FromGitHub() {
+		t.Error("FromGitHub() = false, want true")
+	}
+
+	// The chain is covered by the signature
+	header.Set(ChainHeader, Chain{{Signer: GitHub, Signature: "sha256=def"}}.Header())
+	if _, err := verifier.Verify(payload, header); !errors.Is(err, ErrUnverified) {
+		t.Errorf("Verify() of a changed chain error = %v, want ErrUnverified", err)
+	}
+
+	header = http.Header{}
+	header.Set(SignatureHeader, Sign("other-secret", "", payload))
+	if _, err := verifier.Verify(payload, header); !errors.Is(err, ErrUnverified) {
+		t.Errorf("Verify() with an unknown secret error = %v, want ErrUnverified", err)
+	}
+}
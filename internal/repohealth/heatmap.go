@@ -0,0 +1,54 @@
+package repohealth
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+var heatmapTemplate = template.Must(template.New("heatmap").Funcs(template.FuncMap{
+	"color": color,
+	"component": func(report Report, name string) *Component {
+		if component, ok := report.Components[name]; ok {
+			return &component
+		}
+		return nil
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Repository delivery health</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.4em 0.8em; text-align: center; border: 1px solid #fff; }
+th:first-child, td:first-child { text-align: left; }
+td.none { background: #eee; color: #999; }
+</style>
+</head>
+<body>
+<h1>Repository delivery health</h1>
+<p>Last {{.Days}} days, least healthy first. Scores run from 0 to 100.</p>
+<table>
+<tr><th>Repository</th><th>Score</th>{{range .Components}}<th>{{.}}</th>{{end}}</tr>
+{{range $report := .Reports}}<tr><td>{{$report.Repository}}</td><td style="background: {{color $report.Score}}">{{$report.Score}}</td>{{range $.Components}}{{with component $report .}}<td style="background: {{color .Score}}" title="{{.Samples}} samples">{{.Score}}</td>{{else}}<td class="none">-</td>{{end}}{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// color maps a score to a red to green background color
+func color(score float64) template.CSS {
+	hue := int(score * 1.2)
+	return template.CSS(fmt.Sprintf("hsl(%d, 70%%, 75%%)", hue))
+}
+
+// WriteHeatmap renders reports as an HTML heatmap of repositories by component
+func WriteHeatmap(w io.Writer, reports []Report, days int) error {
+	return heatmapTemplate.Execute(w, map[string]interface{}{
+		"Days":       days,
+		"Components": Components,
+		"Reports":    reports,
+	})
+}
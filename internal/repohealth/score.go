@@ -0,0 +1,155 @@
+package repohealth
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+// Score components, in the order they are shown
+const (
+	ComponentLatency   = "webhook_latency"
+	ComponentCI        = "ci_pass_rate"
+	ComponentMergeWait = "merge_wait"
+	ComponentDeploy    = "deploy_success"
+)
+
+// Components lists every score component in display order
+var Components = []string{ComponentLatency, ComponentCI, ComponentMergeWait, ComponentDeploy}
+
+// Targets are the latency and wait times that still earn a full score.
+// Slower repositories score proportionally less.
+type Targets struct {
+	Latency   time.Duration
+	MergeWait time.Duration
+}
+
+// DefaultTargets are used when no targets are configured
+var DefaultTargets = Targets{
+	Latency:   10 * time.Second,
+	MergeWait: 24 * time.Hour,
+}
+
+// ParseTargets parses a comma-separated list of target=duration pairs, where
+// target is "latency" or "merge_wait", e.g. "latency=30s,merge_wait=48h".
+// Targets that are not listed keep their default.
+func ParseTargets(list string) (Targets, error) {
+	targets := DefaultTargets
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return Targets{}, fmt.Errorf("invalid health target %q", pair)
+		}
+		target, err := time.ParseDuration(value)
+		if err != nil || target <= 0 {
+			return Targets{}, fmt.Errorf("invalid health target duration for %s: %q", name, value)
+		}
+		switch name {
+		case "latency":
+			targets.Latency = target
+		case "merge_wait":
+			targets.MergeWait = target
+		default:
+			return Targets{}, fmt.Errorf("unknown health target %q", name)
+		}
+	}
+	return targets, nil
+}
+
+// Component is one signal of a repository's delivery health. Value is in
+// seconds for latency and wait components and a 0-1 ratio for rates.
+type Component struct {
+	Score   float64 `json:"score"`
+	Value   float64 `json:"value"`
+	Samples int64   `json:"samples"`
+}
+
+// Report is the delivery health of a repository. Score is the average of
+// the components with data, from 0 (struggling) to 100 (healthy).
+type Report struct {
+	Repository string               `json:"repository"`
+	Score      float64              `json:"score"`
+	Components map[string]Component `json:"components"`
+}
+
+// Signals are the per-repository inputs to the score
+type Signals struct {
+	Latency   []db.ListWebhookLatencyByRepositoryRow
+	CI        []db.ListCIOutcomesByRepositoryRow
+	MergeWait []db.ListMergeWaitByRepositoryRow
+	Deploys   []db.ListDeployOutcomesByRepositoryRow
+}
+
+// Build scores every repository that has at least one signal, least healthy first
+func Build(signals Signals, targets Targets) []Report {
+	reports := make(map[string]*Report)
+	set := func(repository, name string, component Component) {
+		report, ok := reports[repository]
+		if !ok {
+			report = &Report{Repository: repository, Components: make(map[string]Component)}
+			reports[repository] = report
+		}
+		report.Components[name] = component
+	}
+
+	for _, row := range signals.Latency {
+		set(row.RepositoryName, ComponentLatency, durationComponent(row.AvgSeconds, row.Samples, targets.Latency))
+	}
+	for _, row := range signals.CI {
+		if row.Succeeded+row.Failed > 0 {
+			set(row.RepositoryName, ComponentCI, rateComponent(row.Succeeded, row.Failed))
+		}
+	}
+	for _, row := range signals.MergeWait {
+		set(row.RepositoryName, ComponentMergeWait, durationComponent(row.AvgSeconds, row.Samples, targets.MergeWait))
+	}
+	for _, row := range signals.Deploys {
+		if row.Succeeded+row.Failed > 0 {
+			set(row.RepositoryName, ComponentDeploy, rateComponent(row.Succeeded, row.Failed))
+		}
+	}
+
+	result := make([]Report, 0, len(reports))
+	for _, report := range reports {
+		var total float64
+		for _, component := range report.Components {
+			total += component.Score
+		}
+		report.Score = round(total / float64(len(report.Components)))
+		result = append(result, *report)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score < result[j].Score
+		}
+		return result[i].Repository < result[j].Repository
+	})
+	return result
+}
+
+// durationComponent scores an average duration against its target
+func durationComponent(seconds float64, samples int64, target time.Duration) Component {
+	score := 100.0
+	if seconds > target.Seconds() {
+		score = 100 * target.Seconds() / seconds
+	}
+	return Component{Score: round(score), Value: round(seconds), Samples: samples}
+}
+
+// rateComponent scores a success rate
+func rateComponent(succeeded, failed int64) Component {
+	rate := float64(succeeded) / float64(succeeded+failed)
+	return Component{Score: round(100 * rate), Value: math.Round(rate*1000) / 1000, Samples: succeeded + failed}
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,91 @@
+package repohealth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+func TestParseTargets(t *testing.T) {
+	targets, err := ParseTargets("latency=30s")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if targets.Latency != 30*time.Second || targets.MergeWait != DefaultTargets.MergeWait {
+		t.Errorf("Unexpected targets: %+v", targets)
+	}
+
+	for _, list := range []string{"latency", "latency=soon", "latency=-1s", "queue=1h"} {
+		if _, err := ParseTargets(list); err == nil {
+			t.Errorf("Expected error for %q", list)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	reports := Build(Signals{
+		Latency: []db.ListWebhookLatencyByRepositoryRow{
+			{RepositoryName: "octo-org/fast", AvgSeconds: 2, Samples: 10},
+			{RepositoryName: "octo-org/slow", AvgSeconds: 40, Samples: 5},
+		},
+		CI: []db.ListCIOutcomesByRepositoryRow{
+			{RepositoryName: "octo-org/slow", Succeeded: 1, Failed: 1},
+			{RepositoryName: "octo-org/fast", Succeeded: 0, Failed: 0},
+		},
+		MergeWait: []db.ListMergeWaitByRepositoryRow{
+			{RepositoryName: "octo-org/fast", AvgSeconds: 3600, Samples: 4},
+		},
+		Deploys: []db.ListDeployOutcomesByRepositoryRow{
+			{RepositoryName: "octo-org/docs", Succeeded: 3, Failed: 1},
+		},
+	}, DefaultTargets)
+
+	if len(reports) != 3 {
+		t.Fatalf("Expected 3 reports, got %d", len(reports))
+	}
+
+	slow := reports[0]
+	if slow.Repository != "octo-org/slow" {
+		t.Fatalf("Expected least healthy repository first, got %s", slow.Repository)
+	}
+	if slow.Components[ComponentLatency].Score != 25 || slow.Components[ComponentCI].Score != 50 {
+		t.Errorf("Unexpected components: %+v", slow.Components)
+	}
+	if slow.Score != 37.5 {
+		t.Errorf("Expected score 37.5, got %v", slow.Score)
+	}
+
+	for _, report := range reports {
+		if report.Repository == "octo-org/fast" {
+			if _, ok := report.Components[ComponentCI]; ok {
+				t.Error("Expected CI without completed runs to be left out")
+			}
+			if report.Score != 100 {
+				t.Errorf("Expected fast repository to score 100, got %v", report.Score)
+			}
+		}
+	}
+}
+
+func TestWriteHeatmap(t *testing.T) {
+	reports := []Report{{
+		Repository: "octo-org/<script>",
+		Score:      50,
+		Components: map[string]Component{ComponentCI: {Score: 50, Value: 0.5, Samples: 2}},
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteHeatmap(&buf, reports, 30); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	html := buf.String()
+	if strings.Contains(html, "<script>") {
+		t.Error("Expected repository names to be escaped")
+	}
+	if !strings.Contains(html, "hsl(60, 70%, 75%)") || !strings.Contains(html, ComponentMergeWait) {
+		t.Errorf("Unexpected heatmap: %s", html)
+	}
+}
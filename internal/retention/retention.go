@@ -0,0 +1,263 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultType is the policy key that applies to event types without their own TTL
+const DefaultType = "*"
+
+// Modes for handling expired events
+const (
+	ModeDelete  = "delete"
+	ModeArchive = "archive"
+)
+
+// DefaultBatchSize is the number of rows removed per statement
+const DefaultBatchSize = 1000
+
+// Policy maps an event type to how long its events are kept
+type Policy map[string]time.Duration
+
+// ParsePolicy parses a comma-separated list of event_type=ttl pairs such as
+// "push=30d,pull_request=365d,*=90d". TTLs accept a "d" suffix for days in
+// addition to the units understood by time.ParseDuration.
+func ParsePolicy(list string) (Policy, error) {
+	policy := make(Policy)
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		eventType, value, ok := strings.Cut(pair, "=")
+		if !ok || eventType == "" {
+			return nil, fmt.Errorf("invalid retention entry %q", pair)
+		}
+		ttl, err := parseTTL(value)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid retention period for %s: %q", eventType, value)
+		}
+		policy[eventType] = ttl
+	}
+	return policy, nil
+}
+
+// parseTTL parses a duration that may use a "d" suffix for days
+func parseTTL(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// explicitTypes lists the event types with their own TTL, sorted
+func (p Policy) explicitTypes() []string {
+	types := make([]string, 0, len(p))
+	for eventType := range p {
+		if eventType != DefaultType {
+			types = append(types, eventType)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Batch selects up to Limit expired events. An empty EventType matches every
+// type not listed in Excluded.
+type Batch struct {
+	EventType string
+	Excluded  []string
+	Cutoff    time.Time
+	Limit     int32
+}
+
+// PruneFunc removes one batch of expired events and returns how many were removed
+type PruneFunc func(ctx context.Context, batch Batch) (int64, error)
+
+// DeleteFunc prunes by deleting expired events
+func DeleteFunc(queries *db.Queries) PruneFunc {
+	return func(ctx context.Context, batch Batch) (int64, error) {
+		return queries.DeleteExpiredWebhookEvents(ctx, db.DeleteExpiredWebhookEventsParams{
+			Cutoff:        pgtype.Timestamptz{Time: batch.Cutoff, Valid: true},
+			EventType:     batch.EventType,
+			ExcludedTypes: batch.Excluded,
+			BatchSize:     batch.Limit,
+		})
+	}
+}
+
+// ArchiveFunc prunes by moving expired events to webhook_events_archive
+func ArchiveFunc(queries *db.Queries) PruneFunc {
+	return func(ctx context.Context, batch Batch) (int64, error) {
+		return queries.ArchiveExpiredWebhookEvents(ctx, db.ArchiveExpiredWebhookEventsParams{
+			Cutoff:        pgtype.Timestamptz{Time: batch.Cutoff, Valid: true},
+			EventType:     batch.EventType,
+			ExcludedTypes: batch.Excluded,
+			BatchSize:     batch.Limit,
+		})
+	}
+}
+
+// Stats describes what the janitor has pruned
+type Stats struct {
+	Mode        string            `json:"mode"`
+	Policy      map[string]string `json:"policy"`
+	Runs        int64             `json:"runs"`
+	LastRun     *time.Time        `json:"last_run,omitempty"`
+	LastPruned  map[string]int64  `json:"last_pruned"`
+	TotalPruned map[string]int64  `json:"total_pruned"`
+	LastError   string            `json:"last_error,omitempty"`
+}
+
+// Janitor periodically prunes events that are past their retention period
+type Janitor struct {
+	policy    Policy
+	mode      string
+	batchSize int32
+	prune     PruneFunc
+
+	mu    sync.Mutex
+	stats Stats
+}
+
+// NewJanitor creates a janitor. mode is only used for reporting; prune
+// decides whether events are deleted or archived.
+func NewJanitor(policy Policy, mode string, batchSize int, prune PruneFunc) *Janitor {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	described := make(map[string]string, len(policy))
+	for eventType, ttl := range policy {
+		described[eventType] = ttl.String()
+	}
+	return &Janitor{
+		policy:    policy,
+		mode:      mode,
+		batchSize: int32(batchSize),
+		prune:     prune,
+		stats: Stats{
+			Mode:        mode,
+			Policy:      described,
+			LastPruned:  map[string]int64{},
+			TotalPruned: map[string]int64{},
+		},
+	}
+}
+
+// RunOnce prunes every expired event, one batch at a time, and returns the
+// number of events pruned per policy key
+func (j *Janitor) RunOnce(ctx context.Context, now time.Time) (map[string]int64, error) {
+	pruned := make(map[string]int64)
+	explicit := j.policy.explicitTypes()
+
+	var batches []Batch
+	for _, eventType := range explicit {
+		batches = append(batches, Batch{EventType: eventType, Excluded: []string{}, Cutoff: now.Add(-j.policy[eventType])})
+	}
+	if ttl, ok := j.policy[DefaultType]; ok {
+		batches = append(batches, Batch{Excluded: explicit, Cutoff: now.Add(-ttl)})
+	}
+
+	var runErr error
+	for _, batch := range batches {
+		batch.Limit = j.batchSize
+		key := batch.EventType
+		if key == "" {
+			key = DefaultType
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				runErr = err
+				break
+			}
+			n, err := j.prune(ctx, batch)
+			if err != nil {
+				runErr = fmt.Errorf("failed to prune %s events: %w", key, err)
+				break
+			}
+			pruned[key] += n
+			if n < int64(batch.Limit) {
+				break
+			}
+		}
+		if runErr != nil {
+			break
+		}
+	}
+
+	j.record(now, pruned, runErr)
+	return pruned, runErr
+}
+
+// record updates the janitor's stats after a run
+func (j *Janitor) record(now time.Time, pruned map[string]int64, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.stats.Runs++
+	j.stats.LastRun = &now
+	j.stats.LastPruned = pruned
+	for key, n := range pruned {
+		j.stats.TotalPruned[key] += n
+	}
+	j.stats.LastError = ""
+	if err != nil {
+		j.stats.LastError = err.Error()
+	}
+}
+
+// Stats returns a snapshot of the janitor's stats
+func (j *Janitor) Stats() Stats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	stats := j.stats
+	stats.LastPruned = make(map[string]int64, len(j.stats.LastPruned))
+	for key, n := range j.stats.LastPruned {
+		stats.LastPruned[key] = n
+	}
+	stats.TotalPruned = make(map[string]int64, len(j.stats.TotalPruned))
+	for key, n := range j.stats.TotalPruned {
+		stats.TotalPruned[key] = n
+	}
+	return stats
+}
+
+// Run prunes expired events every interval until ctx is cancelled
+func (j *Janitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			pruned, err := j.RunOnce(ctx, now.UTC())
+			if err != nil {
+				log.Printf("Retention janitor failed: %v", err)
+			}
+			var total int64
+			for _, n := range pruned {
+				total += n
+			}
+			if total > 0 {
+				log.Printf("Retention janitor pruned %d expired events (mode: %s)", total, j.mode)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("push=30d, pull_request=8760h,*=90d")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if policy["push"] != 30*24*time.Hour || policy["pull_request"] != 365*24*time.Hour || policy[DefaultType] != 90*24*time.Hour {
+		t.Errorf("Unexpected policy: %v", policy)
+	}
+
+	for _, list := range []string{"push", "push=soon", "push=0d", "=30d"} {
+		if _, err := ParsePolicy(list); err == nil {
+			t.Errorf("Expected error for %q", list)
+		}
+	}
+}
+
+func TestJanitor_RunOnce(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	policy := Policy{"push": 24 * time.Hour, DefaultType: 48 * time.Hour}
+
+	var batches []Batch
+	remaining := map[string]int64{"push": 5, "": 2}
+	prune := func(ctx context.Context, batch Batch) (int64, error) {
+		batches = append(batches, batch)
+		n := remaining[batch.EventType]
+		if n > int64(batch.Limit) {
+			n = int64(batch.Limit)
+		}
+		remaining[batch.EventType] -= n
+		return n, nil
+	}
+
+	janitor := NewJanitor(policy, ModeDelete, 2, prune)
+	pruned, err := janitor.RunOnce(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if pruned["push"] != 5 || pruned[DefaultType] != 2 {
+		t.Errorf("Unexpected pruned counts: %v", pruned)
+	}
+
+	// push needs three batches to drain five events, the default rule stops
+	// after a full batch followed by an empty one
+	if len(batches) != 5 {
+		t.Fatalf("Expected 5 batches, got %d", len(batches))
+	}
+	if !batches[0].Cutoff.Equal(now.Add(-24*time.Hour)) || batches[0].Excluded == nil {
+		t.Errorf("Unexpected push batch: %+v", batches[0])
+	}
+	last := batches[len(batches)-1]
+	if last.EventType != "" || len(last.Excluded) != 1 || last.Excluded[0] != "push" || !last.Cutoff.Equal(now.Add(-48*time.Hour)) {
+		t.Errorf("Unexpected default batch: %+v", last)
+	}
+
+	stats := janitor.Stats()
+	if stats.Runs != 1 || stats.TotalPruned["push"] != 5 || stats.LastError != "" {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestJanitor_RunOnce_Error(t *testing.T) {
+	prune := func(ctx context.Context, batch Batch) (int64, error) {
+		return 0, errors.New("connection refused")
+	}
+
+	janitor := NewJanitor(Policy{"push": time.Hour}, ModeArchive, 0, prune)
+	if _, err := janitor.RunOnce(context.Background(), time.Now()); err == nil {
+		t.Fatal("Expected error")
+	}
+	if stats := janitor.Stats(); stats.LastError == "" || stats.Mode != ModeArchive {
+		t.Errorf("Expected error to be recorded, got %+v", stats)
+	}
+}
@@ -0,0 +1,80 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) authorizeUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authorizeStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// authorize checks that a call to a registered service has a token allowed
+// to use the service's scope. Calls to the health and reflection services
+// need no token.
+func (s *Server) authorize(ctx context.Context, fullMethod string) error {
+	service, _, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	scope, ok := s.scopes[service]
+	if !ok {
+		return nil
+	}
+
+	r := request(ctx, fullMethod)
+	token, err := s.auth.Authenticate(r, scope)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, apitoken.ErrNotConfigured):
+		return status.Error(codes.Unavailable, "API authentication not configured")
+	case errors.Is(err, apitoken.ErrUnauthorized):
+		log.Printf("Invalid API token for %s from %s: %v", fullMethod, r.RemoteAddr, err)
+		return status.Error(codes.Unauthenticated, "invalid token")
+	case errors.Is(err, apitoken.ErrForbidden):
+		log.Printf("API token %s used without the %s scope for %s from %s", token.Name, scope, fullMethod, r.RemoteAddr)
+		return status.Errorf(codes.PermissionDenied, "token lacks the %s scope", scope)
+	case errors.Is(err, apitoken.ErrDenied):
+		log.Printf("Policy denied API token %s the %s scope for %s from %s: %v", token.Name, scope, fullMethod, r.RemoteAddr, err)
+		return status.Error(codes.PermissionDenied, "denied by policy")
+	case errors.Is(err, apitoken.ErrOtherTenant):
+		log.Printf("API token %s of %s used for %s from %s", token.Name, token.Organization, fullMethod, r.RemoteAddr)
+		return status.Errorf(codes.PermissionDenied, "token is limited to the %s organization", token.Organization)
+	default:
+		log.Printf("Failed to look up API token: %v", err)
+		return status.Error(codes.Internal, "failed to check token")
+	}
+}
+
+// request builds the HTTP request the authenticator checks from the
+// metadata of a call, so gRPC calls accept the tokens and users of the HTTP
+// API and policies see the full method name as the path
+func request(ctx context.Context, fullMethod string) *http.Request {
+	r, _ := http.NewRequestWithContext(ctx, http.MethodPost, fullMethod, nil)
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		r.Header.Add("Authorization", value)
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		r.RemoteAddr = p.Addr.String()
+	}
+	return r
+}
@@ -0,0 +1,161 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/encryption"
+	"github.com/deedubs/choochoo/internal/rpc/eventsv1"
+	"github.com/deedubs/choochoo/internal/stream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Limits of the events ListEvents returns
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// EventService serves stored events from the event store and live events
+// from the stream hub, over the choochoo.events.v1.EventService API
+type EventService struct {
+	eventsv1.UnimplementedEventServiceServer
+	events database.EventStore
+	hub    *stream.Hub
+	keys   *encryption.Keyring
+}
+
+// NewEventService creates an event service. Without an event store, only
+// StreamEvents is available.
+func NewEventService(events database.EventStore, hub *stream.Hub) *EventService {
+	return &EventService{events: events, hub: hub}
+}
+
+// WithEncryption decrypts stored payloads sealed with keys
+func (es *EventService) WithEncryption(keys *encryption.Keyring) *EventService {
+	es.keys = keys
+	return es
+}
+
+// ListEvents lists the most recent stored events, newest first
+func (es *EventService) ListEvents(ctx context.Context, req *eventsv1.ListEventsRequest) (*eventsv1.ListEventsResponse, error) {
+	if es.events == nil {
+		return nil, status.Error(codes.Unavailable, "database not configured")
+	}
+	limit := req.GetLimit()
+	switch {
+	case limit == 0:
+		limit = DefaultListLimit
+	case limit < 0 || limit > MaxListLimit:
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", MaxListLimit)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	rows, err := es.events.ListWebhookEvents(ctx, db.ListWebhookEventsParams{
+		EventType:      req.GetEventType(),
+		RepositoryName: req.GetRepository(),
+		RowLimit:       limit,
+	})
+	if err != nil {
+		log.Printf("Failed to list events for gRPC: %v", err)
+		return nil, status.Error(codes.Internal, "failed to list events")
+	}
+	resp := &eventsv1.ListEventsResponse{Events: make([]*eventsv1.Event, 0, len(rows))}
+	for _, row := range rows {
+		event, err := es.stored(row)
+		if err != nil {
+			return nil, err
+		}
+		resp.Events = append(resp.Events, event)
+	}
+	return resp, nil
+}
+
+// GetEvent returns a stored event by its delivery ID
+func (es *EventService) GetEvent(ctx context.Context, req *eventsv1.GetEventRequest) (*eventsv1.Event, error) {
+	if es.events == nil {
+		return nil, status.Error(codes.Unavailable, "database not configured")
+	}
+	if req.GetDeliveryId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "delivery_id is required")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	row, err := es.events.GetWebhookEvent(ctx, req.GetDeliveryId())
+	if errors.Is(err, database.ErrEventNotFound) {
+		return nil, status.Errorf(codes.NotFound, "event %s not found", req.GetDeliveryId())
+	}
+	if err != nil {
+		log.Printf("Failed to get event %s for gRPC: %v", req.GetDeliveryId(), err)
+		return nil, status.Error(codes.Internal, "failed to get event")
+	}
+	return es.stored(row)
+}
+
+// StreamEvents streams events as they are received, until the call is
+// cancelled
+func (es *EventService) StreamEvents(req *eventsv1.StreamEventsRequest, srv grpc.ServerStreamingServer[eventsv1.Event]) error {
+	if es.hub == nil {
+		return status.Error(codes.Unavailable, "event stream not available")
+	}
+	filter := stream.Filter{EventTypes: req.GetEventTypes(), Repository: req.GetRepository()}
+	sub := es.hub.Subscribe(filter, stream.DefaultBuffer)
+	defer es.hub.Unsubscribe(sub)
+
+	ctx := srv.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-sub.C:
+			if err := srv.Send(live(msg)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// stored converts a stored event, decrypting its payload
+func (es *EventService) stored(row db.WebhookEvent) (*eventsv1.Event, error) {
+	payload, err := es.keys.Open(row.DeliveryID, row.Payload)
+	if err != nil {
+		log.Printf("Failed to decrypt delivery %s: %v", row.DeliveryID, err)
+		return nil, status.Error(codes.Internal, "failed to decrypt the payload")
+	}
+	event := &eventsv1.Event{
+		DeliveryId:    row.DeliveryID,
+		EventType:     row.EventType,
+		Action:        row.Action.String,
+		Repository:    row.RepositoryName.String,
+		Sender:        row.SenderLogin.String,
+		PayloadSha256: row.PayloadSha256.String,
+		Payload:       payload,
+	}
+	if row.CreatedAt.Valid {
+		event.ReceivedAt = timestamppb.New(row.CreatedAt.Time)
+	}
+	return event, nil
+}
+
+// live converts an event of the stream hub
+func live(msg stream.Message) *eventsv1.Event {
+	return &eventsv1.Event{
+		DeliveryId:    msg.DeliveryID,
+		EventType:     msg.EventType,
+		Action:        msg.Action,
+		Repository:    msg.Repository,
+		Sender:        msg.Sender,
+		ReceivedAt:    timestamppb.New(msg.ReceivedAt),
+		PayloadSha256: msg.Checksum,
+		Payload:       msg.Payload,
+	}
+}
@@ -0,0 +1,171 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/rpc/eventsv1"
+	"github.com/deedubs/choochoo/internal/stream"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeTokens stores tokens by their hash
+type fakeTokens map[string]apitoken.Token
+
+func (f fakeTokens) Lookup(ctx context.Context, hash string) (apitoken.Token, error) {
+	if token, ok := f[hash]; ok {
+		return token, nil
+	}
+	return apitoken.Token{}, apitoken.ErrUnauthorized
+}
+
+func startEventService(t *testing.T, events database.EventStore, hub *stream.Hub) eventsv1.EventServiceClient {
+	t.Helper()
+	auth := apitoken.NewAuthenticator("", fakeTokens{
+		apitoken.Hash("cct_reader"): {Name: "reader", Scopes: []apitoken.Scope{apitoken.ScopeRead}},
+		apitoken.Hash("cct_stats"):  {Name: "stats", Scopes: []apitoken.Scope{apitoken.ScopeStats}},
+	})
+	s, err := New(func(ctx context.Context) error { return nil }, auth, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Register(&eventsv1.EventService_ServiceDesc, NewEventService(events, hub), apitoken.ScopeRead)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	// The health service needs no token
+	if _, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: LivenessService}); err != nil {
+		t.Errorf("Health check without a token failed: %v", err)
+	}
+	return eventsv1.NewEventServiceClient(conn)
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestEventService_Auth(t *testing.T) {
+	client := startEventService(t, database.NewMemoryStore(), stream.NewHub())
+
+	for token, want := range map[string]codes.Code{
+		"":           codes.Unauthenticated,
+		"cct_wrong":  codes.Unauthenticated,
+		"cct_stats":  codes.PermissionDenied,
+		"cct_reader": codes.OK,
+	} {
+		_, err := client.ListEvents(withToken(token), &eventsv1.ListEventsRequest{})
+		if status.Code(err) != want {
+			t.Errorf("ListEvents with token %q = %v, want %v", token, err, want)
+		}
+	}
+
+	events, err := client.StreamEvents(withToken("cct_stats"), &eventsv1.StreamEventsRequest{})
+	if err == nil {
+		_, err = events.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("StreamEvents without the read scope = %v", err)
+	}
+}
+
+func TestEventService_ListAndGetEvents(t *testing.T) {
+	store := database.NewMemoryStore()
+	ctx := context.Background()
+	for _, params := range []db.CreateWebhookEventParams{
+		{DeliveryID: "d1", EventType: "push", RepositoryName: pgtype.Text{String: "octo-org/api", Valid: true}, Payload: []byte(`{"ref":"refs/heads/main"}`)},
+		{DeliveryID: "d2", EventType: "pull_request", Action: pgtype.Text{String: "opened", Valid: true}, RepositoryName: pgtype.Text{String: "octo-org/api", Valid: true}, SenderLogin: pgtype.Text{String: "octocat", Valid: true}, Payload: []byte(`{"number":7}`)},
+	} {
+		if _, err := store.StoreWebhookEvent(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client := startEventService(t, store, stream.NewHub())
+	ctx = withToken("cct_reader")
+
+	resp, err := client.ListEvents(ctx, &eventsv1.ListEventsRequest{EventType: "pull_request"})
+	if err != nil {
+		t.Fatalf("ListEvents failed: %v", err)
+	}
+	if len(resp.Events) != 1 || resp.Events[0].DeliveryId != "d2" || resp.Events[0].Action != "opened" ||
+		resp.Events[0].Sender != "octocat" || string(resp.Events[0].Payload) != `{"number":7}` || resp.Events[0].ReceivedAt == nil {
+		t.Errorf("ListEvents = %v", resp.Events)
+	}
+	if _, err := client.ListEvents(ctx, &eventsv1.ListEventsRequest{Limit: MaxListLimit + 1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListEvents past the limit = %v", err)
+	}
+
+	event, err := client.GetEvent(ctx, &eventsv1.GetEventRequest{DeliveryId: "d1"})
+	if err != nil || event.EventType != "push" || event.Repository != "octo-org/api" {
+		t.Errorf("GetEvent = %v, %v", event, err)
+	}
+	if _, err := client.GetEvent(ctx, &eventsv1.GetEventRequest{DeliveryId: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetEvent of a missing event = %v", err)
+	}
+	if _, err := client.GetEvent(ctx, &eventsv1.GetEventRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("GetEvent without a delivery ID = %v", err)
+	}
+}
+
+func TestEventService_NoDatabase(t *testing.T) {
+	client := startEventService(t, nil, stream.NewHub())
+	if _, err := client.ListEvents(withToken("cct_reader"), &eventsv1.ListEventsRequest{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("ListEvents without a database = %v", err)
+	}
+}
+
+func TestEventService_StreamEvents(t *testing.T) {
+	hub := stream.NewHub()
+	client := startEventService(t, database.NewMemoryStore(), hub)
+
+	ctx, cancel := context.WithCancel(withToken("cct_reader"))
+	defer cancel()
+	events, err := client.StreamEvents(ctx, &eventsv1.StreamEventsRequest{EventTypes: []string{"push"}, Repository: "octo-org/api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Publish once the call has subscribed
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.Subscribers() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("StreamEvents did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	hub.Publish(stream.Message{DeliveryID: "filtered", EventType: "star", Repository: "octo-org/api"})
+	hub.Publish(stream.Message{DeliveryID: "d1", EventType: "push", Repository: "octo-org/api", ReceivedAt: time.Now(), Payload: []byte(`{"ref":"refs/heads/main"}`)})
+
+	event, err := events.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.DeliveryId != "d1" || string(event.Payload) != `{"ref":"refs/heads/main"}` {
+		t.Errorf("StreamEvents sent %v", event)
+	}
+
+	cancel()
+	if _, err := events.Recv(); status.Code(err) != codes.Canceled {
+		t.Errorf("Recv after cancelling = %v", err)
+	}
+}
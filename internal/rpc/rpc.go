@@ -0,0 +1,144 @@
+// Package rpc serves choochoo's gRPC surface. Besides its own services, the
+// server speaks the standard gRPC health checking protocol and server
+// reflection, so grpcurl, Kubernetes gRPC probes and service meshes work
+// without choochoo's protobuf definitions. choochoo's services take the API
+// tokens of the HTTP API in the authorization metadata.
+package rpc
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Health services. The empty service reports the server as a whole, like
+// ReadinessService; LivenessService is serving while the process runs.
+const (
+	LivenessService  = "liveness"
+	ReadinessService = "readiness"
+)
+
+// DefaultCheckInterval is how often the readiness checks are run for the
+// health service
+const DefaultCheckInterval = 5 * time.Second
+
+// ReadyFunc returns an error when the server is not ready to serve
+type ReadyFunc func(ctx context.Context) error
+
+// Server is the gRPC server
+type Server struct {
+	server *grpc.Server
+	health *health.Server
+	ready  ReadyFunc
+	auth   *apitoken.Authenticator
+	// scopes are the scopes the calls of each registered service need
+	scopes map[string]apitoken.Scope
+
+	mu      sync.Mutex
+	serving bool
+}
+
+// New creates a gRPC server reporting the result of ready through the
+// health service, authenticating calls to registered services with auth,
+// and with TLS from certFile and keyFile when set
+func New(ready ReadyFunc, auth *apitoken.Authenticator, certFile, keyFile string) (*Server, error) {
+	s := &Server{health: health.NewServer(), ready: ready, auth: auth, scopes: make(map[string]apitoken.Scope)}
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.authorizeUnary),
+		grpc.ChainStreamInterceptor(s.authorizeStream),
+	}
+	if certFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	s.server = grpc.NewServer(opts...)
+	healthpb.RegisterHealthServer(s.server, s.health)
+	reflection.Register(s.server)
+
+	// Not ready until the first check passes
+	s.health.SetServingStatus(LivenessService, healthpb.HealthCheckResponse_SERVING)
+	s.setReady(false)
+	return s, nil
+}
+
+// Register registers a service implementation, like grpc.Server's, whose
+// calls need a token allowed to use scope. Services must be registered
+// before Serve is called.
+func (s *Server) Register(desc *grpc.ServiceDesc, impl interface{}, scope apitoken.Scope) {
+	s.scopes[desc.ServiceName] = scope
+	s.server.RegisterService(desc, impl)
+}
+
+// Check runs the readiness checks once and updates the health service
+func (s *Server) Check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := s.ready(ctx)
+	s.mu.Lock()
+	changed := s.serving != (err == nil)
+	s.mu.Unlock()
+	if changed {
+		if err != nil {
+			log.Printf("gRPC health: not serving: %v", err)
+		} else {
+			log.Printf("gRPC health: serving")
+		}
+	}
+	s.setReady(err == nil)
+}
+
+func (s *Server) setReady(ready bool) {
+	status := healthpb.HealthCheckResponse_NOT_SERVING
+	if ready {
+		status = healthpb.HealthCheckResponse_SERVING
+	}
+	s.mu.Lock()
+	s.serving = ready
+	s.mu.Unlock()
+	s.health.SetServingStatus("", status)
+	s.health.SetServingStatus(ReadinessService, status)
+}
+
+// Run runs the readiness checks every interval until ctx is done, so Watch
+// streams see changes as they happen
+func (s *Server) Run(ctx context.Context, interval time.Duration) {
+	s.Check(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Check(ctx)
+		}
+	}
+}
+
+// Serve accepts connections on lis until Stop is called
+func (s *Server) Serve(lis net.Listener) error {
+	return s.server.Serve(lis)
+}
+
+// Stop reports every service as not serving, so clients watching the
+// health service move away, and stops the server once pending calls finish
+func (s *Server) Stop() {
+	s.health.Shutdown()
+	s.server.GracefulStop()
+}
@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+)
+
+func startServer(t *testing.T, ready ReadyFunc) (*Server, *grpc.ClientConn) {
+	t.Helper()
+	s, err := New(ready, nil, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return s, conn
+}
+
+func TestServer_Health(t *testing.T) {
+	var failure error = errors.New("database unreachable")
+	s, conn := startServer(t, func(ctx context.Context) error { return failure })
+	client := healthpb.NewHealthClient(conn)
+	ctx := context.Background()
+
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Check(%q) failed: %v", service, err)
+		}
+		return resp.Status
+	}
+
+	// Not ready before the first check
+	if got := check(""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING before the first check, got %v", got)
+	}
+	if got := check(LivenessService); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected liveness to be SERVING, got %v", got)
+	}
+
+	s.Check(ctx)
+	if got := check(ReadinessService); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING with a failing check, got %v", got)
+	}
+
+	failure = nil
+	s.Check(ctx)
+	for _, service := range []string{"", ReadinessService, LivenessService} {
+		if got := check(service); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Expected %q to be SERVING, got %v", service, got)
+		}
+	}
+
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unknown service, got %v", err)
+	}
+}
+
+func TestServer_Reflection(t *testing.T) {
+	_, conn := startServer(t, func(ctx context.Context) error { return nil })
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	services := map[string]bool{}
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services[service.Name] = true
+	}
+	if !services["grpc.health.v1.Health"] || !services["grpc.reflection.v1.ServerReflection"] {
+		t.Errorf("Expected the health and reflection services to be listed, got %v", services)
+	}
+}
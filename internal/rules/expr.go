@@ -0,0 +1,913 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Conditions are written in choochoo's condition language, a small
+// expression language of its own parsed and evaluated here. Its syntax and
+// semantics follow the Common Expression Language (CEL) but it is not CEL
+// and is not checked against CEL's conformance tests. It has literals,
+// lists, field selection and indexing, the logical, equality, ordering and
+// in operators, the has() macro, the exists() and all() list macros, size()
+// and the string functions contains, startsWith, endsWith, matches and
+// lowerAscii.
+//
+// Missing from CEL are arithmetic, the conditional operator ?:, map
+// literals, the exists_one(), map() and filter() macros, type conversions
+// such as int() and string(), timestamps and durations, bytes, the int and
+// uint types, escapes other than \n, \t, \r, \\ and quotes, and type
+// checking at compile time: type errors are found when a condition is
+// evaluated. Numbers are all doubles, as payloads decode JSON numbers as
+// doubles.
+
+// Variables available to conditions
+var Variables = []string{"event", "action", "repository", "sender", "delivery_id", "payload", "model"}
+
+// ErrInvalidCondition is returned for conditions that do not compile
+var ErrInvalidCondition = errors.New("invalid condition")
+
+// Program is a compiled condition
+type Program struct {
+	source string
+	root   node
+}
+
+// String returns the condition source
+func (p *Program) String() string {
+	return p.source
+}
+
+// Compile parses a condition, checking that it only refers to the condition
+// variables
+func Compile(source string) (*Program, error) {
+	return CompileWith(source, Variables)
+}
+
+// CompileWith parses an expression in the condition language that may only
+// refer to variables, for uses other than rules
+func CompileWith(source string, variables []string) (*Program, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCondition, err)
+	}
+	p := &parser{tokens: tokens, scope: make(map[string]int)}
+	for _, name := range variables {
+		p.scope[name] = 1
+	}
+	root, err := p.parseExpr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = p.errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCondition, err)
+	}
+	return &Program{source: source, root: root}, nil
+}
+
+// Eval evaluates the condition with the given variables. Referring to a
+// field that is not set is an error unless another operand of && or ||
+// decides the result, as in CEL; has() tests whether a field is set.
+func (p *Program) Eval(vars map[string]interface{}) (bool, error) {
+	v, err := p.root.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition evaluated to %s, not bool", typeName(v))
+	}
+	return b, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+	str  string
+	num  float64
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of condition"
+	}
+	return strconv.Quote(t.text)
+}
+
+// punctuation lists the operators, longest first
+var punctuation = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "-", "(", ")", "[", "]", ".", ","}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'' || ((c == 'r' || c == 'R') && i+1 < len(src) && (src[i+1] == '"' || src[i+1] == '\'')):
+			start := i
+			raw := c == 'r' || c == 'R'
+			if raw {
+				i++
+			}
+			s, n, err := lexString(src[i:], raw)
+			if err != nil {
+				return nil, fmt.Errorf("%v at offset %d", err, start)
+			}
+			i += n
+			tokens = append(tokens, token{kind: tokString, text: src[start:i], pos: start, str: s})
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.' || src[i] == 'e' || src[i] == 'E') {
+				i++
+			}
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at offset %d", src[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], pos: start, num: num})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		default:
+			matched := false
+			for _, p := range punctuation {
+				if strings.HasPrefix(src[i:], p) {
+					tokens = append(tokens, token{kind: tokPunct, text: p, pos: i})
+					i += len(p)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// lexString reads a quoted string at the start of src, returning its value
+// and length
+func lexString(src string, raw bool) (string, int, error) {
+	quote := src[0]
+	var b strings.Builder
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && !raw && i+1 < len(src):
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\', '"', '\'':
+				b.WriteByte(src[i])
+			default:
+				return "", 0, fmt.Errorf("invalid escape \\%c", src[i])
+			}
+		case c == '\n':
+			return "", 0, errors.New("unterminated string")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, errors.New("unterminated string")
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	// scope counts the declarations of each name: the variables and the
+	// loop variables of enclosing macros
+	scope map[string]int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is the punctuation text
+func (p *parser) accept(text string) bool {
+	if t := p.peek(); t.kind == tokPunct && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.errorf("expected %q, found %s", text, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(format+" at offset %d", append(args, p.peek().pos)...)
+}
+
+func (p *parser) parseExpr() (node, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseRelation()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseRelation()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseRelation() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		op := ""
+		switch {
+		case t.kind == tokPunct && (t.text == "==" || t.text == "!=" || t.text == "<" || t.text == "<=" || t.text == ">" || t.text == ">="):
+			op = t.text
+		case t.kind == tokIdent && t.text == "in":
+			op = "in"
+		default:
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &relation{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	switch {
+	case p.accept("!"):
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &not{operand: operand}, nil
+	case p.accept("-"):
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &negate{operand: operand}, nil
+	}
+	return p.parseMember()
+}
+
+func (p *parser) parseMember() (node, error) {
+	operand, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			t := p.next()
+			if t.kind != tokIdent {
+				return nil, p.errorf("expected a field or function name, found %s", t)
+			}
+			if !p.accept("(") {
+				operand = &selection{operand: operand, field: t.text}
+				continue
+			}
+			if t.text == "exists" || t.text == "all" {
+				operand, err = p.parseMacro(t.text, operand)
+			} else {
+				operand, err = p.parseCall(t.text, operand)
+			}
+			if err != nil {
+				return nil, err
+			}
+		case p.accept("["):
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			operand = &indexing{operand: operand, index: index}
+		default:
+			return operand, nil
+		}
+	}
+}
+
+// parseMacro parses the arguments of list.exists(x, predicate) and
+// list.all(x, predicate), declaring x while parsing the predicate
+func (p *parser) parseMacro(name string, list node) (node, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return nil, p.errorf("%s() expects a variable name, found %s", name, t)
+	}
+	if err := p.expect(","); err != nil {
+		return nil, err
+	}
+	p.scope[t.text]++
+	predicate, err := p.parseExpr()
+	p.scope[t.text]--
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	return &comprehension{all: name == "all", list: list, variable: t.text, predicate: predicate}, nil
+}
+
+// parseCall parses the arguments of a function call, target being the
+// receiver of member calls such as s.startsWith("x")
+func (p *parser) parseCall(name string, target node) (node, error) {
+	var args []node
+	if !p.accept(")") {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if p.accept(")") {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if target != nil {
+		args = append([]node{target}, args...)
+	}
+
+	arity, ok := functions[name]
+	if !ok {
+		return nil, p.errorf("unknown function %q", name)
+	}
+	if len(args) != arity {
+		return nil, p.errorf("%s() expects %d arguments, found %d", name, arity, len(args))
+	}
+	c := &call{name: name, args: args}
+	if name == "matches" {
+		if lit, ok := args[1].(*literal); ok {
+			pattern, isString := lit.value.(string)
+			if !isString {
+				return nil, p.errorf("matches() expects a string pattern")
+			}
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, p.errorf("invalid pattern: %v", err)
+			}
+			c.pattern = re
+		}
+	}
+	return c, nil
+}
+
+// functions maps the supported functions to their arity, counting the
+// receiver of member calls
+var functions = map[string]int{
+	"size":       1,
+	"contains":   2,
+	"startsWith": 2,
+	"endsWith":   2,
+	"matches":    2,
+	"lowerAscii": 1,
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return &literal{value: t.str}, nil
+	case tokNumber:
+		return &literal{value: t.num}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		case "null":
+			return &literal{value: nil}, nil
+		}
+		if p.accept("(") {
+			if t.text == "has" {
+				return p.parseHas()
+			}
+			return p.parseCall(t.text, nil)
+		}
+		if p.scope[t.text] == 0 {
+			return nil, fmt.Errorf("undeclared reference to %q at offset %d", t.text, t.pos)
+		}
+		return &variable{name: t.text}, nil
+	case tokPunct:
+		switch t.text {
+		case "(":
+			inner, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			return inner, p.expect(")")
+		case "[":
+			list := &listNode{}
+			if p.accept("]") {
+				return list, nil
+			}
+			for {
+				elem, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				list.elems = append(list.elems, elem)
+				if p.accept("]") {
+					return list, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("unexpected %s at offset %d", t, t.pos)
+}
+
+// parseHas parses has(a.b), which tests whether field b of a is set
+func (p *parser) parseHas() (node, error) {
+	arg, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	sel, ok := arg.(*selection)
+	if !ok {
+		return nil, p.errorf("has() expects a field selection such as payload.field")
+	}
+	return &presence{operand: sel.operand, field: sel.field}, nil
+}
+
+// node is a compiled expression
+type node interface {
+	eval(vars map[string]interface{}) (interface{}, error)
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (n *literal) eval(map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type variable struct {
+	name string
+}
+
+func (n *variable) eval(vars map[string]interface{}) (interface{}, error) {
+	v, ok := vars[n.name]
+	if !ok {
+		return nil, fmt.Errorf("no value for %q", n.name)
+	}
+	return v, nil
+}
+
+type listNode struct {
+	elems []node
+}
+
+func (n *listNode) eval(vars map[string]interface{}) (interface{}, error) {
+	list := make([]interface{}, 0, len(n.elems))
+	for _, elem := range n.elems {
+		v, err := elem.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+type selection struct {
+	operand node
+	field   string
+}
+
+func (n *selection) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot select %q from %s", n.field, typeName(v))
+	}
+	field, ok := m[n.field]
+	if !ok {
+		return nil, fmt.Errorf("no such key: %s", n.field)
+	}
+	return field, nil
+}
+
+type presence struct {
+	operand node
+	field   string
+}
+
+func (n *presence) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot test %q of %s", n.field, typeName(v))
+	}
+	_, ok = m[n.field]
+	return ok, nil
+}
+
+type indexing struct {
+	operand node
+	index   node
+}
+
+func (n *indexing) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	switch container := v.(type) {
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot index a map with %s", typeName(index))
+		}
+		field, ok := container[key]
+		if !ok {
+			return nil, fmt.Errorf("no such key: %s", key)
+		}
+		return field, nil
+	case []interface{}:
+		f, ok := index.(float64)
+		if !ok || f != float64(int(f)) {
+			return nil, fmt.Errorf("cannot index a list with %s", typeName(index))
+		}
+		if i := int(f); i >= 0 && i < len(container) {
+			return container[i], nil
+		}
+		return nil, fmt.Errorf("index %v out of range", f)
+	}
+	return nil, fmt.Errorf("cannot index %s", typeName(v))
+}
+
+type not struct {
+	operand node
+}
+
+func (n *not) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("no such overload: !%s", typeName(v))
+	}
+	return !b, nil
+}
+
+type negate struct {
+	operand node
+}
+
+func (n *negate) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.operand.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return nil, fmt.Errorf("no such overload: -%s", typeName(v))
+	}
+	return -f, nil
+}
+
+// logical is && or ||. Like CEL, an error on one side is ignored when the
+// other side decides the result.
+type logical struct {
+	and         bool
+	left, right node
+}
+
+func (n *logical) eval(vars map[string]interface{}) (interface{}, error) {
+	left, leftErr := n.operand(n.left, vars)
+	if leftErr == nil && left != n.and {
+		return left, nil
+	}
+	right, rightErr := n.operand(n.right, vars)
+	if rightErr == nil && right != n.and {
+		return right, nil
+	}
+	if leftErr != nil {
+		return nil, leftErr
+	}
+	if rightErr != nil {
+		return nil, rightErr
+	}
+	return n.and, nil
+}
+
+func (n *logical) operand(operand node, vars map[string]interface{}) (bool, error) {
+	v, err := operand.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		op := "||"
+		if n.and {
+			op = "&&"
+		}
+		return false, fmt.Errorf("no such overload: %s %s", typeName(v), op)
+	}
+	return b, nil
+}
+
+type relation struct {
+	op          string
+	left, right node
+}
+
+func (n *relation) eval(vars map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		switch container := right.(type) {
+		case []interface{}:
+			for _, elem := range container {
+				if equal(left, elem) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			key, ok := left.(string)
+			if !ok {
+				return false, nil
+			}
+			_, ok = container[key]
+			return ok, nil
+		}
+		return nil, fmt.Errorf("no such overload: %s in %s", typeName(left), typeName(right))
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("no such overload: double %s %s", n.op, typeName(right))
+		}
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("no such overload: string %s %s", n.op, typeName(right))
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, fmt.Errorf("no such overload: %s %s %s", typeName(left), n.op, typeName(right))
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
+
+// equal compares values as CEL does: values of different types are not
+// equal rather than an error
+func equal(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+type comprehension struct {
+	all       bool
+	list      node
+	variable  string
+	predicate node
+}
+
+func (n *comprehension) eval(vars map[string]interface{}) (interface{}, error) {
+	v, err := n.list.eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	var elems []interface{}
+	switch container := v.(type) {
+	case []interface{}:
+		elems = container
+	case map[string]interface{}:
+		for key := range container {
+			elems = append(elems, key)
+		}
+	default:
+		return nil, fmt.Errorf("cannot iterate over %s", typeName(v))
+	}
+
+	scope := make(map[string]interface{}, len(vars)+1)
+	for name, value := range vars {
+		scope[name] = value
+	}
+	var firstErr error
+	for _, elem := range elems {
+		scope[n.variable] = elem
+		result, err := n.predicate.eval(scope)
+		if err == nil {
+			b, ok := result.(bool)
+			if !ok {
+				err = fmt.Errorf("predicate evaluated to %s, not bool", typeName(result))
+			} else if b != n.all {
+				// exists found a match or all found a mismatch
+				return b, nil
+			}
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return n.all, nil
+}
+
+type call struct {
+	name    string
+	args    []node
+	pattern *regexp.Regexp
+}
+
+func (n *call) eval(vars map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(vars)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+
+	if n.name == "size" {
+		switch v := args[0].(type) {
+		case string:
+			return float64(len([]rune(v))), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("no such overload: size(%s)", typeName(args[0]))
+	}
+
+	strs := make([]string, len(args))
+	for i, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("no such overload: %s() on %s", n.name, typeName(arg))
+		}
+		strs[i] = s
+	}
+	switch n.name {
+	case "contains":
+		return strings.Contains(strs[0], strs[1]), nil
+	case "startsWith":
+		return strings.HasPrefix(strs[0], strs[1]), nil
+	case "endsWith":
+		return strings.HasSuffix(strs[0], strs[1]), nil
+	case "lowerAscii":
+		return strings.Map(func(r rune) rune {
+			if r >= 'A' && r <= 'Z' {
+				return r + 'a' - 'A'
+			}
+			return r
+		}, strs[0]), nil
+	case "matches":
+		re := n.pattern
+		if re == nil {
+			var err error
+			if re, err = regexp.Compile(strs[1]); err != nil {
+				return nil, fmt.Errorf("invalid pattern: %v", err)
+			}
+		}
+		return re.MatchString(strs[0]), nil
+	}
+	return nil, fmt.Errorf("unknown function %q", n.name)
+}
+
+// typeName names the CEL type of a value in errors
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "double"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
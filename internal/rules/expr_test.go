@@ -0,0 +1,125 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func testVars(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var payload interface{}
+	err := json.Unmarshal([]byte(`{
+		"action": "opened",
+		"number": 42,
+		"pull_request": {
+			"title": "Fix flaky retries",
+			"draft": false,
+			"additions": 120,
+			"labels": [{"name": "bug"}, {"name": "ci"}],
+			"head": {"ref": "fix/retries"}
+		},
+		"repository": {"full_name": "octo-org/hello-world", "private": true}
+	}`), &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]interface{}{
+		"event":       "pull_request",
+		"action":      "opened",
+		"repository":  "octo-org/hello-world",
+		"sender":      "octocat",
+		"delivery_id": "d1",
+		"payload":     payload,
+	}
+}
+
+func TestProgram_Eval(t *testing.T) {
+	vars := testVars(t)
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		{`event == "pull_request"`, true},
+		{`event == 'push'`, false},
+		{`event != "push" && action in ["opened", "reopened"]`, true},
+		{`payload.pull_request.draft`, false},
+		{`!payload.pull_request.draft`, true},
+		{`payload.number == 42`, true},
+		{`payload.pull_request.additions > 100 && payload.pull_request.additions <= 120`, true},
+		{`payload["repository"]["private"] == true`, true},
+		{`payload.pull_request.labels[1].name == "ci"`, true},
+		{`payload.pull_request.labels.exists(l, l.name == "bug")`, true},
+		{`payload.pull_request.labels.all(l, l.name.startsWith("b"))`, false},
+		{`size(payload.pull_request.labels) == 2`, true},
+		{`payload.pull_request.title.size() > 5`, true},
+		{`payload.pull_request.head.ref.startsWith("fix/")`, true},
+		{`repository.endsWith("/hello-world") && sender.contains("cat")`, true},
+		{`payload.pull_request.title.lowerAscii().contains("flaky")`, true},
+		{`payload.pull_request.title.matches(r"^Fix\s")`, true},
+		{`has(payload.pull_request.merged)`, false},
+		{`has(payload.pull_request.head)`, true},
+		{`"bug" in payload.pull_request`, false},
+		{`"draft" in payload.pull_request`, true},
+		{`event == "push" && payload.commits[0].id == "x"`, false},
+		{`payload.pull_request.merged || event == "pull_request"`, true},
+		{`-payload.number < 0`, true},
+		{`event == 42`, false},
+		{`(event == "push" || event == "pull_request") && repository.startsWith("octo-org/")`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			program, err := Compile(tt.condition)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			got, err := program.Eval(vars)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProgram_EvalErrors(t *testing.T) {
+	vars := testVars(t)
+	for _, condition := range []string{
+		`payload.pull_request.merged`,
+		`payload.pull_request.merged && event == "pull_request"`,
+		`event`,
+		`event < 3`,
+		`payload.pull_request.labels[5].name == "x"`,
+		`size(payload.number) > 0`,
+	} {
+		program, err := Compile(condition)
+		if err != nil {
+			t.Fatalf("Expected %q to compile, got %v", condition, err)
+		}
+		if _, err := program.Eval(vars); err == nil {
+			t.Errorf("Expected an evaluation error for %q", condition)
+		}
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, condition := range []string{
+		``,
+		`event ==`,
+		`issue.number == 1`,
+		`payload.labels.exists(l, m.name == "bug")`,
+		`event.unknown()`,
+		`event.startsWith()`,
+		`has(event)`,
+		`event.matches("(")`,
+		`"unterminated`,
+		`event == "push" extra`,
+		`event # "push"`,
+	} {
+		if _, err := Compile(condition); !errors.Is(err, ErrInvalidCondition) {
+			t.Errorf("Expected ErrInvalidCondition for %q, got %v", condition, err)
+		}
+	}
+}
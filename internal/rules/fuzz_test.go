@@ -0,0 +1,38 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+)
+
+// FuzzEvaluate compiles mutated conditions and evaluates them on mutated
+// payloads, which must fail with an error rather than panic
+func FuzzEvaluate(f *testing.F) {
+	for _, seed := range []struct{ condition, eventType, payload string }{
+		{`event == "push" && payload.ref == "refs/heads/main"`, "push", `{"ref":"refs/heads/main"}`},
+		{`has(payload.pull_request) && payload.pull_request.additions > 500`, "pull_request", `{"pull_request":{"additions":501}}`},
+		{`sender.endsWith("[bot]")`, "issues", `{"sender":{"login":"dependabot[bot]"}}`},
+		{`payload.commits.exists(c, c.message.matches("^fix"))`, "push", `{"commits":[{"message":"fix: x"},{"message":7}]}`},
+		{`payload.labels.all(l, l.name in ["bug", "triage"]) || size(payload.labels) == 0`, "issues", `{"labels":[{"name":"bug"}]}`},
+		{`!(action in ["opened", "reopened"]) && payload.issue.title.lowerAscii().contains("flaky")`, "issues", `{"action":"edited","issue":{"title":"Flaky"}}`},
+		{`model.head_sha.startsWith("abc") && payload.number >= 1.5`, "pull_request", `{"number":2,"pull_request":{"head":{"sha":"abc"}}}`},
+		{`payload[0] == null`, "push", `[null]`},
+		{`payload.a.b.c`, "push", `not json`},
+		{`((`, "push", `{}`},
+	} {
+		f.Add(seed.condition, seed.eventType, []byte(seed.payload))
+	}
+
+	f.Fuzz(func(t *testing.T, condition, eventType string, payload []byte) {
+		program, err := Compile(condition)
+		if err != nil {
+			return
+		}
+		vars, err := forwarder.Event{EventType: eventType, Repository: "octo-org/api", Sender: "octocat", Payload: payload}.Variables()
+		if err != nil {
+			return
+		}
+		program.Eval(vars)
+	})
+}
@@ -0,0 +1,780 @@
+// Package rules runs operator-defined rules on stored events. A rule pairs a
+// condition over the event with the actions taken when it holds: forwarding
+// the event, notifying a channel, a chat platform or by email, labelling the
+// issue or pull request, setting a commit status, or dropping the event so
+// later rules and the forwarders skip it.
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/chat"
+	"github.com/deedubs/choochoo/internal/email"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/github"
+	"gopkg.in/yaml.v3"
+)
+
+// Action types
+const (
+	ActionForward = "forward"
+	ActionNotify  = "notify"
+	ActionLabel   = "label"
+	ActionDrop    = "drop"
+	ActionSlack   = "slack"
+	ActionDiscord = chat.PlatformDiscord
+	ActionTeams   = chat.PlatformTeams
+	ActionEmail   = "email"
+	ActionStatus  = "status"
+)
+
+// DefaultStatusContext prefixes the rule name to make the context of status
+// actions without one
+const DefaultStatusContext = "choochoo/"
+
+// Rule sources
+const (
+	SourceFile     = "file"
+	SourceDatabase = "database"
+)
+
+// ErrInvalidRule is returned for rules that fail validation
+var ErrInvalidRule = errors.New("invalid rule")
+
+// namePattern restricts rule names to those safe in URLs and logs
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// Action is something a rule does when its condition holds. Forward sends
+// the event payload to URL as the forwarders do, notify sends a short
+// notification with Message to URL, label adds Labels to the issue or pull
+// request of the event and drop stops later rules and the forwarders. Slack
+// posts Message, a chat.Template, to the Slack incoming webhook URL or to
+// Channel as the bot of the Slack bot token; discord and teams post it to
+// the Discord or Microsoft Teams incoming webhook URL. Email sends Subject
+// and Message, an HTML template, to the To addresses, for every event or as
+// a digest of the events matched over the Digest period, such as "24h".
+// Status sets the State of Context, "choochoo/<rule>" by default, on the
+// commit of the event, with Message as the description and URL as the
+// target URL.
+type Action struct {
+	Type    string   `json:"type" yaml:"type"`
+	URL     string   `json:"url,omitempty" yaml:"url,omitempty"`
+	Channel string   `json:"channel,omitempty" yaml:"channel,omitempty"`
+	Message string   `json:"message,omitempty" yaml:"message,omitempty"`
+	Labels  []string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	To      []string `json:"to,omitempty" yaml:"to,omitempty"`
+	Subject string   `json:"subject,omitempty" yaml:"subject,omitempty"`
+	Digest  string   `json:"digest,omitempty" yaml:"digest,omitempty"`
+	Context string   `json:"context,omitempty" yaml:"context,omitempty"`
+	State   string   `json:"state,omitempty" yaml:"state,omitempty"`
+}
+
+// Rule runs its actions on events matching its condition
+type Rule struct {
+	Name      string   `json:"name" yaml:"name"`
+	Condition string   `json:"condition" yaml:"condition"`
+	Actions   []Action `json:"actions" yaml:"actions"`
+	Source    string   `json:"source" yaml:"-"`
+}
+
+// Validate checks the rule name, condition and actions
+func (r Rule) Validate() error {
+	_, err := compile(r)
+	return err
+}
+
+// LoadFunc loads the rules stored in the database
+type LoadFunc func(ctx context.Context) ([]Rule, error)
+
+// Labeler adds labels to issue or pull request number of repo
+type Labeler func(ctx context.Context, repo string, number int, labels []string) error
+
+// StatusSetter sets the status of commit sha of repo
+type StatusSetter func(ctx context.Context, repo, sha string, status github.CommitStatus) error
+
+// LoadFile reads rules from a YAML file with a top-level rules list
+func LoadFile(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Rules []Rule `yaml:"rules"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	names := make(map[string]bool)
+	for i := range file.Rules {
+		file.Rules[i].Source = SourceFile
+		if err := file.Rules[i].Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if names[file.Rules[i].Name] {
+			return nil, fmt.Errorf("%s: %w: duplicate name %q", path, ErrInvalidRule, file.Rules[i].Name)
+		}
+		names[file.Rules[i].Name] = true
+	}
+	return file.Rules, nil
+}
+
+// compiledRule is a rule ready to run
+type compiledRule struct {
+	Rule
+	program   *Program
+	channels  []forwarder.Forwarder
+	templates []*chat.Template
+	emails    []*email.Digest
+}
+
+func compile(r Rule) (*compiledRule, error) {
+	if !namePattern.MatchString(r.Name) {
+		return nil, fmt.Errorf("%w: name %q must be 1 to 64 letters, digits, '.', '-' or '_'", ErrInvalidRule, r.Name)
+	}
+	program, err := Compile(r.Condition)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrInvalidRule, r.Name, err)
+	}
+	if len(r.Actions) == 0 {
+		return nil, fmt.Errorf("%w %q: no actions", ErrInvalidRule, r.Name)
+	}
+
+	c := &compiledRule{
+		Rule:      r,
+		program:   program,
+		channels:  make([]forwarder.Forwarder, len(r.Actions)),
+		templates: make([]*chat.Template, len(r.Actions)),
+		emails:    make([]*email.Digest, len(r.Actions)),
+	}
+	for i, action := range r.Actions {
+		switch action.Type {
+		case ActionForward, ActionNotify:
+			channel, err := forwarder.NewHTTPForwarder(action.URL)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: %s action: %v", ErrInvalidRule, r.Name, action.Type, err)
+			}
+			c.channels[i] = channel
+		case ActionLabel:
+			if len(action.Labels) == 0 {
+				return nil, fmt.Errorf("%w %q: label action without labels", ErrInvalidRule, r.Name)
+			}
+		case ActionSlack:
+			if (action.URL == "") == (action.Channel == "") {
+				return nil, fmt.Errorf("%w %q: slack action needs either a url or a channel", ErrInvalidRule, r.Name)
+			}
+			template, err := chat.ParseTemplate(action.Message)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: slack action: %v", ErrInvalidRule, r.Name, err)
+			}
+			c.templates[i] = template
+			if action.URL != "" {
+				channel, err := chat.NewSlackWebhook(action.URL, template)
+				if err != nil {
+					return nil, fmt.Errorf("%w %q: slack action: %v", ErrInvalidRule, r.Name, err)
+				}
+				c.channels[i] = channel
+			}
+		case ActionDiscord, ActionTeams:
+			template, err := chat.ParseTemplate(action.Message)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: %s action: %v", ErrInvalidRule, r.Name, action.Type, err)
+			}
+			channel, err := chat.NewWebhook(action.Type, action.URL, template)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: %s action: %v", ErrInvalidRule, r.Name, action.Type, err)
+			}
+			c.channels[i] = channel
+		case ActionEmail:
+			to, err := email.ParseRecipients(action.To)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: email action: %v", ErrInvalidRule, r.Name, err)
+			}
+			var every time.Duration
+			if action.Digest != "" {
+				every, err = time.ParseDuration(action.Digest)
+				if err != nil || every < time.Minute {
+					return nil, fmt.Errorf("%w %q: email action: digest must be a duration of at least 1m", ErrInvalidRule, r.Name)
+				}
+			}
+			template, err := email.ParseTemplate(action.Subject, action.Message, every > 0)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: email action: %v", ErrInvalidRule, r.Name, err)
+			}
+			c.emails[i] = &email.Digest{To: to, Template: template, Every: every}
+		case ActionStatus:
+			switch action.State {
+			case "", github.StatePending, github.StateSuccess, github.StateFailure, github.StateError:
+			default:
+				return nil, fmt.Errorf("%w %q: status action: state must be pending, success, failure or error", ErrInvalidRule, r.Name)
+			}
+			if action.URL != "" {
+				if u, err := url.Parse(action.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return nil, fmt.Errorf("%w %q: status action: url must be an http or https URL", ErrInvalidRule, r.Name)
+				}
+			}
+		case ActionDrop:
+		default:
+			return nil, fmt.Errorf("%w %q: unknown action %q", ErrInvalidRule, r.Name, action.Type)
+		}
+	}
+	return c, nil
+}
+
+// Engine evaluates the rules of the rules file followed by those stored in
+// the database, in name order
+type Engine struct {
+	file       []Rule
+	load       LoadFunc
+	labeler    Labeler
+	statuses   StatusSetter
+	slackToken string
+	mailer     *email.Mailer
+	digests    *email.Digester
+	wrap       func(http.RoundTripper) http.RoundTripper
+
+	mu       sync.RWMutex
+	compiled []*compiledRule
+}
+
+// NewEngine creates an engine for the file rules, which must be valid, and
+// the database rules loaded by load, if not nil. Call Reload to load the
+// database rules.
+func NewEngine(file []Rule, load LoadFunc) *Engine {
+	e := &Engine{file: file, load: load}
+	for _, r := range file {
+		if c, err := compile(r); err == nil {
+			e.compiled = append(e.compiled, c)
+		}
+	}
+	return e
+}
+
+// WithTransportWrapper makes the engine wrap the transport of the channels
+// of forward, notify, slack, discord and teams actions with wrap, such as to
+// record the requests they send
+func (e *Engine) WithTransportWrapper(wrap func(http.RoundTripper) http.RoundTripper) *Engine {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.wrap = wrap
+	for _, c := range e.compiled {
+		forwarder.WrapTransports(c.channels, wrap)
+	}
+	return e
+}
+
+// compile compiles a rule like compile, wrapping the transport of its
+// channels
+func (e *Engine) compile(r Rule) (*compiledRule, error) {
+	c, err := compile(r)
+	if err == nil && e.wrap != nil {
+		forwarder.WrapTransports(c.channels, e.wrap)
+	}
+	return c, err
+}
+
+// WithLabeler sets the function label actions add labels with. Without one,
+// label actions are skipped.
+func (e *Engine) WithLabeler(labeler Labeler) *Engine {
+	e.labeler = labeler
+	return e
+}
+
+// WithStatusSetter sets the function status actions set commit statuses
+// with. Without one, status actions are skipped.
+func (e *Engine) WithStatusSetter(statuses StatusSetter) *Engine {
+	e.statuses = statuses
+	return e
+}
+
+// WithSlackToken sets the bot token slack actions with a channel post with.
+// Without one, those actions are skipped.
+func (e *Engine) WithSlackToken(token string) *Engine {
+	e.slackToken = token
+	return e
+}
+
+// WithMailer sets the mailer email actions send with. Without one, those
+// actions are skipped.
+func (e *Engine) WithMailer(mailer *email.Mailer) *Engine {
+	e.mailer = mailer
+	e.digests = email.NewDigester(mailer)
+	return e
+}
+
+// RunDigests sends the email digests that are due until ctx is done
+func (e *Engine) RunDigests(ctx context.Context) {
+	if e.digests != nil {
+		e.digests.Run(ctx, email.DigestCheckInterval)
+	}
+}
+
+// Reload reloads the database rules. Stored rules that no longer compile
+// or share the name of a file rule are skipped.
+func (e *Engine) Reload(ctx context.Context) error {
+	if e.load == nil {
+		return nil
+	}
+	stored, err := e.load(ctx)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	var compiled []*compiledRule
+	for _, r := range e.file {
+		c, err := e.compile(r)
+		if err != nil {
+			continue
+		}
+		names[r.Name] = true
+		compiled = append(compiled, c)
+	}
+	for _, r := range stored {
+		if names[r.Name] {
+			log.Printf("Skipping stored rule %q: a rule in the rules file has the same name", r.Name)
+			continue
+		}
+		c, err := e.compile(r)
+		if err != nil {
+			log.Printf("Skipping stored rule: %v", err)
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+
+	e.mu.Lock()
+	e.compiled = compiled
+	e.mu.Unlock()
+	return nil
+}
+
+// Run reloads the database rules every interval until ctx is done, so rules
+// changed through another replica take effect
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	reload := func() {
+		if err := e.Reload(ctx); err != nil {
+			log.Printf("Failed to reload rules: %v", err)
+		}
+	}
+	reload()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reload()
+		}
+	}
+}
+
+// Rules returns the rules in evaluation order
+func (e *Engine) Rules() []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	rules := make([]Rule, 0, len(e.compiled))
+	for _, c := range e.compiled {
+		rules = append(rules, c.Rule)
+	}
+	return rules
+}
+
+// FileRule reports whether name is the name of a rule in the rules file,
+// which cannot be changed through the database
+func (e *Engine) FileRule(name string) bool {
+	for _, r := range e.file {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Match is a rule whose condition held for an event
+type Match struct {
+	rule *compiledRule
+}
+
+// Name returns the name of the matched rule
+func (m Match) Name() string {
+	return m.rule.Name
+}
+
+// Decision is the outcome of evaluating the rules on an event
+type Decision struct {
+	Matches []Match
+	// Drop is set when a matching rule dropped the event
+	Drop bool
+	// Errors holds the rules whose condition failed to evaluate, which
+	// are treated as not matching
+	Errors []error
+}
+
+// Evaluate runs the rules on an event in order, stopping after the first
+// matching rule with a drop action
+func (e *Engine) Evaluate(event forwarder.Event) Decision {
+	var decision Decision
+	e.mu.RLock()
+	compiled := e.compiled
+	e.mu.RUnlock()
+	if len(compiled) == 0 {
+		return decision
+	}
+
+	vars, err := event.Variables()
+	if err != nil {
+		decision.Errors = append(decision.Errors, err)
+		return decision
+	}
+
+	for _, c := range compiled {
+		matched, err := c.program.Eval(vars)
+		if err != nil {
+			decision.Errors = append(decision.Errors, fmt.Errorf("rule %q: %w", c.Name, err))
+			continue
+		}
+		if !matched {
+			continue
+		}
+		decision.Matches = append(decision.Matches, Match{rule: c})
+		for _, action := range c.Actions {
+			if action.Type == ActionDrop {
+				decision.Drop = true
+			}
+		}
+		if decision.Drop {
+			break
+		}
+	}
+	return decision
+}
+
+// Explanation is the outcome of one rule on an event
+type Explanation struct {
+	Rule      string `json:"rule"`
+	Source    string `json:"source"`
+	Condition string `json:"condition"`
+	Matched   bool   `json:"matched"`
+	// Skipped is set for the rules after one that dropped the event
+	Skipped bool     `json:"skipped,omitempty"`
+	Actions []string `json:"actions,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Explain evaluates every rule on an event like Evaluate, without running
+// any action, and reports the outcome of each
+func (e *Engine) Explain(event forwarder.Event) ([]Explanation, error) {
+	e.mu.RLock()
+	compiled := e.compiled
+	e.mu.RUnlock()
+	if len(compiled) == 0 {
+		return nil, nil
+	}
+
+	vars, err := event.Variables()
+	if err != nil {
+		return nil, err
+	}
+
+	var explanations []Explanation
+	dropped := false
+	for _, c := range compiled {
+		explanation := Explanation{Rule: c.Name, Source: c.Source, Condition: c.Condition, Skipped: dropped}
+		for _, action := range c.Actions {
+			explanation.Actions = append(explanation.Actions, action.Type)
+		}
+		if !dropped {
+			matched, err := c.program.Eval(vars)
+			if err != nil {
+				explanation.Error = err.Error()
+			}
+			explanation.Matched = matched && err == nil
+			dropped = explanation.Matched && slices.Contains(explanation.Actions, ActionDrop)
+		}
+		explanations = append(explanations, explanation)
+	}
+	return explanations, nil
+}
+
+// Notification is the payload notify actions send
+type Notification struct {
+	Rule       string    `json:"rule"`
+	Message    string    `json:"message,omitempty"`
+	DeliveryID string    `json:"delivery_id"`
+	EventType  string    `json:"event_type"`
+	Action     string    `json:"action,omitempty"`
+	Repository string    `json:"repository,omitempty"`
+	Sender     string    `json:"sender,omitempty"`
+	MatchedAt  time.Time `json:"matched_at"`
+}
+
+// Apply runs the actions of the matched rules. Channel failures are logged
+// like those of the forwarders; labelling and commit status failures are
+// returned so the event can be retried.
+func (e *Engine) Apply(ctx context.Context, event forwarder.Event, decision Decision) error {
+	var errs []error
+	for _, match := range decision.Matches {
+		c := match.rule
+		for i, action := range c.Actions {
+			switch action.Type {
+			case ActionForward, ActionDiscord, ActionTeams:
+				forwarder.ForwardAll(ctx, c.channels[i:i+1], event)
+			case ActionNotify:
+				payload, err := json.Marshal(Notification{
+					Rule:       c.Name,
+					Message:    action.Message,
+					DeliveryID: event.DeliveryID,
+					EventType:  event.EventType,
+					Action:     event.Action,
+					Repository: event.Repository,
+					Sender:     event.Sender,
+					MatchedAt:  time.Now().UTC(),
+				})
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				notification := event
+				notification.Payload = payload
+				notification.Checksum = ""
+				forwarder.ForwardAll(ctx, c.channels[i:i+1], notification)
+			case ActionLabel:
+				if err := e.label(ctx, c.Name, event, action.Labels); err != nil {
+					errs = append(errs, fmt.Errorf("rule %q: %w", c.Name, err))
+				}
+			case ActionSlack:
+				channel := c.channels[i]
+				if channel == nil {
+					bot, err := chat.NewSlackBot(e.slackToken, action.Channel, c.templates[i])
+					if err != nil {
+						log.Printf("Rule %q cannot post to Slack (delivery: %s): %v", c.Name, event.DeliveryID, err)
+						continue
+					}
+					if e.wrap != nil {
+						bot.WrapTransport(e.wrap)
+					}
+					channel = bot
+				}
+				forwarder.ForwardAll(ctx, []forwarder.Forwarder{channel}, event)
+			case ActionEmail:
+				e.email(ctx, c, i, event)
+			case ActionStatus:
+				if err := e.status(ctx, c.Name, event, action); err != nil {
+					errs = append(errs, fmt.Errorf("rule %q: %w", c.Name, err))
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// email sends the email of the action at index i of a rule, or adds the
+// event to its digest. Failures are logged like channel failures.
+func (e *Engine) email(ctx context.Context, c *compiledRule, i int, event forwarder.Event) {
+	if e.mailer == nil {
+		log.Printf("Rule %q cannot send email (delivery: %s): SMTP_HOST is not configured", c.Name, event.DeliveryID)
+		return
+	}
+	vars, err := event.Variables()
+	if err != nil {
+		log.Printf("Rule %q cannot send email (delivery: %s): %v", c.Name, event.DeliveryID, err)
+		return
+	}
+	spec := c.emails[i]
+	if spec.Every > 0 {
+		e.digests.Add(fmt.Sprintf("%s#%d", c.Name, i), c.Name, *spec, vars, time.Now())
+		return
+	}
+	subject, body, err := spec.Template.Render(vars)
+	if err == nil && body != "" {
+		err = e.mailer.Send(ctx, spec.To, subject, body)
+	}
+	if err != nil {
+		log.Printf("Rule %q failed to send email (delivery: %s): %v", c.Name, event.DeliveryID, err)
+	}
+}
+
+// label adds labels to the issue or pull request of an event. Events about
+// neither are skipped, as are all events without a labeler.
+func (e *Engine) label(ctx context.Context, rule string, event forwarder.Event, labels []string) error {
+	if e.labeler == nil {
+		log.Printf("Rule %q cannot label (delivery: %s): no GitHub API credentials", rule, event.DeliveryID)
+		return nil
+	}
+	number := issueNumber(event.Payload)
+	if number == 0 || event.Repository == "" {
+		log.Printf("Rule %q cannot label %s event (delivery: %s): no issue or pull request", rule, event.EventType, event.DeliveryID)
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	return e.labeler(ctx, event.Repository, number, labels)
+}
+
+// status sets the commit status of a status action on the commit of an
+// event. Events about no commit are skipped, as are all events without a
+// status setter.
+func (e *Engine) status(ctx context.Context, rule string, event forwarder.Event, action Action) error {
+	if e.statuses == nil {
+		log.Printf("Rule %q cannot set a commit status (delivery: %s): no GitHub API credentials", rule, event.DeliveryID)
+		return nil
+	}
+	sha := commitSHA(event.EventType, event.Payload)
+	if sha == "" || event.Repository == "" {
+		log.Printf("Rule %q cannot set a commit status for %s event (delivery: %s): no commit", rule, event.EventType, event.DeliveryID)
+		return nil
+	}
+	status := github.CommitStatus{
+		State:       action.State,
+		Context:     action.Context,
+		Description: action.Message,
+		TargetURL:   action.URL,
+	}
+	if status.State == "" {
+		status.State = github.StateSuccess
+	}
+	if status.Context == "" {
+		status.Context = DefaultStatusContext + rule
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	return e.statuses(ctx, event.Repository, sha, status)
+}
+
+// commitSHA returns the commit an event is about, or "" for events about no
+// commit and pushes deleting a branch
+func commitSHA(eventType string, payload []byte) string {
+	var event struct {
+		After       string `json:"after"`
+		Deleted     bool   `json:"deleted"`
+		SHA         string `json:"sha"`
+		PullRequest struct {
+			Head struct {
+				SHA string `json:"sha"`
+			} `json:"head"`
+		} `json:"pull_request"`
+		CheckSuite struct {
+			HeadSHA string `json:"head_sha"`
+		} `json:"check_suite"`
+		CheckRun struct {
+			HeadSHA string `json:"head_sha"`
+		} `json:"check_run"`
+		WorkflowRun struct {
+			HeadSHA string `json:"head_sha"`
+		} `json:"workflow_run"`
+		Deployment struct {
+			SHA string `json:"sha"`
+		} `json:"deployment"`
+	}
+	if json.Unmarshal(payload, &event) != nil {
+		return ""
+	}
+	var sha string
+	switch eventType {
+	case "push":
+		if !event.Deleted {
+			sha = event.After
+		}
+	case "pull_request", "pull_request_review", "pull_request_review_comment":
+		sha = event.PullRequest.Head.SHA
+	case "check_suite":
+		sha = event.CheckSuite.HeadSHA
+	case "check_run":
+		sha = event.CheckRun.HeadSHA
+	case "workflow_run":
+		sha = event.WorkflowRun.HeadSHA
+	case "deployment", "deployment_status":
+		sha = event.Deployment.SHA
+	case "status":
+		sha = event.SHA
+	}
+	if strings.Trim(sha, "0") == "" {
+		return ""
+	}
+	return sha
+}
+
+// issueNumber returns the number of the issue or pull request an event is
+// about, or 0
+func issueNumber(payload []byte) int {
+	var event struct {
+		Number int `json:"number"`
+		Issue  *struct {
+			Number int `json:"number"`
+		} `json:"issue"`
+		PullRequest *struct {
+			Number int `json:"number"`
+		} `json:"pull_request"`
+	}
+	if json.Unmarshal(payload, &event) != nil {
+		return 0
+	}
+	switch {
+	case event.Issue != nil && event.Issue.Number > 0:
+		return event.Issue.Number
+	case event.PullRequest != nil && event.PullRequest.Number > 0:
+		return event.PullRequest.Number
+	}
+	return event.Number
+}
+
+// Filter forwards the events matching a condition, such as those posted to
+// the Slack channel of the server configuration
+type Filter struct {
+	program *Program
+	next    forwarder.Forwarder
+}
+
+// NewFilter creates a forwarder passing the events matching condition to
+// next. An empty condition matches every event.
+func NewFilter(condition string, next forwarder.Forwarder) (*Filter, error) {
+	f := &Filter{next: next}
+	if condition != "" {
+		program, err := Compile(condition)
+		if err != nil {
+			return nil, err
+		}
+		f.program = program
+	}
+	return f, nil
+}
+
+// Name identifies the forwarder events are passed to
+func (f *Filter) Name() string {
+	return f.next.Name()
+}
+
+// Forward passes the event on if it matches. Events the condition fails to
+// evaluate on are not passed on and the failure is returned.
+func (f *Filter) Forward(ctx context.Context, event forwarder.Event) error {
+	if f.program != nil {
+		vars, err := event.Variables()
+		if err != nil {
+			return err
+		}
+		matched, err := f.program.Eval(vars)
+		if err != nil || !matched {
+			return err
+		}
+	}
+	return f.next.Forward(ctx, event)
+}
@@ -0,0 +1,319 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/github"
+)
+
+func writeRules(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	path := writeRules(t, `
+rules:
+  - name: large-prs
+    condition: event == "pull_request" && payload.pull_request.additions > 500
+    actions:
+      - type: label
+        labels: [size/large]
+      - type: notify
+        url: https://chat.example.com/reviews
+        message: Large pull request opened
+  - name: ignore-bots
+    condition: sender.endsWith("[bot]")
+    actions:
+      - type: drop
+`)
+	rules, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rules) != 2 || rules[0].Name != "large-prs" || len(rules[0].Actions) != 2 || rules[1].Source != SourceFile {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	for name, content := range map[string]string{
+		"unknown field":  "rules:\n  - name: a\n    when: event == 'push'\n    actions: [{type: drop}]\n",
+		"bad condition":  "rules:\n  - name: a\n    condition: event ==\n    actions: [{type: drop}]\n",
+		"no actions":     "rules:\n  - name: a\n    condition: event == 'push'\n",
+		"unknown action": "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: page}]\n",
+		"bad url":        "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: forward, url: 'ftp://example.com'}]\n",
+		"no labels":      "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: label}]\n",
+		"bad name":       "rules:\n  - name: a b\n    condition: event == 'push'\n    actions: [{type: drop}]\n",
+		"slack target":   "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: slack}]\n",
+		"slack template": "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: slack, channel: '#ci', message: '{{.event'}]\n",
+		"discord url":    "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: discord}]\n",
+		"teams template": "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: teams, url: 'https://example.com/webhook', message: '{{.event'}]\n",
+		"email to":       "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: email}]\n",
+		"email digest":   "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: email, to: [oncall@example.com], digest: 10s}]\n",
+		"status state":   "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: status, state: passed}]\n",
+		"status url":     "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: status, url: 'ftp://example.com'}]\n",
+		"duplicate":      "rules:\n  - name: a\n    condition: event == 'push'\n    actions: [{type: drop}]\n  - name: a\n    condition: event == 'push'\n    actions: [{type: drop}]\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadFile(writeRules(t, content)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	file := []Rule{
+		{Name: "bots", Condition: `sender.endsWith("[bot]")`, Actions: []Action{{Type: ActionDrop}}},
+		{Name: "pushes", Condition: `event == "push"`, Actions: []Action{{Type: ActionLabel, Labels: []string{"x"}}}},
+	}
+	engine := NewEngine(file, func(ctx context.Context) ([]Rule, error) {
+		return []Rule{
+			{Name: "bots", Condition: `true`, Actions: []Action{{Type: ActionDrop}}},
+			{Name: "main", Condition: `payload.ref == "refs/heads/main"`, Actions: []Action{{Type: ActionLabel, Labels: []string{"y"}}}},
+			{Name: "broken", Condition: `event ==`, Actions: []Action{{Type: ActionDrop}}},
+		}, nil
+	})
+	if err := engine.Reload(context.Background()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rules := engine.Rules(); len(rules) != 3 || rules[2].Name != "main" {
+		t.Fatalf("Expected the file rules then the valid stored rules, got %+v", rules)
+	}
+	if !engine.FileRule("bots") || engine.FileRule("main") {
+		t.Error("Expected only file rules to be reported as file rules")
+	}
+
+	push := forwarder.Event{DeliveryID: "d1", EventType: "push", Sender: "octocat", Payload: []byte(`{"ref":"refs/heads/main"}`)}
+	decision := engine.Evaluate(push)
+	if decision.Drop || len(decision.Matches) != 2 || decision.Matches[1].Name() != "main" {
+		t.Errorf("Unexpected decision: %+v", decision)
+	}
+
+	push.Sender = "dependabot[bot]"
+	decision = engine.Evaluate(push)
+	if !decision.Drop || len(decision.Matches) != 1 || decision.Matches[0].Name() != "bots" {
+		t.Errorf("Expected the drop rule to stop later rules, got %+v", decision)
+	}
+
+	issue := forwarder.Event{DeliveryID: "d2", EventType: "issues", Sender: "octocat", Payload: []byte(`{"action":"opened"}`)}
+	decision = engine.Evaluate(issue)
+	if len(decision.Matches) != 0 || len(decision.Errors) != 1 {
+		t.Errorf("Expected the main rule to fail on a payload without ref, got %+v", decision)
+	}
+}
+
+func TestEngine_Explain(t *testing.T) {
+	engine := NewEngine([]Rule{
+		{Name: "bots", Condition: `sender.endsWith("[bot]")`, Actions: []Action{{Type: ActionDrop}}},
+		{Name: "main", Condition: `payload.ref == "refs/heads/main"`, Actions: []Action{{Type: ActionLabel, Labels: []string{"y"}}}},
+	}, nil)
+
+	explanations, err := engine.Explain(forwarder.Event{EventType: "issues", Sender: "octocat", Payload: []byte(`{"action":"opened"}`)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(explanations) != 2 || explanations[0].Matched || explanations[1].Matched || explanations[1].Error == "" {
+		t.Errorf("Expected no match and an error for the main rule, got %+v", explanations)
+	}
+
+	explanations, _ = engine.Explain(forwarder.Event{EventType: "push", Sender: "dependabot[bot]", Payload: []byte(`{"ref":"refs/heads/main"}`)})
+	if !explanations[0].Matched || explanations[0].Actions[0] != ActionDrop || !explanations[1].Skipped || explanations[1].Matched {
+		t.Errorf("Expected the drop rule to skip the main rule, got %+v", explanations)
+	}
+}
+
+func TestEngine_Apply(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received = map[string][]byte{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received[r.URL.Path] = body
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	var labelled []string
+	engine := NewEngine([]Rule{{
+		Name:      "triage",
+		Condition: `event == "issues"`,
+		Actions: []Action{
+			{Type: ActionForward, URL: server.URL + "/forward"},
+			{Type: ActionNotify, URL: server.URL + "/notify", Message: "New issue"},
+			{Type: ActionLabel, Labels: []string{"triage"}},
+			{Type: ActionSlack, URL: server.URL + "/slack", Message: "New issue #{{.payload.issue.number}} in {{.repository}}"},
+			{Type: ActionSlack, Channel: "#triage"},
+			{Type: ActionDiscord, URL: server.URL + "/discord"},
+			{Type: ActionTeams, URL: server.URL + "/teams", Message: "Issue {{.payload.issue.number}}"},
+		},
+	}}, nil).WithLabeler(func(ctx context.Context, repo string, number int, labels []string) error {
+		labelled = append(labelled, repo, strconv.Itoa(number), labels[0])
+		return nil
+	})
+
+	event := forwarder.Event{DeliveryID: "d1", EventType: "issues", Repository: "octo-org/hello-world", Payload: []byte(`{"issue":{"number":7}}`)}
+	if err := engine.Apply(context.Background(), event, engine.Evaluate(event)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if string(received["/forward"]) != string(event.Payload) {
+		t.Errorf("Expected the payload to be forwarded, got %s", received["/forward"])
+	}
+	var notification Notification
+	if err := json.Unmarshal(received["/notify"], &notification); err != nil || notification.Rule != "triage" || notification.Message != "New issue" || notification.DeliveryID != "d1" {
+		t.Errorf("Unexpected notification: %s", received["/notify"])
+	}
+	var message map[string]string
+	if err := json.Unmarshal(received["/slack"], &message); err != nil || message["text"] != "New issue #7 in octo-org/hello-world" {
+		t.Errorf("Unexpected Slack message: %s", received["/slack"])
+	}
+	var content map[string]interface{}
+	if err := json.Unmarshal(received["/discord"], &content); err != nil || content["content"] != "issues in octo-org/hello-world" {
+		t.Errorf("Unexpected Discord message: %s", received["/discord"])
+	}
+	var card map[string]string
+	if err := json.Unmarshal(received["/teams"], &card); err != nil || card["text"] != "Issue 7" {
+		t.Errorf("Unexpected Teams card: %s", received["/teams"])
+	}
+	if len(labelled) != 3 || labelled[0] != "octo-org/hello-world" || labelled[1] != "7" || labelled[2] != "triage" {
+		t.Errorf("Unexpected labels: %v", labelled)
+	}
+
+	failing := errors.New("forbidden")
+	engine.WithLabeler(func(ctx context.Context, repo string, number int, labels []string) error { return failing })
+	if err := engine.Apply(context.Background(), event, engine.Evaluate(event)); !errors.Is(err, failing) {
+		t.Errorf("Expected the labelling error, got %v", err)
+	}
+}
+
+func TestEngine_Apply_Status(t *testing.T) {
+	var statuses []github.CommitStatus
+	engine := NewEngine([]Rule{{
+		Name:      "received",
+		Condition: `event == "push"`,
+		Actions: []Action{
+			{Type: ActionStatus},
+			{Type: ActionStatus, Context: "ci/choochoo", State: github.StatePending, Message: "Queued", URL: "https://choochoo.example.com"},
+		},
+	}}, nil).WithStatusSetter(func(ctx context.Context, repo, sha string, status github.CommitStatus) error {
+		if repo != "octo-org/hello-world" || sha != "abc123" {
+			t.Errorf("Unexpected commit %s@%s", repo, sha)
+		}
+		statuses = append(statuses, status)
+		return nil
+	})
+
+	event := forwarder.Event{DeliveryID: "d1", EventType: "push", Repository: "octo-org/hello-world", Payload: []byte(`{"after":"abc123"}`)}
+	if err := engine.Apply(context.Background(), event, engine.Evaluate(event)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := []github.CommitStatus{
+		{State: github.StateSuccess, Context: "choochoo/received"},
+		{State: github.StatePending, Context: "ci/choochoo", Description: "Queued", TargetURL: "https://choochoo.example.com"},
+	}
+	if !slices.Equal(statuses, want) {
+		t.Errorf("Unexpected statuses: %+v", statuses)
+	}
+
+	deleted := event
+	deleted.Payload = []byte(`{"after":"0000000000000000000000000000000000000000","deleted":true}`)
+	statuses = nil
+	if err := engine.Apply(context.Background(), deleted, engine.Evaluate(deleted)); err != nil || len(statuses) != 0 {
+		t.Errorf("Expected a deleted branch to be skipped, got %+v, %v", statuses, err)
+	}
+
+	failing := errors.New("forbidden")
+	engine.WithStatusSetter(func(ctx context.Context, repo, sha string, status github.CommitStatus) error { return failing })
+	if err := engine.Apply(context.Background(), event, engine.Evaluate(event)); !errors.Is(err, failing) {
+		t.Errorf("Expected the commit status error, got %v", err)
+	}
+}
+
+func TestCommitSHA(t *testing.T) {
+	for _, tc := range []struct {
+		eventType, payload, want string
+	}{
+		{"push", `{"after":"a1"}`, "a1"},
+		{"pull_request", `{"pull_request":{"head":{"sha":"b2"}}}`, "b2"},
+		{"check_run", `{"check_run":{"head_sha":"c3"}}`, "c3"},
+		{"status", `{"sha":"d4"}`, "d4"},
+		{"deployment_status", `{"deployment":{"sha":"e5"}}`, "e5"},
+		{"issues", `{"issue":{"number":3}}`, ""},
+		{"push", `not json`, ""},
+	} {
+		if got := commitSHA(tc.eventType, []byte(tc.payload)); got != tc.want {
+			t.Errorf("Expected %q for %s %s, got %q", tc.want, tc.eventType, tc.payload, got)
+		}
+	}
+}
+
+func TestIssueNumber(t *testing.T) {
+	for payload, want := range map[string]int{
+		`{"issue":{"number":3}}`:                   3,
+		`{"number":5,"pull_request":{"number":5}}`: 5,
+		`{"pull_request":{"number":8}}`:            8,
+		`{"ref":"refs/heads/main"}`:                0,
+		`not json`:                                 0,
+	} {
+		if got := issueNumber([]byte(payload)); got != want {
+			t.Errorf("Expected %d for %s, got %d", want, payload, got)
+		}
+	}
+}
+
+type recordingForwarder struct {
+	events []forwarder.Event
+}
+
+func (r *recordingForwarder) Name() string { return "recording" }
+
+func (r *recordingForwarder) Forward(ctx context.Context, event forwarder.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestFilter(t *testing.T) {
+	rec := &recordingForwarder{}
+	filter, err := NewFilter(`event == "push" && payload.ref == "refs/heads/main"`, rec)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, payload := range []string{`{"ref":"refs/heads/main"}`, `{"ref":"refs/heads/dev"}`} {
+		if err := filter.Forward(context.Background(), forwarder.Event{EventType: "push", Payload: []byte(payload)}); err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	}
+	if err := filter.Forward(context.Background(), forwarder.Event{EventType: "push", Payload: []byte(`{}`)}); err == nil {
+		t.Error("Expected an evaluation error for a push without ref")
+	}
+	if len(rec.events) != 1 || filter.Name() != "recording" {
+		t.Errorf("Expected only the push to main to be forwarded, got %+v", rec.events)
+	}
+
+	all, _ := NewFilter("", rec)
+	all.Forward(context.Background(), forwarder.Event{EventType: "issues", Payload: []byte(`{}`)})
+	if len(rec.events) != 2 {
+		t.Errorf("Expected an empty condition to match every event, got %+v", rec.events)
+	}
+
+	if _, err := NewFilter("event ==", rec); !errors.Is(err, ErrInvalidCondition) {
+		t.Errorf("Expected ErrInvalidCondition, got %v", err)
+	}
+}
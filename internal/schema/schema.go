@@ -0,0 +1,241 @@
+// Package schema generates JSON Schemas of the configuration formats: the
+// server configuration file, config bundles, repository settings files and
+// routes. The schemas are derived from the Go types the formats are parsed
+// into, so they cannot drift from what the server accepts.
+package schema
+
+//go:generate go run ../../cmd/schemagen -dir ../../schemas
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/config"
+	"github.com/deedubs/choochoo/internal/retention"
+	"github.com/deedubs/choochoo/internal/settings"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// Draft is the JSON Schema dialect of the generated schemas
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Names of the published schemas
+const (
+	Config   = "config.schema.json"
+	Bundle   = "bundle.schema.json"
+	RepoFile = "choochoo.schema.json"
+	Route    = "route.schema.json"
+)
+
+// ErrNotFound is returned when generating a schema that does not exist
+var ErrNotFound = errors.New("schema not found")
+
+// Schema is a JSON Schema or subschema
+type Schema map[string]any
+
+// durationPattern matches the durations time.ParseDuration accepts
+const durationPattern = `^-?([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$|^0$`
+
+// Names lists the published schemas, sorted
+func Names() []string {
+	names := []string{Config, Bundle, RepoFile, Route}
+	sort.Strings(names)
+	return names
+}
+
+// Generate returns the schema name as indented JSON
+func Generate(name string) ([]byte, error) {
+	var schema Schema
+	switch name {
+	case Config:
+		schema = configSchema()
+	case Bundle:
+		schema = settingsSchema(reflect.TypeOf(settings.Bundle{}), "choochoo config bundle",
+			"Every dynamic setting of an instance, as exported and applied by choochooctl.")
+	case RepoFile:
+		schema = settingsSchema(reflect.TypeOf(settings.Overlay{}), "choochoo repository settings file",
+			"The "+settings.RepoFile+" of a repository, an override of the instance settings.")
+	case Route:
+		schema = settingsSchema(reflect.TypeOf(settings.Route{}), "choochoo route",
+			"A route sending matching events to a channel URL.")
+	default:
+		return nil, ErrNotFound
+	}
+	schema["$schema"] = Draft
+	schema["$id"] = "/schemas/" + name
+
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// configSchema describes the server configuration file, whose keys are the
+// lowercase names of the environment variables. Lists may be written as
+// arrays and numbers as strings, as with environment variables.
+func configSchema() Schema {
+	properties := Schema{}
+	t := reflect.TypeOf(config.Config{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, env := field.Tag.Get("key"), field.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		var property Schema
+		switch field.Type {
+		case reflect.TypeOf(time.Duration(0)):
+			property = Schema{"$ref": "#/$defs/duration"}
+		default:
+			switch field.Type.Kind() {
+			case reflect.Bool:
+				property = Schema{"type": "boolean"}
+			case reflect.Int, reflect.Int64:
+				property = Schema{"type": []string{"integer", "string"}, "pattern": "^-?[0-9]+$"}
+			default:
+				property = Schema{"$ref": "#/$defs/value"}
+			}
+		}
+		property["description"] = "Same as the " + env + " environment variable"
+		properties[key] = property
+	}
+
+	return Schema{
+		"title":                "choochoo server configuration",
+		"description":          "The YAML or TOML file passed with -config or CHOOCHOO_CONFIG.",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+		"$defs": Schema{
+			"duration": Schema{"type": "string", "pattern": durationPattern},
+			"value": Schema{
+				"anyOf": []Schema{
+					{"type": []string{"string", "number", "boolean"}},
+					{"type": "array", "items": Schema{"type": []string{"string", "number", "boolean"}}},
+				},
+			},
+		},
+	}
+}
+
+// settingsSchema describes a settings type by its yaml tags, with the
+// structs it uses as definitions
+func settingsSchema(t reflect.Type, title, description string) Schema {
+	g := &generator{defs: Schema{}}
+	schema := g.object(t)
+	schema["title"] = title
+	schema["description"] = description
+	if len(g.defs) > 0 {
+		schema["$defs"] = g.defs
+	}
+	return schema
+}
+
+// generator collects the definitions of the structs a schema uses
+type generator struct {
+	defs Schema
+}
+
+// typeSchema returns the schema of a type, referencing the definition of
+// structs
+func (g *generator) typeSchema(t reflect.Type) Schema {
+	switch t.Kind() {
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = g.object(t)
+		}
+		return Schema{"$ref": "#/$defs/" + t.Name()}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	case reflect.Slice:
+		return Schema{"type": "array", "items": g.typeSchema(t.Elem())}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return Schema{"type": "integer"}
+	}
+	return Schema{"type": "string"}
+}
+
+// object describes a struct by its yaml tags. Fields without omitempty are
+// required.
+func (g *generator) object(t reflect.Type) Schema {
+	properties := Schema{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		property := g.typeSchema(field.Type)
+		for name, value := range fieldSchemas[t.Name()+"."+key] {
+			property[name] = value
+		}
+		if name, ok := settingNames[t.Name()+"."+key]; ok {
+			property["description"] = "Same as the " + name + " setting"
+		}
+		properties[key] = property
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, key)
+		}
+	}
+
+	schema := Schema{"type": "object", "properties": properties, "additionalProperties": false}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fieldSchemas refines the schemas of fields whose Go type allows more than
+// the server accepts, keyed by struct name and key
+var fieldSchemas = map[string]Schema{
+	"Bundle.version": {"const": settings.BundleVersion},
+	"Route.match": {
+		"description": "What events are matched by: a severity, category, column, kind or repository pattern, depending on the route list",
+	},
+	"Route.url": {
+		"description": "The channel URL matching events are sent to. In an overlay or override, an empty url removes the route with the same match.",
+	},
+	"Policies.retention_mode":            {"enum": []string{retention.ModeDelete, retention.ModeArchive}},
+	"Policies.retention_interval":        {"pattern": durationPattern},
+	"Policies.access_review_interval":    {"pattern": durationPattern},
+	"Policies.community_digest_interval": {"pattern": durationPattern},
+	"Policies.retention_batch_size":      {"minimum": 1},
+	"Flags.ignored_events":               {"items": Schema{"enum": supportedEvents()}, "uniqueItems": true},
+	"Bundle.overrides": {
+		"description":   "Overlays of organizations (org), repositories (org/repo) and branches (org/repo@branch)",
+		"propertyNames": Schema{"pattern": `^[^/@]+(/[^/@]+(@.+)?)?$`},
+	},
+}
+
+// settingNames maps the struct fields settings are kept in to the names of
+// the settings
+var settingNames = func() map[string]string {
+	structs := map[string]string{"routes": "Routes", "policies": "Policies", "flags": "Flags"}
+	names := make(map[string]string)
+	for _, name := range settings.Names() {
+		if keys := settings.Keys(name); len(keys) == 2 {
+			names[structs[keys[0]]+"."+keys[1]] = name
+		}
+	}
+	return names
+}()
+
+// supportedEvents lists the event types that are stored, sorted
+func supportedEvents() []string {
+	events := make([]string, 0, len(webhook.SupportedEventTypes))
+	for eventType, supported := range webhook.SupportedEventTypes {
+		if supported {
+			events = append(events, eventType)
+		}
+	}
+	sort.Strings(events)
+	return events
+}
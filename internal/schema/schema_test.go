@@ -0,0 +1,86 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGenerate_UpToDate tests that the schemas kept in the repository match
+// the generated ones
+func TestGenerate_UpToDate(t *testing.T) {
+	for _, name := range Names() {
+		generated, err := Generate(name)
+		if err != nil {
+			t.Fatalf("Generate(%s) failed: %v", name, err)
+		}
+		kept, err := os.ReadFile(filepath.Join("..", "..", "schemas", name))
+		if err != nil {
+			t.Fatalf("Failed to read kept schema: %v", err)
+		}
+		if !bytes.Equal(generated, kept) {
+			t.Errorf("schemas/%s is out of date; run go generate ./internal/schema", name)
+		}
+	}
+}
+
+func TestGenerate_Config(t *testing.T) {
+	data, err := Generate(Config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var schema struct {
+		ID         string                    `json:"$id"`
+		Properties map[string]map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Failed to decode schema: %v", err)
+	}
+	if schema.ID != "/schemas/config.schema.json" {
+		t.Errorf("Unexpected $id %q", schema.ID)
+	}
+	if port := schema.Properties["port"]; port["$ref"] != "#/$defs/value" || port["description"] != "Same as the PORT environment variable" {
+		t.Errorf("Unexpected port schema %v", port)
+	}
+	if interval := schema.Properties["retention_interval"]; interval["$ref"] != "#/$defs/duration" {
+		t.Errorf("Expected retention_interval to be a duration, got %v", interval)
+	}
+	if lint := schema.Properties["repo_config_lint"]; lint["type"] != "boolean" {
+		t.Errorf("Expected repo_config_lint to be a boolean, got %v", lint)
+	}
+}
+
+func TestGenerate_RepoFile(t *testing.T) {
+	data, err := Generate(RepoFile)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var schema struct {
+		Properties map[string]map[string]string `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]map[string]any `json:"properties"`
+			Required   []string                  `json:"required"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Failed to decode schema: %v", err)
+	}
+	if ref := schema.Properties["routes"]["$ref"]; ref != "#/$defs/Routes" {
+		t.Errorf("Expected routes to reference Routes, got %q", ref)
+	}
+	if description := schema.Defs["Routes"].Properties["security_alerts"]["description"]; description != "Same as the SECURITY_ALERT_ROUTES setting" {
+		t.Errorf("Unexpected security_alerts description %v", description)
+	}
+	if required := schema.Defs["Route"].Required; len(required) != 2 {
+		t.Errorf("Expected match and url to be required, got %v", required)
+	}
+}
+
+func TestGenerate_NotFound(t *testing.T) {
+	if _, err := Generate("rules.schema.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
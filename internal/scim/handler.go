@@ -0,0 +1,668 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+)
+
+// Prefix is the path the SCIM endpoints are served under, the base URL to
+// configure in the identity provider
+const Prefix = "/scim/v2"
+
+// maxResults is the most resources a list returns
+const maxResults = 200
+
+// maxBodyBytes limits the size of request bodies
+const maxBodyBytes = 1 << 20
+
+// SCIM error types (RFC 7644 section 3.12)
+const (
+	errInvalidFilter = "invalidFilter"
+	errInvalidSyntax = "invalidSyntax"
+	errInvalidValue  = "invalidValue"
+	errUniqueness    = "uniqueness"
+)
+
+// Handler serves the SCIM Users and Groups endpoints to requests with an
+// admin-scoped token, as a provisioning client can make anyone an admin
+type Handler struct {
+	store Store
+	auth  *apitoken.Authenticator
+	mux   *http.ServeMux
+}
+
+// NewHandler creates a handler provisioning the users and groups in store
+func NewHandler(store Store, auth *apitoken.Authenticator) *Handler {
+	h := &Handler{store: store, auth: auth, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET "+Prefix+"/ServiceProviderConfig", h.handleServiceProviderConfig)
+	h.mux.HandleFunc("GET "+Prefix+"/ResourceTypes", h.handleResourceTypes)
+	h.mux.HandleFunc("GET "+Prefix+"/Users", h.handleListUsers)
+	h.mux.HandleFunc("POST "+Prefix+"/Users", h.handleCreateUser)
+	h.mux.HandleFunc("GET "+Prefix+"/Users/{id}", h.handleGetUser)
+	h.mux.HandleFunc("PUT "+Prefix+"/Users/{id}", h.handleReplaceUser)
+	h.mux.HandleFunc("PATCH "+Prefix+"/Users/{id}", h.handlePatchUser)
+	h.mux.HandleFunc("DELETE "+Prefix+"/Users/{id}", h.handleDeleteUser)
+	h.mux.HandleFunc("GET "+Prefix+"/Groups", h.handleListGroups)
+	h.mux.HandleFunc("POST "+Prefix+"/Groups", h.handleCreateGroup)
+	h.mux.HandleFunc("GET "+Prefix+"/Groups/{id}", h.handleGetGroup)
+	h.mux.HandleFunc("PUT "+Prefix+"/Groups/{id}", h.handleReplaceGroup)
+	h.mux.HandleFunc("PATCH "+Prefix+"/Groups/{id}", h.handlePatchGroup)
+	h.mux.HandleFunc("DELETE "+Prefix+"/Groups/{id}", h.handleDeleteGroup)
+	return h
+}
+
+// ServeHTTP authorizes the request and serves the SCIM endpoint it is for
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := h.auth.AuthorizeToken(w, r, apitoken.ScopeAdmin)
+	if !ok {
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	h.mux.ServeHTTP(w, r.WithContext(withOperator(ctx, token.Name)))
+}
+
+type operatorKey struct{}
+
+func withOperator(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, operatorKey{}, name)
+}
+
+// operator returns the name of the token provisioning
+func operator(r *http.Request) string {
+	name, _ := r.Context().Value(operatorKey{}).(string)
+	return name
+}
+
+func (h *Handler) handleServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	writeResource(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{"urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": maxResults},
+		"changePassword": map[string]bool{"supported": true},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{{
+			"type":        "oauthbearertoken",
+			"name":        "API token",
+			"description": "A choochoo API token with the admin scope",
+			"primary":     true,
+		}},
+	})
+}
+
+func (h *Handler) handleResourceTypes(w http.ResponseWriter, r *http.Request) {
+	resourceTypes := []map[string]interface{}{
+		{"schemas": []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"}, "id": "User", "name": "User", "endpoint": "/Users", "schema": SchemaUser},
+		{"schemas": []string{"urn:ietf:params:scim:schemas:core:2.0:ResourceType"}, "id": "Group", "name": "Group", "endpoint": "/Groups", "schema": SchemaGroup},
+	}
+	writeResource(w, http.StatusOK, listResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(resourceTypes),
+		StartIndex:   1,
+		ItemsPerPage: len(resourceTypes),
+		Resources:    resourceTypes,
+	})
+}
+
+func (h *Handler) handleListUsers(w http.ResponseWriter, r *http.Request) {
+	match, err := userFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidFilter, err.Error())
+		return
+	}
+	users, err := h.store.ListUsers(r.Context())
+	if err != nil {
+		h.fail(w, "list SCIM users", err)
+		return
+	}
+	writeList(w, r, slices.DeleteFunc(users, func(u User) bool { return !match(u) }))
+}
+
+func (h *Handler) handleGetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.store.GetUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.fail(w, "get SCIM user", err)
+		return
+	}
+	writeResource(w, http.StatusOK, user)
+}
+
+func (h *Handler) handleCreateUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := decodeUser(w, r)
+	if !ok {
+		return
+	}
+	created, err := h.store.CreateUser(r.Context(), user)
+	if err != nil {
+		h.fail(w, "create SCIM user", err)
+		return
+	}
+	log.Printf("SCIM user %s provisioned by %s", created.UserName, operator(r))
+	writeResource(w, http.StatusCreated, created)
+}
+
+func (h *Handler) handleReplaceUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := decodeUser(w, r)
+	if !ok {
+		return
+	}
+	user.ID = r.PathValue("id")
+	h.updateUser(w, r, user)
+}
+
+func (h *Handler) handlePatchUser(w http.ResponseWriter, r *http.Request) {
+	ops, ok := decodePatch(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.store.GetUser(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.fail(w, "get SCIM user", err)
+		return
+	}
+	for _, op := range ops {
+		if err := op.applyUser(&user); err != nil {
+			writeError(w, http.StatusBadRequest, errInvalidValue, err.Error())
+			return
+		}
+	}
+	if err := user.normalize(); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidValue, err.Error())
+		return
+	}
+	h.updateUser(w, r, user)
+}
+
+func (h *Handler) updateUser(w http.ResponseWriter, r *http.Request, user User) {
+	updated, err := h.store.UpdateUser(r.Context(), user)
+	if err != nil {
+		h.fail(w, "update SCIM user", err)
+		return
+	}
+	if updated.Active {
+		log.Printf("SCIM user %s updated by %s", updated.UserName, operator(r))
+	} else {
+		log.Printf("SCIM user %s deactivated by %s", updated.UserName, operator(r))
+	}
+	writeResource(w, http.StatusOK, updated)
+}
+
+func (h *Handler) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteUser(r.Context(), r.PathValue("id")); err != nil {
+		h.fail(w, "delete SCIM user", err)
+		return
+	}
+	log.Printf("SCIM user %s deprovisioned by %s", r.PathValue("id"), operator(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleListGroups(w http.ResponseWriter, r *http.Request) {
+	match, err := groupFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidFilter, err.Error())
+		return
+	}
+	groups, err := h.store.ListGroups(r.Context())
+	if err != nil {
+		h.fail(w, "list SCIM groups", err)
+		return
+	}
+	groups = slices.DeleteFunc(groups, func(g Group) bool { return !match(g) })
+	if excludesMembers(r) {
+		for i := range groups {
+			groups[i].Members = nil
+		}
+	}
+	writeList(w, r, groups)
+}
+
+func (h *Handler) handleGetGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := h.store.GetGroup(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.fail(w, "get SCIM group", err)
+		return
+	}
+	if excludesMembers(r) {
+		group.Members = nil
+	}
+	writeResource(w, http.StatusOK, group)
+}
+
+func (h *Handler) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := decodeGroup(w, r)
+	if !ok {
+		return
+	}
+	created, err := h.store.CreateGroup(r.Context(), group)
+	if err != nil {
+		h.fail(w, "create SCIM group", err)
+		return
+	}
+	log.Printf("SCIM group %s provisioned by %s", created.DisplayName, operator(r))
+	writeResource(w, http.StatusCreated, created)
+}
+
+func (h *Handler) handleReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	group, ok := decodeGroup(w, r)
+	if !ok {
+		return
+	}
+	group.ID = r.PathValue("id")
+	h.updateGroup(w, r, group)
+}
+
+func (h *Handler) handlePatchGroup(w http.ResponseWriter, r *http.Request) {
+	ops, ok := decodePatch(w, r)
+	if !ok {
+		return
+	}
+	group, err := h.store.GetGroup(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.fail(w, "get SCIM group", err)
+		return
+	}
+	for _, op := range ops {
+		if err := op.applyGroup(&group); err != nil {
+			writeError(w, http.StatusBadRequest, errInvalidValue, err.Error())
+			return
+		}
+	}
+	if err := group.normalize(); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidValue, err.Error())
+		return
+	}
+	h.updateGroup(w, r, group)
+}
+
+func (h *Handler) updateGroup(w http.ResponseWriter, r *http.Request, group Group) {
+	updated, err := h.store.UpdateGroup(r.Context(), group)
+	if err != nil {
+		h.fail(w, "update SCIM group", err)
+		return
+	}
+	log.Printf("SCIM group %s updated by %s, %d members", updated.DisplayName, operator(r), len(updated.Members))
+	writeResource(w, http.StatusOK, updated)
+}
+
+func (h *Handler) handleDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteGroup(r.Context(), r.PathValue("id")); err != nil {
+		h.fail(w, "delete SCIM group", err)
+		return
+	}
+	log.Printf("SCIM group %s deprovisioned by %s", r.PathValue("id"), operator(r))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// fail writes the SCIM error response for a store error
+func (h *Handler) fail(w http.ResponseWriter, action string, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, "", "Resource not found")
+	case errors.Is(err, ErrConflict):
+		writeError(w, http.StatusConflict, errUniqueness, "userName or displayName is already taken")
+	case errors.Is(err, ErrUnknownMember):
+		writeError(w, http.StatusBadRequest, errInvalidValue, "Group members must be provisioned users")
+	default:
+		log.Printf("Failed to %s: %v", action, err)
+		writeError(w, http.StatusInternalServerError, "", "Failed to "+action)
+	}
+}
+
+func decodeUser(w http.ResponseWriter, r *http.Request) (User, bool) {
+	// Users are active unless the identity provider says otherwise
+	user := User{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidSyntax, "Invalid JSON body")
+		return User{}, false
+	}
+	user.Schemas, user.ID, user.Groups, user.Meta = []string{SchemaUser}, "", nil, nil
+	if err := user.normalize(); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidValue, err.Error())
+		return User{}, false
+	}
+	return user, true
+}
+
+func decodeGroup(w http.ResponseWriter, r *http.Request) (Group, bool) {
+	var group Group
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidSyntax, "Invalid JSON body")
+		return Group{}, false
+	}
+	group.Schemas, group.ID, group.Meta = []string{SchemaGroup}, "", nil
+	if err := group.normalize(); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidValue, err.Error())
+		return Group{}, false
+	}
+	return group, true
+}
+
+func decodePatch(w http.ResponseWriter, r *http.Request) ([]patchOperation, bool) {
+	var patch struct {
+		Operations []patchOperation `json:"Operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		writeError(w, http.StatusBadRequest, errInvalidSyntax, "Invalid JSON body")
+		return nil, false
+	}
+	for _, op := range patch.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace", "remove":
+		default:
+			writeError(w, http.StatusBadRequest, errInvalidSyntax, fmt.Sprintf("Unknown patch operation %q", op.Op))
+			return nil, false
+		}
+	}
+	return patch.Operations, true
+}
+
+// patchOperation is one operation of a PATCH request (RFC 7644 section
+// 3.5.2). Attributes choochoo does not keep are ignored, like they are when
+// creating and replacing resources.
+type patchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (op patchOperation) remove() bool {
+	return strings.EqualFold(op.Op, "remove")
+}
+
+// attributes returns the attribute paths and values the operation sets:
+// its path, or each attribute of its value when it has none
+func (op patchOperation) attributes(schema string) (map[string]json.RawMessage, error) {
+	if op.Path != "" {
+		path := op.Path
+		if len(path) > len(schema) && strings.EqualFold(path[:len(schema)+1], schema+":") {
+			path = path[len(schema)+1:]
+		}
+		return map[string]json.RawMessage{path: op.Value}, nil
+	}
+	if op.remove() {
+		return nil, errors.New("remove operations need a path")
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(op.Value, &values); err != nil {
+		return nil, errors.New("operations without a path need an object value")
+	}
+	return values, nil
+}
+
+func (op patchOperation) applyUser(user *User) error {
+	attributes, err := op.attributes(SchemaUser)
+	if err != nil {
+		return err
+	}
+	for path, value := range attributes {
+		attribute, _, _ := strings.Cut(strings.ToLower(path), "[")
+		attribute, _, _ = strings.Cut(attribute, ".")
+		switch attribute {
+		case "active":
+			if op.remove() {
+				return errors.New("active cannot be removed")
+			}
+			if user.Active, err = decodeBool(value); err != nil {
+				return fmt.Errorf("invalid active: %w", err)
+			}
+		case "username":
+			if op.remove() {
+				return errors.New("userName cannot be removed")
+			}
+			if user.UserName, err = decodeString(value); err != nil {
+				return fmt.Errorf("invalid userName: %w", err)
+			}
+		case "displayname":
+			user.DisplayName, err = op.decodeString(value)
+		case "externalid":
+			user.ExternalID, err = op.decodeString(value)
+		case "password":
+			user.Password, err = op.decodeString(value)
+		case "emails":
+			user.Emails, err = op.decodeEmails(value)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func (op patchOperation) applyGroup(group *Group) error {
+	attributes, err := op.attributes(SchemaGroup)
+	if err != nil {
+		return err
+	}
+	for path, value := range attributes {
+		attribute, filter, _ := strings.Cut(path, "[")
+		switch strings.ToLower(attribute) {
+		case "displayname":
+			if op.remove() {
+				return errors.New("displayName cannot be removed")
+			}
+			group.DisplayName, err = decodeString(value)
+		case "externalid":
+			group.ExternalID, err = op.decodeString(value)
+		case "members":
+			err = op.applyMembers(group, strings.TrimSuffix(filter, "]"), value)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// applyMembers adds, replaces or removes the members of a group. Members to
+// remove are given by a value filter in the path, as in
+// members[value eq "2"], or as the value.
+func (op patchOperation) applyMembers(group *Group, filter string, value json.RawMessage) error {
+	var members []Ref
+	if filter != "" {
+		attribute, id, err := parseFilter(filter)
+		if err != nil || attribute != "value" {
+			return errors.New(`want a filter like value eq "id"`)
+		}
+		members = []Ref{{Value: id}}
+	} else if len(value) > 0 && string(value) != "null" {
+		if err := json.Unmarshal(value, &members); err != nil {
+			return errors.New("want a list of members")
+		}
+	}
+
+	switch {
+	case strings.EqualFold(op.Op, "replace") && filter == "":
+		group.Members = members
+	case op.remove() && filter == "" && len(members) == 0:
+		group.Members = nil
+	case op.remove():
+		group.Members = slices.DeleteFunc(group.Members, func(m Ref) bool {
+			return slices.ContainsFunc(members, func(r Ref) bool { return r.Value == m.Value })
+		})
+	default:
+		for _, member := range members {
+			if !slices.ContainsFunc(group.Members, func(m Ref) bool { return m.Value == member.Value }) {
+				group.Members = append(group.Members, member)
+			}
+		}
+	}
+	return nil
+}
+
+// decodeString decodes a string value, or clears it for remove operations
+func (op patchOperation) decodeString(value json.RawMessage) (string, error) {
+	if op.remove() {
+		return "", nil
+	}
+	return decodeString(value)
+}
+
+// decodeEmails decodes a list of emails, or a single address for paths like
+// emails[type eq "work"].value
+func (op patchOperation) decodeEmails(value json.RawMessage) ([]Email, error) {
+	if op.remove() {
+		return nil, nil
+	}
+	if address, err := decodeString(value); err == nil {
+		return []Email{{Value: address, Type: "work", Primary: true}}, nil
+	}
+	var emails []Email
+	if err := json.Unmarshal(value, &emails); err != nil {
+		return nil, errors.New("want an address or a list of emails")
+	}
+	return emails, nil
+}
+
+func decodeString(value json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return "", errors.New("want a string")
+	}
+	return s, nil
+}
+
+// decodeBool decodes a boolean, also accepting "True" and "False" strings as
+// some identity providers send them
+func decodeBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	s, err := decodeString(value)
+	if err != nil {
+		return false, errors.New("want a boolean")
+	}
+	return strconv.ParseBool(strings.ToLower(s))
+}
+
+// filterPattern matches the equality filters identity providers look
+// resources up with, such as userName eq "octocat"
+var filterPattern = regexp.MustCompile(`(?i)^\s*([a-z][a-z0-9.]*)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// parseFilter parses an equality filter, returning the lowercased attribute
+// and the value
+func parseFilter(filter string) (attribute, value string, err error) {
+	m := filterPattern.FindStringSubmatch(filter)
+	if m == nil {
+		return "", "", fmt.Errorf("unsupported filter %q, only attribute eq \"value\" is supported", filter)
+	}
+	if err := json.Unmarshal([]byte(m[2]), &value); err != nil {
+		return "", "", fmt.Errorf("invalid value in filter %q", filter)
+	}
+	return strings.ToLower(m[1]), value, nil
+}
+
+// userFilter returns the function matching the users a filter selects
+func userFilter(filter string) (func(User) bool, error) {
+	if filter == "" {
+		return func(User) bool { return true }, nil
+	}
+	attribute, value, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	switch attribute {
+	case "id":
+		return func(u User) bool { return u.ID == value }, nil
+	case "username":
+		return func(u User) bool { return strings.EqualFold(u.UserName, value) }, nil
+	case "externalid":
+		return func(u User) bool { return u.ExternalID == value }, nil
+	case "displayname":
+		return func(u User) bool { return strings.EqualFold(u.DisplayName, value) }, nil
+	case "emails", "emails.value":
+		return func(u User) bool { return strings.EqualFold(u.Email(), value) }, nil
+	}
+	return nil, fmt.Errorf("users cannot be filtered by %s", attribute)
+}
+
+// groupFilter returns the function matching the groups a filter selects
+func groupFilter(filter string) (func(Group) bool, error) {
+	if filter == "" {
+		return func(Group) bool { return true }, nil
+	}
+	attribute, value, err := parseFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	switch attribute {
+	case "id":
+		return func(g Group) bool { return g.ID == value }, nil
+	case "displayname":
+		return func(g Group) bool { return strings.EqualFold(g.DisplayName, value) }, nil
+	case "externalid":
+		return func(g Group) bool { return g.ExternalID == value }, nil
+	}
+	return nil, fmt.Errorf("groups cannot be filtered by %s", attribute)
+}
+
+// excludesMembers reports whether the request asks for groups without their
+// members, as identity providers do when they only check a group exists
+func excludesMembers(r *http.Request) bool {
+	for _, attribute := range strings.Split(r.URL.Query().Get("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attribute), "members") {
+			return true
+		}
+	}
+	return false
+}
+
+// listResponse is a page of resources (RFC 7644 section 3.4.2)
+type listResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// writeList writes the page of resources selected by the 1-based startIndex
+// and count parameters
+func writeList[T any](w http.ResponseWriter, r *http.Request, resources []T) {
+	start, count := 1, maxResults
+	if s, err := strconv.Atoi(r.URL.Query().Get("startIndex")); err == nil && s > 1 {
+		start = s
+	}
+	if c, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil {
+		count = min(max(c, 0), maxResults)
+	}
+	page := resources[min(start-1, len(resources)):]
+	page = page[:min(count, len(page))]
+	writeResource(w, http.StatusOK, listResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(resources),
+		StartIndex:   start,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	})
+}
+
+func writeResource(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a SCIM error (RFC 7644 section 3.12)
+func writeError(w http.ResponseWriter, status int, scimType, detail string) {
+	writeResource(w, status, struct {
+		Schemas  []string `json:"schemas"`
+		ScimType string   `json:"scimType,omitempty"`
+		Detail   string   `json:"detail"`
+		Status   string   `json:"status"`
+	}{[]string{SchemaError}, scimType, detail, strconv.Itoa(status)})
+}
@@ -0,0 +1,261 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+)
+
+func scimRequest(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, Prefix+path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/scim+json")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func decode[T any](t *testing.T, rr *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(rr.Body.Bytes(), &v); err != nil {
+		t.Fatalf("Invalid response %s: %v", rr.Body, err)
+	}
+	return v
+}
+
+func TestHandler_Unauthorized(t *testing.T) {
+	h := NewHandler(newMemoryStore(), apitoken.NewAuthenticator("secret", nil))
+	req := httptest.NewRequest("GET", Prefix+"/Users", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnauthorized, rr.Code)
+	}
+}
+
+func TestHandler_Users(t *testing.T) {
+	store := newMemoryStore()
+	h := NewHandler(store, apitoken.NewAuthenticator("secret", nil))
+
+	rr := scimRequest(t, h, "POST", "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "octocat@example.com",
+		"name": {"givenName": "Mona", "familyName": "Octocat"},
+		"emails": [{"value": "octocat@example.com", "type": "work", "primary": true}],
+		"password": "hunter2",
+		"title": "Mascot"
+	}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/scim+json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	created := decode[User](t, rr)
+	if created.ID == "" || created.DisplayName != "Mona Octocat" || !created.Active || created.Password != "" {
+		t.Errorf("Created user = %+v", created)
+	}
+	if strings.Contains(rr.Body.String(), "hunter2") {
+		t.Error("Response contains the password")
+	}
+
+	rr = scimRequest(t, h, "POST", "/Users", `{"userName": "OCTOCAT@example.com"}`)
+	if rr.Code != http.StatusConflict || decode[map[string]interface{}](t, rr)["scimType"] != "uniqueness" {
+		t.Errorf("Duplicate user: status %d, body %s", rr.Code, rr.Body)
+	}
+	rr = scimRequest(t, h, "POST", "/Users", `{"displayName": "Nobody"}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("User without userName: expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	rr = scimRequest(t, h, "GET", `/Users?filter=userName+eq+"Octocat@Example.com"`, "")
+	list := decode[struct {
+		TotalResults int    `json:"totalResults"`
+		Resources    []User `json:"Resources"`
+	}](t, rr)
+	if list.TotalResults != 1 || len(list.Resources) != 1 || list.Resources[0].ID != created.ID {
+		t.Errorf("Filtered users = %+v", list)
+	}
+	rr = scimRequest(t, h, "GET", `/Users?filter=title+eq+"Mascot"`, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Unsupported filter: expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	// Azure AD sends booleans as strings and patches attributes by path
+	rr = scimRequest(t, h, "PATCH", "/Users/"+created.ID, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "Replace", "path": "emails[type eq \"work\"].value", "value": "mona@example.com"}
+		]
+	}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	patched := decode[User](t, rr)
+	if patched.Active || patched.Email() != "mona@example.com" || patched.DisplayName != "Mona Octocat" {
+		t.Errorf("Patched user = %+v", patched)
+	}
+
+	// Okta replaces attributes with a value object
+	rr = scimRequest(t, h, "PATCH", "/Users/"+created.ID, `{"Operations": [{"op": "replace", "value": {"active": true, "password": "hunter3"}}]}`)
+	if rr.Code != http.StatusOK || !decode[User](t, rr).Active {
+		t.Errorf("Reactivated user: status %d, body %s", rr.Code, rr.Body)
+	}
+	if _, err := NewDirectory(store, nil).SignIn(context.Background(), "octocat@example.com", "hunter3"); err != nil {
+		t.Errorf("SignIn() with the patched password error = %v", err)
+	}
+
+	rr = scimRequest(t, h, "PUT", "/Users/"+created.ID, `{"userName": "mona@example.com", "displayName": "Mona", "active": false}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body)
+	}
+	if replaced := decode[User](t, rr); replaced.UserName != "mona@example.com" || replaced.Active || replaced.Email() != "" {
+		t.Errorf("Replaced user = %+v", replaced)
+	}
+	if _, err := NewDirectory(store, nil).SignIn(context.Background(), "mona@example.com", "hunter3"); err == nil {
+		t.Error("SignIn() of a deactivated user succeeded")
+	}
+
+	if rr := scimRequest(t, h, "DELETE", "/Users/"+created.ID, ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Delete: expected status code %d, got %d", http.StatusNoContent, rr.Code)
+	}
+	if rr := scimRequest(t, h, "GET", "/Users/"+created.ID, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Deleted user: expected status code %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
+
+func TestHandler_Groups(t *testing.T) {
+	store := newMemoryStore()
+	h := NewHandler(store, apitoken.NewAuthenticator("secret", nil))
+	var ids []string
+	for _, name := range []string{"octocat", "hubot", "monalisa"} {
+		ids = append(ids, decode[User](t, scimRequest(t, h, "POST", "/Users", `{"userName": "`+name+`"}`)).ID)
+	}
+
+	rr := scimRequest(t, h, "POST", "/Groups", `{"displayName": "Choochoo Admins", "members": [{"value": "`+ids[0]+`"}]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+	}
+	group := decode[Group](t, rr)
+
+	rr = scimRequest(t, h, "POST", "/Groups", `{"displayName": "Ghosts", "members": [{"value": "404"}]}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Unknown member: expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+
+	tests := []struct {
+		name  string
+		patch string
+		want  []string
+	}{
+		{"add", `{"op": "add", "path": "members", "value": [{"value": "` + ids[1] + `"}, {"value": "` + ids[0] + `"}]}`, []string{ids[0], ids[1]}},
+		{"remove by filter", `{"op": "remove", "path": "members[value eq \"` + ids[0] + `\"]"}`, []string{ids[1]}},
+		{"remove by value", `{"op": "remove", "path": "members", "value": [{"value": "` + ids[1] + `"}]}`, nil},
+		{"replace", `{"op": "replace", "path": "members", "value": [{"value": "` + ids[2] + `"}]}`, []string{ids[2]}},
+		{"replace without path", `{"op": "replace", "value": {"id": "` + group.ID + `", "displayName": "Choochoo Admins", "members": [{"value": "` + ids[0] + `"}]}}`, []string{ids[0]}},
+		{"remove all", `{"op": "remove", "path": "members"}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := scimRequest(t, h, "PATCH", "/Groups/"+group.ID, `{"Operations": [`+tt.patch+`]}`)
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body)
+			}
+			var members []string
+			for _, member := range decode[Group](t, rr).Members {
+				members = append(members, member.Value)
+			}
+			if strings.Join(members, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Members = %v, want %v", members, tt.want)
+			}
+		})
+	}
+
+	scimRequest(t, h, "PATCH", "/Groups/"+group.ID, `{"Operations": [{"op": "add", "path": "members", "value": [{"value": "`+ids[0]+`"}]}]}`)
+	rr = scimRequest(t, h, "GET", `/Groups?filter=displayName+eq+"choochoo+admins"&excludedAttributes=members`, "")
+	if !strings.Contains(rr.Body.String(), `"totalResults":1`) || strings.Contains(rr.Body.String(), "members") {
+		t.Errorf("Filtered groups without members = %s", rr.Body)
+	}
+	if user := decode[User](t, scimRequest(t, h, "GET", "/Users/"+ids[0], "")); len(user.Groups) != 1 || user.Groups[0].Display != "Choochoo Admins" {
+		t.Errorf("Groups of member = %+v", user.Groups)
+	}
+
+	rr = scimRequest(t, h, "PATCH", "/Groups/"+group.ID, `{"Operations": [{"op": "move", "path": "members"}]}`)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Unknown operation: expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+	if rr := scimRequest(t, h, "DELETE", "/Groups/"+group.ID, ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Delete: expected status code %d, got %d", http.StatusNoContent, rr.Code)
+	}
+}
+
+func TestHandler_List(t *testing.T) {
+	store := newMemoryStore()
+	h := NewHandler(store, apitoken.NewAuthenticator("secret", nil))
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		scimRequest(t, h, "POST", "/Users", `{"userName": "`+name+`"}`)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"a", "b", "c", "d", "e"}},
+		{"?startIndex=2&count=2", []string{"b", "c"}},
+		{"?startIndex=5&count=10", []string{"e"}},
+		{"?startIndex=9", nil},
+		{"?count=0", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			list := decode[struct {
+				TotalResults int    `json:"totalResults"`
+				ItemsPerPage int    `json:"itemsPerPage"`
+				Resources    []User `json:"Resources"`
+			}](t, scimRequest(t, h, "GET", "/Users"+tt.query, ""))
+			var names []string
+			for _, user := range list.Resources {
+				names = append(names, user.UserName)
+			}
+			if list.TotalResults != 5 || list.ItemsPerPage != len(tt.want) || strings.Join(names, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("List = %+v, want %v", list, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter    string
+		attribute string
+		value     string
+		wantErr   bool
+	}{
+		{`userName eq "octocat"`, "username", "octocat", false},
+		{`externalId EQ "00u1\"x"`, "externalid", `00u1"x`, false},
+		{`emails.value eq "octocat@example.com"`, "emails.value", "octocat@example.com", false},
+		{`userName sw "octo"`, "", "", true},
+		{`userName eq "a" and active eq true`, "", "", true},
+		{`userName eq octocat`, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			attribute, value, err := parseFilter(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attribute != tt.attribute || value != tt.value {
+				t.Errorf("parseFilter() = %q, %q, want %q, %q", attribute, value, tt.attribute, tt.value)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+// Package scim provisions dashboard users and groups from an identity
+// provider over SCIM 2.0 (RFC 7643 and 7644). Groups are mapped to token
+// scopes by SCIM_GROUP_ROLES, and provisioned users sign in to the dashboard
+// and the APIs with basic auth and the password the identity provider set.
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Schema URNs of the resources and messages
+const (
+	SchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+var (
+	// ErrNotFound is returned for a user or group that does not exist
+	ErrNotFound = errors.New("resource not found")
+	// ErrConflict is returned for a user name or group name already taken
+	ErrConflict = errors.New("resource already exists")
+	// ErrUnknownMember is returned for a group member that is not a user
+	ErrUnknownMember = errors.New("group member is not a provisioned user")
+)
+
+// Meta is the resource metadata SCIM clients use to tell what changed
+type Meta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location,omitempty"`
+}
+
+// Name is the structured name of a user, used for the display name when the
+// identity provider sends none
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// Email is an email address of a user. Only the primary one is kept.
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Ref refers to a user from a group or a group from a user
+type Ref struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// User is a provisioned dashboard user. Attributes choochoo does not keep
+// are ignored, and the password is never returned.
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []Email  `json:"emails,omitempty"`
+	Active      bool     `json:"active"`
+	Password    string   `json:"password,omitempty"`
+	Groups      []Ref    `json:"groups,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+
+	// passwordHash is the bcrypt hash of Password, or empty to keep the
+	// stored one
+	passwordHash string
+}
+
+// Email returns the primary email address of the user
+func (u User) Email() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// normalize validates a user sent by the identity provider, filling in the
+// display name and hashing the password
+func (u *User) normalize() error {
+	u.UserName = strings.TrimSpace(u.UserName)
+	if u.UserName == "" {
+		return errors.New("userName is required")
+	}
+	if u.DisplayName == "" && u.Name != nil {
+		u.DisplayName = u.Name.Formatted
+		if u.DisplayName == "" {
+			u.DisplayName = strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+		}
+	}
+	u.Name = nil
+	if u.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("invalid password: %w", err)
+		}
+		u.passwordHash = string(hash)
+		u.Password = ""
+	}
+	return nil
+}
+
+// Group is a provisioned group, whose members get the role it is mapped to
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Ref    `json:"members,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// normalize validates a group sent by the identity provider
+func (g *Group) normalize() error {
+	g.DisplayName = strings.TrimSpace(g.DisplayName)
+	if g.DisplayName == "" {
+		return errors.New("displayName is required")
+	}
+	return nil
+}
+
+// Credentials are what a user signs in with
+type Credentials struct {
+	UserName     string
+	PasswordHash string
+	Active       bool
+	Groups       []string
+}
+
+// Store keeps the provisioned users and groups
+type Store interface {
+	ListUsers(ctx context.Context) ([]User, error)
+	// GetUser returns the user with id, or ErrNotFound
+	GetUser(ctx context.Context, id string) (User, error)
+	// CreateUser stores a new user, or returns ErrConflict if the user
+	// name is taken
+	CreateUser(ctx context.Context, user User) (User, error)
+	// UpdateUser replaces a user, keeping the password unless a new one
+	// is set
+	UpdateUser(ctx context.Context, user User) (User, error)
+	DeleteUser(ctx context.Context, id string) error
+	ListGroups(ctx context.Context) ([]Group, error)
+	GetGroup(ctx context.Context, id string) (Group, error)
+	CreateGroup(ctx context.Context, group Group) (Group, error)
+	// UpdateGroup replaces a group and its members
+	UpdateGroup(ctx context.Context, group Group) (Group, error)
+	DeleteGroup(ctx context.Context, id string) error
+	// Credentials returns the credentials of the user called userName, or
+	// ErrNotFound
+	Credentials(ctx context.Context, userName string) (Credentials, error)
+}
+
+// ParseGroupRoles parses a comma-separated list of group=scope pairs, e.g.
+// "Choochoo Admins=admin,Engineering=read". Group names are matched without
+// regard to case.
+func ParseGroupRoles(list string) (map[string]apitoken.Scope, error) {
+	roles := make(map[string]apitoken.Scope)
+	for _, pair := range strings.Split(list, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		group, scope, ok := strings.Cut(pair, "=")
+		group = strings.TrimSpace(group)
+		if !ok || group == "" {
+			return nil, fmt.Errorf("invalid group role %q, want group=scope", pair)
+		}
+		scopes, err := apitoken.ParseScopes(scope)
+		if err != nil {
+			return nil, fmt.Errorf("invalid role of group %s: %w", group, err)
+		}
+		roles[strings.ToLower(group)] = scopes[0]
+	}
+	return roles, nil
+}
+
+// Directory signs in provisioned users with the scopes their groups are
+// mapped to
+type Directory struct {
+	store Store
+	roles map[string]apitoken.Scope
+}
+
+// NewDirectory creates a directory of the users in store, with roles mapping
+// lowercased group names to scopes
+func NewDirectory(store Store, roles map[string]apitoken.Scope) *Directory {
+	return &Directory{store: store, roles: roles}
+}
+
+// Store returns the store of the directory
+func (d *Directory) Store() Store {
+	return d.store
+}
+
+// SignIn checks the password of an active user, returning a token with the
+// scopes of their groups. Users in no mapped group have no scopes.
+func (d *Directory) SignIn(ctx context.Context, userName, password string) (apitoken.Token, error) {
+	creds, err := d.store.Credentials(ctx, userName)
+	if errors.Is(err, ErrNotFound) || (err == nil && creds.PasswordHash == "") {
+		// Compare anyway so unknown users take as long as known ones
+		bcrypt.CompareHashAndPassword(placeholderHash(), []byte(password))
+		return apitoken.Token{}, apitoken.ErrUnauthorized
+	}
+	if err != nil {
+		return apitoken.Token{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(creds.PasswordHash), []byte(password)) != nil || !creds.Active {
+		return apitoken.Token{}, apitoken.ErrUnauthorized
+	}
+
+	token := apitoken.Token{Name: creds.UserName}
+	for _, group := range creds.Groups {
+		scope, ok := d.roles[strings.ToLower(group)]
+		if ok && !token.Allows(scope) {
+			token.Scopes = append(token.Scopes, scope)
+		}
+	}
+	return token, nil
+}
+
+var (
+	placeholderOnce sync.Once
+	placeholder     []byte
+)
+
+// placeholderHash returns a hash to compare passwords of unknown users with
+func placeholderHash() []byte {
+	placeholderOnce.Do(func() {
+		placeholder, _ = bcrypt.GenerateFromPassword([]byte("choochoo"), bcrypt.DefaultCost)
+	})
+	return placeholder
+}
@@ -0,0 +1,258 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+)
+
+// memoryStore is a Store that keeps users and groups in memory
+type memoryStore struct {
+	mu     sync.Mutex
+	nextID int
+	users  map[string]User
+	hashes map[string]string
+	groups map[string]Group
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{users: make(map[string]User), hashes: make(map[string]string), groups: make(map[string]Group)}
+}
+
+func (s *memoryStore) id() string {
+	s.nextID++
+	return strconv.Itoa(s.nextID)
+}
+
+func (s *memoryStore) user(id string) User {
+	user := s.users[id]
+	user.Groups = nil
+	for _, gid := range slices.Sorted(maps.Keys(s.groups)) {
+		group := s.groups[gid]
+		if slices.ContainsFunc(group.Members, func(m Ref) bool { return m.Value == id }) {
+			user.Groups = append(user.Groups, Ref{Value: gid, Display: group.DisplayName})
+		}
+	}
+	return user
+}
+
+func (s *memoryStore) taken(userName, except string) bool {
+	for id, user := range s.users {
+		if id != except && strings.EqualFold(user.UserName, userName) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *memoryStore) ListUsers(ctx context.Context) ([]User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var users []User
+	for _, id := range slices.Sorted(maps.Keys(s.users)) {
+		users = append(users, s.user(id))
+	}
+	return users, nil
+}
+
+func (s *memoryStore) GetUser(ctx context.Context, id string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[id]; !ok {
+		return User{}, ErrNotFound
+	}
+	return s.user(id), nil
+}
+
+func (s *memoryStore) CreateUser(ctx context.Context, user User) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.taken(user.UserName, "") {
+		return User{}, ErrConflict
+	}
+	user.ID = s.id()
+	s.hashes[user.ID] = user.passwordHash
+	user.passwordHash = ""
+	s.users[user.ID] = user
+	return s.user(user.ID), nil
+}
+
+func (s *memoryStore) UpdateUser(ctx context.Context, user User) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[user.ID]; !ok {
+		return User{}, ErrNotFound
+	}
+	if s.taken(user.UserName, user.ID) {
+		return User{}, ErrConflict
+	}
+	if user.passwordHash != "" {
+		s.hashes[user.ID] = user.passwordHash
+	}
+	user.passwordHash = ""
+	s.users[user.ID] = user
+	return s.user(user.ID), nil
+}
+
+func (s *memoryStore) DeleteUser(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.users, id)
+	for gid, group := range s.groups {
+		group.Members = slices.DeleteFunc(group.Members, func(m Ref) bool { return m.Value == id })
+		s.groups[gid] = group
+	}
+	return nil
+}
+
+func (s *memoryStore) ListGroups(ctx context.Context) ([]Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var groups []Group
+	for _, id := range slices.Sorted(maps.Keys(s.groups)) {
+		groups = append(groups, s.groups[id])
+	}
+	return groups, nil
+}
+
+func (s *memoryStore) GetGroup(ctx context.Context, id string) (Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.groups[id]
+	if !ok {
+		return Group{}, ErrNotFound
+	}
+	return group, nil
+}
+
+func (s *memoryStore) CreateGroup(ctx context.Context, group Group) (Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group.ID = s.id()
+	return s.putGroup(group)
+}
+
+func (s *memoryStore) UpdateGroup(ctx context.Context, group Group) (Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.groups[group.ID]; !ok {
+		return Group{}, ErrNotFound
+	}
+	return s.putGroup(group)
+}
+
+func (s *memoryStore) putGroup(group Group) (Group, error) {
+	for i, member := range group.Members {
+		user, ok := s.users[member.Value]
+		if !ok {
+			return Group{}, ErrUnknownMember
+		}
+		group.Members[i].Display = user.UserName
+	}
+	s.groups[group.ID] = group
+	return group, nil
+}
+
+func (s *memoryStore) DeleteGroup(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.groups[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.groups, id)
+	return nil
+}
+
+func (s *memoryStore) Credentials(ctx context.Context, userName string) (Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.users {
+		user := s.user(id)
+		if !strings.EqualFold(user.UserName, userName) {
+			continue
+		}
+		creds := Credentials{UserName: user.UserName, PasswordHash: s.hashes[id], Active: user.Active}
+		for _, group := range user.Groups {
+			creds.Groups = append(creds.Groups, group.Display)
+		}
+		return creds, nil
+	}
+	return Credentials{}, ErrNotFound
+}
+
+func TestParseGroupRoles(t *testing.T) {
+	roles, err := ParseGroupRoles("Choochoo Admins=admin, Engineering = read,")
+	if err != nil {
+		t.Fatalf("ParseGroupRoles() error = %v", err)
+	}
+	want := map[string]apitoken.Scope{"choochoo admins": apitoken.ScopeAdmin, "engineering": apitoken.ScopeRead}
+	if !maps.Equal(roles, want) {
+		t.Errorf("ParseGroupRoles() = %v, want %v", roles, want)
+	}
+
+	for _, list := range []string{"Choochoo Admins", "=admin", "Choochoo Admins=owner", "Choochoo Admins="} {
+		if _, err := ParseGroupRoles(list); err == nil {
+			t.Errorf("ParseGroupRoles(%q) expected an error", list)
+		}
+	}
+}
+
+func TestDirectory_SignIn(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryStore()
+	create := func(userName, password string, active bool) User {
+		user := User{UserName: userName, Password: password, Active: active}
+		if err := user.normalize(); err != nil {
+			t.Fatal(err)
+		}
+		created, err := store.CreateUser(ctx, user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return created
+	}
+	octocat := create("octocat", "hunter2", true)
+	hubot := create("hubot", "hunter2", false)
+	create("monalisa", "", true)
+	store.CreateGroup(ctx, Group{DisplayName: "Engineering", Members: []Ref{{Value: octocat.ID}, {Value: hubot.ID}}})
+	store.CreateGroup(ctx, Group{DisplayName: "Choochoo Admins", Members: []Ref{{Value: octocat.ID}}})
+	store.CreateGroup(ctx, Group{DisplayName: "Marketing", Members: []Ref{{Value: octocat.ID}}})
+
+	directory := NewDirectory(store, map[string]apitoken.Scope{"engineering": apitoken.ScopeRead, "choochoo admins": apitoken.ScopeAdmin})
+
+	token, err := directory.SignIn(ctx, "OctoCat", "hunter2")
+	if err != nil {
+		t.Fatalf("SignIn() error = %v", err)
+	}
+	if token.Name != "octocat" || !slices.Equal(token.Scopes, []apitoken.Scope{apitoken.ScopeRead, apitoken.ScopeAdmin}) {
+		t.Errorf("SignIn() = %+v, want octocat with the read and admin scopes", token)
+	}
+
+	tests := []struct {
+		name     string
+		userName string
+		password string
+	}{
+		{"wrong password", "octocat", "hunter3"},
+		{"deactivated", "hubot", "hunter2"},
+		{"no password", "monalisa", ""},
+		{"unknown", "ghost", "hunter2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := directory.SignIn(ctx, tt.userName, tt.password); !errors.Is(err, apitoken.ErrUnauthorized) {
+				t.Errorf("SignIn() error = %v, want ErrUnauthorized", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PostgreSQL error codes of constraint violations
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+type postgresStore struct {
+	conn *database.Connection
+}
+
+// NewPostgresStore creates a store on the scim_users, scim_groups and
+// scim_group_members tables
+func NewPostgresStore(conn *database.Connection) Store {
+	return &postgresStore{conn: conn}
+}
+
+func (s *postgresStore) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := s.conn.Queries().ListSCIMUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.conn.Queries().ListSCIMGroupMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]User, 0, len(rows))
+	for _, row := range rows {
+		users = append(users, newUser(row, members))
+	}
+	return users, nil
+}
+
+func (s *postgresStore) GetUser(ctx context.Context, id string) (User, error) {
+	userID, ok := parseID(id)
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	row, err := s.conn.Queries().GetSCIMUser(ctx, userID)
+	if err != nil {
+		return User{}, storeError(err)
+	}
+	members, err := s.conn.Queries().ListSCIMGroupMembers(ctx)
+	if err != nil {
+		return User{}, err
+	}
+	return newUser(row, members), nil
+}
+
+func (s *postgresStore) CreateUser(ctx context.Context, user User) (User, error) {
+	row, err := s.conn.Queries().CreateSCIMUser(ctx, db.CreateSCIMUserParams{
+		ExternalID:   user.ExternalID,
+		UserName:     user.UserName,
+		DisplayName:  user.DisplayName,
+		Email:        user.Email(),
+		PasswordHash: pgtype.Text{String: user.passwordHash, Valid: user.passwordHash != ""},
+		Active:       user.Active,
+	})
+	if err != nil {
+		return User{}, storeError(err)
+	}
+	return newUser(row, nil), nil
+}
+
+func (s *postgresStore) UpdateUser(ctx context.Context, user User) (User, error) {
+	userID, ok := parseID(user.ID)
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	_, err := s.conn.Queries().UpdateSCIMUser(ctx, db.UpdateSCIMUserParams{
+		ExternalID:   user.ExternalID,
+		UserName:     user.UserName,
+		DisplayName:  user.DisplayName,
+		Email:        user.Email(),
+		Active:       user.Active,
+		PasswordHash: pgtype.Text{String: user.passwordHash, Valid: user.passwordHash != ""},
+		ID:           userID,
+	})
+	if err != nil {
+		return User{}, storeError(err)
+	}
+	return s.GetUser(ctx, user.ID)
+}
+
+func (s *postgresStore) DeleteUser(ctx context.Context, id string) error {
+	userID, ok := parseID(id)
+	if !ok {
+		return ErrNotFound
+	}
+	deleted, err := s.conn.Queries().DeleteSCIMUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresStore) ListGroups(ctx context.Context) ([]Group, error) {
+	rows, err := s.conn.Queries().ListSCIMGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.conn.Queries().ListSCIMGroupMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]Group, 0, len(rows))
+	for _, row := range rows {
+		groups = append(groups, newGroup(row, members))
+	}
+	return groups, nil
+}
+
+func (s *postgresStore) GetGroup(ctx context.Context, id string) (Group, error) {
+	groupID, ok := parseID(id)
+	if !ok {
+		return Group{}, ErrNotFound
+	}
+	row, err := s.conn.Queries().GetSCIMGroup(ctx, groupID)
+	if err != nil {
+		return Group{}, storeError(err)
+	}
+	members, err := s.conn.Queries().ListSCIMGroupMembers(ctx)
+	if err != nil {
+		return Group{}, err
+	}
+	return newGroup(row, members), nil
+}
+
+func (s *postgresStore) CreateGroup(ctx context.Context, group Group) (Group, error) {
+	var id int32
+	err := s.conn.InTx(ctx, func(q *db.Queries) error {
+		row, err := q.CreateSCIMGroup(ctx, db.CreateSCIMGroupParams{
+			ExternalID:  group.ExternalID,
+			DisplayName: group.DisplayName,
+		})
+		if err != nil {
+			return err
+		}
+		id = row.ID
+		return addMembers(ctx, q, id, group.Members)
+	})
+	if err != nil {
+		return Group{}, storeError(err)
+	}
+	return s.GetGroup(ctx, strconv.Itoa(int(id)))
+}
+
+func (s *postgresStore) UpdateGroup(ctx context.Context, group Group) (Group, error) {
+	groupID, ok := parseID(group.ID)
+	if !ok {
+		return Group{}, ErrNotFound
+	}
+	err := s.conn.InTx(ctx, func(q *db.Queries) error {
+		_, err := q.UpdateSCIMGroup(ctx, db.UpdateSCIMGroupParams{
+			ExternalID:  group.ExternalID,
+			DisplayName: group.DisplayName,
+			ID:          groupID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.RemoveSCIMGroupMembers(ctx, groupID); err != nil {
+			return err
+		}
+		return addMembers(ctx, q, groupID, group.Members)
+	})
+	if err != nil {
+		return Group{}, storeError(err)
+	}
+	return s.GetGroup(ctx, group.ID)
+}
+
+func (s *postgresStore) DeleteGroup(ctx context.Context, id string) error {
+	groupID, ok := parseID(id)
+	if !ok {
+		return ErrNotFound
+	}
+	deleted, err := s.conn.Queries().DeleteSCIMGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *postgresStore) Credentials(ctx context.Context, userName string) (Credentials, error) {
+	row, err := s.conn.Queries().GetSCIMUserByUserName(ctx, userName)
+	if err != nil {
+		return Credentials{}, storeError(err)
+	}
+	groups, err := s.conn.Queries().ListSCIMUserGroupNames(ctx, row.ID)
+	if err != nil {
+		return Credentials{}, err
+	}
+	return Credentials{
+		UserName:     row.UserName,
+		PasswordHash: row.PasswordHash.String,
+		Active:       row.Active,
+		Groups:       groups,
+	}, nil
+}
+
+// addMembers adds the users in members to a group
+func addMembers(ctx context.Context, q *db.Queries, groupID int32, members []Ref) error {
+	for _, member := range members {
+		userID, ok := parseID(member.Value)
+		if !ok {
+			return ErrUnknownMember
+		}
+		if err := q.AddSCIMGroupMember(ctx, db.AddSCIMGroupMemberParams{GroupID: groupID, UserID: userID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// storeError maps missing rows and constraint violations to the errors of
+// the package
+func storeError(err error) error {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return ErrNotFound
+	case errors.As(err, &pgErr) && pgErr.Code == uniqueViolation:
+		return ErrConflict
+	case errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation:
+		return ErrUnknownMember
+	}
+	return err
+}
+
+func parseID(id string) (int32, bool) {
+	n, err := strconv.ParseInt(id, 10, 32)
+	return int32(n), err == nil && n > 0
+}
+
+func newUser(row db.ScimUser, members []db.ListSCIMGroupMembersRow) User {
+	user := User{
+		Schemas:     []string{SchemaUser},
+		ID:          strconv.Itoa(int(row.ID)),
+		ExternalID:  row.ExternalID,
+		UserName:    row.UserName,
+		DisplayName: row.DisplayName,
+		Active:      row.Active,
+		Meta:        newMeta("User", row.CreatedAt, row.UpdatedAt),
+	}
+	if row.Email != "" {
+		user.Emails = []Email{{Value: row.Email, Type: "work", Primary: true}}
+	}
+	for _, member := range members {
+		if member.UserID == row.ID {
+			user.Groups = append(user.Groups, Ref{Value: strconv.Itoa(int(member.GroupID)), Display: member.DisplayName})
+		}
+	}
+	return user
+}
+
+func newGroup(row db.ScimGroup, members []db.ListSCIMGroupMembersRow) Group {
+	group := Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          strconv.Itoa(int(row.ID)),
+		ExternalID:  row.ExternalID,
+		DisplayName: row.DisplayName,
+		Meta:        newMeta("Group", row.CreatedAt, row.UpdatedAt),
+	}
+	for _, member := range members {
+		if member.GroupID == row.ID {
+			group.Members = append(group.Members, Ref{Value: strconv.Itoa(int(member.UserID)), Display: member.UserName})
+		}
+	}
+	return group
+}
+
+func newMeta(resourceType string, created, modified pgtype.Timestamptz) *Meta {
+	return &Meta{ResourceType: resourceType, Created: created.Time, LastModified: modified.Time}
+}
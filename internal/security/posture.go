@@ -0,0 +1,96 @@
+package security
+
+import (
+	"sort"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// Posture summarizes open security alerts and remediation performance
+type Posture struct {
+	GeneratedAt time.Time                 `json:"generated_at"`
+	WindowDays  int                       `json:"window_days"`
+	OpenTotal   int                       `json:"open_total"`
+	Open        map[string]map[string]int `json:"open"`
+	Breaches    []Breach                  `json:"sla_breaches"`
+	Resolution  []ResolutionStats         `json:"resolution"`
+}
+
+// Breach is an open alert that has exceeded its SLA
+type Breach struct {
+	Kind       string    `json:"kind"`
+	Repository string    `json:"repository"`
+	Number     int32     `json:"number"`
+	Severity   string    `json:"severity"`
+	HTMLURL    string    `json:"html_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	OverdueBy  string    `json:"overdue_by"`
+}
+
+// ResolutionStats describes how quickly alerts of a severity were resolved
+type ResolutionStats struct {
+	Severity           string  `json:"severity"`
+	Resolution         string  `json:"resolution"`
+	Count              int64   `json:"count"`
+	MeanHoursToResolve float64 `json:"mean_hours_to_resolve"`
+	SLAHours           float64 `json:"sla_hours"`
+}
+
+// BuildPosture computes a posture report from open alerts and resolution stats
+func BuildPosture(open []db.SecurityAlert, stats []db.GetSecurityAlertResolutionStatsRow, sla SLA, windowDays int, now time.Time) Posture {
+	posture := Posture{
+		GeneratedAt: now,
+		WindowDays:  windowDays,
+		OpenTotal:   len(open),
+		Open:        make(map[string]map[string]int),
+		Breaches:    []Breach{},
+		Resolution:  []ResolutionStats{},
+	}
+
+	for _, alert := range open {
+		if posture.Open[alert.Kind] == nil {
+			posture.Open[alert.Kind] = make(map[string]int)
+		}
+		posture.Open[alert.Kind][alert.Severity]++
+
+		target, ok := sla[alert.Severity]
+		if !ok || !alert.CreatedAt.Valid {
+			continue
+		}
+		age := now.Sub(alert.CreatedAt.Time)
+		if age > target {
+			posture.Breaches = append(posture.Breaches, Breach{
+				Kind:       alert.Kind,
+				Repository: alert.RepositoryName,
+				Number:     alert.AlertNumber,
+				Severity:   alert.Severity,
+				HTMLURL:    alert.HtmlUrl.String,
+				CreatedAt:  alert.CreatedAt.Time,
+				OverdueBy:  (age - target).Round(time.Hour).String(),
+			})
+		}
+	}
+
+	// Most severe, then longest overdue, first
+	sort.SliceStable(posture.Breaches, func(i, j int) bool {
+		a, b := posture.Breaches[i], posture.Breaches[j]
+		if webhook.SeverityRank(a.Severity) != webhook.SeverityRank(b.Severity) {
+			return webhook.SeverityRank(a.Severity) > webhook.SeverityRank(b.Severity)
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+
+	for _, row := range stats {
+		posture.Resolution = append(posture.Resolution, ResolutionStats{
+			Severity:           row.Severity,
+			Resolution:         row.Resolution.String,
+			Count:              row.Resolved,
+			MeanHoursToResolve: row.AvgSeconds / 3600,
+			SLAHours:           sla[row.Severity].Hours(),
+		})
+	}
+
+	return posture
+}
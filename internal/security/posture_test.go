@@ -0,0 +1,43 @@
+package security
+
+import (
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestBuildPosture(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	created := func(days int) pgtype.Timestamptz {
+		return pgtype.Timestamptz{Time: now.AddDate(0, 0, -days), Valid: true}
+	}
+
+	open := []db.SecurityAlert{
+		{Kind: "dependabot_alert", RepositoryName: "a/b", AlertNumber: 1, Severity: "critical", CreatedAt: created(10)},
+		{Kind: "dependabot_alert", RepositoryName: "a/b", AlertNumber: 2, Severity: "low", CreatedAt: created(10)},
+		{Kind: "code_scanning_alert", RepositoryName: "a/c", AlertNumber: 3, Severity: "high", CreatedAt: created(40)},
+	}
+	stats := []db.GetSecurityAlertResolutionStatsRow{
+		{Severity: "high", Resolution: pgtype.Text{String: "fixed", Valid: true}, Resolved: 4, AvgSeconds: 7200},
+	}
+
+	posture := BuildPosture(open, stats, DefaultSLA, 90, now)
+
+	if posture.OpenTotal != 3 {
+		t.Errorf("Expected 3 open alerts, got %d", posture.OpenTotal)
+	}
+	if posture.Open["dependabot_alert"]["critical"] != 1 || posture.Open["code_scanning_alert"]["high"] != 1 {
+		t.Errorf("Unexpected open breakdown: %v", posture.Open)
+	}
+	if len(posture.Breaches) != 2 {
+		t.Fatalf("Expected 2 SLA breaches, got %d", len(posture.Breaches))
+	}
+	if posture.Breaches[0].Severity != "critical" || posture.Breaches[0].OverdueBy != "72h0m0s" {
+		t.Errorf("Expected critical breach first and 3 days overdue, got %+v", posture.Breaches[0])
+	}
+	if len(posture.Resolution) != 1 || posture.Resolution[0].MeanHoursToResolve != 2 || posture.Resolution[0].SLAHours != 720 {
+		t.Errorf("Unexpected resolution stats: %+v", posture.Resolution)
+	}
+}
@@ -0,0 +1,160 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+// SLA maps a severity level to the time allowed to fix or dismiss an alert
+type SLA map[string]time.Duration
+
+// DefaultSLA contains the remediation targets used when none are configured
+var DefaultSLA = SLA{
+	webhook.SeverityCritical: 7 * 24 * time.Hour,
+	webhook.SeverityHigh:     30 * 24 * time.Hour,
+	webhook.SeverityMedium:   90 * 24 * time.Hour,
+	webhook.SeverityLow:      180 * 24 * time.Hour,
+}
+
+// ParseSLA parses a comma-separated list of severity=duration pairs such as
+// "critical=7d,high=30d". Durations accept a "d" suffix for days in addition
+// to the units understood by time.ParseDuration. Severities that are not
+// listed keep their DefaultSLA value.
+func ParseSLA(list string) (SLA, error) {
+	sla := make(SLA, len(DefaultSLA))
+	for severity, target := range DefaultSLA {
+		sla[severity] = target
+	}
+
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		severity, value, ok := strings.Cut(pair, "=")
+		if !ok || webhook.SeverityRank(severity) == 0 {
+			return nil, fmt.Errorf("invalid SLA entry %q", pair)
+		}
+		target, err := parseDays(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SLA duration for %s: %w", severity, err)
+		}
+		sla[severity] = target
+	}
+	return sla, nil
+}
+
+// parseDays parses a duration that may use a "d" suffix for days
+func parseDays(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// Route sends alerts at or above a severity to a channel
+type Route struct {
+	MinSeverity string
+	Channel     forwarder.Forwarder
+}
+
+// Router delivers security alerts to the channels matching their severity
+type Router struct {
+	routes []Route
+}
+
+// NewRouter creates a router for the given routes
+func NewRouter(routes []Route) *Router {
+	return &Router{routes: routes}
+}
+
+// ParseRoutes parses a comma-separated list of severity=url pairs into HTTP
+// routes, e.g. "critical=https://pager.example.com/hook,low=https://chat.example.com/hook"
+func ParseRoutes(list string) ([]Route, error) {
+	var routes []Route
+	for _, pair := range strings.Split(list, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		severity, endpoint, ok := strings.Cut(pair, "=")
+		if !ok || webhook.SeverityRank(severity) == 0 {
+			return nil, fmt.Errorf("invalid security route %q", pair)
+		}
+		channel, err := forwarder.NewHTTPForwarder(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, Route{MinSeverity: severity, Channel: channel})
+	}
+	return routes, nil
+}
+
+// routedActions are the alert actions that notify security channels
+var routedActions = map[string]bool{
+	"created":          true,
+	"reopened":         true,
+	"reopened_by_user": true,
+	"reintroduced":     true,
+}
+
+// Matching returns the routes an alert should be delivered to
+func (r *Router) Matching(alert *webhook.SecurityAlert) []Route {
+	if !alert.IsOpen() || !routedActions[alert.Action] {
+		return nil
+	}
+
+	var matched []Route
+	for _, route := range r.routes {
+		if webhook.SeverityRank(alert.Severity) >= webhook.SeverityRank(route.MinSeverity) {
+			matched = append(matched, route)
+		}
+	}
+	return matched
+}
+
+// Route delivers the normalized alert to every matching channel
+func (r *Router) Route(ctx context.Context, deliveryID string, alert *webhook.SecurityAlert) {
+	if len(r.Matching(alert)) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("Failed to encode security alert: %v", err)
+		return
+	}
+
+	log.Printf("Routing %s %s alert #%d for %s to security channels", alert.Severity, alert.Kind, alert.Number, alert.Repository)
+	r.RouteEvent(ctx, alert.Severity, forwarder.Event{
+		DeliveryID: deliveryID,
+		EventType:  alert.Kind,
+		Action:     alert.Action,
+		Repository: alert.Repository,
+		Payload:    payload,
+	})
+}
+
+// RouteEvent delivers an arbitrary security-relevant event to every channel
+// accepting the given severity
+func (r *Router) RouteEvent(ctx context.Context, severity string, event forwarder.Event) {
+	var channels []forwarder.Forwarder
+	for _, route := range r.routes {
+		if webhook.SeverityRank(severity) >= webhook.SeverityRank(route.MinSeverity) {
+			channels = append(channels, route.Channel)
+		}
+	}
+	forwarder.ForwardAll(ctx, channels, event)
+}
@@ -0,0 +1,106 @@
+package security
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/webhook"
+)
+
+func TestParseSLA(t *testing.T) {
+	sla, err := ParseSLA("critical=2d, low=12h")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if sla[webhook.SeverityCritical] != 48*time.Hour {
+		t.Errorf("Expected critical SLA of 48h, got %v", sla[webhook.SeverityCritical])
+	}
+	if sla[webhook.SeverityLow] != 12*time.Hour {
+		t.Errorf("Expected low SLA of 12h, got %v", sla[webhook.SeverityLow])
+	}
+	if sla[webhook.SeverityHigh] != DefaultSLA[webhook.SeverityHigh] {
+		t.Errorf("Expected unlisted severity to keep its default")
+	}
+
+	for _, invalid := range []string{"urgent=1d", "critical", "high=soon"} {
+		if _, err := ParseSLA(invalid); err == nil {
+			t.Errorf("ParseSLA(%q) expected error, got nil", invalid)
+		}
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes("critical=https://pager.example.com/hook,low=http://chat.example.com/hook")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(routes) != 2 || routes[0].MinSeverity != webhook.SeverityCritical {
+		t.Errorf("Unexpected routes: %+v", routes)
+	}
+
+	if routes, err := ParseRoutes(""); err != nil || len(routes) != 0 {
+		t.Errorf("Expected no routes for empty list, got %v (%v)", routes, err)
+	}
+
+	for _, invalid := range []string{"critical", "urgent=https://example.com", "high=ftp://example.com"} {
+		if _, err := ParseRoutes(invalid); err == nil {
+			t.Errorf("ParseRoutes(%q) expected error, got nil", invalid)
+		}
+	}
+}
+
+func TestRouter_Matching(t *testing.T) {
+	routes, _ := ParseRoutes("critical=https://pager.example.com,medium=https://chat.example.com")
+	router := NewRouter(routes)
+
+	tests := []struct {
+		alert    webhook.SecurityAlert
+		expected int
+	}{
+		{webhook.SecurityAlert{Action: "created", Severity: webhook.SeverityCritical}, 2},
+		{webhook.SecurityAlert{Action: "created", Severity: webhook.SeverityHigh}, 1},
+		{webhook.SecurityAlert{Action: "reopened", Severity: webhook.SeverityMedium}, 1},
+		{webhook.SecurityAlert{Action: "created", Severity: webhook.SeverityLow}, 0},
+		{webhook.SecurityAlert{Action: "dismissed", Severity: webhook.SeverityCritical}, 0},
+	}
+
+	for _, test := range tests {
+		if matched := router.Matching(&test.alert); len(matched) != test.expected {
+			t.Errorf("Matching(%s %s) returned %d routes, expected %d", test.alert.Action, test.alert.Severity, len(matched), test.expected)
+		}
+	}
+
+	resolved := time.Now()
+	closed := webhook.SecurityAlert{Action: "created", Severity: webhook.SeverityCritical, ResolvedAt: &resolved}
+	if matched := router.Matching(&closed); len(matched) != 0 {
+		t.Errorf("Expected resolved alerts not to be routed, got %d routes", len(matched))
+	}
+}
+
+func TestRouter_Route(t *testing.T) {
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("X-GitHub-Event")
+	}))
+	defer server.Close()
+
+	routes, err := ParseRoutes("high=" + server.URL)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	alert := &webhook.SecurityAlert{Kind: webhook.CodeScanningAlertEvent, Action: "created", Severity: webhook.SeverityCritical, Number: 1}
+	NewRouter(routes).Route(context.Background(), "delivery-1", alert)
+
+	select {
+	case eventType := <-received:
+		if eventType != webhook.CodeScanningAlertEvent {
+			t.Errorf("Expected event header %s, got %s", webhook.CodeScanningAlertEvent, eventType)
+		}
+	default:
+		t.Error("Expected alert to be delivered to the security channel")
+	}
+}
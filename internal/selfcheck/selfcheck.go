@@ -0,0 +1,174 @@
+// Package selfcheck verifies that the GitHub credentials of the server grant
+// every permission its enabled features need, so missing permissions are
+// reported at startup instead of failing mid-automation.
+package selfcheck
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/github"
+)
+
+// Feature statuses
+const (
+	StatusOK         = "ok"
+	StatusDegraded   = "degraded"
+	StatusUnverified = "unverified"
+)
+
+// Requirement is a permission a feature needs, e.g. checks:write
+type Requirement struct {
+	Feature    string
+	Permission string
+	Access     string
+}
+
+// String formats the requirement as permission:access
+func (r Requirement) String() string {
+	return r.Permission + ":" + r.Access
+}
+
+// FeatureResult is the outcome of the check for one feature
+type FeatureResult struct {
+	Feature string   `json:"feature"`
+	Status  string   `json:"status"`
+	Missing []string `json:"missing,omitempty"`
+	Reason  string   `json:"reason,omitempty"`
+}
+
+// Report is the outcome of a self-check
+type Report struct {
+	CheckedAt   time.Time          `json:"checked_at"`
+	Configured  bool               `json:"configured"`
+	Auth        string             `json:"auth,omitempty"`
+	Connected   bool               `json:"connected"`
+	Verified    bool               `json:"verified"`
+	Error       string             `json:"error,omitempty"`
+	Permissions github.Permissions `json:"permissions,omitempty"`
+	RateLimit   *github.RateLimit  `json:"rate_limit,omitempty"`
+	Features    []FeatureResult    `json:"features"`
+}
+
+// Degraded lists the features that are not fully operational
+func (r Report) Degraded() []FeatureResult {
+	var degraded []FeatureResult
+	for _, feature := range r.Features {
+		if feature.Status != StatusOK {
+			degraded = append(degraded, feature)
+		}
+	}
+	return degraded
+}
+
+// Evaluate compares granted permissions with the requirements of each
+// feature. Features are reported in the order they first appear in
+// requirements.
+func Evaluate(granted github.Permissions, requirements []Requirement) []FeatureResult {
+	results := []FeatureResult{}
+	index := make(map[string]int)
+	for _, requirement := range requirements {
+		i, ok := index[requirement.Feature]
+		if !ok {
+			i = len(results)
+			index[requirement.Feature] = i
+			results = append(results, FeatureResult{Feature: requirement.Feature, Status: StatusOK})
+		}
+		if !granted.Allows(requirement.Permission, requirement.Access) {
+			results[i].Status = StatusDegraded
+			results[i].Missing = append(results[i].Missing, requirement.String())
+		}
+	}
+	for i := range results {
+		if len(results[i].Missing) > 0 {
+			results[i].Reason = "missing " + strings.Join(results[i].Missing, ", ")
+		}
+	}
+	return results
+}
+
+// Checker runs the self-check and keeps its latest report
+type Checker struct {
+	client       *github.Client
+	requirements []Requirement
+
+	mu   sync.Mutex
+	last Report
+}
+
+// NewChecker creates a checker. client is nil when no GitHub credentials are
+// configured.
+func NewChecker(client *github.Client, requirements []Requirement) *Checker {
+	return &Checker{client: client, requirements: requirements}
+}
+
+// Run checks connectivity and permissions, logs every degraded feature and
+// returns the report
+func (c *Checker) Run(ctx context.Context, now time.Time) Report {
+	report := c.check(ctx, now)
+
+	for _, feature := range report.Degraded() {
+		log.Printf("Warning: %s will be %s: %s", feature.Feature, feature.Status, feature.Reason)
+	}
+	if report.Error != "" {
+		log.Printf("Warning: GitHub self-check failed: %s", report.Error)
+	} else if report.Configured {
+		log.Printf("GitHub self-check passed for %d of %d features", len(report.Features)-len(report.Degraded()), len(report.Features))
+	}
+
+	c.mu.Lock()
+	c.last = report
+	c.mu.Unlock()
+	return report
+}
+
+// check builds a report without recording it
+func (c *Checker) check(ctx context.Context, now time.Time) Report {
+	report := Report{CheckedAt: now, Configured: c.client != nil}
+
+	// setAll gives every feature the same status when permissions are unknown
+	setAll := func(status, reason string) {
+		report.Features = Evaluate(nil, c.requirements)
+		for i := range report.Features {
+			report.Features[i].Status = status
+			report.Features[i].Missing = nil
+			report.Features[i].Reason = reason
+		}
+	}
+
+	if c.client == nil {
+		setAll(StatusDegraded, "GitHub credentials not configured")
+		return report
+	}
+	report.Auth = c.client.Auth()
+
+	permissions, verified, err := c.client.Permissions(ctx)
+	if err != nil {
+		report.Error = err.Error()
+		setAll(StatusDegraded, "GitHub API unreachable: "+err.Error())
+		return report
+	}
+	report.Connected = true
+	report.Verified = verified
+	if limit, ok := c.client.RateLimit(); ok {
+		report.RateLimit = &limit
+	}
+
+	if !verified {
+		setAll(StatusUnverified, "GitHub does not report the permissions of this token")
+		return report
+	}
+	report.Permissions = permissions
+	report.Features = Evaluate(permissions, c.requirements)
+	return report
+}
+
+// Last returns the latest report, or the zero report if the check has not run
+func (c *Checker) Last() Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
@@ -0,0 +1,104 @@
+package selfcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/github"
+)
+
+var requirements = []Requirement{
+	{Feature: "check runs", Permission: "checks", Access: github.Write},
+	{Feature: "auto-labeling", Permission: "contents", Access: github.Read},
+	{Feature: "auto-labeling", Permission: "pull_requests", Access: github.Write},
+}
+
+func TestEvaluate(t *testing.T) {
+	granted := github.Permissions{"contents": github.Write, "pull_requests": github.Read}
+
+	got := Evaluate(granted, requirements)
+	want := []FeatureResult{
+		{Feature: "check runs", Status: StatusDegraded, Missing: []string{"checks:write"}, Reason: "missing checks:write"},
+		{Feature: "auto-labeling", Status: StatusDegraded, Missing: []string{"pull_requests:write"}, Reason: "missing pull_requests:write"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Evaluate() = %+v, want %+v", got, want)
+	}
+
+	granted["checks"] = github.Write
+	granted["pull_requests"] = github.Write
+	for _, result := range Evaluate(granted, requirements) {
+		if result.Status != StatusOK {
+			t.Errorf("Expected %s to be ok, got %+v", result.Feature, result)
+		}
+	}
+}
+
+func TestChecker_NotConfigured(t *testing.T) {
+	checker := NewChecker(nil, requirements)
+	if !checker.Last().CheckedAt.IsZero() {
+		t.Fatal("Expected no report before the first run")
+	}
+
+	report := checker.Run(context.Background(), time.Now())
+	if report.Configured || len(report.Degraded()) != 2 {
+		t.Errorf("Expected both features degraded, got %+v", report)
+	}
+	if checker.Last().CheckedAt.IsZero() {
+		t.Error("Expected the report to be recorded")
+	}
+}
+
+func TestChecker_Token(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rate_limit" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-OAuth-Scopes", "repo, read:org")
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4990")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	report := NewChecker(github.NewTokenClient(server.URL, "secret"), requirements).Run(context.Background(), time.Now())
+	if !report.Connected || !report.Verified || report.Auth != github.AuthToken {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if report.RateLimit == nil || report.RateLimit.Remaining != 4990 {
+		t.Errorf("Unexpected rate limit: %+v", report.RateLimit)
+	}
+	// Classic tokens cannot create check runs
+	degraded := report.Degraded()
+	if len(degraded) != 1 || degraded[0].Feature != "check runs" {
+		t.Errorf("Expected only check runs degraded, got %+v", degraded)
+	}
+
+	report = NewChecker(github.NewTokenClient(server.URL, "wrong"), requirements).Run(context.Background(), time.Now())
+	if report.Connected || report.Error == "" || len(report.Degraded()) != 2 {
+		t.Errorf("Expected a failed check, got %+v", report)
+	}
+}
+
+func TestChecker_Unverified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	report := NewChecker(github.NewTokenClient(server.URL, "github_pat_x"), requirements).Run(context.Background(), time.Now())
+	if !report.Connected || report.Verified {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	for _, feature := range report.Features {
+		if feature.Status != StatusUnverified {
+			t.Errorf("Expected %s to be unverified, got %s", feature.Feature, feature.Status)
+		}
+	}
+}
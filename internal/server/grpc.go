@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/rpc"
+	"github.com/deedubs/choochoo/internal/rpc/eventsv1"
+)
+
+// serveGRPC serves the gRPC surface on the gRPC port: the event service,
+// for tokens with the read scope, and the standard health service reporting
+// the readiness checks of /readyz
+func (ws *WebhookServer) serveGRPC() {
+	srv, err := rpc.New(ws.health.Ready, ws.auth, ws.tlsCertFile, ws.tlsKeyFile)
+	if err != nil {
+		log.Printf("Warning: Failed to create the gRPC server: %v. gRPC is not served.", err)
+		return
+	}
+	events := rpc.NewEventService(ws.events, ws.streamHub).WithEncryption(ws.payloadKeys)
+	srv.Register(&eventsv1.EventService_ServiceDesc, events, apitoken.ScopeRead)
+	lis, err := net.Listen("tcp", ":"+ws.grpcPort)
+	if err != nil {
+		log.Printf("Warning: Failed to listen on GRPC_PORT: %v. gRPC is not served.", err)
+		return
+	}
+
+	go srv.Run(context.Background(), rpc.DefaultCheckInterval)
+	log.Printf("Serving gRPC events, health checks and reflection on port %s", ws.grpcPort)
+	if err := srv.Serve(lis); err != nil {
+		log.Printf("Warning: gRPC server failed: %v", err)
+	}
+}
@@ -2,73 +2,1652 @@ package server
 
 import (
 	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/deedubs/choochoo/internal/access"
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"github.com/deedubs/choochoo/internal/approval"
+	"github.com/deedubs/choochoo/internal/auditlog"
+	"github.com/deedubs/choochoo/internal/authz"
+	"github.com/deedubs/choochoo/internal/capacity"
+	"github.com/deedubs/choochoo/internal/chatops"
+	"github.com/deedubs/choochoo/internal/checkrun"
+	"github.com/deedubs/choochoo/internal/community"
+	"github.com/deedubs/choochoo/internal/config"
 	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/deadletter"
+	"github.com/deedubs/choochoo/internal/discussion"
+	"github.com/deedubs/choochoo/internal/docs"
+	"github.com/deedubs/choochoo/internal/encryption"
+	"github.com/deedubs/choochoo/internal/eventchain"
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/github"
 	"github.com/deedubs/choochoo/internal/handlers"
+	"github.com/deedubs/choochoo/internal/indexadvisor"
+	"github.com/deedubs/choochoo/internal/ipallow"
+	"github.com/deedubs/choochoo/internal/maintenance"
+	"github.com/deedubs/choochoo/internal/metrics"
+	"github.com/deedubs/choochoo/internal/notifier"
+	"github.com/deedubs/choochoo/internal/outbound"
+	"github.com/deedubs/choochoo/internal/outbox"
+	"github.com/deedubs/choochoo/internal/pathlabels"
+	"github.com/deedubs/choochoo/internal/pipeline"
+	"github.com/deedubs/choochoo/internal/privacy"
+	"github.com/deedubs/choochoo/internal/project"
+	"github.com/deedubs/choochoo/internal/querycache"
+	"github.com/deedubs/choochoo/internal/ratelimit"
+	"github.com/deedubs/choochoo/internal/receipt"
+	"github.com/deedubs/choochoo/internal/redact"
+	"github.com/deedubs/choochoo/internal/relay"
+	"github.com/deedubs/choochoo/internal/repohealth"
+	"github.com/deedubs/choochoo/internal/retention"
+	"github.com/deedubs/choochoo/internal/rules"
+	"github.com/deedubs/choochoo/internal/scim"
+	"github.com/deedubs/choochoo/internal/security"
+	"github.com/deedubs/choochoo/internal/selfcheck"
+	"github.com/deedubs/choochoo/internal/settings"
+	"github.com/deedubs/choochoo/internal/status"
+	"github.com/deedubs/choochoo/internal/statuspage"
+	"github.com/deedubs/choochoo/internal/stream"
+	"github.com/deedubs/choochoo/internal/tracing"
+	"github.com/deedubs/choochoo/internal/usage"
+	"github.com/deedubs/choochoo/internal/webhook"
+	"github.com/deedubs/choochoo/internal/workload"
+	"github.com/deedubs/choochoo/internal/workqueue"
+	"github.com/deedubs/choochoo/sql/migrations"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // WebhookServer represents the main server
 type WebhookServer struct {
-	webhookSecret string
-	port          string
-	dbConn        *database.Connection
+	webhookSecret     string
+	sha1Fallback      bool
+	relays            *relay.Verifier
+	identities        *workload.Verifier
+	maxBodySize       int64
+	auditLogToken     string
+	auth              *apitoken.Authenticator
+	adminUsername     string
+	adminPassword     string
+	auditAlertActions map[string]bool
+	port              string
+	grpcPort          string
+	tlsCertFile       string
+	tlsKeyFile        string
+	autocertHosts     []string
+	autocertEmail     string
+	autocertCacheDir  string
+	redirectPort      string
+	dbConn            *database.Connection
+	events            database.EventStore
+	forwarders        []forwarder.Forwarder
+	securityRouter    *security.Router
+	securitySLA       security.SLA
+	streamHub         *stream.Hub
+	wsClientBuffer    int
+	deadLetter        *deadletter.Spool
+	deadLetterRetry   time.Duration
+	accessReviewDir   string
+	accessReviewEvery time.Duration
+	digestRoutes      []community.Route
+	digestEvery       time.Duration
+	discussionRouter  *discussion.Router
+	commands          *chatops.Registry
+	projectRouter     *project.Router
+	docsRouter        *docs.Router
+	notifiers         *notifier.Set
+	settings          *settings.Bundle
+	configLint        *github.Client
+	pipelineChecks    *checkrun.Reporter
+	pathLabels        *pathlabels.Labeler
+	commentPolicy     chatops.Policy
+	commentClient     *github.Client
+	receipts          *receipt.Poster
+	healthTargets     repohealth.Targets
+	statsPrivacy      *privacy.Policy
+	janitor           *retention.Janitor
+	janitorEvery      time.Duration
+	sealer            *eventchain.Sealer
+	sealerEvery       time.Duration
+	selfCheck         *selfcheck.Checker
+	allowlist         *ipallow.Allowlist
+	workQueue         *workqueue.Queue
+	queueDepth        *workqueue.Depth
+	queryCache        *querycache.Cache
+	indexAdvisor      *indexadvisor.Advisor
+	indexCreator      *indexadvisor.Creator
+	maintenance       *maintenance.Job
+	maintenanceEvery  time.Duration
+	workQueueConfig   workqueue.Config
+	outbox            *outbox.Relay
+	outboxForwarders  []forwarder.Forwarder
+	batchWriter       *database.BatchWriter
+	approvals         *approval.Queue
+	redactor          *redact.Redactor
+	payloadKeys       *encryption.Keyring
+	allowlistEvery    time.Duration
+	rateLimiter       *ratelimit.Limiter
+	outbound          *outbound.Log
+	processors        *pipeline.Runner
+	usageAlerts       usage.Alerts
+	usageMonitor      *usage.Monitor
+	usageEvery        time.Duration
+	capacityHistory   int
+	capacityLimits    capacity.Thresholds
+	capacityMonitor   *capacity.Monitor
+	capacityEvery     time.Duration
+	rules             *rules.Engine
+	rulesEvery        time.Duration
+	statusPage        *statuspage.Tracker
+	statusPageTitle   string
+	scim              *scim.Directory
+	metricsPusher     *metrics.Pusher
+	metricsEvery      time.Duration
+	tracing           bool
+	features          *status.Matrix
+	health            *handlers.HealthHandler
 }
 
-// NewWebhookServer creates a new webhook server instance
-func NewWebhookServer() *WebhookServer {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+// NewWebhookServer creates a new webhook server instance from cfg
+func NewWebhookServer(cfg *config.Config) *WebhookServer {
+	if len(cfg.WebhookSecrets()) == 0 {
+		log.Println("Warning: GITHUB_WEBHOOK_SECRET not set. Webhook signature validation will be skipped. Set CHOOCHOO_REQUIRE_SIGNATURE=true to refuse to start without it, which will become the default.")
 	}
 
-	webhookSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
-	if webhookSecret == "" {
-		log.Println("Warning: GITHUB_WEBHOOK_SECRET not set. Webhook signature validation will be skipped.")
+	if cfg.AuditLogToken == "" {
+		log.Println("AUDIT_LOG_TOKEN not set. Audit log streaming on /audit-log is disabled.")
 	}
 
-	// Initialize database connection if DATABASE_URL is set
+	// Trace deliveries from receipt through storage and fan-out. Spans are
+	// exported in batches as they end.
+	tracingEnabled := false
+	if cfg.OTelEndpoint != "" {
+		if _, err := tracing.Setup(context.Background(), cfg.Tracing()); err != nil {
+			log.Printf("Warning: %v. Traces will not be exported.", err)
+		} else {
+			log.Printf("Exporting traces to %s", cfg.OTelEndpoint)
+			tracingEnabled = true
+		}
+	}
+
+	// Initialize database connection if DATABASE_URL is set. A sqlite: URL
+	// stores events in SQLite instead, without the features that need
+	// PostgreSQL.
 	var dbConn *database.Connection
-	if os.Getenv("DATABASE_URL") != "" {
+	var events database.EventStore
+	if database.IsSQLite(cfg.DatabaseURL) {
+		store, err := database.NewSQLiteStore(context.Background(), cfg.DatabaseURL)
+		if err != nil {
+			log.Printf("Warning: Failed to open SQLite database: %v. Webhooks will be logged but not stored.", err)
+		} else {
+			log.Println("Storing events in SQLite. Features that need PostgreSQL are disabled.")
+			events = store
+		}
+	} else if cfg.DatabaseURL != "" {
 		ctx := context.Background()
 		var err error
-		dbConn, err = database.NewConnection(ctx)
+		dbConn, err = database.NewConnection(ctx, cfg.DatabaseURL)
 		if err != nil {
 			log.Printf("Warning: Failed to connect to database: %v. Webhooks will be logged but not stored.", err)
 		} else {
 			log.Println("Successfully connected to database")
+			events = dbConn
 		}
 	} else {
 		log.Println("Warning: DATABASE_URL not set. Webhooks will be logged but not stored in database.")
 	}
 
-	return &WebhookServer{
-		webhookSecret: webhookSecret,
-		port:          port,
-		dbConn:        dbConn,
+	// Settings applied with choochooctl, overridden by the config file and
+	// environment variables
+	if err := cfg.Fill(loadStoredSettings(dbConn)); err != nil {
+		log.Printf("Warning: Invalid %v. Ignoring remaining stored settings.", err)
+	}
+	if err := cfg.ValidateServe(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	auditAlertActions := auditlog.ParseAlertActions(cfg.AuditLogAlertActions)
+
+	// Initialize forwarders for publishing events to external systems. The
+	// stream hub is always registered so live stream clients see every event.
+	streamHub := stream.NewHub()
+	forwarders := []forwarder.Forwarder{streamHub}
+	if cfg.NATSURL != "" {
+		natsConfig := forwarder.NATSConfig{
+			URL:             cfg.NATSURL,
+			SubjectTemplate: cfg.NATSSubjectTemplate,
+			Stream:          cfg.NATSStream,
+		}
+		if cfg.NATSStreamSubjects != "" {
+			natsConfig.StreamSubjects = strings.Split(cfg.NATSStreamSubjects, ",")
+		}
+		natsForwarder, err := forwarder.NewNATSForwarder(context.Background(), natsConfig)
+		if err != nil {
+			log.Printf("Warning: Failed to initialize NATS forwarder: %v. Events will not be published to NATS.", err)
+		} else {
+			log.Printf("Publishing events to NATS at %s", cfg.NATSURL)
+			forwarders = append(forwarders, natsForwarder)
+		}
+	}
+	// Publish events to an SQS queue or SNS topic
+	if cfg.AWSPublishARN != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		awsForwarder, err := forwarder.NewAWSForwarder(ctx, cfg.AWSPublishARN)
+		cancel()
+		if err != nil {
+			log.Printf("Warning: Failed to initialize AWS forwarder: %v. Events will not be published to AWS.", err)
+		} else {
+			log.Printf("Publishing events to %s", awsForwarder.Name())
+			forwarders = append(forwarders, awsForwarder)
+		}
+	}
+	// Post the events matching SLACK_CONDITION to the configured channel
+	slack, err := cfg.Slack()
+	if err != nil {
+		log.Printf("Warning: %v. Events will not be posted to Slack.", err)
+	} else if slack != nil {
+		log.Printf("Posting events to %s", slack.Name())
+		forwarders = append(forwarders, slack)
+	}
+
+	// Configure severity-aware routing and SLA targets for security alerts
+	securityRoutes, err := security.ParseRoutes(cfg.SecurityAlertRoutes)
+	if err != nil {
+		log.Printf("Warning: Invalid SECURITY_ALERT_ROUTES: %v. Security alerts will not be routed.", err)
+		securityRoutes = nil
+	}
+	securitySLA, err := security.ParseSLA(cfg.SecurityAlertSLA)
+	if err != nil {
+		log.Printf("Warning: Invalid SECURITY_ALERT_SLA: %v. Using default SLA targets.", err)
+		securitySLA = security.DefaultSLA
+	}
+
+	// Spool failed database writes to disk so they can be retried
+	var deadLetter *deadletter.Spool
+	if events != nil {
+		deadLetter, err = deadletter.NewSpool(cfg.DeadLetterDir)
+		if err != nil {
+			log.Printf("Warning: %v. Failed database writes will not be retried.", err)
+		}
+	}
+
+	// Route discussion activity by category and register discussion commands
+	discussionRoutes, err := discussion.ParseRoutes(cfg.DiscussionRoutes)
+	if err != nil {
+		log.Printf("Warning: Invalid DISCUSSION_ROUTES: %v. Discussions will not be routed.", err)
+		discussionRoutes = nil
+	}
+	discussionRouter := discussion.NewRouter(discussionRoutes)
+	commands := chatops.NewRegistry()
+	commands.Register("notify", discussion.NotifyCommand(discussionRouter))
+
+	// Hold route, setting and rule changes until a second operator approves
+	// them, on the dashboard or with /approve in a discussion
+	var approvals *approval.Queue
+	if dbConn != nil && cfg.ChangeApproval {
+		approvals = approval.NewQueue(dbConn, cfg.ChangeApprovalExpiry)
+		approvers := approval.ParseApprovers(cfg.ChangeApprovers)
+		commands.Register("approve", approval.ApproveCommand(approvals, approvers))
+		commands.Register("reject", approval.RejectCommand(approvals, approvers))
+	}
+
+	// Scrub personal data and secrets from payloads before they are stored
+	redactPaths, err := redact.ParsePaths(cfg.RedactPaths)
+	if err != nil {
+		log.Printf("Warning: Invalid REDACT_PATHS: %v. No paths will be redacted.", err)
+		redactPaths = nil
+	}
+	redactor := redact.New(cfg.RedactEmails, cfg.RedactSecrets, redactPaths)
+
+	// Encrypt payloads before they are stored
+	payloadKeys, err := cfg.PayloadKeys()
+	if err != nil {
+		log.Printf("Warning: %v. Payloads will be stored unencrypted.", err)
+		payloadKeys = nil
+	}
+
+	// Notify channels when project items enter a column
+	projectRoutes, err := project.ParseRoutes(cfg.ProjectColumnRoutes)
+	if err != nil {
+		log.Printf("Warning: Invalid PROJECT_COLUMN_ROUTES: %v. Project items will not be routed.", err)
+		projectRoutes = nil
+	}
+
+	// Notify channels about wiki changes and failed Pages builds
+	docsRoutes, err := docs.ParseRoutes(cfg.DocsRoutes)
+	if err != nil {
+		log.Printf("Warning: Invalid DOCS_ROUTES: %v. Documentation changes will not be routed.", err)
+		docsRoutes = nil
+	}
+
+	// Opt-in community digests, routed per repository
+	digestRoutes, err := community.ParseRoutes(cfg.CommunityDigestRoutes)
+	if err != nil {
+		log.Printf("Warning: Invalid COMMUNITY_DIGEST_ROUTES: %v. Community digests will not be sent.", err)
+		digestRoutes = nil
+	}
+
+	// Channels of every route list, for test notifications
+	notifiers := notifier.NewSet()
+	for _, route := range securityRoutes {
+		notifiers.Add("security_alerts", route.MinSeverity, route.Channel)
+	}
+	for _, route := range discussionRoutes {
+		notifiers.Add("discussions", route.Category, route.Channel)
+	}
+	for _, route := range projectRoutes {
+		notifiers.Add("project_columns", route.Column, route.Channel)
+	}
+	for _, route := range docsRoutes {
+		notifiers.Add("docs", route.Kind, route.Channel)
+	}
+	for _, route := range digestRoutes {
+		notifiers.Add("community_digests", route.Pattern, route.Channel)
+	}
+
+	// Targets for per-repository delivery health scores
+	healthTargets, err := repohealth.ParseTargets(cfg.RepoHealthTargets)
+	if err != nil {
+		log.Printf("Warning: Invalid REPO_HEALTH_TARGETS: %v. Using default targets.", err)
+		healthTargets = repohealth.DefaultTargets
+	}
+
+	// Protect the activity stats served to tokens with only the stats scope
+	statsPrivacy, err := cfg.StatsPrivacy()
+	if err != nil {
+		log.Printf("Warning: %v. Activity stats are served to stats tokens unprotected.", err)
+	}
+
+	// Prune events past their retention period
+	var janitor *retention.Janitor
+	if dbConn != nil && cfg.RetentionPolicy != "" {
+		janitor = newJanitor(dbConn, cfg)
+	}
+
+	// Chain stored events and sign checkpoints of the chain
+	var sealer *eventchain.Sealer
+	if chainKey, err := cfg.EventChainKey(); err != nil {
+		log.Printf("Warning: %v. Stored events are not chained.", err)
+	} else if chainKey != nil && dbConn != nil {
+		sealer = eventchain.NewSealer(dbConn, payloadKeys, chainKey, cfg.EventChainCheckpointInterval)
+	}
+
+	// Only accept webhook deliveries from GitHub's published hooks ranges
+	trusted, _ := ipallow.ParsePrefixes(cfg.TrustedProxies)
+	var allowlist *ipallow.Allowlist
+	if cfg.GitHubIPAllowlist {
+		allowlist = ipallow.NewAllowlist(ipallow.MetaFetcher(cfg.GitHubAPIURL), trusted)
+	}
+
+	// Timeouts and concurrency caps of each processor
+	processorLimits, _ := pipeline.ParseLimits(cfg.ProcessorLimits, cfg.ProcessorDefaults())
+
+	// Record recent outbound requests of the forwarders and the channels of
+	// the route lists; the GitHub API client and the channels of rules are
+	// wrapped once they are created
+	var outboundLog *outbound.Log
+	if cfg.OutboundLogSize > 0 {
+		outboundLog = outbound.NewLog(cfg.OutboundLogSize, cfg.OutboundLogBodyBytes)
+		forwarder.WrapTransports(forwarders, outboundLog.Transport)
+		notifiers.WrapTransports(outboundLog.Transport)
+	}
+
+	// Limit how fast clients may call the webhook and query endpoints
+	var rateLimiter *ratelimit.Limiter
+	limits := ratelimit.Config{
+		PerIP:       cfg.RateLimitPerIP,
+		PerIPBurst:  cfg.RateLimitPerIPBurst,
+		Global:      cfg.RateLimitGlobal,
+		GlobalBurst: cfg.RateLimitGlobalBurst,
+	}
+	if limits.Enabled() {
+		rateLimiter = ratelimit.New(limits, func(r *http.Request) (netip.Addr, bool) {
+			return ipallow.ClientAddr(r, trusted)
+		})
+	}
+
+	// Cache aggregate query results until the events they depend on arrive
+	var queryCache *querycache.Cache
+	if cfg.QueryCacheTTL > 0 {
+		queryCache = querycache.New(cfg.QueryCacheTTL)
+	}
+
+	// Hostnames to obtain Let's Encrypt certificates for
+	var autocertHosts []string
+	for _, host := range strings.Split(cfg.TLSAutocertHosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			autocertHosts = append(autocertHosts, host)
+		}
+	}
+
+	// Check the GitHub permissions of the features that call the GitHub API
+	githubClient := newGitHubClient(cfg)
+	if githubClient != nil && outboundLog != nil {
+		githubClient.WrapTransport(outboundLog.Transport)
+	}
+	selfCheck := selfcheck.NewChecker(githubClient, githubRequirements(cfg))
+	var configLint *github.Client
+	if cfg.RepoConfigLint {
+		configLint = githubClient
+	}
+	var pipelineChecks *checkrun.Reporter
+	if cfg.PipelineChecks && githubClient != nil {
+		pipelineChecks = checkrun.New(githubClient)
+	}
+	// Label pull requests by the paths they change
+	var pathLabels *pathlabels.Labeler
+	labelRules, err := pathlabels.ParseRules(cfg.PathLabels)
+	if err != nil {
+		log.Printf("Warning: Invalid PATH_LABELS: %v. Pull requests will not be labeled by path.", err)
+		labelRules = nil
+	}
+	if len(labelRules) > 0 && githubClient != nil {
+		pathLabels = pathlabels.New(labelRules, githubClient)
+	}
+
+	// Run slash commands from issue and pull request comments, checking the
+	// role of their authors
+	commentPolicy, err := chatops.ParsePolicy(cfg.CommentCommands)
+	if err != nil {
+		log.Printf("Warning: Invalid COMMENT_COMMANDS: %v. Comment commands will not run.", err)
+		commentPolicy = nil
+	}
+	var commentClient *github.Client
+	if len(commentPolicy) > 0 {
+		commentClient = githubClient
+	}
+	var relays *relay.Verifier
+	if secrets, _ := cfg.Relays(); secrets != nil {
+		relays = relay.NewVerifier(secrets)
+	}
+
+	var receipts *receipt.Poster
+	if events, _ := cfg.Receipts(); len(events) > 0 && githubClient != nil {
+		receipts = receipt.New(events, cfg.ReceiptKind, cfg.ReceiptRateLimit, githubClient)
+	}
+
+	// Run the rules of the rules file and those stored in the database on
+	// processed events
+	var ruleEngine *rules.Engine
+	if cfg.RulesFile != "" || dbConn != nil {
+		var fileRules []rules.Rule
+		if cfg.RulesFile != "" {
+			fileRules, err = rules.LoadFile(cfg.RulesFile)
+			if err != nil {
+				log.Printf("Warning: Invalid RULES_FILE: %v. Only stored rules will run.", err)
+			}
+		}
+		var load rules.LoadFunc
+		if dbConn != nil {
+			load = handlers.LoadRules(dbConn.Queries())
+		}
+		ruleEngine = rules.NewEngine(fileRules, load).WithSlackToken(cfg.SlackBotToken)
+		if outboundLog != nil {
+			ruleEngine.WithTransportWrapper(outboundLog.Transport)
+		}
+		if githubClient != nil {
+			ruleEngine.WithLabeler(githubClient.AddLabels).WithStatusSetter(githubClient.CreateCommitStatus)
+		}
+		// Send the emails of email rule actions through the SMTP server
+		mailer, err := cfg.Mailer()
+		if err != nil {
+			log.Printf("Warning: %v. Rules will not send emails.", err)
+		} else if mailer != nil {
+			log.Printf("Sending rule emails through %s", mailer.Name())
+			ruleEngine.WithMailer(mailer)
+		}
+	}
+	// Approved rule changes take effect right away on this replica
+	if approvals != nil && ruleEngine != nil {
+		approvals.WithRuleReloader(ruleEngine.Reload)
+	}
+
+	ws := &WebhookServer{
+		webhookSecret:     cfg.WebhookSecret,
+		sha1Fallback:      cfg.WebhookSHA1Fallback,
+		relays:            relays,
+		maxBodySize:       cfg.MaxBodyBytes,
+		auditLogToken:     cfg.AuditLogToken,
+		auth:              newAuthenticator(cfg.ManagementAPIToken, dbConn),
+		adminUsername:     cfg.AdminUsername,
+		adminPassword:     cfg.AdminPassword,
+		auditAlertActions: auditAlertActions,
+		port:              cfg.Port,
+		grpcPort:          cfg.GRPCPort,
+		tlsCertFile:       cfg.TLSCertFile,
+		tlsKeyFile:        cfg.TLSKeyFile,
+		autocertHosts:     autocertHosts,
+		autocertEmail:     cfg.TLSAutocertEmail,
+		autocertCacheDir:  cfg.TLSAutocertCacheDir,
+		redirectPort:      cfg.TLSRedirectPort,
+		dbConn:            dbConn,
+		events:            events,
+		forwarders:        forwarders,
+		securityRouter:    security.NewRouter(securityRoutes),
+		securitySLA:       securitySLA,
+		streamHub:         streamHub,
+		wsClientBuffer:    cfg.WSClientBuffer,
+		deadLetter:        deadLetter,
+		deadLetterRetry:   cfg.DeadLetterRetryInterval,
+		accessReviewDir:   cfg.AccessReviewDir,
+		accessReviewEvery: cfg.AccessReviewInterval,
+		digestRoutes:      digestRoutes,
+		digestEvery:       cfg.CommunityDigestInterval,
+		discussionRouter:  discussionRouter,
+		commands:          commands,
+		approvals:         approvals,
+		redactor:          redactor,
+		payloadKeys:       payloadKeys,
+		projectRouter:     project.NewRouter(projectRoutes),
+		docsRouter:        docs.NewRouter(docsRoutes),
+		notifiers:         notifiers,
+		settings:          newSettings(cfg, dbConn),
+		configLint:        configLint,
+		pipelineChecks:    pipelineChecks,
+		pathLabels:        pathLabels,
+		commentPolicy:     commentPolicy,
+		commentClient:     commentClient,
+		receipts:          receipts,
+		healthTargets:     healthTargets,
+		statsPrivacy:      statsPrivacy,
+		janitor:           janitor,
+		janitorEvery:      cfg.RetentionInterval,
+		sealer:            sealer,
+		sealerEvery:       cfg.EventChainInterval,
+		selfCheck:         selfCheck,
+		allowlist:         allowlist,
+		allowlistEvery:    cfg.GitHubIPAllowlistRefresh,
+		rateLimiter:       rateLimiter,
+		queryCache:        queryCache,
+		rules:             ruleEngine,
+		rulesEvery:        cfg.RulesReloadInterval,
+		outbound:          outboundLog,
+		tracing:           tracingEnabled,
+		processors:        pipeline.NewRunner(cfg.ProcessorDefaults(), processorLimits),
+		workQueueConfig: workqueue.Config{
+			Workers:           cfg.WorkQueueWorkers,
+			VisibilityTimeout: cfg.WorkQueueVisibilityTimeout,
+			MaxAttempts:       cfg.WorkQueueMaxAttempts,
+			PollInterval:      cfg.WorkQueuePollInterval,
+		},
+	}
+
+	// Let the authorization policy decide which scopes API and dashboard
+	// requests may use, denying every request if it cannot be set up
+	if policy, err := cfg.Authorization(); err != nil {
+		log.Printf("Warning: %v. API and dashboard requests are denied.", err)
+		ws.auth.WithPolicy(authz.DenyAll(err))
+	} else if policy != nil {
+		ws.auth.WithPolicy(policy)
+	}
+
+	// Provision users and groups over SCIM, signing users in with the roles
+	// their groups are mapped to
+	if roles, err := cfg.SCIMRoles(); err != nil {
+		log.Printf("Warning: %v. SCIM provisioning is disabled.", err)
+	} else if roles != nil && dbConn != nil {
+		ws.scim = scim.NewDirectory(scim.NewPostgresStore(dbConn), roles)
+		ws.auth.WithUsers(ws.scim)
+	}
+
+	// Accept the ID tokens of CI jobs and workloads from the trusted issuers
+	if cfg.WorkloadIdentityFile != "" {
+		if issuers, err := workload.LoadFile(cfg.WorkloadIdentityFile); err != nil {
+			log.Printf("Warning: Invalid WORKLOAD_IDENTITY_FILE: %v. ID tokens are not accepted.", err)
+		} else {
+			ws.identities = workload.NewVerifier(issuers)
+			ws.auth.WithIdentities(ws.identities)
+		}
+	}
+
+	// Process stored events from the database-backed work queue so pending
+	// work survives restarts and is shared between replicas
+	if dbConn != nil && cfg.WorkQueueWorkers > 0 {
+		ws.workQueue = workqueue.New(workqueue.NewPostgresStore(dbConn.Queries(), ws.workQueueConfig), ws.processQueued, ws.workQueueConfig)
+		ws.queueDepth = workqueue.NewDepth(workqueue.PostgresDepth(dbConn.Queries()), time.Second)
+	}
+	// Publish stored events to the external forwarders through the outbox,
+	// so a publish that fails after the event was stored is retried. The
+	// stream hub only serves connected clients and is published to directly.
+	if dbConn != nil && cfg.OutboxEnabled {
+		ws.forwarders, ws.outboxForwarders = []forwarder.Forwarder{streamHub}, forwarders[1:]
+		var targets []string
+		for _, f := range ws.outboxForwarders {
+			targets = append(targets, f.Name())
+		}
+		dbConn.WithOutbox(targets...)
+		ws.outbox = outbox.New(outbox.NewPostgresStore(dbConn.Queries()), ws.publish, outbox.Config{
+			PollInterval: cfg.OutboxPollInterval,
+			Retention:    cfg.OutboxRetention,
+		})
+	}
+	// Store events in batches under load
+	if dbConn != nil && cfg.EventBatchSize > 0 {
+		ws.batchWriter = database.NewBatchWriter(dbConn.WriteBatch, cfg.EventBatchSize, cfg.EventBatchDelay)
 	}
+	ws.usageAlerts = usage.Alerts{GrowthPercent: cfg.UsageAlertGrowthPercent, MinSharePercent: cfg.UsageAlertMinSharePercent}
+	ws.usageEvery = cfg.UsageAlertInterval
+	if dbConn != nil && ws.usageAlerts.Enabled() {
+		ws.usageMonitor = usage.NewMonitor(handlers.LoadUsage(dbConn.Queries()), ws.usageAlerts)
+	}
+	ws.capacityHistory = cfg.CapacityHistoryDays
+	ws.capacityLimits = cfg.CapacityThresholds()
+	ws.capacityEvery = cfg.CapacityCheckInterval
+	if dbConn != nil && ws.capacityLimits.Enabled() {
+		ws.capacityMonitor = capacity.NewMonitor(handlers.LoadCapacity(dbConn.Queries()), ws.capacityHistory, ws.capacityLimits)
+	}
+	// Track slow query API patterns on the events table and suggest, or
+	// build, the indexes they need
+	if dbConn != nil && cfg.IndexAdvisorSlowQuery > 0 {
+		ws.indexAdvisor = indexadvisor.New(cfg.IndexAdvisorSlowQuery)
+		if cfg.IndexAdvisorAutoCreate {
+			ws.indexCreator = indexadvisor.NewCreator(ws.indexAdvisor, indexadvisor.LoadIndexes(dbConn.Queries()), dbConn.CreateIndexConcurrently)
+		}
+	}
+	// Vacuum, analyze and reindex on a schedule, or when an operator asks
+	ws.maintenanceEvery = cfg.MaintenanceInterval
+	if dbConn != nil {
+		ws.maintenance = maintenance.NewJob(cfg.MaintenancePolicy(), maintenance.LoadTables(dbConn.Queries()), indexadvisor.LoadIndexes(dbConn.Queries()), dbConn.RunMaintenance)
+	}
+	// Track deliveries and processing latency for the public status page
+	if cfg.StatusPageEnabled {
+		ws.statusPage = statuspage.NewTracker()
+		ws.statusPageTitle = cfg.StatusPageTitle
+	}
+	// Push business metrics for installs without a Prometheus scraper
+	ws.metricsEvery = cfg.MetricsPushInterval
+	if dbConn != nil && cfg.MetricsPushURL != "" {
+		sink, _ := metrics.NewSink(cfg.MetricsPushURL)
+		ws.metricsPusher = metrics.NewPusher(ws.loadBusinessMetrics, sink, cfg.MetricsPushWindow)
+	}
+	ws.features = newFeatureStatus(cfg, ws)
+	ws.health = newHealthHandler(cfg, ws)
+	return ws
+}
+
+// newHealthHandler creates the health handler with the readiness checks of
+// the configured components
+func newHealthHandler(cfg *config.Config, ws *WebhookServer) *handlers.HealthHandler {
+	health := handlers.NewHealthHandler()
+	if cfg.DatabaseURL == "" {
+		return health
+	}
+
+	health.WithCheck("database", func(ctx context.Context) error {
+		switch {
+		case ws.events == nil:
+			return errors.New("failed to connect at startup")
+		case !ws.events.IsConnected(ctx):
+			return errors.New("database unreachable")
+		}
+		return nil
+	})
+	if database.IsSQLite(cfg.DatabaseURL) {
+		// The SQLite schema is created when the store is opened
+		return health
+	}
+	health.WithCheck("migrations", func(ctx context.Context) error {
+		if ws.dbConn == nil {
+			return errors.New("no database connection")
+		}
+		pending, err := ws.dbConn.PendingMigrations(ctx, migrations.FS)
+		if err != nil {
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d migrations pending, starting with %s; run choochoo migrate", len(pending), pending[0].Name)
+		}
+		return nil
+	})
+	if ws.workQueue != nil && cfg.ReadinessMaxQueueDepth > 0 {
+		health.WithCheck("work_queue", func(ctx context.Context) error {
+			counts, err := ws.dbConn.Queries().CountWorkItems(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to read the queue: %w", err)
+			}
+			if counts.Pending > int64(cfg.ReadinessMaxQueueDepth) {
+				return fmt.Errorf("%d events pending, more than %d", counts.Pending, cfg.ReadinessMaxQueueDepth)
+			}
+			return nil
+		})
+	}
+	return health
+}
+
+// newFeatureStatus records which features are running, disabled by
+// configuration, or degraded because something they depend on is unavailable
+func newFeatureStatus(cfg *config.Config, ws *WebhookServer) *status.Matrix {
+	features := status.NewMatrix()
+
+	// configured reports a feature as disabled when its setting is empty
+	configured := func(name, value, setting string) bool {
+		if value == "" {
+			features.Set(name, status.Disabled, setting+" not set")
+			return false
+		}
+		return true
+	}
+
+	features.Register("database", func(ctx context.Context) (string, string) {
+		switch {
+		case cfg.DatabaseURL == "":
+			return status.Disabled, "DATABASE_URL not set"
+		case ws.events == nil:
+			return status.Degraded, "failed to connect at startup; events are not stored"
+		case !ws.events.IsConnected(ctx):
+			return status.Degraded, "database unreachable"
+		case ws.dbConn == nil:
+			return status.OK, "SQLite; features that need PostgreSQL are disabled"
+		}
+		return status.OK, ""
+	})
+
+	if configured("webhook_signatures", cfg.WebhookSecret, "GITHUB_WEBHOOK_SECRET") {
+		features.Set("webhook_signatures", status.OK, "")
+	}
+
+	switch {
+	case ws.events == nil:
+		features.Set("dead_letter", status.Disabled, "no database")
+	case ws.deadLetter == nil:
+		features.Set("dead_letter", status.Degraded, "spool directory unavailable; failed writes are not retried")
+	default:
+		features.Set("dead_letter", status.OK, "")
+	}
+
+	switch {
+	case ws.dbConn == nil:
+		features.Set("work_queue", status.Disabled, "no database; events are processed during the request")
+	case ws.workQueue == nil:
+		features.Set("work_queue", status.Disabled, "WORK_QUEUE_WORKERS is 0; events are processed during the request")
+	default:
+		features.Register("work_queue", func(ctx context.Context) (string, string) {
+			counts, err := ws.dbConn.Queries().CountWorkItems(ctx)
+			if err != nil {
+				return status.Degraded, "failed to read the queue"
+			}
+			if pause, err := ws.dbConn.Queries().GetWorkQueuePause(ctx); err == nil {
+				return status.Degraded, fmt.Sprintf("paused by %s since %s, %d events pending", pause.PausedBy, pause.PausedAt.Time.UTC().Format(time.RFC3339), counts.Pending)
+			}
+			if counts.Quarantined > 0 {
+				return status.Degraded, fmt.Sprintf("%d quarantined events are not retried", counts.Quarantined)
+			}
+			return status.OK, ""
+		})
+	}
+
+	switch {
+	case !cfg.OutboxEnabled:
+		features.Set("outbox", status.Disabled, "OUTBOX_ENABLED not set; events are published during processing")
+	case ws.outbox == nil:
+		features.Set("outbox", status.Degraded, "no database; events are published during processing")
+	default:
+		features.Register("outbox", func(ctx context.Context) (string, string) {
+			counts, err := ws.dbConn.Queries().CountOutboxEntries(ctx)
+			if err != nil {
+				return status.Degraded, "failed to read the outbox"
+			}
+			if counts.Pending > 0 && counts.Oldest.Valid && time.Since(counts.Oldest.Time) > time.Minute {
+				return status.Degraded, fmt.Sprintf("%d events waiting to be published, the oldest since %s", counts.Pending, counts.Oldest.Time.UTC().Format(time.RFC3339))
+			}
+			return status.OK, ""
+		})
+	}
+
+	switch {
+	case cfg.EventBatchSize == 0:
+		features.Set("event_batching", status.Disabled, "EVENT_BATCH_SIZE not set")
+	case ws.batchWriter == nil:
+		features.Set("event_batching", status.Degraded, "no database; events are not stored")
+	default:
+		features.Set("event_batching", status.OK, "")
+	}
+
+	if configured("nats", cfg.NATSURL, "NATS_URL") {
+		if ws.forwardsTo("nats") {
+			features.Set("nats", status.OK, "")
+		} else {
+			features.Set("nats", status.Degraded, "failed to connect at startup; events are not published")
+		}
+	}
+
+	if configured("aws", cfg.AWSPublishARN, "AWS_PUBLISH_ARN") {
+		if ws.forwardsTo("sqs:") || ws.forwardsTo("sns:") {
+			features.Set("aws", status.OK, "")
+		} else {
+			features.Set("aws", status.Degraded, "failed to set up at startup; events are not published")
+		}
+	}
+
+	if configured("retention", cfg.RetentionPolicy, "RETENTION_POLICY") {
+		if ws.janitor != nil {
+			features.Set("retention", status.OK, "")
+		} else {
+			features.Set("retention", status.Degraded, "invalid policy or no database; events are not pruned")
+		}
+	}
+
+	if configured("event_chain", cfg.EventChainSigningKey+cfg.EventChainSigningKeyFile, "EVENT_CHAIN_SIGNING_KEY") {
+		if ws.sealer != nil {
+			features.Set("event_chain", status.OK, "")
+		} else {
+			features.Set("event_chain", status.Degraded, "invalid signing key or no database; events are not chained")
+		}
+	}
+
+	if configured("access_review", cfg.AccessReviewDir, "ACCESS_REVIEW_DIR") {
+		if ws.dbConn != nil {
+			features.Set("access_review", status.OK, "")
+		} else {
+			features.Set("access_review", status.Degraded, "no database; reviews are not exported")
+		}
+	}
+
+	if configured("community_digests", cfg.CommunityDigestRoutes, "COMMUNITY_DIGEST_ROUTES") {
+		if ws.dbConn != nil && len(ws.digestRoutes) > 0 {
+			features.Set("community_digests", status.OK, "")
+		} else {
+			features.Set("community_digests", status.Degraded, "invalid routes or no database; digests are not sent")
+		}
+	}
+
+	if configured("grpc", cfg.GRPCPort, "GRPC_PORT") {
+		features.Set("grpc", status.OK, "")
+	}
+
+	if ws.rules != nil {
+		features.Set("rules", status.OK, "")
+	} else {
+		features.Set("rules", status.Disabled, "RULES_FILE not set and no database")
+	}
+
+	if configured("email", cfg.SMTPHost, "SMTP_HOST") {
+		if mailer, err := cfg.Mailer(); err == nil && mailer != nil && ws.rules != nil {
+			features.Set("email", status.OK, "")
+		} else {
+			features.Set("email", status.Degraded, "invalid SMTP settings or no rules; emails are not sent")
+		}
+	}
+
+	if configured("slack", cfg.SlackWebhookURL+cfg.SlackChannel, "SLACK_WEBHOOK_URL or SLACK_CHANNEL") {
+		if ws.forwardsTo("slack:") {
+			features.Set("slack", status.OK, "")
+		} else {
+			features.Set("slack", status.Degraded, "invalid Slack settings; events are not posted")
+		}
+	}
+
+	if cfg.GitHubIPAllowlist {
+		features.Register("github_ip_allowlist", func(context.Context) (string, string) {
+			if loaded, _ := ws.allowlist.Loaded(); !loaded {
+				return status.Degraded, "GitHub hook ranges not loaded; webhooks are rejected"
+			}
+			return status.OK, ""
+		})
+	} else {
+		features.Set("github_ip_allowlist", status.Disabled, "GITHUB_IP_ALLOWLIST not set")
+	}
+
+	switch {
+	case !ws.usageAlerts.Enabled():
+		features.Set("usage_alerts", status.Disabled, "USAGE_ALERT_GROWTH_PERCENT not set")
+	case ws.usageMonitor == nil:
+		features.Set("usage_alerts", status.Degraded, "no database; usage is not checked")
+	default:
+		features.Set("usage_alerts", status.OK, "")
+	}
+
+	switch {
+	case !ws.capacityLimits.Enabled():
+		features.Set("capacity_alerts", status.Disabled, "CAPACITY_DISK_LIMIT_GB and CAPACITY_MONTHLY_BUDGET not set")
+	case ws.capacityMonitor == nil:
+		features.Set("capacity_alerts", status.Degraded, "no database; capacity is not checked")
+	default:
+		features.Set("capacity_alerts", status.OK, "")
+	}
+
+	switch {
+	case cfg.IndexAdvisorSlowQuery == 0:
+		features.Set("index_advisor", status.Disabled, "INDEX_ADVISOR_SLOW_QUERY is 0")
+	case ws.indexAdvisor == nil:
+		features.Set("index_advisor", status.Degraded, "no database; queries are not tracked")
+	default:
+		features.Set("index_advisor", status.OK, "")
+	}
+
+	switch {
+	case cfg.MaintenanceInterval == 0:
+		features.Set("table_maintenance", status.Disabled, "MAINTENANCE_INTERVAL not set")
+	case ws.maintenance == nil:
+		features.Set("table_maintenance", status.Degraded, "no database; tables are not maintained")
+	default:
+		features.Register("table_maintenance", func(context.Context) (string, string) {
+			stats := ws.maintenance.Stats()
+			var failed int
+			for _, result := range stats.LastResults {
+				if result.Error != "" {
+					failed++
+				}
+			}
+			if stats.LastError != "" {
+				return status.Degraded, "last run failed: " + stats.LastError
+			}
+			if failed > 0 {
+				return status.Degraded, fmt.Sprintf("%d tasks of the last run failed", failed)
+			}
+			return status.OK, ""
+		})
+	}
+
+	if ws.statusPage != nil {
+		features.Set("status_page", status.OK, "")
+	} else {
+		features.Set("status_page", status.Disabled, "STATUS_PAGE_ENABLED not set")
+	}
+
+	if configured("metrics_push", cfg.MetricsPushURL, "METRICS_PUSH_URL") {
+		if ws.metricsPusher != nil {
+			features.Set("metrics_push", status.OK, "")
+		} else {
+			features.Set("metrics_push", status.Degraded, "no database; metrics are not pushed")
+		}
+	}
+
+	switch {
+	case !cfg.RepoConfigLint:
+		features.Set("repo_config_lint", status.Disabled, "REPO_CONFIG_LINT not set")
+	case ws.configLint == nil:
+		features.Set("repo_config_lint", status.Degraded, "GitHub App key unavailable; settings files are not linted")
+	default:
+		features.Set("repo_config_lint", status.OK, "")
+	}
+
+	switch {
+	case !cfg.PipelineChecks:
+		features.Set("pipeline_checks", status.Disabled, "PIPELINE_CHECKS not set")
+	case ws.pipelineChecks == nil:
+		features.Set("pipeline_checks", status.Degraded, "GitHub App key unavailable; processing is not reported as check runs")
+	default:
+		features.Set("pipeline_checks", status.OK, "")
+	}
+
+	switch {
+	case cfg.PathLabels == "":
+		features.Set("path_labels", status.Disabled, "PATH_LABELS not set")
+	case ws.pathLabels == nil:
+		features.Set("path_labels", status.Degraded, "GitHub credentials unavailable or PATH_LABELS invalid; pull requests are not labeled by path")
+	default:
+		features.Set("path_labels", status.OK, "")
+	}
+
+	switch {
+	case cfg.CommentCommands == "":
+		features.Set("comment_commands", status.Disabled, "COMMENT_COMMANDS not set")
+	case ws.commentClient == nil:
+		features.Set("comment_commands", status.Degraded, "GitHub credentials unavailable or COMMENT_COMMANDS invalid; comment commands do not run")
+	default:
+		features.Set("comment_commands", status.OK, "")
+	}
+
+	if cfg.WebhookRelaySecrets != "" {
+		features.Set("relays", status.OK, "")
+	} else {
+		features.Set("relays", status.Disabled, "WEBHOOK_RELAY_SECRETS not set")
+	}
+
+	switch {
+	case cfg.ReceiptEvents == "":
+		features.Set("receipts", status.Disabled, "RECEIPT_EVENTS not set")
+	case ws.receipts == nil:
+		features.Set("receipts", status.Degraded, "GitHub App key unavailable; receipts are not posted")
+	default:
+		features.Set("receipts", status.OK, "")
+	}
+
+	if ws.rateLimiter != nil {
+		features.Set("rate_limit", status.OK, "")
+	} else {
+		features.Set("rate_limit", status.Disabled, "RATE_LIMIT_PER_IP and RATE_LIMIT_GLOBAL not set")
+	}
+
+	if ws.outbound != nil {
+		features.Set("outbound_log", status.OK, "")
+	} else {
+		features.Set("outbound_log", status.Disabled, "OUTBOUND_LOG_SIZE is 0")
+	}
+
+	if ws.queryCache != nil {
+		features.Set("query_cache", status.OK, "")
+	} else {
+		features.Set("query_cache", status.Disabled, "QUERY_CACHE_TTL is 0")
+	}
+
+	if configured("tracing", cfg.OTelEndpoint, "OTEL_EXPORTER_OTLP_ENDPOINT") {
+		if ws.tracing {
+			features.Set("tracing", status.OK, "")
+		} else {
+			features.Set("tracing", status.Degraded, "failed to create exporter; traces are not exported")
+		}
+	}
+
+	if configured("management_api", cfg.ManagementAPIToken+cfg.DatabaseURL, "MANAGEMENT_API_TOKEN or DATABASE_URL") {
+		features.Set("management_api", status.OK, "")
+	}
+
+	switch {
+	case cfg.AuthzOPAURL != "":
+		features.Set("authorization_policy", status.OK, "decided by OPA")
+	case cfg.AuthzCondition != "":
+		features.Set("authorization_policy", status.OK, "decided by AUTHZ_CONDITION")
+	default:
+		features.Set("authorization_policy", status.Disabled, "AUTHZ_OPA_URL and AUTHZ_CONDITION not set; token scopes apply")
+	}
+
+	switch {
+	case cfg.SCIMGroupRoles == "":
+		features.Set("scim", status.Disabled, "SCIM_GROUP_ROLES not set")
+	case ws.scim == nil:
+		features.Set("scim", status.Degraded, "no database or invalid SCIM_GROUP_ROLES; users cannot be provisioned")
+	default:
+		features.Set("scim", status.OK, "")
+	}
+
+	switch {
+	case cfg.WorkloadIdentityFile == "":
+		features.Set("workload_identity", status.Disabled, "WORKLOAD_IDENTITY_FILE not set")
+	case ws.identities == nil:
+		features.Set("workload_identity", status.Degraded, "invalid WORKLOAD_IDENTITY_FILE; ID tokens are not accepted")
+	default:
+		features.Set("workload_identity", status.OK, "")
+	}
+
+	switch {
+	case !cfg.ChangeApproval:
+		features.Set("change_approval", status.Disabled, "CHANGE_APPROVAL not set")
+	case ws.approvals == nil:
+		features.Set("change_approval", status.Degraded, "no database; changes cannot be made")
+	default:
+		features.Set("change_approval", status.OK, "")
+	}
+
+	if ws.redactor.Enabled() {
+		features.Set("redaction", status.OK, "")
+	} else {
+		features.Set("redaction", status.Disabled, "REDACT_EMAILS, REDACT_SECRETS and REDACT_PATHS not set")
+	}
+
+	if configured("payload_encryption", cfg.PayloadEncryptionKeys+cfg.PayloadEncryptionKeysFile, "PAYLOAD_ENCRYPTION_KEYS or PAYLOAD_ENCRYPTION_KEYS_FILE") {
+		if ws.payloadKeys != nil {
+			features.Set("payload_encryption", status.OK, "")
+		} else {
+			features.Set("payload_encryption", status.Degraded, "failed to load keys; payloads are stored unencrypted")
+		}
+	}
+
+	if configured("admin_dashboard", cfg.AdminPassword+cfg.ManagementAPIToken+cfg.DatabaseURL, "ADMIN_PASSWORD, MANAGEMENT_API_TOKEN or DATABASE_URL") {
+		features.Set("admin_dashboard", status.OK, "")
+	}
+
+	features.Register("github_api", func(context.Context) (string, string) {
+		report := ws.selfCheck.Last()
+		switch {
+		case report.CheckedAt.IsZero():
+			return status.OK, "self-check pending"
+		case !report.Configured:
+			return status.Disabled, "GITHUB_TOKEN or GITHUB_APP_ID not set"
+		case report.Error != "":
+			return status.Degraded, report.Error
+		}
+		var missing []string
+		for _, feature := range report.Degraded() {
+			missing = append(missing, feature.Feature+": "+feature.Reason)
+		}
+		if len(missing) > 0 {
+			return status.Degraded, strings.Join(missing, "; ")
+		}
+		return status.OK, ""
+	})
+
+	return features
+}
+
+// githubRequirements lists the GitHub permissions the enabled features need.
+// Features that call the GitHub API add their permissions here so the
+// self-check can report them as degraded when the credentials do not grant
+// them.
+func githubRequirements(cfg *config.Config) []selfcheck.Requirement {
+	requirements := []selfcheck.Requirement{}
+	if cfg.RepoConfigLint {
+		requirements = append(requirements,
+			selfcheck.Requirement{Feature: "repo_config_lint", Permission: "contents", Access: github.Read},
+			selfcheck.Requirement{Feature: "repo_config_lint", Permission: "checks", Access: github.Write},
+		)
+	}
+	if cfg.PipelineChecks {
+		requirements = append(requirements, selfcheck.Requirement{Feature: "pipeline_checks", Permission: "checks", Access: github.Write})
+	}
+	if cfg.PathLabels != "" {
+		// Listing the files of a pull request and labeling it
+		requirements = append(requirements, selfcheck.Requirement{Feature: "path_labels", Permission: "pull_requests", Access: github.Write})
+	}
+	if cfg.CommentCommands != "" {
+		// Reacting to comments and sending repository_dispatch events
+		requirements = append(requirements,
+			selfcheck.Requirement{Feature: "comment_commands", Permission: "issues", Access: github.Write},
+			selfcheck.Requirement{Feature: "comment_commands", Permission: "contents", Access: github.Write},
+		)
+	}
+	if cfg.ReceiptEvents != "" {
+		// Commit comments need write access to the repository contents
+		permission := "checks"
+		if cfg.ReceiptKind == receipt.KindComment {
+			permission = "contents"
+		}
+		requirements = append(requirements, selfcheck.Requirement{Feature: "receipts", Permission: permission, Access: github.Write})
+	}
+	return requirements
+}
+
+// newGitHubClient creates a GitHub API client from the GITHUB_TOKEN or
+// GITHUB_APP_* settings, returning nil if neither is set or the app key
+// cannot be read
+func newGitHubClient(cfg *config.Config) *github.Client {
+	if cfg.GitHubAppID != 0 {
+		data, err := os.ReadFile(cfg.GitHubAppPrivateKeyPath)
+		if err == nil {
+			var key *rsa.PrivateKey
+			key, err = github.ParsePrivateKey(data)
+			if err == nil {
+				return github.NewAppClient(cfg.GitHubAPIURL, github.App{ID: cfg.GitHubAppID, InstallationID: cfg.GitHubAppInstallationID, PrivateKey: key})
+			}
+		}
+		log.Printf("Warning: Failed to load GITHUB_APP_PRIVATE_KEY_PATH: %v. GitHub API features will be degraded.", err)
+		return nil
+	}
+	if cfg.GitHubToken != "" {
+		return github.NewTokenClient(cfg.GitHubAPIURL, cfg.GitHubToken)
+	}
+	return nil
+}
+
+// newJanitor creates the retention janitor from the RETENTION_* settings,
+// returning nil if the policy is invalid
+func newJanitor(dbConn *database.Connection, cfg *config.Config) *retention.Janitor {
+	policy, err := retention.ParsePolicy(cfg.RetentionPolicy)
+	if err != nil {
+		log.Printf("Warning: Invalid RETENTION_POLICY: %v. Events will not be pruned.", err)
+		return nil
+	}
+
+	switch cfg.RetentionMode {
+	case "", retention.ModeDelete:
+		return retention.NewJanitor(policy, retention.ModeDelete, cfg.RetentionBatchSize, retention.DeleteFunc(dbConn.Queries()))
+	case retention.ModeArchive:
+		return retention.NewJanitor(policy, retention.ModeArchive, cfg.RetentionBatchSize, retention.ArchiveFunc(dbConn.Queries()))
+	default:
+		log.Printf("Warning: Invalid RETENTION_MODE %q. Events will not be pruned.", cfg.RetentionMode)
+		return nil
+	}
+}
+
+// newAuthenticator accepts the management token and, with a database, the
+// stored API tokens
+func newAuthenticator(managementToken string, dbConn *database.Connection) *apitoken.Authenticator {
+	var store apitoken.Store
+	if dbConn != nil {
+		store = apitoken.NewPostgresStore(dbConn.Queries())
+	}
+	return apitoken.NewAuthenticator(managementToken, store)
+}
+
+// loadStoredSettings loads the settings applied with choochooctl, if any
+func loadStoredSettings(dbConn *database.Connection) map[string]string {
+	if dbConn == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stored, err := settings.Load(ctx, dbConn.Queries())
+	if err != nil {
+		log.Printf("Warning: Failed to load stored settings: %v. Using the config file and environment variables only.", err)
+		return nil
+	}
+	if len(stored) > 0 {
+		log.Printf("Loaded %d stored settings", len(stored))
+	}
+	return stored
+}
+
+// newSettings builds the settings hierarchy from the instance settings the
+// server runs with and the organization, repository and branch overrides
+// applied with choochooctl
+func newSettings(cfg *config.Config, dbConn *database.Connection) *settings.Bundle {
+	instance := make(map[string]string)
+	for _, name := range settings.Names() {
+		if value := cfg.String(name); value != "" && value != "0" {
+			instance[name] = value
+		}
+	}
+
+	var overrides map[string]map[string]string
+	if dbConn != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var err error
+		overrides, err = settings.LoadOverrides(ctx, dbConn.Queries())
+		if err != nil {
+			log.Printf("Warning: Failed to load setting overrides: %v. Using the instance settings for every repository.", err)
+		} else if len(overrides) > 0 {
+			log.Printf("Loaded setting overrides for %d organizations, repositories and branches", len(overrides))
+		}
+	}
+	return settings.FromStored(instance, overrides)
+}
+
+// forwardsTo reports whether events are forwarded to a forwarder whose name
+// starts with prefix
+func (ws *WebhookServer) forwardsTo(prefix string) bool {
+	for _, f := range slices.Concat(ws.forwarders, ws.outboxForwarders) {
+		if strings.HasPrefix(f.Name(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookHandler creates the webhook handler with the server's processing
+// pipeline
+func (ws *WebhookServer) webhookHandler() *handlers.WebhookHandler {
+	return handlers.NewWebhookHandler(ws.webhookSecret, ws.dbConn).WithEventStore(ws.events).
+		WithForwarders(ws.forwarders...).
+		WithOutbox(ws.notifyOutbox(), ws.outboxForwarders...).
+		WithMaxBodySize(ws.maxBodySize).
+		WithSHA1Fallback(ws.sha1Fallback).
+		WithRelays(ws.relays).
+		WithQueueDepth(ws.queueDepth).
+		WithSecurityRouter(ws.securityRouter).
+		WithAuditAlerts(ws.auditAlertActions).
+		WithDiscussionRouter(ws.discussionRouter).
+		WithCommands(ws.commands).
+		WithProjectRouter(ws.projectRouter).
+		WithDocsRouter(ws.docsRouter).
+		WithDeadLetter(ws.deadLetter).
+		WithProcessors(ws.processors).
+		WithSettings(ws.settings).
+		WithConfigLint(ws.configLint).
+		WithReceipts(ws.receipts).
+		WithPipelineChecks(ws.pipelineChecks).
+		WithPathLabels(ws.pathLabels).
+		WithCommentCommands(ws.commentPolicy, ws.commentClient).
+		WithRedactor(ws.redactor).
+		WithEncryption(ws.payloadKeys).
+		WithBatchWriter(ws.batchWriter).
+		WithRules(ws.rules).
+		WithLatencyObserver(ws.observeLatency()).
+		WithInvalidation(ws.invalidateQueries())
+}
+
+// invalidateQueries returns the function told of each stored and processed
+// event, or nil without a query cache
+func (ws *WebhookServer) invalidateQueries() func(eventType, repository string) {
+	if ws.queryCache == nil {
+		return nil
+	}
+	return ws.queryCache.Invalidate
+}
+
+// observeLatency returns the function told the processing latency of each
+// event, or nil without a status page
+func (ws *WebhookServer) observeLatency() func(time.Duration) {
+	if ws.statusPage == nil {
+		return nil
+	}
+	return ws.statusPage.ObserveLatency
+}
+
+// notifyOutbox returns the function that wakes the outbox relay, or nil
+// without an outbox
+func (ws *WebhookServer) notifyOutbox() func() {
+	if ws.outbox == nil {
+		return nil
+	}
+	return ws.outbox.Notify
+}
+
+// publish publishes a stored delivery taken from the outbox to its forwarder
+func (ws *WebhookServer) publish(ctx context.Context, entry outbox.Entry) error {
+	return ws.webhookHandler().Publish(ctx, entry.DeliveryID, entry.Target)
+}
+
+// processQueued runs a delivery taken from the work queue through the
+// processing pipeline
+func (ws *WebhookServer) processQueued(ctx context.Context, deliveryID string) error {
+	return ws.webhookHandler().ProcessQueued(ctx, deliveryID)
+}
+
+// Replay runs a stored delivery through the processing pipeline again
+func (ws *WebhookServer) Replay(ctx context.Context, deliveryID string) error {
+	return ws.webhookHandler().Replay(ctx, deliveryID)
+}
+
+// DryRun explains how this build and configuration would handle a delivery,
+// as the dry-run ingest endpoint does, without storing or acting on it
+func (ws *WebhookServer) DryRun(w http.ResponseWriter, r *http.Request) {
+	ws.webhookHandler().HandleDryRun(w, r)
+}
+
+// limit applies the rate limits, if any, to a handler
+func (ws *WebhookServer) limit(handler http.HandlerFunc) http.HandlerFunc {
+	if ws.rateLimiter == nil {
+		return handler
+	}
+	return ws.rateLimiter.Middleware(handler)
+}
+
+// cached caches the results of an aggregate query endpoint depending on the
+// events depends selects, when the query cache is enabled
+func (ws *WebhookServer) cached(depends querycache.Depends, handler http.HandlerFunc) http.HandlerFunc {
+	if ws.queryCache == nil {
+		return handler
+	}
+	return ws.queryCache.Handler(depends, handler)
 }
 
 // Start starts the webhook server
 func (ws *WebhookServer) Start() {
 	mux := http.NewServeMux()
-	
+
 	// Create handlers with the webhook secret for signature validation and database connection
-	webhookHandler := handlers.NewWebhookHandler(ws.webhookSecret, ws.dbConn)
-	healthHandler := handlers.NewHealthHandler()
-	
-	// Register routes
-	mux.HandleFunc("/webhook", webhookHandler.HandleWebhook)
-	mux.HandleFunc("/health", healthHandler.HandleHealth)
-	mux.HandleFunc("/", handlers.HandleRoot)
+	webhookHandler := ws.webhookHandler()
+	if ws.workQueue != nil {
+		webhookHandler.WithWorkQueue(ws.workQueue.Notify)
+	}
+	auditLogHandler := handlers.NewAuditLogHandler(ws.auditLogToken, webhookHandler.Ingest).
+		WithMaxBodySize(ws.maxBodySize)
+	securityHandler := handlers.NewSecurityHandler(ws.dbConn, ws.securitySLA)
+	streamHandler := handlers.NewStreamHandler(ws.streamHub)
+	webSocketHandler := handlers.NewWebSocketHandler(ws.streamHub, ws.wsClientBuffer)
+	protectionHandler := handlers.NewProtectionHandler(ws.dbConn)
+	accessHandler := handlers.NewAccessHandler(ws.dbConn)
+	discussionHandler := handlers.NewDiscussionHandler(ws.dbConn)
+	retentionHandler := handlers.NewRetentionHandler(ws.janitor)
+	batchHandler := handlers.NewBatchHandler(ws.batchWriter)
+	projectHandler := handlers.NewProjectHandler(ws.dbConn)
+	repoHealthHandler := handlers.NewRepoHealthHandler(ws.dbConn, ws.healthTargets).WithIndexAdvisor(ws.indexAdvisor)
+	usageHandler := handlers.NewUsageHandler(ws.dbConn, ws.usageAlerts)
+	statsHandler := handlers.NewStatsHandler(ws.dbConn).WithIndexAdvisor(ws.indexAdvisor)
+	doraHandler := handlers.NewDORAHandler(ws.dbConn)
+	graphQLHandler := handlers.NewGraphQLHandler(ws.dbConn).WithEncryption(ws.payloadKeys)
+	capacityHandler := handlers.NewCapacityHandler(ws.dbConn, ws.capacityHistory, ws.capacityLimits)
+	hooksHandler := handlers.NewHooksHandler(ws.dbConn)
+	activityHandler := handlers.NewActivityHandler(ws.auth, ws.dbConn, ws.statsPrivacy)
+	managementHandler := handlers.NewManagementHandler(ws.auth, ws.dbConn).
+		WithReplay(webhookHandler.Replay).
+		WithNotifiers(ws.notifiers).
+		WithSettings(ws.settings).
+		WithApprovals(ws.approvals).
+		WithRules(ws.rules)
+	adminHandler := handlers.NewAdminHandler(ws.adminUsername, ws.adminPassword, ws.auth, ws.dbConn).
+		WithRuleSaver(managementHandler.SaveRule).
+		WithEncryption(ws.payloadKeys).
+		WithApprovals(ws.approvals).
+		WithIndexAdvisor(ws.indexAdvisor)
+	if ws.dbConn != nil {
+		adminHandler.WithBanners(handlers.LoadBanners(ws.dbConn.Queries()))
+	}
+	var listIndexes indexadvisor.ListFunc
+	if ws.dbConn != nil {
+		listIndexes = indexadvisor.LoadIndexes(ws.dbConn.Queries())
+	}
+	indexHandler := handlers.NewIndexHandler(ws.indexAdvisor, listIndexes, ws.indexCreator != nil)
+	maintenanceHandler := handlers.NewMaintenanceHandler(ws.maintenance, ws.maintenanceEvery)
+	tenantHandler := handlers.NewTenantHandler(ws.auth, ws.dbConn)
+	outboundHandler := handlers.NewOutboundHandler(ws.outbound)
+	selfCheckHandler := handlers.NewSelfCheckHandler(ws.selfCheck, ws.auth)
+	statusHandler := handlers.NewStatusHandler(ws.features)
+	statusPageHandler := handlers.NewStatusPageHandler(ws.statusPageTitle, ws.statusPage, ws.features, ws.auth, ws.dbConn)
+
+	// Register routes. The query APIs and event streams need a token with
+	// the read scope.
+	read := func(next http.HandlerFunc) http.HandlerFunc {
+		return ws.auth.Require(apitoken.ScopeRead, next)
+	}
+	handleWebhook := webhookHandler.HandleWebhook
+	if ws.allowlist != nil {
+		handleWebhook = ws.allowlist.Middleware(handleWebhook)
+	}
+	if ws.statusPage != nil {
+		handleWebhook = ws.statusPage.Middleware(handleWebhook)
+	}
+	mux.HandleFunc("/webhook", ws.limit(handleWebhook))
+	if ws.auditLogToken != "" {
+		mux.HandleFunc("/audit-log", ws.limit(auditLogHandler.HandleAuditLog))
+	}
+	mux.HandleFunc("/api/security/posture", ws.limit(read(ws.cached(webhook.IsSecurityAlertEvent, securityHandler.HandlePosture))))
+	mux.HandleFunc("/api/events/stream", read(streamHandler.HandleStream))
+	mux.HandleFunc("/api/events/batches", ws.limit(read(batchHandler.HandleStats)))
+	mux.HandleFunc("/api/events/{delivery_id}/replay", managementHandler.HandleReplay)
+	mux.HandleFunc("/ws", read(webSocketHandler.HandleWebSocket))
+	mux.HandleFunc("/api/protection/history", ws.limit(read(protectionHandler.HandleHistory)))
+	mux.HandleFunc("/api/access/review", ws.limit(read(accessHandler.HandleReview)))
+	mux.HandleFunc("/api/discussions/search", ws.limit(read(discussionHandler.HandleSearch)))
+	mux.HandleFunc("/api/retention", ws.limit(read(retentionHandler.HandleStats)))
+	mux.HandleFunc("/api/projects/cycle-time", ws.limit(read(ws.cached(querycache.Types(webhook.ProjectsV2ItemEvent), projectHandler.HandleCycleTime))))
+	mux.HandleFunc("/api/repositories/health", ws.limit(read(ws.cached(nil, repoHealthHandler.HandleScores))))
+	mux.HandleFunc("/api/usage", ws.limit(read(ws.cached(nil, usageHandler.HandleReport))))
+	mux.HandleFunc("/api/capacity", ws.limit(read(ws.cached(nil, capacityHandler.HandleReport))))
+	mux.HandleFunc("/api/stats", ws.limit(read(ws.cached(nil, statsHandler.HandleStats))))
+	mux.HandleFunc("/api/metrics/dora", ws.limit(read(ws.cached(querycache.Types(webhook.PushEvent, webhook.PullRequestEvent, "deployment_status"), doraHandler.HandleMetrics))))
+	mux.HandleFunc("/api/graphql", ws.limit(read(graphQLHandler.HandleQuery)))
+	mux.HandleFunc("/api/hooks", ws.limit(read(hooksHandler.HandleList)))
+	mux.HandleFunc("/api/activity/heatmap", ws.limit(activityHandler.HandleHeatmap))
+	mux.HandleFunc("/api/activity/calendar", ws.limit(activityHandler.HandleCalendar))
+	mux.HandleFunc("/api/grafana/dashboard", handlers.HandleGrafanaDashboard)
+	mux.HandleFunc("/schemas", handlers.HandleSchemas)
+	mux.HandleFunc("/schemas/{name}", handlers.HandleSchema)
+	mux.HandleFunc("/api/v1/routes", managementHandler.HandleRoutes)
+	mux.HandleFunc("/api/v1/routes/{kind}/{match...}", managementHandler.HandleRoute)
+	mux.HandleFunc("/api/v1/settings", managementHandler.HandleSettings)
+	mux.HandleFunc("/api/v1/settings/{name}", managementHandler.HandleSetting)
+	mux.HandleFunc("/api/v1/settings/effective", managementHandler.HandleEffectiveSettings)
+	mux.HandleFunc("/api/v1/notifiers/{name}/test", managementHandler.HandleNotifierTest)
+	mux.HandleFunc("/api/v1/changes", managementHandler.HandleChanges)
+	mux.HandleFunc("/api/v1/banners", managementHandler.HandleBanners)
+	mux.HandleFunc("/api/v1/banners/{id}", managementHandler.HandleBanner)
+	mux.HandleFunc("/api/v1/rules", managementHandler.HandleRules)
+	mux.HandleFunc("/api/v1/rules/{name}", managementHandler.HandleRule)
+	mux.HandleFunc("/api/v1/legal-holds", managementHandler.HandleLegalHolds)
+	mux.HandleFunc("/api/v1/legal-holds/audit", managementHandler.HandleLegalHoldAudit)
+	mux.HandleFunc("/api/v1/legal-holds/{id}", managementHandler.HandleLegalHold)
+	mux.HandleFunc("/api/v1/senders/{login}/events", managementHandler.HandleEraseSender)
+	mux.HandleFunc("/api/v1/changes/{id}/approve", managementHandler.HandleApprove)
+	mux.HandleFunc("/api/v1/changes/{id}/reject", managementHandler.HandleReject)
+	mux.HandleFunc("/api/v1/tenants/{org}/settings", tenantHandler.HandleSettings)
+	mux.HandleFunc("/api/v1/tenants/{org}/settings/{name}", tenantHandler.HandleSetting)
+	mux.HandleFunc("/api/v1/tenants/{org}/tokens", tenantHandler.HandleTokens)
+	mux.HandleFunc("/api/v1/tenants/{org}/tokens/{name}", tenantHandler.HandleToken)
+	mux.HandleFunc("/api/v1/outbound", ws.auth.Require(apitoken.ScopeAdmin, outboundHandler.HandleRequests))
+	mux.HandleFunc("/api/v1/indexes", ws.auth.Require(apitoken.ScopeAdmin, indexHandler.HandleReport))
+	mux.HandleFunc("/api/v1/maintenance", ws.auth.Require(apitoken.ScopeAdmin, maintenanceHandler.HandleReport))
+	mux.HandleFunc("/api/v1/maintenance/run", ws.auth.Require(apitoken.ScopeAdmin, maintenanceHandler.HandleRun))
+	mux.HandleFunc("/api/v1/ingest/dry-run", ws.auth.Require(apitoken.ScopeAdmin, webhookHandler.HandleDryRun))
+	mux.HandleFunc("/api/v1/quarantine", managementHandler.HandleQuarantine)
+	mux.HandleFunc("/api/v1/quarantine/{delivery_id}/release", managementHandler.HandleRelease)
+	mux.HandleFunc("/api/v1/status/features", statusHandler.HandleFeatures)
+	if ws.statusPage != nil {
+		mux.HandleFunc("/status", ws.limit(statusPageHandler.HandlePage))
+		mux.HandleFunc("/status.json", ws.limit(statusPageHandler.HandleJSON))
+		mux.HandleFunc("/api/v1/status/incidents", statusPageHandler.HandleIncidents)
+		mux.HandleFunc("/api/v1/status/incidents/{id}", statusPageHandler.HandleIncident)
+	}
+	if ws.scim != nil {
+		mux.Handle(scim.Prefix+"/", scim.NewHandler(ws.scim.Store(), ws.auth))
+	}
+	mux.HandleFunc("/admin", adminHandler.HandleDeliveries)
+	mux.HandleFunc("/admin/deliveries/{delivery_id}", adminHandler.HandleDelivery)
+	mux.HandleFunc("/admin/rules", adminHandler.HandleRules)
+	mux.HandleFunc("/admin/changes", adminHandler.HandleChanges)
+	mux.HandleFunc("/admin/dora", adminHandler.HandleDORA)
+	mux.HandleFunc("/admin/tenants/{org}", adminHandler.HandleTenant)
+	mux.HandleFunc("/api/github/self-check", ws.limit(selfCheckHandler.HandleSelfCheck))
+	mux.HandleFunc("/health", ws.health.HandleHealth)
+	mux.HandleFunc("/healthz", ws.health.HandleLiveness)
+	mux.HandleFunc("/readyz", ws.health.HandleReadiness)
+	mux.HandleFunc("/", handlers.NewRootHandler(ws.auditLogToken != "").HandleRoot)
+
+	// Retry spooled events in the background
+	if ws.deadLetter != nil {
+		go ws.deadLetter.RunRetryLoop(context.Background(), ws.deadLetterRetry, ws.events.RetryWebhookEvent)
+	}
+
+	// Export access reviews in the background
+	if ws.dbConn != nil && ws.accessReviewDir != "" {
+		go access.RunExportLoop(context.Background(), ws.accessReviewEvery, ws.accessReviewDir, 90, ws.loadAccessChanges)
+	}
+
+	// Send community digests in the background
+	if ws.dbConn != nil && len(ws.digestRoutes) > 0 {
+		go community.NewDigester(ws.digestRoutes, ws.loadCommunityEvents).
+			WithBanners(handlers.LoadBanners(ws.dbConn.Queries())).
+			Run(context.Background(), ws.digestEvery)
+	}
+
+	// Prune expired events in the background
+	if ws.janitor != nil {
+		go ws.janitor.Run(context.Background(), ws.janitorEvery)
+	}
+
+	// Chain stored events in the background
+	if ws.sealer != nil {
+		go ws.sealer.Run(context.Background(), ws.sealerEvery)
+	}
+
+	// Alert on repositories whose share of usage grows abnormally
+	if ws.usageMonitor != nil {
+		go ws.usageMonitor.Run(context.Background(), ws.usageEvery)
+	}
+
+	// Build the indexes the advisor suggests in the background
+	if ws.indexCreator != nil {
+		go ws.indexCreator.Run(context.Background(), time.Hour)
+	}
+
+	// Keep the tables healthy within the maintenance window
+	if ws.maintenance != nil && ws.maintenanceEvery > 0 {
+		go ws.maintenance.Run(context.Background(), ws.maintenanceEvery)
+	}
+
+	// Alert when storage is projected to outgrow the disk or the budget
+	if ws.capacityMonitor != nil {
+		go ws.capacityMonitor.Run(context.Background(), ws.capacityEvery)
+	}
+
+	// Push business metrics in the background
+	if ws.metricsPusher != nil {
+		go ws.metricsPusher.Run(context.Background(), ws.metricsEvery)
+	}
+
+	// Write batches of stored events in the background
+	if ws.batchWriter != nil {
+		go ws.batchWriter.Run(context.Background())
+	}
+
+	// Process queued events in the background
+	if ws.workQueue != nil {
+		go ws.workQueue.Run(context.Background())
+		log.Printf("Processing stored events with %d work queue workers", ws.workQueueConfig.Workers)
+	}
+
+	// Publish stored events from the outbox in the background
+	if ws.outbox != nil {
+		go ws.outbox.Run(context.Background())
+		log.Printf("Publishing stored events to %d forwarders through the outbox", len(ws.outboxForwarders))
+	}
 
+	// Pick up rules changed through other replicas
+	if ws.rules != nil && ws.dbConn != nil {
+		go ws.rules.Run(context.Background(), ws.rulesEvery)
+	}
+
+	// Send the email digests of rules when they are due
+	if ws.rules != nil {
+		go ws.rules.RunDigests(context.Background())
+	}
+
+	// Keep GitHub's hooks ranges current
+	if ws.allowlist != nil {
+		go ws.allowlist.Run(context.Background(), ws.allowlistEvery)
+	}
+
+	// Serve gRPC health checks and reflection on their own port
+	if ws.grpcPort != "" {
+		go ws.serveGRPC()
+	}
+
+	// Verify GitHub connectivity and permissions without delaying startup
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		ws.selfCheck.Run(ctx, time.Now())
+	}()
+
+	scheme := "http"
+	if ws.tlsCertFile != "" || len(ws.autocertHosts) > 0 {
+		scheme = "https"
+	}
 	log.Printf("Starting choochoo webhook server on port %s", ws.port)
-	log.Printf("Webhook endpoint: http://localhost:%s/webhook", ws.port)
-	log.Printf("Health check: http://localhost:%s/health", ws.port)
-	
-	if err := http.ListenAndServe(":"+ws.port, mux); err != nil {
+	log.Printf("Webhook endpoint: %s://localhost:%s/webhook", scheme, ws.port)
+	log.Printf("Health check: %s://localhost:%s/health", scheme, ws.port)
+
+	if err := ws.listen(mux); err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+// loadAccessChanges loads every stored access change for periodic access reviews
+func (ws *WebhookServer) loadAccessChanges(ctx context.Context) ([]db.AccessChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return ws.dbConn.Queries().ListAccessChanges(ctx, "")
+}
+
+// loadCommunityEvents loads star, watch, fork and sponsorship events for community digests
+func (ws *WebhookServer) loadCommunityEvents(ctx context.Context, since time.Time) ([]db.ListCommunityEventsSinceRow, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return ws.dbConn.Queries().ListCommunityEventsSince(ctx, pgtype.Timestamptz{Time: since, Valid: true})
+}
+
+// loadBusinessMetrics loads the activity business metrics are derived from
+func (ws *WebhookServer) loadBusinessMetrics(ctx context.Context, since time.Time) (metrics.Signals, error) {
+	sinceTS := pgtype.Timestamptz{Time: since, Valid: true}
+
+	var signals metrics.Signals
+	var err error
+	if signals.MergeWait, err = ws.dbConn.Queries().ListMergeWaitByRepository(ctx, sinceTS); err != nil {
+		return signals, err
+	}
+	if signals.Deploys, err = ws.dbConn.Queries().ListDeployOutcomesByRepository(ctx, sinceTS); err != nil {
+		return signals, err
+	}
+	if signals.Tables, err = ws.dbConn.Queries().ListTableMaintenanceStats(ctx); err != nil {
+		return signals, err
+	}
+	if ws.maintenance != nil {
+		stats := ws.maintenance.Stats()
+		signals.Maintenance = &stats
+	}
+	return signals, nil
+}
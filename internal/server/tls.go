@@ -0,0 +1,67 @@
+package server
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// listen serves handler on the configured port, over TLS when a certificate
+// or autocert hosts are configured, and redirects plain HTTP on the redirect
+// port to HTTPS
+func (ws *WebhookServer) listen(handler http.Handler) error {
+	srv := &http.Server{Addr: ":" + ws.port, Handler: handler}
+
+	switch {
+	case len(ws.autocertHosts) > 0:
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(ws.autocertHosts...),
+			Cache:      autocert.DirCache(ws.autocertCacheDir),
+			Email:      ws.autocertEmail,
+		}
+		srv.TLSConfig = manager.TLSConfig()
+		// The redirect listener also answers HTTP-01 challenges; without it
+		// certificates are obtained with TLS-ALPN-01 on the TLS port
+		if ws.redirectPort != "" {
+			go ws.listenRedirect(manager.HTTPHandler(redirectHandler(ws.port)))
+		}
+		log.Printf("Serving TLS on port %s with certificates from Let's Encrypt for %s", ws.port, strings.Join(ws.autocertHosts, ", "))
+		return srv.ListenAndServeTLS("", "")
+
+	case ws.tlsCertFile != "":
+		if ws.redirectPort != "" {
+			go ws.listenRedirect(redirectHandler(ws.port))
+		}
+		log.Printf("Serving TLS on port %s", ws.port)
+		return srv.ListenAndServeTLS(ws.tlsCertFile, ws.tlsKeyFile)
+	}
+
+	return srv.ListenAndServe()
+}
+
+// listenRedirect serves handler on the redirect port
+func (ws *WebhookServer) listenRedirect(handler http.Handler) {
+	log.Printf("Redirecting HTTP on port %s to HTTPS", ws.redirectPort)
+	if err := http.ListenAndServe(":"+ws.redirectPort, handler); err != nil {
+		log.Printf("Warning: HTTP redirect listener failed: %v", err)
+	}
+}
+
+// redirectHandler redirects requests to the same URL over HTTPS on tlsPort.
+// 308 keeps the method and body, so webhook POSTs are redirected intact.
+func redirectHandler(tlsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if tlsPort != "443" {
+			host = net.JoinHostPort(host, tlsPort)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
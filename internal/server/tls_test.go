@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		tlsPort  string
+		target   string
+		expected string
+	}{
+		{"443", "http://hooks.example.com/webhook", "https://hooks.example.com/webhook"},
+		{"443", "http://hooks.example.com:80/api/retention?x=1", "https://hooks.example.com/api/retention?x=1"},
+		{"8443", "http://hooks.example.com:8080/webhook", "https://hooks.example.com:8443/webhook"},
+	}
+
+	for _, test := range tests {
+		rr := httptest.NewRecorder()
+		redirectHandler(test.tlsPort).ServeHTTP(rr, httptest.NewRequest("POST", test.target, nil))
+
+		if rr.Code != http.StatusPermanentRedirect {
+			t.Errorf("Expected status code %d, got %d", http.StatusPermanentRedirect, rr.Code)
+		}
+		if location := rr.Header().Get("Location"); location != test.expected {
+			t.Errorf("Expected redirect to %s, got %s", test.expected, location)
+		}
+	}
+}
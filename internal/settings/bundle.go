@@ -0,0 +1,298 @@
+package settings
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/community"
+	"github.com/deedubs/choochoo/internal/discussion"
+	"github.com/deedubs/choochoo/internal/docs"
+	"github.com/deedubs/choochoo/internal/project"
+	"github.com/deedubs/choochoo/internal/repohealth"
+	"github.com/deedubs/choochoo/internal/retention"
+	"github.com/deedubs/choochoo/internal/security"
+	"github.com/deedubs/choochoo/internal/webhook"
+	"gopkg.in/yaml.v3"
+)
+
+// BundleVersion is the bundle format written by Export
+const BundleVersion = 1
+
+// Route sends matching events to a channel URL. What Match is compared
+// against depends on the route list: a severity, category, column, kind or
+// repository pattern.
+type Route struct {
+	Match string `yaml:"match"`
+	URL   string `yaml:"url"`
+}
+
+// Routes are the notification targets of the instance
+type Routes struct {
+	SecurityAlerts   []Route `yaml:"security_alerts,omitempty"`
+	Discussions      []Route `yaml:"discussions,omitempty"`
+	ProjectColumns   []Route `yaml:"project_columns,omitempty"`
+	Docs             []Route `yaml:"docs,omitempty"`
+	CommunityDigests []Route `yaml:"community_digests,omitempty"`
+}
+
+// Policies are the retention, SLA and scheduling policies of the instance
+type Policies struct {
+	Retention               map[string]string `yaml:"retention,omitempty"`
+	RetentionMode           string            `yaml:"retention_mode,omitempty"`
+	RetentionInterval       string            `yaml:"retention_interval,omitempty"`
+	RetentionBatchSize      int               `yaml:"retention_batch_size,omitempty"`
+	SecuritySLA             map[string]string `yaml:"security_sla,omitempty"`
+	RepoHealthTargets       map[string]string `yaml:"repo_health_targets,omitempty"`
+	AccessReviewInterval    string            `yaml:"access_review_interval,omitempty"`
+	CommunityDigestInterval string            `yaml:"community_digest_interval,omitempty"`
+}
+
+// Flags toggle optional behavior
+type Flags struct {
+	AuditAlertActions []string `yaml:"audit_alert_actions,omitempty"`
+	IgnoredEvents     []string `yaml:"ignored_events,omitempty"`
+}
+
+// Bundle is the declarative form of every dynamic setting of an instance.
+// Secrets and connection settings such as GITHUB_WEBHOOK_SECRET and
+// DATABASE_URL are deliberately not part of it. Environments holds named
+// overlays on top of the base settings, applied with Resolve, and Overrides
+// the overlays of organizations, repositories and branches, applied with
+// Effective.
+type Bundle struct {
+	Version      int                `yaml:"version"`
+	Routes       Routes             `yaml:"routes,omitempty"`
+	Policies     Policies           `yaml:"policies,omitempty"`
+	Flags        Flags              `yaml:"flags,omitempty"`
+	Environments map[string]Overlay `yaml:"environments,omitempty"`
+	Overrides    map[string]Overlay `yaml:"overrides,omitempty"`
+}
+
+// ParseBundle parses a YAML bundle, rejecting unknown fields
+func ParseBundle(data []byte) (*Bundle, error) {
+	var bundle Bundle
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("invalid config bundle: %w", err)
+	}
+	if bundle.Version != BundleVersion {
+		return nil, fmt.Errorf("unsupported config bundle version %d", bundle.Version)
+	}
+	return &bundle, nil
+}
+
+// Marshal encodes the bundle as YAML
+func (b *Bundle) Marshal() ([]byte, error) {
+	return yaml.Marshal(b)
+}
+
+// Settings flattens the bundle into settings named after the environment
+// variables they replace, validating every value the way the server would
+func (b *Bundle) Settings() (map[string]string, error) {
+	settings := flatten(b.Routes, b.Policies, b.Flags)
+	if err := Validate(settings); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// FromSettings builds a bundle from stored settings
+func FromSettings(settings map[string]string) *Bundle {
+	bundle := &Bundle{Version: BundleVersion}
+	bundle.Routes, bundle.Policies, bundle.Flags = expand(settings)
+	return bundle
+}
+
+// flatten converts routes, policies and flags to settings, leaving out
+// settings that are not set
+func flatten(routes Routes, policies Policies, flags Flags) map[string]string {
+	settings := make(map[string]string)
+	set := func(name, value string) {
+		if value != "" {
+			settings[name] = value
+		}
+	}
+
+	set("SECURITY_ALERT_ROUTES", joinRoutes(routes.SecurityAlerts))
+	set("DISCUSSION_ROUTES", joinRoutes(routes.Discussions))
+	set("PROJECT_COLUMN_ROUTES", joinRoutes(routes.ProjectColumns))
+	set("DOCS_ROUTES", joinRoutes(routes.Docs))
+	set("COMMUNITY_DIGEST_ROUTES", joinRoutes(routes.CommunityDigests))
+
+	set("RETENTION_POLICY", joinPairs(policies.Retention))
+	set("RETENTION_MODE", policies.RetentionMode)
+	set("RETENTION_INTERVAL", policies.RetentionInterval)
+	if policies.RetentionBatchSize != 0 {
+		set("RETENTION_BATCH_SIZE", strconv.Itoa(policies.RetentionBatchSize))
+	}
+	set("SECURITY_ALERT_SLA", joinPairs(policies.SecuritySLA))
+	set("REPO_HEALTH_TARGETS", joinPairs(policies.RepoHealthTargets))
+	set("ACCESS_REVIEW_INTERVAL", policies.AccessReviewInterval)
+	set("COMMUNITY_DIGEST_INTERVAL", policies.CommunityDigestInterval)
+
+	set("AUDIT_LOG_ALERT_ACTIONS", strings.Join(flags.AuditAlertActions, ","))
+	set("IGNORED_EVENTS", strings.Join(flags.IgnoredEvents, ","))
+	return settings
+}
+
+// expand is the inverse of flatten
+func expand(settings map[string]string) (Routes, Policies, Flags) {
+	routes := Routes{
+		SecurityAlerts:   splitRoutes(settings["SECURITY_ALERT_ROUTES"]),
+		Discussions:      splitRoutes(settings["DISCUSSION_ROUTES"]),
+		ProjectColumns:   splitRoutes(settings["PROJECT_COLUMN_ROUTES"]),
+		Docs:             splitRoutes(settings["DOCS_ROUTES"]),
+		CommunityDigests: splitRoutes(settings["COMMUNITY_DIGEST_ROUTES"]),
+	}
+
+	policies := Policies{
+		Retention:               splitPairs(settings["RETENTION_POLICY"]),
+		RetentionMode:           settings["RETENTION_MODE"],
+		RetentionInterval:       settings["RETENTION_INTERVAL"],
+		SecuritySLA:             splitPairs(settings["SECURITY_ALERT_SLA"]),
+		RepoHealthTargets:       splitPairs(settings["REPO_HEALTH_TARGETS"]),
+		AccessReviewInterval:    settings["ACCESS_REVIEW_INTERVAL"],
+		CommunityDigestInterval: settings["COMMUNITY_DIGEST_INTERVAL"],
+	}
+	policies.RetentionBatchSize, _ = strconv.Atoi(settings["RETENTION_BATCH_SIZE"])
+
+	flags := Flags{
+		AuditAlertActions: splitList(settings["AUDIT_LOG_ALERT_ACTIONS"]),
+		IgnoredEvents:     splitList(settings["IGNORED_EVENTS"]),
+	}
+	return routes, policies, flags
+}
+
+// validators check a setting value with the parser the server uses for it
+var validators = map[string]func(string) error{
+	"SECURITY_ALERT_ROUTES":     func(v string) error { _, err := security.ParseRoutes(v); return err },
+	"DISCUSSION_ROUTES":         func(v string) error { _, err := discussion.ParseRoutes(v); return err },
+	"PROJECT_COLUMN_ROUTES":     func(v string) error { _, err := project.ParseRoutes(v); return err },
+	"DOCS_ROUTES":               func(v string) error { _, err := docs.ParseRoutes(v); return err },
+	"COMMUNITY_DIGEST_ROUTES":   func(v string) error { _, err := community.ParseRoutes(v); return err },
+	"RETENTION_POLICY":          func(v string) error { _, err := retention.ParsePolicy(v); return err },
+	"RETENTION_MODE":            validateRetentionMode,
+	"RETENTION_INTERVAL":        validateInterval,
+	"RETENTION_BATCH_SIZE":      validatePositive,
+	"SECURITY_ALERT_SLA":        func(v string) error { _, err := security.ParseSLA(v); return err },
+	"REPO_HEALTH_TARGETS":       func(v string) error { _, err := repohealth.ParseTargets(v); return err },
+	"ACCESS_REVIEW_INTERVAL":    validateInterval,
+	"COMMUNITY_DIGEST_INTERVAL": validateInterval,
+	"AUDIT_LOG_ALERT_ACTIONS":   func(string) error { return nil },
+	"IGNORED_EVENTS":            validateEventTypes,
+}
+
+// Names lists every setting a bundle can manage
+func Names() []string {
+	names := make([]string, 0, len(validators))
+	for name := range validators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that every setting is known and has a valid value
+func Validate(settings map[string]string) error {
+	for _, name := range sortedKeys(settings) {
+		validate, ok := validators[name]
+		if !ok {
+			return fmt.Errorf("unknown setting %s", name)
+		}
+		if err := validate(settings[name]); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func validateRetentionMode(value string) error {
+	if value != retention.ModeDelete && value != retention.ModeArchive {
+		return fmt.Errorf("must be %q or %q", retention.ModeDelete, retention.ModeArchive)
+	}
+	return nil
+}
+
+func validateEventTypes(value string) error {
+	for _, eventType := range splitList(value) {
+		if !webhook.IsSupportedEvent(eventType) {
+			return fmt.Errorf("%q is not a stored event type", eventType)
+		}
+	}
+	return nil
+}
+
+func validateInterval(value string) error {
+	if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+		return fmt.Errorf("%q is not a positive duration", value)
+	}
+	return nil
+}
+
+func validatePositive(value string) error {
+	if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+		return fmt.Errorf("%q is not a positive number", value)
+	}
+	return nil
+}
+
+func joinRoutes(routes []Route) string {
+	pairs := make([]string, 0, len(routes))
+	for _, route := range routes {
+		pairs = append(pairs, route.Match+"="+route.URL)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func splitRoutes(value string) []Route {
+	var routes []Route
+	for _, pair := range splitList(value) {
+		match, url, _ := strings.Cut(pair, "=")
+		routes = append(routes, Route{Match: match, URL: url})
+	}
+	return routes
+}
+
+// joinPairs joins a map into sorted key=value pairs
+func joinPairs(pairs map[string]string) string {
+	joined := make([]string, 0, len(pairs))
+	for _, key := range sortedKeys(pairs) {
+		joined = append(joined, key+"="+pairs[key])
+	}
+	return strings.Join(joined, ",")
+}
+
+func splitPairs(value string) map[string]string {
+	if value == "" {
+		return nil
+	}
+	pairs := make(map[string]string)
+	for _, pair := range splitList(value) {
+		key, v, _ := strings.Cut(pair, "=")
+		pairs[key] = v
+	}
+	return pairs
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,100 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testBundle = `
+version: 1
+routes:
+  security_alerts:
+    - match: critical
+      url: https://chat.example.com/hooks/security
+  project_columns:
+    - match: Blocked
+      url: https://chat.example.com/hooks/blocked
+policies:
+  retention:
+    push: 30d
+    "*": 90d
+  retention_mode: archive
+  retention_batch_size: 500
+flags:
+  audit_alert_actions: [org.add_member, repo.destroy]
+`
+
+func TestBundle_Settings(t *testing.T) {
+	bundle, err := ParseBundle([]byte(testBundle))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	settings, err := bundle.Settings()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := map[string]string{
+		"SECURITY_ALERT_ROUTES":   "critical=https://chat.example.com/hooks/security",
+		"PROJECT_COLUMN_ROUTES":   "Blocked=https://chat.example.com/hooks/blocked",
+		"RETENTION_POLICY":        "*=90d,push=30d",
+		"RETENTION_MODE":          "archive",
+		"RETENTION_BATCH_SIZE":    "500",
+		"AUDIT_LOG_ALERT_ACTIONS": "org.add_member,repo.destroy",
+	}
+	if !reflect.DeepEqual(settings, expected) {
+		t.Errorf("Expected %v, got %v", expected, settings)
+	}
+
+	// Exporting and re-importing the settings yields the same settings
+	roundTrip, err := FromSettings(settings).Settings()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(roundTrip, expected) {
+		t.Errorf("Expected round trip to keep %v, got %v", expected, roundTrip)
+	}
+}
+
+func TestBundle_Invalid(t *testing.T) {
+	bundles := []string{
+		"version: 2",
+		"version: 1\nunknown: true",
+		"version: 1\nroutes:\n  security_alerts:\n    - match: urgent\n      url: https://chat.example.com",
+		"version: 1\npolicies:\n  retention_mode: shred",
+		"version: 1\npolicies:\n  retention_interval: soon",
+	}
+
+	for _, data := range bundles {
+		bundle, err := ParseBundle([]byte(data))
+		if err == nil {
+			_, err = bundle.Settings()
+		}
+		if err == nil {
+			t.Errorf("Expected error for %q", data)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current := map[string]string{"RETENTION_MODE": "delete", "DOCS_ROUTES": "wiki=https://a.example.com", "RETENTION_POLICY": "*=90d"}
+	desired := map[string]string{"RETENTION_MODE": "archive", "RETENTION_POLICY": "*=90d", "RETENTION_INTERVAL": "6h"}
+
+	changes := Diff(current, desired)
+	var lines []string
+	for _, change := range changes {
+		lines = append(lines, change.String())
+	}
+	expected := []string{
+		"+ RETENTION_INTERVAL=6h",
+		"~ RETENTION_MODE=delete -> archive",
+		"- DOCS_ROUTES=wiki=https://a.example.com",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+
+	if changes := Diff(desired, desired); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+}
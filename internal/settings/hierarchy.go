@@ -0,0 +1,201 @@
+package settings
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Levels of the settings hierarchy, from the broadest to the narrowest
+const (
+	LevelInstance     = "instance"
+	LevelOrganization = "organization"
+	LevelRepository   = "repository"
+	LevelBranch       = "branch"
+)
+
+// ScopeLevel returns the level of an override scope: "org" for an
+// organization, "org/repo" for a repository and "org/repo@branch" for a
+// branch
+func ScopeLevel(scope string) (string, error) {
+	repo, branch, hasBranch := strings.Cut(scope, "@")
+	org, name, hasName := strings.Cut(repo, "/")
+	switch {
+	case org == "" || (hasName && (name == "" || strings.Contains(name, "/"))):
+		return "", fmt.Errorf("invalid override scope %q: use org, org/repo or org/repo@branch", scope)
+	case hasBranch && (!hasName || branch == ""):
+		return "", fmt.Errorf("invalid override scope %q: a branch needs org/repo@branch", scope)
+	case hasBranch:
+		return LevelBranch, nil
+	case hasName:
+		return LevelRepository, nil
+	default:
+		return LevelOrganization, nil
+	}
+}
+
+// ScopeOrganization returns the organization of an override scope
+func ScopeOrganization(scope string) string {
+	org, _, _ := strings.Cut(scope, "/")
+	org, _, _ = strings.Cut(org, "@")
+	return org
+}
+
+// scopes lists the override scopes that apply to branch of repo, broadest
+// first
+func scopes(repo, branch string) []string {
+	org, _, hasName := strings.Cut(repo, "/")
+	list := []string{org}
+	if hasName {
+		list = append(list, repo)
+		if branch != "" {
+			list = append(list, repo+"@"+branch)
+		}
+	}
+	return list
+}
+
+// Value is an effective setting together with the level and scope it was
+// set at. Scope is empty for instance settings.
+type Value struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Level string `json:"level"`
+	Scope string `json:"scope,omitempty"`
+}
+
+// Effective returns the settings that apply to branch of repo: the base
+// settings with the overrides of its organization, repository and branch
+// applied in turn, as environments are applied by Resolve. An empty branch
+// stops at the repository.
+func (b *Bundle) Effective(repo, branch string) *Bundle {
+	effective := b.apply(Overlay{})
+	for _, scope := range scopes(repo, branch) {
+		if overlay, ok := b.Overrides[scope]; ok {
+			effective = effective.apply(overlay)
+		}
+	}
+	return effective
+}
+
+// Explain lists the effective settings of branch of repo by name, with the
+// level each value comes from
+func (b *Bundle) Explain(repo, branch string) []Value {
+	effective := b.apply(Overlay{})
+	previous := flatten(effective.Routes, effective.Policies, effective.Flags)
+	values := make(map[string]Value, len(previous))
+	for name, value := range previous {
+		values[name] = Value{Name: name, Value: value, Level: LevelInstance}
+	}
+
+	for _, scope := range scopes(repo, branch) {
+		overlay, ok := b.Overrides[scope]
+		if !ok {
+			continue
+		}
+		level, _ := ScopeLevel(scope)
+		effective = effective.apply(overlay)
+		current := flatten(effective.Routes, effective.Policies, effective.Flags)
+		for name, value := range current {
+			if previous[name] != value {
+				values[name] = Value{Name: name, Value: value, Level: level, Scope: scope}
+			}
+		}
+		for name := range previous {
+			if _, ok := current[name]; !ok {
+				delete(values, name)
+			}
+		}
+		previous = current
+	}
+
+	list := make([]Value, 0, len(values))
+	for _, name := range sortedKeys(previous) {
+		list = append(list, values[name])
+	}
+	return list
+}
+
+// IgnoresEvent reports whether events of eventType from branch of repo are
+// not stored
+func (b *Bundle) IgnoresEvent(repo, branch, eventType string) bool {
+	for _, ignored := range b.Effective(repo, branch).Flags.IgnoredEvents {
+		if ignored == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// OverrideSettings flattens the overrides into settings per scope,
+// validating every scope and value. Routes with an empty url remove the
+// route of a broader scope and are kept as such.
+func (b *Bundle) OverrideSettings() (map[string]map[string]string, error) {
+	overrides := make(map[string]map[string]string, len(b.Overrides))
+	for _, scope := range b.OverrideScopes() {
+		if _, err := ScopeLevel(scope); err != nil {
+			return nil, err
+		}
+		overlay := b.Overrides[scope]
+		settings := flatten(overlay.Routes, overlay.Policies, overlay.Flags)
+		if err := ValidateOverride(settings); err != nil {
+			return nil, fmt.Errorf("override %s: %w", scope, err)
+		}
+		if len(settings) > 0 {
+			overrides[scope] = settings
+		}
+	}
+	return overrides, nil
+}
+
+// ValidateOverride checks the settings of an override scope like Validate,
+// allowing routes with an empty url that remove the route of a broader scope
+func ValidateOverride(settings map[string]string) error {
+	// Removals are not routes the parsers accept
+	check := make(map[string]string, len(settings))
+	for name, value := range settings {
+		if IsRouteSetting(name) {
+			value = withoutRemovals(value)
+		}
+		if value != "" {
+			check[name] = value
+		}
+	}
+	return Validate(check)
+}
+
+// OverrideScopes lists the scopes the bundle overrides, sorted
+func (b *Bundle) OverrideScopes() []string {
+	list := make([]string, 0, len(b.Overrides))
+	for scope := range b.Overrides {
+		list = append(list, scope)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// FromStored builds a bundle from stored instance settings and the stored
+// settings of each override scope
+func FromStored(instance map[string]string, overrides map[string]map[string]string) *Bundle {
+	bundle := FromSettings(instance)
+	for scope, settings := range overrides {
+		if bundle.Overrides == nil {
+			bundle.Overrides = make(map[string]Overlay, len(overrides))
+		}
+		var overlay Overlay
+		overlay.Routes, overlay.Policies, overlay.Flags = expand(settings)
+		bundle.Overrides[scope] = overlay
+	}
+	return bundle
+}
+
+// withoutRemovals drops the routes with an empty url from a route list
+func withoutRemovals(value string) string {
+	var routes []Route
+	for _, route := range splitRoutes(value) {
+		if route.URL != "" {
+			routes = append(routes, route)
+		}
+	}
+	return joinRoutes(routes)
+}
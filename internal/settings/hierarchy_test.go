@@ -0,0 +1,143 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testHierarchyBundle = `
+version: 1
+routes:
+  security_alerts:
+    - match: critical
+      url: https://chat.example.com/hooks/security
+policies:
+  retention_mode: delete
+flags:
+  ignored_events: [star, watch]
+overrides:
+  acme:
+    routes:
+      security_alerts:
+        - match: critical
+          url: https://chat.example.com/hooks/acme
+  acme/api:
+    policies:
+      retention_mode: archive
+    flags:
+      ignored_events: [push]
+  acme/api@main:
+    flags:
+      ignored_events: []
+`
+
+func TestBundle_Explain(t *testing.T) {
+	bundle, err := ParseBundle([]byte(testHierarchyBundle))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected := []Value{
+		{Name: "RETENTION_MODE", Value: "archive", Level: LevelRepository, Scope: "acme/api"},
+		{Name: "SECURITY_ALERT_ROUTES", Value: "critical=https://chat.example.com/hooks/acme", Level: LevelOrganization, Scope: "acme"},
+	}
+	if values := bundle.Explain("acme/api", "main"); !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v", expected, values)
+	}
+
+	expected = []Value{
+		{Name: "IGNORED_EVENTS", Value: "star,watch", Level: LevelInstance},
+		{Name: "RETENTION_MODE", Value: "delete", Level: LevelInstance},
+		{Name: "SECURITY_ALERT_ROUTES", Value: "critical=https://chat.example.com/hooks/security", Level: LevelInstance},
+	}
+	if values := bundle.Explain("other/api", ""); !reflect.DeepEqual(values, expected) {
+		t.Errorf("Expected %v, got %v", expected, values)
+	}
+}
+
+func TestBundle_IgnoresEvent(t *testing.T) {
+	bundle, err := ParseBundle([]byte(testHierarchyBundle))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		repo, branch, eventType string
+		expected                bool
+	}{
+		{"other/api", "main", "star", true},
+		{"acme/web", "", "star", true},
+		{"acme/api", "", "push", true},
+		{"acme/api", "", "star", false},
+		{"acme/api", "main", "push", false},
+	}
+	for _, test := range tests {
+		if got := bundle.IgnoresEvent(test.repo, test.branch, test.eventType); got != test.expected {
+			t.Errorf("IgnoresEvent(%s, %s, %s) = %v, want %v", test.repo, test.branch, test.eventType, got, test.expected)
+		}
+	}
+}
+
+func TestBundle_OverrideSettings(t *testing.T) {
+	bundle, err := ParseBundle([]byte(`
+version: 1
+overrides:
+  acme/api:
+    routes:
+      security_alerts:
+        - match: critical
+          url: ""
+    flags:
+      ignored_events: [push]
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	overrides, err := bundle.OverrideSettings()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := map[string]map[string]string{
+		"acme/api": {"SECURITY_ALERT_ROUTES": "critical=", "IGNORED_EVENTS": "push"},
+	}
+	if !reflect.DeepEqual(overrides, expected) {
+		t.Errorf("Expected %v, got %v", expected, overrides)
+	}
+
+	// Stored overrides read back as the same overrides
+	roundTrip, err := FromStored(nil, overrides).OverrideSettings()
+	if err != nil || !reflect.DeepEqual(roundTrip, expected) {
+		t.Errorf("Expected round trip to keep %v, got %v, %v", expected, roundTrip, err)
+	}
+
+	for _, data := range []string{
+		"version: 1\noverrides:\n  acme/api/web:\n    flags:\n      ignored_events: [push]",
+		"version: 1\noverrides:\n  acme@main:\n    flags:\n      ignored_events: [push]",
+		"version: 1\noverrides:\n  acme:\n    flags:\n      ignored_events: [unknown]",
+	} {
+		bundle, err := ParseBundle([]byte(data))
+		if err == nil {
+			_, err = bundle.OverrideSettings()
+		}
+		if err == nil {
+			t.Errorf("Expected error for %q", data)
+		}
+	}
+}
+
+func TestDiffOverrides(t *testing.T) {
+	current := map[string]map[string]string{"acme": {"IGNORED_EVENTS": "star"}, "acme/api": {"RETENTION_MODE": "archive"}}
+	desired := map[string]map[string]string{"acme": {"IGNORED_EVENTS": "star,watch"}}
+
+	var lines []string
+	for _, change := range DiffOverrides(current, desired) {
+		lines = append(lines, change.String())
+	}
+	expected := []string{
+		"~ acme IGNORED_EVENTS=star -> star,watch",
+		"- acme/api RETENTION_MODE=archive",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+}
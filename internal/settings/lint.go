@@ -0,0 +1,144 @@
+package settings
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoFile is the file a repository describes its settings override in, in
+// the form of an entry under the overrides of a bundle
+const RepoFile = ".choochoo.yml"
+
+// Problem is an error at a line of a settings file
+type Problem struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// String formats the problem as line: message
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// settingKeys maps settings to their keys in an overlay
+var settingKeys = map[string][]string{
+	"SECURITY_ALERT_ROUTES":     {"routes", "security_alerts"},
+	"DISCUSSION_ROUTES":         {"routes", "discussions"},
+	"PROJECT_COLUMN_ROUTES":     {"routes", "project_columns"},
+	"DOCS_ROUTES":               {"routes", "docs"},
+	"COMMUNITY_DIGEST_ROUTES":   {"routes", "community_digests"},
+	"RETENTION_POLICY":          {"policies", "retention"},
+	"RETENTION_MODE":            {"policies", "retention_mode"},
+	"RETENTION_INTERVAL":        {"policies", "retention_interval"},
+	"RETENTION_BATCH_SIZE":      {"policies", "retention_batch_size"},
+	"SECURITY_ALERT_SLA":        {"policies", "security_sla"},
+	"REPO_HEALTH_TARGETS":       {"policies", "repo_health_targets"},
+	"ACCESS_REVIEW_INTERVAL":    {"policies", "access_review_interval"},
+	"COMMUNITY_DIGEST_INTERVAL": {"policies", "community_digest_interval"},
+	"AUDIT_LOG_ALERT_ACTIONS":   {"flags", "audit_alert_actions"},
+	"IGNORED_EVENTS":            {"flags", "ignored_events"},
+}
+
+// Keys returns the nested keys of a setting in a bundle or overlay, such as
+// routes and security_alerts for SECURITY_ALERT_ROUTES, or nil for settings
+// that are not part of bundles
+func Keys(name string) []string {
+	return settingKeys[name]
+}
+
+// yamlLine matches the line number the YAML parser puts in its errors
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// LintRepoFile checks a RepoFile for syntax errors, unknown keys and invalid
+// values, returning the problems in the order they were found
+func LintRepoFile(data []byte) []Problem {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return []Problem{yamlProblem(err.Error())}
+	}
+
+	var overlay Overlay
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&overlay); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return []Problem{yamlProblem(err.Error())}
+		}
+		problems := make([]Problem, 0, len(typeErr.Errors))
+		for _, message := range typeErr.Errors {
+			problems = append(problems, yamlProblem(message))
+		}
+		return problems
+	}
+
+	var problems []Problem
+	settings := flatten(overlay.Routes, overlay.Policies, overlay.Flags)
+	for _, name := range sortedKeys(settings) {
+		value := settings[name]
+		if IsRouteSetting(name) {
+			value = withoutRemovals(value)
+		}
+		if value == "" {
+			continue
+		}
+		if err := validators[name](value); err != nil {
+			keys := settingKeys[name]
+			problems = append(problems, Problem{
+				Line:    keyLine(&root, keys),
+				Message: fmt.Sprintf("invalid %s: %v", strings.Join(keys, "."), err),
+			})
+		}
+	}
+	return problems
+}
+
+// yamlProblem converts a YAML parser error to a problem, on line 1 if the
+// error has no line
+func yamlProblem(message string) Problem {
+	if match := yamlLine.FindStringSubmatch(message); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		// yaml.v3 counts the lines of parser errors, unlike those of
+		// scanner errors, from zero
+		if strings.HasPrefix(match[2], "did not find expected") {
+			line++
+		}
+		return Problem{Line: line, Message: match[2]}
+	}
+	return Problem{Line: 1, Message: strings.TrimPrefix(message, "yaml: ")}
+}
+
+// keyLine returns the line of the nested key keys in a document, or 1 if it
+// is not found
+func keyLine(root *yaml.Node, keys []string) int {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	line := 1
+	for _, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return line
+		}
+		found := false
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				line = node.Content[i].Line
+				node = node.Content[i+1]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return line
+		}
+	}
+	return line
+}
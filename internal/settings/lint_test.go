@@ -0,0 +1,45 @@
+package settings
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLintRepoFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected []int
+	}{
+		{"valid", "flags:\n  ignored_events: [push]\npolicies:\n  retention_mode: archive\n", nil},
+		{"empty", "", nil},
+		{"route removal", "routes:\n  security_alerts:\n    - match: critical\n      url: \"\"\n", nil},
+		{"syntax error", "flags:\n  ignored_events: [push]\n bad: indentation\n", []int{3}},
+		{"scanner error", "flags:\n\tignored_events: [push]\n", []int{2}},
+		{"unknown keys", "flags:\n  ignored: [push]\npolicy:\n  retention_mode: archive\n", []int{2, 3}},
+		{"invalid values", "policies:\n  retention_mode: shred\nflags:\n\n  ignored_events: [milestone]\n", []int{5, 2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var lines []int
+			for _, problem := range LintRepoFile([]byte(test.data)) {
+				if problem.Message == "" {
+					t.Errorf("Expected a message for line %d", problem.Line)
+				}
+				lines = append(lines, problem.Line)
+			}
+			if !reflect.DeepEqual(lines, test.expected) {
+				t.Errorf("Expected problems on lines %v, got %v", test.expected, lines)
+			}
+		})
+	}
+}
+
+func TestLintRepoFile_Messages(t *testing.T) {
+	problems := LintRepoFile([]byte("flags:\n  ignored_events: [milestone]\n"))
+	if len(problems) != 1 || !strings.Contains(problems[0].String(), `line 2: invalid flags.ignored_events: "milestone"`) {
+		t.Errorf("Unexpected problems %v", problems)
+	}
+}
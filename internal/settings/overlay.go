@@ -0,0 +1,133 @@
+package settings
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Overlay holds the settings of one environment that differ from the base
+// settings of a bundle
+type Overlay struct {
+	Routes   Routes   `yaml:"routes,omitempty"`
+	Policies Policies `yaml:"policies,omitempty"`
+	Flags    Flags    `yaml:"flags,omitempty"`
+}
+
+// EnvironmentNames lists the environments a bundle defines, sorted
+func (b *Bundle) EnvironmentNames() []string {
+	names := make([]string, 0, len(b.Environments))
+	for name := range b.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the bundle with the overlay of env applied to its base
+// settings, without any environments. An empty env resolves to the base
+// settings.
+//
+// Routes in an overlay replace base routes with the same match and add the
+// others; a route with an empty url removes the base route. Policy maps are
+// merged key by key, other policies replace the base value when set, and a
+// flag list replaces the base list.
+func (b *Bundle) Resolve(env string) (*Bundle, error) {
+	if env == "" {
+		return b.apply(Overlay{}), nil
+	}
+
+	overlay, ok := b.Environments[env]
+	if !ok {
+		return nil, fmt.Errorf("unknown environment %q (defined: %s)", env, strings.Join(b.EnvironmentNames(), ", "))
+	}
+	return b.apply(overlay), nil
+}
+
+// apply returns the base settings of the bundle with overlay applied, as
+// described for Resolve. Overrides are kept and environments dropped.
+func (b *Bundle) apply(overlay Overlay) *Bundle {
+	resolved := &Bundle{
+		Version:   b.Version,
+		Flags:     b.Flags,
+		Overrides: b.Overrides,
+	}
+
+	resolved.Routes = Routes{
+		SecurityAlerts:   overlayRoutes(b.Routes.SecurityAlerts, overlay.Routes.SecurityAlerts),
+		Discussions:      overlayRoutes(b.Routes.Discussions, overlay.Routes.Discussions),
+		ProjectColumns:   overlayRoutes(b.Routes.ProjectColumns, overlay.Routes.ProjectColumns),
+		Docs:             overlayRoutes(b.Routes.Docs, overlay.Routes.Docs),
+		CommunityDigests: overlayRoutes(b.Routes.CommunityDigests, overlay.Routes.CommunityDigests),
+	}
+
+	base, top := b.Policies, overlay.Policies
+	resolved.Policies = Policies{
+		Retention:               overlayPairs(base.Retention, top.Retention),
+		RetentionMode:           overlayValue(base.RetentionMode, top.RetentionMode),
+		RetentionInterval:       overlayValue(base.RetentionInterval, top.RetentionInterval),
+		RetentionBatchSize:      base.RetentionBatchSize,
+		SecuritySLA:             overlayPairs(base.SecuritySLA, top.SecuritySLA),
+		RepoHealthTargets:       overlayPairs(base.RepoHealthTargets, top.RepoHealthTargets),
+		AccessReviewInterval:    overlayValue(base.AccessReviewInterval, top.AccessReviewInterval),
+		CommunityDigestInterval: overlayValue(base.CommunityDigestInterval, top.CommunityDigestInterval),
+	}
+	if top.RetentionBatchSize != 0 {
+		resolved.Policies.RetentionBatchSize = top.RetentionBatchSize
+	}
+
+	if overlay.Flags.AuditAlertActions != nil {
+		resolved.Flags.AuditAlertActions = overlay.Flags.AuditAlertActions
+	}
+	if overlay.Flags.IgnoredEvents != nil {
+		resolved.Flags.IgnoredEvents = overlay.Flags.IgnoredEvents
+	}
+	return resolved
+}
+
+func overlayRoutes(base, top []Route) []Route {
+	if top == nil {
+		return base
+	}
+
+	routes := make([]Route, 0, len(base)+len(top))
+	replaced := make(map[string]bool)
+	for _, route := range base {
+		for _, override := range top {
+			if override.Match == route.Match {
+				route.URL = override.URL
+				replaced[route.Match] = true
+			}
+		}
+		if route.URL != "" {
+			routes = append(routes, route)
+		}
+	}
+	for _, route := range top {
+		if !replaced[route.Match] && route.URL != "" {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+func overlayPairs(base, top map[string]string) map[string]string {
+	if top == nil {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(top))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range top {
+		merged[key] = value
+	}
+	return merged
+}
+
+func overlayValue(base, top string) string {
+	if top != "" {
+		return top
+	}
+	return base
+}
@@ -0,0 +1,97 @@
+package settings
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testOverlayBundle = `
+version: 1
+routes:
+  security_alerts:
+    - match: critical
+      url: https://chat.example.com/hooks/security
+    - match: high
+      url: https://chat.example.com/hooks/security
+policies:
+  retention:
+    push: 30d
+    "*": 90d
+  retention_mode: archive
+environments:
+  staging:
+    routes:
+      security_alerts:
+        - match: critical
+          url: https://chat.example.com/hooks/staging
+        - match: high
+          url: ""
+    policies:
+      retention:
+        "*": 7d
+      retention_mode: delete
+  prod:
+    routes:
+      docs:
+        - match: pages
+          url: https://chat.example.com/hooks/deploys
+`
+
+func TestBundle_Resolve(t *testing.T) {
+	bundle, err := ParseBundle([]byte(testOverlayBundle))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if names := bundle.EnvironmentNames(); !reflect.DeepEqual(names, []string{"prod", "staging"}) {
+		t.Errorf("Unexpected environments: %v", names)
+	}
+
+	tests := []struct {
+		env      string
+		expected map[string]string
+	}{
+		{"", map[string]string{
+			"SECURITY_ALERT_ROUTES": "critical=https://chat.example.com/hooks/security,high=https://chat.example.com/hooks/security",
+			"RETENTION_POLICY":      "*=90d,push=30d",
+			"RETENTION_MODE":        "archive",
+		}},
+		{"staging", map[string]string{
+			"SECURITY_ALERT_ROUTES": "critical=https://chat.example.com/hooks/staging",
+			"RETENTION_POLICY":      "*=7d,push=30d",
+			"RETENTION_MODE":        "delete",
+		}},
+		{"prod", map[string]string{
+			"SECURITY_ALERT_ROUTES": "critical=https://chat.example.com/hooks/security,high=https://chat.example.com/hooks/security",
+			"DOCS_ROUTES":           "pages=https://chat.example.com/hooks/deploys",
+			"RETENTION_POLICY":      "*=90d,push=30d",
+			"RETENTION_MODE":        "archive",
+		}},
+	}
+
+	for _, test := range tests {
+		resolved, err := bundle.Resolve(test.env)
+		if err != nil {
+			t.Fatalf("Expected no error for %q, got %v", test.env, err)
+		}
+		if resolved.Environments != nil {
+			t.Errorf("Expected resolved bundle for %q to have no environments", test.env)
+		}
+		settings, err := resolved.Settings()
+		if err != nil {
+			t.Fatalf("Expected no error for %q, got %v", test.env, err)
+		}
+		if !reflect.DeepEqual(settings, test.expected) {
+			t.Errorf("Expected %v for %q, got %v", test.expected, test.env, settings)
+		}
+	}
+
+	// Resolving must not change the base settings
+	if len(bundle.Routes.SecurityAlerts) != 2 || bundle.Policies.Retention["*"] != "90d" {
+		t.Errorf("Resolve modified the base bundle: %+v", bundle)
+	}
+
+	if _, err := bundle.Resolve("qa"); err == nil || !strings.Contains(err.Error(), "prod, staging") {
+		t.Errorf("Expected unknown environment error listing environments, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RouteKinds maps the route lists of a bundle to the settings they are
+// stored in
+var RouteKinds = map[string]string{
+	"security_alerts":   "SECURITY_ALERT_ROUTES",
+	"discussions":       "DISCUSSION_ROUTES",
+	"project_columns":   "PROJECT_COLUMN_ROUTES",
+	"docs":              "DOCS_ROUTES",
+	"community_digests": "COMMUNITY_DIGEST_ROUTES",
+}
+
+// StoredRoute is a route together with the list it belongs to. Its ID is
+// stable for as long as the route exists, so external tools can import and
+// track it.
+type StoredRoute struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Match string `json:"match"`
+	URL   string `json:"url"`
+}
+
+// RouteID identifies a route by its kind and match, e.g. "discussions/q-a"
+func RouteID(kind, match string) string {
+	return kind + "/" + match
+}
+
+// IsRouteSetting reports whether name holds a route list
+func IsRouteSetting(name string) bool {
+	for _, setting := range RouteKinds {
+		if setting == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ListRoutes lists the stored routes of every kind, sorted by ID
+func ListRoutes(stored map[string]string) []StoredRoute {
+	kinds := make(map[string]string, len(RouteKinds))
+	for kind, setting := range RouteKinds {
+		kinds[kind] = setting
+	}
+
+	routes := []StoredRoute{}
+	for _, kind := range sortedKeys(kinds) {
+		for _, route := range splitRoutes(stored[kinds[kind]]) {
+			routes = append(routes, StoredRoute{ID: RouteID(kind, route.Match), Kind: kind, Match: route.Match, URL: route.URL})
+		}
+	}
+	return routes
+}
+
+// FindRoute looks up the stored route for match in the list of kind
+func FindRoute(stored map[string]string, kind, match string) (StoredRoute, bool) {
+	for _, route := range splitRoutes(stored[RouteKinds[kind]]) {
+		if route.Match == match {
+			return StoredRoute{ID: RouteID(kind, match), Kind: kind, Match: match, URL: route.URL}, true
+		}
+	}
+	return StoredRoute{}, false
+}
+
+// SetRoute returns the route list value with match routed to url. An existing
+// route for match keeps its position in the list.
+func SetRoute(value, match, url string) string {
+	routes := splitRoutes(value)
+	for i := range routes {
+		if routes[i].Match == match {
+			routes[i].URL = url
+			return joinRoutes(routes)
+		}
+	}
+	return joinRoutes(append(routes, Route{Match: match, URL: url}))
+}
+
+// RemoveRoute returns the route list value without the route for match, and
+// whether there was one
+func RemoveRoute(value, match string) (string, bool) {
+	var kept []Route
+	found := false
+	for _, route := range splitRoutes(value) {
+		if route.Match == match {
+			found = true
+			continue
+		}
+		kept = append(kept, route)
+	}
+	return joinRoutes(kept), found
+}
+
+// CheckRoute checks that a route can be stored in a route list
+func CheckRoute(match, url string) error {
+	if match == "" || strings.ContainsAny(match, "=,") {
+		return fmt.Errorf("invalid route match %q", match)
+	}
+	if url == "" || strings.Contains(url, ",") {
+		return fmt.Errorf("invalid route url %q", url)
+	}
+	return nil
+}
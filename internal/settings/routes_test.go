@@ -0,0 +1,63 @@
+package settings
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListRoutes(t *testing.T) {
+	stored := map[string]string{
+		"DISCUSSION_ROUTES":       "q-a=https://a.example.com,*=https://b.example.com",
+		"COMMUNITY_DIGEST_ROUTES": "octo-org/*=https://c.example.com",
+		"RETENTION_MODE":          "archive",
+	}
+
+	expected := []StoredRoute{
+		{ID: "community_digests/octo-org/*", Kind: "community_digests", Match: "octo-org/*", URL: "https://c.example.com"},
+		{ID: "discussions/q-a", Kind: "discussions", Match: "q-a", URL: "https://a.example.com"},
+		{ID: "discussions/*", Kind: "discussions", Match: "*", URL: "https://b.example.com"},
+	}
+	if routes := ListRoutes(stored); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, routes)
+	}
+
+	route, ok := FindRoute(stored, "discussions", "*")
+	if !ok || route.URL != "https://b.example.com" {
+		t.Errorf("Expected catch-all discussion route, got %+v", route)
+	}
+	if _, ok := FindRoute(stored, "docs", "wiki"); ok {
+		t.Error("Expected no docs route")
+	}
+}
+
+func TestSetRoute(t *testing.T) {
+	value := SetRoute("q-a=https://a.example.com,*=https://b.example.com", "q-a", "https://c.example.com")
+	if value != "q-a=https://c.example.com,*=https://b.example.com" {
+		t.Errorf("Expected route to be replaced in place, got %s", value)
+	}
+	if again := SetRoute(value, "q-a", "https://c.example.com"); again != value {
+		t.Errorf("Expected setting the same route to be idempotent, got %s", again)
+	}
+	if value := SetRoute("", "ideas", "https://d.example.com"); value != "ideas=https://d.example.com" {
+		t.Errorf("Expected route to be added, got %s", value)
+	}
+
+	value, found := RemoveRoute(value, "q-a")
+	if !found || value != "*=https://b.example.com" {
+		t.Errorf("Expected route to be removed, got %s (found %v)", value, found)
+	}
+	if _, found := RemoveRoute(value, "q-a"); found {
+		t.Error("Expected removing a missing route to report it was not found")
+	}
+}
+
+func TestCheckRoute(t *testing.T) {
+	if err := CheckRoute("octo-org/*", "https://a.example.com"); err != nil {
+		t.Errorf("Expected valid route, got %v", err)
+	}
+	for _, route := range [][2]string{{"", "https://a.example.com"}, {"a=b", "https://a.example.com"}, {"q-a", ""}, {"q-a", "https://a.example.com,x"}} {
+		if err := CheckRoute(route[0], route[1]); err == nil {
+			t.Errorf("Expected error for %v", route)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package settings
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+// Change is the difference in one setting between two sets of settings.
+// From is empty for added settings and To is empty for removed ones. Scope
+// is the override scope of the setting, or empty for instance settings.
+type Change struct {
+	Scope string
+	Name  string
+	From  string
+	To    string
+}
+
+// String describes the change as a line of a diff
+func (c Change) String() string {
+	name := c.Name
+	if c.Scope != "" {
+		name = c.Scope + " " + name
+	}
+	switch {
+	case c.From == "":
+		return fmt.Sprintf("+ %s=%s", name, c.To)
+	case c.To == "":
+		return fmt.Sprintf("- %s=%s", name, c.From)
+	default:
+		return fmt.Sprintf("~ %s=%s -> %s", name, c.From, c.To)
+	}
+}
+
+// Diff lists the changes needed to turn current into desired, by name
+func Diff(current, desired map[string]string) []Change {
+	var changes []Change
+	for _, name := range sortedKeys(desired) {
+		if current[name] != desired[name] {
+			changes = append(changes, Change{Name: name, From: current[name], To: desired[name]})
+		}
+	}
+	for _, name := range sortedKeys(current) {
+		if _, ok := desired[name]; !ok {
+			changes = append(changes, Change{Name: name, From: current[name]})
+		}
+	}
+	return changes
+}
+
+// DiffOverrides lists the changes needed to turn the current overrides into
+// the desired ones, by scope and name
+func DiffOverrides(current, desired map[string]map[string]string) []Change {
+	all := make(map[string]string, len(current)+len(desired))
+	for scope := range current {
+		all[scope] = ""
+	}
+	for scope := range desired {
+		all[scope] = ""
+	}
+
+	var changes []Change
+	for _, scope := range sortedKeys(all) {
+		for _, change := range Diff(current[scope], desired[scope]) {
+			change.Scope = scope
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Load reads the stored settings
+func Load(ctx context.Context, queries *db.Queries) (map[string]string, error) {
+	rows, err := queries.ListInstanceSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings := make(map[string]string, len(rows))
+	for _, row := range rows {
+		settings[row.Name] = row.Value
+	}
+	return settings, nil
+}
+
+// LoadOverrides reads the stored overrides, by scope
+func LoadOverrides(ctx context.Context, queries *db.Queries) (map[string]map[string]string, error) {
+	rows, err := queries.ListScopedSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	overrides := make(map[string]map[string]string)
+	for _, row := range rows {
+		if overrides[row.Scope] == nil {
+			overrides[row.Scope] = make(map[string]string)
+		}
+		overrides[row.Scope][row.Name] = row.Value
+	}
+	return overrides, nil
+}
+
+// Apply writes changes to the stored settings. Callers should run it in a
+// transaction so a bundle is applied completely or not at all.
+func Apply(ctx context.Context, queries *db.Queries, changes []Change) error {
+	for _, change := range changes {
+		var err error
+		switch {
+		case change.Scope != "" && change.To == "":
+			err = queries.DeleteScopedSetting(ctx, db.DeleteScopedSettingParams{Scope: change.Scope, Name: change.Name})
+		case change.Scope != "":
+			err = queries.UpsertScopedSetting(ctx, db.UpsertScopedSettingParams{Scope: change.Scope, Name: change.Name, Value: change.To})
+		case change.To == "":
+			err = queries.DeleteInstanceSetting(ctx, change.Name)
+		default:
+			err = queries.UpsertInstanceSetting(ctx, db.UpsertInstanceSettingParams{Name: change.Name, Value: change.To})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply %s: %w", strings.TrimSpace(change.Scope+" "+change.Name), err)
+		}
+	}
+	return nil
+}
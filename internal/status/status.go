@@ -0,0 +1,79 @@
+// Package status reports the operational state of each subsystem, so that a
+// missing token or an unreachable dependency shows up as a degraded feature
+// rather than as silently dropped work.
+package status
+
+import (
+	"context"
+	"sync"
+)
+
+// Feature states
+const (
+	OK       = "ok"
+	Degraded = "degraded"
+	Disabled = "disabled"
+)
+
+// Feature is the state of one subsystem
+type Feature struct {
+	Name   string `json:"name"`
+	State  string `json:"state"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Report is the state of every subsystem. State is degraded if any feature
+// is degraded and ok otherwise; disabled features do not count.
+type Report struct {
+	State    string    `json:"state"`
+	Features []Feature `json:"features"`
+}
+
+// CheckFunc returns the current state of a feature and the reason for it
+type CheckFunc func(ctx context.Context) (state, reason string)
+
+// entry is a registered feature
+type entry struct {
+	name  string
+	check CheckFunc
+}
+
+// Matrix tracks the features of the server in registration order
+type Matrix struct {
+	mu      sync.Mutex
+	entries []entry
+}
+
+// NewMatrix creates an empty matrix
+func NewMatrix() *Matrix {
+	return &Matrix{}
+}
+
+// Register adds a feature whose state is checked on every report
+func (m *Matrix) Register(name string, check CheckFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, entry{name: name, check: check})
+}
+
+// Set adds a feature whose state was decided at startup
+func (m *Matrix) Set(name, state, reason string) {
+	m.Register(name, func(context.Context) (string, string) { return state, reason })
+}
+
+// Report checks every feature
+func (m *Matrix) Report(ctx context.Context) Report {
+	m.mu.Lock()
+	entries := append([]entry(nil), m.entries...)
+	m.mu.Unlock()
+
+	report := Report{State: OK, Features: make([]Feature, 0, len(entries))}
+	for _, e := range entries {
+		state, reason := e.check(ctx)
+		if state == Degraded {
+			report.State = Degraded
+		}
+		report.Features = append(report.Features, Feature{Name: e.name, State: state, Reason: reason})
+	}
+	return report
+}
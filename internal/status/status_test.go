@@ -0,0 +1,40 @@
+package status
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestMatrix_Report(t *testing.T) {
+	matrix := NewMatrix()
+	if report := matrix.Report(context.Background()); report.State != OK || len(report.Features) != 0 {
+		t.Errorf("Expected an empty ok report, got %+v", report)
+	}
+
+	matrix.Set("nats", Disabled, "NATS_URL not set")
+	if report := matrix.Report(context.Background()); report.State != OK {
+		t.Errorf("Disabled features should not degrade the report, got %s", report.State)
+	}
+
+	reachable := true
+	matrix.Register("database", func(context.Context) (string, string) {
+		if reachable {
+			return OK, ""
+		}
+		return Degraded, "database unreachable"
+	})
+	if report := matrix.Report(context.Background()); report.State != OK {
+		t.Errorf("Expected ok, got %s", report.State)
+	}
+
+	reachable = false
+	report := matrix.Report(context.Background())
+	want := Report{State: Degraded, Features: []Feature{
+		{Name: "nats", State: Disabled, Reason: "NATS_URL not set"},
+		{Name: "database", State: Degraded, Reason: "database unreachable"},
+	}}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Report() = %+v, want %+v", report, want)
+	}
+}
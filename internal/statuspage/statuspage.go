@@ -0,0 +1,166 @@
+// Package statuspage builds the public status page of the server: whether
+// webhook deliveries are accepted, how long events take to process and the
+// incident notes posted by the administrators. It is meant for the teams
+// consuming the events, so it says nothing about repositories or
+// configuration.
+package statuspage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/status"
+)
+
+// Page states
+const (
+	Operational = "operational"
+	Degraded    = "degraded"
+	Outage      = "outage"
+)
+
+// Incident impacts
+const (
+	ImpactNone  = "none"
+	ImpactMinor = "minor"
+	ImpactMajor = "major"
+)
+
+// Incident statuses, in the order an incident normally goes through them
+const (
+	Investigating = "investigating"
+	Identified    = "identified"
+	Monitoring    = "monitoring"
+	Resolved      = "resolved"
+)
+
+// Thresholds
+const (
+	// DegradedAvailability is the availability under which the page shows
+	// degraded service
+	DegradedAvailability = 99.0
+	// ResolvedFor is how long resolved incidents stay on the page
+	ResolvedFor = 7 * 24 * time.Hour
+	// MaxTitleLength and MaxBodyLength limit the size of incident notes
+	MaxTitleLength = 200
+	MaxBodyLength  = 5000
+)
+
+// ErrInvalidIncident is returned for incidents that do not pass validation
+var ErrInvalidIncident = errors.New("invalid incident")
+
+// Incident is a note about a disruption, posted by an administrator
+type Incident struct {
+	ID         int32      `json:"id"`
+	Title      string     `json:"title"`
+	Body       string     `json:"body,omitempty"`
+	Impact     string     `json:"impact"`
+	Status     string     `json:"status"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Active reports whether the incident is not resolved yet
+func (i Incident) Active() bool {
+	return i.Status != Resolved
+}
+
+// Validate checks the incident, trimming its title and body and defaulting
+// its impact to minor and its status to investigating
+func (i *Incident) Validate() error {
+	i.Title = strings.TrimSpace(i.Title)
+	i.Body = strings.TrimSpace(i.Body)
+	if i.Impact == "" {
+		i.Impact = ImpactMinor
+	}
+	if i.Status == "" {
+		i.Status = Investigating
+	}
+
+	switch {
+	case i.Title == "":
+		return fmt.Errorf("%w: missing title", ErrInvalidIncident)
+	case len(i.Title) > MaxTitleLength:
+		return fmt.Errorf("%w: title longer than %d characters", ErrInvalidIncident, MaxTitleLength)
+	case len(i.Body) > MaxBodyLength:
+		return fmt.Errorf("%w: body longer than %d characters", ErrInvalidIncident, MaxBodyLength)
+	}
+	switch i.Impact {
+	case ImpactNone, ImpactMinor, ImpactMajor:
+	default:
+		return fmt.Errorf("%w: impact must be none, minor or major, got %q", ErrInvalidIncident, i.Impact)
+	}
+	switch i.Status {
+	case Investigating, Identified, Monitoring, Resolved:
+	default:
+		return fmt.Errorf("%w: status must be investigating, identified, monitoring or resolved, got %q", ErrInvalidIncident, i.Status)
+	}
+	return nil
+}
+
+// Page is the public status page
+type Page struct {
+	Title       string    `json:"title"`
+	State       string    `json:"state"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Ingest and Processing are the states of accepting deliveries and of
+	// processing them
+	Ingest     string     `json:"ingest"`
+	Processing string     `json:"processing"`
+	Deliveries Summary    `json:"deliveries"`
+	Active     []Incident `json:"active_incidents"`
+	Resolved   []Incident `json:"resolved_incidents"`
+}
+
+// Build assembles the page from the tracker's summary, the feature report
+// and the incidents, which should be the active ones and those resolved in
+// the last ResolvedFor, newest first.
+//
+// An active major incident means an outage. An active minor incident,
+// availability under DegradedAvailability or a degraded feature means
+// degraded service. Incidents without impact are shown but change nothing.
+func Build(title string, now time.Time, summary Summary, report status.Report, incidents []Incident) Page {
+	page := Page{
+		Title:       title,
+		State:       Operational,
+		GeneratedAt: now.UTC(),
+		Ingest:      Operational,
+		Processing:  Operational,
+		Deliveries:  summary,
+		Active:      []Incident{},
+		Resolved:    []Incident{},
+	}
+
+	if summary.Availability != nil && *summary.Availability < DegradedAvailability {
+		page.Ingest = Degraded
+	}
+	if report.State == status.Degraded {
+		page.Processing = Degraded
+	}
+	if page.Ingest == Degraded || page.Processing == Degraded {
+		page.State = Degraded
+	}
+
+	for _, incident := range incidents {
+		if !incident.Active() {
+			if incident.ResolvedAt != nil && now.Sub(*incident.ResolvedAt) <= ResolvedFor {
+				page.Resolved = append(page.Resolved, incident)
+			}
+			continue
+		}
+		page.Active = append(page.Active, incident)
+		switch incident.Impact {
+		case ImpactMajor:
+			page.State = Outage
+		case ImpactMinor:
+			if page.State == Operational {
+				page.State = Degraded
+			}
+		}
+	}
+	return page
+}
@@ -0,0 +1,76 @@
+package statuspage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/status"
+)
+
+func TestIncident_Validate(t *testing.T) {
+	incident := Incident{Title: "  Delayed processing  "}
+	if err := incident.Validate(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if incident.Title != "Delayed processing" || incident.Impact != ImpactMinor || incident.Status != Investigating {
+		t.Errorf("Expected a trimmed title and defaults, got %+v", incident)
+	}
+
+	for _, invalid := range []Incident{
+		{},
+		{Title: strings.Repeat("x", MaxTitleLength+1)},
+		{Title: "Outage", Body: strings.Repeat("x", MaxBodyLength+1)},
+		{Title: "Outage", Impact: "critical"},
+		{Title: "Outage", Status: "closed"},
+	} {
+		if err := invalid.Validate(); !errors.Is(err, ErrInvalidIncident) {
+			t.Errorf("Expected ErrInvalidIncident for %+v, got %v", invalid, err)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	now := time.Date(2024, 10, 15, 12, 0, 0, 0, time.UTC)
+	healthy := status.Report{State: status.OK}
+	percent := func(value float64) *float64 { return &value }
+	resolved := func(ago time.Duration) *time.Time {
+		at := now.Add(-ago)
+		return &at
+	}
+
+	tests := []struct {
+		name      string
+		summary   Summary
+		report    status.Report
+		incidents []Incident
+		state     string
+	}{
+		{"no deliveries", Summary{}, healthy, nil, Operational},
+		{"available", Summary{Availability: percent(99.5)}, healthy, nil, Operational},
+		{"failing deliveries", Summary{Availability: percent(97)}, healthy, nil, Degraded},
+		{"degraded feature", Summary{}, status.Report{State: status.Degraded}, nil, Degraded},
+		{"notice", Summary{}, healthy, []Incident{{Impact: ImpactNone, Status: Monitoring}}, Operational},
+		{"minor incident", Summary{}, healthy, []Incident{{Impact: ImpactMinor, Status: Identified}}, Degraded},
+		{"major incident", Summary{Availability: percent(97)}, healthy, []Incident{{Impact: ImpactMajor, Status: Investigating}}, Outage},
+		{"resolved incident", Summary{}, healthy, []Incident{{Impact: ImpactMajor, Status: Resolved, ResolvedAt: resolved(time.Hour)}}, Operational},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := Build("choochoo status", now, tt.summary, tt.report, tt.incidents)
+			if page.State != tt.state {
+				t.Errorf("Expected state %s, got %s", tt.state, page.State)
+			}
+		})
+	}
+
+	page := Build("choochoo status", now, Summary{}, healthy, []Incident{
+		{ID: 3, Impact: ImpactMinor, Status: Monitoring},
+		{ID: 2, Impact: ImpactMajor, Status: Resolved, ResolvedAt: resolved(24 * time.Hour)},
+		{ID: 1, Impact: ImpactMajor, Status: Resolved, ResolvedAt: resolved(ResolvedFor + time.Hour)},
+	})
+	if len(page.Active) != 1 || page.Active[0].ID != 3 || len(page.Resolved) != 1 || page.Resolved[0].ID != 2 {
+		t.Errorf("Expected incident 3 active and 2 recently resolved, got %+v and %+v", page.Active, page.Resolved)
+	}
+}
@@ -0,0 +1,215 @@
+package statuspage
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Window is how far back the tracker remembers deliveries
+const Window = 24 * time.Hour
+
+// latencyBounds are the upper bounds of the latency histogram, from which
+// percentiles are estimated
+var latencyBounds = []time.Duration{
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+}
+
+// bucket holds the deliveries of one hour
+type bucket struct {
+	hour      time.Time
+	requests  int64
+	failures  int64
+	processed int64
+	latency   time.Duration
+	histogram [11]int64
+}
+
+// Hour summarizes the deliveries of one hour
+type Hour struct {
+	Hour     time.Time `json:"hour"`
+	Requests int64     `json:"requests"`
+	Failures int64     `json:"failures"`
+	// Availability is the percentage of deliveries accepted, absent
+	// without deliveries
+	Availability *float64 `json:"availability,omitempty"`
+	// LatencySeconds is the average processing latency, absent without
+	// processed events
+	LatencySeconds *float64 `json:"latency_seconds,omitempty"`
+}
+
+// Tracker counts webhook deliveries and their processing latency in hourly
+// buckets over the last Window. It lives in memory, so a restarted server
+// starts over.
+type Tracker struct {
+	mu      sync.Mutex
+	hours   [24]bucket
+	started time.Time
+	now     func() time.Time
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{started: time.Now(), now: time.Now}
+}
+
+// bucket returns the bucket of the current hour, emptying it if it last
+// held an older hour. mu must be held.
+func (t *Tracker) bucket() *bucket {
+	hour := t.now().UTC().Truncate(time.Hour)
+	b := &t.hours[hour.Unix()/3600%int64(len(t.hours))]
+	if !b.hour.Equal(hour) {
+		*b = bucket{hour: hour}
+	}
+	return b
+}
+
+// ObserveDelivery counts a webhook delivery answered with status. Server
+// errors count against availability; client errors such as bad signatures
+// do not.
+func (t *Tracker) ObserveDelivery(status int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := t.bucket()
+	b.requests++
+	if status >= http.StatusInternalServerError {
+		b.failures++
+	}
+}
+
+// ObserveLatency records how long an event took from being received to
+// being processed
+func (t *Tracker) ObserveLatency(latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := t.bucket()
+	b.processed++
+	b.latency += latency
+	b.histogram[sort.Search(len(latencyBounds), func(i int) bool { return latency <= latencyBounds[i] })]++
+}
+
+// Middleware counts the deliveries answered by next
+func (t *Tracker) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+		t.ObserveDelivery(recorder.status)
+	}
+}
+
+// statusRecorder remembers the status code written to a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Summary is what the tracker saw over the last Window
+type Summary struct {
+	Since    time.Time `json:"since"`
+	Requests int64     `json:"requests"`
+	Failures int64     `json:"failures"`
+	// Availability is the percentage of deliveries accepted, absent
+	// without deliveries
+	Availability *float64 `json:"availability,omitempty"`
+	Processed    int64    `json:"processed"`
+	// LatencySeconds and LatencyP95Seconds are the average and 95th
+	// percentile processing latency, absent without processed events. The
+	// percentile is the upper bound of its histogram bucket.
+	LatencySeconds    *float64 `json:"latency_seconds,omitempty"`
+	LatencyP95Seconds *float64 `json:"latency_p95_seconds,omitempty"`
+	Hours             []Hour   `json:"hours"`
+}
+
+// Summary returns the hours of the last Window, oldest first, with their
+// totals. Since is when the tracker started if that is more recent.
+func (t *Tracker) Summary() Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now().UTC()
+	first := now.Truncate(time.Hour).Add(-Window + time.Hour)
+	summary := Summary{Since: first, Hours: []Hour{}}
+	if t.started.After(first) {
+		summary.Since = t.started.UTC()
+	}
+
+	var latency time.Duration
+	var histogram [11]int64
+	for hour := first; !hour.After(now); hour = hour.Add(time.Hour) {
+		b := t.hours[hour.Unix()/3600%int64(len(t.hours))]
+		if !b.hour.Equal(hour) {
+			b = bucket{hour: hour}
+		}
+		summary.Hours = append(summary.Hours, Hour{
+			Hour:           hour,
+			Requests:       b.requests,
+			Failures:       b.failures,
+			Availability:   availability(b.requests, b.failures),
+			LatencySeconds: average(b.latency, b.processed),
+		})
+		summary.Requests += b.requests
+		summary.Failures += b.failures
+		summary.Processed += b.processed
+		latency += b.latency
+		for i, count := range b.histogram {
+			histogram[i] += count
+		}
+	}
+	summary.Availability = availability(summary.Requests, summary.Failures)
+	summary.LatencySeconds = average(latency, summary.Processed)
+	summary.LatencyP95Seconds = percentile(histogram, summary.Processed, 0.95)
+	return summary
+}
+
+// availability is the percentage of requests that did not fail
+func availability(requests, failures int64) *float64 {
+	if requests == 0 {
+		return nil
+	}
+	percent := float64(requests-failures) * 100 / float64(requests)
+	return &percent
+}
+
+// average is the average of total over count samples, in seconds
+func average(total time.Duration, count int64) *float64 {
+	if count == 0 {
+		return nil
+	}
+	seconds := total.Seconds() / float64(count)
+	return &seconds
+}
+
+// percentile estimates the quantile of a latency histogram as the upper
+// bound of the bucket it falls in. Latencies over the last bound are
+// reported as that bound.
+func percentile(histogram [11]int64, count int64, quantile float64) *float64 {
+	if count == 0 {
+		return nil
+	}
+	rank := int64(float64(count)*quantile + 0.5)
+	var seen int64
+	for i, n := range histogram {
+		seen += n
+		if seen >= rank && i < len(latencyBounds) {
+			seconds := latencyBounds[i].Seconds()
+			return &seconds
+		}
+	}
+	seconds := latencyBounds[len(latencyBounds)-1].Seconds()
+	return &seconds
+}
@@ -0,0 +1,77 @@
+package statuspage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestTracker(now *time.Time) *Tracker {
+	tracker := NewTracker()
+	tracker.started = now.Add(-48 * time.Hour)
+	tracker.now = func() time.Time { return *now }
+	return tracker
+}
+
+func TestTracker_Summary(t *testing.T) {
+	now := time.Date(2024, 10, 15, 12, 30, 0, 0, time.UTC)
+	tracker := newTestTracker(&now)
+
+	for i := 0; i < 98; i++ {
+		tracker.ObserveDelivery(http.StatusOK)
+	}
+	tracker.ObserveDelivery(http.StatusUnauthorized)
+	tracker.ObserveDelivery(http.StatusServiceUnavailable)
+	for i := 0; i < 19; i++ {
+		tracker.ObserveLatency(50 * time.Millisecond)
+	}
+	tracker.ObserveLatency(3 * time.Second)
+
+	summary := tracker.Summary()
+	if summary.Requests != 100 || summary.Failures != 1 || summary.Processed != 20 {
+		t.Fatalf("Unexpected totals %+v", summary)
+	}
+	if summary.Availability == nil || *summary.Availability != 99 {
+		t.Errorf("Expected 99%% availability, got %v", summary.Availability)
+	}
+	if summary.LatencyP95Seconds == nil || *summary.LatencyP95Seconds != 0.1 {
+		t.Errorf("Expected a p95 latency of 0.1s, got %v", summary.LatencyP95Seconds)
+	}
+	if len(summary.Hours) != 24 || !summary.Hours[23].Hour.Equal(now.Truncate(time.Hour)) {
+		t.Errorf("Expected 24 hours ending with the current one, got %+v", summary.Hours)
+	}
+	if summary.Hours[0].Availability != nil {
+		t.Errorf("Expected no availability for an hour without deliveries, got %v", *summary.Hours[0].Availability)
+	}
+}
+
+func TestTracker_Expires(t *testing.T) {
+	now := time.Date(2024, 10, 15, 12, 30, 0, 0, time.UTC)
+	tracker := newTestTracker(&now)
+	tracker.ObserveDelivery(http.StatusInternalServerError)
+
+	now = now.Add(23 * time.Hour)
+	if summary := tracker.Summary(); summary.Failures != 1 {
+		t.Errorf("Expected the failure 23 hours ago to count, got %+v", summary)
+	}
+
+	// The bucket is reused a day later
+	now = now.Add(time.Hour)
+	tracker.ObserveDelivery(http.StatusOK)
+	if summary := tracker.Summary(); summary.Requests != 1 || summary.Failures != 0 {
+		t.Errorf("Expected only the new delivery, got %+v", summary)
+	}
+}
+
+func TestTracker_Middleware(t *testing.T) {
+	tracker := NewTracker()
+	handler := tracker.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Database unavailable", http.StatusServiceUnavailable)
+	})
+	handler(httptest.NewRecorder(), httptest.NewRequest("POST", "/webhook", nil))
+
+	if summary := tracker.Summary(); summary.Requests != 1 || summary.Failures != 1 {
+		t.Errorf("Expected one failed delivery, got %+v", summary)
+	}
+}
@@ -0,0 +1,177 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+	"github.com/deedubs/choochoo/internal/model"
+)
+
+// DefaultBuffer is the number of messages buffered per subscriber
+const DefaultBuffer = 64
+
+// Message is an event as delivered to live stream subscribers
+type Message struct {
+	DeliveryID string          `json:"delivery_id"`
+	EventType  string          `json:"event_type"`
+	Action     string          `json:"action,omitempty"`
+	Repository string          `json:"repository,omitempty"`
+	Sender     string          `json:"sender,omitempty"`
+	ReceivedAt time.Time       `json:"received_at"`
+	Checksum   string          `json:"payload_sha256,omitempty"`
+	Payload    json.RawMessage `json:"payload,omitempty"`
+	// Model is the provider-agnostic model of the event, if it has one
+	Model *model.Event `json:"model,omitempty"`
+}
+
+// Filter selects the messages a subscriber receives. Empty fields match
+// everything.
+type Filter struct {
+	EventTypes []string `json:"event_types,omitempty"`
+	Repository string   `json:"repository,omitempty"`
+}
+
+// ParseFilter builds a filter from a comma-separated event type list and a
+// repository full name
+func ParseFilter(eventTypes, repository string) Filter {
+	var filter Filter
+	for _, eventType := range strings.Split(eventTypes, ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			filter.EventTypes = append(filter.EventTypes, eventType)
+		}
+	}
+	filter.Repository = strings.TrimSpace(repository)
+	return filter
+}
+
+// Matches reports whether a message passes the filter
+func (f Filter) Matches(msg Message) bool {
+	if f.Repository != "" && !strings.EqualFold(f.Repository, msg.Repository) {
+		return false
+	}
+	if len(f.EventTypes) == 0 {
+		return true
+	}
+	for _, eventType := range f.EventTypes {
+		if eventType == msg.EventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscription is a single live stream subscriber
+type Subscription struct {
+	C <-chan Message
+
+	ch      chan Message
+	mu      sync.Mutex
+	filter  Filter
+	dropped uint64
+}
+
+// SetFilter replaces the subscription's filter
+func (s *Subscription) SetFilter(filter Filter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.filter = filter
+}
+
+// Dropped returns the number of messages dropped because the subscriber's
+// buffer was full
+func (s *Subscription) Dropped() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// offer delivers a message without blocking, dropping it if the buffer is full
+func (s *Subscription) offer(msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.filter.Matches(msg) {
+		return
+	}
+	select {
+	case s.ch <- msg:
+	default:
+		s.dropped++
+	}
+}
+
+// Hub fans out received events to live stream subscribers
+type Hub struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// NewHub creates an empty hub
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe registers a subscriber with the given filter and buffer size
+func (h *Hub) Subscribe(filter Filter, buffer int) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBuffer
+	}
+	ch := make(chan Message, buffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe removes a subscriber
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+}
+
+// Subscribers returns the number of active subscribers
+func (h *Hub) Subscribers() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs)
+}
+
+// Publish delivers a message to every matching subscriber. Slow subscribers
+// never block the publisher; messages that do not fit are dropped.
+func (h *Hub) Publish(msg Message) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		sub.offer(msg)
+	}
+}
+
+// Name identifies the hub when used as a forwarder
+func (h *Hub) Name() string {
+	return "stream"
+}
+
+// Forward publishes a forwarded event to live stream subscribers
+func (h *Hub) Forward(ctx context.Context, event forwarder.Event) error {
+	msg := Message{
+		DeliveryID: event.DeliveryID,
+		EventType:  event.EventType,
+		Action:     event.Action,
+		Repository: event.Repository,
+		Sender:     event.Sender,
+		ReceivedAt: time.Now().UTC(),
+		Checksum:   event.Checksum,
+		Model:      event.Model(),
+	}
+	if json.Valid(event.Payload) {
+		msg.Payload = event.Payload
+	}
+	h.Publish(msg)
+	return nil
+}
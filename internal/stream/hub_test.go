@@ -0,0 +1,107 @@
+package stream
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deedubs/choochoo/internal/forwarder"
+)
+
+func TestParseFilter(t *testing.T) {
+	filter := ParseFilter(" push, pull_request ,", " octo-org/hello-world ")
+	if len(filter.EventTypes) != 2 || filter.EventTypes[1] != "pull_request" {
+		t.Errorf("Unexpected event types: %v", filter.EventTypes)
+	}
+	if filter.Repository != "octo-org/hello-world" {
+		t.Errorf("Unexpected repository: %q", filter.Repository)
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	msg := Message{EventType: "push", Repository: "octo-org/hello-world"}
+
+	tests := []struct {
+		filter   Filter
+		expected bool
+	}{
+		{Filter{}, true},
+		{Filter{EventTypes: []string{"push"}}, true},
+		{Filter{EventTypes: []string{"issues", "push"}}, true},
+		{Filter{EventTypes: []string{"issues"}}, false},
+		{Filter{Repository: "Octo-Org/Hello-World"}, true},
+		{Filter{Repository: "octo-org/other"}, false},
+		{Filter{EventTypes: []string{"push"}, Repository: "octo-org/other"}, false},
+	}
+
+	for _, test := range tests {
+		if result := test.filter.Matches(msg); result != test.expected {
+			t.Errorf("%+v.Matches() = %v, expected %v", test.filter, result, test.expected)
+		}
+	}
+}
+
+func TestHub_PublishFiltersSubscribers(t *testing.T) {
+	hub := NewHub()
+	all := hub.Subscribe(Filter{}, 4)
+	pushes := hub.Subscribe(Filter{EventTypes: []string{"push"}}, 4)
+	defer hub.Unsubscribe(all)
+	defer hub.Unsubscribe(pushes)
+
+	hub.Publish(Message{DeliveryID: "1", EventType: "issues"})
+	hub.Publish(Message{DeliveryID: "2", EventType: "push"})
+
+	if len(all.C) != 2 {
+		t.Errorf("Expected unfiltered subscriber to receive 2 messages, got %d", len(all.C))
+	}
+	if len(pushes.C) != 1 {
+		t.Fatalf("Expected push subscriber to receive 1 message, got %d", len(pushes.C))
+	}
+	if msg := <-pushes.C; msg.DeliveryID != "2" {
+		t.Errorf("Expected delivery 2, got %s", msg.DeliveryID)
+	}
+}
+
+func TestHub_DropsWhenBufferFull(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(Filter{}, 1)
+
+	hub.Publish(Message{DeliveryID: "1"})
+	hub.Publish(Message{DeliveryID: "2"})
+
+	if sub.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped message, got %d", sub.Dropped())
+	}
+	if msg := <-sub.C; msg.DeliveryID != "1" {
+		t.Errorf("Expected the first message to be kept, got %s", msg.DeliveryID)
+	}
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(Filter{}, 1)
+	if hub.Subscribers() != 1 {
+		t.Fatalf("Expected 1 subscriber, got %d", hub.Subscribers())
+	}
+
+	hub.Unsubscribe(sub)
+	hub.Publish(Message{DeliveryID: "1"})
+
+	if hub.Subscribers() != 0 || len(sub.C) != 0 {
+		t.Error("Expected unsubscribed subscriber to receive nothing")
+	}
+}
+
+func TestHub_Forward(t *testing.T) {
+	hub := NewHub()
+	sub := hub.Subscribe(Filter{}, 2)
+
+	hub.Forward(context.Background(), forwarder.Event{DeliveryID: "1", EventType: "push", Checksum: "abc", Payload: []byte(`{"ref":"main"}`)})
+	hub.Forward(context.Background(), forwarder.Event{DeliveryID: "2", EventType: "push", Payload: []byte(`not json`)})
+
+	if msg := <-sub.C; string(msg.Payload) != `{"ref":"main"}` || msg.ReceivedAt.IsZero() || msg.Checksum != "abc" || msg.Model == nil || msg.Model.Push.Ref != "main" {
+		t.Errorf("Unexpected message: %+v", msg)
+	}
+	if msg := <-sub.C; msg.Payload != nil {
+		t.Errorf("Expected invalid JSON payload to be omitted, got %s", msg.Payload)
+	}
+}
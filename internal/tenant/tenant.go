@@ -0,0 +1,168 @@
+// Package tenant lets organizations administer their own overrides and API
+// tokens. A tenant is an organization: its tokens are limited to it, and it
+// may change the routes, retention and ignored events of its own scopes,
+// that is the organization itself and its repositories and branches. The
+// instance settings and every other organization stay with the instance
+// administrators.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/settings"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Settings lists the settings a tenant may override. Instance policies such
+// as the retention interval and the SLAs are left to the administrators.
+var Settings = []string{
+	"SECURITY_ALERT_ROUTES",
+	"DISCUSSION_ROUTES",
+	"PROJECT_COLUMN_ROUTES",
+	"DOCS_ROUTES",
+	"COMMUNITY_DIGEST_ROUTES",
+	"RETENTION_POLICY",
+	"RETENTION_MODE",
+	"IGNORED_EVENTS",
+}
+
+var (
+	// ErrNotFound is returned when removing an override that is not set
+	ErrNotFound = errors.New("override not found")
+	// ErrOtherScope is returned for a scope outside of the organization
+	ErrOtherScope = errors.New("scope is outside of the organization")
+	// ErrNotAllowed is returned for a setting tenants may not override
+	ErrNotAllowed = errors.New("setting cannot be overridden by a tenant")
+)
+
+// Override is a stored override of a tenant
+type Override struct {
+	Scope string `json:"scope"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Token is an API token of a tenant, without its secret
+type Token struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Allowed reports whether tenants may override the setting called name
+func Allowed(name string) bool {
+	for _, allowed := range Settings {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckScope checks that scope is a valid override scope of organization
+func CheckScope(organization, scope string) error {
+	if _, err := settings.ScopeLevel(scope); err != nil {
+		return err
+	}
+	if !strings.EqualFold(settings.ScopeOrganization(scope), organization) {
+		return fmt.Errorf("%w %s: %q", ErrOtherScope, organization, scope)
+	}
+	return nil
+}
+
+// ListOverrides returns the stored overrides of organization, sorted by
+// scope and name
+func ListOverrides(ctx context.Context, queries *db.Queries, organization string) ([]Override, error) {
+	stored, err := settings.LoadOverrides(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+	overrides := []Override{}
+	for scope, values := range stored {
+		if !strings.EqualFold(settings.ScopeOrganization(scope), organization) {
+			continue
+		}
+		for name, value := range values {
+			overrides = append(overrides, Override{Scope: scope, Name: name, Value: value})
+		}
+	}
+	sort.Slice(overrides, func(i, j int) bool {
+		if overrides[i].Scope != overrides[j].Scope {
+			return overrides[i].Scope < overrides[j].Scope
+		}
+		return overrides[i].Name < overrides[j].Name
+	})
+	return overrides, nil
+}
+
+// CheckOverride checks that organization may set the setting called name at
+// scope to value. An empty value, removing the override, is always valid.
+func CheckOverride(organization, scope, name, value string) error {
+	if err := CheckScope(organization, scope); err != nil {
+		return err
+	}
+	if !Allowed(name) {
+		return fmt.Errorf("%w: %s", ErrNotAllowed, name)
+	}
+	if value == "" {
+		return nil
+	}
+	return settings.ValidateOverride(map[string]string{name: value})
+}
+
+// SetOverride sets the override of the setting called name at scope of
+// organization, holding the settings lock. An empty value removes it. Like
+// every stored override, the change takes effect when the server restarts.
+func SetOverride(ctx context.Context, dbConn *database.Connection, organization, scope, name, value string) error {
+	if err := CheckOverride(organization, scope, name, value); err != nil {
+		return err
+	}
+
+	return dbConn.InTx(ctx, func(queries *db.Queries) error {
+		if err := queries.LockInstanceSettings(ctx); err != nil {
+			return err
+		}
+		stored, err := settings.LoadOverrides(ctx, queries)
+		if err != nil {
+			return err
+		}
+		current := stored[scope][name]
+		if value == "" && current == "" {
+			return ErrNotFound
+		}
+		changes := settings.Diff(map[string]string{name: current}, map[string]string{name: value})
+		for i := range changes {
+			changes[i].Scope = scope
+		}
+		return settings.Apply(ctx, queries, changes)
+	})
+}
+
+// ListTokens returns the API tokens of organization, including revoked ones
+func ListTokens(ctx context.Context, queries *db.Queries, organization string) ([]Token, error) {
+	rows, err := queries.ListTenantAPITokens(ctx, pgtype.Text{String: strings.ToLower(organization), Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]Token, 0, len(rows))
+	for _, row := range rows {
+		token := Token{Name: row.Name, Scopes: row.Scopes, CreatedAt: row.CreatedAt.Time}
+		if row.LastUsedAt.Valid {
+			token.LastUsedAt = &row.LastUsedAt.Time
+		}
+		if row.RevokedAt.Valid {
+			token.RevokedAt = &row.RevokedAt.Time
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,52 @@
+package tenant
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckScope(t *testing.T) {
+	for _, scope := range []string{"acme", "ACME/api", "acme/api@main"} {
+		if err := CheckScope("acme", scope); err != nil {
+			t.Errorf("Expected %q to be a scope of acme, got %v", scope, err)
+		}
+	}
+	for _, scope := range []string{"globex", "globex/api", "acmecorp/api", "acme-other/api@main"} {
+		if err := CheckScope("acme", scope); !errors.Is(err, ErrOtherScope) {
+			t.Errorf("Expected ErrOtherScope for %q, got %v", scope, err)
+		}
+	}
+	if err := CheckScope("acme", "acme/api/extra"); err == nil || errors.Is(err, ErrOtherScope) {
+		t.Errorf("Expected an invalid scope error, got %v", err)
+	}
+}
+
+func TestCheckOverride(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   string
+		setting string
+		value   string
+		valid   bool
+	}{
+		{"route", "acme/api", "DOCS_ROUTES", "wiki=https://chat.example.com/hook", true},
+		{"route removal", "acme/api", "DOCS_ROUTES", "wiki=", true},
+		{"retention", "acme", "RETENTION_POLICY", "push=30d", true},
+		{"removal", "acme", "IGNORED_EVENTS", "", true},
+		{"invalid value", "acme", "RETENTION_MODE", "shred", false},
+		{"instance policy", "acme", "RETENTION_INTERVAL", "1h", false},
+		{"unknown setting", "acme", "DATABASE_URL", "postgres://", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckOverride("acme", test.scope, test.setting, test.value)
+			if (err == nil) != test.valid {
+				t.Errorf("CheckOverride = %v, want valid %v", err, test.valid)
+			}
+		})
+	}
+
+	if err := CheckOverride("acme", "acme", "RETENTION_INTERVAL", "1h"); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("Expected ErrNotAllowed, got %v", err)
+	}
+}
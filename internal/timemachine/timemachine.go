@@ -0,0 +1,301 @@
+// Package timemachine replays a window of stored events into a staging
+// instance with every automation dark-launched, and diffs how it would handle
+// each event against how the current build does, to validate upgrades of
+// choochoo itself. Both sides run the events through their dry-run ingest
+// endpoint, which traces validation, parsing, the processors, the rules and
+// the forwarders without storing anything or acting on it.
+package timemachine
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/handlers"
+)
+
+// DryRunPath is the dry-run ingest endpoint of an instance
+const DryRunPath = "/api/v1/ingest/dry-run"
+
+// Delivery is a stored event to replay
+type Delivery struct {
+	DeliveryID string
+	EventType  string
+	Repository string
+	ReceivedAt time.Time
+	Payload    []byte
+}
+
+// Target explains how an instance would handle a delivery
+type Target interface {
+	DryRun(ctx context.Context, delivery Delivery) (*handlers.DryRun, error)
+}
+
+// HTTPTarget is an instance reached over HTTP, such as a staging deployment
+type HTTPTarget struct {
+	baseURL string
+	token   string
+	secret  string
+	client  *http.Client
+}
+
+// NewHTTPTarget creates a target for the instance at baseURL, authenticating
+// with an admin token and signing deliveries with secret, if not empty
+func NewHTTPTarget(baseURL, token, secret string) *HTTPTarget {
+	return &HTTPTarget{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		secret:  secret,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// DryRun posts a delivery to the dry-run endpoint of the instance
+func (t *HTTPTarget) DryRun(ctx context.Context, delivery Delivery) (*handlers.DryRun, error) {
+	req, err := newRequest(ctx, t.baseURL+DryRunPath, t.secret, delivery)
+	if err != nil {
+		return nil, err
+	}
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return decode(resp.StatusCode, resp.Body)
+}
+
+// HandlerTarget runs deliveries through a dry-run handler in this process,
+// such as that of the current build
+type HandlerTarget struct {
+	handler http.HandlerFunc
+	secret  string
+}
+
+// NewHandlerTarget creates a target for a dry-run handler, signing
+// deliveries with the first of the secrets the handler accepts, if any
+func NewHandlerTarget(handler http.HandlerFunc, secrets []string) *HandlerTarget {
+	target := &HandlerTarget{handler: handler}
+	if len(secrets) > 0 {
+		target.secret = secrets[0]
+	}
+	return target
+}
+
+// DryRun runs a delivery through the handler
+func (t *HandlerTarget) DryRun(ctx context.Context, delivery Delivery) (*handlers.DryRun, error) {
+	req, err := newRequest(ctx, DryRunPath, t.secret, delivery)
+	if err != nil {
+		return nil, err
+	}
+	rec := &recorder{header: make(http.Header), status: http.StatusOK}
+	t.handler(rec, req)
+	return decode(rec.status, &rec.body)
+}
+
+// recorder keeps the response of a handler run in this process
+type recorder struct {
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+	r.status = status
+	r.wroteHeader = true
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	return r.body.Write(data)
+}
+
+// newRequest builds the dry-run request of a delivery, made like the
+// delivery GitHub sent
+func newRequest(ctx context.Context, url, secret string, delivery Delivery) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", delivery.EventType)
+	req.Header.Set("X-GitHub-Delivery", delivery.DeliveryID)
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(delivery.Payload)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return req, nil
+}
+
+// decode decodes the trace of a dry-run response
+func decode(status int, body io.Reader) (*handlers.DryRun, error) {
+	if status != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(body, 512))
+		return nil, fmt.Errorf("dry run answered %d: %s", status, strings.TrimSpace(string(data)))
+	}
+	var run handlers.DryRun
+	if err := json.NewDecoder(body).Decode(&run); err != nil {
+		return nil, fmt.Errorf("invalid dry-run response: %w", err)
+	}
+	return &run, nil
+}
+
+// Change is a difference in how the baseline and the candidate handle an
+// event. Field is "accepted", "stored", "dropped" or a trace step, such as
+// "rule large-prs" or "processor push"; Baseline and Candidate are the
+// outcomes of each, "absent" for a step only one of them takes.
+type Change struct {
+	Field     string `json:"field"`
+	Baseline  string `json:"baseline"`
+	Candidate string `json:"candidate"`
+}
+
+// Diff is how the handling of one event changed
+type Diff struct {
+	DeliveryID string    `json:"delivery_id"`
+	EventType  string    `json:"event_type"`
+	Repository string    `json:"repository,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+	Changes    []Change  `json:"changes,omitempty"`
+	// Error is set when either side could not run the event
+	Error string `json:"error,omitempty"`
+}
+
+// Report summarizes a replay
+type Report struct {
+	Events    int           `json:"events"`
+	Differing int           `json:"differing"`
+	Failed    int           `json:"failed"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Compare lists the changes between the baseline and the candidate trace of
+// an event. Steps are compared by outcome and result; details, which explain
+// the outcome in prose, are not compared.
+func Compare(baseline, candidate *handlers.DryRun) []Change {
+	var changes []Change
+	flag := func(field string, before, after bool) {
+		if before != after {
+			changes = append(changes, Change{Field: field, Baseline: fmt.Sprint(before), Candidate: fmt.Sprint(after)})
+		}
+	}
+	flag("accepted", baseline.Accepted, candidate.Accepted)
+	flag("stored", baseline.Stored, candidate.Stored)
+	flag("dropped", baseline.Dropped, candidate.Dropped)
+
+	steps := make(map[string]handlers.TraceStep, len(candidate.Trace))
+	for _, step := range candidate.Trace {
+		steps[step.Step] = step
+	}
+	seen := make(map[string]bool, len(baseline.Trace))
+	for _, before := range baseline.Trace {
+		seen[before.Step] = true
+		after, ok := steps[before.Step]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Field: before.Step, Baseline: before.Outcome, Candidate: "absent"})
+		case before.Outcome != after.Outcome:
+			changes = append(changes, Change{Field: before.Step, Baseline: before.Outcome, Candidate: after.Outcome})
+		default:
+			beforeResult, afterResult := result(before), result(after)
+			if beforeResult != afterResult {
+				changes = append(changes, Change{Field: before.Step + " result", Baseline: beforeResult, Candidate: afterResult})
+			}
+		}
+	}
+	for _, after := range candidate.Trace {
+		if !seen[after.Step] {
+			changes = append(changes, Change{Field: after.Step, Baseline: "absent", Candidate: after.Outcome})
+		}
+	}
+	return changes
+}
+
+// result encodes the result of a step for comparison
+func result(step handlers.TraceStep) string {
+	if step.Result == nil {
+		return ""
+	}
+	data, _ := json.Marshal(step.Result)
+	return string(data)
+}
+
+// Replay runs the deliveries, oldest first, through the baseline and the
+// candidate, waiting between deliveries for the time between their receipt
+// divided by speed, or not at all with a speed of 0. observe is called with
+// the diff of each event that changed or failed. Replay stops early when ctx
+// is done.
+func Replay(ctx context.Context, deliveries []Delivery, baseline, candidate Target, speed float64, observe func(Diff)) (Report, error) {
+	var report Report
+	start := time.Now()
+	for i, delivery := range deliveries {
+		if i > 0 && speed > 0 {
+			gap := time.Duration(float64(delivery.ReceivedAt.Sub(deliveries[i-1].ReceivedAt)) / speed)
+			if gap > 0 {
+				select {
+				case <-ctx.Done():
+					report.Duration = time.Since(start)
+					return report, ctx.Err()
+				case <-time.After(gap):
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			report.Duration = time.Since(start)
+			return report, err
+		}
+
+		report.Events++
+		diff := Diff{
+			DeliveryID: delivery.DeliveryID,
+			EventType:  delivery.EventType,
+			Repository: delivery.Repository,
+			ReceivedAt: delivery.ReceivedAt,
+		}
+		before, err := baseline.DryRun(ctx, delivery)
+		if err != nil {
+			diff.Error = "baseline: " + err.Error()
+		}
+		if err == nil {
+			after, err := candidate.DryRun(ctx, delivery)
+			if err != nil {
+				diff.Error = "candidate: " + err.Error()
+			} else {
+				diff.Changes = Compare(before, after)
+			}
+		}
+
+		switch {
+		case diff.Error != "":
+			report.Failed++
+		case len(diff.Changes) > 0:
+			report.Differing++
+		default:
+			continue
+		}
+		if observe != nil {
+			observe(diff)
+		}
+	}
+	report.Duration = time.Since(start)
+	return report, nil
+}
@@ -0,0 +1,214 @@
+package timemachine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/config"
+	"github.com/deedubs/choochoo/internal/handlers"
+	"github.com/deedubs/choochoo/internal/rules"
+)
+
+const testSecret = "test-secret"
+
+// fakeTarget answers with a fixed trace per delivery
+type fakeTarget map[string]*handlers.DryRun
+
+func (f fakeTarget) DryRun(_ context.Context, delivery Delivery) (*handlers.DryRun, error) {
+	run, ok := f[delivery.DeliveryID]
+	if !ok {
+		return nil, errors.New("unknown delivery")
+	}
+	return run, nil
+}
+
+func trace(steps ...handlers.TraceStep) *handlers.DryRun {
+	return &handlers.DryRun{Accepted: true, Stored: true, Trace: steps}
+}
+
+func TestCompare(t *testing.T) {
+	base := trace(
+		handlers.TraceStep{Step: "json", Outcome: "pass"},
+		handlers.TraceStep{Step: "rule main", Outcome: "match", Result: []string{"label"}},
+		handlers.TraceStep{Step: "forwarder slack", Outcome: "pass", Detail: "sent"},
+	)
+
+	tests := []struct {
+		name      string
+		candidate *handlers.DryRun
+		want      []Change
+	}{
+		{
+			name: "same behavior with other details",
+			candidate: trace(
+				handlers.TraceStep{Step: "json", Outcome: "pass"},
+				handlers.TraceStep{Step: "rule main", Outcome: "match", Result: []string{"label"}},
+				handlers.TraceStep{Step: "forwarder slack", Outcome: "pass", Detail: "would be sent"},
+			),
+		},
+		{
+			name: "changed outcome, result and steps",
+			candidate: &handlers.DryRun{Accepted: true, Dropped: true, Trace: []handlers.TraceStep{
+				{Step: "json", Outcome: "pass"},
+				{Step: "rule main", Outcome: "match", Result: []string{"label", "status"}},
+				{Step: "rule bots", Outcome: "match"},
+			}},
+			want: []Change{
+				{Field: "stored", Baseline: "true", Candidate: "false"},
+				{Field: "dropped", Baseline: "false", Candidate: "true"},
+				{Field: "rule main result", Baseline: `["label"]`, Candidate: `["label","status"]`},
+				{Field: "forwarder slack", Baseline: "pass", Candidate: "absent"},
+				{Field: "rule bots", Baseline: "absent", Candidate: "match"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compare(base, tt.candidate); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compare() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	received := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	deliveries := []Delivery{
+		{DeliveryID: "same", EventType: "push", ReceivedAt: received},
+		{DeliveryID: "changed", EventType: "push", ReceivedAt: received.Add(time.Second)},
+		{DeliveryID: "missing", EventType: "push", ReceivedAt: received.Add(2 * time.Second)},
+	}
+	baseline := fakeTarget{
+		"same":    trace(handlers.TraceStep{Step: "json", Outcome: "pass"}),
+		"changed": trace(handlers.TraceStep{Step: "json", Outcome: "pass"}),
+		"missing": trace(),
+	}
+	candidate := fakeTarget{
+		"same":    trace(handlers.TraceStep{Step: "json", Outcome: "pass"}),
+		"changed": trace(handlers.TraceStep{Step: "json", Outcome: "fail"}),
+	}
+
+	var diffs []Diff
+	started := time.Now()
+	report, err := Replay(context.Background(), deliveries, baseline, candidate, 100, func(diff Diff) {
+		diffs = append(diffs, diff)
+	})
+	if err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if elapsed := time.Since(started); elapsed < 20*time.Millisecond {
+		t.Errorf("Replay() took %s, want the 2s window at 100x speed", elapsed)
+	}
+	if report.Events != 3 || report.Differing != 1 || report.Failed != 1 {
+		t.Errorf("Replay() report = %+v", report)
+	}
+	if len(diffs) != 2 || diffs[0].DeliveryID != "changed" || diffs[1].Error != "candidate: unknown delivery" {
+		t.Errorf("Replay() diffs = %+v", diffs)
+	}
+}
+
+func TestReplay_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	deliveries := []Delivery{{DeliveryID: "a"}, {DeliveryID: "b"}}
+	report, err := Replay(ctx, deliveries, fakeTarget{}, fakeTarget{}, 0, nil)
+	if !errors.Is(err, context.Canceled) || report.Events != 0 {
+		t.Errorf("Replay() = %+v, %v, want canceled before any event", report, err)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	// The staging instance drops the pushes the current build only labels
+	staging := handlers.NewWebhookHandler(testSecret, nil).WithRules(rules.NewEngine([]rules.Rule{
+		{Name: "main", Condition: `payload.ref == "refs/heads/main"`, Actions: []rules.Action{{Type: rules.ActionDrop}}},
+	}, nil))
+	var token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("Authorization")
+		if r.URL.Path != DryRunPath {
+			http.NotFound(w, r)
+			return
+		}
+		staging.HandleDryRun(w, r)
+	}))
+	defer server.Close()
+
+	current := handlers.NewWebhookHandler(testSecret, nil).WithRules(rules.NewEngine([]rules.Rule{
+		{Name: "main", Condition: `payload.ref == "refs/heads/main"`, Actions: []rules.Action{{Type: rules.ActionLabel, Labels: []string{"main"}}}},
+	}, nil))
+	delivery := Delivery{
+		DeliveryID: "12345",
+		EventType:  "push",
+		Payload:    []byte(`{"ref":"refs/heads/main","repository":{"full_name":"octo-org/api"}}`),
+	}
+
+	before, err := NewHandlerTarget(current.HandleDryRun, []string{testSecret}).DryRun(context.Background(), delivery)
+	if err != nil {
+		t.Fatalf("HandlerTarget.DryRun() error = %v", err)
+	}
+	after, err := NewHTTPTarget(server.URL+"/", "admin-token", testSecret).DryRun(context.Background(), delivery)
+	if err != nil {
+		t.Fatalf("HTTPTarget.DryRun() error = %v", err)
+	}
+	if token != "Bearer admin-token" {
+		t.Errorf("Authorization = %q", token)
+	}
+	if !before.Accepted || !after.Accepted || before.Repository != "octo-org/api" {
+		t.Fatalf("Signed deliveries not accepted: %+v, %+v", before, after)
+	}
+
+	changes := Compare(before, after)
+	dropped := Change{Field: "dropped", Baseline: "false", Candidate: "true"}
+	if len(changes) == 0 || changes[0] != dropped {
+		t.Errorf("Compare() = %+v, want the drop first", changes)
+	}
+
+	if _, err := NewHTTPTarget(server.URL+"/nowhere", "", "").DryRun(context.Background(), delivery); err == nil {
+		t.Error("DryRun() of a missing endpoint succeeded")
+	}
+}
+
+func TestHandlerTarget_RotatedSecrets(t *testing.T) {
+	// During a rotation the current build accepts both secrets, and signs
+	// dry runs with the first
+	cfg := config.Default()
+	cfg.WebhookSecret = "new-secret, old-secret"
+	current := handlers.NewWebhookHandler(cfg.WebhookSecret, nil)
+	delivery := Delivery{
+		DeliveryID: "12345",
+		EventType:  "push",
+		Payload:    []byte(`{"ref":"refs/heads/main","repository":{"full_name":"octo-org/api"}}`),
+	}
+
+	run, err := NewHandlerTarget(current.HandleDryRun, cfg.WebhookSecrets()).DryRun(context.Background(), delivery)
+	if err != nil {
+		t.Fatalf("HandlerTarget.DryRun() error = %v", err)
+	}
+	if !run.Accepted {
+		t.Errorf("Delivery signed during a rotation not accepted: %+v", run)
+	}
+
+	// The first secret is the one signing, not the whole list
+	rotated := handlers.NewWebhookHandler("new-secret", nil)
+	run, err = NewHandlerTarget(rotated.HandleDryRun, cfg.WebhookSecrets()).DryRun(context.Background(), delivery)
+	if err != nil || !run.Accepted {
+		t.Errorf("Delivery not signed with the first secret: %+v, %v", run, err)
+	}
+}
+
+func TestHandlerTarget_Error(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Dry run not available", http.StatusServiceUnavailable)
+	}
+	_, err := NewHandlerTarget(handler, nil).DryRun(context.Background(), Delivery{DeliveryID: "12345", EventType: "push", Payload: []byte(`{}`)})
+	if err == nil || !strings.Contains(err.Error(), "503: Dry run not available") {
+		t.Errorf("Expected the status and message of the handler, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+// Package tracing traces deliveries with OpenTelemetry from receipt through
+// storage, processing and fan-out. Spans are exported over OTLP/HTTP when an
+// endpoint is configured; otherwise they are not recorded, though incoming
+// trace context is still passed on to forwarded requests.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultServiceName is the service name spans are reported under
+const DefaultServiceName = "choochoo"
+
+const instrumentationName = "github.com/deedubs/choochoo"
+
+// propagator reads and writes W3C traceparent headers
+var propagator = propagation.TraceContext{}
+
+// Config configures the OTLP exporter
+type Config struct {
+	// Endpoint is the base URL of the OTLP/HTTP collector; spans are sent
+	// to its /v1/traces path
+	Endpoint string
+	// Headers are sent with every export, such as collector credentials
+	Headers     map[string]string
+	ServiceName string
+}
+
+// Enabled reports whether spans are exported
+func (c Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// ParseHeaders parses a comma-separated list of key=value headers, the
+// format of OTEL_EXPORTER_OTLP_HEADERS. Values may be URL-encoded.
+func ParseHeaders(list string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid header %q, expected key=value", entry)
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid header %q: %w", entry, err)
+		}
+		headers[key] = decoded
+	}
+	return headers, nil
+}
+
+// Setup installs a tracer provider exporting spans to the configured
+// endpoint. The returned function flushes pending spans and stops the
+// exporter. Without an endpoint, Setup does nothing.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	if !config.Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(config.Endpoint, "/")+"/v1/traces"),
+		otlptracehttp.WithHeaders(config.Headers),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = DefaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe service: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Extract returns ctx continuing the trace of the traceparent header in
+// header, if there is one
+func Extract(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject writes the trace context of ctx to header, so the receiver can
+// continue the trace
+func Inject(ctx context.Context, header http.Header) {
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// TraceParent returns the W3C traceparent of the span in ctx, or "" when
+// ctx is not part of a trace
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// WithTraceParent returns ctx continuing the trace of a traceparent returned
+// by TraceParent
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+}
@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestParseHeaders(t *testing.T) {
+	headers, err := ParseHeaders("authorization=Bearer%20secret, x-team = ops,")
+	if err != nil {
+		t.Fatalf("ParseHeaders failed: %v", err)
+	}
+	if len(headers) != 2 || headers["authorization"] != "Bearer secret" || headers["x-team"] != "ops" {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+
+	for _, list := range []string{"authorization", "=value", "key=%zz"} {
+		if _, err := ParseHeaders(list); err == nil {
+			t.Errorf("Expected an error for %q", list)
+		}
+	}
+}
+
+func TestQueryName(t *testing.T) {
+	tests := map[string]string{
+		"-- name: CreateWebhookEvent :one\nINSERT INTO webhook_events": "CreateWebhookEvent",
+		"begin":      "BEGIN",
+		"  select 1": "SELECT",
+		"":           "query",
+	}
+	for sql, want := range tests {
+		if got := queryName(sql); got != want {
+			t.Errorf("queryName(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
+
+func TestTraceParent(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	if got := TraceParent(context.Background()); got != "" {
+		t.Errorf("Expected no traceparent outside a trace, got %q", got)
+	}
+
+	// A span continuing an incoming request carries on through the queue
+	header := http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+	ctx, span := Start(Extract(context.Background(), header), "webhook.receive")
+	traceParent := TraceParent(ctx)
+	span.End()
+
+	ctx, span = Start(WithTraceParent(context.Background(), traceParent), "workqueue.process")
+	out := http.Header{}
+	Inject(ctx, out)
+	End(span, nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+	for _, s := range spans {
+		if s.SpanContext.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("Span %s is not part of the incoming trace", s.Name)
+		}
+	}
+	if spans[1].Parent.SpanID() != spans[0].SpanContext.SpanID() {
+		t.Errorf("Expected the queued span to continue the receiving span")
+	}
+	if out.Get("Traceparent") != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+spans[1].SpanContext.SpanID().String()+"-01" {
+		t.Errorf("Unexpected outgoing traceparent %q", out.Get("Traceparent"))
+	}
+}
+
+func TestSetup_Disabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Config{})
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown failed: %v", err)
+	}
+}
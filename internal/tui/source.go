@@ -0,0 +1,166 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Statuses of a delivery, as on the admin dashboard
+const (
+	StatusProcessed   = "processed"
+	StatusQueued      = "queued"
+	StatusRetrying    = "retrying"
+	StatusQuarantined = "quarantined"
+)
+
+// Delivery is a stored event with its processing status
+type Delivery struct {
+	DeliveryID string
+	EventType  string
+	Action     string
+	Repository string
+	ReceivedAt time.Time
+	Status     string
+	Attempts   int32
+	Processor  string
+	Error      string
+}
+
+// Snapshot is the state of an instance at one poll
+type Snapshot struct {
+	// Deliveries are the most recent events, newest first
+	Deliveries []Delivery
+	// Failures are the quarantined events and those being retried
+	Failures      []Delivery
+	Pending       int64
+	Quarantined   int64
+	OutboxPending int64
+	OutboxOldest  time.Time
+	PausedBy      string
+	PausedAt      time.Time
+}
+
+// Paused reports whether processing of the work queue is paused
+func (s Snapshot) Paused() bool {
+	return s.PausedBy != ""
+}
+
+// Source reads the state of an instance and runs the actions of the UI
+type Source interface {
+	Snapshot(ctx context.Context) (Snapshot, error)
+	// Replay processes a stored event again through the work queue
+	Replay(ctx context.Context, deliveryID string) error
+	// Pause stops the work queue from processing events until Resume
+	Pause(ctx context.Context, operator string) error
+	Resume(ctx context.Context) error
+}
+
+type postgresSource struct {
+	queries *db.Queries
+	limit   int32
+}
+
+// NewPostgresSource creates a source reading the limit most recent
+// deliveries and the queues of the database
+func NewPostgresSource(queries *db.Queries, limit int) Source {
+	return &postgresSource{queries: queries, limit: int32(limit)}
+}
+
+func (s *postgresSource) Snapshot(ctx context.Context) (Snapshot, error) {
+	var snapshot Snapshot
+	rows, err := s.queries.ListDeliveries(ctx, db.ListDeliveriesParams{RowLimit: s.limit})
+	if err != nil {
+		return snapshot, err
+	}
+	for _, row := range rows {
+		delivery := newDelivery(row)
+		snapshot.Deliveries = append(snapshot.Deliveries, delivery)
+		if delivery.Status == StatusRetrying {
+			snapshot.Failures = append(snapshot.Failures, delivery)
+		}
+	}
+
+	quarantined, err := s.queries.ListQuarantinedWorkItems(ctx)
+	if err != nil {
+		return snapshot, err
+	}
+	for _, row := range quarantined[:min(len(quarantined), int(s.limit))] {
+		snapshot.Failures = append(snapshot.Failures, Delivery{
+			DeliveryID: row.DeliveryID,
+			EventType:  row.EventType,
+			Repository: row.RepositoryName.String,
+			ReceivedAt: row.QuarantinedAt.Time,
+			Status:     StatusQuarantined,
+			Attempts:   row.Attempts,
+			Processor:  row.FailedProcessor.String,
+			Error:      row.LastError.String,
+		})
+	}
+
+	counts, err := s.queries.CountWorkItems(ctx)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.Pending, snapshot.Quarantined = counts.Pending, counts.Quarantined
+
+	outbox, err := s.queries.CountOutboxEntries(ctx)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.OutboxPending, snapshot.OutboxOldest = outbox.Pending, outbox.Oldest.Time
+
+	pause, err := s.queries.GetWorkQueuePause(ctx)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return snapshot, err
+	}
+	snapshot.PausedBy, snapshot.PausedAt = pause.PausedBy, pause.PausedAt.Time
+	return snapshot, nil
+}
+
+// Replay releases a quarantined event, or queues a processed one again
+func (s *postgresSource) Replay(ctx context.Context, deliveryID string) error {
+	released, err := s.queries.ReleaseWorkItem(ctx, deliveryID)
+	if err != nil || released > 0 {
+		return err
+	}
+	return s.queries.EnqueueWorkItem(ctx, db.EnqueueWorkItemParams{DeliveryID: deliveryID, TraceParent: pgtype.Text{}})
+}
+
+func (s *postgresSource) Pause(ctx context.Context, operator string) error {
+	return s.queries.PauseWorkQueue(ctx, operator)
+}
+
+func (s *postgresSource) Resume(ctx context.Context) error {
+	_, err := s.queries.ResumeWorkQueue(ctx)
+	return err
+}
+
+// newDelivery returns a delivery with its status derived from its work item,
+// if it still has one
+func newDelivery(row db.ListDeliveriesRow) Delivery {
+	delivery := Delivery{
+		DeliveryID: row.DeliveryID,
+		EventType:  row.EventType,
+		Action:     row.Action.String,
+		Repository: row.RepositoryName.String,
+		ReceivedAt: row.CreatedAt.Time,
+		Status:     StatusProcessed,
+		Attempts:   row.Attempts.Int32,
+		Processor:  row.FailedProcessor.String,
+		Error:      row.LastError.String,
+	}
+	switch {
+	case row.QuarantinedAt.Valid:
+		delivery.Status = StatusQuarantined
+	case row.LastError.Valid:
+		delivery.Status = StatusRetrying
+	case row.Attempts.Valid:
+		delivery.Status = StatusQueued
+	}
+	return delivery
+}
@@ -0,0 +1,272 @@
+// Package tui is the terminal UI of choochooctl tui, for operators who live
+// in terminals rather than browsers. It shows the events an instance
+// receives as they arrive, its queue depths and recent failures, and can
+// replay events and pause processing.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// DefaultInterval is how often the UI polls the instance by default
+const DefaultInterval = time.Second
+
+// actionTimeout bounds polls and actions
+const actionTimeout = 5 * time.Second
+
+// Panes of the UI
+const (
+	paneEvents = iota
+	paneFailures
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	headerStyle   = lipgloss.NewStyle().Bold(true).Underline(true)
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	newStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	warnStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	helpStyle     = lipgloss.NewStyle().Faint(true)
+)
+
+// snapshotMsg carries the result of a poll
+type snapshotMsg struct {
+	snapshot Snapshot
+	err      error
+}
+
+// tickMsg asks for the next poll
+type tickMsg struct{}
+
+// actionMsg reports the outcome of an action, which the next poll shows
+type actionMsg struct {
+	done string
+	err  error
+}
+
+// Model is the state of the UI
+type Model struct {
+	source   Source
+	operator string
+	interval time.Duration
+
+	snapshot Snapshot
+	err      error
+	loaded   bool
+	// seen holds the deliveries of the previous poll, so new ones stand out
+	seen   map[string]bool
+	fresh  map[string]bool
+	pane   int
+	cursor int
+	status string
+	height int
+}
+
+// New creates the UI of source, polling it every interval. Pauses are
+// recorded as made by operator.
+func New(source Source, operator string, interval time.Duration) Model {
+	return Model{source: source, operator: operator, interval: interval}
+}
+
+// Init polls the instance for the first time
+func (m Model) Init() tea.Cmd {
+	return m.poll()
+}
+
+func (m Model) poll() tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+		defer cancel()
+		snapshot, err := m.source.Snapshot(ctx)
+		return snapshotMsg{snapshot: snapshot, err: err}
+	}
+}
+
+func (m Model) act(done string, action func(ctx context.Context) error) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), actionTimeout)
+		defer cancel()
+		return actionMsg{done: done, err: action(ctx)}
+	}
+}
+
+// Update handles polls, actions and key presses
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case snapshotMsg:
+		m.err = msg.err
+		if msg.err == nil {
+			m.fresh = make(map[string]bool)
+			seen := make(map[string]bool, len(msg.snapshot.Deliveries))
+			for _, delivery := range msg.snapshot.Deliveries {
+				seen[delivery.DeliveryID] = true
+				if m.loaded && !m.seen[delivery.DeliveryID] {
+					m.fresh[delivery.DeliveryID] = true
+				}
+			}
+			m.snapshot, m.seen, m.loaded = msg.snapshot, seen, true
+			m.cursor = min(m.cursor, max(len(m.rows())-1, 0))
+		}
+		return m, tea.Tick(m.interval, func(time.Time) tea.Msg { return tickMsg{} })
+	case tickMsg:
+		return m, m.poll()
+	case actionMsg:
+		m.status = msg.done
+		if msg.err != nil {
+			m.status = "Failed: " + msg.err.Error()
+		}
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		return m.key(msg)
+	}
+	return m, nil
+}
+
+func (m Model) key(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "tab":
+		m.pane = (m.pane + 1) % 2
+		m.cursor = 0
+	case "up", "k":
+		m.cursor = max(m.cursor-1, 0)
+	case "down", "j":
+		m.cursor = min(m.cursor+1, max(len(m.rows())-1, 0))
+	case "r":
+		rows := m.rows()
+		if len(rows) == 0 {
+			return m, nil
+		}
+		id := rows[m.cursor].DeliveryID
+		m.status = "Replaying " + id + "..."
+		return m, m.act("Queued "+id+" for replay", func(ctx context.Context) error {
+			return m.source.Replay(ctx, id)
+		})
+	case "p":
+		if m.snapshot.Paused() {
+			m.status = "Resuming..."
+			return m, m.act("Resumed processing", m.source.Resume)
+		}
+		m.status = "Pausing..."
+		return m, m.act("Paused processing", func(ctx context.Context) error {
+			return m.source.Pause(ctx, m.operator)
+		})
+	}
+	return m, nil
+}
+
+// rows returns the deliveries of the pane shown
+func (m Model) rows() []Delivery {
+	if m.pane == paneFailures {
+		return m.snapshot.Failures
+	}
+	return m.snapshot.Deliveries
+}
+
+// View renders the UI
+func (m Model) View() string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("choochoo") + "  ")
+	switch {
+	case !m.loaded && m.err == nil:
+		b.WriteString("connecting...\n")
+		return b.String()
+	case m.snapshot.Paused():
+		b.WriteString(warnStyle.Render(fmt.Sprintf("⏸ paused by %s since %s", m.snapshot.PausedBy, m.snapshot.PausedAt.Local().Format(time.TimeOnly))))
+	default:
+		b.WriteString(newStyle.Render("▶ processing"))
+	}
+	b.WriteString("\n")
+	b.WriteString(m.depths() + "\n")
+	if m.err != nil {
+		b.WriteString(errorStyle.Render("Failed to poll: "+m.err.Error()) + "\n")
+	}
+	b.WriteString("\n")
+
+	events, failures := "Events", fmt.Sprintf("Failures (%d)", len(m.snapshot.Failures))
+	if m.pane == paneEvents {
+		events = headerStyle.Render(events)
+	} else {
+		failures = headerStyle.Render(failures)
+	}
+	b.WriteString(events + "   " + failures + "\n")
+
+	rows := m.rows()
+	if len(rows) == 0 {
+		b.WriteString(helpStyle.Render("  nothing to show") + "\n")
+	}
+	// Keep the header, the tabs and the footer on screen
+	visible := len(rows)
+	if m.height > 0 {
+		visible = max(m.height-9, 1)
+	}
+	start := max(0, m.cursor-visible+1)
+	for i := start; i < min(len(rows), start+visible); i++ {
+		line := m.line(rows[i])
+		switch {
+		case i == m.cursor:
+			line = selectedStyle.Render(line)
+		case m.fresh[rows[i].DeliveryID]:
+			line = newStyle.Render(line)
+		case rows[i].Status == StatusQuarantined || rows[i].Status == StatusRetrying:
+			line = errorStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n")
+	if m.status != "" {
+		b.WriteString(m.status + "\n")
+	}
+	pause := "pause"
+	if m.snapshot.Paused() {
+		pause = "resume"
+	}
+	b.WriteString(helpStyle.Render("tab switch pane · ↑/↓ select · r replay · p "+pause+" · q quit") + "\n")
+	return b.String()
+}
+
+// depths renders the queue depths and the rate of recent events
+func (m Model) depths() string {
+	recent := 0
+	for _, delivery := range m.snapshot.Deliveries {
+		if time.Since(delivery.ReceivedAt) < time.Minute {
+			recent++
+		}
+	}
+	line := fmt.Sprintf("%d events in the last minute · queue %d pending, %d quarantined · outbox %d pending",
+		recent, m.snapshot.Pending, m.snapshot.Quarantined, m.snapshot.OutboxPending)
+	if m.snapshot.OutboxPending > 0 && !m.snapshot.OutboxOldest.IsZero() {
+		line += fmt.Sprintf(", oldest %s ago", time.Since(m.snapshot.OutboxOldest).Round(time.Second))
+	}
+	if m.snapshot.Quarantined > 0 {
+		return warnStyle.Render(line)
+	}
+	return line
+}
+
+// line renders a delivery as a row of the pane shown
+func (m Model) line(d Delivery) string {
+	event := d.EventType
+	if d.Action != "" {
+		event += "." + d.Action
+	}
+	line := fmt.Sprintf("%s  %-28.28s %-32.32s %-11s %s", d.ReceivedAt.Local().Format(time.TimeOnly), event, d.Repository, d.Status, d.DeliveryID)
+	if m.pane == paneFailures {
+		failure := d.Error
+		if d.Processor != "" {
+			failure = d.Processor + ": " + failure
+		}
+		line += fmt.Sprintf("  %d attempts  %s", d.Attempts, failure)
+	}
+	return line
+}
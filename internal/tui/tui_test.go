@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakeSource is a Source recording the actions run on it
+type fakeSource struct {
+	snapshot Snapshot
+	replayed []string
+	pausedBy string
+	resumed  bool
+}
+
+func (s *fakeSource) Snapshot(ctx context.Context) (Snapshot, error) {
+	return s.snapshot, nil
+}
+
+func (s *fakeSource) Replay(ctx context.Context, deliveryID string) error {
+	s.replayed = append(s.replayed, deliveryID)
+	return nil
+}
+
+func (s *fakeSource) Pause(ctx context.Context, operator string) error {
+	s.pausedBy = operator
+	return nil
+}
+
+func (s *fakeSource) Resume(ctx context.Context) error {
+	s.resumed = true
+	return nil
+}
+
+// run runs cmd and feeds its message back into the model
+func run(t *testing.T, m tea.Model, cmd tea.Cmd) tea.Model {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("expected a command")
+	}
+	m, _ = m.Update(cmd())
+	return m
+}
+
+func press(m tea.Model, key string) (tea.Model, tea.Cmd) {
+	if key == "tab" {
+		return m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	}
+	return m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+}
+
+func TestModel(t *testing.T) {
+	now := time.Now()
+	source := &fakeSource{snapshot: Snapshot{
+		Deliveries: []Delivery{
+			{DeliveryID: "d2", EventType: "push", Repository: "octo/repo", ReceivedAt: now, Status: StatusProcessed},
+			{DeliveryID: "d1", EventType: "issues", Action: "opened", Repository: "octo/repo", ReceivedAt: now, Status: StatusRetrying},
+		},
+		Failures: []Delivery{
+			{DeliveryID: "d0", EventType: "push", Status: StatusQuarantined, Attempts: 5, Processor: "slack", Error: "timeout"},
+		},
+		Pending:     3,
+		Quarantined: 1,
+	}}
+
+	var m tea.Model = New(source, "choochooctl:octocat", time.Second)
+	if view := m.View(); !strings.Contains(view, "connecting") {
+		t.Errorf("View() before the first poll = %q, want connecting", view)
+	}
+	m = run(t, m, m.Init())
+
+	view := m.View()
+	for _, want := range []string{"2 events in the last minute", "queue 3 pending, 1 quarantined", "issues.opened", "processing"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("View() missing %q:\n%s", want, view)
+		}
+	}
+
+	m, _ = press(m, "j")
+	m, cmd := press(m, "r")
+	m = run(t, m, cmd)
+	if len(source.replayed) != 1 || source.replayed[0] != "d1" {
+		t.Errorf("replayed = %v, want [d1]", source.replayed)
+	}
+	if !strings.Contains(m.View(), "Queued d1 for replay") {
+		t.Errorf("View() missing the replay status:\n%s", m.View())
+	}
+
+	m, _ = press(m, "tab")
+	if view := m.View(); !strings.Contains(view, "slack: timeout") {
+		t.Errorf("View() of failures missing the error:\n%s", view)
+	}
+	m, cmd = press(m, "r")
+	m = run(t, m, cmd)
+	if len(source.replayed) != 2 || source.replayed[1] != "d0" {
+		t.Errorf("replayed = %v, want [d1 d0]", source.replayed)
+	}
+
+	m, cmd = press(m, "p")
+	m = run(t, m, cmd)
+	if source.pausedBy != "choochooctl:octocat" {
+		t.Errorf("paused by %q, want choochooctl:octocat", source.pausedBy)
+	}
+	source.snapshot.PausedBy, source.snapshot.PausedAt = source.pausedBy, now
+	m = run(t, m, m.(Model).poll())
+	if view := m.View(); !strings.Contains(view, "paused by choochooctl:octocat") {
+		t.Errorf("View() missing the pause:\n%s", view)
+	}
+	m, cmd = press(m, "p")
+	run(t, m, cmd)
+	if !source.resumed {
+		t.Error("expected p to resume a paused queue")
+	}
+}
+
+func TestModel_HighlightsNewDeliveries(t *testing.T) {
+	source := &fakeSource{snapshot: Snapshot{Deliveries: []Delivery{{DeliveryID: "d1"}}}}
+	var m tea.Model = New(source, "", time.Second)
+	m = run(t, m, m.Init())
+	if fresh := m.(Model).fresh; len(fresh) != 0 {
+		t.Errorf("fresh after the first poll = %v, want none", fresh)
+	}
+
+	source.snapshot.Deliveries = append([]Delivery{{DeliveryID: "d2"}}, source.snapshot.Deliveries...)
+	m = run(t, m, m.(Model).poll())
+	if fresh := m.(Model).fresh; len(fresh) != 1 || !fresh["d2"] {
+		t.Errorf("fresh = %v, want d2", fresh)
+	}
+}
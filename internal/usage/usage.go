@@ -0,0 +1,244 @@
+// Package usage attributes storage and processing costs to repositories and
+// their owners, so a shared instance can be charged back to the teams that
+// use it.
+package usage
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+// Groupings of a report
+const (
+	GroupRepository = "repository"
+	GroupOwner      = "owner"
+)
+
+// Alerts flag rows whose share of the month grows abnormally compared to the
+// previous month
+type Alerts struct {
+	// GrowthPercent is how much a share must grow, relative to the previous
+	// month, to be flagged. 0 disables alerts.
+	GrowthPercent int
+	// MinSharePercent ignores rows with a smaller share, so small
+	// repositories doubling their usage are not flagged
+	MinSharePercent int
+}
+
+// Enabled reports whether alerts are configured
+func (a Alerts) Enabled() bool {
+	return a.GrowthPercent > 0
+}
+
+// anomalous reports whether a share grew abnormally from previous
+func (a Alerts) anomalous(share, previous float64) bool {
+	if !a.Enabled() || share < float64(a.MinSharePercent) {
+		return false
+	}
+	return share >= previous*(1+float64(a.GrowthPercent)/100)
+}
+
+// Totals sums the usage of a month
+type Totals struct {
+	Events            int64   `json:"events"`
+	BytesStored       int64   `json:"bytes_stored"`
+	ProcessingSeconds float64 `json:"processing_seconds"`
+}
+
+// Row is the usage of one repository or owner. Shares are percentages of the
+// month's totals; Share averages the storage and processing shares and is
+// what alerts compare.
+type Row struct {
+	Name              string  `json:"name"`
+	Events            int64   `json:"events"`
+	BytesStored       int64   `json:"bytes_stored"`
+	ProcessingSeconds float64 `json:"processing_seconds"`
+	StorageShare      float64 `json:"storage_share"`
+	ProcessingShare   float64 `json:"processing_share"`
+	Share             float64 `json:"share"`
+	PreviousShare     float64 `json:"previous_share"`
+	Anomalous         bool    `json:"anomalous"`
+}
+
+// Report attributes a month's usage, largest share first
+type Report struct {
+	Month   string `json:"month"`
+	GroupBy string `json:"group_by"`
+	Totals  Totals `json:"totals"`
+	Rows    []Row  `json:"rows"`
+}
+
+// ParseMonth parses a month in YYYY-MM form, defaulting to the month of now
+func ParseMonth(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	month, err := time.Parse("2006-01", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid month %q, expected YYYY-MM", value)
+	}
+	return month, nil
+}
+
+// groupName returns the name a repository is attributed to
+func groupName(group, repository string) string {
+	if group == GroupOwner {
+		owner, _, _ := strings.Cut(repository, "/")
+		return owner
+	}
+	return repository
+}
+
+// totals groups usage rows by name
+func totals(group string, rows []db.RepositoryUsage) (map[string]*Row, Totals) {
+	grouped := make(map[string]*Row)
+	var sum Totals
+	for _, usage := range rows {
+		name := groupName(group, usage.RepositoryName)
+		row := grouped[name]
+		if row == nil {
+			row = &Row{Name: name}
+			grouped[name] = row
+		}
+		seconds := float64(usage.ProcessingUs) / 1e6
+		row.Events += usage.Events
+		row.BytesStored += usage.BytesStored
+		row.ProcessingSeconds += seconds
+		sum.Events += usage.Events
+		sum.BytesStored += usage.BytesStored
+		sum.ProcessingSeconds += seconds
+	}
+	for _, row := range grouped {
+		row.StorageShare = percent(float64(row.BytesStored), float64(sum.BytesStored))
+		row.ProcessingShare = percent(row.ProcessingSeconds, sum.ProcessingSeconds)
+		row.Share = (row.StorageShare + row.ProcessingShare) / 2
+	}
+	return grouped, sum
+}
+
+func percent(part, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return part / whole * 100
+}
+
+// BuildReport attributes the usage of month, comparing each share with the
+// usage of the previous month to flag abnormal growth
+func BuildReport(month time.Time, group string, current, previous []db.RepositoryUsage, alerts Alerts) Report {
+	grouped, sum := totals(group, current)
+	before, _ := totals(group, previous)
+
+	report := Report{Month: month.Format("2006-01"), GroupBy: group, Totals: sum, Rows: make([]Row, 0, len(grouped))}
+	for name, row := range grouped {
+		if previous, ok := before[name]; ok {
+			row.PreviousShare = previous.Share
+		}
+		row.Anomalous = alerts.anomalous(row.Share, row.PreviousShare)
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		if report.Rows[i].Share != report.Rows[j].Share {
+			return report.Rows[i].Share > report.Rows[j].Share
+		}
+		return report.Rows[i].Name < report.Rows[j].Name
+	})
+	return report
+}
+
+// WriteCSV writes the rows of a report as CSV
+func WriteCSV(w io.Writer, report Report) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"month", report.GroupBy, "events", "bytes_stored", "processing_seconds", "storage_share", "processing_share", "share", "previous_share", "anomalous"})
+	for _, row := range report.Rows {
+		out.Write([]string{
+			report.Month,
+			row.Name,
+			strconv.FormatInt(row.Events, 10),
+			strconv.FormatInt(row.BytesStored, 10),
+			strconv.FormatFloat(row.ProcessingSeconds, 'f', 3, 64),
+			strconv.FormatFloat(row.StorageShare, 'f', 2, 64),
+			strconv.FormatFloat(row.ProcessingShare, 'f', 2, 64),
+			strconv.FormatFloat(row.Share, 'f', 2, 64),
+			strconv.FormatFloat(row.PreviousShare, 'f', 2, 64),
+			strconv.FormatBool(row.Anomalous),
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// LoadFunc loads the usage of every repository in a month
+type LoadFunc func(ctx context.Context, month time.Time) ([]db.RepositoryUsage, error)
+
+// Monitor raises an alert when a repository's share of the current month
+// grows abnormally
+type Monitor struct {
+	load   LoadFunc
+	alerts Alerts
+
+	mu      sync.Mutex
+	alerted map[string]bool
+}
+
+// NewMonitor creates a monitor
+func NewMonitor(load LoadFunc, alerts Alerts) *Monitor {
+	return &Monitor{load: load, alerts: alerts, alerted: make(map[string]bool)}
+}
+
+// Check compares the current month with the previous one and logs an alert
+// for each repository that grew abnormally, once per repository and month
+func (m *Monitor) Check(ctx context.Context, now time.Time) ([]Row, error) {
+	month, _ := ParseMonth("", now.UTC())
+	current, err := m.load(ctx, month)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := m.load(ctx, month.AddDate(0, -1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var raised []Row
+	report := BuildReport(month, GroupRepository, current, previous, m.alerts)
+	for _, row := range report.Rows {
+		key := report.Month + " " + row.Name
+		if !row.Anomalous || m.alerted[key] {
+			continue
+		}
+		m.alerted[key] = true
+		raised = append(raised, row)
+		log.Printf("ALERT: usage share of %s grew from %.1f%% to %.1f%% in %s", row.Name, row.PreviousShare, row.Share, report.Month)
+	}
+	return raised, nil
+}
+
+// Run checks usage every interval until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Check(ctx, time.Now()); err != nil {
+				log.Printf("Failed to check usage: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package usage
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+func usageRow(repository string, bytesStored, processingUs int64) db.RepositoryUsage {
+	return db.RepositoryUsage{RepositoryName: repository, Events: 1, BytesStored: bytesStored, ProcessingUs: processingUs}
+}
+
+func TestBuildReport(t *testing.T) {
+	month := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	current := []db.RepositoryUsage{
+		usageRow("octo-org/api", 600, 6_000_000),
+		usageRow("octo-org/web", 200, 2_000_000),
+		usageRow("other-org/docs", 200, 2_000_000),
+	}
+	previous := []db.RepositoryUsage{
+		usageRow("octo-org/api", 200, 2_000_000),
+		usageRow("octo-org/web", 400, 4_000_000),
+		usageRow("other-org/docs", 400, 4_000_000),
+	}
+
+	report := BuildReport(month, GroupRepository, current, previous, Alerts{GrowthPercent: 50, MinSharePercent: 10})
+	if report.Month != "2024-05" || report.Totals.BytesStored != 1000 || report.Totals.ProcessingSeconds != 10 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if len(report.Rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(report.Rows))
+	}
+	api := report.Rows[0]
+	if api.Name != "octo-org/api" || api.Share != 60 || api.PreviousShare != 20 || !api.Anomalous {
+		t.Errorf("Expected octo-org/api to be flagged, got %+v", api)
+	}
+	for _, row := range report.Rows[1:] {
+		if row.Anomalous {
+			t.Errorf("Expected %s not to be flagged", row.Name)
+		}
+	}
+
+	owners := BuildReport(month, GroupOwner, current, previous, Alerts{})
+	if len(owners.Rows) != 2 || owners.Rows[0].Name != "octo-org" || owners.Rows[0].Share != 80 || owners.Rows[0].Anomalous {
+		t.Errorf("Unexpected owner rows: %+v", owners.Rows)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	month := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	report := BuildReport(month, GroupOwner, []db.RepositoryUsage{usageRow("octo-org/api", 100, 1_500_000)}, nil, Alerts{})
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, report); err != nil {
+		t.Fatalf("WriteCSV() failed: %v", err)
+	}
+	want := "month,owner,events,bytes_stored,processing_seconds,storage_share,processing_share,share,previous_share,anomalous\n" +
+		"2024-05,octo-org,1,100,1.500,100.00,100.00,100.00,0.00,false\n"
+	if buf.String() != want {
+		t.Errorf("Unexpected CSV:\n%s", buf.String())
+	}
+}
+
+func TestParseMonth(t *testing.T) {
+	now := time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC)
+	if month, err := ParseMonth("", now); err != nil || !month.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseMonth(\"\") = %v, %v", month, err)
+	}
+	if month, err := ParseMonth("2023-12", now); err != nil || month.Month() != time.December {
+		t.Errorf("ParseMonth(2023-12) = %v, %v", month, err)
+	}
+	if _, err := ParseMonth("December", now); err == nil || !strings.Contains(err.Error(), "YYYY-MM") {
+		t.Errorf("Expected an error for an invalid month, got %v", err)
+	}
+}
+
+func TestMonitor_Check(t *testing.T) {
+	load := func(ctx context.Context, month time.Time) ([]db.RepositoryUsage, error) {
+		if month.Month() == time.May {
+			return []db.RepositoryUsage{usageRow("octo-org/api", 900, 9_000_000), usageRow("octo-org/web", 100, 1_000_000)}, nil
+		}
+		return []db.RepositoryUsage{usageRow("octo-org/api", 100, 1_000_000), usageRow("octo-org/web", 900, 9_000_000)}, nil
+	}
+	monitor := NewMonitor(load, Alerts{GrowthPercent: 50, MinSharePercent: 5})
+	now := time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC)
+
+	raised, err := monitor.Check(context.Background(), now)
+	if err != nil {
+		t.Fatalf("Check() failed: %v", err)
+	}
+	if len(raised) != 1 || raised[0].Name != "octo-org/api" {
+		t.Errorf("Expected an alert for octo-org/api, got %+v", raised)
+	}
+
+	raised, _ = monitor.Check(context.Background(), now)
+	if len(raised) != 0 {
+		t.Errorf("Expected the alert to be raised once per month, got %+v", raised)
+	}
+}
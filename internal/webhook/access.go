@@ -0,0 +1,285 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Access event types
+const (
+	MemberEvent       = "member"
+	MembershipEvent   = "membership"
+	TeamEvent         = "team"
+	OrganizationEvent = "organization"
+)
+
+// Access change kinds
+const (
+	AccessGranted  = "granted"
+	AccessRevoked  = "revoked"
+	AccessModified = "modified"
+)
+
+// IsAccessEvent checks if an event type describes a change in who can access what
+func IsAccessEvent(eventType string) bool {
+	switch eventType {
+	case MemberEvent, MembershipEvent, TeamEvent, OrganizationEvent:
+		return true
+	}
+	return false
+}
+
+// AccessChange is the normalized form of an access event: a principal (user
+// or team) gaining, losing or changing access to a repository, team or
+// organization
+type AccessChange struct {
+	Source             string `json:"source"`
+	Action             string `json:"action"`
+	Change             string `json:"change"`
+	Organization       string `json:"organization"`
+	Repository         string `json:"repository,omitempty"`
+	Team               string `json:"team,omitempty"`
+	Principal          string `json:"principal"`
+	PrincipalType      string `json:"principal_type"`
+	Permission         string `json:"permission,omitempty"`
+	PreviousPermission string `json:"previous_permission,omitempty"`
+}
+
+// Resource describes what access was changed on
+func (ac *AccessChange) Resource() string {
+	switch {
+	case ac.Repository != "":
+		return "repository:" + ac.Repository
+	case ac.Team != "":
+		return "team:" + ac.Organization + "/" + ac.Team
+	default:
+		return "organization:" + ac.Organization
+	}
+}
+
+// IsAdminGrant reports whether the change gave a principal admin access it
+// did not have before
+func (ac *AccessChange) IsAdminGrant() bool {
+	return ac.Change != AccessRevoked && ac.Permission == "admin" && ac.PreviousPermission != "admin"
+}
+
+type accountRef struct {
+	Login string `json:"login"`
+}
+
+type teamRef struct {
+	Slug       string `json:"slug"`
+	Permission string `json:"permission"`
+}
+
+// repositoryPermissions is the permissions object GitHub includes for a
+// team's access to a repository
+type repositoryPermissions struct {
+	Admin    bool `json:"admin"`
+	Maintain bool `json:"maintain"`
+	Push     bool `json:"push"`
+	Triage   bool `json:"triage"`
+	Pull     bool `json:"pull"`
+}
+
+// level returns the highest permission granted
+func (p repositoryPermissions) level() string {
+	switch {
+	case p.Admin:
+		return "admin"
+	case p.Maintain:
+		return "maintain"
+	case p.Push:
+		return "write"
+	case p.Triage:
+		return "triage"
+	case p.Pull:
+		return "read"
+	}
+	return ""
+}
+
+type memberPayload struct {
+	Action  string     `json:"action"`
+	Member  accountRef `json:"member"`
+	Changes struct {
+		Permission struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"permission"`
+		OldPermission struct {
+			From string `json:"from"`
+		} `json:"old_permission"`
+	} `json:"changes"`
+	Repository   map[string]interface{} `json:"repository,omitempty"`
+	Organization accountRef             `json:"organization"`
+}
+
+type membershipPayload struct {
+	Action       string     `json:"action"`
+	Scope        string     `json:"scope"`
+	Member       accountRef `json:"member"`
+	Team         teamRef    `json:"team"`
+	Organization accountRef `json:"organization"`
+}
+
+type teamPayload struct {
+	Action     string  `json:"action"`
+	Team       teamRef `json:"team"`
+	Repository struct {
+		FullName    string                `json:"full_name"`
+		Permissions repositoryPermissions `json:"permissions"`
+	} `json:"repository"`
+	Changes struct {
+		Repository struct {
+			Permissions struct {
+				From repositoryPermissions `json:"from"`
+			} `json:"permissions"`
+		} `json:"repository"`
+	} `json:"changes"`
+	Organization accountRef `json:"organization"`
+}
+
+type organizationPayload struct {
+	Action     string `json:"action"`
+	Membership struct {
+		Role string     `json:"role"`
+		User accountRef `json:"user"`
+	} `json:"membership"`
+	Organization accountRef `json:"organization"`
+}
+
+// ParseAccessChange parses a member, membership, team or organization event
+// into its normalized form. It returns nil without an error for actions that
+// do not change access, such as a team being renamed.
+func ParseAccessChange(eventType string, body []byte) (*AccessChange, error) {
+	switch eventType {
+	case MemberEvent:
+		var payload memberPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+		}
+		repository := repositoryFullName(payload.Repository)
+		change := &AccessChange{
+			Source:        eventType,
+			Action:        payload.Action,
+			Organization:  organizationOf(payload.Organization.Login, repository),
+			Repository:    repository,
+			Principal:     payload.Member.Login,
+			PrincipalType: "user",
+			Permission:    payload.Changes.Permission.To,
+		}
+		switch payload.Action {
+		case "added":
+			change.Change = AccessGranted
+			if change.Permission == "" {
+				change.Permission = "write"
+			}
+		case "edited":
+			change.Change = AccessModified
+			change.PreviousPermission = payload.Changes.Permission.From
+			if change.PreviousPermission == "" {
+				change.PreviousPermission = payload.Changes.OldPermission.From
+			}
+		case "removed":
+			change.Change = AccessRevoked
+		default:
+			return nil, nil
+		}
+		return change, nil
+
+	case MembershipEvent:
+		var payload membershipPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+		}
+		change := &AccessChange{
+			Source:        eventType,
+			Action:        payload.Action,
+			Organization:  payload.Organization.Login,
+			Team:          payload.Team.Slug,
+			Principal:     payload.Member.Login,
+			PrincipalType: "user",
+			Permission:    "member",
+		}
+		switch payload.Action {
+		case "added":
+			change.Change = AccessGranted
+		case "removed":
+			change.Change = AccessRevoked
+		default:
+			return nil, nil
+		}
+		return change, nil
+
+	case TeamEvent:
+		var payload teamPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+		}
+		repository := payload.Repository.FullName
+		change := &AccessChange{
+			Source:        eventType,
+			Action:        payload.Action,
+			Organization:  organizationOf(payload.Organization.Login, repository),
+			Repository:    repository,
+			Principal:     payload.Team.Slug,
+			PrincipalType: "team",
+			Permission:    payload.Repository.Permissions.level(),
+		}
+		switch payload.Action {
+		case "added_to_repository":
+			change.Change = AccessGranted
+		case "removed_from_repository":
+			change.Change = AccessRevoked
+			change.Permission = ""
+		case "edited":
+			previous := payload.Changes.Repository.Permissions.From.level()
+			if repository == "" || previous == "" {
+				return nil, nil
+			}
+			change.Change = AccessModified
+			change.PreviousPermission = previous
+		default:
+			return nil, nil
+		}
+		return change, nil
+
+	case OrganizationEvent:
+		var payload organizationPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+		}
+		change := &AccessChange{
+			Source:        eventType,
+			Action:        payload.Action,
+			Organization:  payload.Organization.Login,
+			Principal:     payload.Membership.User.Login,
+			PrincipalType: "user",
+			Permission:    payload.Membership.Role,
+		}
+		switch payload.Action {
+		case "member_added":
+			change.Change = AccessGranted
+		case "member_removed":
+			change.Change = AccessRevoked
+			change.Permission = ""
+		default:
+			return nil, nil
+		}
+		return change, nil
+	}
+
+	return nil, fmt.Errorf("%s is not an access event", eventType)
+}
+
+// organizationOf returns the organization login, falling back to the owner
+// of the repository full name
+func organizationOf(login, repository string) string {
+	if login != "" {
+		return login
+	}
+	owner, _, _ := strings.Cut(repository, "/")
+	return owner
+}
@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"testing"
+)
+
+// TestParseAccessChange_Member tests parsing collaborator changes
+func TestParseAccessChange_Member(t *testing.T) {
+	body := []byte(`{
+		"action": "edited",
+		"member": {"login": "octocat"},
+		"changes": {"old_permission": {"from": "write"}, "permission": {"to": "admin"}},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`)
+
+	change, err := ParseAccessChange(MemberEvent, body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if change.Change != AccessModified || change.Principal != "octocat" || change.Organization != "octo-org" {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+	if change.Permission != "admin" || change.PreviousPermission != "write" || !change.IsAdminGrant() {
+		t.Errorf("Expected write to admin upgrade, got %+v", change)
+	}
+	if change.Resource() != "repository:octo-org/hello-world" {
+		t.Errorf("Unexpected resource: %s", change.Resource())
+	}
+}
+
+// TestParseAccessChange_Team tests parsing team repository access
+func TestParseAccessChange_Team(t *testing.T) {
+	body := []byte(`{
+		"action": "added_to_repository",
+		"team": {"slug": "platform"},
+		"repository": {"full_name": "octo-org/hello-world", "permissions": {"admin": false, "maintain": true, "push": true, "pull": true}},
+		"organization": {"login": "octo-org"}
+	}`)
+
+	change, err := ParseAccessChange(TeamEvent, body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if change.Change != AccessGranted || change.PrincipalType != "team" || change.Principal != "platform" || change.Permission != "maintain" {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+	if change.IsAdminGrant() {
+		t.Error("Expected maintain access not to be an admin grant")
+	}
+}
+
+// TestParseAccessChange_MembershipAndOrganization tests team and org membership
+func TestParseAccessChange_MembershipAndOrganization(t *testing.T) {
+	membership, err := ParseAccessChange(MembershipEvent, []byte(`{
+		"action": "removed", "scope": "team",
+		"member": {"login": "octocat"}, "team": {"slug": "platform"}, "organization": {"login": "octo-org"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if membership.Change != AccessRevoked || membership.Resource() != "team:octo-org/platform" {
+		t.Errorf("Unexpected membership change: %+v", membership)
+	}
+
+	org, err := ParseAccessChange(OrganizationEvent, []byte(`{
+		"action": "member_added",
+		"membership": {"role": "admin", "user": {"login": "octocat"}},
+		"organization": {"login": "octo-org"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if org.Change != AccessGranted || !org.IsAdminGrant() || org.Resource() != "organization:octo-org" {
+		t.Errorf("Unexpected organization change: %+v", org)
+	}
+}
+
+// TestParseAccessChange_IgnoredActions tests actions that do not change access
+func TestParseAccessChange_IgnoredActions(t *testing.T) {
+	change, err := ParseAccessChange(OrganizationEvent, []byte(`{"action": "renamed", "organization": {"login": "octo-org"}}`))
+	if err != nil || change != nil {
+		t.Errorf("Expected renamed to be ignored, got %+v, %v", change, err)
+	}
+
+	if _, err := ParseAccessChange("push", []byte(`{}`)); err == nil {
+		t.Error("Expected error for non-access event")
+	}
+}
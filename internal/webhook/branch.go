@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ParseBranch returns the branch an event is about: the pushed branch of a
+// push and the base branch of a pull request. It returns "" for events that
+// are not about a branch, such as tag pushes.
+func ParseBranch(body []byte) string {
+	var event struct {
+		Ref         string `json:"ref"`
+		PullRequest *struct {
+			Base struct {
+				Ref string `json:"ref"`
+			} `json:"base"`
+		} `json:"pull_request"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return ""
+	}
+	if branch, ok := strings.CutPrefix(event.Ref, "refs/heads/"); ok {
+		return branch
+	}
+	if event.PullRequest != nil {
+		return event.PullRequest.Base.Ref
+	}
+	return ""
+}
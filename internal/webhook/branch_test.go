@@ -0,0 +1,21 @@
+package webhook
+
+import "testing"
+
+func TestParseBranch(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+	}{
+		{`{"ref": "refs/heads/release/1.2"}`, "release/1.2"},
+		{`{"ref": "refs/tags/v1.2.0"}`, ""},
+		{`{"pull_request": {"base": {"ref": "main"}}}`, "main"},
+		{`{"action": "created"}`, ""},
+		{`not json`, ""},
+	}
+	for _, test := range tests {
+		if branch := ParseBranch([]byte(test.body)); branch != test.expected {
+			t.Errorf("ParseBranch(%s) = %q, want %q", test.body, branch, test.expected)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CI event types
+const (
+	CheckSuiteEvent  = "check_suite"
+	CheckRunEvent    = "check_run"
+	WorkflowRunEvent = "workflow_run"
+)
+
+// IsCIEvent checks if an event type describes a check suite, check run or
+// Actions workflow run
+func IsCIEvent(eventType string) bool {
+	return eventType == CheckSuiteEvent || eventType == CheckRunEvent || eventType == WorkflowRunEvent
+}
+
+// appRef is the GitHub App behind a check suite or check run
+type appRef struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// CheckSuite is the check_suite object of a check_suite event
+type CheckSuite struct {
+	ID         int64      `json:"id"`
+	HeadBranch string     `json:"head_branch"`
+	HeadSHA    string     `json:"head_sha"`
+	Status     string     `json:"status"`
+	Conclusion string     `json:"conclusion"`
+	App        appRef     `json:"app"`
+	CreatedAt  *time.Time `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+}
+
+// CheckRun is the check_run object of a check_run event
+type CheckRun struct {
+	ID          int64      `json:"id"`
+	Name        string     `json:"name"`
+	HeadSHA     string     `json:"head_sha"`
+	Status      string     `json:"status"`
+	Conclusion  string     `json:"conclusion"`
+	HTMLURL     string     `json:"html_url"`
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	App         appRef     `json:"app"`
+	CheckSuite  struct {
+		HeadBranch string `json:"head_branch"`
+	} `json:"check_suite"`
+}
+
+// WorkflowRun is the workflow_run object of a workflow_run event
+type WorkflowRun struct {
+	ID           int64      `json:"id"`
+	Name         string     `json:"name"`
+	HeadBranch   string     `json:"head_branch"`
+	HeadSHA      string     `json:"head_sha"`
+	Event        string     `json:"event"`
+	Status       string     `json:"status"`
+	Conclusion   string     `json:"conclusion"`
+	RunAttempt   int        `json:"run_attempt"`
+	HTMLURL      string     `json:"html_url"`
+	RunStartedAt *time.Time `json:"run_started_at"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
+
+// CIRun is a check suite, check run or workflow run normalized from its
+// event. Name is the check name, the workflow name, or the name of the app
+// of a check suite.
+type CIRun struct {
+	Kind       string `json:"kind"`
+	ID         int64  `json:"id"`
+	Repository string `json:"repository"`
+	Name       string `json:"name"`
+	App        string `json:"app,omitempty"`
+	HeadBranch string `json:"head_branch,omitempty"`
+	HeadSHA    string `json:"head_sha"`
+	// Event is what triggered a workflow run, such as push
+	Event       string     `json:"event,omitempty"`
+	Status      string     `json:"status"`
+	Conclusion  string     `json:"conclusion,omitempty"`
+	Attempt     int        `json:"attempt"`
+	HTMLURL     string     `json:"html_url,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// UpdatedAt orders the deliveries of a run
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Completed reports whether the run has finished
+func (r *CIRun) Completed() bool {
+	return r.Status == "completed"
+}
+
+// Duration is how long a completed run took, from its start to its
+// completion
+func (r *CIRun) Duration() (time.Duration, bool) {
+	if !r.Completed() || r.StartedAt == nil || r.CompletedAt == nil || r.CompletedAt.Before(*r.StartedAt) {
+		return 0, false
+	}
+	return r.CompletedAt.Sub(*r.StartedAt), true
+}
+
+type ciPayload struct {
+	CheckSuite  *CheckSuite            `json:"check_suite"`
+	CheckRun    *CheckRun              `json:"check_run"`
+	WorkflowRun *WorkflowRun           `json:"workflow_run"`
+	Repository  map[string]interface{} `json:"repository,omitempty"`
+}
+
+// ParseCIRun parses a check_suite, check_run or workflow_run event
+func ParseCIRun(eventType string, body []byte) (*CIRun, error) {
+	if !IsCIEvent(eventType) {
+		return nil, fmt.Errorf("%s is not a CI event", eventType)
+	}
+
+	var payload ciPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+	}
+
+	run := &CIRun{Kind: eventType, Repository: repositoryFullName(payload.Repository), Attempt: 1}
+	switch eventType {
+	case CheckSuiteEvent:
+		suite := payload.CheckSuite
+		if suite == nil || suite.ID == 0 {
+			return nil, fmt.Errorf("%s payload is missing the check suite ID", eventType)
+		}
+		run.ID, run.Name, run.App = suite.ID, suite.App.Name, suite.App.Slug
+		run.HeadBranch, run.HeadSHA = suite.HeadBranch, suite.HeadSHA
+		run.Status, run.Conclusion = suite.Status, suite.Conclusion
+		// Suites have no start and completion times; they are created when
+		// requested and last updated when they complete
+		run.StartedAt = suite.CreatedAt
+		if run.Completed() {
+			run.CompletedAt = suite.UpdatedAt
+		}
+		run.UpdatedAt = firstTime(suite.UpdatedAt, suite.CreatedAt)
+	case CheckRunEvent:
+		check := payload.CheckRun
+		if check == nil || check.ID == 0 {
+			return nil, fmt.Errorf("%s payload is missing the check run ID", eventType)
+		}
+		run.ID, run.Name, run.App = check.ID, check.Name, check.App.Slug
+		run.HeadBranch, run.HeadSHA = check.CheckSuite.HeadBranch, check.HeadSHA
+		run.Status, run.Conclusion, run.HTMLURL = check.Status, check.Conclusion, check.HTMLURL
+		run.StartedAt, run.CompletedAt = check.StartedAt, check.CompletedAt
+		run.UpdatedAt = firstTime(check.CompletedAt, check.StartedAt)
+	case WorkflowRunEvent:
+		workflow := payload.WorkflowRun
+		if workflow == nil || workflow.ID == 0 {
+			return nil, fmt.Errorf("%s payload is missing the workflow run ID", eventType)
+		}
+		run.ID, run.Name, run.App = workflow.ID, workflow.Name, "github-actions"
+		run.HeadBranch, run.HeadSHA, run.Event = workflow.HeadBranch, workflow.HeadSHA, workflow.Event
+		run.Status, run.Conclusion, run.HTMLURL = workflow.Status, workflow.Conclusion, workflow.HTMLURL
+		if workflow.RunAttempt > 0 {
+			run.Attempt = workflow.RunAttempt
+		}
+		run.StartedAt = workflow.RunStartedAt
+		if run.Completed() {
+			run.CompletedAt = workflow.UpdatedAt
+		}
+		run.UpdatedAt = firstTime(workflow.UpdatedAt, workflow.RunStartedAt)
+	}
+	return run, nil
+}
+
+// firstTime returns the first of times that is set, or the zero time
+func firstTime(times ...*time.Time) time.Time {
+	for _, t := range times {
+		if t != nil {
+			return *t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseCIRun_WorkflowRun tests parsing workflow_run events
+func TestParseCIRun_WorkflowRun(t *testing.T) {
+	run, err := ParseCIRun(WorkflowRunEvent, []byte(`{
+		"action": "completed",
+		"workflow_run": {
+			"id": 30433642, "name": "Build", "head_branch": "main", "head_sha": "acb5820",
+			"event": "push", "status": "completed", "conclusion": "success", "run_attempt": 2,
+			"html_url": "https://github.com/octo-org/hello-world/actions/runs/30433642",
+			"run_started_at": "2024-05-01T10:00:00Z", "updated_at": "2024-05-01T10:04:30Z"
+		},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if run.Kind != WorkflowRunEvent || run.ID != 30433642 || run.Name != "Build" || run.Repository != "octo-org/hello-world" {
+		t.Errorf("Unexpected run: %+v", run)
+	}
+	if run.Event != "push" || run.Conclusion != "success" || run.Attempt != 2 || run.HeadBranch != "main" {
+		t.Errorf("Unexpected run details: %+v", run)
+	}
+	if duration, ok := run.Duration(); !ok || duration != 270*time.Second {
+		t.Errorf("Expected a duration of 4m30s, got %v, %v", duration, ok)
+	}
+}
+
+// TestParseCIRun_CheckRun tests parsing check_run events
+func TestParseCIRun_CheckRun(t *testing.T) {
+	run, err := ParseCIRun(CheckRunEvent, []byte(`{
+		"action": "created",
+		"check_run": {
+			"id": 128620228, "name": "lint", "head_sha": "ce587453", "status": "in_progress", "conclusion": null,
+			"started_at": "2024-05-01T10:00:00Z", "completed_at": null,
+			"app": {"slug": "octoapp"}, "check_suite": {"head_branch": "feature"}
+		},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if run.Name != "lint" || run.App != "octoapp" || run.HeadBranch != "feature" || run.Completed() || run.Attempt != 1 {
+		t.Errorf("Unexpected run: %+v", run)
+	}
+	if _, ok := run.Duration(); ok {
+		t.Error("Expected no duration for a run in progress")
+	}
+	if !run.UpdatedAt.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected the run to be ordered by its start, got %v", run.UpdatedAt)
+	}
+}
+
+// TestParseCIRun_CheckSuite tests parsing check_suite events
+func TestParseCIRun_CheckSuite(t *testing.T) {
+	run, err := ParseCIRun(CheckSuiteEvent, []byte(`{
+		"action": "completed",
+		"check_suite": {
+			"id": 118578147, "head_branch": "main", "head_sha": "d6fde92", "status": "completed", "conclusion": "failure",
+			"app": {"slug": "github-actions", "name": "GitHub Actions"},
+			"created_at": "2024-05-01T10:00:00Z", "updated_at": "2024-05-01T10:01:00Z"
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if run.Name != "GitHub Actions" || run.App != "github-actions" || run.Conclusion != "failure" {
+		t.Errorf("Unexpected run: %+v", run)
+	}
+	if duration, ok := run.Duration(); !ok || duration != time.Minute {
+		t.Errorf("Expected a duration of 1m, got %v, %v", duration, ok)
+	}
+}
+
+// TestParseCIRun_Invalid tests rejecting payloads without a run
+func TestParseCIRun_Invalid(t *testing.T) {
+	for eventType, body := range map[string]string{
+		CheckSuiteEvent:  `{"action": "requested"}`,
+		CheckRunEvent:    `{"check_run": {"name": "lint"}}`,
+		WorkflowRunEvent: `not json`,
+		PullRequestEvent: `{}`,
+	} {
+		if _, err := ParseCIRun(eventType, []byte(body)); err == nil {
+			t.Errorf("Expected error for %s payload %s", eventType, body)
+		}
+	}
+}
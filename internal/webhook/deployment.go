@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DeploymentStatusEvent is the event type of deployment status changes
+const DeploymentStatusEvent = "deployment_status"
+
+// DeploymentStatus is a deployment_status event normalized with its
+// deployment. State is pending, queued, in_progress, success, failure,
+// error or inactive.
+type DeploymentStatus struct {
+	DeploymentID int64  `json:"deployment_id"`
+	Repository   string `json:"repository"`
+	Environment  string `json:"environment"`
+	Ref          string `json:"ref"`
+	SHA          string `json:"sha"`
+	State        string `json:"state"`
+	// CreatedAt is when the status was set
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Finished reports whether the deployment succeeded or failed
+func (s *DeploymentStatus) Finished() bool {
+	return s.State == "success" || s.State == "failure" || s.State == "error"
+}
+
+type deploymentStatusPayload struct {
+	DeploymentStatus *struct {
+		State     string     `json:"state"`
+		CreatedAt *time.Time `json:"created_at"`
+		UpdatedAt *time.Time `json:"updated_at"`
+	} `json:"deployment_status"`
+	Deployment *struct {
+		ID          int64  `json:"id"`
+		Environment string `json:"environment"`
+		Ref         string `json:"ref"`
+		SHA         string `json:"sha"`
+	} `json:"deployment"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+}
+
+// ParseDeploymentStatus parses a deployment_status event
+func ParseDeploymentStatus(body []byte) (*DeploymentStatus, error) {
+	var payload deploymentStatusPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid deployment_status payload: %w", err)
+	}
+	if payload.Deployment == nil || payload.Deployment.ID == 0 {
+		return nil, fmt.Errorf("deployment_status payload is missing the deployment ID")
+	}
+	if payload.DeploymentStatus == nil || payload.DeploymentStatus.State == "" {
+		return nil, fmt.Errorf("deployment_status payload is missing the status")
+	}
+	return &DeploymentStatus{
+		DeploymentID: payload.Deployment.ID,
+		Repository:   repositoryFullName(payload.Repository),
+		Environment:  payload.Deployment.Environment,
+		Ref:          payload.Deployment.Ref,
+		SHA:          payload.Deployment.SHA,
+		State:        payload.DeploymentStatus.State,
+		CreatedAt:    firstTime(payload.DeploymentStatus.CreatedAt, payload.DeploymentStatus.UpdatedAt),
+	}, nil
+}
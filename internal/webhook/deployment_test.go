@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseDeploymentStatus tests parsing deployment_status events
+func TestParseDeploymentStatus(t *testing.T) {
+	status, err := ParseDeploymentStatus([]byte(`{
+		"action": "created",
+		"deployment_status": {"id": 2, "state": "failure", "created_at": "2024-05-01T10:05:00Z", "updated_at": "2024-05-01T10:06:00Z"},
+		"deployment": {"id": 42, "environment": "production", "ref": "main", "sha": "a1b2c3"},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if status.DeploymentID != 42 || status.Repository != "octo-org/hello-world" || status.Environment != "production" ||
+		status.Ref != "main" || status.SHA != "a1b2c3" || status.State != "failure" || !status.Finished() {
+		t.Errorf("Unexpected deployment status: %+v", status)
+	}
+	if !status.CreatedAt.Equal(time.Date(2024, 5, 1, 10, 5, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected creation time %v", status.CreatedAt)
+	}
+
+	pending, err := ParseDeploymentStatus([]byte(`{"deployment_status": {"state": "in_progress"}, "deployment": {"id": 42}}`))
+	if err != nil || pending.Finished() {
+		t.Errorf("Expected an unfinished deployment, got %+v, %v", pending, err)
+	}
+
+	for _, body := range []string{`not json`, `{"deployment_status": {"state": "success"}}`, `{"deployment": {"id": 42}}`} {
+		if _, err := ParseDeploymentStatus([]byte(body)); err == nil {
+			t.Errorf("Expected an error for %s", body)
+		}
+	}
+}
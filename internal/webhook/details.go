@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Outcomes of the runs, deployments and page builds events report
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// EventDetails are the fields of a payload that reports read in SQL, kept
+// in plaintext next to the event since the payload may be encrypted
+type EventDetails struct {
+	// SourceUpdatedAt is when the pull request of a pull_request event
+	// changed on GitHub
+	SourceUpdatedAt *time.Time
+	// Outcome is OutcomeSuccess or OutcomeFailure for completed workflow
+	// runs, finished deployments and page builds
+	Outcome string
+	// Subject is the sponsored account of a sponsorship event
+	Subject string
+}
+
+// Empty reports whether none of the details are set
+func (d EventDetails) Empty() bool {
+	return d.SourceUpdatedAt == nil && d.Outcome == "" && d.Subject == ""
+}
+
+// workflowOutcomes maps the conclusions of workflow runs to outcomes.
+// Cancelled, skipped and neutral runs have none.
+var workflowOutcomes = map[string]string{
+	"success":         OutcomeSuccess,
+	"failure":         OutcomeFailure,
+	"timed_out":       OutcomeFailure,
+	"startup_failure": OutcomeFailure,
+}
+
+type detailsPayload struct {
+	Action      string `json:"action"`
+	PullRequest *struct {
+		UpdatedAt *time.Time `json:"updated_at"`
+	} `json:"pull_request"`
+	WorkflowRun *struct {
+		Conclusion string `json:"conclusion"`
+	} `json:"workflow_run"`
+	DeploymentStatus *struct {
+		State string `json:"state"`
+	} `json:"deployment_status"`
+	Build *struct {
+		Status string `json:"status"`
+	} `json:"build"`
+	Sponsorship *struct {
+		Sponsorable struct {
+			Login string `json:"login"`
+		} `json:"sponsorable"`
+	} `json:"sponsorship"`
+}
+
+// ParseEventDetails extracts the details of an event, which are empty for
+// event types without any and for payloads that do not parse
+func ParseEventDetails(eventType string, body []byte) EventDetails {
+	var details EventDetails
+	var payload detailsPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return details
+	}
+
+	switch eventType {
+	case PullRequestEvent:
+		if payload.PullRequest != nil {
+			details.SourceUpdatedAt = payload.PullRequest.UpdatedAt
+		}
+	case WorkflowRunEvent:
+		if payload.Action == "completed" && payload.WorkflowRun != nil {
+			details.Outcome = workflowOutcomes[payload.WorkflowRun.Conclusion]
+		}
+	case DeploymentStatusEvent:
+		if payload.DeploymentStatus != nil {
+			switch payload.DeploymentStatus.State {
+			case "success":
+				details.Outcome = OutcomeSuccess
+			case "failure", "error":
+				details.Outcome = OutcomeFailure
+			}
+		}
+	case PageBuildEvent:
+		if payload.Build != nil {
+			switch payload.Build.Status {
+			case "built":
+				details.Outcome = OutcomeSuccess
+			case "errored":
+				details.Outcome = OutcomeFailure
+			}
+		}
+	case "sponsorship":
+		if payload.Sponsorship != nil {
+			details.Subject = payload.Sponsorship.Sponsorable.Login
+		}
+	}
+	return details
+}
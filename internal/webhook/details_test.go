@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseEventDetails tests extracting the fields reports read from
+// payloads
+func TestParseEventDetails(t *testing.T) {
+	updated := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		eventType string
+		body      string
+		expected  EventDetails
+	}{
+		{"pull request", PullRequestEvent, `{"action":"opened","pull_request":{"updated_at":"2024-05-01T10:00:00Z"}}`, EventDetails{SourceUpdatedAt: &updated}},
+		{"successful workflow run", WorkflowRunEvent, `{"action":"completed","workflow_run":{"conclusion":"success"}}`, EventDetails{Outcome: OutcomeSuccess}},
+		{"timed out workflow run", WorkflowRunEvent, `{"action":"completed","workflow_run":{"conclusion":"timed_out"}}`, EventDetails{Outcome: OutcomeFailure}},
+		{"cancelled workflow run", WorkflowRunEvent, `{"action":"completed","workflow_run":{"conclusion":"cancelled"}}`, EventDetails{}},
+		{"requested workflow run", WorkflowRunEvent, `{"action":"requested","workflow_run":{"conclusion":null}}`, EventDetails{}},
+		{"failed deployment", DeploymentStatusEvent, `{"deployment_status":{"state":"error"}}`, EventDetails{Outcome: OutcomeFailure}},
+		{"pending deployment", DeploymentStatusEvent, `{"deployment_status":{"state":"pending"}}`, EventDetails{}},
+		{"page build", PageBuildEvent, `{"build":{"status":"built"}}`, EventDetails{Outcome: OutcomeSuccess}},
+		{"sponsorship", "sponsorship", `{"action":"created","sponsorship":{"sponsorable":{"login":"octo-org"}}}`, EventDetails{Subject: "octo-org"}},
+		{"other event", PushEvent, `{"ref":"refs/heads/main"}`, EventDetails{}},
+		{"invalid payload", PullRequestEvent, `not json`, EventDetails{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			details := ParseEventDetails(test.eventType, []byte(test.body))
+			if details.Outcome != test.expected.Outcome || details.Subject != test.expected.Subject {
+				t.Errorf("Expected %+v, got %+v", test.expected, details)
+			}
+			if (details.SourceUpdatedAt == nil) != (test.expected.SourceUpdatedAt == nil) ||
+				details.SourceUpdatedAt != nil && !details.SourceUpdatedAt.Equal(*test.expected.SourceUpdatedAt) {
+				t.Errorf("Expected source update %v, got %v", test.expected.SourceUpdatedAt, details.SourceUpdatedAt)
+			}
+			if details.Empty() != test.expected.Empty() {
+				t.Errorf("Expected Empty() %v", test.expected.Empty())
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Discussion event types
+const (
+	DiscussionEvent        = "discussion"
+	DiscussionCommentEvent = "discussion_comment"
+)
+
+// IsDiscussionEvent checks if an event type is a GitHub Discussions event
+func IsDiscussionEvent(eventType string) bool {
+	return eventType == DiscussionEvent || eventType == DiscussionCommentEvent
+}
+
+// Discussion is the discussion object of discussion and discussion_comment events
+type Discussion struct {
+	Number        int       `json:"number"`
+	Title         string    `json:"title"`
+	Body          string    `json:"body"`
+	State         string    `json:"state"`
+	HTMLURL       string    `json:"html_url"`
+	AnswerHTMLURL string    `json:"answer_html_url"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Category      struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	} `json:"category"`
+	User accountRef `json:"user"`
+}
+
+// Answered reports whether an answer has been marked
+func (d *Discussion) Answered() bool {
+	return d.AnswerHTMLURL != ""
+}
+
+// DiscussionComment is the comment object of a discussion_comment event
+type DiscussionComment struct {
+	ID        int64      `json:"id"`
+	Body      string     `json:"body"`
+	HTMLURL   string     `json:"html_url"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	User      accountRef `json:"user"`
+	// AuthorAssociation is the author's relationship to the repository,
+	// e.g. OWNER, MEMBER, COLLABORATOR or NONE
+	AuthorAssociation string `json:"author_association"`
+}
+
+// DiscussionActivity is a parsed discussion or discussion_comment event
+type DiscussionActivity struct {
+	EventType  string             `json:"event_type"`
+	Action     string             `json:"action"`
+	Repository string             `json:"repository"`
+	Discussion Discussion         `json:"discussion"`
+	Comment    *DiscussionComment `json:"comment,omitempty"`
+}
+
+type discussionPayload struct {
+	Action     string                 `json:"action"`
+	Discussion Discussion             `json:"discussion"`
+	Comment    *DiscussionComment     `json:"comment"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+}
+
+// ParseDiscussionActivity parses a discussion or discussion_comment event
+func ParseDiscussionActivity(eventType string, body []byte) (*DiscussionActivity, error) {
+	if !IsDiscussionEvent(eventType) {
+		return nil, fmt.Errorf("%s is not a discussion event", eventType)
+	}
+
+	var payload discussionPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+	}
+	if payload.Discussion.Number == 0 {
+		return nil, fmt.Errorf("%s payload is missing the discussion number", eventType)
+	}
+	if eventType == DiscussionCommentEvent && (payload.Comment == nil || payload.Comment.ID == 0) {
+		return nil, fmt.Errorf("%s payload is missing the comment", eventType)
+	}
+
+	activity := &DiscussionActivity{
+		EventType:  eventType,
+		Action:     payload.Action,
+		Repository: repositoryFullName(payload.Repository),
+		Discussion: payload.Discussion,
+	}
+	if eventType == DiscussionCommentEvent {
+		activity.Comment = payload.Comment
+	}
+	return activity, nil
+}
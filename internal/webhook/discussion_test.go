@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"testing"
+)
+
+// TestParseDiscussionActivity tests parsing discussion and comment events
+func TestParseDiscussionActivity(t *testing.T) {
+	discussion, err := ParseDiscussionActivity(DiscussionEvent, []byte(`{
+		"action": "answered",
+		"discussion": {
+			"number": 7, "title": "How do I deploy?", "body": "Steps please", "state": "open",
+			"answer_html_url": "https://github.com/octo-org/hello-world/discussions/7#discussioncomment-1",
+			"category": {"name": "Q&A", "slug": "q-a"}, "user": {"login": "octocat"}
+		},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if discussion.Repository != "octo-org/hello-world" || discussion.Discussion.Number != 7 || discussion.Discussion.Category.Slug != "q-a" {
+		t.Errorf("Unexpected activity: %+v", discussion)
+	}
+	if !discussion.Discussion.Answered() || discussion.Comment != nil {
+		t.Errorf("Expected answered discussion without comment, got %+v", discussion)
+	}
+
+	comment, err := ParseDiscussionActivity(DiscussionCommentEvent, []byte(`{
+		"action": "created",
+		"discussion": {"number": 7, "title": "How do I deploy?"},
+		"comment": {"id": 99, "body": "/notify support", "user": {"login": "hubot"}},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if comment.Comment == nil || comment.Comment.ID != 99 || comment.Comment.User.Login != "hubot" {
+		t.Errorf("Unexpected comment activity: %+v", comment)
+	}
+}
+
+// TestParseDiscussionActivity_Invalid tests rejecting incomplete payloads
+func TestParseDiscussionActivity_Invalid(t *testing.T) {
+	tests := []struct {
+		eventType string
+		body      string
+	}{
+		{"push", `{}`},
+		{DiscussionEvent, `{"discussion": {}}`},
+		{DiscussionCommentEvent, `{"discussion": {"number": 1}}`},
+		{DiscussionEvent, `not json`},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseDiscussionActivity(test.eventType, []byte(test.body)); err == nil {
+			t.Errorf("Expected error for %s %s", test.eventType, test.body)
+		}
+	}
+}
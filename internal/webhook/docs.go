@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Documentation event types
+const (
+	GollumEvent    = "gollum"
+	PageBuildEvent = "page_build"
+)
+
+// IsDocsEvent checks if an event type describes wiki or GitHub Pages activity
+func IsDocsEvent(eventType string) bool {
+	return eventType == GollumEvent || eventType == PageBuildEvent
+}
+
+// WikiPage is a wiki page created or edited in a gollum event
+type WikiPage struct {
+	PageName string `json:"page_name"`
+	Title    string `json:"title"`
+	Action   string `json:"action"`
+	SHA      string `json:"sha"`
+	HTMLURL  string `json:"html_url"`
+}
+
+// WikiChange is a parsed gollum event
+type WikiChange struct {
+	Repository string     `json:"repository"`
+	Sender     string     `json:"sender"`
+	Pages      []WikiPage `json:"pages"`
+}
+
+// PageBuild is a parsed page_build event
+type PageBuild struct {
+	Repository string `json:"repository"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	Commit     string `json:"commit"`
+	Pusher     string `json:"pusher"`
+	URL        string `json:"url"`
+}
+
+// Failed reports whether the Pages build errored
+func (pb *PageBuild) Failed() bool {
+	return pb.Status == "errored"
+}
+
+type gollumPayload struct {
+	Pages      []WikiPage             `json:"pages"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+	Sender     accountRef             `json:"sender"`
+}
+
+type pageBuildPayload struct {
+	Build struct {
+		URL    string `json:"url"`
+		Status string `json:"status"`
+		Error  struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Pusher accountRef `json:"pusher"`
+		Commit string     `json:"commit"`
+	} `json:"build"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+}
+
+// ParseWikiChange parses a gollum event
+func ParseWikiChange(body []byte) (*WikiChange, error) {
+	var payload gollumPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", GollumEvent, err)
+	}
+	if len(payload.Pages) == 0 {
+		return nil, fmt.Errorf("%s payload has no pages", GollumEvent)
+	}
+
+	return &WikiChange{
+		Repository: repositoryFullName(payload.Repository),
+		Sender:     payload.Sender.Login,
+		Pages:      payload.Pages,
+	}, nil
+}
+
+// ParsePageBuild parses a page_build event
+func ParsePageBuild(body []byte) (*PageBuild, error) {
+	var payload pageBuildPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", PageBuildEvent, err)
+	}
+	if payload.Build.Status == "" {
+		return nil, fmt.Errorf("%s payload is missing the build status", PageBuildEvent)
+	}
+
+	return &PageBuild{
+		Repository: repositoryFullName(payload.Repository),
+		Status:     payload.Build.Status,
+		Error:      payload.Build.Error.Message,
+		Commit:     payload.Build.Commit,
+		Pusher:     payload.Build.Pusher.Login,
+		URL:        payload.Build.URL,
+	}, nil
+}
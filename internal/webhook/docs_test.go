@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"testing"
+)
+
+// TestParseWikiChange tests parsing gollum events
+func TestParseWikiChange(t *testing.T) {
+	change, err := ParseWikiChange([]byte(`{
+		"pages": [
+			{"page_name": "Home", "title": "Home", "action": "edited", "sha": "abc", "html_url": "https://github.com/octo-org/hello-world/wiki/Home"},
+			{"page_name": "Runbook", "title": "Runbook", "action": "created", "sha": "def"}
+		],
+		"repository": {"full_name": "octo-org/hello-world"},
+		"sender": {"login": "octocat"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if change.Repository != "octo-org/hello-world" || change.Sender != "octocat" || len(change.Pages) != 2 {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+	if change.Pages[1].Action != "created" || change.Pages[1].PageName != "Runbook" {
+		t.Errorf("Unexpected page: %+v", change.Pages[1])
+	}
+
+	if _, err := ParseWikiChange([]byte(`{"pages": []}`)); err == nil {
+		t.Error("Expected error for gollum event without pages")
+	}
+}
+
+// TestParsePageBuild tests parsing page_build events
+func TestParsePageBuild(t *testing.T) {
+	build, err := ParsePageBuild([]byte(`{
+		"build": {"status": "errored", "error": {"message": "Page build failed."}, "pusher": {"login": "octocat"}, "commit": "abc"},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !build.Failed() || build.Error != "Page build failed." || build.Pusher != "octocat" {
+		t.Errorf("Unexpected build: %+v", build)
+	}
+
+	built, err := ParsePageBuild([]byte(`{"build": {"status": "built", "error": {"message": null}}}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if built.Failed() {
+		t.Error("Expected successful build not to be failed")
+	}
+
+	if _, err := ParsePageBuild([]byte(`{"build": {}}`)); err == nil {
+		t.Error("Expected error for page_build event without a status")
+	}
+}
@@ -0,0 +1,13 @@
+// Package events is where make webhook-types writes Go types of the payloads
+// of the supported webhook events, generated by cmd/webhookgen from the
+// webhook definitions of GitHub's OpenAPI description, along with New, which
+// returns one to decode a payload into by event type.
+//
+// The generated file, events_gen.go, is not committed yet, so the package
+// declares nothing and nothing imports it: payloads are parsed by the models
+// written by hand in internal/webhook. Generating it needs the description at
+// a tag or commit SHA of github/rest-api-description, set as
+// GITHUB_SPEC_REF.
+package events
+
+//go:generate go run ../../../cmd/webhookgen -ref $GITHUB_SPEC_REF -out events_gen.go
@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// IssueCommentEvent is the event type GitHub sends for issue and pull request comments
+const IssueCommentEvent = "issue_comment"
+
+// IssueComment is the comment object of an issue_comment event
+type IssueComment struct {
+	ID        int64      `json:"id"`
+	Body      string     `json:"body"`
+	HTMLURL   string     `json:"html_url"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	User      accountRef `json:"user"`
+}
+
+// IssueCommentActivity is a parsed issue_comment event
+type IssueCommentActivity struct {
+	Action        string       `json:"action"`
+	Repository    string       `json:"repository"`
+	IssueNumber   int          `json:"issue_number"`
+	IsPullRequest bool         `json:"is_pull_request"`
+	Comment       IssueComment `json:"comment"`
+}
+
+type issueCommentPayload struct {
+	Action string `json:"action"`
+	Issue  struct {
+		Number      int             `json:"number"`
+		PullRequest json.RawMessage `json:"pull_request"`
+	} `json:"issue"`
+	Comment    IssueComment           `json:"comment"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+}
+
+// ParseIssueCommentActivity parses an issue_comment event. Comments on pull
+// requests arrive as issue comments whose issue has a pull_request object.
+func ParseIssueCommentActivity(body []byte) (*IssueCommentActivity, error) {
+	var payload issueCommentPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", IssueCommentEvent, err)
+	}
+	if payload.Issue.Number == 0 || payload.Comment.ID == 0 {
+		return nil, fmt.Errorf("%s payload is missing the issue or comment", IssueCommentEvent)
+	}
+
+	return &IssueCommentActivity{
+		Action:        payload.Action,
+		Repository:    repositoryFullName(payload.Repository),
+		IssueNumber:   payload.Issue.Number,
+		IsPullRequest: len(payload.Issue.PullRequest) > 0 && string(payload.Issue.PullRequest) != "null",
+		Comment:       payload.Comment,
+	}, nil
+}
@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"testing"
+)
+
+// TestParseIssueCommentActivity tests parsing issue and pull request comments
+func TestParseIssueCommentActivity(t *testing.T) {
+	activity, err := ParseIssueCommentActivity([]byte(`{
+		"action": "edited",
+		"issue": {"number": 12, "pull_request": {"url": "https://api.github.com/repos/octo-org/hello-world/pulls/12"}},
+		"comment": {"id": 555, "body": "LGTM", "user": {"login": "octocat"}, "created_at": "2024-03-01T12:00:00Z", "updated_at": "2024-03-01T13:00:00Z"},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if activity.Repository != "octo-org/hello-world" || activity.IssueNumber != 12 || !activity.IsPullRequest {
+		t.Errorf("Unexpected activity: %+v", activity)
+	}
+	if activity.Comment.ID != 555 || activity.Comment.Body != "LGTM" || activity.Comment.User.Login != "octocat" {
+		t.Errorf("Unexpected comment: %+v", activity.Comment)
+	}
+
+	issue, err := ParseIssueCommentActivity([]byte(`{"action": "created", "issue": {"number": 3}, "comment": {"id": 1}}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if issue.IsPullRequest {
+		t.Error("Expected comment on an issue not to be marked as a pull request comment")
+	}
+}
+
+// TestParseIssueCommentActivity_Invalid tests rejecting incomplete payloads
+func TestParseIssueCommentActivity_Invalid(t *testing.T) {
+	for _, body := range []string{`{"issue": {"number": 3}}`, `{"comment": {"id": 1}}`, `not json`} {
+		if _, err := ParseIssueCommentActivity([]byte(body)); err == nil {
+			t.Errorf("Expected error for %s", body)
+		}
+	}
+}
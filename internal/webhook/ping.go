@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PingEvent is the event GitHub sends when a hook is created
+const PingEvent = "ping"
+
+// HookRegistration is a hook pointing at this server, as described by its
+// ping event. The hook's secret is never part of it.
+type HookRegistration struct {
+	HookID int64 `json:"hook_id"`
+	// Type is Repository, Organization or App
+	Type string `json:"type"`
+	// Target is the repository full name, organization login or app ID the
+	// hook belongs to
+	Target      string     `json:"target"`
+	Active      bool       `json:"active"`
+	Events      []string   `json:"events"`
+	URL         string     `json:"url"`
+	ContentType string     `json:"content_type"`
+	InsecureSSL bool       `json:"insecure_ssl"`
+	Zen         string     `json:"zen"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+type pingPayload struct {
+	Zen    string `json:"zen"`
+	HookID int64  `json:"hook_id"`
+	Hook   struct {
+		ID     int64    `json:"id"`
+		Type   string   `json:"type"`
+		Active bool     `json:"active"`
+		Events []string `json:"events"`
+		AppID  int64    `json:"app_id"`
+		Config struct {
+			URL         string          `json:"url"`
+			ContentType string          `json:"content_type"`
+			InsecureSSL json.RawMessage `json:"insecure_ssl"`
+		} `json:"config"`
+		CreatedAt *time.Time `json:"created_at"`
+		UpdatedAt *time.Time `json:"updated_at"`
+	} `json:"hook"`
+	Repository   map[string]interface{} `json:"repository,omitempty"`
+	Organization accountRef             `json:"organization"`
+}
+
+// ParsePing parses a ping event into the registration of its hook
+func ParsePing(body []byte) (*HookRegistration, error) {
+	var payload pingPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", PingEvent, err)
+	}
+	hookID := payload.HookID
+	if hookID == 0 {
+		hookID = payload.Hook.ID
+	}
+	if hookID == 0 {
+		return nil, fmt.Errorf("%s payload is missing the hook ID", PingEvent)
+	}
+
+	registration := &HookRegistration{
+		HookID:      hookID,
+		Type:        payload.Hook.Type,
+		Active:      payload.Hook.Active,
+		Events:      payload.Hook.Events,
+		URL:         payload.Hook.Config.URL,
+		ContentType: payload.Hook.Config.ContentType,
+		// GitHub sends "0" or "1", as a string or a number
+		InsecureSSL: strings.Trim(string(payload.Hook.Config.InsecureSSL), `"`) == "1",
+		Zen:         payload.Zen,
+		CreatedAt:   payload.Hook.CreatedAt,
+		UpdatedAt:   payload.Hook.UpdatedAt,
+	}
+	if registration.Events == nil {
+		registration.Events = []string{}
+	}
+	switch {
+	case payload.Hook.AppID != 0 && (registration.Type == "" || registration.Type == "App"):
+		registration.Type = "App"
+		registration.Target = strconv.FormatInt(payload.Hook.AppID, 10)
+	case payload.Repository != nil:
+		registration.Target = repositoryFullName(payload.Repository)
+	case payload.Organization.Login != "":
+		registration.Target = payload.Organization.Login
+	}
+	return registration, nil
+}
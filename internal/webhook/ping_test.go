@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"testing"
+)
+
+// TestParsePing tests parsing the ping events of repository, organization
+// and app hooks
+func TestParsePing(t *testing.T) {
+	repository, err := ParsePing([]byte(`{
+		"zen": "Design for failure.",
+		"hook_id": 42,
+		"hook": {
+			"type": "Repository", "id": 42, "name": "web", "active": true,
+			"events": ["push", "pull_request"],
+			"config": {"content_type": "json", "insecure_ssl": "0", "url": "https://choochoo.example.com/webhook", "secret": "********"},
+			"created_at": "2024-05-01T10:00:00Z", "updated_at": "2024-05-01T10:00:00Z"
+		},
+		"repository": {"full_name": "octo-org/hello-world"},
+		"sender": {"login": "octocat"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if repository.HookID != 42 || repository.Type != "Repository" || repository.Target != "octo-org/hello-world" {
+		t.Errorf("Unexpected registration: %+v", repository)
+	}
+	if !repository.Active || len(repository.Events) != 2 || repository.URL != "https://choochoo.example.com/webhook" ||
+		repository.ContentType != "json" || repository.InsecureSSL || repository.Zen != "Design for failure." {
+		t.Errorf("Unexpected hook details: %+v", repository)
+	}
+
+	organization, err := ParsePing([]byte(`{
+		"hook_id": 7,
+		"hook": {"type": "Organization", "id": 7, "events": ["*"], "config": {"insecure_ssl": 1}},
+		"organization": {"login": "octo-org"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if organization.Target != "octo-org" || !organization.InsecureSSL {
+		t.Errorf("Unexpected registration: %+v", organization)
+	}
+
+	app, err := ParsePing([]byte(`{"hook": {"type": "App", "id": 9, "app_id": 1234, "config": {}}}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if app.HookID != 9 || app.Type != "App" || app.Target != "1234" || app.Events == nil {
+		t.Errorf("Unexpected registration: %+v", app)
+	}
+
+	if _, err := ParsePing([]byte(`{"zen": "Keep it logically awesome."}`)); err == nil {
+		t.Error("Expected error for ping event without a hook")
+	}
+}
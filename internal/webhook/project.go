@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProjectsV2ItemEvent is the event type GitHub sends for Projects (v2) items
+const ProjectsV2ItemEvent = "projects_v2_item"
+
+// ProjectItemChange is a projects_v2_item event that moved an item between
+// columns of a single select field such as Status, or took it off the board.
+// To is empty when the item was archived or deleted.
+type ProjectItemChange struct {
+	Action        string `json:"action"`
+	Organization  string `json:"organization"`
+	ProjectNodeID string `json:"project_node_id"`
+	ItemNodeID    string `json:"item_node_id"`
+	ContentNodeID string `json:"content_node_id,omitempty"`
+	ContentType   string `json:"content_type,omitempty"`
+	FieldName     string `json:"field_name,omitempty"`
+	From          string `json:"from,omitempty"`
+	To            string `json:"to,omitempty"`
+}
+
+type projectItemPayload struct {
+	Action string `json:"action"`
+	Item   struct {
+		NodeID        string `json:"node_id"`
+		ProjectNodeID string `json:"project_node_id"`
+		ContentNodeID string `json:"content_node_id"`
+		ContentType   string `json:"content_type"`
+	} `json:"projects_v2_item"`
+	Changes struct {
+		FieldValue struct {
+			FieldName string          `json:"field_name"`
+			FieldType string          `json:"field_type"`
+			From      json.RawMessage `json:"from"`
+			To        json.RawMessage `json:"to"`
+		} `json:"field_value"`
+	} `json:"changes"`
+	Organization accountRef `json:"organization"`
+}
+
+// optionName returns the name of a single select option, which GitHub sends
+// either as an option object or as a plain string
+func optionName(raw json.RawMessage) string {
+	var option struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &option); err == nil {
+		return option.Name
+	}
+	var name string
+	json.Unmarshal(raw, &name)
+	return name
+}
+
+// ParseProjectItemChange parses a projects_v2_item event. It returns nil
+// without an error for edits that do not move the item between columns,
+// such as changing a text or date field.
+func ParseProjectItemChange(body []byte) (*ProjectItemChange, error) {
+	var payload projectItemPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", ProjectsV2ItemEvent, err)
+	}
+	if payload.Item.NodeID == "" || payload.Item.ProjectNodeID == "" {
+		return nil, fmt.Errorf("%s payload is missing the item", ProjectsV2ItemEvent)
+	}
+
+	change := &ProjectItemChange{
+		Action:        payload.Action,
+		Organization:  payload.Organization.Login,
+		ProjectNodeID: payload.Item.ProjectNodeID,
+		ItemNodeID:    payload.Item.NodeID,
+		ContentNodeID: payload.Item.ContentNodeID,
+		ContentType:   payload.Item.ContentType,
+	}
+	switch payload.Action {
+	case "edited":
+		field := payload.Changes.FieldValue
+		if field.FieldType != "single_select" {
+			return nil, nil
+		}
+		change.FieldName = field.FieldName
+		change.From = optionName(field.From)
+		change.To = optionName(field.To)
+		if change.From == change.To {
+			return nil, nil
+		}
+	case "archived", "deleted":
+	default:
+		return nil, nil
+	}
+	return change, nil
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"testing"
+)
+
+// TestParseProjectItemChange tests parsing column moves and removals
+func TestParseProjectItemChange(t *testing.T) {
+	moved, err := ParseProjectItemChange([]byte(`{
+		"action": "edited",
+		"projects_v2_item": {"node_id": "PVTI_1", "project_node_id": "PVT_1", "content_node_id": "I_1", "content_type": "Issue"},
+		"changes": {"field_value": {
+			"field_name": "Status", "field_type": "single_select",
+			"from": {"id": "a", "name": "In Progress"}, "to": {"id": "b", "name": "Blocked"}
+		}},
+		"organization": {"login": "octo-org"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if moved == nil || moved.From != "In Progress" || moved.To != "Blocked" || moved.FieldName != "Status" || moved.Organization != "octo-org" {
+		t.Errorf("Unexpected move: %+v", moved)
+	}
+
+	archived, err := ParseProjectItemChange([]byte(`{"action": "archived", "projects_v2_item": {"node_id": "PVTI_1", "project_node_id": "PVT_1"}}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if archived == nil || archived.To != "" {
+		t.Errorf("Unexpected archive: %+v", archived)
+	}
+}
+
+// TestParseProjectItemChange_Ignored tests that edits which do not move an item are ignored
+func TestParseProjectItemChange_Ignored(t *testing.T) {
+	bodies := []string{
+		`{"action": "reordered", "projects_v2_item": {"node_id": "PVTI_1", "project_node_id": "PVT_1"}}`,
+		`{"action": "edited", "projects_v2_item": {"node_id": "PVTI_1", "project_node_id": "PVT_1"}, "changes": {"field_value": {"field_type": "text", "from": "a", "to": "b"}}}`,
+		`{"action": "edited", "projects_v2_item": {"node_id": "PVTI_1", "project_node_id": "PVT_1"}, "changes": {"field_value": {"field_type": "single_select"}}}`,
+	}
+
+	for _, body := range bodies {
+		change, err := ParseProjectItemChange([]byte(body))
+		if err != nil || change != nil {
+			t.Errorf("Expected %s to be ignored, got %+v, %v", body, change, err)
+		}
+	}
+
+	if _, err := ParseProjectItemChange([]byte(`{"action": "edited", "projects_v2_item": {}}`)); err == nil {
+		t.Error("Expected error for payload without an item")
+	}
+}
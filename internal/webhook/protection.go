@@ -0,0 +1,325 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Branch protection event types
+const (
+	BranchProtectionRuleEvent = "branch_protection_rule"
+	RepositoryRulesetEvent    = "repository_ruleset"
+)
+
+// IsProtectionEvent checks if an event type describes a branch protection change
+func IsProtectionEvent(eventType string) bool {
+	return eventType == BranchProtectionRuleEvent || eventType == RepositoryRulesetEvent
+}
+
+// ProtectionConfig is a normalized snapshot of the protections applied to a
+// branch, whether they come from a classic branch protection rule or a
+// repository ruleset
+type ProtectionConfig struct {
+	Enforced               bool     `json:"enforced"`
+	RequirePullRequest     bool     `json:"require_pull_request"`
+	RequiredApprovals      int      `json:"required_approvals"`
+	DismissStaleReviews    bool     `json:"dismiss_stale_reviews"`
+	RequireCodeOwnerReview bool     `json:"require_code_owner_review"`
+	RequiredStatusChecks   []string `json:"required_status_checks"`
+	StrictStatusChecks     bool     `json:"strict_status_checks"`
+	EnforceAdmins          bool     `json:"enforce_admins"`
+	RequireSignatures      bool     `json:"require_signatures"`
+	RequireLinearHistory   bool     `json:"require_linear_history"`
+	AllowForcePushes       bool     `json:"allow_force_pushes"`
+	AllowDeletions         bool     `json:"allow_deletions"`
+}
+
+// ProtectionChange is the normalized form of a protection event
+type ProtectionChange struct {
+	Source     string            `json:"source"`
+	Action     string            `json:"action"`
+	Repository string            `json:"repository"`
+	RuleID     int64             `json:"rule_id"`
+	Pattern    string            `json:"pattern"`
+	Config     ProtectionConfig  `json:"config"`
+	Previous   *ProtectionConfig `json:"previous,omitempty"`
+}
+
+// branchProtectionRule is the rule object of a branch_protection_rule event
+type branchProtectionRule struct {
+	ID                                       int64    `json:"id"`
+	Name                                     string   `json:"name"`
+	AdminEnforced                            bool     `json:"admin_enforced"`
+	RequiredApprovingReviewCount             int      `json:"required_approving_review_count"`
+	DismissStaleReviewsOnPush                bool     `json:"dismiss_stale_reviews_on_push"`
+	RequireCodeOwnerReview                   bool     `json:"require_code_owner_review"`
+	RequiredStatusChecks                     []string `json:"required_status_checks"`
+	RequiredStatusChecksEnforcementLevel     string   `json:"required_status_checks_enforcement_level"`
+	StrictRequiredStatusChecksPolicy         bool     `json:"strict_required_status_checks_policy"`
+	PullRequestReviewsEnforcementLevel       string   `json:"pull_request_reviews_enforcement_level"`
+	SignatureRequirementEnforcementLevel     string   `json:"signature_requirement_enforcement_level"`
+	LinearHistoryRequirementEnforcementLevel string   `json:"linear_history_requirement_enforcement_level"`
+	AllowForcePushesEnforcementLevel         string   `json:"allow_force_pushes_enforcement_level"`
+	AllowDeletionsEnforcementLevel           string   `json:"allow_deletions_enforcement_level"`
+}
+
+// fieldChange holds the previous value of an edited field
+type fieldChange struct {
+	From any `json:"from"`
+}
+
+type branchProtectionRulePayload struct {
+	Action     string                 `json:"action"`
+	Rule       branchProtectionRule   `json:"rule"`
+	Changes    map[string]fieldChange `json:"changes"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+}
+
+// config converts a classic branch protection rule into a ProtectionConfig
+func (r branchProtectionRule) config() ProtectionConfig {
+	enabled := func(level string) bool { return level != "" && level != "off" }
+	var checks []string
+	if enabled(r.RequiredStatusChecksEnforcementLevel) {
+		checks = sortedCopy(r.RequiredStatusChecks)
+	}
+	return ProtectionConfig{
+		Enforced:               true,
+		RequirePullRequest:     enabled(r.PullRequestReviewsEnforcementLevel),
+		RequiredApprovals:      r.RequiredApprovingReviewCount,
+		DismissStaleReviews:    r.DismissStaleReviewsOnPush,
+		RequireCodeOwnerReview: r.RequireCodeOwnerReview,
+		RequiredStatusChecks:   checks,
+		StrictStatusChecks:     r.StrictRequiredStatusChecksPolicy,
+		EnforceAdmins:          r.AdminEnforced,
+		RequireSignatures:      enabled(r.SignatureRequirementEnforcementLevel),
+		RequireLinearHistory:   enabled(r.LinearHistoryRequirementEnforcementLevel),
+		AllowForcePushes:       enabled(r.AllowForcePushesEnforcementLevel),
+		AllowDeletions:         enabled(r.AllowDeletionsEnforcementLevel),
+	}
+}
+
+// rulesetRule is a single rule within a repository ruleset
+type rulesetRule struct {
+	Type       string          `json:"type"`
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+type repositoryRuleset struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Target      string `json:"target"`
+	Enforcement string `json:"enforcement"`
+	Conditions  struct {
+		RefName struct {
+			Include []string `json:"include"`
+		} `json:"ref_name"`
+	} `json:"conditions"`
+	BypassActors []json.RawMessage `json:"bypass_actors"`
+	Rules        []rulesetRule     `json:"rules"`
+}
+
+type repositoryRulesetPayload struct {
+	Action     string                 `json:"action"`
+	Ruleset    repositoryRuleset      `json:"repository_ruleset"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+}
+
+// config converts a repository ruleset into a ProtectionConfig. Rulesets
+// block force pushes and deletions unless the corresponding rule is absent.
+func (rs repositoryRuleset) config() ProtectionConfig {
+	cfg := ProtectionConfig{
+		Enforced:         rs.Enforcement == "active",
+		EnforceAdmins:    len(rs.BypassActors) == 0,
+		AllowForcePushes: true,
+		AllowDeletions:   true,
+	}
+
+	for _, rule := range rs.Rules {
+		switch rule.Type {
+		case "pull_request":
+			var params struct {
+				RequiredApprovingReviewCount int  `json:"required_approving_review_count"`
+				DismissStaleReviewsOnPush    bool `json:"dismiss_stale_reviews_on_push"`
+				RequireCodeOwnerReview       bool `json:"require_code_owner_review"`
+			}
+			json.Unmarshal(rule.Parameters, &params)
+			cfg.RequirePullRequest = true
+			cfg.RequiredApprovals = params.RequiredApprovingReviewCount
+			cfg.DismissStaleReviews = params.DismissStaleReviewsOnPush
+			cfg.RequireCodeOwnerReview = params.RequireCodeOwnerReview
+		case "required_status_checks":
+			var params struct {
+				RequiredStatusChecks []struct {
+					Context string `json:"context"`
+				} `json:"required_status_checks"`
+				StrictRequiredStatusChecksPolicy bool `json:"strict_required_status_checks_policy"`
+			}
+			json.Unmarshal(rule.Parameters, &params)
+			for _, check := range params.RequiredStatusChecks {
+				cfg.RequiredStatusChecks = append(cfg.RequiredStatusChecks, check.Context)
+			}
+			sort.Strings(cfg.RequiredStatusChecks)
+			cfg.StrictStatusChecks = params.StrictRequiredStatusChecksPolicy
+		case "required_signatures":
+			cfg.RequireSignatures = true
+		case "required_linear_history":
+			cfg.RequireLinearHistory = true
+		case "non_fast_forward":
+			cfg.AllowForcePushes = false
+		case "deletion":
+			cfg.AllowDeletions = false
+		}
+	}
+	return cfg
+}
+
+// ParseProtectionChange parses a branch_protection_rule or repository_ruleset
+// event into its normalized form
+func ParseProtectionChange(eventType string, body []byte) (*ProtectionChange, error) {
+	switch eventType {
+	case BranchProtectionRuleEvent:
+		var payload branchProtectionRulePayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+		}
+		if payload.Rule.ID == 0 {
+			return nil, fmt.Errorf("%s payload is missing the rule id", eventType)
+		}
+		change := &ProtectionChange{
+			Source:     eventType,
+			Action:     payload.Action,
+			Repository: repositoryFullName(payload.Repository),
+			RuleID:     payload.Rule.ID,
+			Pattern:    payload.Rule.Name,
+			Config:     payload.Rule.config(),
+		}
+		if payload.Action == "edited" && len(payload.Changes) > 0 {
+			change.Previous = previousFromChanges(payload.Rule, payload.Changes)
+		}
+		if payload.Action == "deleted" {
+			change.Config = ProtectionConfig{AllowForcePushes: true, AllowDeletions: true}
+		}
+		return change, nil
+
+	case RepositoryRulesetEvent:
+		var payload repositoryRulesetPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+		}
+		if payload.Ruleset.ID == 0 {
+			return nil, fmt.Errorf("%s payload is missing the ruleset id", eventType)
+		}
+		pattern := strings.Join(payload.Ruleset.Conditions.RefName.Include, ",")
+		if pattern == "" {
+			pattern = payload.Ruleset.Name
+		}
+		change := &ProtectionChange{
+			Source:     eventType,
+			Action:     payload.Action,
+			Repository: repositoryFullName(payload.Repository),
+			RuleID:     payload.Ruleset.ID,
+			Pattern:    pattern,
+			Config:     payload.Ruleset.config(),
+		}
+		if payload.Action == "deleted" {
+			change.Config = ProtectionConfig{AllowForcePushes: true, AllowDeletions: true}
+		}
+		return change, nil
+	}
+
+	return nil, fmt.Errorf("%s is not a branch protection event", eventType)
+}
+
+// previousFromChanges reconstructs the rule as it was before an edit by
+// applying the "from" values GitHub includes in the changes object
+func previousFromChanges(rule branchProtectionRule, changes map[string]fieldChange) *ProtectionConfig {
+	raw, err := json.Marshal(rule)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+	for name, change := range changes {
+		fields[name] = change.From
+	}
+	raw, err = json.Marshal(fields)
+	if err != nil {
+		return nil
+	}
+	var previous branchProtectionRule
+	if err := json.Unmarshal(raw, &previous); err != nil {
+		return nil
+	}
+	cfg := previous.config()
+	return &cfg
+}
+
+// Weakenings lists the ways next is weaker than previous
+func Weakenings(previous, next ProtectionConfig) []string {
+	var reasons []string
+	if previous.Enforced && !next.Enforced {
+		return []string{"protection is no longer enforced"}
+	}
+	if !previous.Enforced {
+		return nil
+	}
+
+	if previous.RequirePullRequest && !next.RequirePullRequest {
+		reasons = append(reasons, "pull requests are no longer required")
+	}
+	if next.RequiredApprovals < previous.RequiredApprovals {
+		reasons = append(reasons, fmt.Sprintf("required approvals reduced from %d to %d", previous.RequiredApprovals, next.RequiredApprovals))
+	}
+	if previous.DismissStaleReviews && !next.DismissStaleReviews {
+		reasons = append(reasons, "stale reviews are no longer dismissed")
+	}
+	if previous.RequireCodeOwnerReview && !next.RequireCodeOwnerReview {
+		reasons = append(reasons, "code owner review is no longer required")
+	}
+	for _, check := range previous.RequiredStatusChecks {
+		if !containsString(next.RequiredStatusChecks, check) {
+			reasons = append(reasons, fmt.Sprintf("required status check %q removed", check))
+		}
+	}
+	if previous.StrictStatusChecks && !next.StrictStatusChecks {
+		reasons = append(reasons, "branches no longer need to be up to date before merging")
+	}
+	if previous.EnforceAdmins && !next.EnforceAdmins {
+		reasons = append(reasons, "administrators can now bypass protections")
+	}
+	if previous.RequireSignatures && !next.RequireSignatures {
+		reasons = append(reasons, "signed commits are no longer required")
+	}
+	if previous.RequireLinearHistory && !next.RequireLinearHistory {
+		reasons = append(reasons, "linear history is no longer required")
+	}
+	if !previous.AllowForcePushes && next.AllowForcePushes {
+		reasons = append(reasons, "force pushes are now allowed")
+	}
+	if !previous.AllowDeletions && next.AllowDeletions {
+		reasons = append(reasons, "branch deletion is now allowed")
+	}
+	return reasons
+}
+
+func sortedCopy(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"testing"
+)
+
+const testBranchProtectionRule = `{
+	"action": "edited",
+	"rule": {
+		"id": 21,
+		"name": "main",
+		"admin_enforced": false,
+		"required_approving_review_count": 1,
+		"pull_request_reviews_enforcement_level": "non_admins",
+		"required_status_checks": ["ci", "lint"],
+		"required_status_checks_enforcement_level": "everyone",
+		"allow_force_pushes_enforcement_level": "off",
+		"allow_deletions_enforcement_level": "off"
+	},
+	"changes": {
+		"required_approving_review_count": {"from": 2},
+		"admin_enforced": {"from": true}
+	},
+	"repository": {"full_name": "octo-org/hello-world"}
+}`
+
+// TestParseProtectionChange_BranchProtectionRule tests parsing a classic rule edit
+func TestParseProtectionChange_BranchProtectionRule(t *testing.T) {
+	change, err := ParseProtectionChange(BranchProtectionRuleEvent, []byte(testBranchProtectionRule))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if change.RuleID != 21 || change.Pattern != "main" || change.Repository != "octo-org/hello-world" {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+	if !change.Config.RequirePullRequest || change.Config.RequiredApprovals != 1 || len(change.Config.RequiredStatusChecks) != 2 {
+		t.Errorf("Unexpected config: %+v", change.Config)
+	}
+	if change.Previous == nil {
+		t.Fatal("Expected previous config to be reconstructed from changes")
+	}
+	if change.Previous.RequiredApprovals != 2 || !change.Previous.EnforceAdmins {
+		t.Errorf("Unexpected previous config: %+v", change.Previous)
+	}
+
+	reasons := Weakenings(*change.Previous, change.Config)
+	if len(reasons) != 2 {
+		t.Errorf("Expected 2 weakenings, got %v", reasons)
+	}
+}
+
+// TestParseProtectionChange_Ruleset tests parsing a repository ruleset event
+func TestParseProtectionChange_Ruleset(t *testing.T) {
+	body := []byte(`{
+		"action": "created",
+		"repository_ruleset": {
+			"id": 42,
+			"name": "protect main",
+			"target": "branch",
+			"enforcement": "active",
+			"conditions": {"ref_name": {"include": ["~DEFAULT_BRANCH"], "exclude": []}},
+			"rules": [
+				{"type": "deletion"},
+				{"type": "non_fast_forward"},
+				{"type": "pull_request", "parameters": {"required_approving_review_count": 2, "require_code_owner_review": true}},
+				{"type": "required_status_checks", "parameters": {"required_status_checks": [{"context": "test"}], "strict_required_status_checks_policy": true}}
+			]
+		},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`)
+
+	change, err := ParseProtectionChange(RepositoryRulesetEvent, body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	cfg := change.Config
+	if change.Pattern != "~DEFAULT_BRANCH" || change.RuleID != 42 {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+	if !cfg.Enforced || cfg.AllowForcePushes || cfg.AllowDeletions || !cfg.EnforceAdmins {
+		t.Errorf("Unexpected enforcement flags: %+v", cfg)
+	}
+	if cfg.RequiredApprovals != 2 || !cfg.RequireCodeOwnerReview || !cfg.StrictStatusChecks || cfg.RequiredStatusChecks[0] != "test" {
+		t.Errorf("Unexpected rule parameters: %+v", cfg)
+	}
+}
+
+// TestParseProtectionChange_Deleted tests that deleted rules lose enforcement
+func TestParseProtectionChange_Deleted(t *testing.T) {
+	body := []byte(`{"action":"deleted","rule":{"id":21,"name":"main","pull_request_reviews_enforcement_level":"everyone"}}`)
+
+	change, err := ParseProtectionChange(BranchProtectionRuleEvent, body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if change.Config.Enforced {
+		t.Error("Expected deleted rule to be unenforced")
+	}
+}
+
+// TestParseProtectionChange_Invalid tests rejection of bad payloads
+func TestParseProtectionChange_Invalid(t *testing.T) {
+	tests := []struct {
+		eventType string
+		body      string
+	}{
+		{"push", `{}`},
+		{BranchProtectionRuleEvent, `{"action":"created","rule":{}}`},
+		{RepositoryRulesetEvent, `not json`},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseProtectionChange(test.eventType, []byte(test.body)); err == nil {
+			t.Errorf("ParseProtectionChange(%q, %q) expected error, got nil", test.eventType, test.body)
+		}
+	}
+}
+
+// TestWeakenings tests detection of weakened protections
+func TestWeakenings(t *testing.T) {
+	strong := ProtectionConfig{
+		Enforced:             true,
+		RequirePullRequest:   true,
+		RequiredApprovals:    2,
+		RequiredStatusChecks: []string{"ci"},
+		EnforceAdmins:        true,
+	}
+
+	tests := []struct {
+		name     string
+		next     func(ProtectionConfig) ProtectionConfig
+		expected int
+	}{
+		{"unchanged", func(c ProtectionConfig) ProtectionConfig { return c }, 0},
+		{"strengthened", func(c ProtectionConfig) ProtectionConfig { c.RequiredApprovals = 3; return c }, 0},
+		{"fewer approvals", func(c ProtectionConfig) ProtectionConfig { c.RequiredApprovals = 1; return c }, 1},
+		{"check removed", func(c ProtectionConfig) ProtectionConfig { c.RequiredStatusChecks = nil; return c }, 1},
+		{"force pushes", func(c ProtectionConfig) ProtectionConfig { c.AllowForcePushes = true; return c }, 1},
+		{"disabled", func(c ProtectionConfig) ProtectionConfig { c.Enforced = false; return c }, 1},
+	}
+
+	for _, test := range tests {
+		if reasons := Weakenings(strong, test.next(strong)); len(reasons) != test.expected {
+			t.Errorf("%s: expected %d weakenings, got %v", test.name, test.expected, reasons)
+		}
+	}
+
+	if reasons := Weakenings(ProtectionConfig{}, strong); len(reasons) != 0 {
+		t.Errorf("Expected enabling protection not to be a weakening, got %v", reasons)
+	}
+}
@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PullRequestEvent is the event type GitHub sends for pull request activity
+const PullRequestEvent = "pull_request"
+
+// branchRef is the head or base branch of a pull request
+type branchRef struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+// PullRequest is the pull_request object of a pull_request event
+type PullRequest struct {
+	Number    int        `json:"number"`
+	Title     string     `json:"title"`
+	State     string     `json:"state"`
+	Draft     bool       `json:"draft"`
+	Merged    bool       `json:"merged"`
+	HTMLURL   string     `json:"html_url"`
+	Head      branchRef  `json:"head"`
+	Base      branchRef  `json:"base"`
+	User      accountRef `json:"user"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
+	MergedAt  *time.Time `json:"merged_at"`
+}
+
+// PullRequestActivity is a parsed pull_request event
+type PullRequestActivity struct {
+	Action      string      `json:"action"`
+	Repository  string      `json:"repository"`
+	PullRequest PullRequest `json:"pull_request"`
+}
+
+type pullRequestPayload struct {
+	Action      string                 `json:"action"`
+	PullRequest PullRequest            `json:"pull_request"`
+	Repository  map[string]interface{} `json:"repository,omitempty"`
+}
+
+// ParsePullRequestActivity parses a pull_request event
+func ParsePullRequestActivity(body []byte) (*PullRequestActivity, error) {
+	var payload pullRequestPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", PullRequestEvent, err)
+	}
+	if payload.PullRequest.Number == 0 {
+		return nil, fmt.Errorf("%s payload is missing the pull request number", PullRequestEvent)
+	}
+
+	return &PullRequestActivity{
+		Action:      payload.Action,
+		Repository:  repositoryFullName(payload.Repository),
+		PullRequest: payload.PullRequest,
+	}, nil
+}
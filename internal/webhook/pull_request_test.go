@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"testing"
+)
+
+// TestParsePullRequestActivity tests parsing a merged pull request
+func TestParsePullRequestActivity(t *testing.T) {
+	activity, err := ParsePullRequestActivity([]byte(`{
+		"action": "closed",
+		"pull_request": {
+			"number": 42, "title": "Add caching", "state": "closed", "merged": true,
+			"head": {"ref": "feature/cache", "sha": "abc"}, "base": {"ref": "main", "sha": "def"},
+			"user": {"login": "octocat"},
+			"created_at": "2024-03-01T12:00:00Z", "updated_at": "2024-03-02T12:00:00Z",
+			"closed_at": "2024-03-02T12:00:00Z", "merged_at": "2024-03-02T12:00:00Z"
+		},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	pr := activity.PullRequest
+	if activity.Repository != "octo-org/hello-world" || activity.Action != "closed" || pr.Number != 42 {
+		t.Errorf("Unexpected activity: %+v", activity)
+	}
+	if pr.Head.Ref != "feature/cache" || pr.Base.Ref != "main" || pr.User.Login != "octocat" {
+		t.Errorf("Unexpected refs or author: %+v", pr)
+	}
+	if !pr.Merged || pr.MergedAt == nil || pr.ClosedAt == nil {
+		t.Errorf("Expected merged pull request with timestamps, got %+v", pr)
+	}
+}
+
+// TestParsePullRequestActivity_Invalid tests rejecting incomplete payloads
+func TestParsePullRequestActivity_Invalid(t *testing.T) {
+	for _, body := range []string{`{"pull_request": {}}`, `not json`} {
+		if _, err := ParsePullRequestActivity([]byte(body)); err == nil {
+			t.Errorf("Expected error for %s", body)
+		}
+	}
+}
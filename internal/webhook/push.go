@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PushEvent is the event type GitHub sends for pushes
+const PushEvent = "push"
+
+// CommitAuthor is the author or committer of a pushed commit
+type CommitAuthor struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// PushCommit is a commit included in a push event
+type PushCommit struct {
+	ID        string       `json:"id"`
+	Message   string       `json:"message"`
+	Timestamp time.Time    `json:"timestamp"`
+	URL       string       `json:"url"`
+	Distinct  bool         `json:"distinct"`
+	Author    CommitAuthor `json:"author"`
+	Added     []string     `json:"added,omitempty"`
+	Modified  []string     `json:"modified,omitempty"`
+}
+
+// Push is a parsed push event
+type Push struct {
+	Repository string       `json:"repository"`
+	Ref        string       `json:"ref"`
+	Before     string       `json:"before"`
+	After      string       `json:"after"`
+	Deleted    bool         `json:"deleted"`
+	Commits    []PushCommit `json:"commits"`
+}
+
+type pushPayload struct {
+	Ref        string                 `json:"ref"`
+	Before     string                 `json:"before"`
+	After      string                 `json:"after"`
+	Deleted    bool                   `json:"deleted"`
+	Commits    []PushCommit           `json:"commits"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+}
+
+// Changes reports whether a commit of the push added or modified the file at
+// path. GitHub lists the changed files of up to 20 commits per push.
+func (p *Push) Changes(path string) bool {
+	for _, commit := range p.Commits {
+		for _, files := range [][]string{commit.Added, commit.Modified} {
+			for _, file := range files {
+				if file == path {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ParsePush parses a push event. Commits without a SHA are dropped.
+func ParsePush(body []byte) (*Push, error) {
+	var payload pushPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid push payload: %w", err)
+	}
+	if payload.Ref == "" {
+		return nil, fmt.Errorf("push payload is missing the ref")
+	}
+
+	push := &Push{
+		Repository: repositoryFullName(payload.Repository),
+		Ref:        payload.Ref,
+		Before:     payload.Before,
+		After:      payload.After,
+		Deleted:    payload.Deleted,
+	}
+	for _, commit := range payload.Commits {
+		if commit.ID != "" {
+			push.Commits = append(push.Commits, commit)
+		}
+	}
+	return push, nil
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"testing"
+)
+
+// TestParsePush tests parsing commits out of a push event
+func TestParsePush(t *testing.T) {
+	push, err := ParsePush([]byte(`{
+		"ref": "refs/heads/main",
+		"before": "aaa",
+		"after": "bbb",
+		"commits": [
+			{
+				"id": "bbb", "message": "Fix the build", "timestamp": "2024-03-01T12:00:00-05:00",
+				"distinct": true, "author": {"name": "Mona Lisa", "email": "mona@example.com", "username": "octocat"}
+			},
+			{"message": "missing sha"}
+		],
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if push.Repository != "octo-org/hello-world" || push.Ref != "refs/heads/main" || push.After != "bbb" {
+		t.Errorf("Unexpected push: %+v", push)
+	}
+	if len(push.Commits) != 1 {
+		t.Fatalf("Expected 1 commit, got %d", len(push.Commits))
+	}
+	commit := push.Commits[0]
+	if commit.ID != "bbb" || commit.Author.Username != "octocat" || commit.Author.Email != "mona@example.com" {
+		t.Errorf("Unexpected commit: %+v", commit)
+	}
+	if commit.Timestamp.UTC().Hour() != 17 {
+		t.Errorf("Expected timestamp to keep its offset, got %v", commit.Timestamp)
+	}
+}
+
+// TestParsePush_Invalid tests rejecting incomplete payloads
+func TestParsePush_Invalid(t *testing.T) {
+	for _, body := range []string{`{}`, `not json`} {
+		if _, err := ParsePush([]byte(body)); err == nil {
+			t.Errorf("Expected error for %s", body)
+		}
+	}
+}
+
+// TestPush_Changes tests finding changed files in the commits of a push
+func TestPush_Changes(t *testing.T) {
+	push, err := ParsePush([]byte(`{
+		"ref": "refs/heads/main",
+		"commits": [
+			{"id": "aaa", "added": ["docs/setup.md"], "modified": ["README.md"]},
+			{"id": "bbb", "removed": ["old.yml"], "modified": [".choochoo.yml"]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for path, expected := range map[string]bool{".choochoo.yml": true, "docs/setup.md": true, "old.yml": false, "main.go": false} {
+		if push.Changes(path) != expected {
+			t.Errorf("Expected Changes(%q) to be %v", path, expected)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Publishing event types
+const (
+	ReleaseEvent = "release"
+	CreateEvent  = "create"
+	DeleteEvent  = "delete"
+)
+
+// IsRefEvent checks if an event type describes a branch or tag being
+// created or deleted
+func IsRefEvent(eventType string) bool {
+	return eventType == CreateEvent || eventType == DeleteEvent
+}
+
+// Release is the release object of a release event
+type Release struct {
+	ID              int64  `json:"id"`
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish"`
+	Name            string `json:"name"`
+	Body            string `json:"body"`
+	Draft           bool   `json:"draft"`
+	Prerelease      bool   `json:"prerelease"`
+	HTMLURL         string `json:"html_url"`
+	Author          struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	CreatedAt   *time.Time `json:"created_at"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+// ReleaseChange is a release event: what happened to which release of which
+// repository. Action is published, unpublished, created, edited, deleted,
+// prereleased or released.
+type ReleaseChange struct {
+	Action     string  `json:"action"`
+	Repository string  `json:"repository"`
+	Sender     string  `json:"sender,omitempty"`
+	Release    Release `json:"release"`
+}
+
+// Deleted reports whether the release was deleted
+func (c *ReleaseChange) Deleted() bool {
+	return c.Action == "deleted"
+}
+
+type releasePayload struct {
+	Action     string                 `json:"action"`
+	Release    *Release               `json:"release"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+	Sender     struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// ParseRelease parses a release event
+func ParseRelease(body []byte) (*ReleaseChange, error) {
+	var payload releasePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid release payload: %w", err)
+	}
+	if payload.Release == nil || payload.Release.ID == 0 {
+		return nil, fmt.Errorf("release payload is missing the release ID")
+	}
+	return &ReleaseChange{
+		Action:     payload.Action,
+		Repository: repositoryFullName(payload.Repository),
+		Sender:     payload.Sender.Login,
+		Release:    *payload.Release,
+	}, nil
+}
+
+// RefChange is a create or delete event: a branch or tag created or deleted
+// in a repository. Ref is the short name, such as main or v1.2.0.
+type RefChange struct {
+	// Action is created or deleted
+	Action     string `json:"action"`
+	Repository string `json:"repository"`
+	// RefType is branch or tag
+	RefType string `json:"ref_type"`
+	Ref     string `json:"ref"`
+	// PusherType is user, or deploy_key for refs changed with a deploy key
+	PusherType string `json:"pusher_type,omitempty"`
+	Sender     string `json:"sender,omitempty"`
+}
+
+type refPayload struct {
+	Ref        string                 `json:"ref"`
+	RefType    string                 `json:"ref_type"`
+	PusherType string                 `json:"pusher_type"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+	Sender     struct {
+		Login string `json:"login"`
+	} `json:"sender"`
+}
+
+// ParseRefChange parses a create or delete event
+func ParseRefChange(eventType string, body []byte) (*RefChange, error) {
+	if !IsRefEvent(eventType) {
+		return nil, fmt.Errorf("%s is not a create or delete event", eventType)
+	}
+
+	var payload refPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+	}
+	if payload.Ref == "" {
+		return nil, fmt.Errorf("%s payload is missing the ref", eventType)
+	}
+	if payload.RefType != "branch" && payload.RefType != "tag" {
+		return nil, fmt.Errorf("%s payload has unknown ref type %q", eventType, payload.RefType)
+	}
+
+	action := "created"
+	if eventType == DeleteEvent {
+		action = "deleted"
+	}
+	return &RefChange{
+		Action:     action,
+		Repository: repositoryFullName(payload.Repository),
+		RefType:    payload.RefType,
+		Ref:        payload.Ref,
+		PusherType: payload.PusherType,
+		Sender:     payload.Sender.Login,
+	}, nil
+}
@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseRelease tests parsing release events
+func TestParseRelease(t *testing.T) {
+	change, err := ParseRelease([]byte(`{
+		"action": "published",
+		"release": {
+			"id": 1296269, "tag_name": "v1.2.0", "target_commitish": "main", "name": "v1.2.0",
+			"body": "Bug fixes", "draft": false, "prerelease": true,
+			"html_url": "https://github.com/octo-org/hello-world/releases/v1.2.0",
+			"author": {"login": "octocat"},
+			"created_at": "2024-05-01T10:00:00Z", "published_at": "2024-05-01T10:05:00Z"
+		},
+		"repository": {"full_name": "octo-org/hello-world"},
+		"sender": {"login": "hubot"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if change.Action != "published" || change.Repository != "octo-org/hello-world" || change.Sender != "hubot" || change.Deleted() {
+		t.Errorf("Unexpected change: %+v", change)
+	}
+	release := change.Release
+	if release.ID != 1296269 || release.TagName != "v1.2.0" || !release.Prerelease || release.Author.Login != "octocat" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+	if release.PublishedAt == nil || !release.PublishedAt.Equal(time.Date(2024, 5, 1, 10, 5, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected publication time %v", release.PublishedAt)
+	}
+
+	for _, body := range []string{`not json`, `{"action":"published"}`} {
+		if _, err := ParseRelease([]byte(body)); err == nil {
+			t.Errorf("Expected an error for %s", body)
+		}
+	}
+}
+
+// TestParseRefChange tests parsing create and delete events
+func TestParseRefChange(t *testing.T) {
+	created, err := ParseRefChange(CreateEvent, []byte(`{
+		"ref": "v1.2.0", "ref_type": "tag", "master_branch": "main", "pusher_type": "user",
+		"repository": {"full_name": "octo-org/hello-world"}, "sender": {"login": "octocat"}
+	}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created.Action != "created" || created.RefType != "tag" || created.Ref != "v1.2.0" || created.Sender != "octocat" {
+		t.Errorf("Unexpected ref change: %+v", created)
+	}
+
+	deleted, err := ParseRefChange(DeleteEvent, []byte(`{"ref": "feature", "ref_type": "branch", "pusher_type": "deploy_key", "repository": {"full_name": "octo-org/hello-world"}}`))
+	if err != nil || deleted.Action != "deleted" || deleted.RefType != "branch" || deleted.PusherType != "deploy_key" {
+		t.Errorf("Unexpected ref change: %+v, %v", deleted, err)
+	}
+
+	for eventType, body := range map[string]string{
+		PushEvent:   `{"ref": "refs/heads/main"}`,
+		CreateEvent: `{"ref_type": "tag"}`,
+		DeleteEvent: `{"ref": "main", "ref_type": "repository"}`,
+	} {
+		if _, err := ParseRefChange(eventType, []byte(body)); err == nil {
+			t.Errorf("Expected an error for %s %s", eventType, body)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Security alert event types
+const (
+	DependabotAlertEvent     = "dependabot_alert"
+	CodeScanningAlertEvent   = "code_scanning_alert"
+	SecretScanningAlertEvent = "secret_scanning_alert"
+)
+
+// Severity levels, ordered from least to most severe
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+var severityRank = map[string]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// SeverityRank returns the ordering of a severity level, or 0 if unknown
+func SeverityRank(severity string) int {
+	return severityRank[severity]
+}
+
+// IsSecurityAlertEvent checks if an event type is a security alert event
+func IsSecurityAlertEvent(eventType string) bool {
+	switch eventType {
+	case DependabotAlertEvent, CodeScanningAlertEvent, SecretScanningAlertEvent:
+		return true
+	}
+	return false
+}
+
+// DependabotAlertPayload is the payload of a dependabot_alert event
+type DependabotAlertPayload struct {
+	Action     string                 `json:"action"`
+	Alert      DependabotAlert        `json:"alert"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+}
+
+// DependabotAlert is the alert object of a dependabot_alert event
+type DependabotAlert struct {
+	Number           int        `json:"number"`
+	State            string     `json:"state"`
+	HTMLURL          string     `json:"html_url"`
+	CreatedAt        time.Time  `json:"created_at"`
+	FixedAt          *time.Time `json:"fixed_at"`
+	DismissedAt      *time.Time `json:"dismissed_at"`
+	AutoDismissedAt  *time.Time `json:"auto_dismissed_at"`
+	SecurityAdvisory struct {
+		GHSAID   string `json:"ghsa_id"`
+		Summary  string `json:"summary"`
+		Severity string `json:"severity"`
+	} `json:"security_advisory"`
+	SecurityVulnerability struct {
+		Severity string `json:"severity"`
+		Package  struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+	} `json:"security_vulnerability"`
+}
+
+// CodeScanningAlertPayload is the payload of a code_scanning_alert event
+type CodeScanningAlertPayload struct {
+	Action     string                 `json:"action"`
+	Alert      CodeScanningAlert      `json:"alert"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+}
+
+// CodeScanningAlert is the alert object of a code_scanning_alert event
+type CodeScanningAlert struct {
+	Number      int        `json:"number"`
+	State       string     `json:"state"`
+	HTMLURL     string     `json:"html_url"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FixedAt     *time.Time `json:"fixed_at"`
+	DismissedAt *time.Time `json:"dismissed_at"`
+	Rule        struct {
+		ID                    string `json:"id"`
+		Description           string `json:"description"`
+		Severity              string `json:"severity"`
+		SecuritySeverityLevel string `json:"security_severity_level"`
+	} `json:"rule"`
+}
+
+// SecretScanningAlertPayload is the payload of a secret_scanning_alert event
+type SecretScanningAlertPayload struct {
+	Action     string                 `json:"action"`
+	Alert      SecretScanningAlert    `json:"alert"`
+	Repository map[string]interface{} `json:"repository,omitempty"`
+}
+
+// SecretScanningAlert is the alert object of a secret_scanning_alert event
+type SecretScanningAlert struct {
+	Number      int        `json:"number"`
+	State       string     `json:"state"`
+	HTMLURL     string     `json:"html_url"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+	Resolution  string     `json:"resolution"`
+	SecretType  string     `json:"secret_type"`
+	DisplayName string     `json:"secret_type_display_name"`
+}
+
+// SecurityAlert is the normalized form of any security alert event
+type SecurityAlert struct {
+	Kind       string     `json:"kind"`
+	Action     string     `json:"action"`
+	Repository string     `json:"repository"`
+	Number     int        `json:"number"`
+	Severity   string     `json:"severity"`
+	State      string     `json:"state"`
+	Summary    string     `json:"summary"`
+	HTMLURL    string     `json:"html_url"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Resolution string     `json:"resolution,omitempty"`
+}
+
+// IsOpen reports whether the alert is still open
+func (sa *SecurityAlert) IsOpen() bool {
+	return sa.ResolvedAt == nil
+}
+
+// ParseSecurityAlert parses a security alert event into its normalized form
+func ParseSecurityAlert(eventType string, body []byte) (*SecurityAlert, error) {
+	var alert SecurityAlert
+
+	switch eventType {
+	case DependabotAlertEvent:
+		var payload DependabotAlertPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+		}
+		a := payload.Alert
+		severity := a.SecurityVulnerability.Severity
+		if severity == "" {
+			severity = a.SecurityAdvisory.Severity
+		}
+		alert = SecurityAlert{
+			Action:     payload.Action,
+			Repository: repositoryFullName(payload.Repository),
+			Number:     a.Number,
+			Severity:   normalizeSeverity(severity),
+			State:      a.State,
+			Summary:    a.SecurityAdvisory.Summary,
+			HTMLURL:    a.HTMLURL,
+			CreatedAt:  a.CreatedAt,
+		}
+		switch {
+		case a.FixedAt != nil:
+			alert.ResolvedAt, alert.Resolution = a.FixedAt, "fixed"
+		case a.DismissedAt != nil:
+			alert.ResolvedAt, alert.Resolution = a.DismissedAt, "dismissed"
+		case a.AutoDismissedAt != nil:
+			alert.ResolvedAt, alert.Resolution = a.AutoDismissedAt, "dismissed"
+		}
+
+	case CodeScanningAlertEvent:
+		var payload CodeScanningAlertPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+		}
+		a := payload.Alert
+		severity := a.Rule.SecuritySeverityLevel
+		if severity == "" {
+			severity = a.Rule.Severity
+		}
+		alert = SecurityAlert{
+			Action:     payload.Action,
+			Repository: repositoryFullName(payload.Repository),
+			Number:     a.Number,
+			Severity:   normalizeSeverity(severity),
+			State:      a.State,
+			Summary:    a.Rule.Description,
+			HTMLURL:    a.HTMLURL,
+			CreatedAt:  a.CreatedAt,
+		}
+		switch {
+		case a.FixedAt != nil:
+			alert.ResolvedAt, alert.Resolution = a.FixedAt, "fixed"
+		case a.DismissedAt != nil:
+			alert.ResolvedAt, alert.Resolution = a.DismissedAt, "dismissed"
+		}
+
+	case SecretScanningAlertEvent:
+		var payload SecretScanningAlertPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, fmt.Errorf("invalid %s payload: %w", eventType, err)
+		}
+		a := payload.Alert
+		summary := a.DisplayName
+		if summary == "" {
+			summary = a.SecretType
+		}
+		// Leaked secrets carry no severity of their own and are always treated as high
+		alert = SecurityAlert{
+			Action:     payload.Action,
+			Repository: repositoryFullName(payload.Repository),
+			Number:     a.Number,
+			Severity:   SeverityHigh,
+			State:      a.State,
+			Summary:    summary,
+			HTMLURL:    a.HTMLURL,
+			CreatedAt:  a.CreatedAt,
+		}
+		if a.ResolvedAt != nil {
+			alert.ResolvedAt = a.ResolvedAt
+			alert.Resolution = "fixed"
+			if a.Resolution != "" && a.Resolution != "revoked" {
+				alert.Resolution = "dismissed"
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("%s is not a security alert event", eventType)
+	}
+
+	alert.Kind = eventType
+	if alert.Number == 0 {
+		return nil, fmt.Errorf("%s payload is missing the alert number", eventType)
+	}
+	return &alert, nil
+}
+
+// normalizeSeverity maps the various GitHub severity vocabularies onto
+// low/medium/high/critical
+func normalizeSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return SeverityCritical
+	case "high", "error":
+		return SeverityHigh
+	case "medium", "moderate", "warning":
+		return SeverityMedium
+	default:
+		return SeverityLow
+	}
+}
+
+// repositoryFullName extracts full_name from a repository object
+func repositoryFullName(repository map[string]interface{}) string {
+	if repository == nil {
+		return ""
+	}
+	name, _ := repository["full_name"].(string)
+	return name
+}
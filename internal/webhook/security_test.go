@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"testing"
+)
+
+// TestParseSecurityAlert_Dependabot tests parsing a dependabot_alert payload
+func TestParseSecurityAlert_Dependabot(t *testing.T) {
+	body := []byte(`{
+		"action": "fixed",
+		"alert": {
+			"number": 2,
+			"state": "fixed",
+			"html_url": "https://github.com/octo-org/hello-world/security/dependabot/2",
+			"created_at": "2024-01-01T00:00:00Z",
+			"fixed_at": "2024-01-03T00:00:00Z",
+			"security_advisory": {"ghsa_id": "GHSA-xxxx", "summary": "Prototype pollution", "severity": "high"},
+			"security_vulnerability": {"severity": "moderate", "package": {"ecosystem": "npm", "name": "lodash"}}
+		},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`)
+
+	alert, err := ParseSecurityAlert(DependabotAlertEvent, body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if alert.Kind != DependabotAlertEvent || alert.Number != 2 || alert.Repository != "octo-org/hello-world" {
+		t.Errorf("Unexpected alert: %+v", alert)
+	}
+	if alert.Severity != SeverityMedium {
+		t.Errorf("Expected vulnerability severity to be used and normalized to medium, got %s", alert.Severity)
+	}
+	if alert.IsOpen() || alert.Resolution != "fixed" {
+		t.Errorf("Expected fixed alert, got resolution %q", alert.Resolution)
+	}
+}
+
+// TestParseSecurityAlert_CodeScanning tests parsing a code_scanning_alert payload
+func TestParseSecurityAlert_CodeScanning(t *testing.T) {
+	body := []byte(`{
+		"action": "created",
+		"alert": {
+			"number": 7,
+			"state": "open",
+			"created_at": "2024-01-01T00:00:00Z",
+			"rule": {"id": "js/xss", "description": "Cross-site scripting", "severity": "error", "security_severity_level": "critical"}
+		},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`)
+
+	alert, err := ParseSecurityAlert(CodeScanningAlertEvent, body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if alert.Severity != SeverityCritical || !alert.IsOpen() || alert.Summary != "Cross-site scripting" {
+		t.Errorf("Unexpected alert: %+v", alert)
+	}
+}
+
+// TestParseSecurityAlert_SecretScanning tests parsing a secret_scanning_alert payload
+func TestParseSecurityAlert_SecretScanning(t *testing.T) {
+	body := []byte(`{
+		"action": "resolved",
+		"alert": {
+			"number": 3,
+			"state": "resolved",
+			"resolution": "false_positive",
+			"created_at": "2024-01-01T00:00:00Z",
+			"resolved_at": "2024-01-02T00:00:00Z",
+			"secret_type": "github_personal_access_token"
+		},
+		"repository": {"full_name": "octo-org/hello-world"}
+	}`)
+
+	alert, err := ParseSecurityAlert(SecretScanningAlertEvent, body)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if alert.Severity != SeverityHigh || alert.Resolution != "dismissed" || alert.Summary != "github_personal_access_token" {
+		t.Errorf("Unexpected alert: %+v", alert)
+	}
+}
+
+// TestParseSecurityAlert_Invalid tests rejection of bad payloads
+func TestParseSecurityAlert_Invalid(t *testing.T) {
+	tests := []struct {
+		eventType string
+		body      string
+	}{
+		{"push", `{}`},
+		{DependabotAlertEvent, `not json`},
+		{CodeScanningAlertEvent, `{"action":"created","alert":{}}`},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseSecurityAlert(test.eventType, []byte(test.body)); err == nil {
+			t.Errorf("ParseSecurityAlert(%q, %q) expected error, got nil", test.eventType, test.body)
+		}
+	}
+}
+
+// TestSeverityRank tests severity ordering
+func TestSeverityRank(t *testing.T) {
+	if !(SeverityRank(SeverityCritical) > SeverityRank(SeverityHigh) &&
+		SeverityRank(SeverityHigh) > SeverityRank(SeverityMedium) &&
+		SeverityRank(SeverityMedium) > SeverityRank(SeverityLow)) {
+		t.Error("Expected severities to be ordered low < medium < high < critical")
+	}
+	if SeverityRank("bogus") != 0 {
+		t.Error("Expected unknown severity to rank 0")
+	}
+}
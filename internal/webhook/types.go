@@ -12,6 +12,41 @@ var SupportedEventTypes = map[string]bool{
 	"push":          true,
 	"issue_comment": true,
 	"pull_request":  true,
+
+	"dependabot_alert":      true,
+	"code_scanning_alert":   true,
+	"secret_scanning_alert": true,
+
+	"branch_protection_rule": true,
+	"repository_ruleset":     true,
+
+	"member":       true,
+	"membership":   true,
+	"team":         true,
+	"organization": true,
+
+	"star":        true,
+	"watch":       true,
+	"fork":        true,
+	"sponsorship": true,
+
+	"discussion":         true,
+	"discussion_comment": true,
+
+	"projects_v2_item": true,
+
+	"gollum":     true,
+	"page_build": true,
+
+	"workflow_run":      true,
+	"deployment_status": true,
+
+	"check_suite": true,
+	"check_run":   true,
+
+	"release": true,
+	"create":  true,
+	"delete":  true,
 }
 
 // IsSupportedEvent checks if an event type should be stored in the database
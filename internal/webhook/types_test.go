@@ -14,9 +14,10 @@ func TestIsSupportedEvent(t *testing.T) {
 		{"issue_comment", true},
 		{"pull_request", true},
 		{"ping", false},
-		{"release", false},
+		{"release", true},
+		{"milestone", false},
 		{"issues", false},
-		{"fork", false},
+		{"fork", true},
 		{"", false},
 	}
 
@@ -43,10 +44,22 @@ func TestSupportedEventTypes(t *testing.T) {
 	}
 
 	// Test that unsupported events are not in the map (or false)
-	unsupportedEvents := []string{"ping", "release", "issues", "fork"}
+	unsupportedEvents := []string{"ping", "milestone", "issues"}
 	for _, eventType := range unsupportedEvents {
 		if SupportedEventTypes[eventType] {
 			t.Errorf("SupportedEventTypes[%q] should be false or not present", eventType)
 		}
 	}
-}
\ No newline at end of file
+}
+
+// TestIsSupportedEvent_SecurityAlerts tests that security alert events are stored
+func TestIsSupportedEvent_SecurityAlerts(t *testing.T) {
+	for _, eventType := range []string{"dependabot_alert", "code_scanning_alert", "secret_scanning_alert"} {
+		if !IsSupportedEvent(eventType) {
+			t.Errorf("Expected %s to be a supported event", eventType)
+		}
+		if !IsSecurityAlertEvent(eventType) {
+			t.Errorf("Expected %s to be a security alert event", eventType)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package webhookgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Example is an example payload GitHub documents for a webhook
+type Example struct {
+	// Event is the event type of the webhook
+	Event string
+	// Name names the example after its webhook, like pull-request-opened,
+	// and after the example when the webhook has several
+	Name string
+	// Payload is the example, indented
+	Payload []byte
+}
+
+// Examples returns the example payloads of the webhooks of events in an
+// OpenAPI description, in the order of their webhooks' names. Examples are
+// read from the examples and example of each webhook's request body.
+func Examples(spec []byte, events []string) ([]Example, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI description: %w", err)
+	}
+	l := &loader{doc: doc}
+	hooks, err := l.hooks()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(events))
+	for _, event := range events {
+		wanted[event] = true
+	}
+
+	var examples []Example
+	for _, hook := range hooks {
+		if !wanted[hook.event] {
+			continue
+		}
+		values := make(map[string]interface{})
+		if named, ok := hook.media["examples"].(map[string]interface{}); ok {
+			for key, v := range named {
+				example, _ := l.resolve(v).(map[string]interface{})
+				if value, ok := example["value"]; ok {
+					values[key] = value
+				}
+			}
+		}
+		if value, ok := hook.media["example"]; ok && len(values) == 0 {
+			values["default"] = value
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			name := hook.name
+			if len(keys) > 1 {
+				name += "-" + key
+			}
+			payload, err := json.MarshalIndent(values[key], "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("example %s: %w", name, err)
+			}
+			if !bytes.HasPrefix(payload, []byte("{")) {
+				return nil, fmt.Errorf("example %s is not a JSON object", name)
+			}
+			examples = append(examples, Example{Event: hook.event, Name: name, Payload: append(payload, '\n')})
+		}
+	}
+	return examples, nil
+}
@@ -0,0 +1,41 @@
+package webhookgen
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExamples(t *testing.T) {
+	spec, err := os.ReadFile("testdata/webhooks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	examples, err := Examples(spec, []string{"push", "pull_request"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Example{
+		// pull-request-closed has no examples, and a single example keeps
+		// the name of its webhook
+		{Event: "pull_request", Name: "pull-request-opened", Payload: []byte("{\n  \"action\": \"opened\",\n  \"number\": 2,\n  \"pull_request\": {\n    \"number\": 2\n  }\n}\n")},
+		{Event: "push", Name: "push-default", Payload: []byte("{\n  \"commits\": [\n    {\n      \"id\": \"6113728f27ae82c7b1a177c8d03f9e96e0adf246\"\n    }\n  ],\n  \"ref\": \"refs/heads/main\"\n}\n")},
+		{Event: "push", Name: "push-tag", Payload: []byte("{\n  \"commits\": [],\n  \"ref\": \"refs/tags/v1.0.0\"\n}\n")},
+	}
+	if len(examples) != len(want) {
+		t.Fatalf("Examples() returned %d examples, want %d: %+v", len(examples), len(want), examples)
+	}
+	for i, example := range examples {
+		if example.Event != want[i].Event || example.Name != want[i].Name || string(example.Payload) != string(want[i].Payload) {
+			t.Errorf("Examples()[%d] = %s %s %s, want %s %s %s", i,
+				example.Event, example.Name, example.Payload, want[i].Event, want[i].Name, want[i].Payload)
+		}
+	}
+
+	if examples, err := Examples(spec, []string{"star"}); err != nil || len(examples) != 0 {
+		t.Errorf("Examples() of an event without webhooks = %v, %v", examples, err)
+	}
+	if _, err := Examples([]byte(`{"openapi": "3.0.3", "paths": {}}`), []string{"push"}); err == nil {
+		t.Error("Examples() of a description without webhooks succeeded")
+	}
+}
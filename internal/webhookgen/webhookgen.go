@@ -0,0 +1,721 @@
+// Package webhookgen generates Go types of webhook payloads from GitHub's
+// OpenAPI description. The description has a webhook for each action of an
+// event, so the schemas of an event's webhooks are merged into one type any
+// of its payloads decodes into. Component schemas become shared named
+// types, and identical inline objects are generated once.
+package webhookgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Options control what is generated
+type Options struct {
+	// Package is the name of the generated package
+	Package string
+	// Source names the description in the header of the generated file
+	Source string
+	// Events are the event types to generate types for
+	Events []string
+}
+
+type kind int
+
+const (
+	kindAny kind = iota
+	kindString
+	kindTime
+	kindInteger
+	kindNumber
+	kindBoolean
+	kindObject
+	kindMap
+	kindArray
+)
+
+// shape is a schema reduced to what decides its Go type
+type shape struct {
+	kind kind
+	// ref is the name of the component schema an object came from
+	ref      string
+	props    map[string]*shape
+	required map[string]bool
+	elem     *shape
+	nullable bool
+	doc      string
+}
+
+// Generate generates the Go types of the payloads of opts.Events from an
+// OpenAPI description, as a formatted Go source file
+func Generate(spec []byte, opts Options) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI description: %w", err)
+	}
+	l := &loader{doc: doc, refs: make(map[string]*shape), building: make(map[*shape]bool), merged: make(map[[2]*shape]*shape)}
+
+	webhooks, err := l.webhooks()
+	if err != nil {
+		return nil, err
+	}
+	events := append([]string(nil), opts.Events...)
+	sort.Strings(events)
+
+	g := &generator{names: make(map[string]bool), named: make(map[*shape]string), refs: make(map[string]string), bodies: make(map[string]string)}
+	// Event types are named first, so no component takes their names
+	for _, event := range events {
+		g.names[exportedName(event)+"Event"] = true
+	}
+	var roots []string
+	var missing []string
+	for _, event := range events {
+		schemas := webhooks[event]
+		if len(schemas) == 0 {
+			missing = append(missing, event)
+			continue
+		}
+		var merged *shape
+		for _, s := range schemas {
+			merged = l.merge(merged, s, false)
+		}
+		root := *merged
+		root.ref = ""
+		root.doc = fmt.Sprintf("is the payload of %s events", event)
+		if len(schemas) > 1 {
+			root.doc += fmt.Sprintf(", merged from the schemas of its %d actions", len(schemas))
+		}
+		name := g.structType(&root, exportedName(event)+"Event", true)
+		roots = append(roots, event, name)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("no webhooks for events %s in the description", strings.Join(missing, ", "))
+	}
+	return g.file(opts, roots)
+}
+
+// loader reduces the schemas of a description to shapes
+type loader struct {
+	doc  map[string]interface{}
+	refs map[string]*shape
+	// building holds the shapes of references being built
+	building map[*shape]bool
+	merged   map[[2]*shape]*shape
+}
+
+// webhook is a webhook of a description with its JSON request body
+type webhook struct {
+	name  string
+	event string
+	media map[string]interface{}
+}
+
+// hooks returns the webhooks of the description in the order of their
+// names. OpenAPI 3.1 descriptions list webhooks under webhooks, and GitHub's
+// 3.0 descriptions under x-webhooks.
+func (l *loader) hooks() ([]webhook, error) {
+	hooks, _ := l.doc["webhooks"].(map[string]interface{})
+	if hooks == nil {
+		hooks, _ = l.doc["x-webhooks"].(map[string]interface{})
+	}
+	if hooks == nil {
+		return nil, fmt.Errorf("the description has no webhooks")
+	}
+	names := make([]string, 0, len(hooks))
+	for name := range hooks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var webhooks []webhook
+	for _, name := range names {
+		item, _ := hooks[name].(map[string]interface{})
+		op, _ := l.resolve(item["post"]).(map[string]interface{})
+		if op == nil {
+			continue
+		}
+		body, _ := l.resolve(op["requestBody"]).(map[string]interface{})
+		content, _ := body["content"].(map[string]interface{})
+		media, _ := content["application/json"].(map[string]interface{})
+		if media["schema"] == nil {
+			return nil, fmt.Errorf("webhook %s has no JSON request body", name)
+		}
+		webhooks = append(webhooks, webhook{name: name, event: webhookEvent(op), media: media})
+	}
+	return webhooks, nil
+}
+
+// webhooks returns the request body schemas of the webhooks of each event,
+// in the order of their names
+func (l *loader) webhooks() (map[string][]*shape, error) {
+	hooks, err := l.hooks()
+	if err != nil {
+		return nil, err
+	}
+	events := make(map[string][]*shape)
+	for _, hook := range hooks {
+		s, err := l.shape(hook.media["schema"], 0)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %s: %w", hook.name, err)
+		}
+		events[hook.event] = append(events[hook.event], s)
+	}
+	return events, nil
+}
+
+// webhookEvent returns the event type of a webhook operation: the
+// subcategory GitHub files it under, or else the part of its operation ID
+// before the action, like pull-request in pull-request/opened
+func webhookEvent(op map[string]interface{}) string {
+	name, _ := op["operationId"].(string)
+	if ext, ok := op["x-github"].(map[string]interface{}); ok {
+		if sub, ok := ext["subcategory"].(string); ok && sub != "" {
+			name = sub
+		}
+	}
+	name, _, _ = strings.Cut(name, "/")
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+// resolve follows a $ref within the description
+func (l *loader) resolve(v interface{}) interface{} {
+	for i := 0; i < 16; i++ {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return v
+		}
+		v = l.pointer(ref)
+	}
+	return nil
+}
+
+// pointer looks up a local JSON pointer like #/components/schemas/user
+func (l *loader) pointer(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var v interface{} = l.doc
+	for _, part := range strings.Split(ref[2:], "/") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		v = m[part]
+	}
+	return v
+}
+
+// maxSchemaDepth bounds the nesting of inline schemas
+const maxSchemaDepth = 64
+
+func (l *loader) shape(v interface{}, depth int) (*shape, error) {
+	if depth > maxSchemaDepth {
+		return nil, fmt.Errorf("schemas are nested more than %d levels deep", maxSchemaDepth)
+	}
+	schema, ok := v.(map[string]interface{})
+	if !ok {
+		return &shape{kind: kindAny}, nil
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if s, ok := l.refs[ref]; ok {
+			return s, nil
+		}
+		target, ok := l.pointer(ref).(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolved reference %s", ref)
+		}
+		// Registered before it is built, so recursive references end here
+		s := &shape{}
+		l.refs[ref] = s
+		l.building[s] = true
+		built, err := l.shape(target, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		*s = *built
+		delete(l.building, s)
+		if s.kind == kindObject && strings.HasPrefix(ref, "#/components/schemas/") {
+			s.ref = strings.TrimPrefix(ref, "#/components/schemas/")
+		}
+		return s, nil
+	}
+
+	s, err := l.shapeOf(schema, depth)
+	if err != nil {
+		return nil, err
+	}
+	// Combinators may return shared shapes, so they are copied to change.
+	// A reference still being built is left as it is: it can only be an
+	// object, which is a pointer whether or not it is nullable.
+	nullable, _ := schema["nullable"].(bool)
+	doc := firstSentence(schema["description"])
+	if !l.building[s] && ((nullable && !s.nullable) || (doc != "" && s.doc == "")) {
+		copied := *s
+		copied.nullable = copied.nullable || nullable
+		if copied.doc == "" {
+			copied.doc = doc
+		}
+		s = &copied
+	}
+	return s, nil
+}
+
+func (l *loader) shapeOf(schema map[string]interface{}, depth int) (*shape, error) {
+	for _, key := range []string{"allOf", "oneOf", "anyOf"} {
+		variants, ok := schema[key].([]interface{})
+		if !ok {
+			continue
+		}
+		// Properties next to the combinator belong to each variant
+		var merged *shape
+		if _, ok := schema["properties"]; ok {
+			rest := make(map[string]interface{}, len(schema))
+			for k, v := range schema {
+				if k != key {
+					rest[k] = v
+				}
+			}
+			var err error
+			if merged, err = l.shape(rest, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		nullable := false
+		for _, variant := range variants {
+			s, err := l.shape(variant, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if s.kind == kindAny && isNullSchema(l.resolve(variant)) {
+				nullable = true
+				continue
+			}
+			merged = l.merge(merged, s, key == "allOf")
+		}
+		if merged == nil {
+			merged = &shape{kind: kindAny}
+		}
+		if nullable {
+			copied := *merged
+			copied.nullable = true
+			merged = &copied
+		}
+		return merged, nil
+	}
+
+	s := &shape{}
+	switch types := schema["type"].(type) {
+	case string:
+		s.kind = scalarKind(types, schema["format"])
+	case []interface{}:
+		// OpenAPI 3.1 writes nullable types as a list with null
+		var kinds []string
+		for _, t := range types {
+			if name, _ := t.(string); name == "null" {
+				s.nullable = true
+			} else if name != "" {
+				kinds = append(kinds, name)
+			}
+		}
+		if len(kinds) == 1 {
+			s.kind = scalarKind(kinds[0], schema["format"])
+		}
+	default:
+		if _, ok := schema["properties"]; ok {
+			s.kind = kindObject
+		}
+	}
+
+	switch s.kind {
+	case kindObject:
+		props, _ := schema["properties"].(map[string]interface{})
+		if len(props) == 0 {
+			s.kind = kindMap
+			return s, nil
+		}
+		s.props = make(map[string]*shape, len(props))
+		s.required = make(map[string]bool)
+		for name, prop := range props {
+			p, err := l.shape(prop, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			s.props[name] = p
+		}
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if name, ok := name.(string); ok && s.props[name] != nil {
+				s.required[name] = true
+			}
+		}
+	case kindArray:
+		elem, err := l.shape(schema["items"], depth+1)
+		if err != nil {
+			return nil, err
+		}
+		s.elem = elem
+	}
+	return s, nil
+}
+
+func isNullSchema(v interface{}) bool {
+	schema, _ := v.(map[string]interface{})
+	t, _ := schema["type"].(string)
+	return t == "null"
+}
+
+func scalarKind(t string, format interface{}) kind {
+	switch t {
+	case "string":
+		if format == "date-time" {
+			return kindTime
+		}
+		return kindString
+	case "integer":
+		return kindInteger
+	case "number":
+		return kindNumber
+	case "boolean":
+		return kindBoolean
+	case "object":
+		return kindObject
+	case "array":
+		return kindArray
+	}
+	return kindAny
+}
+
+// merge combines two shapes into one a value of either decodes into. For
+// allOf, properties required by either are required; otherwise only those
+// required by both are.
+func (l *loader) merge(a, b *shape, all bool) *shape {
+	switch {
+	case a == nil:
+		return b
+	case b == nil || a == b:
+		return a
+	}
+	key := [2]*shape{a, b}
+	if m, ok := l.merged[key]; ok {
+		return m
+	}
+
+	m := &shape{kind: a.kind, nullable: a.nullable || b.nullable, doc: a.doc}
+	if m.doc == "" {
+		m.doc = b.doc
+	}
+	switch {
+	case a.kind == kindAny || b.kind == kindAny:
+		m.kind = kindAny
+	case a.kind == kindObject && b.kind == kindObject:
+		l.merged[key] = m
+		if a.ref == b.ref {
+			m.ref = a.ref
+		}
+		m.props = make(map[string]*shape)
+		m.required = make(map[string]bool)
+		for name, p := range a.props {
+			m.props[name] = l.merge(p, b.props[name], all)
+		}
+		for name, p := range b.props {
+			if a.props[name] == nil {
+				m.props[name] = p
+			}
+		}
+		for name := range m.props {
+			if all {
+				m.required[name] = a.required[name] || b.required[name]
+			} else {
+				m.required[name] = a.required[name] && b.required[name]
+			}
+		}
+	case a.kind == kindMap && b.kind == kindObject:
+		return l.merge(b, a, all)
+	case a.kind == kindObject && b.kind == kindMap:
+		m = &shape{}
+		*m = *a
+		m.nullable = a.nullable || b.nullable
+	case a.kind == kindArray && b.kind == kindArray:
+		m.elem = l.merge(a.elem, b.elem, false)
+	case a.kind != b.kind:
+		// Integers and numbers decode as numbers, and other mixes as they are
+		if (a.kind == kindInteger || a.kind == kindNumber) && (b.kind == kindInteger || b.kind == kindNumber) {
+			m.kind = kindNumber
+		} else {
+			m.kind = kindAny
+		}
+	}
+	return m
+}
+
+// generator renders shapes as Go types
+type generator struct {
+	// names holds the type names in use
+	names map[string]bool
+	// named holds the name of each shape rendered as a named type
+	named map[*shape]string
+	// refs holds the name of each component schema rendered, as copies of
+	// a component's shape that differ in nullability are the same type
+	refs map[string]string
+	// bodies holds the name of each rendered inline struct by its body, so
+	// identical inline objects share a type
+	bodies map[string]string
+	decls  []decl
+	time   bool
+	raw    bool
+}
+
+type decl struct {
+	name string
+	src  string
+}
+
+// structType renders an object shape as a named struct type, returning its
+// name. Component schemas are named after the component, and inline
+// objects after hint.
+func (g *generator) structType(s *shape, hint string, root bool) string {
+	if name, ok := g.named[s]; ok {
+		return name
+	}
+	if name, ok := g.refs[s.ref]; ok && !root {
+		return name
+	}
+	name := hint
+	if s.ref != "" && !root {
+		name = exportedName(s.ref)
+	}
+
+	// Component types may refer to themselves, so they are named before
+	// their fields are rendered. Inline ones cannot, and are shared when
+	// identical.
+	switch {
+	case root:
+		g.named[s] = name
+	case s.ref != "":
+		name = g.claim(name)
+		g.named[s] = name
+		g.refs[s.ref] = name
+	}
+	body := g.fields(s, name)
+	if s.ref == "" && !root {
+		if existing, ok := g.bodies[body]; ok {
+			g.named[s] = existing
+			return existing
+		}
+		name = g.claim(name)
+		g.named[s] = name
+		g.bodies[body] = name
+	}
+
+	var b strings.Builder
+	switch {
+	case root:
+		fmt.Fprintf(&b, "// %s %s\n", name, s.doc)
+	case s.ref != "":
+		fmt.Fprintf(&b, "// %s is the %s schema of GitHub's webhook payloads\n", name, s.ref)
+	default:
+		fmt.Fprintf(&b, "// %s is an object of GitHub's webhook payloads\n", name)
+	}
+	if s.doc != "" && !root {
+		fmt.Fprintf(&b, "//\n// %s.\n", s.doc)
+	}
+	fmt.Fprintf(&b, "type %s struct {\n%s}\n", name, body)
+	g.decls = append(g.decls, decl{name: name, src: b.String()})
+	return name
+}
+
+// claim reserves a unique type name based on name
+func (g *generator) claim(name string) string {
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.names[unique] = true
+	return unique
+}
+
+// fields renders the fields of an object shape
+func (g *generator) fields(s *shape, parent string) string {
+	props := make([]string, 0, len(s.props))
+	for name := range s.props {
+		props = append(props, name)
+	}
+	sort.Strings(props)
+
+	var b strings.Builder
+	used := make(map[string]bool)
+	for _, prop := range props {
+		p := s.props[prop]
+		field := exportedName(prop)
+		for i := 2; used[field]; i++ {
+			field = exportedName(prop) + strconv.Itoa(i)
+		}
+		used[field] = true
+
+		typ := g.goType(p, parent+field)
+		tag := prop
+		if !s.required[prop] {
+			tag += ",omitempty"
+		}
+		if p.doc != "" && p.kind != kindObject {
+			fmt.Fprintf(&b, "\t// %s\n", p.doc)
+		}
+		fmt.Fprintf(&b, "\t%s %s `json:%q`\n", field, typ, tag)
+	}
+	return b.String()
+}
+
+// goType returns the Go type of a shape. Objects are pointers, so they can
+// be missing or null, and so can scalars that may be null.
+func (g *generator) goType(s *shape, hint string) string {
+	var typ string
+	switch s.kind {
+	case kindString:
+		typ = "string"
+	case kindTime:
+		typ = "time.Time"
+		g.time = true
+	case kindInteger:
+		typ = "int64"
+	case kindNumber:
+		typ = "float64"
+	case kindBoolean:
+		typ = "bool"
+	case kindMap:
+		return "map[string]any"
+	case kindArray:
+		elem := s.elem
+		if elem.kind == kindObject {
+			return "[]" + g.structType(elem, singular(hint), false)
+		}
+		return "[]" + strings.TrimPrefix(g.goType(elem, singular(hint)), "*")
+	case kindObject:
+		return "*" + g.structType(s, hint, false)
+	default:
+		g.raw = true
+		return "json.RawMessage"
+	}
+	if s.nullable {
+		return "*" + typ
+	}
+	return typ
+}
+
+// file renders the generated file. roots alternates event types and the
+// names of their types.
+func (g *generator) file(opts Options, roots []string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by webhookgen from %s. DO NOT EDIT.\n\n", opts.Source)
+	fmt.Fprintf(&b, "package %s\n\n", opts.Package)
+	if g.time || g.raw {
+		b.WriteString("import (\n")
+		if g.raw {
+			b.WriteString("\t\"encoding/json\"\n")
+		}
+		if g.time {
+			b.WriteString("\t\"time\"\n")
+		}
+		b.WriteString(")\n\n")
+	}
+
+	b.WriteString("// New returns a new payload of an event type, or false for event types\n")
+	b.WriteString("// without a generated type\n")
+	b.WriteString("func New(eventType string) (any, bool) {\n\tswitch eventType {\n")
+	for i := 0; i < len(roots); i += 2 {
+		fmt.Fprintf(&b, "\tcase %q:\n\t\treturn new(%s), true\n", roots[i], roots[i+1])
+	}
+	b.WriteString("\t}\n\treturn nil, false\n}\n")
+
+	// EventTypes lists the event types with generated types
+	b.WriteString("\n// EventTypes are the event types with generated types\n")
+	b.WriteString("var EventTypes = []string{\n")
+	for i := 0; i < len(roots); i += 2 {
+		fmt.Fprintf(&b, "\t%q,\n", roots[i])
+	}
+	b.WriteString("}\n")
+
+	decls := append([]decl(nil), g.decls...)
+	sort.Slice(decls, func(i, j int) bool { return decls[i].name < decls[j].name })
+	for _, d := range decls {
+		b.WriteString("\n")
+		b.WriteString(d.src)
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format the generated source: %w", err)
+	}
+	return src, nil
+}
+
+// initialisms are written in capitals in exported names, as golint wants
+var initialisms = map[string]bool{
+	"api": true, "ci": true, "cpu": true, "css": true, "dns": true, "gpg": true, "html": true,
+	"http": true, "https": true, "id": true, "ip": true, "json": true, "sha": true, "ssh": true,
+	"ssl": true, "sso": true, "tls": true, "ui": true, "uri": true, "url": true, "uuid": true, "xml": true,
+}
+
+// exportedName converts a property or schema name like html_url, node-id
+// or +1 to an exported Go identifier
+func exportedName(name string) string {
+	var b strings.Builder
+	switch {
+	case strings.HasPrefix(name, "+"):
+		b.WriteString("Plus")
+	case strings.HasPrefix(name, "-"):
+		b.WriteString("Minus")
+	}
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if initialisms[strings.ToLower(word)] {
+			b.WriteString(strings.ToUpper(word))
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "X" + s
+	}
+	return s
+}
+
+// singular names the elements of a list from the name of the list
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "ss"):
+		return name + "Item"
+	case strings.HasSuffix(name, "s"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return name + "Item"
+}
+
+// firstSentence returns the first sentence of a description on one line
+func firstSentence(v interface{}) string {
+	s, _ := v.(string)
+	s = strings.Join(strings.Fields(s), " ")
+	if i := strings.Index(s, ". "); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSuffix(s, ".")
+	if len(s) > 120 {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,116 @@
+package webhookgen
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	spec, err := os.ReadFile("testdata/webhooks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate(spec, Options{Package: "events", Source: "testdata/webhooks.json", Events: []string{"push", "pull_request"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(src)
+
+	for _, want := range []string{
+		"// Code generated by webhookgen from testdata/webhooks.json. DO NOT EDIT.\n\npackage events\n",
+		"case \"pull_request\":\n\t\treturn new(PullRequestEvent), true",
+		// Both actions of pull_request are merged, and only what both
+		// require is required
+		"// PullRequestEvent is the payload of pull_request events, merged from the schemas of its 2 actions",
+		"PullRequest *PullRequestEventPullRequest `json:\"pull_request\"`",
+		"Merged         bool       `json:\"merged,omitempty\"`",
+		"MergedAt       *time.Time `json:\"merged_at,omitempty\"`",
+		"Number         int64      `json:\"number\"`",
+		// Components are shared named types, and may refer to themselves
+		"Repository  *RepositoryWebhooks          `json:\"repository\"`",
+		"// SimpleUser is the simple-user schema of GitHub's webhook payloads\n//\n// A GitHub user.\ntype SimpleUser struct",
+		"Name    *string `json:\"name,omitempty\"`",
+		"Parent *Team  `json:\"parent,omitempty\"`",
+		"Plus1      int64 `json:\"+1,omitempty\"`",
+		// Values of several types are left to the consumer
+		"CreatedAt        json.RawMessage `json:\"created_at,omitempty\"`",
+		"CustomProperties map[string]any  `json:\"custom_properties,omitempty\"`",
+		// Identical inline objects share a type
+		"Commits    []PushEventCommit `json:\"commits\"`",
+		"HeadCommit *PushEventCommit  `json:\"head_commit,omitempty\"`",
+		"// The full git ref that was pushed\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Generate() is missing %q in\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "PushEventHeadCommit") || strings.Contains(got, "SimpleUser2") {
+		t.Errorf("Generate() duplicated a type:\n%s", got)
+	}
+}
+
+func TestGenerate_OpenAPI31(t *testing.T) {
+	spec := `{
+		"openapi": "3.1.0",
+		"webhooks": {
+			"star-created": {"post": {
+				"operationId": "star/created",
+				"requestBody": {"content": {"application/json": {"schema": {
+					"type": "object",
+					"properties": {
+						"action": {"type": "string"},
+						"starred_at": {"type": ["string", "null"], "format": "date-time"},
+						"count": {"type": ["integer", "number"]}
+					},
+					"required": ["action", "starred_at"]
+				}}}}
+			}}
+		}
+	}`
+	src, err := Generate([]byte(spec), Options{Package: "events", Source: "test", Events: []string{"star"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"type StarEvent struct",
+		"StarredAt *time.Time      `json:\"starred_at\"`",
+		"Count     json.RawMessage `json:\"count,omitempty\"`",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("Generate() is missing %q in\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerate_Invalid(t *testing.T) {
+	spec, err := os.ReadFile("testdata/webhooks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Generate(spec, Options{Package: "events", Events: []string{"push", "star"}}); err == nil || !strings.Contains(err.Error(), "no webhooks for events star") {
+		t.Errorf("Generate() for a missing event = %v", err)
+	}
+	if _, err := Generate([]byte(`{"openapi": "3.0.3", "paths": {}}`), Options{Package: "events"}); err == nil {
+		t.Error("Generate() of a description without webhooks succeeded")
+	}
+	if _, err := Generate([]byte(`not json`), Options{Package: "events"}); err == nil {
+		t.Error("Generate() of invalid JSON succeeded")
+	}
+}
+
+func TestExportedName(t *testing.T) {
+	for name, want := range map[string]string{
+		"html_url":            "HTMLURL",
+		"node_id":             "NodeID",
+		"projects_v2_item":    "ProjectsV2Item",
+		"repository-webhooks": "RepositoryWebhooks",
+		"+1":                  "Plus1",
+		"-1":                  "Minus1",
+		"8bit":                "X8bit",
+	} {
+		if got := exportedName(name); got != want {
+			t.Errorf("exportedName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+// Package workload authenticates machine clients, such as CI jobs and
+// Kubernetes workloads, with the OIDC ID tokens their platform issues them,
+// so pipelines need no long-lived API token. A token is accepted when a
+// trusted issuer signed it for the configured audience and its subject
+// matches a pattern of the issuer, and it gets the scopes of that issuer.
+package workload
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+	"gopkg.in/yaml.v3"
+)
+
+// leeway is the clock skew allowed when checking the lifetime of tokens
+const leeway = 30 * time.Second
+
+// keysTTL is how long the keys of an issuer are cached. Keys are fetched
+// again sooner for tokens signed with an unknown key, at most once per
+// refetchInterval.
+const (
+	keysTTL         = time.Hour
+	refetchInterval = time.Minute
+)
+
+// Issuer trusts the ID tokens of one issuer for an audience
+type Issuer struct {
+	// Issuer is the iss claim of its tokens and where OIDC discovery starts
+	Issuer string `yaml:"issuer"`
+	// JWKSURL is where its signing keys are fetched from instead of the
+	// jwks_uri found by discovery, such as for a Kubernetes API server that
+	// only serves discovery to authenticated clients
+	JWKSURL  string `yaml:"jwks_url"`
+	Audience string `yaml:"audience"`
+	// Subjects are the sub claims accepted, as path.Match patterns in which
+	// * does not match '/', e.g. "repo:acme/*:ref:refs/heads/main"
+	Subjects []string         `yaml:"subjects"`
+	Scopes   []apitoken.Scope `yaml:"scopes"`
+}
+
+func (i Issuer) validate() error {
+	u, err := url.Parse(i.Issuer)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("issuer %q must be an https URL", i.Issuer)
+	}
+	if i.JWKSURL != "" {
+		if u, err := url.Parse(i.JWKSURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("issuer %s: invalid jwks_url %q", i.Issuer, i.JWKSURL)
+		}
+	}
+	if i.Audience == "" {
+		return fmt.Errorf("issuer %s: audience is required", i.Issuer)
+	}
+	if len(i.Subjects) == 0 {
+		return fmt.Errorf("issuer %s: at least one subject is required", i.Issuer)
+	}
+	for _, subject := range i.Subjects {
+		if _, err := path.Match(subject, ""); err != nil {
+			return fmt.Errorf("issuer %s: invalid subject %q", i.Issuer, subject)
+		}
+	}
+	if len(i.Scopes) == 0 {
+		return fmt.Errorf("issuer %s: at least one scope is required", i.Issuer)
+	}
+	for _, scope := range i.Scopes {
+		if _, err := apitoken.ParseScopes(string(scope)); err != nil {
+			return fmt.Errorf("issuer %s: %w", i.Issuer, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the issuer accepts a token with claims
+func (i Issuer) matches(c claims) bool {
+	if c.Issuer != i.Issuer || !c.Audience.contains(i.Audience) {
+		return false
+	}
+	for _, subject := range i.Subjects {
+		if ok, _ := path.Match(subject, c.Subject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadFile reads the trusted issuers from a YAML file with a top-level
+// issuers list
+func LoadFile(filename string) ([]Issuer, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Issuers []Issuer `yaml:"issuers"`
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if len(file.Issuers) == 0 {
+		return nil, fmt.Errorf("%s: no issuers", filename)
+	}
+	for _, issuer := range file.Issuers {
+		if err := issuer.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+	}
+	return file.Issuers, nil
+}
+
+// audience is the aud claim, a string or a list of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+func (a audience) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	Expiry    int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+}
+
+// Verifier verifies ID tokens against the trusted issuers
+type Verifier struct {
+	issuers []Issuer
+	client  *http.Client
+	now     func() time.Time
+
+	mu sync.Mutex
+	// keys caches the signing keys of each issuer
+	keys map[string]*keySet
+}
+
+type keySet struct {
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewVerifier creates a verifier trusting issuers, as loaded by LoadFile
+func NewVerifier(issuers []Issuer) *Verifier {
+	return &Verifier{
+		issuers: issuers,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		now:     time.Now,
+		keys:    make(map[string]*keySet),
+	}
+}
+
+// Verify verifies an ID token, returning an API token named after its
+// subject with the scopes of the issuer that accepts it. Tokens that are
+// invalid, expired or not accepted by any issuer return
+// apitoken.ErrUnauthorized.
+func (v *Verifier) Verify(ctx context.Context, raw string) (apitoken.Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return apitoken.Token{}, apitoken.ErrUnauthorized
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	var c claims
+	if decode(parts[0], &header) != nil || decode(parts[1], &c) != nil {
+		return apitoken.Token{}, fmt.Errorf("%w: malformed ID token", apitoken.ErrUnauthorized)
+	}
+
+	var issuer *Issuer
+	for i := range v.issuers {
+		if v.issuers[i].matches(c) {
+			issuer = &v.issuers[i]
+			break
+		}
+	}
+	if issuer == nil {
+		return apitoken.Token{}, fmt.Errorf("%w: no trusted issuer accepts %s for %v from %s", apitoken.ErrUnauthorized, c.Subject, []string(c.Audience), c.Issuer)
+	}
+	now := v.now()
+	if c.Expiry == 0 || now.After(time.Unix(c.Expiry, 0).Add(leeway)) || (c.NotBefore != 0 && now.Before(time.Unix(c.NotBefore, 0).Add(-leeway))) {
+		return apitoken.Token{}, fmt.Errorf("%w: ID token of %s expired or not yet valid", apitoken.ErrUnauthorized, c.Subject)
+	}
+
+	key, err := v.key(ctx, *issuer, header.KeyID)
+	if err != nil {
+		return apitoken.Token{}, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || verifySignature(header.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature) != nil {
+		return apitoken.Token{}, fmt.Errorf("%w: invalid ID token signature", apitoken.ErrUnauthorized)
+	}
+	return apitoken.Token{Name: "workload:" + c.Subject, Scopes: issuer.Scopes}, nil
+}
+
+func decode(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// key returns the signing key kid of issuer, fetching the keys of the
+// issuer when they are not cached, are stale or lack kid
+func (v *Verifier) key(ctx context.Context, issuer Issuer, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	cached := v.keys[issuer.Issuer]
+	now := v.now()
+	if cached != nil {
+		if key, ok := cached.keys[kid]; ok && now.Sub(cached.fetched) < keysTTL {
+			return key, nil
+		}
+		if now.Sub(cached.fetched) < refetchInterval {
+			return nil, fmt.Errorf("%w: unknown signing key %q of %s", apitoken.ErrUnauthorized, kid, issuer.Issuer)
+		}
+	}
+
+	keys, err := v.fetchKeys(ctx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the signing keys of %s: %w", issuer.Issuer, err)
+	}
+	v.keys[issuer.Issuer] = &keySet{keys: keys, fetched: now}
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q of %s", apitoken.ErrUnauthorized, kid, issuer.Issuer)
+	}
+	return key, nil
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context, issuer Issuer) (map[string]crypto.PublicKey, error) {
+	jwksURL := issuer.JWKSURL
+	if jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, strings.TrimSuffix(issuer.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, err
+		}
+		if discovery.JWKSURI == "" {
+			return nil, errors.New("discovery document has no jwks_uri")
+		}
+		jwksURL = discovery.JWKSURI
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := v.getJSON(ctx, jwksURL, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		// Keys of other types or uses are skipped rather than failing the set
+		if key, err := k.publicKey(); err == nil && (k.Use == "" || k.Use == "sig") {
+			keys[k.KeyID] = key
+		}
+	}
+	return keys, nil
+}
+
+func (v *Verifier) getJSON(ctx context.Context, u string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, u)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// jwk is a JSON Web Key of a signing key set
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	number := func(value string) (*big.Int, error) {
+		data, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil || len(data) == 0 {
+			return nil, errors.New("invalid key parameter")
+		}
+		return new(big.Int).SetBytes(data), nil
+	}
+	switch k.KeyType {
+	case "RSA":
+		n, err := number(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := number(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := number(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := number(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
+
+// algorithms are the supported JWS algorithms with their hash
+var algorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+}
+
+func verifySignature(algorithm string, key crypto.PublicKey, signed, signature []byte) error {
+	hash, ok := algorithms[algorithm]
+	if !ok {
+		return fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(algorithm, "RS") {
+			return errors.New("algorithm does not match the key")
+		}
+		return rsa.VerifyPKCS1v15(key, hash, digest, signature)
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if algorithm != fmt.Sprintf("ES%d", key.Curve.Params().BitSize) || len(signature) != 2*size {
+			return errors.New("algorithm does not match the key")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	}
+	return errors.New("unsupported key")
+}
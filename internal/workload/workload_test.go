@@ -0,0 +1,190 @@
+package workload
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/apitoken"
+)
+
+// issuer is an OIDC issuer signing ID tokens with an RSA and an EC key
+type issuer struct {
+	url     string
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	fetches int
+}
+
+func newIssuer(t *testing.T) (*issuer, *http.Client) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	iss := &issuer{rsaKey: rsaKey, ecKey: ecKey}
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"issuer": iss.url, "jwks_uri": iss.url + "/keys"})
+		case "/keys":
+			iss.fetches++
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+				{"kty": "RSA", "kid": "rsa", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+				{"kty": "EC", "kid": "ec", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	iss.url = server.URL
+	return iss, server.Client()
+}
+
+// sign returns an ID token with claims, signed with the key kid
+func (iss *issuer) sign(t *testing.T, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	algorithm := "RS256"
+	if kid == "ec" {
+		algorithm = "ES256"
+	}
+	encode := func(v interface{}) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(map[string]string{"alg": algorithm, "kid": kid, "typ": "JWT"}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	if kid == "ec" {
+		r, s, err := ecdsa.Sign(rand.Reader, iss.ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	} else {
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, iss.rsaKey, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	iss, client := newIssuer(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	verifier := NewVerifier([]Issuer{{
+		Issuer:   iss.url,
+		Audience: "choochoo",
+		Subjects: []string{"repo:acme/*:ref:refs/heads/main"},
+		Scopes:   []apitoken.Scope{apitoken.ScopeRead, apitoken.ScopeReplay},
+	}})
+	verifier.client = client
+	verifier.now = func() time.Time { return now }
+
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss": iss.url,
+			"aud": "choochoo",
+			"sub": "repo:acme/api:ref:refs/heads/main",
+			"exp": now.Add(5 * time.Minute).Unix(),
+		}
+		for key, value := range changes {
+			c[key] = value
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"RSA", iss.sign(t, "rsa", claims(nil)), nil},
+		{"EC", iss.sign(t, "ec", claims(nil)), nil},
+		{"audience list", iss.sign(t, "rsa", claims(map[string]interface{}{"aud": []string{"other", "choochoo"}})), nil},
+		{"other audience", iss.sign(t, "rsa", claims(map[string]interface{}{"aud": "other"})), apitoken.ErrUnauthorized},
+		{"other subject", iss.sign(t, "rsa", claims(map[string]interface{}{"sub": "repo:acme/api:ref:refs/heads/dev"})), apitoken.ErrUnauthorized},
+		{"other issuer", iss.sign(t, "rsa", claims(map[string]interface{}{"iss": "https://evil.example.com"})), apitoken.ErrUnauthorized},
+		{"expired", iss.sign(t, "rsa", claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()})), apitoken.ErrUnauthorized},
+		{"not yet valid", iss.sign(t, "rsa", claims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()})), apitoken.ErrUnauthorized},
+		{"unknown key", withKeyID(iss.sign(t, "rsa", claims(nil)), "rotated"), apitoken.ErrUnauthorized},
+		{"forged", iss.sign(t, "rsa", claims(nil)) + "x", apitoken.ErrUnauthorized},
+		{"malformed", "not.a.token", apitoken.ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := verifier.Verify(context.Background(), tt.token)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.want)
+			}
+			if err == nil && (token.Name != "workload:repo:acme/api:ref:refs/heads/main" || !token.Allows(apitoken.ScopeReplay) || token.Allows(apitoken.ScopeAdmin)) {
+				t.Errorf("Verify() = %+v", token)
+			}
+		})
+	}
+	// Unknown keys fetch the keys again, but not more than once a minute
+	if iss.fetches != 1 {
+		t.Errorf("keys fetched %d times, want 1", iss.fetches)
+	}
+	now = now.Add(2 * time.Minute)
+	verifier.Verify(context.Background(), withKeyID(iss.sign(t, "rsa", claims(nil)), "rotated"))
+	if iss.fetches != 2 {
+		t.Errorf("keys fetched %d times after a minute, want 2", iss.fetches)
+	}
+}
+
+// withKeyID replaces the header of token with one naming the key kid
+func withKeyID(token, kid string) string {
+	_, rest, _ := strings.Cut(token, ".")
+	return base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"`+kid+`"}`)) + "." + rest
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		filename := filepath.Join(dir, "identities.yaml")
+		os.WriteFile(filename, []byte(content), 0o600)
+		return filename
+	}
+
+	issuers, err := LoadFile(write(`issuers:
+  - issuer: https://token.actions.githubusercontent.com
+    audience: choochoo
+    subjects: ["repo:acme/*:ref:refs/heads/main"]
+    scopes: [read, replay]
+`))
+	if err != nil || len(issuers) != 1 || issuers[0].Scopes[1] != apitoken.ScopeReplay {
+		t.Fatalf("LoadFile() = %+v, %v", issuers, err)
+	}
+
+	for name, content := range map[string]string{
+		"http issuer":   "issuers:\n  - {issuer: http://example.com, audience: a, subjects: [s], scopes: [read]}\n",
+		"no audience":   "issuers:\n  - {issuer: https://example.com, subjects: [s], scopes: [read]}\n",
+		"no subjects":   "issuers:\n  - {issuer: https://example.com, audience: a, scopes: [read]}\n",
+		"bad pattern":   "issuers:\n  - {issuer: https://example.com, audience: a, subjects: ['['], scopes: [read]}\n",
+		"unknown scope": "issuers:\n  - {issuer: https://example.com, audience: a, subjects: [s], scopes: [root]}\n",
+		"unknown key":   "issuers:\n  - {issuer: https://example.com, audience: a, subjects: [s], scopes: [read], role: x}\n",
+		"empty":         "issuers: []\n",
+	} {
+		if _, err := LoadFile(write(content)); err == nil {
+			t.Errorf("LoadFile() with %s expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package workqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+)
+
+// Depth reads the number of pending items at most once per interval, so
+// callers on hot paths, such as webhook responses, do not count the queue on
+// every request
+type Depth struct {
+	count    func(ctx context.Context) (int64, error)
+	interval time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	value  int64
+	readAt time.Time
+}
+
+// NewDepth creates a depth that counts pending items with count
+func NewDepth(count func(ctx context.Context) (int64, error), interval time.Duration) *Depth {
+	return &Depth{count: count, interval: interval, now: time.Now}
+}
+
+// PostgresDepth counts the pending items of the queue in queries
+func PostgresDepth(queries *db.Queries) func(ctx context.Context) (int64, error) {
+	return func(ctx context.Context) (int64, error) {
+		counts, err := queries.CountWorkItems(ctx)
+		return counts.Pending, err
+	}
+}
+
+// Get returns the number of pending items, counted at most interval ago
+func (d *Depth) Get(ctx context.Context) (int64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := d.now()
+	if !d.readAt.IsZero() && now.Sub(d.readAt) < d.interval {
+		return d.value, nil
+	}
+	value, err := d.count(ctx)
+	if err != nil {
+		return 0, err
+	}
+	d.value, d.readAt = value, now
+	return value, nil
+}
@@ -0,0 +1,276 @@
+// Package workqueue processes stored webhook events from a Postgres-backed
+// queue, so pending work survives restarts and is shared across replicas.
+// Workers claim items with SELECT ... FOR UPDATE SKIP LOCKED and hide them
+// for a visibility timeout; an item whose worker dies becomes visible again
+// once the timeout passes. Items that fail MaxAttempts times are quarantined
+// with the processor that failed them: they stay in the queue but are no
+// longer claimed until released.
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/tracing"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Defaults for Config
+const (
+	DefaultWorkers           = 4
+	DefaultVisibilityTimeout = 5 * time.Minute
+	DefaultMaxAttempts       = 5
+	DefaultPollInterval      = time.Second
+)
+
+// ErrSkip is returned by a HandleFunc for items that cannot be processed and
+// should be removed from the queue without retrying, such as events that
+// were pruned after being queued
+var ErrSkip = errors.New("skip work item")
+
+// Item is a claimed work item
+type Item struct {
+	ID         int64
+	DeliveryID string
+	// Attempts counts this attempt
+	Attempts int
+	// TraceParent continues the trace of the request that queued the item
+	TraceParent string
+}
+
+// Failure describes why processing an item failed
+type Failure struct {
+	Err error
+	// Processor names the processor that failed, when the error identifies
+	// one
+	Processor string
+}
+
+// Store holds the queue
+type Store interface {
+	// Claim claims up to limit visible items that are not quarantined
+	Claim(ctx context.Context, limit int) ([]Item, error)
+	// Complete removes a processed item
+	Complete(ctx context.Context, id int64) error
+	// Fail records why an item failed and hides it until retryAfter passes
+	Fail(ctx context.Context, id int64, failure Failure, retryAfter time.Duration) error
+	// Quarantine records why an item failed and stops it being claimed
+	Quarantine(ctx context.Context, id int64, failure Failure) error
+}
+
+// failedProcessor returns the name of the processor that caused err, if any
+func failedProcessor(err error) string {
+	var failed interface{ FailedProcessor() string }
+	if errors.As(err, &failed) {
+		return failed.FailedProcessor()
+	}
+	return ""
+}
+
+// HandleFunc processes the stored event of a work item
+type HandleFunc func(ctx context.Context, deliveryID string) error
+
+// Config tunes a queue
+type Config struct {
+	Workers           int
+	VisibilityTimeout time.Duration
+	MaxAttempts       int
+	PollInterval      time.Duration
+}
+
+// Queue runs workers that process items from a store
+type Queue struct {
+	store  Store
+	handle HandleFunc
+	config Config
+	wake   chan struct{}
+}
+
+// New creates a queue
+func New(store Store, handle HandleFunc, config Config) *Queue {
+	return &Queue{store: store, handle: handle, config: config, wake: make(chan struct{}, 1)}
+}
+
+// Notify wakes an idle worker after an item was enqueued, instead of leaving
+// it for the next poll
+func (q *Queue) Notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run starts the workers and blocks until ctx is cancelled
+func (q *Queue) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < q.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// work processes items until ctx is cancelled, waiting for a poll or a
+// notification whenever the queue is empty
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(q.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for q.RunOnce(ctx) {
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// RunOnce claims and processes a single item, reporting whether there was one
+func (q *Queue) RunOnce(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	items, err := q.store.Claim(ctx, 1)
+	if err != nil {
+		log.Printf("Failed to claim work items: %v", err)
+		return false
+	}
+	if len(items) == 0 {
+		return false
+	}
+	q.process(ctx, items[0])
+	return true
+}
+
+// process runs the handler on an item within its visibility timeout and
+// records the outcome
+func (q *Queue) process(ctx context.Context, item Item) {
+	spanCtx, span := tracing.Start(tracing.WithTraceParent(ctx, item.TraceParent), "workqueue.process",
+		attribute.String("github.delivery", item.DeliveryID),
+		attribute.Int("workqueue.attempt", item.Attempts),
+	)
+	err := q.run(spanCtx, item)
+	tracing.End(span, err)
+
+	// Record the outcome even if ctx was cancelled during shutdown
+	storeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	switch {
+	case err == nil || errors.Is(err, ErrSkip):
+		if err != nil {
+			log.Printf("Skipping queued delivery %s: %v", item.DeliveryID, err)
+		}
+		if err := q.store.Complete(storeCtx, item.ID); err != nil {
+			log.Printf("Failed to complete work item for delivery %s: %v", item.DeliveryID, err)
+		}
+		return
+	}
+
+	failure := Failure{Err: err, Processor: failedProcessor(err)}
+	if item.Attempts >= q.config.MaxAttempts {
+		log.Printf("Delivery %s failed %d times and is quarantined (processor: %s): %v", item.DeliveryID, item.Attempts, failure.Processor, err)
+		err = q.store.Quarantine(storeCtx, item.ID, failure)
+	} else {
+		log.Printf("Processing delivery %s failed (attempt %d of %d): %v", item.DeliveryID, item.Attempts, q.config.MaxAttempts, err)
+		err = q.store.Fail(storeCtx, item.ID, failure, q.backoff(item.Attempts))
+	}
+	if err != nil {
+		log.Printf("Failed to record failure of delivery %s: %v", item.DeliveryID, err)
+	}
+}
+
+// run calls the handler, turning a panic into an error so one malformed
+// payload cannot take down the worker
+func (q *Queue) run(ctx context.Context, item Item) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, q.config.VisibilityTimeout)
+	defer cancel()
+	return q.handle(ctx, item.DeliveryID)
+}
+
+// backoff doubles the retry delay with each attempt, up to the visibility
+// timeout
+func (q *Queue) backoff(attempts int) time.Duration {
+	delay := time.Second << min(attempts, 20)
+	return min(delay, q.config.VisibilityTimeout)
+}
+
+// postgresStore keeps the queue in the work_items table
+type postgresStore struct {
+	queries           *db.Queries
+	visibilityTimeout time.Duration
+}
+
+// NewPostgresStore creates a store on the work_items table
+func NewPostgresStore(queries *db.Queries, config Config) Store {
+	return &postgresStore{queries: queries, visibilityTimeout: config.VisibilityTimeout}
+}
+
+// Claim claims up to limit visible items that are not quarantined
+func (s *postgresStore) Claim(ctx context.Context, limit int) ([]Item, error) {
+	rows, err := s.queries.ClaimWorkItems(ctx, db.ClaimWorkItemsParams{
+		VisibilitySeconds: seconds(s.visibilityTimeout),
+		BatchSize:         int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Item, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, Item{
+			ID:          row.ID,
+			DeliveryID:  row.DeliveryID,
+			Attempts:    int(row.Attempts),
+			TraceParent: row.TraceParent.String,
+		})
+	}
+	return items, nil
+}
+
+// Complete removes a processed item
+func (s *postgresStore) Complete(ctx context.Context, id int64) error {
+	return s.queries.CompleteWorkItem(ctx, id)
+}
+
+// Fail records why an item failed and hides it until retryAfter passes
+func (s *postgresStore) Fail(ctx context.Context, id int64, failure Failure, retryAfter time.Duration) error {
+	return s.queries.FailWorkItem(ctx, failParams(id, failure, seconds(retryAfter), false))
+}
+
+// Quarantine records why an item failed and stops it being claimed
+func (s *postgresStore) Quarantine(ctx context.Context, id int64, failure Failure) error {
+	return s.queries.FailWorkItem(ctx, failParams(id, failure, 0, true))
+}
+
+func failParams(id int64, failure Failure, retrySeconds int32, quarantine bool) db.FailWorkItemParams {
+	return db.FailWorkItemParams{
+		LastError:       pgtype.Text{String: failure.Err.Error(), Valid: true},
+		FailedProcessor: pgtype.Text{String: failure.Processor, Valid: failure.Processor != ""},
+		RetrySeconds:    retrySeconds,
+		Quarantine:      quarantine,
+		ID:              id,
+	}
+}
+
+// seconds rounds a duration up to whole seconds
+func seconds(d time.Duration) int32 {
+	return int32((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,192 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore is a Store that keeps items in memory
+type memoryStore struct {
+	mu    sync.Mutex
+	items map[int64]*memoryItem
+}
+
+type memoryItem struct {
+	Item
+	visibleAt   time.Time
+	failure     Failure
+	quarantined bool
+}
+
+func newMemoryStore(deliveryIDs ...string) *memoryStore {
+	store := &memoryStore{items: make(map[int64]*memoryItem)}
+	for i, deliveryID := range deliveryIDs {
+		store.items[int64(i+1)] = &memoryItem{Item: Item{ID: int64(i + 1), DeliveryID: deliveryID}}
+	}
+	return store
+}
+
+func (s *memoryStore) Claim(ctx context.Context, limit int) ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []Item
+	for _, id := range slices.Sorted(maps.Keys(s.items)) {
+		item := s.items[id]
+		if len(claimed) == limit || item.quarantined || time.Now().Before(item.visibleAt) {
+			continue
+		}
+		item.Attempts++
+		item.visibleAt = time.Now().Add(time.Hour)
+		claimed = append(claimed, item.Item)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, id)
+	return nil
+}
+
+func (s *memoryStore) Fail(ctx context.Context, id int64, failure Failure, retryAfter time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[id].failure = failure
+	// Make failed items visible immediately so tests do not wait
+	s.items[id].visibleAt = time.Time{}
+	return nil
+}
+
+func (s *memoryStore) Quarantine(ctx context.Context, id int64, failure Failure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[id].failure = failure
+	s.items[id].quarantined = true
+	return nil
+}
+
+// processorError identifies the processor that failed, like the webhook
+// handler's errors do
+type processorError struct{ name string }
+
+func (e processorError) Error() string           { return e.name + " failed" }
+func (e processorError) FailedProcessor() string { return e.name }
+
+func testConfig() Config {
+	return Config{Workers: 2, VisibilityTimeout: time.Minute, MaxAttempts: 3, PollInterval: 10 * time.Millisecond}
+}
+
+func TestQueue_RunOnce(t *testing.T) {
+	store := newMemoryStore("ok", "pruned", "flaky", "malformed", "rejected")
+	calls := make(map[string]int)
+	handle := func(ctx context.Context, deliveryID string) error {
+		calls[deliveryID]++
+		switch deliveryID {
+		case "pruned":
+			return ErrSkip
+		case "flaky":
+			if calls[deliveryID] < 2 {
+				return errors.New("downstream unavailable")
+			}
+		case "malformed":
+			panic("nil map")
+		case "rejected":
+			return fmt.Errorf("processing: %w", processorError{name: "docs"})
+		}
+		return nil
+	}
+	queue := New(store, handle, testConfig())
+
+	for queue.RunOnce(context.Background()) {
+	}
+
+	if calls["ok"] != 1 || calls["pruned"] != 1 || calls["flaky"] != 2 || calls["malformed"] != 3 || calls["rejected"] != 3 {
+		t.Errorf("Unexpected calls: %v", calls)
+	}
+	if len(store.items) != 2 {
+		t.Fatalf("Expected only the quarantined items to remain, got %d items", len(store.items))
+	}
+	malformed := store.items[4]
+	if malformed == nil || !malformed.quarantined || malformed.Attempts != 3 || malformed.failure.Err.Error() != "panic: nil map" || malformed.failure.Processor != "" {
+		t.Errorf("Unexpected quarantined item: %+v", malformed)
+	}
+	rejected := store.items[5]
+	if rejected == nil || !rejected.quarantined || rejected.failure.Processor != "docs" {
+		t.Errorf("Expected the failing processor to be recorded, got %+v", rejected)
+	}
+}
+
+func TestQueue_Run(t *testing.T) {
+	store := newMemoryStore("a", "b", "c")
+	var mu sync.Mutex
+	processed := make(map[string]bool)
+	handle := func(ctx context.Context, deliveryID string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		processed[deliveryID] = true
+		return nil
+	}
+	queue := New(store, handle, testConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		queue.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(processed)
+		mu.Unlock()
+		if n == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out with %d items processed", n)
+		}
+		queue.Notify()
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+}
+
+func TestQueue_Backoff(t *testing.T) {
+	queue := New(nil, nil, testConfig())
+	if got := queue.backoff(1); got != 2*time.Second {
+		t.Errorf("backoff(1) = %v, want 2s", got)
+	}
+	if got := queue.backoff(30); got != time.Minute {
+		t.Errorf("backoff(30) = %v, want the visibility timeout", got)
+	}
+}
+
+func TestDepth_Get(t *testing.T) {
+	counts := 0
+	depth := NewDepth(func(ctx context.Context) (int64, error) {
+		counts++
+		return int64(counts * 10), nil
+	}, time.Second)
+	now := time.Now()
+	depth.now = func() time.Time { return now }
+
+	for _, want := range []int64{10, 10} {
+		if got, err := depth.Get(context.Background()); err != nil || got != want {
+			t.Fatalf("Get() = %d, %v, want %d", got, err, want)
+		}
+	}
+	now = now.Add(time.Second)
+	if got, _ := depth.Get(context.Background()); got != 20 {
+		t.Errorf("Get() after the interval = %d, want 20", got)
+	}
+}
@@ -1,9 +1,596 @@
 package main
 
-import "github.com/deedubs/choochoo/internal/server"
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/deedubs/choochoo/internal/checksum"
+	"github.com/deedubs/choochoo/internal/config"
+	"github.com/deedubs/choochoo/internal/database"
+	"github.com/deedubs/choochoo/internal/db"
+	"github.com/deedubs/choochoo/internal/deadletter"
+	"github.com/deedubs/choochoo/internal/encryption"
+	"github.com/deedubs/choochoo/internal/eventchain"
+	"github.com/deedubs/choochoo/internal/server"
+	"github.com/deedubs/choochoo/internal/timemachine"
+	"github.com/deedubs/choochoo/sql/migrations"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const usage = `Usage: choochoo [-config FILE] <command> [flags]
+
+Commands:
+  serve [-insecure-dev]   Run the webhook server (the default)
+  migrate                 Apply pending database migrations
+  events list             List stored events
+  replay DELIVERY_ID      Run a stored event through the pipeline again
+  redrive                 Retry events in the dead-letter spool
+  rekey                   Re-encrypt stored payloads with the active key
+  chain verify            Verify the tamper-evident event chain
+  chain keygen            Generate an event chain signing key
+  timemachine             Diff how a staging instance handles stored events
+
+Run "choochoo <command> -h" for the flags of a command.
+`
 
 func main() {
-	srv := server.NewWebhookServer()
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	configPath := flag.String("config", os.Getenv(config.PathEnv), "YAML or TOML config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+
+	switch args[0] {
+	case "serve":
+		serve(cfg, args[1:])
+	case "migrate":
+		os.Exit(migrate(cfg, args[1:]))
+	case "events":
+		os.Exit(events(cfg, args[1:]))
+	case "replay":
+		os.Exit(replay(cfg, args[1:]))
+	case "redrive":
+		os.Exit(redrive(cfg, args[1:]))
+	case "rekey":
+		os.Exit(rekey(cfg, args[1:]))
+	case "chain":
+		os.Exit(chain(cfg, args[1:]))
+	case "timemachine":
+		os.Exit(timeMachine(cfg, args[1:]))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// serve runs the webhook server until it exits
+func serve(cfg *config.Config, args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	insecureDev := flags.Bool("insecure-dev", false, "serve without GITHUB_WEBHOOK_SECRET even with CHOOCHOO_REQUIRE_SIGNATURE, accepting unsigned deliveries")
+	flags.Parse(args)
+
+	cfg.InsecureDev = *insecureDev
+	if err := cfg.ValidateServe(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if cfg.InsecureDev && len(cfg.WebhookSecrets()) == 0 {
+		log.Println("Warning: Running with -insecure-dev. Unsigned webhook deliveries are accepted; do not use this in production.")
+	}
+
+	srv := server.NewWebhookServer(cfg)
 	srv.Start()
 }
 
+// connect opens the configured database for a command
+func connect(ctx context.Context, command string, cfg *config.Config) (*database.Connection, bool) {
+	if cfg.DatabaseURL == "" {
+		fmt.Fprintf(os.Stderr, "%s: DATABASE_URL is not configured\n", command)
+		return nil, false
+	}
+	dbConn, err := database.NewConnection(ctx, cfg.DatabaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return nil, false
+	}
+	return dbConn, true
+}
+
+// openEvents opens the configured event store, PostgreSQL or SQLite, for a
+// command
+func openEvents(ctx context.Context, command string, cfg *config.Config) (database.EventStore, bool) {
+	if cfg.DatabaseURL == "" {
+		fmt.Fprintf(os.Stderr, "%s: DATABASE_URL is not configured\n", command)
+		return nil, false
+	}
+	events, err := database.OpenEventStore(ctx, cfg.DatabaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		return nil, false
+	}
+	return events, true
+}
+
+// migrate applies the embedded schema migrations that have not been applied
+func migrate(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	baseline := flags.String("baseline", "", "record migrations up to this version as applied without running them, for databases migrated by hand")
+	flags.Parse(args)
+
+	ctx := context.Background()
+	if database.IsSQLite(cfg.DatabaseURL) {
+		// Opening a SQLite store creates its schema
+		store, ok := openEvents(ctx, "migrate", cfg)
+		if !ok {
+			return 1
+		}
+		store.Close(ctx)
+		fmt.Println("SQLite schema is up to date")
+		return 0
+	}
+	dbConn, ok := connect(ctx, "migrate", cfg)
+	if !ok {
+		return 1
+	}
+	defer dbConn.Close(ctx)
+
+	var applied []database.Migration
+	var err error
+	if *baseline != "" {
+		applied, err = dbConn.Baseline(ctx, migrations.FS, *baseline)
+	} else {
+		applied, err = dbConn.Migrate(ctx, migrations.FS)
+	}
+	for _, migration := range applied {
+		fmt.Printf("Applied %s\n", migration.Name)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	if len(applied) == 0 {
+		fmt.Println("No pending migrations")
+	}
+	return 0
+}
+
+// events runs an events subcommand
+func events(cfg *config.Config, args []string) int {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintln(os.Stderr, "Usage: choochoo events list [-type TYPE] [-repo OWNER/NAME] [-limit N] [-json]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("events list", flag.ExitOnError)
+	eventType := flags.String("type", "", "only list events of this type")
+	repo := flags.String("repo", "", "only list events from this repository")
+	limit := flags.Int("limit", 20, "maximum number of events to list")
+	asJSON := flags.Bool("json", false, "print one JSON object per event, without payloads")
+	flags.Parse(args[1:])
+	if *limit <= 0 {
+		fmt.Fprintln(os.Stderr, "events: -limit must be positive")
+		return 2
+	}
+
+	ctx := context.Background()
+	store, ok := openEvents(ctx, "events", cfg)
+	if !ok {
+		return 1
+	}
+	defer store.Close(ctx)
+
+	rows, err := store.ListWebhookEvents(ctx, db.ListWebhookEventsParams{
+		EventType:      *eventType,
+		RepositoryName: *repo,
+		RowLimit:       int32(*limit),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "events: %v\n", err)
+		return 1
+	}
+
+	keys, err := cfg.PayloadKeys()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "events: %v\n", err)
+		return 1
+	}
+	// Each payload is checked against its checksum, so an export shows
+	// which payloads changed since they were received
+	status := make([]string, len(rows))
+	mismatched := 0
+	for i, row := range rows {
+		status[i] = verifyPayload(keys, row)
+		if status[i] != checksumOK && status[i] != checksumNone {
+			mismatched++
+		}
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for i, row := range rows {
+			encoder.Encode(map[string]interface{}{
+				"delivery_id":    row.DeliveryID,
+				"event_type":     row.EventType,
+				"action":         row.Action.String,
+				"repository":     row.RepositoryName.String,
+				"sender":         row.SenderLogin.String,
+				"created_at":     row.CreatedAt.Time,
+				"payload_sha256": row.PayloadSha256.String,
+				"checksum":       status[i],
+			})
+		}
+	} else {
+		table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "RECEIVED\tDELIVERY\tEVENT\tACTION\tREPOSITORY\tSENDER\tCHECKSUM")
+		for i, row := range rows {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				row.CreatedAt.Time.Format(time.RFC3339), row.DeliveryID, row.EventType,
+				row.Action.String, row.RepositoryName.String, row.SenderLogin.String, status[i])
+		}
+		table.Flush()
+	}
+
+	if mismatched > 0 {
+		fmt.Fprintf(os.Stderr, "events: %d payloads could not be verified against their checksums\n", mismatched)
+		return 1
+	}
+	return 0
+}
+
+// Outcomes of checking a stored payload against its checksum
+const (
+	checksumOK       = "ok"
+	checksumNone     = "none"
+	checksumMismatch = "mismatch"
+	checksumSealed   = "undecryptable"
+)
+
+// verifyPayload checks the payload of a stored event against its checksum.
+// Events stored before checksums were recorded report checksumNone.
+func verifyPayload(keys *encryption.Keyring, row db.WebhookEvent) string {
+	if !row.PayloadSha256.Valid {
+		return checksumNone
+	}
+	payload, err := keys.Open(row.DeliveryID, row.Payload)
+	if err != nil {
+		return checksumSealed
+	}
+	if checksum.Verify(payload, row.PayloadSha256.String) != nil {
+		return checksumMismatch
+	}
+	return checksumOK
+}
+
+// replay runs a stored delivery through the processing pipeline again
+func replay(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: choochoo replay DELIVERY_ID")
+		return 2
+	}
+	deliveryID := flags.Arg(0)
+
+	if cfg.DatabaseURL == "" {
+		fmt.Fprintln(os.Stderr, "replay: DATABASE_URL is not configured")
+		return 1
+	}
+	srv := server.NewWebhookServer(cfg)
+	if err := srv.Replay(context.Background(), deliveryID); err != nil {
+		fmt.Fprintf(os.Stderr, "replay: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Replayed delivery %s\n", deliveryID)
+	return 0
+}
+
+// redrive retries every event in the dead-letter spool once and reports the result
+func redrive(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("redrive", flag.ExitOnError)
+	dir := flags.String("dir", cfg.DeadLetterDir, "dead-letter spool directory")
+	flags.Parse(args)
+
+	ctx := context.Background()
+
+	spool, err := deadletter.NewSpool(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "redrive: %v\n", err)
+		return 1
+	}
+
+	store, ok := openEvents(ctx, "redrive", cfg)
+	if !ok {
+		return 1
+	}
+	defer store.Close(ctx)
+
+	redriven, failed, err := spool.Redrive(ctx, store.RetryWebhookEvent)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "redrive: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Redrove %d events from %s (%d still failing)\n", redriven, spool.Dir(), failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// rekey re-encrypts the stored payloads that are not encrypted with the
+// active key, so retired keys can be removed
+func rekey(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("rekey", flag.ExitOnError)
+	flags.Parse(args)
+
+	keys, err := cfg.PayloadKeys()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rekey: %v\n", err)
+		return 1
+	}
+	if keys == nil {
+		fmt.Fprintln(os.Stderr, "rekey: PAYLOAD_ENCRYPTION_KEYS is not configured")
+		return 1
+	}
+	if database.IsSQLite(cfg.DatabaseURL) {
+		fmt.Fprintln(os.Stderr, "rekey: requires a PostgreSQL DATABASE_URL")
+		return 1
+	}
+
+	ctx := context.Background()
+	dbConn, ok := connect(ctx, "rekey", cfg)
+	if !ok {
+		return 1
+	}
+	defer dbConn.Close(ctx)
+
+	rekeyed, err := keys.Rekey(ctx, dbConn.Queries())
+	fmt.Printf("Re-encrypted %d payloads with key %s\n", rekeyed, keys.ActiveKey())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rekey: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// chain runs an event chain subcommand
+func chain(cfg *config.Config, args []string) int {
+	if len(args) > 0 && args[0] == "keygen" {
+		key, err := eventchain.GenerateKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "chain: %v\n", err)
+			return 1
+		}
+		fmt.Printf("EVENT_CHAIN_SIGNING_KEY=%s\n", eventchain.EncodeKey(key))
+		fmt.Printf("Public key: %s\n", eventchain.EncodePublicKey(key.Public().(ed25519.PublicKey)))
+		return 0
+	}
+	if len(args) == 0 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, "Usage: choochoo chain verify [-public-key KEY] [-json] | choochoo chain keygen")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("chain verify", flag.ExitOnError)
+	publicKey := flags.String("public-key", "", "public key checkpoints are signed with (default: that of EVENT_CHAIN_SIGNING_KEY)")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Parse(args[1:])
+
+	var key ed25519.PublicKey
+	if *publicKey != "" {
+		parsed, err := eventchain.ParsePublicKey(*publicKey)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "chain: %v\n", err)
+			return 2
+		}
+		key = parsed
+	} else {
+		signing, err := cfg.EventChainKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "chain: %v\n", err)
+			return 1
+		}
+		if signing == nil {
+			fmt.Fprintln(os.Stderr, "chain: pass -public-key or configure EVENT_CHAIN_SIGNING_KEY")
+			return 2
+		}
+		key = signing.Public().(ed25519.PublicKey)
+	}
+	keys, err := cfg.PayloadKeys()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "chain: %v\n", err)
+		return 1
+	}
+	if database.IsSQLite(cfg.DatabaseURL) {
+		fmt.Fprintln(os.Stderr, "chain: requires a PostgreSQL DATABASE_URL")
+		return 1
+	}
+
+	ctx := context.Background()
+	dbConn, ok := connect(ctx, "chain", cfg)
+	if !ok {
+		return 1
+	}
+	defer dbConn.Close(ctx)
+
+	report, err := eventchain.Verify(ctx, dbConn.Queries(), key, keys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "chain: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		json.NewEncoder(os.Stdout).Encode(report)
+	} else {
+		for _, problem := range report.Problems {
+			where := ""
+			if problem.DeliveryID != "" {
+				where = fmt.Sprintf(" (link %d, delivery %s)", problem.Seq, problem.DeliveryID)
+			}
+			fmt.Printf("%s: %s%s\n", problem.Kind, problem.Detail, where)
+		}
+		fmt.Printf("Verified %d chained events up to link %d (%s)\n", report.Events, report.HeadSeq, report.HeadHash)
+		if latest := report.LatestCheckpoint; latest != nil {
+			fmt.Printf("Latest valid checkpoint: link %d, signed %s\n", latest.Seq, latest.CreatedAt.Time.Format(time.RFC3339))
+		} else {
+			fmt.Println("No valid checkpoints")
+		}
+	}
+
+	if len(report.Problems) > 0 {
+		fmt.Fprintf(os.Stderr, "chain: %d problems found in the stored history\n", len(report.Problems))
+		return 1
+	}
+	return 0
+}
+
+// timeMachine replays a window of stored events, at accelerated speed, into
+// the dry-run endpoint of a staging instance and diffs how it would handle
+// each of them against how this build and configuration do
+func timeMachine(cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("timemachine", flag.ExitOnError)
+	target := flags.String("target", "", "base URL of the staging instance to compare with (required)")
+	token := flags.String("token", os.Getenv("CHOOCHOO_TARGET_TOKEN"), "admin API token of the staging instance (default: CHOOCHOO_TARGET_TOKEN)")
+	secret := flags.String("secret", cfg.WebhookSecret, "webhook secret of the staging instance (default: GITHUB_WEBHOOK_SECRET)")
+	since := flags.String("since", "24h", "start of the window, as an RFC 3339 time or a duration before now")
+	until := flags.String("until", "", "end of the window, as an RFC 3339 time or a duration before now (default: now)")
+	speed := flags.Float64("speed", 60, "how many times faster than received to replay events; 0 replays them back to back")
+	eventType := flags.String("type", "", "only replay events of this type")
+	repo := flags.String("repo", "", "only replay events from this repository")
+	limit := flags.Int("limit", 10000, "maximum number of events to replay")
+	asJSON := flags.Bool("json", false, "print one JSON object per differing event and the report")
+	flags.Parse(args)
+
+	if *target == "" {
+		fmt.Fprintln(os.Stderr, "timemachine: -target is required")
+		return 2
+	}
+	if *limit <= 0 || *speed < 0 {
+		fmt.Fprintln(os.Stderr, "timemachine: -limit must be positive and -speed not negative")
+		return 2
+	}
+	now := time.Now()
+	start, err := parseWhen(*since, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "timemachine: invalid -since: %v\n", err)
+		return 2
+	}
+	end := now
+	if *until != "" {
+		if end, err = parseWhen(*until, now); err != nil {
+			fmt.Fprintf(os.Stderr, "timemachine: invalid -until: %v\n", err)
+			return 2
+		}
+	}
+	if !start.Before(end) {
+		fmt.Fprintln(os.Stderr, "timemachine: -since must be before -until")
+		return 2
+	}
+	keys, err := cfg.PayloadKeys()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "timemachine: %v\n", err)
+		return 1
+	}
+	if database.IsSQLite(cfg.DatabaseURL) {
+		fmt.Fprintln(os.Stderr, "timemachine: requires a PostgreSQL DATABASE_URL")
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	dbConn, ok := connect(ctx, "timemachine", cfg)
+	if !ok {
+		return 1
+	}
+	defer dbConn.Close(context.Background())
+
+	rows, err := dbConn.Queries().SearchWebhookEvents(ctx, db.SearchWebhookEventsParams{
+		EventType:      *eventType,
+		RepositoryName: *repo,
+		Since:          pgtype.Timestamptz{Time: start, Valid: true},
+		Until:          pgtype.Timestamptz{Time: end, Valid: true},
+		RowLimit:       int32(*limit),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "timemachine: %v\n", err)
+		return 1
+	}
+	if len(rows) == *limit {
+		fmt.Fprintf(os.Stderr, "timemachine: replaying only the latest %d events of the window; raise -limit for more\n", *limit)
+	}
+	// Events are listed newest first and replayed in the order received
+	slices.Reverse(rows)
+	deliveries := make([]timemachine.Delivery, 0, len(rows))
+	for _, row := range rows {
+		payload, err := keys.Open(row.DeliveryID, row.Payload)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "timemachine: skipping delivery %s: %v\n", row.DeliveryID, err)
+			continue
+		}
+		deliveries = append(deliveries, timemachine.Delivery{
+			DeliveryID: row.DeliveryID,
+			EventType:  row.EventType,
+			Repository: row.RepositoryName.String,
+			ReceivedAt: row.CreatedAt.Time,
+			Payload:    payload,
+		})
+	}
+
+	baseline := timemachine.NewHandlerTarget(server.NewWebhookServer(cfg).DryRun, cfg.WebhookSecrets())
+	candidate := timemachine.NewHTTPTarget(*target, *token, *secret)
+	encoder := json.NewEncoder(os.Stdout)
+	report, err := timemachine.Replay(ctx, deliveries, baseline, candidate, *speed, func(diff timemachine.Diff) {
+		if *asJSON {
+			encoder.Encode(diff)
+			return
+		}
+		fmt.Printf("%s %s %s %s\n", diff.ReceivedAt.Format(time.RFC3339), diff.DeliveryID, diff.EventType, diff.Repository)
+		if diff.Error != "" {
+			fmt.Printf("  error: %s\n", diff.Error)
+		}
+		for _, change := range diff.Changes {
+			fmt.Printf("  %s: %s -> %s\n", change.Field, change.Baseline, change.Candidate)
+		}
+	})
+	if *asJSON {
+		encoder.Encode(report)
+	} else {
+		fmt.Printf("Replayed %d events in %s: %d handled differently, %d failed\n",
+			report.Events, report.Duration.Round(time.Second), report.Differing, report.Failed)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "timemachine: %v\n", err)
+		return 1
+	}
+	if report.Differing > 0 || report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// parseWhen parses an RFC 3339 time or a duration before now
+func parseWhen(value string, now time.Time) (time.Time, error) {
+	if ago, err := time.ParseDuration(value); err == nil {
+		return now.Add(-ago), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
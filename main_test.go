@@ -6,10 +6,21 @@ import (
 	"os"
 	"testing"
 
+	"github.com/deedubs/choochoo/internal/config"
 	"github.com/deedubs/choochoo/internal/handlers"
 	"github.com/deedubs/choochoo/internal/server"
 )
 
+// loadConfig loads the configuration from the environment
+func loadConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	return cfg
+}
+
 // Tests for NewWebhookServer
 
 func TestNewWebhookServer_DefaultPort(t *testing.T) {
@@ -22,7 +33,7 @@ func TestNewWebhookServer_DefaultPort(t *testing.T) {
 		}
 	}()
 
-	srv := server.NewWebhookServer()
+	srv := server.NewWebhookServer(loadConfig(t))
 	// Note: We can't access the port field directly anymore since it's not exported
 	// This is actually better encapsulation, but we'll need to test the behavior differently
 	// For now, we'll just ensure the server can be created without error
@@ -42,7 +53,7 @@ func TestNewWebhookServer_CustomPort(t *testing.T) {
 		}
 	}()
 
-	srv := server.NewWebhookServer()
+	srv := server.NewWebhookServer(loadConfig(t))
 	// Note: Port field is now private, but we can ensure server was created
 	if srv == nil {
 		t.Error("Expected server to be created, got nil")
@@ -60,7 +71,7 @@ func TestNewWebhookServer_WithSecret(t *testing.T) {
 		}
 	}()
 
-	srv := server.NewWebhookServer()
+	srv := server.NewWebhookServer(loadConfig(t))
 	// Note: webhookSecret field is now private, but we can ensure server was created
 	if srv == nil {
 		t.Error("Expected server to be created, got nil")
@@ -80,7 +91,7 @@ func TestWebhookServer_RoutingIntegration(t *testing.T) {
 	
 	mux.HandleFunc("/webhook", webhookHandler.HandleWebhook)
 	mux.HandleFunc("/health", healthHandler.HandleHealth)
-	mux.HandleFunc("/", handlers.HandleRoot)
+	mux.HandleFunc("/", handlers.NewRootHandler(false).HandleRoot)
 
 	testServer := httptest.NewServer(mux)
 	defer testServer.Close()
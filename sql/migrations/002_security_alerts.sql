@@ -0,0 +1,23 @@
+-- Create security_alerts table to track the lifecycle of security alerts
+CREATE TABLE security_alerts (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(50) NOT NULL,
+    repository_name VARCHAR(255) NOT NULL,
+    alert_number INTEGER NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    state VARCHAR(50) NOT NULL,
+    summary TEXT,
+    html_url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    resolution VARCHAR(20),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (kind, repository_name, alert_number)
+);
+
+-- Add indexes for posture reporting
+CREATE INDEX idx_security_alerts_open ON security_alerts (severity) WHERE resolved_at IS NULL;
+CREATE INDEX idx_security_alerts_resolved_at ON security_alerts (resolved_at);
+
+-- Add a comment to the table
+COMMENT ON TABLE security_alerts IS 'Tracks dependabot, code scanning and secret scanning alerts for SLA reporting';
@@ -0,0 +1,22 @@
+-- Create branch_protection_history table as a compliance trail of protection changes
+CREATE TABLE branch_protection_history (
+    id SERIAL PRIMARY KEY,
+    delivery_id VARCHAR(255) NOT NULL,
+    repository_name VARCHAR(255) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    rule_id BIGINT NOT NULL,
+    pattern TEXT NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    config JSONB NOT NULL,
+    weakened BOOLEAN NOT NULL DEFAULT FALSE,
+    weakened_reasons TEXT[] NOT NULL DEFAULT '{}',
+    sender_login VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add indexes for history lookups
+CREATE INDEX idx_branch_protection_history_rule ON branch_protection_history (repository_name, source, rule_id, created_at DESC);
+CREATE INDEX idx_branch_protection_history_pattern ON branch_protection_history (repository_name, pattern, created_at DESC);
+
+-- Add a comment to the table
+COMMENT ON TABLE branch_protection_history IS 'History of branch protection rule and repository ruleset configurations';
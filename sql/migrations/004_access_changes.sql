@@ -0,0 +1,25 @@
+-- Create access_changes table to track who gained or lost access to what
+CREATE TABLE access_changes (
+    id SERIAL PRIMARY KEY,
+    delivery_id VARCHAR(255) NOT NULL,
+    source VARCHAR(50) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    change VARCHAR(20) NOT NULL,
+    organization VARCHAR(255) NOT NULL,
+    repository_name VARCHAR(255),
+    team_slug VARCHAR(255),
+    principal VARCHAR(255) NOT NULL,
+    principal_type VARCHAR(20) NOT NULL,
+    permission VARCHAR(50),
+    previous_permission VARCHAR(50),
+    admin_grant BOOLEAN NOT NULL DEFAULT FALSE,
+    sender_login VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add indexes for access reviews
+CREATE INDEX idx_access_changes_organization ON access_changes (organization, created_at);
+CREATE INDEX idx_access_changes_principal ON access_changes (principal, created_at);
+
+-- Add a comment to the table
+COMMENT ON TABLE access_changes IS 'Member, team and organization access changes for access reviews';
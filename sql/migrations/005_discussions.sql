@@ -0,0 +1,39 @@
+-- Create discussions table with the latest state of each discussion
+CREATE TABLE discussions (
+    id SERIAL PRIMARY KEY,
+    repository_name VARCHAR(255) NOT NULL,
+    discussion_number INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    category VARCHAR(255),
+    state VARCHAR(50) NOT NULL,
+    answered BOOLEAN NOT NULL DEFAULT FALSE,
+    author_login VARCHAR(255),
+    html_url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (repository_name, discussion_number)
+);
+
+-- Create discussion_comments table with the latest body of each comment
+CREATE TABLE discussion_comments (
+    id SERIAL PRIMARY KEY,
+    comment_id BIGINT NOT NULL UNIQUE,
+    repository_name VARCHAR(255) NOT NULL,
+    discussion_number INTEGER NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    author_login VARCHAR(255),
+    html_url TEXT,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Add full text search indexes over discussion and comment bodies
+CREATE INDEX idx_discussions_search ON discussions USING GIN (to_tsvector('english', title || ' ' || body));
+CREATE INDEX idx_discussion_comments_search ON discussion_comments USING GIN (to_tsvector('english', body));
+CREATE INDEX idx_discussion_comments_discussion ON discussion_comments (repository_name, discussion_number);
+
+-- Add comments to the tables
+COMMENT ON TABLE discussions IS 'Latest state of GitHub Discussions for search and notification routing';
+COMMENT ON TABLE discussion_comments IS 'Latest body of GitHub Discussions comments for search';
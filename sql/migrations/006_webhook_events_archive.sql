@@ -0,0 +1,35 @@
+-- Create webhook_events_archive table for events moved out by the retention janitor
+CREATE TABLE webhook_events_archive (
+    id INTEGER PRIMARY KEY,
+    delivery_id VARCHAR(255) NOT NULL UNIQUE,
+    event_type VARCHAR(50) NOT NULL,
+    repository_name VARCHAR(255),
+    sender_login VARCHAR(255),
+    action VARCHAR(100),
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+    archived_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add indexes for common queries
+CREATE INDEX idx_webhook_events_archive_event_type ON webhook_events_archive (event_type);
+CREATE INDEX idx_webhook_events_archive_created_at ON webhook_events_archive (created_at);
+
+-- Delivery IDs stay unique once events are archived: a redelivery of an
+-- archived event is skipped, so it is reported as a duplicate like one of a
+-- stored event
+CREATE FUNCTION skip_archived_delivery() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM webhook_events_archive WHERE delivery_id = NEW.delivery_id) THEN
+        RETURN NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER webhook_events_skip_archived
+    BEFORE INSERT ON webhook_events
+    FOR EACH ROW EXECUTE FUNCTION skip_archived_delivery();
+
+-- Add a comment to the table
+COMMENT ON TABLE webhook_events_archive IS 'Webhook events past their retention period that were archived rather than deleted';
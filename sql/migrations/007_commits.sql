@@ -0,0 +1,23 @@
+-- Create commits table with commits normalized from push events
+CREATE TABLE commits (
+    id SERIAL PRIMARY KEY,
+    sha VARCHAR(64) NOT NULL,
+    repository_name VARCHAR(255) NOT NULL,
+    ref VARCHAR(255) NOT NULL,
+    author_name VARCHAR(255),
+    author_email VARCHAR(255),
+    author_login VARCHAR(255),
+    message TEXT NOT NULL,
+    committed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    delivery_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (repository_name, ref, sha)
+);
+
+-- Add indexes for commit analytics
+CREATE INDEX idx_commits_sha ON commits (sha);
+CREATE INDEX idx_commits_repository_committed_at ON commits (repository_name, committed_at);
+CREATE INDEX idx_commits_author_login ON commits (author_login);
+
+-- Add a comment to the table
+COMMENT ON TABLE commits IS 'Commits normalized from push events for analytics';
@@ -0,0 +1,22 @@
+-- Create project_item_moves table to track Projects (v2) items across columns
+CREATE TABLE project_item_moves (
+    id SERIAL PRIMARY KEY,
+    delivery_id VARCHAR(255) NOT NULL UNIQUE,
+    organization VARCHAR(255) NOT NULL,
+    project_node_id VARCHAR(255) NOT NULL,
+    item_node_id VARCHAR(255) NOT NULL,
+    content_node_id VARCHAR(255),
+    content_type VARCHAR(50),
+    action VARCHAR(50) NOT NULL,
+    field_name VARCHAR(255),
+    from_column VARCHAR(255),
+    to_column VARCHAR(255),
+    actor VARCHAR(255) NOT NULL,
+    moved_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add indexes for cycle time reports
+CREATE INDEX idx_project_item_moves_item ON project_item_moves (project_node_id, item_node_id, moved_at);
+
+-- Add a comment to the table
+COMMENT ON TABLE project_item_moves IS 'Projects (v2) items moving between columns, archived or deleted';
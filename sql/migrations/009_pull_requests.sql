@@ -0,0 +1,27 @@
+-- Create pull_requests table with the latest state of each pull request
+CREATE TABLE pull_requests (
+    id SERIAL PRIMARY KEY,
+    repository_name VARCHAR(255) NOT NULL,
+    pr_number INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    state VARCHAR(20) NOT NULL,
+    draft BOOLEAN NOT NULL DEFAULT FALSE,
+    merged BOOLEAN NOT NULL DEFAULT FALSE,
+    head_ref VARCHAR(255) NOT NULL,
+    base_ref VARCHAR(255) NOT NULL,
+    author_login VARCHAR(255),
+    html_url TEXT,
+    last_action VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    closed_at TIMESTAMP WITH TIME ZONE,
+    merged_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (repository_name, pr_number)
+);
+
+-- Add indexes for pull request analytics
+CREATE INDEX idx_pull_requests_repository_state ON pull_requests (repository_name, state);
+CREATE INDEX idx_pull_requests_author_login ON pull_requests (author_login);
+
+-- Add a comment to the table
+COMMENT ON TABLE pull_requests IS 'Latest state of pull requests normalized from pull_request events';
@@ -0,0 +1,21 @@
+-- Create comments table with the latest body of each issue and pull request comment
+CREATE TABLE comments (
+    id SERIAL PRIMARY KEY,
+    comment_id BIGINT NOT NULL UNIQUE,
+    repository_name VARCHAR(255) NOT NULL,
+    issue_number INTEGER NOT NULL,
+    is_pull_request BOOLEAN NOT NULL DEFAULT FALSE,
+    author_login VARCHAR(255),
+    body TEXT NOT NULL,
+    html_url TEXT,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Add indexes for comment lookups
+CREATE INDEX idx_comments_repository_issue ON comments (repository_name, issue_number);
+CREATE INDEX idx_comments_author_login ON comments (author_login);
+
+-- Add a comment to the table
+COMMENT ON TABLE comments IS 'Issue and pull request comments normalized from issue_comment events';
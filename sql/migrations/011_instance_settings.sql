@@ -0,0 +1,9 @@
+-- Create instance_settings table with settings applied from config bundles
+CREATE TABLE instance_settings (
+    name VARCHAR(255) PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add a comment to the table
+COMMENT ON TABLE instance_settings IS 'Dynamic instance settings applied from config bundles';
@@ -0,0 +1,15 @@
+-- Create work_items table as the queue of stored webhook events waiting to be processed
+CREATE TABLE work_items (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id VARCHAR(255) NOT NULL UNIQUE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    visible_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add an index for claiming visible items
+CREATE INDEX idx_work_items_visible_at ON work_items (visible_at);
+
+-- Add a comment to the table
+COMMENT ON TABLE work_items IS 'Stored webhook events waiting to be processed';
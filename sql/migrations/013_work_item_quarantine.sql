@@ -0,0 +1,7 @@
+-- Quarantine work items that keep failing, recording the processor that failed
+ALTER TABLE work_items
+    ADD COLUMN failed_processor VARCHAR(100),
+    ADD COLUMN quarantined_at TIMESTAMP WITH TIME ZONE;
+
+-- Add an index for listing quarantined items
+CREATE INDEX idx_work_items_quarantined_at ON work_items (quarantined_at) WHERE quarantined_at IS NOT NULL;
@@ -0,0 +1,12 @@
+-- Create repository_usage table to attribute storage and processing costs
+CREATE TABLE repository_usage (
+    month DATE NOT NULL,
+    repository_name VARCHAR(255) NOT NULL,
+    events BIGINT NOT NULL DEFAULT 0,
+    bytes_stored BIGINT NOT NULL DEFAULT 0,
+    processing_us BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (month, repository_name)
+);
+
+-- Add a comment to the table
+COMMENT ON TABLE repository_usage IS 'Monthly storage and processing usage per repository for cost attribution';
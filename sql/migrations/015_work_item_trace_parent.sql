@@ -0,0 +1,3 @@
+-- Carry the W3C traceparent of the request that queued a work item to the
+-- worker that processes it
+ALTER TABLE work_items ADD COLUMN trace_parent VARCHAR(55);
@@ -0,0 +1,17 @@
+-- Create api_tokens table with hashed bearer tokens for the query, replay
+-- and admin APIs
+CREATE TABLE api_tokens (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Names identify active tokens; revoked tokens keep theirs for auditing
+CREATE UNIQUE INDEX idx_api_tokens_active_name ON api_tokens(name) WHERE revoked_at IS NULL;
+
+-- Add a comment to the table
+COMMENT ON TABLE api_tokens IS 'Hashed API tokens with their scopes';
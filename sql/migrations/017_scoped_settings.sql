@@ -0,0 +1,12 @@
+-- Create scoped_settings table with the organization, repository and branch
+-- overrides of config bundles
+CREATE TABLE scoped_settings (
+    scope VARCHAR(512) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (scope, name)
+);
+
+-- Add a comment to the table
+COMMENT ON TABLE scoped_settings IS 'Organization, repository and branch overrides of instance settings';
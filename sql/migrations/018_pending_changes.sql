@@ -0,0 +1,20 @@
+-- Create pending_changes table with route and setting changes waiting for
+-- a second operator's approval
+CREATE TABLE pending_changes (
+    id SERIAL PRIMARY KEY,
+    setting_name VARCHAR(255) NOT NULL,
+    route_match TEXT NOT NULL DEFAULT '',
+    value TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    requested_by VARCHAR(255) NOT NULL,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    decided_by VARCHAR(255),
+    decided_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Pending changes are listed by expiry
+CREATE INDEX idx_pending_changes_pending ON pending_changes(expires_at) WHERE status = 'pending';
+
+-- Add a comment to the table
+COMMENT ON TABLE pending_changes IS 'Route and setting changes with their approval decisions';
@@ -0,0 +1,7 @@
+-- Limit API tokens to one organization, for tenants administering their own
+-- repositories. Tokens without an organization are instance tokens.
+ALTER TABLE api_tokens ADD COLUMN organization VARCHAR(255);
+
+CREATE INDEX idx_api_tokens_organization ON api_tokens(organization) WHERE organization IS NOT NULL;
+
+COMMENT ON COLUMN api_tokens.organization IS 'Organization a tenant token is limited to, lowercased, or NULL for instance tokens';
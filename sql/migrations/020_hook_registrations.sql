@@ -0,0 +1,21 @@
+-- Create hook_registrations table with the hooks pointing at this server,
+-- captured from the ping event GitHub sends when a hook is created
+CREATE TABLE hook_registrations (
+    id SERIAL PRIMARY KEY,
+    hook_id BIGINT NOT NULL UNIQUE,
+    hook_type VARCHAR(50) NOT NULL DEFAULT '',
+    target VARCHAR(255) NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    events TEXT[] NOT NULL,
+    url TEXT NOT NULL DEFAULT '',
+    content_type VARCHAR(50) NOT NULL DEFAULT '',
+    insecure_ssl BOOLEAN NOT NULL DEFAULT FALSE,
+    zen TEXT NOT NULL DEFAULT '',
+    delivery_id VARCHAR(255) NOT NULL,
+    hook_created_at TIMESTAMP WITH TIME ZONE,
+    hook_updated_at TIMESTAMP WITH TIME ZONE,
+    pinged_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add a comment to the table
+COMMENT ON TABLE hook_registrations IS 'GitHub hooks pointing at this server, from their ping events';
@@ -0,0 +1,18 @@
+-- Create delivery_activity table, a projection of stored deliveries per
+-- repository and hour for heat maps and activity calendars
+CREATE TABLE delivery_activity (
+    hour TIMESTAMP WITH TIME ZONE NOT NULL,
+    repository_name VARCHAR(255) NOT NULL,
+    events BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (hour, repository_name)
+);
+
+-- Start the projection from the events already stored
+INSERT INTO delivery_activity (hour, repository_name, events)
+SELECT date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC', COALESCE(repository_name, ''), COUNT(*)
+FROM webhook_events
+WHERE created_at IS NOT NULL
+GROUP BY 1, 2;
+
+-- Add a comment to the table
+COMMENT ON TABLE delivery_activity IS 'Stored deliveries per repository and hour for heat maps and activity calendars';
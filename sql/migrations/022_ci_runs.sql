@@ -0,0 +1,29 @@
+-- Create ci_runs table with the latest state of each check suite, check run
+-- and Actions workflow run
+CREATE TABLE ci_runs (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    run_id BIGINT NOT NULL,
+    repository_name VARCHAR(255) NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    app_slug VARCHAR(255),
+    head_branch VARCHAR(255),
+    head_sha VARCHAR(64) NOT NULL DEFAULT '',
+    trigger_event VARCHAR(50),
+    status VARCHAR(50) NOT NULL,
+    conclusion VARCHAR(50),
+    run_attempt INTEGER NOT NULL DEFAULT 1,
+    html_url TEXT,
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    duration_ms BIGINT,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (kind, run_id)
+);
+
+-- Add indexes for CI analytics
+CREATE INDEX idx_ci_runs_repository_name ON ci_runs (repository_name, kind, name);
+CREATE INDEX idx_ci_runs_completed_at ON ci_runs (completed_at) WHERE completed_at IS NOT NULL;
+
+-- Add a comment to the table
+COMMENT ON TABLE ci_runs IS 'Latest state of check suites, check runs and workflow runs for CI analytics';
@@ -0,0 +1,19 @@
+-- Create status_incidents table with the incident notes of the public
+-- status page
+CREATE TABLE status_incidents (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    impact VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Add an index for listing the incidents shown on the page
+CREATE INDEX idx_status_incidents_resolved_at ON status_incidents (resolved_at);
+
+-- Add a comment to the table
+COMMENT ON TABLE status_incidents IS 'Incident notes shown on the public status page';
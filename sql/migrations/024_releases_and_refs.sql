@@ -0,0 +1,40 @@
+-- Create releases table with the latest state of each release
+CREATE TABLE releases (
+    id SERIAL PRIMARY KEY,
+    release_id BIGINT NOT NULL UNIQUE,
+    repository_name VARCHAR(255) NOT NULL,
+    tag_name VARCHAR(255) NOT NULL,
+    target_commitish VARCHAR(255),
+    name TEXT,
+    body TEXT,
+    draft BOOLEAN NOT NULL DEFAULT FALSE,
+    prerelease BOOLEAN NOT NULL DEFAULT FALSE,
+    author_login VARCHAR(255),
+    html_url TEXT,
+    last_action VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+    published_at TIMESTAMP WITH TIME ZONE,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create refs table with every branch and tag created or deleted
+CREATE TABLE refs (
+    id SERIAL PRIMARY KEY,
+    delivery_id VARCHAR(255) NOT NULL UNIQUE,
+    repository_name VARCHAR(255) NOT NULL,
+    ref_type VARCHAR(20) NOT NULL,
+    ref VARCHAR(255) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    pusher_type VARCHAR(50),
+    sender_login VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add indexes for changelogs and audits
+CREATE INDEX idx_releases_repository_name ON releases (repository_name, published_at DESC);
+CREATE INDEX idx_refs_repository_name ON refs (repository_name, ref_type, created_at DESC);
+
+-- Add comments to the tables
+COMMENT ON TABLE releases IS 'Latest state of each release for changelogs';
+COMMENT ON TABLE refs IS 'Branches and tags created and deleted, for auditing publishing activity';
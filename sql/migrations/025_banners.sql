@@ -0,0 +1,19 @@
+-- Create banners table with the maintenance notes shown on the dashboard
+-- and in digests
+CREATE TABLE banners (
+    id SERIAL PRIMARY KEY,
+    message VARCHAR(500) NOT NULL,
+    level VARCHAR(20) NOT NULL,
+    dashboard BOOLEAN NOT NULL DEFAULT TRUE,
+    digests BOOLEAN NOT NULL DEFAULT TRUE,
+    starts_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ends_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add an index for finding the active banners
+CREATE INDEX idx_banners_ends_at ON banners (ends_at, starts_at);
+
+-- Add a comment to the table
+COMMENT ON TABLE banners IS 'Maintenance notes shown on the dashboard and appended to digests while active';
@@ -0,0 +1,13 @@
+-- Create rules table with the conditional actions run on stored events,
+-- alongside those of the rules file
+CREATE TABLE rules (
+    name VARCHAR(64) PRIMARY KEY,
+    condition TEXT NOT NULL,
+    actions JSONB NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add a comment to the table
+COMMENT ON TABLE rules IS 'Operator-defined conditions over events and the actions run when they hold';
@@ -0,0 +1,5 @@
+-- Add the SHA-256 of each payload, taken over its canonical JSON form when it
+-- was received, so stored, replayed and forwarded payloads can be verified.
+-- Events stored before checksums were recorded have none.
+ALTER TABLE webhook_events ADD COLUMN payload_sha256 CHAR(64);
+ALTER TABLE webhook_events_archive ADD COLUMN payload_sha256 CHAR(64);
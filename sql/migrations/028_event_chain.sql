@@ -0,0 +1,27 @@
+-- Create event_chain table linking each stored event to the one chained
+-- before it. Each link hashes the previous hash, the delivery ID and the
+-- payload checksum, so changing or deleting stored history breaks the chain.
+CREATE TABLE event_chain (
+    seq BIGINT PRIMARY KEY,
+    event_id INTEGER NOT NULL UNIQUE,
+    delivery_id VARCHAR(255) NOT NULL,
+    payload_sha256 CHAR(64) NOT NULL,
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    event_created_at TIMESTAMP WITH TIME ZONE,
+    chained_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create event_chain_checkpoints table with signatures over the head of the
+-- chain, so it cannot be rewritten without the signing key
+CREATE TABLE event_chain_checkpoints (
+    seq BIGINT PRIMARY KEY,
+    hash CHAR(64) NOT NULL,
+    key_id VARCHAR(64) NOT NULL,
+    signature TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add comments to the tables
+COMMENT ON TABLE event_chain IS 'Hash chain over stored events for tamper evidence';
+COMMENT ON TABLE event_chain_checkpoints IS 'Signed checkpoints of the event hash chain';
@@ -0,0 +1,31 @@
+-- Create legal_holds table with the repositories and time ranges whose
+-- stored events must be preserved
+CREATE TABLE legal_holds (
+    id SERIAL PRIMARY KEY,
+    repository_name VARCHAR(255),
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    reason VARCHAR(500) NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    released_by VARCHAR(255),
+    released_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Add an index for finding the holds in force
+CREATE INDEX idx_legal_holds_active ON legal_holds (repository_name) WHERE released_at IS NULL;
+
+-- Create legal_hold_audit table recording the holds placed and released and
+-- the deletions they blocked
+CREATE TABLE legal_hold_audit (
+    id BIGSERIAL PRIMARY KEY,
+    action VARCHAR(50) NOT NULL,
+    hold_ids INTEGER[] NOT NULL DEFAULT '{}',
+    actor VARCHAR(255) NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add comments to the tables
+COMMENT ON TABLE legal_holds IS 'Repositories and time ranges under legal hold, whose events retention and erasure keep';
+COMMENT ON TABLE legal_hold_audit IS 'Legal holds placed and released and the deletions they blocked';
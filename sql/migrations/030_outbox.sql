@@ -0,0 +1,21 @@
+-- Create outbox table of stored events waiting to be published to each forwarder
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id VARCHAR(255) NOT NULL,
+    target VARCHAR(255) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    visible_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (delivery_id, target)
+);
+
+-- Add an index for claiming undelivered entries
+CREATE INDEX idx_outbox_pending ON outbox (visible_at) WHERE delivered_at IS NULL;
+
+-- Add an index for pruning delivered entries
+CREATE INDEX idx_outbox_delivered_at ON outbox (delivered_at) WHERE delivered_at IS NOT NULL;
+
+-- Add a comment to the table
+COMMENT ON TABLE outbox IS 'Stored webhook events waiting to be published to forwarders';
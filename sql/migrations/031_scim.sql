@@ -0,0 +1,40 @@
+-- Create scim_users table of the dashboard users provisioned by an identity
+-- provider over SCIM
+CREATE TABLE scim_users (
+    id SERIAL PRIMARY KEY,
+    external_id VARCHAR(255) NOT NULL DEFAULT '',
+    user_name VARCHAR(255) NOT NULL,
+    display_name VARCHAR(255) NOT NULL DEFAULT '',
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    password_hash TEXT,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- User names are case-insensitive, as SCIM requires
+CREATE UNIQUE INDEX idx_scim_users_user_name ON scim_users (LOWER(user_name));
+
+-- Create scim_groups table of the groups mapped to choochoo roles
+CREATE TABLE scim_groups (
+    id SERIAL PRIMARY KEY,
+    external_id VARCHAR(255) NOT NULL DEFAULT '',
+    display_name VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create scim_group_members table of the users in each group
+CREATE TABLE scim_group_members (
+    group_id INTEGER NOT NULL REFERENCES scim_groups (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES scim_users (id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+-- Add an index for finding the groups of a user
+CREATE INDEX idx_scim_group_members_user_id ON scim_group_members (user_id);
+
+-- Add comments to the tables
+COMMENT ON TABLE scim_users IS 'Dashboard users provisioned over SCIM';
+COMMENT ON TABLE scim_groups IS 'Groups provisioned over SCIM, mapped to roles by SCIM_GROUP_ROLES';
+COMMENT ON TABLE scim_group_members IS 'Members of the groups provisioned over SCIM';
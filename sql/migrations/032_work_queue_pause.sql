@@ -0,0 +1,11 @@
+-- Create work_queue_pause table, holding a row while processing of the work
+-- queue is paused. Workers claim no items while it exists, so a pause holds
+-- across replicas without restarting them.
+CREATE TABLE work_queue_pause (
+    singleton BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (singleton),
+    paused_by VARCHAR(255) NOT NULL,
+    paused_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add a comment to the table
+COMMENT ON TABLE work_queue_pause IS 'Who paused processing of the work queue, while it is paused';
@@ -0,0 +1,11 @@
+-- Create event_provenance table with the verification chain of each stored
+-- event: GitHub's signature and those of the relays it came through, in
+-- order. Like the event chain, it is kept when the event is archived.
+CREATE TABLE event_provenance (
+    delivery_id VARCHAR(255) PRIMARY KEY,
+    chain JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Add a comment to the table
+COMMENT ON TABLE event_provenance IS 'Signatures verified for each stored event, from GitHub through each relay';
@@ -0,0 +1,38 @@
+-- Create deployments table with the final state of each finished deployment,
+-- normalized from deployment_status events when they are received so the
+-- DORA metrics do not read payloads, which may be encrypted
+CREATE TABLE deployments (
+    id SERIAL PRIMARY KEY,
+    deployment_id BIGINT NOT NULL,
+    repository_name VARCHAR(255) NOT NULL,
+    environment VARCHAR(255) NOT NULL DEFAULT '',
+    ref VARCHAR(255) NOT NULL DEFAULT '',
+    sha VARCHAR(64) NOT NULL DEFAULT '',
+    state VARCHAR(50) NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (repository_name, deployment_id)
+);
+
+-- Add an index for DORA metrics over a period
+CREATE INDEX idx_deployments_finished_at ON deployments (finished_at);
+
+-- Backfill the deployments of the events stored so far. Encrypted payloads
+-- cannot be read here and are skipped.
+INSERT INTO deployments (deployment_id, repository_name, environment, ref, sha, state, finished_at)
+SELECT DISTINCT ON (repository_name, payload->'deployment'->>'id')
+    (payload->'deployment'->>'id')::bigint,
+    repository_name,
+    COALESCE(payload->'deployment'->>'environment', ''),
+    COALESCE(payload->'deployment'->>'ref', ''),
+    COALESCE(payload->'deployment'->>'sha', ''),
+    payload->'deployment_status'->>'state',
+    COALESCE((payload->'deployment_status'->>'created_at')::timestamptz, created_at)
+FROM webhook_events
+WHERE event_type = 'deployment_status'
+  AND repository_name IS NOT NULL
+  AND payload->'deployment'->>'id' IS NOT NULL
+  AND payload->'deployment_status'->>'state' IN ('success', 'failure', 'error')
+ORDER BY repository_name, payload->'deployment'->>'id', created_at DESC;
+
+-- Add a comment to the table
+COMMENT ON TABLE deployments IS 'Final state of finished deployments for DORA metrics';
@@ -0,0 +1,45 @@
+-- Create event_details table with the fields of payloads that reports read
+-- in SQL, taken from each event when it is stored so the reports do not read
+-- payloads, which may be encrypted. Rows go with their event.
+CREATE TABLE event_details (
+    delivery_id VARCHAR(255) PRIMARY KEY REFERENCES webhook_events (delivery_id) ON DELETE CASCADE,
+    source_updated_at TIMESTAMP WITH TIME ZONE,
+    outcome VARCHAR(20),
+    subject VARCHAR(255)
+);
+
+-- Backfill the details of the events stored so far. Encrypted payloads
+-- cannot be read here and are skipped.
+INSERT INTO event_details (delivery_id, source_updated_at, outcome, subject)
+SELECT delivery_id, source_updated_at, outcome, subject
+FROM (
+    SELECT
+        delivery_id,
+        CASE WHEN event_type = 'pull_request'
+            THEN (payload->'pull_request'->>'updated_at')::timestamptz END AS source_updated_at,
+        CASE
+            WHEN event_type = 'workflow_run' AND action = 'completed' THEN
+                CASE
+                    WHEN payload->'workflow_run'->>'conclusion' = 'success' THEN 'success'
+                    WHEN payload->'workflow_run'->>'conclusion' IN ('failure', 'timed_out', 'startup_failure') THEN 'failure'
+                END
+            WHEN event_type = 'deployment_status' THEN
+                CASE
+                    WHEN payload->'deployment_status'->>'state' = 'success' THEN 'success'
+                    WHEN payload->'deployment_status'->>'state' IN ('failure', 'error') THEN 'failure'
+                END
+            WHEN event_type = 'page_build' THEN
+                CASE
+                    WHEN payload->'build'->>'status' = 'built' THEN 'success'
+                    WHEN payload->'build'->>'status' = 'errored' THEN 'failure'
+                END
+        END AS outcome,
+        CASE WHEN event_type = 'sponsorship'
+            THEN payload->'sponsorship'->'sponsorable'->>'login' END AS subject
+    FROM webhook_events
+    WHERE event_type IN ('pull_request', 'workflow_run', 'deployment_status', 'page_build', 'sponsorship')
+) details
+WHERE source_updated_at IS NOT NULL OR outcome IS NOT NULL OR subject IS NOT NULL;
+
+-- Add a comment to the table
+COMMENT ON TABLE event_details IS 'Payload fields of stored events read by reports, in plaintext';
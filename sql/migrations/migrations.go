@@ -0,0 +1,10 @@
+// Package migrations embeds the database schema migrations so the server
+// binary can apply them with "choochoo migrate"
+package migrations
+
+import "embed"
+
+// FS holds the migration files, named <version>_<description>.sql
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,23 @@
+-- name: InsertAccessChange :one
+INSERT INTO access_changes (
+    delivery_id,
+    source,
+    action,
+    change,
+    organization,
+    repository_name,
+    team_slug,
+    principal,
+    principal_type,
+    permission,
+    previous_permission,
+    admin_grant,
+    sender_login
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+) RETURNING *;
+
+-- name: ListAccessChanges :many
+SELECT * FROM access_changes
+WHERE (@organization::text = '' OR organization = @organization::text)
+ORDER BY created_at ASC, id ASC;
@@ -0,0 +1,36 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (name, token_hash, scopes, organization)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetActiveAPITokenByHash :one
+SELECT * FROM api_tokens
+WHERE token_hash = $1 AND revoked_at IS NULL;
+
+-- name: ListAPITokens :many
+SELECT * FROM api_tokens
+ORDER BY created_at, id;
+
+-- name: ListTenantAPITokens :many
+-- Tokens limited to an organization, including revoked ones.
+SELECT * FROM api_tokens
+WHERE organization = $1
+ORDER BY created_at, id;
+
+-- name: RevokeAPIToken :execrows
+UPDATE api_tokens
+SET revoked_at = NOW()
+WHERE name = $1 AND revoked_at IS NULL;
+
+-- name: RevokeTenantAPIToken :execrows
+-- Revokes a token only if it is limited to the organization.
+UPDATE api_tokens
+SET revoked_at = NOW()
+WHERE name = $1 AND organization = $2 AND revoked_at IS NULL;
+
+-- name: TouchAPIToken :exec
+-- Records a use of a token, at most once a minute to spare writes on busy
+-- tokens.
+UPDATE api_tokens
+SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
@@ -0,0 +1,20 @@
+-- name: CreateBanner :one
+INSERT INTO banners (message, level, dashboard, digests, starts_at, ends_at, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: ListBanners :many
+SELECT * FROM banners
+ORDER BY starts_at DESC, id DESC;
+
+-- name: ListActiveBanners :many
+-- Banners started by and not ended at the given time, most urgent first.
+SELECT * FROM banners
+WHERE starts_at <= @at AND ends_at > @at
+ORDER BY CASE level WHEN 'critical' THEN 0 WHEN 'warning' THEN 1 ELSE 2 END, starts_at, id;
+
+-- name: EndBanner :execrows
+-- Ends a banner that has not ended yet, at the given time.
+UPDATE banners
+SET ends_at = GREATEST(starts_at, @at)
+WHERE id = @id AND ends_at > @at;
@@ -0,0 +1,29 @@
+-- name: InsertBranchProtectionChange :one
+INSERT INTO branch_protection_history (
+    delivery_id,
+    repository_name,
+    source,
+    rule_id,
+    pattern,
+    action,
+    config,
+    weakened,
+    weakened_reasons,
+    sender_login
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+) RETURNING *;
+
+-- name: GetLatestBranchProtection :one
+SELECT * FROM branch_protection_history
+WHERE repository_name = $1 AND source = $2 AND rule_id = $3
+ORDER BY created_at DESC, id DESC
+LIMIT 1;
+
+-- name: ListBranchProtectionHistory :many
+SELECT * FROM branch_protection_history
+WHERE repository_name = @repository_name
+  AND (@pattern::text = '' OR pattern = @pattern::text)
+  AND (NOT @weakened_only::bool OR weakened)
+ORDER BY created_at DESC, id DESC
+LIMIT @row_limit;
@@ -0,0 +1,28 @@
+-- name: ListDailyIngest :many
+-- Events stored per UTC day with their payload bytes and the number of
+-- events in the busiest minute of the day.
+SELECT
+    day,
+    SUM(events)::bigint AS events,
+    SUM(bytes)::bigint AS bytes,
+    MAX(events)::bigint AS peak_minute_events
+FROM (
+    SELECT
+        (created_at AT TIME ZONE 'UTC')::date AS day,
+        date_trunc('minute', created_at) AS minute,
+        COUNT(*) AS events,
+        SUM(octet_length(payload::text)) AS bytes
+    FROM webhook_events
+    WHERE created_at >= $1
+    GROUP BY 1, 2
+) minutes
+GROUP BY day
+ORDER BY day;
+
+-- name: GetStorageSize :one
+-- Bytes the database takes on disk, bytes the events table takes including
+-- its indexes, and the planner's estimate of its rows, which is cheap to read.
+SELECT
+    pg_database_size(current_database())::bigint AS database_bytes,
+    pg_total_relation_size('webhook_events')::bigint AS events_bytes,
+    GREATEST((SELECT reltuples FROM pg_class WHERE oid = 'webhook_events'::regclass), 0)::bigint AS events_estimate;
@@ -0,0 +1,34 @@
+-- name: UpsertCIRun :exec
+-- Older deliveries arriving after newer ones do not overwrite newer state.
+INSERT INTO ci_runs (
+    kind,
+    run_id,
+    repository_name,
+    name,
+    app_slug,
+    head_branch,
+    head_sha,
+    trigger_event,
+    status,
+    conclusion,
+    run_attempt,
+    html_url,
+    started_at,
+    completed_at,
+    duration_ms,
+    updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
+)
+ON CONFLICT (kind, run_id) DO UPDATE SET
+    name = EXCLUDED.name,
+    head_branch = EXCLUDED.head_branch,
+    status = EXCLUDED.status,
+    conclusion = EXCLUDED.conclusion,
+    run_attempt = EXCLUDED.run_attempt,
+    html_url = EXCLUDED.html_url,
+    started_at = EXCLUDED.started_at,
+    completed_at = EXCLUDED.completed_at,
+    duration_ms = EXCLUDED.duration_ms,
+    updated_at = EXCLUDED.updated_at
+WHERE ci_runs.updated_at <= EXCLUDED.updated_at;
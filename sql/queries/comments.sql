@@ -0,0 +1,21 @@
+-- name: UpsertComment :exec
+INSERT INTO comments (
+    comment_id,
+    repository_name,
+    issue_number,
+    is_pull_request,
+    author_login,
+    body,
+    html_url,
+    deleted,
+    created_at,
+    updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT (comment_id) DO UPDATE SET
+    body = EXCLUDED.body,
+    html_url = EXCLUDED.html_url,
+    deleted = comments.deleted OR EXCLUDED.deleted,
+    updated_at = EXCLUDED.updated_at
+WHERE comments.updated_at <= EXCLUDED.updated_at OR EXCLUDED.deleted;
@@ -0,0 +1,21 @@
+-- name: InsertCommit :exec
+INSERT INTO commits (
+    sha,
+    repository_name,
+    ref,
+    author_name,
+    author_email,
+    author_login,
+    message,
+    committed_at,
+    delivery_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (repository_name, ref, sha) DO NOTHING;
+
+-- name: ListCommitsByRepository :many
+SELECT * FROM commits
+WHERE repository_name = $1
+ORDER BY committed_at DESC
+LIMIT $2 OFFSET $3;
@@ -0,0 +1,20 @@
+-- name: AddDeliveryActivity :exec
+-- Counts a stored delivery of a repository in the current UTC hour.
+INSERT INTO delivery_activity (hour, repository_name, events)
+VALUES (date_trunc('hour', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC', @repository_name, 1)
+ON CONFLICT (hour, repository_name) DO UPDATE
+SET events = delivery_activity.events + 1;
+
+-- name: ListDeliveryActivity :many
+-- Sums stored deliveries per local day and hour since a time, for one
+-- repository, every repository of an owner, or every repository.
+SELECT
+    (hour AT TIME ZONE @time_zone::text)::date AS day,
+    EXTRACT(HOUR FROM hour AT TIME ZONE @time_zone::text)::int AS hour_of_day,
+    SUM(events)::bigint AS events
+FROM delivery_activity
+WHERE hour >= @since
+  AND (@repository_name::text = '' OR lower(repository_name) = lower(@repository_name::text))
+  AND (@owner::text = '' OR lower(split_part(repository_name, '/', 1)) = lower(@owner::text))
+GROUP BY 1, 2
+ORDER BY 1, 2;
@@ -0,0 +1,20 @@
+-- name: UpsertDeployment :exec
+-- Older statuses arriving after newer ones do not overwrite the newer state.
+INSERT INTO deployments (
+    deployment_id,
+    repository_name,
+    environment,
+    ref,
+    sha,
+    state,
+    finished_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (repository_name, deployment_id) DO UPDATE SET
+    environment = EXCLUDED.environment,
+    ref = EXCLUDED.ref,
+    sha = EXCLUDED.sha,
+    state = EXCLUDED.state,
+    finished_at = EXCLUDED.finished_at
+WHERE deployments.finished_at <= EXCLUDED.finished_at;
@@ -0,0 +1,71 @@
+-- name: UpsertDiscussion :exec
+INSERT INTO discussions (
+    repository_name,
+    discussion_number,
+    title,
+    body,
+    category,
+    state,
+    answered,
+    author_login,
+    html_url,
+    created_at,
+    updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+ON CONFLICT (repository_name, discussion_number) DO UPDATE SET
+    title = EXCLUDED.title,
+    body = EXCLUDED.body,
+    category = EXCLUDED.category,
+    state = EXCLUDED.state,
+    answered = EXCLUDED.answered,
+    html_url = EXCLUDED.html_url,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: UpsertDiscussionComment :exec
+INSERT INTO discussion_comments (
+    comment_id,
+    repository_name,
+    discussion_number,
+    body,
+    author_login,
+    html_url,
+    deleted,
+    created_at,
+    updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+ON CONFLICT (comment_id) DO UPDATE SET
+    body = EXCLUDED.body,
+    html_url = EXCLUDED.html_url,
+    deleted = EXCLUDED.deleted,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: SearchDiscussions :many
+SELECT
+    d.repository_name,
+    d.discussion_number,
+    d.title,
+    d.category,
+    d.state,
+    d.answered,
+    d.author_login,
+    d.html_url,
+    d.updated_at
+FROM discussions d
+WHERE d.state <> 'deleted'
+  AND (@repository_name::text = '' OR d.repository_name = @repository_name::text)
+  AND (
+    to_tsvector('english', d.title || ' ' || d.body) @@ websearch_to_tsquery('english', @query::text)
+    OR EXISTS (
+        SELECT 1 FROM discussion_comments c
+        WHERE c.repository_name = d.repository_name
+          AND c.discussion_number = d.discussion_number
+          AND NOT c.deleted
+          AND to_tsvector('english', c.body) @@ websearch_to_tsquery('english', @query::text)
+    )
+  )
+ORDER BY d.updated_at DESC
+LIMIT @row_limit;
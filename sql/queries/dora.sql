@@ -0,0 +1,28 @@
+-- name: ListDeploymentOutcomes :many
+-- The final state of each deployment that finished in a time range.
+SELECT
+    repository_name::text AS repository_name,
+    deployment_id,
+    environment::text AS environment,
+    ref::text AS ref,
+    sha::text AS sha,
+    state::text AS state,
+    finished_at AS created_at
+FROM deployments
+WHERE finished_at >= @since
+  AND finished_at < @until
+ORDER BY repository_name, deployment_id;
+
+-- name: ListPushedCommits :many
+-- Commits pushed in a time range, with when they were made and pushed.
+SELECT repository_name, ref, sha, committed_at, created_at
+FROM commits
+WHERE created_at >= @since
+  AND created_at < @until;
+
+-- name: ListMergedPullRequests :many
+SELECT repository_name, pr_number, base_ref, merged_at
+FROM pull_requests
+WHERE merged
+  AND merged_at >= @since
+  AND merged_at < @until;
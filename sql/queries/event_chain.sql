@@ -0,0 +1,56 @@
+-- name: LockEventChain :exec
+-- Serializes appends to the chain until the end of the transaction.
+SELECT pg_advisory_xact_lock(hashtext('event_chain'));
+
+-- name: GetEventChainHead :one
+SELECT * FROM event_chain
+ORDER BY seq DESC
+LIMIT 1;
+
+-- name: ListUnchainedWebhookEvents :many
+-- Lists stored events that are not chained yet, in ID order, among those
+-- stored since @since.
+SELECT e.id, e.delivery_id, e.payload, e.payload_sha256, e.created_at
+FROM webhook_events e
+WHERE e.created_at >= @since
+  AND NOT EXISTS (SELECT 1 FROM event_chain c WHERE c.event_id = e.id)
+ORDER BY e.id
+LIMIT @row_limit;
+
+-- name: CountUnchainedWebhookEvents :one
+-- Counts stored events that are not chained although they were stored
+-- before @before.
+SELECT COUNT(*) FROM webhook_events e
+WHERE e.created_at < @before
+  AND NOT EXISTS (SELECT 1 FROM event_chain c WHERE c.event_id = e.id);
+
+-- name: CreateEventChainLink :exec
+INSERT INTO event_chain (seq, event_id, delivery_id, payload_sha256, prev_hash, hash, event_created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListEventChain :many
+-- Lists links after @after_seq with the stored or archived event they chain.
+-- Events that no longer exist have no delivery ID or payload.
+SELECT c.seq, c.delivery_id, c.payload_sha256, c.prev_hash, c.hash,
+    COALESCE(e.delivery_id, a.delivery_id) AS event_delivery_id,
+    COALESCE(e.payload, a.payload) AS payload
+FROM event_chain c
+LEFT JOIN webhook_events e ON e.id = c.event_id
+LEFT JOIN webhook_events_archive a ON a.id = c.event_id
+WHERE c.seq > @after_seq
+ORDER BY c.seq
+LIMIT @row_limit;
+
+-- name: GetLatestEventChainCheckpoint :one
+SELECT * FROM event_chain_checkpoints
+ORDER BY seq DESC
+LIMIT 1;
+
+-- name: CreateEventChainCheckpoint :one
+INSERT INTO event_chain_checkpoints (seq, hash, key_id, signature)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListEventChainCheckpoints :many
+SELECT * FROM event_chain_checkpoints
+ORDER BY seq;
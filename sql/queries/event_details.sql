@@ -0,0 +1,4 @@
+-- name: CreateEventDetails :exec
+INSERT INTO event_details (delivery_id, source_updated_at, outcome, subject)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (delivery_id) DO NOTHING;
@@ -0,0 +1,8 @@
+-- name: CreateEventProvenance :exec
+INSERT INTO event_provenance (delivery_id, chain)
+VALUES ($1, $2)
+ON CONFLICT (delivery_id) DO NOTHING;
+
+-- name: GetEventProvenance :one
+SELECT * FROM event_provenance
+WHERE delivery_id = $1;
@@ -0,0 +1,52 @@
+-- name: SearchWebhookEvents :many
+-- Most recent events matching the filters of the GraphQL events field.
+-- Empty strings and NULL times match every event.
+SELECT * FROM webhook_events
+WHERE (@event_type::text = '' OR event_type = @event_type::text)
+  AND (@repository_name::text = '' OR repository_name = @repository_name::text)
+  AND (@sender_login::text = '' OR sender_login = @sender_login::text)
+  AND (@action::text = '' OR action = @action::text)
+  AND (@since::timestamptz IS NULL OR created_at >= @since::timestamptz)
+  AND (@until::timestamptz IS NULL OR created_at < @until::timestamptz)
+ORDER BY created_at DESC, id DESC
+LIMIT @row_limit;
+
+-- name: ListRepositorySummaries :many
+-- Repositories with stored events, most recently active first, optionally
+-- only the one named.
+SELECT
+    repository_name::text AS repository_name,
+    COUNT(*)::bigint AS events,
+    MIN(created_at)::timestamptz AS first_event_at,
+    MAX(created_at)::timestamptz AS last_event_at
+FROM webhook_events
+WHERE repository_name IS NOT NULL
+  AND (@repository_name::text = '' OR repository_name = @repository_name::text)
+GROUP BY repository_name
+ORDER BY last_event_at DESC, repository_name
+LIMIT @row_limit;
+
+-- name: SearchPullRequests :many
+-- Most recently updated pull requests, optionally filtered by repository,
+-- state and author.
+SELECT * FROM pull_requests
+WHERE (@repository_name::text = '' OR repository_name = @repository_name::text)
+  AND (@state::text = '' OR state = @state::text)
+  AND (@author_login::text = '' OR author_login = @author_login::text)
+ORDER BY updated_at DESC, id DESC
+LIMIT @row_limit;
+
+-- name: GetPullRequest :one
+SELECT * FROM pull_requests
+WHERE repository_name = $1 AND pr_number = $2;
+
+-- name: SearchCommits :many
+-- Most recent commits, optionally filtered by repository, ref, author and
+-- the push that delivered them.
+SELECT * FROM commits
+WHERE (@repository_name::text = '' OR repository_name = @repository_name::text)
+  AND (@ref::text = '' OR ref = @ref::text)
+  AND (@author_login::text = '' OR author_login = @author_login::text)
+  AND (@delivery_id::text = '' OR delivery_id = @delivery_id::text)
+ORDER BY committed_at DESC, id DESC
+LIMIT @row_limit;
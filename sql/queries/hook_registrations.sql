@@ -0,0 +1,34 @@
+-- name: UpsertHookRegistration :exec
+INSERT INTO hook_registrations (
+    hook_id,
+    hook_type,
+    target,
+    active,
+    events,
+    url,
+    content_type,
+    insecure_ssl,
+    zen,
+    delivery_id,
+    hook_created_at,
+    hook_updated_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+)
+ON CONFLICT (hook_id) DO UPDATE SET
+    hook_type = EXCLUDED.hook_type,
+    target = EXCLUDED.target,
+    active = EXCLUDED.active,
+    events = EXCLUDED.events,
+    url = EXCLUDED.url,
+    content_type = EXCLUDED.content_type,
+    insecure_ssl = EXCLUDED.insecure_ssl,
+    zen = EXCLUDED.zen,
+    delivery_id = EXCLUDED.delivery_id,
+    hook_created_at = EXCLUDED.hook_created_at,
+    hook_updated_at = EXCLUDED.hook_updated_at,
+    pinged_at = NOW();
+
+-- name: ListHookRegistrations :many
+SELECT * FROM hook_registrations
+ORDER BY pinged_at DESC, hook_id;
@@ -0,0 +1,24 @@
+-- name: ListEventIndexes :many
+-- Indexes of the events table with their scans since the statistics were
+-- reset, their size, the planner's estimate of their entries and the average
+-- width of their keys, 32 bytes for expressions.
+SELECT
+    s.indexrelname::text AS name,
+    pg_get_indexdef(s.indexrelid)::text AS definition,
+    s.idx_scan::bigint AS scans,
+    s.idx_tup_read::bigint AS tuples_read,
+    pg_relation_size(s.indexrelid)::bigint AS size_bytes,
+    GREATEST(c.reltuples, 0)::bigint AS entries,
+    COALESCE((
+        SELECT SUM(COALESCE(st.avg_width, 32))
+        FROM unnest(i.indkey::int2[]) AS k(attnum)
+        LEFT JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = k.attnum AND k.attnum > 0
+        LEFT JOIN pg_stats st ON st.schemaname = s.schemaname AND st.tablename = s.relname AND st.attname = a.attname
+    ), 32)::bigint AS key_width,
+    i.indisunique AS is_unique,
+    i.indisvalid AS is_valid
+FROM pg_stat_user_indexes s
+JOIN pg_index i ON i.indexrelid = s.indexrelid
+JOIN pg_class c ON c.oid = s.indexrelid
+WHERE s.relname = 'webhook_events'
+ORDER BY s.indexrelname;
@@ -0,0 +1,19 @@
+-- name: ListInstanceSettings :many
+SELECT * FROM instance_settings
+ORDER BY name;
+
+-- name: UpsertInstanceSetting :exec
+INSERT INTO instance_settings (name, value, updated_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (name) DO UPDATE SET
+    value = EXCLUDED.value,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: DeleteInstanceSetting :exec
+DELETE FROM instance_settings
+WHERE name = $1;
+
+-- name: LockInstanceSettings :exec
+-- Serializes read-modify-write updates of settings until the end of the
+-- transaction.
+SELECT pg_advisory_xact_lock(hashtext('instance_settings'));
@@ -0,0 +1,47 @@
+-- name: CreateLegalHold :one
+INSERT INTO legal_holds (repository_name, starts_at, ends_at, reason, created_by)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListLegalHolds :many
+-- Lists the holds in force first, then the released ones, newest first.
+SELECT * FROM legal_holds
+ORDER BY released_at IS NOT NULL, id DESC;
+
+-- name: ReleaseLegalHold :execrows
+UPDATE legal_holds
+SET released_at = NOW(), released_by = @released_by
+WHERE id = @id AND released_at IS NULL;
+
+-- name: ListLegalHoldsOnSender :many
+-- Lists the holds in force on any stored or archived event of a sender.
+SELECT h.* FROM legal_holds h
+WHERE h.released_at IS NULL
+  AND EXISTS (
+      SELECT 1 FROM (
+          SELECT repository_name, created_at FROM webhook_events WHERE sender_login = @sender_login
+          UNION ALL
+          SELECT repository_name, created_at FROM webhook_events_archive WHERE sender_login = @sender_login
+      ) e
+      WHERE (h.repository_name IS NULL OR h.repository_name = e.repository_name)
+        AND (h.starts_at IS NULL OR e.created_at >= h.starts_at)
+        AND (h.ends_at IS NULL OR e.created_at < h.ends_at)
+  )
+ORDER BY h.id;
+
+-- name: EraseSenderWebhookEvents :execrows
+DELETE FROM webhook_events
+WHERE sender_login = @sender_login;
+
+-- name: EraseSenderArchivedWebhookEvents :execrows
+DELETE FROM webhook_events_archive
+WHERE sender_login = @sender_login;
+
+-- name: CreateLegalHoldAuditEntry :exec
+INSERT INTO legal_hold_audit (action, hold_ids, actor, details)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListLegalHoldAudit :many
+SELECT * FROM legal_hold_audit
+ORDER BY id DESC
+LIMIT @row_limit;
@@ -0,0 +1,23 @@
+-- name: ListTableMaintenanceStats :many
+-- Tables of the current schema with their live and dead rows, the rows
+-- changed since they were last analyzed, their vacuum and analyze history,
+-- and their size on disk: the heap, the TOAST table of large values and the
+-- indexes.
+SELECT
+    s.relname::text AS name,
+    s.n_live_tup::bigint AS live_tuples,
+    s.n_dead_tup::bigint AS dead_tuples,
+    s.n_mod_since_analyze::bigint AS modified_since_analyze,
+    GREATEST(s.last_vacuum, s.last_autovacuum)::timestamptz AS last_vacuum,
+    GREATEST(s.last_analyze, s.last_autoanalyze)::timestamptz AS last_analyze,
+    s.vacuum_count::bigint AS vacuum_count,
+    s.autovacuum_count::bigint AS autovacuum_count,
+    s.analyze_count::bigint AS analyze_count,
+    s.autoanalyze_count::bigint AS autoanalyze_count,
+    pg_relation_size(s.relid)::bigint AS heap_bytes,
+    COALESCE(pg_total_relation_size(NULLIF(c.reltoastrelid, 0)), 0)::bigint AS toast_bytes,
+    pg_indexes_size(s.relid)::bigint AS index_bytes
+FROM pg_stat_user_tables s
+JOIN pg_class c ON c.oid = s.relid
+WHERE s.schemaname = current_schema()
+ORDER BY s.relname;
@@ -0,0 +1,48 @@
+-- name: EnqueueOutboxEntries :exec
+-- Adds an entry for each stored event and target.
+INSERT INTO outbox (delivery_id, target)
+SELECT delivery_id, target
+FROM unnest(@delivery_ids::text[]) AS events (delivery_id)
+CROSS JOIN unnest(@targets::text[]) AS targets (target)
+ON CONFLICT (delivery_id, target) DO NOTHING;
+
+-- name: ClaimOutboxEntries :many
+-- Claims undelivered entries and hides them from other relays until the
+-- visibility timeout passes. SKIP LOCKED lets replicas share the outbox.
+UPDATE outbox
+SET attempts = attempts + 1,
+    visible_at = NOW() + make_interval(secs => @visibility_seconds::int)
+WHERE id IN (
+    SELECT id FROM outbox
+    WHERE visible_at <= NOW()
+      AND delivered_at IS NULL
+    ORDER BY id
+    LIMIT @batch_size::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkOutboxEntryDelivered :exec
+UPDATE outbox
+SET delivered_at = NOW(),
+    last_error = NULL
+WHERE id = $1;
+
+-- name: FailOutboxEntry :exec
+-- Records the error and makes the entry visible again after a backoff.
+UPDATE outbox
+SET last_error = @last_error,
+    visible_at = NOW() + make_interval(secs => @retry_seconds::int)
+WHERE id = @id;
+
+-- name: CountOutboxEntries :one
+-- Entries waiting to be published, and the oldest of them.
+SELECT
+    COUNT(*) AS pending,
+    MIN(created_at)::timestamptz AS oldest
+FROM outbox
+WHERE delivered_at IS NULL;
+
+-- name: DeleteDeliveredOutboxEntries :execrows
+DELETE FROM outbox
+WHERE delivered_at < @delivered_before;
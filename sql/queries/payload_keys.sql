@@ -0,0 +1,23 @@
+-- name: ListWebhookEventsToRekey :many
+-- Events after after_id whose payloads are not encrypted with key_id,
+-- including plaintext payloads, in batches for re-encryption.
+SELECT id, delivery_id, payload FROM webhook_events
+WHERE id > @after_id
+  AND (payload->'choochoo_encrypted'->>'key_id') IS DISTINCT FROM @key_id::text
+ORDER BY id
+LIMIT @row_limit;
+
+-- name: UpdateWebhookEventPayload :exec
+UPDATE webhook_events SET payload = $2 WHERE id = $1;
+
+-- name: ListArchivedEventsToRekey :many
+-- Archived events after after_id whose payloads are not encrypted with
+-- key_id, in batches for re-encryption.
+SELECT id, delivery_id, payload FROM webhook_events_archive
+WHERE id > @after_id
+  AND (payload->'choochoo_encrypted'->>'key_id') IS DISTINCT FROM @key_id::text
+ORDER BY id
+LIMIT @row_limit;
+
+-- name: UpdateArchivedEventPayload :exec
+UPDATE webhook_events_archive SET payload = $2 WHERE id = $1;
@@ -0,0 +1,21 @@
+-- name: CreatePendingChange :one
+INSERT INTO pending_changes (setting_name, route_match, value, requested_by, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetPendingChangeForUpdate :one
+-- Locks the change until the end of the transaction, so it is decided once.
+SELECT * FROM pending_changes
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ListPendingChanges :many
+-- Changes waiting for a decision that have not expired.
+SELECT * FROM pending_changes
+WHERE status = 'pending' AND expires_at > NOW()
+ORDER BY id;
+
+-- name: DecidePendingChange :exec
+UPDATE pending_changes
+SET status = $2, decided_by = $3, decided_at = NOW()
+WHERE id = $1;
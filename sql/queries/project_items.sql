@@ -0,0 +1,41 @@
+-- name: InsertProjectItemMove :exec
+INSERT INTO project_item_moves (
+    delivery_id,
+    organization,
+    project_node_id,
+    item_node_id,
+    content_node_id,
+    content_type,
+    action,
+    field_name,
+    from_column,
+    to_column,
+    actor
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+)
+ON CONFLICT (delivery_id) DO NOTHING;
+
+-- name: ListProjectColumnCycleTimes :many
+-- Time spent in each column of a single select field, measured from an item
+-- entering the column until it moves on or leaves the board. Archived and
+-- deleted rows have no field and end the time in the current column.
+SELECT
+    to_column::TEXT AS column_name,
+    COUNT(DISTINCT item_node_id) AS items,
+    COUNT(*) AS transitions,
+    AVG(EXTRACT(EPOCH FROM left_at - moved_at))::FLOAT8 AS avg_seconds,
+    (PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM left_at - moved_at)))::FLOAT8 AS median_seconds
+FROM (
+    SELECT
+        item_node_id,
+        to_column,
+        moved_at,
+        LEAD(moved_at) OVER (PARTITION BY item_node_id ORDER BY moved_at, id) AS left_at
+    FROM project_item_moves
+    WHERE project_node_id = @project_node_id
+      AND (field_name IS NULL OR field_name = @field_name::TEXT)
+) AS stays
+WHERE to_column IS NOT NULL AND left_at IS NOT NULL
+GROUP BY to_column
+ORDER BY to_column;
@@ -0,0 +1,34 @@
+-- name: UpsertPullRequest :exec
+-- Older deliveries arriving after newer ones do not overwrite newer state.
+INSERT INTO pull_requests (
+    repository_name,
+    pr_number,
+    title,
+    state,
+    draft,
+    merged,
+    head_ref,
+    base_ref,
+    author_login,
+    html_url,
+    last_action,
+    created_at,
+    updated_at,
+    closed_at,
+    merged_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+)
+ON CONFLICT (repository_name, pr_number) DO UPDATE SET
+    title = EXCLUDED.title,
+    state = EXCLUDED.state,
+    draft = EXCLUDED.draft,
+    merged = EXCLUDED.merged,
+    head_ref = EXCLUDED.head_ref,
+    base_ref = EXCLUDED.base_ref,
+    html_url = EXCLUDED.html_url,
+    last_action = EXCLUDED.last_action,
+    updated_at = EXCLUDED.updated_at,
+    closed_at = EXCLUDED.closed_at,
+    merged_at = EXCLUDED.merged_at
+WHERE pull_requests.updated_at <= EXCLUDED.updated_at;
@@ -0,0 +1,14 @@
+-- name: InsertRefChange :exec
+-- Redelivered events do not add duplicates.
+INSERT INTO refs (
+    delivery_id,
+    repository_name,
+    ref_type,
+    ref,
+    action,
+    pusher_type,
+    sender_login
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (delivery_id) DO NOTHING;
@@ -0,0 +1,35 @@
+-- name: UpsertRelease :exec
+-- A deleted release stays deleted; release IDs are not reused.
+INSERT INTO releases (
+    release_id,
+    repository_name,
+    tag_name,
+    target_commitish,
+    name,
+    body,
+    draft,
+    prerelease,
+    author_login,
+    html_url,
+    last_action,
+    created_at,
+    published_at,
+    deleted_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+)
+ON CONFLICT (release_id) DO UPDATE SET
+    repository_name = EXCLUDED.repository_name,
+    tag_name = EXCLUDED.tag_name,
+    target_commitish = EXCLUDED.target_commitish,
+    name = EXCLUDED.name,
+    body = EXCLUDED.body,
+    draft = EXCLUDED.draft,
+    prerelease = EXCLUDED.prerelease,
+    author_login = EXCLUDED.author_login,
+    html_url = EXCLUDED.html_url,
+    last_action = EXCLUDED.last_action,
+    created_at = EXCLUDED.created_at,
+    published_at = EXCLUDED.published_at,
+    deleted_at = COALESCE(releases.deleted_at, EXCLUDED.deleted_at),
+    updated_at = NOW();
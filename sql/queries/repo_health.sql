@@ -0,0 +1,48 @@
+-- name: ListWebhookLatencyByRepository :many
+-- Delivery latency is the time between a pull request changing on GitHub and
+-- the event being stored.
+SELECT
+    e.repository_name::text AS repository_name,
+    AVG(EXTRACT(EPOCH FROM e.created_at - d.source_updated_at))::float8 AS avg_seconds,
+    COUNT(*) AS samples
+FROM webhook_events e
+JOIN event_details d ON d.delivery_id = e.delivery_id
+WHERE e.event_type = 'pull_request'
+  AND e.repository_name IS NOT NULL
+  AND d.source_updated_at IS NOT NULL
+  AND e.created_at >= $1
+GROUP BY e.repository_name;
+
+-- name: ListCIOutcomesByRepository :many
+SELECT
+    e.repository_name::text AS repository_name,
+    COUNT(*) FILTER (WHERE d.outcome = 'success') AS succeeded,
+    COUNT(*) FILTER (WHERE d.outcome = 'failure') AS failed
+FROM webhook_events e
+LEFT JOIN event_details d ON d.delivery_id = e.delivery_id
+WHERE e.event_type = 'workflow_run'
+  AND e.action = 'completed'
+  AND e.repository_name IS NOT NULL
+  AND e.created_at >= $1
+GROUP BY e.repository_name;
+
+-- name: ListMergeWaitByRepository :many
+SELECT
+    repository_name,
+    AVG(EXTRACT(EPOCH FROM merged_at - created_at))::float8 AS avg_seconds,
+    COUNT(*) AS samples
+FROM pull_requests
+WHERE merged AND merged_at >= $1
+GROUP BY repository_name;
+
+-- name: ListDeployOutcomesByRepository :many
+SELECT
+    e.repository_name::text AS repository_name,
+    COUNT(*) FILTER (WHERE d.outcome = 'success') AS succeeded,
+    COUNT(*) FILTER (WHERE d.outcome = 'failure') AS failed
+FROM webhook_events e
+LEFT JOIN event_details d ON d.delivery_id = e.delivery_id
+WHERE e.event_type IN ('deployment_status', 'page_build')
+  AND e.repository_name IS NOT NULL
+  AND e.created_at >= $1
+GROUP BY e.repository_name;
@@ -0,0 +1,13 @@
+-- name: AddRepositoryUsage :exec
+-- Adds to the usage of a repository in the current UTC month.
+INSERT INTO repository_usage (month, repository_name, events, bytes_stored, processing_us)
+VALUES (date_trunc('month', NOW() AT TIME ZONE 'UTC')::date, @repository_name, @events, @bytes_stored, @processing_us)
+ON CONFLICT (month, repository_name) DO UPDATE
+SET events = repository_usage.events + EXCLUDED.events,
+    bytes_stored = repository_usage.bytes_stored + EXCLUDED.bytes_stored,
+    processing_us = repository_usage.processing_us + EXCLUDED.processing_us;
+
+-- name: ListRepositoryUsage :many
+SELECT * FROM repository_usage
+WHERE month = $1
+ORDER BY repository_name;
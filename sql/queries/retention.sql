@@ -0,0 +1,56 @@
+-- name: DeleteExpiredWebhookEvents :execrows
+-- Events under legal hold are kept.
+DELETE FROM webhook_events
+WHERE id IN (
+    SELECT id FROM webhook_events
+    WHERE created_at < @cutoff
+      AND (@event_type::text = '' OR event_type = @event_type::text)
+      AND NOT (event_type = ANY(@excluded_types::text[]))
+      AND NOT EXISTS (
+          SELECT 1 FROM legal_holds h
+          WHERE h.released_at IS NULL
+            AND (h.repository_name IS NULL OR h.repository_name = webhook_events.repository_name)
+            AND (h.starts_at IS NULL OR webhook_events.created_at >= h.starts_at)
+            AND (h.ends_at IS NULL OR webhook_events.created_at < h.ends_at)
+      )
+    ORDER BY id
+    LIMIT @batch_size::int
+);
+
+-- name: ArchiveExpiredWebhookEvents :one
+-- Events under legal hold are kept. An archived event with the same delivery
+-- ID, such as one restored by hand, is replaced, so no deleted event goes
+-- unarchived. Returns the number of deleted events.
+WITH expired AS (
+    DELETE FROM webhook_events
+    WHERE id IN (
+        SELECT id FROM webhook_events
+        WHERE created_at < @cutoff
+          AND (@event_type::text = '' OR event_type = @event_type::text)
+          AND NOT (event_type = ANY(@excluded_types::text[]))
+          AND NOT EXISTS (
+              SELECT 1 FROM legal_holds h
+              WHERE h.released_at IS NULL
+                AND (h.repository_name IS NULL OR h.repository_name = webhook_events.repository_name)
+                AND (h.starts_at IS NULL OR webhook_events.created_at >= h.starts_at)
+                AND (h.ends_at IS NULL OR webhook_events.created_at < h.ends_at)
+          )
+        ORDER BY id
+        LIMIT @batch_size::int
+    )
+    RETURNING id, delivery_id, event_type, repository_name, sender_login, action, payload, payload_sha256, created_at
+), archived AS (
+    INSERT INTO webhook_events_archive (id, delivery_id, event_type, repository_name, sender_login, action, payload, payload_sha256, created_at)
+    SELECT id, delivery_id, event_type, repository_name, sender_login, action, payload, payload_sha256, created_at FROM expired
+    ON CONFLICT (delivery_id) DO UPDATE SET
+        id = EXCLUDED.id,
+        event_type = EXCLUDED.event_type,
+        repository_name = EXCLUDED.repository_name,
+        sender_login = EXCLUDED.sender_login,
+        action = EXCLUDED.action,
+        payload = EXCLUDED.payload,
+        payload_sha256 = EXCLUDED.payload_sha256,
+        created_at = EXCLUDED.created_at,
+        archived_at = NOW()
+)
+SELECT count(*) FROM expired;
@@ -0,0 +1,17 @@
+-- name: ListRules :many
+SELECT * FROM rules
+ORDER BY name;
+
+-- name: UpsertRule :one
+-- Creates a rule or replaces the condition and actions of an existing one.
+INSERT INTO rules (name, condition, actions, created_by)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (name) DO UPDATE SET
+    condition = EXCLUDED.condition,
+    actions = EXCLUDED.actions,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE name = $1;
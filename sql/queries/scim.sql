@@ -0,0 +1,86 @@
+-- name: CreateSCIMUser :one
+INSERT INTO scim_users (external_id, user_name, display_name, email, password_hash, active)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetSCIMUser :one
+SELECT * FROM scim_users
+WHERE id = $1;
+
+-- name: GetSCIMUserByUserName :one
+SELECT * FROM scim_users
+WHERE LOWER(user_name) = LOWER(@user_name);
+
+-- name: ListSCIMUsers :many
+SELECT * FROM scim_users
+ORDER BY id;
+
+-- name: UpdateSCIMUser :one
+-- Replaces a user, keeping the password hash unless a new one is given.
+UPDATE scim_users
+SET external_id = @external_id,
+    user_name = @user_name,
+    display_name = @display_name,
+    email = @email,
+    active = @active,
+    password_hash = COALESCE(sqlc.narg(password_hash), password_hash),
+    updated_at = NOW()
+WHERE id = @id
+RETURNING *;
+
+-- name: DeleteSCIMUser :execrows
+DELETE FROM scim_users
+WHERE id = $1;
+
+-- name: CreateSCIMGroup :one
+INSERT INTO scim_groups (external_id, display_name)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetSCIMGroup :one
+SELECT * FROM scim_groups
+WHERE id = $1;
+
+-- name: ListSCIMGroups :many
+SELECT * FROM scim_groups
+ORDER BY id;
+
+-- name: UpdateSCIMGroup :one
+UPDATE scim_groups
+SET external_id = @external_id,
+    display_name = @display_name,
+    updated_at = NOW()
+WHERE id = @id
+RETURNING *;
+
+-- name: DeleteSCIMGroup :execrows
+DELETE FROM scim_groups
+WHERE id = $1;
+
+-- name: ListSCIMGroupMembers :many
+-- Lists every membership with the names of the user and the group.
+SELECT m.group_id, m.user_id, u.user_name, g.display_name
+FROM scim_group_members m
+JOIN scim_users u ON u.id = m.user_id
+JOIN scim_groups g ON g.id = m.group_id
+ORDER BY m.group_id, m.user_id;
+
+-- name: ListSCIMUserGroupNames :many
+SELECT g.display_name
+FROM scim_group_members m
+JOIN scim_groups g ON g.id = m.group_id
+WHERE m.user_id = $1
+ORDER BY g.display_name;
+
+-- name: AddSCIMGroupMember :exec
+INSERT INTO scim_group_members (group_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveSCIMGroupMember :exec
+DELETE FROM scim_group_members
+WHERE group_id = $1 AND user_id = $2;
+
+-- name: RemoveSCIMGroupMembers :exec
+DELETE FROM scim_group_members
+WHERE group_id = $1;
@@ -0,0 +1,14 @@
+-- name: ListScopedSettings :many
+SELECT * FROM scoped_settings
+ORDER BY scope, name;
+
+-- name: UpsertScopedSetting :exec
+INSERT INTO scoped_settings (scope, name, value, updated_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (scope, name) DO UPDATE SET
+    value = EXCLUDED.value,
+    updated_at = EXCLUDED.updated_at;
+
+-- name: DeleteScopedSetting :exec
+DELETE FROM scoped_settings
+WHERE scope = $1 AND name = $2;
@@ -0,0 +1,40 @@
+-- name: UpsertSecurityAlert :one
+INSERT INTO security_alerts (
+    kind,
+    repository_name,
+    alert_number,
+    severity,
+    state,
+    summary,
+    html_url,
+    created_at,
+    resolved_at,
+    resolution
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT (kind, repository_name, alert_number) DO UPDATE SET
+    severity = EXCLUDED.severity,
+    state = EXCLUDED.state,
+    summary = EXCLUDED.summary,
+    html_url = EXCLUDED.html_url,
+    resolved_at = EXCLUDED.resolved_at,
+    resolution = EXCLUDED.resolution,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: ListOpenSecurityAlerts :many
+SELECT * FROM security_alerts
+WHERE resolved_at IS NULL
+ORDER BY created_at;
+
+-- name: GetSecurityAlertResolutionStats :many
+SELECT
+    severity,
+    resolution,
+    COUNT(*) AS resolved,
+    AVG(EXTRACT(EPOCH FROM (resolved_at - created_at)))::float8 AS avg_seconds
+FROM security_alerts
+WHERE resolved_at >= $1
+GROUP BY severity, resolution
+ORDER BY severity, resolution;
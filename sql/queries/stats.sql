@@ -0,0 +1,47 @@
+-- name: CountEventsPerDayByType :many
+-- Events stored per UTC day and event type in a time range.
+SELECT
+    (created_at AT TIME ZONE 'UTC')::date AS day,
+    event_type,
+    COUNT(*)::bigint AS events
+FROM webhook_events
+WHERE created_at >= @since
+  AND created_at < @until
+GROUP BY 1, 2
+ORDER BY 1, 2;
+
+-- name: ListMostActiveRepositories :many
+-- Repositories with the most events stored in a time range.
+SELECT
+    repository_name::text AS repository_name,
+    COUNT(*)::bigint AS events
+FROM webhook_events
+WHERE created_at >= @since
+  AND created_at < @until
+  AND repository_name IS NOT NULL
+GROUP BY repository_name
+ORDER BY events DESC, repository_name
+LIMIT @row_limit;
+
+-- name: ListMostActiveSenders :many
+-- Senders of the most events stored in a time range.
+SELECT
+    sender_login::text AS sender_login,
+    COUNT(*)::bigint AS events
+FROM webhook_events
+WHERE created_at >= @since
+  AND created_at < @until
+  AND sender_login IS NOT NULL
+GROUP BY sender_login
+ORDER BY events DESC, sender_login
+LIMIT @row_limit;
+
+-- name: CountPullRequestActivity :one
+-- Pull requests opened, merged and closed without merging in a time range,
+-- and those open now.
+SELECT
+    COUNT(*) FILTER (WHERE created_at >= @since AND created_at < @until)::bigint AS opened,
+    COUNT(*) FILTER (WHERE merged AND merged_at >= @since AND merged_at < @until)::bigint AS merged,
+    COUNT(*) FILTER (WHERE NOT merged AND closed_at >= @since AND closed_at < @until)::bigint AS closed,
+    COUNT(*) FILTER (WHERE state = 'open')::bigint AS open
+FROM pull_requests;
@@ -0,0 +1,41 @@
+-- name: CreateStatusIncident :one
+INSERT INTO status_incidents (title, body, impact, status, created_by, resolved_at)
+VALUES (
+    @title,
+    @body,
+    @impact,
+    @status::text,
+    @created_by,
+    CASE WHEN @status::text = 'resolved' THEN NOW() END
+)
+RETURNING *;
+
+-- name: GetStatusIncident :one
+SELECT * FROM status_incidents
+WHERE id = $1;
+
+-- name: UpdateStatusIncident :one
+-- Keeps resolved_at from when the incident was first resolved and clears it
+-- when the incident is reopened.
+UPDATE status_incidents
+SET title = @title,
+    body = @body,
+    impact = @impact,
+    status = @status::text,
+    updated_at = NOW(),
+    resolved_at = CASE
+        WHEN @status::text <> 'resolved' THEN NULL
+        ELSE COALESCE(resolved_at, NOW())
+    END
+WHERE id = @id
+RETURNING *;
+
+-- name: DeleteStatusIncident :execrows
+DELETE FROM status_incidents
+WHERE id = $1;
+
+-- name: ListStatusIncidents :many
+-- Unresolved incidents and those resolved since the given time, newest first.
+SELECT * FROM status_incidents
+WHERE resolved_at IS NULL OR resolved_at >= @since
+ORDER BY created_at DESC, id DESC;
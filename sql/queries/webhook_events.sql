@@ -5,15 +5,73 @@ INSERT INTO webhook_events (
     repository_name,
     sender_login,
     action,
-    payload
+    payload,
+    payload_sha256
 ) VALUES (
-    $1, $2, $3, $4, $5, $6
-) RETURNING *;
+    $1, $2, $3, $4, $5, $6, $7
+)
+ON CONFLICT (delivery_id) DO NOTHING
+RETURNING *;
+
+-- name: CreateWebhookEvents :many
+-- Stores a batch of events in one statement, returning the delivery IDs that
+-- were not already stored. Empty strings are stored as NULL.
+INSERT INTO webhook_events (
+    delivery_id,
+    event_type,
+    repository_name,
+    sender_login,
+    action,
+    payload,
+    payload_sha256
+)
+SELECT delivery_id, event_type, NULLIF(repository_name, ''), NULLIF(sender_login, ''), NULLIF(action, ''), payload::jsonb, NULLIF(payload_sha256, '')
+FROM unnest(
+    @delivery_ids::text[],
+    @event_types::text[],
+    @repository_names::text[],
+    @sender_logins::text[],
+    @actions::text[],
+    @payloads::text[],
+    @payload_sha256s::text[]
+) AS batch (delivery_id, event_type, repository_name, sender_login, action, payload, payload_sha256)
+ON CONFLICT (delivery_id) DO NOTHING
+RETURNING delivery_id;
 
 -- name: GetWebhookEventByDeliveryID :one
 SELECT * FROM webhook_events 
 WHERE delivery_id = $1;
 
+-- name: ListWebhookEvents :many
+-- Most recent events, optionally filtered by event type and repository.
+SELECT * FROM webhook_events
+WHERE (@event_type::text = '' OR event_type = @event_type::text)
+  AND (@repository_name::text = '' OR repository_name = @repository_name::text)
+ORDER BY created_at DESC, id DESC
+LIMIT @row_limit;
+
+-- name: ListDeliveries :many
+-- Most recent events with their work queue state, optionally filtered by
+-- event type and repository. Events without a work item were processed.
+SELECT
+    e.delivery_id,
+    e.event_type,
+    e.repository_name,
+    e.sender_login,
+    e.action,
+    e.created_at,
+    octet_length(e.payload::text)::int AS payload_bytes,
+    w.attempts,
+    w.last_error,
+    w.failed_processor,
+    w.quarantined_at
+FROM webhook_events e
+LEFT JOIN work_items w ON w.delivery_id = e.delivery_id
+WHERE (@event_type::text = '' OR e.event_type = @event_type::text)
+  AND (@repository_name::text = '' OR e.repository_name = @repository_name::text)
+ORDER BY e.created_at DESC, e.id DESC
+LIMIT @row_limit;
+
 -- name: ListWebhookEventsByType :many
 SELECT * FROM webhook_events 
 WHERE event_type = $1
@@ -26,10 +84,31 @@ WHERE repository_name = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
+-- name: ListCommunityEventsSince :many
+SELECT
+    COALESCE(e.repository_name, d.subject, '')::text AS subject,
+    e.event_type,
+    COALESCE(e.action, '')::text AS action,
+    COALESCE(e.sender_login, '')::text AS sender_login,
+    e.created_at
+FROM webhook_events e
+LEFT JOIN event_details d ON d.delivery_id = e.delivery_id
+WHERE e.event_type IN ('star', 'watch', 'fork', 'sponsorship')
+  AND e.created_at >= $1
+ORDER BY e.created_at ASC;
+
 -- name: CountWebhookEventsByType :one
 SELECT COUNT(*) FROM webhook_events 
 WHERE event_type = $1;
 
 -- name: DeleteOldWebhookEvents :exec
+-- Events under legal hold are kept.
 DELETE FROM webhook_events 
-WHERE created_at < $1;
\ No newline at end of file
+WHERE created_at < $1
+  AND NOT EXISTS (
+      SELECT 1 FROM legal_holds h
+      WHERE h.released_at IS NULL
+        AND (h.repository_name IS NULL OR h.repository_name = webhook_events.repository_name)
+        AND (h.starts_at IS NULL OR webhook_events.created_at >= h.starts_at)
+        AND (h.ends_at IS NULL OR webhook_events.created_at < h.ends_at)
+  );
\ No newline at end of file
@@ -0,0 +1,73 @@
+-- name: EnqueueWorkItem :exec
+INSERT INTO work_items (delivery_id, trace_parent)
+VALUES ($1, $2)
+ON CONFLICT (delivery_id) DO NOTHING;
+
+-- name: EnqueueWorkItems :exec
+-- Queues a batch of stored events. Empty trace parents are stored as NULL.
+INSERT INTO work_items (delivery_id, trace_parent)
+SELECT delivery_id, NULLIF(trace_parent, '')
+FROM unnest(@delivery_ids::text[], @trace_parents::text[]) AS batch (delivery_id, trace_parent)
+ON CONFLICT (delivery_id) DO NOTHING;
+
+-- name: ClaimWorkItems :many
+-- Claims visible items and hides them from other workers until the
+-- visibility timeout passes. SKIP LOCKED lets replicas share the queue.
+-- Nothing is claimed while the queue is paused.
+UPDATE work_items
+SET attempts = attempts + 1,
+    visible_at = NOW() + make_interval(secs => @visibility_seconds::int)
+WHERE id IN (
+    SELECT id FROM work_items
+    WHERE visible_at <= NOW()
+      AND quarantined_at IS NULL
+      AND NOT EXISTS (SELECT 1 FROM work_queue_pause)
+    ORDER BY id
+    LIMIT @batch_size::int
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteWorkItem :exec
+DELETE FROM work_items
+WHERE id = $1;
+
+-- name: FailWorkItem :exec
+-- Records the error and makes the item visible again after a backoff, or
+-- quarantines it so it is no longer retried.
+UPDATE work_items
+SET last_error = @last_error,
+    failed_processor = @failed_processor,
+    visible_at = NOW() + make_interval(secs => @retry_seconds::int),
+    quarantined_at = CASE WHEN @quarantine::bool THEN NOW() END
+WHERE id = @id;
+
+-- name: CountWorkItems :one
+-- Items waiting to be processed, and quarantined items.
+SELECT
+    COUNT(*) FILTER (WHERE quarantined_at IS NULL) AS pending,
+    COUNT(*) FILTER (WHERE quarantined_at IS NOT NULL) AS quarantined
+FROM work_items;
+
+-- name: ListQuarantinedWorkItems :many
+SELECT
+    w.delivery_id,
+    COALESCE(e.event_type, '')::text AS event_type,
+    e.repository_name,
+    w.failed_processor,
+    w.last_error,
+    w.attempts,
+    w.quarantined_at
+FROM work_items w
+LEFT JOIN webhook_events e ON e.delivery_id = w.delivery_id
+WHERE w.quarantined_at IS NOT NULL
+ORDER BY w.quarantined_at DESC, w.id DESC;
+
+-- name: ReleaseWorkItem :execrows
+-- Returns a quarantined item to the queue with its attempts reset.
+UPDATE work_items
+SET attempts = 0,
+    quarantined_at = NULL,
+    visible_at = NOW()
+WHERE delivery_id = $1
+  AND quarantined_at IS NOT NULL;
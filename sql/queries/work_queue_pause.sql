@@ -0,0 +1,10 @@
+-- name: GetWorkQueuePause :one
+SELECT * FROM work_queue_pause;
+
+-- name: PauseWorkQueue :exec
+INSERT INTO work_queue_pause (paused_by)
+VALUES ($1)
+ON CONFLICT DO NOTHING;
+
+-- name: ResumeWorkQueue :execrows
+DELETE FROM work_queue_pause;
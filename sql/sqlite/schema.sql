@@ -0,0 +1,20 @@
+-- SQLite schema of the event store, the equivalent of the webhook_events
+-- table in the PostgreSQL migrations. Statements are idempotent and run
+-- whenever the store is opened.
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    delivery_id TEXT NOT NULL UNIQUE,
+    event_type TEXT NOT NULL,
+    repository_name TEXT,
+    sender_login TEXT,
+    action TEXT,
+    payload TEXT NOT NULL,
+    -- UTC timestamps with a fixed width, so they sort as text
+    created_at TEXT NOT NULL,
+    payload_sha256 TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_event_type ON webhook_events (event_type);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_repository ON webhook_events (repository_name);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_sender ON webhook_events (sender_login);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_created_at ON webhook_events (created_at);
@@ -0,0 +1,10 @@
+// Package sqlite embeds the schema of the SQLite event store, which is
+// applied when the store is opened rather than with "choochoo migrate"
+package sqlite
+
+import _ "embed"
+
+// Schema creates the tables of the SQLite event store if they do not exist
+//
+//go:embed schema.sql
+var Schema string